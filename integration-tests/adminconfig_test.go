@@ -0,0 +1,58 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAdminConfig tests that the effective configuration is returned with the secrets redacted and with the
+// credentials that were read again from the credential files.
+func TestAdminConfig(t *testing.T) {
+	usernameFile := filepath.Join(t.TempDir(), "username")
+	require.Nil(t, os.WriteFile(usernameFile, []byte("cassandra\n"), 0600))
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TargetUsername = "cassandra"
+	conf.TargetUsernameFile = usernameFile
+	conf.CredentialFilesPollIntervalMs = 50
+	conf.AdminKeyspaceRoles = "admin"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	getConfig := func() *admin.EffectiveConfig {
+		recorder := httptest.NewRecorder()
+		admin.Handler(testSetup.Proxy).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, admin.ConfigPath, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var effectiveConfig admin.EffectiveConfig
+		require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &effectiveConfig))
+		return &effectiveConfig
+	}
+
+	effectiveConfig := getConfig()
+	require.Equal(t, "cassandra", effectiveConfig.Settings["ZDM_TARGET_USERNAME"])
+	require.Equal(t, "*****", effectiveConfig.Settings["ZDM_TARGET_PASSWORD"])
+	require.Equal(t, "*****", effectiveConfig.Settings["ZDM_ORIGIN_PASSWORD"])
+	require.Equal(t, usernameFile, effectiveConfig.Settings["ZDM_TARGET_USERNAME_FILE"])
+	require.Equal(t, map[string]string{"zdm_admin.settings.read_routing": "null"}, effectiveConfig.RuntimeSettings)
+
+	require.Nil(t, os.WriteFile(usernameFile, []byte("user2\n"), 0600))
+	require.Eventually(t, func() bool {
+		return getConfig().Settings["ZDM_TARGET_USERNAME"] == "user2"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	admin.Handler(nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, admin.ConfigPath, nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
@@ -0,0 +1,77 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+// TestAdminKeyspace tests that the read routing can be changed with an UPDATE statement on zdm_admin.settings
+func TestAdminKeyspace(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.AdminKeyspaceRoles = conf.TargetUsername
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originReads := int32(0)
+	targetReads := int32(0)
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newReadCountingHandler(&originReads),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newReadCountingHandler(&targetReads),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient, err := testclient.Connect(
+		context.Background(), testSetup.Proxy.GetListenAddr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, nil)
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	send := func(query string) message.Message {
+		response, _, err := testClient.SendMessage(
+			context.Background(), primitive.ProtocolVersion4, &message.Query{Query: query})
+		require.Nil(t, err)
+		return response.Body.Message
+	}
+	requireReads := func(expectedOrigin int32, expectedTarget int32) {
+		atomic.StoreInt32(&originReads, 0)
+		atomic.StoreInt32(&targetReads, 0)
+		require.IsType(t, &message.VoidResult{}, send("SELECT * FROM ks.events"))
+		require.Equal(t, expectedOrigin, atomic.LoadInt32(&originReads))
+		require.Equal(t, expectedTarget, atomic.LoadInt32(&targetReads))
+	}
+	selectReadRouting := func() interface{} {
+		result, ok := send("SELECT read_routing FROM zdm_admin.settings").(*message.RowsResult)
+		require.True(t, ok)
+		rowSet, err := zdmproxy.ParseRowsResult(
+			zdmproxy.GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4, result, nil, nil)
+		require.Nil(t, err)
+		require.Len(t, rowSet.Rows, 1)
+		return rowSet.Rows[0].Values[0]
+	}
+
+	requireReads(1, 0)
+	require.Nil(t, selectReadRouting())
+
+	require.IsType(t, &message.VoidResult{}, send("UPDATE zdm_admin.settings SET read_routing = 'target'"))
+	require.Equal(t, "target", selectReadRouting())
+	requireReads(0, 1)
+
+	require.IsType(t, &message.Invalid{}, send("UPDATE zdm_admin.settings SET read_routing = 'both'"))
+	require.IsType(t, &message.VoidResult{}, send("UPDATE zdm_admin.settings SET read_routing = null"))
+	requireReads(1, 0)
+}
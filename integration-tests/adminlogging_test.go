@@ -0,0 +1,72 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAdminLogging tests that the log level is changed and that the debug logs of a module or of a client are
+// enabled and disabled through the admin API.
+func TestAdminLogging(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	send := func(method string, path string, expectedCode int) *zdmproxy.LogLevelStatus {
+		recorder := httptest.NewRecorder()
+		admin.Handler(testSetup.Proxy).ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		require.Equal(t, expectedCode, recorder.Code, recorder.Body.String())
+		if expectedCode != http.StatusOK {
+			return nil
+		}
+		var status zdmproxy.LogLevelStatus
+		require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		return &status
+	}
+
+	status := send(http.MethodGet, admin.LoggingPath, http.StatusOK)
+	require.Equal(t, "info", status.Level)
+	require.Empty(t, status.Modules)
+	require.Empty(t, status.Clients)
+
+	status = send(http.MethodPost, admin.LoggingPath+"/level?level=warn", http.StatusOK)
+	require.Equal(t, "warning", status.Level)
+	require.Equal(t, log.WarnLevel, log.GetLevel())
+	send(http.MethodPost, admin.LoggingPath+"/level?level=verbose", http.StatusBadRequest)
+
+	status = send(http.MethodPost, admin.LoggingPath+"/enable?modules=parser,cluster_connector&clients=127.0.0.1", http.StatusOK)
+	require.Equal(t, 2, len(status.Modules))
+	require.Equal(t, "cluster_connector", status.Modules[0].Name)
+	require.Equal(t, "parser", status.Modules[1].Name)
+	require.Equal(t, 1, len(status.Clients))
+	send(http.MethodPost, admin.LoggingPath+"/enable?modules=coalescer", http.StatusBadRequest)
+	send(http.MethodPost, admin.LoggingPath+"/enable?modules=parser&duration=abc", http.StatusBadRequest)
+
+	status = send(http.MethodPost, admin.LoggingPath+"/disable?modules=parser", http.StatusOK)
+	require.Equal(t, 1, len(status.Modules))
+	status = send(http.MethodPost, admin.LoggingPath+"/disable", http.StatusOK)
+	require.Empty(t, status.Modules)
+	require.Empty(t, status.Clients)
+
+	testSetup.Proxy.ToggleDebugLogLevel()
+	require.Equal(t, log.DebugLevel, log.GetLevel())
+	testSetup.Proxy.ToggleDebugLogLevel()
+	require.Equal(t, log.InfoLevel, log.GetLevel())
+
+	recorder := httptest.NewRecorder()
+	admin.Handler(nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, admin.LoggingPath, nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
@@ -0,0 +1,138 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestAsyncDualWrites tests that the client response of a write doesn't wait for the secondary cluster when
+// ZDM_DUAL_WRITE_MODE is ASYNC and that the secondary write is retried in the background.
+func TestAsyncDualWrites(t *testing.T) {
+
+	type test struct {
+		name                   string
+		maxRetries             int
+		targetFailures         int32
+		targetError            message.Error
+		blockTarget            bool
+		expectedTargetAttempts int32
+		expectedRetried        int
+		expectedFailed         int
+	}
+
+	writeTimeout := &message.WriteTimeout{
+		ErrorMessage: "write timeout",
+		Consistency:  primitive.ConsistencyLevelLocalQuorum,
+		Received:     1,
+		BlockFor:     2,
+		WriteType:    primitive.WriteTypeSimple,
+	}
+
+	tests := []test{
+		{
+			name:                   "client response doesn't wait for secondary write",
+			maxRetries:             3,
+			blockTarget:            true,
+			expectedTargetAttempts: 1,
+		},
+		{
+			name:                   "secondary write succeeds after retries",
+			maxRetries:             3,
+			targetFailures:         2,
+			targetError:            writeTimeout,
+			expectedTargetAttempts: 3,
+			expectedRetried:        2,
+		},
+		{
+			name:                   "secondary write fails after retries",
+			maxRetries:             1,
+			targetFailures:         10,
+			targetError:            writeTimeout,
+			expectedTargetAttempts: 2,
+			expectedRetried:        1,
+			expectedFailed:         1,
+		},
+		{
+			name:                   "secondary write is not retried for non retryable errors",
+			maxRetries:             3,
+			targetFailures:         10,
+			targetError:            &message.Invalid{ErrorMessage: "invalid"},
+			expectedTargetAttempts: 1,
+			expectedFailed:         1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.DualWriteMode = config.DualWriteModeAsync
+			conf.DualWriteAsyncMaxRetries = tt.maxRetries
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originRecorder := &writeRecorder{}
+			targetAttempts := int32(0)
+			releaseTarget := make(chan bool)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				originRecorder.handler(),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+					query, ok := request.Body.Message.(*message.Query)
+					if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+						return nil
+					}
+					attempt := atomic.AddInt32(&targetAttempts, 1)
+					if tt.blockTarget {
+						<-releaseTarget
+					}
+					if attempt <= tt.targetFailures {
+						return frame.NewFrame(request.Header.Version, request.Header.StreamId, tt.targetError)
+					}
+					return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+				},
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			defer close(releaseTarget)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{
+					Query: "INSERT INTO ks.tbl (a) VALUES (1)",
+				}))
+			require.Nil(t, err)
+			require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			require.Equal(t, []string{"INSERT INTO ks.tbl (a) VALUES (1)"}, originRecorder.get())
+
+			utils.RequireWithRetries(t, func() (err error, fatal bool) {
+				attempts := atomic.LoadInt32(&targetAttempts)
+				if attempts != tt.expectedTargetAttempts {
+					return fmt.Errorf("expected %v attempts on target but got %v", tt.expectedTargetAttempts, attempts), false
+				}
+				values := getProxyMetricValues(t, testSetup)
+				metrics := fmt.Sprintf("retried=%v failed=%v",
+					values["zdm_proxy_async_writes_retried_total"], values["zdm_proxy_async_writes_failed_total"])
+				expectedMetrics := fmt.Sprintf("retried=%d failed=%d", tt.expectedRetried, tt.expectedFailed)
+				if metrics != expectedMetrics {
+					return fmt.Errorf("expected metrics %v but got %v", expectedMetrics, metrics), false
+				}
+				return nil, false
+			}, 50, 100*time.Millisecond)
+		})
+	}
+}
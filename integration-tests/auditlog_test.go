@@ -0,0 +1,87 @@
+package integration_tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.AuditLogDestination = config.AuditLogDestinationFile
+	conf.AuditLogQueryFormat = config.SlowQueryFormatObfuscated
+	conf.AuditLogFilePath = filepath.Join(t.TempDir(), "audit.log")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newPrimaryClusterWriteHandler(&message.VoidResult{}),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newPrimaryClusterWriteHandler(&message.VoidResult{}),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient, err := testclient.Connect(
+		context.Background(), testSetup.Proxy.GetListenAddr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, &testclient.Options{StartupOptions: map[string]string{
+			message.StartupOptionApplicationName: "orders-service",
+			message.StartupOptionClientId:        "a7f1c2d4-0000-4000-8000-000000000001",
+			message.StartupOptionDriverName:      "gocql",
+		}})
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	_, _, err = testClient.SendMessage(context.Background(), primitive.ProtocolVersion4,
+		&message.Query{Query: "INSERT INTO ks1.tb1 (a) VALUES ('secret')"})
+	require.Nil(t, err)
+
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		file, err := os.Open(conf.AuditLogFilePath)
+		if err != nil {
+			return err, true
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), "secret") {
+				return fmt.Errorf("audit record with a literal of the statement: %v", scanner.Text()), true
+			}
+			var fields map[string]interface{}
+			if err = json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+				return err, true
+			}
+			if fields["opcode"] != "QUERY" {
+				continue
+			}
+			require.Equal(t, "both", fields["forward_decision"])
+			require.Equal(t, "success", fields["outcome"])
+			require.Equal(t, "ks1", fields["keyspace"])
+			require.Equal(t, "tb1", fields["table"])
+			require.Equal(t, "INSERT INTO ks1.tb1 (a) VALUES (?)", fields["query"])
+			require.Equal(t, "orders-service", fields["application"])
+			require.Equal(t, "a7f1c2d4-0000-4000-8000-000000000001", fields["client_id"])
+			require.Equal(t, "gocql", fields["driver"])
+			return nil, false
+		}
+		return fmt.Errorf("audit record of the QUERY request not found"), false
+	}, 20, 100*time.Millisecond)
+}
@@ -1,11 +1,16 @@
 package integration_tests
 
 import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/simulacron"
 	"github.com/datastax/zdm-proxy/integration-tests/utils"
 	"github.com/gocql/gocql"
 	"github.com/stretchr/testify/require"
+	"sync/atomic"
 	"testing"
 )
 
@@ -155,6 +160,80 @@ func TestBatchWriteSuccessful(t *testing.T) {
 	require.True(t, err == nil, "batch shouldn't have failed but it did")
 }
 
+// TestBatchRouting tests that a BATCH is only forwarded to a single cluster when none of its child statements
+// are writes.
+func TestBatchRouting(t *testing.T) {
+
+	type test struct {
+		name                string
+		children            []*message.BatchChild
+		expectedOriginCount int32
+		expectedTargetCount int32
+	}
+
+	tests := []test{
+		{
+			name: "writes",
+			children: []*message.BatchChild{
+				{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"}, {QueryOrId: "SELECT * FROM ks.tb"}},
+			expectedOriginCount: 1,
+			expectedTargetCount: 1,
+		},
+		{
+			name: "reads",
+			children: []*message.BatchChild{
+				{QueryOrId: "SELECT * FROM ks.tb"}, {QueryOrId: "SELECT * FROM ks.tb2"}},
+			expectedOriginCount: 1,
+		},
+		{
+			name: "reads of intercepted tables",
+			children: []*message.BatchChild{
+				{QueryOrId: "SELECT * FROM system.local"}, {QueryOrId: "SELECT * FROM system.peers"}},
+			expectedOriginCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originBatches := int32(0)
+			targetBatches := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newBatchCountHandler(&originBatches),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newBatchCountHandler(&targetBatches),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Batch{Children: tt.children}))
+			require.Nil(t, err)
+			require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			require.Equal(t, tt.expectedOriginCount, atomic.LoadInt32(&originBatches))
+			require.Equal(t, tt.expectedTargetCount, atomic.LoadInt32(&targetBatches))
+		})
+	}
+}
+
+func newBatchCountHandler(count *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if _, ok := request.Body.Message.(*message.Batch); !ok {
+			return nil
+		}
+		atomic.AddInt32(count, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
+
 func newBatchOptions() *simulacron.WhenBatchOptions {
 	return simulacron.
 		NewWhenBatchOptions().
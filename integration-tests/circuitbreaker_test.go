@@ -0,0 +1,85 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreaker tests that the requests to TARGET are rejected (or only forwarded to ORIGIN if
+// ZDM_CIRCUIT_BREAKER_DEGRADE_TO_PRIMARY_ONLY is set) while its circuit breaker is open and that the breaker closes
+// when TARGET recovers.
+func TestCircuitBreaker(t *testing.T) {
+	for _, degrade := range []bool{false, true} {
+		t.Run(fmt.Sprintf("degrade=%v", degrade), func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.TargetCircuitBreakerErrorThresholdPercent = 50
+			conf.TargetCircuitBreakerMinRequests = 4
+			conf.TargetCircuitBreakerWindowMs = 200
+			conf.TargetCircuitBreakerOpenMs = 500
+			conf.CircuitBreakerDegradeToPrimaryOnly = degrade
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			targetFailures := 4
+			unavailable := &message.Unavailable{ErrorMessage: "unavailable", Consistency: primitive.ConsistencyLevelQuorum}
+			originQueries := int32(0)
+			targetQueries := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRequestRetryHandler(&originQueries, 0, unavailable),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRequestRetryHandler(&targetQueries, int32(targetFailures), unavailable),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			write := func() message.Message {
+				response, err := testSetup.Client.CqlConnection.SendAndReceive(frame.NewFrame(
+					primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"}))
+				require.Nil(t, err)
+				return response.Body.Message
+			}
+			breakerState := func() string {
+				return getProxyMetricValues(t, testSetup)[`zdm_proxy_circuit_breaker_state{cluster="target"}`]
+			}
+
+			require.Equal(t, "0", breakerState())
+			for i := 0; i < targetFailures; i++ {
+				require.IsType(t, &message.Unavailable{}, write())
+			}
+			require.Eventually(t, func() bool {
+				return breakerState() == "1"
+			}, 2*time.Second, 10*time.Millisecond)
+
+			if degrade {
+				require.IsType(t, &message.VoidResult{}, write())
+				require.Equal(t, int32(targetFailures+1), atomic.LoadInt32(&originQueries))
+			} else {
+				require.IsType(t, &message.Overloaded{}, write())
+				require.Equal(t, int32(targetFailures), atomic.LoadInt32(&originQueries))
+			}
+			require.Equal(t, int32(targetFailures), atomic.LoadInt32(&targetQueries))
+			metricValues := getProxyMetricValues(t, testSetup)
+			require.Equal(t, "1", metricValues[`zdm_proxy_circuit_breaker_rejected_requests_total{cluster="target"}`])
+			require.Equal(t, "0", metricValues[`zdm_proxy_circuit_breaker_rejected_requests_total{cluster="origin"}`])
+
+			// TARGET recovered, the probe request closes the breaker
+			time.Sleep(time.Duration(conf.TargetCircuitBreakerOpenMs) * time.Millisecond)
+			require.IsType(t, &message.VoidResult{}, write())
+			require.Equal(t, int32(targetFailures+1), atomic.LoadInt32(&targetQueries))
+			require.Equal(t, "0", breakerState())
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClientConnectionsBreakdownMetrics tests the gauges of connected clients by protocol version and by driver
+func TestClientConnectionsBreakdownMetrics(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	proxy, err := zdmproxy.RunWithOptions(conf, context.Background(), &zdmproxy.ZdmProxyOptions{
+		Listeners:         []net.Listener{listener},
+		MetricsRegisterer: prometheus.NewRegistry(),
+	})
+	require.Nil(t, err)
+	defer proxy.Shutdown()
+
+	getMetricLines := func(name string) []string {
+		recorder := httptest.NewRecorder()
+		proxy.GetMetricHandler().GetHttpHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var lines []string
+		for _, line := range strings.Split(recorder.Body.String(), "\n") {
+			if strings.HasPrefix(line, name+"{") {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	connect := func(version primitive.ProtocolVersion, startupOptions map[string]string) *testclient.TestClient {
+		testClient, err := testclient.Connect(
+			context.Background(), proxy.GetListenAddr().String(), version,
+			conf.TargetUsername, conf.TargetPassword, &testclient.Options{StartupOptions: startupOptions})
+		require.Nil(t, err)
+		return testClient
+	}
+
+	javaClient := connect(primitive.ProtocolVersion4, map[string]string{
+		message.StartupOptionDriverName:    "DataStax Java Driver",
+		message.StartupOptionDriverVersion: "4.14.1",
+	})
+	defer javaClient.Shutdown()
+	unknownClient := connect(primitive.ProtocolVersion3, nil)
+
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		return requireLines([]string{
+			`zdm_proxy_client_connections_by_protocol_version{protocol_version="V3"} 1`,
+			`zdm_proxy_client_connections_by_protocol_version{protocol_version="V4"} 1`,
+		}, getMetricLines("zdm_proxy_client_connections_by_protocol_version")), false
+	}, 10, 100*time.Millisecond)
+	require.Equal(t, []string{
+		`zdm_proxy_client_connections_by_driver{driver_name="DataStax Java Driver",driver_version="4.14.1"} 1`,
+		`zdm_proxy_client_connections_by_driver{driver_name="unknown",driver_version="unknown"} 1`,
+	}, getMetricLines("zdm_proxy_client_connections_by_driver"))
+
+	require.Nil(t, unknownClient.Shutdown())
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		return requireLines([]string{
+			`zdm_proxy_client_connections_by_driver{driver_name="DataStax Java Driver",driver_version="4.14.1"} 1`,
+			`zdm_proxy_client_connections_by_driver{driver_name="unknown",driver_version="unknown"} 0`,
+		}, getMetricLines("zdm_proxy_client_connections_by_driver")), false
+	}, 10, 100*time.Millisecond)
+	require.Equal(t, []string{
+		`zdm_proxy_client_connections_by_protocol_version{protocol_version="V3"} 0`,
+		`zdm_proxy_client_connections_by_protocol_version{protocol_version="V4"} 1`,
+	}, getMetricLines("zdm_proxy_client_connections_by_protocol_version"))
+}
+
+func requireLines(expected []string, actual []string) error {
+	if strings.Join(expected, "\n") != strings.Join(actual, "\n") {
+		return fmt.Errorf("expected %v but got %v", expected, actual)
+	}
+	return nil
+}
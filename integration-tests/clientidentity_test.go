@@ -0,0 +1,60 @@
+package integration_tests
+
+import (
+	"context"
+	"errors"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClientIdentityLogFields tests that the application identity sent in the STARTUP options is attached
+// to the log entries of the client connection
+func TestClientIdentityLogFields(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	buffer := createLogHooks(log.InfoLevel)
+	defer log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+
+	testClient, err := testclient.Connect(
+		context.Background(), testSetup.Proxy.GetListenAddr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, &testclient.Options{StartupOptions: map[string]string{
+			message.StartupOptionApplicationName:    "orders-service",
+			message.StartupOptionApplicationVersion: "1.2.0",
+			message.StartupOptionDriverName:         "gocql",
+		}})
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		for _, line := range strings.Split(buffer.String(), "\n") {
+			if strings.Contains(line, "Handshake successful") {
+				require.Contains(t, line, "application=orders-service")
+				require.Contains(t, line, "application_version=1.2.0")
+				require.Contains(t, line, "driver=gocql")
+				require.NotContains(t, line, "client_id=")
+				return nil, false
+			}
+		}
+		return errors.New("handshake log entry not found"), false
+	}, 10, 100*time.Millisecond)
+}
@@ -0,0 +1,77 @@
+package integration_tests
+
+import (
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/cqlserver"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestClientIdleTimeout(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyClientIdleTimeoutMs = 1000
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	newClient := func() *cqlserver.Client {
+		testClient, err := cqlserver.NewCqlClient(conf.ProxyListenAddress, conf.ProxyListenPort,
+			conf.OriginUsername, conf.OriginPassword, false)
+		require.Nil(t, err)
+		err = testClient.Connect(primitive.ProtocolVersion4)
+		require.Nil(t, err)
+		return testClient
+	}
+
+	// the first client only sends heartbeats, the second one sends USE requests
+	idleClient := newClient()
+	defer idleClient.Close()
+	activeClient := newClient()
+	defer activeClient.Close()
+
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		if !idleClient.CqlConnection.IsClosed() {
+			heartbeat := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Options{})
+			_, _ = idleClient.CqlConnection.SendAndReceive(heartbeat)
+		}
+
+		useRequest := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "USE ks1"})
+		response, err := activeClient.CqlConnection.SendAndReceive(useRequest)
+		if err != nil {
+			return err, true
+		}
+		if _, ok := response.Body.Message.(*message.SetKeyspaceResult); !ok {
+			return fmt.Errorf("expected SetKeyspaceResult but got %v", response.Body.Message), true
+		}
+
+		if !idleClient.CqlConnection.IsClosed() {
+			return errors.New("idle client connection was not closed"), false
+		}
+		return nil, false
+	}, 30, 200*time.Millisecond)
+
+	// the cluster connections of the idle client connection are closed as well,
+	// only the control connection and the cluster connection of the active client are left
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		serverConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+		if err != nil {
+			return err, true
+		}
+		if len(serverConns) != 2 {
+			return fmt.Errorf("expected 2 connections but got %v: %v", len(serverConns), serverConns), false
+		}
+		return nil, false
+	}, 20, 100*time.Millisecond)
+	require.False(t, activeClient.CqlConnection.IsClosed())
+}
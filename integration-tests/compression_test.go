@@ -0,0 +1,132 @@
+package integration_tests
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestCompression tests that the frames of a client that requested compression are decompressed by the proxy and
+// compressed with the compression that is requested from each cluster.
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		name              string
+		clientCompression primitive.Compression
+		originCompression string
+		targetCompression string
+		expectedOrigin    primitive.Compression
+		expectedTarget    primitive.Compression
+	}{
+		{
+			name:              "compression of the client",
+			clientCompression: primitive.CompressionLz4,
+			expectedOrigin:    primitive.CompressionLz4,
+			expectedTarget:    primitive.CompressionLz4,
+		},
+		{
+			name:              "compression of each cluster",
+			clientCompression: primitive.CompressionSnappy,
+			originCompression: "NONE",
+			targetCompression: "LZ4",
+			expectedOrigin:    primitive.CompressionNone,
+			expectedTarget:    primitive.CompressionLz4,
+		},
+		{
+			name:              "compression of the clusters only",
+			clientCompression: primitive.CompressionNone,
+			originCompression: "SNAPPY",
+			targetCompression: "LZ4",
+			expectedOrigin:    primitive.CompressionSnappy,
+			expectedTarget:    primitive.CompressionLz4,
+		},
+	}
+
+	value := make([]byte, 50000)
+	rand.New(rand.NewSource(0)).Read(value)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.OriginCompression = tt.originCompression
+			conf.TargetCompression = tt.targetCompression
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originStartups := &startupCompressions{}
+			targetStartups := &startupCompressions{}
+			originInserted := int32(0)
+			targetInserted := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				originStartups.handler,
+				newProtocolV5Handler(value, &originInserted),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				targetStartups.handler,
+				newProtocolV5Handler(value, &targetInserted),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+			testSetup.Client.CqlClient.Compression = tt.clientCompression
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			sendRequest := func(msg message.Message) *frame.Frame {
+				request := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, msg)
+				request.SetCompress(tt.clientCompression != primitive.CompressionNone)
+				response, err := testSetup.Client.CqlConnection.SendAndReceive(request)
+				require.Nil(t, err)
+				return response
+			}
+
+			response := sendRequest(&message.Query{
+				Query:   protocolV5InsertQuery,
+				Options: &message.QueryOptions{PositionalValues: []*primitive.Value{primitive.NewValue(value)}},
+			})
+			require.IsType(t, &message.VoidResult{}, response.Body.Message)
+
+			response = sendRequest(&message.Query{Query: protocolV5SelectQuery})
+			rows, ok := response.Body.Message.(*message.RowsResult)
+			require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+			require.Equal(t, 1, len(rows.Data))
+			require.True(t, bytes.Equal(value, rows.Data[0][0]))
+
+			require.Equal(t, int32(1), atomic.LoadInt32(&originInserted))
+			require.Equal(t, int32(1), atomic.LoadInt32(&targetInserted))
+			// the first STARTUP request is sent by the control connection
+			require.Equal(t, []primitive.Compression{primitive.CompressionNone, tt.expectedOrigin}, originStartups.get())
+			require.Equal(t, []primitive.Compression{primitive.CompressionNone, tt.expectedTarget}, targetStartups.get())
+		})
+	}
+}
+
+// startupCompressions records the compression of the STARTUP requests that are received by a CQL server.
+type startupCompressions struct {
+	lock         sync.Mutex
+	compressions []primitive.Compression
+}
+
+func (recv *startupCompressions) handler(
+	request *frame.Frame, _ *client.CqlServerConnection, _ client.RequestHandlerContext) *frame.Frame {
+	if startup, ok := request.Body.Message.(*message.Startup); ok {
+		recv.lock.Lock()
+		recv.compressions = append(recv.compressions, startup.GetCompression())
+		recv.lock.Unlock()
+	}
+	return nil
+}
+
+func (recv *startupCompressions) get() []primitive.Compression {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]primitive.Compression(nil), recv.compressions...)
+}
@@ -7,10 +7,10 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
-	"github.com/datastax/zdm-proxy/integration-tests/client"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/utils"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
 	"github.com/stretchr/testify/require"
 	"sync/atomic"
 	"testing"
@@ -85,7 +85,7 @@ func TestRequestedProtocolVersionUnsupportedByProxy(t *testing.T) {
 		errExpected     string
 	}{
 		{
-			"request v5, response v4",
+			"request v5 with max protocol version v4, response v4",
 			primitive.ProtocolVersion5,
 			primitive.ProtocolVersion4,
 			"Invalid or unsupported protocol version (5)",
@@ -101,6 +101,7 @@ func TestRequestedProtocolVersionUnsupportedByProxy(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			cfg := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			cfg.ProxyMaxProtocolVersion = 4
 			testSetup, err := setup.NewCqlServerTestSetup(t, cfg, false, false, false)
 			require.Nil(t, err)
 			defer testSetup.Cleanup()
@@ -111,7 +112,7 @@ func TestRequestedProtocolVersionUnsupportedByProxy(t *testing.T) {
 			err = testSetup.Start(cfg, false, primitive.ProtocolVersion3)
 			require.Nil(t, err)
 
-			testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+			testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 			require.Nil(t, err)
 
 			encodedFrame, err := createFrameWithUnsupportedVersion(test.requestVersion, 0, false)
@@ -137,7 +138,7 @@ func TestReturnedProtocolVersionUnsupportedByProxy(t *testing.T) {
 	}
 	tests := []*test{
 		{
-			"DSE_V2 request, v5 returned, v4 expected",
+			"DSE_V2 request, v5 returned with max protocol version v4, v4 expected",
 			primitive.ProtocolVersionDse2,
 			primitive.ProtocolVersion5,
 			primitive.ProtocolVersion4,
@@ -178,7 +179,7 @@ func TestReturnedProtocolVersionUnsupportedByProxy(t *testing.T) {
 		err = testSetup.Start(cfg, false, primitive.ProtocolVersion4)
 		require.Nil(t, err)
 
-		testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+		testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 		require.Nil(t, err)
 
 		enableHandlers.Store(true)
@@ -198,11 +199,13 @@ func TestReturnedProtocolVersionUnsupportedByProxy(t *testing.T) {
 			t.Run("no async reads", func(t *testing.T) {
 				cfg := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
 				cfg.ReadMode = config.ReadModePrimaryOnly
+				cfg.ProxyMaxProtocolVersion = 4
 				runTestFunc(t, test, cfg)
 			})
 			t.Run("async reads", func(t *testing.T) {
 				cfg := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
 				cfg.ReadMode = config.ReadModeDualAsyncOnSecondary
+				cfg.ProxyMaxProtocolVersion = 4
 				runTestFunc(t, test, cfg)
 			})
 		})
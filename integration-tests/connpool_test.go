@@ -0,0 +1,289 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/cqlserver"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClusterConnectionPoolSharesConnections(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyClusterConnectionPoolSize = 1
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	clientCount := 5
+	for i := 0; i < clientCount; i++ {
+		testClient, err := cqlserver.NewCqlClient(conf.ProxyListenAddress, conf.ProxyListenPort,
+			conf.OriginUsername, conf.OriginPassword, false)
+		require.Nil(t, err)
+		err = testClient.Connect(primitive.ProtocolVersion4)
+		require.Nil(t, err)
+		defer testClient.Close()
+
+		useRequest := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "USE ks1"})
+		response, err := testClient.CqlConnection.SendAndReceive(useRequest)
+		require.Nil(t, err)
+		require.IsType(t, &message.SetKeyspaceResult{}, response.Body.Message)
+		require.Equal(t, "ks1", response.Body.Message.(*message.SetKeyspaceResult).Keyspace)
+
+		heartbeat := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Options{})
+		response, err = testClient.CqlConnection.SendAndReceive(heartbeat)
+		require.Nil(t, err)
+		require.IsType(t, &message.Supported{}, response.Body.Message)
+	}
+
+	// control connection + one shared connection for keyspace ks1,
+	// the shared connection without keyspace is closed once all clients switched to ks1
+	for _, server := range []*client.CqlServer{testSetup.Origin.CqlServer, testSetup.Target.CqlServer} {
+		utils.RequireWithRetries(t, func() (err error, fatal bool) {
+			serverConns, err := server.AllAcceptedClients()
+			if err != nil {
+				return err, true
+			}
+			if len(serverConns) != 2 {
+				return fmt.Errorf("expected 2 connections but got %v: %v", len(serverConns), serverConns), false
+			}
+			return nil, false
+		}, 50, 100*time.Millisecond)
+	}
+}
+
+func TestClusterConnectionPoolReplacesConnectionsThatMissHeartbeats(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyClusterConnectionPoolSize = 1
+	conf.HeartbeatIntervalMs = 200
+	conf.HeartbeatMaxMissed = 2
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	// the OPTIONS requests received on this ORIGIN connection are never answered
+	unresponsiveConn := atomic.Value{}
+	unresponsiveConn.Store("")
+	testSetup.Origin.CqlServer.RequestRawHandlers = []client.RawRequestHandler{
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) []byte {
+			if request.Header.OpCode == primitive.OpCodeOptions &&
+				conn.RemoteAddr().String() == unresponsiveConn.Load().(string) {
+				return []byte{}
+			}
+			return nil
+		},
+	}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	controlConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(controlConns))
+
+	testClient, err := cqlserver.NewCqlClient(conf.ProxyListenAddress, conf.ProxyListenPort,
+		conf.OriginUsername, conf.OriginPassword, false)
+	require.Nil(t, err)
+	err = testClient.Connect(primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer testClient.Close()
+
+	serverConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(serverConns))
+	var sharedConn string
+	for _, serverConn := range serverConns {
+		if serverConn != controlConns[0] {
+			sharedConn = serverConn.RemoteAddr().String()
+		}
+	}
+	unresponsiveConn.Store(sharedConn)
+
+	// the idle shared connection misses its heartbeats and is replaced, the client connection is kept
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		serverConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+		if err != nil {
+			return err, true
+		}
+		if len(serverConns) != 2 {
+			return fmt.Errorf("expected 2 connections but got %v: %v", len(serverConns), serverConns), false
+		}
+		for _, serverConn := range serverConns {
+			if serverConn.RemoteAddr().String() == sharedConn {
+				return fmt.Errorf("shared connection %v was not replaced", sharedConn), false
+			}
+		}
+		return nil, false
+	}, 50, 100*time.Millisecond)
+
+	heartbeat := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Options{})
+	response, err := testClient.CqlConnection.SendAndReceive(heartbeat)
+	require.Nil(t, err)
+	require.IsType(t, &message.Supported{}, response.Body.Message)
+}
+
+func TestClusterConnectionPoolMovesClientsOfBrokenConnections(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyClusterConnectionPoolSize = 1
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	// the ORIGIN connections that received a REGISTER request, the hanging queries are never answered
+	registered := &sync.Map{}
+	testSetup.Origin.CqlServer.RequestRawHandlers = []client.RawRequestHandler{
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) []byte {
+			switch msg := request.Body.Message.(type) {
+			case *message.Register:
+				registered.Store(conn.RemoteAddr().String(), true)
+			case *message.Query:
+				if msg.Query == "SELECT * FROM ks1.hanging" {
+					return []byte{}
+				}
+			}
+			return nil
+		},
+	}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	controlConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(controlConns))
+
+	var testClients []*cqlserver.Client
+	for i := 0; i < 2; i++ {
+		testClient, err := cqlserver.NewCqlClient(conf.ProxyListenAddress, conf.ProxyListenPort,
+			conf.OriginUsername, conf.OriginPassword, false)
+		require.Nil(t, err)
+		err = testClient.Connect(primitive.ProtocolVersion4)
+		require.Nil(t, err)
+		defer testClient.Close()
+		testClients = append(testClients, testClient)
+	}
+	register := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId,
+		&message.Register{EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}})
+	response, err := testClients[0].CqlConnection.SendAndReceive(register)
+	require.Nil(t, err)
+	require.IsType(t, &message.Ready{}, response.Body.Message)
+
+	serverConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(serverConns))
+	var sharedConn *client.CqlServerConnection
+	for _, serverConn := range serverConns {
+		if serverConn != controlConns[0] {
+			sharedConn = serverConn
+		}
+	}
+
+	hangingQuery, err := testClients[0].CqlConnection.Send(frame.NewFrame(primitive.ProtocolVersion4,
+		client.ManagedStreamId, &message.Query{Query: "SELECT * FROM ks1.hanging"}))
+	require.Nil(t, err)
+	require.Nil(t, sharedConn.Close())
+
+	// the request that was in flight on the broken connection fails
+	response, err = testClients[0].CqlConnection.Receive(hangingQuery)
+	require.Nil(t, err)
+	require.IsType(t, &message.Overloaded{}, response.Body.Message)
+
+	// the client connections are kept and use a new shared connection that the events were registered on,
+	// the requests get OVERLOADED until it is opened
+	for _, testClient := range testClients {
+		utils.RequireWithRetries(t, func() (err error, fatal bool) {
+			heartbeat := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Options{})
+			response, err := testClient.CqlConnection.SendAndReceive(heartbeat)
+			if err != nil {
+				return err, true
+			}
+			if _, ok := response.Body.Message.(*message.Supported); !ok {
+				return fmt.Errorf("expected SUPPORTED but got %v", response.Body.Message), false
+			}
+			return nil, false
+		}, 50, 100*time.Millisecond)
+	}
+	serverConns, err = testSetup.Origin.CqlServer.AllAcceptedClients()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(serverConns))
+	for _, serverConn := range serverConns {
+		if serverConn != controlConns[0] {
+			require.NotEqual(t, sharedConn.RemoteAddr().String(), serverConn.RemoteAddr().String())
+			_, ok := registered.Load(serverConn.RemoteAddr().String())
+			require.True(t, ok)
+		}
+	}
+}
+
+func TestClusterConnectionPoolReplaysRegisterOnKeyspaceSwitch(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyClusterConnectionPoolSize = 1
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	registered := &sync.Map{}
+	testSetup.Origin.CqlServer.RequestRawHandlers = []client.RawRequestHandler{
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) []byte {
+			if request.Header.OpCode == primitive.OpCodeRegister {
+				registered.Store(conn.RemoteAddr().String(), true)
+			}
+			return nil
+		},
+	}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	controlConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(controlConns))
+
+	testClient, err := cqlserver.NewCqlClient(conf.ProxyListenAddress, conf.ProxyListenPort,
+		conf.OriginUsername, conf.OriginPassword, false)
+	require.Nil(t, err)
+	err = testClient.Connect(primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	defer testClient.Close()
+
+	register := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId,
+		&message.Register{EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}})
+	response, err := testClient.CqlConnection.SendAndReceive(register)
+	require.Nil(t, err)
+	require.IsType(t, &message.Ready{}, response.Body.Message)
+	useRequest := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "USE ks1"})
+	response, err = testClient.CqlConnection.SendAndReceive(useRequest)
+	require.Nil(t, err)
+	require.IsType(t, &message.SetKeyspaceResult{}, response.Body.Message)
+
+	// the shared connection of keyspace ks1 replaces the one without keyspace and gets the REGISTER request replayed
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		serverConns, err := testSetup.Origin.CqlServer.AllAcceptedClients()
+		if err != nil {
+			return err, true
+		}
+		if len(serverConns) != 2 {
+			return fmt.Errorf("expected 2 connections but got %v: %v", len(serverConns), serverConns), false
+		}
+		for _, serverConn := range serverConns {
+			if serverConn == controlConns[0] {
+				continue
+			}
+			if _, ok := registered.Load(serverConn.RemoteAddr().String()); !ok {
+				return fmt.Errorf("REGISTER was not replayed on %v", serverConn.RemoteAddr()), false
+			}
+		}
+		return nil, false
+	}, 50, 100*time.Millisecond)
+}
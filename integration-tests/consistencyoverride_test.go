@@ -0,0 +1,93 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestConsistencyOverride tests that the consistency level of the requests that are forwarded to TARGET is
+// overridden with ZDM_TARGET_CONSISTENCY_OVERRIDE while the requests that are forwarded to ORIGIN keep the
+// consistency level of the client.
+func TestConsistencyOverride(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TargetConsistencyOverride = "QUORUM:LOCAL_ONE"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originRecorder := &consistencyRecorder{}
+	targetRecorder := &consistencyRecorder{}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		originRecorder.handler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		targetRecorder.handler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	insert := "INSERT INTO ks.tb (a) VALUES (1)"
+	for _, msg := range []message.Message{
+		&message.Query{Query: insert, Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelQuorum}},
+		&message.Query{Query: insert, Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelAll}},
+		&message.Batch{
+			Children:    []*message.BatchChild{{QueryOrId: insert}},
+			Consistency: primitive.ConsistencyLevelQuorum,
+		},
+	} {
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, msg))
+		require.Nil(t, err)
+		require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	}
+
+	require.Equal(t, []primitive.ConsistencyLevel{
+		primitive.ConsistencyLevelQuorum, primitive.ConsistencyLevelAll, primitive.ConsistencyLevelQuorum,
+	}, originRecorder.get())
+	require.Equal(t, []primitive.ConsistencyLevel{
+		primitive.ConsistencyLevelLocalOne, primitive.ConsistencyLevelAll, primitive.ConsistencyLevelLocalOne,
+	}, targetRecorder.get())
+}
+
+// consistencyRecorder records the consistency levels of the INSERT and BATCH requests that a cluster receives.
+type consistencyRecorder struct {
+	lock   sync.Mutex
+	levels []primitive.ConsistencyLevel
+}
+
+func (recv *consistencyRecorder) handler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		var consistency primitive.ConsistencyLevel
+		switch msg := request.Body.Message.(type) {
+		case *message.Query:
+			if !strings.HasPrefix(msg.Query, "INSERT") {
+				return nil
+			}
+			consistency = msg.Options.Consistency
+		case *message.Batch:
+			consistency = msg.Consistency
+		default:
+			return nil
+		}
+		recv.lock.Lock()
+		recv.levels = append(recv.levels, consistency)
+		recv.lock.Unlock()
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
+
+func (recv *consistencyRecorder) get() []primitive.ConsistencyLevel {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]primitive.ConsistencyLevel(nil), recv.levels...)
+}
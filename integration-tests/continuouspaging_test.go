@@ -0,0 +1,138 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	continuousPagingQuery = "SELECT v FROM ks.tb"
+	continuousPagingPages = 8 // the test client buffers up to 10 frames per stream id
+)
+
+// TestContinuousPaging tests that the pages of a DSE continuous paging request are forwarded to the client in order
+// from a single cluster and that the REVISE requests are forwarded to that cluster.
+func TestContinuousPaging(t *testing.T) {
+	tests := []struct {
+		name     string
+		poolSize int
+	}{
+		{"dedicated cluster connections", 0},
+		{"pooled cluster connections", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.ProxyClusterConnectionPoolSize = tt.poolSize
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originHandler := &continuousPagingHandler{}
+			targetHandler := &continuousPagingHandler{}
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				originHandler.handle,
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				targetHandler.handle,
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersionDse2)
+			require.Nil(t, err)
+
+			for i := 0; i < 3; i++ {
+				request := frame.NewFrame(primitive.ProtocolVersionDse2, client.ManagedStreamId, &message.Query{
+					Query: continuousPagingQuery,
+					Options: &message.QueryOptions{
+						ContinuousPagingOptions: &message.ContinuousPagingOptions{MaxPages: continuousPagingPages},
+					},
+				})
+				inFlight, err := testSetup.Client.CqlConnection.Send(request)
+				require.Nil(t, err)
+				for page := 1; page <= continuousPagingPages; page++ {
+					response, err := testSetup.Client.CqlConnection.Receive(inFlight)
+					require.Nil(t, err)
+					rows, ok := response.Body.Message.(*message.RowsResult)
+					require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+					require.Equal(t, int32(page), rows.Metadata.ContinuousPageNumber)
+					require.Equal(t, page == continuousPagingPages, rows.Metadata.LastContinuousPage)
+				}
+
+				response, err := testSetup.Client.CqlConnection.SendAndReceive(frame.NewFrame(
+					primitive.ProtocolVersionDse2, client.ManagedStreamId, &message.Revise{
+						RevisionType:   primitive.DseRevisionTypeCancelContinuousPaging,
+						TargetStreamId: int32(request.Header.StreamId),
+					}))
+				require.Nil(t, err)
+				require.IsType(t, &message.RowsResult{}, response.Body.Message)
+			}
+
+			require.Equal(t, int32(3), atomic.LoadInt32(&originHandler.queries))
+			require.Equal(t, int32(3), atomic.LoadInt32(&originHandler.revisions))
+			require.Equal(t, int32(0), atomic.LoadInt32(&targetHandler.queries))
+			require.Equal(t, int32(0), atomic.LoadInt32(&targetHandler.revisions))
+		})
+	}
+}
+
+// continuousPagingHandler answers continuous paging requests with continuousPagingPages pages and REVISE requests
+// with a single row.
+type continuousPagingHandler struct {
+	queries   int32
+	revisions int32
+}
+
+func (recv *continuousPagingHandler) handle(
+	request *frame.Frame, conn *client.CqlServerConnection, _ client.RequestHandlerContext) *frame.Frame {
+	version := request.Header.Version
+	streamId := request.Header.StreamId
+	switch msg := request.Body.Message.(type) {
+	case *message.Query:
+		if msg.Query != continuousPagingQuery {
+			return nil
+		}
+		atomic.AddInt32(&recv.queries, 1)
+		for page := 1; page < continuousPagingPages; page++ {
+			if err := conn.Send(frame.NewFrame(version, streamId, newContinuousPage(page, false))); err != nil {
+				return nil
+			}
+		}
+		return frame.NewFrame(version, streamId, newContinuousPage(continuousPagingPages, true))
+	case *message.Revise:
+		atomic.AddInt32(&recv.revisions, 1)
+		return frame.NewFrame(version, streamId, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "tb", Name: "[applied]", Type: datatype.Boolean},
+				},
+			},
+			Data: message.RowSet{{{1}}},
+		})
+	}
+	return nil
+}
+
+func newContinuousPage(page int, last bool) *message.RowsResult {
+	return &message.RowsResult{
+		Metadata: &message.RowsMetadata{
+			ColumnCount: 1,
+			Columns: []*message.ColumnMetadata{
+				{Keyspace: "ks", Table: "tb", Name: "v", Type: datatype.Int},
+			},
+			ContinuousPageNumber: int32(page),
+			LastContinuousPage:   last,
+		},
+		Data: message.RowSet{{{0, 0, 0, byte(page)}}},
+	}
+}
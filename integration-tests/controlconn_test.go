@@ -779,7 +779,8 @@ func checkRegisterMessages(t *testing.T, registerMessages []*message.Register, l
 	defer lock.Unlock()
 	require.Equal(t, 1, len(registerMessages))
 	registerMsg := registerMessages[0]
-	require.Equal(t, []primitive.EventType{primitive.EventTypeTopologyChange}, registerMsg.EventTypes)
+	require.Equal(t, []primitive.EventType{
+		primitive.EventTypeTopologyChange, primitive.EventTypeStatusChange}, registerMsg.EventTypes)
 }
 
 func groupHostsPerDc(hosts []*zdmproxy.Host) map[string][]*zdmproxy.Host {
@@ -0,0 +1,90 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestCounterWritesPolicy tests that the counter updates are forwarded according to ZDM_COUNTER_WRITES_POLICY.
+func TestCounterWritesPolicy(t *testing.T) {
+
+	type test struct {
+		name                         string
+		counterWritesPolicy          string
+		primaryCluster               string
+		query                        string
+		expectedOriginCount          int32
+		expectedTargetCount          int32
+		expectedResponse             message.Message
+		expectedCounterWriteRequests string
+	}
+
+	counterQuery := "UPDATE ks.tb SET c = c + 1 WHERE a = 1"
+	tests := []test{
+		{"dual write", config.CounterWritesPolicyDualWrite, config.PrimaryClusterOrigin, counterQuery,
+			1, 1, &message.VoidResult{}, "0"},
+		{"primary only on origin", config.CounterWritesPolicyPrimaryOnly, config.PrimaryClusterOrigin, counterQuery,
+			1, 0, &message.VoidResult{}, "1"},
+		{"primary only on target", config.CounterWritesPolicyPrimaryOnly, config.PrimaryClusterTarget, counterQuery,
+			0, 1, &message.VoidResult{}, "1"},
+		{"primary only with counter batch", config.CounterWritesPolicyPrimaryOnly, config.PrimaryClusterOrigin,
+			"BEGIN COUNTER BATCH UPDATE ks.tb SET c = c - 1 WHERE a = 1; APPLY BATCH", 1, 0, &message.VoidResult{}, "1"},
+		{"reject", config.CounterWritesPolicyReject, config.PrimaryClusterOrigin, counterQuery,
+			0, 0, &message.Invalid{}, "1"},
+		{"regular write", config.CounterWritesPolicyReject, config.PrimaryClusterOrigin,
+			"UPDATE ks.tb SET b = 1 WHERE a = 1", 1, 1, &message.VoidResult{}, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.CounterWritesPolicy = tt.counterWritesPolicy
+			conf.PrimaryCluster = tt.primaryCluster
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originQueries := int32(0)
+			targetQueries := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newCounterWritesHandler(&originQueries),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newCounterWritesHandler(&targetQueries),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+			require.Equal(t, tt.expectedOriginCount, atomic.LoadInt32(&originQueries))
+			require.Equal(t, tt.expectedTargetCount, atomic.LoadInt32(&targetQueries))
+			require.Equal(t, tt.expectedCounterWriteRequests,
+				getProxyMetricValues(t, testSetup)["zdm_proxy_counter_write_requests_total"])
+		})
+	}
+}
+
+func newCounterWritesHandler(count *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.Contains(query.Query, " ks.tb ") {
+			return nil
+		}
+		atomic.AddInt32(count, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
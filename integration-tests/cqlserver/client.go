@@ -2,9 +2,10 @@ package cqlserver
 
 import (
 	"context"
-	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/client"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"net"
+	"strconv"
 )
 
 type Client struct {
@@ -20,7 +21,7 @@ func NewCqlClient(addr string, port int, username string, password string, conne
 			Password: password,
 		}
 	}
-	proxyAddr := fmt.Sprintf("%s:%d", addr, port)
+	proxyAddr := net.JoinHostPort(addr, strconv.Itoa(port))
 	clt := client.NewCqlClient(proxyAddr, authCreds)
 
 	var clientConn *client.CqlClientConnection
@@ -2,9 +2,10 @@ package cqlserver
 
 import (
 	"context"
-	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/client"
 	log "github.com/sirupsen/logrus"
+	"net"
+	"strconv"
 	"time"
 )
 
@@ -14,7 +15,7 @@ type Cluster struct {
 }
 
 func NewCqlServerCluster(listenAddr string, port int, username string, password string, start bool) (*Cluster, error) {
-	addr := fmt.Sprintf("%s:%d", listenAddr, port)
+	addr := net.JoinHostPort(listenAddr, strconv.Itoa(port))
 	var authCreds *client.AuthCredentials
 	if username != "" || password != "" {
 		authCreds = &client.AuthCredentials{
@@ -0,0 +1,124 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestCredentialsModes tests that the client credentials are forwarded to the clusters with the CLIENT credentials
+// mode and that the credentials of the proxy configuration are sent to the clusters with the CONFIGURED mode.
+func TestCredentialsModes(t *testing.T) {
+	originCreds := &client.AuthCredentials{Username: "origin_username", Password: "originPassword"}
+	targetCreds := &client.AuthCredentials{Username: "target_username", Password: "targetPassword"}
+
+	tests := []struct {
+		name                   string
+		originMode             string
+		targetMode             string
+		clientCreds            *client.AuthCredentials
+		success                bool
+		expectedOriginUsername string
+		expectedTargetUsername string
+	}{
+		{
+			name:                   "client credentials on origin",
+			originMode:             "CLIENT",
+			targetMode:             "CONFIGURED",
+			clientCreds:            originCreds,
+			success:                true,
+			expectedOriginUsername: originCreds.Username,
+			expectedTargetUsername: targetCreds.Username,
+		},
+		{
+			name:                   "client credentials on target",
+			originMode:             "CONFIGURED",
+			targetMode:             "AUTO",
+			clientCreds:            targetCreds,
+			success:                true,
+			expectedOriginUsername: originCreds.Username,
+			expectedTargetUsername: targetCreds.Username,
+		},
+		{
+			name:                   "client credentials on both clusters",
+			originMode:             "CLIENT",
+			targetMode:             "CLIENT",
+			clientCreds:            targetCreds,
+			success:                false,
+			expectedOriginUsername: targetCreds.Username,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.OriginUsername = originCreds.Username
+			conf.OriginPassword = originCreds.Password
+			conf.TargetUsername = targetCreds.Username
+			conf.TargetPassword = targetCreds.Password
+			conf.OriginCredentialsMode = tt.originMode
+			conf.TargetCredentialsMode = tt.targetMode
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originRequestHandler := NewFakeRequestHandler()
+			targetRequestHandler := NewFakeRequestHandler()
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				originRequestHandler.HandleRequest,
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				targetRequestHandler.HandleRequest,
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			proxy, err := setup.NewProxyInstanceWithConfig(conf)
+			require.Nil(t, err)
+			defer proxy.Shutdown()
+
+			testClient := client.NewCqlClient(
+				fmt.Sprintf("%s:%d", conf.ProxyListenAddress, conf.ProxyListenPort), tt.clientCreds)
+			cqlConn, err := testClient.Connect(context.Background())
+			require.Nil(t, err)
+			defer cqlConn.Close()
+
+			err = cqlConn.InitiateHandshake(primitive.ProtocolVersion4, 0)
+			if tt.success {
+				require.Nil(t, err, "handshake failed: %v", err)
+			} else {
+				require.NotNil(t, err, "expected failure in handshake")
+				require.Contains(t, err.Error(),
+					(&message.AuthenticationError{ErrorMessage: "invalid credentials"}).String())
+			}
+
+			// the first connection of each cluster is the control connection
+			require.Equal(t, tt.expectedOriginUsername, authResponseUsername(t, originRequestHandler.GetRequests()[1]))
+			if tt.expectedTargetUsername != "" {
+				require.Equal(t, tt.expectedTargetUsername, authResponseUsername(t, targetRequestHandler.GetRequests()[1]))
+			}
+		})
+	}
+}
+
+// authResponseUsername returns the username of the AUTH_RESPONSE request of a connection.
+func authResponseUsername(t *testing.T, requests []*frame.Frame) string {
+	for _, request := range requests {
+		if authResponse, ok := request.Body.Message.(*message.AuthResponse); ok {
+			creds := &client.AuthCredentials{}
+			require.Nil(t, creds.Unmarshal(authResponse.Token))
+			return creds.Username
+		}
+	}
+	require.Fail(t, "no AUTH_RESPONSE request was received")
+	return ""
+}
@@ -0,0 +1,121 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDestructiveStatementsConfirmation(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.DestructiveStatementsConfirmationEnabled = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originForwarded := int32(0)
+	targetForwarded := int32(0)
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newDestructiveStatementHandler(&originForwarded),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newDestructiveStatementHandler(&targetForwarded),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	adminHandler := admin.DestructiveStatementsHandler(testSetup.Proxy)
+	callAdminApi := func(method string, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		adminHandler.ServeHTTP(recorder, httptest.NewRequest(method, admin.DestructiveStatementsPath+path, nil))
+		return recorder
+	}
+	sendQuery := func(query string) message.Message {
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: query}))
+		require.Nil(t, err)
+		return response.Body.Message
+	}
+	requireForwarded := func(expected int32) {
+		require.Equal(t, expected, atomic.LoadInt32(&originForwarded))
+		require.Equal(t, expected, atomic.LoadInt32(&targetForwarded))
+	}
+
+	require.IsType(t, &message.Unauthorized{}, sendQuery("DROP TABLE ks.tb"))
+	requireForwarded(0)
+
+	recorder := callAdminApi(http.MethodPost, "/tokens")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var token admin.ConfirmationToken
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &token))
+
+	confirmedQuery := fmt.Sprintf("/* zdm-confirmation-token: %v */ DROP TABLE ks.tb", token.Token)
+	require.IsType(t, &message.VoidResult{}, sendQuery(confirmedQuery))
+	requireForwarded(1)
+	require.IsType(t, &message.Unauthorized{}, sendQuery(confirmedQuery))
+	requireForwarded(1)
+
+	require.Equal(t, http.StatusBadRequest, callAdminApi(http.MethodPost, "/unlock?duration=2h").Code)
+	recorder = callAdminApi(http.MethodPost, "/unlock?duration=10m")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var status zdmproxy.DestructiveStatementGuardStatus
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	require.True(t, status.Unlocked)
+
+	require.IsType(t, &message.VoidResult{}, sendQuery("TRUNCATE ks.tb"))
+	require.IsType(t, &message.VoidResult{}, sendQuery("DROP KEYSPACE ks"))
+	requireForwarded(3)
+
+	require.Equal(t, http.StatusOK, callAdminApi(http.MethodPost, "/lock").Code)
+	require.IsType(t, &message.Unauthorized{}, sendQuery("TRUNCATE ks.tb"))
+	requireForwarded(3)
+
+	recorder = callAdminApi(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	require.False(t, status.Unlocked)
+	require.Equal(t, http.StatusNotFound, callAdminApi(http.MethodGet, "/tokens").Code)
+}
+
+func TestDestructiveStatementsConfirmationDisabled(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, true, true, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	recorder := httptest.NewRecorder()
+	admin.DestructiveStatementsHandler(testSetup.Proxy).ServeHTTP(
+		recorder, httptest.NewRequest(http.MethodPost, admin.DestructiveStatementsPath+"/tokens", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	admin.DefaultDestructiveStatementsHandler().ServeHTTP(
+		recorder, httptest.NewRequest(http.MethodGet, admin.DestructiveStatementsPath, nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func newDestructiveStatementHandler(forwarded *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !(strings.Contains(query.Query, "DROP") || strings.Contains(query.Query, "TRUNCATE")) {
+			return nil
+		}
+		atomic.AddInt32(forwarded, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
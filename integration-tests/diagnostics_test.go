@@ -0,0 +1,70 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDiagnostics tests that the runtime stats, the dumps and the profiles are served on the admin API only when
+// ZDM_DIAGNOSTICS_ENABLED is set.
+func TestDiagnostics(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.DiagnosticsEnabled = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		admin.Handler(testSetup.Proxy).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	recorder := get(admin.DiagnosticsPath)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var stats zdmproxy.RuntimeStats
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	require.Greater(t, stats.Goroutines, 0)
+	require.Greater(t, stats.HeapInUseBytes, uint64(0))
+
+	recorder = get(admin.DiagnosticsPath + "/dump")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "Profile goroutine:")
+	require.Contains(t, recorder.Body.String(), "Profile heap:")
+
+	recorder = get(admin.PprofPath + "/")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "goroutine (")
+
+	recorder = get(admin.PprofPath + "/goroutine?debug=1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "goroutine profile: total")
+
+	recorder = get(admin.PprofPath + "/heap")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEmpty(t, recorder.Body.Bytes())
+
+	recorder = get(admin.PprofPath + "/profile?seconds=1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEmpty(t, recorder.Body.Bytes())
+
+	require.Equal(t, http.StatusNotFound, get(admin.PprofPath+"/unknown").Code)
+	require.Equal(t, http.StatusBadRequest, get(admin.PprofPath+"/profile?seconds=3600").Code)
+
+	testSetup.Proxy.Conf.DiagnosticsEnabled = false
+	require.Equal(t, http.StatusNotFound, get(admin.DiagnosticsPath).Code)
+	require.Equal(t, http.StatusNotFound, get(admin.PprofPath+"/heap").Code)
+
+	recorder = httptest.NewRecorder()
+	admin.Handler(nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, admin.DiagnosticsPath, nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
@@ -0,0 +1,275 @@
+package integration_tests
+
+import (
+	"context"
+	"errors"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEmbeddedProxy tests a proxy created with injected listener, metrics registry and lifecycle callbacks
+func TestEmbeddedProxy(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	refuseClients := &atomic.Value{}
+	refuseClients.Store(false)
+	connected := make(chan net.Addr, 10)
+	disconnected := make(chan net.Addr, 10)
+	shutdown := make(chan bool, 1)
+	registry := prometheus.NewRegistry()
+
+	proxy, err := zdmproxy.RunWithOptions(conf, context.Background(), &zdmproxy.ZdmProxyOptions{
+		Listeners:         []net.Listener{listener},
+		MetricsRegisterer: registry,
+		OnClientConnect: func(clientAddress net.Addr) error {
+			if refuseClients.Load().(bool) {
+				return errors.New("refused by test")
+			}
+			connected <- clientAddress
+			return nil
+		},
+		OnClientDisconnect: func(clientAddress net.Addr) {
+			disconnected <- clientAddress
+		},
+		OnShutdown: func() {
+			shutdown <- true
+		},
+	})
+	require.Nil(t, err)
+	require.Equal(t, listener.Addr().String(), proxy.GetListenAddr().String())
+
+	testClient, err := testclient.Connect(
+		context.Background(), listener.Addr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, nil)
+	require.Nil(t, err)
+	require.Equal(t, testClient.LocalAddr().String(), receiveAddr(t, connected).String())
+
+	response, _, err := testClient.SendMessage(context.Background(), primitive.ProtocolVersion4, &message.Query{
+		Query:   "SELECT * FROM system.local",
+		Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne},
+	})
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeResult, response.Header.OpCode, response.Body.Message)
+
+	metricFamilies, err := registry.Gather()
+	require.Nil(t, err)
+	foundProxyMetrics := false
+	for _, metricFamily := range metricFamilies {
+		if strings.HasPrefix(metricFamily.GetName(), "zdm_") {
+			foundProxyMetrics = true
+		}
+	}
+	require.True(t, foundProxyMetrics, "proxy metrics were not registered in the provided registry")
+
+	_ = testClient.Shutdown()
+	require.Equal(t, testClient.LocalAddr().String(), receiveAddr(t, disconnected).String())
+
+	refuseClients.Store(true)
+	refusedClient, err := testclient.NewTestClient(context.Background(), listener.Addr().String())
+	require.Nil(t, err)
+	defer refusedClient.Shutdown()
+	err = refusedClient.PerformDefaultHandshake(context.Background(), primitive.ProtocolVersion4, true)
+	require.NotNil(t, err)
+
+	proxy.Shutdown()
+	select {
+	case <-shutdown:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnShutdown was not called")
+	}
+}
+
+// TestMultipleEmbeddedProxiesWithIsolatedMetrics tests that multiple proxies can run in the same process
+// when each one has its own metrics registry
+func TestMultipleEmbeddedProxiesWithIsolatedMetrics(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	voidResultHandler := func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if _, ok := request.Body.Message.(*message.Query); ok {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+		return nil
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}), voidResultHandler}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}), voidResultHandler}
+
+	err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	var proxies []*zdmproxy.ZdmProxy
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		proxy, err := zdmproxy.RunWithOptions(conf, context.Background(), &zdmproxy.ZdmProxyOptions{
+			Listeners:         []net.Listener{listener},
+			MetricsRegisterer: prometheus.NewRegistry(),
+		})
+		require.Nil(t, err)
+		defer proxy.Shutdown()
+		proxies = append(proxies, proxy)
+	}
+
+	testClient, err := testclient.Connect(
+		context.Background(), proxies[0].GetListenAddr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, nil)
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	requestDurationCount := func(proxy *zdmproxy.ZdmProxy) string {
+		recorder := httptest.NewRecorder()
+		proxy.GetMetricHandler().GetHttpHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		for _, line := range strings.Split(recorder.Body.String(), "\n") {
+			if strings.HasPrefix(line, `zdm_proxy_request_duration_seconds_count{type="reads_origin"}`) {
+				return line
+			}
+		}
+		return ""
+	}
+
+	require.Equal(t, `zdm_proxy_request_duration_seconds_count{type="reads_origin"} 0`, requestDurationCount(proxies[0]))
+	require.Equal(t, `zdm_proxy_request_duration_seconds_count{type="reads_origin"} 0`, requestDurationCount(proxies[1]))
+
+	response, _, err := testClient.SendMessage(context.Background(), primitive.ProtocolVersion4, &message.Query{
+		Query:   "SELECT * FROM ks.tb",
+		Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne},
+	})
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeResult, response.Header.OpCode, response.Body.Message)
+
+	require.NotEqual(t, `zdm_proxy_request_duration_seconds_count{type="reads_origin"} 0`, requestDurationCount(proxies[0]))
+	require.Equal(t, `zdm_proxy_request_duration_seconds_count{type="reads_origin"} 0`, requestDurationCount(proxies[1]))
+}
+
+// TestEmbeddedProxyWithMultipleListeners tests that the proxy accepts client connections on all injected listeners
+// and keeps accepting connections on the other listeners if one of them is closed externally
+func TestEmbeddedProxyWithMultipleListeners(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	var listeners []net.Listener
+	var listenAddrs []net.Addr
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		listeners = append(listeners, listener)
+		listenAddrs = append(listenAddrs, listener.Addr())
+	}
+
+	proxy, err := zdmproxy.RunWithOptions(conf, context.Background(), &zdmproxy.ZdmProxyOptions{Listeners: listeners})
+	require.Nil(t, err)
+	defer proxy.Shutdown()
+	require.Equal(t, listenAddrs, proxy.GetListenAddrs())
+
+	for _, listenAddr := range listenAddrs {
+		testClient, err := testclient.Connect(
+			context.Background(), listenAddr.String(), primitive.ProtocolVersion4,
+			conf.TargetUsername, conf.TargetPassword, nil)
+		require.Nil(t, err, "could not connect to %v: %v", listenAddr, err)
+		_ = testClient.Shutdown()
+	}
+
+	_ = listeners[0].Close()
+	testClient, err := testclient.Connect(
+		context.Background(), listenAddrs[1].String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, nil)
+	require.Nil(t, err)
+	_ = testClient.Shutdown()
+}
+
+// TestEmbeddedProxySlowClientConnectCallback tests that a client connection whose OnClientConnect callback is blocked
+// doesn't prevent the proxy from accepting other client connections
+func TestEmbeddedProxySlowClientConnectCallback(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+	err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	blockedClient, err := net.Dial("tcp", listener.Addr().String())
+	require.Nil(t, err)
+	defer blockedClient.Close()
+
+	blocked := make(chan net.Addr, 1)
+	release := make(chan struct{})
+	proxy, err := zdmproxy.RunWithOptions(conf, context.Background(), &zdmproxy.ZdmProxyOptions{
+		Listeners: []net.Listener{listener},
+		OnClientConnect: func(clientAddress net.Addr) error {
+			if clientAddress.String() == blockedClient.LocalAddr().String() {
+				blocked <- clientAddress
+				<-release
+			}
+			return nil
+		},
+	})
+	require.Nil(t, err)
+	defer proxy.Shutdown()
+	defer close(release)
+	receiveAddr(t, blocked)
+
+	testClient, err := testclient.Connect(
+		context.Background(), listener.Addr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, nil)
+	require.Nil(t, err)
+	_ = testClient.Shutdown()
+}
+
+func receiveAddr(t *testing.T, addrChannel chan net.Addr) net.Addr {
+	select {
+	case addr := <-addrChannel:
+		return addr
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for lifecycle callback")
+		return nil
+	}
+}
@@ -22,7 +22,9 @@ var IsDse bool
 var RunCcmTests bool
 var RunMockTests bool
 var RunAllTlsTests bool
+var RunStressTests bool
 var Debug bool
+var CheckLeaks bool
 
 func InitGlobalVars() {
 	flags := map[string]interface{}{
@@ -51,10 +53,20 @@ func InitGlobalVars() {
 			getEnvironmentVariableOrDefault("RUN_ALL_TLS_TESTS", "false"),
 			"RUN_ALL_TLS_TESTS"),
 
+		"RUN_STRESS_TESTS": flag.String(
+			"RUN_STRESS_TESTS",
+			getEnvironmentVariableOrDefault("RUN_STRESS_TESTS", "false"),
+			"RUN_STRESS_TESTS"),
+
 		"DEBUG": flag.Bool(
 			"DEBUG",
 			getEnvironmentVariableBoolOrDefault("DEBUG", false),
 			"DEBUG"),
+
+		"CHECK_LEAKS": flag.Bool(
+			"CHECK_LEAKS",
+			getEnvironmentVariableBoolOrDefault("CHECK_LEAKS", true),
+			"CHECK_LEAKS"),
 	}
 
 	flag.Parse()
@@ -64,7 +76,9 @@ func InitGlobalVars() {
 	runCcmTests := *flags["RUN_CCMTESTS"].(*string)
 	runMockTests := *flags["RUN_MOCKTESTS"].(*string)
 	runAllTlsTests := *flags["RUN_ALL_TLS_TESTS"].(*string)
+	runStressTests := *flags["RUN_STRESS_TESTS"].(*string)
 	Debug = *flags["DEBUG"].(*bool)
+	CheckLeaks = *flags["CHECK_LEAKS"].(*bool)
 
 	if DseVersion != "" {
 		IsDse = true
@@ -85,6 +99,10 @@ func InitGlobalVars() {
 	if strings.ToLower(runAllTlsTests) == "true" {
 		RunAllTlsTests = true
 	}
+
+	if strings.ToLower(runStressTests) == "true" {
+		RunStressTests = true
+	}
 }
 
 func getEnvironmentVariableOrDefault(key string, defaultValue string) string {
@@ -6,9 +6,9 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/integration-tests/ccm"
-	"github.com/datastax/zdm-proxy/integration-tests/client"
 	"github.com/datastax/zdm-proxy/integration-tests/env"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -47,12 +47,12 @@ func TestSchemaEvents(t *testing.T) {
 			defer proxyInstance.Shutdown()
 
 			// test client that connects to the proxy
-			testClientForEvents, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+			testClientForEvents, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 			require.True(t, err == nil, "unable to connect to test client: %v", err)
 			defer testClientForEvents.Shutdown()
 
 			// test client that connects to the C* node directly
-			testClientForSchemaChange, err := client.NewTestClient(context.Background(), tt.endpointSchemaChange)
+			testClientForSchemaChange, err := testclient.NewTestClient(context.Background(), tt.endpointSchemaChange)
 			require.True(t, err == nil, "unable to connect to test client: %v", err)
 			defer testClientForSchemaChange.Shutdown()
 
@@ -111,7 +111,7 @@ func TestTopologyStatusEvents(t *testing.T) {
 		t.Skip("Test requires CCM, set RUN_CCMTESTS env variable to TRUE")
 	}
 
-	tempCcmSetup, err := setup.NewTemporaryCcmTestSetup(true, false)
+	tempCcmSetup, err := setup.NewTemporaryCcmTestSetup(t, true, false)
 	require.Nil(t, err)
 	defer tempCcmSetup.Cleanup()
 
@@ -143,7 +143,7 @@ func TestTopologyStatusEvents(t *testing.T) {
 			require.Nil(t, err)
 			defer proxyInstance.Shutdown()
 
-			testClientForEvents, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+			testClientForEvents, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 			require.True(t, err == nil, "unable to connect to test client: %v", err)
 			defer testClientForEvents.Shutdown()
 
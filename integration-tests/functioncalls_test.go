@@ -223,6 +223,50 @@ func TestNowFunctionReplacementSimpleStatement(t *testing.T) {
 	})
 }
 
+// TestFunctionReplacementSameValueOnBothClusters tests that the calls of the non-deterministic functions that are
+// replaced by the proxy are replaced with the same values in the QUERY and BATCH requests of both clusters.
+func TestFunctionReplacementSameValueOnBothClusters(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ReplaceCqlFunctions = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originRecorder := &writeRecorder{}
+	targetRecorder := &writeRecorder{}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		originRecorder.handler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		targetRecorder.handler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	insert := "INSERT INTO ks.tb (a, b, c, d, e) " +
+		"VALUES (uuid(), currentTimestamp(), currentDate(), currentTime(), toTimestamp(now()))"
+	for _, msg := range []message.Message{
+		&message.Query{Query: insert},
+		&message.Batch{Children: []*message.BatchChild{{QueryOrId: insert}}},
+	} {
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, msg))
+		require.Nil(t, err)
+		require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	}
+
+	originQueries := originRecorder.get()
+	require.Equal(t, 2, len(originQueries))
+	require.Equal(t, originQueries, targetRecorder.get())
+	for _, query := range originQueries {
+		require.Regexp(t, "^INSERT INTO ks.tb \\(a, b, c, d, e\\) "+
+			"VALUES \\([0-9a-f-]{36}, [0-9]+, '[0-9-]{10}', '[0-9:.]{12}', [0-9]+\\)$", query)
+	}
+}
+
 func TestNowFunctionReplacementPreparedStatement(t *testing.T) {
 
 	timeUuidStart, err := uuid.NewUUID()
@@ -1,9 +1,15 @@
 package integration_tests
 
 import (
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/writer"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
 )
 
 func createLogHooks(logLevels ...log.Level) *utils.ThreadsafeBuffer {
@@ -15,3 +21,19 @@ func createLogHooks(logLevels ...log.Level) *utils.ThreadsafeBuffer {
 	log.AddHook(hook)
 	return buffer
 }
+
+// getProxyMetricValues returns the values of the metrics of the proxy by name (including the labels).
+func getProxyMetricValues(t *testing.T, testSetup *setup.CqlServerTestSetup) map[string]string {
+	recorder := httptest.NewRecorder()
+	testSetup.Proxy.GetMetricHandler().GetHttpHandler().ServeHTTP(
+		recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	values := map[string]string{}
+	for _, line := range strings.Split(recorder.Body.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && !strings.HasPrefix(line, "#") {
+			values[fields[0]] = fields[1]
+		}
+	}
+	return values
+}
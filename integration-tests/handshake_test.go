@@ -0,0 +1,186 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandshakeFastPath(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyHandshakeFastPathEnabled = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originOptions := new(int32)
+	targetOptions := new(int32)
+	authOrder := newHandshakeAuthOrder()
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHandshakeObserverHandler(originOptions),
+		authOrder.primaryHandler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newHandshakeObserverHandler(targetOptions),
+		authOrder.secondaryHandler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	// the handshakes of the control connections are not checked
+	authOrder.enable()
+
+	var originOptionsFirstConn, targetOptionsFirstConn int32
+	for i := 0; i < 3; i++ {
+		testClient := client.NewCqlClient(
+			fmt.Sprintf("%s:%d", conf.ProxyListenAddress, conf.ProxyListenPort),
+			&client.AuthCredentials{Username: conf.TargetUsername, Password: conf.TargetPassword})
+		cqlConn, err := testClient.Connect(context.Background())
+		require.Nil(t, err, "client connection failed: %v", err)
+
+		response, err := cqlConn.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Options{}))
+		require.Nil(t, err)
+		require.Equal(t, primitive.OpCodeSupported, response.Header.OpCode)
+
+		// secondary handshake runs while the primary cluster is authenticating the client
+		err = cqlConn.InitiateHandshake(primitive.ProtocolVersion4, 0)
+		require.Nil(t, err, "handshake failed: %v", err)
+		require.Equal(t, int32(0), authOrder.getSequential())
+
+		query := &message.Query{
+			Query:   "SELECT * FROM system.local",
+			Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne},
+		}
+		response, err = cqlConn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, query))
+		require.Nil(t, err)
+		require.Equal(t, primitive.OpCodeResult, response.Header.OpCode, response.Body.Message)
+
+		_ = cqlConn.Close()
+
+		if i == 0 {
+			originOptionsFirstConn = atomic.LoadInt32(originOptions)
+			targetOptionsFirstConn = atomic.LoadInt32(targetOptions)
+		}
+	}
+
+	// only the OPTIONS request of the first client connection was forwarded to the clusters
+	require.Equal(t, originOptionsFirstConn, atomic.LoadInt32(originOptions))
+	require.Equal(t, targetOptionsFirstConn, atomic.LoadInt32(targetOptions))
+}
+
+func TestHandshakeFastPathPrimaryAuthFailure(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyHandshakeFastPathEnabled = true
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	// the primary cluster rejects the first AUTH_RESPONSE of the client connection
+	rejectAuth := int32(0)
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			if _, ok := request.Body.Message.(*message.AuthResponse); ok && atomic.CompareAndSwapInt32(&rejectAuth, 1, 0) {
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId,
+					&message.AuthenticationError{ErrorMessage: "invalid credentials"})
+			}
+			return nil
+		},
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	atomic.StoreInt32(&rejectAuth, 1)
+
+	testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	response, _, err := testClient.SendMessage(context.Background(), primitive.ProtocolVersion4, &message.Startup{})
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeAuthenticate, response.Header.OpCode)
+
+	creds := &client.AuthCredentials{Username: conf.TargetUsername, Password: conf.TargetPassword}
+	response, _, err = testClient.SendMessage(
+		context.Background(), primitive.ProtocolVersion4, &message.AuthResponse{Token: creds.Marshal()})
+	require.Nil(t, err)
+	require.IsType(t, &message.AuthenticationError{}, response.Body.Message)
+
+	// the secondary connection was already authenticated so the client has to open a new connection
+	response, _, err = testClient.SendMessage(
+		context.Background(), primitive.ProtocolVersion4, &message.AuthResponse{Token: creds.Marshal()})
+	require.Nil(t, err)
+	require.IsType(t, &message.AuthenticationError{}, response.Body.Message)
+
+	cqlConn, err := client.NewCqlClient(
+		fmt.Sprintf("%s:%d", conf.ProxyListenAddress, conf.ProxyListenPort), creds).Connect(context.Background())
+	require.Nil(t, err)
+	defer cqlConn.Close()
+	err = cqlConn.InitiateHandshake(primitive.ProtocolVersion4, 0)
+	require.Nil(t, err, "handshake failed: %v", err)
+}
+
+// newHandshakeObserverHandler counts OPTIONS requests, the requests are then handled by the next handlers.
+func newHandshakeObserverHandler(optionsCount *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if _, ok := request.Body.Message.(*message.Options); ok {
+			atomic.AddInt32(optionsCount, 1)
+		}
+		return nil
+	}
+}
+
+// handshakeAuthOrder checks that the secondary cluster receives the AUTH_RESPONSE of a client connection before the
+// primary cluster answers it, i.e. that the secondary handshake doesn't wait for the primary handshake to finish.
+type handshakeAuthOrder struct {
+	enabled       int32
+	secondaryAuth chan bool
+	sequential    int32 // AUTH_RESPONSE requests that the primary cluster answered before the secondary received one
+}
+
+func newHandshakeAuthOrder() *handshakeAuthOrder {
+	return &handshakeAuthOrder{secondaryAuth: make(chan bool, 10)}
+}
+
+func (recv *handshakeAuthOrder) enable() {
+	atomic.StoreInt32(&recv.enabled, 1)
+}
+
+func (recv *handshakeAuthOrder) getSequential() int32 {
+	return atomic.LoadInt32(&recv.sequential)
+}
+
+// primaryHandler holds the AUTH_RESPONSE requests until the secondary cluster receives one.
+func (recv *handshakeAuthOrder) primaryHandler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if _, ok := request.Body.Message.(*message.AuthResponse); ok && atomic.LoadInt32(&recv.enabled) == 1 {
+			select {
+			case <-recv.secondaryAuth:
+			case <-time.After(5 * time.Second):
+				atomic.AddInt32(&recv.sequential, 1)
+			}
+		}
+		return nil
+	}
+}
+
+func (recv *handshakeAuthOrder) secondaryHandler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		if _, ok := request.Body.Message.(*message.AuthResponse); ok && atomic.LoadInt32(&recv.enabled) == 1 {
+			recv.secondaryAuth <- true
+		}
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestInterceptedResponseCache tests that the responses of intercepted system queries are reused by other client
+// connections
+func TestInterceptedResponseCache(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.InterceptedQueriesCacheTtlMs = 60000
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	proxy, err := zdmproxy.RunWithOptions(conf, context.Background(), &zdmproxy.ZdmProxyOptions{
+		Listeners:         []net.Listener{listener},
+		MetricsRegisterer: prometheus.NewRegistry(),
+	})
+	require.Nil(t, err)
+	defer proxy.Shutdown()
+
+	getMetricLine := func(name string) string {
+		recorder := httptest.NewRecorder()
+		proxy.GetMetricHandler().GetHttpHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		for _, line := range strings.Split(recorder.Body.String(), "\n") {
+			if strings.HasPrefix(line, name+" ") {
+				return line
+			}
+		}
+		return ""
+	}
+	queryLocal := func() *message.RowsResult {
+		testClient, err := testclient.Connect(
+			context.Background(), proxy.GetListenAddr().String(), primitive.ProtocolVersion4,
+			conf.TargetUsername, conf.TargetPassword, nil)
+		require.Nil(t, err)
+		defer testClient.Shutdown()
+		response, _, err := testClient.SendMessage(
+			context.Background(), primitive.ProtocolVersion4, &message.Query{Query: "SELECT * FROM system.local"})
+		require.Nil(t, err)
+		require.IsType(t, &message.RowsResult{}, response.Body.Message)
+		return response.Body.Message.(*message.RowsResult)
+	}
+
+	first := queryLocal()
+	second := queryLocal()
+	require.Equal(t, first, second)
+	require.Equal(t, "zdm_proxy_intercepted_response_cache_misses_total 1",
+		getMetricLine("zdm_proxy_intercepted_response_cache_misses_total"))
+	require.Equal(t, "zdm_proxy_intercepted_response_cache_hits_total 1",
+		getMetricLine("zdm_proxy_intercepted_response_cache_hits_total"))
+}
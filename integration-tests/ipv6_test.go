@@ -0,0 +1,68 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datacodec"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestIpv6Addresses tests that the proxy listens on an IPv6 address, connects to clusters whose contact points are
+// IPv6 literals and returns the IPv6 listen address in the virtualized system.local table.
+func TestIpv6Addresses(t *testing.T) {
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("Skipping IPv6 test, IPv6 loopback is not available: %v", err)
+	}
+	_ = l.Close()
+
+	conf := setup.NewTestConfig("::1", "::1")
+	conf.TargetPort = 9043
+	conf.ProxyListenAddress = "::1"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	proxyAddr := testSetup.Proxy.GetListenAddr().String()
+	require.Equal(t, fmt.Sprintf("[::1]:%d", conf.ProxyListenPort), proxyAddr)
+
+	testClient, err := testclient.Connect(
+		context.Background(), proxyAddr, primitive.ProtocolVersion4, conf.TargetUsername, conf.TargetPassword, nil)
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	response, _, err := testClient.SendMessage(
+		context.Background(), primitive.ProtocolVersion4, &message.Query{Query: "SELECT rpc_address FROM system.local"})
+	require.Nil(t, err)
+	rows, ok := response.Body.Message.(*message.RowsResult)
+	require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+	require.Equal(t, 1, len(rows.Data))
+	var rpcAddress net.IP
+	_, err = datacodec.Inet.Decode(rows.Data[0][0], &rpcAddress, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+	require.Equal(t, "::1", rpcAddress.String())
+
+	recorder := httptest.NewRecorder()
+	testSetup.Proxy.GetMetricHandler().GetHttpHandler().ServeHTTP(
+		recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "node=\"[::1]:9042\"")
+	require.Contains(t, recorder.Body.String(), "node=\"[::1]:9043\"")
+}
@@ -0,0 +1,92 @@
+package integration_tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestLogCorrelation tests that the JSON logs of the client handler and of the cluster connectors of a client
+// connection have the same connection_id and that the logs of a request have its request_id.
+func TestLogCorrelation(t *testing.T) {
+	logs := &lockedBuffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFormatter(log.StandardLogger().Formatter)
+	log.SetFormatter(zdmproxy.NewLogFormatter(common.LogFormatJson))
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.DebugLevel)
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.StatementFilterRules = "TRUNCATE REJECT"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "TRUNCATE ks.tb"}))
+	require.Nil(t, err)
+	require.IsType(t, &message.Invalid{}, response.Body.Message)
+
+	var rejectedEntry map[string]interface{}
+	var connectorEntries []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(logs.String()))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &entry), scanner.Text())
+		require.Contains(t, entry, "time")
+		require.Contains(t, entry, "level")
+		msg := fmt.Sprintf("%v", entry["msg"])
+		if strings.HasPrefix(msg, "Rejecting request with stream id") {
+			rejectedEntry = entry
+		} else if strings.Contains(msg, "Listening to replies sent by node") {
+			connectorEntries = append(connectorEntries, entry)
+		}
+	}
+	require.NotNil(t, rejectedEntry)
+	connectionId := rejectedEntry["connection_id"]
+	require.NotNil(t, connectionId)
+	require.Equal(t, float64(response.Header.StreamId), rejectedEntry["stream_id"])
+	require.Equal(t, fmt.Sprintf("%v-%v", connectionId, response.Header.StreamId), rejectedEntry["request_id"])
+
+	var connectionConnectors []string
+	for _, entry := range connectorEntries {
+		if entry["connection_id"] == connectionId {
+			connectionConnectors = append(connectionConnectors, fmt.Sprintf("%v", entry["msg"]))
+		}
+	}
+	require.Equal(t, 2, len(connectionConnectors), connectionConnectors)
+}
+
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (recv *lockedBuffer) Write(p []byte) (int, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.buf.Write(p)
+}
+
+func (recv *lockedBuffer) String() string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.buf.String()
+}
@@ -0,0 +1,108 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestLwtPolicy tests that the lightweight transactions are only forwarded to the primary cluster when ZDM_LWT_POLICY
+// is PRIMARY_ONLY and that the response of the primary cluster is always returned when it is DUAL_WRITE.
+func TestLwtPolicy(t *testing.T) {
+
+	type test struct {
+		name                string
+		lwtPolicy           string
+		primaryCluster      string
+		query               string
+		targetFails         bool
+		expectedOriginCount int32
+		expectedTargetCount int32
+		expectedResponse    message.Message
+		expectedLwtRequests string
+	}
+
+	lwtQuery := "INSERT INTO ks.tb (a) VALUES (1) IF NOT EXISTS"
+	tests := []test{
+		{"dual write", config.LwtPolicyDualWrite, config.PrimaryClusterOrigin, lwtQuery,
+			false, 1, 1, &message.RowsResult{}, "1"},
+		{"dual write with secondary failure", config.LwtPolicyDualWrite, config.PrimaryClusterOrigin, lwtQuery,
+			true, 1, 1, &message.RowsResult{}, "1"},
+		{"dual write with primary failure", config.LwtPolicyDualWrite, config.PrimaryClusterTarget, lwtQuery,
+			true, 1, 1, &message.Overloaded{}, "1"},
+		{"primary only on origin", config.LwtPolicyPrimaryOnly, config.PrimaryClusterOrigin, lwtQuery,
+			false, 1, 0, &message.RowsResult{}, "1"},
+		{"primary only on target", config.LwtPolicyPrimaryOnly, config.PrimaryClusterTarget, lwtQuery,
+			false, 0, 1, &message.RowsResult{}, "1"},
+		{"primary only with conditional update in batch", config.LwtPolicyPrimaryOnly, config.PrimaryClusterOrigin,
+			"BEGIN BATCH UPDATE ks.tb SET b = 1 WHERE a = 1 IF b = 0; APPLY BATCH", false, 1, 0, &message.RowsResult{}, "1"},
+		{"regular write with secondary failure", config.LwtPolicyPrimaryOnly, config.PrimaryClusterOrigin,
+			"INSERT INTO ks.tb (a) VALUES (1)", true, 1, 1, &message.Overloaded{}, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.LwtPolicy = tt.lwtPolicy
+			conf.PrimaryCluster = tt.primaryCluster
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originQueries := int32(0)
+			targetQueries := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newLwtHandler(&originQueries, false),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newLwtHandler(&targetQueries, tt.targetFails),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+			require.Equal(t, tt.expectedOriginCount, atomic.LoadInt32(&originQueries))
+			require.Equal(t, tt.expectedTargetCount, atomic.LoadInt32(&targetQueries))
+			require.Equal(t, tt.expectedLwtRequests, getProxyMetricValues(t, testSetup)["zdm_proxy_lwt_requests_total"])
+		})
+	}
+}
+
+func newLwtHandler(count *int32, fail bool) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.Contains(query.Query, " ks.tb ") {
+			return nil
+		}
+		atomic.AddInt32(count, 1)
+		if fail {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Overloaded{ErrorMessage: "overloaded"})
+		}
+		if !strings.Contains(query.Query, " IF ") {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "tb", Name: "[applied]", Index: 0, Type: datatype.Boolean},
+				},
+			},
+			Data: message.RowSet{{[]byte{1}}},
+		})
+	}
+}
@@ -31,16 +31,34 @@ var nodeMetrics = []metrics.Metric{
 	metrics.OriginWriteTimeouts,
 	metrics.OriginReadTimeouts,
 	metrics.OriginUnpreparedErrors,
+	metrics.OriginSyntaxErrors,
+	metrics.OriginAuthErrors,
 	metrics.OriginOtherErrors,
 
 	metrics.TargetClientTimeouts,
 	metrics.TargetWriteTimeouts,
 	metrics.TargetReadTimeouts,
 	metrics.TargetUnpreparedErrors,
+	metrics.TargetSyntaxErrors,
+	metrics.TargetAuthErrors,
 	metrics.TargetOtherErrors,
 
 	metrics.OpenOriginConnections,
 	metrics.OpenTargetConnections,
+
+	metrics.InFlightRequestsOrigin,
+	metrics.InFlightRequestsTarget,
+
+	metrics.PooledStreamIdsInUseOrigin,
+	metrics.PooledStreamIdsInUseTarget,
+	metrics.PooledStreamIdsCapacityOrigin,
+	metrics.PooledStreamIdsCapacityTarget,
+	metrics.PooledStreamIdsExhaustedOrigin,
+	metrics.PooledStreamIdsExhaustedTarget,
+	metrics.PooledHeartbeatsSentOrigin,
+	metrics.PooledHeartbeatsSentTarget,
+	metrics.PooledHeartbeatsFailedOrigin,
+	metrics.PooledHeartbeatsFailedTarget,
 }
 
 var proxyMetrics = []metrics.Metric{
@@ -57,11 +75,72 @@ var proxyMetrics = []metrics.Metric{
 	metrics.ProxyReadsOriginDuration,
 	metrics.ProxyWritesDuration,
 
+	metrics.StatementDurationReadsOrigin,
+	metrics.StatementDurationReadsTarget,
+	metrics.StatementDurationReadsBoth,
+	metrics.StatementDurationWritesOrigin,
+	metrics.StatementDurationWritesTarget,
+	metrics.StatementDurationWritesBoth,
+	metrics.StatementDurationAuthOrigin,
+	metrics.StatementDurationAuthTarget,
+	metrics.StatementDurationAuthBoth,
+	metrics.StatementDurationControlOrigin,
+	metrics.StatementDurationControlTarget,
+	metrics.StatementDurationControlBoth,
+
 	metrics.InFlightReadsTarget,
 	metrics.InFlightReadsOrigin,
 	metrics.InFlightWrites,
 
 	metrics.OpenClientConnections,
+
+	metrics.QueuedRequestsOrigin,
+	metrics.QueuedRequestsTarget,
+	metrics.RequestQueueDurationOrigin,
+	metrics.RequestQueueDurationTarget,
+	metrics.RejectedQueuedRequestsOrigin,
+	metrics.RejectedQueuedRequestsTarget,
+	metrics.RateLimitedRequestsOrigin,
+	metrics.RateLimitedRequestsTarget,
+	metrics.RetriedRequestsOrigin,
+	metrics.RetriedRequestsTarget,
+	metrics.CircuitBreakerStateOrigin,
+	metrics.CircuitBreakerStateTarget,
+	metrics.CircuitBreakerRejectedRequestsOrigin,
+	metrics.CircuitBreakerRejectedRequestsTarget,
+	metrics.LatencyBudgetBreachesOrigin,
+	metrics.LatencyBudgetBreachesTarget,
+
+	metrics.TargetFilteredWrites,
+	metrics.TargetUnsampledWrites,
+	metrics.LwtRequests,
+	metrics.CounterWriteRequests,
+	metrics.CrossClusterPagingStates,
+	metrics.SecondaryWriteFailures,
+	metrics.WriteJournalQueued,
+	metrics.WriteJournalReplayed,
+	metrics.WriteJournalDropped,
+	metrics.WriteJournalPendingEntries,
+	metrics.SlowQueries,
+	metrics.AsyncWritesRetried,
+	metrics.AsyncWritesFailed,
+	metrics.ReadComparisons,
+	metrics.ReadComparisonMismatchesRowCount,
+	metrics.ReadComparisonMismatchesChecksum,
+	metrics.ReadComparisonMismatchesError,
+	metrics.WriteComparisons,
+	metrics.WriteComparisonMismatchesResponse,
+	metrics.WriteComparisonMismatchesApplied,
+	metrics.TargetIncompatibleSchemaChanges,
+	metrics.DestructiveStatementsRejected,
+	metrics.ClientLimitedRequests,
+	metrics.InFlightRequests,
+	metrics.BackpressureActivations,
+	metrics.ClientIdleConnectionsClosed,
+	metrics.InterceptedResponseCacheHits,
+	metrics.InterceptedResponseCacheMisses,
+
+	metrics.FleetLeader,
 }
 
 var allMetrics = append(proxyMetrics, nodeMetrics...)
@@ -260,6 +339,7 @@ func checkMetrics(
 	asyncEnabled := readMode == config.ReadModeDualAsyncOnSecondary
 	prefix := "zdm"
 	require.Contains(t, lines, fmt.Sprintf("%v %v", getPrometheusName(prefix, metrics.OpenClientConnections), openClientConns))
+	require.Contains(t, lines, fmt.Sprintf("%v 1", getPrometheusName(prefix, metrics.FleetLeader)))
 
 	require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusName(prefix, metrics.FailedWritesOnBoth)))
 	require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusName(prefix, metrics.FailedWritesOnOrigin)))
@@ -312,6 +392,8 @@ func checkMetrics(
 
 		require.Contains(t, lines, fmt.Sprintf("%v{node=\"%v\"} %v", getPrometheusName(prefix, metrics.OpenOriginConnections), originHost, openOriginConns))
 		require.Contains(t, lines, fmt.Sprintf("%v{node=\"%v\"} %v", getPrometheusName(prefix, metrics.OpenTargetConnections), targetHost, openTargetConns))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.InFlightRequestsOrigin, originHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.InFlightRequestsTarget, targetHost)))
 
 		if asyncEnabled {
 			require.Contains(t, lines, fmt.Sprintf("%v{node=\"%v\"} %v", getPrometheusName(prefix, metrics.OpenAsyncConnections), asyncHost, openAsyncConns))
@@ -324,6 +406,8 @@ func checkMetrics(
 			require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncWriteFailures, asyncHost)))
 			require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncOverloadedErrors, asyncHost)))
 			require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncUnpreparedErrors, asyncHost)))
+			require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncSyntaxErrors, asyncHost)))
+			require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.AsyncAuthErrors, asyncHost)))
 		} else {
 			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.OpenAsyncConnections)))
 			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncReadTimeouts)))
@@ -335,6 +419,8 @@ func checkMetrics(
 			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncWriteFailures)))
 			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncOverloadedErrors)))
 			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncUnpreparedErrors)))
+			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncSyntaxErrors)))
+			require.NotContains(t, lines, fmt.Sprintf("%v", getPrometheusName(prefix, metrics.AsyncAuthErrors)))
 		}
 
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginReadTimeouts, originHost)))
@@ -345,6 +431,8 @@ func checkMetrics(
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginReadFailures, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginWriteFailures, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginOverloadedErrors, originHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginSyntaxErrors, originHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginAuthErrors, originHost)))
 
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetReadTimeouts, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetWriteTimeouts, targetHost)))
@@ -354,6 +442,8 @@ func checkMetrics(
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetReadFailures, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetWriteFailures, targetHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetOverloadedErrors, targetHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetSyntaxErrors, targetHost)))
+		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetAuthErrors, targetHost)))
 
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.OriginUnpreparedErrors, originHost)))
 		require.Contains(t, lines, fmt.Sprintf("%v 0", getPrometheusNameWithNodeLabel(prefix, metrics.TargetUnpreparedErrors, targetHost)))
@@ -0,0 +1,109 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMigrationProgress tests that the migration progress estimation is exposed by the metrics and the admin API
+// when ZDM_MIGRATION_PROGRESS_ENABLED is set.
+func TestMigrationProgress(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.MigrationProgressEnabled = true
+	conf.MigrationProgressSampleIntervalMs = 100
+	conf.MigrationProgressWindowMs = 60000
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newTableMetricsHandler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newWriteTimeoutHandler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	// the write fails on TARGET
+	queries := []string{
+		"SELECT * FROM ks.tb",
+		"SELECT * FROM ks.tb WHERE k = 1",
+		"INSERT INTO ks.tb (k, v) VALUES (1, 'a')",
+	}
+	for _, query := range queries {
+		_, err = testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: query}))
+		require.Nil(t, err)
+	}
+
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		recorder := httptest.NewRecorder()
+		admin.Handler(testSetup.Proxy).ServeHTTP(
+			recorder, httptest.NewRequest(http.MethodGet, admin.MigrationProgressPath, nil))
+		if recorder.Code != http.StatusOK {
+			return fmt.Errorf("unexpected status code %v: %v", recorder.Code, recorder.Body.String()), true
+		}
+		var status zdmproxy.MigrationProgressStatus
+		err = json.Unmarshal(recorder.Body.Bytes(), &status)
+		if err != nil {
+			return err, true
+		}
+		if status.OriginReads != 2 || status.TargetReads != 0 || status.OriginReadsRatio != 1 ||
+			status.DualWrites != 1 || status.DualWriteFailures != 1 || status.DualWriteFailuresRatio != 1 {
+			return fmt.Errorf("unexpected migration progress %+v", status), false
+		}
+		return nil, false
+	}, 50, 100*time.Millisecond)
+
+	values := getProxyMetricValues(t, testSetup)
+	require.Equal(t, "1", values["zdm_proxy_migration_origin_reads_ratio"])
+	require.Equal(t, "1", values["zdm_proxy_migration_dual_write_failures_ratio"])
+	require.Equal(t, "0", values["zdm_proxy_migration_read_mismatches_ratio"])
+	require.Equal(t, "0", values["zdm_proxy_migration_replay_queue_depth"])
+}
+
+// TestMigrationProgressDisabled tests that the migration progress estimation is not served when
+// ZDM_MIGRATION_PROGRESS_ENABLED is false.
+func TestMigrationProgressDisabled(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	admin.Handler(testSetup.Proxy).ServeHTTP(
+		recorder, httptest.NewRequest(http.MethodGet, admin.MigrationProgressPath, nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.NotContains(t, getProxyMetricValues(t, testSetup), "zdm_proxy_migration_origin_reads_ratio")
+}
+
+func newWriteTimeoutHandler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.WriteTimeout{
+			ErrorMessage: "write timeout", Consistency: primitive.ConsistencyLevelOne, WriteType: primitive.WriteTypeSimple})
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
-	"github.com/datastax/zdm-proxy/integration-tests/client"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/simulacron"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
 	"github.com/stretchr/testify/require"
 	"strings"
 	"testing"
@@ -18,7 +18,7 @@ func TestAtLeastOneClusterReturnsNoResponse(t *testing.T) {
 	require.Nil(t, err)
 	defer simulacronSetup.Cleanup()
 
-	testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+	testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 	require.True(t, err == nil, "testClient setup failed: %s", err)
 
 	defer testClient.Shutdown()
@@ -0,0 +1,149 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// TestPagingStatePolicy tests the next page of a paged read when the reads are forwarded to TARGET (read_routing
+// setting of zdm_admin.settings) after the first page was returned by ORIGIN
+func TestPagingStatePolicy(t *testing.T) {
+	type test struct {
+		name                string
+		policy              string
+		expectedSecondPage  string
+		expectedError       string
+		expectedPagingState []byte
+	}
+	tests := []test{
+		{
+			name:                "none",
+			policy:              config.PagingStatePolicyNone,
+			expectedError:       "Invalid paging state",
+			expectedPagingState: []byte("origin-paging-state"),
+		},
+		{
+			name:               "originating cluster",
+			policy:             config.PagingStatePolicyOriginatingCluster,
+			expectedSecondPage: "origin-2",
+		},
+		{
+			name:   "reject",
+			policy: config.PagingStatePolicyReject,
+			expectedError: "Paging state rejected by ZDM proxy because it was returned by ORIGIN but the read is now " +
+				"forwarded to another cluster (ZDM_PAGING_STATE_POLICY is REJECT), restart the query without a paging state",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.AdminKeyspaceRoles = conf.TargetUsername
+			conf.PagingStatePolicy = tt.policy
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newPagedReadHandler("origin"),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newPagedReadHandler("target"),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			testClient, err := testclient.Connect(
+				context.Background(), testSetup.Proxy.GetListenAddr().String(), primitive.ProtocolVersion4,
+				conf.TargetUsername, conf.TargetPassword, nil)
+			require.Nil(t, err)
+			defer testClient.Shutdown()
+
+			send := func(msg message.Message) message.Message {
+				response, _, err := testClient.SendMessage(context.Background(), primitive.ProtocolVersion4, msg)
+				require.Nil(t, err)
+				return response.Body.Message
+			}
+			selectPage := func(pagingState []byte) message.Message {
+				return send(&message.Query{
+					Query:   "SELECT * FROM ks.paged",
+					Options: &message.QueryOptions{PageSize: 1, PagingState: pagingState},
+				})
+			}
+
+			firstPage, ok := selectPage(nil).(*message.RowsResult)
+			require.True(t, ok)
+			require.Equal(t, []byte("origin-1"), firstPage.Data[0][0])
+			pagingState := firstPage.Metadata.PagingState
+			if tt.expectedPagingState != nil {
+				require.Equal(t, tt.expectedPagingState, pagingState)
+			} else {
+				require.NotEqual(t, []byte("origin-paging-state"), pagingState)
+			}
+
+			require.IsType(t, &message.VoidResult{}, send(&message.Query{
+				Query: "UPDATE zdm_admin.settings SET read_routing = 'target'"}))
+
+			secondPage := selectPage(pagingState)
+			if tt.expectedError != "" {
+				require.IsType(t, &message.Invalid{}, secondPage)
+				require.Equal(t, tt.expectedError, secondPage.(*message.Invalid).ErrorMessage)
+			} else {
+				require.IsType(t, &message.RowsResult{}, secondPage)
+				require.Equal(t, []byte(tt.expectedSecondPage), secondPage.(*message.RowsResult).Data[0][0])
+				require.Nil(t, secondPage.(*message.RowsResult).Metadata.PagingState)
+			}
+
+			// the new queries are forwarded to TARGET
+			firstPage, ok = selectPage(nil).(*message.RowsResult)
+			require.True(t, ok)
+			require.Equal(t, []byte("target-1"), firstPage.Data[0][0])
+		})
+	}
+}
+
+// newPagedReadHandler returns the first page of "SELECT * FROM ks.paged" with the "<cluster>-paging-state" paging
+// state and the second page if the request has this paging state, the paging states of the other cluster are
+// rejected.
+func newPagedReadHandler(cluster string) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.Contains(query.Query, "ks.paged") {
+			return nil
+		}
+		var pagingState []byte
+		if query.Options != nil {
+			pagingState = query.Options.PagingState
+		}
+		page, nextPagingState := "1", []byte(cluster+"-paging-state")
+		if pagingState != nil {
+			if string(pagingState) != cluster+"-paging-state" {
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Invalid{
+					ErrorMessage: "Invalid paging state"})
+			}
+			page, nextPagingState = "2", nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "paged", Name: "v", Type: datatype.Varchar}},
+				PagingState: nextPagingState,
+			},
+			Data: message.RowSet{{[]byte(cluster + "-" + page)}},
+		})
+	}
+}
@@ -9,11 +9,11 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
-	"github.com/datastax/zdm-proxy/integration-tests/client"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/simulacron"
 	"github.com/datastax/zdm-proxy/integration-tests/utils"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
 	"github.com/rs/zerolog"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -28,7 +28,7 @@ func TestPreparedIdProxyCacheMiss(t *testing.T) {
 	require.Nil(t, err)
 	defer simulacronSetup.Cleanup()
 
-	testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+	testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 	require.True(t, err == nil, "testClient setup failed: %s", err)
 
 	defer testClient.Shutdown()
@@ -69,7 +69,7 @@ func TestPreparedIdPreparationMismatch(t *testing.T) {
 	require.Nil(t, err)
 	defer simulacronSetup.Cleanup()
 
-	testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+	testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 	require.True(t, err == nil, "testClient setup failed: %s", err)
 
 	defer testClient.Shutdown()
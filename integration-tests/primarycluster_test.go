@@ -0,0 +1,93 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// TestPrimaryClusterDualResponses tests that the response of the primary cluster is returned to the client for
+// the requests that are forwarded to both clusters, both when the two clusters succeed and when they both fail.
+func TestPrimaryClusterDualResponses(t *testing.T) {
+
+	type test struct {
+		name             string
+		primaryCluster   string
+		fail             bool
+		expectedResponse message.Message
+	}
+
+	tests := []test{
+		{"origin primary and both succeed", config.PrimaryClusterOrigin, false, &message.VoidResult{}},
+		{"target primary and both succeed", config.PrimaryClusterTarget, false, &message.RowsResult{}},
+		{"origin primary and both fail", config.PrimaryClusterOrigin, true, &message.WriteTimeout{}},
+		{"target primary and both fail", config.PrimaryClusterTarget, true, &message.Overloaded{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.PrimaryCluster = tt.primaryCluster
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originResponse := message.Message(&message.VoidResult{})
+			targetResponse := message.Message(&message.RowsResult{
+				Metadata: &message.RowsMetadata{
+					ColumnCount: 1,
+					Columns: []*message.ColumnMetadata{
+						{Keyspace: "ks", Table: "tbl", Name: "[applied]", Index: 0, Type: datatype.Boolean},
+					},
+				},
+				Data: message.RowSet{{[]byte{1}}},
+			})
+			if tt.fail {
+				originResponse = &message.WriteTimeout{
+					ErrorMessage: "write timeout",
+					Consistency:  primitive.ConsistencyLevelLocalQuorum,
+					Received:     1,
+					BlockFor:     2,
+					WriteType:    primitive.WriteTypeSimple,
+				}
+				targetResponse = &message.Overloaded{ErrorMessage: "overloaded"}
+			}
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newPrimaryClusterWriteHandler(originResponse),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newPrimaryClusterWriteHandler(targetResponse),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{
+					Query: "INSERT INTO ks.tbl (a) VALUES (1) IF NOT EXISTS",
+				}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+		})
+	}
+}
+
+func newPrimaryClusterWriteHandler(msg message.Message) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, msg)
+	}
+}
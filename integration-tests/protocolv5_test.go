@@ -0,0 +1,117 @@
+package integration_tests
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	protocolV5InsertQuery = "INSERT INTO ks.tb (k, v) VALUES (0, ?)"
+	protocolV5SelectQuery = "SELECT v FROM ks.tb WHERE k = 0"
+)
+
+// TestProtocolV5 tests that a client can negotiate protocol v5 through the proxy and that the requests and responses
+// are forwarded with the modern framing layout (segments) on the client connection and on the cluster connections.
+func TestProtocolV5(t *testing.T) {
+	tests := []struct {
+		name     string
+		poolSize int
+	}{
+		{"dedicated cluster connections", 0},
+		{"pooled cluster connections", 2},
+	}
+
+	// the test client and server can't split a frame across several segments so the frames must fit in a segment
+	largeValue := bytes.Repeat([]byte{0xCA, 0xFE}, 30000)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.ProxyClusterConnectionPoolSize = tt.poolSize
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originInserted := int32(0)
+			targetInserted := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newProtocolV5Handler(largeValue, &originInserted),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newProtocolV5Handler(largeValue, &targetInserted),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion5)
+			require.Nil(t, err)
+
+			sendRequest := func(msg message.Message) *frame.Frame {
+				response, err := testSetup.Client.CqlConnection.SendAndReceive(
+					frame.NewFrame(primitive.ProtocolVersion5, client.ManagedStreamId, msg))
+				require.Nil(t, err)
+				require.Equal(t, primitive.ProtocolVersion5, response.Header.Version)
+				return response
+			}
+
+			// intercepted by the proxy
+			response := sendRequest(&message.Query{Query: "SELECT * FROM system.local"})
+			rows, ok := response.Body.Message.(*message.RowsResult)
+			require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+			require.Equal(t, 1, len(rows.Data))
+
+			for i := 0; i < 3; i++ {
+				response = sendRequest(&message.Query{
+					Query:   protocolV5InsertQuery,
+					Options: &message.QueryOptions{PositionalValues: []*primitive.Value{primitive.NewValue(largeValue)}},
+				})
+				require.IsType(t, &message.VoidResult{}, response.Body.Message)
+
+				response = sendRequest(&message.Query{Query: protocolV5SelectQuery})
+				rows, ok = response.Body.Message.(*message.RowsResult)
+				require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+				require.Equal(t, 1, len(rows.Data))
+				require.Equal(t, largeValue, []byte(rows.Data[0][0]))
+			}
+
+			require.Equal(t, int32(3), atomic.LoadInt32(&originInserted))
+			require.Equal(t, int32(3), atomic.LoadInt32(&targetInserted))
+		})
+	}
+}
+
+func newProtocolV5Handler(largeValue []byte, inserted *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok {
+			return nil
+		}
+		switch query.Query {
+		case protocolV5InsertQuery:
+			if query.Options != nil && len(query.Options.PositionalValues) == 1 &&
+				bytes.Equal(largeValue, query.Options.PositionalValues[0].Contents) {
+				atomic.AddInt32(inserted, 1)
+			}
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		case protocolV5SelectQuery:
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+				Metadata: &message.RowsMetadata{
+					ColumnCount: 1,
+					Columns: []*message.ColumnMetadata{
+						{Keyspace: "ks", Table: "tb", Name: "v", Index: 0, Type: datatype.Blob},
+					},
+				},
+				Data: message.RowSet{{largeValue}},
+			})
+		}
+		return nil
+	}
+}
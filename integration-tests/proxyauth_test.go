@@ -0,0 +1,94 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestProxyAuthentication tests that the clients are authenticated with the proxy credentials and that the proxy
+// authenticates to both clusters with the configured credentials once the client credentials are valid.
+func TestProxyAuthentication(t *testing.T) {
+	originCreds := &client.AuthCredentials{Username: "origin_username", Password: "originPassword"}
+	targetCreds := &client.AuthCredentials{Username: "target_username", Password: "targetPassword"}
+
+	tests := []struct {
+		name        string
+		clientCreds *client.AuthCredentials
+		success     bool
+	}{
+		{"proxy credentials", &client.AuthCredentials{Username: "app", Password: "appPassword"}, true},
+		{"other proxy credentials", &client.AuthCredentials{Username: "app2", Password: "app:Password2"}, true},
+		{"invalid password", &client.AuthCredentials{Username: "app", Password: "originPassword"}, false},
+		{"cluster credentials", originCreds, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.OriginUsername = originCreds.Username
+			conf.OriginPassword = originCreds.Password
+			conf.TargetUsername = targetCreds.Username
+			conf.TargetPassword = targetCreds.Password
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originRequestHandler := NewFakeRequestHandler()
+			targetRequestHandler := NewFakeRequestHandler()
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				originRequestHandler.HandleRequest,
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				targetRequestHandler.HandleRequest,
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			conf.ProxyAuthCredentials = "app:appPassword, app2:app:Password2"
+			proxy, err := setup.NewProxyInstanceWithConfig(conf)
+			require.Nil(t, err)
+			defer proxy.Shutdown()
+
+			testClient := client.NewCqlClient(
+				fmt.Sprintf("%s:%d", conf.ProxyListenAddress, conf.ProxyListenPort), tt.clientCreds)
+			cqlConn, err := testClient.Connect(context.Background())
+			require.Nil(t, err)
+			defer cqlConn.Close()
+
+			err = cqlConn.InitiateHandshake(primitive.ProtocolVersion4, 0)
+			// the first connection of each cluster is the control connection
+			originRequests := originRequestHandler.GetRequests()[1]
+			targetRequests := targetRequestHandler.GetRequests()[1]
+			if !tt.success {
+				require.NotNil(t, err, "expected failure in handshake")
+				require.Contains(t, err.Error(), (&message.AuthenticationError{
+					ErrorMessage: "Provided username and/or password are incorrect"}).String())
+				// the clusters only received the STARTUP request
+				require.Equal(t, 1, len(originRequests))
+				require.Equal(t, 1, len(targetRequests))
+				return
+			}
+
+			require.Nil(t, err, "handshake failed: %v", err)
+			require.Equal(t, originCreds.Username, authResponseUsername(t, originRequests))
+			require.Equal(t, targetCreds.Username, authResponseUsername(t, targetRequests))
+
+			response, err := cqlConn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Query{
+				Query:   "SELECT * FROM system.peers",
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne},
+			}))
+			require.Nil(t, err)
+			require.Equal(t, primitive.OpCodeResult, response.Body.Message.GetOpCode(), response.Body.Message)
+		})
+	}
+}
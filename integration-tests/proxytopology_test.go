@@ -0,0 +1,89 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProxyTopologyAddressesFile tests that the proxy instances that are added to or removed from
+// ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE are returned by the system.peers queries and that the clients that registered
+// for TOPOLOGY_CHANGE events are notified.
+func TestProxyTopologyAddressesFile(t *testing.T) {
+	addressesFile := filepath.Join(t.TempDir(), "proxy_addresses")
+	err := os.WriteFile(addressesFile, []byte("127.0.0.1\n"), 0644)
+	require.Nil(t, err)
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyTopologyAddressesFile = addressesFile
+	conf.ProxyTopologyAddressesFilePollIntervalMs = 100
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	clientConn := testSetup.Client.CqlConnection
+	response, err := clientConn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId,
+		&message.Register{EventTypes: []primitive.EventType{primitive.EventTypeTopologyChange}}))
+	require.Nil(t, err)
+	require.IsType(t, &message.Ready{}, response.Body.Message)
+
+	requirePeersCount := func(expected int) {
+		response, err := clientConn.SendAndReceive(frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId,
+			&message.Query{Query: "SELECT * FROM system.peers"}))
+		require.Nil(t, err)
+		rows, ok := response.Body.Message.(*message.RowsResult)
+		require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+		require.Equal(t, expected, len(rows.Data))
+	}
+
+	requireEvent := func(changeType primitive.TopologyChangeType, address string) {
+		event, err := clientConn.ReceiveEvent()
+		require.Nil(t, err)
+		require.Equal(t, int16(-1), event.Header.StreamId)
+		require.Equal(t, &message.TopologyChangeEvent{
+			ChangeType: changeType,
+			Address:    &primitive.Inet{Addr: net.ParseIP(address), Port: int32(conf.ProxyListenPort)},
+		}, event.Body.Message)
+	}
+
+	requirePeersCount(0)
+
+	err = os.WriteFile(addressesFile, []byte("127.0.0.1\n127.0.0.2\n"), 0644)
+	require.Nil(t, err)
+	requireEvent(primitive.TopologyChangeTypeMovedNode, "127.0.0.1")
+	requireEvent(primitive.TopologyChangeTypeNewNode, "127.0.0.2")
+	requirePeersCount(1)
+
+	// the address of this proxy instance must be in the file, the change is ignored
+	err = os.WriteFile(addressesFile, []byte("127.0.0.2,127.0.0.3"), 0644)
+	require.Nil(t, err)
+
+	err = os.WriteFile(addressesFile, []byte("127.0.0.2, 127.0.0.1"), 0644)
+	require.Nil(t, err)
+	requireEvent(primitive.TopologyChangeTypeMovedNode, "127.0.0.2")
+	requireEvent(primitive.TopologyChangeTypeMovedNode, "127.0.0.1")
+	requirePeersCount(1)
+
+	err = os.WriteFile(addressesFile, []byte("127.0.0.1"), 0644)
+	require.Nil(t, err)
+	requireEvent(primitive.TopologyChangeTypeRemovedNode, "127.0.0.2")
+	requireEvent(primitive.TopologyChangeTypeMovedNode, "127.0.0.1")
+	requirePeersCount(0)
+}
@@ -0,0 +1,121 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const readMirroringQuery = "SELECT v FROM ks.tb WHERE k = 0"
+
+// TestReadMirroring tests that the reads are also forwarded to the secondary cluster when ZDM_READ_MIRRORING_ENABLED
+// is true and that the mismatches between the two responses are tracked in the metrics.
+func TestReadMirroring(t *testing.T) {
+
+	type test struct {
+		name               string
+		targetRows         message.RowSet
+		targetError        message.Error
+		expectedMismatches map[string]string
+	}
+
+	originRows := message.RowSet{{[]byte("a")}, {[]byte("b")}}
+
+	tests := []test{
+		{
+			name:       "same rows in a different order",
+			targetRows: message.RowSet{{[]byte("b")}, {[]byte("a")}},
+		},
+		{
+			name:               "different values",
+			targetRows:         message.RowSet{{[]byte("a")}, {[]byte("c")}},
+			expectedMismatches: map[string]string{"checksum": "1"},
+		},
+		{
+			name:               "missing row",
+			targetRows:         message.RowSet{{[]byte("a")}},
+			expectedMismatches: map[string]string{"row_count": "1"},
+		},
+		{
+			name:               "error on secondary",
+			targetError:        &message.ReadTimeout{ErrorMessage: "read timeout", Consistency: primitive.ConsistencyLevelOne},
+			expectedMismatches: map[string]string{"error": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.ReadMirroringEnabled = true
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newReadMirroringHandler(originRows, nil),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newReadMirroringHandler(tt.targetRows, tt.targetError),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: readMirroringQuery}))
+			require.Nil(t, err)
+			rows, ok := response.Body.Message.(*message.RowsResult)
+			require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+			require.Equal(t, originRows, rows.Data)
+
+			utils.RequireWithRetries(t, func() (err error, fatal bool) {
+				values := getProxyMetricValues(t, testSetup)
+				if values["zdm_proxy_read_comparisons_total"] != "1" {
+					return fmt.Errorf("expected 1 comparison but got %v", values["zdm_proxy_read_comparisons_total"]), false
+				}
+				for _, mismatchType := range []string{"row_count", "checksum", "error"} {
+					expected := "0"
+					if value, ok := tt.expectedMismatches[mismatchType]; ok {
+						expected = value
+					}
+					actual := values[fmt.Sprintf("zdm_proxy_read_comparison_mismatches_total{type=\"%v\"}", mismatchType)]
+					if actual != expected {
+						return fmt.Errorf("expected %v %v mismatches but got %v", expected, mismatchType, actual), false
+					}
+				}
+				return nil, false
+			}, 50, 100*time.Millisecond)
+		})
+	}
+}
+
+func newReadMirroringHandler(rows message.RowSet, errMsg message.Error) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || query.Query != readMirroringQuery {
+			return nil
+		}
+		if errMsg != nil {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, errMsg)
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "tb", Name: "v", Index: 0, Type: datatype.Varchar},
+				},
+			},
+			Data: rows,
+		})
+	}
+}
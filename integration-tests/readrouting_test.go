@@ -0,0 +1,78 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestApplicationReadRoutingRules(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ApplicationReadRoutingRules = "analytics-service ks TARGET; analytics-service ks.pinned ORIGIN"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originReads := int32(0)
+	targetReads := int32(0)
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newReadCountingHandler(&originReads),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newReadCountingHandler(&targetReads),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	connect := func(applicationName string) *testclient.TestClient {
+		testClient, err := testclient.Connect(
+			context.Background(), testSetup.Proxy.GetListenAddr().String(), primitive.ProtocolVersion4,
+			conf.TargetUsername, conf.TargetPassword, &testclient.Options{StartupOptions: map[string]string{
+				message.StartupOptionApplicationName: applicationName,
+			}})
+		require.Nil(t, err)
+		return testClient
+	}
+	requireReads := func(testClient *testclient.TestClient, query string, expectedOrigin int32, expectedTarget int32) {
+		atomic.StoreInt32(&originReads, 0)
+		atomic.StoreInt32(&targetReads, 0)
+		response, _, err := testClient.SendMessage(
+			context.Background(), primitive.ProtocolVersion4, &message.Query{Query: query})
+		require.Nil(t, err)
+		require.IsType(t, &message.VoidResult{}, response.Body.Message)
+		require.Equal(t, expectedOrigin, atomic.LoadInt32(&originReads), query)
+		require.Equal(t, expectedTarget, atomic.LoadInt32(&targetReads), query)
+	}
+
+	analyticsClient := connect("analytics-service")
+	defer analyticsClient.Shutdown()
+	requireReads(analyticsClient, "SELECT * FROM ks.events", 0, 1)
+	requireReads(analyticsClient, "SELECT * FROM ks.pinned", 1, 0)
+	requireReads(analyticsClient, "SELECT * FROM ks2.events", 1, 0)
+
+	otherClient := connect("orders-service")
+	defer otherClient.Shutdown()
+	requireReads(otherClient, "SELECT * FROM ks.events", 1, 0)
+}
+
+func newReadCountingHandler(reads *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "SELECT * FROM ks") {
+			return nil
+		}
+		atomic.AddInt32(reads, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
@@ -0,0 +1,126 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRequestInterceptors tests that the request interceptors of an embedded proxy can reject, modify and route
+// the requests
+func TestRequestInterceptors(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originQueries := &recordedQueries{lock: &sync.Mutex{}}
+	targetQueries := &recordedQueries{lock: &sync.Mutex{}}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		originQueries.handler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		targetQueries.handler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(nil, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	tenancyCheck := zdmproxy.RequestInterceptorFunc(func(request *zdmproxy.InterceptedRequest) zdmproxy.RequestInterceptorResult {
+		if query, ok := request.Frame.Body.Message.(*message.Query); ok && strings.Contains(query.Query, "other_tenant.") {
+			return zdmproxy.RequestInterceptorResult{
+				Reject: &message.Unauthorized{ErrorMessage: "user " + request.Username + " can't access other_tenant"}}
+		}
+		return zdmproxy.RequestInterceptorResult{}
+	})
+	rewrite := zdmproxy.RequestInterceptorFunc(func(request *zdmproxy.InterceptedRequest) zdmproxy.RequestInterceptorResult {
+		if query, ok := request.Frame.Body.Message.(*message.Query); ok && strings.Contains(query.Query, "tb_old") {
+			query.Query = strings.ReplaceAll(query.Query, "tb_old", "tb_new")
+			return zdmproxy.RequestInterceptorResult{Modified: true}
+		}
+		return zdmproxy.RequestInterceptorResult{}
+	})
+	route := zdmproxy.RequestInterceptorFunc(func(request *zdmproxy.InterceptedRequest) zdmproxy.RequestInterceptorResult {
+		if query, ok := request.Frame.Body.Message.(*message.Query); ok && strings.Contains(query.Query, "tb_new") {
+			return zdmproxy.RequestInterceptorResult{Route: zdmproxy.RequestRouteTarget}
+		}
+		return zdmproxy.RequestInterceptorResult{}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	proxy, err := zdmproxy.RunWithOptions(conf, context.Background(), &zdmproxy.ZdmProxyOptions{
+		Listeners:           []net.Listener{listener},
+		MetricsRegisterer:   prometheus.NewRegistry(),
+		RequestInterceptors: []zdmproxy.RequestInterceptor{tenancyCheck, rewrite, route},
+	})
+	require.Nil(t, err)
+	defer proxy.Shutdown()
+
+	testClient, err := testclient.Connect(
+		context.Background(), listener.Addr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, nil)
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	sendQuery := func(query string) *frame.Frame {
+		response, _, err := testClient.SendMessage(context.Background(), primitive.ProtocolVersion4, &message.Query{
+			Query:   query,
+			Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne},
+		})
+		require.Nil(t, err)
+		return response
+	}
+
+	response := sendQuery("INSERT INTO ks.tb (a) VALUES (1)")
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	require.Equal(t, []string{"INSERT INTO ks.tb (a) VALUES (1)"}, originQueries.get())
+	require.Equal(t, []string{"INSERT INTO ks.tb (a) VALUES (1)"}, targetQueries.get())
+
+	response = sendQuery("INSERT INTO other_tenant.tb (a) VALUES (1)")
+	require.IsType(t, &message.Unauthorized{}, response.Body.Message)
+	require.Equal(t, "user "+conf.TargetUsername+" can't access other_tenant",
+		response.Body.Message.(*message.Unauthorized).ErrorMessage)
+
+	// modified by the second interceptor and routed by the third one
+	response = sendQuery("INSERT INTO ks.tb_old (a) VALUES (1)")
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	require.Equal(t, []string{"INSERT INTO ks.tb (a) VALUES (1)"}, originQueries.get())
+	require.Equal(t, []string{"INSERT INTO ks.tb (a) VALUES (1)", "INSERT INTO ks.tb_new (a) VALUES (1)"},
+		targetQueries.get())
+}
+
+type recordedQueries struct {
+	lock    *sync.Mutex
+	queries []string
+}
+
+func (recv *recordedQueries) handler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		recv.lock.Lock()
+		recv.queries = append(recv.queries, query.Query)
+		recv.lock.Unlock()
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
+
+func (recv *recordedQueries) get() []string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]string(nil), recv.queries...)
+}
@@ -0,0 +1,220 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/cqlserver"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTargetConcurrentRequestLimit(t *testing.T) {
+
+	type test struct {
+		name              string
+		maxQueued         int
+		queueTimeoutMs    int
+		requests          int
+		expectedRejected  int
+		expectedForwarded int
+	}
+
+	tests := []test{
+		{
+			name:              "request queue is full",
+			maxQueued:         1,
+			queueTimeoutMs:    10000,
+			requests:          3,
+			expectedRejected:  1,
+			expectedForwarded: 2,
+		},
+		{
+			name:              "request times out in the queue",
+			maxQueued:         10,
+			queueTimeoutMs:    200,
+			requests:          2,
+			expectedRejected:  1,
+			expectedForwarded: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.TargetMaxConcurrentRequests = 1
+			conf.TargetMaxQueuedRequests = tt.maxQueued
+			conf.TargetRequestQueueTimeoutMs = tt.queueTimeoutMs
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			forwarded := int32(0)
+			releaseTarget := make(chan bool)
+			defer func() {
+				select {
+				case <-releaseTarget:
+				default:
+					close(releaseTarget)
+				}
+			}()
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newInsertHandler(nil, nil),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newInsertHandler(&forwarded, releaseTarget),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			results := make(chan *frame.Frame, tt.requests)
+			for i := 0; i < tt.requests; i++ {
+				insert := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{
+					Query: "INSERT INTO ks.tbl (a) VALUES (1)",
+				})
+				inFlight, err := testSetup.Client.CqlConnection.Send(insert)
+				require.Nil(t, err)
+				go func() {
+					response, err := testSetup.Client.CqlConnection.Receive(inFlight)
+					if err != nil {
+						results <- nil
+						return
+					}
+					results <- response
+				}()
+			}
+
+			// the target is not responding so the rejected requests are the only ones that can complete
+			rejected := 0
+			for i := 0; i < tt.expectedRejected; i++ {
+				select {
+				case response := <-results:
+					require.NotNil(t, response)
+					require.IsType(t, &message.Overloaded{}, response.Body.Message)
+					require.True(t, strings.Contains(
+						response.Body.Message.(*message.Overloaded).ErrorMessage, "maximum number of concurrent requests"))
+					rejected++
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out waiting for rejected request")
+				}
+			}
+			utils.RequireWithRetries(t, func() (err error, fatal bool) {
+				if count := atomic.LoadInt32(&forwarded); count != 1 {
+					return fmt.Errorf("expected 1 request forwarded to target but got %v", count), false
+				}
+				return nil, false
+			}, 50, 100*time.Millisecond)
+
+			close(releaseTarget)
+			for i := 0; i < tt.requests-rejected; i++ {
+				select {
+				case response := <-results:
+					require.NotNil(t, response)
+					require.IsType(t, &message.VoidResult{}, response.Body.Message)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out waiting for response")
+				}
+			}
+			require.Equal(t, int32(tt.expectedForwarded), atomic.LoadInt32(&forwarded))
+		})
+	}
+}
+
+func TestTargetConcurrentRequestLimitAcrossClientConnections(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TargetMaxConcurrentRequests = 1
+	conf.TargetMaxQueuedRequests = 10
+	conf.TargetRequestQueueTimeoutMs = 10000
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	forwarded := int32(0)
+	releaseTarget := make(chan bool)
+	defer func() {
+		select {
+		case <-releaseTarget:
+		default:
+			close(releaseTarget)
+		}
+	}()
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newInsertHandler(nil, nil),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newInsertHandler(&forwarded, releaseTarget),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	otherClient, err := cqlserver.NewCqlClient(conf.ProxyListenAddress, conf.ProxyListenPort,
+		conf.OriginUsername, conf.OriginPassword, true)
+	require.Nil(t, err)
+	defer otherClient.Close()
+
+	results := make(chan *frame.Frame, 2)
+	for _, clientConn := range []*client.CqlClientConnection{testSetup.Client.CqlConnection, otherClient.CqlConnection} {
+		insert := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{
+			Query: "INSERT INTO ks.tbl (a) VALUES (1)",
+		})
+		inFlight, err := clientConn.Send(insert)
+		require.Nil(t, err)
+		go func(clientConn *client.CqlClientConnection) {
+			response, err := clientConn.Receive(inFlight)
+			if err != nil {
+				results <- nil
+				return
+			}
+			results <- response
+		}(clientConn)
+	}
+
+	// the request of the second client connection waits in the queue until the response of the first one frees a slot
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		if count := atomic.LoadInt32(&forwarded); count != 1 {
+			return fmt.Errorf("expected 1 request forwarded to target but got %v", count), false
+		}
+		return nil, false
+	}, 50, 100*time.Millisecond)
+
+	close(releaseTarget)
+	for i := 0; i < 2; i++ {
+		select {
+		case response := <-results:
+			require.NotNil(t, response)
+			require.IsType(t, &message.VoidResult{}, response.Body.Message)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for response")
+		}
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&forwarded))
+}
+
+func newInsertHandler(forwarded *int32, release chan bool) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+			return nil
+		}
+		if forwarded != nil {
+			atomic.AddInt32(forwarded, 1)
+		}
+		if release != nil {
+			<-release
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
@@ -0,0 +1,109 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestRequestRetries tests that the idempotent requests that fail with a retryable error are retried on the cluster
+// that returned the error (ZDM_ORIGIN_REQUEST_MAX_RETRIES and ZDM_TARGET_REQUEST_MAX_RETRIES).
+func TestRequestRetries(t *testing.T) {
+
+	type test struct {
+		name                  string
+		originMaxRetries      int
+		targetMaxRetries      int
+		query                 string
+		originFailures        int32
+		targetFailures        int32
+		failure               message.Message
+		expectedOriginCount   int32
+		expectedTargetCount   int32
+		expectedResponse      message.Message
+		expectedOriginRetries string
+		expectedTargetRetries string
+	}
+
+	readQuery := "SELECT * FROM ks.tb WHERE a = 1"
+	writeQuery := "INSERT INTO ks.tb (a) VALUES (1)"
+	overloaded := &message.Overloaded{ErrorMessage: "overloaded"}
+	unavailable := &message.Unavailable{ErrorMessage: "unavailable", Consistency: primitive.ConsistencyLevelQuorum}
+	tests := []test{
+		{"read retried until success", 2, 0, readQuery, 2, 0, overloaded,
+			3, 0, &message.VoidResult{}, "2", "0"},
+		{"read retries exhausted", 2, 0, readQuery, 5, 0, overloaded,
+			3, 0, &message.Overloaded{}, "2", "0"},
+		{"read not retried when disabled", 0, 2, readQuery, 1, 0, overloaded,
+			1, 0, &message.Overloaded{}, "0", "0"},
+		{"write retried on target only", 2, 2, writeQuery, 0, 1, unavailable,
+			1, 2, &message.VoidResult{}, "0", "1"},
+		{"write retried on both clusters", 1, 1, writeQuery, 1, 1, unavailable,
+			2, 2, &message.VoidResult{}, "1", "1"},
+		{"invalid error not retried", 2, 2, writeQuery, 0, 1, &message.Invalid{ErrorMessage: "invalid"},
+			1, 1, &message.Invalid{}, "0", "0"},
+		{"lightweight transaction not retried", 2, 2, "INSERT INTO ks.tb (a) VALUES (1) IF NOT EXISTS", 1, 0,
+			overloaded, 1, 1, &message.Overloaded{}, "0", "0"},
+		{"now() not retried", 2, 2, "INSERT INTO ks.tb (a, b) VALUES (1, now())", 1, 0,
+			overloaded, 1, 1, &message.Overloaded{}, "0", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.OriginRequestMaxRetries = tt.originMaxRetries
+			conf.TargetRequestMaxRetries = tt.targetMaxRetries
+			conf.OriginRequestRetryBackoffMinMs = 10
+			conf.OriginRequestRetryBackoffMaxMs = 50
+			conf.TargetRequestRetryBackoffMinMs = 10
+			conf.TargetRequestRetryBackoffMaxMs = 50
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originQueries := int32(0)
+			targetQueries := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRequestRetryHandler(&originQueries, tt.originFailures, tt.failure),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRequestRetryHandler(&targetQueries, tt.targetFailures, tt.failure),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+			require.Equal(t, tt.expectedOriginCount, atomic.LoadInt32(&originQueries))
+			require.Equal(t, tt.expectedTargetCount, atomic.LoadInt32(&targetQueries))
+			metricValues := getProxyMetricValues(t, testSetup)
+			require.Equal(t, tt.expectedOriginRetries, metricValues[`zdm_proxy_retried_requests_total{cluster="origin"}`])
+			require.Equal(t, tt.expectedTargetRetries, metricValues[`zdm_proxy_retried_requests_total{cluster="target"}`])
+		})
+	}
+}
+
+// newRequestRetryHandler returns a handler that fails the first requests on ks.tb with the provided error.
+func newRequestRetryHandler(count *int32, failures int32, failure message.Message) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.Contains(query.Query, " ks.tb ") {
+			return nil
+		}
+		if atomic.AddInt32(count, 1) <= failures {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, failure)
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
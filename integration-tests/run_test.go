@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/datastax/zdm-proxy/integration-tests/client"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
 	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
 	"github.com/jpillora/backoff"
 	"github.com/stretchr/testify/require"
@@ -55,7 +55,7 @@ func TestRunWithRetries(t *testing.T) {
 	}()
 
 	expectedFailureFunc := func(errMsg string) (err error, fatal bool) {
-		testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+		testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 		if err == nil {
 			testClient.Shutdown()
 			return errors.New(errMsg), false
@@ -83,7 +83,7 @@ func TestRunWithRetries(t *testing.T) {
 	require.Nil(t, err)
 
 	utils.RequireWithRetries(t, func() (err error, fatal bool) {
-		testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+		testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 		if err != nil {
 			return fmt.Errorf("expected successful connection attempt but proxy refused: %w", err), false
 		}
@@ -23,7 +23,7 @@ they are registered separately on the parent test.
 */
 
 func TestWithHttpHandlers(t *testing.T) {
-	metricsHandler, readinessHandler := runner.SetupHandlers()
+	metricsHandler, readinessHandler, adminHandler := runner.SetupHandlers()
 
 	t.Run("testMetrics", func(t *testing.T) {
 		testMetrics(t, metricsHandler)
@@ -32,20 +32,21 @@ func TestWithHttpHandlers(t *testing.T) {
 	metricsHandler.SetHandler(metrics.DefaultHttpHandler())
 
 	t.Run("testHttpEndpointsWithProxyNotInitialized", func(t *testing.T) {
-		testHttpEndpointsWithProxyNotInitialized(t, metricsHandler, readinessHandler)
+		testHttpEndpointsWithProxyNotInitialized(t, metricsHandler, readinessHandler, adminHandler)
 	})
 
 	t.Run("testHttpEndpointsWithProxyInitialized", func(t *testing.T) {
-		testHttpEndpointsWithProxyInitialized(t, metricsHandler, readinessHandler)
+		testHttpEndpointsWithProxyInitialized(t, metricsHandler, readinessHandler, adminHandler)
 	})
 
 	t.Run("testHttpEndpointsWithUnavailableNode", func(t *testing.T) {
-		testHttpEndpointsWithUnavailableNode(t, metricsHandler, readinessHandler)
+		testHttpEndpointsWithUnavailableNode(t, metricsHandler, readinessHandler, adminHandler)
 	})
 }
 
 func testHttpEndpointsWithProxyNotInitialized(
-	t *testing.T, metricsHandler *httpzdmproxy.HandlerWithFallback, healthHandler *httpzdmproxy.HandlerWithFallback) {
+	t *testing.T, metricsHandler *httpzdmproxy.HandlerWithFallback, healthHandler *httpzdmproxy.HandlerWithFallback,
+	adminHandler *httpzdmproxy.HandlerWithFallback) {
 
 	simulacronSetup, err := setup.NewSimulacronTestSetupWithSession(t, false, false)
 	require.Nil(t, err)
@@ -69,7 +70,7 @@ func testHttpEndpointsWithProxyNotInitialized(
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		runner.RunMain(conf, ctx, metricsHandler, healthHandler)
+		runner.RunMain(conf, ctx, metricsHandler, healthHandler, adminHandler)
 	}()
 
 	time.Sleep(500 * time.Millisecond)
@@ -87,7 +88,8 @@ func testHttpEndpointsWithProxyNotInitialized(
 }
 
 func testHttpEndpointsWithProxyInitialized(
-	t *testing.T, metricsHandler *httpzdmproxy.HandlerWithFallback, healthHandler *httpzdmproxy.HandlerWithFallback) {
+	t *testing.T, metricsHandler *httpzdmproxy.HandlerWithFallback, healthHandler *httpzdmproxy.HandlerWithFallback,
+	adminHandler *httpzdmproxy.HandlerWithFallback) {
 
 	simulacronSetup, err := setup.NewSimulacronTestSetupWithSession(t, false, false)
 	require.Nil(t, err)
@@ -106,7 +108,7 @@ func testHttpEndpointsWithProxyInitialized(
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		runner.RunMain(conf, ctx, metricsHandler, healthHandler)
+		runner.RunMain(conf, ctx, metricsHandler, healthHandler, adminHandler)
 	}()
 
 	httpAddr := fmt.Sprintf("%s:%d", conf.MetricsAddress, conf.MetricsPort)
@@ -134,11 +136,13 @@ func testHttpEndpointsWithProxyInitialized(
 		FailureCountThreshold: conf.HeartbeatFailureThreshold,
 		Status:                health.UP,
 	}, report.TargetStatus)
+	require.Equal(t, health.UP, report.ListenerStatus)
 	require.Equal(t, health.UP, report.Status)
 }
 
 func testHttpEndpointsWithUnavailableNode(
-	t *testing.T, metricsHandler *httpzdmproxy.HandlerWithFallback, healthHandler *httpzdmproxy.HandlerWithFallback) {
+	t *testing.T, metricsHandler *httpzdmproxy.HandlerWithFallback, healthHandler *httpzdmproxy.HandlerWithFallback,
+	adminHandler *httpzdmproxy.HandlerWithFallback) {
 
 	simulacronSetup, err := setup.NewSimulacronTestSetupWithSession(t, false, false)
 	require.Nil(t, err)
@@ -157,7 +161,7 @@ func testHttpEndpointsWithUnavailableNode(
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		runner.RunMain(conf, ctx, metricsHandler, healthHandler)
+		runner.RunMain(conf, ctx, metricsHandler, healthHandler, adminHandler)
 	}()
 
 	httpAddr := fmt.Sprintf("%s:%d", conf.MetricsAddress, conf.MetricsPort)
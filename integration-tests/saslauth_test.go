@@ -0,0 +1,160 @@
+package integration_tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+const dseAuthenticator = "com.datastax.bdp.cassandra.auth.DseAuthenticator"
+
+var (
+	gssapiChallenges = [][]byte{{0x60, 0x01}, {0x60, 0x02}}
+	gssapiResponses  = [][]byte{[]byte("GSSAPI"), {0x05, 0x00, 0x01}, {0x05, 0x00, 0x02}}
+)
+
+// TestSaslAuthenticatorRelay tests that a multi-round SASL negotiation with a mechanism other than PLAIN (GSSAPI) is
+// relayed between the client and the primary cluster unchanged while the proxy authenticates to the secondary cluster
+// with the configured credentials.
+func TestSaslAuthenticatorRelay(t *testing.T) {
+	originCreds := &client.AuthCredentials{Username: "origin_username", Password: "originPassword"}
+	targetCreds := &client.AuthCredentials{Username: "target_username", Password: "targetPassword"}
+	version := primitive.ProtocolVersion4
+
+	serverConf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	serverConf.OriginUsername = ""
+	serverConf.OriginPassword = ""
+	serverConf.TargetUsername = targetCreds.Username
+	serverConf.TargetPassword = targetCreds.Password
+	testSetup, err := setup.NewCqlServerTestSetup(t, serverConf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originSasl := &saslHandler{plainCreds: originCreds}
+	targetRequestHandler := NewFakeRequestHandler()
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		originSasl.handle,
+		client.HeartbeatHandler,
+		client.NewSetKeyspaceHandler(func(_ string) {}),
+		client.RegisterHandler,
+		client.NewSystemTablesHandler("origin", "dc1"),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		targetRequestHandler.HandleRequest,
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(nil, false, version)
+	require.Nil(t, err)
+
+	proxyConf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	proxyConf.OriginUsername = originCreds.Username
+	proxyConf.OriginPassword = originCreds.Password
+	proxyConf.TargetUsername = targetCreds.Username
+	proxyConf.TargetPassword = targetCreds.Password
+	proxy, err := setup.NewProxyInstanceWithConfig(proxyConf)
+	require.Nil(t, err)
+	defer proxy.Shutdown()
+
+	testClient := client.NewCqlClient(
+		fmt.Sprintf("%s:%d", proxyConf.ProxyListenAddress, proxyConf.ProxyListenPort), nil)
+	cqlConn, err := testClient.Connect(context.Background())
+	require.Nil(t, err)
+	defer cqlConn.Close()
+
+	response, err := cqlConn.SendAndReceive(frame.NewFrame(version, 0, message.NewStartup()))
+	require.Nil(t, err)
+	authenticate, ok := response.Body.Message.(*message.Authenticate)
+	require.True(t, ok, "expected AUTHENTICATE but got %v", response.Body.Message)
+	require.Equal(t, dseAuthenticator, authenticate.Authenticator)
+
+	for i, token := range gssapiResponses {
+		response, err = cqlConn.SendAndReceive(frame.NewFrame(version, 0, &message.AuthResponse{Token: token}))
+		require.Nil(t, err)
+		if i < len(gssapiChallenges) {
+			challenge, ok := response.Body.Message.(*message.AuthChallenge)
+			require.True(t, ok, "expected AUTH_CHALLENGE but got %v", response.Body.Message)
+			require.Equal(t, gssapiChallenges[i], challenge.Token)
+		}
+	}
+	require.IsType(t, &message.AuthSuccess{}, response.Body.Message)
+
+	response, err = cqlConn.SendAndReceive(frame.NewFrame(version, 0, &message.Query{
+		Query:   "SELECT * FROM system.peers",
+		Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne},
+	}))
+	require.Nil(t, err)
+	require.Equal(t, primitive.OpCodeResult, response.Body.Message.GetOpCode(), response.Body.Message)
+
+	require.Equal(t, 1, originSasl.gssapiHandshakes())
+	// the first connection of target is the control connection
+	require.Equal(t, targetCreds.Username, authResponseUsername(t, targetRequestHandler.GetRequests()[1]))
+}
+
+// saslHandler replaces the handshake handler of the test server, it performs the handshakes of a DseAuthenticator that supports the PLAIN mechanism and a fake GSSAPI
+// mechanism that expects gssapiResponses and sends gssapiChallenges.
+type saslHandler struct {
+	plainCreds *client.AuthCredentials
+
+	lock             sync.Mutex
+	gssapiSuccessful int
+}
+
+func (recv *saslHandler) handle(
+	request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+	version := request.Header.Version
+	streamId := request.Header.StreamId
+	switch msg := request.Body.Message.(type) {
+	case *message.Startup:
+		ctx.PutAttribute("sasl_round", 0)
+		return frame.NewFrame(version, streamId, &message.Authenticate{Authenticator: dseAuthenticator})
+	case *message.AuthResponse:
+		round, _ := ctx.GetAttribute("sasl_round").(int)
+		ctx.PutAttribute("sasl_round", round+1)
+		mechanism, _ := ctx.GetAttribute("sasl_mechanism").(string)
+		if round == 0 {
+			ctx.PutAttribute("sasl_mechanism", string(msg.Token))
+			if string(msg.Token) == "PLAIN" {
+				return frame.NewFrame(version, streamId, &message.AuthChallenge{Token: []byte("PLAIN-START")})
+			}
+			mechanism = string(msg.Token)
+		}
+		success := false
+		switch mechanism {
+		case "PLAIN":
+			creds := &client.AuthCredentials{}
+			success = creds.Unmarshal(msg.Token) == nil &&
+				creds.Username == recv.plainCreds.Username && creds.Password == recv.plainCreds.Password
+		case "GSSAPI":
+			if !bytes.Equal(gssapiResponses[round], msg.Token) {
+				break
+			}
+			if round < len(gssapiChallenges) {
+				return frame.NewFrame(version, streamId, &message.AuthChallenge{Token: gssapiChallenges[round]})
+			}
+			success = true
+			recv.lock.Lock()
+			recv.gssapiSuccessful++
+			recv.lock.Unlock()
+		}
+		if !success {
+			return frame.NewFrame(version, streamId, &message.AuthenticationError{ErrorMessage: "invalid credentials"})
+		}
+		return frame.NewFrame(version, streamId, &message.AuthSuccess{})
+	}
+	return nil
+}
+
+func (recv *saslHandler) gssapiHandshakes() int {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.gssapiSuccessful
+}
@@ -0,0 +1,97 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSchemaStatementPolicies(t *testing.T) {
+
+	type test struct {
+		name                    string
+		query                   string
+		expectedOriginForwarded int32
+		expectedTargetForwarded int32
+		expectedRejectedMsg     string
+	}
+
+	tests := []test{
+		{
+			name:                    "create table",
+			query:                   "CREATE TABLE ks.tb (a int PRIMARY KEY)",
+			expectedOriginForwarded: 1,
+			expectedTargetForwarded: 1,
+		},
+		{
+			name:                    "create index with origin only policy",
+			query:                   "CREATE INDEX idx ON ks.tb (b)",
+			expectedOriginForwarded: 1,
+			expectedTargetForwarded: 0,
+		},
+		{
+			name:                    "create custom index with both policy",
+			query:                   "CREATE CUSTOM INDEX idx ON ks.tb (b) USING 'StorageAttachedIndex'",
+			expectedOriginForwarded: 1,
+			expectedTargetForwarded: 1,
+		},
+		{
+			name:                    "create materialized view with reject policy",
+			query:                   "CREATE MATERIALIZED VIEW ks.mv AS SELECT * FROM ks.tb WHERE a IS NOT NULL PRIMARY KEY (a)",
+			expectedOriginForwarded: 0,
+			expectedTargetForwarded: 0,
+			expectedRejectedMsg: "Materialized view statements are rejected by ZDM proxy " +
+				"(ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY is REJECT)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.MaterializedViewStatementsPolicy = config.SchemaStatementPolicyReject
+			conf.IndexStatementsPolicy = config.SchemaStatementPolicyOriginOnly
+			conf.CustomIndexStatementsPolicy = config.SchemaStatementPolicyBoth
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originForwarded := int32(0)
+			targetForwarded := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newSchemaChangeHandler(&originForwarded),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newSchemaChangeHandler(&targetForwarded),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			if tt.expectedRejectedMsg != "" {
+				require.IsType(t, &message.Invalid{}, response.Body.Message)
+				require.Equal(t, tt.expectedRejectedMsg, response.Body.Message.(*message.Invalid).ErrorMessage)
+
+				response, err = testSetup.Client.CqlConnection.SendAndReceive(
+					frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Prepare{Query: tt.query}))
+				require.Nil(t, err)
+				require.IsType(t, &message.Invalid{}, response.Body.Message)
+				require.Equal(t, tt.expectedRejectedMsg, response.Body.Message.(*message.Invalid).ErrorMessage)
+			} else {
+				require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			}
+			require.Equal(t, tt.expectedOriginForwarded, atomic.LoadInt32(&originForwarded))
+			require.Equal(t, tt.expectedTargetForwarded, atomic.LoadInt32(&targetForwarded))
+		})
+	}
+}
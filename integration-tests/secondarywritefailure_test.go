@@ -0,0 +1,145 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSecondaryWriteFailureMode tests the response of a write that only fails on the secondary cluster
+// (ZDM_SECONDARY_WRITE_FAILURE_MODE).
+func TestSecondaryWriteFailureMode(t *testing.T) {
+
+	type test struct {
+		name                string
+		mode                string
+		expectedResponse    message.Message
+		expectedTargetCount int32
+		expectedFailures    string
+	}
+
+	tests := []test{
+		{"return error", "RETURN_ERROR", &message.WriteTimeout{}, 1, "0"},
+		{"log and continue", "LOG_AND_CONTINUE", &message.VoidResult{}, 1, "1"},
+		{"queue for replay", "QUEUE_FOR_REPLAY", &message.VoidResult{}, 2, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.SecondaryWriteFailureMode = tt.mode
+			conf.SecondaryWriteReplayDelayMs = 10
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			writeTimeout := &message.WriteTimeout{
+				ErrorMessage: "write timeout", Consistency: primitive.ConsistencyLevelQuorum, WriteType: primitive.WriteTypeSimple}
+			originQueries := int32(0)
+			targetQueries := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRequestRetryHandler(&originQueries, 0, writeTimeout),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRequestRetryHandler(&targetQueries, 1, writeTimeout),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(frame.NewFrame(
+				primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+			require.Equal(t, int32(1), atomic.LoadInt32(&originQueries))
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt32(&targetQueries) == tt.expectedTargetCount
+			}, 2*time.Second, 10*time.Millisecond)
+			time.Sleep(50 * time.Millisecond)
+			require.Equal(t, tt.expectedTargetCount, atomic.LoadInt32(&targetQueries))
+			metricValues := getProxyMetricValues(t, testSetup)
+			require.Equal(t, tt.expectedFailures, metricValues["zdm_proxy_secondary_write_failures_total"])
+		})
+	}
+}
+
+// TestSecondaryWriteJournal tests that a write that only fails on the secondary cluster is replayed from the write
+// journal (ZDM_SECONDARY_WRITE_JOURNAL_TYPE) with the time at which the proxy received it as default timestamp.
+func TestSecondaryWriteJournal(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		journalType string
+	}{
+		{"memory", "MEMORY"},
+		{"file", "FILE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.SecondaryWriteFailureMode = "QUEUE_FOR_REPLAY"
+			conf.SecondaryWriteJournalType = tt.journalType
+			conf.SecondaryWriteJournalDirectory = t.TempDir()
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			writeTimeout := &message.WriteTimeout{
+				ErrorMessage: "write timeout", Consistency: primitive.ConsistencyLevelQuorum, WriteType: primitive.WriteTypeSimple}
+			originQueries := int32(0)
+			targetQueries := int32(0)
+			replayedTimestamp := int64(0)
+			targetHandler := newRequestRetryHandler(&targetQueries, 1, writeTimeout)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newRequestRetryHandler(&originQueries, 0, writeTimeout),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+					response := targetHandler(request, conn, ctx)
+					query, ok := request.Body.Message.(*message.Query)
+					if response != nil && ok && query.Options != nil && query.Options.DefaultTimestamp != nil {
+						atomic.StoreInt64(&replayedTimestamp, query.Options.DefaultTimestamp.Value)
+					}
+					return response
+				},
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			sentAt := time.Now()
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(frame.NewFrame(
+				primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"}))
+			require.Nil(t, err)
+			require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt32(&targetQueries) == 2
+			}, 5*time.Second, 10*time.Millisecond)
+			timestamp := time.Unix(0, atomic.LoadInt64(&replayedTimestamp)*int64(time.Microsecond))
+			require.True(t, timestamp.After(sentAt.Add(-time.Second)) && timestamp.Before(sentAt.Add(time.Second)),
+				"unexpected default timestamp %v of the replayed write", timestamp)
+
+			require.Eventually(t, func() bool {
+				metricValues := getProxyMetricValues(t, testSetup)
+				return metricValues["zdm_proxy_write_journal_replayed_total"] == "1"
+			}, 2*time.Second, 10*time.Millisecond)
+			metricValues := getProxyMetricValues(t, testSetup)
+			require.Equal(t, "1", metricValues["zdm_proxy_secondary_write_failures_total"])
+			require.Equal(t, "1", metricValues["zdm_proxy_write_journal_queued_total"])
+			require.Equal(t, "0", metricValues["zdm_proxy_write_journal_dropped_total"])
+			require.Equal(t, "0", metricValues["zdm_proxy_write_journal_pending_entries"])
+			require.Equal(t, int32(1), atomic.LoadInt32(&originQueries))
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package setup
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// leakCheckTimeout is how long the goroutines and file descriptors that were created during a test have to go away
+// after the cleanup of the test setup.
+var leakCheckTimeout = 10 * time.Second
+
+// ignoredLeakFunctions are the functions of the goroutines that are started on first use and never stop.
+var ignoredLeakFunctions = []string{
+	"os/signal.signal_recv",
+	"os/signal.loop",
+}
+
+// resourceSnapshot holds the goroutines and the open file descriptors of the test process at the start of a test so
+// that the cleanup of the test setup can fail the test if some of the goroutines or file descriptors that were created
+// during the test are still there, i.e. if the proxy, the clusters or the clients were not shut down properly.
+type resourceSnapshot struct {
+	goroutines map[string]bool
+	fds        map[string]string
+}
+
+// takeResourceSnapshot returns nil if the leak checks are disabled (CHECK_LEAKS).
+func takeResourceSnapshot() *resourceSnapshot {
+	if !env.CheckLeaks {
+		return nil
+	}
+	goroutines := make(map[string]bool)
+	for id := range goroutineStacks() {
+		goroutines[id] = true
+	}
+	return &resourceSnapshot{
+		goroutines: goroutines,
+		fds:        openFileDescriptors(),
+	}
+}
+
+// assertNoLeaks fails the test with the stacks of the leaked goroutines and the targets of the leaked file
+// descriptors if they are not gone after leakCheckTimeout.
+func (recv *resourceSnapshot) assertNoLeaks(t *testing.T) {
+	if recv == nil {
+		return
+	}
+	t.Helper()
+
+	var leakedGoroutines, leakedFds []string
+	deadline := time.Now().Add(leakCheckTimeout)
+	for {
+		leakedGoroutines = recv.leakedGoroutines()
+		leakedFds = recv.leakedFileDescriptors()
+		if (len(leakedGoroutines) == 0 && len(leakedFds) == 0) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if len(leakedGoroutines) > 0 {
+		t.Errorf("%v goroutines leaked by the test:\n\n%v", len(leakedGoroutines), strings.Join(leakedGoroutines, "\n\n"))
+	}
+	if len(leakedFds) > 0 {
+		t.Errorf("%v file descriptors leaked by the test:\n%v", len(leakedFds), strings.Join(leakedFds, "\n"))
+	}
+}
+
+func (recv *resourceSnapshot) leakedGoroutines() []string {
+	var leaked []string
+	for id, stack := range goroutineStacks() {
+		if recv.goroutines[id] || isIgnoredGoroutine(stack) {
+			continue
+		}
+		leaked = append(leaked, stack)
+	}
+	sort.Strings(leaked)
+	return leaked
+}
+
+func (recv *resourceSnapshot) leakedFileDescriptors() []string {
+	var leaked []string
+	for fd, target := range openFileDescriptors() {
+		if previousTarget, ok := recv.fds[fd]; ok && previousTarget == target {
+			continue
+		}
+		leaked = append(leaked, fmt.Sprintf("%v -> %v", fd, target))
+	}
+	sort.Strings(leaked)
+	return leaked
+}
+
+// goroutineStacks returns the stacks of all goroutines except the current one keyed by goroutine id.
+func goroutineStacks() map[string]string {
+	buf := make([]byte, 1024*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	// the first stack is the one of the current goroutine
+	stacks := make(map[string]string)
+	for i, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if i == 0 {
+			continue
+		}
+		header := strings.Fields(string(stack))
+		if len(header) < 2 || header[0] != "goroutine" {
+			continue
+		}
+		stacks[header[1]] = string(stack)
+	}
+	return stacks
+}
+
+func isIgnoredGoroutine(stack string) bool {
+	for _, function := range ignoredLeakFunctions {
+		if strings.Contains(stack, function) {
+			return true
+		}
+	}
+	return false
+}
+
+// openFileDescriptors returns the targets of the open file descriptors keyed by file descriptor, it returns an empty
+// map on platforms without /proc.
+func openFileDescriptors() map[string]string {
+	fds := make(map[string]string)
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return fds
+	}
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", entry.Name()))
+		if err != nil {
+			// the file descriptor that was used to read the directory is closed already
+			continue
+		}
+		fds[entry.Name()] = target
+	}
+	return fds
+}
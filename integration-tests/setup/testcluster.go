@@ -120,6 +120,9 @@ type SimulacronTestSetup struct {
 	Origin *simulacron.Cluster
 	Target *simulacron.Cluster
 	Proxy  *zdmproxy.ZdmProxy
+
+	t         *testing.T
+	resources *resourceSnapshot
 }
 
 func NewSimulacronTestSetupWithSession(t *testing.T, createProxy bool, createSession bool) (*SimulacronTestSetup, error) {
@@ -146,6 +149,7 @@ func NewSimulacronTestSetupWithSessionAndNodesAndConfig(t *testing.T, createProx
 	if err != nil {
 		log.Panic("simulacron target startup failed: ", err)
 	}
+	resources := takeResourceSnapshot()
 	var proxyInstance *zdmproxy.ZdmProxy
 	if createProxy {
 		if config == nil {
@@ -162,9 +166,11 @@ func NewSimulacronTestSetupWithSessionAndNodesAndConfig(t *testing.T, createProx
 		proxyInstance = nil
 	}
 	return &SimulacronTestSetup{
-		Origin: origin,
-		Target: target,
-		Proxy:  proxyInstance,
+		Origin:    origin,
+		Target:    target,
+		Proxy:     proxyInstance,
+		t:         t,
+		resources: resources,
 	}, nil
 }
 
@@ -190,15 +196,21 @@ func (setup *SimulacronTestSetup) Cleanup() {
 	if err != nil {
 		log.Errorf("remove origin simulacron cluster error: %s", err)
 	}
+
+	simulacron.CloseIdleConnections()
+	setup.resources.assertNoLeaks(setup.t)
 }
 
 type CcmTestSetup struct {
 	Origin *ccm.Cluster
 	Target *ccm.Cluster
 	Proxy  *zdmproxy.ZdmProxy
+
+	t         *testing.T
+	resources *resourceSnapshot
 }
 
-func NewTemporaryCcmTestSetup(start bool, createProxy bool) (*CcmTestSetup, error) {
+func NewTemporaryCcmTestSetup(t *testing.T, start bool, createProxy bool) (*CcmTestSetup, error) {
 	firstClusterId := env.Rand.Uint64() % (math.MaxUint64 - 1)
 	origin, err := ccm.GetNewCluster(firstClusterId, 20, env.OriginNodes, start)
 	if err != nil {
@@ -211,6 +223,7 @@ func NewTemporaryCcmTestSetup(start bool, createProxy bool) (*CcmTestSetup, erro
 		origin.Remove()
 		return nil, err
 	}
+	resources := takeResourceSnapshot()
 
 	var proxyInstance *zdmproxy.ZdmProxy
 	if createProxy {
@@ -223,9 +236,11 @@ func NewTemporaryCcmTestSetup(start bool, createProxy bool) (*CcmTestSetup, erro
 	}
 
 	return &CcmTestSetup{
-		Origin: origin,
-		Target: target,
-		Proxy:  proxyInstance,
+		Origin:    origin,
+		Target:    target,
+		Proxy:     proxyInstance,
+		t:         t,
+		resources: resources,
 	}, nil
 }
 
@@ -263,6 +278,8 @@ func (setup *CcmTestSetup) Cleanup() {
 	if err != nil {
 		log.Errorf("remove origin ccm cluster error: %s", err)
 	}
+
+	setup.resources.assertNoLeaks(setup.t)
 }
 
 type CqlServerTestSetup struct {
@@ -270,12 +287,16 @@ type CqlServerTestSetup struct {
 	Target *cqlserver.Cluster
 	Proxy  *zdmproxy.ZdmProxy
 	Client *cqlserver.Client
+
+	t         *testing.T
+	resources *resourceSnapshot
 }
 
 func NewCqlServerTestSetup(t *testing.T, conf *config.Config, start bool, createProxy bool, connectClient bool) (*CqlServerTestSetup, error) {
 	if !env.RunMockTests {
 		t.Skip("Skipping CQLServer tests, RUN_MOCKTESTS is false")
 	}
+	resources := takeResourceSnapshot()
 	origin, err := cqlserver.NewCqlServerCluster(conf.OriginContactPoints, conf.OriginPort,
 		conf.OriginUsername, conf.OriginPassword, start)
 	if err != nil {
@@ -328,10 +349,12 @@ func NewCqlServerTestSetup(t *testing.T, conf *config.Config, start bool, create
 	}
 
 	return &CqlServerTestSetup{
-		Origin: origin,
-		Target: target,
-		Proxy:  proxyInstance,
-		Client: cqlClient,
+		Origin:    origin,
+		Target:    target,
+		Proxy:     proxyInstance,
+		Client:    cqlClient,
+		t:         t,
+		resources: resources,
 	}, nil
 }
 
@@ -374,6 +397,8 @@ func (setup *CqlServerTestSetup) Cleanup() {
 	if err != nil {
 		log.Errorf("close origin cql server error: %s", err)
 	}
+
+	setup.resources.assertNoLeaks(setup.t)
 }
 
 func NewProxyInstance(origin TestCluster, target TestCluster) (*zdmproxy.ZdmProxy, error) {
@@ -387,9 +412,11 @@ func NewProxyInstanceWithConfig(config *config.Config) (*zdmproxy.ZdmProxy, erro
 func NewTestConfig(originHost string, targetHost string) *config.Config {
 	conf := config.New()
 
+	conf.ProxyTopologyVirtualizationEnabled = true
 	conf.ProxyTopologyIndex = 0
 	conf.ProxyTopologyAddresses = ""
 	conf.ProxyTopologyNumTokens = 8
+	conf.InterceptedQueriesCacheTtlMs = 1000
 
 	conf.OriginEnableHostAssignment = true
 	conf.TargetEnableHostAssignment = true
@@ -405,6 +432,8 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 	conf.TargetPort = 9042
 
 	conf.ForwardClientCredentialsToOrigin = false
+	conf.OriginCredentialsMode = "AUTO"
+	conf.TargetCredentialsMode = "AUTO"
 
 	conf.MetricsAddress = "localhost"
 	conf.MetricsPort = 14001
@@ -413,18 +442,29 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 
 	conf.OriginConnectionTimeoutMs = 30000
 	conf.TargetConnectionTimeoutMs = 30000
+	conf.OriginConnectionAttemptDelayMs = 250
+	conf.TargetConnectionAttemptDelayMs = 250
 	conf.HeartbeatIntervalMs = 30000
 
 	conf.HeartbeatRetryIntervalMaxMs = 30000
 	conf.HeartbeatRetryIntervalMinMs = 250
 	conf.HeartbeatRetryBackoffFactor = 2
 	conf.HeartbeatFailureThreshold = 1
+	conf.HeartbeatClusterConnectionsEnabled = true
+	conf.HeartbeatMaxMissed = 3
 
 	conf.MetricsOriginLatencyBucketsMs = "1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000"
 	conf.MetricsTargetLatencyBucketsMs = "1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000"
 	conf.MetricsAsyncReadLatencyBucketsMs = "1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000"
 
 	conf.MetricsEnabled = true
+	conf.MetricsBackend = config.MetricsBackendPrometheus
+	conf.MetricsStatsdAddress = "localhost:8125"
+	conf.MetricsStatsdFlushIntervalMs = 1000
+	conf.MetricsStatsdPrefix = "zdm"
+	conf.MetricsStatsdTagsEnabled = true
+	conf.MigrationProgressSampleIntervalMs = 10000
+	conf.MigrationProgressWindowMs = 300000
 
 	conf.RequestWriteQueueSizeFrames = 128
 	conf.RequestWriteBufferSizeBytes = 4096
@@ -450,10 +490,65 @@ func NewTestConfig(originHost string, targetHost string) *config.Config {
 	conf.ReadMode = config.ReadModePrimaryOnly
 	conf.SystemQueriesMode = config.SystemQueriesModeOrigin
 	conf.AsyncHandshakeTimeoutMs = 4000
+	conf.TargetWriteSamplingPercentage = 100
+	conf.WriteComparisonSamplePercentage = 0
+	conf.DualWriteMode = config.DualWriteModeSync
+	conf.DualWriteAsyncTimeoutMs = 10000
+	conf.DualWriteAsyncMaxRetries = 3
+	conf.LwtPolicy = config.LwtPolicyDualWrite
+	conf.CounterWritesPolicy = config.CounterWritesPolicyDualWrite
+	conf.PagingStatePolicy = config.PagingStatePolicyNone
+	conf.SecondaryWriteFailureMode = config.SecondaryWriteFailureModeReturnError
+	conf.SecondaryWriteReplayDelayMs = 1000
+	conf.SecondaryWriteJournalType = config.SecondaryWriteJournalTypeNone
+	conf.SecondaryWriteJournalMaxSizeBytes = 104857600
+	conf.SecondaryWriteJournalReplayRate = 100
+	conf.TargetDdlCompatibilityMode = config.TargetDdlCompatibilityModeWarn
+	conf.MaterializedViewStatementsPolicy = config.SchemaStatementPolicyBoth
+	conf.IndexStatementsPolicy = config.SchemaStatementPolicyBoth
+	conf.CustomIndexStatementsPolicy = config.SchemaStatementPolicyBoth
+	conf.DestructiveStatementsConfirmationEnabled = false
+	conf.DestructiveStatementsConfirmationTokenTtlMs = 300000
+	conf.DestructiveStatementsMaxUnlockDurationMs = 3600000
+	conf.TrafficCaptureMaxDurationMs = 3600000
+	conf.TrafficCaptureMaxFileSizeBytes = 104857600
+	conf.OriginLatencyBudgetPercentile = 99
+	conf.OriginLatencyBudgetWindowMs = 60000
+	conf.TargetLatencyBudgetPercentile = 99
+	conf.TargetLatencyBudgetWindowMs = 60000
+	conf.OriginRequestRetryBackoffMinMs = 100
+	conf.OriginRequestRetryBackoffMaxMs = 1000
+	conf.TargetRequestRetryBackoffMinMs = 100
+	conf.TargetRequestRetryBackoffMaxMs = 1000
+	conf.OriginCircuitBreakerMinRequests = 20
+	conf.OriginCircuitBreakerWindowMs = 10000
+	conf.OriginCircuitBreakerOpenMs = 30000
+	conf.TargetCircuitBreakerMinRequests = 20
+	conf.TargetCircuitBreakerWindowMs = 10000
+	conf.TargetCircuitBreakerOpenMs = 30000
+	conf.StallDetectionThresholdMs = 30000
+	conf.StallDiagnosticsIntervalMs = 3600000
+	conf.TracingSampler = config.TracingSamplerParentBasedAlwaysOn
+	conf.TracingSamplerRatio = 1
+	conf.TracingServiceName = "zdm-proxy"
+	conf.SlowQueryLogQueryFormat = config.SlowQueryFormatObfuscated
+	conf.SlowQueryLogSampleRatio = 1
+	conf.SlowQueryLogMaxPerSecond = 10
+	conf.AuditLogQueryFormat = config.SlowQueryFormatDigest
+	conf.AuditLogFilePath = "zdm-proxy-audit.log"
+	conf.AuditLogFileMaxSizeBytes = 104857600
+	conf.AuditLogFileMaxBackups = 5
+	conf.ProxyTcpNoDelay = true
+	conf.OriginTcpNoDelay = true
+	conf.TargetTcpNoDelay = true
+	conf.ProxyMaxProtocolVersion = 5
+	conf.ProxyClusterConnectionsPerClient = 1
+	conf.ProxyClusterConnectionDispatchPolicy = config.ConnectionDispatchPolicyRoundRobin
 
 	conf.ProxyRequestTimeoutMs = 10000
 
 	conf.LogLevel = "INFO"
+	conf.LogFormat = config.LogFormatText
 
 	return conf
 }
@@ -8,10 +8,10 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
-	"github.com/datastax/zdm-proxy/integration-tests/client"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/simulacron"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
 	"github.com/rs/zerolog"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -219,7 +219,7 @@ func TestStressShutdown(t *testing.T) {
 				zerolog.SetGlobalLevel(zerolog.WarnLevel)
 				defer zerolog.SetGlobalLevel(oldZeroLogLevel)
 
-				cqlConn, err := client.NewTestClientWithRequestTimeout(context.Background(), "127.0.0.1:14002", 10*time.Second)
+				cqlConn, err := testclient.NewTestClientWithRequestTimeout(context.Background(), "127.0.0.1:14002", 10*time.Second)
 				require.Nil(t, err)
 				defer cqlConn.Shutdown()
 
@@ -250,7 +250,7 @@ func TestStressShutdown(t *testing.T) {
 						for {
 							id := rand.Int()
 							connTimeoutCtx, connTimeoutCancelFn := context.WithTimeout(globalCtx, 5*time.Second)
-							tempCqlConn, err := client.NewTestClientWithRequestTimeout(connTimeoutCtx, "127.0.0.1:14002", 10*time.Second)
+							tempCqlConn, err := testclient.NewTestClientWithRequestTimeout(connTimeoutCtx, "127.0.0.1:14002", 10*time.Second)
 							connTimeoutCancelFn() // avoid context leak
 
 							// create new waitgroup dedicated for this "iteration" so the next iteration starts only
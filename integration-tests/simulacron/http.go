@@ -100,3 +100,9 @@ func (process *Process) execHttp(method string, url string, body interface{}) ([
 
 	return bytes, nil
 }
+
+// CloseIdleConnections closes the connections to the simulacron HTTP API that are not in use so that they are not
+// reported as leaks by the test setups.
+func CloseIdleConnections() {
+	httpClient.CloseIdleConnections()
+}
@@ -0,0 +1,107 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStatementFilter(t *testing.T) {
+
+	type test struct {
+		name                    string
+		query                   string
+		expectedOriginForwarded int32
+		expectedTargetForwarded int32
+		expectedRejectedMsg     string
+	}
+
+	tests := []test{
+		{
+			name:                "truncate",
+			query:               "TRUNCATE ks.tb",
+			expectedRejectedMsg: "Statement rejected by ZDM proxy (ZDM_STATEMENT_FILTER_RULES: TRUNCATE REJECT)",
+		},
+		{
+			name:                "drop table",
+			query:               "DROP TABLE IF EXISTS ks.tb",
+			expectedRejectedMsg: "Statement rejected by ZDM proxy (ZDM_STATEMENT_FILTER_RULES: DROP REJECT)",
+		},
+		{
+			name:                    "delete routed to origin",
+			query:                   "DELETE FROM ks.events WHERE a = 1",
+			expectedOriginForwarded: 1,
+			expectedTargetForwarded: 0,
+		},
+		{
+			name:                    "delete of another table",
+			query:                   "DELETE FROM ks.tb WHERE a = 1",
+			expectedOriginForwarded: 1,
+			expectedTargetForwarded: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.StatementFilterRules = "TRUNCATE REJECT; DROP REJECT; DELETE ks.events ORIGIN"
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originForwarded := int32(0)
+			targetForwarded := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newFilteredStatementHandler(&originForwarded),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newFilteredStatementHandler(&targetForwarded),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			if tt.expectedRejectedMsg != "" {
+				require.IsType(t, &message.Invalid{}, response.Body.Message)
+				require.Equal(t, tt.expectedRejectedMsg, response.Body.Message.(*message.Invalid).ErrorMessage)
+
+				response, err = testSetup.Client.CqlConnection.SendAndReceive(
+					frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Prepare{Query: tt.query}))
+				require.Nil(t, err)
+				require.IsType(t, &message.Invalid{}, response.Body.Message)
+				require.Equal(t, tt.expectedRejectedMsg, response.Body.Message.(*message.Invalid).ErrorMessage)
+			} else {
+				require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			}
+			require.Equal(t, tt.expectedOriginForwarded, atomic.LoadInt32(&originForwarded))
+			require.Equal(t, tt.expectedTargetForwarded, atomic.LoadInt32(&targetForwarded))
+		})
+	}
+}
+
+func newFilteredStatementHandler(forwarded *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok {
+			return nil
+		}
+		switch strings.Fields(query.Query)[0] {
+		case "TRUNCATE", "DROP", "DELETE":
+		default:
+			return nil
+		}
+		atomic.AddInt32(forwarded, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
@@ -0,0 +1,98 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const statementMetricsPreparedQuery = "SELECT * FROM ks.tb WHERE k = ?"
+
+// TestStatementDurationMetrics tests that the latency of the requests is tracked by statement type and by
+// cluster(s) that the requests were forwarded to, including the EXECUTE requests of the prepared statements.
+func TestStatementDurationMetrics(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newStatementMetricsHandler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newStatementMetricsHandler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(frame.NewFrame(
+		primitive.ProtocolVersion4, client.ManagedStreamId, &message.Prepare{Query: statementMetricsPreparedQuery}))
+	require.Nil(t, err)
+	prepared, ok := response.Body.Message.(*message.PreparedResult)
+	require.True(t, ok, "expected PreparedResult but got %v", response.Body.Message)
+
+	requests := []message.Message{
+		&message.Execute{QueryId: prepared.PreparedQueryId, Options: &message.QueryOptions{
+			PositionalValues: []*primitive.Value{primitive.NewValue([]byte{0, 0, 0, 1})}}},
+		&message.Query{Query: "SELECT * FROM ks.tb"},
+		&message.Query{Query: "INSERT INTO ks.tb (k, v) VALUES (1, 'a')"},
+	}
+	for _, request := range requests {
+		_, err = testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, request))
+		require.Nil(t, err)
+	}
+
+	expected := map[string]string{
+		`zdm_proxy_statement_request_duration_seconds_count{forwarded_to="origin",statement_type="read"}`: "2",
+		`zdm_proxy_statement_request_duration_seconds_count{forwarded_to="both",statement_type="write"}`:  "1",
+		`zdm_proxy_statement_request_duration_seconds_count{forwarded_to="target",statement_type="read"}`: "0",
+	}
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		values := getProxyMetricValues(t, testSetup)
+		for name, expectedValue := range expected {
+			if values[name] != expectedValue {
+				return fmt.Errorf("expected %v to be %v but got %v", name, expectedValue, values[name]), false
+			}
+		}
+		return nil, false
+	}, 50, 100*time.Millisecond)
+}
+
+func newStatementMetricsHandler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		switch msg := request.Body.Message.(type) {
+		case *message.Prepare:
+			if msg.Query != statementMetricsPreparedQuery {
+				return nil
+			}
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.PreparedResult{
+				PreparedQueryId:   []byte("statement_metrics"),
+				VariablesMetadata: &message.VariablesMetadata{},
+				ResultMetadata:    &message.RowsMetadata{},
+			})
+		case *message.Execute:
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+				Metadata: &message.RowsMetadata{}})
+		case *message.Query:
+			if msg.Query == "SELECT * FROM ks.tb" {
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.RowsResult{
+					Metadata: &message.RowsMetadata{}})
+			}
+			if msg.Query == "INSERT INTO ks.tb (k, v) VALUES (1, 'a')" {
+				return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+			}
+		}
+		return nil
+	}
+}
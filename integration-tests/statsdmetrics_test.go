@@ -0,0 +1,88 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStatsdMetrics tests that the metrics are sent to the StatsD server with DogStatsD tags when ZDM_METRICS_BACKEND
+// is STATSD.
+func TestStatsdMetrics(t *testing.T) {
+	statsdConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer statsdConn.Close()
+	lock := &sync.Mutex{}
+	var lines []string
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, _, err := statsdConn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			lock.Lock()
+			lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+			lock.Unlock()
+		}
+	}()
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.MetricsBackend = config.MetricsBackendStatsd
+	conf.MetricsStatsdAddress = statsdConn.LocalAddr().String()
+	conf.MetricsStatsdFlushIntervalMs = 100
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newTableMetricsHandler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newTableMetricsHandler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	_, err = testSetup.Client.CqlConnection.SendAndReceive(
+		frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "SELECT * FROM ks.tb"}))
+	require.Nil(t, err)
+
+	expected := []*regexp.Regexp{
+		regexp.MustCompile(`^zdm\.client_connections_total:1\|g$`),
+		regexp.MustCompile(`^zdm\.proxy_request_duration_seconds:\d+\.\d{3}\|ms\|#type:reads_origin$`),
+		regexp.MustCompile(`^zdm\.origin_request_duration_seconds:\d+\.\d{3}\|ms\|#node:127\.0\.1\.1:\d+$`),
+	}
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, expectedLine := range expected {
+			found := false
+			for _, line := range lines {
+				if expectedLine.MatchString(line) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("no line matches %v in %v", expectedLine, lines), false
+			}
+		}
+		return nil, false
+	}, 50, 100*time.Millisecond)
+}
@@ -0,0 +1,153 @@
+package integration_tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/env"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/simulacron"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const (
+	stressReadQuery  = "SELECT * FROM ks.stress"
+	stressWriteQuery = "INSERT INTO ks.stress (k, v) VALUES (1, 'value')"
+)
+
+// TestStressClientHandler drives a single proxy with thousands of concurrent streams across many connections while
+// runtime settings are toggled and nodes are bounced, it is meant to be run with -race to flush out data races in the
+// request and response pipelines of the client handler and cluster connectors:
+//
+//	go test -race -v ./integration-tests -RUN_STRESS_TESTS=true -run TestStressClientHandler
+func TestStressClientHandler(t *testing.T) {
+	if !env.RunStressTests {
+		t.Skip("Skipping stress tests, set RUN_STRESS_TESTS to true")
+	}
+
+	c := setup.NewTestConfig("", "")
+	c.ReadMode = config.ReadModeDualAsyncOnSecondary
+	c.TrafficCaptureDirectory = t.TempDir()
+	testSetup, err := setup.NewSimulacronTestSetupWithSessionAndNodesAndConfig(t, true, false, 3, c)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	rows := simulacron.NewRowsResult(map[string]simulacron.DataType{
+		"k": simulacron.DataTypeInt,
+		"v": simulacron.DataTypeText,
+	}).WithRow(map[string]interface{}{
+		"k": 1,
+		"v": "value",
+	})
+	for _, cluster := range []*simulacron.Cluster{testSetup.Origin, testSetup.Target} {
+		err = cluster.Prime(simulacron.WhenQuery(stressReadQuery, simulacron.NewWhenQueryOptions()).ThenRowsSuccess(rows))
+		require.Nil(t, err)
+	}
+
+	newRequest := func(stream int, iteration int) message.Message {
+		if (stream+iteration)%2 == 0 {
+			return &message.Query{Query: stressReadQuery}
+		}
+		return &message.Query{Query: stressWriteQuery}
+	}
+
+	trafficCapture := testSetup.Proxy.GetTrafficCapture()
+	frameLogger := testSetup.Proxy.GetFrameLogger()
+	bounceNode := func(cluster *simulacron.Cluster, iteration int) error {
+		// the first node is the contact point of the control connection
+		node := cluster.Datacenters[0].Nodes[1+iteration%(len(cluster.Datacenters[0].Nodes)-1)]
+		err := node.DisableConnectionListener()
+		if err != nil {
+			return err
+		}
+		err = node.DropAllConnections()
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Second)
+		return node.EnableConnectionListener()
+	}
+
+	result := utils.RunStress(context.Background(), &utils.StressOptions{
+		Address:              testSetup.Proxy.GetListenAddr().String(),
+		ProtocolVersion:      primitive.ProtocolVersion4,
+		Connections:          20,
+		StreamsPerConnection: 100,
+		RequestTimeout:       5 * time.Second,
+		Duration:             30 * time.Second,
+		NewRequest:           newRequest,
+		Disruptions: []*utils.StressDisruption{
+			{
+				Name:     "traffic capture",
+				Interval: 500 * time.Millisecond,
+				Run: func(iteration int) error {
+					if iteration%2 == 1 {
+						trafficCapture.Stop()
+						return nil
+					}
+					_, err := trafficCapture.Start(time.Minute, nil, iteration%4 == 0)
+					return err
+				},
+			},
+			{
+				Name:     "frame logging",
+				Interval: 300 * time.Millisecond,
+				Run: func(iteration int) error {
+					// a client that doesn't exist so that the requests of the stress run are not logged
+					var err error
+					if iteration%2 == 0 {
+						_, err = frameLogger.Enable([]string{"127.0.0.1:1"}, time.Minute)
+					} else {
+						_, err = frameLogger.Disable(nil)
+					}
+					return err
+				},
+			},
+			{
+				Name:     "bounce origin node",
+				Interval: 3 * time.Second,
+				Run: func(iteration int) error {
+					return bounceNode(testSetup.Origin, iteration)
+				},
+			},
+			{
+				Name:     "bounce target node",
+				Interval: 4 * time.Second,
+				Run: func(iteration int) error {
+					return bounceNode(testSetup.Target, iteration)
+				},
+			},
+		},
+	})
+	trafficCapture.Stop()
+	t.Logf("Stress run: %v", result)
+
+	require.Empty(t, result.DisruptionErrors)
+	require.Greater(t, result.Requests-result.FailedRequests, int64(0))
+
+	// the proxy keeps serving requests after the stress run
+	utils.RequireWithRetries(t, func() (error, bool) {
+		testClient, err := testclient.Connect(
+			context.Background(), testSetup.Proxy.GetListenAddr().String(), primitive.ProtocolVersion4, "", "", nil)
+		if err != nil {
+			return err, false
+		}
+		defer testClient.Shutdown()
+		for i := 0; i < 100; i++ {
+			response, _, err := testClient.SendMessage(context.Background(), primitive.ProtocolVersion4, newRequest(0, i))
+			if err != nil {
+				return err, false
+			}
+			if errMsg, ok := response.Body.Message.(message.Error); ok {
+				return fmt.Errorf("request %v failed: %v", i, errMsg), false
+			}
+		}
+		return nil, false
+	}, 10, time.Second)
+}
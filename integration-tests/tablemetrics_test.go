@@ -0,0 +1,82 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTableMetrics tests that the requests are tracked by keyspace and table when ZDM_METRICS_TABLE_LEVEL_ENABLED
+// is set and that the tables after ZDM_METRICS_TABLE_LEVEL_MAX_TABLES are tracked under the "other" table.
+func TestTableMetrics(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.MetricsTableLevelEnabled = true
+	conf.MetricsTableLevelMaxTables = 1
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newTableMetricsHandler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newTableMetricsHandler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	queries := []string{
+		"SELECT * FROM ks.tb",
+		"SELECT * FROM ks.tb WHERE k = 1",
+		"INSERT INTO ks.tb (k, v) VALUES (1, 'a')",
+		"SELECT * FROM ks.tb2",
+	}
+	for _, query := range queries {
+		_, err = testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: query}))
+		require.Nil(t, err)
+	}
+
+	expected := map[string]string{
+		`zdm_proxy_table_requests_total{forwarded_to="origin",keyspace="ks",table="tb"}`:                 "2",
+		`zdm_proxy_table_requests_total{forwarded_to="both",keyspace="ks",table="tb"}`:                   "1",
+		`zdm_proxy_table_requests_total{forwarded_to="origin",keyspace="other",table="other"}`:           "1",
+		`zdm_proxy_table_request_duration_seconds_count{forwarded_to="origin",keyspace="ks",table="tb"}`: "2",
+		`zdm_proxy_table_request_duration_seconds_count{forwarded_to="both",keyspace="ks",table="tb"}`:   "1",
+	}
+	utils.RequireWithRetries(t, func() (err error, fatal bool) {
+		values := getProxyMetricValues(t, testSetup)
+		for name, expectedValue := range expected {
+			if values[name] != expectedValue {
+				return fmt.Errorf("expected %v to be %v but got %v", name, expectedValue, values[name]), false
+			}
+		}
+		for name := range values {
+			if strings.Contains(name, `table="tb2"`) {
+				return fmt.Errorf("unexpected metric %v after the maximum number of tables", name), true
+			}
+		}
+		return nil, false
+	}, 50, 100*time.Millisecond)
+}
+
+func newTableMetricsHandler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.Contains(query.Query, "ks.tb") {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
@@ -0,0 +1,74 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestTableRoutingRules tests that the statements on the tables of ZDM_TABLE_ROUTING_RULES are only forwarded to
+// the cluster of the rule.
+func TestTableRoutingRules(t *testing.T) {
+
+	type test struct {
+		name                string
+		query               string
+		expectedOriginCount int32
+		expectedTargetCount int32
+	}
+
+	tests := []test{
+		{"write of pinned table", "INSERT INTO ks.legacy (a) VALUES (1)", 1, 0},
+		{"read of pinned table", "SELECT * FROM ks.legacy", 1, 0},
+		{"read of pinned system table", "SELECT * FROM system_auth.roles", 0, 1},
+		{"write of other table", "INSERT INTO ks.tb (a) VALUES (1)", 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.TableRoutingRules = "ks.legacy ORIGIN; system_auth.roles TARGET"
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originQueries := int32(0)
+			targetQueries := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newTableRoutingCountHandler(&originQueries),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newTableRoutingCountHandler(&targetQueries),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			require.Equal(t, tt.expectedOriginCount, atomic.LoadInt32(&originQueries))
+			require.Equal(t, tt.expectedTargetCount, atomic.LoadInt32(&targetQueries))
+		})
+	}
+}
+
+func newTableRoutingCountHandler(count *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !(strings.Contains(query.Query, " ks.") || strings.Contains(query.Query, " system_auth.")) {
+			return nil
+		}
+		atomic.AddInt32(count, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
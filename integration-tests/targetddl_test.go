@@ -0,0 +1,96 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTargetDdlCompatibility(t *testing.T) {
+
+	type test struct {
+		name                string
+		mode                string
+		query               string
+		expectedForwarded   int32
+		expectedRejectedMsg string
+	}
+
+	tests := []test{
+		{
+			name:              "compatible schema change",
+			mode:              config.TargetDdlCompatibilityModeReject,
+			query:             "CREATE TABLE ks.tb (a int PRIMARY KEY)",
+			expectedForwarded: 1,
+		},
+		{
+			name:              "incompatible schema change with warn mode",
+			mode:              config.TargetDdlCompatibilityModeWarn,
+			query:             "CREATE MATERIALIZED VIEW ks.mv AS SELECT * FROM ks.tb WHERE a IS NOT NULL PRIMARY KEY (a)",
+			expectedForwarded: 1,
+		},
+		{
+			name:              "incompatible schema change with reject mode",
+			mode:              config.TargetDdlCompatibilityModeReject,
+			query:             "CREATE MATERIALIZED VIEW ks.mv AS SELECT * FROM ks.tb WHERE a IS NOT NULL PRIMARY KEY (a)",
+			expectedForwarded: 0,
+			expectedRejectedMsg: "Schema change rejected by ZDM proxy because it is not compatible with TARGET: " +
+				"materialized views are not supported by TARGET (MATERIALIZED_VIEWS)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.TargetDdlUnsupportedFeatures = "MATERIALIZED_VIEWS"
+			conf.TargetDdlCompatibilityMode = tt.mode
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originForwarded := int32(0)
+			targetForwarded := int32(0)
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newSchemaChangeHandler(&originForwarded),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newSchemaChangeHandler(&targetForwarded),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: tt.query}))
+			require.Nil(t, err)
+			if tt.expectedRejectedMsg != "" {
+				require.IsType(t, &message.Invalid{}, response.Body.Message)
+				require.Equal(t, tt.expectedRejectedMsg, response.Body.Message.(*message.Invalid).ErrorMessage)
+			} else {
+				require.IsType(t, &message.VoidResult{}, response.Body.Message)
+			}
+			require.Equal(t, tt.expectedForwarded, atomic.LoadInt32(&originForwarded))
+			require.Equal(t, tt.expectedForwarded, atomic.LoadInt32(&targetForwarded))
+		})
+	}
+}
+
+func newSchemaChangeHandler(forwarded *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || !strings.HasPrefix(query.Query, "CREATE") {
+			return nil
+		}
+		atomic.AddInt32(forwarded, 1)
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
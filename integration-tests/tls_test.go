@@ -893,6 +893,39 @@ func TestTls_MutualOrigin_NoTarget(t *testing.T) {
 	}
 }
 
+// TestTls_DevMode tests that clients can connect to the proxy with the TLS material that it generates in dev mode
+func TestTls_DevMode(t *testing.T) {
+	for _, mutualTls := range []bool{false, true} {
+		t.Run(fmt.Sprintf("mutual TLS %v", mutualTls), func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.ProxyTlsDevModeEnabled = true
+			conf.ProxyTlsRequireClientAuth = mutualTls
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			caCert, clientCert, clientKey := testSetup.Proxy.GetDevModeTlsCertificates()
+			tlsConfig, err := getClientSideTlsConfigFromLoadedFiles(caCert, clientCert, clientKey, mutualTls, "localhost")
+			require.Nil(t, err)
+
+			testClient := client.NewCqlClient(testSetup.Proxy.GetListenAddr().String(),
+				&client.AuthCredentials{Username: conf.TargetUsername, Password: conf.TargetPassword})
+			testClient.TLSConfig = tlsConfig
+			cqlConn, err := testClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+			require.Nil(t, err)
+			defer cqlConn.Close()
+
+			noTlsClient := client.NewCqlClient(testSetup.Proxy.GetListenAddr().String(),
+				&client.AuthCredentials{Username: conf.TargetUsername, Password: conf.TargetPassword})
+			_, err = noTlsClient.ConnectAndInit(context.Background(), primitive.ProtocolVersion4, 1)
+			require.NotNil(t, err)
+		})
+	}
+}
+
 func skipNonEssentialTests(essentialTest bool, t *testing.T) {
 	if !essentialTest && !env.RunAllTlsTests {
 		t.Skip("Skipping this test. To run it, set the env var RUN_ALL_TLS_TESTS to true")
@@ -904,7 +937,7 @@ func setupOriginAndTargetClusters(clusterConf clusterTlsConfiguration, t *testin
 		t.Skip("Test requires CCM, set RUN_CCMTESTS env variable to TRUE")
 	}
 
-	ccmSetup, err := setup.NewTemporaryCcmTestSetup(false, false)
+	ccmSetup, err := setup.NewTemporaryCcmTestSetup(t, false, false)
 	if ccmSetup == nil {
 		return nil, fmt.Errorf("ccm setup could not be created and is nil")
 	}
@@ -0,0 +1,100 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestTracing tests that the spans of a write are exported to the OTLP endpoint with the trace context of the client
+// as parent and that the requests forwarded to the clusters carry the trace context of the proxy spans.
+func TestTracing(t *testing.T) {
+	spansLock := &sync.Mutex{}
+	var spans []*zdmproxy.OtlpSpan
+	server := httptest.NewServer(http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/traces", req.URL.Path)
+		var exportRequest *zdmproxy.OtlpExportRequest
+		require.Nil(t, json.NewDecoder(req.Body).Decode(&exportRequest))
+		spansLock.Lock()
+		defer spansLock.Unlock()
+		for _, resourceSpans := range exportRequest.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TracingOtlpEndpoint = server.URL
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	traceParentsLock := &sync.Mutex{}
+	traceParents := make(map[string]string)
+	newTraceParentHandler := func(cluster string) client.RequestHandler {
+		return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+			query, ok := request.Body.Message.(*message.Query)
+			if !ok || !strings.Contains(query.Query, " ks.tb ") {
+				return nil
+			}
+			traceParentsLock.Lock()
+			traceParents[cluster] = string(request.Body.CustomPayload["traceparent"])
+			traceParentsLock.Unlock()
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+	}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newTraceParentHandler("origin"),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newTraceParentHandler("target"),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	clientTraceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	query := frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId,
+		&message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"})
+	query.SetCustomPayload(map[string][]byte{"traceparent": []byte(clientTraceParent)})
+	response, err := testSetup.Client.CqlConnection.SendAndReceive(query)
+	require.Nil(t, err)
+	require.IsType(t, &message.VoidResult{}, response.Body.Message)
+
+	// the spans are exported when the proxy shuts down
+	testSetup.Proxy.Shutdown()
+	testSetup.Proxy = nil
+
+	spansByName := make(map[string]*zdmproxy.OtlpSpan)
+	for _, span := range spans {
+		if span.TraceId == "4bf92f3577b34da6a3ce929d0e0e4736" {
+			spansByName[span.Name] = span
+		}
+	}
+	require.Equal(t, 6, len(spansByName), spansByName)
+	root := spansByName["QUERY"]
+	require.Equal(t, "00f067aa0ba902b7", root.ParentSpanId)
+	for _, name := range []string{
+		"inspect request", "forward to ORIGIN", "forward to TARGET", "aggregate responses", "write response"} {
+		require.Equal(t, root.SpanId, spansByName[name].ParentSpanId, name)
+	}
+
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+spansByName["forward to ORIGIN"].SpanId+"-01",
+		traceParents["origin"])
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+spansByName["forward to TARGET"].SpanId+"-01",
+		traceParents["target"])
+}
@@ -0,0 +1,104 @@
+package integration_tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
+	"github.com/datastax/zdm-proxy/proxy/pkg/replay"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestTrafficCapture tests that the frames of the client connections are written to a capture file between the
+// start and the stop of a capture with the admin API.
+func TestTrafficCapture(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TrafficCaptureDirectory = t.TempDir()
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	callAdminApi := func(method string, path string) *zdmproxy.TrafficCaptureStatus {
+		recorder := httptest.NewRecorder()
+		admin.Handler(testSetup.Proxy).ServeHTTP(recorder, httptest.NewRequest(method, admin.TrafficCapturePath+path, nil))
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var status *zdmproxy.TrafficCaptureStatus
+		require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		return status
+	}
+	sendQuery := func() {
+		_, err := testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "SELECT * FROM system.local"}))
+		require.Nil(t, err)
+	}
+
+	require.Nil(t, callAdminApi(http.MethodGet, ""))
+	sendQuery()
+
+	status := callAdminApi(http.MethodPost, "/start?duration=1m&clients=127.0.0.1&redact=true")
+	require.True(t, status.Active)
+	require.Equal(t, []string{"127.0.0.1"}, status.Clients)
+	require.True(t, status.Redacted)
+	sendQuery()
+
+	status = callAdminApi(http.MethodPost, "/stop")
+	require.False(t, status.Active)
+	require.Equal(t, 2, status.Frames)
+	fileInfo, err := os.Stat(status.File)
+	require.Nil(t, err)
+	require.Equal(t, status.Bytes, fileInfo.Size())
+	sendQuery()
+	require.Equal(t, status, callAdminApi(http.MethodGet, ""))
+
+	recorder := httptest.NewRecorder()
+	admin.Handler(testSetup.Proxy).ServeHTTP(recorder, httptest.NewRequest(
+		http.MethodPost, admin.TrafficCapturePath+"/start?duration=2h", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// TestTrafficCapture_Replay tests that the requests of a capture file are replayed against a cluster.
+func TestTrafficCapture_Replay(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TrafficCaptureDirectory = t.TempDir()
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	capture := testSetup.Proxy.GetTrafficCapture()
+	_, err = capture.Start(time.Minute, nil, false)
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "SELECT * FROM system.local"}))
+		require.Nil(t, err)
+	}
+	status := capture.Stop()
+	require.Equal(t, 6, status.Frames)
+
+	capturedFrames, err := replay.ReadCaptureFiles([]string{status.File})
+	require.Nil(t, err)
+	require.Len(t, capturedFrames.Frames, 6)
+	require.Equal(t, "SELECT * FROM system.local", capturedFrames.Frames[0].Frame.Body.Message.(*message.Query).Query)
+	require.True(t, capturedFrames.Frames[1].Frame.Header.IsResponse)
+
+	result, err := replay.Replay(context.Background(), capturedFrames, &replay.Config{
+		Address:     testSetup.Target.InitialContactPoint,
+		Credentials: &client.AuthCredentials{Username: conf.TargetUsername, Password: conf.TargetPassword},
+	})
+	require.Nil(t, err)
+	require.Equal(t, &replay.Result{Connections: 1, Requests: 3, Errors: map[string]int{}}, result)
+}
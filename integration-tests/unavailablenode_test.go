@@ -6,9 +6,9 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
-	"github.com/datastax/zdm-proxy/integration-tests/client"
 	"github.com/datastax/zdm-proxy/integration-tests/setup"
 	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
 	"github.com/stretchr/testify/require"
 	"strings"
 	"testing"
@@ -25,7 +25,7 @@ func TestUnavailableNode(t *testing.T) {
 			require.Nil(t, err)
 			defer simulacronSetup.Cleanup()
 
-			testClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+			testClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 			require.True(t, err == nil, "testClient setup failed: %s", err)
 			defer testClient.Shutdown()
 
@@ -78,7 +78,7 @@ func TestUnavailableNode(t *testing.T) {
 			}
 
 			// open new connection to verify that the same proxy instance continues working normally
-			newTestClient, err := client.NewTestClient(context.Background(), "127.0.0.1:14002")
+			newTestClient, err := testclient.NewTestClient(context.Background(), "127.0.0.1:14002")
 			require.True(t, err == nil, "newTestClient setup failed: %s", err)
 			defer newTestClient.Shutdown()
 
@@ -0,0 +1,115 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	useKeyspaceTableQuery = "SELECT * FROM tb"
+	useKeyspaceLocalQuery = "SELECT * FROM local"
+)
+
+// TestUseKeyspaceSwitching tests that the unqualified table names of the statements are resolved against the keyspace
+// of the last successful USE statement of the client connection, including quoted (case-sensitive) keyspaces.
+func TestUseKeyspaceSwitching(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TableRoutingRules = "ks1.tb TARGET; \"MyKs\".tb TARGET"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originQueries := int32(0)
+	targetQueries := int32(0)
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		newUseKeyspaceHandler(&originQueries),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		newUseKeyspaceHandler(&targetQueries),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	sendQuery := func(query string) message.Message {
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: query}))
+		require.Nil(t, err)
+		return response.Body.Message
+	}
+
+	requireTableQueryRouting := func(useStatement string, expectedCluster string) {
+		if useStatement != "" {
+			require.IsType(t, &message.SetKeyspaceResult{}, sendQuery(useStatement), useStatement)
+		}
+		atomic.StoreInt32(&originQueries, 0)
+		atomic.StoreInt32(&targetQueries, 0)
+		require.IsType(t, &message.VoidResult{}, sendQuery(useKeyspaceTableQuery), useStatement)
+		expectedOriginQueries, expectedTargetQueries := int32(1), int32(0)
+		if expectedCluster == "target" {
+			expectedOriginQueries, expectedTargetQueries = 0, 1
+		}
+		require.Equal(t, expectedOriginQueries, atomic.LoadInt32(&originQueries), useStatement)
+		require.Equal(t, expectedTargetQueries, atomic.LoadInt32(&targetQueries), useStatement)
+	}
+
+	requireTableQueryRouting("", "origin")
+	requireTableQueryRouting("USE ks1", "target")
+	requireTableQueryRouting("USE ks2", "origin")
+	requireTableQueryRouting("USE \"MyKs\"", "target")
+	requireTableQueryRouting("USE MyKs", "origin")
+	requireTableQueryRouting("use \"ks1\";", "target")
+
+	// a failed USE statement does not change the keyspace
+	require.IsType(t, &message.Invalid{}, sendQuery("USE missing"))
+	requireTableQueryRouting("", "target")
+
+	// the unqualified system tables are intercepted
+	require.IsType(t, &message.SetKeyspaceResult{}, sendQuery("USE system"))
+	atomic.StoreInt32(&originQueries, 0)
+	atomic.StoreInt32(&targetQueries, 0)
+	require.IsType(t, &message.RowsResult{}, sendQuery(useKeyspaceLocalQuery))
+	require.Equal(t, int32(0), atomic.LoadInt32(&originQueries))
+	require.Equal(t, int32(0), atomic.LoadInt32(&targetQueries))
+}
+
+// newUseKeyspaceHandler returns a handler that replies to USE statements with the keyspace in its internal form
+// like Cassandra does and that counts the statements on unqualified tables.
+func newUseKeyspaceHandler(count *int32) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok {
+			return nil
+		}
+		switch query.Query {
+		case useKeyspaceTableQuery, useKeyspaceLocalQuery:
+			atomic.AddInt32(count, 1)
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+		}
+
+		statement := strings.TrimSuffix(strings.TrimSpace(query.Query), ";")
+		if len(statement) < 4 || !strings.EqualFold(statement[:4], "use ") {
+			return nil
+		}
+		keyspace := strings.TrimSpace(statement[4:])
+		if len(keyspace) > 1 && strings.HasPrefix(keyspace, "\"") && strings.HasSuffix(keyspace, "\"") {
+			keyspace = strings.ReplaceAll(keyspace[1:len(keyspace)-1], "\"\"", "\"")
+		} else {
+			keyspace = strings.ToLower(keyspace)
+		}
+		if keyspace == "missing" {
+			return frame.NewFrame(request.Header.Version, request.Header.StreamId,
+				&message.Invalid{ErrorMessage: "Keyspace 'missing' does not exist"})
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.SetKeyspaceResult{Keyspace: keyspace})
+	}
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StressOptions are the parameters of RunStress.
+type StressOptions struct {
+	Address         string
+	ProtocolVersion primitive.ProtocolVersion
+	Username        string
+	Password        string
+
+	// Connections is the number of client connections that are open at the same time.
+	Connections int
+
+	// StreamsPerConnection is the number of concurrent requests of each connection, it can't exceed the number of
+	// stream ids of the test client.
+	StreamsPerConnection int
+
+	// RequestTimeout uses the default timeout of the test client if zero.
+	RequestTimeout time.Duration
+
+	Duration time.Duration
+
+	// NewRequest returns the message of a request of a stream, it is called concurrently.
+	NewRequest func(stream int, iteration int) message.Message
+
+	// Disruptions run in the background while the requests are sent.
+	Disruptions []*StressDisruption
+}
+
+// StressDisruption is an action that is repeated every Interval during a stress run, e.g. toggling a runtime setting
+// or bouncing a node.
+type StressDisruption struct {
+	Name     string
+	Interval time.Duration
+	Run      func(iteration int) error
+}
+
+// StressResult is the outcome of a stress run.
+type StressResult struct {
+	Requests       int64
+	FailedRequests int64
+
+	// Connections is the number of connections that were opened, a connection is replaced by a new one when the
+	// test client returns an error
+	Connections int64
+
+	// number of failed requests by error code of the response, or "client error" if the test client returned an error
+	Errors map[string]int64
+
+	// number of errors by disruption name
+	DisruptionErrors map[string]int64
+}
+
+func (recv *StressResult) String() string {
+	return fmt.Sprintf("StressResult{Requests=%v, FailedRequests=%v, Connections=%v, Errors=%v, DisruptionErrors=%v}",
+		recv.Requests, recv.FailedRequests, recv.Connections, recv.Errors, recv.DisruptionErrors)
+}
+
+const stressClientError = "client error"
+
+// RunStress sends requests on many concurrent streams across many connections to the proxy for the duration of the
+// stress run, while the disruptions are applied in the background. The connections are replaced when the test client
+// returns an error (e.g. because the proxy closed the connection after a node went down) so that the load is kept
+// up during the whole run.
+func RunStress(ctx context.Context, options *StressOptions) *StressResult {
+	ctx, cancelFn := context.WithTimeout(ctx, options.Duration)
+	defer cancelFn()
+
+	stress := &stressRun{
+		options: options,
+		lock:    &sync.Mutex{},
+		result: &StressResult{
+			Errors:           make(map[string]int64),
+			DisruptionErrors: make(map[string]int64),
+		},
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < options.Connections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stress.runConnection(ctx)
+		}()
+	}
+	for _, disruption := range options.Disruptions {
+		wg.Add(1)
+		go func(disruption *StressDisruption) {
+			defer wg.Done()
+			stress.runDisruption(ctx, disruption)
+		}(disruption)
+	}
+	wg.Wait()
+
+	stress.result.Connections = atomic.LoadInt64(&stress.connections)
+	return stress.result
+}
+
+type stressRun struct {
+	options     *StressOptions
+	connections int64
+
+	lock   *sync.Mutex
+	result *StressResult
+}
+
+func (recv *stressRun) runConnection(ctx context.Context) {
+	for ctx.Err() == nil {
+		testClient, err := testclient.Connect(
+			ctx, recv.options.Address, recv.options.ProtocolVersion, recv.options.Username, recv.options.Password,
+			&testclient.Options{RequestTimeout: recv.options.RequestTimeout})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Debugf("Stress connection could not be opened: %v.", err)
+				time.Sleep(100 * time.Millisecond)
+			}
+			continue
+		}
+		atomic.AddInt64(&recv.connections, 1)
+		recv.runStreams(ctx, testClient)
+		_ = testClient.Shutdown()
+	}
+}
+
+// runStreams sends requests on the connection until the end of the stress run or until the test client returns an
+// error.
+func (recv *stressRun) runStreams(ctx context.Context, testClient *testclient.TestClient) {
+	connCtx, connCancelFn := context.WithCancel(ctx)
+	defer connCancelFn()
+
+	wg := &sync.WaitGroup{}
+	for stream := 0; stream < recv.options.StreamsPerConnection; stream++ {
+		wg.Add(1)
+		go func(stream int) {
+			defer wg.Done()
+			for iteration := 0; connCtx.Err() == nil; iteration++ {
+				response, _, err := testClient.SendMessage(
+					connCtx, recv.options.ProtocolVersion, recv.options.NewRequest(stream, iteration))
+				if connCtx.Err() != nil {
+					return
+				}
+				if err != nil {
+					log.Debugf("Stress request failed on %v: %v.", testClient.LocalAddr(), err)
+					recv.recordRequest(stressClientError)
+					connCancelFn()
+					return
+				}
+				if errMsg, ok := response.Body.Message.(message.Error); ok {
+					recv.recordRequest(errMsg.GetErrorCode().String())
+				} else {
+					recv.recordRequest("")
+				}
+			}
+		}(stream)
+	}
+	wg.Wait()
+}
+
+func (recv *stressRun) runDisruption(ctx context.Context, disruption *StressDisruption) {
+	ticker := time.NewTicker(disruption.Interval)
+	defer ticker.Stop()
+	for iteration := 0; ; iteration++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := disruption.Run(iteration)
+		if err != nil {
+			log.Warnf("Stress disruption %v failed: %v.", disruption.Name, err)
+			recv.lock.Lock()
+			recv.result.DisruptionErrors[disruption.Name]++
+			recv.lock.Unlock()
+		}
+	}
+}
+
+// recordRequest records a successful request if errorName is empty.
+func (recv *stressRun) recordRequest(errorName string) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.result.Requests++
+	if errorName != "" {
+		recv.result.FailedRequests++
+		recv.result.Errors[errorName]++
+	}
+}
@@ -1,6 +1,7 @@
 package integration_tests
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/client"
@@ -996,3 +997,57 @@ func validatePartitionerFromSystemLocal(t *testing.T, remoteEndpoint string, cre
 	require.Equal(t, expectedPartitioner, decodedPartitionerValue)
 
 }
+
+// TestVirtualizationDisabled tests that the system.local queries are forwarded to the cluster instead of being
+// intercepted by the proxy when ZDM_PROXY_TOPOLOGY_VIRTUALIZATION_ENABLED is false.
+func TestVirtualizationDisabled(t *testing.T) {
+	// host_id returned by the system tables handler of the go-cassandra-native-protocol CQL server
+	clusterHostId := []byte{0xC0, 0xD1, 0xD2, 0x1E, 0xBB, 0x01, 0x41, 0x96, 0x86, 0xDB, 0xBC, 0x31, 0x7B, 0xC1, 0x79, 0x6A}
+
+	tests := []struct {
+		name                  string
+		virtualizationEnabled bool
+		hostIdFromCluster     bool
+	}{
+		{name: "enabled", virtualizationEnabled: true, hostIdFromCluster: false},
+		{name: "disabled", virtualizationEnabled: false, hostIdFromCluster: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.ProxyTopologyVirtualizationEnabled = tt.virtualizationEnabled
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: "SELECT * FROM system.local"}))
+			require.Nil(t, err)
+			rowsResult, ok := response.Body.Message.(*message.RowsResult)
+			require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+			require.Equal(t, 1, len(rowsResult.Data))
+
+			hostIdIndex := -1
+			for i, col := range rowsResult.Metadata.Columns {
+				if col.Name == "host_id" {
+					hostIdIndex = i
+				}
+			}
+			require.NotEqual(t, -1, hostIdIndex)
+
+			hostId := rowsResult.Data[0][hostIdIndex]
+			require.Equal(t, tt.hostIdFromCluster, bytes.Equal(clusterHostId, hostId), "unexpected host_id %v", hostId)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package integration_tests
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/proxy/pkg/testclient"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+// TestProxyVirtualTables tests that the system_views.zdm_* tables are answered by the proxy with its own state
+func TestProxyVirtualTables(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.ProxyVirtualTablesEnabled = true
+	conf.ApplicationReadRoutingRules = "analytics-service ks TARGET"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {})}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {})}
+
+	err = testSetup.Start(conf, false, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	testClient, err := testclient.Connect(
+		context.Background(), testSetup.Proxy.GetListenAddr().String(), primitive.ProtocolVersion4,
+		conf.TargetUsername, conf.TargetPassword, &testclient.Options{StartupOptions: map[string]string{
+			message.StartupOptionApplicationName: "analytics-service",
+		}})
+	require.Nil(t, err)
+	defer testClient.Shutdown()
+
+	query := func(query string) *zdmproxy.ParsedRowSet {
+		response, _, err := testClient.SendMessage(
+			context.Background(), primitive.ProtocolVersion4, &message.Query{Query: query})
+		require.Nil(t, err)
+		result, ok := response.Body.Message.(*message.RowsResult)
+		require.True(t, ok, "expected rows result but got %v", response.Body.Message)
+		rowSet, err := zdmproxy.ParseRowsResult(
+			zdmproxy.GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4, result, nil, nil)
+		require.Nil(t, err)
+		return rowSet
+	}
+
+	clients := query("SELECT address, protocol_version, application_name FROM system_views.zdm_clients")
+	require.Len(t, clients.Rows, 1)
+	require.Equal(t, []interface{}{net.ParseIP("127.0.0.1").To4(), int32(4), "analytics-service"}, clients.Rows[0].Values)
+
+	rules := query("SELECT rule_type, application_name, keyspace_name, cluster FROM system_views.zdm_routing_rules")
+	require.Len(t, rules.Rows, 1)
+	require.Equal(t, []interface{}{"application_read_routing", "analytics-service", "ks", "TARGET"}, rules.Rows[0].Values)
+}
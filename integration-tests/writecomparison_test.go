@@ -0,0 +1,116 @@
+package integration_tests
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/datastax/zdm-proxy/integration-tests/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const writeComparisonQuery = "INSERT INTO ks.tb (k, v) VALUES (0, 'a') IF NOT EXISTS"
+
+// TestWriteComparison tests that the origin and target responses of the dual writes are compared when
+// ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE is set and that the mismatches are tracked in the metrics.
+func TestWriteComparison(t *testing.T) {
+
+	type test struct {
+		name               string
+		targetResponse     message.Message
+		expectedMismatches map[string]string
+	}
+
+	tests := []test{
+		{
+			name:           "same responses",
+			targetResponse: newAppliedResult(true),
+		},
+		{
+			name:               "different applied state",
+			targetResponse:     newAppliedResult(false),
+			expectedMismatches: map[string]string{"applied": "1"},
+		},
+		{
+			name: "error on target",
+			targetResponse: &message.WriteTimeout{
+				ErrorMessage: "write timeout", Consistency: primitive.ConsistencyLevelOne, WriteType: primitive.WriteTypeCas},
+			expectedMismatches: map[string]string{"response": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.WriteComparisonSamplePercentage = 100
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				newWriteComparisonHandler(newAppliedResult(true)),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				newWriteComparisonHandler(tt.targetResponse),
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			_, err = testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{Query: writeComparisonQuery}))
+			require.Nil(t, err)
+
+			utils.RequireWithRetries(t, func() (err error, fatal bool) {
+				values := getProxyMetricValues(t, testSetup)
+				if values["zdm_proxy_write_comparisons_total"] != "1" {
+					return fmt.Errorf("expected 1 comparison but got %v", values["zdm_proxy_write_comparisons_total"]), false
+				}
+				for _, mismatchType := range []string{"response", "applied"} {
+					expected := "0"
+					if value, ok := tt.expectedMismatches[mismatchType]; ok {
+						expected = value
+					}
+					actual := values[fmt.Sprintf("zdm_proxy_write_comparison_mismatches_total{type=\"%v\"}", mismatchType)]
+					if actual != expected {
+						return fmt.Errorf("expected %v %v mismatches but got %v", expected, mismatchType, actual), false
+					}
+				}
+				return nil, false
+			}, 50, 100*time.Millisecond)
+		})
+	}
+}
+
+func newAppliedResult(applied bool) *message.RowsResult {
+	value := []byte{0}
+	if applied {
+		value = []byte{1}
+	}
+	return &message.RowsResult{
+		Metadata: &message.RowsMetadata{
+			ColumnCount: 1,
+			Columns: []*message.ColumnMetadata{
+				{Keyspace: "ks", Table: "tb", Name: "[applied]", Index: 0, Type: datatype.Boolean},
+			},
+		},
+		Data: message.RowSet{{value}},
+	}
+}
+
+func newWriteComparisonHandler(response message.Message) client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+		query, ok := request.Body.Message.(*message.Query)
+		if !ok || query.Query != writeComparisonQuery {
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+	}
+}
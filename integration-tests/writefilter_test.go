@@ -0,0 +1,94 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTargetWriteFilter(t *testing.T) {
+	conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+	conf.TargetWriteFilterRules = "ks.tbl.tenant IN ('skipped')"
+	testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+	require.Nil(t, err)
+	defer testSetup.Cleanup()
+
+	originRecorder := &writeRecorder{}
+	targetRecorder := &writeRecorder{}
+	testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+		originRecorder.handler(),
+		client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+	}
+	testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+		targetRecorder.handler(),
+		client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+	}
+
+	err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+	require.Nil(t, err)
+
+	requests := []message.Message{
+		&message.Query{Query: "INSERT INTO ks.tbl (tenant, a) VALUES ('skipped', 1)"},
+		&message.Query{Query: "INSERT INTO ks.tbl (tenant, a) VALUES ('migrated', 1)"},
+		&message.Batch{Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tbl (tenant, a) VALUES ('skipped', 2)"},
+			{QueryOrId: "INSERT INTO ks.tbl (tenant, a) VALUES ('migrated', 2)"},
+		}},
+	}
+	for _, request := range requests {
+		response, err := testSetup.Client.CqlConnection.SendAndReceive(
+			frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, request))
+		require.Nil(t, err)
+		require.IsType(t, &message.VoidResult{}, response.Body.Message)
+	}
+
+	require.Equal(t, []string{
+		"INSERT INTO ks.tbl (tenant, a) VALUES ('skipped', 1)",
+		"INSERT INTO ks.tbl (tenant, a) VALUES ('migrated', 1)",
+		"INSERT INTO ks.tbl (tenant, a) VALUES ('skipped', 2)",
+		"INSERT INTO ks.tbl (tenant, a) VALUES ('migrated', 2)",
+	}, originRecorder.get())
+	require.Equal(t, []string{
+		"INSERT INTO ks.tbl (tenant, a) VALUES ('migrated', 1)",
+		"INSERT INTO ks.tbl (tenant, a) VALUES ('migrated', 2)",
+	}, targetRecorder.get())
+}
+
+type writeRecorder struct {
+	lock    sync.Mutex
+	queries []string
+}
+
+func (recv *writeRecorder) handler() client.RequestHandler {
+	return func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) (response *frame.Frame) {
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		switch msg := request.Body.Message.(type) {
+		case *message.Query:
+			if strings.HasPrefix(msg.Query, "INSERT") {
+				recv.queries = append(recv.queries, msg.Query)
+			} else {
+				return nil
+			}
+		case *message.Batch:
+			for _, child := range msg.Children {
+				recv.queries = append(recv.queries, child.QueryOrId.(string))
+			}
+		default:
+			return nil
+		}
+		return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.VoidResult{})
+	}
+}
+
+func (recv *writeRecorder) get() []string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]string{}, recv.queries...)
+}
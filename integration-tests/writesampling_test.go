@@ -0,0 +1,96 @@
+package integration_tests
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/integration-tests/setup"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestTargetWriteSampling(t *testing.T) {
+
+	type test struct {
+		name                  string
+		percentage            float64
+		ignoreTargetFailures  bool
+		targetFails           bool
+		expectedTargetQueries int
+		expectedResponse      message.Message
+	}
+
+	tests := []test{
+		{
+			name:                  "no writes sampled",
+			percentage:            0,
+			targetFails:           true,
+			expectedTargetQueries: 0,
+			expectedResponse:      &message.VoidResult{},
+		},
+		{
+			name:                  "sampled write fails on target",
+			percentage:            99.9999999,
+			targetFails:           true,
+			expectedTargetQueries: 1,
+			expectedResponse:      &message.WriteTimeout{},
+		},
+		{
+			name:                  "sampled write fails on target and target failures are ignored",
+			percentage:            99.9999999,
+			ignoreTargetFailures:  true,
+			targetFails:           true,
+			expectedTargetQueries: 1,
+			expectedResponse:      &message.VoidResult{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := setup.NewTestConfig("127.0.1.1", "127.0.1.2")
+			conf.TargetWriteSamplingPercentage = tt.percentage
+			conf.TargetWriteSamplingIgnoreTargetFailures = tt.ignoreTargetFailures
+			testSetup, err := setup.NewCqlServerTestSetup(t, conf, false, false, false)
+			require.Nil(t, err)
+			defer testSetup.Cleanup()
+
+			originRecorder := &writeRecorder{}
+			targetQueries := 0
+			testSetup.Origin.CqlServer.RequestHandlers = []client.RequestHandler{
+				originRecorder.handler(),
+				client.NewDriverConnectionInitializationHandler("origin", "dc1", func(_ string) {}),
+			}
+			testSetup.Target.CqlServer.RequestHandlers = []client.RequestHandler{
+				func(request *frame.Frame, conn *client.CqlServerConnection, ctx client.RequestHandlerContext) *frame.Frame {
+					query, ok := request.Body.Message.(*message.Query)
+					if !ok || !strings.HasPrefix(query.Query, "INSERT") {
+						return nil
+					}
+					targetQueries++
+					return frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.WriteTimeout{
+						ErrorMessage: "write timeout",
+						Consistency:  primitive.ConsistencyLevelLocalQuorum,
+						Received:     1,
+						BlockFor:     2,
+						WriteType:    primitive.WriteTypeSimple,
+					})
+				},
+				client.NewDriverConnectionInitializationHandler("target", "dc1", func(_ string) {}),
+			}
+
+			err = testSetup.Start(conf, true, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+
+			response, err := testSetup.Client.CqlConnection.SendAndReceive(
+				frame.NewFrame(primitive.ProtocolVersion4, client.ManagedStreamId, &message.Query{
+					Query: "INSERT INTO ks.tbl (a) VALUES (1)",
+				}))
+			require.Nil(t, err)
+			require.IsType(t, tt.expectedResponse, response.Body.Message)
+			require.Equal(t, []string{"INSERT INTO ks.tbl (a) VALUES (1)"}, originRecorder.get())
+			require.Equal(t, tt.expectedTargetQueries, targetQueries)
+		})
+	}
+}
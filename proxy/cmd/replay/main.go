@@ -0,0 +1,99 @@
+// Command replay re-executes the requests of the capture files that were written by the traffic capture of the ZDM
+// proxy against a cluster, e.g. to validate the target cluster with production traffic:
+//
+//	replay -address 10.0.0.1:9042 -username cassandra -password cassandra -speed 1 zdm-capture-*.pcap
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/zdm-proxy/proxy/pkg/replay"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	address     = flag.String("address", "127.0.0.1:9042", "Address (host:port) of the node that receives the requests")
+	username    = flag.String("username", "", "Username of the cluster, empty if the cluster doesn't require authentication")
+	password    = flag.String("password", "", "Password of the cluster")
+	keyspace    = flag.String("keyspace", "", "Keyspace that is set on every connection before the requests are replayed")
+	speed       = flag.Float64("speed", 0, "Factor that is applied to the pace of the capture (e.g. 1 for the original pace), 0 replays the requests as fast as possible")
+	readTimeout = flag.Duration("timeout", 12*time.Second, "Timeout of each request")
+	logLevel    = flag.String("log-level", "INFO", "Log level")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %v [flags] capture-file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	level, err := log.ParseLevel(*logLevel)
+	if err != nil {
+		log.Errorf("Invalid log level: %v.", err)
+		os.Exit(2)
+	}
+	log.SetLevel(level)
+	if *speed < 0 {
+		log.Errorf("Invalid speed %v, it must be 0 or a positive number.", *speed)
+		os.Exit(2)
+	}
+
+	capture, err := replay.ReadCaptureFiles(flag.Args())
+	if err != nil {
+		log.Errorf("%v.", err)
+		os.Exit(1)
+	}
+	if capture.SkippedFrames > 0 {
+		log.Warnf("%v compressed frames of the capture files can't be replayed.", capture.SkippedFrames)
+	}
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Info("Stopping the replay.")
+		cancelFn()
+	}()
+
+	var credentials *client.AuthCredentials
+	if *username != "" || *password != "" {
+		credentials = &client.AuthCredentials{Username: *username, Password: *password}
+	}
+	log.Infof("Replaying %v frames of %v capture files against %v.", len(capture.Frames), flag.NArg(), *address)
+	start := time.Now()
+	result, err := replay.Replay(ctx, capture, &replay.Config{
+		Address:     *address,
+		Credentials: credentials,
+		Keyspace:    *keyspace,
+		Speed:       *speed,
+		ReadTimeout: *readTimeout,
+	})
+	log.Infof("Replayed %v requests on %v connections in %v: %v failed, %v skipped (handshake and other requests).",
+		result.Requests, result.Connections, time.Since(start).Round(time.Millisecond),
+		result.FailedRequests, result.SkippedRequests)
+	var errors []string
+	for description := range result.Errors {
+		errors = append(errors, description)
+	}
+	sort.Strings(errors)
+	for _, description := range errors {
+		log.Infof("  %v: %v", description, result.Errors[description])
+	}
+	if err != nil {
+		log.Errorf("Replay was interrupted: %v.", err)
+		os.Exit(1)
+	}
+}
@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"flag"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
 	"github.com/datastax/zdm-proxy/proxy/pkg/runner"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
+var configFile = flag.String("config", "",
+	"YAML or JSON config file, the environment variables override its settings (same as ZDM_CONFIG_FILE)")
+
 func runSignalListener(cancelFunc context.CancelFunc) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -24,7 +29,7 @@ func runSignalListener(cancelFunc context.CancelFunc) {
 }
 
 func launchProxy(profilingSupported bool) {
-	conf, err := config.New().ParseEnvVars()
+	conf, err := config.New().ParseConfigFileAndEnvVars(*configFile)
 	if err != nil {
 		log.Errorf("Error loading configuration: %v. Aborting startup.", err)
 		os.Exit(-1)
@@ -37,6 +42,13 @@ func launchProxy(profilingSupported bool) {
 	}
 	log.SetLevel(logLevel)
 
+	logFormat, err := conf.ParseLogFormat()
+	if err != nil {
+		log.Errorf("Error loading log format configuration: %v. Aborting startup.", err)
+		os.Exit(-1)
+	}
+	log.SetFormatter(zdmproxy.NewLogFormatter(logFormat))
+
 	if profilingSupported {
 		log.Debugf("Proxy built with profiling support")
 	} else {
@@ -47,6 +59,6 @@ func launchProxy(profilingSupported bool) {
 	runSignalListener(cancelFunc)
 	log.Info("SIGINT/SIGTERM listener started.")
 
-	metricsHandler, readinessHandler := runner.SetupHandlers()
-	runner.RunMain(conf, ctx, metricsHandler, readinessHandler)
+	metricsHandler, readinessHandler, adminHandler := runner.SetupHandlers()
+	runner.RunMain(conf, ctx, metricsHandler, readinessHandler, adminHandler)
 }
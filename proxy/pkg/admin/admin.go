@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DestructiveStatementsPath = "/admin/destructive-statements"
+
+type ConfirmationToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+func DefaultDestructiveStatementsHandler() http.Handler {
+	return DestructiveStatementsHandler(nil)
+}
+
+// DestructiveStatementsHandler serves the admin API that confirms TRUNCATE and DROP statements
+// (ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED):
+//   - GET /admin/destructive-statements returns the current status
+//   - POST /admin/destructive-statements/tokens creates a one-time confirmation token
+//   - POST /admin/destructive-statements/unlock?duration=10m allows every destructive statement for a time window
+//   - POST /admin/destructive-statements/lock ends the time window and revokes the unused tokens
+func DestructiveStatementsHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		guard := proxy.GetDestructiveStatementGuard()
+		if guard == nil {
+			http.Error(rsp, "Confirmation of destructive statements is disabled "+
+				"(ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED)", http.StatusNotFound)
+			return
+		}
+
+		action := strings.Trim(strings.TrimPrefix(req.URL.Path, DestructiveStatementsPath), "/")
+		switch {
+		case action == "" && req.Method == http.MethodGet:
+			writeJson(rsp, http.StatusOK, guard.GetStatus())
+		case action == "tokens" && req.Method == http.MethodPost:
+			token, expiresAt := guard.CreateToken()
+			log.Infof("Created destructive statement confirmation token through the admin API (client %v), "+
+				"it expires at %v.", req.RemoteAddr, expiresAt)
+			writeJson(rsp, http.StatusOK, &ConfirmationToken{Token: token, ExpiresAt: expiresAt})
+		case action == "unlock" && req.Method == http.MethodPost:
+			duration, err := time.ParseDuration(req.URL.Query().Get("duration"))
+			if err != nil {
+				http.Error(rsp, fmt.Sprintf("Invalid duration parameter: %v", err), http.StatusBadRequest)
+				return
+			}
+			_, err = guard.Unlock(duration)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Warnf("Destructive statements were unlocked for %v through the admin API (client %v).",
+				duration, req.RemoteAddr)
+			writeJson(rsp, http.StatusOK, guard.GetStatus())
+		case action == "lock" && req.Method == http.MethodPost:
+			guard.Lock()
+			log.Infof("Destructive statements were locked through the admin API (client %v).", req.RemoteAddr)
+			writeJson(rsp, http.StatusOK, guard.GetStatus())
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
+
+func writeJson(rsp http.ResponseWriter, statusCode int, body interface{}) {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		uid := uuid.New()
+		log.Errorf("Could not serialize admin API response (code: %v): %v", uid, err)
+		http.Error(rsp, fmt.Sprintf("Internal server error with code %v", uid), http.StatusInternalServerError)
+		return
+	}
+
+	rsp.Header().Set("Content-Type", "application/json")
+	rsp.WriteHeader(statusCode)
+	rsp.Write(bytes)
+}
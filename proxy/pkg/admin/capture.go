@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TrafficCapturePath = "/admin/capture"
+
+func DefaultTrafficCaptureHandler() http.Handler {
+	return TrafficCaptureHandler(nil)
+}
+
+// TrafficCaptureHandler serves the admin API that captures the traffic of client connections into pcap files
+// (ZDM_TRAFFIC_CAPTURE_DIRECTORY):
+//   - GET /admin/capture returns the status of the capture in progress or of the last capture
+//   - POST /admin/capture/start?duration=5m&clients=10.0.0.1,10.0.0.2:53412&redact=true starts a capture of the
+//     connections of the provided clients (every client if empty), redact removes the values from the frames
+//   - POST /admin/capture/stop stops the capture in progress
+func TrafficCaptureHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		capture := proxy.GetTrafficCapture()
+		if capture == nil {
+			http.Error(rsp, "Traffic capture is disabled (ZDM_TRAFFIC_CAPTURE_DIRECTORY)", http.StatusNotFound)
+			return
+		}
+
+		action := strings.Trim(strings.TrimPrefix(req.URL.Path, TrafficCapturePath), "/")
+		switch {
+		case action == "" && req.Method == http.MethodGet:
+			writeJson(rsp, http.StatusOK, capture.GetStatus())
+		case action == "start" && req.Method == http.MethodPost:
+			query := req.URL.Query()
+			duration, err := time.ParseDuration(query.Get("duration"))
+			if err != nil {
+				http.Error(rsp, fmt.Sprintf("Invalid duration parameter: %v", err), http.StatusBadRequest)
+				return
+			}
+			redact := false
+			if query.Get("redact") != "" {
+				redact, err = strconv.ParseBool(query.Get("redact"))
+				if err != nil {
+					http.Error(rsp, fmt.Sprintf("Invalid redact parameter: %v", err), http.StatusBadRequest)
+					return
+				}
+			}
+			var clients []string
+			if query.Get("clients") != "" {
+				clients = strings.Split(query.Get("clients"), ",")
+			}
+			status, err := capture.Start(duration, clients, redact)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Infof("Traffic capture %v was started for %v through the admin API (client %v, clients %v, redacted %v).",
+				status.File, duration, req.RemoteAddr, status.Clients, redact)
+			writeJson(rsp, http.StatusOK, status)
+		case action == "stop" && req.Method == http.MethodPost:
+			writeJson(rsp, http.StatusOK, capture.Stop())
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
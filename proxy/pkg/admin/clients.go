@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+const ClientsPath = "/admin/clients"
+
+func DefaultClientsHandler() http.Handler {
+	return ClientsHandler(nil)
+}
+
+// ClientsHandler serves the admin API of the client connections:
+//   - GET /admin/clients returns the client connections that finished the handshake
+//   - POST /admin/clients/drain?clients=10.0.0.1,10.0.0.2:53412 drains the connections of the provided clients
+//     (every client if empty): the new requests get an OVERLOADED error so that the drivers retry them on another
+//     proxy instance and the connections are closed once their in flight requests are completed
+func ClientsHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		action := strings.Trim(strings.TrimPrefix(req.URL.Path, ClientsPath), "/")
+		switch {
+		case action == "" && req.Method == http.MethodGet:
+			writeJson(rsp, http.StatusOK, proxy.GetConnectedClients())
+		case action == "drain" && req.Method == http.MethodPost:
+			var clients []string
+			if query := req.URL.Query(); query.Get("clients") != "" {
+				clients = strings.Split(query.Get("clients"), ",")
+			}
+			drained, err := proxy.DrainClients(clients)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Warnf("%v client connections were drained through the admin API (client %v, clients %v).",
+				len(drained), req.RemoteAddr, clients)
+			writeJson(rsp, http.StatusOK, drained)
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
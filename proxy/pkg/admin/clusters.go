@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/health"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const ClustersPath = "/admin/clusters"
+
+// ClusterHealth is the state of the connections to a cluster in the response of GET /admin/clusters.
+type ClusterHealth struct {
+	*zdmproxy.ClusterConnectionStatus
+	ControlConnection *health.ControlConnStatus
+}
+
+func DefaultClustersHandler() http.Handler {
+	return ClustersHandler(nil)
+}
+
+// ClustersHandler serves GET /admin/clusters which returns the health of the connections to ORIGIN and TARGET: the
+// status of the control connection (same as the readiness endpoint), the nodes that it discovered and the state of
+// the circuit breaker.
+func ClustersHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+		if req.Method != http.MethodGet {
+			http.NotFound(rsp, req)
+			return
+		}
+
+		report := health.PerformHealthCheck(proxy)
+		clusters := make([]*ClusterHealth, 0, 2)
+		for _, cluster := range []common.ClusterType{common.ClusterTypeOrigin, common.ClusterTypeTarget} {
+			status, err := proxy.GetClusterConnectionStatus(cluster)
+			if err != nil {
+				uid := uuid.New()
+				log.Errorf("Could not get the connection status of %v (code: %v): %v", cluster, uid, err)
+				http.Error(rsp, fmt.Sprintf("Internal server error with code %v", uid), http.StatusInternalServerError)
+				return
+			}
+			controlConnStatus := report.OriginStatus
+			if cluster == common.ClusterTypeTarget {
+				controlConnStatus = report.TargetStatus
+			}
+			clusters = append(clusters, &ClusterHealth{ClusterConnectionStatus: status, ControlConnection: controlConnStatus})
+		}
+		writeJson(rsp, http.StatusOK, clusters)
+	})
+}
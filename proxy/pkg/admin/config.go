@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"net/http"
+)
+
+// PathPrefix is the prefix of every path of the admin API.
+const PathPrefix = "/admin/"
+
+const ConfigPath = "/admin/config"
+
+// EffectiveConfig is the response of GET /admin/config.
+type EffectiveConfig struct {
+	// Settings has the value of every setting keyed by its environment variable name, with secrets redacted.
+	Settings map[string]interface{}
+	// RuntimeSettings has the settings that were changed with the zdm_admin keyspace.
+	RuntimeSettings map[string]string
+}
+
+func DefaultConfigHandler() http.Handler {
+	return ConfigHandler(nil)
+}
+
+// ConfigHandler serves GET /admin/config which returns the effective configuration of the proxy, i.e. the settings
+// after the defaults, credential files and decryption were applied and the changes that were made at runtime.
+// Passwords and the settings that were provided encrypted are redacted.
+func ConfigHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+		if req.Method != http.MethodGet {
+			http.NotFound(rsp, req)
+			return
+		}
+
+		writeJson(rsp, http.StatusOK, &EffectiveConfig{
+			Settings:        proxy.GetEffectiveConfig().RedactedValues(),
+			RuntimeSettings: proxy.GetRuntimeSettings(),
+		})
+	})
+}
+
+func DefaultHandler() http.Handler {
+	return Handler(nil)
+}
+
+// Handler serves every path of the admin API, see ConfigHandler, DestructiveStatementsHandler,
+// TrafficCaptureHandler, FrameLoggingHandler, ClientsHandler, PreparedStatementsHandler, ClustersHandler,
+// ReadRoutingHandler, DiagnosticsHandler, PprofHandler, LoggingHandler and MigrationProgressHandler.
+func Handler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(ConfigPath, ConfigHandler(proxy))
+	mux.Handle(DestructiveStatementsPath, DestructiveStatementsHandler(proxy))
+	mux.Handle(DestructiveStatementsPath+"/", DestructiveStatementsHandler(proxy))
+	mux.Handle(TrafficCapturePath, TrafficCaptureHandler(proxy))
+	mux.Handle(TrafficCapturePath+"/", TrafficCaptureHandler(proxy))
+	mux.Handle(FrameLoggingPath, FrameLoggingHandler(proxy))
+	mux.Handle(FrameLoggingPath+"/", FrameLoggingHandler(proxy))
+	mux.Handle(ClientsPath, ClientsHandler(proxy))
+	mux.Handle(ClientsPath+"/", ClientsHandler(proxy))
+	mux.Handle(PreparedStatementsPath, PreparedStatementsHandler(proxy))
+	mux.Handle(ClustersPath, ClustersHandler(proxy))
+	mux.Handle(ReadRoutingPath, ReadRoutingHandler(proxy))
+	mux.Handle(DiagnosticsPath, DiagnosticsHandler(proxy))
+	mux.Handle(DiagnosticsPath+"/", DiagnosticsHandler(proxy))
+	mux.Handle(PprofPath+"/", PprofHandler(proxy))
+	mux.Handle(LoggingPath, LoggingHandler(proxy))
+	mux.Handle(LoggingPath+"/", LoggingHandler(proxy))
+	mux.Handle(MigrationProgressPath, MigrationProgressHandler(proxy))
+	return mux
+}
+
+// RegisterHandler serves every path of the admin API with the provided handler, i.e. the handler returned by Handler.
+func RegisterHandler(mux *http.ServeMux, handler http.Handler) {
+	mux.Handle(PathPrefix, handler)
+}
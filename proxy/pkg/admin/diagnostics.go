@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DiagnosticsPath = "/admin/diagnostics"
+
+const PprofPath = "/admin/debug/pprof"
+
+// maxCpuProfileDuration is the maximum duration of the CPU profiles so that a request can't keep profiling forever.
+const maxCpuProfileDuration = 5 * time.Minute
+
+func DefaultDiagnosticsHandler() http.Handler {
+	return DiagnosticsHandler(nil)
+}
+
+// DiagnosticsHandler serves the runtime diagnostics of the proxy process (ZDM_DIAGNOSTICS_ENABLED):
+//   - GET /admin/diagnostics returns the runtime stats (goroutines, heap and garbage collector)
+//   - GET /admin/diagnostics/dump returns the runtime stats, the stacks of all goroutines and the heap profile in text
+//     format
+func DiagnosticsHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if !checkDiagnosticsEnabled(proxy, rsp) {
+			return
+		}
+
+		action := strings.Trim(strings.TrimPrefix(req.URL.Path, DiagnosticsPath), "/")
+		switch {
+		case action == "" && req.Method == http.MethodGet:
+			writeJson(rsp, http.StatusOK, zdmproxy.GetRuntimeStats())
+		case action == "dump" && req.Method == http.MethodGet:
+			log.Infof("Dumping the runtime diagnostics through the admin API (client %v).", req.RemoteAddr)
+			rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			err := zdmproxy.WriteRuntimeDiagnostics(rsp)
+			if err != nil {
+				log.Warnf("Could not write the runtime diagnostics to the admin API client %v: %v", req.RemoteAddr, err)
+			}
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
+
+func DefaultPprofHandler() http.Handler {
+	return PprofHandler(nil)
+}
+
+// PprofHandler serves the profiles of the Go runtime in the format of net/http/pprof so that they can be read with
+// go tool pprof (ZDM_DIAGNOSTICS_ENABLED):
+//   - GET /admin/debug/pprof/ lists the available profiles
+//   - GET /admin/debug/pprof/profile?seconds=30 returns a CPU profile of the provided duration
+//   - GET /admin/debug/pprof/<profile>?debug=1 returns a profile (goroutine, heap, allocs, block, mutex or
+//     threadcreate), debug 1 and 2 return it in text format
+func PprofHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if !checkDiagnosticsEnabled(proxy, rsp) {
+			return
+		}
+		if req.Method != http.MethodGet {
+			http.NotFound(rsp, req)
+			return
+		}
+
+		name := strings.Trim(strings.TrimPrefix(req.URL.Path, PprofPath), "/")
+		switch name {
+		case "":
+			rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			for _, profile := range pprof.Profiles() {
+				fmt.Fprintf(rsp, "%v (%v)\n", profile.Name(), profile.Count())
+			}
+			fmt.Fprintln(rsp, "profile")
+		case "profile":
+			writeCpuProfile(rsp, req)
+		default:
+			writeProfile(rsp, req, name)
+		}
+	})
+}
+
+func writeCpuProfile(rsp http.ResponseWriter, req *http.Request) {
+	seconds := 30
+	var err error
+	if req.URL.Query().Get("seconds") != "" {
+		seconds, err = strconv.Atoi(req.URL.Query().Get("seconds"))
+		if err != nil || seconds <= 0 {
+			http.Error(rsp, fmt.Sprintf("Invalid seconds parameter: %v", req.URL.Query().Get("seconds")),
+				http.StatusBadRequest)
+			return
+		}
+	}
+	duration := time.Duration(seconds) * time.Second
+	if duration > maxCpuProfileDuration {
+		http.Error(rsp, fmt.Sprintf("Invalid seconds parameter: %v; the maximum is %v",
+			seconds, maxCpuProfileDuration), http.StatusBadRequest)
+		return
+	}
+
+	rsp.Header().Set("Content-Type", "application/octet-stream")
+	rsp.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	err = pprof.StartCPUProfile(rsp)
+	if err != nil {
+		// the CPU profile is already enabled, e.g. by another request
+		rsp.Header().Del("Content-Disposition")
+		http.Error(rsp, fmt.Sprintf("Could not start the CPU profile: %v", err), http.StatusConflict)
+		return
+	}
+	log.Infof("Profiling the CPU for %v through the admin API (client %v).", duration, req.RemoteAddr)
+	select {
+	case <-time.After(duration):
+	case <-req.Context().Done():
+	}
+	pprof.StopCPUProfile()
+}
+
+func writeProfile(rsp http.ResponseWriter, req *http.Request, name string) {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		http.Error(rsp, fmt.Sprintf("Unknown profile: %v", name), http.StatusNotFound)
+		return
+	}
+	debug := 0
+	if req.URL.Query().Get("debug") != "" {
+		var err error
+		debug, err = strconv.Atoi(req.URL.Query().Get("debug"))
+		if err != nil || debug < 0 {
+			http.Error(rsp, fmt.Sprintf("Invalid debug parameter: %v", req.URL.Query().Get("debug")),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	if debug == 0 {
+		rsp.Header().Set("Content-Type", "application/octet-stream")
+		rsp.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%v"`, name))
+	} else {
+		rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	err := profile.WriteTo(rsp, debug)
+	if err != nil {
+		log.Warnf("Could not write the %v profile to the admin API client %v: %v", name, req.RemoteAddr, err)
+	}
+}
+
+// checkDiagnosticsEnabled writes the error response and returns false if the diagnostics can't be served.
+func checkDiagnosticsEnabled(proxy *zdmproxy.ZdmProxy, rsp http.ResponseWriter) bool {
+	if proxy == nil {
+		http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+		return false
+	}
+	if !proxy.IsDiagnosticsEnabled() {
+		http.Error(rsp, "Diagnostics are disabled (ZDM_DIAGNOSTICS_ENABLED)", http.StatusNotFound)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const FrameLoggingPath = "/admin/frame-logging"
+
+func DefaultFrameLoggingHandler() http.Handler {
+	return FrameLoggingHandler(nil)
+}
+
+// FrameLoggingHandler serves the admin API that logs the headers and the redacted bodies of the frames of client
+// connections:
+//   - GET /admin/frame-logging returns the clients whose frames are logged
+//   - POST /admin/frame-logging/enable?clients=10.0.0.1,10.0.0.2:53412&duration=5m logs the frames of the
+//     connections of the provided clients for the provided duration (15m if empty)
+//   - POST /admin/frame-logging/disable?clients=10.0.0.1 stops logging the frames of the provided clients (every
+//     client if empty)
+func FrameLoggingHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		frameLogger := proxy.GetFrameLogger()
+		action := strings.Trim(strings.TrimPrefix(req.URL.Path, FrameLoggingPath), "/")
+		query := req.URL.Query()
+		var clients []string
+		if query.Get("clients") != "" {
+			clients = strings.Split(query.Get("clients"), ",")
+		}
+		switch {
+		case action == "" && req.Method == http.MethodGet:
+			writeJson(rsp, http.StatusOK, frameLogger.GetStatus())
+		case action == "enable" && req.Method == http.MethodPost:
+			var duration time.Duration
+			if query.Get("duration") != "" {
+				var err error
+				duration, err = time.ParseDuration(query.Get("duration"))
+				if err != nil {
+					http.Error(rsp, fmt.Sprintf("Invalid duration parameter: %v", err), http.StatusBadRequest)
+					return
+				}
+			}
+			status, err := frameLogger.Enable(clients, duration)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Infof("Frame logging was enabled through the admin API (client %v, clients %v).", req.RemoteAddr, clients)
+			writeJson(rsp, http.StatusOK, status)
+		case action == "disable" && req.Method == http.MethodPost:
+			status, err := frameLogger.Disable(clients)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Infof("Frame logging was disabled through the admin API (client %v, clients %v).", req.RemoteAddr, clients)
+			writeJson(rsp, http.StatusOK, status)
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const LoggingPath = "/admin/logging"
+
+func DefaultLoggingHandler() http.Handler {
+	return LoggingHandler(nil)
+}
+
+// LoggingHandler serves the admin API that changes the log level at runtime:
+//   - GET /admin/logging returns the log level and the modules and clients whose debug logs are enabled
+//   - POST /admin/logging/level?level=debug changes the log level of the proxy
+//   - POST /admin/logging/enable?modules=parser,cluster_connector&clients=10.0.0.1&duration=5m enables the debug logs
+//     of the provided modules (client_handler, cluster_connector, parser or control_connection) and of the
+//     connections of the provided clients for the provided duration (15m if empty)
+//   - POST /admin/logging/disable?modules=parser&clients=10.0.0.1 disables the debug logs of the provided modules and
+//     clients (every module and client if both are empty)
+func LoggingHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		controller := proxy.GetLogLevelController()
+		action := strings.Trim(strings.TrimPrefix(req.URL.Path, LoggingPath), "/")
+		query := req.URL.Query()
+		modules := splitQueryParameter(query.Get("modules"))
+		clients := splitQueryParameter(query.Get("clients"))
+		switch {
+		case action == "" && req.Method == http.MethodGet:
+			writeJson(rsp, http.StatusOK, controller.GetStatus())
+		case action == "level" && req.Method == http.MethodPost:
+			level, err := log.ParseLevel(strings.TrimSpace(query.Get("level")))
+			if err != nil {
+				http.Error(rsp, fmt.Sprintf("Invalid level parameter: %v", err), http.StatusBadRequest)
+				return
+			}
+			status := controller.SetLevel(level)
+			log.Warnf("Log level was changed to %v through the admin API (client %v).", level, req.RemoteAddr)
+			writeJson(rsp, http.StatusOK, status)
+		case action == "enable" && req.Method == http.MethodPost:
+			var duration time.Duration
+			if query.Get("duration") != "" {
+				var err error
+				duration, err = time.ParseDuration(query.Get("duration"))
+				if err != nil {
+					http.Error(rsp, fmt.Sprintf("Invalid duration parameter: %v", err), http.StatusBadRequest)
+					return
+				}
+			}
+			status, err := controller.EnableDebug(modules, clients, duration)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Infof("Debug logs were enabled through the admin API (client %v, modules %v, clients %v).",
+				req.RemoteAddr, modules, clients)
+			writeJson(rsp, http.StatusOK, status)
+		case action == "disable" && req.Method == http.MethodPost:
+			status, err := controller.DisableDebug(modules, clients)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Infof("Debug logs were disabled through the admin API (client %v, modules %v, clients %v).",
+				req.RemoteAddr, modules, clients)
+			writeJson(rsp, http.StatusOK, status)
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
+
+func splitQueryParameter(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
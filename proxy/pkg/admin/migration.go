@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"net/http"
+)
+
+const MigrationProgressPath = "/admin/migration"
+
+func DefaultMigrationProgressHandler() http.Handler {
+	return MigrationProgressHandler(nil)
+}
+
+// MigrationProgressHandler serves GET /admin/migration which returns the last migration progress estimation
+// (ZDM_MIGRATION_PROGRESS_ENABLED): the reads answered by each cluster, the dual writes that failed, the read
+// comparison mismatches and the writes of the write journal that are waiting to be replayed.
+func MigrationProgressHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+		if req.Method != http.MethodGet {
+			http.NotFound(rsp, req)
+			return
+		}
+
+		status := proxy.GetMigrationProgress()
+		if status == nil {
+			http.Error(rsp, "Migration progress estimation is disabled (ZDM_MIGRATION_PROGRESS_ENABLED)",
+				http.StatusNotFound)
+			return
+		}
+		writeJson(rsp, http.StatusOK, status)
+	})
+}
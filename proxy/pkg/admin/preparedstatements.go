@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"net/http"
+)
+
+const PreparedStatementsPath = "/admin/prepared-statements"
+
+func DefaultPreparedStatementsHandler() http.Handler {
+	return PreparedStatementsHandler(nil)
+}
+
+// PreparedStatementsHandler serves GET /admin/prepared-statements which returns the number of statements of the
+// prepared statement cache and its entries, i.e. the prepared ids of both clusters, the query and the forward decision.
+func PreparedStatementsHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+		if req.Method != http.MethodGet {
+			http.NotFound(rsp, req)
+			return
+		}
+
+		writeJson(rsp, http.StatusOK, proxy.GetPreparedStatementCacheStatus())
+	})
+}
@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
+	"net/http"
+	"strings"
+)
+
+const ReadRoutingPath = "/admin/read-routing"
+
+func DefaultReadRoutingHandler() http.Handler {
+	return ReadRoutingHandler(nil)
+}
+
+// ReadRoutingHandler serves the admin API that forwards the reads to ORIGIN or TARGET at runtime, it changes the
+// read_routing setting of the zdm_admin keyspace (ZDM_ADMIN_KEYSPACE_ROLES) so the change is shared with the other
+// proxy instances if ZDM_FLEET_STORE_TYPE is set:
+//   - GET /admin/read-routing returns the primary cluster and the cluster that the reads are forwarded to
+//   - POST /admin/read-routing?cluster=target forwards the reads to the provided cluster (origin or target), null
+//     forwards them to the primary cluster again
+func ReadRoutingHandler(proxy *zdmproxy.ZdmProxy) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if proxy == nil {
+			http.Error(rsp, "Proxy is starting up", http.StatusServiceUnavailable)
+			return
+		}
+
+		status := proxy.GetReadRoutingStatus()
+		if status == nil {
+			http.Error(rsp, "The zdm_admin keyspace is disabled (ZDM_ADMIN_KEYSPACE_ROLES)", http.StatusNotFound)
+			return
+		}
+
+		action := strings.Trim(strings.TrimPrefix(req.URL.Path, ReadRoutingPath), "/")
+		switch {
+		case action == "" && req.Method == http.MethodGet:
+			writeJson(rsp, http.StatusOK, status)
+		case action == "" && req.Method == http.MethodPost:
+			status, err := proxy.SetReadRouting(req.URL.Query().Get("cluster"), "admin API client "+req.RemoteAddr)
+			if err != nil {
+				http.Error(rsp, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJson(rsp, http.StatusOK, status)
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
@@ -3,13 +3,14 @@ package common
 import (
 	"fmt"
 	"net"
+	"regexp"
 )
 
 // TopologyConfig contains configuration parameters for 2 features related to multi zdm-proxy instance deployment:
 //   - Virtualization of system.peers
 //   - Assignment of C* hosts per proxy instance for request connections
 type TopologyConfig struct {
-	VirtualizationEnabled bool     // enabled if ZDM_PROXY_TOPOLOGY_ADDRESSES is not empty
+	VirtualizationEnabled bool     // ZDM_PROXY_TOPOLOGY_VIRTUALIZATION_ENABLED
 	Addresses             []net.IP // comes from ZDM_PROXY_TOPOLOGY_ADDRESSES
 	Count                 int      // comes from length of ZDM_PROXY_TOPOLOGY_ADDRESSES
 	Index                 int      // comes from ZDM_PROXY_TOPOLOGY_INDEX
@@ -24,25 +25,32 @@ func (recv *TopologyConfig) String() string {
 // ClusterTlsConfig contains all TLS configuration parameters to connect to a cluster
 //   - TLS enabled is an internal flag that is automatically set based on the configuration provided
 //   - SCB and all other parameters are mutually exclusive: if SCB is provided, no other parameters must be specified. Doing so will result in a validation errExpected
-//   - When using a non-SCB configuration, all other three parameters must be specified (ServerCaPath, ClientCertPath, ClientKeyPath).
+//   - When using a non-SCB configuration, ClientCertPath and ClientKeyPath must be specified together and ServerCaPath can only be omitted if TLS was explicitly enabled (the system CAs are used then).
+//   - ServerName enables host name verification and InsecureSkipVerify disables the verification of the server certificates.
 type ClusterTlsConfig struct {
 	TlsEnabled              bool
 	ServerCaPath            string
 	ClientCertPath          string
 	ClientKeyPath           string
+	ServerName              string
+	InsecureSkipVerify      bool
 	SecureConnectBundlePath string
 }
 
 func (recv *ClusterTlsConfig) String() string {
-	return fmt.Sprintf("ClusterTlsConfig{TlsEnabled=%v, ProxyCaPath=%v, ClientCertPath=%v, ClientKeyPath=%v}",
-		recv.TlsEnabled, recv.ServerCaPath, recv.ClientCertPath, recv.ClientKeyPath)
+	return fmt.Sprintf("ClusterTlsConfig{TlsEnabled=%v, ProxyCaPath=%v, ClientCertPath=%v, ClientKeyPath=%v, "+
+		"ServerName=%v, InsecureSkipVerify=%v}",
+		recv.TlsEnabled, recv.ServerCaPath, recv.ClientCertPath, recv.ClientKeyPath,
+		recv.ServerName, recv.InsecureSkipVerify)
 }
 
 // ProxyTlsConfig contains all TLS configuration parameters to enable TLS at proxy level
 //   - TLS enabled is an internal flag that is automatically set based on the configuration provided
 //   - All three properties (ProxyCaPath, ProxyCertPath and ProxyKeyPath) are required for proxy TLS to be enabled
+//   - DevMode means that the CA and server certificate are generated at startup instead of being loaded from files
 type ProxyTlsConfig struct {
 	TlsEnabled    bool
+	DevMode       bool
 	ProxyCaPath   string
 	ProxyCertPath string
 	ProxyKeyPath  string
@@ -50,11 +58,284 @@ type ProxyTlsConfig struct {
 }
 
 func (recv *ProxyTlsConfig) String() string {
-	return fmt.Sprintf("ProxyTlsConfig{TlsEnabled=%v, ProxyCaPath=%v, ProxyCertPath=%v, ProxyKeyPath=%v, ClientAuth=%v}",
-		recv.TlsEnabled, recv.ProxyCaPath, recv.ProxyCertPath, recv.ProxyKeyPath, recv.ClientAuth)
+	return fmt.Sprintf("ProxyTlsConfig{TlsEnabled=%v, DevMode=%v, ProxyCaPath=%v, ProxyCertPath=%v, ProxyKeyPath=%v, ClientAuth=%v}",
+		recv.TlsEnabled, recv.DevMode, recv.ProxyCaPath, recv.ProxyCertPath, recv.ProxyKeyPath, recv.ClientAuth)
 
 }
 
+// RequestLimitConfig contains the parameters that cap the number of concurrent requests sent to a cluster
+//   - Enabled is an internal flag that is set if MaxConcurrentRequests is greater than 0
+//   - Requests that exceed MaxConcurrentRequests wait in a queue of up to MaxQueuedRequests requests for up to QueueTimeoutMs
+type RequestLimitConfig struct {
+	Enabled               bool
+	MaxConcurrentRequests int
+	MaxQueuedRequests     int
+	QueueTimeoutMs        int
+}
+
+func (recv *RequestLimitConfig) String() string {
+	return fmt.Sprintf("RequestLimitConfig{Enabled=%v, MaxConcurrentRequests=%v, MaxQueuedRequests=%v, QueueTimeoutMs=%v}",
+		recv.Enabled, recv.MaxConcurrentRequests, recv.MaxQueuedRequests, recv.QueueTimeoutMs)
+}
+
+// ClientRequestLimitConfig contains the limits of the requests of each client connection, 0 means unlimited
+type ClientRequestLimitConfig struct {
+	MaxRequestsPerSecond int
+	MaxInFlightRequests  int
+}
+
+func (recv *ClientRequestLimitConfig) Enabled() bool {
+	return recv.MaxRequestsPerSecond > 0 || recv.MaxInFlightRequests > 0
+}
+
+func (recv *ClientRequestLimitConfig) String() string {
+	return fmt.Sprintf("ClientRequestLimitConfig{MaxRequestsPerSecond=%v, MaxInFlightRequests=%v}",
+		recv.MaxRequestsPerSecond, recv.MaxInFlightRequests)
+}
+
+// RequestRetryConfig is how many times the idempotent requests are retried on a cluster that returns a retryable
+// error and the bounds of the exponential backoff between the retries.
+type RequestRetryConfig struct {
+	Enabled      bool
+	MaxRetries   int
+	BackoffMinMs int
+	BackoffMaxMs int
+}
+
+func (recv *RequestRetryConfig) String() string {
+	return fmt.Sprintf("RequestRetryConfig{Enabled=%v, MaxRetries=%v, BackoffMinMs=%v, BackoffMaxMs=%v}",
+		recv.Enabled, recv.MaxRetries, recv.BackoffMinMs, recv.BackoffMaxMs)
+}
+
+// RequestTimeoutConfig is how long the proxy waits for the response of each cluster and the timeouts that override
+// them for some statement types, 0 if the statement type timeout is not overridden.
+type RequestTimeoutConfig struct {
+	OriginMs int
+	TargetMs int
+	ReadMs   int
+	WriteMs  int
+	DdlMs    int
+}
+
+func (recv *RequestTimeoutConfig) String() string {
+	return fmt.Sprintf("RequestTimeoutConfig{OriginMs=%v, TargetMs=%v, ReadMs=%v, WriteMs=%v, DdlMs=%v}",
+		recv.OriginMs, recv.TargetMs, recv.ReadMs, recv.WriteMs, recv.DdlMs)
+}
+
+// CircuitBreakerConfig opens the circuit breaker of a cluster when at least ErrorThresholdPercent percent of the
+// responses of the last WindowMs are failures and there were at least MinRequests requests, the breaker probes the
+// cluster again after OpenMs.
+type CircuitBreakerConfig struct {
+	Enabled               bool
+	ErrorThresholdPercent int
+	MinRequests           int
+	WindowMs              int
+	OpenMs                int
+}
+
+func (recv *CircuitBreakerConfig) String() string {
+	return fmt.Sprintf("CircuitBreakerConfig{Enabled=%v, ErrorThresholdPercent=%v, MinRequests=%v, WindowMs=%v, OpenMs=%v}",
+		recv.Enabled, recv.ErrorThresholdPercent, recv.MinRequests, recv.WindowMs, recv.OpenMs)
+}
+
+// MigrationProgressConfig computes the migration progress estimation from the requests of the last WindowMs, the
+// window slides (and the estimation is updated) every SampleIntervalMs.
+type MigrationProgressConfig struct {
+	Enabled          bool
+	SampleIntervalMs int
+	WindowMs         int
+}
+
+func (recv *MigrationProgressConfig) String() string {
+	return fmt.Sprintf("MigrationProgressConfig{Enabled=%v, SampleIntervalMs=%v, WindowMs=%v}",
+		recv.Enabled, recv.SampleIntervalMs, recv.WindowMs)
+}
+
+// LatencyBudgetConfig is the latency SLO of a cluster: the Percentile percentile of the latency of the requests
+// over the last WindowMs must not exceed BudgetMs.
+type LatencyBudgetConfig struct {
+	Enabled    bool
+	BudgetMs   int
+	Percentile float64
+	WindowMs   int
+}
+
+func (recv *LatencyBudgetConfig) String() string {
+	return fmt.Sprintf("LatencyBudgetConfig{Enabled=%v, BudgetMs=%v, Percentile=%v, WindowMs=%v}",
+		recv.Enabled, recv.BudgetMs, recv.Percentile, recv.WindowMs)
+}
+
+// SocketOptions are the options of the TCP sockets of the client connections or of the connections to a cluster
+//   - NoDelay disables Nagle's algorithm (TCP_NODELAY)
+//   - The kernel buffer sizes (SO_RCVBUF and SO_SNDBUF) are left to the operating system default if they are 0
+type SocketOptions struct {
+	NoDelay                bool
+	ReceiveBufferSizeBytes int
+	SendBufferSizeBytes    int
+}
+
+func (recv *SocketOptions) String() string {
+	return fmt.Sprintf("SocketOptions{NoDelay=%v, ReceiveBufferSizeBytes=%v, SendBufferSizeBytes=%v}",
+		recv.NoDelay, recv.ReceiveBufferSizeBytes, recv.SendBufferSizeBytes)
+}
+
+// DestructiveStatementsConfirmationConfig contains the parameters of the confirmation of TRUNCATE and DROP statements
+//   - Confirmation tokens that are created with the admin API expire after TokenTtlMs if they are not used
+//   - Destructive statements can be unlocked with the admin API for up to MaxUnlockDurationMs
+type DestructiveStatementsConfirmationConfig struct {
+	Enabled             bool
+	TokenTtlMs          int
+	MaxUnlockDurationMs int
+}
+
+func (recv *DestructiveStatementsConfirmationConfig) String() string {
+	return fmt.Sprintf("DestructiveStatementsConfirmationConfig{Enabled=%v, TokenTtlMs=%v, MaxUnlockDurationMs=%v}",
+		recv.Enabled, recv.TokenTtlMs, recv.MaxUnlockDurationMs)
+}
+
+// TrafficCaptureConfig contains the parameters of the traffic capture of the admin API
+//   - The capture files are written to Directory
+//   - A capture stops after MaxDurationMs at most or when its file reaches MaxFileSizeBytes
+type TrafficCaptureConfig struct {
+	Enabled          bool
+	Directory        string
+	MaxDurationMs    int
+	MaxFileSizeBytes int
+}
+
+func (recv *TrafficCaptureConfig) String() string {
+	return fmt.Sprintf("TrafficCaptureConfig{Enabled=%v, Directory=%v, MaxDurationMs=%v, MaxFileSizeBytes=%v}",
+		recv.Enabled, recv.Directory, recv.MaxDurationMs, recv.MaxFileSizeBytes)
+}
+
+// WriteJournalConfig contains the parameters of the journal of the writes that failed on the secondary cluster
+//   - The journal is stored in a file of Directory or in memory if Directory is empty
+//   - New writes are dropped while the pending writes take MaxSizeBytes
+//   - At most ReplayRate writes are replayed per second
+type WriteJournalConfig struct {
+	Enabled      bool
+	Directory    string
+	MaxSizeBytes int
+	ReplayRate   int
+}
+
+func (recv *WriteJournalConfig) String() string {
+	return fmt.Sprintf("WriteJournalConfig{Enabled=%v, Directory=%v, MaxSizeBytes=%v, ReplayRate=%v}",
+		recv.Enabled, recv.Directory, recv.MaxSizeBytes, recv.ReplayRate)
+}
+
+// StallDetectionConfig contains the parameters of the watchdog that detects stalled write queues and worker pools
+//   - A component is stalled when it doesn't make progress or stays full for ThresholdMs
+//   - The diagnostics of a stall are logged once every DiagnosticsIntervalMs at most
+type StallDetectionConfig struct {
+	Enabled               bool
+	ThresholdMs           int
+	DiagnosticsIntervalMs int
+}
+
+func (recv *StallDetectionConfig) String() string {
+	return fmt.Sprintf("StallDetectionConfig{Enabled=%v, ThresholdMs=%v, DiagnosticsIntervalMs=%v}",
+		recv.Enabled, recv.ThresholdMs, recv.DiagnosticsIntervalMs)
+}
+
+// ClusterConnectionHeartbeatConfig contains the parameters of the heartbeats of the shared cluster connections
+//   - An OPTIONS heartbeat is sent when a connection didn't receive a frame for IntervalMs
+//   - A connection is replaced when it misses MaxMissed heartbeats in a row
+type ClusterConnectionHeartbeatConfig struct {
+	Enabled    bool
+	IntervalMs int
+	MaxMissed  int
+}
+
+func (recv *ClusterConnectionHeartbeatConfig) String() string {
+	return fmt.Sprintf("ClusterConnectionHeartbeatConfig{Enabled=%v, IntervalMs=%v, MaxMissed=%v}",
+		recv.Enabled, recv.IntervalMs, recv.MaxMissed)
+}
+
+// TracingConfig contains the parameters of the distributed tracing of the requests
+//   - The spans are exported to Endpoint with the OTLP/HTTP protocol (JSON encoding)
+//   - Sampler decides which traces are recorded, SamplerRatio is the ratio of the TRACEIDRATIO samplers
+//   - ServiceName is the service.name resource attribute of the spans
+type TracingConfig struct {
+	Enabled      bool
+	Endpoint     string
+	Sampler      TracingSampler
+	SamplerRatio float64
+	ServiceName  string
+}
+
+func (recv *TracingConfig) String() string {
+	return fmt.Sprintf("TracingConfig{Enabled=%v, Endpoint=%v, Sampler=%v, SamplerRatio=%v, ServiceName=%v}",
+		recv.Enabled, recv.Endpoint, recv.Sampler, recv.SamplerRatio, recv.ServiceName)
+}
+
+// SlowQueryLogConfig contains the parameters of the slow query log
+//   - The requests that take longer than ThresholdMs are logged with the query in QueryFormat
+//   - SampleRatio of the slow queries are logged, MaxPerSecond at most
+type SlowQueryLogConfig struct {
+	Enabled      bool
+	ThresholdMs  int
+	QueryFormat  SlowQueryFormat
+	SampleRatio  float64
+	MaxPerSecond int
+}
+
+func (recv *SlowQueryLogConfig) String() string {
+	return fmt.Sprintf("SlowQueryLogConfig{Enabled=%v, ThresholdMs=%v, QueryFormat=%v, SampleRatio=%v, MaxPerSecond=%v}",
+		recv.Enabled, recv.ThresholdMs, recv.QueryFormat, recv.SampleRatio, recv.MaxPerSecond)
+}
+
+// AuditLogConfig contains the parameters of the audit log
+//   - Destination is FILE or SYSLOG, the audit log is disabled if it is undefined
+//   - The queries are logged in QueryFormat
+//   - The FILE audit log is written to FilePath, it is rotated after FileMaxSizeBytes and FileMaxBackups are kept
+//   - The SYSLOG audit log is sent to SyslogAddress over SyslogNetwork, or to the local syslog daemon if it is empty
+type AuditLogConfig struct {
+	Destination      AuditLogDestination
+	QueryFormat      SlowQueryFormat
+	FilePath         string
+	FileMaxSizeBytes int
+	FileMaxBackups   int
+	SyslogNetwork    string
+	SyslogAddress    string
+}
+
+func (recv *AuditLogConfig) String() string {
+	return fmt.Sprintf("AuditLogConfig{Destination=%v, QueryFormat=%v, FilePath=%v, FileMaxSizeBytes=%v, "+
+		"FileMaxBackups=%v, SyslogNetwork=%v, SyslogAddress=%v}",
+		recv.Destination, recv.QueryFormat, recv.FilePath, recv.FileMaxSizeBytes,
+		recv.FileMaxBackups, recv.SyslogNetwork, recv.SyslogAddress)
+}
+
+// FleetStoreConfig contains the parameters of the shared store that propagates the settings of the zdm_admin
+// keyspace to every proxy instance of the fleet
+//   - Type is "etcd" or "consul", the store is disabled if empty
+//   - Endpoints are the base URLs of the HTTP API of the store, e.g. http://etcd-0:2379
+//   - Key is the key that holds the settings
+type FleetStoreConfig struct {
+	Type      string
+	Endpoints []string
+	Key       string
+}
+
+func (recv *FleetStoreConfig) String() string {
+	return fmt.Sprintf("FleetStoreConfig{Type=%v, Endpoints=%v, Key=%v}", recv.Type, recv.Endpoints, recv.Key)
+}
+
+// LeaderElectionConfig contains the parameters of the election of the proxy instance that runs the singleton
+// background tasks
+//   - Key is the key of the fleet store that holds the leadership
+//   - TtlMs is how long the leadership is kept if the leader can not renew it, e.g. because it crashed
+type LeaderElectionConfig struct {
+	Enabled bool
+	Key     string
+	TtlMs   int
+}
+
+func (recv *LeaderElectionConfig) String() string {
+	return fmt.Sprintf("LeaderElectionConfig{Enabled=%v, Key=%v, TtlMs=%v}", recv.Enabled, recv.Key, recv.TtlMs)
+}
+
 type ReadMode struct {
 	slug string
 }
@@ -69,6 +350,20 @@ var (
 	ReadModeDualAsyncOnSecondary = ReadMode{"DUAL_ASYNC_ON_SECONDARY"}
 )
 
+type DualWriteMode struct {
+	slug string
+}
+
+func (r DualWriteMode) String() string {
+	return r.slug
+}
+
+var (
+	DualWriteModeUndefined = DualWriteMode{""}
+	DualWriteModeSync      = DualWriteMode{"SYNC"}
+	DualWriteModeAsync     = DualWriteMode{"ASYNC"}
+)
+
 type SystemQueriesMode struct {
 	slug string
 }
@@ -90,3 +385,318 @@ const (
 	ClusterTypeOrigin = ClusterType("ORIGIN")
 	ClusterTypeTarget = ClusterType("TARGET")
 )
+
+// WriteFilterRule describes writes that are not forwarded to TARGET (see ZDM_TARGET_WRITE_FILTER_RULES):
+// a write to Keyspace.Table is dropped from TARGET if the value that it writes (or restricts) for Column
+// satisfies Operator against Values. IN and NOT IN accept multiple values, every other operator accepts one value.
+type WriteFilterRule struct {
+	Keyspace string
+	Table    string
+	Column   string
+	Operator WriteFilterOperator
+	Values   []string
+}
+
+func (recv *WriteFilterRule) String() string {
+	return fmt.Sprintf("WriteFilterRule{Keyspace=%v, Table=%v, Column=%v, Operator=%v, Values=%v}",
+		recv.Keyspace, recv.Table, recv.Column, recv.Operator, recv.Values)
+}
+
+// ApplicationReadRoutingRule forwards the reads of the client connections whose APPLICATION_NAME is Application
+// to Cluster (see ZDM_APPLICATION_READ_ROUTING_RULES). Keyspace and Table are empty if the rule applies to
+// every keyspace or to every table of Keyspace.
+type ApplicationReadRoutingRule struct {
+	Application string
+	Keyspace    string
+	Table       string
+	Cluster     ClusterType
+}
+
+func (recv *ApplicationReadRoutingRule) String() string {
+	return fmt.Sprintf("ApplicationReadRoutingRule{Application=%v, Keyspace=%v, Table=%v, Cluster=%v}",
+		recv.Application, recv.Keyspace, recv.Table, recv.Cluster)
+}
+
+// TableRoutingRule forwards every statement on Keyspace.Table to Cluster only (see ZDM_TABLE_ROUTING_RULES).
+type TableRoutingRule struct {
+	Keyspace string
+	Table    string
+	Cluster  ClusterType
+}
+
+func (recv *TableRoutingRule) String() string {
+	return fmt.Sprintf("TableRoutingRule{Keyspace=%v, Table=%v, Cluster=%v}", recv.Keyspace, recv.Table, recv.Cluster)
+}
+
+// StatementFilterRule rejects the statements that it matches or forwards them to a single cluster
+// (see ZDM_STATEMENT_FILTER_RULES). A rule either matches the queries with Pattern or the statements of a kind on
+// a keyspace or a table.
+type StatementFilterRule struct {
+	Definition string         // the rule as it was configured
+	Statement  string         // first keyword of the statements in upper case, * matches every statement
+	Keyspace   string         // empty matches every keyspace
+	Table      string         // empty matches every table of Keyspace
+	Pattern    *regexp.Regexp // nil if the rule matches the statements by kind
+	Action     StatementFilterAction
+}
+
+func (recv *StatementFilterRule) String() string {
+	if recv.Pattern != nil {
+		return fmt.Sprintf("StatementFilterRule{Pattern=%v, Action=%v}", recv.Pattern, recv.Action)
+	}
+	return fmt.Sprintf("StatementFilterRule{Statement=%v, Keyspace=%v, Table=%v, Action=%v}",
+		recv.Statement, recv.Keyspace, recv.Table, recv.Action)
+}
+
+type StatementFilterAction struct {
+	slug string
+}
+
+func (r StatementFilterAction) String() string {
+	return r.slug
+}
+
+var (
+	StatementFilterActionUndefined = StatementFilterAction{""}
+	StatementFilterActionReject    = StatementFilterAction{"REJECT"}
+	StatementFilterActionOrigin    = StatementFilterAction{"ORIGIN"}
+	StatementFilterActionTarget    = StatementFilterAction{"TARGET"}
+)
+
+type WriteFilterOperator string
+
+const (
+	WriteFilterOperatorEqual          = WriteFilterOperator("=")
+	WriteFilterOperatorNotEqual       = WriteFilterOperator("!=")
+	WriteFilterOperatorLessThan       = WriteFilterOperator("<")
+	WriteFilterOperatorLessOrEqual    = WriteFilterOperator("<=")
+	WriteFilterOperatorGreaterThan    = WriteFilterOperator(">")
+	WriteFilterOperatorGreaterOrEqual = WriteFilterOperator(">=")
+	WriteFilterOperatorIn             = WriteFilterOperator("IN")
+	WriteFilterOperatorNotIn          = WriteFilterOperator("NOT IN")
+)
+
+type TargetDdlCompatibilityMode struct {
+	slug string
+}
+
+func (r TargetDdlCompatibilityMode) String() string {
+	return r.slug
+}
+
+var (
+	TargetDdlCompatibilityModeUndefined = TargetDdlCompatibilityMode{""}
+	TargetDdlCompatibilityModeWarn      = TargetDdlCompatibilityMode{"WARN"}
+	TargetDdlCompatibilityModeReject    = TargetDdlCompatibilityMode{"REJECT"}
+)
+
+// TargetDdlFeature is a schema feature that TARGET doesn't support (see ZDM_TARGET_DDL_UNSUPPORTED_FEATURES).
+type TargetDdlFeature string
+
+const (
+	TargetDdlFeatureMaterializedViews     = TargetDdlFeature("MATERIALIZED_VIEWS")
+	TargetDdlFeatureSasiIndexes           = TargetDdlFeature("SASI_INDEXES")
+	TargetDdlFeatureTriggers              = TargetDdlFeature("TRIGGERS")
+	TargetDdlFeatureUserDefinedFunctions  = TargetDdlFeature("USER_DEFINED_FUNCTIONS")
+	TargetDdlFeatureKeyspaceSchemaChanges = TargetDdlFeature("KEYSPACE_SCHEMA_CHANGES")
+)
+
+// SchemaStatementPolicy decides how the proxy handles a kind of schema statement
+// (see ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY, ZDM_INDEX_STATEMENTS_POLICY and ZDM_CUSTOM_INDEX_STATEMENTS_POLICY).
+type SchemaStatementPolicy struct {
+	slug string
+}
+
+func (r SchemaStatementPolicy) String() string {
+	return r.slug
+}
+
+var (
+	SchemaStatementPolicyUndefined  = SchemaStatementPolicy{""}
+	SchemaStatementPolicyBoth       = SchemaStatementPolicy{"BOTH"}
+	SchemaStatementPolicyOriginOnly = SchemaStatementPolicy{"ORIGIN_ONLY"}
+	SchemaStatementPolicyReject     = SchemaStatementPolicy{"REJECT"}
+)
+
+// LwtPolicy decides how the proxy handles the lightweight transactions that would be forwarded to both clusters
+// (see ZDM_LWT_POLICY).
+type LwtPolicy struct {
+	slug string
+}
+
+func (r LwtPolicy) String() string {
+	return r.slug
+}
+
+var (
+	LwtPolicyUndefined   = LwtPolicy{""}
+	LwtPolicyDualWrite   = LwtPolicy{"DUAL_WRITE"}
+	LwtPolicyPrimaryOnly = LwtPolicy{"PRIMARY_ONLY"}
+)
+
+// ConnectionDispatchPolicy decides which of the cluster connections of a client connection a request is sent to
+// (see ZDM_PROXY_CLUSTER_CONNECTION_DISPATCH_POLICY).
+type ConnectionDispatchPolicy struct {
+	slug string
+}
+
+func (r ConnectionDispatchPolicy) String() string {
+	return r.slug
+}
+
+var (
+	ConnectionDispatchPolicyUndefined     = ConnectionDispatchPolicy{""}
+	ConnectionDispatchPolicyRoundRobin    = ConnectionDispatchPolicy{"ROUND_ROBIN"}
+	ConnectionDispatchPolicyLeastInFlight = ConnectionDispatchPolicy{"LEAST_IN_FLIGHT"}
+)
+
+// CounterWritesPolicy decides how the proxy handles the counter updates that would be forwarded to both clusters
+// (see ZDM_COUNTER_WRITES_POLICY).
+type CounterWritesPolicy struct {
+	slug string
+}
+
+func (r CounterWritesPolicy) String() string {
+	return r.slug
+}
+
+var (
+	CounterWritesPolicyUndefined   = CounterWritesPolicy{""}
+	CounterWritesPolicyDualWrite   = CounterWritesPolicy{"DUAL_WRITE"}
+	CounterWritesPolicyPrimaryOnly = CounterWritesPolicy{"PRIMARY_ONLY"}
+	CounterWritesPolicyReject      = CounterWritesPolicy{"REJECT"}
+)
+
+// PagingStatePolicy decides how the proxy handles the paging states of the reads that belong to another cluster than
+// the cluster that the read is forwarded to (see ZDM_PAGING_STATE_POLICY).
+type PagingStatePolicy struct {
+	slug string
+}
+
+func (r PagingStatePolicy) String() string {
+	return r.slug
+}
+
+var (
+	PagingStatePolicyUndefined          = PagingStatePolicy{""}
+	PagingStatePolicyNone               = PagingStatePolicy{"NONE"}
+	PagingStatePolicyOriginatingCluster = PagingStatePolicy{"ORIGINATING_CLUSTER"}
+	PagingStatePolicyReject             = PagingStatePolicy{"REJECT"}
+)
+
+// SecondaryWriteFailureMode decides what the proxy returns to the client when a write that was forwarded to both
+// clusters only fails on the secondary cluster (see ZDM_SECONDARY_WRITE_FAILURE_MODE).
+type SecondaryWriteFailureMode struct {
+	slug string
+}
+
+func (r SecondaryWriteFailureMode) String() string {
+	return r.slug
+}
+
+var (
+	SecondaryWriteFailureModeUndefined      = SecondaryWriteFailureMode{""}
+	SecondaryWriteFailureModeReturnError    = SecondaryWriteFailureMode{"RETURN_ERROR"}
+	SecondaryWriteFailureModeLogAndContinue = SecondaryWriteFailureMode{"LOG_AND_CONTINUE"}
+	SecondaryWriteFailureModeQueueForReplay = SecondaryWriteFailureMode{"QUEUE_FOR_REPLAY"}
+)
+
+// TracingSampler decides which requests are traced (see ZDM_TRACING_SAMPLER), the PARENTBASED samplers follow the
+// sampling decision of the trace context that the client sends in the custom payload of the request.
+type TracingSampler struct {
+	slug string
+}
+
+func (r TracingSampler) String() string {
+	return r.slug
+}
+
+var (
+	TracingSamplerUndefined               = TracingSampler{""}
+	TracingSamplerAlwaysOn                = TracingSampler{"ALWAYS_ON"}
+	TracingSamplerAlwaysOff               = TracingSampler{"ALWAYS_OFF"}
+	TracingSamplerTraceIdRatio            = TracingSampler{"TRACEIDRATIO"}
+	TracingSamplerParentBasedAlwaysOn     = TracingSampler{"PARENTBASED_ALWAYS_ON"}
+	TracingSamplerParentBasedAlwaysOff    = TracingSampler{"PARENTBASED_ALWAYS_OFF"}
+	TracingSamplerParentBasedTraceIdRatio = TracingSampler{"PARENTBASED_TRACEIDRATIO"}
+)
+
+// SlowQueryFormat decides how the query of a slow request is logged (see ZDM_SLOW_QUERY_LOG_QUERY_FORMAT), the
+// literals of OBFUSCATED queries are replaced with ? and DIGEST only logs the digest of the obfuscated query.
+type SlowQueryFormat struct {
+	slug string
+}
+
+func (r SlowQueryFormat) String() string {
+	return r.slug
+}
+
+var (
+	SlowQueryFormatUndefined  = SlowQueryFormat{""}
+	SlowQueryFormatObfuscated = SlowQueryFormat{"OBFUSCATED"}
+	SlowQueryFormatDigest     = SlowQueryFormat{"DIGEST"}
+)
+
+// AuditLogDestination decides where the audit log is written (see ZDM_AUDIT_LOG_DESTINATION).
+type AuditLogDestination struct {
+	slug string
+}
+
+func (r AuditLogDestination) String() string {
+	return r.slug
+}
+
+var (
+	AuditLogDestinationUndefined = AuditLogDestination{""}
+	AuditLogDestinationFile      = AuditLogDestination{"FILE"}
+	AuditLogDestinationSyslog    = AuditLogDestination{"SYSLOG"}
+)
+
+// CredentialsMode decides which credentials the proxy sends to a cluster when the client authenticates (see
+// ZDM_ORIGIN_CREDENTIALS_MODE and ZDM_TARGET_CREDENTIALS_MODE): CLIENT forwards the credentials of the client,
+// CONFIGURED sends the credentials of the proxy configuration and AUTO picks one of them depending on which clusters
+// have authentication enabled.
+type CredentialsMode struct {
+	slug string
+}
+
+func (r CredentialsMode) String() string {
+	return r.slug
+}
+
+var (
+	CredentialsModeUndefined  = CredentialsMode{""}
+	CredentialsModeAuto       = CredentialsMode{"AUTO"}
+	CredentialsModeClient     = CredentialsMode{"CLIENT"}
+	CredentialsModeConfigured = CredentialsMode{"CONFIGURED"}
+)
+
+// LogFormat decides how the logs are formatted (see ZDM_LOG_FORMAT).
+type LogFormat struct {
+	slug string
+}
+
+func (r LogFormat) String() string {
+	return r.slug
+}
+
+var (
+	LogFormatUndefined = LogFormat{""}
+	LogFormatText      = LogFormat{"TEXT"}
+	LogFormatJson      = LogFormat{"JSON"}
+)
+
+// MetricsBackend decides where the proxy metrics are exported (see ZDM_METRICS_BACKEND).
+type MetricsBackend struct {
+	slug string
+}
+
+func (r MetricsBackend) String() string {
+	return r.slug
+}
+
+var (
+	MetricsBackendUndefined  = MetricsBackend{""}
+	MetricsBackendPrometheus = MetricsBackend{"PROMETHEUS"}
+	MetricsBackendStatsd     = MetricsBackend{"STATSD"}
+)
@@ -0,0 +1,922 @@
+package config
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// OriginConfig holds the settings of the connections to the ORIGIN cluster.
+type OriginConfig struct {
+
+	// Origin bucket
+
+	OriginContactPoints           string `split_words:"true"`
+	OriginPort                    int    `default:"9042" split_words:"true"`
+	OriginSecureConnectBundlePath string `split_words:"true"`
+	OriginLocalDatacenter         string `split_words:"true"`
+	OriginUsername                string `split_words:"true"`
+	OriginPassword                string `split_words:"true" json:"-"`
+	OriginConnectionTimeoutMs     int    `default:"30000" split_words:"true"`
+
+	// OriginConnectionAttemptDelayMs is the delay between the connection attempts to the addresses of an ORIGIN node
+	// whose host name resolves to several addresses (e.g. both A and AAAA records), the attempts alternate between
+	// IPv6 and IPv4 and the first connection that is established wins (RFC 8305). 0 disables the staggered attempts.
+	OriginConnectionAttemptDelayMs int `default:"250" split_words:"true"`
+
+	// OriginTcpNoDelay, OriginSocketReceiveBufferSizeBytes and OriginSocketSendBufferSizeBytes are the socket options
+	// of the connections to ORIGIN (TCP_NODELAY, SO_RCVBUF and SO_SNDBUF), a buffer size of 0 keeps the operating
+	// system default.
+	OriginTcpNoDelay                   bool `default:"true" split_words:"true"`
+	OriginSocketReceiveBufferSizeBytes int  `default:"0" split_words:"true"`
+	OriginSocketSendBufferSizeBytes    int  `default:"0" split_words:"true"`
+
+	// OriginUsernameFile and OriginPasswordFile are the paths of files with the ORIGIN credentials (e.g. Docker or
+	// Kubernetes secrets) that are used instead of ZDM_ORIGIN_USERNAME and ZDM_ORIGIN_PASSWORD. The files are
+	// checked for changes every ZDM_CREDENTIAL_FILES_POLL_INTERVAL_MS and the new credentials are used by the
+	// connections that are opened after the change.
+	OriginUsernameFile string `split_words:"true"`
+	OriginPasswordFile string `split_words:"true"`
+
+	// OriginTlsEnabled enables TLS on the connections to ORIGIN without a custom CA, the certificates of the nodes
+	// are then verified with the CA certificates of the system. TLS is also enabled if ZDM_ORIGIN_TLS_SERVER_CA_PATH
+	// is set. OriginTlsServerName is sent in the SNI extension and the certificates of the nodes are verified against
+	// it, the host name is not verified if it is not set. OriginTlsInsecureSkipVerify disables the verification of
+	// the certificates of the nodes altogether and should only be used for testing.
+	OriginTlsEnabled            bool   `default:"false" split_words:"true"`
+	OriginTlsServerCaPath       string `split_words:"true"`
+	OriginTlsClientCertPath     string `split_words:"true"`
+	OriginTlsClientKeyPath      string `split_words:"true"`
+	OriginTlsServerName         string `split_words:"true"`
+	OriginTlsInsecureSkipVerify bool   `default:"false" split_words:"true"`
+
+	OriginMaxConcurrentRequests int `default:"0" split_words:"true"`
+	OriginMaxQueuedRequests     int `default:"1000" split_words:"true"`
+	OriginRequestQueueTimeoutMs int `default:"5000" split_words:"true"`
+
+	// OriginMaxRequestsPerSecond limits the QUERY, EXECUTE and BATCH requests that are sent to ORIGIN, the requests
+	// over the limit get an OVERLOADED error. The limit applies to the whole fleet if ZDM_FLEET_STORE_TYPE is set,
+	// otherwise to this proxy instance. 0 means unlimited.
+	OriginMaxRequestsPerSecond int `default:"0" split_words:"true"`
+
+	// OriginLatencyBudgetMs is the latency SLO of ORIGIN: an alert is raised when the OriginLatencyBudgetPercentile
+	// percentile of the latency of the ORIGIN requests exceeds it over the last OriginLatencyBudgetWindowMs, see
+	// ZDM_LATENCY_BUDGET_WEBHOOK_URL. 0 disables the alerts.
+	OriginLatencyBudgetMs         int     `default:"0" split_words:"true"`
+	OriginLatencyBudgetPercentile float64 `default:"99" split_words:"true"`
+	OriginLatencyBudgetWindowMs   int     `default:"60000" split_words:"true"`
+
+	// OriginConsistencyOverride rewrites the consistency level of the QUERY, EXECUTE and BATCH requests that are
+	// forwarded to ORIGIN, the requests that are forwarded to the other cluster are not changed. It is either a level
+	// that replaces every non serial level (e.g. LOCAL_ONE) or comma separated <from>:<to> mappings
+	// (e.g. QUORUM:LOCAL_ONE,ALL:LOCAL_QUORUM). The serial consistency level is never changed.
+	OriginConsistencyOverride string `split_words:"true"`
+
+	// OriginCompression is the compression (NONE, LZ4 or SNAPPY) that is requested in the STARTUP requests that are sent
+	// to ORIGIN regardless of the compression requested by the client, the proxy decompresses and compresses the frames
+	// on each side. Empty means that the compression requested by the client is used. SNAPPY is not supported by
+	// protocol v5 so NONE is used instead with this version.
+	OriginCompression string `split_words:"true"`
+
+	// OriginRequestTimeoutMs is how long the proxy waits for the response of ORIGIN before the request times out, 0 means
+	// that ZDM_PROXY_REQUEST_TIMEOUT_MS is used. When a request is forwarded to both clusters it times out as soon as
+	// the response of one cluster takes longer than the timeout of that cluster. The statement type timeouts
+	// (ZDM_PROXY_READ_REQUEST_TIMEOUT_MS, ZDM_PROXY_WRITE_REQUEST_TIMEOUT_MS and ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS)
+	// override it.
+	OriginRequestTimeoutMs int `default:"0" split_words:"true"`
+
+	// OriginRequestMaxRetries is how many times the idempotent requests (SELECT statements and the writes that are not
+	// lightweight transactions or counter updates and that don't call now() or the other functions replaced by the
+	// proxy) are retried on ORIGIN when it returns a READ_TIMEOUT, WRITE_TIMEOUT, UNAVAILABLE or OVERLOADED error,
+	// 0 disables the retries. The delay before each retry grows exponentially from OriginRequestRetryBackoffMinMs to
+	// OriginRequestRetryBackoffMaxMs. The error is returned to the client when there are no retries left and the
+	// retries don't extend the request timeout.
+	OriginRequestMaxRetries        int `default:"0" split_words:"true"`
+	OriginRequestRetryBackoffMinMs int `default:"100" split_words:"true"`
+	OriginRequestRetryBackoffMaxMs int `default:"1000" split_words:"true"`
+
+	// OriginCircuitBreakerErrorThresholdPercent opens the circuit breaker of ORIGIN when at least this percentage of the
+	// responses of the last OriginCircuitBreakerWindowMs are failures (server errors, timeouts, UNAVAILABLE, OVERLOADED)
+	// and there were at least OriginCircuitBreakerMinRequests requests, 0 disables the breaker. While the breaker is open
+	// the QUERY, EXECUTE and BATCH requests to ORIGIN get an OVERLOADED error without being sent (see
+	// ZDM_CIRCUIT_BREAKER_DEGRADE_TO_PRIMARY_ONLY), one request is sent to ORIGIN after OriginCircuitBreakerOpenMs to probe
+	// it and the breaker closes again if it succeeds.
+	OriginCircuitBreakerErrorThresholdPercent int `default:"0" split_words:"true"`
+	OriginCircuitBreakerMinRequests           int `default:"20" split_words:"true"`
+	OriginCircuitBreakerWindowMs              int `default:"10000" split_words:"true"`
+	OriginCircuitBreakerOpenMs                int `default:"30000" split_words:"true"`
+
+	// OriginEnableHostAssignment isn't supported and may change at any time.
+	OriginEnableHostAssignment bool `default:"true" split_words:"true"`
+
+	// OriginForwardAuthorizationId controls whether the authorization-id (DSE proxy authentication, execute-as)
+	// sent by the client is forwarded to ORIGIN with the credentials of the client, disable it if ORIGIN doesn't support
+	// proxy authentication.
+	OriginForwardAuthorizationId bool `default:"true" split_words:"true"`
+
+	// OriginForwardAuthorizationIdWithConfiguredCredentials controls whether the authorization-id sent by the client is
+	// added to OriginUsername and OriginPassword when the proxy sends the configured credentials to ORIGIN (see
+	// OriginCredentialsMode). This grants every client the permission of the configured user to execute requests as
+	// any other role so it is disabled by default.
+	OriginForwardAuthorizationIdWithConfiguredCredentials bool `default:"false" split_words:"true"`
+
+	// OriginCredentialsMode decides which credentials are sent to ORIGIN when a client authenticates: CLIENT forwards the
+	// credentials of the client, CONFIGURED sends OriginUsername and OriginPassword and AUTO (default) forwards the client
+	// credentials to one cluster and sends the configured credentials to the other depending on which clusters have
+	// authentication enabled (see ZDM_FORWARD_CLIENT_CREDENTIALS_TO_ORIGIN).
+	OriginCredentialsMode string `default:"AUTO" split_words:"true"`
+}
+
+// TargetConfig holds the settings of the connections to the TARGET cluster.
+type TargetConfig struct {
+
+	// Target bucket
+
+	TargetContactPoints           string `split_words:"true"`
+	TargetPort                    int    `default:"9042" split_words:"true"`
+	TargetSecureConnectBundlePath string `split_words:"true"`
+	TargetLocalDatacenter         string `split_words:"true"`
+	TargetUsername                string `split_words:"true"`
+	TargetPassword                string `split_words:"true" json:"-"`
+	TargetConnectionTimeoutMs     int    `default:"30000" split_words:"true"`
+
+	// TargetConnectionAttemptDelayMs is the delay between the connection attempts to the addresses of a TARGET node
+	// whose host name resolves to several addresses (e.g. both A and AAAA records), the attempts alternate between
+	// IPv6 and IPv4 and the first connection that is established wins (RFC 8305). 0 disables the staggered attempts.
+	TargetConnectionAttemptDelayMs int `default:"250" split_words:"true"`
+
+	// TargetTcpNoDelay, TargetSocketReceiveBufferSizeBytes and TargetSocketSendBufferSizeBytes are the socket options
+	// of the connections to TARGET (TCP_NODELAY, SO_RCVBUF and SO_SNDBUF), a buffer size of 0 keeps the operating
+	// system default.
+	TargetTcpNoDelay                   bool `default:"true" split_words:"true"`
+	TargetSocketReceiveBufferSizeBytes int  `default:"0" split_words:"true"`
+	TargetSocketSendBufferSizeBytes    int  `default:"0" split_words:"true"`
+
+	// TargetUsernameFile and TargetPasswordFile are the paths of files with the TARGET credentials (e.g. Docker or
+	// Kubernetes secrets) that are used instead of ZDM_TARGET_USERNAME and ZDM_TARGET_PASSWORD. The files are
+	// checked for changes every ZDM_CREDENTIAL_FILES_POLL_INTERVAL_MS and the new credentials are used by the
+	// connections that are opened after the change.
+	TargetUsernameFile string `split_words:"true"`
+	TargetPasswordFile string `split_words:"true"`
+
+	// TargetTlsEnabled enables TLS on the connections to TARGET without a custom CA, the certificates of the nodes
+	// are then verified with the CA certificates of the system. TLS is also enabled if ZDM_TARGET_TLS_SERVER_CA_PATH
+	// is set. TargetTlsServerName is sent in the SNI extension and the certificates of the nodes are verified against
+	// it, the host name is not verified if it is not set. TargetTlsInsecureSkipVerify disables the verification of
+	// the certificates of the nodes altogether and should only be used for testing.
+	TargetTlsEnabled            bool   `default:"false" split_words:"true"`
+	TargetTlsServerCaPath       string `split_words:"true"`
+	TargetTlsClientCertPath     string `split_words:"true"`
+	TargetTlsClientKeyPath      string `split_words:"true"`
+	TargetTlsServerName         string `split_words:"true"`
+	TargetTlsInsecureSkipVerify bool   `default:"false" split_words:"true"`
+
+	TargetMaxConcurrentRequests int `default:"0" split_words:"true"`
+	TargetMaxQueuedRequests     int `default:"1000" split_words:"true"`
+	TargetRequestQueueTimeoutMs int `default:"5000" split_words:"true"`
+
+	// TargetMaxRequestsPerSecond limits the QUERY, EXECUTE and BATCH requests that are sent to TARGET, the requests
+	// over the limit get an OVERLOADED error. The limit applies to the whole fleet if ZDM_FLEET_STORE_TYPE is set,
+	// otherwise to this proxy instance. 0 means unlimited.
+	TargetMaxRequestsPerSecond int `default:"0" split_words:"true"`
+
+	// TargetLatencyBudgetMs is the latency SLO of TARGET: an alert is raised when the TargetLatencyBudgetPercentile
+	// percentile of the latency of the TARGET requests exceeds it over the last TargetLatencyBudgetWindowMs, see
+	// ZDM_LATENCY_BUDGET_WEBHOOK_URL. 0 disables the alerts.
+	TargetLatencyBudgetMs         int     `default:"0" split_words:"true"`
+	TargetLatencyBudgetPercentile float64 `default:"99" split_words:"true"`
+	TargetLatencyBudgetWindowMs   int     `default:"60000" split_words:"true"`
+
+	// TargetConsistencyOverride rewrites the consistency level of the QUERY, EXECUTE and BATCH requests that are
+	// forwarded to TARGET, the requests that are forwarded to the other cluster are not changed. It is either a level
+	// that replaces every non serial level (e.g. LOCAL_ONE) or comma separated <from>:<to> mappings
+	// (e.g. QUORUM:LOCAL_ONE,ALL:LOCAL_QUORUM). The serial consistency level is never changed.
+	TargetConsistencyOverride string `split_words:"true"`
+
+	// TargetCompression is the compression (NONE, LZ4 or SNAPPY) that is requested in the STARTUP requests that are sent
+	// to TARGET regardless of the compression requested by the client, the proxy decompresses and compresses the frames
+	// on each side. Empty means that the compression requested by the client is used. SNAPPY is not supported by
+	// protocol v5 so NONE is used instead with this version.
+	TargetCompression string `split_words:"true"`
+
+	// TargetRequestTimeoutMs is how long the proxy waits for the response of TARGET before the request times out, 0 means
+	// that ZDM_PROXY_REQUEST_TIMEOUT_MS is used. When a request is forwarded to both clusters it times out as soon as
+	// the response of one cluster takes longer than the timeout of that cluster. The statement type timeouts
+	// (ZDM_PROXY_READ_REQUEST_TIMEOUT_MS, ZDM_PROXY_WRITE_REQUEST_TIMEOUT_MS and ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS)
+	// override it.
+	TargetRequestTimeoutMs int `default:"0" split_words:"true"`
+
+	// TargetRequestMaxRetries is how many times the idempotent requests (SELECT statements and the writes that are not
+	// lightweight transactions or counter updates and that don't call now() or the other functions replaced by the
+	// proxy) are retried on TARGET when it returns a READ_TIMEOUT, WRITE_TIMEOUT, UNAVAILABLE or OVERLOADED error,
+	// 0 disables the retries. The delay before each retry grows exponentially from TargetRequestRetryBackoffMinMs to
+	// TargetRequestRetryBackoffMaxMs. The error is returned to the client when there are no retries left and the
+	// retries don't extend the request timeout.
+	TargetRequestMaxRetries        int `default:"0" split_words:"true"`
+	TargetRequestRetryBackoffMinMs int `default:"100" split_words:"true"`
+	TargetRequestRetryBackoffMaxMs int `default:"1000" split_words:"true"`
+
+	// TargetCircuitBreakerErrorThresholdPercent opens the circuit breaker of TARGET when at least this percentage of the
+	// responses of the last TargetCircuitBreakerWindowMs are failures (server errors, timeouts, UNAVAILABLE, OVERLOADED)
+	// and there were at least TargetCircuitBreakerMinRequests requests, 0 disables the breaker. While the breaker is open
+	// the QUERY, EXECUTE and BATCH requests to TARGET get an OVERLOADED error without being sent (see
+	// ZDM_CIRCUIT_BREAKER_DEGRADE_TO_PRIMARY_ONLY), one request is sent to TARGET after TargetCircuitBreakerOpenMs to probe
+	// it and the breaker closes again if it succeeds.
+	TargetCircuitBreakerErrorThresholdPercent int `default:"0" split_words:"true"`
+	TargetCircuitBreakerMinRequests           int `default:"20" split_words:"true"`
+	TargetCircuitBreakerWindowMs              int `default:"10000" split_words:"true"`
+	TargetCircuitBreakerOpenMs                int `default:"30000" split_words:"true"`
+
+	// TargetEnableHostAssignment isn't supported and may change at any time.
+	TargetEnableHostAssignment bool `default:"true" split_words:"true"`
+
+	// TargetForwardAuthorizationId controls whether the authorization-id (DSE proxy authentication, execute-as)
+	// sent by the client is forwarded to TARGET with the credentials of the client, disable it if TARGET doesn't support
+	// proxy authentication.
+	TargetForwardAuthorizationId bool `default:"true" split_words:"true"`
+
+	// TargetForwardAuthorizationIdWithConfiguredCredentials controls whether the authorization-id sent by the client is
+	// added to TargetUsername and TargetPassword when the proxy sends the configured credentials to TARGET (see
+	// TargetCredentialsMode). This grants every client the permission of the configured user to execute requests as
+	// any other role so it is disabled by default.
+	TargetForwardAuthorizationIdWithConfiguredCredentials bool `default:"false" split_words:"true"`
+
+	// TargetCredentialsMode decides which credentials are sent to TARGET when a client authenticates: CLIENT forwards the
+	// credentials of the client, CONFIGURED sends TargetUsername and TargetPassword and AUTO (default) forwards the client
+	// credentials to one cluster and sends the configured credentials to the other depending on which clusters have
+	// authentication enabled (see ZDM_FORWARD_CLIENT_CREDENTIALS_TO_ORIGIN).
+	TargetCredentialsMode string `default:"AUTO" split_words:"true"`
+}
+
+func (c *OriginConfig) Validate() error {
+	_, err := c.ParseOriginContactPoints()
+	if err != nil {
+		return fmt.Errorf("invalid origin configuration: %w", err)
+	}
+
+	_, err = c.ParseOriginTlsConfig(false)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginRequestLimitConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginMaxRequestsPerSecond()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginLatencyBudgetConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginSocketOptions()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginConsistencyOverride()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginCompression()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginRequestRetryConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginCircuitBreakerConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginCredentialsMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginConnectionAttemptDelay()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *TargetConfig) Validate() error {
+	_, err := c.ParseTargetContactPoints()
+	if err != nil {
+		return fmt.Errorf("invalid target configuration: %w", err)
+	}
+
+	_, err = c.ParseTargetTlsConfig(false)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetRequestLimitConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetMaxRequestsPerSecond()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetLatencyBudgetConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetSocketOptions()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetConsistencyOverride()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetCompression()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetRequestRetryConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetCircuitBreakerConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetCredentialsMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetConnectionAttemptDelay()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *OriginConfig) ParseOriginRequestLimitConfig() (*common.RequestLimitConfig, error) {
+	return parseRequestLimitConfig(
+		"ORIGIN", c.OriginMaxConcurrentRequests, c.OriginMaxQueuedRequests, c.OriginRequestQueueTimeoutMs)
+}
+
+func (c *TargetConfig) ParseTargetRequestLimitConfig() (*common.RequestLimitConfig, error) {
+	return parseRequestLimitConfig(
+		"TARGET", c.TargetMaxConcurrentRequests, c.TargetMaxQueuedRequests, c.TargetRequestQueueTimeoutMs)
+}
+
+func (c *OriginConfig) ParseOriginMaxRequestsPerSecond() (int, error) {
+	return parseMaxRequestsPerSecond("ORIGIN", c.OriginMaxRequestsPerSecond)
+}
+
+func (c *TargetConfig) ParseTargetMaxRequestsPerSecond() (int, error) {
+	return parseMaxRequestsPerSecond("TARGET", c.TargetMaxRequestsPerSecond)
+}
+
+func (c *OriginConfig) ParseOriginLatencyBudgetConfig() (*common.LatencyBudgetConfig, error) {
+	return parseLatencyBudgetConfig(
+		"ORIGIN", c.OriginLatencyBudgetMs, c.OriginLatencyBudgetPercentile, c.OriginLatencyBudgetWindowMs)
+}
+
+func (c *TargetConfig) ParseTargetLatencyBudgetConfig() (*common.LatencyBudgetConfig, error) {
+	return parseLatencyBudgetConfig(
+		"TARGET", c.TargetLatencyBudgetMs, c.TargetLatencyBudgetPercentile, c.TargetLatencyBudgetWindowMs)
+}
+
+func (c *OriginConfig) ParseOriginCircuitBreakerConfig() (*common.CircuitBreakerConfig, error) {
+	return parseCircuitBreakerConfig(
+		"ORIGIN", c.OriginCircuitBreakerErrorThresholdPercent, c.OriginCircuitBreakerMinRequests,
+		c.OriginCircuitBreakerWindowMs, c.OriginCircuitBreakerOpenMs)
+}
+
+func (c *TargetConfig) ParseTargetCircuitBreakerConfig() (*common.CircuitBreakerConfig, error) {
+	return parseCircuitBreakerConfig(
+		"TARGET", c.TargetCircuitBreakerErrorThresholdPercent, c.TargetCircuitBreakerMinRequests,
+		c.TargetCircuitBreakerWindowMs, c.TargetCircuitBreakerOpenMs)
+}
+
+func (c *OriginConfig) ParseOriginSocketOptions() (*common.SocketOptions, error) {
+	return parseSocketOptions(
+		"ORIGIN", c.OriginTcpNoDelay, c.OriginSocketReceiveBufferSizeBytes, c.OriginSocketSendBufferSizeBytes)
+}
+
+func (c *TargetConfig) ParseTargetSocketOptions() (*common.SocketOptions, error) {
+	return parseSocketOptions(
+		"TARGET", c.TargetTcpNoDelay, c.TargetSocketReceiveBufferSizeBytes, c.TargetSocketSendBufferSizeBytes)
+}
+
+func (c *OriginConfig) ParseOriginConsistencyOverride() (map[primitive.ConsistencyLevel]primitive.ConsistencyLevel, error) {
+	return parseConsistencyOverride("ORIGIN", c.OriginConsistencyOverride)
+}
+
+func (c *TargetConfig) ParseTargetConsistencyOverride() (map[primitive.ConsistencyLevel]primitive.ConsistencyLevel, error) {
+	return parseConsistencyOverride("TARGET", c.TargetConsistencyOverride)
+}
+
+func (c *OriginConfig) ParseOriginCompression() (primitive.Compression, error) {
+	return parseCompression("ORIGIN", c.OriginCompression)
+}
+
+func (c *TargetConfig) ParseTargetCompression() (primitive.Compression, error) {
+	return parseCompression("TARGET", c.TargetCompression)
+}
+
+func (c *OriginConfig) ParseOriginCredentialsMode() (common.CredentialsMode, error) {
+	return parseCredentialsMode("ORIGIN", c.OriginCredentialsMode)
+}
+
+func (c *TargetConfig) ParseTargetCredentialsMode() (common.CredentialsMode, error) {
+	return parseCredentialsMode("TARGET", c.TargetCredentialsMode)
+}
+
+func (c *OriginConfig) ParseOriginConnectionAttemptDelay() (int, error) {
+	return parseConnectionAttemptDelay("ORIGIN", c.OriginConnectionAttemptDelayMs)
+}
+
+func (c *TargetConfig) ParseTargetConnectionAttemptDelay() (int, error) {
+	return parseConnectionAttemptDelay("TARGET", c.TargetConnectionAttemptDelayMs)
+}
+
+func parseConnectionAttemptDelay(cluster string, attemptDelayMs int) (int, error) {
+	if attemptDelayMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_%v_CONNECTION_ATTEMPT_DELAY_MS (%v); "+
+			"it must be 0 (disabled) or a positive number", cluster, attemptDelayMs)
+	}
+	return attemptDelayMs, nil
+}
+
+func (c *OriginConfig) ParseOriginRequestRetryConfig() (*common.RequestRetryConfig, error) {
+	return parseRequestRetryConfig(
+		"ORIGIN", c.OriginRequestMaxRetries, c.OriginRequestRetryBackoffMinMs, c.OriginRequestRetryBackoffMaxMs)
+}
+
+func (c *TargetConfig) ParseTargetRequestRetryConfig() (*common.RequestRetryConfig, error) {
+	return parseRequestRetryConfig(
+		"TARGET", c.TargetRequestMaxRetries, c.TargetRequestRetryBackoffMinMs, c.TargetRequestRetryBackoffMaxMs)
+}
+
+func parseRequestRetryConfig(
+	cluster string, maxRetries int, backoffMinMs int, backoffMaxMs int) (*common.RequestRetryConfig, error) {
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_REQUEST_MAX_RETRIES (%v); "+
+			"it must be 0 (disabled) or a positive number", cluster, maxRetries)
+	}
+	if maxRetries == 0 {
+		return &common.RequestRetryConfig{Enabled: false}, nil
+	}
+	if backoffMinMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_REQUEST_RETRY_BACKOFF_MIN_MS (%v); "+
+			"it must be a positive number", cluster, backoffMinMs)
+	}
+	if backoffMaxMs < backoffMinMs {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_REQUEST_RETRY_BACKOFF_MAX_MS (%v); "+
+			"it must be greater than or equal to ZDM_%v_REQUEST_RETRY_BACKOFF_MIN_MS (%v)",
+			cluster, backoffMaxMs, cluster, backoffMinMs)
+	}
+	return &common.RequestRetryConfig{
+		Enabled:      true,
+		MaxRetries:   maxRetries,
+		BackoffMinMs: backoffMinMs,
+		BackoffMaxMs: backoffMaxMs,
+	}, nil
+}
+
+var consistencyLevelsByName = map[string]primitive.ConsistencyLevel{
+	"ANY":          primitive.ConsistencyLevelAny,
+	"ONE":          primitive.ConsistencyLevelOne,
+	"TWO":          primitive.ConsistencyLevelTwo,
+	"THREE":        primitive.ConsistencyLevelThree,
+	"QUORUM":       primitive.ConsistencyLevelQuorum,
+	"ALL":          primitive.ConsistencyLevelAll,
+	"LOCAL_QUORUM": primitive.ConsistencyLevelLocalQuorum,
+	"EACH_QUORUM":  primitive.ConsistencyLevelEachQuorum,
+	"SERIAL":       primitive.ConsistencyLevelSerial,
+	"LOCAL_SERIAL": primitive.ConsistencyLevelLocalSerial,
+	"LOCAL_ONE":    primitive.ConsistencyLevelLocalOne,
+}
+
+// parseCompression returns the compression that replaces the compression requested by the client, it is empty if
+// the compression requested by the client is used.
+func parseCompression(cluster string, setting string) (primitive.Compression, error) {
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return "", nil
+	}
+	compression := primitive.Compression(strings.ToUpper(setting))
+	switch compression {
+	case primitive.CompressionNone, primitive.CompressionLz4, primitive.CompressionSnappy:
+		return compression, nil
+	default:
+		return "", fmt.Errorf("invalid value for ZDM_%v_COMPRESSION (%v); possible values are: %v, %v and %v",
+			cluster, setting, primitive.CompressionNone, primitive.CompressionLz4, primitive.CompressionSnappy)
+	}
+}
+
+func parseCredentialsMode(cluster string, setting string) (common.CredentialsMode, error) {
+	switch strings.ToUpper(strings.TrimSpace(setting)) {
+	case common.CredentialsModeAuto.String():
+		return common.CredentialsModeAuto, nil
+	case common.CredentialsModeClient.String():
+		return common.CredentialsModeClient, nil
+	case common.CredentialsModeConfigured.String():
+		return common.CredentialsModeConfigured, nil
+	default:
+		return common.CredentialsModeUndefined, fmt.Errorf(
+			"invalid value for ZDM_%v_CREDENTIALS_MODE (%v); possible values are: %v, %v and %v",
+			cluster, setting, common.CredentialsModeAuto, common.CredentialsModeClient, common.CredentialsModeConfigured)
+	}
+}
+
+// parseConsistencyOverride returns the level that replaces each consistency level, the map is empty if the
+// override is disabled.
+func parseConsistencyOverride(
+	cluster string, setting string) (map[primitive.ConsistencyLevel]primitive.ConsistencyLevel, error) {
+	overrides := make(map[primitive.ConsistencyLevel]primitive.ConsistencyLevel)
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return overrides, nil
+	}
+
+	invalidErr := func(reason string) error {
+		return fmt.Errorf("invalid value for ZDM_%v_CONSISTENCY_OVERRIDE (%v); %v", cluster, setting, reason)
+	}
+
+	if !strings.Contains(setting, ":") {
+		level, ok := consistencyLevelsByName[strings.ToUpper(setting)]
+		if !ok {
+			return nil, invalidErr("it must be a consistency level or comma separated <from>:<to> mappings")
+		}
+		if level.IsSerial() {
+			return nil, invalidErr("a serial consistency level can only be used in a <from>:<to> mapping")
+		}
+		for _, from := range consistencyLevelsByName {
+			if from.IsNonSerial() {
+				overrides[from] = level
+			}
+		}
+		return overrides, nil
+	}
+
+	for _, mapping := range strings.Split(setting, ",") {
+		levels := strings.Split(mapping, ":")
+		if len(levels) != 2 {
+			return nil, invalidErr(fmt.Sprintf("the mapping '%v' must have the format <from>:<to>", strings.TrimSpace(mapping)))
+		}
+		var mappedLevels [2]primitive.ConsistencyLevel
+		for i, name := range levels {
+			level, ok := consistencyLevelsByName[strings.ToUpper(strings.TrimSpace(name))]
+			if !ok {
+				return nil, invalidErr(fmt.Sprintf("unknown consistency level '%v'", strings.TrimSpace(name)))
+			}
+			mappedLevels[i] = level
+		}
+		if _, ok := overrides[mappedLevels[0]]; ok {
+			return nil, invalidErr(fmt.Sprintf("the consistency level '%v' is mapped more than once",
+				strings.TrimSpace(levels[0])))
+		}
+		overrides[mappedLevels[0]] = mappedLevels[1]
+	}
+	return overrides, nil
+}
+
+// parseSocketOptions is also used for the listener (ZDM_PROXY_* settings).
+func parseSocketOptions(
+	prefix string, noDelay bool, receiveBufferSizeBytes int, sendBufferSizeBytes int) (*common.SocketOptions, error) {
+	if receiveBufferSizeBytes < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_SOCKET_RECEIVE_BUFFER_SIZE_BYTES (%v); "+
+			"it must be 0 (operating system default) or a positive number", prefix, receiveBufferSizeBytes)
+	}
+	if sendBufferSizeBytes < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_SOCKET_SEND_BUFFER_SIZE_BYTES (%v); "+
+			"it must be 0 (operating system default) or a positive number", prefix, sendBufferSizeBytes)
+	}
+	return &common.SocketOptions{
+		NoDelay:                noDelay,
+		ReceiveBufferSizeBytes: receiveBufferSizeBytes,
+		SendBufferSizeBytes:    sendBufferSizeBytes,
+	}, nil
+}
+
+func parseLatencyBudgetConfig(
+	cluster string, budgetMs int, percentile float64, windowMs int) (*common.LatencyBudgetConfig, error) {
+	if budgetMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_LATENCY_BUDGET_MS (%v); "+
+			"it must be 0 (disabled) or a positive number", cluster, budgetMs)
+	}
+	if budgetMs == 0 {
+		return &common.LatencyBudgetConfig{Enabled: false}, nil
+	}
+	if percentile <= 0 || percentile >= 100 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_LATENCY_BUDGET_PERCENTILE (%v); "+
+			"it must be greater than 0 and lower than 100", cluster, percentile)
+	}
+	if windowMs < 1000 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_LATENCY_BUDGET_WINDOW_MS (%v); "+
+			"it must be at least 1000", cluster, windowMs)
+	}
+	return &common.LatencyBudgetConfig{
+		Enabled:    true,
+		BudgetMs:   budgetMs,
+		Percentile: percentile,
+		WindowMs:   windowMs,
+	}, nil
+}
+
+func parseCircuitBreakerConfig(
+	cluster string, errorThresholdPercent int, minRequests int, windowMs int, openMs int) (*common.CircuitBreakerConfig, error) {
+	if errorThresholdPercent < 0 || errorThresholdPercent > 100 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT (%v); "+
+			"it must be 0 (disabled) or a number between 1 and 100", cluster, errorThresholdPercent)
+	}
+	if errorThresholdPercent == 0 {
+		return &common.CircuitBreakerConfig{Enabled: false}, nil
+	}
+	if minRequests <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_CIRCUIT_BREAKER_MIN_REQUESTS (%v); "+
+			"it must be a positive number", cluster, minRequests)
+	}
+	if windowMs < 100 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_CIRCUIT_BREAKER_WINDOW_MS (%v); "+
+			"it must be at least 100", cluster, windowMs)
+	}
+	if openMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_CIRCUIT_BREAKER_OPEN_MS (%v); "+
+			"it must be a positive number", cluster, openMs)
+	}
+	return &common.CircuitBreakerConfig{
+		Enabled:               true,
+		ErrorThresholdPercent: errorThresholdPercent,
+		MinRequests:           minRequests,
+		WindowMs:              windowMs,
+		OpenMs:                openMs,
+	}, nil
+}
+
+func parseMaxRequestsPerSecond(cluster string, maxRequestsPerSecond int) (int, error) {
+	if maxRequestsPerSecond < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_%v_MAX_REQUESTS_PER_SECOND (%v); "+
+			"it must be 0 (unlimited) or a positive number", cluster, maxRequestsPerSecond)
+	}
+	return maxRequestsPerSecond, nil
+}
+
+func parseRequestLimitConfig(
+	cluster string, maxConcurrentRequests int, maxQueuedRequests int, queueTimeoutMs int) (*common.RequestLimitConfig, error) {
+	if maxConcurrentRequests < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_MAX_CONCURRENT_REQUESTS (%v); "+
+			"it must be 0 (unlimited) or a positive number", cluster, maxConcurrentRequests)
+	}
+	if maxConcurrentRequests == 0 {
+		return &common.RequestLimitConfig{Enabled: false}, nil
+	}
+	if maxQueuedRequests < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_MAX_QUEUED_REQUESTS (%v); "+
+			"it must be 0 (no queueing) or a positive number", cluster, maxQueuedRequests)
+	}
+	if queueTimeoutMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_%v_REQUEST_QUEUE_TIMEOUT_MS (%v); "+
+			"it must be a positive number", cluster, queueTimeoutMs)
+	}
+	return &common.RequestLimitConfig{
+		Enabled:               true,
+		MaxConcurrentRequests: maxConcurrentRequests,
+		MaxQueuedRequests:     maxQueuedRequests,
+		QueueTimeoutMs:        queueTimeoutMs,
+	}, nil
+}
+
+func (c *OriginConfig) ParseOriginContactPoints() ([]string, error) {
+	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginContactPoints) {
+		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginContactPoints are mutually exclusive. Please specify only one of them.")
+	}
+
+	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginLocalDatacenter) {
+		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginLocalDatacenter are mutually exclusive. Please specify only one of them.")
+	}
+
+	if isNotDefined(c.OriginSecureConnectBundlePath) && isNotDefined(c.OriginContactPoints) {
+		return nil, fmt.Errorf("Both OriginSecureConnectBundlePath and OriginContactPoints are empty. Please specify either one of them.")
+	}
+
+	if isDefined(c.OriginContactPoints) && (c.OriginPort == 0) {
+		return nil, fmt.Errorf("OriginContactPoints was specified but the port is missing. Please provide OriginPort")
+	}
+
+	if (c.OriginEnableHostAssignment == false) && (isDefined(c.OriginLocalDatacenter)) {
+		return nil, fmt.Errorf("OriginLocalDatacenter was specified but OriginEnableHostAssignment is false. Please enable host assignment or don't set the datacenter.")
+	}
+
+	if isNotDefined(c.OriginSecureConnectBundlePath) {
+		contactPoints := parseContactPoints(c.OriginContactPoints)
+		if len(contactPoints) <= 0 {
+			return nil, fmt.Errorf("could not parse origin contact points: %v", c.OriginContactPoints)
+		}
+
+		return contactPoints, nil
+	}
+
+	return nil, nil
+}
+
+func (c *TargetConfig) ParseTargetContactPoints() ([]string, error) {
+	if isDefined(c.TargetSecureConnectBundlePath) && isDefined(c.TargetContactPoints) {
+		return nil, fmt.Errorf("TargetSecureConnectBundlePath and TargetContactPoints are mutually exclusive. Please specify only one of them.")
+	}
+
+	if isDefined(c.TargetSecureConnectBundlePath) && isDefined(c.TargetLocalDatacenter) {
+		return nil, fmt.Errorf("TargetSecureConnectBundlePath and TargetLocalDatacenter are mutually exclusive. Please specify only one of them.")
+	}
+
+	if isNotDefined(c.TargetSecureConnectBundlePath) && isNotDefined(c.TargetContactPoints) {
+		return nil, fmt.Errorf("Both TargetSecureConnectBundlePath and TargetContactPoints are empty. Please specify either one of them.")
+	}
+
+	if (isDefined(c.TargetContactPoints)) && (c.TargetPort == 0) {
+		return nil, fmt.Errorf("TargetContactPoints was specified but the port is missing. Please provide TargetPort")
+	}
+
+	if (c.TargetEnableHostAssignment == false) && (isDefined(c.TargetLocalDatacenter)) {
+		return nil, fmt.Errorf("TargetLocalDatacenter was specified but TargetEnableHostAssignment is false. Please enable host assignment or don't set the datacenter.")
+	}
+
+	if isNotDefined(c.TargetSecureConnectBundlePath) {
+		contactPoints := parseContactPoints(c.TargetContactPoints)
+		if len(contactPoints) <= 0 {
+			return nil, fmt.Errorf("could not parse target contact points: %v", c.TargetContactPoints)
+		}
+
+		return contactPoints, nil
+	}
+
+	return nil, nil
+}
+
+func parseContactPoints(setting string) []string {
+	contactPoints := strings.Split(strings.ReplaceAll(setting, " ", ""), ",")
+	for i, contactPoint := range contactPoints {
+		contactPoints[i] = trimAddressBrackets(contactPoint)
+	}
+	return contactPoints
+}
+
+func (c *OriginConfig) ParseOriginTlsConfig(displayLogMessages bool) (*common.ClusterTlsConfig, error) {
+
+	// No TLS defined
+
+	if isNotDefined(c.OriginSecureConnectBundlePath) &&
+		!c.OriginTlsEnabled &&
+		isNotDefined(c.OriginTlsServerCaPath) &&
+		isNotDefined(c.OriginTlsClientCertPath) &&
+		isNotDefined(c.OriginTlsClientKeyPath) {
+		if isDefined(c.OriginTlsServerName) || c.OriginTlsInsecureSkipVerify {
+			return &common.ClusterTlsConfig{}, fmt.Errorf("incomplete TLS configuration for Origin: " +
+				"ZDM_ORIGIN_TLS_SERVER_NAME and ZDM_ORIGIN_TLS_INSECURE_SKIP_VERIFY require ZDM_ORIGIN_TLS_ENABLED " +
+				"or ZDM_ORIGIN_TLS_SERVER_CA_PATH to be set")
+		}
+		if displayLogMessages {
+			log.Infof("TLS was not configured for Origin")
+		}
+		return &common.ClusterTlsConfig{
+			TlsEnabled: false,
+		}, nil
+	}
+
+	//SCB specified
+
+	if isDefined(c.OriginSecureConnectBundlePath) {
+		if isDefined(c.OriginTlsServerCaPath) || isDefined(c.OriginTlsClientCertPath) || isDefined(c.OriginTlsClientKeyPath) ||
+			isDefined(c.OriginTlsServerName) || c.OriginTlsInsecureSkipVerify {
+			return &common.ClusterTlsConfig{}, fmt.Errorf("Incorrect TLS configuration for Origin: Secure Connect Bundle and custom TLS parameters cannot be specified at the same time.")
+		}
+
+		if displayLogMessages {
+			log.Infof("Mutual TLS configured for Origin using an Astra secure connect bundle")
+		}
+		return &common.ClusterTlsConfig{
+			TlsEnabled:              true,
+			SecureConnectBundlePath: c.OriginSecureConnectBundlePath,
+		}, nil
+	}
+
+	// Custom TLS params specified
+
+	if isDefined(c.OriginTlsClientCertPath) != isDefined(c.OriginTlsClientKeyPath) ||
+		(isNotDefined(c.OriginTlsServerCaPath) && !c.OriginTlsEnabled) {
+		return &common.ClusterTlsConfig{}, fmt.Errorf("incomplete TLS configuration for Origin: when using mutual TLS, " +
+			"please specify Server CA path, Client Cert path and Client Key path")
+	}
+
+	if displayLogMessages {
+		tlsMode := "One-way"
+		if isDefined(c.OriginTlsClientCertPath) {
+			tlsMode = "Mutual"
+		}
+		if c.OriginTlsInsecureSkipVerify {
+			log.Warnf("%v TLS configured for Origin without verification of the certificates of the nodes.", tlsMode)
+		} else if isDefined(c.OriginTlsServerName) {
+			log.Infof("%v TLS configured for Origin with host name verification against %v.", tlsMode, c.OriginTlsServerName)
+		} else {
+			log.Infof("%v TLS configured for Origin. Please note that the host name is only verified if "+
+				"ZDM_ORIGIN_TLS_SERVER_NAME is set.", tlsMode)
+		}
+	}
+	return &common.ClusterTlsConfig{
+		TlsEnabled:         true,
+		ServerCaPath:       c.OriginTlsServerCaPath,
+		ClientCertPath:     c.OriginTlsClientCertPath,
+		ClientKeyPath:      c.OriginTlsClientKeyPath,
+		ServerName:         c.OriginTlsServerName,
+		InsecureSkipVerify: c.OriginTlsInsecureSkipVerify,
+	}, nil
+}
+
+func (c *TargetConfig) ParseTargetTlsConfig(displayLogMessages bool) (*common.ClusterTlsConfig, error) {
+
+	// No TLS defined
+
+	if isNotDefined(c.TargetSecureConnectBundlePath) &&
+		!c.TargetTlsEnabled &&
+		isNotDefined(c.TargetTlsServerCaPath) &&
+		isNotDefined(c.TargetTlsClientCertPath) &&
+		isNotDefined(c.TargetTlsClientKeyPath) {
+		if isDefined(c.TargetTlsServerName) || c.TargetTlsInsecureSkipVerify {
+			return &common.ClusterTlsConfig{}, fmt.Errorf("incomplete TLS configuration for Target: " +
+				"ZDM_TARGET_TLS_SERVER_NAME and ZDM_TARGET_TLS_INSECURE_SKIP_VERIFY require ZDM_TARGET_TLS_ENABLED " +
+				"or ZDM_TARGET_TLS_SERVER_CA_PATH to be set")
+		}
+		if displayLogMessages {
+			log.Infof("TLS was not configured for Target")
+		}
+		return &common.ClusterTlsConfig{
+			TlsEnabled: false,
+		}, nil
+	}
+
+	//SCB specified
+
+	if isDefined(c.TargetSecureConnectBundlePath) {
+		if isDefined(c.TargetTlsServerCaPath) || isDefined(c.TargetTlsClientCertPath) || isDefined(c.TargetTlsClientKeyPath) ||
+			isDefined(c.TargetTlsServerName) || c.TargetTlsInsecureSkipVerify {
+			return &common.ClusterTlsConfig{}, fmt.Errorf("Incorrect TLS configuration for Target: Secure Connect Bundle and custom TLS parameters cannot be specified at the same time.")
+		}
+
+		return &common.ClusterTlsConfig{
+			TlsEnabled:              true,
+			SecureConnectBundlePath: c.TargetSecureConnectBundlePath,
+		}, nil
+	}
+
+	// Custom TLS params specified
+
+	if isDefined(c.TargetTlsClientCertPath) != isDefined(c.TargetTlsClientKeyPath) ||
+		(isNotDefined(c.TargetTlsServerCaPath) && !c.TargetTlsEnabled) {
+		return &common.ClusterTlsConfig{}, fmt.Errorf("incomplete TLS configuration for Target: when using mutual TLS, " +
+			"please specify Server CA path, Client Cert path and Client Key path")
+	}
+
+	if displayLogMessages {
+		tlsMode := "One-way"
+		if isDefined(c.TargetTlsClientCertPath) {
+			tlsMode = "Mutual"
+		}
+		if c.TargetTlsInsecureSkipVerify {
+			log.Warnf("%v TLS configured for Target without verification of the certificates of the nodes.", tlsMode)
+		} else if isDefined(c.TargetTlsServerName) {
+			log.Infof("%v TLS configured for Target with host name verification against %v.", tlsMode, c.TargetTlsServerName)
+		} else {
+			log.Infof("%v TLS configured for Target. Please note that the host name is only verified if "+
+				"ZDM_TARGET_TLS_SERVER_NAME is set.", tlsMode)
+		}
+	}
+	return &common.ClusterTlsConfig{
+		TlsEnabled:         true,
+		ServerCaPath:       c.TargetTlsServerCaPath,
+		ClientCertPath:     c.TargetTlsClientCertPath,
+		ClientKeyPath:      c.TargetTlsClientKeyPath,
+		ServerName:         c.TargetTlsServerName,
+		InsecureSkipVerify: c.TargetTlsInsecureSkipVerify,
+	}, nil
+}
@@ -7,76 +7,140 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	log "github.com/sirupsen/logrus"
 	"net"
+	"net/url"
+	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Config holds the values of environment variables necessary for proper Proxy function.
+//
+// Related settings are grouped in sections (OriginConfig, TargetConfig, ListenerConfig, MetricsConfig, LoggingConfig
+// and RoutingConfig) that validate their own settings. The sections are embedded so the field names and the
+// environment variable names are the same as the ones of the settings that don't belong to a section.
 type Config struct {
 
 	// Global bucket
 
-	PrimaryCluster          string `default:"ORIGIN" split_words:"true"`
-	ReadMode                string `default:"PRIMARY_ONLY" split_words:"true"`
-	ReplaceCqlFunctions     bool   `default:"false" split_words:"true"`
-	AsyncHandshakeTimeoutMs int    `default:"4000" split_words:"true"`
-	LogLevel                string `default:"INFO" split_words:"true"`
+	LogLevel string `default:"INFO" split_words:"true"`
+
+	// LogFormat is TEXT or JSON, the logs of the client connections have a connection_id field and the logs of their
+	// requests also have a request_id field so that the logs of the client handler and of the cluster connectors for
+	// the same request can be joined.
+	LogFormat string `default:"TEXT" split_words:"true"`
+
+	// ConfigFile is a YAML (.yaml or .yml) or JSON (.json) file with settings, the keys are the names of the
+	// environment variables with or without the ZDM_ prefix (case insensitive) and the environment variables override
+	// the settings of the file. It can also be provided with the -config flag.
+	ConfigFile string `split_words:"true"`
+
+	// ConfigFilePollIntervalMs is how often the config file is checked for changes, 0 disables the change detection.
+	// The changes of the settings that can be changed at runtime (see ReloadableSettings) are applied without
+	// restarting the proxy, the config file is also reloaded when the proxy receives a SIGHUP signal.
+	ConfigFilePollIntervalMs int `default:"10000" split_words:"true"`
+
+	// ExplainRequests logs the routing decisions of every request at DEBUG level.
+	// The requests of the clients in ExplainRequestsClientAddresses (comma separated IPs) are explained at INFO level.
+	ExplainRequests                bool   `default:"false" split_words:"true"`
+	ExplainRequestsClientAddresses string `split_words:"true"`
+
+	// AdminKeyspaceRoles (comma separated) are the roles that can operate the proxy with CQL statements on the
+	// reserved zdm_admin keyspace, e.g. UPDATE zdm_admin.settings SET read_routing = 'target'. The role of a client
+	// is the username of the credentials that it authenticated with. The zdm_admin keyspace is disabled if empty.
+	AdminKeyspaceRoles string `split_words:"true"`
+
+	// ConfigDecryptionCommand decrypts the settings that are provided encrypted with the enc:cmd:<base64 ciphertext>
+	// format, e.g. "age --decrypt --identity /etc/zdm-proxy/key.txt" or "gpg --batch --quiet --decrypt". The ciphertext
+	// is written to the stdin of the command and the plaintext is read from its stdout. Other providers (e.g. a KMS
+	// client) can be added with RegisterDecryptionProvider and used with the enc:<provider>:<base64 ciphertext> format.
+	ConfigDecryptionCommand string `split_words:"true"`
+
+	// CredentialFilesPollIntervalMs is how often the credential files (ZDM_*_USERNAME_FILE and ZDM_*_PASSWORD_FILE)
+	// are checked for changes, 0 disables the change detection.
+	CredentialFilesPollIntervalMs int `default:"10000" split_words:"true"`
+
+	// FleetStoreType ("etcd" or "consul") enables the propagation of the settings of the zdm_admin keyspace (e.g.
+	// read_routing) to every proxy instance through a shared store, the instances watch the FleetStoreKey key of
+	// the store at FleetStoreEndpoints (comma separated base URLs of the HTTP API). ZDM_ADMIN_KEYSPACE_ROLES
+	// must be set.
+	FleetStoreType      string `split_words:"true"`
+	FleetStoreEndpoints string `split_words:"true"`
+	FleetStoreKey       string `default:"zdm-proxy/settings" split_words:"true"`
+
+	// LeaderElectionEnabled elects the proxy instance that runs the singleton background tasks through the fleet store
+	// (ZDM_FLEET_STORE_TYPE), the leadership is held by the LeaderElectionKey key and it expires LeaderElectionTtlMs
+	// after the leader stops renewing it.
+	LeaderElectionEnabled bool   `default:"false" split_words:"true"`
+	LeaderElectionKey     string `default:"zdm-proxy/leader" split_words:"true"`
+	LeaderElectionTtlMs   int    `default:"15000" split_words:"true"`
+
+	// AdminApiPort serves the admin API (/admin/*) on a separate HTTP endpoint at AdminApiAddress instead of the
+	// metrics endpoint (ZDM_METRICS_ADDRESS and ZDM_METRICS_PORT), the admin API is served on the metrics endpoint
+	// if it is 0.
+	AdminApiAddress string `default:"localhost" split_words:"true"`
+	AdminApiPort    int    `default:"0" split_words:"true"`
+
+	// TrafficCaptureDirectory enables the traffic capture of the admin API (/admin/capture), the frames that are
+	// exchanged with the selected client connections are written to a pcap file in this directory. A capture
+	// stops after TrafficCaptureMaxDurationMs at most or when the file reaches TrafficCaptureMaxFileSizeBytes.
+	TrafficCaptureDirectory        string `split_words:"true"`
+	TrafficCaptureMaxDurationMs    int    `default:"3600000" split_words:"true"`
+	TrafficCaptureMaxFileSizeBytes int    `default:"104857600" split_words:"true"`
+
+	// LatencyBudgetWebhookUrl receives a POST request with a JSON body when the latency budget of a cluster
+	// (ZDM_ORIGIN_LATENCY_BUDGET_MS and ZDM_TARGET_LATENCY_BUDGET_MS) is breached and when it recovers.
+	LatencyBudgetWebhookUrl string `split_words:"true"`
+
+	// StallDetectionThresholdMs is the time after which a write queue or a worker pool that doesn't make progress
+	// (or that stays full) is considered stalled, the goroutine stacks and the main gauges are then logged once every
+	// StallDiagnosticsIntervalMs at most. The stall detection is disabled if StallDetectionThresholdMs is 0.
+	StallDetectionThresholdMs  int `default:"30000" split_words:"true"`
+	StallDiagnosticsIntervalMs int `default:"3600000" split_words:"true"`
+
+	// DiagnosticsEnabled serves the profiles of the Go runtime (/admin/debug/pprof/) and a snapshot of the goroutines
+	// and of the heap (/admin/diagnostics) on the admin API. RuntimeStatsLogIntervalMs logs the number of goroutines,
+	// the heap usage and the garbage collection pauses periodically, 0 disables it.
+	DiagnosticsEnabled        bool `default:"false" split_words:"true"`
+	RuntimeStatsLogIntervalMs int  `default:"0" split_words:"true"`
+
+	RoutingConfig
 
 	// Proxy Topology (also known as system.peers "virtualization") bucket
 
+	// ProxyTopologyVirtualizationEnabled intercepts the system.local and system.peers queries and returns the proxy
+	// instances (ProxyTopologyAddresses) with synthetic host ids and tokens so the drivers only connect to the proxy.
+	// If it is disabled these queries are forwarded to the cluster (ZDM_SYSTEM_QUERIES_MODE) like other system queries
+	// and the drivers see the nodes of the cluster, this should only be used if the drivers can't reach the nodes.
+	ProxyTopologyVirtualizationEnabled bool `default:"true" split_words:"true"`
+
 	ProxyTopologyIndex     int    `default:"0" split_words:"true"`
 	ProxyTopologyAddresses string `split_words:"true"`
 	ProxyTopologyNumTokens int    `default:"8" split_words:"true"`
 
-	// Origin bucket
-
-	OriginContactPoints           string `split_words:"true"`
-	OriginPort                    int    `default:"9042" split_words:"true"`
-	OriginSecureConnectBundlePath string `split_words:"true"`
-	OriginLocalDatacenter         string `split_words:"true"`
-	OriginUsername                string `required:"true" split_words:"true"`
-	OriginPassword                string `required:"true" split_words:"true" json:"-"`
-	OriginConnectionTimeoutMs     int    `default:"30000" split_words:"true"`
-
-	OriginTlsServerCaPath   string `split_words:"true"`
-	OriginTlsClientCertPath string `split_words:"true"`
-	OriginTlsClientKeyPath  string `split_words:"true"`
-
-	// Target bucket
-
-	TargetContactPoints           string `split_words:"true"`
-	TargetPort                    int    `default:"9042" split_words:"true"`
-	TargetSecureConnectBundlePath string `split_words:"true"`
-	TargetLocalDatacenter         string `split_words:"true"`
-	TargetUsername                string `required:"true" split_words:"true"`
-	TargetPassword                string `required:"true" split_words:"true" json:"-"`
-	TargetConnectionTimeoutMs     int    `default:"30000" split_words:"true"`
-
-	TargetTlsServerCaPath   string `split_words:"true"`
-	TargetTlsClientCertPath string `split_words:"true"`
-	TargetTlsClientKeyPath  string `split_words:"true"`
-
-	// Proxy bucket
-
-	ProxyListenAddress        string `default:"localhost" split_words:"true"`
-	ProxyListenPort           int    `default:"14002" split_words:"true"`
-	ProxyRequestTimeoutMs     int    `default:"10000" split_words:"true"`
-	ProxyMaxClientConnections int    `default:"1000" split_words:"true"`
-
-	ProxyTlsCaPath            string `split_words:"true"`
-	ProxyTlsCertPath          string `split_words:"true"`
-	ProxyTlsKeyPath           string `split_words:"true"`
-	ProxyTlsRequireClientAuth bool   `split_words:"true"`
-
-	// Metrics bucket
-
-	MetricsEnabled bool   `default:"true" split_words:"true"`
-	MetricsAddress string `default:"localhost" split_words:"true"`
-	MetricsPort    int    `default:"14001" split_words:"true"`
-
-	MetricsOriginLatencyBucketsMs    string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
-	MetricsTargetLatencyBucketsMs    string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
-	MetricsAsyncReadLatencyBucketsMs string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
+	// ProxyTopologyAddressesFile is a file with the addresses of the proxy instances (comma or newline separated)
+	// that replaces ProxyTopologyAddresses. The file is checked for changes every
+	// ProxyTopologyAddressesFilePollIntervalMs (0 disables the change detection) so that proxy instances can be
+	// added or removed without restarting the other instances, the drivers are notified with TOPOLOGY_CHANGE events.
+	// Each instance keeps the address that ProxyTopologyIndex pointed to when it started so it can move in the list.
+	ProxyTopologyAddressesFile               string `split_words:"true"`
+	ProxyTopologyAddressesFilePollIntervalMs int    `default:"10000" split_words:"true"`
+
+	// InterceptedQueriesCacheTtlMs is how long the responses of the intercepted system.local and system.peers queries
+	// are cached and reused for other client connections, 0 disables the cache.
+	InterceptedQueriesCacheTtlMs int `default:"1000" split_words:"true"`
+
+	// ProxyVirtualTablesEnabled makes the proxy answer the queries on the system_views.zdm_* tables with its own
+	// state (connected clients, prepared statements and routing rules). Note that the addresses and application
+	// names of the connected clients are visible to every client that can connect to the proxy.
+	ProxyVirtualTablesEnabled bool `default:"false" split_words:"true"`
+
+	OriginConfig
+	TargetConfig
+	ListenerConfig
+	MetricsConfig
+	LoggingConfig
 
 	// Heartbeat bucket
 
@@ -87,17 +151,20 @@ type Config struct {
 	HeartbeatRetryBackoffFactor float64 `default:"2" split_words:"true"`
 	HeartbeatFailureThreshold   int     `default:"1" split_words:"true"`
 
+	// HeartbeatClusterConnectionsEnabled sends an OPTIONS heartbeat on the shared cluster connections (see
+	// ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE and ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT) that didn't receive a frame
+	// for HeartbeatIntervalMs. The connections that miss HeartbeatMaxMissed heartbeats in a row are closed and their
+	// clients are moved to a new connection. The dedicated cluster connections don't send heartbeats because the stream
+	// ids are owned by the client.
+	HeartbeatClusterConnectionsEnabled bool `default:"true" split_words:"true"`
+	HeartbeatMaxMissed                 int  `default:"3" split_words:"true"`
+
 	//////////////////////////////////////////////////////////////////////
 	/// THE SETTINGS BELOW AREN'T SUPPORTED AND MAY CHANGE AT ANY TIME ///
 	//////////////////////////////////////////////////////////////////////
 
-	SystemQueriesMode string `default:"ORIGIN" split_words:"true"`
-
 	ForwardClientCredentialsToOrigin bool `default:"false" split_words:"true"` // only takes effect if both clusters have auth enabled
 
-	OriginEnableHostAssignment bool `default:"true" split_words:"true"`
-	TargetEnableHostAssignment bool `default:"true" split_words:"true"`
-
 	//////////////////////////////////////////////////////////////////////////////////////////////////////////
 	/// THE SETTINGS BELOW ARE FOR PERFORMANCE TUNING; THEY AREN'T SUPPORTED AND MAY CHANGE AT ANY TIME //////
 	//////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -119,10 +186,28 @@ type Config struct {
 
 	AsyncConnectorWriteQueueSizeFrames int `default:"2048" split_words:"true"`
 	AsyncConnectorWriteBufferSizeBytes int `default:"4096" split_words:"true"`
+
+	// names of the fields that were provided encrypted, their values are not displayed by String
+	decryptedFields map[string]bool
+
+	// environment variable names of the settings of the config file
+	fileSettings map[string]bool
 }
 
 func (c *Config) String() string {
 	serializedConfig, _ := json.Marshal(c)
+	if len(c.decryptedFields) == 0 {
+		return string(serializedConfig)
+	}
+
+	var fields map[string]interface{}
+	_ = json.Unmarshal(serializedConfig, &fields)
+	for name := range c.decryptedFields {
+		if _, ok := fields[name]; ok {
+			fields[name] = decryptedValuePlaceholder
+		}
+	}
+	serializedConfig, _ = json.Marshal(fields)
 	return string(serializedConfig)
 }
 
@@ -131,25 +216,99 @@ func New() *Config {
 	return &Config{}
 }
 
+// Defaults returns a Config struct with the default value of every setting, i.e., the values that ParseEnvVars uses
+// for the environment variables that are not set. Required settings (e.g. credentials) are left empty.
+func Defaults() *Config {
+	c := New()
+	err := applyDefaults(reflect.ValueOf(c).Elem())
+	if err != nil {
+		// the default tags are part of the source code so this can only happen due to a bug
+		panic(err)
+	}
+	return c
+}
+
+// applyDefaults sets the value of the "default" tag of every field of the struct and its embedded sections.
+func applyDefaults(structValue reflect.Value) error {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := structValue.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			err := applyDefaults(fieldValue)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		defaultValue, ok := field.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+
+		err := setFieldValue(fieldValue, defaultValue)
+		if err != nil {
+			return fmt.Errorf("invalid default value for %v: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
 // ParseEnvVars fills out the fields of the Config struct according to envconfig rules
 // See: Usage @ https://github.com/kelseyhightower/envconfig
+// The settings of the config file of ZDM_CONFIG_FILE are applied if it is set, see ParseConfigFileAndEnvVars.
 func (c *Config) ParseEnvVars() (*Config, error) {
+	return c.ParseConfigFileAndEnvVars("")
+}
+
+// ParseConfigFileAndEnvVars fills out the fields of the Config struct with the settings of the YAML or JSON config
+// file at configFile (ZDM_CONFIG_FILE if empty, no file if both are empty) and the environment variables, that
+// override the settings of the file.
+func (c *Config) ParseConfigFileAndEnvVars(configFile string) (*Config, error) {
 	err := envconfig.Process("ZDM", c)
 	if err != nil {
 		return nil, fmt.Errorf("could not load environment variables: %w", err)
 	}
 
+	if configFile == "" {
+		configFile = c.ConfigFile
+	}
+	if configFile != "" {
+		c.ConfigFile = configFile
+		err = c.applyConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.readCredentialFiles()
+	if err != nil {
+		return nil, fmt.Errorf("could not load environment variables: %w", err)
+	}
+
+	err = c.decryptValues()
+	if err != nil {
+		return nil, err
+	}
+
+	c.ProxyListenAddress = trimAddressBrackets(c.ProxyListenAddress)
+	c.MetricsAddress = trimAddressBrackets(c.MetricsAddress)
+	c.AdminApiAddress = trimAddressBrackets(c.AdminApiAddress)
+
 	err = c.Validate()
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("Parsed configuration: %v", c)
+	log.Infof("Parsed configuration:\n%v", c.Dump())
 
 	return c, nil
 }
 
-func lookupFirstIp4(host string) (net.IP, error) {
+// lookupFirstIp returns the first IPv4 address that host resolves to or the first IPv6 address if it doesn't resolve
+// to an IPv4 address.
+func lookupFirstIp(host string) (net.IP, error) {
 	ips, err := net.LookupIP(host)
 	if err != nil {
 		return nil, err
@@ -160,18 +319,31 @@ func lookupFirstIp4(host string) (net.IP, error) {
 			return ip4, nil
 		}
 	}
-	return nil, fmt.Errorf("could not resolve %v to an ipv4 address", host)
+	if len(ips) > 0 {
+		return ips[0], nil
+	}
+	return nil, fmt.Errorf("could not resolve %v to an ip address", host)
 }
 
 func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 	var proxyAddressesTyped []net.IP
 	defaultLocalIp4Addr := net.IPv4(127, 0, 0, 1)
-	if isNotDefined(c.ProxyTopologyAddresses) {
+	if isDefined(c.ProxyTopologyAddressesFile) {
+		if isDefined(c.ProxyTopologyAddresses) {
+			return nil, fmt.Errorf("invalid value for ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE (%v); "+
+				"ZDM_PROXY_TOPOLOGY_ADDRESSES must not be set as well", c.ProxyTopologyAddressesFile)
+		}
+		var err error
+		proxyAddressesTyped, err = c.ReadProxyTopologyAddressesFile()
+		if err != nil {
+			return nil, err
+		}
+	} else if isNotDefined(c.ProxyTopologyAddresses) {
 		log.Debugf("[TopologyConfig] Proxy Topology Addresses not defined, attempting to use proxy listen address for system.local: %v.", c.ProxyListenAddress)
 		if isDefined(c.ProxyListenAddress) {
-			parsedListenAddress, err := lookupFirstIp4(c.ProxyListenAddress)
+			parsedListenAddress, err := lookupFirstIp(c.ProxyListenAddress)
 			if err != nil {
-				log.Debugf("[TopologyConfig] Could not resolve Proxy Listen Address to an IPv4 address: %v. Falling back to default: %v.", err, defaultLocalIp4Addr.String())
+				log.Debugf("[TopologyConfig] Could not resolve Proxy Listen Address to an IP address: %v. Falling back to default: %v.", err, defaultLocalIp4Addr.String())
 			} else {
 				proxyAddressesTyped = []net.IP{parsedListenAddress}
 			}
@@ -189,7 +361,7 @@ func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 
 		proxyAddressesTyped = make([]net.IP, 0, len(proxyAddresses))
 		for i := 0; i < len(proxyAddresses); i++ {
-			proxyAddr := proxyAddresses[i]
+			proxyAddr := trimAddressBrackets(proxyAddresses[i])
 			parsedIp := net.ParseIP(proxyAddr)
 			if parsedIp == nil {
 				return nil, fmt.Errorf("invalid proxy address in ZDM_PROXY_TOPOLOGY_ADDRESSES env var: %v", proxyAddr)
@@ -210,8 +382,13 @@ func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 		return nil, fmt.Errorf("invalid ZDM_PROXY_TOPOLOGY_NUM_TOKENS (%v), it must be positive and equal or less than 256", c.ProxyTopologyNumTokens)
 	}
 
+	if c.InterceptedQueriesCacheTtlMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_INTERCEPTED_QUERIES_CACHE_TTL_MS (%v); it must not be negative",
+			c.InterceptedQueriesCacheTtlMs)
+	}
+
 	return &common.TopologyConfig{
-		VirtualizationEnabled: true, // keep flag for now until we are absolutely certain we will never need it again
+		VirtualizationEnabled: c.ProxyTopologyVirtualizationEnabled,
 		Addresses:             proxyAddressesTyped,
 		Index:                 proxyIndex,
 		Count:                 proxyInstanceCount,
@@ -219,118 +396,169 @@ func (c *Config) ParseTopologyConfig() (*common.TopologyConfig, error) {
 	}, nil
 }
 
+// ReadProxyTopologyAddressesFile returns the addresses of the proxy instances that are in
+// ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE.
+func (c *Config) ReadProxyTopologyAddressesFile() ([]net.IP, error) {
+	contents, err := os.ReadFile(c.ProxyTopologyAddressesFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE (%v); could not read file: %w",
+			c.ProxyTopologyAddressesFile, err)
+	}
+	proxyAddresses := strings.FieldsFunc(string(contents), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(proxyAddresses) == 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE (%v); the file has no addresses",
+			c.ProxyTopologyAddressesFile)
+	}
+	proxyAddressesTyped := make([]net.IP, 0, len(proxyAddresses))
+	for _, proxyAddress := range proxyAddresses {
+		parsedIp := net.ParseIP(trimAddressBrackets(proxyAddress))
+		if parsedIp == nil {
+			return nil, fmt.Errorf("invalid value for ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE (%v); invalid proxy address: %v",
+				c.ProxyTopologyAddressesFile, proxyAddress)
+		}
+		proxyAddressesTyped = append(proxyAddressesTyped, parsedIp)
+	}
+	return proxyAddressesTyped, nil
+}
+
+func (c *Config) ParseProxyTopologyAddressesFilePollInterval() (int, error) {
+	if c.ProxyTopologyAddressesFilePollIntervalMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE_POLL_INTERVAL_MS (%v); "+
+			"it must be 0 (disabled) or a positive number", c.ProxyTopologyAddressesFilePollIntervalMs)
+	}
+	return c.ProxyTopologyAddressesFilePollIntervalMs, nil
+}
+
 func (c *Config) Validate() error {
 	_, err := c.ParseLogLevel()
 	if err != nil {
 		return fmt.Errorf("invalid log level: %w", err)
 	}
 
-	_, err = c.ParseTargetContactPoints()
+	_, err = c.ParseLogFormat()
 	if err != nil {
-		return fmt.Errorf("invalid target configuration: %w", err)
+		return err
 	}
 
-	_, err = c.ParseOriginContactPoints()
+	_, err = c.ParseExplainRequestsClientAddresses()
 	if err != nil {
-		return fmt.Errorf("invalid origin configuration: %w", err)
+		return err
 	}
 
-	_, err = c.ParseOriginBuckets()
+	_, err = c.ParseAdminKeyspaceRoles()
 	if err != nil {
-		return fmt.Errorf("could not parse origin buckets: %v", err)
+		return err
 	}
 
-	_, err = c.ParseTargetBuckets()
+	_, err = c.ParseCredentialFilesPollInterval()
 	if err != nil {
-		return fmt.Errorf("could not parse target buckets: %v", err)
+		return err
 	}
 
-	_, err = c.ParseTopologyConfig()
+	_, err = c.ParseConfigFilePollInterval()
 	if err != nil {
 		return err
 	}
 
-	_, err = c.ParseOriginTlsConfig(false)
+	_, err = c.ParseProxyTopologyAddressesFilePollInterval()
 	if err != nil {
 		return err
 	}
 
-	_, err = c.ParseTargetTlsConfig(false)
+	_, err = c.ParseFleetStoreConfig()
 	if err != nil {
 		return err
 	}
 
-	_, err = c.ParseProxyTlsConfig(false)
+	_, err = c.ParseLeaderElectionConfig()
 	if err != nil {
 		return err
 	}
 
-	_, err = c.ParsePrimaryCluster()
+	_, err = c.ParseAdminApiAddress()
 	if err != nil {
 		return err
 	}
 
-	_, err = c.ParseSystemQueriesMode()
+	_, err = c.ParseTrafficCaptureConfig()
 	if err != nil {
 		return err
 	}
 
-	_, err = c.ParseReadMode()
+	_, err = c.ParseLatencyBudgetWebhookUrl()
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	_, err = c.ParseStallDetectionConfig()
+	if err != nil {
+		return err
+	}
 
-const (
-	SystemQueriesModeOrigin = "ORIGIN"
-	SystemQueriesModeTarget = "TARGET"
-)
+	_, err = c.ParseClusterConnectionHeartbeatConfig()
+	if err != nil {
+		return err
+	}
 
-func (c *Config) ParseSystemQueriesMode() (common.SystemQueriesMode, error) {
-	switch strings.ToUpper(c.SystemQueriesMode) {
-	case SystemQueriesModeTarget:
-		return common.SystemQueriesModeTarget, nil
-	case SystemQueriesModeOrigin:
-		return common.SystemQueriesModeOrigin, nil
-	default:
-		return common.SystemQueriesModeUndefined, fmt.Errorf("invalid value for ZDM_SYSTEM_QUERIES_MODE; possible values are: %v and %v",
-			SystemQueriesModeTarget, SystemQueriesModeOrigin)
+	_, err = c.ParseRuntimeStatsLogInterval()
+	if err != nil {
+		return err
 	}
-}
 
-const (
-	PrimaryClusterOrigin = "ORIGIN"
-	PrimaryClusterTarget = "TARGET"
-)
+	sections := []interface{ Validate() error }{
+		&c.TargetConfig, &c.OriginConfig, &c.MetricsConfig, &c.LoggingConfig, &c.ListenerConfig,
+		&c.RoutingConfig}
+	for _, section := range sections {
+		err = section.Validate()
+		if err != nil {
+			return err
+		}
+	}
 
-func (c *Config) ParsePrimaryCluster() (common.ClusterType, error) {
-	switch strings.ToUpper(c.PrimaryCluster) {
-	case PrimaryClusterOrigin:
-		return common.ClusterTypeOrigin, nil
-	case PrimaryClusterTarget:
-		return common.ClusterTypeTarget, nil
-	default:
-		return common.ClusterTypeNone, fmt.Errorf("invalid value for ZDM_PRIMARY_CLUSTER; possible values are: %v and %v",
-			PrimaryClusterOrigin, PrimaryClusterTarget)
+	if c.TokenAwareRoutingEnabled && c.ProxyClusterConnectionPoolSize == 0 {
+		return fmt.Errorf("invalid value for ZDM_TOKEN_AWARE_ROUTING_ENABLED (%v); it requires the cluster "+
+			"connections to be pooled (ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE)", c.TokenAwareRoutingEnabled)
 	}
+
+	_, err = c.ParseProxyAuthCredentials()
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.ParseCredentialsModes()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseRequestTimeoutConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTopologyConfig()
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
 const (
-	ReadModePrimaryOnly          = "PRIMARY_ONLY"
-	ReadModeDualAsyncOnSecondary = "DUAL_ASYNC_ON_SECONDARY"
+	LogFormatText = "TEXT"
+	LogFormatJson = "JSON"
 )
 
-func (c *Config) ParseReadMode() (common.ReadMode, error) {
-	switch strings.ToUpper(c.ReadMode) {
-	case ReadModePrimaryOnly:
-		return common.ReadModePrimaryOnly, nil
-	case ReadModeDualAsyncOnSecondary:
-		return common.ReadModeDualAsyncOnSecondary, nil
+func (c *Config) ParseLogFormat() (common.LogFormat, error) {
+	switch strings.ToUpper(strings.TrimSpace(c.LogFormat)) {
+	case LogFormatText:
+		return common.LogFormatText, nil
+	case LogFormatJson:
+		return common.LogFormatJson, nil
 	default:
-		return common.ReadModeUndefined, fmt.Errorf("invalid value for ZDM_READ_MODE; possible values are: %v and %v",
-			ReadModePrimaryOnly, ReadModeDualAsyncOnSecondary)
+		return common.LogFormatUndefined, fmt.Errorf("invalid value for ZDM_LOG_FORMAT; possible values are: %v and %v",
+			LogFormatText, LogFormatJson)
 	}
 }
 
@@ -345,253 +573,228 @@ func (c *Config) ParseLogLevel() (log.Level, error) {
 	return level, nil
 }
 
-func (c *Config) ParseOriginBuckets() ([]float64, error) {
-	return c.parseBuckets(c.MetricsOriginLatencyBucketsMs)
-}
+func (c *Config) ParseExplainRequestsClientAddresses() ([]net.IP, error) {
+	var addresses []net.IP
+	if isNotDefined(c.ExplainRequestsClientAddresses) {
+		return addresses, nil
+	}
 
-func (c *Config) ParseTargetBuckets() ([]float64, error) {
-	return c.parseBuckets(c.MetricsTargetLatencyBucketsMs)
-}
+	for _, addressStr := range strings.Split(c.ExplainRequestsClientAddresses, ",") {
+		address := net.ParseIP(strings.TrimSpace(addressStr))
+		if address == nil {
+			return nil, fmt.Errorf("invalid value for ZDM_EXPLAIN_REQUESTS_CLIENT_ADDRESSES (%v); %v is not a valid IP address",
+				c.ExplainRequestsClientAddresses, strings.TrimSpace(addressStr))
+		}
+		addresses = append(addresses, address)
+	}
 
-func (c *Config) ParseAsyncBuckets() ([]float64, error) {
-	return c.parseBuckets(c.MetricsAsyncReadLatencyBucketsMs)
+	return addresses, nil
 }
 
-func (c *Config) parseBuckets(bucketsConfigStr string) ([]float64, error) {
-	var bucketsArr []float64
-	bucketsStrArr := strings.Split(bucketsConfigStr, ",")
-	if len(bucketsStrArr) == 0 {
-		return nil, fmt.Errorf("unable to parse buckets from %v: at least one bucket is required", bucketsConfigStr)
+func (c *Config) ParseAdminKeyspaceRoles() ([]string, error) {
+	var roles []string
+	if isNotDefined(c.AdminKeyspaceRoles) {
+		return roles, nil
 	}
 
-	for _, bucketStr := range bucketsStrArr {
-		bucket, err := strconv.ParseFloat(strings.TrimSpace(bucketStr), 64)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"unable to parse buckets from %v: could not convert %v to float",
-				bucketsConfigStr,
-				bucketStr)
+	for _, roleStr := range strings.Split(c.AdminKeyspaceRoles, ",") {
+		role := strings.TrimSpace(roleStr)
+		if role == "" {
+			return nil, fmt.Errorf("invalid value for ZDM_ADMIN_KEYSPACE_ROLES (%v); role names must not be empty",
+				c.AdminKeyspaceRoles)
 		}
-		bucketsArr = append(bucketsArr, bucket/1000) // convert ms to seconds
+		roles = append(roles, role)
 	}
 
-	return bucketsArr, nil
+	return roles, nil
 }
 
-func (c *Config) ParseOriginContactPoints() ([]string, error) {
-	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginContactPoints) {
-		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginContactPoints are mutually exclusive. Please specify only one of them.")
+func (c *Config) ParseFleetStoreConfig() (*common.FleetStoreConfig, error) {
+	storeType := strings.ToLower(strings.TrimSpace(c.FleetStoreType))
+	if storeType == "" {
+		return &common.FleetStoreConfig{}, nil
 	}
-
-	if isDefined(c.OriginSecureConnectBundlePath) && isDefined(c.OriginLocalDatacenter) {
-		return nil, fmt.Errorf("OriginSecureConnectBundlePath and OriginLocalDatacenter are mutually exclusive. Please specify only one of them.")
+	if storeType != "etcd" && storeType != "consul" {
+		return nil, fmt.Errorf("invalid value for ZDM_FLEET_STORE_TYPE (%v); valid values are etcd and consul",
+			c.FleetStoreType)
 	}
 
-	if isNotDefined(c.OriginSecureConnectBundlePath) && isNotDefined(c.OriginContactPoints) {
-		return nil, fmt.Errorf("Both OriginSecureConnectBundlePath and OriginContactPoints are empty. Please specify either one of them.")
+	var endpoints []string
+	for _, endpointStr := range strings.Split(c.FleetStoreEndpoints, ",") {
+		endpoint := strings.TrimRight(strings.TrimSpace(endpointStr), "/")
+		if endpoint == "" {
+			continue
+		}
+		parsedEndpoint, err := url.Parse(endpoint)
+		if err != nil || (parsedEndpoint.Scheme != "http" && parsedEndpoint.Scheme != "https") || parsedEndpoint.Host == "" {
+			return nil, fmt.Errorf("invalid value for ZDM_FLEET_STORE_ENDPOINTS (%v); %v is not a valid http or https URL",
+				c.FleetStoreEndpoints, endpoint)
+		}
+		endpoints = append(endpoints, endpoint)
 	}
-
-	if isDefined(c.OriginContactPoints) && (c.OriginPort == 0) {
-		return nil, fmt.Errorf("OriginContactPoints was specified but the port is missing. Please provide OriginPort")
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_FLEET_STORE_ENDPOINTS (%v); "+
+			"at least one endpoint is required when ZDM_FLEET_STORE_TYPE is set", c.FleetStoreEndpoints)
 	}
 
-	if (c.OriginEnableHostAssignment == false) && (isDefined(c.OriginLocalDatacenter)) {
-		return nil, fmt.Errorf("OriginLocalDatacenter was specified but OriginEnableHostAssignment is false. Please enable host assignment or don't set the datacenter.")
+	key := strings.Trim(strings.TrimSpace(c.FleetStoreKey), "/")
+	if key == "" {
+		return nil, fmt.Errorf("invalid value for ZDM_FLEET_STORE_KEY (%v); it must not be empty", c.FleetStoreKey)
 	}
 
-	if isNotDefined(c.OriginSecureConnectBundlePath) {
-		contactPoints := parseContactPoints(c.OriginContactPoints)
-		if len(contactPoints) <= 0 {
-			return nil, fmt.Errorf("could not parse origin contact points: %v", c.OriginContactPoints)
-		}
-
-		return contactPoints, nil
+	if isNotDefined(c.AdminKeyspaceRoles) {
+		return nil, fmt.Errorf("invalid value for ZDM_FLEET_STORE_TYPE (%v); ZDM_ADMIN_KEYSPACE_ROLES must be set as well",
+			c.FleetStoreType)
 	}
 
-	return nil, nil
+	return &common.FleetStoreConfig{Type: storeType, Endpoints: endpoints, Key: key}, nil
 }
 
-func (c *Config) ParseTargetContactPoints() ([]string, error) {
-	if isDefined(c.TargetSecureConnectBundlePath) && isDefined(c.TargetContactPoints) {
-		return nil, fmt.Errorf("TargetSecureConnectBundlePath and TargetContactPoints are mutually exclusive. Please specify only one of them.")
-	}
-
-	if isDefined(c.TargetSecureConnectBundlePath) && isDefined(c.TargetLocalDatacenter) {
-		return nil, fmt.Errorf("TargetSecureConnectBundlePath and TargetLocalDatacenter are mutually exclusive. Please specify only one of them.")
+func (c *Config) ParseLeaderElectionConfig() (*common.LeaderElectionConfig, error) {
+	if !c.LeaderElectionEnabled {
+		return &common.LeaderElectionConfig{}, nil
 	}
 
-	if isNotDefined(c.TargetSecureConnectBundlePath) && isNotDefined(c.TargetContactPoints) {
-		return nil, fmt.Errorf("Both TargetSecureConnectBundlePath and TargetContactPoints are empty. Please specify either one of them.")
+	fleetStoreConfig, err := c.ParseFleetStoreConfig()
+	if err != nil {
+		return nil, err
 	}
-
-	if (isDefined(c.TargetContactPoints)) && (c.TargetPort == 0) {
-		return nil, fmt.Errorf("TargetContactPoints was specified but the port is missing. Please provide TargetPort")
+	if fleetStoreConfig.Type == "" {
+		return nil, fmt.Errorf("invalid value for ZDM_LEADER_ELECTION_ENABLED (%v); ZDM_FLEET_STORE_TYPE must be set as well",
+			c.LeaderElectionEnabled)
 	}
 
-	if (c.TargetEnableHostAssignment == false) && (isDefined(c.TargetLocalDatacenter)) {
-		return nil, fmt.Errorf("TargetLocalDatacenter was specified but TargetEnableHostAssignment is false. Please enable host assignment or don't set the datacenter.")
+	key := strings.Trim(strings.TrimSpace(c.LeaderElectionKey), "/")
+	if key == "" || key == fleetStoreConfig.Key {
+		return nil, fmt.Errorf("invalid value for ZDM_LEADER_ELECTION_KEY (%v); "+
+			"it must not be empty or the same as ZDM_FLEET_STORE_KEY", c.LeaderElectionKey)
 	}
 
-	if isNotDefined(c.TargetSecureConnectBundlePath) {
-		contactPoints := parseContactPoints(c.TargetContactPoints)
-		if len(contactPoints) <= 0 {
-			return nil, fmt.Errorf("could not parse target contact points: %v", c.TargetContactPoints)
-		}
-
-		return contactPoints, nil
+	// consul does not support session TTLs lower than 10 seconds
+	if c.LeaderElectionTtlMs < 10000 {
+		return nil, fmt.Errorf("invalid value for ZDM_LEADER_ELECTION_TTL_MS (%v); it must be at least 10000",
+			c.LeaderElectionTtlMs)
 	}
 
-	return nil, nil
+	return &common.LeaderElectionConfig{Enabled: true, Key: key, TtlMs: c.LeaderElectionTtlMs}, nil
 }
 
-func parseContactPoints(setting string) []string {
-	return strings.Split(strings.ReplaceAll(setting, " ", ""), ",")
+// ParseAdminApiAddress returns the host:port address of the admin API endpoint, it returns an empty string if the
+// admin API is served on the metrics endpoint.
+func (c *Config) ParseAdminApiAddress() (string, error) {
+	if c.AdminApiPort == 0 {
+		return "", nil
+	}
+	if c.AdminApiPort < 0 || c.AdminApiPort > 65535 {
+		return "", fmt.Errorf("invalid value for ZDM_ADMIN_API_PORT (%v); it must be 0 (metrics endpoint) or a valid port",
+			c.AdminApiPort)
+	}
+	if c.AdminApiPort == c.MetricsPort && c.AdminApiAddress == c.MetricsAddress {
+		return "", fmt.Errorf("invalid value for ZDM_ADMIN_API_PORT (%v); it must not be the same as ZDM_METRICS_PORT, "+
+			"set it to 0 to serve the admin API on the metrics endpoint", c.AdminApiPort)
+	}
+	return net.JoinHostPort(c.AdminApiAddress, strconv.Itoa(c.AdminApiPort)), nil
 }
 
-func (c *Config) ParseOriginTlsConfig(displayLogMessages bool) (*common.ClusterTlsConfig, error) {
-
-	// No TLS defined
-
-	if isNotDefined(c.OriginSecureConnectBundlePath) &&
-		isNotDefined(c.OriginTlsServerCaPath) &&
-		isNotDefined(c.OriginTlsClientCertPath) &&
-		isNotDefined(c.OriginTlsClientKeyPath) {
-		if displayLogMessages {
-			log.Infof("TLS was not configured for Origin")
-		}
-		return &common.ClusterTlsConfig{
-			TlsEnabled: false,
-		}, nil
+func (c *Config) ParseTrafficCaptureConfig() (*common.TrafficCaptureConfig, error) {
+	directory := strings.TrimSpace(c.TrafficCaptureDirectory)
+	if directory == "" {
+		return &common.TrafficCaptureConfig{}, nil
 	}
 
-	//SCB specified
-
-	if isDefined(c.OriginSecureConnectBundlePath) {
-		if isDefined(c.OriginTlsServerCaPath) || isDefined(c.OriginTlsClientCertPath) || isDefined(c.OriginTlsClientKeyPath) {
-			return &common.ClusterTlsConfig{}, fmt.Errorf("Incorrect TLS configuration for Origin: Secure Connect Bundle and custom TLS parameters cannot be specified at the same time.")
-		}
-
-		if displayLogMessages {
-			log.Infof("Mutual TLS configured for Origin using an Astra secure connect bundle")
-		}
-		return &common.ClusterTlsConfig{
-			TlsEnabled:              true,
-			SecureConnectBundlePath: c.OriginSecureConnectBundlePath,
-		}, nil
+	fileInfo, err := os.Stat(directory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for ZDM_TRAFFIC_CAPTURE_DIRECTORY (%v); %w", c.TrafficCaptureDirectory, err)
 	}
-
-	// Custom TLS params specified
-
-	if isDefined(c.OriginTlsServerCaPath) && (isNotDefined(c.OriginTlsClientCertPath) && isNotDefined(c.OriginTlsClientKeyPath)) {
-		if displayLogMessages {
-			log.Infof("One-way TLS configured for Origin. Please note that hostname verification is not currently supported.")
-		}
-		return &common.ClusterTlsConfig{
-			TlsEnabled:   true,
-			ServerCaPath: c.OriginTlsServerCaPath,
-		}, nil
+	if !fileInfo.IsDir() {
+		return nil, fmt.Errorf("invalid value for ZDM_TRAFFIC_CAPTURE_DIRECTORY (%v); it is not a directory",
+			c.TrafficCaptureDirectory)
 	}
 
-	if isDefined(c.OriginTlsServerCaPath) && isDefined(c.OriginTlsClientCertPath) && isDefined(c.OriginTlsClientKeyPath) {
-		if displayLogMessages {
-			log.Infof("Mutual TLS configured for Origin. Please note that hostname verification is not currently supported.")
-		}
-		return &common.ClusterTlsConfig{
-			TlsEnabled:     true,
-			ServerCaPath:   c.OriginTlsServerCaPath,
-			ClientCertPath: c.OriginTlsClientCertPath,
-			ClientKeyPath:  c.OriginTlsClientKeyPath,
-		}, nil
+	if c.TrafficCaptureMaxDurationMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_TRAFFIC_CAPTURE_MAX_DURATION_MS (%v); it must be a positive number",
+			c.TrafficCaptureMaxDurationMs)
 	}
 
-	return &common.ClusterTlsConfig{}, fmt.Errorf("incomplete TLS configuration for Origin: when using mutual TLS, " +
-		"please specify Server CA path, Client Cert path and Client Key path")
+	if c.TrafficCaptureMaxFileSizeBytes <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_TRAFFIC_CAPTURE_MAX_FILE_SIZE_BYTES (%v); it must be a positive number",
+			c.TrafficCaptureMaxFileSizeBytes)
+	}
 
+	return &common.TrafficCaptureConfig{
+		Enabled:          true,
+		Directory:        directory,
+		MaxDurationMs:    c.TrafficCaptureMaxDurationMs,
+		MaxFileSizeBytes: c.TrafficCaptureMaxFileSizeBytes,
+	}, nil
 }
 
-func (c *Config) ParseTargetTlsConfig(displayLogMessages bool) (*common.ClusterTlsConfig, error) {
-
-	// No TLS defined
-
-	if isNotDefined(c.TargetSecureConnectBundlePath) &&
-		isNotDefined(c.TargetTlsServerCaPath) &&
-		isNotDefined(c.TargetTlsClientCertPath) &&
-		isNotDefined(c.TargetTlsClientKeyPath) {
-		if displayLogMessages {
-			log.Infof("TLS was not configured for Target")
-		}
-		return &common.ClusterTlsConfig{
-			TlsEnabled: false,
-		}, nil
+// ParseLatencyBudgetWebhookUrl returns an empty string if the latency budget alerts are not sent to a webhook.
+func (c *Config) ParseLatencyBudgetWebhookUrl() (string, error) {
+	webhookUrl := strings.TrimSpace(c.LatencyBudgetWebhookUrl)
+	if webhookUrl == "" {
+		return "", nil
 	}
 
-	//SCB specified
-
-	if isDefined(c.TargetSecureConnectBundlePath) {
-		if isDefined(c.TargetTlsServerCaPath) || isDefined(c.TargetTlsClientCertPath) || isDefined(c.TargetTlsClientKeyPath) {
-			return &common.ClusterTlsConfig{}, fmt.Errorf("Incorrect TLS configuration for Target: Secure Connect Bundle and custom TLS parameters cannot be specified at the same time.")
-		}
-
-		return &common.ClusterTlsConfig{
-			TlsEnabled:              true,
-			SecureConnectBundlePath: c.TargetSecureConnectBundlePath,
-		}, nil
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid value for ZDM_LATENCY_BUDGET_WEBHOOK_URL (%v); %w", c.LatencyBudgetWebhookUrl, err)
+	}
+	if (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return "", fmt.Errorf("invalid value for ZDM_LATENCY_BUDGET_WEBHOOK_URL (%v); it must be an http or https URL",
+			c.LatencyBudgetWebhookUrl)
 	}
 
-	// Custom TLS params specified
+	return webhookUrl, nil
+}
 
-	if isDefined(c.TargetTlsServerCaPath) && (isNotDefined(c.TargetTlsClientCertPath) && isNotDefined(c.TargetTlsClientKeyPath)) {
-		if displayLogMessages {
-			log.Infof("One-way TLS configured for Target. Please note that hostname verification is not currently supported.")
-		}
-		return &common.ClusterTlsConfig{
-			TlsEnabled:   true,
-			ServerCaPath: c.TargetTlsServerCaPath,
-		}, nil
+func (c *Config) ParseStallDetectionConfig() (*common.StallDetectionConfig, error) {
+	if c.StallDetectionThresholdMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_STALL_DETECTION_THRESHOLD_MS (%v); it must be 0 (disabled) or a positive number",
+			c.StallDetectionThresholdMs)
+	}
+	if c.StallDetectionThresholdMs == 0 {
+		return &common.StallDetectionConfig{}, nil
 	}
 
-	if isDefined(c.TargetTlsServerCaPath) && isDefined(c.TargetTlsClientCertPath) && isDefined(c.TargetTlsClientKeyPath) {
-		if displayLogMessages {
-			log.Infof("Mutual TLS configured for Target. Please note that hostname verification is not currently supported.")
-		}
-		return &common.ClusterTlsConfig{
-			TlsEnabled:     true,
-			ServerCaPath:   c.TargetTlsServerCaPath,
-			ClientCertPath: c.TargetTlsClientCertPath,
-			ClientKeyPath:  c.TargetTlsClientKeyPath,
-		}, nil
+	if c.StallDiagnosticsIntervalMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_STALL_DIAGNOSTICS_INTERVAL_MS (%v); it must be a positive number",
+			c.StallDiagnosticsIntervalMs)
 	}
 
-	return &common.ClusterTlsConfig{}, fmt.Errorf("incomplete TLS configuration for Target: when using mutual TLS, please specify Server CA path, Client Cert path and Client Key path")
+	return &common.StallDetectionConfig{
+		Enabled:               true,
+		ThresholdMs:           c.StallDetectionThresholdMs,
+		DiagnosticsIntervalMs: c.StallDiagnosticsIntervalMs,
+	}, nil
 }
 
-func (c *Config) ParseProxyTlsConfig(displayLogMessages bool) (*common.ProxyTlsConfig, error) {
-
-	if isNotDefined(c.ProxyTlsCaPath) &&
-		isNotDefined(c.ProxyTlsCertPath) &&
-		isNotDefined(c.ProxyTlsKeyPath) {
-		if displayLogMessages {
-			log.Info("Proxy TLS was not configured.")
-		}
-		return &common.ProxyTlsConfig{
-			TlsEnabled: false,
-		}, nil
+func (c *Config) ParseRuntimeStatsLogInterval() (int, error) {
+	if c.RuntimeStatsLogIntervalMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_RUNTIME_STATS_LOG_INTERVAL_MS (%v); "+
+			"it must be 0 (disabled) or a positive number", c.RuntimeStatsLogIntervalMs)
 	}
+	return c.RuntimeStatsLogIntervalMs, nil
+}
 
-	if isDefined(c.ProxyTlsCaPath) && isDefined(c.ProxyTlsCertPath) && isDefined(c.ProxyTlsKeyPath) {
-		if displayLogMessages {
-			log.Info("Proxy TLS configured. Please note that hostname verification is not currently supported.")
-		}
-		return &common.ProxyTlsConfig{
-			TlsEnabled:    true,
-			ProxyCaPath:   c.ProxyTlsCaPath,
-			ProxyCertPath: c.ProxyTlsCertPath,
-			ProxyKeyPath:  c.ProxyTlsKeyPath,
-			ClientAuth:    c.ProxyTlsRequireClientAuth,
-		}, nil
+func (c *Config) ParseClusterConnectionHeartbeatConfig() (*common.ClusterConnectionHeartbeatConfig, error) {
+	if !c.HeartbeatClusterConnectionsEnabled {
+		return &common.ClusterConnectionHeartbeatConfig{}, nil
+	}
+	if c.HeartbeatIntervalMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_HEARTBEAT_INTERVAL_MS (%v); it must be a positive number",
+			c.HeartbeatIntervalMs)
+	}
+	if c.HeartbeatMaxMissed <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_HEARTBEAT_MAX_MISSED (%v); it must be a positive number",
+			c.HeartbeatMaxMissed)
 	}
 
-	return &common.ProxyTlsConfig{}, fmt.Errorf("incomplete Proxy TLS configuration: when enabling proxy TLS, please specify CA path, Cert path and Key path")
+	return &common.ClusterConnectionHeartbeatConfig{
+		Enabled:    true,
+		IntervalMs: c.HeartbeatIntervalMs,
+		MaxMissed:  c.HeartbeatMaxMissed,
+	}, nil
 }
 
 func isDefined(propertyValue string) bool {
@@ -601,3 +804,86 @@ func isDefined(propertyValue string) bool {
 func isNotDefined(propertyValue string) bool {
 	return !isDefined(propertyValue)
 }
+
+// trimAddressBrackets removes the brackets of an IPv6 literal (e.g. "[::1]" becomes "::1") so that the address can be
+// parsed with net.ParseIP or passed to net.JoinHostPort.
+func trimAddressBrackets(address string) string {
+	if strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]") {
+		return address[1 : len(address)-1]
+	}
+	return address
+}
+
+// ParseRequestTimeoutConfig returns the request timeout of each cluster, ZDM_PROXY_REQUEST_TIMEOUT_MS unless it is
+// set for the cluster, and the timeouts of the statement types that override them.
+func (c *Config) ParseRequestTimeoutConfig() (*common.RequestTimeoutConfig, error) {
+	if c.ProxyRequestTimeoutMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_REQUEST_TIMEOUT_MS (%v); it must be a positive number",
+			c.ProxyRequestTimeoutMs)
+	}
+	settings := []struct {
+		name  string
+		value int
+	}{
+		{"ZDM_ORIGIN_REQUEST_TIMEOUT_MS", c.OriginRequestTimeoutMs},
+		{"ZDM_TARGET_REQUEST_TIMEOUT_MS", c.TargetRequestTimeoutMs},
+		{"ZDM_PROXY_READ_REQUEST_TIMEOUT_MS", c.ProxyReadRequestTimeoutMs},
+		{"ZDM_PROXY_WRITE_REQUEST_TIMEOUT_MS", c.ProxyWriteRequestTimeoutMs},
+		{"ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS", c.ProxyDdlRequestTimeoutMs},
+	}
+	for _, setting := range settings {
+		if setting.value < 0 {
+			return nil, fmt.Errorf("invalid value for %v (%v); it must be 0 (not set) or a positive number",
+				setting.name, setting.value)
+		}
+	}
+
+	timeoutConfig := &common.RequestTimeoutConfig{
+		OriginMs: c.ProxyRequestTimeoutMs,
+		TargetMs: c.ProxyRequestTimeoutMs,
+		ReadMs:   c.ProxyReadRequestTimeoutMs,
+		WriteMs:  c.ProxyWriteRequestTimeoutMs,
+		DdlMs:    c.ProxyDdlRequestTimeoutMs,
+	}
+	if c.OriginRequestTimeoutMs > 0 {
+		timeoutConfig.OriginMs = c.OriginRequestTimeoutMs
+	}
+	if c.TargetRequestTimeoutMs > 0 {
+		timeoutConfig.TargetMs = c.TargetRequestTimeoutMs
+	}
+	return timeoutConfig, nil
+}
+
+// ParseCredentialsModes returns the credentials modes of ORIGIN and TARGET, the client credentials have to be
+// forwarded to at least one cluster because the proxy can't authenticate the client otherwise unless the proxy
+// authenticates the clients itself (see ZDM_PROXY_AUTH_CREDENTIALS), both clusters are CONFIGURED then.
+func (c *Config) ParseCredentialsModes() (origin common.CredentialsMode, target common.CredentialsMode, err error) {
+	origin, err = c.ParseOriginCredentialsMode()
+	if err != nil {
+		return common.CredentialsModeUndefined, common.CredentialsModeUndefined, err
+	}
+	target, err = c.ParseTargetCredentialsMode()
+	if err != nil {
+		return common.CredentialsModeUndefined, common.CredentialsModeUndefined, err
+	}
+	if c.IsProxyAuthEnabled() {
+		// the clients authenticate with the proxy credentials so only the configured credentials are valid on the clusters
+		for _, mode := range []struct {
+			cluster string
+			mode    common.CredentialsMode
+		}{{"ORIGIN", origin}, {"TARGET", target}} {
+			if mode.mode == common.CredentialsModeClient {
+				return common.CredentialsModeUndefined, common.CredentialsModeUndefined, fmt.Errorf(
+					"invalid value for ZDM_%v_CREDENTIALS_MODE (%v); the client credentials can't be forwarded when "+
+						"the proxy authenticates the clients (ZDM_PROXY_AUTH_CREDENTIALS)", mode.cluster, mode.mode)
+			}
+		}
+		return common.CredentialsModeConfigured, common.CredentialsModeConfigured, nil
+	}
+	if origin == common.CredentialsModeConfigured && target == common.CredentialsModeConfigured {
+		return common.CredentialsModeUndefined, common.CredentialsModeUndefined, fmt.Errorf(
+			"invalid values for ZDM_ORIGIN_CREDENTIALS_MODE and ZDM_TARGET_CREDENTIALS_MODE (%v); "+
+				"the client credentials must be forwarded to at least one cluster", common.CredentialsModeConfigured)
+	}
+	return origin, target, nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseAdminApiAddress(t *testing.T) {
+	type test struct {
+		name            string
+		envVars         []envVar
+		expectedAddress string
+		errExpected     bool
+		errMsg          string
+	}
+
+	tests := []test{
+		{
+			name:            "Valid: Default serves the admin API on the metrics endpoint",
+			envVars:         []envVar{},
+			expectedAddress: "",
+		},
+		{
+			name:            "Valid: Separate port",
+			envVars:         []envVar{{"ZDM_ADMIN_API_PORT", "14003"}},
+			expectedAddress: "localhost:14003",
+		},
+		{
+			name: "Valid: IPv6 address",
+			envVars: []envVar{
+				{"ZDM_ADMIN_API_ADDRESS", "[::1]"},
+				{"ZDM_ADMIN_API_PORT", "14003"},
+			},
+			expectedAddress: "[::1]:14003",
+		},
+		{
+			name: "Valid: Metrics port on another address",
+			envVars: []envVar{
+				{"ZDM_ADMIN_API_ADDRESS", "127.0.0.2"},
+				{"ZDM_ADMIN_API_PORT", "14001"},
+			},
+			expectedAddress: "127.0.0.2:14001",
+		},
+		{
+			name:        "Invalid: Negative port",
+			envVars:     []envVar{{"ZDM_ADMIN_API_PORT", "-1"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_ADMIN_API_PORT (-1); it must be 0 (metrics endpoint) or a valid port",
+		},
+		{
+			name:        "Invalid: Same port as the metrics endpoint",
+			envVars:     []envVar{{"ZDM_ADMIN_API_PORT", "14001"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ADMIN_API_PORT (14001); it must not be the same as ZDM_METRICS_PORT, " +
+				"set it to 0 to serve the admin API on the metrics endpoint",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				address, _ := conf.ParseAdminApiAddress()
+				require.Equal(t, tt.expectedAddress, address)
+			}
+		})
+	}
+}
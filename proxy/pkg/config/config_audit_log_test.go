@@ -0,0 +1,124 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseAuditLogConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.AuditLogConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default audit log disabled",
+			envVars:        []envVar{},
+			expectedConfig: &common.AuditLogConfig{},
+		},
+		{
+			name:    "Valid: File audit log",
+			envVars: []envVar{{"ZDM_AUDIT_LOG_DESTINATION", "file"}, {"ZDM_AUDIT_LOG_FILE_PATH", "/var/log/audit.log"}},
+			expectedConfig: &common.AuditLogConfig{
+				Destination:      common.AuditLogDestinationFile,
+				QueryFormat:      common.SlowQueryFormatDigest,
+				FilePath:         "/var/log/audit.log",
+				FileMaxSizeBytes: 104857600,
+				FileMaxBackups:   5,
+			},
+		},
+		{
+			name: "Valid: Syslog audit log with obfuscated queries",
+			envVars: []envVar{
+				{"ZDM_AUDIT_LOG_DESTINATION", "SYSLOG"}, {"ZDM_AUDIT_LOG_SYSLOG_ADDRESS", "udp://syslog:514"},
+				{"ZDM_AUDIT_LOG_QUERY_FORMAT", "OBFUSCATED"}},
+			expectedConfig: &common.AuditLogConfig{
+				Destination:   common.AuditLogDestinationSyslog,
+				QueryFormat:   common.SlowQueryFormatObfuscated,
+				SyslogNetwork: "udp",
+				SyslogAddress: "syslog:514",
+			},
+		},
+		{
+			name:    "Valid: Local syslog daemon",
+			envVars: []envVar{{"ZDM_AUDIT_LOG_DESTINATION", "SYSLOG"}},
+			expectedConfig: &common.AuditLogConfig{
+				Destination: common.AuditLogDestinationSyslog,
+				QueryFormat: common.SlowQueryFormatDigest,
+			},
+		},
+		{
+			name:        "Invalid: Unknown destination",
+			envVars:     []envVar{{"ZDM_AUDIT_LOG_DESTINATION", "KAFKA"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_AUDIT_LOG_DESTINATION; possible values are: FILE, SYSLOG and empty (disabled)",
+		},
+		{
+			name:        "Invalid: Unknown query format",
+			envVars:     []envVar{{"ZDM_AUDIT_LOG_DESTINATION", "FILE"}, {"ZDM_AUDIT_LOG_QUERY_FORMAT", "RAW"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_AUDIT_LOG_QUERY_FORMAT; possible values are: OBFUSCATED and DIGEST",
+		},
+		{
+			name:        "Invalid: File max size not positive",
+			envVars:     []envVar{{"ZDM_AUDIT_LOG_DESTINATION", "FILE"}, {"ZDM_AUDIT_LOG_FILE_MAX_SIZE_BYTES", "0"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_AUDIT_LOG_FILE_MAX_SIZE_BYTES (0); it must be a positive number",
+		},
+		{
+			name:        "Invalid: File max backups negative",
+			envVars:     []envVar{{"ZDM_AUDIT_LOG_DESTINATION", "FILE"}, {"ZDM_AUDIT_LOG_FILE_MAX_BACKUPS", "-1"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_AUDIT_LOG_FILE_MAX_BACKUPS (-1); it must be 0 or a positive number",
+		},
+		{
+			name: "Invalid: Syslog address without scheme",
+			envVars: []envVar{
+				{"ZDM_AUDIT_LOG_DESTINATION", "SYSLOG"}, {"ZDM_AUDIT_LOG_SYSLOG_ADDRESS", "syslog:514"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_AUDIT_LOG_SYSLOG_ADDRESS (syslog:514); it must be an address like " +
+				"udp://host:514 or tcp://host:514",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualConfig, _ := conf.ParseAuditLogConfig()
+				require.Equal(t, tt.expectedConfig, actualConfig)
+			}
+		})
+	}
+}
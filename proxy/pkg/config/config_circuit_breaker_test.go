@@ -0,0 +1,118 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseCircuitBreakerConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedOrigin *common.CircuitBreakerConfig
+		expectedTarget *common.CircuitBreakerConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Circuit breakers disabled",
+			envVars:        []envVar{},
+			expectedOrigin: &common.CircuitBreakerConfig{Enabled: false},
+			expectedTarget: &common.CircuitBreakerConfig{Enabled: false},
+		},
+		{
+			name:           "Valid: Target circuit breaker with defaults",
+			envVars:        []envVar{{"ZDM_TARGET_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT", "50"}},
+			expectedOrigin: &common.CircuitBreakerConfig{Enabled: false},
+			expectedTarget: &common.CircuitBreakerConfig{
+				Enabled: true, ErrorThresholdPercent: 50, MinRequests: 20, WindowMs: 10000, OpenMs: 30000},
+		},
+		{
+			name: "Valid: Origin circuit breaker",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT", "100"},
+				{"ZDM_ORIGIN_CIRCUIT_BREAKER_MIN_REQUESTS", "5"},
+				{"ZDM_ORIGIN_CIRCUIT_BREAKER_WINDOW_MS", "1000"},
+				{"ZDM_ORIGIN_CIRCUIT_BREAKER_OPEN_MS", "2000"},
+			},
+			expectedOrigin: &common.CircuitBreakerConfig{
+				Enabled: true, ErrorThresholdPercent: 100, MinRequests: 5, WindowMs: 1000, OpenMs: 2000},
+			expectedTarget: &common.CircuitBreakerConfig{Enabled: false},
+		},
+		{
+			name:        "Invalid: Threshold over 100",
+			envVars:     []envVar{{"ZDM_ORIGIN_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT", "101"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT (101); " +
+				"it must be 0 (disabled) or a number between 1 and 100",
+		},
+		{
+			name: "Invalid: Min requests",
+			envVars: []envVar{
+				{"ZDM_TARGET_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT", "50"},
+				{"ZDM_TARGET_CIRCUIT_BREAKER_MIN_REQUESTS", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_CIRCUIT_BREAKER_MIN_REQUESTS (0); it must be a positive number",
+		},
+		{
+			name: "Invalid: Window",
+			envVars: []envVar{
+				{"ZDM_TARGET_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT", "50"},
+				{"ZDM_TARGET_CIRCUIT_BREAKER_WINDOW_MS", "50"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_CIRCUIT_BREAKER_WINDOW_MS (50); it must be at least 100",
+		},
+		{
+			name: "Invalid: Open duration",
+			envVars: []envVar{
+				{"ZDM_TARGET_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT", "50"},
+				{"ZDM_TARGET_CIRCUIT_BREAKER_OPEN_MS", "-1"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_CIRCUIT_BREAKER_OPEN_MS (-1); it must be a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, _ := conf.ParseOriginCircuitBreakerConfig()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				actualTarget, _ := conf.ParseTargetCircuitBreakerConfig()
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestConfig_ParseProxyClientIdleTimeout(t *testing.T) {
+
+	type test struct {
+		name            string
+		envVars         []envVar
+		expectedTimeout time.Duration
+		errExpected     bool
+		errMsg          string
+	}
+
+	tests := []test{
+		{
+			name:            "Valid: Idle timeout unset",
+			envVars:         []envVar{},
+			expectedTimeout: 0,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Valid: Idle timeout disabled",
+			envVars:         []envVar{{"ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS", "0"}},
+			expectedTimeout: 0,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Valid: Idle timeout set",
+			envVars:         []envVar{{"ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS", "600000"}},
+			expectedTimeout: 10 * time.Minute,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Invalid: Negative idle timeout",
+			envVars:         []envVar{{"ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS", "-1"}},
+			expectedTimeout: 0,
+			errExpected:     true,
+			errMsg: "invalid value for ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualTimeout, _ := conf.ParseProxyClientIdleTimeout()
+				require.Equal(t, tt.expectedTimeout, actualTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseProxyClientRequestLimitConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.ClientRequestLimitConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Unlimited by default",
+			envVars:        []envVar{},
+			expectedConfig: &common.ClientRequestLimitConfig{MaxRequestsPerSecond: 0, MaxInFlightRequests: 0},
+			errExpected:    false,
+			errMsg:         "",
+		},
+		{
+			name: "Valid: Both limits",
+			envVars: []envVar{
+				{"ZDM_PROXY_MAX_CLIENT_REQUESTS_PER_SECOND", "500"},
+				{"ZDM_PROXY_MAX_CLIENT_IN_FLIGHT_REQUESTS", "64"},
+			},
+			expectedConfig: &common.ClientRequestLimitConfig{MaxRequestsPerSecond: 500, MaxInFlightRequests: 64},
+			errExpected:    false,
+			errMsg:         "",
+		},
+		{
+			name:           "Invalid: Negative requests per second",
+			envVars:        []envVar{{"ZDM_PROXY_MAX_CLIENT_REQUESTS_PER_SECOND", "-1"}},
+			expectedConfig: nil,
+			errExpected:    true,
+			errMsg: "invalid value for ZDM_PROXY_MAX_CLIENT_REQUESTS_PER_SECOND (-1); " +
+				"it must be 0 (unlimited) or a positive number",
+		},
+		{
+			name:           "Invalid: Negative in flight requests",
+			envVars:        []envVar{{"ZDM_PROXY_MAX_CLIENT_IN_FLIGHT_REQUESTS", "-5"}},
+			expectedConfig: nil,
+			errExpected:    true,
+			errMsg: "invalid value for ZDM_PROXY_MAX_CLIENT_IN_FLIGHT_REQUESTS (-5); " +
+				"it must be 0 (unlimited) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualConfig, _ := conf.ParseProxyClientRequestLimitConfig()
+				require.Equal(t, tt.expectedConfig, actualConfig)
+			}
+		})
+	}
+}
+
+func TestConfig_ParseProxyMaxInFlightRequests(t *testing.T) {
+
+	type test struct {
+		name        string
+		envVars     []envVar
+		expectedMax int
+		errExpected bool
+		errMsg      string
+	}
+
+	tests := []test{
+		{
+			name:        "Valid: Unlimited by default",
+			envVars:     []envVar{},
+			expectedMax: 0,
+			errExpected: false,
+			errMsg:      "",
+		},
+		{
+			name:        "Valid: Max in flight requests set",
+			envVars:     []envVar{{"ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS", "10000"}},
+			expectedMax: 10000,
+			errExpected: false,
+			errMsg:      "",
+		},
+		{
+			name:        "Invalid: Negative max in flight requests",
+			envVars:     []envVar{{"ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS", "-1"}},
+			expectedMax: 0,
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS (-1); " +
+				"it must be 0 (unlimited) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualMax, _ := conf.ParseProxyMaxInFlightRequests()
+				require.Equal(t, tt.expectedMax, actualMax)
+			}
+		})
+	}
+}
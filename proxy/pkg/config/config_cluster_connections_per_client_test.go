@@ -0,0 +1,112 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseProxyClusterConnectionsPerClient(t *testing.T) {
+
+	type test struct {
+		name                   string
+		envVars                []envVar
+		expectedConnections    int
+		expectedDispatchPolicy common.ConnectionDispatchPolicy
+		errExpected            bool
+		errMsg                 string
+	}
+
+	tests := []test{
+		{
+			name:                   "Valid: Connections per client unset",
+			envVars:                []envVar{},
+			expectedConnections:    1,
+			expectedDispatchPolicy: common.ConnectionDispatchPolicyRoundRobin,
+			errExpected:            false,
+			errMsg:                 "",
+		},
+		{
+			name: "Valid: Connections per client and dispatch policy set",
+			envVars: []envVar{
+				{"ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT", "4"},
+				{"ZDM_PROXY_CLUSTER_CONNECTION_DISPATCH_POLICY", "least_in_flight"},
+			},
+			expectedConnections:    4,
+			expectedDispatchPolicy: common.ConnectionDispatchPolicyLeastInFlight,
+			errExpected:            false,
+			errMsg:                 "",
+		},
+		{
+			name:                "Invalid: Zero connections per client",
+			envVars:             []envVar{{"ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT", "0"}},
+			expectedConnections: 0,
+			errExpected:         true,
+			errMsg:              "invalid value for ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT (0); it must be a positive number",
+		},
+		{
+			name: "Valid: Cluster connection failover with one connection per client",
+			envVars: []envVar{
+				{"ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT", "1"},
+				{"ZDM_PROXY_CLUSTER_CONNECTION_FAILOVER_ENABLED", "true"},
+			},
+			expectedConnections:    1,
+			expectedDispatchPolicy: common.ConnectionDispatchPolicyRoundRobin,
+			errExpected:            false,
+			errMsg:                 "",
+		},
+		{
+			name: "Invalid: Cluster connection failover with several connections per client",
+			envVars: []envVar{
+				{"ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT", "2"},
+				{"ZDM_PROXY_CLUSTER_CONNECTION_FAILOVER_ENABLED", "true"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT (2); it must be 1 when " +
+				"ZDM_PROXY_CLUSTER_CONNECTION_FAILOVER_ENABLED is true",
+		},
+		{
+			name:        "Invalid: Unknown dispatch policy",
+			envVars:     []envVar{{"ZDM_PROXY_CLUSTER_CONNECTION_DISPATCH_POLICY", "RANDOM"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_CLUSTER_CONNECTION_DISPATCH_POLICY; " +
+				"possible values are: ROUND_ROBIN and LEAST_IN_FLIGHT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualConnections, _ := conf.ParseProxyClusterConnectionsPerClient()
+				require.Equal(t, tt.expectedConnections, actualConnections)
+				actualDispatchPolicy, _ := conf.ParseProxyClusterConnectionDispatchPolicy()
+				require.Equal(t, tt.expectedDispatchPolicy, actualDispatchPolicy)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseCompression(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedOrigin primitive.Compression
+		expectedTarget primitive.Compression
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Compression unset",
+			envVars:        []envVar{},
+			expectedOrigin: "",
+			expectedTarget: "",
+		},
+		{
+			name:           "Valid: Origin compression",
+			envVars:        []envVar{{"ZDM_ORIGIN_COMPRESSION", " lz4 "}},
+			expectedOrigin: primitive.CompressionLz4,
+			expectedTarget: "",
+		},
+		{
+			name:           "Valid: Origin and target compression",
+			envVars:        []envVar{{"ZDM_ORIGIN_COMPRESSION", "NONE"}, {"ZDM_TARGET_COMPRESSION", "Snappy"}},
+			expectedOrigin: primitive.CompressionNone,
+			expectedTarget: primitive.CompressionSnappy,
+		},
+		{
+			name:        "Invalid: Unknown compression",
+			envVars:     []envVar{{"ZDM_TARGET_COMPRESSION", "gzip"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_COMPRESSION (gzip); possible values are: NONE, LZ4 and SNAPPY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, _ := conf.ParseOriginCompression()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				actualTarget, _ := conf.ParseTargetCompression()
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseConnectionAttemptDelay(t *testing.T) {
+
+	type test struct {
+		name                string
+		envVars             []envVar
+		expectedOriginDelay int
+		expectedTargetDelay int
+		errExpected         bool
+		errMsg              string
+	}
+
+	tests := []test{
+		{
+			name:                "Valid: Attempt delays unset",
+			envVars:             []envVar{},
+			expectedOriginDelay: 250,
+			expectedTargetDelay: 250,
+			errExpected:         false,
+			errMsg:              "",
+		},
+		{
+			name:                "Valid: Staggered attempts disabled on ORIGIN",
+			envVars:             []envVar{{"ZDM_ORIGIN_CONNECTION_ATTEMPT_DELAY_MS", "0"}},
+			expectedOriginDelay: 0,
+			expectedTargetDelay: 250,
+			errExpected:         false,
+			errMsg:              "",
+		},
+		{
+			name: "Valid: Attempt delay per cluster",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_CONNECTION_ATTEMPT_DELAY_MS", "100"},
+				{"ZDM_TARGET_CONNECTION_ATTEMPT_DELAY_MS", "50"},
+			},
+			expectedOriginDelay: 100,
+			expectedTargetDelay: 50,
+			errExpected:         false,
+			errMsg:              "",
+		},
+		{
+			name:        "Invalid: Negative ORIGIN attempt delay",
+			envVars:     []envVar{{"ZDM_ORIGIN_CONNECTION_ATTEMPT_DELAY_MS", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_CONNECTION_ATTEMPT_DELAY_MS (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+		{
+			name:        "Invalid: Negative TARGET attempt delay",
+			envVars:     []envVar{{"ZDM_TARGET_CONNECTION_ATTEMPT_DELAY_MS", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_CONNECTION_ATTEMPT_DELAY_MS (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOriginDelay, _ := conf.ParseOriginConnectionAttemptDelay()
+				require.Equal(t, tt.expectedOriginDelay, actualOriginDelay)
+				actualTargetDelay, _ := conf.ParseTargetConnectionAttemptDelay()
+				require.Equal(t, tt.expectedTargetDelay, actualTargetDelay)
+			}
+		})
+	}
+}
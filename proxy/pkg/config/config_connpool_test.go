@@ -0,0 +1,100 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseProxyClusterConnectionPoolSize(t *testing.T) {
+
+	type test struct {
+		name         string
+		envVars      []envVar
+		expectedSize int
+		errExpected  bool
+		errMsg       string
+	}
+
+	tests := []test{
+		{
+			name:         "Valid: Pool size unset",
+			envVars:      []envVar{},
+			expectedSize: 0,
+			errExpected:  false,
+			errMsg:       "",
+		},
+		{
+			name:         "Valid: Pool disabled",
+			envVars:      []envVar{{"ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE", "0"}},
+			expectedSize: 0,
+			errExpected:  false,
+			errMsg:       "",
+		},
+		{
+			name:         "Valid: Pool enabled",
+			envVars:      []envVar{{"ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE", "8"}},
+			expectedSize: 8,
+			errExpected:  false,
+			errMsg:       "",
+		},
+		{
+			name: "Valid: Token aware routing with pool",
+			envVars: []envVar{
+				{"ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE", "8"},
+				{"ZDM_TOKEN_AWARE_ROUTING_ENABLED", "true"},
+			},
+			expectedSize: 8,
+			errExpected:  false,
+			errMsg:       "",
+		},
+		{
+			name:        "Invalid: Token aware routing without pool",
+			envVars:     []envVar{{"ZDM_TOKEN_AWARE_ROUTING_ENABLED", "true"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TOKEN_AWARE_ROUTING_ENABLED (true); it requires the cluster " +
+				"connections to be pooled (ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE)",
+		},
+		{
+			name:         "Invalid: Negative pool size",
+			envVars:      []envVar{{"ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE", "-1"}},
+			expectedSize: 0,
+			errExpected:  true,
+			errMsg: "invalid value for ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualSize, _ := conf.ParseProxyClusterConnectionPoolSize()
+				require.Equal(t, tt.expectedSize, actualSize)
+			}
+		})
+	}
+}
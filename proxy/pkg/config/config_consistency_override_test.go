@@ -0,0 +1,125 @@
+package config
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseConsistencyOverride(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedOrigin map[primitive.ConsistencyLevel]primitive.ConsistencyLevel
+		expectedTarget map[primitive.ConsistencyLevel]primitive.ConsistencyLevel
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Consistency overrides unset",
+			envVars:        []envVar{},
+			expectedOrigin: map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{},
+			expectedTarget: map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{},
+		},
+		{
+			name:           "Valid: Target consistency level",
+			envVars:        []envVar{{"ZDM_TARGET_CONSISTENCY_OVERRIDE", " local_one "}},
+			expectedOrigin: map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{},
+			expectedTarget: map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{
+				primitive.ConsistencyLevelAny:         primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelOne:         primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelTwo:         primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelThree:       primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelQuorum:      primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelAll:         primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelLocalQuorum: primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelEachQuorum:  primitive.ConsistencyLevelLocalOne,
+				primitive.ConsistencyLevelLocalOne:    primitive.ConsistencyLevelLocalOne,
+			},
+		},
+		{
+			name:    "Valid: Origin consistency mappings",
+			envVars: []envVar{{"ZDM_ORIGIN_CONSISTENCY_OVERRIDE", "QUORUM:LOCAL_QUORUM, all : local_quorum"}},
+			expectedOrigin: map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{
+				primitive.ConsistencyLevelQuorum: primitive.ConsistencyLevelLocalQuorum,
+				primitive.ConsistencyLevelAll:    primitive.ConsistencyLevelLocalQuorum,
+			},
+			expectedTarget: map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{},
+		},
+		{
+			name:        "Invalid: Unknown consistency level",
+			envVars:     []envVar{{"ZDM_TARGET_CONSISTENCY_OVERRIDE", "LOCAL"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_CONSISTENCY_OVERRIDE (LOCAL); " +
+				"it must be a consistency level or comma separated <from>:<to> mappings",
+		},
+		{
+			name:        "Invalid: Serial consistency level",
+			envVars:     []envVar{{"ZDM_TARGET_CONSISTENCY_OVERRIDE", "SERIAL"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_CONSISTENCY_OVERRIDE (SERIAL); " +
+				"a serial consistency level can only be used in a <from>:<to> mapping",
+		},
+		{
+			name:        "Invalid: Mapping format",
+			envVars:     []envVar{{"ZDM_ORIGIN_CONSISTENCY_OVERRIDE", "QUORUM:ONE,ALL"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_CONSISTENCY_OVERRIDE (QUORUM:ONE,ALL); " +
+				"the mapping 'ALL' must have the format <from>:<to>",
+		},
+		{
+			name:        "Invalid: Unknown consistency level in mapping",
+			envVars:     []envVar{{"ZDM_ORIGIN_CONSISTENCY_OVERRIDE", "QUORUM:LOCAL"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_CONSISTENCY_OVERRIDE (QUORUM:LOCAL); " +
+				"unknown consistency level 'LOCAL'",
+		},
+		{
+			name:        "Invalid: Duplicate mapping",
+			envVars:     []envVar{{"ZDM_TARGET_CONSISTENCY_OVERRIDE", "QUORUM:ONE,quorum:LOCAL_ONE"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_CONSISTENCY_OVERRIDE (QUORUM:ONE,quorum:LOCAL_ONE); " +
+				"the consistency level 'quorum' is mapped more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, _ := conf.ParseOriginConsistencyOverride()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				actualTarget, _ := conf.ParseTargetConsistencyOverride()
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseCounterWritesPolicy(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedPolicy common.CounterWritesPolicy
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedPolicy: common.CounterWritesPolicyDualWrite,
+		},
+		{
+			name:           "Valid: Primary only",
+			envVars:        []envVar{{"ZDM_COUNTER_WRITES_POLICY", "primary_only"}},
+			expectedPolicy: common.CounterWritesPolicyPrimaryOnly,
+		},
+		{
+			name:           "Valid: Dual write",
+			envVars:        []envVar{{"ZDM_COUNTER_WRITES_POLICY", "DUAL_WRITE"}},
+			expectedPolicy: common.CounterWritesPolicyDualWrite,
+		},
+		{
+			name:           "Valid: Reject",
+			envVars:        []envVar{{"ZDM_COUNTER_WRITES_POLICY", "Reject"}},
+			expectedPolicy: common.CounterWritesPolicyReject,
+		},
+		{
+			name:        "Invalid: Unknown policy",
+			envVars:     []envVar{{"ZDM_COUNTER_WRITES_POLICY", "ORIGIN_ONLY"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_COUNTER_WRITES_POLICY; possible values are: DUAL_WRITE, PRIMARY_ONLY and REJECT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				policy, _ := conf.ParseCounterWritesPolicy()
+				require.Equal(t, tt.expectedPolicy, policy)
+			}
+		})
+	}
+}
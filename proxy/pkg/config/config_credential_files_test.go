@@ -0,0 +1,131 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_CredentialFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, contents string) string {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+	usernameFile := writeFile("username", "fileUser\n")
+	passwordFile := writeFile("password", "  filePassword \n")
+	missingFile := filepath.Join(dir, "missing")
+
+	type test struct {
+		name                   string
+		envVars                []envVar
+		expectedOriginUsername string
+		expectedOriginPassword string
+		expectedTargetPassword string
+		errExpected            bool
+		errMsg                 string
+	}
+
+	tests := []test{
+		{
+			name: "Valid: credentials from env vars",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_USERNAME", "originUser"}, {"ZDM_ORIGIN_PASSWORD", "originPassword"},
+				{"ZDM_TARGET_USERNAME", "targetUser"}, {"ZDM_TARGET_PASSWORD", "targetPassword"},
+			},
+			expectedOriginUsername: "originUser",
+			expectedOriginPassword: "originPassword",
+			expectedTargetPassword: "targetPassword",
+		},
+		{
+			name: "Valid: credentials from files",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_USERNAME_FILE", usernameFile}, {"ZDM_ORIGIN_PASSWORD_FILE", passwordFile},
+				{"ZDM_TARGET_USERNAME", "targetUser"}, {"ZDM_TARGET_PASSWORD_FILE", passwordFile},
+			},
+			expectedOriginUsername: "fileUser",
+			expectedOriginPassword: "filePassword",
+			expectedTargetPassword: "filePassword",
+		},
+		{
+			name: "Valid: empty credentials",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_USERNAME", ""}, {"ZDM_ORIGIN_PASSWORD", ""},
+				{"ZDM_TARGET_USERNAME", "targetUser"}, {"ZDM_TARGET_PASSWORD", "targetPassword"},
+			},
+			expectedOriginUsername: "",
+			expectedOriginPassword: "",
+			expectedTargetPassword: "targetPassword",
+		},
+		{
+			name: "Invalid: missing credential",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_USERNAME", "originUser"},
+				{"ZDM_TARGET_USERNAME", "targetUser"}, {"ZDM_TARGET_PASSWORD", "targetPassword"},
+			},
+			errExpected: true,
+			errMsg: "could not load environment variables: " +
+				"required key ZDM_ORIGIN_PASSWORD missing value (or ZDM_ORIGIN_PASSWORD_FILE)",
+		},
+		{
+			name: "Invalid: both env var and file",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_USERNAME", "originUser"}, {"ZDM_ORIGIN_PASSWORD", "originPassword"},
+				{"ZDM_TARGET_USERNAME", "targetUser"}, {"ZDM_TARGET_PASSWORD", "targetPassword"},
+				{"ZDM_TARGET_PASSWORD_FILE", passwordFile},
+			},
+			errExpected: true,
+			errMsg: "could not load environment variables: invalid value for ZDM_TARGET_PASSWORD_FILE (" +
+				passwordFile + "); ZDM_TARGET_PASSWORD must not be set as well",
+		},
+		{
+			name: "Invalid: missing file",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_USERNAME_FILE", missingFile}, {"ZDM_ORIGIN_PASSWORD", "originPassword"},
+				{"ZDM_TARGET_USERNAME", "targetUser"}, {"ZDM_TARGET_PASSWORD", "targetPassword"},
+			},
+			errExpected: true,
+			errMsg: "could not load environment variables: invalid value for ZDM_ORIGIN_USERNAME_FILE (" +
+				missingFile + "); could not read credential file: open " + missingFile + ": no such file or directory",
+		},
+		{
+			name: "Invalid: negative poll interval",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_USERNAME", "originUser"}, {"ZDM_ORIGIN_PASSWORD", "originPassword"},
+				{"ZDM_TARGET_USERNAME", "targetUser"}, {"ZDM_TARGET_PASSWORD", "targetPassword"},
+				{"ZDM_CREDENTIAL_FILES_POLL_INTERVAL_MS", "-1"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_CREDENTIAL_FILES_POLL_INTERVAL_MS (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if tt.errExpected {
+				require.NotNil(t, err)
+				require.Equal(t, tt.errMsg, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedOriginUsername, conf.OriginUsername)
+			require.Equal(t, tt.expectedOriginPassword, conf.OriginPassword)
+			require.Equal(t, tt.expectedTargetPassword, conf.TargetPassword)
+		})
+	}
+}
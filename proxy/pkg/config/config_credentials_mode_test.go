@@ -0,0 +1,91 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseCredentialsModes(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedOrigin common.CredentialsMode
+		expectedTarget common.CredentialsMode
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default credentials modes",
+			envVars:        []envVar{},
+			expectedOrigin: common.CredentialsModeAuto,
+			expectedTarget: common.CredentialsModeAuto,
+		},
+		{
+			name:           "Valid: Client credentials forwarded to both clusters",
+			envVars:        []envVar{{"ZDM_ORIGIN_CREDENTIALS_MODE", "client"}, {"ZDM_TARGET_CREDENTIALS_MODE", "CLIENT"}},
+			expectedOrigin: common.CredentialsModeClient,
+			expectedTarget: common.CredentialsModeClient,
+		},
+		{
+			name:           "Valid: Configured credentials on origin",
+			envVars:        []envVar{{"ZDM_ORIGIN_CREDENTIALS_MODE", " Configured "}},
+			expectedOrigin: common.CredentialsModeConfigured,
+			expectedTarget: common.CredentialsModeAuto,
+		},
+		{
+			name:        "Invalid: Unknown credentials mode",
+			envVars:     []envVar{{"ZDM_TARGET_CREDENTIALS_MODE", "proxy"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_CREDENTIALS_MODE (proxy); " +
+				"possible values are: AUTO, CLIENT and CONFIGURED",
+		},
+		{
+			name: "Invalid: Configured credentials on both clusters",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_CREDENTIALS_MODE", "CONFIGURED"}, {"ZDM_TARGET_CREDENTIALS_MODE", "CONFIGURED"}},
+			errExpected: true,
+			errMsg: "invalid values for ZDM_ORIGIN_CREDENTIALS_MODE and ZDM_TARGET_CREDENTIALS_MODE (CONFIGURED); " +
+				"the client credentials must be forwarded to at least one cluster",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, actualTarget, _ := conf.ParseCredentialsModes()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseDestructiveStatementsConfirmationConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.DestructiveStatementsConfirmationConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedConfig: &common.DestructiveStatementsConfirmationConfig{Enabled: false},
+		},
+		{
+			name:    "Valid: Enabled with default token TTL and max unlock duration",
+			envVars: []envVar{{"ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED", "true"}},
+			expectedConfig: &common.DestructiveStatementsConfirmationConfig{
+				Enabled: true, TokenTtlMs: 300000, MaxUnlockDurationMs: 3600000},
+		},
+		{
+			name: "Valid: Enabled without unlocking",
+			envVars: []envVar{
+				{"ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED", "true"},
+				{"ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_TOKEN_TTL_MS", "1000"},
+				{"ZDM_DESTRUCTIVE_STATEMENTS_MAX_UNLOCK_DURATION_MS", "0"},
+			},
+			expectedConfig: &common.DestructiveStatementsConfirmationConfig{
+				Enabled: true, TokenTtlMs: 1000, MaxUnlockDurationMs: 0},
+		},
+		{
+			name: "Valid: Invalid token TTL is ignored when disabled",
+			envVars: []envVar{
+				{"ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_TOKEN_TTL_MS", "0"},
+			},
+			expectedConfig: &common.DestructiveStatementsConfirmationConfig{Enabled: false},
+		},
+		{
+			name: "Invalid: Token TTL",
+			envVars: []envVar{
+				{"ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED", "true"},
+				{"ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_TOKEN_TTL_MS", "0"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_TOKEN_TTL_MS (0); " +
+				"it must be a positive number",
+		},
+		{
+			name: "Invalid: Max unlock duration",
+			envVars: []envVar{
+				{"ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED", "true"},
+				{"ZDM_DESTRUCTIVE_STATEMENTS_MAX_UNLOCK_DURATION_MS", "-1"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_DESTRUCTIVE_STATEMENTS_MAX_UNLOCK_DURATION_MS (-1); " +
+				"it must be 0 (unlocking is not allowed) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				confirmationConfig, _ := conf.ParseDestructiveStatementsConfirmationConfig()
+				require.Equal(t, tt.expectedConfig, confirmationConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseRuntimeStatsLogInterval(t *testing.T) {
+
+	type test struct {
+		name                       string
+		envVars                    []envVar
+		expectedInterval           int
+		expectedDiagnosticsEnabled bool
+		errExpected                bool
+		errMsg                     string
+	}
+
+	tests := []test{
+		{
+			name:                       "Valid: Diagnostics unset",
+			envVars:                    []envVar{},
+			expectedInterval:           0,
+			expectedDiagnosticsEnabled: false,
+			errExpected:                false,
+			errMsg:                     "",
+		},
+		{
+			name: "Valid: Diagnostics enabled",
+			envVars: []envVar{
+				{"ZDM_DIAGNOSTICS_ENABLED", "true"},
+				{"ZDM_RUNTIME_STATS_LOG_INTERVAL_MS", "60000"}},
+			expectedInterval:           60000,
+			expectedDiagnosticsEnabled: true,
+			errExpected:                false,
+			errMsg:                     "",
+		},
+		{
+			name:                       "Invalid: Negative log interval",
+			envVars:                    []envVar{{"ZDM_RUNTIME_STATS_LOG_INTERVAL_MS", "-1"}},
+			expectedInterval:           0,
+			expectedDiagnosticsEnabled: false,
+			errExpected:                true,
+			errMsg: "invalid value for ZDM_RUNTIME_STATS_LOG_INTERVAL_MS (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualInterval, _ := conf.ParseRuntimeStatsLogInterval()
+				require.Equal(t, tt.expectedInterval, actualInterval)
+				require.Equal(t, tt.expectedDiagnosticsEnabled, conf.DiagnosticsEnabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseDualWriteMode(t *testing.T) {
+
+	type test struct {
+		name                  string
+		envVars               []envVar
+		expectedDualWriteMode common.DualWriteMode
+		errExpected           bool
+		errMsg                string
+	}
+
+	tests := []test{
+		{
+			name:                  "Valid: Dual write mode unset",
+			envVars:               []envVar{},
+			expectedDualWriteMode: common.DualWriteModeSync,
+		},
+		{
+			name:                  "Valid: Sync dual writes",
+			envVars:               []envVar{{"ZDM_DUAL_WRITE_MODE", "sync"}},
+			expectedDualWriteMode: common.DualWriteModeSync,
+		},
+		{
+			name:                  "Valid: Async dual writes",
+			envVars:               []envVar{{"ZDM_DUAL_WRITE_MODE", "ASYNC"}},
+			expectedDualWriteMode: common.DualWriteModeAsync,
+		},
+		{
+			name: "Valid: Async dual writes without retries",
+			envVars: []envVar{
+				{"ZDM_DUAL_WRITE_MODE", "ASYNC"},
+				{"ZDM_DUAL_WRITE_ASYNC_MAX_RETRIES", "0"},
+			},
+			expectedDualWriteMode: common.DualWriteModeAsync,
+		},
+		{
+			name:                  "Invalid: Unknown dual write mode",
+			envVars:               []envVar{{"ZDM_DUAL_WRITE_MODE", "PRIMARY_ONLY"}},
+			expectedDualWriteMode: common.DualWriteModeUndefined,
+			errExpected:           true,
+			errMsg:                "invalid value for ZDM_DUAL_WRITE_MODE; possible values are: SYNC and ASYNC",
+		},
+		{
+			name: "Invalid: Async dual writes with zero timeout",
+			envVars: []envVar{
+				{"ZDM_DUAL_WRITE_MODE", "ASYNC"},
+				{"ZDM_DUAL_WRITE_ASYNC_TIMEOUT_MS", "0"},
+			},
+			expectedDualWriteMode: common.DualWriteModeUndefined,
+			errExpected:           true,
+			errMsg:                "invalid value for ZDM_DUAL_WRITE_ASYNC_TIMEOUT_MS (0); it must be a positive number",
+		},
+		{
+			name: "Invalid: Async dual writes with negative retries",
+			envVars: []envVar{
+				{"ZDM_DUAL_WRITE_MODE", "ASYNC"},
+				{"ZDM_DUAL_WRITE_ASYNC_MAX_RETRIES", "-1"},
+			},
+			expectedDualWriteMode: common.DualWriteModeUndefined,
+			errExpected:           true,
+			errMsg: "invalid value for ZDM_DUAL_WRITE_ASYNC_MAX_RETRIES (-1); " +
+				"it must be 0 (writes are not retried) or a positive number",
+		},
+		{
+			name: "Valid: Async settings are ignored with sync dual writes",
+			envVars: []envVar{
+				{"ZDM_DUAL_WRITE_MODE", "SYNC"},
+				{"ZDM_DUAL_WRITE_ASYNC_TIMEOUT_MS", "0"},
+			},
+			expectedDualWriteMode: common.DualWriteModeSync,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualDualWriteMode, _ := conf.ParseDualWriteMode()
+				require.Equal(t, tt.expectedDualWriteMode, actualDualWriteMode)
+			}
+		})
+	}
+}
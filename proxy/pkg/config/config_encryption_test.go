@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type upperCaseDecryptionProvider struct{}
+
+func (recv *upperCaseDecryptionProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 {
+		return nil, fmt.Errorf("empty ciphertext")
+	}
+	return []byte(strings.ToUpper(string(ciphertext))), nil
+}
+
+func TestConfig_EncryptedValues(t *testing.T) {
+	RegisterDecryptionProvider("test", &upperCaseDecryptionProvider{})
+	// the env vars (including PATH) are cleared by the tests
+	const catPath = "/bin/cat"
+	encrypt := func(provider string, plaintext string) string {
+		return "enc:" + provider + ":" + base64.StdEncoding.EncodeToString([]byte(plaintext))
+	}
+
+	type test struct {
+		name                   string
+		envVars                []envVar
+		expectedOriginPassword string
+		expectedTargetUsername string
+		errExpected            bool
+		errMsg                 string
+	}
+
+	tests := []test{
+		{
+			name:                   "Valid: plain values",
+			envVars:                []envVar{},
+			expectedOriginPassword: "originPassword",
+			expectedTargetUsername: "targetUser",
+		},
+		{
+			name: "Valid: registered provider",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_PASSWORD", encrypt("test", "secret")},
+				{"ZDM_TARGET_USERNAME", encrypt("test", "user")},
+			},
+			expectedOriginPassword: "SECRET",
+			expectedTargetUsername: "USER",
+		},
+		{
+			name: "Valid: decryption command",
+			envVars: []envVar{
+				{"ZDM_CONFIG_DECRYPTION_COMMAND", catPath},
+				{"ZDM_ORIGIN_PASSWORD", encrypt("cmd", "secret\n")},
+			},
+			expectedOriginPassword: "secret",
+			expectedTargetUsername: "targetUser",
+		},
+		{
+			name:        "Invalid: decryption command not set",
+			envVars:     []envVar{{"ZDM_ORIGIN_PASSWORD", encrypt("cmd", "secret")}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_ORIGIN_PASSWORD; could not decrypt it: ZDM_CONFIG_DECRYPTION_COMMAND is not set",
+		},
+		{
+			name:        "Invalid: unknown provider",
+			envVars:     []envVar{{"ZDM_ORIGIN_PASSWORD", encrypt("kms", "secret")}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_PASSWORD; could not decrypt it: " +
+				"unknown decryption provider 'kms', valid providers are: cmd, test",
+		},
+		{
+			name:        "Invalid: missing provider",
+			envVars:     []envVar{{"ZDM_TARGET_USERNAME", "enc:c2VjcmV0"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_USERNAME; could not decrypt it: " +
+				"expected enc:<provider>:<base64 ciphertext>",
+		},
+		{
+			name:        "Invalid: provider error",
+			envVars:     []envVar{{"ZDM_TARGET_USERNAME", "enc:test:"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_USERNAME; could not decrypt it: empty ciphertext",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			if tt.name == "Valid: decryption command" {
+				if _, err := os.Stat(catPath); err != nil {
+					t.Skipf("%v is not available", catPath)
+				}
+			}
+
+			conf, err := New().ParseEnvVars()
+			if tt.errExpected {
+				require.NotNil(t, err)
+				require.Equal(t, tt.errMsg, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedOriginPassword, conf.OriginPassword)
+			require.Equal(t, tt.expectedTargetUsername, conf.TargetUsername)
+			if tt.expectedTargetUsername == "USER" {
+				require.NotContains(t, conf.String(), "USER")
+				require.Contains(t, conf.String(), `"TargetUsername":"*****"`)
+			}
+		})
+	}
+}
+
+func TestConfig_EnvVarName(t *testing.T) {
+	for _, name := range []string{"OriginPassword", "ProxyTlsCaPath", "TargetSecureConnectBundlePath", "ConfigDecryptionCommand"} {
+		field, ok := reflect.TypeOf(Config{}).FieldByName(name)
+		require.True(t, ok, name)
+		require.Equal(t, map[string]string{
+			"OriginPassword":                "ZDM_ORIGIN_PASSWORD",
+			"ProxyTlsCaPath":                "ZDM_PROXY_TLS_CA_PATH",
+			"TargetSecureConnectBundlePath": "ZDM_TARGET_SECURE_CONNECT_BUNDLE_PATH",
+			"ConfigDecryptionCommand":       "ZDM_CONFIG_DECRYPTION_COMMAND",
+		}[name], envVarName(field))
+	}
+}
+
+func TestConfig_RedactedValues(t *testing.T) {
+	RegisterDecryptionProvider("test", &upperCaseDecryptionProvider{})
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+	setEnvVar("ZDM_TARGET_USERNAME", "enc:test:"+base64.StdEncoding.EncodeToString([]byte("user")))
+	setEnvVar("ZDM_ORIGIN_PASSWORD", "")
+
+	conf, err := New().ParseEnvVars()
+	require.Nil(t, err)
+	values := conf.RedactedValues()
+	require.Equal(t, "originUser", values["ZDM_ORIGIN_USERNAME"])
+	require.Equal(t, "", values["ZDM_ORIGIN_PASSWORD"])
+	require.Equal(t, "*****", values["ZDM_TARGET_USERNAME"])
+	require.Equal(t, "*****", values["ZDM_TARGET_PASSWORD"])
+	require.Equal(t, 7890, values["ZDM_ORIGIN_PORT"])
+	require.Equal(t, "PRIMARY_ONLY", values["ZDM_READ_MODE"])
+	require.NotContains(t, values, "ZDM_DECRYPTED_FIELDS")
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestConfig_ParseExplainRequestsClientAddresses(t *testing.T) {
+
+	type test struct {
+		name              string
+		envVars           []envVar
+		expectedAddresses []net.IP
+		errExpected       bool
+		errMsg            string
+	}
+
+	tests := []test{
+		{
+			name:              "Valid: No client addresses",
+			envVars:           []envVar{},
+			expectedAddresses: nil,
+		},
+		{
+			name:              "Valid: IPv4 and IPv6 client addresses",
+			envVars:           []envVar{{"ZDM_EXPLAIN_REQUESTS_CLIENT_ADDRESSES", "10.0.0.1, ::1"}},
+			expectedAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")},
+		},
+		{
+			name:        "Invalid: Hostname",
+			envVars:     []envVar{{"ZDM_EXPLAIN_REQUESTS_CLIENT_ADDRESSES", "10.0.0.1,localhost"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_EXPLAIN_REQUESTS_CLIENT_ADDRESSES (10.0.0.1,localhost); " +
+				"localhost is not a valid IP address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				addresses, _ := conf.ParseExplainRequestsClientAddresses()
+				require.Equal(t, tt.expectedAddresses, addresses)
+			}
+		})
+	}
+}
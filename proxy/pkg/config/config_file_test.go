@@ -0,0 +1,159 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_ParseConfigFileAndEnvVars(t *testing.T) {
+	directory := t.TempDir()
+	writeFile := func(name string, contents string) string {
+		path := filepath.Join(directory, name)
+		require.Nil(t, os.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+
+	yamlFile := writeFile("zdm-proxy.yaml", `
+origin_contact_points: [origin1.hostname.com, origin2.hostname.com]
+origin_port: 9043
+ZDM_ORIGIN_USERNAME: fileOriginUser
+origin_password: fileOriginPassword
+target_contact_points: target.hostname.com
+target_username: fileTargetUser
+target_password: fileTargetPassword
+primary_cluster: TARGET
+proxy_topology_virtualization_enabled: false
+tracing_sampler_ratio: 0.25
+traffic_capture_max_file_size_bytes: 104857601
+`)
+	jsonFile := writeFile("zdm-proxy.json", `{
+  "origin_contact_points": "origin.hostname.com",
+  "origin_username": "fileOriginUser",
+  "origin_password": "fileOriginPassword",
+  "target_contact_points": "target.hostname.com",
+  "target_username": "fileTargetUser",
+  "target_password": "fileTargetPassword",
+  "traffic_capture_max_file_size_bytes": 104857601
+}`)
+
+	t.Run("YAML file", func(t *testing.T) {
+		clearAllEnvVars()
+		conf, err := New().ParseConfigFileAndEnvVars(yamlFile)
+		require.Nil(t, err)
+		require.Equal(t, yamlFile, conf.ConfigFile)
+		require.Equal(t, "origin1.hostname.com,origin2.hostname.com", conf.OriginContactPoints)
+		require.Equal(t, 9043, conf.OriginPort)
+		require.Equal(t, "fileOriginUser", conf.OriginUsername)
+		require.Equal(t, "fileOriginPassword", conf.OriginPassword)
+		require.Equal(t, "TARGET", conf.PrimaryCluster)
+		require.False(t, conf.ProxyTopologyVirtualizationEnabled)
+		require.Equal(t, 0.25, conf.TracingSamplerRatio)
+		require.Equal(t, 104857601, conf.TrafficCaptureMaxFileSizeBytes)
+		require.Equal(t, "INFO", conf.LogLevel)
+	})
+
+	t.Run("JSON file from ZDM_CONFIG_FILE", func(t *testing.T) {
+		clearAllEnvVars()
+		setEnvVar("ZDM_CONFIG_FILE", jsonFile)
+		conf, err := New().ParseEnvVars()
+		require.Nil(t, err)
+		require.Equal(t, "origin.hostname.com", conf.OriginContactPoints)
+		require.Equal(t, "fileTargetUser", conf.TargetUsername)
+		require.Equal(t, 104857601, conf.TrafficCaptureMaxFileSizeBytes)
+	})
+
+	t.Run("Environment variables override the file", func(t *testing.T) {
+		clearAllEnvVars()
+		setOriginCredentialsEnvVars()
+		setEnvVar("ZDM_ORIGIN_PORT", "7890")
+		setEnvVar("ZDM_PRIMARY_CLUSTER", "ORIGIN")
+		conf, err := New().ParseConfigFileAndEnvVars(yamlFile)
+		require.Nil(t, err)
+		require.Equal(t, "originUser", conf.OriginUsername)
+		require.Equal(t, "originPassword", conf.OriginPassword)
+		require.Equal(t, 7890, conf.OriginPort)
+		require.Equal(t, "ORIGIN", conf.PrimaryCluster)
+		require.Equal(t, "fileTargetUser", conf.TargetUsername)
+	})
+
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		errMsg   string
+	}{
+		{
+			name:     "Unknown setting",
+			file:     "unknown.yaml",
+			contents: "origin_contact_point: origin.hostname.com",
+			errMsg:   "invalid setting origin_contact_point in config file %v; unknown setting",
+		},
+		{
+			name:     "Invalid integer",
+			file:     "invalid-int.yaml",
+			contents: "origin_port: abc",
+			errMsg:   "invalid value for origin_port in config file %v; expected an integer but got 'abc'",
+		},
+		{
+			name:     "Nested value",
+			file:     "nested.json",
+			contents: `{"origin_port": {"value": 9042}}`,
+			errMsg:   "invalid value for origin_port in config file %v; expected a scalar or a list but got map[string]interface {}",
+		},
+		{
+			name:     "Unsupported extension",
+			file:     "zdm-proxy.toml",
+			contents: "origin_port = 9042",
+			errMsg:   "could not load config file %v: unsupported file extension .toml, expected .yaml, .yml or .json",
+		},
+		{
+			name:     "Validation",
+			file:     "invalid-setting.yml",
+			contents: "origin_contact_points: origin\ntarget_contact_points: target\nprimary_cluster: ASYNC",
+			errMsg:   "invalid value for ZDM_PRIMARY_CLUSTER; possible values are: ORIGIN and TARGET",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			path := writeFile(tt.file, tt.contents)
+			_, err := New().ParseConfigFileAndEnvVars(path)
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), strings.Replace(tt.errMsg, "%v", path, 1))
+		})
+	}
+}
+
+func TestConfig_Dump(t *testing.T) {
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+
+	conf, err := New().ParseEnvVars()
+	require.Nil(t, err)
+	dump := conf.Dump()
+	require.Contains(t, dump, "origin_username: originUser\n")
+	require.Contains(t, dump, "origin_password: '*****'\n")
+	require.Contains(t, dump, "target_password: '*****'\n")
+	require.Contains(t, dump, "origin_port: 7890\n")
+	require.NotContains(t, dump, "originPassword")
+	require.NotContains(t, dump, "config_file:")
+
+	// the dump can be used as a config file
+	path := filepath.Join(t.TempDir(), "dump.yaml")
+	require.Nil(t, os.WriteFile(path, []byte(dump), 0600))
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	dumpedConf, err := New().ParseConfigFileAndEnvVars(path)
+	require.Nil(t, err)
+	require.Equal(t, conf.OriginContactPoints, dumpedConf.OriginContactPoints)
+	require.Equal(t, conf.TargetPort, dumpedConf.TargetPort)
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseFleetStoreConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.FleetStoreConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedConfig: &common.FleetStoreConfig{},
+		},
+		{
+			name: "Valid: etcd",
+			envVars: []envVar{
+				{"ZDM_FLEET_STORE_TYPE", "ETCD"},
+				{"ZDM_FLEET_STORE_ENDPOINTS", "http://etcd-0:2379/, https://etcd-1:2379"},
+				{"ZDM_ADMIN_KEYSPACE_ROLES", "ops"},
+			},
+			expectedConfig: &common.FleetStoreConfig{
+				Type: "etcd", Endpoints: []string{"http://etcd-0:2379", "https://etcd-1:2379"}, Key: "zdm-proxy/settings"},
+		},
+		{
+			name: "Valid: consul with key",
+			envVars: []envVar{
+				{"ZDM_FLEET_STORE_TYPE", "consul"},
+				{"ZDM_FLEET_STORE_ENDPOINTS", "http://127.0.0.1:8500"},
+				{"ZDM_FLEET_STORE_KEY", "/migrations/ks1/"},
+				{"ZDM_ADMIN_KEYSPACE_ROLES", "ops"},
+			},
+			expectedConfig: &common.FleetStoreConfig{
+				Type: "consul", Endpoints: []string{"http://127.0.0.1:8500"}, Key: "migrations/ks1"},
+		},
+		{
+			name: "Invalid: type",
+			envVars: []envVar{
+				{"ZDM_FLEET_STORE_TYPE", "zookeeper"},
+				{"ZDM_FLEET_STORE_ENDPOINTS", "http://127.0.0.1:2181"},
+				{"ZDM_ADMIN_KEYSPACE_ROLES", "ops"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_FLEET_STORE_TYPE (zookeeper); valid values are etcd and consul",
+		},
+		{
+			name: "Invalid: no endpoints",
+			envVars: []envVar{
+				{"ZDM_FLEET_STORE_TYPE", "etcd"},
+				{"ZDM_ADMIN_KEYSPACE_ROLES", "ops"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_FLEET_STORE_ENDPOINTS (); " +
+				"at least one endpoint is required when ZDM_FLEET_STORE_TYPE is set",
+		},
+		{
+			name: "Invalid: endpoint without scheme",
+			envVars: []envVar{
+				{"ZDM_FLEET_STORE_TYPE", "etcd"},
+				{"ZDM_FLEET_STORE_ENDPOINTS", "etcd-0:2379"},
+				{"ZDM_ADMIN_KEYSPACE_ROLES", "ops"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_FLEET_STORE_ENDPOINTS (etcd-0:2379); etcd-0:2379 is not a valid http or https URL",
+		},
+		{
+			name: "Invalid: no admin keyspace roles",
+			envVars: []envVar{
+				{"ZDM_FLEET_STORE_TYPE", "etcd"},
+				{"ZDM_FLEET_STORE_ENDPOINTS", "http://etcd-0:2379"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_FLEET_STORE_TYPE (etcd); ZDM_ADMIN_KEYSPACE_ROLES must be set as well",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if tt.errExpected {
+				require.NotNil(t, err)
+				require.Equal(t, tt.errMsg, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			fleetStoreConfig, err := conf.ParseFleetStoreConfig()
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedConfig, fleetStoreConfig)
+		})
+	}
+}
+
+func TestConfig_ParseLeaderElectionConfig(t *testing.T) {
+	fleetStoreEnvVars := []envVar{
+		{"ZDM_FLEET_STORE_TYPE", "etcd"},
+		{"ZDM_FLEET_STORE_ENDPOINTS", "http://etcd-0:2379"},
+		{"ZDM_ADMIN_KEYSPACE_ROLES", "ops"},
+	}
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.LeaderElectionConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        fleetStoreEnvVars,
+			expectedConfig: &common.LeaderElectionConfig{},
+		},
+		{
+			name:           "Valid: Enabled",
+			envVars:        append([]envVar{{"ZDM_LEADER_ELECTION_ENABLED", "true"}}, fleetStoreEnvVars...),
+			expectedConfig: &common.LeaderElectionConfig{Enabled: true, Key: "zdm-proxy/leader", TtlMs: 15000},
+		},
+		{
+			name:        "Invalid: No fleet store",
+			envVars:     []envVar{{"ZDM_LEADER_ELECTION_ENABLED", "true"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_LEADER_ELECTION_ENABLED (true); ZDM_FLEET_STORE_TYPE must be set as well",
+		},
+		{
+			name: "Invalid: Same key as the fleet store",
+			envVars: append([]envVar{
+				{"ZDM_LEADER_ELECTION_ENABLED", "true"}, {"ZDM_LEADER_ELECTION_KEY", "zdm-proxy/settings"}}, fleetStoreEnvVars...),
+			errExpected: true,
+			errMsg: "invalid value for ZDM_LEADER_ELECTION_KEY (zdm-proxy/settings); " +
+				"it must not be empty or the same as ZDM_FLEET_STORE_KEY",
+		},
+		{
+			name: "Invalid: TTL",
+			envVars: append([]envVar{
+				{"ZDM_LEADER_ELECTION_ENABLED", "true"}, {"ZDM_LEADER_ELECTION_TTL_MS", "5000"}}, fleetStoreEnvVars...),
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_LEADER_ELECTION_TTL_MS (5000); it must be at least 10000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if tt.errExpected {
+				require.NotNil(t, err)
+				require.Equal(t, tt.errMsg, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			leaderElectionConfig, err := conf.ParseLeaderElectionConfig()
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedConfig, leaderElectionConfig)
+		})
+	}
+}
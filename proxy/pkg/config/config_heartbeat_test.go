@@ -0,0 +1,85 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseClusterConnectionHeartbeatConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.ClusterConnectionHeartbeatConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default heartbeats",
+			envVars:        []envVar{},
+			expectedConfig: &common.ClusterConnectionHeartbeatConfig{Enabled: true, IntervalMs: 30000, MaxMissed: 3},
+		},
+		{
+			name: "Valid: Heartbeat interval and missed heartbeats",
+			envVars: []envVar{
+				{"ZDM_HEARTBEAT_INTERVAL_MS", "5000"}, {"ZDM_HEARTBEAT_MAX_MISSED", "1"}},
+			expectedConfig: &common.ClusterConnectionHeartbeatConfig{Enabled: true, IntervalMs: 5000, MaxMissed: 1},
+		},
+		{
+			name: "Valid: Heartbeats disabled",
+			envVars: []envVar{
+				{"ZDM_HEARTBEAT_CLUSTER_CONNECTIONS_ENABLED", "false"}, {"ZDM_HEARTBEAT_MAX_MISSED", "0"}},
+			expectedConfig: &common.ClusterConnectionHeartbeatConfig{},
+		},
+		{
+			name:        "Invalid: Missed heartbeats not positive",
+			envVars:     []envVar{{"ZDM_HEARTBEAT_MAX_MISSED", "0"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_HEARTBEAT_MAX_MISSED (0); it must be a positive number",
+		},
+		{
+			name:        "Invalid: Heartbeat interval not positive",
+			envVars:     []envVar{{"ZDM_HEARTBEAT_INTERVAL_MS", "-1"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_HEARTBEAT_INTERVAL_MS (-1); it must be a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualConfig, _ := conf.ParseClusterConnectionHeartbeatConfig()
+				require.Equal(t, tt.expectedConfig, actualConfig)
+			}
+		})
+	}
+}
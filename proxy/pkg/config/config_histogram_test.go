@@ -0,0 +1,141 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseHistogramBuckets(t *testing.T) {
+
+	type test struct {
+		name                       string
+		envVars                    []envVar
+		expectedOriginBuckets      []float64
+		expectedOverrides          map[string][]float64
+		expectedNativeBucketFactor float64
+		errExpected                bool
+		errMsg                     string
+	}
+
+	tests := []test{
+		{
+			name:                  "Valid: Bucket list",
+			envVars:               []envVar{{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", "5, 10, 100"}},
+			expectedOriginBuckets: []float64{0.005, 0.01, 0.1},
+			expectedOverrides:     map[string][]float64{},
+		},
+		{
+			name:                  "Valid: Exponential buckets",
+			envVars:               []envVar{{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", "exponential:1,2,4"}},
+			expectedOriginBuckets: []float64{0.001, 0.002, 0.004, 0.008},
+			expectedOverrides:     map[string][]float64{},
+		},
+		{
+			name:                  "Valid: Linear buckets",
+			envVars:               []envVar{{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", " linear: 10, 10, 3"}},
+			expectedOriginBuckets: []float64{0.01, 0.02, 0.03},
+			expectedOverrides:     map[string][]float64{},
+		},
+		{
+			name: "Valid: Overrides",
+			envVars: []envVar{
+				{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", "5, 10, 100"},
+				{"ZDM_METRICS_HISTOGRAM_BUCKETS_MS_OVERRIDES",
+					"proxy_request_duration_seconds=exponential:1,10,3; async_request_duration_seconds=50,500;"},
+			},
+			expectedOriginBuckets: []float64{0.005, 0.01, 0.1},
+			expectedOverrides: map[string][]float64{
+				"proxy_request_duration_seconds": {0.001, 0.01, 0.1},
+				"async_request_duration_seconds": {0.05, 0.5},
+			},
+		},
+		{
+			name: "Valid: Native histograms",
+			envVars: []envVar{
+				{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", "5, 10, 100"},
+				{"ZDM_METRICS_NATIVE_HISTOGRAMS_ENABLED", "true"},
+			},
+			expectedOriginBuckets:      []float64{0.005, 0.01, 0.1},
+			expectedOverrides:          map[string][]float64{},
+			expectedNativeBucketFactor: 1.1,
+		},
+		{
+			name:        "Invalid: Exponential factor",
+			envVars:     []envVar{{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", "exponential:1,1,4"}},
+			errExpected: true,
+			errMsg: "could not parse origin buckets: unable to parse buckets from exponential:1,1,4: " +
+				"start must be positive and factor must be greater than 1",
+		},
+		{
+			name:        "Invalid: Linear count",
+			envVars:     []envVar{{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", "linear:1,1,0"}},
+			errExpected: true,
+			errMsg:      "could not parse origin buckets: unable to parse buckets from linear:1,1,0: count must be a positive integer",
+		},
+		{
+			name:        "Invalid: Strategy parameters",
+			envVars:     []envVar{{"ZDM_METRICS_ORIGIN_LATENCY_BUCKETS_MS", "linear:1,1"}},
+			errExpected: true,
+			errMsg: "could not parse origin buckets: unable to parse buckets from linear:1,1: " +
+				"expected 3 parameters (start, factor or width, count) but got 2",
+		},
+		{
+			name:        "Invalid: Override without buckets",
+			envVars:     []envVar{{"ZDM_METRICS_HISTOGRAM_BUCKETS_MS_OVERRIDES", "proxy_request_duration_seconds"}},
+			errExpected: true,
+			errMsg: "could not parse histogram buckets overrides: invalid histogram buckets override " +
+				"proxy_request_duration_seconds: expected <metric_name>=<buckets>",
+		},
+		{
+			name: "Invalid: Native histogram bucket factor",
+			envVars: []envVar{
+				{"ZDM_METRICS_NATIVE_HISTOGRAMS_ENABLED", "true"},
+				{"ZDM_METRICS_NATIVE_HISTOGRAM_BUCKET_FACTOR", "1"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_METRICS_NATIVE_HISTOGRAM_BUCKET_FACTOR (1); it must be greater than 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				originBuckets, _ := conf.ParseOriginBuckets()
+				require.InDeltaSlice(t, tt.expectedOriginBuckets, originBuckets, 1e-9)
+				overrides, _ := conf.ParseHistogramBucketsOverrides()
+				require.Equal(t, len(tt.expectedOverrides), len(overrides))
+				for name, expectedBuckets := range tt.expectedOverrides {
+					require.InDeltaSlice(t, expectedBuckets, overrides[name], 1e-9)
+				}
+				nativeBucketFactor, _ := conf.ParseNativeHistogramBucketFactor()
+				require.Equal(t, tt.expectedNativeBucketFactor, nativeBucketFactor)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestConfig_Ipv6Addresses(t *testing.T) {
+
+	type test struct {
+		name                   string
+		envVars                []envVar
+		expectedOriginContacts []string
+		expectedTargetContacts []string
+		expectedListenAddress  string
+		expectedMetricsAddress string
+		expectedTopologyAddrs  []net.IP
+	}
+
+	tests := []test{
+		{
+			name: "Unbracketed literals",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_CONTACT_POINTS", "::1, 10.0.0.1"},
+				{"ZDM_TARGET_CONTACT_POINTS", "fd00::1,fd00::2"},
+				{"ZDM_PROXY_LISTEN_ADDRESS", "::1"},
+				{"ZDM_METRICS_ADDRESS", "::"},
+			},
+			expectedOriginContacts: []string{"::1", "10.0.0.1"},
+			expectedTargetContacts: []string{"fd00::1", "fd00::2"},
+			expectedListenAddress:  "::1",
+			expectedMetricsAddress: "::",
+			expectedTopologyAddrs:  []net.IP{net.ParseIP("::1")},
+		},
+		{
+			name: "Bracketed literals",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_CONTACT_POINTS", "[::1], 10.0.0.1"},
+				{"ZDM_TARGET_CONTACT_POINTS", "[fd00::1],[fd00::2]"},
+				{"ZDM_PROXY_LISTEN_ADDRESS", "[::1]"},
+				{"ZDM_METRICS_ADDRESS", "[::]"},
+				{"ZDM_PROXY_TOPOLOGY_ADDRESSES", "[fd00::10], 10.0.0.10"},
+			},
+			expectedOriginContacts: []string{"::1", "10.0.0.1"},
+			expectedTargetContacts: []string{"fd00::1", "fd00::2"},
+			expectedListenAddress:  "::1",
+			expectedMetricsAddress: "::",
+			expectedTopologyAddrs:  []net.IP{net.ParseIP("fd00::10"), net.ParseIP("10.0.0.10")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setEnvVar("ZDM_ORIGIN_PORT", "9042")
+			setEnvVar("ZDM_TARGET_PORT", "9042")
+
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			conf, err := New().ParseEnvVars()
+			require.Nil(t, err)
+
+			originContactPoints, err := conf.ParseOriginContactPoints()
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedOriginContacts, originContactPoints)
+
+			targetContactPoints, err := conf.ParseTargetContactPoints()
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedTargetContacts, targetContactPoints)
+
+			require.Equal(t, tt.expectedListenAddress, conf.ProxyListenAddress)
+			require.Equal(t, tt.expectedMetricsAddress, conf.MetricsAddress)
+
+			topologyConfig, err := conf.ParseTopologyConfig()
+			require.Nil(t, err)
+			require.Equal(t, len(tt.expectedTopologyAddrs), len(topologyConfig.Addresses))
+			for i, expectedAddr := range tt.expectedTopologyAddrs {
+				require.True(t, expectedAddr.Equal(topologyConfig.Addresses[i]),
+					"expected %v but got %v", expectedAddr, topologyConfig.Addresses[i])
+			}
+		})
+	}
+}
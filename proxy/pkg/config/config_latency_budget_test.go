@@ -0,0 +1,121 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseLatencyBudgetConfig(t *testing.T) {
+
+	type test struct {
+		name               string
+		envVars            []envVar
+		expectedOrigin     *common.LatencyBudgetConfig
+		expectedTarget     *common.LatencyBudgetConfig
+		expectedWebhookUrl string
+		errExpected        bool
+		errMsg             string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Latency budgets unset",
+			envVars:        []envVar{},
+			expectedOrigin: &common.LatencyBudgetConfig{Enabled: false},
+			expectedTarget: &common.LatencyBudgetConfig{Enabled: false},
+		},
+		{
+			name: "Valid: Target latency budget with default percentile and window",
+			envVars: []envVar{
+				{"ZDM_TARGET_LATENCY_BUDGET_MS", "50"},
+				{"ZDM_LATENCY_BUDGET_WEBHOOK_URL", " https://alerts.example.com/hook "},
+			},
+			expectedOrigin:     &common.LatencyBudgetConfig{Enabled: false},
+			expectedTarget:     &common.LatencyBudgetConfig{Enabled: true, BudgetMs: 50, Percentile: 99, WindowMs: 60000},
+			expectedWebhookUrl: "https://alerts.example.com/hook",
+		},
+		{
+			name: "Valid: Origin latency budget",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_LATENCY_BUDGET_MS", "20"},
+				{"ZDM_ORIGIN_LATENCY_BUDGET_PERCENTILE", "99.9"},
+				{"ZDM_ORIGIN_LATENCY_BUDGET_WINDOW_MS", "300000"},
+			},
+			expectedOrigin: &common.LatencyBudgetConfig{Enabled: true, BudgetMs: 20, Percentile: 99.9, WindowMs: 300000},
+			expectedTarget: &common.LatencyBudgetConfig{Enabled: false},
+		},
+		{
+			name:        "Invalid: Negative budget",
+			envVars:     []envVar{{"ZDM_ORIGIN_LATENCY_BUDGET_MS", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_LATENCY_BUDGET_MS (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+		{
+			name: "Invalid: Percentile",
+			envVars: []envVar{
+				{"ZDM_TARGET_LATENCY_BUDGET_MS", "50"},
+				{"ZDM_TARGET_LATENCY_BUDGET_PERCENTILE", "100"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_LATENCY_BUDGET_PERCENTILE (100); " +
+				"it must be greater than 0 and lower than 100",
+		},
+		{
+			name: "Invalid: Window",
+			envVars: []envVar{
+				{"ZDM_TARGET_LATENCY_BUDGET_MS", "50"},
+				{"ZDM_TARGET_LATENCY_BUDGET_WINDOW_MS", "500"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_LATENCY_BUDGET_WINDOW_MS (500); it must be at least 1000",
+		},
+		{
+			name:        "Invalid: Webhook URL",
+			envVars:     []envVar{{"ZDM_LATENCY_BUDGET_WEBHOOK_URL", "alerts.example.com"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_LATENCY_BUDGET_WEBHOOK_URL (alerts.example.com); " +
+				"it must be an http or https URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, _ := conf.ParseOriginLatencyBudgetConfig()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				actualTarget, _ := conf.ParseTargetLatencyBudgetConfig()
+				require.Equal(t, tt.expectedTarget, actualTarget)
+				actualWebhookUrl, _ := conf.ParseLatencyBudgetWebhookUrl()
+				require.Equal(t, tt.expectedWebhookUrl, actualWebhookUrl)
+			}
+		})
+	}
+}
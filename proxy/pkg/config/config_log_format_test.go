@@ -0,0 +1,71 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseLogFormat(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedFormat common.LogFormat
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedFormat: common.LogFormatText,
+		},
+		{
+			name:           "Valid: JSON",
+			envVars:        []envVar{{"ZDM_LOG_FORMAT", "json"}},
+			expectedFormat: common.LogFormatJson,
+		},
+		{
+			name:        "Invalid: Unknown format",
+			envVars:     []envVar{{"ZDM_LOG_FORMAT", "LOGFMT"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_LOG_FORMAT; possible values are: TEXT and JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualFormat, _ := conf.ParseLogFormat()
+				require.Equal(t, tt.expectedFormat, actualFormat)
+			}
+		})
+	}
+}
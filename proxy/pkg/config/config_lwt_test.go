@@ -0,0 +1,77 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseLwtPolicy(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedPolicy common.LwtPolicy
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedPolicy: common.LwtPolicyDualWrite,
+		},
+		{
+			name:           "Valid: Primary only",
+			envVars:        []envVar{{"ZDM_LWT_POLICY", "primary_only"}},
+			expectedPolicy: common.LwtPolicyPrimaryOnly,
+		},
+		{
+			name:           "Valid: Dual write",
+			envVars:        []envVar{{"ZDM_LWT_POLICY", "DUAL_WRITE"}},
+			expectedPolicy: common.LwtPolicyDualWrite,
+		},
+		{
+			name:        "Invalid: Unknown policy",
+			envVars:     []envVar{{"ZDM_LWT_POLICY", "ORIGIN_ONLY"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_LWT_POLICY; possible values are: DUAL_WRITE and PRIMARY_ONLY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				policy, _ := conf.ParseLwtPolicy()
+				require.Equal(t, tt.expectedPolicy, policy)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseProxyMaxProtocolVersion(t *testing.T) {
+	type test struct {
+		name            string
+		envVars         []envVar
+		expectedVersion primitive.ProtocolVersion
+		errExpected     bool
+		errMsg          string
+	}
+
+	tests := []test{
+		{
+			name:            "Valid: Default",
+			envVars:         []envVar{},
+			expectedVersion: primitive.ProtocolVersion5,
+		},
+		{
+			name:            "Valid: v4",
+			envVars:         []envVar{{"ZDM_PROXY_MAX_PROTOCOL_VERSION", "4"}},
+			expectedVersion: primitive.ProtocolVersion4,
+		},
+		{
+			name:            "Valid: v3",
+			envVars:         []envVar{{"ZDM_PROXY_MAX_PROTOCOL_VERSION", "3"}},
+			expectedVersion: primitive.ProtocolVersion3,
+		},
+		{
+			name:        "Invalid: v2",
+			envVars:     []envVar{{"ZDM_PROXY_MAX_PROTOCOL_VERSION", "2"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_PROXY_MAX_PROTOCOL_VERSION (2); it must be 3, 4 or 5",
+		},
+		{
+			name:        "Invalid: v6",
+			envVars:     []envVar{{"ZDM_PROXY_MAX_PROTOCOL_VERSION", "6"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_PROXY_MAX_PROTOCOL_VERSION (6); it must be 3, 4 or 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				version, _ := conf.ParseProxyMaxProtocolVersion()
+				require.Equal(t, tt.expectedVersion, version)
+			}
+		})
+	}
+}
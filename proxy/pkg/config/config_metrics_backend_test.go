@@ -0,0 +1,117 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestConfig_ParseMetricsBackend(t *testing.T) {
+
+	type test struct {
+		name                  string
+		envVars               []envVar
+		expectedBackend       common.MetricsBackend
+		expectedAddress       string
+		expectedFlushInterval time.Duration
+		errExpected           bool
+		errMsg                string
+	}
+
+	tests := []test{
+		{
+			name:            "Valid: Default",
+			envVars:         []envVar{},
+			expectedBackend: common.MetricsBackendPrometheus,
+		},
+		{
+			name: "Valid: StatsD settings are ignored with Prometheus",
+			envVars: []envVar{
+				{"ZDM_METRICS_STATSD_ADDRESS", "localhost"},
+				{"ZDM_METRICS_STATSD_FLUSH_INTERVAL_MS", "0"},
+			},
+			expectedBackend: common.MetricsBackendPrometheus,
+		},
+		{
+			name:                  "Valid: StatsD with default settings",
+			envVars:               []envVar{{"ZDM_METRICS_BACKEND", "statsd"}},
+			expectedBackend:       common.MetricsBackendStatsd,
+			expectedAddress:       "localhost:8125",
+			expectedFlushInterval: time.Second,
+		},
+		{
+			name: "Valid: StatsD",
+			envVars: []envVar{
+				{"ZDM_METRICS_BACKEND", "STATSD"},
+				{"ZDM_METRICS_STATSD_ADDRESS", " 10.0.0.1:9125 "},
+				{"ZDM_METRICS_STATSD_FLUSH_INTERVAL_MS", "10000"},
+			},
+			expectedBackend:       common.MetricsBackendStatsd,
+			expectedAddress:       "10.0.0.1:9125",
+			expectedFlushInterval: 10 * time.Second,
+		},
+		{
+			name:        "Invalid: Unknown backend",
+			envVars:     []envVar{{"ZDM_METRICS_BACKEND", "GRAPHITE"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_METRICS_BACKEND; possible values are: PROMETHEUS and STATSD",
+		},
+		{
+			name: "Invalid: StatsD address without port",
+			envVars: []envVar{
+				{"ZDM_METRICS_BACKEND", "STATSD"},
+				{"ZDM_METRICS_STATSD_ADDRESS", "localhost"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_METRICS_STATSD_ADDRESS (localhost); it must be <host>:<port>",
+		},
+		{
+			name: "Invalid: StatsD flush interval",
+			envVars: []envVar{
+				{"ZDM_METRICS_BACKEND", "STATSD"},
+				{"ZDM_METRICS_STATSD_FLUSH_INTERVAL_MS", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_METRICS_STATSD_FLUSH_INTERVAL_MS (0); it must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualBackend, _ := conf.ParseMetricsBackend()
+				require.Equal(t, tt.expectedBackend, actualBackend)
+				actualAddress, _ := conf.ParseMetricsStatsdAddress()
+				require.Equal(t, tt.expectedAddress, actualAddress)
+				actualFlushInterval, _ := conf.ParseMetricsStatsdFlushInterval()
+				require.Equal(t, tt.expectedFlushInterval, actualFlushInterval)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseMigrationProgressConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.MigrationProgressConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedConfig: &common.MigrationProgressConfig{},
+		},
+		{
+			name: "Valid: Settings are ignored if disabled",
+			envVars: []envVar{
+				{"ZDM_MIGRATION_PROGRESS_SAMPLE_INTERVAL_MS", "0"},
+			},
+			expectedConfig: &common.MigrationProgressConfig{},
+		},
+		{
+			name:           "Valid: Enabled with default settings",
+			envVars:        []envVar{{"ZDM_MIGRATION_PROGRESS_ENABLED", "true"}},
+			expectedConfig: &common.MigrationProgressConfig{Enabled: true, SampleIntervalMs: 10000, WindowMs: 300000},
+		},
+		{
+			name: "Valid: Enabled",
+			envVars: []envVar{
+				{"ZDM_MIGRATION_PROGRESS_ENABLED", "true"},
+				{"ZDM_MIGRATION_PROGRESS_SAMPLE_INTERVAL_MS", "60000"},
+				{"ZDM_MIGRATION_PROGRESS_WINDOW_MS", "3600000"},
+			},
+			expectedConfig: &common.MigrationProgressConfig{Enabled: true, SampleIntervalMs: 60000, WindowMs: 3600000},
+		},
+		{
+			name: "Invalid: Sample interval",
+			envVars: []envVar{
+				{"ZDM_MIGRATION_PROGRESS_ENABLED", "true"},
+				{"ZDM_MIGRATION_PROGRESS_SAMPLE_INTERVAL_MS", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_MIGRATION_PROGRESS_SAMPLE_INTERVAL_MS (0); it must be greater than 0",
+		},
+		{
+			name: "Invalid: Window shorter than the sample interval",
+			envVars: []envVar{
+				{"ZDM_MIGRATION_PROGRESS_ENABLED", "true"},
+				{"ZDM_MIGRATION_PROGRESS_WINDOW_MS", "5000"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_MIGRATION_PROGRESS_WINDOW_MS (5000); it must be at least " +
+				"ZDM_MIGRATION_PROGRESS_SAMPLE_INTERVAL_MS (10000)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualConfig, _ := conf.ParseMigrationProgressConfig()
+				require.Equal(t, tt.expectedConfig, actualConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParsePagingStatePolicy(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedPolicy common.PagingStatePolicy
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedPolicy: common.PagingStatePolicyNone,
+		},
+		{
+			name:           "Valid: Originating cluster",
+			envVars:        []envVar{{"ZDM_PAGING_STATE_POLICY", "originating_cluster"}},
+			expectedPolicy: common.PagingStatePolicyOriginatingCluster,
+		},
+		{
+			name:           "Valid: Reject",
+			envVars:        []envVar{{"ZDM_PAGING_STATE_POLICY", " REJECT "}},
+			expectedPolicy: common.PagingStatePolicyReject,
+		},
+		{
+			name:        "Invalid: Unknown policy",
+			envVars:     []envVar{{"ZDM_PAGING_STATE_POLICY", "TARGET"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PAGING_STATE_POLICY; possible values are: NONE, ORIGINATING_CLUSTER " +
+				"and REJECT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				policy, _ := conf.ParsePagingStatePolicy()
+				require.Equal(t, tt.expectedPolicy, policy)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_ParseProxyAuthCredentials(t *testing.T) {
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials")
+	require.Nil(t, os.WriteFile(credentialsFile, []byte("# app credentials\napp1:pass1\n\napp2:pa:ss2\n"), 0600))
+
+	type test struct {
+		name                string
+		envVars             []envVar
+		expectedCredentials map[string]string
+		expectedOrigin      common.CredentialsMode
+		expectedTarget      common.CredentialsMode
+		errExpected         bool
+		errMsg              string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Proxy authentication disabled",
+			envVars:        []envVar{},
+			expectedOrigin: common.CredentialsModeAuto,
+			expectedTarget: common.CredentialsModeAuto,
+		},
+		{
+			name:                "Valid: Credentials from env var",
+			envVars:             []envVar{{"ZDM_PROXY_AUTH_CREDENTIALS", "app1:pass1, app2:pa:ss2"}},
+			expectedCredentials: map[string]string{"app1": "pass1", "app2": "pa:ss2"},
+			expectedOrigin:      common.CredentialsModeConfigured,
+			expectedTarget:      common.CredentialsModeConfigured,
+		},
+		{
+			name:                "Valid: Credentials from file",
+			envVars:             []envVar{{"ZDM_PROXY_AUTH_CREDENTIALS_FILE", credentialsFile}},
+			expectedCredentials: map[string]string{"app1": "pass1", "app2": "pa:ss2"},
+			expectedOrigin:      common.CredentialsModeConfigured,
+			expectedTarget:      common.CredentialsModeConfigured,
+		},
+		{
+			name: "Valid: Configured credentials modes",
+			envVars: []envVar{
+				{"ZDM_PROXY_AUTH_CREDENTIALS", "app1:pass1"},
+				{"ZDM_ORIGIN_CREDENTIALS_MODE", "CONFIGURED"}, {"ZDM_TARGET_CREDENTIALS_MODE", "CONFIGURED"}},
+			expectedCredentials: map[string]string{"app1": "pass1"},
+			expectedOrigin:      common.CredentialsModeConfigured,
+			expectedTarget:      common.CredentialsModeConfigured,
+		},
+		{
+			name: "Invalid: Both env var and file",
+			envVars: []envVar{
+				{"ZDM_PROXY_AUTH_CREDENTIALS", "app1:pass1"}, {"ZDM_PROXY_AUTH_CREDENTIALS_FILE", credentialsFile}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_AUTH_CREDENTIALS_FILE (" + credentialsFile + "); " +
+				"ZDM_PROXY_AUTH_CREDENTIALS must not be set as well",
+		},
+		{
+			name:        "Invalid: Entry without password",
+			envVars:     []envVar{{"ZDM_PROXY_AUTH_CREDENTIALS", "app1:pass1,app2"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_AUTH_CREDENTIALS; " +
+				"the entries must have the format <username>:<password>",
+		},
+		{
+			name:        "Invalid: Duplicate username",
+			envVars:     []envVar{{"ZDM_PROXY_AUTH_CREDENTIALS", "app1:pass1,app1:pass2"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_PROXY_AUTH_CREDENTIALS; the username app1 is set more than once",
+		},
+		{
+			name:        "Invalid: No entries",
+			envVars:     []envVar{{"ZDM_PROXY_AUTH_CREDENTIALS", " , "}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_AUTH_CREDENTIALS; " +
+				"there must be at least one <username>:<password> entry",
+		},
+		{
+			name: "Invalid: Client credentials forwarded with proxy authentication",
+			envVars: []envVar{
+				{"ZDM_PROXY_AUTH_CREDENTIALS", "app1:pass1"}, {"ZDM_TARGET_CREDENTIALS_MODE", "CLIENT"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_CREDENTIALS_MODE (CLIENT); the client credentials can't be " +
+				"forwarded when the proxy authenticates the clients (ZDM_PROXY_AUTH_CREDENTIALS)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualCredentials, _ := conf.ParseProxyAuthCredentials()
+				require.Equal(t, tt.expectedCredentials, actualCredentials)
+				require.Equal(t, tt.expectedCredentials != nil, conf.IsProxyAuthEnabled())
+				actualOrigin, actualTarget, _ := conf.ParseCredentialsModes()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
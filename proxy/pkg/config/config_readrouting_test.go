@@ -0,0 +1,99 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseApplicationReadRoutingRules(t *testing.T) {
+
+	type test struct {
+		name          string
+		envVars       []envVar
+		expectedRules []*common.ApplicationReadRoutingRule
+		errExpected   bool
+		errMsg        string
+	}
+
+	tests := []test{
+		{
+			name:          "Valid: No rules",
+			envVars:       []envVar{},
+			expectedRules: nil,
+		},
+		{
+			name: "Valid: Multiple rules",
+			envVars: []envVar{{"ZDM_APPLICATION_READ_ROUTING_RULES",
+				"analytics-service origin; Reporting KS TARGET; \"billing app\" ks.\"Invoices\" ORIGIN;"}},
+			expectedRules: []*common.ApplicationReadRoutingRule{
+				{Application: "analytics-service", Cluster: common.ClusterTypeOrigin},
+				{Application: "Reporting", Keyspace: "ks", Cluster: common.ClusterTypeTarget},
+				{Application: "billing app", Keyspace: "ks", Table: "Invoices", Cluster: common.ClusterTypeOrigin},
+			},
+		},
+		{
+			name:        "Invalid: Unknown cluster",
+			envVars:     []envVar{{"ZDM_APPLICATION_READ_ROUTING_RULES", "analytics-service ks.tb BOTH"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_APPLICATION_READ_ROUTING_RULES (analytics-service ks.tb BOTH): " +
+				"unknown cluster BOTH; possible values are: ORIGIN and TARGET",
+		},
+		{
+			name:        "Invalid: Missing cluster",
+			envVars:     []envVar{{"ZDM_APPLICATION_READ_ROUTING_RULES", "analytics-service"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_APPLICATION_READ_ROUTING_RULES (analytics-service): " +
+				"expected <application name> [<keyspace>[.<table>]] <cluster>",
+		},
+		{
+			name:        "Invalid: Too many names",
+			envVars:     []envVar{{"ZDM_APPLICATION_READ_ROUTING_RULES", "analytics-service ks.tb.col ORIGIN"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_APPLICATION_READ_ROUTING_RULES (analytics-service ks.tb.col ORIGIN): " +
+				"expected <keyspace> or <keyspace>.<table> but got ks.tb.col",
+		},
+		{
+			name:        "Invalid: Missing closing quote",
+			envVars:     []envVar{{"ZDM_APPLICATION_READ_ROUTING_RULES", "\"billing app ORIGIN"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_APPLICATION_READ_ROUTING_RULES (\"billing app ORIGIN): " +
+				"missing closing quote in application name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				rules, _ := conf.ParseApplicationReadRoutingRules()
+				require.Equal(t, tt.expectedRules, rules)
+			}
+		})
+	}
+}
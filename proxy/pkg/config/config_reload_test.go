@@ -0,0 +1,71 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zdm-proxy.yaml")
+	writeConfigFile := func(contents string) {
+		require.Nil(t, os.WriteFile(path, []byte(contents), 0600))
+	}
+
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	writeConfigFile(`
+origin_contact_points: origin.hostname.com
+target_contact_points: target.hostname.com
+log_level: INFO
+read_max_workers: 16
+`)
+	conf, err := New().ParseConfigFileAndEnvVars(path)
+	require.Nil(t, err)
+
+	reloaded, changes, err := conf.Reload()
+	require.Nil(t, err)
+	require.True(t, changes.IsEmpty())
+
+	writeConfigFile(`
+origin_contact_points: origin2.hostname.com
+target_contact_points: target.hostname.com
+log_level: DEBUG
+read_max_workers: 32
+slow_query_log_threshold_ms: 500
+proxy_listen_port: 9042
+heartbeat_interval_ms: 10000
+`)
+	reloaded, changes, err = conf.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"ZDM_LOG_LEVEL", "ZDM_READ_MAX_WORKERS", "ZDM_SLOW_QUERY_LOG_THRESHOLD_MS"}, changes.Reloadable)
+	require.Equal(t, []string{"ZDM_ORIGIN_CONTACT_POINTS", "ZDM_PROXY_LISTEN_PORT"}, changes.Endpoints)
+	require.Equal(t, []string{"ZDM_HEARTBEAT_INTERVAL_MS"}, changes.RestartRequired)
+	require.Equal(t, "DEBUG", reloaded.LogLevel)
+
+	applied := conf.WithSettings(reloaded, []string{"ZDM_LOG_LEVEL", "ZDM_READ_MAX_WORKERS"})
+	require.Equal(t, "DEBUG", applied.LogLevel)
+	require.Equal(t, 32, applied.ReadMaxWorkers)
+	require.Equal(t, 0, applied.SlowQueryLogThresholdMs)
+	require.Equal(t, "origin.hostname.com", applied.OriginContactPoints)
+	require.Equal(t, "INFO", conf.LogLevel)
+	require.Equal(t, 16, conf.ReadMaxWorkers)
+
+	// the environment variables still override the file
+	setEnvVar("ZDM_LOG_LEVEL", "WARN")
+	reloaded, changes, err = applied.Reload()
+	require.Nil(t, err)
+	require.Equal(t, "WARN", reloaded.LogLevel)
+	require.Contains(t, changes.Reloadable, "ZDM_LOG_LEVEL")
+
+	writeConfigFile("origin_contact_points: [")
+	_, _, err = conf.Reload()
+	require.NotNil(t, err)
+
+	conf.ConfigFile = ""
+	_, _, err = conf.Reload()
+	require.Equal(t, "there is no config file to reload (ZDM_CONFIG_FILE or -config)", err.Error())
+}
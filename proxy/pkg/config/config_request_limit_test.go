@@ -0,0 +1,191 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseRequestLimitConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedOrigin *common.RequestLimitConfig
+		expectedTarget *common.RequestLimitConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Request limits unset",
+			envVars:        []envVar{},
+			expectedOrigin: &common.RequestLimitConfig{Enabled: false},
+			expectedTarget: &common.RequestLimitConfig{Enabled: false},
+			errExpected:    false,
+			errMsg:         "",
+		},
+		{
+			name:           "Valid: Target request limit with default queue",
+			envVars:        []envVar{{"ZDM_TARGET_MAX_CONCURRENT_REQUESTS", "100"}},
+			expectedOrigin: &common.RequestLimitConfig{Enabled: false},
+			expectedTarget: &common.RequestLimitConfig{
+				Enabled: true, MaxConcurrentRequests: 100, MaxQueuedRequests: 1000, QueueTimeoutMs: 5000},
+			errExpected: false,
+			errMsg:      "",
+		},
+		{
+			name: "Valid: Origin request limit without queue",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_MAX_CONCURRENT_REQUESTS", "50"},
+				{"ZDM_ORIGIN_MAX_QUEUED_REQUESTS", "0"},
+				{"ZDM_ORIGIN_REQUEST_QUEUE_TIMEOUT_MS", "100"},
+			},
+			expectedOrigin: &common.RequestLimitConfig{
+				Enabled: true, MaxConcurrentRequests: 50, MaxQueuedRequests: 0, QueueTimeoutMs: 100},
+			expectedTarget: &common.RequestLimitConfig{Enabled: false},
+			errExpected:    false,
+			errMsg:         "",
+		},
+		{
+			name:        "Invalid: Negative request limit",
+			envVars:     []envVar{{"ZDM_TARGET_MAX_CONCURRENT_REQUESTS", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_MAX_CONCURRENT_REQUESTS (-1); " +
+				"it must be 0 (unlimited) or a positive number",
+		},
+		{
+			name: "Invalid: Negative queue size",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_MAX_CONCURRENT_REQUESTS", "10"},
+				{"ZDM_ORIGIN_MAX_QUEUED_REQUESTS", "-5"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_MAX_QUEUED_REQUESTS (-5); " +
+				"it must be 0 (no queueing) or a positive number",
+		},
+		{
+			name: "Invalid: Zero queue timeout",
+			envVars: []envVar{
+				{"ZDM_TARGET_MAX_CONCURRENT_REQUESTS", "10"},
+				{"ZDM_TARGET_REQUEST_QUEUE_TIMEOUT_MS", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_REQUEST_QUEUE_TIMEOUT_MS (0); it must be a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, _ := conf.ParseOriginRequestLimitConfig()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				actualTarget, _ := conf.ParseTargetRequestLimitConfig()
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
+
+func TestConfig_ParseMaxRequestsPerSecond(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedOrigin int
+		expectedTarget int
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Rate limits unset",
+			envVars:        []envVar{},
+			expectedOrigin: 0,
+			expectedTarget: 0,
+			errExpected:    false,
+			errMsg:         "",
+		},
+		{
+			name: "Valid: Rate limits set",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_MAX_REQUESTS_PER_SECOND", "5000"},
+				{"ZDM_TARGET_MAX_REQUESTS_PER_SECOND", "2000"},
+			},
+			expectedOrigin: 5000,
+			expectedTarget: 2000,
+			errExpected:    false,
+			errMsg:         "",
+		},
+		{
+			name:        "Invalid: Negative rate limit",
+			envVars:     []envVar{{"ZDM_ORIGIN_MAX_REQUESTS_PER_SECOND", "-100"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_MAX_REQUESTS_PER_SECOND (-100); " +
+				"it must be 0 (unlimited) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, _ := conf.ParseOriginMaxRequestsPerSecond()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				actualTarget, _ := conf.ParseTargetMaxRequestsPerSecond()
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseRequestRetryConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedOrigin *common.RequestRetryConfig
+		expectedTarget *common.RequestRetryConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Retries disabled",
+			envVars:        []envVar{},
+			expectedOrigin: &common.RequestRetryConfig{Enabled: false},
+			expectedTarget: &common.RequestRetryConfig{Enabled: false},
+		},
+		{
+			name:           "Valid: Target retries with default backoff",
+			envVars:        []envVar{{"ZDM_TARGET_REQUEST_MAX_RETRIES", "3"}},
+			expectedOrigin: &common.RequestRetryConfig{Enabled: false},
+			expectedTarget: &common.RequestRetryConfig{Enabled: true, MaxRetries: 3, BackoffMinMs: 100, BackoffMaxMs: 1000},
+		},
+		{
+			name: "Valid: Origin retries",
+			envVars: []envVar{
+				{"ZDM_ORIGIN_REQUEST_MAX_RETRIES", "2"},
+				{"ZDM_ORIGIN_REQUEST_RETRY_BACKOFF_MIN_MS", "50"},
+				{"ZDM_ORIGIN_REQUEST_RETRY_BACKOFF_MAX_MS", "50"},
+			},
+			expectedOrigin: &common.RequestRetryConfig{Enabled: true, MaxRetries: 2, BackoffMinMs: 50, BackoffMaxMs: 50},
+			expectedTarget: &common.RequestRetryConfig{Enabled: false},
+		},
+		{
+			name:        "Invalid: Negative max retries",
+			envVars:     []envVar{{"ZDM_ORIGIN_REQUEST_MAX_RETRIES", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_REQUEST_MAX_RETRIES (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+		{
+			name: "Invalid: Backoff min",
+			envVars: []envVar{
+				{"ZDM_TARGET_REQUEST_MAX_RETRIES", "1"},
+				{"ZDM_TARGET_REQUEST_RETRY_BACKOFF_MIN_MS", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_REQUEST_RETRY_BACKOFF_MIN_MS (0); it must be a positive number",
+		},
+		{
+			name: "Invalid: Backoff max lower than min",
+			envVars: []envVar{
+				{"ZDM_TARGET_REQUEST_MAX_RETRIES", "1"},
+				{"ZDM_TARGET_REQUEST_RETRY_BACKOFF_MIN_MS", "500"},
+				{"ZDM_TARGET_REQUEST_RETRY_BACKOFF_MAX_MS", "200"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_REQUEST_RETRY_BACKOFF_MAX_MS (200); " +
+				"it must be greater than or equal to ZDM_TARGET_REQUEST_RETRY_BACKOFF_MIN_MS (500)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualOrigin, _ := conf.ParseOriginRequestRetryConfig()
+				require.Equal(t, tt.expectedOrigin, actualOrigin)
+				actualTarget, _ := conf.ParseTargetRequestRetryConfig()
+				require.Equal(t, tt.expectedTarget, actualTarget)
+			}
+		})
+	}
+}
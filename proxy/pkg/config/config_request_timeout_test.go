@@ -0,0 +1,95 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseRequestTimeoutConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.RequestTimeoutConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default request timeouts",
+			envVars:        []envVar{},
+			expectedConfig: &common.RequestTimeoutConfig{OriginMs: 10000, TargetMs: 10000},
+		},
+		{
+			name: "Valid: Request timeout of each cluster",
+			envVars: []envVar{
+				{"ZDM_PROXY_REQUEST_TIMEOUT_MS", "5000"}, {"ZDM_TARGET_REQUEST_TIMEOUT_MS", "1000"}},
+			expectedConfig: &common.RequestTimeoutConfig{OriginMs: 5000, TargetMs: 1000},
+		},
+		{
+			name: "Valid: Request timeouts of the statement types",
+			envVars: []envVar{
+				{"ZDM_PROXY_READ_REQUEST_TIMEOUT_MS", "2000"}, {"ZDM_PROXY_WRITE_REQUEST_TIMEOUT_MS", "3000"},
+				{"ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS", "60000"}},
+			expectedConfig: &common.RequestTimeoutConfig{
+				OriginMs: 10000, TargetMs: 10000, ReadMs: 2000, WriteMs: 3000, DdlMs: 60000},
+		},
+		{
+			name:        "Invalid: Proxy request timeout not positive",
+			envVars:     []envVar{{"ZDM_PROXY_REQUEST_TIMEOUT_MS", "0"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_PROXY_REQUEST_TIMEOUT_MS (0); it must be a positive number",
+		},
+		{
+			name:        "Invalid: Negative cluster request timeout",
+			envVars:     []envVar{{"ZDM_ORIGIN_REQUEST_TIMEOUT_MS", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_REQUEST_TIMEOUT_MS (-1); " +
+				"it must be 0 (not set) or a positive number",
+		},
+		{
+			name:        "Invalid: Negative statement type request timeout",
+			envVars:     []envVar{{"ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS", "-100"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS (-100); " +
+				"it must be 0 (not set) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualConfig, _ := conf.ParseRequestTimeoutConfig()
+				require.Equal(t, tt.expectedConfig, actualConfig)
+			}
+		})
+	}
+}
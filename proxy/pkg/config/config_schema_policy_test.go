@@ -0,0 +1,86 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseSchemaStatementPolicies(t *testing.T) {
+
+	type test struct {
+		name                      string
+		envVars                   []envVar
+		expectedMaterializedViews common.SchemaStatementPolicy
+		expectedIndexes           common.SchemaStatementPolicy
+		expectedCustomIndexes     common.SchemaStatementPolicy
+		errExpected               bool
+		errMsg                    string
+	}
+
+	tests := []test{
+		{
+			name:                      "Valid: Default",
+			envVars:                   []envVar{},
+			expectedMaterializedViews: common.SchemaStatementPolicyBoth,
+			expectedIndexes:           common.SchemaStatementPolicyBoth,
+			expectedCustomIndexes:     common.SchemaStatementPolicyBoth,
+		},
+		{
+			name: "Valid: Origin only and reject",
+			envVars: []envVar{
+				{"ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY", "origin_only"},
+				{"ZDM_INDEX_STATEMENTS_POLICY", "BOTH"},
+				{"ZDM_CUSTOM_INDEX_STATEMENTS_POLICY", "REJECT"},
+			},
+			expectedMaterializedViews: common.SchemaStatementPolicyOriginOnly,
+			expectedIndexes:           common.SchemaStatementPolicyBoth,
+			expectedCustomIndexes:     common.SchemaStatementPolicyReject,
+		},
+		{
+			name:        "Invalid: Unknown policy",
+			envVars:     []envVar{{"ZDM_INDEX_STATEMENTS_POLICY", "TARGET_ONLY"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_INDEX_STATEMENTS_POLICY; possible values are: BOTH, ORIGIN_ONLY and REJECT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				materializedViews, _ := conf.ParseMaterializedViewStatementsPolicy()
+				require.Equal(t, tt.expectedMaterializedViews, materializedViews)
+				indexes, _ := conf.ParseIndexStatementsPolicy()
+				require.Equal(t, tt.expectedIndexes, indexes)
+				customIndexes, _ := conf.ParseCustomIndexStatementsPolicy()
+				require.Equal(t, tt.expectedCustomIndexes, customIndexes)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseSecondaryWriteFailureMode(t *testing.T) {
+
+	type test struct {
+		name         string
+		envVars      []envVar
+		expectedMode common.SecondaryWriteFailureMode
+		errExpected  bool
+		errMsg       string
+	}
+
+	tests := []test{
+		{
+			name:         "Valid: Default",
+			envVars:      []envVar{},
+			expectedMode: common.SecondaryWriteFailureModeReturnError,
+		},
+		{
+			name:         "Valid: Log and continue",
+			envVars:      []envVar{{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "log_and_continue"}},
+			expectedMode: common.SecondaryWriteFailureModeLogAndContinue,
+		},
+		{
+			name: "Valid: Queue for replay",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_REPLAY_DELAY_MS", "0"},
+			},
+			expectedMode: common.SecondaryWriteFailureModeQueueForReplay,
+		},
+		{
+			name: "Invalid: Replay delay",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_REPLAY_DELAY_MS", "-1"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SECONDARY_WRITE_REPLAY_DELAY_MS (-1); it must be 0 or a positive number",
+		},
+		{
+			name:        "Invalid: Unknown mode",
+			envVars:     []envVar{{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "IGNORE"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_SECONDARY_WRITE_FAILURE_MODE; possible values are: " +
+				"RETURN_ERROR, LOG_AND_CONTINUE and QUEUE_FOR_REPLAY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				mode, _ := conf.ParseSecondaryWriteFailureMode()
+				require.Equal(t, tt.expectedMode, mode)
+			}
+		})
+	}
+}
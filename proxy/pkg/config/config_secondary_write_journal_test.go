@@ -0,0 +1,160 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_ParseSecondaryWriteJournalConfig(t *testing.T) {
+	directory := t.TempDir()
+	missingDirectory := filepath.Join(directory, "missing")
+	file := filepath.Join(directory, "file")
+	require.Nil(t, os.WriteFile(file, []byte{}, 0600))
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.WriteJournalConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedConfig: &common.WriteJournalConfig{Enabled: false},
+		},
+		{
+			name: "Valid: Memory with default max size and replay rate",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "memory"},
+			},
+			expectedConfig: &common.WriteJournalConfig{Enabled: true, MaxSizeBytes: 104857600, ReplayRate: 100},
+		},
+		{
+			name: "Valid: File with max size and replay rate",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "FILE"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY", directory},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_MAX_SIZE_BYTES", "1024"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_REPLAY_RATE", "10"},
+			},
+			expectedConfig: &common.WriteJournalConfig{
+				Enabled: true, Directory: directory, MaxSizeBytes: 1024, ReplayRate: 10},
+		},
+		{
+			name:           "Valid: Invalid replay rate is ignored when disabled",
+			envVars:        []envVar{{"ZDM_SECONDARY_WRITE_JOURNAL_REPLAY_RATE", "0"}},
+			expectedConfig: &common.WriteJournalConfig{Enabled: false},
+		},
+		{
+			name: "Invalid: Failure mode",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "LOG_AND_CONTINUE"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "memory"},
+			},
+			errExpected: true,
+			errMsg:      "ZDM_SECONDARY_WRITE_JOURNAL_TYPE MEMORY requires ZDM_SECONDARY_WRITE_FAILURE_MODE QUEUE_FOR_REPLAY",
+		},
+		{
+			name: "Invalid: Unknown type",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "KAFKA"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SECONDARY_WRITE_JOURNAL_TYPE; possible values are: NONE, MEMORY and FILE",
+		},
+		{
+			name: "Invalid: File without directory",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "FILE"},
+			},
+			errExpected: true,
+			errMsg:      "ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY is required when ZDM_SECONDARY_WRITE_JOURNAL_TYPE is FILE",
+		},
+		{
+			name: "Invalid: Missing directory",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "FILE"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY", missingDirectory},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY (" + missingDirectory + "); " +
+				"stat " + missingDirectory + ": no such file or directory",
+		},
+		{
+			name: "Invalid: Not a directory",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "FILE"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY", file},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY (" + file + "); it is not a directory",
+		},
+		{
+			name: "Invalid: Max size",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "MEMORY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_MAX_SIZE_BYTES", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SECONDARY_WRITE_JOURNAL_MAX_SIZE_BYTES (0); it must be a positive number",
+		},
+		{
+			name: "Invalid: Replay rate",
+			envVars: []envVar{
+				{"ZDM_SECONDARY_WRITE_FAILURE_MODE", "QUEUE_FOR_REPLAY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_TYPE", "MEMORY"},
+				{"ZDM_SECONDARY_WRITE_JOURNAL_REPLAY_RATE", "-1"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SECONDARY_WRITE_JOURNAL_REPLAY_RATE (-1); it must be a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				writeJournalConfig, _ := conf.ParseSecondaryWriteJournalConfig()
+				require.Equal(t, tt.expectedConfig, writeJournalConfig)
+			}
+		})
+	}
+}
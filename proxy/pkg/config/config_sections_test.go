@@ -0,0 +1,84 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_DefaultsMatchEnvVarDefaults(t *testing.T) {
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+
+	conf, err := New().ParseEnvVars()
+	require.Nil(t, err)
+
+	defaults := Defaults()
+	require.Equal(t, 9042, defaults.OriginPort)
+	require.Equal(t, 14002, defaults.ProxyListenPort)
+	require.Equal(t, true, defaults.MetricsEnabled)
+	require.Equal(t, 1.1, defaults.MetricsNativeHistogramBucketFactor)
+	require.Equal(t, true, defaults.TargetEnableHostAssignment)
+	require.Equal(t, true, defaults.OriginForwardAuthorizationId)
+	require.Equal(t, true, defaults.TargetForwardAuthorizationId)
+	require.Equal(t, false, defaults.OriginForwardAuthorizationIdWithConfiguredCredentials)
+	require.Equal(t, false, defaults.TargetForwardAuthorizationIdWithConfiguredCredentials)
+	require.Equal(t, 100.0, defaults.TargetWriteSamplingPercentage)
+	require.Equal(t, -1, defaults.ReadMaxWorkers)
+
+	defaults.OriginUsername, defaults.OriginPassword = conf.OriginUsername, conf.OriginPassword
+	defaults.TargetUsername, defaults.TargetPassword = conf.TargetUsername, conf.TargetPassword
+	defaults.OriginContactPoints, defaults.OriginPort = conf.OriginContactPoints, conf.OriginPort
+	defaults.TargetContactPoints, defaults.TargetPort = conf.TargetContactPoints, conf.TargetPort
+	require.Equal(t, conf, defaults)
+	require.Nil(t, defaults.Validate())
+}
+
+func TestConfig_SectionValidation(t *testing.T) {
+	routingConfig := Defaults().RoutingConfig
+	require.Nil(t, routingConfig.Validate())
+	routingConfig.ReadMode = "DUAL"
+	require.Equal(t, "invalid value for ZDM_READ_MODE; possible values are: PRIMARY_ONLY and DUAL_ASYNC_ON_SECONDARY",
+		routingConfig.Validate().Error())
+
+	listenerConfig := Defaults().ListenerConfig
+	require.Nil(t, listenerConfig.Validate())
+	listenerConfig.ProxyTlsCaPath = "/path/to/ca"
+	require.Equal(t, "incomplete Proxy TLS configuration: when enabling proxy TLS, please specify CA path, Cert path and Key path",
+		listenerConfig.Validate().Error())
+
+	metricsConfig := Defaults().MetricsConfig
+	require.Nil(t, metricsConfig.Validate())
+	metricsConfig.MetricsAsyncReadLatencyBucketsMs = "linear:1,1,0"
+	require.Equal(t, "could not parse async buckets: unable to parse buckets from linear:1,1,0: count must be a positive integer",
+		metricsConfig.Validate().Error())
+	metricsConfig = Defaults().MetricsConfig
+	metricsConfig.TracingOtlpEndpoint = "otel-collector:4318"
+	require.Equal(t, "invalid value for ZDM_TRACING_OTLP_ENDPOINT (otel-collector:4318); it must be an http or https URL",
+		metricsConfig.Validate().Error())
+
+	loggingConfig := Defaults().LoggingConfig
+	require.Nil(t, loggingConfig.Validate())
+	loggingConfig.SlowQueryLogThresholdMs = 100
+	loggingConfig.SlowQueryLogSampleRatio = 2
+	require.Equal(t, "invalid value for ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO (2); it must be greater than 0 and at most 1",
+		loggingConfig.Validate().Error())
+	loggingConfig = Defaults().LoggingConfig
+	loggingConfig.AuditLogDestination = "KAFKA"
+	require.Equal(t, "invalid value for ZDM_AUDIT_LOG_DESTINATION; possible values are: FILE, SYSLOG and empty (disabled)",
+		loggingConfig.Validate().Error())
+
+	originConfig := Defaults().OriginConfig
+	require.Equal(t, "invalid origin configuration: Both OriginSecureConnectBundlePath and OriginContactPoints are empty. "+
+		"Please specify either one of them.", originConfig.Validate().Error())
+	originConfig.OriginContactPoints = "origin.hostname.com"
+	require.Nil(t, originConfig.Validate())
+
+	targetConfig := Defaults().TargetConfig
+	targetConfig.TargetContactPoints = "target.hostname.com"
+	targetConfig.TargetMaxConcurrentRequests = -1
+	require.Equal(t, "invalid value for ZDM_TARGET_MAX_CONCURRENT_REQUESTS (-1); it must be 0 (unlimited) or a positive number",
+		targetConfig.Validate().Error())
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestConfig_ParseProxyShutdownDrainTimeout(t *testing.T) {
+
+	type test struct {
+		name            string
+		envVars         []envVar
+		expectedTimeout time.Duration
+		errExpected     bool
+		errMsg          string
+	}
+
+	tests := []test{
+		{
+			name:            "Valid: Drain timeout unset",
+			envVars:         []envVar{},
+			expectedTimeout: 30 * time.Second,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Valid: No draining",
+			envVars:         []envVar{{"ZDM_PROXY_SHUTDOWN_DRAIN_TIMEOUT_MS", "0"}},
+			expectedTimeout: 0,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Valid: Drain timeout set",
+			envVars:         []envVar{{"ZDM_PROXY_SHUTDOWN_DRAIN_TIMEOUT_MS", "5000"}},
+			expectedTimeout: 5 * time.Second,
+			errExpected:     false,
+			errMsg:          "",
+		},
+		{
+			name:            "Invalid: Negative drain timeout",
+			envVars:         []envVar{{"ZDM_PROXY_SHUTDOWN_DRAIN_TIMEOUT_MS", "-1"}},
+			expectedTimeout: 0,
+			errExpected:     true,
+			errMsg: "invalid value for ZDM_PROXY_SHUTDOWN_DRAIN_TIMEOUT_MS (-1); " +
+				"it must be 0 (no draining) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatal("Unexpected configuration validation error, stopping test here")
+				}
+			}
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				actualTimeout, _ := conf.ParseProxyShutdownDrainTimeout()
+				require.Equal(t, tt.expectedTimeout, actualTimeout)
+			}
+		})
+	}
+}
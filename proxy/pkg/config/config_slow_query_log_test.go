@@ -0,0 +1,126 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseSlowQueryLogConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.SlowQueryLogConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedConfig: &common.SlowQueryLogConfig{Enabled: false},
+		},
+		{
+			name:    "Valid: Threshold with default format, sample ratio and max per second",
+			envVars: []envVar{{"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS", "500"}},
+			expectedConfig: &common.SlowQueryLogConfig{
+				Enabled:      true,
+				ThresholdMs:  500,
+				QueryFormat:  common.SlowQueryFormatObfuscated,
+				SampleRatio:  1,
+				MaxPerSecond: 10,
+			},
+		},
+		{
+			name: "Valid: Digest with sample ratio and max per second",
+			envVars: []envVar{
+				{"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS", "100"},
+				{"ZDM_SLOW_QUERY_LOG_QUERY_FORMAT", "digest"},
+				{"ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO", "0.1"},
+				{"ZDM_SLOW_QUERY_LOG_MAX_PER_SECOND", "50"},
+			},
+			expectedConfig: &common.SlowQueryLogConfig{
+				Enabled:      true,
+				ThresholdMs:  100,
+				QueryFormat:  common.SlowQueryFormatDigest,
+				SampleRatio:  0.1,
+				MaxPerSecond: 50,
+			},
+		},
+		{
+			name:           "Valid: Invalid format is ignored when disabled",
+			envVars:        []envVar{{"ZDM_SLOW_QUERY_LOG_QUERY_FORMAT", "RAW"}},
+			expectedConfig: &common.SlowQueryLogConfig{Enabled: false},
+		},
+		{
+			name:        "Invalid: Threshold",
+			envVars:     []envVar{{"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS", "-1"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SLOW_QUERY_LOG_THRESHOLD_MS (-1); it must be 0 (disabled) or a positive number",
+		},
+		{
+			name: "Invalid: Query format",
+			envVars: []envVar{
+				{"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS", "100"},
+				{"ZDM_SLOW_QUERY_LOG_QUERY_FORMAT", "RAW"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SLOW_QUERY_LOG_QUERY_FORMAT; possible values are: OBFUSCATED and DIGEST",
+		},
+		{
+			name: "Invalid: Sample ratio",
+			envVars: []envVar{
+				{"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS", "100"},
+				{"ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO (0); it must be greater than 0 and at most 1",
+		},
+		{
+			name: "Invalid: Max per second",
+			envVars: []envVar{
+				{"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS", "100"},
+				{"ZDM_SLOW_QUERY_LOG_MAX_PER_SECOND", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_SLOW_QUERY_LOG_MAX_PER_SECOND (0); it must be a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				slowQueryLogConfig, _ := conf.ParseSlowQueryLogConfig()
+				require.Equal(t, tt.expectedConfig, slowQueryLogConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseSocketOptions(t *testing.T) {
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedProxy  *common.SocketOptions
+		expectedOrigin *common.SocketOptions
+		expectedTarget *common.SocketOptions
+		errExpected    bool
+		errMsg         string
+	}
+
+	defaultOptions := &common.SocketOptions{NoDelay: true, ReceiveBufferSizeBytes: 0, SendBufferSizeBytes: 0}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedProxy:  defaultOptions,
+			expectedOrigin: defaultOptions,
+			expectedTarget: defaultOptions,
+		},
+		{
+			name: "Valid: Per listener and per cluster options",
+			envVars: []envVar{
+				{"ZDM_PROXY_TCP_NO_DELAY", "false"},
+				{"ZDM_PROXY_SOCKET_RECEIVE_BUFFER_SIZE_BYTES", "65536"},
+				{"ZDM_ORIGIN_SOCKET_SEND_BUFFER_SIZE_BYTES", "131072"},
+				{"ZDM_TARGET_TCP_NO_DELAY", "false"},
+				{"ZDM_TARGET_SOCKET_RECEIVE_BUFFER_SIZE_BYTES", "262144"},
+				{"ZDM_TARGET_SOCKET_SEND_BUFFER_SIZE_BYTES", "262144"},
+			},
+			expectedProxy:  &common.SocketOptions{NoDelay: false, ReceiveBufferSizeBytes: 65536, SendBufferSizeBytes: 0},
+			expectedOrigin: &common.SocketOptions{NoDelay: true, ReceiveBufferSizeBytes: 0, SendBufferSizeBytes: 131072},
+			expectedTarget: &common.SocketOptions{NoDelay: false, ReceiveBufferSizeBytes: 262144, SendBufferSizeBytes: 262144},
+		},
+		{
+			name:        "Invalid: Negative proxy receive buffer size",
+			envVars:     []envVar{{"ZDM_PROXY_SOCKET_RECEIVE_BUFFER_SIZE_BYTES", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_PROXY_SOCKET_RECEIVE_BUFFER_SIZE_BYTES (-1); " +
+				"it must be 0 (operating system default) or a positive number",
+		},
+		{
+			name:        "Invalid: Negative origin send buffer size",
+			envVars:     []envVar{{"ZDM_ORIGIN_SOCKET_SEND_BUFFER_SIZE_BYTES", "-5"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_ORIGIN_SOCKET_SEND_BUFFER_SIZE_BYTES (-5); " +
+				"it must be 0 (operating system default) or a positive number",
+		},
+		{
+			name:        "Invalid: Negative target receive buffer size",
+			envVars:     []envVar{{"ZDM_TARGET_SOCKET_RECEIVE_BUFFER_SIZE_BYTES", "-10"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_SOCKET_RECEIVE_BUFFER_SIZE_BYTES (-10); " +
+				"it must be 0 (operating system default) or a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				proxySocketOptions, _ := conf.ParseProxySocketOptions()
+				require.Equal(t, tt.expectedProxy, proxySocketOptions)
+				originSocketOptions, _ := conf.ParseOriginSocketOptions()
+				require.Equal(t, tt.expectedOrigin, originSocketOptions)
+				targetSocketOptions, _ := conf.ParseTargetSocketOptions()
+				require.Equal(t, tt.expectedTarget, targetSocketOptions)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseStallDetectionConfig(t *testing.T) {
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.StallDetectionConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:    "Valid: Default",
+			envVars: []envVar{},
+			expectedConfig: &common.StallDetectionConfig{
+				Enabled: true, ThresholdMs: 30000, DiagnosticsIntervalMs: 3600000},
+		},
+		{
+			name: "Valid: Threshold and interval",
+			envVars: []envVar{
+				{"ZDM_STALL_DETECTION_THRESHOLD_MS", "5000"},
+				{"ZDM_STALL_DIAGNOSTICS_INTERVAL_MS", "60000"},
+			},
+			expectedConfig: &common.StallDetectionConfig{
+				Enabled: true, ThresholdMs: 5000, DiagnosticsIntervalMs: 60000},
+		},
+		{
+			name:           "Valid: Disabled",
+			envVars:        []envVar{{"ZDM_STALL_DETECTION_THRESHOLD_MS", "0"}},
+			expectedConfig: &common.StallDetectionConfig{Enabled: false},
+		},
+		{
+			name: "Valid: Invalid interval is ignored when disabled",
+			envVars: []envVar{
+				{"ZDM_STALL_DETECTION_THRESHOLD_MS", "0"},
+				{"ZDM_STALL_DIAGNOSTICS_INTERVAL_MS", "0"},
+			},
+			expectedConfig: &common.StallDetectionConfig{Enabled: false},
+		},
+		{
+			name:        "Invalid: Negative threshold",
+			envVars:     []envVar{{"ZDM_STALL_DETECTION_THRESHOLD_MS", "-1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_STALL_DETECTION_THRESHOLD_MS (-1); " +
+				"it must be 0 (disabled) or a positive number",
+		},
+		{
+			name:        "Invalid: Interval",
+			envVars:     []envVar{{"ZDM_STALL_DIAGNOSTICS_INTERVAL_MS", "-5"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_STALL_DIAGNOSTICS_INTERVAL_MS (-5); it must be a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				stallDetectionConfig, _ := conf.ParseStallDetectionConfig()
+				require.Equal(t, tt.expectedConfig, stallDetectionConfig)
+			}
+		})
+	}
+}
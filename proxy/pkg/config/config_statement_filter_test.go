@@ -0,0 +1,118 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestConfig_ParseStatementFilterRules(t *testing.T) {
+
+	type test struct {
+		name          string
+		envVars       []envVar
+		expectedRules []*common.StatementFilterRule
+		errExpected   bool
+		errMsg        string
+	}
+
+	tests := []test{
+		{
+			name:          "Valid: No rules",
+			envVars:       []envVar{},
+			expectedRules: nil,
+		},
+		{
+			name: "Valid: Multiple rules",
+			envVars: []envVar{{"ZDM_STATEMENT_FILTER_RULES",
+				"truncate REJECT; DROP ks.* reject; DELETE KS.\"Legacy_Events\" origin; * ks.tb TARGET; " +
+					"/ALLOW\\s+FILTERING/ REJECT;"}},
+			expectedRules: []*common.StatementFilterRule{
+				{Definition: "truncate REJECT", Statement: "TRUNCATE", Action: common.StatementFilterActionReject},
+				{Definition: "DROP ks.* reject", Statement: "DROP", Keyspace: "ks",
+					Action: common.StatementFilterActionReject},
+				{Definition: "DELETE KS.\"Legacy_Events\" origin", Statement: "DELETE", Keyspace: "ks",
+					Table: "Legacy_Events", Action: common.StatementFilterActionOrigin},
+				{Definition: "* ks.tb TARGET", Statement: "*", Keyspace: "ks", Table: "tb",
+					Action: common.StatementFilterActionTarget},
+				{Definition: "/ALLOW\\s+FILTERING/ REJECT", Pattern: regexp.MustCompile("(?i)ALLOW\\s+FILTERING"),
+					Action: common.StatementFilterActionReject},
+			},
+		},
+		{
+			name:        "Invalid: Unknown action",
+			envVars:     []envVar{{"ZDM_STATEMENT_FILTER_RULES", "TRUNCATE BOTH"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_STATEMENT_FILTER_RULES (TRUNCATE BOTH): " +
+				"unknown action BOTH; possible values are: REJECT, ORIGIN and TARGET",
+		},
+		{
+			name:        "Invalid: Missing action",
+			envVars:     []envVar{{"ZDM_STATEMENT_FILTER_RULES", "TRUNCATE"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_STATEMENT_FILTER_RULES (TRUNCATE): " +
+				"expected <statement> [<keyspace>.<table>] <action>",
+		},
+		{
+			name:        "Invalid: Table is not fully qualified",
+			envVars:     []envVar{{"ZDM_STATEMENT_FILTER_RULES", "DROP tb REJECT"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_STATEMENT_FILTER_RULES (DROP tb REJECT): " +
+				"expected <keyspace>.<table> or <keyspace>.* but got tb",
+		},
+		{
+			name:        "Invalid: Empty identifier",
+			envVars:     []envVar{{"ZDM_STATEMENT_FILTER_RULES", "DROP .tb REJECT"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_STATEMENT_FILTER_RULES (DROP .tb REJECT): empty identifier in .tb",
+		},
+		{
+			name:        "Invalid: Regex without action",
+			envVars:     []envVar{{"ZDM_STATEMENT_FILTER_RULES", "/TRUNCATE/"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_STATEMENT_FILTER_RULES (/TRUNCATE/): expected /<regex>/ <action>",
+		},
+		{
+			name:        "Invalid: Regex",
+			envVars:     []envVar{{"ZDM_STATEMENT_FILTER_RULES", "/TRUNCATE(/ REJECT"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_STATEMENT_FILTER_RULES (/TRUNCATE(/ REJECT): " +
+				"invalid regex: error parsing regexp: missing closing ): `(?i)TRUNCATE(`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				rules, _ := conf.ParseStatementFilterRules()
+				require.Equal(t, tt.expectedRules, rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseMetricsTableLevelMaxTables(t *testing.T) {
+
+	type test struct {
+		name              string
+		envVars           []envVar
+		expectedMaxTables int
+		errExpected       bool
+		errMsg            string
+	}
+
+	tests := []test{
+		{
+			name:              "Valid: Default",
+			envVars:           []envVar{},
+			expectedMaxTables: 0,
+		},
+		{
+			name:              "Valid: Enabled with default max tables",
+			envVars:           []envVar{{"ZDM_METRICS_TABLE_LEVEL_ENABLED", "true"}},
+			expectedMaxTables: 100,
+		},
+		{
+			name: "Valid: Enabled with max tables",
+			envVars: []envVar{
+				{"ZDM_METRICS_TABLE_LEVEL_ENABLED", "true"},
+				{"ZDM_METRICS_TABLE_LEVEL_MAX_TABLES", "20"},
+			},
+			expectedMaxTables: 20,
+		},
+		{
+			name:              "Valid: Invalid max tables ignored when disabled",
+			envVars:           []envVar{{"ZDM_METRICS_TABLE_LEVEL_MAX_TABLES", "0"}},
+			expectedMaxTables: 0,
+		},
+		{
+			name: "Invalid: Zero max tables",
+			envVars: []envVar{
+				{"ZDM_METRICS_TABLE_LEVEL_ENABLED", "true"},
+				{"ZDM_METRICS_TABLE_LEVEL_MAX_TABLES", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_METRICS_TABLE_LEVEL_MAX_TABLES (0); it must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				maxTables, _ := conf.ParseMetricsTableLevelMaxTables()
+				require.Equal(t, tt.expectedMaxTables, maxTables)
+			}
+		})
+	}
+}
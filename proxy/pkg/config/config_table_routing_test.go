@@ -0,0 +1,101 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseTableRoutingRules(t *testing.T) {
+
+	type test struct {
+		name          string
+		envVars       []envVar
+		expectedRules []*common.TableRoutingRule
+		errExpected   bool
+		errMsg        string
+	}
+
+	tests := []test{
+		{
+			name:          "Valid: No rules",
+			envVars:       []envVar{},
+			expectedRules: nil,
+		},
+		{
+			name: "Valid: Multiple rules",
+			envVars: []envVar{{"ZDM_TABLE_ROUTING_RULES",
+				"system_auth.roles target; KS.\"Legacy_Events\" ORIGIN;"}},
+			expectedRules: []*common.TableRoutingRule{
+				{Keyspace: "system_auth", Table: "roles", Cluster: common.ClusterTypeTarget},
+				{Keyspace: "ks", Table: "Legacy_Events", Cluster: common.ClusterTypeOrigin},
+			},
+		},
+		{
+			name:        "Invalid: Unknown cluster",
+			envVars:     []envVar{{"ZDM_TABLE_ROUTING_RULES", "ks.tb BOTH"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TABLE_ROUTING_RULES (ks.tb BOTH): " +
+				"unknown cluster BOTH; possible values are: ORIGIN and TARGET",
+		},
+		{
+			name:        "Invalid: Missing cluster",
+			envVars:     []envVar{{"ZDM_TABLE_ROUTING_RULES", "ks.tb"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TABLE_ROUTING_RULES (ks.tb): expected <keyspace>.<table> <cluster>",
+		},
+		{
+			name:        "Invalid: Table is not fully qualified",
+			envVars:     []envVar{{"ZDM_TABLE_ROUTING_RULES", "tb ORIGIN"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TABLE_ROUTING_RULES (tb ORIGIN): expected <keyspace>.<table> but got tb",
+		},
+		{
+			name:        "Invalid: Empty identifier",
+			envVars:     []envVar{{"ZDM_TABLE_ROUTING_RULES", "ks. ORIGIN"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TABLE_ROUTING_RULES (ks. ORIGIN): empty identifier in ks.",
+		},
+		{
+			name:        "Invalid: Table with more than one rule",
+			envVars:     []envVar{{"ZDM_TABLE_ROUTING_RULES", "ks.tb ORIGIN; KS.TB TARGET"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TABLE_ROUTING_RULES (KS.TB TARGET): table ks.tb has more than one rule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				rules, _ := conf.ParseTableRoutingRules()
+				require.Equal(t, tt.expectedRules, rules)
+			}
+		})
+	}
+}
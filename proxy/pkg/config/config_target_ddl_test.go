@@ -0,0 +1,103 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseTargetDdlSettings(t *testing.T) {
+
+	type test struct {
+		name                         string
+		envVars                      []envVar
+		expectedFeatures             []common.TargetDdlFeature
+		expectedCompactionStrategies []string
+		expectedMode                 common.TargetDdlCompatibilityMode
+		errExpected                  bool
+		errMsg                       string
+	}
+
+	tests := []test{
+		{
+			name:         "Valid: Default",
+			envVars:      []envVar{},
+			expectedMode: common.TargetDdlCompatibilityModeWarn,
+		},
+		{
+			name: "Valid: Features, compaction strategies and reject mode",
+			envVars: []envVar{
+				{"ZDM_TARGET_DDL_UNSUPPORTED_FEATURES", "materialized_views, TRIGGERS"},
+				{"ZDM_TARGET_DDL_UNSUPPORTED_COMPACTION_STRATEGIES", "DateTieredCompactionStrategy, ,TimeWindowCompactionStrategy"},
+				{"ZDM_TARGET_DDL_COMPATIBILITY_MODE", "reject"},
+			},
+			expectedFeatures: []common.TargetDdlFeature{
+				common.TargetDdlFeatureMaterializedViews, common.TargetDdlFeatureTriggers},
+			expectedCompactionStrategies: []string{"DateTieredCompactionStrategy", "TimeWindowCompactionStrategy"},
+			expectedMode:                 common.TargetDdlCompatibilityModeReject,
+		},
+		{
+			name:    "Valid: Astra",
+			envVars: []envVar{{"ZDM_TARGET_DDL_UNSUPPORTED_FEATURES", "ASTRA"}},
+			expectedFeatures: []common.TargetDdlFeature{
+				common.TargetDdlFeatureMaterializedViews,
+				common.TargetDdlFeatureSasiIndexes,
+				common.TargetDdlFeatureTriggers,
+				common.TargetDdlFeatureUserDefinedFunctions,
+				common.TargetDdlFeatureKeyspaceSchemaChanges,
+			},
+			expectedMode: common.TargetDdlCompatibilityModeWarn,
+		},
+		{
+			name:        "Invalid: Unknown feature",
+			envVars:     []envVar{{"ZDM_TARGET_DDL_UNSUPPORTED_FEATURES", "TRIGGERS,UDT"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_DDL_UNSUPPORTED_FEATURES (TRIGGERS,UDT); unknown feature UDT, " +
+				"possible values are: [MATERIALIZED_VIEWS SASI_INDEXES TRIGGERS USER_DEFINED_FUNCTIONS KEYSPACE_SCHEMA_CHANGES] and ASTRA",
+		},
+		{
+			name:        "Invalid: Unknown mode",
+			envVars:     []envVar{{"ZDM_TARGET_DDL_COMPATIBILITY_MODE", "IGNORE"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TARGET_DDL_COMPATIBILITY_MODE; possible values are: WARN and REJECT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				features, _ := conf.ParseTargetDdlUnsupportedFeatures()
+				require.Equal(t, tt.expectedFeatures, features)
+				require.Equal(t, tt.expectedCompactionStrategies, conf.ParseTargetDdlUnsupportedCompactionStrategies())
+				mode, _ := conf.ParseTargetDdlCompatibilityMode()
+				require.Equal(t, tt.expectedMode, mode)
+			}
+		})
+	}
+}
@@ -93,3 +93,57 @@ func TestTargetConfig_WithHostnameButWithoutPort(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 9042, c.TargetPort)
 }
+
+func TestConfig_InterceptedQueriesCacheTtl(t *testing.T) {
+	defer clearAllEnvVars()
+
+	// general setup
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+
+	conf, err := New().ParseEnvVars()
+	require.Nil(t, err)
+	require.Equal(t, 1000, conf.InterceptedQueriesCacheTtlMs)
+
+	// test-specific setup
+	setEnvVar("ZDM_INTERCEPTED_QUERIES_CACHE_TTL_MS", "-1")
+
+	_, err = New().ParseEnvVars()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid value for ZDM_INTERCEPTED_QUERIES_CACHE_TTL_MS (-1); it must not be negative")
+}
+
+func TestConfig_AdminKeyspaceRoles(t *testing.T) {
+	defer clearAllEnvVars()
+
+	// general setup
+	clearAllEnvVars()
+	setOriginCredentialsEnvVars()
+	setTargetCredentialsEnvVars()
+	setOriginContactPointsAndPortEnvVars()
+	setTargetContactPointsAndPortEnvVars()
+
+	conf, err := New().ParseEnvVars()
+	require.Nil(t, err)
+	roles, err := conf.ParseAdminKeyspaceRoles()
+	require.Nil(t, err)
+	require.Empty(t, roles)
+
+	// test-specific setup
+	setEnvVar("ZDM_ADMIN_KEYSPACE_ROLES", "ops_admin, migration_lead")
+
+	conf, err = New().ParseEnvVars()
+	require.Nil(t, err)
+	roles, err = conf.ParseAdminKeyspaceRoles()
+	require.Nil(t, err)
+	require.Equal(t, []string{"ops_admin", "migration_lead"}, roles)
+
+	setEnvVar("ZDM_ADMIN_KEYSPACE_ROLES", "ops_admin,,migration_lead")
+
+	_, err = New().ParseEnvVars()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid value for ZDM_ADMIN_KEYSPACE_ROLES (ops_admin,,migration_lead); role names must not be empty")
+}
@@ -14,6 +14,8 @@ type tlsTest struct {
 	serverCaPath       string
 	clientCertPath     string
 	clientKeyPath      string
+	serverName         string
+	insecureSkipVerify bool
 	scbPath            string
 	errExpected        bool
 	errMsg             string
@@ -171,6 +173,57 @@ func TestOriginConfig_ClusterTlsConfig(t *testing.T) {
 			errExpected:    true,
 			errMsg:         "incomplete TLS configuration for Origin: when using mutual TLS, please specify Server CA path, Client Cert path and Client Key path",
 		},
+		{name: "TLS enabled without custom CA",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_ORIGIN_TLS_ENABLED", "true"},
+			},
+			tlsEnabled:  true,
+			errExpected: false,
+		},
+		{name: "TLS enabled without custom CA for mutual TLS",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_ORIGIN_TLS_ENABLED", "true"},
+				{"ZDM_ORIGIN_TLS_CLIENT_CERT_PATH", "/path/to/origin/client/cert"},
+				{"ZDM_ORIGIN_TLS_CLIENT_KEY_PATH", "/path/to/origin/client/key"},
+			},
+			tlsEnabled:     true,
+			clientCertPath: "/path/to/origin/client/cert",
+			clientKeyPath:  "/path/to/origin/client/key",
+			errExpected:    false,
+		},
+		{name: "Custom TLS config with server name and insecure skip verify",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_ORIGIN_TLS_SERVER_CA_PATH", "/path/to/origin/server/ca"},
+				{"ZDM_ORIGIN_TLS_SERVER_NAME", "origin.example.com"},
+				{"ZDM_ORIGIN_TLS_INSECURE_SKIP_VERIFY", "true"},
+			},
+			tlsEnabled:         true,
+			serverCaPath:       "/path/to/origin/server/ca",
+			serverName:         "origin.example.com",
+			insecureSkipVerify: true,
+			errExpected:        false,
+		},
+		{name: "Server name without TLS",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_ORIGIN_TLS_SERVER_NAME", "origin.example.com"},
+			},
+			errExpected: true,
+			errMsg: "incomplete TLS configuration for Origin: ZDM_ORIGIN_TLS_SERVER_NAME and " +
+				"ZDM_ORIGIN_TLS_INSECURE_SKIP_VERIFY require ZDM_ORIGIN_TLS_ENABLED or ZDM_ORIGIN_TLS_SERVER_CA_PATH to be set",
+		},
+		{name: "SCB and server name",
+			needsContactPoints: false,
+			envVars: []envVar{
+				{"ZDM_ORIGIN_SECURE_CONNECT_BUNDLE_PATH", "/path/to/origin/bundle"},
+				{"ZDM_ORIGIN_TLS_SERVER_NAME", "origin.example.com"},
+			},
+			errExpected: true,
+			errMsg:      "Incorrect TLS configuration for Origin: Secure Connect Bundle and custom TLS parameters cannot be specified at the same time.",
+		},
 	}
 
 	for _, tt := range tests {
@@ -211,6 +264,8 @@ func TestOriginConfig_ClusterTlsConfig(t *testing.T) {
 				require.Equal(t, tt.serverCaPath, tlsConf.ServerCaPath)
 				require.Equal(t, tt.clientCertPath, tlsConf.ClientCertPath)
 				require.Equal(t, tt.clientKeyPath, tlsConf.ClientKeyPath)
+				require.Equal(t, tt.serverName, tlsConf.ServerName)
+				require.Equal(t, tt.insecureSkipVerify, tlsConf.InsecureSkipVerify)
 				require.Equal(t, tt.scbPath, tlsConf.SecureConnectBundlePath)
 			}
 		})
@@ -369,6 +424,57 @@ func TestTargetConfig_ClusterTlsConfig(t *testing.T) {
 			errExpected:    true,
 			errMsg:         "incomplete TLS configuration for Target: when using mutual TLS, please specify Server CA path, Client Cert path and Client Key path",
 		},
+		{name: "TLS enabled without custom CA",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_TARGET_TLS_ENABLED", "true"},
+			},
+			tlsEnabled:  true,
+			errExpected: false,
+		},
+		{name: "TLS enabled without custom CA for mutual TLS",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_TARGET_TLS_ENABLED", "true"},
+				{"ZDM_TARGET_TLS_CLIENT_CERT_PATH", "/path/to/target/client/cert"},
+				{"ZDM_TARGET_TLS_CLIENT_KEY_PATH", "/path/to/target/client/key"},
+			},
+			tlsEnabled:     true,
+			clientCertPath: "/path/to/target/client/cert",
+			clientKeyPath:  "/path/to/target/client/key",
+			errExpected:    false,
+		},
+		{name: "Custom TLS config with server name and insecure skip verify",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_TARGET_TLS_SERVER_CA_PATH", "/path/to/target/server/ca"},
+				{"ZDM_TARGET_TLS_SERVER_NAME", "target.example.com"},
+				{"ZDM_TARGET_TLS_INSECURE_SKIP_VERIFY", "true"},
+			},
+			tlsEnabled:         true,
+			serverCaPath:       "/path/to/target/server/ca",
+			serverName:         "target.example.com",
+			insecureSkipVerify: true,
+			errExpected:        false,
+		},
+		{name: "Server name without TLS",
+			needsContactPoints: true,
+			envVars: []envVar{
+				{"ZDM_TARGET_TLS_SERVER_NAME", "target.example.com"},
+			},
+			errExpected: true,
+			errMsg: "incomplete TLS configuration for Target: ZDM_TARGET_TLS_SERVER_NAME and " +
+				"ZDM_TARGET_TLS_INSECURE_SKIP_VERIFY require ZDM_TARGET_TLS_ENABLED or ZDM_TARGET_TLS_SERVER_CA_PATH to be set",
+		},
+		{name: "SCB and server name",
+			needsContactPoints: false,
+			envVars: []envVar{
+				{"ZDM_TARGET_SECURE_CONNECT_BUNDLE_PATH", "/path/to/target/bundle"},
+				{"ZDM_TARGET_TLS_SERVER_NAME", "target.example.com"},
+			},
+			errExpected: true,
+			errMsg:      "Incorrect TLS configuration for Target: Secure Connect Bundle and custom TLS parameters cannot be specified at the same time.",
+		},
 	}
 
 	for _, tt := range tests {
@@ -410,8 +516,81 @@ func TestTargetConfig_ClusterTlsConfig(t *testing.T) {
 				require.Equal(t, tt.serverCaPath, tlsConf.ServerCaPath)
 				require.Equal(t, tt.clientCertPath, tlsConf.ClientCertPath)
 				require.Equal(t, tt.clientKeyPath, tlsConf.ClientKeyPath)
+				require.Equal(t, tt.serverName, tlsConf.ServerName)
+				require.Equal(t, tt.insecureSkipVerify, tlsConf.InsecureSkipVerify)
 				require.Equal(t, tt.scbPath, tlsConf.SecureConnectBundlePath)
 			}
 		})
 	}
 }
+
+func TestConfig_ProxyTlsDevMode(t *testing.T) {
+
+	type test struct {
+		name        string
+		envVars     []envVar
+		expected    *common.ProxyTlsConfig
+		errExpected bool
+		errMsg      string
+	}
+
+	tests := []test{
+		{
+			name:     "Dev mode disabled",
+			envVars:  []envVar{},
+			expected: &common.ProxyTlsConfig{TlsEnabled: false},
+		},
+		{
+			name:     "Dev mode enabled",
+			envVars:  []envVar{{"ZDM_PROXY_TLS_DEV_MODE_ENABLED", "true"}},
+			expected: &common.ProxyTlsConfig{TlsEnabled: true, DevMode: true},
+		},
+		{
+			name: "Dev mode enabled with client auth",
+			envVars: []envVar{
+				{"ZDM_PROXY_TLS_DEV_MODE_ENABLED", "true"},
+				{"ZDM_PROXY_TLS_REQUIRE_CLIENT_AUTH", "true"},
+			},
+			expected: &common.ProxyTlsConfig{TlsEnabled: true, DevMode: true, ClientAuth: true},
+		},
+		{
+			name: "Dev mode enabled with CA path",
+			envVars: []envVar{
+				{"ZDM_PROXY_TLS_DEV_MODE_ENABLED", "true"},
+				{"ZDM_PROXY_TLS_CA_PATH", "/path/to/ca"},
+			},
+			errExpected: true,
+			errMsg: "invalid Proxy TLS configuration: ZDM_PROXY_TLS_DEV_MODE_ENABLED " +
+				"can not be used with the CA path, Cert path and Key path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if tt.errExpected {
+				require.NotNil(t, err)
+				require.Equal(t, tt.errMsg, err.Error())
+				return
+			}
+			require.Nil(t, err)
+
+			proxyTlsConfig, err := conf.ParseProxyTlsConfig(false)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, proxyTlsConfig)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_TopologyAddressesFile(t *testing.T) {
+
+	type test struct {
+		name              string
+		fileContents      string
+		envVars           []envVar
+		expectedAddresses []net.IP
+		expectedIndex     int
+		errExpected       bool
+		errMsg            string
+	}
+
+	tests := []test{
+		{
+			name:              "Newline separated",
+			fileContents:      "10.0.0.1\n10.0.0.2\n",
+			envVars:           []envVar{{"ZDM_PROXY_TOPOLOGY_INDEX", "1"}},
+			expectedAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+			expectedIndex:     1,
+		},
+		{
+			name:              "Comma separated with IPv6 literals",
+			fileContents:      "10.0.0.1, [fd00::1],fd00::2",
+			expectedAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("fd00::1"), net.ParseIP("fd00::2")},
+			expectedIndex:     0,
+		},
+		{
+			name:         "Empty file",
+			fileContents: " \n",
+			errExpected:  true,
+			errMsg:       "the file has no addresses",
+		},
+		{
+			name:         "Invalid address",
+			fileContents: "10.0.0.1\nproxy2\n",
+			errExpected:  true,
+			errMsg:       "invalid proxy address: proxy2",
+		},
+		{
+			name:         "Index out of range",
+			fileContents: "10.0.0.1",
+			envVars:      []envVar{{"ZDM_PROXY_TOPOLOGY_INDEX", "1"}},
+			errExpected:  true,
+			errMsg:       "proxy index (1) must be less than length of addresses (1)",
+		},
+		{
+			name:         "Addresses also set",
+			fileContents: "10.0.0.1",
+			envVars:      []envVar{{"ZDM_PROXY_TOPOLOGY_ADDRESSES", "10.0.0.1"}},
+			errExpected:  true,
+			errMsg:       "ZDM_PROXY_TOPOLOGY_ADDRESSES must not be set as well",
+		},
+		{
+			name:         "Negative poll interval",
+			fileContents: "10.0.0.1",
+			envVars:      []envVar{{"ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE_POLL_INTERVAL_MS", "-1"}},
+			errExpected:  true,
+			errMsg:       "invalid value for ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE_POLL_INTERVAL_MS (-1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			addressesFile := filepath.Join(t.TempDir(), "proxy_addresses")
+			require.Nil(t, os.WriteFile(addressesFile, []byte(tt.fileContents), 0644))
+			setEnvVar("ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE", addressesFile)
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			conf, err := New().ParseEnvVars()
+			if tt.errExpected {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+			require.Nil(t, err)
+
+			topologyConfig, err := conf.ParseTopologyConfig()
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedIndex, topologyConfig.Index)
+			require.Equal(t, len(tt.expectedAddresses), topologyConfig.Count)
+			for i, expectedAddr := range tt.expectedAddresses {
+				require.True(t, expectedAddr.Equal(topologyConfig.Addresses[i]),
+					"expected %v but got %v", expectedAddr, topologyConfig.Addresses[i])
+			}
+		})
+	}
+}
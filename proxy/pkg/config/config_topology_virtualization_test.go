@@ -0,0 +1,56 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_TopologyVirtualization(t *testing.T) {
+
+	type test struct {
+		name     string
+		envVars  []envVar
+		expected bool
+	}
+
+	tests := []test{
+		{
+			name:     "Default",
+			envVars:  []envVar{},
+			expected: true,
+		},
+		{
+			name:     "Disabled",
+			envVars:  []envVar{{"ZDM_PROXY_TOPOLOGY_VIRTUALIZATION_ENABLED", "false"}},
+			expected: false,
+		},
+		{
+			name:     "Enabled",
+			envVars:  []envVar{{"ZDM_PROXY_TOPOLOGY_VIRTUALIZATION_ENABLED", "true"}},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			conf, err := New().ParseEnvVars()
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, conf.ProxyTopologyVirtualizationEnabled)
+
+			topologyConfig, err := conf.ParseTopologyConfig()
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, topologyConfig.VirtualizationEnabled)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseTracingConfig(t *testing.T) {
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.TracingConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedConfig: &common.TracingConfig{Enabled: false},
+		},
+		{
+			name:    "Valid: Endpoint without path",
+			envVars: []envVar{{"ZDM_TRACING_OTLP_ENDPOINT", " http://otel-collector:4318 "}},
+			expectedConfig: &common.TracingConfig{
+				Enabled:      true,
+				Endpoint:     "http://otel-collector:4318/v1/traces",
+				Sampler:      common.TracingSamplerParentBasedAlwaysOn,
+				SamplerRatio: 1,
+				ServiceName:  "zdm-proxy",
+			},
+		},
+		{
+			name: "Valid: Endpoint with path, sampler and service name",
+			envVars: []envVar{
+				{"ZDM_TRACING_OTLP_ENDPOINT", "https://otel-collector/custom/traces"},
+				{"ZDM_TRACING_SAMPLER", "parentbased_traceidratio"},
+				{"ZDM_TRACING_SAMPLER_RATIO", "0.25"},
+				{"ZDM_TRACING_SERVICE_NAME", "zdm-proxy-eu"},
+			},
+			expectedConfig: &common.TracingConfig{
+				Enabled:      true,
+				Endpoint:     "https://otel-collector/custom/traces",
+				Sampler:      common.TracingSamplerParentBasedTraceIdRatio,
+				SamplerRatio: 0.25,
+				ServiceName:  "zdm-proxy-eu",
+			},
+		},
+		{
+			name:           "Valid: Invalid sampler is ignored when disabled",
+			envVars:        []envVar{{"ZDM_TRACING_SAMPLER", "SOMETIMES"}},
+			expectedConfig: &common.TracingConfig{Enabled: false},
+		},
+		{
+			name:        "Invalid: Endpoint",
+			envVars:     []envVar{{"ZDM_TRACING_OTLP_ENDPOINT", "otel-collector:4318"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TRACING_OTLP_ENDPOINT (otel-collector:4318); it must be an http or https URL",
+		},
+		{
+			name: "Invalid: Sampler",
+			envVars: []envVar{
+				{"ZDM_TRACING_OTLP_ENDPOINT", "http://otel-collector:4318"},
+				{"ZDM_TRACING_SAMPLER", "SOMETIMES"},
+			},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TRACING_SAMPLER; possible values are: ALWAYS_ON, ALWAYS_OFF, TRACEIDRATIO, " +
+				"PARENTBASED_ALWAYS_ON, PARENTBASED_ALWAYS_OFF and PARENTBASED_TRACEIDRATIO",
+		},
+		{
+			name: "Invalid: Sampler ratio",
+			envVars: []envVar{
+				{"ZDM_TRACING_OTLP_ENDPOINT", "http://otel-collector:4318"},
+				{"ZDM_TRACING_SAMPLER_RATIO", "1.5"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TRACING_SAMPLER_RATIO (1.5); it must be between 0 and 1",
+		},
+		{
+			name: "Invalid: Service name",
+			envVars: []envVar{
+				{"ZDM_TRACING_OTLP_ENDPOINT", "http://otel-collector:4318"},
+				{"ZDM_TRACING_SERVICE_NAME", " "},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TRACING_SERVICE_NAME ( ); it must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				tracingConfig, _ := conf.ParseTracingConfig()
+				require.Equal(t, tt.expectedConfig, tracingConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_ParseTrafficCaptureConfig(t *testing.T) {
+	directory := t.TempDir()
+	missingDirectory := filepath.Join(directory, "missing")
+	file := filepath.Join(directory, "file")
+	require.Nil(t, os.WriteFile(file, []byte{}, 0600))
+
+	type test struct {
+		name           string
+		envVars        []envVar
+		expectedConfig *common.TrafficCaptureConfig
+		errExpected    bool
+		errMsg         string
+	}
+
+	tests := []test{
+		{
+			name:           "Valid: Default",
+			envVars:        []envVar{},
+			expectedConfig: &common.TrafficCaptureConfig{Enabled: false},
+		},
+		{
+			name:    "Valid: Enabled with default max duration and file size",
+			envVars: []envVar{{"ZDM_TRAFFIC_CAPTURE_DIRECTORY", directory}},
+			expectedConfig: &common.TrafficCaptureConfig{
+				Enabled: true, Directory: directory, MaxDurationMs: 3600000, MaxFileSizeBytes: 104857600},
+		},
+		{
+			name: "Valid: Enabled with max duration and file size",
+			envVars: []envVar{
+				{"ZDM_TRAFFIC_CAPTURE_DIRECTORY", directory},
+				{"ZDM_TRAFFIC_CAPTURE_MAX_DURATION_MS", "60000"},
+				{"ZDM_TRAFFIC_CAPTURE_MAX_FILE_SIZE_BYTES", "1024"},
+			},
+			expectedConfig: &common.TrafficCaptureConfig{
+				Enabled: true, Directory: directory, MaxDurationMs: 60000, MaxFileSizeBytes: 1024},
+		},
+		{
+			name:           "Valid: Invalid max duration is ignored when disabled",
+			envVars:        []envVar{{"ZDM_TRAFFIC_CAPTURE_MAX_DURATION_MS", "0"}},
+			expectedConfig: &common.TrafficCaptureConfig{Enabled: false},
+		},
+		{
+			name:        "Invalid: Missing directory",
+			envVars:     []envVar{{"ZDM_TRAFFIC_CAPTURE_DIRECTORY", missingDirectory}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TRAFFIC_CAPTURE_DIRECTORY (" + missingDirectory + "); " +
+				"stat " + missingDirectory + ": no such file or directory",
+		},
+		{
+			name:        "Invalid: Not a directory",
+			envVars:     []envVar{{"ZDM_TRAFFIC_CAPTURE_DIRECTORY", file}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TRAFFIC_CAPTURE_DIRECTORY (" + file + "); it is not a directory",
+		},
+		{
+			name: "Invalid: Max duration",
+			envVars: []envVar{
+				{"ZDM_TRAFFIC_CAPTURE_DIRECTORY", directory},
+				{"ZDM_TRAFFIC_CAPTURE_MAX_DURATION_MS", "0"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TRAFFIC_CAPTURE_MAX_DURATION_MS (0); it must be a positive number",
+		},
+		{
+			name: "Invalid: Max file size",
+			envVars: []envVar{
+				{"ZDM_TRAFFIC_CAPTURE_DIRECTORY", directory},
+				{"ZDM_TRAFFIC_CAPTURE_MAX_FILE_SIZE_BYTES", "-1"},
+			},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_TRAFFIC_CAPTURE_MAX_FILE_SIZE_BYTES (-1); it must be a positive number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				trafficCaptureConfig, _ := conf.ParseTrafficCaptureConfig()
+				require.Equal(t, tt.expectedConfig, trafficCaptureConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseWriteComparisonSamplePercentage(t *testing.T) {
+
+	type test struct {
+		name               string
+		envVars            []envVar
+		expectedPercentage float64
+		errExpected        bool
+		errMsg             string
+	}
+
+	tests := []test{
+		{
+			name:               "Valid: Default",
+			envVars:            []envVar{},
+			expectedPercentage: 0,
+		},
+		{
+			name:               "Valid: Fraction",
+			envVars:            []envVar{{"ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE", "0.5"}},
+			expectedPercentage: 0.5,
+		},
+		{
+			name: "Valid: All writes with TARGET as primary cluster",
+			envVars: []envVar{
+				{"ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE", "100"},
+				{"ZDM_PRIMARY_CLUSTER", "TARGET"},
+			},
+			expectedPercentage: 100,
+		},
+		{
+			name:        "Invalid: Greater than 100",
+			envVars:     []envVar{{"ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE", "101"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE (101); it must be between 0 and 100",
+		},
+		{
+			name:        "Invalid: Negative",
+			envVars:     []envVar{{"ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE", "-1"}},
+			errExpected: true,
+			errMsg:      "invalid value for ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE (-1); it must be between 0 and 100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				percentage, _ := conf.ParseWriteComparisonSamplePercentage()
+				require.Equal(t, tt.expectedPercentage, percentage)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfig_ParseTargetWriteFilterRules(t *testing.T) {
+
+	type test struct {
+		name          string
+		envVars       []envVar
+		expectedRules []*common.WriteFilterRule
+		errExpected   bool
+		errMsg        string
+	}
+
+	tests := []test{
+		{
+			name:          "Valid: No rules",
+			envVars:       []envVar{},
+			expectedRules: nil,
+		},
+		{
+			name: "Valid: Multiple rules",
+			envVars: []envVar{{"ZDM_TARGET_WRITE_FILTER_RULES",
+				"KS.Users.tenant_id IN ('t1', 'it''s'); ks.\"Events\".created_at < 2020-01-01T00:00:00Z; ks.tb.c not in (1,2);"}},
+			expectedRules: []*common.WriteFilterRule{
+				{Keyspace: "ks", Table: "users", Column: "tenant_id", Operator: common.WriteFilterOperatorIn, Values: []string{"t1", "it's"}},
+				{Keyspace: "ks", Table: "Events", Column: "created_at", Operator: common.WriteFilterOperatorLessThan, Values: []string{"2020-01-01T00:00:00Z"}},
+				{Keyspace: "ks", Table: "tb", Column: "c", Operator: common.WriteFilterOperatorNotIn, Values: []string{"1", "2"}},
+			},
+		},
+		{
+			name:    "Valid: Operators with common prefixes",
+			envVars: []envVar{{"ZDM_TARGET_WRITE_FILTER_RULES", "ks.tb.a <= 1; ks.tb.b >= 2; ks.tb.c != 'x'"}},
+			expectedRules: []*common.WriteFilterRule{
+				{Keyspace: "ks", Table: "tb", Column: "a", Operator: common.WriteFilterOperatorLessOrEqual, Values: []string{"1"}},
+				{Keyspace: "ks", Table: "tb", Column: "b", Operator: common.WriteFilterOperatorGreaterOrEqual, Values: []string{"2"}},
+				{Keyspace: "ks", Table: "tb", Column: "c", Operator: common.WriteFilterOperatorNotEqual, Values: []string{"x"}},
+			},
+		},
+		{
+			name:        "Invalid: Missing operator",
+			envVars:     []envVar{{"ZDM_TARGET_WRITE_FILTER_RULES", "ks.tb.a"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_WRITE_FILTER_RULES (ks.tb.a): " +
+				"expected <keyspace>.<table>.<column> <operator> <value(s)>",
+		},
+		{
+			name:        "Invalid: Column without keyspace",
+			envVars:     []envVar{{"ZDM_TARGET_WRITE_FILTER_RULES", "tb.a = 1"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_WRITE_FILTER_RULES (tb.a = 1): " +
+				"expected column name with format <keyspace>.<table>.<column> but got tb.a",
+		},
+		{
+			name:        "Invalid: Unknown operator",
+			envVars:     []envVar{{"ZDM_TARGET_WRITE_FILTER_RULES", "ks.tb.a LIKE 'x%'"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_WRITE_FILTER_RULES (ks.tb.a LIKE 'x%'): " +
+				"unknown operator in LIKE 'x%'; possible operators are: =, !=, <, <=, >, >=, IN and NOT IN",
+		},
+		{
+			name:        "Invalid: IN without parentheses",
+			envVars:     []envVar{{"ZDM_TARGET_WRITE_FILTER_RULES", "ks.tb.a IN 1, 2"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_WRITE_FILTER_RULES (ks.tb.a IN 1, 2): " +
+				"IN expects a parenthesized list of values but got 1, 2",
+		},
+		{
+			name:        "Invalid: Empty value",
+			envVars:     []envVar{{"ZDM_TARGET_WRITE_FILTER_RULES", "ks.tb.a IN (1, )"}},
+			errExpected: true,
+			errMsg: "invalid value for ZDM_TARGET_WRITE_FILTER_RULES (ks.tb.a IN (1, )): " +
+				"empty value in IN (1, )",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAllEnvVars()
+
+			// set test-specific env vars
+			for _, envVar := range tt.envVars {
+				setEnvVar(envVar.vName, envVar.vValue)
+			}
+
+			// set other general env vars
+			setOriginCredentialsEnvVars()
+			setTargetCredentialsEnvVars()
+			setOriginContactPointsAndPortEnvVars()
+			setTargetContactPointsAndPortEnvVars()
+
+			conf, err := New().ParseEnvVars()
+			if err != nil {
+				if tt.errExpected {
+					require.Equal(t, tt.errMsg, err.Error())
+					return
+				} else {
+					t.Fatalf("Unexpected configuration validation error, stopping test here: %v", err)
+				}
+			}
+			require.False(t, tt.errExpected, "Expected configuration validation error")
+
+			if conf == nil {
+				t.Fatal("No configuration validation error was thrown but the parsed configuration is null, stopping test here")
+			} else {
+				rules, _ := conf.ParseTargetWriteFilterRules()
+				require.Equal(t, tt.expectedRules, rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const configFileEnvVarName = "ZDM_CONFIG_FILE"
+
+// applyConfigFile sets the settings of the YAML (.yaml or .yml) or JSON (.json) file at path that are not set with an
+// environment variable, i.e. the environment variables override the file. The keys of the file are the names of the
+// environment variables with or without the ZDM_ prefix, case insensitive (e.g. origin_contact_points), and the
+// values are scalars or lists that are joined with commas.
+func (c *Config) applyConfigFile(path string) error {
+	settings, err := readConfigFile(path)
+	if err != nil {
+		return fmt.Errorf("could not load config file %v: %w", path, err)
+	}
+
+	fields := make(map[string]reflect.Value)
+	collectSettingFields(reflect.ValueOf(c).Elem(), fields)
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		envVar := strings.ToUpper(name)
+		if !strings.HasPrefix(envVar, "ZDM_") {
+			envVar = "ZDM_" + envVar
+		}
+		fieldValue, ok := fields[envVar]
+		if !ok || envVar == configFileEnvVarName {
+			return fmt.Errorf("invalid setting %v in config file %v; unknown setting", name, path)
+		}
+		value, err := configFileValueString(settings[name])
+		if err != nil {
+			return fmt.Errorf("invalid value for %v in config file %v; %w", name, path, err)
+		}
+		if c.fileSettings == nil {
+			c.fileSettings = make(map[string]bool)
+		}
+		c.fileSettings[envVar] = true
+		if _, isSet := os.LookupEnv(envVar); isSet {
+			continue
+		}
+		err = setFieldValue(fieldValue, value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %v in config file %v; %w", name, path, err)
+		}
+	}
+	return nil
+}
+
+func readConfigFile(path string) (map[string]interface{}, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, &settings)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		decoder.UseNumber()
+		err = decoder.Decode(&settings)
+	default:
+		return nil, fmt.Errorf("unsupported file extension %v, expected .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse it: %w", err)
+	}
+	return settings, nil
+}
+
+// collectSettingFields returns the fields of the settings of the struct and its embedded sections keyed by the name
+// of their environment variable.
+func collectSettingFields(structValue reflect.Value, fields map[string]reflect.Value) {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := structValue.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectSettingFields(fieldValue, fields)
+			continue
+		}
+		if field.IsExported() {
+			fields[envVarName(field)] = fieldValue
+		}
+	}
+}
+
+// configFileValueString returns the value of a setting of the config file in the format of its environment variable.
+func configFileValueString(value interface{}) (string, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return typedValue, nil
+	case bool, int, int64, uint64, json.Number:
+		return fmt.Sprint(typedValue), nil
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), nil
+	case []interface{}:
+		values := make([]string, 0, len(typedValue))
+		for _, element := range typedValue {
+			if _, isList := element.([]interface{}); isList {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			elementValue, err := configFileValueString(element)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, elementValue)
+		}
+		return strings.Join(values, ","), nil
+	default:
+		return "", fmt.Errorf("expected a scalar or a list but got %T", value)
+	}
+}
+
+// setFieldValue parses the value of a setting like envconfig parses the value of its environment variable.
+func setFieldValue(fieldValue reflect.Value, value string) error {
+	switch fieldValue.Kind() {
+	case reflect.String:
+		fieldValue.SetString(value)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer but got '%v'", value)
+		}
+		fieldValue.SetInt(int64(parsed))
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected a boolean but got '%v'", value)
+		}
+		fieldValue.SetBool(parsed)
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number but got '%v'", value)
+		}
+		fieldValue.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported type %v", fieldValue.Type())
+	}
+	return nil
+}
+
+// Dump returns the value of every setting as a YAML document that can be used as a config file, the secrets are
+// redacted like in RedactedValues. It is logged when the proxy starts.
+func (c *Config) Dump() string {
+	values := make(map[string]interface{})
+	for envVar, value := range c.RedactedValues() {
+		if envVar == configFileEnvVarName {
+			continue
+		}
+		values[strings.ToLower(strings.TrimPrefix(envVar, "ZDM_"))] = value
+	}
+	dump, err := yaml.Marshal(values)
+	if err != nil {
+		return fmt.Sprintf("could not serialize the configuration: %v", err)
+	}
+	return string(dump)
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// credentialFile is a credential setting that can be read from a file with the <setting>_FILE variant.
+type credentialFile struct {
+	envVarName string
+	value      *string
+	path       string
+}
+
+func (c *Config) getCredentialFiles() []credentialFile {
+	return []credentialFile{
+		{"ZDM_ORIGIN_USERNAME", &c.OriginUsername, c.OriginUsernameFile},
+		{"ZDM_ORIGIN_PASSWORD", &c.OriginPassword, c.OriginPasswordFile},
+		{"ZDM_TARGET_USERNAME", &c.TargetUsername, c.TargetUsernameFile},
+		{"ZDM_TARGET_PASSWORD", &c.TargetPassword, c.TargetPasswordFile},
+	}
+}
+
+// readCredentialFiles replaces the credentials that are provided with the <setting>_FILE variants with the contents
+// of the files. Every credential must be provided with either the environment variable or the file variant.
+func (c *Config) readCredentialFiles() error {
+	for _, file := range c.getCredentialFiles() {
+		_, isSet := os.LookupEnv(file.envVarName)
+		isSet = isSet || c.fileSettings[file.envVarName]
+		if isNotDefined(file.path) {
+			if !isSet {
+				return fmt.Errorf("required key %v missing value (or %v_FILE)", file.envVarName, file.envVarName)
+			}
+			continue
+		}
+
+		if isSet {
+			return fmt.Errorf("invalid value for %v_FILE (%v); %v must not be set as well",
+				file.envVarName, file.path, file.envVarName)
+		}
+		value, err := readCredentialFile(file.path)
+		if err != nil {
+			return fmt.Errorf("invalid value for %v_FILE (%v); %w", file.envVarName, file.path, err)
+		}
+		*file.value = value
+	}
+	return nil
+}
+
+// ReadCredentialFile returns the value of a credential file (e.g. a Docker or Kubernetes secret) without the
+// leading and trailing whitespace, the value is decrypted if it is encrypted (see ConfigDecryptionCommand).
+func (c *Config) ReadCredentialFile(path string) (string, error) {
+	value, err := readCredentialFile(path)
+	if err != nil || !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, err
+	}
+	return c.decryptValue(strings.TrimPrefix(value, encryptedValuePrefix))
+}
+
+func readCredentialFile(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read credential file: %w", err)
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+func (c *Config) ParseCredentialFilesPollInterval() (int, error) {
+	if c.CredentialFilesPollIntervalMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_CREDENTIAL_FILES_POLL_INTERVAL_MS (%v); "+
+			"it must be 0 (disabled) or a positive number", c.CredentialFilesPollIntervalMs)
+	}
+	return c.CredentialFilesPollIntervalMs, nil
+}
+
+// IsProxyAuthEnabled returns true if the proxy authenticates the clients with its own credentials (see
+// ProxyAuthCredentials).
+func (c *Config) IsProxyAuthEnabled() bool {
+	return isDefined(c.ProxyAuthCredentials) || isDefined(c.ProxyAuthCredentialsFile)
+}
+
+// ParseProxyAuthCredentials returns the passwords of the proxy credentials keyed on username, nil if the proxy doesn't
+// authenticate the clients itself.
+func (c *Config) ParseProxyAuthCredentials() (map[string]string, error) {
+	if isDefined(c.ProxyAuthCredentials) && isDefined(c.ProxyAuthCredentialsFile) {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_AUTH_CREDENTIALS_FILE (%v); "+
+			"ZDM_PROXY_AUTH_CREDENTIALS must not be set as well", c.ProxyAuthCredentialsFile)
+	}
+	if isDefined(c.ProxyAuthCredentials) {
+		credentials, err := parseProxyAuthCredentials(strings.Split(c.ProxyAuthCredentials, ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_PROXY_AUTH_CREDENTIALS; %w", err)
+		}
+		return credentials, nil
+	}
+	if isDefined(c.ProxyAuthCredentialsFile) {
+		contents, err := c.ReadCredentialFile(c.ProxyAuthCredentialsFile)
+		if err == nil {
+			var credentials map[string]string
+			credentials, err = parseProxyAuthCredentials(strings.Split(contents, "\n"))
+			if err == nil {
+				return credentials, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_AUTH_CREDENTIALS_FILE (%v); %w",
+			c.ProxyAuthCredentialsFile, err)
+	}
+	return nil, nil
+}
+
+// parseProxyAuthCredentials parses <username>:<password> entries, the empty entries and the lines that start with #
+// are ignored.
+func parseProxyAuthCredentials(entries []string) (map[string]string, error) {
+	credentials := make(map[string]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		separator := strings.Index(entry, ":")
+		if separator <= 0 {
+			return nil, fmt.Errorf("the entries must have the format <username>:<password>")
+		}
+		username := entry[:separator]
+		if _, exists := credentials[username]; exists {
+			return nil, fmt.Errorf("the username %v is set more than once", username)
+		}
+		credentials[username] = entry[separator+1:]
+	}
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("there must be at least one <username>:<password> entry")
+	}
+	return credentials, nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	encryptedValuePrefix          = "enc:"
+	commandDecryptionProviderName = "cmd"
+	decryptionCommandTimeout      = 30 * time.Second
+	decryptedValuePlaceholder     = "*****"
+	configDecryptionCommandField  = "ConfigDecryptionCommand"
+)
+
+// DecryptionProvider decrypts the settings that are provided encrypted with the enc:<provider>:<base64 ciphertext>
+// format, e.g. with a KMS client.
+type DecryptionProvider interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+var (
+	decryptionProviders     = map[string]DecryptionProvider{}
+	decryptionProvidersLock = &sync.RWMutex{}
+)
+
+// RegisterDecryptionProvider makes the provider available to the settings with the enc:<name>:<base64 ciphertext>
+// format. It must be called before ParseEnvVars, e.g. in an init function of a custom build.
+func RegisterDecryptionProvider(name string, provider DecryptionProvider) {
+	decryptionProvidersLock.Lock()
+	defer decryptionProvidersLock.Unlock()
+	decryptionProviders[name] = provider
+}
+
+// commandDecryptionProvider writes the ciphertext to the stdin of ZDM_CONFIG_DECRYPTION_COMMAND and reads the
+// plaintext from its stdout.
+type commandDecryptionProvider struct {
+	command []string
+}
+
+func (recv *commandDecryptionProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), decryptionCommandTimeout)
+	defer cancelFn()
+
+	cmd := exec.CommandContext(ctx, recv.command[0], recv.command[1:]...)
+	cmd.Stdin = bytes.NewReader(ciphertext)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	plaintext, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v failed: %w (%v)", recv.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.TrimRight(plaintext, "\r\n"), nil
+}
+
+func (c *Config) getDecryptionProvider(name string) (DecryptionProvider, error) {
+	if name == commandDecryptionProviderName {
+		command := strings.Fields(c.ConfigDecryptionCommand)
+		if len(command) == 0 {
+			return nil, fmt.Errorf("ZDM_CONFIG_DECRYPTION_COMMAND is not set")
+		}
+		return &commandDecryptionProvider{command: command}, nil
+	}
+
+	decryptionProvidersLock.RLock()
+	defer decryptionProvidersLock.RUnlock()
+	provider, ok := decryptionProviders[name]
+	if !ok {
+		names := []string{commandDecryptionProviderName}
+		for registeredName := range decryptionProviders {
+			names = append(names, registeredName)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown decryption provider '%v', valid providers are: %v",
+			name, strings.Join(names, ", "))
+	}
+	return provider, nil
+}
+
+// decryptValues replaces the settings that are provided with the enc:<provider>:<base64 ciphertext> format
+// with their plaintext.
+func (c *Config) decryptValues() error {
+	return c.decryptStructValues(reflect.ValueOf(c).Elem())
+}
+
+func (c *Config) decryptStructValues(structValue reflect.Value) error {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := structValue.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			err := c.decryptStructValues(fieldValue)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.Type.Kind() != reflect.String || field.Name == configDecryptionCommandField ||
+			!strings.HasPrefix(fieldValue.String(), encryptedValuePrefix) {
+			continue
+		}
+
+		plaintext, err := c.decryptValue(strings.TrimPrefix(fieldValue.String(), encryptedValuePrefix))
+		if err != nil {
+			return fmt.Errorf("invalid value for %v; could not decrypt it: %w", envVarName(field), err)
+		}
+		fieldValue.SetString(plaintext)
+		if c.decryptedFields == nil {
+			c.decryptedFields = make(map[string]bool)
+		}
+		c.decryptedFields[field.Name] = true
+	}
+	return nil
+}
+
+func (c *Config) decryptValue(value string) (string, error) {
+	separatorIdx := strings.Index(value, ":")
+	if separatorIdx < 0 {
+		return "", fmt.Errorf("expected %v<provider>:<base64 ciphertext>", encryptedValuePrefix)
+	}
+	provider, err := c.getDecryptionProvider(value[:separatorIdx])
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(value[separatorIdx+1:])
+	if err != nil {
+		return "", fmt.Errorf("the ciphertext is not valid base64: %w", err)
+	}
+	plaintext, err := provider.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+var (
+	envVarWordsRegexp   = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
+	envVarAcronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
+)
+
+// envVarName returns the name of the environment variable of a field with the same rules as envconfig.
+func envVarName(field reflect.StructField) string {
+	if field.Tag.Get("split_words") != "true" {
+		return "ZDM_" + strings.ToUpper(field.Name)
+	}
+	var words []string
+	for _, word := range envVarWordsRegexp.FindAllString(field.Name, -1) {
+		if m := envVarAcronymRegexp.FindStringSubmatch(word); len(m) == 3 {
+			words = append(words, m[1], m[2])
+		} else {
+			words = append(words, word)
+		}
+	}
+	return "ZDM_" + strings.ToUpper(strings.Join(words, "_"))
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// ListenerConfig holds the settings of the proxy listener and of the client connections that it accepts.
+type ListenerConfig struct {
+
+	// Proxy bucket
+
+	ProxyListenAddress        string `default:"localhost" split_words:"true"`
+	ProxyListenPort           int    `default:"14002" split_words:"true"`
+	ProxyRequestTimeoutMs     int    `default:"10000" split_words:"true"`
+	ProxyMaxClientConnections int    `default:"1000" split_words:"true"`
+
+	// ProxyReadRequestTimeoutMs, ProxyWriteRequestTimeoutMs and ProxyDdlRequestTimeoutMs override the request timeout
+	// of both clusters for the SELECT statements, the INSERT, UPDATE, DELETE and BATCH statements and the schema
+	// changes (CREATE, ALTER, DROP and TRUNCATE statements) respectively. 0 means that the timeout of the cluster is
+	// used (see ZDM_ORIGIN_REQUEST_TIMEOUT_MS and ZDM_TARGET_REQUEST_TIMEOUT_MS).
+	ProxyReadRequestTimeoutMs  int `default:"0" split_words:"true"`
+	ProxyWriteRequestTimeoutMs int `default:"0" split_words:"true"`
+	ProxyDdlRequestTimeoutMs   int `default:"0" split_words:"true"`
+
+	// ProxyShutdownDrainTimeoutMs is the maximum time that the proxy waits for the in flight requests of the client
+	// connections when it is drained (SIGINT/SIGTERM), the connections that still have in flight requests after this
+	// timeout are closed. A value of 0 closes the client connections without waiting.
+	ProxyShutdownDrainTimeoutMs int `default:"30000" split_words:"true"`
+
+	// ProxyClientIdleTimeoutMs closes the client connections that didn't send a request other than OPTIONS (the
+	// heartbeats of the drivers) for this long, their cluster connections are closed as well. 0 means that the idle
+	// client connections are never closed.
+	ProxyClientIdleTimeoutMs int `default:"0" split_words:"true"`
+
+	// ProxyMaxClientRequestsPerSecond and ProxyMaxClientInFlightRequests limit the QUERY, EXECUTE and BATCH requests
+	// of each client connection, the requests over the limits get an OVERLOADED error. 0 means unlimited.
+	ProxyMaxClientRequestsPerSecond int `default:"0" split_words:"true"`
+	ProxyMaxClientInFlightRequests  int `default:"0" split_words:"true"`
+
+	// ProxyMaxInFlightRequests is the maximum number of requests that are in flight across all client connections,
+	// the client connections stop reading requests from their sockets while it is reached. 0 means unlimited.
+	ProxyMaxInFlightRequests int `default:"0" split_words:"true"`
+
+	// ProxyTcpNoDelay, ProxySocketReceiveBufferSizeBytes and ProxySocketSendBufferSizeBytes are the socket options of
+	// the client connections (TCP_NODELAY, SO_RCVBUF and SO_SNDBUF), a buffer size of 0 keeps the operating system
+	// default.
+	ProxyTcpNoDelay                   bool `default:"true" split_words:"true"`
+	ProxySocketReceiveBufferSizeBytes int  `default:"0" split_words:"true"`
+	ProxySocketSendBufferSizeBytes    int  `default:"0" split_words:"true"`
+
+	// ProxyClusterConnectionPoolSize is the maximum number of connections per cluster node that are shared by
+	// all client connections. A value of 0 means each client connection gets its own cluster connections.
+	ProxyClusterConnectionPoolSize int `default:"0" split_words:"true"`
+
+	// ProxyClusterConnectionsPerClient is the number of connections that each client connection opens to each cluster
+	// when the cluster connections are not pooled, the requests of the client are dispatched across them with
+	// ProxyClusterConnectionDispatchPolicy (ROUND_ROBIN or LEAST_IN_FLIGHT).
+	ProxyClusterConnectionsPerClient     int    `default:"1" split_words:"true"`
+	ProxyClusterConnectionDispatchPolicy string `default:"ROUND_ROBIN" split_words:"true"`
+
+	// ProxyClusterConnectionFailoverEnabled reopens the cluster connection of a client connection to another node of
+	// the local datacenter (replaying its handshake) when the node goes down or the connection breaks, instead of
+	// closing the client connection. The in flight requests get an OVERLOADED error so the drivers retry them. It is
+	// ignored when the cluster connections are pooled (ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE) and it can't be
+	// enabled with more than one cluster connection per client (ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT).
+	ProxyClusterConnectionFailoverEnabled bool `default:"false" split_words:"true"`
+
+	// ProxyHandshakeFastPathEnabled makes the proxy answer OPTIONS requests sent before STARTUP with a cached
+	// SUPPORTED response and authenticate with the secondary cluster while the primary cluster is still
+	// processing the client's AUTH_RESPONSE. Note that this means the secondary cluster is authenticated
+	// even if the primary cluster rejects the client's credentials, the client then has to open a new connection
+	// to authenticate again.
+	ProxyHandshakeFastPathEnabled bool `default:"false" split_words:"true"`
+
+	// ProxyMaxProtocolVersion is the highest protocol version that the proxy negotiates with the clients, a client
+	// that requests a higher version gets a protocol error so that it downgrades. DSE protocol versions are not capped.
+	ProxyMaxProtocolVersion int `default:"5" split_words:"true"`
+
+	ProxyTlsCaPath            string `split_words:"true"`
+	ProxyTlsCertPath          string `split_words:"true"`
+	ProxyTlsKeyPath           string `split_words:"true"`
+	ProxyTlsRequireClientAuth bool   `split_words:"true"`
+
+	// ProxyTlsDevModeEnabled makes the proxy generate an ephemeral CA and server certificate at startup for the
+	// client listener instead of loading them from ZDM_PROXY_TLS_CA_PATH, ZDM_PROXY_TLS_CERT_PATH and
+	// ZDM_PROXY_TLS_KEY_PATH. The CA (and a client certificate if ZDM_PROXY_TLS_REQUIRE_CLIENT_AUTH is true) is
+	// printed to the log so that it can be used by the client. Only meant for local development and tests.
+	ProxyTlsDevModeEnabled bool `default:"false" split_words:"true"`
+
+	// ProxyAuthCredentials (comma separated <username>:<password> entries) or ProxyAuthCredentialsFile (one
+	// <username>:<password> entry per line) make the proxy authenticate the clients itself with these credentials
+	// instead of the clusters, the proxy then authenticates to both clusters with the configured credentials so the
+	// clients don't need the credentials of the clusters.
+	ProxyAuthCredentials     string `split_words:"true" json:"-"`
+	ProxyAuthCredentialsFile string `split_words:"true"`
+}
+
+func (c *ListenerConfig) Validate() error {
+	_, err := c.ParseProxyTlsConfig(false)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseProxyClusterConnectionPoolSize()
+	if err != nil {
+		return err
+	}
+
+	clusterConnectionsPerClient, err := c.ParseProxyClusterConnectionsPerClient()
+	if err != nil {
+		return err
+	}
+
+	if c.ProxyClusterConnectionFailoverEnabled && clusterConnectionsPerClient > 1 {
+		return fmt.Errorf("invalid value for ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT (%v); it must be 1 when "+
+			"ZDM_PROXY_CLUSTER_CONNECTION_FAILOVER_ENABLED is true", clusterConnectionsPerClient)
+	}
+
+	_, err = c.ParseProxyClusterConnectionDispatchPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseProxySocketOptions()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseProxyMaxProtocolVersion()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseProxyShutdownDrainTimeout()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseProxyClientIdleTimeout()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseProxyClientRequestLimitConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseProxyMaxInFlightRequests()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *ListenerConfig) ParseProxySocketOptions() (*common.SocketOptions, error) {
+	return parseSocketOptions(
+		"PROXY", c.ProxyTcpNoDelay, c.ProxySocketReceiveBufferSizeBytes, c.ProxySocketSendBufferSizeBytes)
+}
+
+func (c *ListenerConfig) ParseProxyMaxProtocolVersion() (primitive.ProtocolVersion, error) {
+	version := primitive.ProtocolVersion(c.ProxyMaxProtocolVersion)
+	if version < primitive.ProtocolVersion3 || version > primitive.ProtocolVersion5 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_MAX_PROTOCOL_VERSION (%v); it must be 3, 4 or 5",
+			c.ProxyMaxProtocolVersion)
+	}
+	return version, nil
+}
+
+func (c *ListenerConfig) ParseProxyClusterConnectionPoolSize() (int, error) {
+	if c.ProxyClusterConnectionPoolSize < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE (%v); "+
+			"it must be 0 (disabled) or a positive number", c.ProxyClusterConnectionPoolSize)
+	}
+	return c.ProxyClusterConnectionPoolSize, nil
+}
+
+func (c *ListenerConfig) ParseProxyClusterConnectionsPerClient() (int, error) {
+	if c.ProxyClusterConnectionsPerClient < 1 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT (%v); "+
+			"it must be a positive number", c.ProxyClusterConnectionsPerClient)
+	}
+	return c.ProxyClusterConnectionsPerClient, nil
+}
+
+const (
+	ConnectionDispatchPolicyRoundRobin    = "ROUND_ROBIN"
+	ConnectionDispatchPolicyLeastInFlight = "LEAST_IN_FLIGHT"
+)
+
+func (c *ListenerConfig) ParseProxyClusterConnectionDispatchPolicy() (common.ConnectionDispatchPolicy, error) {
+	switch strings.ToUpper(c.ProxyClusterConnectionDispatchPolicy) {
+	case ConnectionDispatchPolicyRoundRobin:
+		return common.ConnectionDispatchPolicyRoundRobin, nil
+	case ConnectionDispatchPolicyLeastInFlight:
+		return common.ConnectionDispatchPolicyLeastInFlight, nil
+	default:
+		return common.ConnectionDispatchPolicyUndefined, fmt.Errorf(
+			"invalid value for ZDM_PROXY_CLUSTER_CONNECTION_DISPATCH_POLICY; possible values are: %v and %v",
+			ConnectionDispatchPolicyRoundRobin, ConnectionDispatchPolicyLeastInFlight)
+	}
+}
+
+func (c *ListenerConfig) ParseProxyShutdownDrainTimeout() (time.Duration, error) {
+	if c.ProxyShutdownDrainTimeoutMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_SHUTDOWN_DRAIN_TIMEOUT_MS (%v); "+
+			"it must be 0 (no draining) or a positive number", c.ProxyShutdownDrainTimeoutMs)
+	}
+	return time.Duration(c.ProxyShutdownDrainTimeoutMs) * time.Millisecond, nil
+}
+
+func (c *ListenerConfig) ParseProxyClientIdleTimeout() (time.Duration, error) {
+	if c.ProxyClientIdleTimeoutMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS (%v); "+
+			"it must be 0 (disabled) or a positive number", c.ProxyClientIdleTimeoutMs)
+	}
+	return time.Duration(c.ProxyClientIdleTimeoutMs) * time.Millisecond, nil
+}
+
+func (c *ListenerConfig) ParseProxyClientRequestLimitConfig() (*common.ClientRequestLimitConfig, error) {
+	if c.ProxyMaxClientRequestsPerSecond < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_MAX_CLIENT_REQUESTS_PER_SECOND (%v); "+
+			"it must be 0 (unlimited) or a positive number", c.ProxyMaxClientRequestsPerSecond)
+	}
+	if c.ProxyMaxClientInFlightRequests < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_PROXY_MAX_CLIENT_IN_FLIGHT_REQUESTS (%v); "+
+			"it must be 0 (unlimited) or a positive number", c.ProxyMaxClientInFlightRequests)
+	}
+	return &common.ClientRequestLimitConfig{
+		MaxRequestsPerSecond: c.ProxyMaxClientRequestsPerSecond,
+		MaxInFlightRequests:  c.ProxyMaxClientInFlightRequests,
+	}, nil
+}
+
+func (c *ListenerConfig) ParseProxyMaxInFlightRequests() (int, error) {
+	if c.ProxyMaxInFlightRequests < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS (%v); "+
+			"it must be 0 (unlimited) or a positive number", c.ProxyMaxInFlightRequests)
+	}
+	return c.ProxyMaxInFlightRequests, nil
+}
+
+func (c *ListenerConfig) ParseProxyTlsConfig(displayLogMessages bool) (*common.ProxyTlsConfig, error) {
+
+	if c.ProxyTlsDevModeEnabled {
+		if isDefined(c.ProxyTlsCaPath) || isDefined(c.ProxyTlsCertPath) || isDefined(c.ProxyTlsKeyPath) {
+			return &common.ProxyTlsConfig{}, fmt.Errorf("invalid Proxy TLS configuration: ZDM_PROXY_TLS_DEV_MODE_ENABLED " +
+				"can not be used with the CA path, Cert path and Key path")
+		}
+		if displayLogMessages {
+			log.Warn("Proxy TLS dev mode enabled, the CA and server certificate will be generated at startup. " +
+				"Do not use it in production.")
+		}
+		return &common.ProxyTlsConfig{
+			TlsEnabled: true,
+			DevMode:    true,
+			ClientAuth: c.ProxyTlsRequireClientAuth,
+		}, nil
+	}
+
+	if isNotDefined(c.ProxyTlsCaPath) &&
+		isNotDefined(c.ProxyTlsCertPath) &&
+		isNotDefined(c.ProxyTlsKeyPath) {
+		if displayLogMessages {
+			log.Info("Proxy TLS was not configured.")
+		}
+		return &common.ProxyTlsConfig{
+			TlsEnabled: false,
+		}, nil
+	}
+
+	if isDefined(c.ProxyTlsCaPath) && isDefined(c.ProxyTlsCertPath) && isDefined(c.ProxyTlsKeyPath) {
+		if displayLogMessages {
+			log.Info("Proxy TLS configured. Please note that hostname verification is not currently supported.")
+		}
+		return &common.ProxyTlsConfig{
+			TlsEnabled:    true,
+			ProxyCaPath:   c.ProxyTlsCaPath,
+			ProxyCertPath: c.ProxyTlsCertPath,
+			ProxyKeyPath:  c.ProxyTlsKeyPath,
+			ClientAuth:    c.ProxyTlsRequireClientAuth,
+		}, nil
+	}
+
+	return &common.ProxyTlsConfig{}, fmt.Errorf("incomplete Proxy TLS configuration: when enabling proxy TLS, please specify CA path, Cert path and Key path")
+}
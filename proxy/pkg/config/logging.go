@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net/url"
+	"strings"
+)
+
+// LoggingConfig holds the settings of the logs of the requests (slow query log and audit log) that are written in
+// addition to the logs of the proxy.
+type LoggingConfig struct {
+
+	// Slow query log bucket
+
+	// SlowQueryLogThresholdMs enables the slow query log, the QUERY, PREPARE, EXECUTE and BATCH requests that take
+	// longer are logged as JSON with their obfuscated query (SlowQueryLogQueryFormat OBFUSCATED) or only its digest
+	// (DIGEST). SlowQueryLogSampleRatio of the slow queries are logged, SlowQueryLogMaxPerSecond at most.
+	SlowQueryLogThresholdMs  int     `default:"0" split_words:"true"`
+	SlowQueryLogQueryFormat  string  `default:"OBFUSCATED" split_words:"true"`
+	SlowQueryLogSampleRatio  float64 `default:"1" split_words:"true"`
+	SlowQueryLogMaxPerSecond int     `default:"10" split_words:"true"`
+
+	// Audit log bucket
+
+	// AuditLogDestination enables the audit log (FILE or SYSLOG), every request is logged as JSON with its opcode,
+	// the digest of its obfuscated query, the keyspace and table, the client user, address and application identity,
+	// the routing decision and the outcome. The FILE audit log is written to AuditLogFilePath and rotated when it reaches
+	// AuditLogFileMaxSizeBytes (AuditLogFileMaxBackups rotated files are kept), the SYSLOG audit log is sent to
+	// AuditLogSyslogAddress (e.g. udp://syslog:514) or to the local syslog daemon if it is empty.
+	AuditLogDestination      string `split_words:"true"`
+	AuditLogQueryFormat      string `default:"DIGEST" split_words:"true"`
+	AuditLogFilePath         string `default:"zdm-proxy-audit.log" split_words:"true"`
+	AuditLogFileMaxSizeBytes int    `default:"104857600" split_words:"true"`
+	AuditLogFileMaxBackups   int    `default:"5" split_words:"true"`
+	AuditLogSyslogAddress    string `split_words:"true"`
+}
+
+func (c *LoggingConfig) Validate() error {
+	_, err := c.ParseSlowQueryLogConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseAuditLogConfig()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const (
+	SlowQueryFormatObfuscated = "OBFUSCATED"
+	SlowQueryFormatDigest     = "DIGEST"
+)
+
+func (c *LoggingConfig) ParseSlowQueryLogConfig() (*common.SlowQueryLogConfig, error) {
+	if c.SlowQueryLogThresholdMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_SLOW_QUERY_LOG_THRESHOLD_MS (%v); it must be 0 (disabled) or a positive number",
+			c.SlowQueryLogThresholdMs)
+	}
+	if c.SlowQueryLogThresholdMs == 0 {
+		return &common.SlowQueryLogConfig{}, nil
+	}
+
+	var queryFormat common.SlowQueryFormat
+	switch strings.ToUpper(strings.TrimSpace(c.SlowQueryLogQueryFormat)) {
+	case SlowQueryFormatObfuscated:
+		queryFormat = common.SlowQueryFormatObfuscated
+	case SlowQueryFormatDigest:
+		queryFormat = common.SlowQueryFormatDigest
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_SLOW_QUERY_LOG_QUERY_FORMAT; possible values are: %v and %v",
+			SlowQueryFormatObfuscated, SlowQueryFormatDigest)
+	}
+
+	if c.SlowQueryLogSampleRatio <= 0 || c.SlowQueryLogSampleRatio > 1 {
+		return nil, fmt.Errorf("invalid value for ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO (%v); it must be greater than 0 and at most 1",
+			c.SlowQueryLogSampleRatio)
+	}
+
+	if c.SlowQueryLogMaxPerSecond <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_SLOW_QUERY_LOG_MAX_PER_SECOND (%v); it must be a positive number",
+			c.SlowQueryLogMaxPerSecond)
+	}
+
+	return &common.SlowQueryLogConfig{
+		Enabled:      true,
+		ThresholdMs:  c.SlowQueryLogThresholdMs,
+		QueryFormat:  queryFormat,
+		SampleRatio:  c.SlowQueryLogSampleRatio,
+		MaxPerSecond: c.SlowQueryLogMaxPerSecond,
+	}, nil
+}
+
+const (
+	AuditLogDestinationFile   = "FILE"
+	AuditLogDestinationSyslog = "SYSLOG"
+)
+
+func (c *LoggingConfig) ParseAuditLogConfig() (*common.AuditLogConfig, error) {
+	var destination common.AuditLogDestination
+	switch strings.ToUpper(strings.TrimSpace(c.AuditLogDestination)) {
+	case "":
+		return &common.AuditLogConfig{}, nil
+	case AuditLogDestinationFile:
+		destination = common.AuditLogDestinationFile
+	case AuditLogDestinationSyslog:
+		destination = common.AuditLogDestinationSyslog
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_DESTINATION; possible values are: %v, %v and empty (disabled)",
+			AuditLogDestinationFile, AuditLogDestinationSyslog)
+	}
+
+	var queryFormat common.SlowQueryFormat
+	switch strings.ToUpper(strings.TrimSpace(c.AuditLogQueryFormat)) {
+	case SlowQueryFormatObfuscated:
+		queryFormat = common.SlowQueryFormatObfuscated
+	case SlowQueryFormatDigest:
+		queryFormat = common.SlowQueryFormatDigest
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_QUERY_FORMAT; possible values are: %v and %v",
+			SlowQueryFormatObfuscated, SlowQueryFormatDigest)
+	}
+
+	auditLogConfig := &common.AuditLogConfig{
+		Destination: destination,
+		QueryFormat: queryFormat,
+	}
+
+	if destination == common.AuditLogDestinationFile {
+		filePath := strings.TrimSpace(c.AuditLogFilePath)
+		if filePath == "" {
+			return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_FILE_PATH; it must not be empty when " +
+				"ZDM_AUDIT_LOG_DESTINATION is FILE")
+		}
+		if c.AuditLogFileMaxSizeBytes <= 0 {
+			return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_FILE_MAX_SIZE_BYTES (%v); it must be a positive number",
+				c.AuditLogFileMaxSizeBytes)
+		}
+		if c.AuditLogFileMaxBackups < 0 {
+			return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_FILE_MAX_BACKUPS (%v); it must be 0 or a positive number",
+				c.AuditLogFileMaxBackups)
+		}
+		auditLogConfig.FilePath = filePath
+		auditLogConfig.FileMaxSizeBytes = c.AuditLogFileMaxSizeBytes
+		auditLogConfig.FileMaxBackups = c.AuditLogFileMaxBackups
+		return auditLogConfig, nil
+	}
+
+	syslogAddress := strings.TrimSpace(c.AuditLogSyslogAddress)
+	if syslogAddress != "" {
+		parsedAddress, err := url.Parse(syslogAddress)
+		if err != nil || (parsedAddress.Scheme != "udp" && parsedAddress.Scheme != "tcp") || parsedAddress.Host == "" {
+			return nil, fmt.Errorf("invalid value for ZDM_AUDIT_LOG_SYSLOG_ADDRESS (%v); it must be an address like "+
+				"udp://host:514 or tcp://host:514", syslogAddress)
+		}
+		auditLogConfig.SyslogNetwork = parsedAddress.Scheme
+		auditLogConfig.SyslogAddress = parsedAddress.Host
+	}
+	return auditLogConfig, nil
+}
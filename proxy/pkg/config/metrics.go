@@ -0,0 +1,437 @@
+package config
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricsConfig holds the settings of the metrics endpoint, of the histograms and of the distributed tracing.
+type MetricsConfig struct {
+
+	// Metrics bucket
+
+	MetricsEnabled bool   `default:"true" split_words:"true"`
+	MetricsAddress string `default:"localhost" split_words:"true"`
+	MetricsPort    int    `default:"14001" split_words:"true"`
+
+	// MetricsBackend is PROMETHEUS (the metrics are served on the metrics endpoint) or STATSD (the metrics are sent to
+	// MetricsStatsdAddress every MetricsStatsdFlushIntervalMs, the metrics endpoint returns 404).
+	MetricsBackend string `default:"PROMETHEUS" split_words:"true"`
+
+	// MetricsStatsdPrefix is prepended to the metric names, e.g. zdm.proxy_request_duration_seconds. The labels are
+	// sent as DogStatsD tags if MetricsStatsdTagsEnabled is true, otherwise their values are appended to the metric
+	// names because plain StatsD servers don't support tags.
+	MetricsStatsdAddress         string `default:"localhost:8125" split_words:"true"`
+	MetricsStatsdFlushIntervalMs int    `default:"1000" split_words:"true"`
+	MetricsStatsdPrefix          string `default:"zdm" split_words:"true"`
+	MetricsStatsdTagsEnabled     bool   `default:"true" split_words:"true"`
+
+	// ReadinessRequiresOrigin and ReadinessRequiresTarget are the clusters whose control connection must be up for the
+	// readiness endpoint (/health/readiness) to report the proxy as ready, e.g. ReadinessRequiresTarget can be
+	// disabled so that the proxy keeps receiving traffic while TARGET is down. The proxy must also be accepting
+	// client connections.
+	ReadinessRequiresOrigin bool `default:"true" split_words:"true"`
+	ReadinessRequiresTarget bool `default:"true" split_words:"true"`
+
+	MetricsOriginLatencyBucketsMs    string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
+	MetricsTargetLatencyBucketsMs    string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
+	MetricsAsyncReadLatencyBucketsMs string `default:"1, 4, 7, 10, 25, 40, 60, 80, 100, 150, 250, 500, 1000, 2500, 5000, 10000, 15000" split_words:"true"`
+
+	// MetricsHistogramBucketsMsOverrides sets the buckets of specific histograms, e.g.
+	// "proxy_request_duration_seconds=exponential:1,2,15;async_request_duration_seconds=linear:10,10,20".
+	// Buckets can be a list of values or an exponential/linear strategy, same as the *LatencyBucketsMs settings.
+	MetricsHistogramBucketsMsOverrides string `split_words:"true"`
+
+	// MetricsNativeHistogramsEnabled exposes Prometheus native histograms alongside the classic buckets.
+	MetricsNativeHistogramsEnabled     bool    `default:"false" split_words:"true"`
+	MetricsNativeHistogramBucketFactor float64 `default:"1.1" split_words:"true"`
+
+	// MetricsTableLevelEnabled tracks the requests and their latency by keyspace and table, the tables after the
+	// first MetricsTableLevelMaxTables are tracked under the "other" keyspace and table to cap the cardinality.
+	MetricsTableLevelEnabled   bool `default:"false" split_words:"true"`
+	MetricsTableLevelMaxTables int  `default:"100" split_words:"true"`
+
+	// MigrationProgressEnabled estimates the progress of the migration from the requests of the last
+	// MigrationProgressWindowMs (fraction of the reads answered by ORIGIN, dual write failure rate, read comparison
+	// mismatch rate and pending writes of the write journal), the estimation is updated every
+	// MigrationProgressSampleIntervalMs and is exposed by the proxy_migration_* metrics and the admin API.
+	MigrationProgressEnabled          bool `default:"false" split_words:"true"`
+	MigrationProgressSampleIntervalMs int  `default:"10000" split_words:"true"`
+	MigrationProgressWindowMs         int  `default:"300000" split_words:"true"`
+
+	// Tracing bucket
+
+	// TracingOtlpEndpoint enables the distributed tracing of the requests, the spans are exported to this OTLP/HTTP
+	// endpoint (e.g. http://otel-collector:4318, /v1/traces is appended if the URL has no path). TracingSampler is
+	// one of the standard OpenTelemetry samplers and TracingSamplerRatio is the ratio of the TRACEIDRATIO samplers.
+	TracingOtlpEndpoint string  `split_words:"true"`
+	TracingSampler      string  `default:"PARENTBASED_ALWAYS_ON" split_words:"true"`
+	TracingSamplerRatio float64 `default:"1" split_words:"true"`
+	TracingServiceName  string  `default:"zdm-proxy" split_words:"true"`
+}
+
+func (c *MetricsConfig) Validate() error {
+	_, err := c.ParseMetricsBackend()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseMetricsStatsdAddress()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseMetricsStatsdFlushInterval()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseOriginBuckets()
+	if err != nil {
+		return fmt.Errorf("could not parse origin buckets: %v", err)
+	}
+
+	_, err = c.ParseTargetBuckets()
+	if err != nil {
+		return fmt.Errorf("could not parse target buckets: %v", err)
+	}
+
+	_, err = c.ParseAsyncBuckets()
+	if err != nil {
+		return fmt.Errorf("could not parse async buckets: %v", err)
+	}
+
+	_, err = c.ParseHistogramBucketsOverrides()
+	if err != nil {
+		return fmt.Errorf("could not parse histogram buckets overrides: %v", err)
+	}
+
+	_, err = c.ParseNativeHistogramBucketFactor()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseMetricsTableLevelMaxTables()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseMigrationProgressConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTracingConfig()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const (
+	MetricsBackendPrometheus = "PROMETHEUS"
+	MetricsBackendStatsd     = "STATSD"
+)
+
+func (c *MetricsConfig) ParseMetricsBackend() (common.MetricsBackend, error) {
+	switch strings.ToUpper(strings.TrimSpace(c.MetricsBackend)) {
+	case MetricsBackendPrometheus:
+		return common.MetricsBackendPrometheus, nil
+	case MetricsBackendStatsd:
+		return common.MetricsBackendStatsd, nil
+	default:
+		return common.MetricsBackendUndefined, fmt.Errorf(
+			"invalid value for ZDM_METRICS_BACKEND; possible values are: %v and %v",
+			MetricsBackendPrometheus, MetricsBackendStatsd)
+	}
+}
+
+// ParseMetricsStatsdAddress returns the host:port of the StatsD server or an empty string if the metrics backend
+// is not STATSD.
+func (c *MetricsConfig) ParseMetricsStatsdAddress() (string, error) {
+	backend, err := c.ParseMetricsBackend()
+	if err != nil || backend != common.MetricsBackendStatsd {
+		return "", err
+	}
+
+	address := strings.TrimSpace(c.MetricsStatsdAddress)
+	_, port, err := net.SplitHostPort(address)
+	if err != nil || port == "" {
+		return "", fmt.Errorf("invalid value for ZDM_METRICS_STATSD_ADDRESS (%v); it must be <host>:<port>",
+			c.MetricsStatsdAddress)
+	}
+
+	return address, nil
+}
+
+// ParseMetricsStatsdFlushInterval returns the interval of the StatsD flushes or 0 if the metrics backend is not
+// STATSD.
+func (c *MetricsConfig) ParseMetricsStatsdFlushInterval() (time.Duration, error) {
+	backend, err := c.ParseMetricsBackend()
+	if err != nil || backend != common.MetricsBackendStatsd {
+		return 0, err
+	}
+
+	if c.MetricsStatsdFlushIntervalMs <= 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_METRICS_STATSD_FLUSH_INTERVAL_MS (%v); it must be greater than 0",
+			c.MetricsStatsdFlushIntervalMs)
+	}
+
+	return time.Duration(c.MetricsStatsdFlushIntervalMs) * time.Millisecond, nil
+}
+
+func (c *MetricsConfig) ParseOriginBuckets() ([]float64, error) {
+	return c.parseBuckets(c.MetricsOriginLatencyBucketsMs)
+}
+
+func (c *MetricsConfig) ParseTargetBuckets() ([]float64, error) {
+	return c.parseBuckets(c.MetricsTargetLatencyBucketsMs)
+}
+
+func (c *MetricsConfig) ParseAsyncBuckets() ([]float64, error) {
+	return c.parseBuckets(c.MetricsAsyncReadLatencyBucketsMs)
+}
+
+// ParseHistogramBucketsOverrides returns the buckets (in seconds) of the histograms that are set
+// in ZDM_METRICS_HISTOGRAM_BUCKETS_MS_OVERRIDES keyed on the metric name.
+func (c *MetricsConfig) ParseHistogramBucketsOverrides() (map[string][]float64, error) {
+	overrides := make(map[string][]float64)
+	if isNotDefined(c.MetricsHistogramBucketsMsOverrides) {
+		return overrides, nil
+	}
+
+	for _, entry := range strings.Split(c.MetricsHistogramBucketsMsOverrides, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		nameAndBuckets := strings.SplitN(entry, "=", 2)
+		if len(nameAndBuckets) != 2 || strings.TrimSpace(nameAndBuckets[0]) == "" {
+			return nil, fmt.Errorf("invalid histogram buckets override %v: expected <metric_name>=<buckets>", entry)
+		}
+		name := strings.TrimSpace(nameAndBuckets[0])
+		if _, exists := overrides[name]; exists {
+			return nil, fmt.Errorf("duplicate histogram buckets override for %v", name)
+		}
+		buckets, err := c.parseBuckets(nameAndBuckets[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid histogram buckets override for %v: %w", name, err)
+		}
+		overrides[name] = buckets
+	}
+
+	return overrides, nil
+}
+
+// ParseNativeHistogramBucketFactor returns the bucket factor of the native histograms
+// or 0 if native histograms are disabled.
+func (c *MetricsConfig) ParseNativeHistogramBucketFactor() (float64, error) {
+	if !c.MetricsNativeHistogramsEnabled {
+		return 0, nil
+	}
+
+	if c.MetricsNativeHistogramBucketFactor <= 1 {
+		return 0, fmt.Errorf("invalid value for ZDM_METRICS_NATIVE_HISTOGRAM_BUCKET_FACTOR (%v); it must be greater than 1",
+			c.MetricsNativeHistogramBucketFactor)
+	}
+
+	return c.MetricsNativeHistogramBucketFactor, nil
+}
+
+// ParseMetricsTableLevelMaxTables returns the maximum number of tables that are tracked in the table level metrics
+// or 0 if the table level metrics are disabled.
+func (c *MetricsConfig) ParseMetricsTableLevelMaxTables() (int, error) {
+	if !c.MetricsTableLevelEnabled {
+		return 0, nil
+	}
+
+	if c.MetricsTableLevelMaxTables <= 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_METRICS_TABLE_LEVEL_MAX_TABLES (%v); it must be greater than 0",
+			c.MetricsTableLevelMaxTables)
+	}
+
+	return c.MetricsTableLevelMaxTables, nil
+}
+
+func (c *MetricsConfig) ParseMigrationProgressConfig() (*common.MigrationProgressConfig, error) {
+	if !c.MigrationProgressEnabled {
+		return &common.MigrationProgressConfig{}, nil
+	}
+
+	if c.MigrationProgressSampleIntervalMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_MIGRATION_PROGRESS_SAMPLE_INTERVAL_MS (%v); it must be greater than 0",
+			c.MigrationProgressSampleIntervalMs)
+	}
+
+	if c.MigrationProgressWindowMs < c.MigrationProgressSampleIntervalMs {
+		return nil, fmt.Errorf("invalid value for ZDM_MIGRATION_PROGRESS_WINDOW_MS (%v); it must be at least "+
+			"ZDM_MIGRATION_PROGRESS_SAMPLE_INTERVAL_MS (%v)",
+			c.MigrationProgressWindowMs, c.MigrationProgressSampleIntervalMs)
+	}
+
+	return &common.MigrationProgressConfig{
+		Enabled:          true,
+		SampleIntervalMs: c.MigrationProgressSampleIntervalMs,
+		WindowMs:         c.MigrationProgressWindowMs,
+	}, nil
+}
+
+const (
+	TracingSamplerAlwaysOn                = "ALWAYS_ON"
+	TracingSamplerAlwaysOff               = "ALWAYS_OFF"
+	TracingSamplerTraceIdRatio            = "TRACEIDRATIO"
+	TracingSamplerParentBasedAlwaysOn     = "PARENTBASED_ALWAYS_ON"
+	TracingSamplerParentBasedAlwaysOff    = "PARENTBASED_ALWAYS_OFF"
+	TracingSamplerParentBasedTraceIdRatio = "PARENTBASED_TRACEIDRATIO"
+)
+
+func (c *MetricsConfig) ParseTracingConfig() (*common.TracingConfig, error) {
+	endpoint := strings.TrimSpace(c.TracingOtlpEndpoint)
+	if endpoint == "" {
+		return &common.TracingConfig{}, nil
+	}
+
+	parsedEndpoint, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for ZDM_TRACING_OTLP_ENDPOINT (%v); %w", c.TracingOtlpEndpoint, err)
+	}
+	if (parsedEndpoint.Scheme != "http" && parsedEndpoint.Scheme != "https") || parsedEndpoint.Host == "" {
+		return nil, fmt.Errorf("invalid value for ZDM_TRACING_OTLP_ENDPOINT (%v); it must be an http or https URL",
+			c.TracingOtlpEndpoint)
+	}
+	if parsedEndpoint.Path == "" || parsedEndpoint.Path == "/" {
+		parsedEndpoint.Path = "/v1/traces"
+	}
+
+	var sampler common.TracingSampler
+	switch strings.ToUpper(strings.TrimSpace(c.TracingSampler)) {
+	case TracingSamplerAlwaysOn:
+		sampler = common.TracingSamplerAlwaysOn
+	case TracingSamplerAlwaysOff:
+		sampler = common.TracingSamplerAlwaysOff
+	case TracingSamplerTraceIdRatio:
+		sampler = common.TracingSamplerTraceIdRatio
+	case TracingSamplerParentBasedAlwaysOn:
+		sampler = common.TracingSamplerParentBasedAlwaysOn
+	case TracingSamplerParentBasedAlwaysOff:
+		sampler = common.TracingSamplerParentBasedAlwaysOff
+	case TracingSamplerParentBasedTraceIdRatio:
+		sampler = common.TracingSamplerParentBasedTraceIdRatio
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_TRACING_SAMPLER; possible values are: %v, %v, %v, %v, %v and %v",
+			TracingSamplerAlwaysOn, TracingSamplerAlwaysOff, TracingSamplerTraceIdRatio, TracingSamplerParentBasedAlwaysOn,
+			TracingSamplerParentBasedAlwaysOff, TracingSamplerParentBasedTraceIdRatio)
+	}
+
+	if c.TracingSamplerRatio < 0 || c.TracingSamplerRatio > 1 {
+		return nil, fmt.Errorf("invalid value for ZDM_TRACING_SAMPLER_RATIO (%v); it must be between 0 and 1",
+			c.TracingSamplerRatio)
+	}
+
+	serviceName := strings.TrimSpace(c.TracingServiceName)
+	if serviceName == "" {
+		return nil, fmt.Errorf("invalid value for ZDM_TRACING_SERVICE_NAME (%v); it must not be empty",
+			c.TracingServiceName)
+	}
+
+	return &common.TracingConfig{
+		Enabled:      true,
+		Endpoint:     parsedEndpoint.String(),
+		Sampler:      sampler,
+		SamplerRatio: c.TracingSamplerRatio,
+		ServiceName:  serviceName,
+	}, nil
+}
+
+const (
+	exponentialBucketsPrefix = "exponential:"
+	linearBucketsPrefix      = "linear:"
+)
+
+// parseBuckets parses a list of buckets in milliseconds (e.g. "1, 5, 10") or a bucket strategy,
+// "exponential:<start>,<factor>,<count>" or "linear:<start>,<width>,<count>", and returns the buckets in seconds.
+func (c *MetricsConfig) parseBuckets(bucketsConfigStr string) ([]float64, error) {
+	trimmedConfigStr := strings.TrimSpace(bucketsConfigStr)
+	if strings.HasPrefix(trimmedConfigStr, exponentialBucketsPrefix) {
+		return parseBucketsStrategy(bucketsConfigStr, strings.TrimPrefix(trimmedConfigStr, exponentialBucketsPrefix), true)
+	} else if strings.HasPrefix(trimmedConfigStr, linearBucketsPrefix) {
+		return parseBucketsStrategy(bucketsConfigStr, strings.TrimPrefix(trimmedConfigStr, linearBucketsPrefix), false)
+	}
+
+	values, err := parseBucketValues(bucketsConfigStr, trimmedConfigStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var bucketsArr []float64
+	for _, bucket := range values {
+		bucketsArr = append(bucketsArr, bucket/1000) // convert ms to seconds
+	}
+
+	return bucketsArr, nil
+}
+
+func parseBucketsStrategy(bucketsConfigStr string, parametersStr string, exponential bool) ([]float64, error) {
+	parameters, err := parseBucketValues(bucketsConfigStr, parametersStr)
+	if err != nil {
+		return nil, err
+	}
+	if len(parameters) != 3 {
+		return nil, fmt.Errorf(
+			"unable to parse buckets from %v: expected 3 parameters (start, factor or width, count) but got %d",
+			bucketsConfigStr, len(parameters))
+	}
+
+	start, step := parameters[0], parameters[1]
+	count := int(parameters[2])
+	if float64(count) != parameters[2] || count < 1 {
+		return nil, fmt.Errorf("unable to parse buckets from %v: count must be a positive integer", bucketsConfigStr)
+	}
+
+	bucketsArr := make([]float64, 0, count)
+	if exponential {
+		if start <= 0 || step <= 1 {
+			return nil, fmt.Errorf(
+				"unable to parse buckets from %v: start must be positive and factor must be greater than 1", bucketsConfigStr)
+		}
+		for i := 0; i < count; i++ {
+			bucketsArr = append(bucketsArr, start/1000) // convert ms to seconds
+			start *= step
+		}
+	} else {
+		if step <= 0 {
+			return nil, fmt.Errorf("unable to parse buckets from %v: width must be positive", bucketsConfigStr)
+		}
+		for i := 0; i < count; i++ {
+			bucketsArr = append(bucketsArr, (start+float64(i)*step)/1000) // convert ms to seconds
+		}
+	}
+
+	return bucketsArr, nil
+}
+
+func parseBucketValues(bucketsConfigStr string, valuesStr string) ([]float64, error) {
+	var values []float64
+	bucketsStrArr := strings.Split(valuesStr, ",")
+	if len(bucketsStrArr) == 0 {
+		return nil, fmt.Errorf("unable to parse buckets from %v: at least one bucket is required", bucketsConfigStr)
+	}
+
+	for _, bucketStr := range bucketsStrArr {
+		value, err := strconv.ParseFloat(strings.TrimSpace(bucketStr), 64)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to parse buckets from %v: could not convert %v to float",
+				bucketsConfigStr,
+				bucketStr)
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"reflect"
+)
+
+// RedactedValues returns the value of every setting keyed by its environment variable name. The secrets, i.e. the
+// settings that are never logged (passwords) and the settings that were provided encrypted, are replaced with *****
+// unless they are empty.
+func (c *Config) RedactedValues() map[string]interface{} {
+	values := make(map[string]interface{})
+	c.addRedactedValues(reflect.ValueOf(c).Elem(), values)
+	return values
+}
+
+func (c *Config) addRedactedValues(structValue reflect.Value, values map[string]interface{}) {
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := structValue.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			c.addRedactedValues(fieldValue, values)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		secret := field.Tag.Get("json") == "-" || c.decryptedFields[field.Name]
+		if secret && !fieldValue.IsZero() {
+			values[envVarName(field)] = decryptedValuePlaceholder
+		} else {
+			values[envVarName(field)] = fieldValue.Interface()
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ReloadableSettings are the environment variable names of the settings that can be changed at runtime by changing
+// the config file, see Reload.
+var ReloadableSettings = []string{
+	"ZDM_LOG_LEVEL",
+	"ZDM_PRIMARY_CLUSTER",
+	"ZDM_READ_MAX_WORKERS",
+	"ZDM_WRITE_MAX_WORKERS",
+	"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS",
+	"ZDM_SLOW_QUERY_LOG_QUERY_FORMAT",
+	"ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO",
+	"ZDM_SLOW_QUERY_LOG_MAX_PER_SECOND",
+	"ZDM_ORIGIN_MAX_REQUESTS_PER_SECOND",
+	"ZDM_TARGET_MAX_REQUESTS_PER_SECOND",
+}
+
+// endpointSettings are the listener addresses and the cluster endpoints, their changes are always rejected because
+// the connections of the proxy would have to be opened again.
+var endpointSettings = []string{
+	"ZDM_PROXY_LISTEN_ADDRESS",
+	"ZDM_PROXY_LISTEN_PORT",
+	"ZDM_ORIGIN_CONTACT_POINTS",
+	"ZDM_ORIGIN_PORT",
+	"ZDM_ORIGIN_SECURE_CONNECT_BUNDLE_PATH",
+	"ZDM_ORIGIN_LOCAL_DATACENTER",
+	"ZDM_TARGET_CONTACT_POINTS",
+	"ZDM_TARGET_PORT",
+	"ZDM_TARGET_SECURE_CONNECT_BUNDLE_PATH",
+	"ZDM_TARGET_LOCAL_DATACENTER",
+}
+
+// ConfigChanges are the environment variable names of the settings that changed when the config file was reloaded.
+type ConfigChanges struct {
+	// Reloadable are the changed settings that can be applied at runtime.
+	Reloadable []string
+	// Endpoints are the changed listener addresses and cluster endpoints.
+	Endpoints []string
+	// RestartRequired are the other changed settings, they are only applied when the proxy restarts.
+	RestartRequired []string
+}
+
+func (recv *ConfigChanges) IsEmpty() bool {
+	return len(recv.Reloadable) == 0 && len(recv.Endpoints) == 0 && len(recv.RestartRequired) == 0
+}
+
+func (c *Config) ParseConfigFilePollInterval() (int, error) {
+	if c.ConfigFilePollIntervalMs < 0 {
+		return 0, fmt.Errorf("invalid value for ZDM_CONFIG_FILE_POLL_INTERVAL_MS (%v); "+
+			"it must be 0 (disabled) or a positive number", c.ConfigFilePollIntervalMs)
+	}
+	return c.ConfigFilePollIntervalMs, nil
+}
+
+// Reload parses the config file and the environment variables again and returns the new configuration with the
+// settings that changed. The configuration is not modified, see WithSettings.
+func (c *Config) Reload() (*Config, *ConfigChanges, error) {
+	if c.ConfigFile == "" {
+		return nil, nil, fmt.Errorf("there is no config file to reload (ZDM_CONFIG_FILE or -config)")
+	}
+	reloaded, err := New().ParseConfigFileAndEnvVars(c.ConfigFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reloaded, c.diff(reloaded), nil
+}
+
+// diff returns the settings whose value is different in other. The credentials that are read from files are not
+// compared because their changes are already applied at runtime (ZDM_CREDENTIAL_FILES_POLL_INTERVAL_MS).
+func (c *Config) diff(other *Config) *ConfigChanges {
+	fields := make(map[string]reflect.Value)
+	collectSettingFields(reflect.ValueOf(c).Elem(), fields)
+	otherFields := make(map[string]reflect.Value)
+	collectSettingFields(reflect.ValueOf(other).Elem(), otherFields)
+
+	ignored := map[string]bool{configFileEnvVarName: true}
+	for _, file := range c.getCredentialFiles() {
+		if file.path != "" {
+			ignored[file.envVarName] = true
+		}
+	}
+
+	changes := &ConfigChanges{}
+	for envVar, fieldValue := range fields {
+		if ignored[envVar] || reflect.DeepEqual(fieldValue.Interface(), otherFields[envVar].Interface()) {
+			continue
+		}
+		switch {
+		case containsSetting(ReloadableSettings, envVar):
+			changes.Reloadable = append(changes.Reloadable, envVar)
+		case containsSetting(endpointSettings, envVar):
+			changes.Endpoints = append(changes.Endpoints, envVar)
+		default:
+			changes.RestartRequired = append(changes.RestartRequired, envVar)
+		}
+	}
+	sort.Strings(changes.Reloadable)
+	sort.Strings(changes.Endpoints)
+	sort.Strings(changes.RestartRequired)
+	return changes
+}
+
+// WithSettings returns a copy of the configuration with the value of the provided settings (environment variable
+// names) of other.
+func (c *Config) WithSettings(other *Config, settings []string) *Config {
+	conf := *c
+	fields := make(map[string]reflect.Value)
+	collectSettingFields(reflect.ValueOf(&conf).Elem(), fields)
+	otherFields := make(map[string]reflect.Value)
+	collectSettingFields(reflect.ValueOf(other).Elem(), otherFields)
+	for _, setting := range settings {
+		fields[setting].Set(otherFields[setting])
+	}
+	return &conf
+}
+
+func containsSetting(settings []string, envVar string) bool {
+	for _, setting := range settings {
+		if setting == envVar {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,993 @@
+package config
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// RoutingConfig holds the settings that decide to which cluster(s) each request is forwarded.
+type RoutingConfig struct {
+	PrimaryCluster          string `default:"ORIGIN" split_words:"true"`
+	ReadMode                string `default:"PRIMARY_ONLY" split_words:"true"`
+	ReplaceCqlFunctions     bool   `default:"false" split_words:"true"`
+	AsyncHandshakeTimeoutMs int    `default:"4000" split_words:"true"`
+
+	// SystemQueriesMode isn't supported and may change at any time.
+	SystemQueriesMode string `default:"ORIGIN" split_words:"true"`
+
+	// TokenAwareRoutingEnabled forwards each QUERY and EXECUTE request to the node of the local datacenter that owns
+	// the token of its partition key, through the pooled connections of that node, instead of the node that the
+	// client connection was assigned to. It requires ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE and only applies to
+	// clusters that use the Murmur3 partitioner. Requests whose partition key can't be computed are not rerouted.
+	TokenAwareRoutingEnabled bool `default:"false" split_words:"true"`
+
+	// TargetWriteFilterRules drops writes from TARGET based on the values that they write, for partial migrations, e.g.
+	// "ks.users.tenant_id IN ('tenant1', 'tenant2'); ks.events.created_at < 2020-01-01T00:00:00Z".
+	// Each rule has the format <keyspace>.<table>.<column> <operator> <value(s)>, see ParseTargetWriteFilterRules.
+	TargetWriteFilterRules string `split_words:"true"`
+
+	// TargetWriteSamplingPercentage is the percentage of writes that are forwarded to TARGET, the other writes are
+	// only forwarded to ORIGIN. This allows observing TARGET under a fraction of the production write load
+	// before enabling full dual writes. It can only be lower than 100 if ORIGIN is the primary cluster.
+	TargetWriteSamplingPercentage float64 `default:"100" split_words:"true"`
+
+	// TargetWriteSamplingIgnoreTargetFailures returns the ORIGIN response to the client when a sampled write
+	// fails only on TARGET, i.e., the TARGET response is only tracked in the metrics.
+	// This only applies if TargetWriteSamplingPercentage is lower than 100.
+	TargetWriteSamplingIgnoreTargetFailures bool `default:"false" split_words:"true"`
+
+	// DualWriteMode is either SYNC (the client response waits for the write on both clusters) or ASYNC (the client
+	// response is sent as soon as the primary cluster answers and the write is forwarded to the secondary cluster
+	// in the background by the async connector).
+	DualWriteMode string `default:"SYNC" split_words:"true"`
+
+	// DualWriteAsyncTimeoutMs is the timeout of the writes that are forwarded to the secondary cluster in the
+	// background and DualWriteAsyncMaxRetries is the number of times that they are retried if they time out or fail
+	// with an error that doesn't depend on the request itself (e.g. WRITE_TIMEOUT or OVERLOADED but not SYNTAX_ERROR).
+	// Only idempotent writes should be used with retries, like with the retry policies of the drivers.
+	// These only apply if DualWriteMode is ASYNC.
+	DualWriteAsyncTimeoutMs  int `default:"10000" split_words:"true"`
+	DualWriteAsyncMaxRetries int `default:"3" split_words:"true"`
+
+	// LwtPolicy decides how lightweight transactions (e.g. INSERT ... IF NOT EXISTS) are handled because their
+	// [applied] result can differ between the clusters: DUAL_WRITE (forwarded to both clusters, the response of the
+	// primary cluster is always returned to the client even if the secondary cluster fails) or PRIMARY_ONLY (only
+	// forwarded to the primary cluster).
+	LwtPolicy string `default:"DUAL_WRITE" split_words:"true"`
+
+	// CounterWritesPolicy decides how counter updates are handled because they are not idempotent, a retry of
+	// a dual write can count the same update twice on one cluster: DUAL_WRITE (forwarded to both clusters),
+	// PRIMARY_ONLY (only forwarded to the primary cluster) or REJECT (an error is returned to the client without
+	// forwarding the update to any cluster).
+	CounterWritesPolicy string `default:"DUAL_WRITE" split_words:"true"`
+
+	// CircuitBreakerDegradeToPrimaryOnly forwards the writes to the primary cluster only while the circuit breaker
+	// of the secondary cluster is open (see ZDM_ORIGIN_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT and
+	// ZDM_TARGET_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT) instead of returning an error to the client. The writes that
+	// are not forwarded to the secondary cluster in the meantime have to be migrated again.
+	CircuitBreakerDegradeToPrimaryOnly bool `default:"false" split_words:"true"`
+
+	// SecondaryWriteFailureMode decides what is returned to the client when a write that was forwarded to both
+	// clusters succeeds on the primary cluster but fails on the secondary cluster: RETURN_ERROR (the error of the
+	// secondary cluster), LOG_AND_CONTINUE (the response of the primary cluster, the failure is logged and counted by
+	// the proxy_secondary_write_failures_total metric) or QUEUE_FOR_REPLAY (like LOG_AND_CONTINUE but the write is
+	// also sent again to the secondary cluster in the background after SecondaryWriteReplayDelayMs, with the retries
+	// and the timeout of ZDM_DUAL_WRITE_ASYNC_MAX_RETRIES and ZDM_DUAL_WRITE_ASYNC_TIMEOUT_MS).
+	SecondaryWriteFailureMode   string `default:"RETURN_ERROR" split_words:"true"`
+	SecondaryWriteReplayDelayMs int    `default:"1000" split_words:"true"`
+
+	// SecondaryWriteJournalType stores the writes that are queued for replay (SecondaryWriteFailureMode
+	// QUEUE_FOR_REPLAY) in a journal of the proxy instead of the client connection: NONE (no journal), MEMORY or FILE
+	// (a file in SecondaryWriteJournalDirectory, each proxy instance needs its own directory). The writes of the
+	// journal are replayed in order while the circuit breaker of the secondary cluster is closed, at most
+	// SecondaryWriteJournalReplayRate writes per second, and new writes are dropped while the pending writes take
+	// SecondaryWriteJournalMaxSizeBytes.
+	SecondaryWriteJournalType         string `default:"NONE" split_words:"true"`
+	SecondaryWriteJournalDirectory    string `split_words:"true"`
+	SecondaryWriteJournalMaxSizeBytes int    `default:"104857600" split_words:"true"`
+	SecondaryWriteJournalReplayRate   int    `default:"100" split_words:"true"`
+
+	// ReadMirroringEnabled forwards the reads to the secondary cluster in the background (like ReadMode
+	// DUAL_ASYNC_ON_SECONDARY) and compares the row count and a checksum of the rows of the secondary response
+	// with the primary response that is returned to the client. Mismatches are logged with the digest of the query
+	// and tracked by the proxy_read_comparison_mismatches_total metric.
+	ReadMirroringEnabled bool `default:"false" split_words:"true"`
+
+	// WriteComparisonSamplePercentage is the percentage of the dual writes whose ORIGIN and TARGET responses are
+	// compared, i.e., the kind of the responses (e.g. success and WriteTimeout) and the [applied] column of the
+	// lightweight transactions. Mismatches are logged with the digest of the query and tracked by the
+	// proxy_write_comparison_mismatches_total metric. The writes that are forwarded to the secondary cluster in the
+	// background (DualWriteMode ASYNC) are not compared.
+	WriteComparisonSamplePercentage float64 `default:"0" split_words:"true"`
+
+	// TargetDdlUnsupportedFeatures is a comma separated list of schema features that TARGET doesn't support,
+	// see ParseTargetDdlUnsupportedFeatures. Schema changes that use them are handled according to
+	// TargetDdlCompatibilityMode instead of letting TARGET return an error in the middle of the migration.
+	TargetDdlUnsupportedFeatures string `split_words:"true"`
+
+	// TargetDdlUnsupportedCompactionStrategies is a comma separated list of compaction strategy classes that TARGET
+	// doesn't support, e.g. "DateTieredCompactionStrategy". Fully qualified and short class names are accepted.
+	TargetDdlUnsupportedCompactionStrategies string `split_words:"true"`
+
+	// TargetDdlCompatibilityMode is either WARN (log a warning and forward the schema change) or REJECT (return
+	// an error to the client without forwarding the schema change to any cluster).
+	TargetDdlCompatibilityMode string `default:"WARN" split_words:"true"`
+
+	// MaterializedViewStatementsPolicy decides how CREATE, ALTER and DROP MATERIALIZED VIEW statements are handled:
+	// BOTH (forwarded to both clusters), ORIGIN_ONLY (only forwarded to ORIGIN) or REJECT (an error is returned to
+	// the client without forwarding the statement to any cluster).
+	MaterializedViewStatementsPolicy string `default:"BOTH" split_words:"true"`
+
+	// IndexStatementsPolicy decides how CREATE INDEX statements are handled, see MaterializedViewStatementsPolicy.
+	IndexStatementsPolicy string `default:"BOTH" split_words:"true"`
+
+	// CustomIndexStatementsPolicy decides how CREATE CUSTOM INDEX statements and CREATE INDEX statements with
+	// a USING clause (e.g. SASI or SAI indexes) are handled, see MaterializedViewStatementsPolicy.
+	CustomIndexStatementsPolicy string `default:"BOTH" split_words:"true"`
+
+	// DestructiveStatementsConfirmationEnabled requires TRUNCATE and DROP statements to be confirmed through the
+	// admin API before they are forwarded, either with a one-time token or by unlocking them for a time window.
+	DestructiveStatementsConfirmationEnabled bool `default:"false" split_words:"true"`
+
+	DestructiveStatementsConfirmationTokenTtlMs int `default:"300000" split_words:"true"`
+	DestructiveStatementsMaxUnlockDurationMs    int `default:"3600000" split_words:"true"`
+
+	// ApplicationReadRoutingRules forwards the reads of specific applications to a cluster based on the
+	// APPLICATION_NAME option that the driver sends in the STARTUP request, e.g.
+	// "analytics-service ORIGIN; reporting-service ks.events TARGET". See ParseApplicationReadRoutingRules.
+	ApplicationReadRoutingRules string `split_words:"true"`
+
+	// TableRoutingRules pins the statements on specific tables to a cluster during a phased migration, e.g.
+	// "system_auth.roles TARGET; ks.legacy_events ORIGIN". Reads and writes of these tables are only forwarded to
+	// that cluster and the rules take precedence over ZDM_SYSTEM_QUERIES_MODE and over the interception of the
+	// system.local and system.peers queries. See ParseTableRoutingRules.
+	TableRoutingRules string `split_words:"true"`
+
+	// StatementFilterRules rejects the statements that match a rule or forwards them to a single cluster, e.g.
+	// "TRUNCATE REJECT; DROP REJECT; DELETE ks.events ORIGIN; /ALLOW\s+FILTERING/ REJECT" blocks TRUNCATE and DROP
+	// statements during the migration window. The rules take precedence over every other routing setting except
+	// ZDM_ADMIN_KEYSPACE_ROLES. See ParseStatementFilterRules.
+	StatementFilterRules string `split_words:"true"`
+
+	// PagingStatePolicy decides how the paging states of the reads are handled when the reads are forwarded to
+	// another cluster in the middle of a paged query (e.g. after a ZDM_PRIMARY_CLUSTER reload or a change of the
+	// read_routing setting of the zdm_admin keyspace), a paging state of one cluster can't be used by the other
+	// cluster: NONE (the paging states are forwarded untouched), ORIGINATING_CLUSTER (the paging states are tagged with
+	// the cluster that returned them and the next pages are read from that cluster) or REJECT (the paging states are
+	// tagged and an error is returned to the client if a paging state belongs to the other cluster, the query has to
+	// be restarted without a paging state).
+	PagingStatePolicy string `default:"NONE" split_words:"true"`
+}
+
+func (c *RoutingConfig) Validate() error {
+	_, err := c.ParsePrimaryCluster()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseSystemQueriesMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseReadMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseDualWriteMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseLwtPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseCounterWritesPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParsePagingStatePolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseSecondaryWriteFailureMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseSecondaryWriteJournalConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetWriteFilterRules()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetWriteSamplingPercentage()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseWriteComparisonSamplePercentage()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetDdlUnsupportedFeatures()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTargetDdlCompatibilityMode()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseMaterializedViewStatementsPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseIndexStatementsPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseCustomIndexStatementsPolicy()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseDestructiveStatementsConfirmationConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseApplicationReadRoutingRules()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseTableRoutingRules()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ParseStatementFilterRules()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const (
+	SystemQueriesModeOrigin = "ORIGIN"
+	SystemQueriesModeTarget = "TARGET"
+)
+
+func (c *RoutingConfig) ParseSystemQueriesMode() (common.SystemQueriesMode, error) {
+	switch strings.ToUpper(c.SystemQueriesMode) {
+	case SystemQueriesModeTarget:
+		return common.SystemQueriesModeTarget, nil
+	case SystemQueriesModeOrigin:
+		return common.SystemQueriesModeOrigin, nil
+	default:
+		return common.SystemQueriesModeUndefined, fmt.Errorf("invalid value for ZDM_SYSTEM_QUERIES_MODE; possible values are: %v and %v",
+			SystemQueriesModeTarget, SystemQueriesModeOrigin)
+	}
+}
+
+const (
+	PrimaryClusterOrigin = "ORIGIN"
+	PrimaryClusterTarget = "TARGET"
+)
+
+func (c *RoutingConfig) ParsePrimaryCluster() (common.ClusterType, error) {
+	switch strings.ToUpper(c.PrimaryCluster) {
+	case PrimaryClusterOrigin:
+		return common.ClusterTypeOrigin, nil
+	case PrimaryClusterTarget:
+		return common.ClusterTypeTarget, nil
+	default:
+		return common.ClusterTypeNone, fmt.Errorf("invalid value for ZDM_PRIMARY_CLUSTER; possible values are: %v and %v",
+			PrimaryClusterOrigin, PrimaryClusterTarget)
+	}
+}
+
+const (
+	ReadModePrimaryOnly          = "PRIMARY_ONLY"
+	ReadModeDualAsyncOnSecondary = "DUAL_ASYNC_ON_SECONDARY"
+)
+
+func (c *RoutingConfig) ParseReadMode() (common.ReadMode, error) {
+	switch strings.ToUpper(c.ReadMode) {
+	case ReadModePrimaryOnly:
+		return common.ReadModePrimaryOnly, nil
+	case ReadModeDualAsyncOnSecondary:
+		return common.ReadModeDualAsyncOnSecondary, nil
+	default:
+		return common.ReadModeUndefined, fmt.Errorf("invalid value for ZDM_READ_MODE; possible values are: %v and %v",
+			ReadModePrimaryOnly, ReadModeDualAsyncOnSecondary)
+	}
+}
+
+const (
+	DualWriteModeSync  = "SYNC"
+	DualWriteModeAsync = "ASYNC"
+)
+
+func (c *RoutingConfig) ParseDualWriteMode() (common.DualWriteMode, error) {
+	var dualWriteMode common.DualWriteMode
+	switch strings.ToUpper(c.DualWriteMode) {
+	case DualWriteModeSync:
+		return common.DualWriteModeSync, nil
+	case DualWriteModeAsync:
+		dualWriteMode = common.DualWriteModeAsync
+	default:
+		return common.DualWriteModeUndefined, fmt.Errorf("invalid value for ZDM_DUAL_WRITE_MODE; possible values are: %v and %v",
+			DualWriteModeSync, DualWriteModeAsync)
+	}
+
+	if c.DualWriteAsyncTimeoutMs <= 0 {
+		return common.DualWriteModeUndefined, fmt.Errorf("invalid value for ZDM_DUAL_WRITE_ASYNC_TIMEOUT_MS (%v); "+
+			"it must be a positive number", c.DualWriteAsyncTimeoutMs)
+	}
+	if c.DualWriteAsyncMaxRetries < 0 {
+		return common.DualWriteModeUndefined, fmt.Errorf("invalid value for ZDM_DUAL_WRITE_ASYNC_MAX_RETRIES (%v); "+
+			"it must be 0 (writes are not retried) or a positive number", c.DualWriteAsyncMaxRetries)
+	}
+	return dualWriteMode, nil
+}
+
+const (
+	LwtPolicyDualWrite   = "DUAL_WRITE"
+	LwtPolicyPrimaryOnly = "PRIMARY_ONLY"
+)
+
+func (c *RoutingConfig) ParseLwtPolicy() (common.LwtPolicy, error) {
+	switch strings.ToUpper(c.LwtPolicy) {
+	case LwtPolicyDualWrite:
+		return common.LwtPolicyDualWrite, nil
+	case LwtPolicyPrimaryOnly:
+		return common.LwtPolicyPrimaryOnly, nil
+	default:
+		return common.LwtPolicyUndefined, fmt.Errorf("invalid value for ZDM_LWT_POLICY; possible values are: %v and %v",
+			LwtPolicyDualWrite, LwtPolicyPrimaryOnly)
+	}
+}
+
+const (
+	CounterWritesPolicyDualWrite   = "DUAL_WRITE"
+	CounterWritesPolicyPrimaryOnly = "PRIMARY_ONLY"
+	CounterWritesPolicyReject      = "REJECT"
+)
+
+func (c *RoutingConfig) ParseCounterWritesPolicy() (common.CounterWritesPolicy, error) {
+	switch strings.ToUpper(c.CounterWritesPolicy) {
+	case CounterWritesPolicyDualWrite:
+		return common.CounterWritesPolicyDualWrite, nil
+	case CounterWritesPolicyPrimaryOnly:
+		return common.CounterWritesPolicyPrimaryOnly, nil
+	case CounterWritesPolicyReject:
+		return common.CounterWritesPolicyReject, nil
+	default:
+		return common.CounterWritesPolicyUndefined, fmt.Errorf(
+			"invalid value for ZDM_COUNTER_WRITES_POLICY; possible values are: %v, %v and %v",
+			CounterWritesPolicyDualWrite, CounterWritesPolicyPrimaryOnly, CounterWritesPolicyReject)
+	}
+}
+
+const (
+	PagingStatePolicyNone               = "NONE"
+	PagingStatePolicyOriginatingCluster = "ORIGINATING_CLUSTER"
+	PagingStatePolicyReject             = "REJECT"
+)
+
+func (c *RoutingConfig) ParsePagingStatePolicy() (common.PagingStatePolicy, error) {
+	switch strings.ToUpper(strings.TrimSpace(c.PagingStatePolicy)) {
+	case PagingStatePolicyNone:
+		return common.PagingStatePolicyNone, nil
+	case PagingStatePolicyOriginatingCluster:
+		return common.PagingStatePolicyOriginatingCluster, nil
+	case PagingStatePolicyReject:
+		return common.PagingStatePolicyReject, nil
+	default:
+		return common.PagingStatePolicyUndefined, fmt.Errorf(
+			"invalid value for ZDM_PAGING_STATE_POLICY; possible values are: %v, %v and %v",
+			PagingStatePolicyNone, PagingStatePolicyOriginatingCluster, PagingStatePolicyReject)
+	}
+}
+
+const (
+	SecondaryWriteFailureModeReturnError    = "RETURN_ERROR"
+	SecondaryWriteFailureModeLogAndContinue = "LOG_AND_CONTINUE"
+	SecondaryWriteFailureModeQueueForReplay = "QUEUE_FOR_REPLAY"
+)
+
+func (c *RoutingConfig) ParseSecondaryWriteFailureMode() (common.SecondaryWriteFailureMode, error) {
+	switch strings.ToUpper(c.SecondaryWriteFailureMode) {
+	case SecondaryWriteFailureModeReturnError:
+		return common.SecondaryWriteFailureModeReturnError, nil
+	case SecondaryWriteFailureModeLogAndContinue:
+		return common.SecondaryWriteFailureModeLogAndContinue, nil
+	case SecondaryWriteFailureModeQueueForReplay:
+		if c.SecondaryWriteReplayDelayMs < 0 {
+			return common.SecondaryWriteFailureModeUndefined, fmt.Errorf(
+				"invalid value for ZDM_SECONDARY_WRITE_REPLAY_DELAY_MS (%v); it must be 0 or a positive number",
+				c.SecondaryWriteReplayDelayMs)
+		}
+		return common.SecondaryWriteFailureModeQueueForReplay, nil
+	default:
+		return common.SecondaryWriteFailureModeUndefined, fmt.Errorf(
+			"invalid value for ZDM_SECONDARY_WRITE_FAILURE_MODE; possible values are: %v, %v and %v",
+			SecondaryWriteFailureModeReturnError, SecondaryWriteFailureModeLogAndContinue,
+			SecondaryWriteFailureModeQueueForReplay)
+	}
+}
+
+const (
+	SecondaryWriteJournalTypeNone   = "NONE"
+	SecondaryWriteJournalTypeMemory = "MEMORY"
+	SecondaryWriteJournalTypeFile   = "FILE"
+)
+
+func (c *RoutingConfig) ParseSecondaryWriteJournalConfig() (*common.WriteJournalConfig, error) {
+	directory := ""
+	switch strings.ToUpper(c.SecondaryWriteJournalType) {
+	case SecondaryWriteJournalTypeNone:
+		return &common.WriteJournalConfig{}, nil
+	case SecondaryWriteJournalTypeMemory:
+	case SecondaryWriteJournalTypeFile:
+		directory = strings.TrimSpace(c.SecondaryWriteJournalDirectory)
+		if directory == "" {
+			return nil, fmt.Errorf("ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY is required when "+
+				"ZDM_SECONDARY_WRITE_JOURNAL_TYPE is %v", SecondaryWriteJournalTypeFile)
+		}
+		fileInfo, err := os.Stat(directory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY (%v); %w",
+				c.SecondaryWriteJournalDirectory, err)
+		}
+		if !fileInfo.IsDir() {
+			return nil, fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_JOURNAL_DIRECTORY (%v); it is not a directory",
+				c.SecondaryWriteJournalDirectory)
+		}
+	default:
+		return nil, fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_JOURNAL_TYPE; possible values are: %v, %v and %v",
+			SecondaryWriteJournalTypeNone, SecondaryWriteJournalTypeMemory, SecondaryWriteJournalTypeFile)
+	}
+
+	if strings.ToUpper(c.SecondaryWriteFailureMode) != SecondaryWriteFailureModeQueueForReplay {
+		return nil, fmt.Errorf("ZDM_SECONDARY_WRITE_JOURNAL_TYPE %v requires ZDM_SECONDARY_WRITE_FAILURE_MODE %v",
+			strings.ToUpper(c.SecondaryWriteJournalType), SecondaryWriteFailureModeQueueForReplay)
+	}
+
+	if c.SecondaryWriteJournalMaxSizeBytes <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_JOURNAL_MAX_SIZE_BYTES (%v); "+
+			"it must be a positive number", c.SecondaryWriteJournalMaxSizeBytes)
+	}
+
+	if c.SecondaryWriteJournalReplayRate <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_SECONDARY_WRITE_JOURNAL_REPLAY_RATE (%v); "+
+			"it must be a positive number", c.SecondaryWriteJournalReplayRate)
+	}
+
+	return &common.WriteJournalConfig{
+		Enabled:      true,
+		Directory:    directory,
+		MaxSizeBytes: c.SecondaryWriteJournalMaxSizeBytes,
+		ReplayRate:   c.SecondaryWriteJournalReplayRate,
+	}, nil
+}
+
+// ParseTargetWriteSamplingPercentage returns the percentage of writes that are forwarded to TARGET.
+func (c *RoutingConfig) ParseTargetWriteSamplingPercentage() (float64, error) {
+	if c.TargetWriteSamplingPercentage < 0 || c.TargetWriteSamplingPercentage > 100 {
+		return 0, fmt.Errorf("invalid value for ZDM_TARGET_WRITE_SAMPLING_PERCENTAGE (%v); "+
+			"it must be between 0 and 100", c.TargetWriteSamplingPercentage)
+	}
+	if c.TargetWriteSamplingPercentage < 100 && strings.ToUpper(c.PrimaryCluster) != PrimaryClusterOrigin {
+		return 0, fmt.Errorf("invalid value for ZDM_TARGET_WRITE_SAMPLING_PERCENTAGE (%v); "+
+			"writes can only be sampled if ZDM_PRIMARY_CLUSTER is %v", c.TargetWriteSamplingPercentage, PrimaryClusterOrigin)
+	}
+	return c.TargetWriteSamplingPercentage, nil
+}
+
+// ParseWriteComparisonSamplePercentage returns the percentage of dual writes whose responses are compared.
+func (c *RoutingConfig) ParseWriteComparisonSamplePercentage() (float64, error) {
+	if c.WriteComparisonSamplePercentage < 0 || c.WriteComparisonSamplePercentage > 100 {
+		return 0, fmt.Errorf("invalid value for ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE (%v); "+
+			"it must be between 0 and 100", c.WriteComparisonSamplePercentage)
+	}
+	return c.WriteComparisonSamplePercentage, nil
+}
+
+const (
+	TargetDdlCompatibilityModeWarn   = "WARN"
+	TargetDdlCompatibilityModeReject = "REJECT"
+)
+
+func (c *RoutingConfig) ParseTargetDdlCompatibilityMode() (common.TargetDdlCompatibilityMode, error) {
+	switch strings.ToUpper(c.TargetDdlCompatibilityMode) {
+	case TargetDdlCompatibilityModeWarn:
+		return common.TargetDdlCompatibilityModeWarn, nil
+	case TargetDdlCompatibilityModeReject:
+		return common.TargetDdlCompatibilityModeReject, nil
+	default:
+		return common.TargetDdlCompatibilityModeUndefined, fmt.Errorf(
+			"invalid value for ZDM_TARGET_DDL_COMPATIBILITY_MODE; possible values are: %v and %v",
+			TargetDdlCompatibilityModeWarn, TargetDdlCompatibilityModeReject)
+	}
+}
+
+// TargetDdlFeaturesAstra is a shortcut for the schema features that Astra DB doesn't support.
+const TargetDdlFeaturesAstra = "ASTRA"
+
+var targetDdlFeatures = []common.TargetDdlFeature{
+	common.TargetDdlFeatureMaterializedViews,
+	common.TargetDdlFeatureSasiIndexes,
+	common.TargetDdlFeatureTriggers,
+	common.TargetDdlFeatureUserDefinedFunctions,
+	common.TargetDdlFeatureKeyspaceSchemaChanges,
+}
+
+// ParseTargetDdlUnsupportedFeatures parses the comma separated features of ZDM_TARGET_DDL_UNSUPPORTED_FEATURES:
+//   - MATERIALIZED_VIEWS: CREATE and ALTER MATERIALIZED VIEW
+//   - SASI_INDEXES: CREATE CUSTOM INDEX with the SASIIndex class
+//   - TRIGGERS: CREATE TRIGGER
+//   - USER_DEFINED_FUNCTIONS: CREATE FUNCTION and CREATE AGGREGATE
+//   - KEYSPACE_SCHEMA_CHANGES: CREATE, ALTER and DROP KEYSPACE
+//   - ASTRA: every feature above, Astra DB doesn't support any of them through CQL
+func (c *RoutingConfig) ParseTargetDdlUnsupportedFeatures() ([]common.TargetDdlFeature, error) {
+	var features []common.TargetDdlFeature
+	if isNotDefined(c.TargetDdlUnsupportedFeatures) {
+		return features, nil
+	}
+
+	for _, featureStr := range strings.Split(c.TargetDdlUnsupportedFeatures, ",") {
+		featureStr = strings.ToUpper(strings.TrimSpace(featureStr))
+		if featureStr == TargetDdlFeaturesAstra {
+			features = append(features, targetDdlFeatures...)
+			continue
+		}
+		found := false
+		for _, feature := range targetDdlFeatures {
+			if featureStr == string(feature) {
+				features = append(features, feature)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid value for ZDM_TARGET_DDL_UNSUPPORTED_FEATURES (%v); unknown feature %v, "+
+				"possible values are: %v and %v", c.TargetDdlUnsupportedFeatures, featureStr, targetDdlFeatures, TargetDdlFeaturesAstra)
+		}
+	}
+
+	return features, nil
+}
+
+func (c *RoutingConfig) ParseTargetDdlUnsupportedCompactionStrategies() []string {
+	var compactionStrategies []string
+	if isNotDefined(c.TargetDdlUnsupportedCompactionStrategies) {
+		return compactionStrategies
+	}
+
+	for _, compactionStrategy := range strings.Split(c.TargetDdlUnsupportedCompactionStrategies, ",") {
+		compactionStrategy = strings.TrimSpace(compactionStrategy)
+		if compactionStrategy != "" {
+			compactionStrategies = append(compactionStrategies, compactionStrategy)
+		}
+	}
+	return compactionStrategies
+}
+
+const (
+	SchemaStatementPolicyBoth       = "BOTH"
+	SchemaStatementPolicyOriginOnly = "ORIGIN_ONLY"
+	SchemaStatementPolicyReject     = "REJECT"
+)
+
+func (c *RoutingConfig) ParseMaterializedViewStatementsPolicy() (common.SchemaStatementPolicy, error) {
+	return parseSchemaStatementPolicy("ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY", c.MaterializedViewStatementsPolicy)
+}
+
+func (c *RoutingConfig) ParseIndexStatementsPolicy() (common.SchemaStatementPolicy, error) {
+	return parseSchemaStatementPolicy("ZDM_INDEX_STATEMENTS_POLICY", c.IndexStatementsPolicy)
+}
+
+func (c *RoutingConfig) ParseCustomIndexStatementsPolicy() (common.SchemaStatementPolicy, error) {
+	return parseSchemaStatementPolicy("ZDM_CUSTOM_INDEX_STATEMENTS_POLICY", c.CustomIndexStatementsPolicy)
+}
+
+func (c *RoutingConfig) ParseDestructiveStatementsConfirmationConfig() (
+	*common.DestructiveStatementsConfirmationConfig, error) {
+	if !c.DestructiveStatementsConfirmationEnabled {
+		return &common.DestructiveStatementsConfirmationConfig{Enabled: false}, nil
+	}
+	if c.DestructiveStatementsConfirmationTokenTtlMs <= 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_TOKEN_TTL_MS (%v); "+
+			"it must be a positive number", c.DestructiveStatementsConfirmationTokenTtlMs)
+	}
+	if c.DestructiveStatementsMaxUnlockDurationMs < 0 {
+		return nil, fmt.Errorf("invalid value for ZDM_DESTRUCTIVE_STATEMENTS_MAX_UNLOCK_DURATION_MS (%v); "+
+			"it must be 0 (unlocking is not allowed) or a positive number", c.DestructiveStatementsMaxUnlockDurationMs)
+	}
+	return &common.DestructiveStatementsConfirmationConfig{
+		Enabled:             true,
+		TokenTtlMs:          c.DestructiveStatementsConfirmationTokenTtlMs,
+		MaxUnlockDurationMs: c.DestructiveStatementsMaxUnlockDurationMs,
+	}, nil
+}
+
+func parseSchemaStatementPolicy(envVarName string, value string) (common.SchemaStatementPolicy, error) {
+	switch strings.ToUpper(value) {
+	case SchemaStatementPolicyBoth:
+		return common.SchemaStatementPolicyBoth, nil
+	case SchemaStatementPolicyOriginOnly:
+		return common.SchemaStatementPolicyOriginOnly, nil
+	case SchemaStatementPolicyReject:
+		return common.SchemaStatementPolicyReject, nil
+	default:
+		return common.SchemaStatementPolicyUndefined, fmt.Errorf(
+			"invalid value for %v; possible values are: %v, %v and %v",
+			envVarName, SchemaStatementPolicyBoth, SchemaStatementPolicyOriginOnly, SchemaStatementPolicyReject)
+	}
+}
+
+// writeFilterOperators is sorted so that operators are matched before their prefixes (e.g. "<=" before "<").
+var writeFilterOperators = []common.WriteFilterOperator{
+	common.WriteFilterOperatorNotIn,
+	common.WriteFilterOperatorIn,
+	common.WriteFilterOperatorNotEqual,
+	common.WriteFilterOperatorLessOrEqual,
+	common.WriteFilterOperatorGreaterOrEqual,
+	common.WriteFilterOperatorLessThan,
+	common.WriteFilterOperatorGreaterThan,
+	common.WriteFilterOperatorEqual,
+}
+
+// ParseTargetWriteFilterRules parses the semicolon separated rules of ZDM_TARGET_WRITE_FILTER_RULES.
+// Each rule has the format <keyspace>.<table>.<column> <operator> <value(s)> where operator is one of
+// =, !=, <, <=, >, >=, IN and NOT IN. IN and NOT IN take a parenthesized list of comma separated values.
+// Identifiers are case-insensitive unless they are double-quoted and values can be single-quoted.
+func (c *RoutingConfig) ParseTargetWriteFilterRules() ([]*common.WriteFilterRule, error) {
+	var rules []*common.WriteFilterRule
+	if isNotDefined(c.TargetWriteFilterRules) {
+		return rules, nil
+	}
+
+	for _, entry := range strings.Split(c.TargetWriteFilterRules, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseWriteFilterRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_TARGET_WRITE_FILTER_RULES (%v): %w", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseWriteFilterRule(entry string) (*common.WriteFilterRule, error) {
+	nameAndCondition := strings.SplitN(entry, " ", 2)
+	if len(nameAndCondition) != 2 {
+		return nil, fmt.Errorf("expected <keyspace>.<table>.<column> <operator> <value(s)>")
+	}
+
+	names := strings.Split(nameAndCondition[0], ".")
+	if len(names) != 3 {
+		return nil, fmt.Errorf("expected column name with format <keyspace>.<table>.<column> but got %v", nameAndCondition[0])
+	}
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("empty identifier in column name %v", nameAndCondition[0])
+		}
+		names[i] = parseWriteFilterIdentifier(name)
+	}
+
+	condition := strings.TrimSpace(nameAndCondition[1])
+	var operator common.WriteFilterOperator
+	for _, op := range writeFilterOperators {
+		if strings.HasPrefix(strings.ToUpper(condition), string(op)) {
+			operator = op
+			break
+		}
+	}
+	if operator == "" {
+		return nil, fmt.Errorf("unknown operator in %v; possible operators are: =, !=, <, <=, >, >=, IN and NOT IN", condition)
+	}
+
+	valuesStr := strings.TrimSpace(condition[len(operator):])
+	var values []string
+	if operator == common.WriteFilterOperatorIn || operator == common.WriteFilterOperatorNotIn {
+		if !strings.HasPrefix(valuesStr, "(") || !strings.HasSuffix(valuesStr, ")") {
+			return nil, fmt.Errorf("%v expects a parenthesized list of values but got %v", operator, valuesStr)
+		}
+		for _, value := range strings.Split(valuesStr[1:len(valuesStr)-1], ",") {
+			values = append(values, parseWriteFilterValue(value))
+		}
+	} else {
+		values = []string{parseWriteFilterValue(valuesStr)}
+	}
+	for _, value := range values {
+		if value == "" {
+			return nil, fmt.Errorf("empty value in %v", condition)
+		}
+	}
+
+	return &common.WriteFilterRule{
+		Keyspace: names[0],
+		Table:    names[1],
+		Column:   names[2],
+		Operator: operator,
+		Values:   values,
+	}, nil
+}
+
+func parseWriteFilterIdentifier(identifier string) string {
+	if len(identifier) > 1 && strings.HasPrefix(identifier, "\"") && strings.HasSuffix(identifier, "\"") {
+		return identifier[1 : len(identifier)-1]
+	}
+	return strings.ToLower(identifier)
+}
+
+func parseWriteFilterValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > 1 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
+
+// ParseApplicationReadRoutingRules parses the semicolon separated rules of ZDM_APPLICATION_READ_ROUTING_RULES.
+// Each rule has the format <application name> [<keyspace>[.<table>]] <cluster> where cluster is ORIGIN or TARGET.
+// The application name is case-sensitive and has to be double-quoted if it contains spaces, keyspace and table
+// names follow the same rules as the identifiers of ZDM_TARGET_WRITE_FILTER_RULES.
+func (c *RoutingConfig) ParseApplicationReadRoutingRules() ([]*common.ApplicationReadRoutingRule, error) {
+	var rules []*common.ApplicationReadRoutingRule
+	if isNotDefined(c.ApplicationReadRoutingRules) {
+		return rules, nil
+	}
+
+	for _, entry := range strings.Split(c.ApplicationReadRoutingRules, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseApplicationReadRoutingRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_APPLICATION_READ_ROUTING_RULES (%v): %w", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseApplicationReadRoutingRule(entry string) (*common.ApplicationReadRoutingRule, error) {
+	var application, remaining string
+	if strings.HasPrefix(entry, "\"") {
+		end := strings.Index(entry[1:], "\"")
+		if end < 0 {
+			return nil, fmt.Errorf("missing closing quote in application name")
+		}
+		application, remaining = entry[1:end+1], entry[end+2:]
+	} else {
+		applicationAndRemaining := strings.SplitN(entry, " ", 2)
+		application = applicationAndRemaining[0]
+		if len(applicationAndRemaining) == 2 {
+			remaining = applicationAndRemaining[1]
+		}
+	}
+	if application == "" {
+		return nil, fmt.Errorf("empty application name")
+	}
+
+	fields := strings.Fields(remaining)
+	if len(fields) != 1 && len(fields) != 2 {
+		return nil, fmt.Errorf("expected <application name> [<keyspace>[.<table>]] <cluster>")
+	}
+
+	rule := &common.ApplicationReadRoutingRule{Application: application}
+	switch strings.ToUpper(fields[len(fields)-1]) {
+	case PrimaryClusterOrigin:
+		rule.Cluster = common.ClusterTypeOrigin
+	case PrimaryClusterTarget:
+		rule.Cluster = common.ClusterTypeTarget
+	default:
+		return nil, fmt.Errorf("unknown cluster %v; possible values are: %v and %v",
+			fields[len(fields)-1], PrimaryClusterOrigin, PrimaryClusterTarget)
+	}
+
+	if len(fields) == 2 {
+		names := strings.Split(fields[0], ".")
+		if len(names) > 2 {
+			return nil, fmt.Errorf("expected <keyspace> or <keyspace>.<table> but got %v", fields[0])
+		}
+		for i, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("empty identifier in %v", fields[0])
+			}
+			names[i] = parseWriteFilterIdentifier(name)
+		}
+		rule.Keyspace = names[0]
+		if len(names) == 2 {
+			rule.Table = names[1]
+		}
+	}
+
+	return rule, nil
+}
+
+// ParseTableRoutingRules parses the semicolon separated rules of ZDM_TABLE_ROUTING_RULES.
+// Each rule has the format <keyspace>.<table> <cluster> where cluster is ORIGIN or TARGET, keyspace and table
+// names follow the same rules as the identifiers of ZDM_TARGET_WRITE_FILTER_RULES. A table can only have one rule.
+func (c *RoutingConfig) ParseTableRoutingRules() ([]*common.TableRoutingRule, error) {
+	var rules []*common.TableRoutingRule
+	if isNotDefined(c.TableRoutingRules) {
+		return rules, nil
+	}
+
+	tables := make(map[string]bool)
+	for _, entry := range strings.Split(c.TableRoutingRules, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseTableRoutingRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_TABLE_ROUTING_RULES (%v): %w", entry, err)
+		}
+		qualifiedName := rule.Keyspace + "." + rule.Table
+		if tables[qualifiedName] {
+			return nil, fmt.Errorf("invalid value for ZDM_TABLE_ROUTING_RULES (%v): "+
+				"table %v has more than one rule", entry, qualifiedName)
+		}
+		tables[qualifiedName] = true
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseTableRoutingRule(entry string) (*common.TableRoutingRule, error) {
+	fields := strings.Fields(entry)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("expected <keyspace>.<table> <cluster>")
+	}
+
+	rule := &common.TableRoutingRule{}
+	switch strings.ToUpper(fields[1]) {
+	case PrimaryClusterOrigin:
+		rule.Cluster = common.ClusterTypeOrigin
+	case PrimaryClusterTarget:
+		rule.Cluster = common.ClusterTypeTarget
+	default:
+		return nil, fmt.Errorf("unknown cluster %v; possible values are: %v and %v",
+			fields[1], PrimaryClusterOrigin, PrimaryClusterTarget)
+	}
+
+	names := strings.Split(fields[0], ".")
+	if len(names) != 2 {
+		return nil, fmt.Errorf("expected <keyspace>.<table> but got %v", fields[0])
+	}
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("empty identifier in %v", fields[0])
+		}
+		names[i] = parseWriteFilterIdentifier(name)
+	}
+	rule.Keyspace = names[0]
+	rule.Table = names[1]
+
+	return rule, nil
+}
+
+const (
+	StatementFilterActionReject = "REJECT"
+	StatementFilterActionOrigin = "ORIGIN"
+	StatementFilterActionTarget = "TARGET"
+)
+
+// ParseStatementFilterRules parses the semicolon separated rules of ZDM_STATEMENT_FILTER_RULES, the first rule that
+// matches a statement applies. Each rule has one of these formats:
+//
+//	<statement> [<keyspace>.<table> | <keyspace>.*] <action>
+//	/<regex>/ <action>
+//
+// where statement is the first keyword of the statements (e.g. SELECT, DELETE, TRUNCATE or DROP) or * for every
+// statement, regex is matched against the whole query ignoring case (it can't contain semicolons) and action is
+// REJECT, ORIGIN or TARGET. Keyspace and table names follow the same rules as the identifiers of
+// ZDM_TARGET_WRITE_FILTER_RULES.
+func (c *RoutingConfig) ParseStatementFilterRules() ([]*common.StatementFilterRule, error) {
+	var rules []*common.StatementFilterRule
+	if isNotDefined(c.StatementFilterRules) {
+		return rules, nil
+	}
+
+	for _, entry := range strings.Split(c.StatementFilterRules, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseStatementFilterRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for ZDM_STATEMENT_FILTER_RULES (%v): %w", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseStatementFilterRule(entry string) (*common.StatementFilterRule, error) {
+	rule := &common.StatementFilterRule{Definition: entry}
+
+	var fields []string
+	if strings.HasPrefix(entry, "/") {
+		end := strings.LastIndex(entry, "/")
+		if end == 0 {
+			return nil, fmt.Errorf("expected /<regex>/ <action>")
+		}
+		pattern, err := regexp.Compile("(?i)" + entry[1:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		rule.Pattern = pattern
+		fields = strings.Fields(entry[end+1:])
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("expected /<regex>/ <action>")
+		}
+	} else {
+		fields = strings.Fields(entry)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("expected <statement> [<keyspace>.<table>] <action>")
+		}
+		rule.Statement = strings.ToUpper(fields[0])
+		if len(fields) == 3 {
+			names := strings.Split(fields[1], ".")
+			if len(names) != 2 {
+				return nil, fmt.Errorf("expected <keyspace>.<table> or <keyspace>.* but got %v", fields[1])
+			}
+			for _, name := range names {
+				if name == "" {
+					return nil, fmt.Errorf("empty identifier in %v", fields[1])
+				}
+			}
+			rule.Keyspace = parseWriteFilterIdentifier(names[0])
+			if names[1] != "*" {
+				rule.Table = parseWriteFilterIdentifier(names[1])
+			}
+		}
+	}
+
+	action := fields[len(fields)-1]
+	switch strings.ToUpper(action) {
+	case StatementFilterActionReject:
+		rule.Action = common.StatementFilterActionReject
+	case StatementFilterActionOrigin:
+		rule.Action = common.StatementFilterActionOrigin
+	case StatementFilterActionTarget:
+		rule.Action = common.StatementFilterActionTarget
+	default:
+		return nil, fmt.Errorf("unknown action %v; possible values are: %v, %v and %v",
+			action, StatementFilterActionReject, StatementFilterActionOrigin, StatementFilterActionTarget)
+	}
+
+	return rule, nil
+}
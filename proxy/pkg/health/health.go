@@ -16,7 +16,9 @@ func DefaultReadinessHandler() http.Handler {
 type StatusReport struct {
 	OriginStatus *ControlConnStatus
 	TargetStatus *ControlConnStatus
-	Status       Status
+	// ListenerStatus is UP if the proxy is accepting client connections.
+	ListenerStatus Status `json:",omitempty"`
+	Status         Status
 }
 
 type ControlConnStatus struct {
@@ -84,17 +86,36 @@ func PerformHealthCheck(proxy *zdmproxy.ZdmProxy) *StatusReport {
 
 	originControlConnStatus := newControlConnStatus(originControlConn, proxy.Conf.HeartbeatFailureThreshold)
 	targetControlConnStatus := newControlConnStatus(targetControlConn, proxy.Conf.HeartbeatFailureThreshold)
-	status := UP
-	if originControlConnStatus.Status != UP || targetControlConnStatus.Status != UP {
-		status = DOWN
+	listenerStatus := DOWN
+	if proxy.IsAcceptingConnections() {
+		listenerStatus = UP
 	}
 	return &StatusReport{
-		OriginStatus: originControlConnStatus,
-		TargetStatus: targetControlConnStatus,
-		Status:       status,
+		OriginStatus:   originControlConnStatus,
+		TargetStatus:   targetControlConnStatus,
+		ListenerStatus: listenerStatus,
+		Status: computeReadinessStatus(originControlConnStatus, targetControlConnStatus, listenerStatus,
+			proxy.Conf.ReadinessRequiresOrigin, proxy.Conf.ReadinessRequiresTarget),
 	}
 }
 
+// computeReadinessStatus returns UP if the proxy is accepting client connections and the control connections of the
+// required clusters (ZDM_READINESS_REQUIRES_ORIGIN and ZDM_READINESS_REQUIRES_TARGET) are up.
+func computeReadinessStatus(
+	originStatus *ControlConnStatus, targetStatus *ControlConnStatus, listenerStatus Status,
+	requiresOrigin bool, requiresTarget bool) Status {
+	if listenerStatus != UP {
+		return DOWN
+	}
+	if requiresOrigin && originStatus.Status != UP {
+		return DOWN
+	}
+	if requiresTarget && targetStatus.Status != UP {
+		return DOWN
+	}
+	return UP
+}
+
 func newControlConnStatus(controlConn *zdmproxy.ControlConn, failureThreshold int) *ControlConnStatus {
 	currentEndpoint := controlConn.GetCurrentContactPoint()
 	var addr string
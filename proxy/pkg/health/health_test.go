@@ -0,0 +1,36 @@
+package health
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestComputeReadinessStatus(t *testing.T) {
+	up := &ControlConnStatus{Status: UP}
+	down := &ControlConnStatus{Status: DOWN}
+
+	tests := []struct {
+		name           string
+		originStatus   *ControlConnStatus
+		targetStatus   *ControlConnStatus
+		listenerStatus Status
+		requiresOrigin bool
+		requiresTarget bool
+		expected       Status
+	}{
+		{"both clusters up", up, up, UP, true, true, UP},
+		{"target down", up, down, UP, true, true, DOWN},
+		{"origin down", down, up, UP, true, true, DOWN},
+		{"target down but not required", up, down, UP, true, false, UP},
+		{"origin down but not required", down, up, UP, false, true, UP},
+		{"no cluster required", down, down, UP, false, false, UP},
+		{"listener down", up, up, DOWN, true, true, DOWN},
+		{"listener down and no cluster required", up, up, DOWN, false, false, DOWN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, computeReadinessStatus(
+				tt.originStatus, tt.targetStatus, tt.listenerStatus, tt.requiresOrigin, tt.requiresTarget))
+		})
+	}
+}
@@ -7,15 +7,21 @@ import (
 )
 
 func StartHttpServer(addr string, wg *sync.WaitGroup) *http.Server {
-	srv := &http.Server{Addr: addr}
+	return StartHttpServerWithHandler(addr, nil, "metrics", wg)
+}
+
+// StartHttpServerWithHandler starts an http server that serves the provided handler (http.DefaultServeMux if nil),
+// name is the name of the endpoint in the log entries.
+func StartHttpServerWithHandler(addr string, handler http.Handler, name string, wg *sync.WaitGroup) *http.Server {
+	srv := &http.Server{Addr: addr, Handler: handler}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
 		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			log.Errorf("Failed to listen on the metrics endpoint: %v. "+
-				"The proxy will stay up and listen for CQL requests.", err)
+			log.Errorf("Failed to listen on the %v endpoint: %v. "+
+				"The proxy will stay up and listen for CQL requests.", name, err)
 		}
 	}()
 
@@ -0,0 +1,28 @@
+package metrics
+
+// HistogramConfig holds the settings that a MetricFactory applies to every histogram that it creates.
+type HistogramConfig struct {
+	// BucketOverrides contains the buckets of specific histograms keyed on the metric name (without namespace).
+	// These take precedence over the buckets that are provided when the histogram is created.
+	BucketOverrides map[string][]float64
+
+	// NativeHistogramBucketFactor enables native (sparse) histograms if it is greater than 1.
+	// See the NativeHistogramBucketFactor documentation of the Prometheus client for more details.
+	NativeHistogramBucketFactor float64
+}
+
+// GetBuckets returns the buckets that should be used for the provided histogram.
+func (recv *HistogramConfig) GetBuckets(mn Metric, defaultBuckets []float64) []float64 {
+	if recv == nil {
+		return defaultBuckets
+	}
+	if buckets, ok := recv.BucketOverrides[mn.GetName()]; ok {
+		return buckets
+	}
+	return defaultBuckets
+}
+
+// NativeHistogramsEnabled returns true if native histograms should be created alongside the classic buckets.
+func (recv *HistogramConfig) NativeHistogramsEnabled() bool {
+	return recv != nil && recv.NativeHistogramBucketFactor > 1
+}
@@ -180,6 +180,70 @@ func (recv *MetricHandler) GetNodeMetrics(
 	return &NodeMetrics{OriginMetrics: originMetrics, TargetMetrics: targetMetrics, AsyncMetrics: asyncMetrics}, nil
 }
 
+// GetClientConnectionsByProtocolVersion returns the gauge of the client connections that negotiated the provided
+// protocol version, the label values are only known when clients connect so the gauges are created on demand.
+func (recv *MetricHandler) GetClientConnectionsByProtocolVersion(protocolVersion string) (Gauge, error) {
+	return recv.metricFactory.GetOrCreateGauge(ClientConnectionsByProtocolVersion.WithLabels(map[string]string{
+		clientConnectionsProtocolVersionLabel: protocolVersion,
+	}))
+}
+
+// GetClientConnectionsByDriver returns the gauge of the client connections that were opened by the provided driver.
+func (recv *MetricHandler) GetClientConnectionsByDriver(driverName string, driverVersion string) (Gauge, error) {
+	return recv.metricFactory.GetOrCreateGauge(ClientConnectionsByDriver.WithLabels(map[string]string{
+		clientConnectionsDriverNameLabel:    driverName,
+		clientConnectionsDriverVersionLabel: driverVersion,
+	}))
+}
+
+// GetTableRequests returns the counter of the requests of the provided table that were forwarded to the provided
+// cluster(s) ("origin", "target" or "both"), the counters are created on demand like the client connection gauges.
+func (recv *MetricHandler) GetTableRequests(keyspace string, table string, forwardedTo string) (Counter, error) {
+	return recv.metricFactory.GetOrCreateCounter(TableRequests.WithLabels(getTableLabels(keyspace, table, forwardedTo)))
+}
+
+// GetTableRequestDuration returns the latency histogram of the requests of the provided table that were forwarded to
+// the provided cluster(s), it uses the TARGET buckets for the requests that were only forwarded to TARGET.
+func (recv *MetricHandler) GetTableRequestDuration(keyspace string, table string, forwardedTo string) (Histogram, error) {
+	buckets := recv.originBuckets
+	if forwardedTo == "target" {
+		buckets = recv.targetBuckets
+	}
+	return recv.metricFactory.GetOrCreateHistogram(
+		TableRequestDuration.WithLabels(getTableLabels(keyspace, table, forwardedTo)), buckets)
+}
+
+func getTableLabels(keyspace string, table string, forwardedTo string) map[string]string {
+	return map[string]string{
+		tableRequestsKeyspaceLabel:    keyspace,
+		tableRequestsTableLabel:       table,
+		tableRequestsForwardedToLabel: forwardedTo,
+	}
+}
+
+// CreateMigrationProgressGauges creates the gauges of the migration progress estimation, they are only created if it
+// is enabled (ZDM_MIGRATION_PROGRESS_ENABLED).
+func (recv *MetricHandler) CreateMigrationProgressGauges(
+	originReadsRatio func() float64, dualWriteFailuresRatio func() float64, readMismatchesRatio func() float64,
+	replayQueueDepth func() float64) error {
+	gauges := []struct {
+		metric Metric
+		value  func() float64
+	}{
+		{MigrationOriginReadsRatio, originReadsRatio},
+		{MigrationDualWriteFailuresRatio, dualWriteFailuresRatio},
+		{MigrationReadMismatchesRatio, readMismatchesRatio},
+		{MigrationReplayQueueDepth, replayQueueDepth},
+	}
+	for _, gauge := range gauges {
+		_, err := recv.metricFactory.GetOrCreateGaugeFunc(gauge.metric, gauge.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (recv *MetricHandler) UnregisterAllMetrics() error {
 	return recv.metricFactory.UnregisterAllMetrics()
 }
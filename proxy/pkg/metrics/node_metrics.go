@@ -21,6 +21,8 @@ const (
 	errorOverloaded    = "overloaded"
 	errorUnavailable   = "unavailable"
 	errorUnprepared    = "unprepared"
+	errorSyntaxError   = "syntax_error"
+	errorAuthError     = "auth_error"
 	errorOther         = "other"
 
 	nodeLabel = "node"
@@ -83,6 +85,20 @@ var (
 			originFailedRequestsErrorLabel: errorUnavailable,
 		},
 	)
+	OriginSyntaxErrors = NewMetricWithLabels(
+		originFailedRequestsName,
+		originFailedRequestsDescription,
+		map[string]string{
+			originFailedRequestsErrorLabel: errorSyntaxError,
+		},
+	)
+	OriginAuthErrors = NewMetricWithLabels(
+		originFailedRequestsName,
+		originFailedRequestsDescription,
+		map[string]string{
+			originFailedRequestsErrorLabel: errorAuthError,
+		},
+	)
 	OriginOtherErrors = NewMetricWithLabels(
 		originFailedRequestsName,
 		originFailedRequestsDescription,
@@ -147,6 +163,20 @@ var (
 			targetFailedRequestsErrorLabel: errorUnavailable,
 		},
 	)
+	TargetSyntaxErrors = NewMetricWithLabels(
+		targetFailedRequestsName,
+		targetFailedRequestsDescription,
+		map[string]string{
+			targetFailedRequestsErrorLabel: errorSyntaxError,
+		},
+	)
+	TargetAuthErrors = NewMetricWithLabels(
+		targetFailedRequestsName,
+		targetFailedRequestsDescription,
+		map[string]string{
+			targetFailedRequestsErrorLabel: errorAuthError,
+		},
+	)
 	TargetOtherErrors = NewMetricWithLabels(
 		targetFailedRequestsName,
 		targetFailedRequestsDescription,
@@ -211,6 +241,20 @@ var (
 			asyncFailedRequestsErrorLabel: errorUnavailable,
 		},
 	)
+	AsyncSyntaxErrors = NewMetricWithLabels(
+		asyncFailedRequestsName,
+		asyncFailedRequestsDescription,
+		map[string]string{
+			asyncFailedRequestsErrorLabel: errorSyntaxError,
+		},
+	)
+	AsyncAuthErrors = NewMetricWithLabels(
+		asyncFailedRequestsName,
+		asyncFailedRequestsDescription,
+		map[string]string{
+			asyncFailedRequestsErrorLabel: errorAuthError,
+		},
+	)
 	AsyncOtherErrors = NewMetricWithLabels(
 		asyncFailedRequestsName,
 		asyncFailedRequestsDescription,
@@ -245,10 +289,82 @@ var (
 		"Number of connections currently open for async requests",
 	)
 
+	InFlightRequestsOrigin = NewMetric(
+		"origin_inflight_requests_total",
+		"Number of requests currently in flight on each Origin node",
+	)
+	InFlightRequestsTarget = NewMetric(
+		"target_inflight_requests_total",
+		"Number of requests currently in flight on each Target node",
+	)
 	InFlightRequestsAsync = NewMetric(
 		"async_inflight_requests_total",
 		"Number of async requests currently in flight",
 	)
+
+	PooledStreamIdsInUseOrigin = NewMetric(
+		"origin_pooled_stream_ids_in_use",
+		"Number of stream ids in use on the pooled connections to each Origin node",
+	)
+	PooledStreamIdsCapacityOrigin = NewMetric(
+		"origin_pooled_stream_ids_capacity",
+		"Number of stream ids of the pooled connections to each Origin node",
+	)
+	PooledStreamIdsExhaustedOrigin = NewMetric(
+		"origin_pooled_stream_ids_exhausted_total",
+		"Running total of requests that found a pooled connection to each Origin node out of stream ids",
+	)
+
+	PooledStreamIdsInUseTarget = NewMetric(
+		"target_pooled_stream_ids_in_use",
+		"Number of stream ids in use on the pooled connections to each Target node",
+	)
+	PooledStreamIdsCapacityTarget = NewMetric(
+		"target_pooled_stream_ids_capacity",
+		"Number of stream ids of the pooled connections to each Target node",
+	)
+	PooledStreamIdsExhaustedTarget = NewMetric(
+		"target_pooled_stream_ids_exhausted_total",
+		"Running total of requests that found a pooled connection to each Target node out of stream ids",
+	)
+
+	PooledStreamIdsInUseAsync = NewMetric(
+		"async_pooled_stream_ids_in_use",
+		"Number of stream ids in use on the pooled connections to each node of the Async Connector",
+	)
+	PooledStreamIdsCapacityAsync = NewMetric(
+		"async_pooled_stream_ids_capacity",
+		"Number of stream ids of the pooled connections to each node of the Async Connector",
+	)
+	PooledStreamIdsExhaustedAsync = NewMetric(
+		"async_pooled_stream_ids_exhausted_total",
+		"Running total of requests that found a pooled connection to each node of the Async Connector out of stream ids",
+	)
+
+	PooledHeartbeatsSentOrigin = NewMetric(
+		"origin_pooled_heartbeats_sent_total",
+		"Running total of heartbeats sent on the idle pooled connections to each Origin node",
+	)
+	PooledHeartbeatsFailedOrigin = NewMetric(
+		"origin_pooled_heartbeats_failed_total",
+		"Running total of heartbeats that the pooled connections to each Origin node didn't answer in time",
+	)
+	PooledHeartbeatsSentTarget = NewMetric(
+		"target_pooled_heartbeats_sent_total",
+		"Running total of heartbeats sent on the idle pooled connections to each Target node",
+	)
+	PooledHeartbeatsFailedTarget = NewMetric(
+		"target_pooled_heartbeats_failed_total",
+		"Running total of heartbeats that the pooled connections to each Target node didn't answer in time",
+	)
+	PooledHeartbeatsSentAsync = NewMetric(
+		"async_pooled_heartbeats_sent_total",
+		"Running total of heartbeats sent on the idle pooled connections to each node of the Async Connector",
+	)
+	PooledHeartbeatsFailedAsync = NewMetric(
+		"async_pooled_heartbeats_failed_total",
+		"Running total of heartbeats that the pooled connections to each node of the Async Connector didn't answer in time",
+	)
 )
 
 type NodeMetrics struct {
@@ -266,6 +382,8 @@ type NodeMetricsInstance struct {
 	UnpreparedErrors  Counter
 	OverloadedErrors  Counter
 	UnavailableErrors Counter
+	SyntaxErrors      Counter
+	AuthErrors        Counter
 	OtherErrors       Counter
 
 	RequestDuration Histogram
@@ -273,6 +391,13 @@ type NodeMetricsInstance struct {
 	OpenConnections Gauge
 
 	InFlightRequests Gauge
+
+	PooledStreamIdsInUse     Gauge
+	PooledStreamIdsCapacity  Gauge
+	PooledStreamIdsExhausted Counter
+
+	PooledHeartbeatsSent   Counter
+	PooledHeartbeatsFailed Counter
 }
 
 func CreateCounterNodeMetric(metricFactory MetricFactory, nodeDescription string, mn Metric) (Counter, error) {
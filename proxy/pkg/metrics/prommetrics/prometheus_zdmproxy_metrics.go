@@ -10,12 +10,19 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 )
 
 const metricsPrefix = "zdm"
 
+const (
+	nativeHistogramMaxBucketNumber  = 160
+	nativeHistogramMinResetDuration = time.Hour
+)
+
 type PrometheusMetricFactory struct {
 	registerer           prometheus.Registerer
+	histogramConfig      *metrics.HistogramConfig
 	lock                 *sync.Mutex
 	registeredCollectors []*collectorEntry
 }
@@ -24,9 +31,13 @@ type PrometheusMetricFactory struct {
 	Instantiation and initialization
  ***/
 
-func NewPrometheusMetricFactory(registerer prometheus.Registerer) *PrometheusMetricFactory {
+// NewPrometheusMetricFactory creates a factory that registers the metrics with the provided registerer.
+// The histogram config is optional, the default buckets and classic histograms are used if it is nil.
+func NewPrometheusMetricFactory(
+	registerer prometheus.Registerer, histogramConfig *metrics.HistogramConfig) *PrometheusMetricFactory {
 	m := &PrometheusMetricFactory{
 		registerer:           registerer,
+		histogramConfig:      histogramConfig,
 		lock:                 &sync.Mutex{},
 		registeredCollectors: make([]*collectorEntry, 0),
 	}
@@ -123,19 +134,17 @@ func (pm *PrometheusMetricFactory) GetOrCreateGauge(mn metrics.Metric) (metrics.
 }
 
 func (pm *PrometheusMetricFactory) GetOrCreateGaugeFunc(mn metrics.Metric, mf func() float64) (metrics.GaugeFunc, error) {
-	var gf prometheus.Collector
-	if mn.GetLabels() != nil {
-		return nil, fmt.Errorf("could not add metric %v because gauge functions don't support labels currently", mn)
-	} else {
-		gf = prometheus.NewGaugeFunc(
-			prometheus.GaugeOpts{
-				Namespace: metricsPrefix,
-				Name:      mn.GetName(),
-				Help:      mn.GetDescription(),
-			},
-			mf,
-		)
-	}
+	// there is no vector of gauge functions, each set of label values is a gauge function with constant labels that
+	// belongs to the same metric family
+	var gf prometheus.Collector = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace:   metricsPrefix,
+			Name:        mn.GetName(),
+			Help:        mn.GetDescription(),
+			ConstLabels: mn.GetLabels(),
+		},
+		mf,
+	)
 
 	var err error
 	gf, err = pm.registerCollector(mn, gf)
@@ -152,23 +161,23 @@ func (pm *PrometheusMetricFactory) GetOrCreateGaugeFunc(mn metrics.Metric, mf fu
 
 func (pm *PrometheusMetricFactory) GetOrCreateHistogram(mn metrics.Metric, buckets []float64) (metrics.Histogram, error) {
 
+	opts := prometheus.HistogramOpts{
+		Namespace: metricsPrefix,
+		Name:      mn.GetName(),
+		Help:      mn.GetDescription(),
+		Buckets:   pm.histogramConfig.GetBuckets(mn, buckets),
+	}
+	if pm.histogramConfig.NativeHistogramsEnabled() {
+		opts.NativeHistogramBucketFactor = pm.histogramConfig.NativeHistogramBucketFactor
+		opts.NativeHistogramMaxBucketNumber = nativeHistogramMaxBucketNumber
+		opts.NativeHistogramMinResetDuration = nativeHistogramMinResetDuration
+	}
+
 	var h prometheus.Collector
 	if mn.GetLabels() != nil {
-		h = prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Namespace: metricsPrefix,
-				Name:      mn.GetName(),
-				Help:      mn.GetDescription(),
-				Buckets:   buckets,
-			},
-			getLabelNames(mn))
+		h = prometheus.NewHistogramVec(opts, getLabelNames(mn))
 	} else {
-		h = prometheus.NewHistogram(prometheus.HistogramOpts{
-			Namespace: metricsPrefix,
-			Name:      mn.GetName(),
-			Help:      mn.GetDescription(),
-			Buckets:   buckets,
-		})
+		h = prometheus.NewHistogram(opts)
 	}
 
 	var err error
@@ -220,11 +229,21 @@ func (pm *PrometheusMetricFactory) UnregisterAllMetrics() error {
 	return nil
 }
 
+// HttpHandler returns a handler that serves the metrics of the registerer if it is also a prometheus.Gatherer
+// (e.g. a *prometheus.Registry), otherwise it serves the metrics of the default registry.
 func (pm *PrometheusMetricFactory) HttpHandler() http.Handler {
-	return promhttp.Handler()
+	if pm.registerer == prometheus.DefaultRegisterer {
+		return promhttp.Handler()
+	}
+	gatherer, ok := pm.registerer.(prometheus.Gatherer)
+	if !ok {
+		log.Warnf("Metrics registerer is not a prometheus.Gatherer, the metrics endpoint serves the default registry.")
+		return promhttp.Handler()
+	}
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
 }
 
-// Register this collector with Prometheus's DefaultRegisterer.
+// Register this collector with the registerer of this factory.
 // If it is a metric with labels and the registerer
 // returns an AlreadyRegisteredError then the returned collector is the existing one (and no error is returned).
 // If the registerer does not return any error, registerCollector returns c, i.e., the provided collector.
@@ -6,12 +6,14 @@ import (
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
 
 func TestNewPrometheusZdmProxyMetrics(t *testing.T) {
-	actual := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	actual := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	assert.NotNil(t, actual)
 	assert.Empty(t, actual.registeredCollectors)
 }
@@ -19,7 +21,7 @@ func TestNewPrometheusZdmProxyMetrics(t *testing.T) {
 func TestPrometheusZdmProxyMetrics_AddCounter(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	counterMetric := newTestMetric("test_counter")
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	assert.Empty(t, handler.registeredCollectors)
 	counter, err := handler.GetOrCreateCounter(counterMetric)
 	assert.Contains(t, handler.registeredCollectors, &collectorEntry{
@@ -41,7 +43,7 @@ func TestPrometheusZdmProxyMetrics_AddCounter(t *testing.T) {
 func TestPrometheusZdmProxyMetrics_AddCounterWithLabels(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	counterMetric := newTestMetricWithLabels("test_counter", map[string]string{"counter_type": "type1"})
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	assert.Empty(t, handler.registeredCollectors)
 
 	counter, err := handler.GetOrCreateCounter(counterMetric)
@@ -70,7 +72,7 @@ func TestPrometheusZdmProxyMetrics_AddCounterWithLabels(t *testing.T) {
 func TestPrometheusZdmProxyMetrics_AddGauge(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	gaugeMetric := newTestMetric("test_gauge")
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	assert.Empty(t, handler.registeredCollectors)
 	gauge, err := handler.GetOrCreateGauge(gaugeMetric)
 	assert.Nil(t, err)
@@ -92,7 +94,7 @@ func TestPrometheusZdmProxyMetrics_AddGauge(t *testing.T) {
 func TestPrometheusZdmProxyMetrics_AddGaugeWithLabels(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	gaugeMetric := newTestMetricWithLabels("test_gauge_with_labels", map[string]string{"gauge_type": "gauge1"})
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	assert.Empty(t, handler.registeredCollectors)
 
 	g, err := handler.GetOrCreateGauge(gaugeMetric)
@@ -123,7 +125,7 @@ func TestPrometheusZdmProxyMetrics_AddGaugeWithLabels(t *testing.T) {
 func TestPrometheusZdmProxyMetrics_AddGaugeFunction(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	gaugeFuncMetric := newTestMetric("test_gauge_func")
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	assert.Empty(t, handler.registeredCollectors)
 	gf, err := handler.GetOrCreateGaugeFunc(gaugeFuncMetric, func() float64 { return 12.34 })
 	assert.Nil(t, err)
@@ -142,10 +144,47 @@ func TestPrometheusZdmProxyMetrics_AddGaugeFunction(t *testing.T) {
 	assert.Len(t, gather, 1)
 }
 
+func TestPrometheusZdmProxyMetrics_AddGaugeFunctionWithLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gaugeFuncMetric := newTestMetricWithLabels("test_gauge_func_with_labels", map[string]string{"gauge_type": "gauge1"})
+	handler := NewPrometheusMetricFactory(registry, nil)
+	assert.Empty(t, handler.registeredCollectors)
+
+	gf, err := handler.GetOrCreateGaugeFunc(gaugeFuncMetric, func() float64 { return 12.34 })
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(handler.registeredCollectors))
+
+	newGf, err := handler.GetOrCreateGaugeFunc(gaugeFuncMetric, func() float64 { return 56.78 })
+	require.Nil(t, err)
+	assert.Equal(t, gf, newGf)
+	assert.Equal(t, 1, len(handler.registeredCollectors))
+
+	gaugeFuncMetric = newTestMetricWithLabels(gaugeFuncMetric.GetName(), map[string]string{"gauge_type": "gauge2"})
+	otherGf, err := handler.GetOrCreateGaugeFunc(gaugeFuncMetric, func() float64 { return 90 })
+	require.Nil(t, err)
+	assert.NotEqual(t, gf, otherGf)
+	assert.Equal(t, 2, len(handler.registeredCollectors))
+
+	gather, err := registry.Gather()
+	require.Nil(t, err)
+	require.Len(t, gather, 1)
+	values := make(map[string]float64)
+	for _, m := range gather[0].GetMetric() {
+		values[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	assert.Equal(t, map[string]float64{"gauge1": 12.34, "gauge2": 90}, values)
+
+	err = handler.UnregisterAllMetrics()
+	require.Nil(t, err)
+	gather, err = registry.Gather()
+	require.Nil(t, err)
+	assert.Len(t, gather, 0)
+}
+
 func TestPrometheusZdmProxyMetrics_AddHistogram(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	histogramMetric := newTestMetric("test_histogram")
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	assert.Empty(t, handler.registeredCollectors)
 	h, err := handler.GetOrCreateHistogram(histogramMetric, nil)
 	assert.Nil(t, err)
@@ -167,7 +206,7 @@ func TestPrometheusZdmProxyMetrics_AddHistogram(t *testing.T) {
 func TestPrometheusZdmProxyMetrics_AddHistogramWithLabels(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	histogramMetric := newTestMetricWithLabels("test_histogram_with_labels", map[string]string{"label1": "value1"})
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	assert.Empty(t, handler.registeredCollectors)
 
 	h, err := handler.GetOrCreateHistogram(histogramMetric, nil)
@@ -198,7 +237,7 @@ func TestPrometheusZdmProxyMetrics_AddHistogramWithLabels(t *testing.T) {
 }
 
 func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Counter(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	counterMetric := newTestMetric("test_add_count_by_one_counter")
 	c, err := handler.GetOrCreateCounter(counterMetric)
 	assert.Nil(t, err)
@@ -209,7 +248,7 @@ func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Counter(t *testing.T) {
 }
 
 func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Gauge(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	gaugeMetric := newTestMetric("test_add_count_by_one_counter")
 	g, err := handler.GetOrCreateGauge(gaugeMetric)
 	assert.Nil(t, err)
@@ -220,7 +259,7 @@ func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Gauge(t *testing.T) {
 }
 
 func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Counter_Labels(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	counterMetric := newTestMetricWithLabels("test_add_count_by_one_counter_labels", map[string]string{"l": "v"})
 	c, err := handler.GetOrCreateCounter(counterMetric)
 	assert.Nil(t, err)
@@ -231,7 +270,7 @@ func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Counter_Labels(t *testing
 }
 
 func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Gauge_Labels(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	gaugeMetric := newTestMetricWithLabels("test_add_count_by_one_counter_labels", map[string]string{"label": "value"})
 	g, err := handler.GetOrCreateGauge(gaugeMetric)
 	assert.Nil(t, err)
@@ -242,7 +281,7 @@ func TestPrometheusZdmProxyMetrics_IncrementCountByOne_Gauge_Labels(t *testing.T
 }
 
 func TestPrometheusZdmProxyMetrics_DecrementCountByOne(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	gaugeMetric := newTestMetric("test_decrement_count_gauge")
 	g, err := handler.GetOrCreateGauge(gaugeMetric)
 	assert.Nil(t, err)
@@ -253,7 +292,7 @@ func TestPrometheusZdmProxyMetrics_DecrementCountByOne(t *testing.T) {
 }
 
 func TestPrometheusZdmProxyMetrics_DecrementCountByOne_Labels(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	gaugeMetric := newTestMetricWithLabels("test_decrement_count_gauge_labels", map[string]string{"label": "value"})
 	g, err := handler.GetOrCreateGauge(gaugeMetric)
 	g.Subtract(1)
@@ -264,7 +303,7 @@ func TestPrometheusZdmProxyMetrics_DecrementCountByOne_Labels(t *testing.T) {
 }
 
 func TestPrometheusZdmProxyMetrics_TrackInHistogram(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	histogramMetric := newTestMetric("test_histogram")
 	h, err := handler.GetOrCreateHistogram(histogramMetric, nil)
 	assert.Nil(t, err)
@@ -280,7 +319,7 @@ func TestPrometheusZdmProxyMetrics_TrackInHistogram(t *testing.T) {
 }
 
 func TestPrometheusZdmProxyMetrics_TrackInHistogram_WithLabels(t *testing.T) {
-	handler := NewPrometheusMetricFactory(prometheus.NewRegistry())
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), nil)
 	histogramMetric := newTestMetricWithLabels("test_histogram_with_labels", map[string]string{"l": "v"})
 	h, err := handler.GetOrCreateHistogram(histogramMetric, nil)
 	assert.Nil(t, err)
@@ -295,9 +334,48 @@ func TestPrometheusZdmProxyMetrics_TrackInHistogram_WithLabels(t *testing.T) {
 	assert.InDelta(t, 500, sum, 5)
 }
 
+func TestPrometheusZdmProxyMetrics_HistogramBucketOverrides(t *testing.T) {
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), &metrics.HistogramConfig{
+		BucketOverrides: map[string][]float64{"test_histogram": {0.1, 1}},
+	})
+	h, err := handler.GetOrCreateHistogram(newTestMetric("test_histogram"), []float64{0.5})
+	require.Nil(t, err)
+	otherH, err := handler.GetOrCreateHistogram(newTestMetric("test_other_histogram"), []float64{0.5})
+	require.Nil(t, err)
+
+	var m = &dto.Metric{}
+	require.Nil(t, h.(*PrometheusHistogram).h.(prometheus.Histogram).Write(m))
+	require.Len(t, m.Histogram.GetBucket(), 2)
+	require.EqualValues(t, 0.1, m.Histogram.GetBucket()[0].GetUpperBound())
+	require.EqualValues(t, 1, m.Histogram.GetBucket()[1].GetUpperBound())
+	require.Zero(t, m.Histogram.GetSchema())
+
+	m = &dto.Metric{}
+	require.Nil(t, otherH.(*PrometheusHistogram).h.(prometheus.Histogram).Write(m))
+	require.Len(t, m.Histogram.GetBucket(), 1)
+	require.EqualValues(t, 0.5, m.Histogram.GetBucket()[0].GetUpperBound())
+}
+
+func TestPrometheusZdmProxyMetrics_NativeHistogram(t *testing.T) {
+	handler := NewPrometheusMetricFactory(prometheus.NewRegistry(), &metrics.HistogramConfig{
+		NativeHistogramBucketFactor: 1.1,
+	})
+	h, err := handler.GetOrCreateHistogram(newTestMetricWithLabels("test_histogram", map[string]string{"l": "v"}), []float64{0.5})
+	require.Nil(t, err)
+	h.Track(time.Now().Add(-time.Millisecond * 100))
+
+	var m = &dto.Metric{}
+	require.Nil(t, h.(*PrometheusHistogram).h.(prometheus.Histogram).Write(m))
+	// classic buckets are still exposed
+	require.Len(t, m.Histogram.GetBucket(), 1)
+	// schema 3 is the highest resolution schema with a bucket growth factor <= 1.1
+	require.EqualValues(t, 3, m.Histogram.GetSchema())
+	require.NotEmpty(t, m.Histogram.GetPositiveSpan())
+}
+
 func TestPrometheusZdmProxyMetrics_UnregisterAllMetrics(t *testing.T) {
 	registry := prometheus.NewRegistry()
-	handler := NewPrometheusMetricFactory(registry)
+	handler := NewPrometheusMetricFactory(registry, nil)
 	counterMetric := newTestMetric("test_counter")
 	counterMetricWithLabels1 := newTestMetricWithLabels("test_counter_with_labels", map[string]string{"counter_type": "counter1"})
 	counterMetricWithLabels2 := newTestMetricWithLabels("test_counter_with_labels", map[string]string{"counter_type": "counter2"})
@@ -335,6 +413,33 @@ func TestPrometheusZdmProxyMetrics_UnregisterAllMetrics(t *testing.T) {
 	assert.Len(t, gather, 0)
 }
 
+func TestPrometheusZdmProxyMetrics_HttpHandlerServesOwnRegistry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	otherRegistry := prometheus.NewRegistry()
+	handler := NewPrometheusMetricFactory(registry, nil)
+	otherHandler := NewPrometheusMetricFactory(otherRegistry, nil)
+
+	counter, err := handler.GetOrCreateCounter(newTestMetric("test_counter"))
+	require.Nil(t, err)
+	counter.Add(3)
+	_, err = otherHandler.GetOrCreateCounter(newTestMetric("test_counter"))
+	require.Nil(t, err)
+	_, err = otherHandler.GetOrCreateCounter(newTestMetric("other_test_counter"))
+	require.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	handler.HttpHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "zdm_test_counter 3")
+	require.NotContains(t, recorder.Body.String(), "other_test_counter")
+
+	recorder = httptest.NewRecorder()
+	otherHandler.HttpHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "zdm_test_counter 0")
+	require.Contains(t, recorder.Body.String(), "zdm_other_test_counter 0")
+}
+
 func getCounterValue(counter prometheus.Counter) (float64, error) {
 	var m = &dto.Metric{}
 	if err := counter.Write(m); err != nil {
@@ -21,9 +21,76 @@ const (
 	requestDurationTypeLabel   = "type"
 	requestDurationDescription = "Histogram that tracks the latency of requests at proxy entry point"
 
+	statementRequestDurationName             = "proxy_statement_request_duration_seconds"
+	statementRequestDurationTypeLabel        = "statement_type"
+	statementRequestDurationForwardedToLabel = "forwarded_to"
+	statementRequestDurationDescription      = "Histogram that tracks the latency of requests at proxy entry point by statement type (read, write, auth or control) and cluster(s) that the requests were forwarded to"
+
+	statementTypeRead    = "read"
+	statementTypeWrite   = "write"
+	statementTypeAuth    = "auth"
+	statementTypeControl = "control"
+
 	inFlightRequestsName        = "proxy_inflight_requests_total"
 	inFlightRequestsTypeLabel   = "type"
 	inFlightRequestsDescription = "Number of requests currently in flight in the proxy"
+
+	queuedRequestsName         = "proxy_queued_requests_total"
+	queuedRequestsClusterLabel = "cluster"
+	queuedRequestsDescription  = "Number of requests currently waiting for the concurrent request limit of a cluster"
+
+	requestQueueDurationName         = "proxy_request_queue_duration_seconds"
+	requestQueueDurationClusterLabel = "cluster"
+	requestQueueDurationDescription  = "Histogram that tracks how long requests wait for the concurrent request limit of a cluster"
+
+	rejectedQueuedRequestsName         = "proxy_rejected_queued_requests_total"
+	rejectedQueuedRequestsClusterLabel = "cluster"
+	rejectedQueuedRequestsDescription  = "Running total of requests rejected because the request queue of a cluster was full or timed out"
+
+	rateLimitedRequestsName         = "proxy_rate_limited_requests_total"
+	rateLimitedRequestsClusterLabel = "cluster"
+	rateLimitedRequestsDescription  = "Running total of requests rejected because the maximum rate of requests to a cluster was reached"
+
+	retriedRequestsName         = "proxy_retried_requests_total"
+	retriedRequestsClusterLabel = "cluster"
+	retriedRequestsDescription  = "Running total of the retries of idempotent requests that failed with a retryable error on a cluster"
+
+	circuitBreakerStateName         = "proxy_circuit_breaker_state"
+	circuitBreakerStateClusterLabel = "cluster"
+	circuitBreakerStateDescription  = "State of the circuit breaker of a cluster: 0 closed, 1 open, 2 half open"
+
+	circuitBreakerRejectedRequestsName         = "proxy_circuit_breaker_rejected_requests_total"
+	circuitBreakerRejectedRequestsClusterLabel = "cluster"
+	circuitBreakerRejectedRequestsDescription  = "Running total of requests rejected because the circuit breaker of a cluster was open"
+
+	latencyBudgetBreachesName         = "proxy_latency_budget_breaches_total"
+	latencyBudgetBreachesClusterLabel = "cluster"
+	latencyBudgetBreachesDescription  = "Running total of the times that the latency of the requests to a cluster exceeded its latency budget"
+
+	readComparisonMismatchesName        = "proxy_read_comparison_mismatches_total"
+	readComparisonMismatchesTypeLabel   = "type"
+	readComparisonMismatchesDescription = "Running total of reads whose primary and secondary responses didn't match"
+
+	writeComparisonMismatchesName        = "proxy_write_comparison_mismatches_total"
+	writeComparisonMismatchesTypeLabel   = "type"
+	writeComparisonMismatchesDescription = "Running total of dual writes whose origin and target responses didn't match"
+
+	clientConnectionsByProtocolVersionName        = "proxy_client_connections_by_protocol_version"
+	clientConnectionsProtocolVersionLabel         = "protocol_version"
+	clientConnectionsByProtocolVersionDescription = "Number of client connections that completed the handshake by negotiated protocol version"
+
+	clientConnectionsByDriverName        = "proxy_client_connections_by_driver"
+	clientConnectionsDriverNameLabel     = "driver_name"
+	clientConnectionsDriverVersionLabel  = "driver_version"
+	clientConnectionsByDriverDescription = "Number of client connections that completed the handshake by DRIVER_NAME and DRIVER_VERSION of the STARTUP request"
+
+	tableRequestsName               = "proxy_table_requests_total"
+	tableRequestsDescription        = "Running total of requests by keyspace, table and cluster(s) that the requests were forwarded to"
+	tableRequestDurationName        = "proxy_table_request_duration_seconds"
+	tableRequestDurationDescription = "Histogram that tracks the latency of requests at proxy entry point by keyspace, table and cluster(s) that the requests were forwarded to"
+	tableRequestsKeyspaceLabel      = "keyspace"
+	tableRequestsTableLabel         = "table"
+	tableRequestsForwardedToLabel   = "forwarded_to"
 )
 
 var (
@@ -63,6 +130,19 @@ var (
 		},
 	)
 
+	StatementDurationReadsOrigin   = newStatementRequestDurationMetric(statementTypeRead, failedRequestsClusterOrigin)
+	StatementDurationReadsTarget   = newStatementRequestDurationMetric(statementTypeRead, failedRequestsClusterTarget)
+	StatementDurationReadsBoth     = newStatementRequestDurationMetric(statementTypeRead, failedRequestsClusterBoth)
+	StatementDurationWritesOrigin  = newStatementRequestDurationMetric(statementTypeWrite, failedRequestsClusterOrigin)
+	StatementDurationWritesTarget  = newStatementRequestDurationMetric(statementTypeWrite, failedRequestsClusterTarget)
+	StatementDurationWritesBoth    = newStatementRequestDurationMetric(statementTypeWrite, failedRequestsClusterBoth)
+	StatementDurationAuthOrigin    = newStatementRequestDurationMetric(statementTypeAuth, failedRequestsClusterOrigin)
+	StatementDurationAuthTarget    = newStatementRequestDurationMetric(statementTypeAuth, failedRequestsClusterTarget)
+	StatementDurationAuthBoth      = newStatementRequestDurationMetric(statementTypeAuth, failedRequestsClusterBoth)
+	StatementDurationControlOrigin = newStatementRequestDurationMetric(statementTypeControl, failedRequestsClusterOrigin)
+	StatementDurationControlTarget = newStatementRequestDurationMetric(statementTypeControl, failedRequestsClusterTarget)
+	StatementDurationControlBoth   = newStatementRequestDurationMetric(statementTypeControl, failedRequestsClusterBoth)
+
 	PSCacheSize = NewMetric(
 		"pscache_entries_total",
 		"Number of entries currently in the prepared statement cache",
@@ -120,6 +200,314 @@ var (
 		"client_connections_total",
 		"Number of client connections currently open",
 	)
+
+	QueuedRequestsOrigin = NewMetricWithLabels(
+		queuedRequestsName,
+		queuedRequestsDescription,
+		map[string]string{
+			queuedRequestsClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	QueuedRequestsTarget = NewMetricWithLabels(
+		queuedRequestsName,
+		queuedRequestsDescription,
+		map[string]string{
+			queuedRequestsClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	RequestQueueDurationOrigin = NewMetricWithLabels(
+		requestQueueDurationName,
+		requestQueueDurationDescription,
+		map[string]string{
+			requestQueueDurationClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	RequestQueueDurationTarget = NewMetricWithLabels(
+		requestQueueDurationName,
+		requestQueueDurationDescription,
+		map[string]string{
+			requestQueueDurationClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	RejectedQueuedRequestsOrigin = NewMetricWithLabels(
+		rejectedQueuedRequestsName,
+		rejectedQueuedRequestsDescription,
+		map[string]string{
+			rejectedQueuedRequestsClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	RejectedQueuedRequestsTarget = NewMetricWithLabels(
+		rejectedQueuedRequestsName,
+		rejectedQueuedRequestsDescription,
+		map[string]string{
+			rejectedQueuedRequestsClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	RateLimitedRequestsOrigin = NewMetricWithLabels(
+		rateLimitedRequestsName,
+		rateLimitedRequestsDescription,
+		map[string]string{
+			rateLimitedRequestsClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	RateLimitedRequestsTarget = NewMetricWithLabels(
+		rateLimitedRequestsName,
+		rateLimitedRequestsDescription,
+		map[string]string{
+			rateLimitedRequestsClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	RetriedRequestsOrigin = NewMetricWithLabels(
+		retriedRequestsName,
+		retriedRequestsDescription,
+		map[string]string{
+			retriedRequestsClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	RetriedRequestsTarget = NewMetricWithLabels(
+		retriedRequestsName,
+		retriedRequestsDescription,
+		map[string]string{
+			retriedRequestsClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	CircuitBreakerStateOrigin = NewMetricWithLabels(
+		circuitBreakerStateName,
+		circuitBreakerStateDescription,
+		map[string]string{
+			circuitBreakerStateClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	CircuitBreakerStateTarget = NewMetricWithLabels(
+		circuitBreakerStateName,
+		circuitBreakerStateDescription,
+		map[string]string{
+			circuitBreakerStateClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	CircuitBreakerRejectedRequestsOrigin = NewMetricWithLabels(
+		circuitBreakerRejectedRequestsName,
+		circuitBreakerRejectedRequestsDescription,
+		map[string]string{
+			circuitBreakerRejectedRequestsClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	CircuitBreakerRejectedRequestsTarget = NewMetricWithLabels(
+		circuitBreakerRejectedRequestsName,
+		circuitBreakerRejectedRequestsDescription,
+		map[string]string{
+			circuitBreakerRejectedRequestsClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	LatencyBudgetBreachesOrigin = NewMetricWithLabels(
+		latencyBudgetBreachesName,
+		latencyBudgetBreachesDescription,
+		map[string]string{
+			latencyBudgetBreachesClusterLabel: failedRequestsClusterOrigin,
+		},
+	)
+	LatencyBudgetBreachesTarget = NewMetricWithLabels(
+		latencyBudgetBreachesName,
+		latencyBudgetBreachesDescription,
+		map[string]string{
+			latencyBudgetBreachesClusterLabel: failedRequestsClusterTarget,
+		},
+	)
+
+	TargetFilteredWrites = NewMetric(
+		"proxy_target_filtered_writes_total",
+		"Running total of writes that were not forwarded to TARGET because they matched a target write filter rule",
+	)
+	TargetUnsampledWrites = NewMetric(
+		"proxy_target_unsampled_writes_total",
+		"Running total of writes that were not forwarded to TARGET because they were not sampled",
+	)
+	LwtRequests = NewMetric(
+		"proxy_lwt_requests_total",
+		"Running total of lightweight transactions (conditional writes) that were handled according to ZDM_LWT_POLICY",
+	)
+	CounterWriteRequests = NewMetric(
+		"proxy_counter_write_requests_total",
+		"Running total of counter updates that were handled according to ZDM_COUNTER_WRITES_POLICY",
+	)
+	CrossClusterPagingStates = NewMetric(
+		"proxy_cross_cluster_paging_states_total",
+		"Running total of reads with a paging state of another cluster than the cluster that they were forwarded to that were handled according to ZDM_PAGING_STATE_POLICY",
+	)
+	SecondaryWriteFailures = NewMetric(
+		"proxy_secondary_write_failures_total",
+		"Running total of writes that only failed on the secondary cluster and that were handled according to ZDM_SECONDARY_WRITE_FAILURE_MODE",
+	)
+	WriteJournalQueued = NewMetric(
+		"proxy_write_journal_queued_total",
+		"Running total of writes that failed on the secondary cluster and were added to the write journal (ZDM_SECONDARY_WRITE_JOURNAL_TYPE)",
+	)
+	WriteJournalReplayed = NewMetric(
+		"proxy_write_journal_replayed_total",
+		"Running total of writes of the write journal that were replayed successfully on the secondary cluster",
+	)
+	WriteJournalDropped = NewMetric(
+		"proxy_write_journal_dropped_total",
+		"Running total of writes that were dropped from the write journal because it was full or because their replay was rejected by the secondary cluster",
+	)
+	WriteJournalPendingEntries = NewMetric(
+		"proxy_write_journal_pending_entries",
+		"Number of writes of the write journal that are waiting to be replayed on the secondary cluster",
+	)
+	SlowQueries = NewMetric(
+		"proxy_slow_queries_total",
+		"Running total of requests that took longer than ZDM_SLOW_QUERY_LOG_THRESHOLD_MS, including the ones that were not logged because of the sampling",
+	)
+	AsyncWritesRetried = NewMetric(
+		"proxy_async_writes_retried_total",
+		"Running total of retries of the writes that were forwarded to the secondary cluster in the background",
+	)
+	AsyncWritesFailed = NewMetric(
+		"proxy_async_writes_failed_total",
+		"Running total of writes that were forwarded to the secondary cluster in the background and failed after all retries",
+	)
+	ReadComparisons = NewMetric(
+		"proxy_read_comparisons_total",
+		"Running total of reads whose primary and secondary responses were compared",
+	)
+	ReadComparisonMismatchesRowCount = NewMetricWithLabels(
+		readComparisonMismatchesName,
+		readComparisonMismatchesDescription,
+		map[string]string{
+			readComparisonMismatchesTypeLabel: "row_count",
+		},
+	)
+	ReadComparisonMismatchesChecksum = NewMetricWithLabels(
+		readComparisonMismatchesName,
+		readComparisonMismatchesDescription,
+		map[string]string{
+			readComparisonMismatchesTypeLabel: "checksum",
+		},
+	)
+	ReadComparisonMismatchesError = NewMetricWithLabels(
+		readComparisonMismatchesName,
+		readComparisonMismatchesDescription,
+		map[string]string{
+			readComparisonMismatchesTypeLabel: "error",
+		},
+	)
+	WriteComparisons = NewMetric(
+		"proxy_write_comparisons_total",
+		"Running total of dual writes whose origin and target responses were compared",
+	)
+	WriteComparisonMismatchesResponse = NewMetricWithLabels(
+		writeComparisonMismatchesName,
+		writeComparisonMismatchesDescription,
+		map[string]string{
+			writeComparisonMismatchesTypeLabel: "response",
+		},
+	)
+	WriteComparisonMismatchesApplied = NewMetricWithLabels(
+		writeComparisonMismatchesName,
+		writeComparisonMismatchesDescription,
+		map[string]string{
+			writeComparisonMismatchesTypeLabel: "applied",
+		},
+	)
+	TargetIncompatibleSchemaChanges = NewMetric(
+		"proxy_target_incompatible_schema_changes_total",
+		"Running total of schema changes that use features that TARGET doesn't support",
+	)
+	ClientConnectionsByProtocolVersion = NewMetricWithLabels(
+		clientConnectionsByProtocolVersionName,
+		clientConnectionsByProtocolVersionDescription,
+		map[string]string{
+			clientConnectionsProtocolVersionLabel: "",
+		},
+	)
+	ClientConnectionsByDriver = NewMetricWithLabels(
+		clientConnectionsByDriverName,
+		clientConnectionsByDriverDescription,
+		map[string]string{
+			clientConnectionsDriverNameLabel:    "",
+			clientConnectionsDriverVersionLabel: "",
+		},
+	)
+	TableRequests = NewMetricWithLabels(
+		tableRequestsName,
+		tableRequestsDescription,
+		map[string]string{
+			tableRequestsKeyspaceLabel:    "",
+			tableRequestsTableLabel:       "",
+			tableRequestsForwardedToLabel: "",
+		},
+	)
+	TableRequestDuration = NewMetricWithLabels(
+		tableRequestDurationName,
+		tableRequestDurationDescription,
+		map[string]string{
+			tableRequestsKeyspaceLabel:    "",
+			tableRequestsTableLabel:       "",
+			tableRequestsForwardedToLabel: "",
+		},
+	)
+
+	DestructiveStatementsRejected = NewMetric(
+		"proxy_destructive_statements_rejected_total",
+		"Running total of TRUNCATE and DROP statements that were rejected because they were not confirmed",
+	)
+
+	ClientLimitedRequests = NewMetric(
+		"proxy_client_limited_requests_total",
+		"Running total of requests rejected because a client connection reached its maximum rate of requests or of in flight requests",
+	)
+
+	InFlightRequests = NewMetric(
+		"proxy_in_flight_requests",
+		"Number of requests that are in flight across all client connections if ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS is set",
+	)
+	BackpressureActivations = NewMetric(
+		"proxy_backpressure_activations_total",
+		"Running total of the times that the client connections stopped reading requests because ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS was reached",
+	)
+
+	ClientIdleConnectionsClosed = NewMetric(
+		"proxy_client_idle_connections_closed_total",
+		"Running total of client connections that were closed because they didn't send a request for ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS",
+	)
+
+	InterceptedResponseCacheHits = NewMetric(
+		"proxy_intercepted_response_cache_hits_total",
+		"Running total of intercepted system queries that were answered with a cached response",
+	)
+	InterceptedResponseCacheMisses = NewMetric(
+		"proxy_intercepted_response_cache_misses_total",
+		"Running total of intercepted system queries whose response was not cached",
+	)
+
+	FleetLeader = NewMetric(
+		"proxy_fleet_leader",
+		"1 if this proxy instance runs the singleton background tasks (it is the elected leader or leader election is disabled), 0 otherwise",
+	)
+
+	MigrationOriginReadsRatio = NewMetric(
+		"proxy_migration_origin_reads_ratio",
+		"Fraction of the reads of the migration progress window that were answered by ORIGIN (0 if there were no reads)",
+	)
+	MigrationDualWriteFailuresRatio = NewMetric(
+		"proxy_migration_dual_write_failures_ratio",
+		"Fraction of the writes of the migration progress window that were forwarded to both clusters and failed on at least one of them",
+	)
+	MigrationReadMismatchesRatio = NewMetric(
+		"proxy_migration_read_mismatches_ratio",
+		"Fraction of the read comparisons of the migration progress window whose responses did not match",
+	)
+	MigrationReplayQueueDepth = NewMetric(
+		"proxy_migration_replay_queue_depth",
+		"Number of writes of the write journal that were waiting to be replayed on the secondary cluster when the migration progress was last sampled",
+	)
 )
 
 type ProxyMetrics struct {
@@ -136,9 +524,89 @@ type ProxyMetrics struct {
 	ProxyReadsTargetDuration Histogram
 	ProxyWritesDuration      Histogram
 
+	StatementDurationReads   *StatementDurations
+	StatementDurationWrites  *StatementDurations
+	StatementDurationAuth    *StatementDurations
+	StatementDurationControl *StatementDurations
+
 	InFlightReadsOrigin Gauge
 	InFlightReadsTarget Gauge
 	InFlightWrites      Gauge
 
 	OpenClientConnections GaugeFunc
+
+	QueuedRequestsOrigin Gauge
+	QueuedRequestsTarget Gauge
+
+	RequestQueueDurationOrigin Histogram
+	RequestQueueDurationTarget Histogram
+
+	RejectedQueuedRequestsOrigin Counter
+	RejectedQueuedRequestsTarget Counter
+
+	RateLimitedRequestsOrigin Counter
+	RateLimitedRequestsTarget Counter
+
+	RetriedRequestsOrigin Counter
+	RetriedRequestsTarget Counter
+
+	CircuitBreakerStateOrigin Gauge
+	CircuitBreakerStateTarget Gauge
+
+	CircuitBreakerRejectedRequestsOrigin Counter
+	CircuitBreakerRejectedRequestsTarget Counter
+
+	LatencyBudgetBreachesOrigin Counter
+	LatencyBudgetBreachesTarget Counter
+
+	TargetFilteredWrites              Counter
+	TargetUnsampledWrites             Counter
+	LwtRequests                       Counter
+	CounterWriteRequests              Counter
+	CrossClusterPagingStates          Counter
+	SecondaryWriteFailures            Counter
+	WriteJournalQueued                Counter
+	WriteJournalReplayed              Counter
+	WriteJournalDropped               Counter
+	WriteJournalPendingEntries        Gauge
+	SlowQueries                       Counter
+	AsyncWritesRetried                Counter
+	AsyncWritesFailed                 Counter
+	ReadComparisons                   Counter
+	ReadComparisonMismatchesRowCount  Counter
+	ReadComparisonMismatchesChecksum  Counter
+	ReadComparisonMismatchesError     Counter
+	WriteComparisons                  Counter
+	WriteComparisonMismatchesResponse Counter
+	WriteComparisonMismatchesApplied  Counter
+	TargetIncompatibleSchemaChanges   Counter
+	DestructiveStatementsRejected     Counter
+	ClientLimitedRequests             Counter
+	InFlightRequests                  GaugeFunc
+	BackpressureActivations           Counter
+	ClientIdleConnectionsClosed       Counter
+
+	InterceptedResponseCacheHits   Counter
+	InterceptedResponseCacheMisses Counter
+
+	FleetLeader GaugeFunc
+}
+
+// StatementDurations holds the latency histograms of a statement type by cluster(s) that the requests were
+// forwarded to.
+type StatementDurations struct {
+	Origin Histogram
+	Target Histogram
+	Both   Histogram
+}
+
+func newStatementRequestDurationMetric(statementType string, forwardedTo string) Metric {
+	return NewMetricWithLabels(
+		statementRequestDurationName,
+		statementRequestDurationDescription,
+		map[string]string{
+			statementRequestDurationTypeLabel:        statementType,
+			statementRequestDurationForwardedToLabel: forwardedTo,
+		},
+	)
 }
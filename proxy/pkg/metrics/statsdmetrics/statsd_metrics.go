@@ -0,0 +1,100 @@
+package statsdmetrics
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type StatsdCounter struct {
+	value int64 // value added since the last flush, first field for the 64-bit alignment of the atomic operations
+	name  string
+	tags  string
+}
+
+func (recv *StatsdCounter) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.value, int64(valueToAdd))
+}
+
+func (recv *StatsdCounter) line() string {
+	value := atomic.SwapInt64(&recv.value, 0)
+	if value == 0 {
+		return ""
+	}
+	return formatLine(recv.name, strconv.FormatInt(value, 10), "c", recv.tags)
+}
+
+type StatsdGauge struct {
+	value int64
+	name  string
+	tags  string
+}
+
+func (recv *StatsdGauge) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.value, int64(valueToAdd))
+}
+
+func (recv *StatsdGauge) Subtract(valueToSubtract int) {
+	atomic.AddInt64(&recv.value, -int64(valueToSubtract))
+}
+
+func (recv *StatsdGauge) line() string {
+	return formatLine(recv.name, strconv.FormatInt(atomic.LoadInt64(&recv.value), 10), "g", recv.tags)
+}
+
+type StatsdGaugeFunc struct {
+	name string
+	tags string
+	mf   func() float64
+}
+
+func (recv *StatsdGaugeFunc) line() string {
+	return formatLine(recv.name, strconv.FormatFloat(recv.mf(), 'f', -1, 64), "g", recv.tags)
+}
+
+// StatsdHistogram sends the tracked durations as StatsD timers in milliseconds on every flush, the percentiles are
+// computed by the StatsD server so the buckets of the histogram are not used. At most maxPending durations are kept
+// between two flushes, the timers are then sent with a sample rate so that the StatsD server still counts all of them.
+type StatsdHistogram struct {
+	name       string
+	tags       string
+	maxPending int
+
+	lock    *sync.Mutex
+	pending []float64
+	tracked int
+}
+
+func (recv *StatsdHistogram) Track(begin time.Time) {
+	elapsedTimeInMs := float64(time.Since(begin)) / float64(time.Millisecond)
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.tracked++
+	if len(recv.pending) < recv.maxPending {
+		recv.pending = append(recv.pending, elapsedTimeInMs)
+	}
+}
+
+// lines returns the timers of the durations tracked since the last flush.
+func (recv *StatsdHistogram) lines() []string {
+	recv.lock.Lock()
+	pending, tracked := recv.pending, recv.tracked
+	recv.pending, recv.tracked = nil, 0
+	recv.lock.Unlock()
+
+	metricType := "ms"
+	if tracked > len(pending) {
+		metricType += "|@" + strconv.FormatFloat(float64(len(pending))/float64(tracked), 'f', 6, 64)
+	}
+	lines := make([]string, 0, len(pending))
+	for _, elapsedTimeInMs := range pending {
+		lines = append(lines, formatLine(recv.name, strconv.FormatFloat(elapsedTimeInMs, 'f', 3, 64), metricType, recv.tags))
+	}
+	return lines
+}
+
+// formatLine returns a line of the StatsD protocol, <name>:<value>|<type> followed by the DogStatsD tags (if any).
+func formatLine(name string, value string, metricType string, tags string) string {
+	return name + ":" + value + "|" + metricType + tags
+}
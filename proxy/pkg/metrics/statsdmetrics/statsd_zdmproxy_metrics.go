@@ -0,0 +1,282 @@
+package statsdmetrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPacketSize is the maximum size of the UDP packets, the lines are batched in packets that fit in the MTU of
+// most networks.
+const maxPacketSize = 1432
+
+// maxPendingDurations is the maximum number of durations that each histogram keeps between two flushes.
+const maxPendingDurations = 10000
+
+// StatsdMetricFactory sends the metrics to a StatsD (or DogStatsD) server over UDP. The counters, gauges, gauge
+// functions and the durations tracked by the histograms are sent on every flush, tracking a metric never writes to
+// the connection.
+type StatsdMetricFactory struct {
+	conn          net.Conn
+	prefix        string
+	tagsEnabled   bool
+	flushInterval time.Duration
+
+	lock       *sync.Mutex
+	counters   map[string]*StatsdCounter
+	gauges     map[string]*StatsdGauge
+	gaugeFuncs map[string]*StatsdGaugeFunc
+	histograms map[string]*StatsdHistogram
+
+	maxPendingDurations int
+
+	packetLock   *sync.Mutex
+	packet       []byte
+	closed       bool
+	writeFailing bool
+}
+
+/***
+	Instantiation and initialization
+ ***/
+
+// NewStatsdMetricFactory creates a factory that sends the metrics to the StatsD server at the provided address.
+// The prefix is prepended to the metric names, the labels are sent as DogStatsD tags if tagsEnabled is true,
+// otherwise their values are appended to the metric names. The metrics are only sent while Run is running.
+func NewStatsdMetricFactory(
+	address string, prefix string, tagsEnabled bool, flushInterval time.Duration) (*StatsdMetricFactory, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the connection to the StatsD server %v: %w", address, err)
+	}
+	return &StatsdMetricFactory{
+		conn:          conn,
+		prefix:        strings.TrimSuffix(prefix, "."),
+		tagsEnabled:   tagsEnabled,
+		flushInterval: flushInterval,
+		lock:          &sync.Mutex{},
+		counters:      make(map[string]*StatsdCounter),
+		gauges:        make(map[string]*StatsdGauge),
+		gaugeFuncs:    make(map[string]*StatsdGaugeFunc),
+		histograms:    make(map[string]*StatsdHistogram),
+
+		maxPendingDurations: maxPendingDurations,
+
+		packetLock: &sync.Mutex{},
+		packet:     make([]byte, 0, maxPacketSize),
+	}, nil
+}
+
+// Run flushes the metrics every flush interval until the context is canceled, then it flushes them one last time
+// and closes the connection.
+func (sm *StatsdMetricFactory) Run(ctx context.Context) {
+	ticker := time.NewTicker(sm.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			sm.Flush()
+			sm.close()
+			return
+		case <-ticker.C:
+			sm.Flush()
+		}
+	}
+}
+
+/***
+	Methods for adding metrics
+ ***/
+
+func (sm *StatsdMetricFactory) GetOrCreateCounter(mn metrics.Metric) (metrics.Counter, error) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	c, ok := sm.counters[mn.String()]
+	if !ok {
+		name, tags := sm.formatMetric(mn)
+		c = &StatsdCounter{name: name, tags: tags}
+		sm.counters[mn.String()] = c
+	}
+	return c, nil
+}
+
+func (sm *StatsdMetricFactory) GetOrCreateGauge(mn metrics.Metric) (metrics.Gauge, error) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	g, ok := sm.gauges[mn.String()]
+	if !ok {
+		name, tags := sm.formatMetric(mn)
+		g = &StatsdGauge{name: name, tags: tags}
+		sm.gauges[mn.String()] = g
+	}
+	return g, nil
+}
+
+func (sm *StatsdMetricFactory) GetOrCreateGaugeFunc(mn metrics.Metric, mf func() float64) (metrics.GaugeFunc, error) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	gf, ok := sm.gaugeFuncs[mn.String()]
+	if !ok {
+		name, tags := sm.formatMetric(mn)
+		gf = &StatsdGaugeFunc{name: name, tags: tags, mf: mf}
+		sm.gaugeFuncs[mn.String()] = gf
+	}
+	return gf, nil
+}
+
+func (sm *StatsdMetricFactory) GetOrCreateHistogram(mn metrics.Metric, buckets []float64) (metrics.Histogram, error) {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	h, ok := sm.histograms[mn.String()]
+	if !ok {
+		name, tags := sm.formatMetric(mn)
+		h = &StatsdHistogram{name: name, tags: tags, maxPending: sm.maxPendingDurations, lock: &sync.Mutex{}}
+		sm.histograms[mn.String()] = h
+	}
+	return h, nil
+}
+
+func (sm *StatsdMetricFactory) UnregisterAllMetrics() error {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	sm.counters = make(map[string]*StatsdCounter)
+	sm.gauges = make(map[string]*StatsdGauge)
+	sm.gaugeFuncs = make(map[string]*StatsdGaugeFunc)
+	sm.histograms = make(map[string]*StatsdHistogram)
+	return nil
+}
+
+// HttpHandler returns the http handler implementation for the metrics endpoint.
+func (sm *StatsdMetricFactory) HttpHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "Metrics are sent to a StatsD server by this proxy instance (ZDM_METRICS_BACKEND).",
+			http.StatusNotFound)
+	})
+}
+
+// Flush sends the counters (the value added since the previous flush), the gauges, the gauge functions and the
+// pending durations of the histograms.
+func (sm *StatsdMetricFactory) Flush() {
+	sm.lock.Lock()
+	lines := make([]string, 0, len(sm.counters)+len(sm.gauges)+len(sm.gaugeFuncs))
+	for _, c := range sm.counters {
+		if line := c.line(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	for _, g := range sm.gauges {
+		lines = append(lines, g.line())
+	}
+	gaugeFuncs := make([]*StatsdGaugeFunc, 0, len(sm.gaugeFuncs))
+	for _, gf := range sm.gaugeFuncs {
+		gaugeFuncs = append(gaugeFuncs, gf)
+	}
+	histograms := make([]*StatsdHistogram, 0, len(sm.histograms))
+	for _, h := range sm.histograms {
+		histograms = append(histograms, h)
+	}
+	sm.lock.Unlock()
+
+	// the gauge functions may acquire other locks of the proxy so they are called without holding the lock
+	for _, gf := range gaugeFuncs {
+		lines = append(lines, gf.line())
+	}
+	for _, h := range histograms {
+		lines = append(lines, h.lines()...)
+	}
+
+	sm.packetLock.Lock()
+	defer sm.packetLock.Unlock()
+	for _, line := range lines {
+		sm.appendLineLocked(line)
+	}
+	sm.writePacketLocked()
+}
+
+// appendLineLocked adds the line to the packet, the packet is written first if the line doesn't fit.
+func (sm *StatsdMetricFactory) appendLineLocked(line string) {
+	if sm.closed {
+		return
+	}
+	if len(sm.packet) > 0 && len(sm.packet)+1+len(line) > maxPacketSize {
+		sm.writePacketLocked()
+	}
+	if len(sm.packet) > 0 {
+		sm.packet = append(sm.packet, '\n')
+	}
+	sm.packet = append(sm.packet, line...)
+}
+
+func (sm *StatsdMetricFactory) writePacketLocked() {
+	if sm.closed || len(sm.packet) == 0 {
+		return
+	}
+	_, err := sm.conn.Write(sm.packet)
+	sm.packet = sm.packet[:0]
+	if err != nil {
+		if !sm.writeFailing {
+			log.Warnf("Could not send the metrics to the StatsD server %v: %v.", sm.conn.RemoteAddr(), err)
+			sm.writeFailing = true
+		}
+		return
+	}
+	if sm.writeFailing {
+		log.Infof("Metrics are being sent to the StatsD server %v again.", sm.conn.RemoteAddr())
+		sm.writeFailing = false
+	}
+}
+
+func (sm *StatsdMetricFactory) close() {
+	sm.packetLock.Lock()
+	defer sm.packetLock.Unlock()
+	sm.closed = true
+	err := sm.conn.Close()
+	if err != nil {
+		log.Debugf("Could not close the connection to the StatsD server %v: %v.", sm.conn.RemoteAddr(), err)
+	}
+}
+
+// formatMetric returns the name of the metric with the prefix and the DogStatsD tags of its labels, the label values
+// are appended to the name instead if the tags are disabled.
+func (sm *StatsdMetricFactory) formatMetric(mn metrics.Metric) (string, string) {
+	name := mn.GetName()
+	if sm.prefix != "" {
+		name = sm.prefix + "." + name
+	}
+
+	labels := mn.GetLabels()
+	if len(labels) == 0 {
+		return name, ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if !sm.tagsEnabled {
+		for _, k := range keys {
+			name = name + "." + nameReplacer.Replace(labels[k])
+		}
+		return name, ""
+	}
+
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, tagReplacer.Replace(k)+":"+tagReplacer.Replace(labels[k]))
+	}
+	return name, "|#" + strings.Join(tags, ",")
+}
+
+// tagReplacer replaces the characters that delimit the fields and the tags of a DogStatsD line.
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+// nameReplacer also replaces the characters that delimit the value and the segments of the metric names.
+var nameReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_", ":", "_", ".", "_", "@", "_", " ", "_")
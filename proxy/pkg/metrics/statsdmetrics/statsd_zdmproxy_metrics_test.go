@@ -0,0 +1,251 @@
+package statsdmetrics
+
+import (
+	"context"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStatsdMetricFactory_Flush(t *testing.T) {
+	server := newTestStatsdServer(t)
+	defer server.close()
+	factory, err := NewStatsdMetricFactory(server.address(), "zdm.", true, time.Minute)
+	require.Nil(t, err)
+	defer factory.close()
+
+	counter, err := factory.GetOrCreateCounter(metrics.NewMetric("test_counter", "counter"))
+	require.Nil(t, err)
+	sameCounter, err := factory.GetOrCreateCounter(metrics.NewMetric("test_counter", "counter"))
+	require.Nil(t, err)
+	require.Same(t, counter, sameCounter)
+	gauge, err := factory.GetOrCreateGauge(metrics.NewMetricWithLabels(
+		"test_gauge", "gauge", map[string]string{"node": "127.0.0.1:9042", "cluster": "origin"}))
+	require.Nil(t, err)
+	_, err = factory.GetOrCreateGaugeFunc(metrics.NewMetric("test_gauge_func", "gauge func"), func() float64 {
+		return 1.5
+	})
+	require.Nil(t, err)
+
+	counter.Add(2)
+	counter.Add(3)
+	gauge.Add(5)
+	gauge.Subtract(1)
+	factory.Flush()
+	require.ElementsMatch(t, []string{
+		"zdm.test_counter:5|c",
+		"zdm.test_gauge:4|g|#cluster:origin,node:127.0.0.1:9042",
+		"zdm.test_gauge_func:1.5|g",
+	}, server.receive(t, 3))
+
+	// the counters are only sent if they changed since the previous flush
+	factory.Flush()
+	require.ElementsMatch(t, []string{
+		"zdm.test_gauge:4|g|#cluster:origin,node:127.0.0.1:9042",
+		"zdm.test_gauge_func:1.5|g",
+	}, server.receive(t, 2))
+}
+
+func TestStatsdMetricFactory_Histogram(t *testing.T) {
+	server := newTestStatsdServer(t)
+	defer server.close()
+	factory, err := NewStatsdMetricFactory(server.address(), "", true, time.Minute)
+	require.Nil(t, err)
+	defer factory.close()
+
+	histogram, err := factory.GetOrCreateHistogram(metrics.NewMetricWithLabels(
+		"test_histogram", "histogram", map[string]string{"type": "reads_origin"}), []float64{0.1, 1})
+	require.Nil(t, err)
+	histogram.Track(time.Now().Add(-250 * time.Millisecond))
+	factory.Flush()
+
+	lines := server.receive(t, 1)
+	require.Regexp(t, `^test_histogram:25\d\.\d{3}\|ms\|#type:reads_origin$`, lines[0])
+}
+
+func TestStatsdMetricFactory_HistogramSampleRate(t *testing.T) {
+	server := newTestStatsdServer(t)
+	defer server.close()
+	factory, err := NewStatsdMetricFactory(server.address(), "", false, time.Minute)
+	require.Nil(t, err)
+	defer factory.close()
+	factory.maxPendingDurations = 2
+
+	histogram, err := factory.GetOrCreateHistogram(metrics.NewMetric("test_histogram", "histogram"), nil)
+	require.Nil(t, err)
+	for i := 0; i < 8; i++ {
+		histogram.Track(time.Now())
+	}
+	factory.Flush()
+	for _, line := range server.receive(t, 2) {
+		require.Regexp(t, `^test_histogram:\d+\.\d{3}\|ms\|@0\.250000$`, line)
+	}
+
+	// the sample rate is only sent when durations were dropped
+	histogram.Track(time.Now())
+	factory.Flush()
+	require.Regexp(t, `^test_histogram:\d+\.\d{3}\|ms$`, server.receive(t, 1)[0])
+}
+
+func TestStatsdMetricFactory_TagsDisabled(t *testing.T) {
+	server := newTestStatsdServer(t)
+	defer server.close()
+	factory, err := NewStatsdMetricFactory(server.address(), "zdm", false, time.Minute)
+	require.Nil(t, err)
+	defer factory.close()
+
+	counter, err := factory.GetOrCreateCounter(metrics.NewMetricWithLabels(
+		"test_counter", "counter", map[string]string{"node": "127.0.0.1:9042", "cluster": "origin"}))
+	require.Nil(t, err)
+	counter.Add(1)
+	factory.Flush()
+	require.Equal(t, []string{"zdm.test_counter.origin.127_0_0_1_9042:1|c"}, server.receive(t, 1))
+}
+
+func TestStatsdMetricFactory_PacketSize(t *testing.T) {
+	server := newTestStatsdServer(t)
+	defer server.close()
+	factory, err := NewStatsdMetricFactory(server.address(), "zdm", true, time.Minute)
+	require.Nil(t, err)
+	defer factory.close()
+
+	histogram, err := factory.GetOrCreateHistogram(metrics.NewMetric("test_histogram", "histogram"), nil)
+	require.Nil(t, err)
+	count := 2 * maxPacketSize / len("zdm.test_histogram:0.000|ms")
+	for i := 0; i < count; i++ {
+		histogram.Track(time.Now())
+	}
+	factory.Flush()
+	require.Len(t, server.receive(t, count), count)
+	for _, packetSize := range server.getPacketSizes() {
+		require.LessOrEqual(t, packetSize, maxPacketSize)
+	}
+}
+
+func TestStatsdMetricFactory_Run(t *testing.T) {
+	server := newTestStatsdServer(t)
+	defer server.close()
+	factory, err := NewStatsdMetricFactory(server.address(), "zdm", true, 50*time.Millisecond)
+	require.Nil(t, err)
+
+	counter, err := factory.GetOrCreateCounter(metrics.NewMetric("test_counter", "counter"))
+	require.Nil(t, err)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		factory.Run(ctx)
+	}()
+
+	counter.Add(1)
+	require.Equal(t, []string{"zdm.test_counter:1|c"}, server.receive(t, 1))
+
+	// the metrics are flushed one last time when the context is canceled
+	counter.Add(2)
+	cancelFn()
+	<-done
+	require.Equal(t, []string{"zdm.test_counter:2|c"}, server.receive(t, 1))
+
+	// the metrics that are tracked after the connection was closed are dropped
+	counter.Add(3)
+	factory.Flush()
+}
+
+func TestStatsdMetricFactory_UnregisterAllMetrics(t *testing.T) {
+	server := newTestStatsdServer(t)
+	defer server.close()
+	factory, err := NewStatsdMetricFactory(server.address(), "zdm", true, time.Minute)
+	require.Nil(t, err)
+	defer factory.close()
+
+	_, err = factory.GetOrCreateGauge(metrics.NewMetric("test_gauge", "gauge"))
+	require.Nil(t, err)
+	require.Nil(t, factory.UnregisterAllMetrics())
+	_, err = factory.GetOrCreateGauge(metrics.NewMetric("other_gauge", "gauge"))
+	require.Nil(t, err)
+	factory.Flush()
+	require.Equal(t, []string{"zdm.other_gauge:0|g"}, server.receive(t, 1))
+}
+
+func TestStatsdMetricFactory_HttpHandler(t *testing.T) {
+	factory, err := NewStatsdMetricFactory("127.0.0.1:8125", "zdm", true, time.Minute)
+	require.Nil(t, err)
+	defer factory.close()
+
+	rsp := httptest.NewRecorder()
+	factory.HttpHandler().ServeHTTP(rsp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusNotFound, rsp.Code)
+}
+
+type testStatsdServer struct {
+	conn        net.PacketConn
+	lock        *sync.Mutex
+	lines       []string
+	packetSizes []int
+	done        chan struct{}
+}
+
+func newTestStatsdServer(t *testing.T) *testStatsdServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := &testStatsdServer{
+		conn: conn,
+		lock: &sync.Mutex{},
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(server.done)
+		buf := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			server.lock.Lock()
+			server.packetSizes = append(server.packetSizes, n)
+			server.lines = append(server.lines, strings.Split(string(buf[:n]), "\n")...)
+			server.lock.Unlock()
+		}
+	}()
+	return server
+}
+
+func (recv *testStatsdServer) address() string {
+	return recv.conn.LocalAddr().String()
+}
+
+// receive waits for the provided number of lines and returns them (sorted), it fails if there are more lines.
+func (recv *testStatsdServer) receive(t *testing.T, count int) []string {
+	var lines []string
+	require.Eventually(t, func() bool {
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		if len(recv.lines) < count {
+			return false
+		}
+		lines = recv.lines
+		recv.lines = nil
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	sort.Strings(lines)
+	require.Len(t, lines, count, "unexpected lines: %v", lines)
+	return lines
+}
+
+func (recv *testStatsdServer) getPacketSizes() []int {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]int(nil), recv.packetSizes...)
+}
+
+func (recv *testStatsdServer) close() {
+	_ = recv.conn.Close()
+	<-recv.done
+}
@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"net"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	pcapMagic       = 0xa1b2c3d4
+	pcapLinkTypeRaw = 101
+)
+
+var codec = frame.NewRawCodec()
+
+// Capture has the frames of one or more capture files that were written by the traffic capture of the proxy
+// (see zdmproxy.TrafficCapture), in the order in which they were captured.
+type Capture struct {
+	Frames []*CapturedFrame
+
+	// number of frames that can't be replayed because they are compressed
+	SkippedFrames int
+}
+
+type CapturedFrame struct {
+	Time time.Time
+
+	// address of the client connection, the frames of a connection are replayed on the same connection
+	Client string
+
+	Frame *frame.Frame
+}
+
+// ReadCaptureFiles reads the provided capture files, the frames of the files are merged by capture time.
+func ReadCaptureFiles(fileNames []string) (*Capture, error) {
+	capture := &Capture{}
+	for _, fileName := range fileNames {
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("could not read capture file %v: %w", fileName, err)
+		}
+		err = capture.readPcap(fileName, data)
+		if err != nil {
+			return nil, fmt.Errorf("could not read capture file %v: %w", fileName, err)
+		}
+	}
+	sort.SliceStable(capture.Frames, func(i, j int) bool {
+		return capture.Frames[i].Time.Before(capture.Frames[j].Time)
+	})
+	return capture, nil
+}
+
+// readPcap reassembles the TCP streams of a libpcap file with raw IP packets and decodes their frames.
+func (recv *Capture) readPcap(fileName string, data []byte) error {
+	if len(data) < 24 || binary.LittleEndian.Uint32(data[0:]) != pcapMagic {
+		return fmt.Errorf("not a pcap file written by the proxy")
+	}
+	if linkType := binary.LittleEndian.Uint32(data[20:]); linkType != pcapLinkTypeRaw {
+		return fmt.Errorf("unsupported link type %v, expected %v (raw IP)", linkType, pcapLinkTypeRaw)
+	}
+	data = data[24:]
+
+	streams := make(map[string]*bytes.Buffer)
+	for len(data) > 0 {
+		if len(data) < 16 {
+			return fmt.Errorf("truncated packet header")
+		}
+		timestamp := time.Unix(int64(binary.LittleEndian.Uint32(data[0:])), int64(binary.LittleEndian.Uint32(data[4:]))*1000)
+		packetLength := int(binary.LittleEndian.Uint32(data[8:]))
+		if len(data) < 16+packetLength {
+			return fmt.Errorf("truncated packet")
+		}
+		packet := data[16 : 16+packetLength]
+		data = data[16+packetLength:]
+
+		src, dst, payload, err := parseTcpPacket(packet)
+		if err != nil {
+			return err
+		}
+		streamKey := src.String() + "->" + dst.String()
+		stream, ok := streams[streamKey]
+		if !ok {
+			stream = &bytes.Buffer{}
+			streams[streamKey] = stream
+		}
+		stream.Write(payload)
+
+		for stream.Len() >= primitive.FrameHeaderLengthV3AndHigher {
+			bodyLength := int(binary.BigEndian.Uint32(stream.Bytes()[5:]))
+			if stream.Len() < primitive.FrameHeaderLengthV3AndHigher+bodyLength {
+				break
+			}
+			rawFrame, err := codec.DecodeRawFrame(stream)
+			if err != nil {
+				return fmt.Errorf("could not decode frame of stream %v: %w", streamKey, err)
+			}
+			if rawFrame.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+				recv.SkippedFrames++
+				continue
+			}
+			decodedFrame, err := codec.ConvertFromRawFrame(rawFrame)
+			if err != nil {
+				return fmt.Errorf("could not decode frame %v of stream %v: %w", rawFrame.Header, streamKey, err)
+			}
+			client := src
+			if decodedFrame.Header.IsResponse {
+				client = dst
+			}
+			recv.Frames = append(recv.Frames, &CapturedFrame{
+				Time:   timestamp,
+				Client: fileName + "/" + client.String(),
+				Frame:  decodedFrame,
+			})
+		}
+	}
+	return nil
+}
+
+func parseTcpPacket(packet []byte) (*net.TCPAddr, *net.TCPAddr, []byte, error) {
+	var srcIp, dstIp net.IP
+	var tcpSegment []byte
+	switch {
+	case len(packet) >= 20 && packet[0]>>4 == 4:
+		headerLength := int(packet[0]&0x0f) * 4
+		if len(packet) < headerLength {
+			return nil, nil, nil, fmt.Errorf("truncated IPv4 packet")
+		}
+		srcIp, dstIp, tcpSegment = packet[12:16], packet[16:20], packet[headerLength:]
+	case len(packet) >= 40 && packet[0]>>4 == 6:
+		srcIp, dstIp, tcpSegment = packet[8:24], packet[24:40], packet[40:]
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported IP packet")
+	}
+	if len(tcpSegment) < 20 || len(tcpSegment) < int(tcpSegment[12]>>4)*4 {
+		return nil, nil, nil, fmt.Errorf("truncated TCP segment")
+	}
+	src := &net.TCPAddr{IP: srcIp, Port: int(binary.BigEndian.Uint16(tcpSegment[0:]))}
+	dst := &net.TCPAddr{IP: dstIp, Port: int(binary.BigEndian.Uint16(tcpSegment[2:]))}
+	return src, dst, tcpSegment[int(tcpSegment[12]>>4)*4:], nil
+}
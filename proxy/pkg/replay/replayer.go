@@ -0,0 +1,363 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// Config is the configuration of a replay.
+type Config struct {
+	// Address (host:port) of the node of the cluster that receives the requests.
+	Address string
+
+	// Credentials are nil if the cluster doesn't require authentication.
+	Credentials *client.AuthCredentials
+
+	// Keyspace is set on every connection before the requests are replayed if not empty, the USE requests of the
+	// capture are replayed too.
+	Keyspace string
+
+	// Speed is the factor that is applied to the pace of the capture, e.g. 2 replays the requests twice as fast as
+	// they were captured and 0 replays them as fast as possible.
+	Speed float64
+
+	// ReadTimeout is the timeout of each request.
+	ReadTimeout time.Duration
+}
+
+// Result is the outcome of a replay.
+type Result struct {
+	Connections     int
+	Requests        int
+	FailedRequests  int
+	SkippedRequests int
+
+	// number of failed requests by error (e.g. the error code of the responses)
+	Errors map[string]int
+}
+
+// Replay re-executes the requests of the capture against the cluster. The requests of each captured client
+// connection are sent on a connection of their own one after the other, in the order in which they were captured.
+// The handshake requests (STARTUP, AUTH_RESPONSE, OPTIONS and REGISTER) are not replayed, the connections perform
+// their own handshake with the credentials of the configuration.
+//
+// The prepared statement ids of the capture are replaced with the ids that are returned by the cluster and the
+// statements are prepared again when the cluster returns UNPREPARED, as long as the PREPARE request and its response
+// are part of the capture.
+func Replay(ctx context.Context, capture *Capture, conf *Config) (*Result, error) {
+	replayer := newReplayer(capture, conf)
+
+	var connections []*replayConnection
+	connectionsByClient := make(map[string]*replayConnection)
+	var requests []*CapturedFrame
+	for _, capturedFrame := range capture.Frames {
+		if capturedFrame.Frame.Header.IsResponse {
+			continue
+		}
+		if !isReplayedRequest(capturedFrame.Frame) {
+			replayer.result.SkippedRequests++
+			continue
+		}
+		connection, ok := connectionsByClient[capturedFrame.Client]
+		if !ok {
+			connection = &replayConnection{client: capturedFrame.Client, version: capturedFrame.Frame.Header.Version}
+			connectionsByClient[capturedFrame.Client] = connection
+			connections = append(connections, connection)
+		}
+		connection.pending++
+		requests = append(requests, capturedFrame)
+	}
+	replayer.result.Connections = len(connections)
+
+	wg := &sync.WaitGroup{}
+	for _, connection := range connections {
+		connection.requests = make(chan *frame.Frame, connection.pending)
+		wg.Add(1)
+		go func(connection *replayConnection) {
+			defer wg.Done()
+			replayer.runConnection(ctx, connection)
+		}(connection)
+	}
+
+	err := replayer.dispatch(ctx, requests, connectionsByClient)
+	for _, connection := range connections {
+		close(connection.requests)
+	}
+	wg.Wait()
+	return replayer.result, err
+}
+
+// dispatch sends the requests to the connections in the order in which they were captured, at the pace of the
+// capture if Config.Speed is positive.
+func (recv *replayer) dispatch(
+	ctx context.Context, requests []*CapturedFrame, connections map[string]*replayConnection) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	firstRequestTime := requests[0].Time
+	for _, request := range requests {
+		if recv.conf.Speed > 0 {
+			delay := time.Duration(float64(request.Time.Sub(firstRequestTime))/recv.conf.Speed) - time.Since(start)
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		connections[request.Client].requests <- request.Frame
+	}
+	return nil
+}
+
+type replayer struct {
+	conf *Config
+
+	// PREPARE requests of the capture keyed by the prepared statement id that was returned when they were captured
+	preparedStatements map[string]*message.Prepare
+
+	lock   *sync.Mutex
+	result *Result
+
+	// prepared statements that were returned by the cluster keyed by the id of the capture
+	preparedResults map[string]*message.PreparedResult
+}
+
+type replayConnection struct {
+	client   string
+	version  primitive.ProtocolVersion
+	pending  int
+	requests chan *frame.Frame
+}
+
+func newReplayer(capture *Capture, conf *Config) *replayer {
+	return &replayer{
+		conf:               conf,
+		preparedStatements: findPreparedStatements(capture),
+		lock:               &sync.Mutex{},
+		result:             &Result{Errors: make(map[string]int)},
+		preparedResults:    make(map[string]*message.PreparedResult),
+	}
+}
+
+// findPreparedStatements matches the PREPARE requests of the capture with their responses.
+func findPreparedStatements(capture *Capture) map[string]*message.Prepare {
+	preparedStatements := make(map[string]*message.Prepare)
+	pendingPrepares := make(map[string]*message.Prepare)
+	for _, capturedFrame := range capture.Frames {
+		key := fmt.Sprintf("%v/%v", capturedFrame.Client, capturedFrame.Frame.Header.StreamId)
+		if !capturedFrame.Frame.Header.IsResponse {
+			if prepare, ok := capturedFrame.Frame.Body.Message.(*message.Prepare); ok {
+				pendingPrepares[key] = prepare
+			} else {
+				delete(pendingPrepares, key)
+			}
+			continue
+		}
+		prepare, ok := pendingPrepares[key]
+		if !ok {
+			continue
+		}
+		delete(pendingPrepares, key)
+		if preparedResult, ok := capturedFrame.Frame.Body.Message.(*message.PreparedResult); ok {
+			preparedStatements[string(preparedResult.PreparedQueryId)] = prepare
+		}
+	}
+	return preparedStatements
+}
+
+func isReplayedRequest(f *frame.Frame) bool {
+	switch f.Body.Message.(type) {
+	case *message.Query, *message.Prepare, *message.Execute, *message.Batch:
+		return true
+	default:
+		return false
+	}
+}
+
+func (recv *replayer) runConnection(ctx context.Context, connection *replayConnection) {
+	var conn *client.CqlClientConnection
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	var connectErr error
+	for request := range connection.requests {
+		if ctx.Err() != nil {
+			continue
+		}
+		if conn == nil && connectErr == nil {
+			conn, connectErr = recv.connect(ctx, connection.version)
+			if connectErr != nil {
+				log.Warnf("Could not open the connection that replays the requests of %v: %v.", connection.client, connectErr)
+			} else {
+				log.Debugf("Replaying the requests of %v on connection %v.", connection.client, conn)
+			}
+		}
+		if connectErr != nil {
+			recv.recordFailure("could not connect")
+			continue
+		}
+		recv.replayRequest(conn, connection.client, request)
+	}
+}
+
+func (recv *replayer) connect(ctx context.Context, version primitive.ProtocolVersion) (*client.CqlClientConnection, error) {
+	cqlClient := client.NewCqlClient(recv.conf.Address, recv.conf.Credentials)
+	if recv.conf.ReadTimeout > 0 {
+		cqlClient.ReadTimeout = recv.conf.ReadTimeout
+	}
+	conn, err := cqlClient.ConnectAndInit(ctx, version, client.ManagedStreamId)
+	if err != nil {
+		return nil, err
+	}
+	if recv.conf.Keyspace != "" {
+		response, err := conn.SendAndReceive(
+			frame.NewFrame(version, client.ManagedStreamId, &message.Query{Query: "USE " + recv.conf.Keyspace}))
+		if err == nil {
+			if errorMessage, ok := response.Body.Message.(message.Error); ok {
+				err = fmt.Errorf("%v", errorMessage)
+			}
+		}
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("could not set keyspace %v: %w", recv.conf.Keyspace, err)
+		}
+	}
+	return conn, nil
+}
+
+func (recv *replayer) replayRequest(conn *client.CqlClientConnection, clientAddr string, request *frame.Frame) {
+	request.Header.StreamId = client.ManagedStreamId
+	recv.replacePreparedIds(request.Body.Message)
+	response, err := conn.SendAndReceive(request)
+	if err == nil {
+		if unprepared, ok := response.Body.Message.(*message.Unprepared); ok && recv.prepareAgain(conn, request, unprepared) {
+			response, err = conn.SendAndReceive(request)
+		}
+	}
+	if err != nil {
+		log.Debugf("Replayed request %v of %v failed: %v.", request.Header, clientAddr, err)
+		recv.recordFailure("request error")
+		return
+	}
+
+	switch msg := response.Body.Message.(type) {
+	case message.Error:
+		log.Debugf("Replayed request %v of %v returned %v.", request.Header, clientAddr, msg)
+		recv.recordFailure(msg.GetErrorCode().String())
+	case *message.PreparedResult:
+		if prepare, ok := request.Body.Message.(*message.Prepare); ok {
+			recv.recordPreparedResult(prepare, msg)
+		}
+		recv.recordSuccess()
+	default:
+		recv.recordSuccess()
+	}
+}
+
+// prepareAgain prepares the statement that the cluster doesn't know on the connection and replaces its id in the
+// request, it returns false if the statement is not part of the capture.
+func (recv *replayer) prepareAgain(
+	conn *client.CqlClientConnection, request *frame.Frame, unprepared *message.Unprepared) bool {
+	capturedId, ok := recv.capturedIdOf(unprepared.Id)
+	if !ok {
+		return false
+	}
+	prepare := recv.preparedStatements[capturedId]
+	response, err := conn.SendAndReceive(frame.NewFrame(request.Header.Version, client.ManagedStreamId, prepare))
+	if err != nil {
+		return false
+	}
+	preparedResult, ok := response.Body.Message.(*message.PreparedResult)
+	if !ok {
+		return false
+	}
+	recv.recordPreparedResult(prepare, preparedResult)
+	replacePreparedId(request.Body.Message, unprepared.Id, preparedResult)
+	return true
+}
+
+// capturedIdOf returns the id of the capture of a prepared statement id that was sent to the cluster.
+func (recv *replayer) capturedIdOf(id []byte) (string, bool) {
+	if _, ok := recv.preparedStatements[string(id)]; ok {
+		return string(id), true
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for capturedId, preparedResult := range recv.preparedResults {
+		if string(preparedResult.PreparedQueryId) == string(id) {
+			return capturedId, true
+		}
+	}
+	return "", false
+}
+
+func (recv *replayer) recordPreparedResult(prepare *message.Prepare, preparedResult *message.PreparedResult) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for capturedId, capturedPrepare := range recv.preparedStatements {
+		if capturedPrepare.Query == prepare.Query && capturedPrepare.Keyspace == prepare.Keyspace {
+			recv.preparedResults[capturedId] = preparedResult
+		}
+	}
+}
+
+// replacePreparedIds replaces the prepared statement ids of the capture with the ids that were returned by the
+// cluster.
+func (recv *replayer) replacePreparedIds(msg message.Message) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for capturedId, preparedResult := range recv.preparedResults {
+		replacePreparedId(msg, []byte(capturedId), preparedResult)
+	}
+}
+
+func replacePreparedId(msg message.Message, id []byte, preparedResult *message.PreparedResult) {
+	switch typedMsg := msg.(type) {
+	case *message.Execute:
+		if string(typedMsg.QueryId) == string(id) {
+			typedMsg.QueryId = preparedResult.PreparedQueryId
+			if typedMsg.ResultMetadataId != nil {
+				typedMsg.ResultMetadataId = preparedResult.ResultMetadataId
+			}
+		}
+	case *message.Batch:
+		for _, child := range typedMsg.Children {
+			if childId, ok := child.QueryOrId.([]byte); ok && string(childId) == string(id) {
+				child.QueryOrId = preparedResult.PreparedQueryId
+			}
+		}
+	}
+}
+
+func (recv *replayer) recordSuccess() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.result.Requests++
+}
+
+func (recv *replayer) recordFailure(reason string) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.result.Requests++
+	recv.result.FailedRequests++
+	recv.result.Errors[reason]++
+}
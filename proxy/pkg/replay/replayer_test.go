@@ -0,0 +1,148 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCluster records the requests that it receives and forgets a prepared statement after its first EXECUTE.
+type fakeCluster struct {
+	lock        *sync.Mutex
+	requests    []string
+	preparedIds map[string]bool
+	prepares    int
+}
+
+func startFakeCluster(t *testing.T) (*fakeCluster, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	address := listener.Addr().String()
+	require.Nil(t, listener.Close())
+
+	cluster := &fakeCluster{lock: &sync.Mutex{}, preparedIds: map[string]bool{}}
+	server := client.NewCqlServer(address, &client.AuthCredentials{Username: "user", Password: "password"})
+	server.RequestHandlers = []client.RequestHandler{client.HandshakeHandler, cluster.handle}
+	require.Nil(t, server.Start(context.Background()))
+	t.Cleanup(func() { _ = server.Close() })
+	return cluster, address
+}
+
+func (recv *fakeCluster) handle(request *frame.Frame, _ *client.CqlServerConnection, _ client.RequestHandlerContext) *frame.Frame {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	var response message.Message = &message.VoidResult{}
+	switch msg := request.Body.Message.(type) {
+	case *message.Query:
+		recv.requests = append(recv.requests, msg.Query)
+		if msg.Query == "invalid" {
+			response = &message.Invalid{ErrorMessage: "invalid query"}
+		}
+	case *message.Prepare:
+		recv.prepares++
+		id := fmt.Sprintf("id-%v", recv.prepares)
+		recv.preparedIds[id] = true
+		recv.requests = append(recv.requests, "PREPARE "+msg.Query)
+		response = &message.PreparedResult{PreparedQueryId: []byte(id)}
+	case *message.Execute:
+		recv.requests = append(recv.requests, "EXECUTE "+string(msg.QueryId))
+		if !recv.preparedIds[string(msg.QueryId)] {
+			response = &message.Unprepared{ErrorMessage: "unprepared", Id: msg.QueryId}
+		}
+		delete(recv.preparedIds, string(msg.QueryId))
+	default:
+		return nil
+	}
+	return frame.NewFrame(request.Header.Version, request.Header.StreamId, response)
+}
+
+func (recv *fakeCluster) receivedRequests() []string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]string{}, recv.requests...)
+}
+
+func newCapturedFrame(t time.Time, client string, streamId int16, msg message.Message) *CapturedFrame {
+	return &CapturedFrame{Time: t, Client: client, Frame: frame.NewFrame(primitive.ProtocolVersion4, streamId, msg)}
+}
+
+func TestReplay(t *testing.T) {
+	cluster, address := startFakeCluster(t)
+	now := time.Now()
+	capture := &Capture{Frames: []*CapturedFrame{
+		newCapturedFrame(now, "a", 0, &message.Startup{}),
+		newCapturedFrame(now, "a", 1, &message.Query{Query: "INSERT 1"}),
+		newCapturedFrame(now, "a", 1, &message.VoidResult{}),
+		newCapturedFrame(now, "a", 2, &message.Prepare{Query: "SELECT 1"}),
+		newCapturedFrame(now, "a", 2, &message.PreparedResult{PreparedQueryId: []byte("captured-id")}),
+		newCapturedFrame(now, "a", 3, &message.Execute{QueryId: []byte("captured-id")}),
+		newCapturedFrame(now, "a", 4, &message.Execute{QueryId: []byte("captured-id")}),
+		newCapturedFrame(now, "a", 5, &message.Query{Query: "invalid"}),
+		newCapturedFrame(now, "b", 1, &message.Execute{QueryId: []byte("unknown-id")}),
+	}}
+
+	result, err := Replay(context.Background(), capture, &Config{
+		Address:     address,
+		Credentials: &client.AuthCredentials{Username: "user", Password: "password"},
+		Keyspace:    "ks",
+	})
+	require.Nil(t, err)
+	require.Equal(t, &Result{
+		Connections:     2,
+		Requests:        6,
+		FailedRequests:  2,
+		SkippedRequests: 1,
+		Errors: map[string]int{
+			primitive.ErrorCodeInvalid.String():    1,
+			primitive.ErrorCodeUnprepared.String(): 1,
+		},
+	}, result)
+
+	var requestsOfA []string
+	for _, request := range cluster.receivedRequests() {
+		if request != "EXECUTE unknown-id" && request != "USE ks" {
+			requestsOfA = append(requestsOfA, request)
+		}
+	}
+	// the second EXECUTE is sent again after the statement is prepared again
+	require.Equal(t, []string{
+		"INSERT 1", "PREPARE SELECT 1", "EXECUTE id-1", "EXECUTE id-1", "PREPARE SELECT 1", "EXECUTE id-2", "invalid",
+	}, requestsOfA)
+	require.Contains(t, cluster.receivedRequests(), "USE ks")
+
+	_, err = Replay(context.Background(), capture, &Config{Address: address})
+	require.Nil(t, err)
+}
+
+func TestReplay_Pacing(t *testing.T) {
+	cluster, address := startFakeCluster(t)
+	now := time.Now()
+	capture := &Capture{Frames: []*CapturedFrame{
+		newCapturedFrame(now, "a", 1, &message.Query{Query: "first"}),
+		newCapturedFrame(now.Add(400*time.Millisecond), "b", 1, &message.Query{Query: "second"}),
+	}}
+	conf := &Config{Address: address, Credentials: &client.AuthCredentials{Username: "user", Password: "password"}}
+
+	conf.Speed = 2
+	start := time.Now()
+	result, err := Replay(context.Background(), capture, conf)
+	require.Nil(t, err)
+	require.Equal(t, 2, result.Requests)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Equal(t, []string{"first", "second"}, cluster.receivedRequests())
+
+	conf.Speed = 0.1
+	ctx, cancelFn := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelFn()
+	result, err = Replay(ctx, capture, conf)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, 1, result.Requests)
+}
@@ -3,7 +3,7 @@ package runner
 import (
 	"context"
 	"errors"
-	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/admin"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
 	"github.com/datastax/zdm-proxy/proxy/pkg/health"
 	"github.com/datastax/zdm-proxy/proxy/pkg/httpzdmproxy"
@@ -11,30 +11,55 @@ import (
 	"github.com/datastax/zdm-proxy/proxy/pkg/zdmproxy"
 	"github.com/jpillora/backoff"
 	log "github.com/sirupsen/logrus"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
-func SetupHandlers() (metricsHandler *httpzdmproxy.HandlerWithFallback, readinessHandler *httpzdmproxy.HandlerWithFallback) {
+func SetupHandlers() (
+	metricsHandler *httpzdmproxy.HandlerWithFallback,
+	readinessHandler *httpzdmproxy.HandlerWithFallback,
+	adminHandler *httpzdmproxy.HandlerWithFallback) {
 	metricsHandler = httpzdmproxy.NewHandlerWithFallback(metrics.DefaultHttpHandler())
 	readinessHandler = httpzdmproxy.NewHandlerWithFallback(health.DefaultReadinessHandler())
+	adminHandler = httpzdmproxy.NewHandlerWithFallback(admin.DefaultHandler())
 
 	http.Handle("/metrics", metricsHandler.Handler())
 	http.Handle("/health/readiness", readinessHandler.Handler())
 	http.Handle("/health/liveness", health.LivenessHandler())
-	return metricsHandler, readinessHandler
+	admin.RegisterHandler(http.DefaultServeMux, adminHandler.Handler())
+	return metricsHandler, readinessHandler, adminHandler
 }
 
 func RunMain(
 	conf *config.Config,
 	ctx context.Context,
 	metricsHandler *httpzdmproxy.HandlerWithFallback,
-	readinessHandler *httpzdmproxy.HandlerWithFallback) {
+	readinessHandler *httpzdmproxy.HandlerWithFallback,
+	adminHandler *httpzdmproxy.HandlerWithFallback) {
 
-	log.Infof("Starting http server (metrics and health checks) on %v:%d", conf.MetricsAddress, conf.MetricsPort)
+	metricsAddr := net.JoinHostPort(conf.MetricsAddress, strconv.Itoa(conf.MetricsPort))
 	wg := &sync.WaitGroup{}
-	srv := httpzdmproxy.StartHttpServer(fmt.Sprintf("%s:%d", conf.MetricsAddress, conf.MetricsPort), wg)
+	servers := make([]*http.Server, 0, 2)
+	adminAddr, err := conf.ParseAdminApiAddress()
+	if err != nil || adminAddr == "" {
+		log.Infof("Starting http server (metrics, health checks and admin API) on %v", metricsAddr)
+		servers = append(servers, httpzdmproxy.StartHttpServer(metricsAddr, wg))
+	} else {
+		log.Infof("Starting http server (metrics and health checks) on %v", metricsAddr)
+		servers = append(servers, httpzdmproxy.StartHttpServerWithHandler(
+			metricsAddr, withoutAdminApi(http.DefaultServeMux), "metrics", wg))
+		log.Infof("Starting http server (admin API) on %v", adminAddr)
+		adminMux := http.NewServeMux()
+		admin.RegisterHandler(adminMux, adminHandler.Handler())
+		servers = append(servers, httpzdmproxy.StartHttpServerWithHandler(adminAddr, adminMux, "admin API", wg))
+	}
 
 	b := &backoff.Backoff{
 		Min:    100 * time.Millisecond,
@@ -43,28 +68,87 @@ func RunMain(
 		Jitter: true,
 	}
 
-	zdmProxy, err := zdmproxy.RunWithRetries(conf, ctx, b)
+	var options *zdmproxy.ZdmProxyOptions
+	listeners, err := SystemdListeners()
+	if err != nil {
+		log.Errorf("Could not use socket activated listeners, falling back to %v: %v",
+			net.JoinHostPort(conf.ProxyListenAddress, strconv.Itoa(conf.ProxyListenPort)), err)
+	} else if len(listeners) > 0 {
+		options = &zdmproxy.ZdmProxyOptions{Listeners: listeners}
+	}
+
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	defer signal.Stop(reloadSignals)
+	debugSignals := make(chan os.Signal, 1)
+	if len(debugLevelSignals) > 0 {
+		// signal.Notify relays every signal if none is provided
+		signal.Notify(debugSignals, debugLevelSignals...)
+		defer signal.Stop(debugSignals)
+	}
+
+	zdmProxy, err := zdmproxy.RunWithRetriesAndOptions(conf, ctx, b, options)
 
 	if err == nil {
 		metricsHandler.SetHandler(zdmProxy.GetMetricHandler().GetHttpHandler())
 		readinessHandler.SetHandler(health.ReadinessHandler(zdmProxy))
+		adminHandler.SetHandler(admin.Handler(zdmProxy))
 
-		log.Info("Proxy started. Waiting for SIGINT/SIGTERM to shutdown.")
-		<-ctx.Done()
+		log.Info("Proxy started. Waiting for SIGINT/SIGTERM to shutdown, SIGHUP to reload the config file or " +
+			"SIGUSR1 to toggle the DEBUG log level.")
+		handleSignals(ctx, zdmProxy, reloadSignals, debugSignals)
 
+		zdmProxy.Drain()
 		zdmProxy.Shutdown()
 		metricsHandler.ClearHandler()
 		readinessHandler.ClearHandler()
+		adminHandler.ClearHandler()
 	} else if !errors.Is(err, zdmproxy.ShutdownErr) {
 		log.Errorf("Error launching proxy: %v", err)
 	}
 
 	log.Info("Shutting down httpzdmproxy server, waiting up to 5 seconds.")
-	srvShutdownCtx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	if err := srv.Shutdown(srvShutdownCtx); err != nil {
-		log.Errorf("Failed to gracefully shutdown httpzdmproxy server: %v", err)
+	srvShutdownCtx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	for _, srv := range servers {
+		if err := srv.Shutdown(srvShutdownCtx); err != nil {
+			log.Errorf("Failed to gracefully shutdown httpzdmproxy server: %v", err)
+		}
 	}
 
 	wg.Wait()
 	log.Info("Http server shutdown.")
 }
+
+// handleSignals reloads the configuration of the proxy every time a reload signal is received and toggles the DEBUG
+// log level every time a debug signal is received until ctx is done.
+func handleSignals(
+	ctx context.Context, zdmProxy *zdmproxy.ZdmProxy, reloadSignals <-chan os.Signal, debugSignals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-reloadSignals:
+			log.Infof("Received %v, reloading the configuration.", sig)
+			err := zdmProxy.ReloadConfig()
+			if err != nil {
+				log.Errorf("%v, keeping the current configuration.", err)
+			}
+		case sig := <-debugSignals:
+			log.Infof("Received %v, toggling the DEBUG log level.", sig)
+			zdmProxy.ToggleDebugLogLevel()
+		}
+	}
+}
+
+// withoutAdminApi returns a handler that doesn't serve the admin API paths, they are served on a separate endpoint
+// if ZDM_ADMIN_API_PORT is set.
+func withoutAdminApi(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, admin.PathPrefix) {
+			http.NotFound(rsp, req)
+			return
+		}
+		handler.ServeHTTP(rsp, req)
+	})
+}
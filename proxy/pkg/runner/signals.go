@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// debugLevelSignals toggle the DEBUG log level.
+var debugLevelSignals = []os.Signal{syscall.SIGUSR1}
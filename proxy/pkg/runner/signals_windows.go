@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package runner
+
+import "os"
+
+// debugLevelSignals is empty because SIGUSR1 doesn't exist on windows, the log level can be changed with the admin
+// API instead.
+var debugLevelSignals []os.Signal
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"os"
+	"strconv"
+)
+
+// File descriptor of the first socket passed by systemd, see sd_listen_fds(3).
+const systemdListenFdsStart = 3
+
+// SystemdListeners returns the listeners of the sockets passed by systemd socket activation
+// (LISTEN_PID and LISTEN_FDS environment variables) or nil if the proxy was not socket activated.
+// The environment variables are unset so that they are not inherited by child processes.
+func SystemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS environment variable (%v)", os.Getenv("LISTEN_FDS"))
+	}
+
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	return listenersFromFds(systemdListenFdsStart, count)
+}
+
+func listenersFromFds(firstFd int, count int) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, count)
+	for fd := firstFd; fd < firstFd+count; fd++ {
+		file := os.NewFile(uintptr(fd), fmt.Sprintf("systemd-listen-fd-%d", fd))
+		l, err := net.FileListener(file)
+		// FileListener duplicates the file descriptor so the original one can be closed
+		_ = file.Close()
+		if err != nil {
+			for _, createdListener := range listeners {
+				_ = createdListener.Close()
+			}
+			return nil, fmt.Errorf("could not create listener from file descriptor %d: %w", fd, err)
+		}
+		log.Infof("Using socket activated listener on %v.", l.Addr())
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
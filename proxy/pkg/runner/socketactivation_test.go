@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestSystemdListeners_NotSocketActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := SystemdListeners()
+	require.Nil(t, err)
+	require.Nil(t, listeners)
+	require.Equal(t, "1", os.Getenv("LISTEN_FDS"))
+}
+
+func TestSystemdListeners_InvalidListenFds(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "abc")
+
+	_, err := SystemdListeners()
+	require.Equal(t, "invalid LISTEN_FDS environment variable (abc)", err.Error())
+}
+
+func TestListenersFromFds(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+
+	file, err := l.(*net.TCPListener).File()
+	require.Nil(t, err)
+
+	listeners, err := listenersFromFds(int(file.Fd()), 1)
+	require.Nil(t, err)
+	require.Len(t, listeners, 1)
+	defer listeners[0].Close()
+	require.Equal(t, l.Addr().String(), listeners[0].Addr().String())
+
+	go func() {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+	conn, err := listeners[0].Accept()
+	require.Nil(t, err)
+	_ = conn.Close()
+}
@@ -1,8 +1,22 @@
-package client
+// Package testclient provides a raw CQL client that can be used to write tests against the ZDM proxy (or any other
+// CQL endpoint).
+//
+// Unlike a driver, TestClient doesn't perform any kind of validation or negotiation on its own: callers open a
+// connection, perform the handshake with the protocol version of their choosing (or skip it entirely) and then
+// send arbitrary frames, including raw frames with invalid bodies or headers, and inspect the responses:
+//
+//	testClient, err := testclient.Connect(ctx, "127.0.0.1:14002", primitive.ProtocolVersion4, "user", "pass", nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer testClient.Shutdown()
+//	response, _, err := testClient.SendMessage(ctx, primitive.ProtocolVersion4, &message.Options{})
+package testclient
 
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/frame"
@@ -29,6 +43,7 @@ type TestClient struct {
 	closed                bool
 	connection            net.Conn
 	eventsQueue           chan *frame.Frame
+	startupOptions        map[string]string
 }
 
 type request struct {
@@ -44,22 +59,81 @@ func newRequest(buffer []byte) *request {
 }
 
 const (
-	numberOfStreamIds = int16(2048)
-	eventQueueLength  = 2048
+	numberOfStreamIds     = int16(2048)
+	eventQueueLength      = 2048
+	defaultRequestTimeout = 2 * time.Second
 )
 
+// Options are the settings used to open a TestClient connection, the zero value uses plain TCP and the default
+// request timeout.
+type Options struct {
+	// RequestTimeout is how long the client waits for a response, defaults to 2 seconds.
+	RequestTimeout time.Duration
+
+	// TlsConfig enables TLS on the connection when not nil.
+	TlsConfig *tls.Config
+
+	// Dialer is used to open the connection, defaults to a net.Dialer with no timeout (use the context instead).
+	Dialer *net.Dialer
+
+	// StartupOptions are added to the STARTUP request of the handshake, e.g. DRIVER_NAME and DRIVER_VERSION.
+	StartupOptions map[string]string
+}
+
 func NewTestClient(ctx context.Context, address string) (*TestClient, error) {
-	return NewTestClientWithRequestTimeout(ctx, address, 2*time.Second)
+	return NewTestClientWithRequestTimeout(ctx, address, defaultRequestTimeout)
 }
 
 func NewTestClientWithRequestTimeout(ctx context.Context, address string, requestTimeout time.Duration) (*TestClient, error) {
+	return NewTestClientWithOptions(ctx, address, &Options{RequestTimeout: requestTimeout})
+}
+
+// Connect opens a connection and performs the handshake with the provided protocol version. Authentication is
+// only performed if username or password are not empty.
+func Connect(
+	ctx context.Context, address string, version primitive.ProtocolVersion,
+	username string, password string, options *Options) (*TestClient, error) {
+	testClient, err := NewTestClientWithOptions(ctx, address, options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = testClient.PerformHandshake(ctx, version, username != "" || password != "", username, password)
+	if err != nil {
+		_ = testClient.Shutdown()
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+
+	return testClient, nil
+}
+
+// NewTestClientWithOptions opens a connection without performing the handshake. Options can be nil.
+func NewTestClientWithOptions(ctx context.Context, address string, options *Options) (*TestClient, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	requestTimeout := options.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = defaultRequestTimeout
+	}
+	dialer := options.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
 	streamIdsQueue := make(chan int16, numberOfStreamIds)
 	for i := int16(0); i < numberOfStreamIds; i++ {
 		streamIdsQueue <- i
 	}
 
-	d := net.Dialer{}
-	conn, err := d.DialContext(ctx, "tcp", address)
+	var conn net.Conn
+	var err error
+	if options.TlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: options.TlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not open connection: %w", err)
 	}
@@ -77,6 +151,7 @@ func NewTestClientWithRequestTimeout(ctx context.Context, address string, reques
 		stateLock:             &sync.RWMutex{},
 		connection:            conn,
 		eventsQueue:           make(chan *frame.Frame, eventQueueLength),
+		startupOptions:        options.StartupOptions,
 	}
 
 	client.waitGroup.Add(1)
@@ -167,28 +242,65 @@ func (testClient *TestClient) isClosed() bool {
 	return testClient.closed
 }
 
+// LocalAddr returns the local address of the connection, i.e., the client address that the server sees.
+func (testClient *TestClient) LocalAddr() net.Addr {
+	return testClient.connection.LocalAddr()
+}
+
+// RemoteAddr returns the address of the server.
+func (testClient *TestClient) RemoteAddr() net.Addr {
+	return testClient.connection.RemoteAddr()
+}
+
+// PerformHandshake sends a STARTUP request with the provided protocol version and, if useAuth is true, authenticates
+// with the provided credentials (supports both PasswordAuthenticator and DseAuthenticator).
 func (testClient *TestClient) PerformHandshake(
 	ctx context.Context, version primitive.ProtocolVersion, useAuth bool, username string, password string) error {
-	response, _, err := testClient.SendMessage(ctx, version, message.NewStartup())
+	if useAuth {
+		return testClient.PerformHandshakeWithAuthenticator(ctx, version, NewDsePlainTextAuthenticator(username, password))
+	}
+	return testClient.PerformHandshakeWithAuthenticator(ctx, version, nil)
+}
+
+// PerformHandshakeWithAuthenticator sends a STARTUP request with the provided protocol version and, if the
+// authenticator is not nil, answers the server's AUTHENTICATE and AUTH_CHALLENGE responses with it.
+func (testClient *TestClient) PerformHandshakeWithAuthenticator(
+	ctx context.Context, version primitive.ProtocolVersion, authenticator Authenticator) error {
+	startup := message.NewStartup()
+	for key, value := range testClient.startupOptions {
+		startup.Options[key] = value
+	}
+	response, _, err := testClient.SendMessage(ctx, version, startup)
 	if err != nil {
 		return fmt.Errorf("could not send startup frame: %w", err)
 	}
 
-	if useAuth {
+	if authenticator != nil {
 		parsedAuthenticateResponse, ok := response.Body.Message.(*message.Authenticate)
 		if !ok {
 			return fmt.Errorf("expected authenticate but got %02x", response.Body.Message.GetOpCode())
 		}
 
-		authenticator := NewDsePlainTextAuthenticator(username, password)
-		initialResponse, err := authenticator.InitialResponse(parsedAuthenticateResponse.Authenticator)
+		token, err := authenticator.InitialResponse(parsedAuthenticateResponse.Authenticator)
 		if err != nil {
 			return fmt.Errorf("could not create initial response token: %w", err)
 		}
 
-		response, _, err = testClient.SendMessage(ctx, version, &message.AuthResponse{Token: initialResponse})
-		if err != nil {
-			return fmt.Errorf("could not send auth response: %w", err)
+		for {
+			response, _, err = testClient.SendMessage(ctx, version, &message.AuthResponse{Token: token})
+			if err != nil {
+				return fmt.Errorf("could not send auth response: %w", err)
+			}
+
+			challenge, ok := response.Body.Message.(*message.AuthChallenge)
+			if !ok {
+				break
+			}
+
+			token, err = authenticator.EvaluateChallenge(challenge.Token)
+			if err != nil {
+				return fmt.Errorf("could not evaluate auth challenge: %w", err)
+			}
 		}
 
 		if response.Body.Message.GetOpCode() != primitive.OpCodeAuthSuccess {
@@ -311,6 +423,25 @@ func (testClient *TestClient) SendRawRequest(ctx context.Context, streamId int16
 	return response, nil
 }
 
+// SendRawFrame sends a frame without decoding or validating its body, useful to send malformed requests.
+// The stream id of the frame is replaced with a stream id borrowed from the client.
+func (testClient *TestClient) SendRawFrame(ctx context.Context, request *frame.RawFrame) (*frame.Frame, int16, error) {
+	streamId, err := testClient.BorrowStreamId()
+	if err != nil {
+		return nil, streamId, err
+	}
+
+	request.Header.StreamId = streamId
+
+	buf := &bytes.Buffer{}
+	err = frame.NewRawCodec().EncodeRawFrame(request, buf)
+	if err != nil {
+		return nil, streamId, fmt.Errorf("could not encode request: %w", err)
+	}
+	response, err := testClient.SendRawRequest(ctx, streamId, buf.Bytes())
+	return response, streamId, err
+}
+
 func (testClient *TestClient) SendRequest(ctx context.Context, request *frame.Frame) (*frame.Frame, int16, error) {
 	streamId, err := testClient.BorrowStreamId()
 	if err != nil {
@@ -0,0 +1,54 @@
+package testclient
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/client"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTestClient_ConnectAndSendRequests(t *testing.T) {
+	server := client.NewCqlServer("127.0.0.1:19042", &client.AuthCredentials{Username: "user", Password: "pass"})
+	server.RequestHandlers = []client.RequestHandler{
+		client.NewDriverConnectionInitializationHandler("cluster", "dc1", func(_ string) {}),
+	}
+	require.Nil(t, server.Start(context.Background()))
+	defer server.Close()
+
+	for _, version := range []primitive.ProtocolVersion{primitive.ProtocolVersion3, primitive.ProtocolVersion4} {
+		t.Run(version.String(), func(t *testing.T) {
+			testClient, err := Connect(context.Background(), "127.0.0.1:19042", version, "user", "pass", nil)
+			require.Nil(t, err)
+			defer testClient.Shutdown()
+
+			response, _, err := testClient.SendMessage(context.Background(), version, &message.Options{})
+			require.Nil(t, err)
+			require.Equal(t, primitive.OpCodeSupported, response.Header.OpCode)
+			require.Equal(t, version, response.Header.Version)
+
+			rawRequest, err := frame.NewRawCodec().ConvertToRawFrame(frame.NewFrame(version, 0, &message.Query{
+				Query:   "SELECT * FROM system.local",
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne},
+			}))
+			require.Nil(t, err)
+			response, streamId, err := testClient.SendRawFrame(context.Background(), rawRequest)
+			require.Nil(t, err)
+			require.Equal(t, streamId, response.Header.StreamId)
+			require.Equal(t, primitive.OpCodeResult, response.Header.OpCode, response.Body.Message)
+		})
+	}
+}
+
+func TestTestClient_HandshakeWrongCredentials(t *testing.T) {
+	server := client.NewCqlServer("127.0.0.1:19043", &client.AuthCredentials{Username: "user", Password: "pass"})
+	server.RequestHandlers = []client.RequestHandler{client.HandshakeHandler}
+	require.Nil(t, server.Start(context.Background()))
+	defer server.Close()
+
+	_, err := Connect(context.Background(), "127.0.0.1:19043", primitive.ProtocolVersion4, "user", "wrong", nil)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "expected auth success")
+}
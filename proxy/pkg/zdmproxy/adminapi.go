@@ -0,0 +1,171 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PreparedStatementCacheStatus is the content of the prepared statement cache that is reported by the admin API.
+type PreparedStatementCacheStatus struct {
+	Statements            int
+	InterceptedStatements int
+	Entries               []*CachedPreparedStatement
+}
+
+type CachedPreparedStatement struct {
+	OriginPreparedId string
+	TargetPreparedId string
+	Query            string
+	Keyspace         string `json:",omitempty"`
+	ForwardDecision  string
+}
+
+// ClusterConnectionStatus is the state of the connections to a cluster that is reported by the admin API.
+type ClusterConnectionStatus struct {
+	Cluster common.ClusterType
+	// Hosts are the nodes of the local datacenter that the control connection discovered.
+	Hosts []*ClusterHost
+	// CircuitBreaker is CLOSED, OPEN, HALF_OPEN or DISABLED (ZDM_<CLUSTER>_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT).
+	CircuitBreaker string
+}
+
+type ClusterHost struct {
+	Address    string
+	HostId     string
+	Datacenter string
+	Rack       string
+	// State is DOWN if a STATUS_CHANGE event reported that the host is down, UP otherwise.
+	State string
+}
+
+// ReadRoutingStatus is the read_routing setting of the zdm_admin keyspace that is reported by the admin API.
+type ReadRoutingStatus struct {
+	PrimaryCluster common.ClusterType
+	// ReadRouting is ORIGIN or TARGET if the reads are not forwarded to the primary cluster, it is empty otherwise.
+	ReadRouting common.ClusterType `json:",omitempty"`
+}
+
+// GetConnectedClients returns the client connections that finished the handshake ordered by connection time.
+func (p *ZdmProxy) GetConnectedClients() []*ConnectedClient {
+	return p.connectedClients.list()
+}
+
+// DrainClients stops accepting requests from the connections of the provided clients (IPs or IP:port addresses)
+// and closes them once their in flight requests are completed, every connection is drained if clients is empty.
+// The new requests of a draining connection get an OVERLOADED error so the drivers retry them on another proxy
+// instance. It returns the drained connections.
+func (p *ZdmProxy) DrainClients(clients []string) ([]*ConnectedClient, error) {
+	return p.connectedClients.drain(clients)
+}
+
+// GetPreparedStatementCacheStatus returns the entries of the prepared statement cache ordered by prepared id.
+func (p *ZdmProxy) GetPreparedStatementCacheStatus() *PreparedStatementCacheStatus {
+	statements, interceptedStatements := p.PreparedStatementCache.GetEntryCounts()
+	entries := p.PreparedStatementCache.GetEntries()
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].GetOriginPreparedId(), entries[j].GetOriginPreparedId()) < 0
+	})
+
+	status := &PreparedStatementCacheStatus{
+		Statements:            statements,
+		InterceptedStatements: interceptedStatements,
+		Entries:               make([]*CachedPreparedStatement, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		prepareRequestInfo := entry.GetPrepareRequestInfo()
+		status.Entries = append(status.Entries, &CachedPreparedStatement{
+			OriginPreparedId: hex.EncodeToString(entry.GetOriginPreparedId()),
+			TargetPreparedId: hex.EncodeToString(entry.GetTargetPreparedId()),
+			Query:            prepareRequestInfo.GetQuery(),
+			Keyspace:         prepareRequestInfo.GetKeyspace(),
+			ForwardDecision:  string(prepareRequestInfo.GetBaseRequestInfo().GetForwardDecision()),
+		})
+	}
+	return status
+}
+
+// GetClusterConnectionStatus returns the state of the connections to ORIGIN or TARGET, the state of the control
+// connection is reported by the readiness endpoint.
+func (p *ZdmProxy) GetClusterConnectionStatus(cluster common.ClusterType) (*ClusterConnectionStatus, error) {
+	p.lock.RLock()
+	controlConn, breaker := p.originControlConn, p.originCircuitBreaker
+	if cluster == common.ClusterTypeTarget {
+		controlConn, breaker = p.targetControlConn, p.targetCircuitBreaker
+	}
+	p.lock.RUnlock()
+
+	status := &ClusterConnectionStatus{
+		Cluster:        cluster,
+		Hosts:          make([]*ClusterHost, 0),
+		CircuitBreaker: breaker.getStateName(),
+	}
+	if controlConn == nil {
+		return status, nil
+	}
+	hosts, err := controlConn.GetOrderedHostsInLocalDatacenter()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the hosts of %v: %w", cluster, err)
+	}
+	for _, host := range hosts {
+		status.Hosts = append(status.Hosts, &ClusterHost{
+			Address:    net.JoinHostPort(host.Address.String(), strconv.Itoa(host.Port)),
+			HostId:     host.HostId.String(),
+			Datacenter: host.Datacenter,
+			Rack:       host.Rack,
+			State:      getHostState(controlConn, host),
+		})
+	}
+	return status, nil
+}
+
+func getHostState(controlConn *ControlConn, host *Host) string {
+	if controlConn.IsHostUp(host) {
+		return string(primitive.StatusChangeTypeUp)
+	}
+	return string(primitive.StatusChangeTypeDown)
+}
+
+// GetReadRoutingStatus returns nil if the zdm_admin keyspace is disabled (ZDM_ADMIN_KEYSPACE_ROLES).
+func (p *ZdmProxy) GetReadRoutingStatus() *ReadRoutingStatus {
+	p.lock.RLock()
+	adminKeyspace := p.adminKeyspace
+	p.lock.RUnlock()
+
+	if adminKeyspace == nil {
+		return nil
+	}
+	return &ReadRoutingStatus{PrimaryCluster: adminKeyspace.primaryCluster, ReadRouting: adminKeyspace.getReadRouting()}
+}
+
+// SetReadRouting changes the read_routing setting of the zdm_admin keyspace like
+// UPDATE zdm_admin.settings SET read_routing = <readRouting>, readRouting is origin, target or null (case
+// insensitive). changedBy describes who changed it in the log entry.
+func (p *ZdmProxy) SetReadRouting(readRouting string, changedBy string) (*ReadRoutingStatus, error) {
+	p.lock.RLock()
+	adminKeyspace := p.adminKeyspace
+	p.lock.RUnlock()
+
+	if adminKeyspace == nil {
+		return nil, fmt.Errorf("the %v keyspace is disabled (ZDM_ADMIN_KEYSPACE_ROLES)", adminKeyspaceName)
+	}
+	cluster := common.ClusterType(strings.ToUpper(readRouting))
+	switch cluster {
+	case common.ClusterTypeOrigin, common.ClusterTypeTarget:
+	case "NULL":
+		cluster = common.ClusterTypeNone
+	default:
+		return nil, fmt.Errorf("invalid value for %v: '%v', expected origin, target or null", readRoutingSettingName, readRouting)
+	}
+	err := adminKeyspace.updateReadRouting(cluster, changedBy)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetReadRoutingStatus(), nil
+}
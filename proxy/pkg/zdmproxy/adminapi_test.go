@@ -0,0 +1,70 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestZdmProxy_ReadRouting(t *testing.T) {
+	proxy := &ZdmProxy{lock: &sync.RWMutex{}}
+	require.Nil(t, proxy.GetReadRoutingStatus())
+	_, err := proxy.SetReadRouting("target", "test")
+	require.EqualError(t, err, "the zdm_admin keyspace is disabled (ZDM_ADMIN_KEYSPACE_ROLES)")
+
+	proxy.adminKeyspace = newAdminKeyspace([]string{"admin"}, common.ClusterTypeOrigin)
+	require.Equal(t, &ReadRoutingStatus{PrimaryCluster: common.ClusterTypeOrigin}, proxy.GetReadRoutingStatus())
+
+	status, err := proxy.SetReadRouting("Target", "test")
+	require.Nil(t, err)
+	require.Equal(t, &ReadRoutingStatus{
+		PrimaryCluster: common.ClusterTypeOrigin, ReadRouting: common.ClusterTypeTarget}, status)
+	require.Equal(t, common.ClusterTypeTarget, proxy.adminKeyspace.getReadRouting())
+
+	_, err = proxy.SetReadRouting("async", "test")
+	require.EqualError(t, err, "invalid value for read_routing: 'async', expected origin, target or null")
+	require.Equal(t, common.ClusterTypeTarget, proxy.adminKeyspace.getReadRouting())
+
+	status, err = proxy.SetReadRouting("null", "test")
+	require.Nil(t, err)
+	require.Equal(t, &ReadRoutingStatus{PrimaryCluster: common.ClusterTypeOrigin}, status)
+}
+
+func TestZdmProxy_GetPreparedStatementCacheStatus(t *testing.T) {
+	psCache := NewPreparedStatementCache()
+	psCache.Store(
+		&message.PreparedResult{PreparedQueryId: []byte{0x02}}, &message.PreparedResult{PreparedQueryId: []byte{0x12}},
+		NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, "INSERT INTO ks.tb (a) VALUES (?)", "ks"))
+	psCache.StoreIntercepted(
+		&message.PreparedResult{PreparedQueryId: []byte{0x01}},
+		NewPrepareRequestInfo(NewInterceptedRequestInfo(local, nil), nil, false, "SELECT * FROM system.local", ""))
+	proxy := &ZdmProxy{lock: &sync.RWMutex{}, PreparedStatementCache: psCache}
+
+	require.Equal(t, &PreparedStatementCacheStatus{
+		Statements:            1,
+		InterceptedStatements: 1,
+		Entries: []*CachedPreparedStatement{
+			{OriginPreparedId: "01", TargetPreparedId: "01", Query: "SELECT * FROM system.local",
+				ForwardDecision: string(forwardToNone)},
+			{OriginPreparedId: "02", TargetPreparedId: "12", Query: "INSERT INTO ks.tb (a) VALUES (?)", Keyspace: "ks",
+				ForwardDecision: string(forwardToBoth)},
+		},
+	}, proxy.GetPreparedStatementCacheStatus())
+}
+
+func TestZdmProxy_DrainClients(t *testing.T) {
+	proxy := &ZdmProxy{lock: &sync.RWMutex{}, connectedClients: newConnectedClients()}
+	require.Empty(t, proxy.GetConnectedClients())
+
+	drained, err := proxy.DrainClients(nil)
+	require.Nil(t, err)
+	require.Empty(t, drained)
+
+	_, err = proxy.DrainClients([]string{"10.0.0.1"})
+	require.EqualError(t, err, "there are no connections of the clients [10.0.0.1]")
+
+	_, err = proxy.DrainClients([]string{"client1"})
+	require.EqualError(t, err, "invalid client client1, it must be an IP or an IP:port address")
+}
@@ -0,0 +1,252 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	adminKeyspaceName      = "zdm_admin"
+	adminSettingsTableName = "settings"
+)
+
+const (
+	readRoutingSettingName    = "read_routing"
+	primaryClusterSettingName = "primary_cluster"
+)
+
+// avoids tokenizing every query that could not be parsed by the query inspector
+var adminKeyspaceRegex = regexp.MustCompile(`(?i)\bzdm_admin\b`)
+
+/*
+
+cqlsh> describe zdm_admin.settings;
+CREATE TABLE zdm_admin.settings (
+    read_routing text,
+    primary_cluster text
+)
+*/
+
+var adminSettingsColumns = []*message.ColumnMetadata{
+	{Keyspace: adminKeyspaceName, Table: adminSettingsTableName, Name: readRoutingSettingName, Type: datatype.Varchar},
+	{Keyspace: adminKeyspaceName, Table: adminSettingsTableName, Name: primaryClusterSettingName, Type: datatype.Varchar},
+}
+
+// adminKeyspace executes the statements on the reserved zdm_admin keyspace so that the roles of
+// ZDM_ADMIN_KEYSPACE_ROLES can operate the proxy with cqlsh. The zdm_admin.settings table has a single row:
+//   - read_routing: the cluster that the reads are forwarded to, null if they are forwarded to ZDM_PRIMARY_CLUSTER;
+//     it can be changed with UPDATE zdm_admin.settings SET read_routing = 'target' (or 'origin' or null)
+//   - primary_cluster: the value of ZDM_PRIMARY_CLUSTER, it can not be changed
+//
+// The settings only apply to this proxy instance unless ZDM_FLEET_STORE_TYPE is set, in that case they are written to
+// the fleet store and every instance applies them (see fleetCoordinator). The WHERE clause of the statements
+// is ignored.
+type adminKeyspace struct {
+	roles          map[string]bool
+	primaryCluster common.ClusterType
+	readRouting    common.ClusterType // ClusterTypeNone if the reads are forwarded to the primary cluster
+	fleet          *fleetCoordinator  // nil if ZDM_FLEET_STORE_TYPE is not set
+	lock           *sync.RWMutex
+}
+
+// newAdminKeyspace returns nil if there are no roles (the zdm_admin keyspace is disabled).
+func newAdminKeyspace(roles []string, primaryCluster common.ClusterType) *adminKeyspace {
+	if len(roles) == 0 {
+		return nil
+	}
+	rolesMap := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		rolesMap[role] = true
+	}
+	return &adminKeyspace{
+		roles:          rolesMap,
+		primaryCluster: primaryCluster,
+		readRouting:    common.ClusterTypeNone,
+		lock:           &sync.RWMutex{},
+	}
+}
+
+// isAdminKeyspaceStatement returns true if the statement is on the zdm_admin keyspace.
+func isAdminKeyspaceStatement(queryInfo QueryInfo) bool {
+	if queryInfo.getApplicableKeyspace() == adminKeyspaceName {
+		return true
+	}
+	// the query inspector can not parse UPDATE statements without a WHERE clause
+	if queryInfo.getStatementType() != statementTypeOther || !adminKeyspaceRegex.MatchString(queryInfo.getQuery()) {
+		return false
+	}
+	tokens := tokenizeDdl(queryInfo.getQuery())
+	return len(tokens) > 1 && tokens[0].text == "UPDATE" && adminIdentifier(tokens[1]) == adminKeyspaceName
+}
+
+// getReadRouting returns ClusterTypeNone if the reads are forwarded to the primary cluster.
+func (recv *adminKeyspace) getReadRouting() common.ClusterType {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	return recv.readRouting
+}
+
+// setReadRouting returns the previous value.
+func (recv *adminKeyspace) setReadRouting(readRouting common.ClusterType) common.ClusterType {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	previousReadRouting := recv.readRouting
+	recv.readRouting = readRouting
+	return previousReadRouting
+}
+
+// execute returns the response of a statement on the zdm_admin keyspace, role is the username
+// that the client authenticated with.
+func (recv *adminKeyspace) execute(
+	queryInfo QueryInfo, role string, clientAddress string, genericTypeCodec *GenericTypeCodec,
+	version primitive.ProtocolVersion) (message.Message, error) {
+	if !recv.roles[role] {
+		log.Warnf("Client %v with role '%v' is not allowed to execute statements on the %v keyspace: %v",
+			clientAddress, role, adminKeyspaceName, queryInfo.getQuery())
+		return &message.Unauthorized{ErrorMessage: fmt.Sprintf(
+			"Role '%v' has no permission on keyspace %v (ZDM_ADMIN_KEYSPACE_ROLES)", role, adminKeyspaceName)}, nil
+	}
+
+	switch queryInfo.getStatementType() {
+	case statementTypeSelect:
+		if queryInfo.getTableName() != adminSettingsTableName {
+			return &message.Invalid{ErrorMessage: fmt.Sprintf(
+				"unconfigured table %v.%v", adminKeyspaceName, queryInfo.getTableName())}, nil
+		}
+		parsedSelectClause := queryInfo.getParsedSelectClause()
+		if parsedSelectClause == nil {
+			return &message.Invalid{ErrorMessage: fmt.Sprintf(
+				"Unsupported select clause on %v.%v", adminKeyspaceName, adminSettingsTableName)}, nil
+		}
+		result, err := newVirtualTableResult(nil, "", genericTypeCodec, version, adminKeyspaceName,
+			adminSettingsTableName, adminSettingsColumns, recv.getSettingsRows, parsedSelectClause)
+		if columnNotFoundErr, ok := err.(*ColumnNotFoundErr); ok {
+			return &message.Invalid{ErrorMessage: fmt.Sprintf("Undefined column name %v", columnNotFoundErr.Name)}, nil
+		}
+		return result, err
+	case statementTypeUpdate, statementTypeOther:
+		settings, err := parseAdminSettingsUpdate(queryInfo.getQuery())
+		if err != nil {
+			return &message.Invalid{ErrorMessage: err.Error()}, nil
+		}
+		readRouting, ok := settings[readRoutingSettingName]
+		if !ok {
+			return &message.VoidResult{}, nil
+		}
+		err = recv.updateReadRouting(readRouting, fmt.Sprintf("client %v with role '%v'", clientAddress, role))
+		if err != nil {
+			return &message.ServerError{ErrorMessage: err.Error()}, nil
+		}
+		return &message.VoidResult{}, nil
+	default:
+		return &message.Invalid{ErrorMessage: fmt.Sprintf(
+			"Only SELECT and UPDATE statements on %v.%v are supported", adminKeyspaceName, adminSettingsTableName)}, nil
+	}
+}
+
+// updateReadRouting changes the read_routing setting and writes it to the fleet store if ZDM_FLEET_STORE_TYPE is set,
+// changedBy describes who changed it in the log entry.
+func (recv *adminKeyspace) updateReadRouting(readRouting common.ClusterType, changedBy string) error {
+	if recv.fleet != nil {
+		err := recv.fleet.publish(map[string]common.ClusterType{readRoutingSettingName: readRouting})
+		if err != nil {
+			log.Errorf("Could not write the settings of the %v keyspace to the %v: %v.",
+				adminKeyspaceName, recv.fleet.description, err)
+			return fmt.Errorf("Could not write the settings to the fleet store: %v", err)
+		}
+	}
+	previousReadRouting := recv.setReadRouting(readRouting)
+	log.Warnf("Setting %v of the %v keyspace changed from %v to %v by %v.",
+		readRoutingSettingName, adminKeyspaceName, readRoutingSettingValue(previousReadRouting),
+		readRoutingSettingValue(readRouting), changedBy)
+	return nil
+}
+
+func (recv *adminKeyspace) getSettingsRows() [][]interface{} {
+	var readRouting interface{}
+	if cluster := recv.getReadRouting(); cluster != common.ClusterTypeNone {
+		readRouting = readRoutingSettingValue(cluster)
+	}
+	return [][]interface{}{{readRouting, strings.ToLower(string(recv.primaryCluster))}}
+}
+
+func readRoutingSettingValue(cluster common.ClusterType) string {
+	if cluster == common.ClusterTypeNone {
+		return "null"
+	}
+	return strings.ToLower(string(cluster))
+}
+
+// parseAdminSettingsUpdate returns the new value of each setting of a statement like
+// UPDATE zdm_admin.settings SET read_routing = 'target', ClusterTypeNone means null.
+func parseAdminSettingsUpdate(query string) (map[string]common.ClusterType, error) {
+	tokens := tokenizeDdl(query)
+	if len(tokens) < 5 || tokens[0].text != "UPDATE" || adminIdentifier(tokens[1]) != adminKeyspaceName ||
+		tokens[2].text != "." || tokens[4].text != "SET" {
+		return nil, fmt.Errorf(
+			"Expected UPDATE %v.%v SET <setting> = <value> [, <setting> = <value>]", adminKeyspaceName, adminSettingsTableName)
+	}
+	if tableName := adminIdentifier(tokens[3]); tableName != adminSettingsTableName {
+		return nil, fmt.Errorf("unconfigured table %v.%v", adminKeyspaceName, tableName)
+	}
+
+	settings := make(map[string]common.ClusterType)
+	assignments := tokens[5:]
+	for len(assignments) > 0 && assignments[0].text != "WHERE" && assignments[0].text != ";" {
+		if len(assignments) < 3 || assignments[1].text != "=" {
+			return nil, fmt.Errorf("Expected <setting> = <value> after SET but got %v", assignments[0].text)
+		}
+		name, value := adminIdentifier(assignments[0]), assignments[2]
+		switch name {
+		case readRoutingSettingName:
+			cluster, err := parseReadRoutingSetting(value)
+			if err != nil {
+				return nil, err
+			}
+			settings[name] = cluster
+		case primaryClusterSettingName:
+			return nil, fmt.Errorf("Setting %v can not be changed, it is set with ZDM_PRIMARY_CLUSTER", name)
+		default:
+			return nil, fmt.Errorf("Undefined column name %v", name)
+		}
+
+		assignments = assignments[3:]
+		if len(assignments) > 0 && assignments[0].text == "," {
+			assignments = assignments[1:]
+		}
+	}
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("Expected at least one <setting> = <value> after SET")
+	}
+	return settings, nil
+}
+
+func parseReadRoutingSetting(value ddlToken) (common.ClusterType, error) {
+	if !value.literal {
+		if value.text == "NULL" {
+			return common.ClusterTypeNone, nil
+		}
+		return "", fmt.Errorf("Invalid value for %v: %v, expected 'origin', 'target' or null", readRoutingSettingName, value.text)
+	}
+	switch cluster := common.ClusterType(strings.ToUpper(value.text)); cluster {
+	case common.ClusterTypeOrigin, common.ClusterTypeTarget:
+		return cluster, nil
+	default:
+		return "", fmt.Errorf("Invalid value for %v: '%v', expected 'origin', 'target' or null", readRoutingSettingName, value.text)
+	}
+}
+
+// adminIdentifier returns the internal form of an identifier token, see extractIdentifier.
+func adminIdentifier(token ddlToken) string {
+	if strings.HasPrefix(token.text, "\"") && strings.HasSuffix(token.text, "\"") && len(token.text) >= 2 {
+		return strings.ReplaceAll(token.text[1:len(token.text)-1], "\"\"", "\"")
+	}
+	return strings.ToLower(token.text)
+}
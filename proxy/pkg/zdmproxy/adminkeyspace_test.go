@@ -0,0 +1,96 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAdminKeyspace_RequestInfo(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	getRequestInfo := func(query string, adminKeyspaceEnabled bool) RequestInfo {
+		return getRequestInfoFromQueryInfo(mockQueryFrame(t, query), common.ClusterTypeOrigin, false, true, false,
+			adminKeyspaceEnabled, inspectCqlQuery(query, "", generator), nil, nil, nil)
+	}
+
+	tests := []struct {
+		query string
+		admin bool
+	}{
+		{"SELECT * FROM zdm_admin.settings", true},
+		{"UPDATE zdm_admin.settings SET read_routing = 'target'", true},
+		{"update \"zdm_admin\".settings set read_routing = null where key = 1", true},
+		{"USE zdm_admin", true},
+		{"INSERT INTO ks.zdm_admin (a) VALUES (1)", false},
+		{"UPDATE ks.tb SET a = 'zdm_admin'", false},
+		{"SELECT * FROM ks.tb", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			requestInfo := getRequestInfo(tt.query, true)
+			if tt.admin {
+				require.IsType(t, &AdminRequestInfo{}, requestInfo)
+				require.Equal(t, forwardToNone, requestInfo.GetForwardDecision())
+			} else {
+				require.NotEqual(t, forwardToNone, requestInfo.GetForwardDecision())
+			}
+			require.NotEqual(t, forwardToNone, getRequestInfo(tt.query, false).GetForwardDecision())
+		})
+	}
+}
+
+func TestAdminKeyspace_Execute(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	require.Nil(t, newAdminKeyspace(nil, common.ClusterTypeOrigin))
+	adminKeyspace := newAdminKeyspace([]string{"ops"}, common.ClusterTypeOrigin)
+
+	execute := func(query string, role string) message.Message {
+		response, err := adminKeyspace.execute(inspectCqlQuery(query, "", generator), role, "127.0.0.1:9042",
+			GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4)
+		require.Nil(t, err)
+		return response
+	}
+	selectSettings := func() []interface{} {
+		response := execute("SELECT read_routing, primary_cluster FROM zdm_admin.settings", "ops")
+		require.IsType(t, &message.RowsResult{}, response)
+		rowSet, err := ParseRowsResult(
+			GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4, response.(*message.RowsResult), nil, nil)
+		require.Nil(t, err)
+		require.Len(t, rowSet.Rows, 1)
+		return rowSet.Rows[0].Values
+	}
+
+	require.IsType(t, &message.Unauthorized{}, execute("SELECT * FROM zdm_admin.settings", "cassandra"))
+	require.IsType(t, &message.Unauthorized{}, execute("UPDATE zdm_admin.settings SET read_routing = 'target'", ""))
+	require.Equal(t, []interface{}{nil, "origin"}, selectSettings())
+	require.Equal(t, common.ClusterTypeNone, adminKeyspace.getReadRouting())
+
+	require.Equal(t, &message.VoidResult{}, execute("UPDATE zdm_admin.settings SET read_routing = 'TARGET'", "ops"))
+	require.Equal(t, common.ClusterTypeTarget, adminKeyspace.getReadRouting())
+	require.Equal(t, []interface{}{"target", "origin"}, selectSettings())
+
+	require.Equal(t, &message.VoidResult{}, execute("UPDATE zdm_admin.settings SET read_routing = null;", "ops"))
+	require.Equal(t, common.ClusterTypeNone, adminKeyspace.getReadRouting())
+
+	invalidStatements := []string{
+		"UPDATE zdm_admin.settings SET read_routing = 'both'",
+		"UPDATE zdm_admin.settings SET read_routing = ?",
+		"UPDATE zdm_admin.settings SET primary_cluster = 'target'",
+		"UPDATE zdm_admin.settings SET unknown = 'target'",
+		"UPDATE zdm_admin.other SET read_routing = 'target'",
+		"SELECT unknown FROM zdm_admin.settings",
+		"SELECT * FROM zdm_admin.other",
+		"DELETE FROM zdm_admin.settings WHERE key = 1",
+		"USE zdm_admin",
+	}
+	for _, query := range invalidStatements {
+		require.IsType(t, &message.Invalid{}, execute(query, "ops"), query)
+	}
+	require.Equal(t, common.ClusterTypeNone, adminKeyspace.getReadRouting())
+}
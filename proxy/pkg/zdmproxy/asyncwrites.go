@@ -0,0 +1,123 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// asyncWrite is a write that is forwarded to the secondary cluster in the background when ZDM_DUAL_WRITE_MODE is ASYNC.
+//
+// The client response doesn't wait for it so a write that fails is retried (up to ZDM_DUAL_WRITE_ASYNC_MAX_RETRIES
+// times) and if it still fails it is logged and counted in the proxy_async_writes_failed_total metric.
+type asyncWrite struct {
+	requestInfo  RequestInfo
+	request      *frame.RawFrame
+	retriesLeft  int
+	proxyMetrics *metrics.ProxyMetrics
+}
+
+func isRetryableAsyncWriteError(errMsg message.Error) bool {
+	switch errMsg.GetErrorCode() {
+	case primitive.ErrorCodeServerError, primitive.ErrorCodeUnavailable, primitive.ErrorCodeOverloaded,
+		primitive.ErrorCodeIsBootstrapping, primitive.ErrorCodeTruncateError, primitive.ErrorCodeWriteTimeout,
+		primitive.ErrorCodeWriteFailure, primitive.ErrorCodeUnprepared:
+		return true
+	default:
+		return false
+	}
+}
+
+func (cc *ClusterConnector) getAsyncWriteTimeout() time.Duration {
+	return time.Duration(cc.conf.DualWriteAsyncTimeoutMs) * time.Millisecond
+}
+
+// sendAsyncWrite sends the provided write to the cluster, the caller is responsible for calling
+// clientHandlerRequestWg.Done() if the write could not be sent.
+func (cc *ClusterConnector) sendAsyncWrite(write *asyncWrite) bool {
+	request := write.request.Clone()
+	if !cc.validateAsyncStateForRequest(request) {
+		return false
+	}
+
+	asyncReqCtx := NewAsyncRequestContext(write.requestInfo, request.Header.StreamId, false, time.Now())
+	asyncReqCtx.write = write
+	return cc.sendAsyncRequestContext(asyncReqCtx, request, cc.getAsyncWriteTimeout(), func() {
+		cc.retryAsyncWrite(write, "timed out")
+	})
+}
+
+// retryAsyncWrite sends the provided write again if it has retries left, otherwise the write is considered failed.
+func (cc *ClusterConnector) retryAsyncWrite(write *asyncWrite, reason string) {
+	if write.retriesLeft > 0 {
+		write.retriesLeft--
+		write.proxyMetrics.AsyncWritesRetried.Add(1)
+		log.Debugf("[%s] Async write (%v) %v, retrying it (%d retries left).",
+			cc.connectorType, write.request.Header.OpCode, reason, write.retriesLeft)
+		if cc.sendAsyncWrite(write) {
+			return
+		}
+		reason = "could not be retried"
+	}
+	cc.failAsyncWrite(write, reason)
+}
+
+func (cc *ClusterConnector) failAsyncWrite(write *asyncWrite, reason string) {
+	write.proxyMetrics.AsyncWritesFailed.Add(1)
+	log.Warnf("[%s] Async write (%v) to %v %v, the %v cluster might be missing this write.",
+		cc.connectorType, write.request.Header.OpCode, cc.clusterType, reason, cc.clusterType)
+	cc.clientHandlerRequestWg.Done()
+}
+
+// handleAsyncWriteResponse handles the response of an async write (or of the PREPARE request that re-prepares the
+// statement of an async write that failed with UNPREPARED).
+func (cc *ClusterConnector) handleAsyncWriteResponse(
+	asyncReqCtx *asyncRequestContextImpl, response *frame.RawFrame, errMsg message.Error) {
+	write := asyncReqCtx.write
+	if errMsg == nil {
+		if asyncReqCtx.reprepare {
+			if cc.sendAsyncWrite(write) {
+				return
+			}
+			cc.failAsyncWrite(write, "could not be sent after re-preparing its statement")
+			return
+		}
+		cc.clientHandlerRequestWg.Done()
+		return
+	}
+
+	if asyncReqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
+		trackClusterErrorMetricsFromErrorMessage(errMsg, cc.connectorType, cc.nodeMetrics)
+	}
+
+	reason := fmt.Sprintf("failed with error code %v (%v)", errMsg.GetErrorCode(), errMsg.GetErrorMessage())
+	if !isRetryableAsyncWriteError(errMsg) {
+		cc.failAsyncWrite(write, reason)
+		return
+	}
+
+	unprepared, ok := errMsg.(*message.Unprepared)
+	if !ok || asyncReqCtx.reprepare {
+		cc.retryAsyncWrite(write, reason)
+		return
+	}
+
+	prepareRequestInfo, prepareRawFrame, err := cc.newAsyncPrepareRequest(unprepared, response)
+	if err != nil {
+		cc.failAsyncWrite(write, fmt.Sprintf("%v and its statement could not be re-prepared: %v", reason, err))
+		return
+	}
+	prepareReqCtx := NewAsyncRequestContext(prepareRequestInfo, prepareRawFrame.Header.StreamId, false, time.Now())
+	prepareReqCtx.write = write
+	prepareReqCtx.reprepare = true
+	if !cc.validateAsyncStateForRequest(prepareRawFrame) ||
+		!cc.sendAsyncRequestContext(prepareReqCtx, prepareRawFrame, cc.getAsyncWriteTimeout(), func() {
+			cc.retryAsyncWrite(write, "PREPARE timed out")
+		}) {
+		cc.failAsyncWrite(write, fmt.Sprintf("%v and its statement could not be re-prepared", reason))
+	}
+}
@@ -0,0 +1,200 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditLog logs every request of the client connections as a JSON line (see ZDM_AUDIT_LOG_DESTINATION) with its
+// opcode, the client user, address and application identity (see clientIdentity), the routing decision, the outcome
+// and the keyspace, table and digest of its statements. The literals of the statements are always obfuscated and the bound values are never logged, the
+// obfuscated query is only logged if ZDM_AUDIT_LOG_QUERY_FORMAT is OBFUSCATED.
+//
+// The audit records are built by requestExplanation because it already tracks the routing decision and the outcome
+// of the requests on every path that completes them.
+type auditLog struct {
+	conf   *common.AuditLogConfig
+	logger *log.Logger
+	writer io.WriteCloser
+}
+
+// newAuditLog returns nil if the audit log is disabled.
+func newAuditLog(conf *common.AuditLogConfig) (*auditLog, error) {
+	var writer io.WriteCloser
+	var err error
+	switch conf.Destination {
+	case common.AuditLogDestinationUndefined:
+		return nil, nil
+	case common.AuditLogDestinationFile:
+		writer, err = newRotatingFileWriter(conf.FilePath, int64(conf.FileMaxSizeBytes), conf.FileMaxBackups)
+	case common.AuditLogDestinationSyslog:
+		writer, err = newSyslogWriter(conf.SyslogNetwork, conf.SyslogAddress)
+	default:
+		return nil, fmt.Errorf("unknown audit log destination: %v", conf.Destination)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open the audit log: %w", err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(writer)
+	logger.SetFormatter(&log.JSONFormatter{})
+	return &auditLog{
+		conf:   conf,
+		logger: logger,
+		writer: writer,
+	}, nil
+}
+
+// record logs the audit record of a request that is done.
+func (recv *auditLog) record(explanation *requestExplanation) {
+	fields := log.Fields{
+		"client":      explanation.clientAddress,
+		"stream":      explanation.streamId,
+		"opcode":      protocolConstantName(explanation.opCode),
+		"outcome":     explanation.auditOutcome(),
+		"duration_ms": toMilliseconds(time.Since(explanation.startTime)),
+	}
+	if explanation.user != "" {
+		fields["user"] = explanation.user
+	}
+	if explanation.identity != nil {
+		for name, value := range explanation.identity.fields() {
+			fields[name] = value
+		}
+	}
+	if explanation.destinations != "" {
+		fields["forward_decision"] = explanation.destinations
+	}
+	if explanation.response != nil {
+		fields["response"] = describeSlowQueryResponse(explanation.response)
+		fields["response_from"] = explanation.responseFrom
+	}
+	addQueryFields(
+		fields, explanation.request, explanation.requestInfo, explanation.connectionKeyspace, recv.conf.QueryFormat)
+	recv.logger.WithFields(fields).Info("Audit")
+}
+
+func (recv *auditLog) close() {
+	if recv == nil {
+		return
+	}
+	if err := recv.writer.Close(); err != nil {
+		log.Warnf("Could not close the audit log: %v.", err)
+	}
+}
+
+// rotatingFileWriter appends to a file that is renamed to <path>.1 when it reaches maxSizeBytes, the older rotated
+// files are renamed to <path>.2 and so on until maxBackups (the oldest one is removed).
+type rotatingFileWriter struct {
+	lock         *sync.Mutex
+	path         string
+	maxSizeBytes int64
+	maxBackups   int
+	file         *os.File
+	size         int64
+}
+
+func newRotatingFileWriter(path string, maxSizeBytes int64, maxBackups int) (*rotatingFileWriter, error) {
+	writer := &rotatingFileWriter{
+		lock:         &sync.Mutex{},
+		path:         path,
+		maxSizeBytes: maxSizeBytes,
+		maxBackups:   maxBackups,
+	}
+	if err := writer.open(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (recv *rotatingFileWriter) open() error {
+	file, err := os.OpenFile(recv.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	recv.file = file
+	recv.size = info.Size()
+	return nil
+}
+
+// Write writes a whole record, the file is rotated before the record if the record doesn't fit in it.
+func (recv *rotatingFileWriter) Write(p []byte) (int, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.file == nil {
+		return 0, os.ErrClosed
+	}
+	if recv.size > 0 && recv.size+int64(len(p)) > recv.maxSizeBytes {
+		if err := recv.rotate(); err != nil {
+			// the record is still written if the file could be reopened
+			log.Warnf("Could not rotate the audit log %v: %v.", recv.path, err)
+			if recv.file == nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := recv.file.Write(p)
+	recv.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file and the rotated files and then reopens the current file, it is reopened even if
+// the files could not be renamed.
+func (recv *rotatingFileWriter) rotate() error {
+	closeErr := recv.file.Close()
+	recv.file = nil
+	renameErr := recv.renameFiles()
+	openErr := recv.open()
+	for _, err := range []error{openErr, renameErr, closeErr} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (recv *rotatingFileWriter) renameFiles() error {
+	if recv.maxBackups == 0 {
+		return ignoreNotExist(os.Remove(recv.path))
+	}
+	for i := recv.maxBackups - 1; i > 0; i-- {
+		if err := ignoreNotExist(os.Rename(recv.backupPath(i), recv.backupPath(i+1))); err != nil {
+			return err
+		}
+	}
+	return ignoreNotExist(os.Rename(recv.path, recv.backupPath(1)))
+}
+
+func ignoreNotExist(err error) error {
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (recv *rotatingFileWriter) backupPath(index int) string {
+	return fmt.Sprintf("%v.%d", recv.path, index)
+}
+
+func (recv *rotatingFileWriter) Close() error {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.file == nil {
+		return nil
+	}
+	err := recv.file.Close()
+	recv.file = nil
+	return err
+}
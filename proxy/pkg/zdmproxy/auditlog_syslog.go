@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package zdmproxy
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// newSyslogWriter connects to the syslog daemon at address or to the local one if the address is empty, every
+// audit record is sent as a separate message.
+func newSyslogWriter(network string, address string) (io.WriteCloser, error) {
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_USER, "zdm-proxy")
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
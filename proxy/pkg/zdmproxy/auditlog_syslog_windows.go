@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package zdmproxy
+
+import (
+	"errors"
+	"io"
+)
+
+func newSyslogWriter(network string, address string) (io.WriteCloser, error) {
+	return nil, errors.New("the SYSLOG audit log is not supported on windows")
+}
@@ -0,0 +1,164 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_Record(t *testing.T) {
+	disabled, err := newAuditLog(&common.AuditLogConfig{})
+	require.Nil(t, err)
+	require.Nil(t, disabled)
+
+	newExplanation := func(t *testing.T, audit *auditLog, msg message.Message) *requestExplanation {
+		request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 5, msg))
+		require.Nil(t, err)
+		return &requestExplanation{
+			auditLog:           audit,
+			clientAddress:      "127.0.0.1:9042",
+			user:               "app",
+			identity:           &clientIdentity{applicationName: "orders-service", clientId: "c1", driverName: "gocql"},
+			startTime:          time.Now(),
+			opCode:             request.Header.OpCode,
+			streamId:           request.Header.StreamId,
+			request:            request,
+			connectionKeyspace: "ks",
+		}
+	}
+	readRecord := func(t *testing.T, output *bytes.Buffer) map[string]interface{} {
+		var fields map[string]interface{}
+		require.Nil(t, json.Unmarshal(output.Bytes(), &fields), output.String())
+		output.Reset()
+		return fields
+	}
+
+	for _, queryFormat := range []common.SlowQueryFormat{common.SlowQueryFormatObfuscated, common.SlowQueryFormatDigest} {
+		t.Run(queryFormat.String(), func(t *testing.T) {
+			output := &bytes.Buffer{}
+			logger := log.New()
+			logger.SetOutput(output)
+			logger.SetFormatter(&log.JSONFormatter{})
+			audit := &auditLog{conf: &common.AuditLogConfig{QueryFormat: queryFormat}, logger: logger}
+
+			// forwarded request
+			explanation := newExplanation(t, audit, &message.Query{
+				Query:   "SELECT * FROM tb WHERE a = 'secret'",
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalQuorum}})
+			explanation.describe(
+				NewFrameDecodeContext(explanation.request), NewGenericRequestInfo(forwardToOrigin, false, true), false)
+			response, err := defaultCodec.ConvertToRawFrame(
+				frame.NewFrame(primitive.ProtocolVersion4, 5, &message.VoidResult{}))
+			require.Nil(t, err)
+			explanation.setResponseOutcome(response, common.ClusterTypeOrigin)
+			explanation.log()
+
+			fields := readRecord(t, output)
+			require.Equal(t, "Audit", fields["msg"])
+			require.Equal(t, "127.0.0.1:9042", fields["client"])
+			require.Equal(t, "app", fields["user"])
+			require.Equal(t, "orders-service", fields["application"])
+			require.Equal(t, "c1", fields["client_id"])
+			require.Equal(t, "gocql", fields["driver"])
+			require.NotContains(t, fields, "driver_version")
+			require.Equal(t, float64(5), fields["stream"])
+			require.Equal(t, "QUERY", fields["opcode"])
+			require.Equal(t, "origin", fields["forward_decision"])
+			require.Equal(t, "success", fields["outcome"])
+			require.Equal(t, "RESULT", fields["response"])
+			require.Equal(t, "ORIGIN", fields["response_from"])
+			require.Equal(t, "ks", fields["keyspace"])
+			require.Equal(t, "tb", fields["table"])
+			require.Equal(t, "LOCAL_QUORUM", fields["consistency"])
+			require.Equal(t, 32, len(fields["query_digest"].(string)))
+			if queryFormat == common.SlowQueryFormatObfuscated {
+				require.Equal(t, "SELECT * FROM tb WHERE a = ?", fields["query"])
+			} else {
+				require.NotContains(t, fields, "query")
+			}
+
+			// the error messages are not recorded, they can contain the literals of the statements
+			explanation = newExplanation(t, audit, &message.Query{Query: "INSERT INTO ks2.tb2 (a) VALUES ('secret')"})
+			errResponse, err := defaultCodec.ConvertToRawFrame(
+				frame.NewFrame(primitive.ProtocolVersion4, 5, &message.Invalid{ErrorMessage: "invalid value 'secret'"}))
+			require.Nil(t, err)
+			explanation.setResponseOutcome(errResponse, common.ClusterTypeTarget)
+			explanation.log()
+
+			fields = readRecord(t, output)
+			require.Equal(t, "error", fields["outcome"])
+			require.Equal(t, "Invalid", fields["response"])
+			require.Equal(t, "TARGET", fields["response_from"])
+			require.Equal(t, "ks2", fields["keyspace"])
+			require.NotContains(t, fields, "forward_decision")
+
+			explanation = newExplanation(t, audit, &message.Query{Query: "SELECT * FROM tb WHERE a = 'secret'"})
+			explanation.setErrorOutcome(errors.New("could not parse 'secret'"))
+			explanation.log()
+			require.Equal(t, "failed", readRecord(t, output)["outcome"])
+
+			explanation = newExplanation(t, audit, &message.Query{Query: "SELECT * FROM tb WHERE a = 'secret'"})
+			explanation.setCanceledOutcome()
+			explanation.log()
+			require.Equal(t, "canceled", readRecord(t, output)["outcome"])
+
+			require.False(t, strings.Contains(output.String(), "secret"))
+		})
+	}
+}
+
+func TestRotatingFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writer, err := newRotatingFileWriter(path, 10, 2)
+	require.Nil(t, err)
+
+	write := func(record string) {
+		_, err := writer.Write([]byte(record))
+		require.Nil(t, err)
+	}
+	requireContent := func(path string, expected string) {
+		content, err := os.ReadFile(path)
+		require.Nil(t, err)
+		require.Equal(t, expected, string(content))
+	}
+
+	write("aaaa\n")
+	write("bbbb\n")
+	requireContent(path, "aaaa\nbbbb\n")
+
+	// a record that doesn't fit rotates the file, records larger than the max size are not split
+	write("cccccccccccc\n")
+	requireContent(path, "cccccccccccc\n")
+	requireContent(path+".1", "aaaa\nbbbb\n")
+	write("dddd\n")
+	write("eeeee\n")
+	requireContent(path, "eeeee\n")
+	requireContent(path+".1", "dddd\n")
+	requireContent(path+".2", "cccccccccccc\n")
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+	require.Nil(t, writer.Close())
+
+	// the size of an existing file is taken into account
+	writer, err = newRotatingFileWriter(path, 10, 0)
+	require.Nil(t, err)
+	write("fff\n")
+	requireContent(path, "eeeee\nfff\n")
+	write("gggg\n")
+	requireContent(path, "gggg\n")
+	requireContent(path+".1", "dddd\n")
+	require.Nil(t, writer.Close())
+	_, err = writer.Write([]byte("hhhh\n"))
+	require.NotNil(t, err)
+}
@@ -8,10 +8,24 @@ import (
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
 )
 
+const (
+	passwordAuthenticator = "org.apache.cassandra.auth.PasswordAuthenticator"
+	dseAuthenticator      = "com.datastax.bdp.cassandra.auth.DseAuthenticator"
+)
+
+// handshakeAuthenticator computes the AUTH_RESPONSE tokens of the handshakes that the proxy performs itself, i.e.
+// the secondary and async handshakes.
+type handshakeAuthenticator interface {
+	// InitialResponse returns the token of the AUTH_RESPONSE to the AUTHENTICATE response of the provided authenticator.
+	InitialResponse(authenticator string) ([]byte, error)
+	// EvaluateChallenge returns the token of the AUTH_RESPONSE to the provided AUTH_CHALLENGE token.
+	EvaluateChallenge(challenge []byte) ([]byte, error)
+}
+
 // Returns a proper response frame to authenticate using passed in username and password
 // Utilizes the users request frame to maintain the correct version & stream id.
 func performHandshakeStep(
-	authenticator *DsePlainTextAuthenticator,
+	authenticator handshakeAuthenticator,
 	version primitive.ProtocolVersion,
 	streamId int16,
 	lastResponse *frame.Frame) (*frame.Frame, error) {
@@ -97,9 +111,9 @@ var (
 
 func (a *DsePlainTextAuthenticator) InitialResponse(authenticator string) ([]byte, error) {
 	switch authenticator {
-	case "com.datastax.bdp.cassandra.auth.DseAuthenticator":
+	case dseAuthenticator:
 		return mechanism, nil
-	case "org.apache.cassandra.auth.PasswordAuthenticator":
+	case passwordAuthenticator:
 		return a.Credentials.Marshal(), nil
 	}
 	return nil, fmt.Errorf("unknown authenticator: %v", authenticator)
@@ -126,3 +140,52 @@ func ParseCredentialsFromRequest(token []byte) (credsInToken *AuthCredentials, e
 
 	return authCreds, nil
 }
+
+// clientAuthNegotiation follows the SASL negotiation between the client and the primary cluster to find the
+// AUTH_RESPONSE tokens that carry plain text credentials. The tokens of other mechanisms (e.g. GSSAPI with
+// DseAuthenticator) can't be translated so they are relayed to the primary cluster unchanged.
+type clientAuthNegotiation struct {
+	authenticator string // authenticator of the AUTHENTICATE response of the primary cluster
+	mechanism     string // SASL mechanism selected by the client, only with DseAuthenticator
+}
+
+// newClientAuthNegotiation returns the negotiation that follows the provided response of the primary cluster to the
+// STARTUP request of the client.
+func newClientAuthNegotiation(startupResponse *frame.RawFrame) *clientAuthNegotiation {
+	negotiation := &clientAuthNegotiation{}
+	if startupResponse.Header.OpCode != primitive.OpCodeAuthenticate {
+		return negotiation
+	}
+	parsedFrame, err := defaultCodec.ConvertFromRawFrame(startupResponse)
+	if err != nil {
+		return negotiation
+	}
+	if authenticate, ok := parsedFrame.Body.Message.(*message.Authenticate); ok {
+		negotiation.authenticator = authenticate.Authenticator
+	}
+	return negotiation
+}
+
+// evaluateResponse returns the plain text credentials of an AUTH_RESPONSE token of the client, nil if the token
+// doesn't carry credentials (e.g. the selection of the PLAIN mechanism). It returns false if the token is opaque to
+// the proxy and has to be relayed unchanged.
+func (n *clientAuthNegotiation) evaluateResponse(token []byte) (creds *AuthCredentials, plainText bool, err error) {
+	switch n.authenticator {
+	case dseAuthenticator:
+		// the first token selects the mechanism unless the client sends plain text credentials right away
+		if n.mechanism == "" && token != nil && !bytes.Contains(token, []byte{0}) {
+			n.mechanism = string(token)
+		}
+		if n.mechanism != "" && n.mechanism != string(mechanism) {
+			return nil, false, nil
+		}
+	case passwordAuthenticator, "":
+	default:
+		// custom authenticators are only supported with tokens in the PasswordAuthenticator format
+		if bytes.Count(token, []byte{0}) < 2 {
+			return nil, false, nil
+		}
+	}
+	creds, err = ParseCredentialsFromRequest(token)
+	return creds, true, err
+}
@@ -0,0 +1,161 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAuthCredentials_AuthorizationIdRoundTrip(t *testing.T) {
+	creds := &AuthCredentials{AuthId: "alice", Username: "service", Password: "secret"}
+	require.Equal(t, []byte("alice\x00service\x00secret"), creds.Marshal())
+
+	parsed, err := ParseCredentialsFromRequest(creds.Marshal())
+	require.Nil(t, err)
+	require.Equal(t, creds, parsed)
+
+	parsed, err = ParseCredentialsFromRequest([]byte("\x00service\x00secret"))
+	require.Nil(t, err)
+	require.Equal(t, &AuthCredentials{Username: "service", Password: "secret"}, parsed)
+}
+
+func TestWithAuthorizationId(t *testing.T) {
+	creds := &AuthCredentials{AuthId: "alice", Username: "service", Password: "secret"}
+	require.Same(t, creds, withAuthorizationId(creds, "alice"))
+	require.Equal(t, &AuthCredentials{Username: "service", Password: "secret"}, withAuthorizationId(creds, ""))
+	require.Equal(t,
+		&AuthCredentials{AuthId: "bob", Username: "service", Password: "secret"}, withAuthorizationId(creds, "bob"))
+	require.Equal(t, "alice", creds.AuthId)
+}
+
+func TestResolveCredentialsModes(t *testing.T) {
+	auto, client, configured := common.CredentialsModeAuto, common.CredentialsModeClient, common.CredentialsModeConfigured
+	tests := []struct {
+		name                       string
+		forwardClientCredsToOrigin bool
+		origin                     common.CredentialsMode
+		target                     common.CredentialsMode
+		expectedOrigin             common.CredentialsMode
+		expectedTarget             common.CredentialsMode
+	}{
+		{"auto", false, auto, auto, configured, client},
+		{"auto forward client credentials to origin", true, auto, auto, client, configured},
+		{"client on both", true, client, client, client, client},
+		{"client on origin", false, client, auto, client, configured},
+		{"configured on origin", true, configured, auto, configured, client},
+		{"configured on target", false, auto, configured, client, configured},
+		{"explicit modes", true, configured, client, configured, client},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin, target := resolveCredentialsModes(tt.forwardClientCredsToOrigin, tt.origin, tt.target)
+			require.Equal(t, tt.expectedOrigin, origin)
+			require.Equal(t, tt.expectedTarget, target)
+		})
+	}
+}
+
+func TestClientHandler_HandshakeCredentials(t *testing.T) {
+	conf := config.New()
+	conf.OriginForwardAuthorizationId = true
+	conf.TargetForwardAuthorizationId = true
+	ch := &ClientHandler{
+		conf:                  conf,
+		originUsername:        "origin",
+		originPassword:        "originpass",
+		targetUsername:        "target",
+		targetPassword:        "targetpass",
+		originCredentialsMode: common.CredentialsModeClient,
+		targetCredentialsMode: common.CredentialsModeConfigured,
+	}
+
+	// the authorization-id is forwarded with the client credentials
+	clientCreds := &AuthCredentials{AuthId: "alice", Username: "service", Password: "secret"}
+	require.Same(t, clientCreds, ch.handshakeCredentials(clientCreds, common.ClusterTypeOrigin))
+
+	// the authorization-id is not added to the credentials of the proxy configuration by default
+	require.Equal(t,
+		&AuthCredentials{Username: "target", Password: "targetpass"},
+		ch.handshakeCredentials(clientCreds, common.ClusterTypeTarget))
+
+	// unless it is explicitly enabled
+	conf.TargetForwardAuthorizationIdWithConfiguredCredentials = true
+	require.Equal(t,
+		&AuthCredentials{AuthId: "alice", Username: "target", Password: "targetpass"},
+		ch.handshakeCredentials(clientCreds, common.ClusterTypeTarget))
+
+	// the authorization-id is removed from the client credentials when it is disabled
+	conf.OriginForwardAuthorizationId = false
+	require.Equal(t,
+		&AuthCredentials{Username: "service", Password: "secret"},
+		ch.handshakeCredentials(clientCreds, common.ClusterTypeOrigin))
+
+	ch.targetCredentialsMode = common.CredentialsModeClient
+	require.Same(t, clientCreds, ch.handshakeCredentials(clientCreds, common.ClusterTypeTarget))
+}
+
+func TestClientAuthNegotiation(t *testing.T) {
+	plainCreds := &AuthCredentials{Username: "service", Password: "secret"}
+	newNegotiation := func(authenticator string) *clientAuthNegotiation {
+		f, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+			primitive.ProtocolVersion4, 0, &message.Authenticate{Authenticator: authenticator}))
+		require.Nil(t, err)
+		negotiation := newClientAuthNegotiation(f)
+		require.Equal(t, authenticator, negotiation.authenticator)
+		return negotiation
+	}
+
+	// PasswordAuthenticator
+	negotiation := newNegotiation(passwordAuthenticator)
+	creds, plainText, err := negotiation.evaluateResponse(plainCreds.Marshal())
+	require.Nil(t, err)
+	require.True(t, plainText)
+	require.Equal(t, plainCreds, creds)
+
+	// DseAuthenticator with the PLAIN mechanism
+	negotiation = newNegotiation(dseAuthenticator)
+	creds, plainText, err = negotiation.evaluateResponse([]byte("PLAIN"))
+	require.Nil(t, err)
+	require.True(t, plainText)
+	require.Nil(t, creds)
+	creds, plainText, err = negotiation.evaluateResponse(plainCreds.Marshal())
+	require.Nil(t, err)
+	require.True(t, plainText)
+	require.Equal(t, plainCreds, creds)
+
+	// DseAuthenticator with plain text credentials right away
+	negotiation = newNegotiation(dseAuthenticator)
+	creds, plainText, err = negotiation.evaluateResponse(plainCreds.Marshal())
+	require.Nil(t, err)
+	require.True(t, plainText)
+	require.Equal(t, plainCreds, creds)
+
+	// DseAuthenticator with the GSSAPI mechanism, the tokens of every round are opaque
+	negotiation = newNegotiation(dseAuthenticator)
+	for _, token := range [][]byte{[]byte("GSSAPI"), {0x60, 0x00, 0x00, 0x06}, {}} {
+		creds, plainText, err = negotiation.evaluateResponse(token)
+		require.Nil(t, err)
+		require.False(t, plainText)
+		require.Nil(t, creds)
+	}
+	require.Equal(t, "GSSAPI", negotiation.mechanism)
+
+	// custom authenticator
+	negotiation = newNegotiation("com.example.CustomAuthenticator")
+	_, plainText, err = negotiation.evaluateResponse([]byte{0x01, 0x02})
+	require.Nil(t, err)
+	require.False(t, plainText)
+	creds, plainText, err = negotiation.evaluateResponse(plainCreds.Marshal())
+	require.Nil(t, err)
+	require.True(t, plainText)
+	require.Equal(t, plainCreds, creds)
+
+	// primary cluster without authentication
+	f, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 0, &message.Ready{}))
+	require.Nil(t, err)
+	require.Equal(t, &clientAuthNegotiation{}, newClientAuthNegotiation(f))
+}
@@ -0,0 +1,115 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/segment"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// The pools in this file recycle the buffers and the frame bodies whose lifetime is owned by a single goroutine of the
+// proxy so that they don't have to be allocated (and garbage collected) for every frame.
+//
+// A frame body can only be released with releaseFrameBody once nothing references the frame anymore, this is only
+// the case for the frames that are forwarded between a pooledConn and its shared connection (see clusterConnPool).
+// The frames that are handled by the client handler are retained by the request contexts, the caches and the
+// retries so they are never released.
+
+const (
+	// buffers that grew larger than this are not pooled so that a burst of large frames doesn't pin memory
+	maxPooledBufferSize = 1024 * 1024
+
+	minPooledFrameBodyBits = 8  // 256 bytes
+	maxPooledFrameBodyBits = 17 // 128 KiB, the largest payload of a segment
+)
+
+var bufferPool = &sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+
+// getBuffer returns an empty buffer, it should be returned with putBuffer once its contents are not used anymore.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledBufferSize {
+		return
+	}
+	buffer.Reset()
+	bufferPool.Put(buffer)
+}
+
+var segmentPayloadPool = &sync.Pool{New: func() interface{} {
+	payload := make([]byte, segment.MaxPayloadLength)
+	return &payload
+}}
+
+// frameBodyPools holds one pool per power of two between 2^minPooledFrameBodyBits and 2^maxPooledFrameBodyBits.
+var frameBodyPools [maxPooledFrameBodyBits - minPooledFrameBodyBits + 1]sync.Pool
+
+func frameBodyPoolIndex(size int) int {
+	if size <= 1<<minPooledFrameBodyBits {
+		return 0
+	}
+	return bits.Len(uint(size-1)) - minPooledFrameBodyBits
+}
+
+// getFrameBody returns a slice of the provided length whose contents are undefined.
+func getFrameBody(length int) []byte {
+	if length == 0 {
+		return []byte{}
+	}
+	index := frameBodyPoolIndex(length)
+	if index >= len(frameBodyPools) {
+		return make([]byte, length)
+	}
+	if body, ok := frameBodyPools[index].Get().(*[]byte); ok {
+		return (*body)[:length]
+	}
+	return make([]byte, length, 1<<(index+minPooledFrameBodyBits))
+}
+
+// releaseFrameBody returns the body of the provided frame to the pool, the frame must not be used afterwards.
+// Bodies that were not allocated by getFrameBody are pooled if their capacity matches one of the pools.
+func releaseFrameBody(f *frame.RawFrame) {
+	body := f.Body
+	f.Body = nil
+	if cap(body) < 1<<minPooledFrameBodyBits {
+		return
+	}
+	index := frameBodyPoolIndex(cap(body))
+	if index >= len(frameBodyPools) || cap(body) != 1<<(index+minPooledFrameBodyBits) {
+		return
+	}
+	body = body[:0]
+	frameBodyPools[index].Put(&body)
+}
+
+// readPooledRawFrame reads a frame like readRawFrame but its body is taken from the frame body pools.
+func readPooledRawFrame(
+	reader io.Reader, connectionAddr string, clientHandlerContext context.Context) (*frame.RawFrame, error) {
+	rawFrame, err := decodePooledRawFrame(reader)
+	if err != nil {
+		return nil, adaptConnErr(connectionAddr, clientHandlerContext, err)
+	}
+	return rawFrame, nil
+}
+
+func decodePooledRawFrame(reader io.Reader) (*frame.RawFrame, error) {
+	header, err := defaultCodec.DecodeHeader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode frame header: %w", err)
+	}
+	if header.BodyLength < 0 {
+		return nil, fmt.Errorf("cannot read frame body: invalid body length: %d", header.BodyLength)
+	}
+	body := getFrameBody(int(header.BodyLength))
+	_, err = io.ReadFull(reader, body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read frame body: %w", err)
+	}
+	return &frame.RawFrame{Header: header, Body: body}, nil
+}
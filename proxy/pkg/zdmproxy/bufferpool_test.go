@@ -0,0 +1,146 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/go-cassandra-native-protocol/segment"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFrameBodyPool(t *testing.T) {
+	require.Equal(t, 0, frameBodyPoolIndex(1))
+	require.Equal(t, 0, frameBodyPoolIndex(256))
+	require.Equal(t, 1, frameBodyPoolIndex(257))
+	require.Equal(t, len(frameBodyPools)-1, frameBodyPoolIndex(segment.MaxPayloadLength))
+	require.Equal(t, len(frameBodyPools), frameBodyPoolIndex(1<<maxPooledFrameBodyBits+1))
+
+	body := getFrameBody(300)
+	require.Equal(t, 300, len(body))
+	require.Equal(t, 512, cap(body))
+
+	// bodies larger than the largest pool are allocated
+	body = getFrameBody(1<<maxPooledFrameBodyBits + 1)
+	require.Equal(t, 1<<maxPooledFrameBodyBits+1, cap(body))
+
+	f := &frame.RawFrame{Body: getFrameBody(10)}
+	releaseFrameBody(f)
+	require.Nil(t, f.Body)
+
+	require.Equal(t, []byte{}, getFrameBody(0))
+}
+
+func TestReadPooledRawFrame(t *testing.T) {
+	expected := mockQueryFrame(t, "SELECT * FROM ks1.tb1")
+	encoded := &bytes.Buffer{}
+	require.Nil(t, defaultCodec.EncodeRawFrame(expected, encoded))
+	encoded.Write([]byte{0x04})
+
+	reader := bytes.NewReader(encoded.Bytes())
+	actual, err := decodePooledRawFrame(reader)
+	require.Nil(t, err)
+	require.Equal(t, expected, actual)
+
+	// truncated frame
+	_, err = decodePooledRawFrame(reader)
+	require.NotNil(t, err)
+}
+
+func newEncodedFrame(b *testing.B) []byte {
+	f := newBenchmarkFrame(b, &message.Query{
+		Query: "INSERT INTO ks1.tb1 (id, name, value) VALUES (?, ?, ?)",
+		Options: &message.QueryOptions{
+			Consistency: primitive.ConsistencyLevelLocalQuorum,
+			PositionalValues: []*primitive.Value{
+				primitive.NewValue([]byte{0, 0, 0, 1}),
+				primitive.NewValue([]byte("name")),
+				primitive.NewValue(make([]byte, 2048)),
+			},
+		},
+	})
+	encoded := &bytes.Buffer{}
+	require.Nil(b, defaultCodec.EncodeRawFrame(f, encoded))
+	return encoded.Bytes()
+}
+
+func BenchmarkReadRawFrame(b *testing.B) {
+	encoded := newEncodedFrame(b)
+	reader := bytes.NewReader(encoded)
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.Reset(encoded)
+			if _, err := defaultCodec.DecodeRawFrame(reader); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.Reset(encoded)
+			f, err := decodePooledRawFrame(reader)
+			if err != nil {
+				b.Fatal(err)
+			}
+			releaseFrameBody(f)
+		}
+	})
+}
+
+func BenchmarkWriteFrameToBuffer(b *testing.B) {
+	f, err := defaultCodec.DecodeRawFrame(bytes.NewReader(newEncodedFrame(b)))
+	require.Nil(b, err)
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buffer := &bytes.Buffer{}
+			if err := defaultCodec.EncodeRawFrame(f, buffer); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buffer := getBuffer()
+			if err := defaultCodec.EncodeRawFrame(f, buffer); err != nil {
+				b.Fatal(err)
+			}
+			putBuffer(buffer)
+		}
+	})
+}
+
+func BenchmarkSegmentLz4Decompress(b *testing.B) {
+	payload := bytes.Repeat([]byte("0123456789"), 1000)
+	compressed := make([]byte, lz4.CompressBlockBound(len(payload)))
+	n, err := lz4.CompressBlock(payload, compressed, nil)
+	require.Nil(b, err)
+	compressed = compressed[:n]
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			compressedPayload := &bytes.Buffer{}
+			_, _ = compressedPayload.ReadFrom(bytes.NewReader(compressed))
+			decompressed := make([]byte, segment.MaxPayloadLength)
+			if _, err := lz4.UncompressBlock(compressedPayload.Bytes(), decompressed); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		compressor := &segmentLz4Compressor{}
+		dest := &bytes.Buffer{}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			dest.Reset()
+			if err := compressor.Decompress(bytes.NewReader(compressed), dest); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// circuitBreakerSlots is the number of slots of the sliding window of a circuit breaker, the error rate is evaluated
+// every time the window slides by one slot.
+const circuitBreakerSlots = 10
+
+// The values of the states are the values of the proxy_circuit_breaker_state metric.
+const (
+	circuitBreakerClosed int32 = iota
+	circuitBreakerOpen
+	circuitBreakerHalfOpen
+)
+
+const (
+	circuitBreakerEventOpened   = "circuit_breaker_opened"
+	circuitBreakerEventHalfOpen = "circuit_breaker_half_open"
+	circuitBreakerEventClosed   = "circuit_breaker_closed"
+)
+
+// circuitBreaker stops forwarding requests to a cluster that fails too often (ZDM_ORIGIN_CIRCUIT_BREAKER_* and
+// ZDM_TARGET_CIRCUIT_BREAKER_*). It is shared by the cluster connectors of all the client connections:
+//   - closed: the requests are forwarded, the breaker opens when at least ErrorThresholdPercent percent of the
+//     responses of the sliding window are failures and there were at least MinRequests requests
+//   - open: the requests are rejected until OpenMs elapsed since the breaker opened
+//   - half open: one request is forwarded to probe the cluster, the breaker closes if it succeeds and opens again
+//     if it fails
+//
+// The state changes are structured log events and are reflected by the proxy_circuit_breaker_state metric.
+type circuitBreaker struct {
+	cluster          common.ClusterType
+	conf             *common.CircuitBreakerConfig
+	stateGauge       metrics.Gauge
+	rejectedRequests metrics.Counter
+
+	// only modified with the lock held, it is read without the lock while the breaker is closed
+	state int32
+
+	lock        *sync.Mutex
+	openedAt    time.Time
+	probeSentAt time.Time
+
+	// the responses are only counted while the breaker is closed
+	window *slidingWindow
+}
+
+// counters of the sliding window of a circuit breaker
+const (
+	circuitBreakerRequests = iota
+	circuitBreakerFailures
+	circuitBreakerCounters
+)
+
+// newCircuitBreaker returns nil if the circuit breaker of the cluster is disabled.
+func newCircuitBreaker(
+	cluster common.ClusterType, conf *common.CircuitBreakerConfig, stateGauge metrics.Gauge,
+	rejectedRequests metrics.Counter) *circuitBreaker {
+	if !conf.Enabled {
+		return nil
+	}
+	return &circuitBreaker{
+		cluster:          cluster,
+		conf:             conf,
+		stateGauge:       stateGauge,
+		rejectedRequests: rejectedRequests,
+		state:            circuitBreakerClosed,
+		lock:             &sync.Mutex{},
+		window:           newSlidingWindow(circuitBreakerSlots, circuitBreakerCounters),
+	}
+}
+
+// isCircuitBreakerFailure returns true if the response is an error that the circuit breaker counts as a failure of
+// the cluster, i.e. not an error that depends on the request itself like SYNTAX_ERROR or UNAUTHORIZED.
+func isCircuitBreakerFailure(response *frame.RawFrame) bool {
+	if response.Header.OpCode != primitive.OpCodeError {
+		return false
+	}
+	errMsg, err := decodeError(response)
+	if err != nil {
+		log.Errorf("Could not decode error response for the circuit breaker: %v", err)
+		return false
+	}
+	switch errMsg.GetErrorCode() {
+	case primitive.ErrorCodeServerError, primitive.ErrorCodeOverloaded, primitive.ErrorCodeIsBootstrapping,
+		primitive.ErrorCodeUnavailable, primitive.ErrorCodeReadTimeout, primitive.ErrorCodeWriteTimeout,
+		primitive.ErrorCodeReadFailure, primitive.ErrorCodeWriteFailure:
+		return true
+	default:
+		return false
+	}
+}
+
+// allowRequest returns false if a request must not be forwarded to the cluster. It returns true if the breaker is
+// nil (disabled) or closed and for the request that probes the cluster when the breaker is half open.
+func (recv *circuitBreaker) allowRequest() bool {
+	if recv == nil || atomic.LoadInt32(&recv.state) == circuitBreakerClosed {
+		return true
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	now := time.Now()
+	openDuration := time.Duration(recv.conf.OpenMs) * time.Millisecond
+	switch recv.state {
+	case circuitBreakerOpen:
+		if now.Sub(recv.openedAt) >= openDuration {
+			recv.setState(circuitBreakerHalfOpen, 0, 0)
+			recv.probeSentAt = now
+			return true
+		}
+	case circuitBreakerHalfOpen:
+		// the probe may never get a response (e.g. its connection was closed), another request probes the cluster then
+		if now.Sub(recv.probeSentAt) >= openDuration {
+			recv.probeSentAt = now
+			return true
+		}
+	default:
+		return true
+	}
+	recv.rejectedRequests.Add(1)
+	return false
+}
+
+// isClosed returns true if the breaker is nil (disabled) or closed, it doesn't send a probe request like allowRequest.
+func (recv *circuitBreaker) isClosed() bool {
+	return recv == nil || atomic.LoadInt32(&recv.state) == circuitBreakerClosed
+}
+
+// getStateName returns the state that is reported by the admin API, DISABLED if the breaker is nil.
+func (recv *circuitBreaker) getStateName() string {
+	if recv == nil {
+		return "DISABLED"
+	}
+	switch atomic.LoadInt32(&recv.state) {
+	case circuitBreakerOpen:
+		return "OPEN"
+	case circuitBreakerHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "CLOSED"
+	}
+}
+
+// recordResponse counts a response (or a timeout if response is nil) of the cluster.
+func (recv *circuitBreaker) recordResponse(response *frame.RawFrame) {
+	if recv == nil {
+		return
+	}
+	failure := response == nil || isCircuitBreakerFailure(response)
+
+	if atomic.LoadInt32(&recv.state) == circuitBreakerClosed {
+		recv.window.add(circuitBreakerRequests, 1)
+		if failure {
+			recv.window.add(circuitBreakerFailures, 1)
+		}
+		return
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.state != circuitBreakerHalfOpen {
+		// responses to the requests that were sent before the breaker opened
+		return
+	}
+	if failure {
+		recv.openedAt = time.Now()
+		recv.setState(circuitBreakerOpen, 0, 0)
+	} else {
+		recv.window.reset()
+		recv.setState(circuitBreakerClosed, 0, 0)
+	}
+}
+
+// run evaluates the error rate every time the window slides by one slot until the context is canceled.
+func (recv *circuitBreaker) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(recv.conf.WindowMs) * time.Millisecond / circuitBreakerSlots)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recv.evaluate()
+			recv.window.slide()
+		}
+	}
+}
+
+// evaluate opens the breaker if it is closed and the error rate of the window reached the threshold.
+func (recv *circuitBreaker) evaluate() {
+	if atomic.LoadInt32(&recv.state) != circuitBreakerClosed {
+		return
+	}
+
+	sums := recv.window.sums()
+	requests, failures := sums[circuitBreakerRequests], sums[circuitBreakerFailures]
+	if requests < int64(recv.conf.MinRequests) ||
+		failures*100 < requests*int64(recv.conf.ErrorThresholdPercent) {
+		return
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.state != circuitBreakerClosed {
+		return
+	}
+	recv.openedAt = time.Now()
+	recv.window.reset()
+	recv.setState(circuitBreakerOpen, requests, failures)
+}
+
+// setState must be called with the lock held, requests and failures are the counts of the window that opened
+// a closed breaker.
+func (recv *circuitBreaker) setState(state int32, requests int64, failures int64) {
+	previousState := recv.state
+	atomic.StoreInt32(&recv.state, state)
+	recv.stateGauge.Add(int(state - previousState))
+
+	logger := log.WithFields(log.Fields{
+		"cluster":         recv.cluster,
+		"error_threshold": recv.conf.ErrorThresholdPercent,
+		"window_ms":       recv.conf.WindowMs,
+		"open_ms":         recv.conf.OpenMs,
+	})
+	switch state {
+	case circuitBreakerOpen:
+		if previousState == circuitBreakerHalfOpen {
+			logger.WithField("event", circuitBreakerEventOpened).Warnf(
+				"The circuit breaker of %v opened again because the probe request failed.", recv.cluster)
+		} else {
+			logger.WithFields(log.Fields{
+				"event":    circuitBreakerEventOpened,
+				"requests": requests,
+				"failures": failures,
+			}).Warnf("The circuit breaker of %v opened: %v of the last %v requests failed, "+
+				"the requests to %v are rejected for %vms.", recv.cluster, failures, requests, recv.cluster, recv.conf.OpenMs)
+		}
+	case circuitBreakerHalfOpen:
+		logger.WithField("event", circuitBreakerEventHalfOpen).Infof(
+			"The circuit breaker of %v is half open, sending a request to probe %v.", recv.cluster, recv.cluster)
+	case circuitBreakerClosed:
+		logger.WithField("event", circuitBreakerEventClosed).Infof(
+			"The circuit breaker of %v closed, the probe request succeeded.", recv.cluster)
+	}
+}
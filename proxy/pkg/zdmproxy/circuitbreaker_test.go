@@ -0,0 +1,122 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingGauge struct {
+	value int64
+}
+
+func (recv *countingGauge) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.value, int64(valueToAdd))
+}
+
+func (recv *countingGauge) Subtract(valueToSubtract int) {
+	atomic.AddInt64(&recv.value, -int64(valueToSubtract))
+}
+
+func newTestResponse(t *testing.T, msg message.Message) *frame.RawFrame {
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, msg))
+	require.Nil(t, err)
+	return response
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	require.Nil(t, newCircuitBreaker(
+		common.ClusterTypeTarget, &common.CircuitBreakerConfig{}, &countingGauge{}, &countingCounter{}))
+	var disabledBreaker *circuitBreaker
+	require.True(t, disabledBreaker.allowRequest())
+	require.True(t, disabledBreaker.isClosed())
+	disabledBreaker.recordResponse(nil)
+
+	state := &countingGauge{}
+	rejected := &countingCounter{}
+	breaker := newCircuitBreaker(common.ClusterTypeTarget, &common.CircuitBreakerConfig{
+		Enabled: true, ErrorThresholdPercent: 50, MinRequests: 4, WindowMs: 1000, OpenMs: 100}, state, rejected)
+
+	success := newTestResponse(t, &message.VoidResult{})
+	failure := newTestResponse(t, &message.WriteTimeout{
+		Consistency: primitive.ConsistencyLevelQuorum, WriteType: primitive.WriteTypeSimple})
+	requestError := newTestResponse(t, &message.Invalid{ErrorMessage: "invalid"})
+
+	// not enough requests
+	breaker.recordResponse(failure)
+	breaker.recordResponse(nil)
+	breaker.recordResponse(failure)
+	breaker.evaluate()
+	require.True(t, breaker.isClosed())
+
+	// 3 failures out of 7 requests, errors that depend on the request are not failures
+	breaker.window.slide()
+	breaker.recordResponse(success)
+	breaker.recordResponse(requestError)
+	breaker.recordResponse(requestError)
+	breaker.recordResponse(success)
+	breaker.evaluate()
+	require.True(t, breaker.isClosed())
+	require.True(t, breaker.allowRequest())
+
+	// 4 failures out of 8 requests
+	breaker.recordResponse(failure)
+	breaker.evaluate()
+	require.False(t, breaker.isClosed())
+	require.Equal(t, int64(circuitBreakerOpen), atomic.LoadInt64(&state.value))
+	require.False(t, breaker.allowRequest())
+	require.Equal(t, int64(1), atomic.LoadInt64(&rejected.count))
+
+	// responses of the requests that were sent before the breaker opened are ignored
+	breaker.recordResponse(success)
+	require.False(t, breaker.allowRequest())
+
+	// one probe request once the breaker is half open, it fails
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, breaker.allowRequest())
+	require.Equal(t, int64(circuitBreakerHalfOpen), atomic.LoadInt64(&state.value))
+	require.False(t, breaker.allowRequest())
+	breaker.recordResponse(nil)
+	require.Equal(t, int64(circuitBreakerOpen), atomic.LoadInt64(&state.value))
+	require.False(t, breaker.allowRequest())
+
+	// the next probe succeeds
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, breaker.allowRequest())
+	breaker.recordResponse(success)
+	require.True(t, breaker.isClosed())
+	require.Equal(t, int64(circuitBreakerClosed), atomic.LoadInt64(&state.value))
+	require.Equal(t, int64(4), atomic.LoadInt64(&rejected.count))
+
+	// the window was reset when the breaker closed
+	breaker.recordResponse(failure)
+	breaker.evaluate()
+	require.True(t, breaker.isClosed())
+}
+
+func TestIsCircuitBreakerFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      message.Message
+		expected bool
+	}{
+		{"server error", &message.ServerError{ErrorMessage: "error"}, true},
+		{"overloaded", &message.Overloaded{}, true},
+		{"unavailable", &message.Unavailable{Consistency: primitive.ConsistencyLevelQuorum}, true},
+		{"read timeout", &message.ReadTimeout{Consistency: primitive.ConsistencyLevelQuorum}, true},
+		{"syntax error", &message.SyntaxError{}, false},
+		{"unauthorized", &message.Unauthorized{}, false},
+		{"rows", &message.RowsResult{Metadata: &message.RowsMetadata{}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, isCircuitBreakerFailure(newTestResponse(t, tt.msg)))
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const ClientConnectorLogPrefix = "CLIENT-CONNECTOR"
@@ -39,7 +40,10 @@ type ClientConnector struct {
 
 	clientHandlerShutdownRequestCancelFn context.CancelFunc
 
+	maxProtocolVersion primitive.ProtocolVersion
+
 	writeCoalescer *writeCoalescer
+	framing        *connFraming
 
 	responsesDoneChan <-chan bool
 	requestsDoneCtx   context.Context
@@ -50,6 +54,22 @@ type ClientConnector struct {
 	readScheduler *Scheduler
 
 	shutdownRequestCtx context.Context
+
+	// nil if the traffic capture is disabled
+	trafficCapture *TrafficCapture
+
+	frameLogger *FrameLogger
+
+	// nil if the requests of the client connection are not limited
+	requestLimiter *clientRequestLimiter
+
+	// nil if the in flight requests across all client connections are not capped
+	inFlightBudget *inFlightBudget
+
+	// nil if the idle client connections are never closed
+	idleTimeout *clientIdleTimeout
+
+	logEntry *log.Entry // entry with the correlation id of the client connection
 }
 
 func NewClientConnector(
@@ -65,7 +85,16 @@ func NewClientConnector(
 	readScheduler *Scheduler,
 	writeScheduler *Scheduler,
 	shutdownRequestCtx context.Context,
-	clientHandlerShutdownRequestCancelFn context.CancelFunc) *ClientConnector {
+	clientHandlerShutdownRequestCancelFn context.CancelFunc,
+	maxProtocolVersion primitive.ProtocolVersion,
+	trafficCapture *TrafficCapture,
+	frameLogger *FrameLogger,
+	stallWatchdog *stallWatchdog,
+	requestLimiter *clientRequestLimiter,
+	inFlightBudget *inFlightBudget,
+	idleTimeout *clientIdleTimeout,
+	connectionId uint64) *ClientConnector {
+	framing := newConnFraming()
 	return &ClientConnector{
 		connection:              connection,
 		conf:                    conf,
@@ -82,7 +111,10 @@ func NewClientConnector(
 			ClientConnectorLogPrefix,
 			false,
 			false,
-			writeScheduler),
+			writeScheduler,
+			stallWatchdog,
+			framing),
+		framing:                              framing,
 		responsesDoneChan:                    responsesDoneChan,
 		requestsDoneCtx:                      requestsDoneCtx,
 		eventsDoneChan:                       eventsDoneChan,
@@ -90,6 +122,13 @@ func NewClientConnector(
 		readScheduler:                        readScheduler,
 		shutdownRequestCtx:                   shutdownRequestCtx,
 		clientHandlerShutdownRequestCancelFn: clientHandlerShutdownRequestCancelFn,
+		maxProtocolVersion:                   maxProtocolVersion,
+		trafficCapture:                       trafficCapture,
+		frameLogger:                          frameLogger,
+		requestLimiter:                       requestLimiter,
+		inFlightBudget:                       inFlightBudget,
+		idleTimeout:                          idleTimeout,
+		logEntry:                             newConnectionLogEntry(connectionId),
 	}
 }
 
@@ -99,6 +138,17 @@ func NewClientConnector(
 func (cc *ClientConnector) run(activeClients *int32) {
 	cc.listenForRequests()
 	cc.writeCoalescer.RunWriteQueueLoop()
+	if cc.idleTimeout != nil {
+		cc.clientHandlerWg.Add(1)
+		go func() {
+			defer cc.clientHandlerWg.Done()
+			cc.idleTimeout.run(cc.clientHandlerContext, func() {
+				cc.getLogger().Infof("[%s] Client connection %v didn't send any request for %v, closing it.",
+					ClientConnectorLogPrefix, cc.connection.RemoteAddr(), cc.idleTimeout.timeout)
+				cc.clientHandlerCancelFunc()
+			})
+		}()
+	}
 	cc.clientHandlerWg.Add(1)
 	go func() {
 		defer cc.clientHandlerWg.Done()
@@ -106,25 +156,30 @@ func (cc *ClientConnector) run(activeClients *int32) {
 		<-cc.requestsDoneCtx.Done()
 		<-cc.eventsDoneChan
 
-		log.Debugf("[%s] All in flight requests are done, requesting cluster connections of client handler %v "+
+		cc.getLogger().Debugf("[%s] All in flight requests are done, requesting cluster connections of client handler %v "+
 			"to be terminated.", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 		cc.clientHandlerCancelFunc()
 
-		log.Infof("[%s] Shutting down client connection to %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+		cc.getLogger().Infof("[%s] Shutting down client connection to %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 		err := cc.connection.Close()
 		if err != nil {
-			log.Warnf("[%s] Error received while closing connection to %v: %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
+			cc.getLogger().Warnf("[%s] Error received while closing connection to %v: %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr(), err)
 		}
 
-		log.Debugf("[%s] Waiting until request listener is done.", ClientConnectorLogPrefix)
+		cc.getLogger().Debugf("[%s] Waiting until request listener is done.", ClientConnectorLogPrefix)
 		<-cc.clientConnectorRequestsDoneChan
-		log.Debugf("[%s] Shutting down write coalescer.", ClientConnectorLogPrefix)
+		cc.getLogger().Debugf("[%s] Shutting down write coalescer.", ClientConnectorLogPrefix)
 		cc.writeCoalescer.Close()
 
 		atomic.AddInt32(activeClients, -1)
 	}()
 }
 
+// getLogger returns the logger of the client connection, its entries have the id of the client connection.
+func (cc *ClientConnector) getLogger() *log.Entry {
+	return logLevels.getLogger(cc.logEntry, LogModuleClientHandler, cc.connection.RemoteAddr())
+}
+
 func (cc *ClientConnector) listenForRequests() {
 
 	log.Tracef("[%s] listenForRequests for client %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
@@ -136,7 +191,7 @@ func (cc *ClientConnector) listenForRequests() {
 
 		wg := &sync.WaitGroup{}
 		defer wg.Wait()
-		defer log.Debugf("[%s] Shutting down request listener, waiting until request listener tasks are done...", ClientConnectorLogPrefix)
+		defer cc.getLogger().Debugf("[%s] Shutting down request listener, waiting until request listener tasks are done...", ClientConnectorLogPrefix)
 
 		lock := &sync.RWMutex{}
 		closed := false
@@ -156,7 +211,7 @@ func (cc *ClientConnector) listenForRequests() {
 			select {
 			case <-cc.clientHandlerContext.Done():
 			case <-cc.shutdownRequestCtx.Done():
-				log.Debugf("[%s] Entering \"draining\" mode of request listener %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
+				cc.getLogger().Debugf("[%s] Entering \"draining\" mode of request listener %v", ClientConnectorLogPrefix, cc.connection.RemoteAddr())
 			}
 
 			setDrainModeNowFunc()
@@ -166,9 +221,19 @@ func (cc *ClientConnector) listenForRequests() {
 		connectionAddr := cc.connection.RemoteAddr().String()
 		protocolErrOccurred := false
 		for cc.clientHandlerContext.Err() == nil {
-			f, err := readRawFrame(bufferedReader, connectionAddr, cc.clientHandlerContext)
+			if cc.inFlightBudget != nil && !cc.inFlightBudget.wait(cc.clientHandlerContext) {
+				break
+			}
+			f, err := cc.framing.readFrame(bufferedReader, connectionAddr, cc.clientHandlerContext)
+			if err == nil {
+				cc.trafficCapture.record(cc.connection.RemoteAddr(), cc.connection.LocalAddr(), true, f)
+				cc.frameLogger.log(cc.connection.RemoteAddr(), true, f)
+				if cc.idleTimeout != nil {
+					cc.idleTimeout.touch(f, time.Now())
+				}
+			}
 
-			protocolErrResponseFrame, err := checkProtocolError(f, err, protocolErrOccurred, ClientConnectorLogPrefix)
+			protocolErrResponseFrame, err := checkProtocolError(f, err, protocolErrOccurred, cc.maxProtocolVersion, ClientConnectorLogPrefix)
 			if err != nil {
 				handleConnectionError(
 					err, cc.clientHandlerContext, cc.clientHandlerCancelFunc, ClientConnectorLogPrefix, "reading", connectionAddr)
@@ -209,13 +274,15 @@ func (cc *ClientConnector) sendOverloadedToClient(request *frame.RawFrame) {
 	response := frame.NewFrame(request.Header.Version, request.Header.StreamId, msg)
 	rawResponse, err := defaultCodec.ConvertToRawFrame(response)
 	if err != nil {
-		log.Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
+		cc.getLogger().Errorf("[%s] Could not convert frame (%v) to raw frame: %v", ClientConnectorLogPrefix, response, err)
 	} else {
 		cc.sendResponseToClient(rawResponse)
 	}
 }
 
-func checkProtocolError(f *frame.RawFrame, connErr error, protocolErrorOccurred bool, prefix string) (protocolErrResponse *frame.RawFrame, fatalErr error) {
+func checkProtocolError(
+	f *frame.RawFrame, connErr error, protocolErrorOccurred bool, maxProtocolVersion primitive.ProtocolVersion,
+	prefix string) (protocolErrResponse *frame.RawFrame, fatalErr error) {
 	var protocolErrMsg *message.ProtocolError
 	var streamId int16
 	var logMsg string
@@ -224,8 +291,8 @@ func checkProtocolError(f *frame.RawFrame, connErr error, protocolErrorOccurred
 		logMsg = fmt.Sprintf("Protocol error detected while decoding a frame: %v.", connErr)
 		streamId = 0
 	} else {
-		protocolErrMsg = checkProtocolVersion(f.Header.Version)
-		logMsg = "Protocol v5 detected while decoding a frame."
+		protocolErrMsg = checkProtocolVersion(f.Header.Version, maxProtocolVersion)
+		logMsg = fmt.Sprintf("Protocol %v detected while decoding a frame.", f.Header.Version)
 		streamId = f.Header.StreamId
 	}
 
@@ -257,5 +324,11 @@ func generateProtocolErrorResponseFrame(streamId int16, protocolErrMsg *message.
 }
 
 func (cc *ClientConnector) sendResponseToClient(frame *frame.RawFrame) {
+	cc.trafficCapture.record(cc.connection.RemoteAddr(), cc.connection.LocalAddr(), false, frame)
+	cc.frameLogger.log(cc.connection.RemoteAddr(), false, frame)
+	if cc.requestLimiter != nil && !isIntermediateContinuousPage(frame) {
+		// released before the client gets the response so that the stream id is free when the client reuses it
+		cc.requestLimiter.release(frame.Header.StreamId)
+	}
 	cc.writeCoalescer.Enqueue(frame)
 }
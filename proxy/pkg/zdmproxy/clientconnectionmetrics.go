@@ -0,0 +1,65 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const unknownDriverLabel = "unknown"
+
+// trackConnectedClient increments the gauges of the connected clients by protocol version and by driver
+// and returns a function that decrements them when the client connection is closed.
+func trackConnectedClient(
+	metricHandler *metrics.MetricHandler, version primitive.ProtocolVersion, identity *clientIdentity) func() {
+	protocolVersion := getProtocolVersionLabel(version)
+	driverName, driverVersion := getDriverLabels(identity)
+
+	var gauges []metrics.Gauge
+	gauge, err := metricHandler.GetClientConnectionsByProtocolVersion(protocolVersion)
+	if err != nil {
+		log.Errorf("Could not track client connection with protocol version %v: %v", protocolVersion, err)
+	} else {
+		gauges = append(gauges, gauge)
+	}
+	gauge, err = metricHandler.GetClientConnectionsByDriver(driverName, driverVersion)
+	if err != nil {
+		log.Errorf("Could not track client connection of driver %v %v: %v", driverName, driverVersion, err)
+	} else {
+		gauges = append(gauges, gauge)
+	}
+
+	for _, gauge := range gauges {
+		gauge.Add(1)
+	}
+	return func() {
+		for _, gauge := range gauges {
+			gauge.Subtract(1)
+		}
+	}
+}
+
+func getProtocolVersionLabel(version primitive.ProtocolVersion) string {
+	switch version {
+	case primitive.ProtocolVersionDse1:
+		return "DSE_V1"
+	case primitive.ProtocolVersionDse2:
+		return "DSE_V2"
+	default:
+		return fmt.Sprintf("V%d", version)
+	}
+}
+
+// getDriverLabels returns the DRIVER_NAME and DRIVER_VERSION options of the STARTUP request,
+// drivers that don't send them are tracked as "unknown".
+func getDriverLabels(identity *clientIdentity) (string, string) {
+	driverName, driverVersion := unknownDriverLabel, unknownDriverLabel
+	if identity.driverName != "" {
+		driverName = identity.driverName
+	}
+	if identity.driverVersion != "" {
+		driverVersion = identity.driverVersion
+	}
+	return driverName, driverVersion
+}
@@ -0,0 +1,25 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetProtocolVersionLabel(t *testing.T) {
+	require.Equal(t, "V3", getProtocolVersionLabel(primitive.ProtocolVersion3))
+	require.Equal(t, "V4", getProtocolVersionLabel(primitive.ProtocolVersion4))
+	require.Equal(t, "V5", getProtocolVersionLabel(primitive.ProtocolVersion5))
+	require.Equal(t, "DSE_V1", getProtocolVersionLabel(primitive.ProtocolVersionDse1))
+	require.Equal(t, "DSE_V2", getProtocolVersionLabel(primitive.ProtocolVersionDse2))
+}
+
+func TestGetDriverLabels(t *testing.T) {
+	driverName, driverVersion := getDriverLabels(&clientIdentity{driverName: "DataStax Java Driver", driverVersion: "4.14.1"})
+	require.Equal(t, "DataStax Java Driver", driverName)
+	require.Equal(t, "4.14.1", driverVersion)
+
+	driverName, driverVersion = getDriverLabels(&clientIdentity{driverName: "gocql"})
+	require.Equal(t, "gocql", driverName)
+	require.Equal(t, unknownDriverLabel, driverVersion)
+}
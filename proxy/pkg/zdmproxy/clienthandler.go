@@ -12,10 +12,10 @@ import (
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
-	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"net"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -52,6 +52,8 @@ type ClientHandler struct {
 	clientHandlerContext    context.Context
 	clientHandlerCancelFunc context.CancelFunc
 
+	// keyspace (in its internal form) of the last successful USE statement, it is used to resolve the unqualified
+	// table names of the statements of this client connection
 	currentKeyspaceName *atomic.Value
 	handshakeDone       *atomic.Value
 
@@ -62,6 +64,105 @@ type ClientHandler struct {
 	secondaryHandshakeCreds  *AuthCredentials
 	asyncHandshakeCreds      *AuthCredentials
 
+	// nil until the STARTUP request is received
+	clientIdentity *clientIdentity
+
+	// correlation id of the client connection, see newConnectionLogEntry
+	connectionId uint64
+
+	// *log.Entry with the connection id and with the fields of the client identity once the STARTUP request is
+	// received
+	logger *atomic.Value
+
+	// nil if the handshake fast path is disabled
+	handshakeCache *handshakeCache
+
+	// nil if there are no target write filter rules
+	targetWriteFilter *targetWriteFilter
+
+	// nil if every write is forwarded to target
+	targetWriteSampler *targetWriteSampler
+
+	// nil if the responses of the dual writes are not compared
+	writeComparisonSampler *writeComparisonSampler
+
+	// nil if the table level metrics are disabled
+	tableMetrics *tableMetrics
+
+	// nil if the migration progress estimation is disabled
+	migrationProgress *migrationProgress
+
+	// nil if there are no table routing rules
+	tableRoutingRules *tableRoutingRules
+
+	// nil if there are no statement filter rules
+	statementFilter *statementFilter
+
+	// nil if the consistency level of the requests forwarded to the cluster is not overridden
+	originConsistencyOverride *consistencyOverride
+	targetConsistencyOverride *consistencyOverride
+
+	// nil if the idempotent requests are not retried on the cluster
+	originRequestRetryPolicy *requestRetryPolicy
+	targetRequestRetryPolicy *requestRetryPolicy
+
+	requestTimeoutPolicy *requestTimeoutPolicy
+
+	// nil if there are no unsupported target schema features
+	targetDdlChecker *targetDdlChecker
+
+	// nil if every schema statement is forwarded to both clusters
+	schemaStatementPolicies *schemaStatementPolicies
+
+	// nil if destructive statements don't need to be confirmed
+	destructiveStatementGuard *DestructiveStatementGuard
+
+	// nil if the requests of the client connection are not limited
+	requestLimiter *clientRequestLimiter
+
+	// nil if the in flight requests across all client connections are not capped
+	inFlightBudget *inFlightBudget
+
+	// nil if there are no application read routing rules
+	applicationReadRouting *applicationReadRouting
+
+	// rules of the client application, nil until the STARTUP request is received or if none apply to the client
+	sessionReadRoutingRules sessionReadRoutingRules
+
+	// nil if the responses of intercepted queries are not cached
+	interceptedResponseCache *interceptedResponseCache
+
+	connectedClients *connectedClients
+
+	// nil if the system_views.zdm_* tables are disabled
+	proxyVirtualTables *proxyVirtualTables
+
+	// nil if the zdm_admin keyspace is disabled
+	adminKeyspace *adminKeyspace
+
+	// notifies the changes of the proxy instances (ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE)
+	proxyTopology *proxyTopology
+
+	// protocol version of the REGISTER request of the client for TOPOLOGY_CHANGE events, 0 until the client registers
+	topologyEventsVersion int32
+	// protocol version of the REGISTER request of the client for STATUS_CHANGE events, 0 until the client registers
+	statusEventsVersion int32
+
+	// username of the credentials that the client authenticated with, empty until the handshake finishes
+	clientRole string
+
+	// SASL negotiation between the client and the primary cluster, nil until the STARTUP response is received
+	clientAuthNegotiation *clientAuthNegotiation
+
+	// nil if the clients are not authenticated by the proxy (see ZDM_PROXY_AUTH_CREDENTIALS)
+	proxyAuthenticator *proxyAuthenticator
+	// response of the primary cluster to the STARTUP request when the clients are authenticated by the proxy
+	primaryStartupResponse *frame.RawFrame
+
+	// channels of the secondary handshakes that were started before the primary handshake finished (fast path)
+	earlySecondaryHandshakeChannel chan error
+	earlyAsyncHandshakeChannel     chan error
+
 	targetUsername string
 	targetPassword string
 
@@ -106,9 +207,22 @@ type ClientHandler struct {
 	targetObserver *protocolEventObserverImpl
 
 	primaryCluster               common.ClusterType
+	eventRouter                  *eventRouter
+	asyncReads                   bool
+	asyncWrites                  bool
+	lwtPolicy                    common.LwtPolicy
+	counterWritesPolicy          common.CounterWritesPolicy
+	pagingStatePolicy            common.PagingStatePolicy
+	secondaryWriteFailureMode    common.SecondaryWriteFailureMode
+	secondaryWriteJournal        *writeJournal // nil if ZDM_SECONDARY_WRITE_JOURNAL_TYPE is NONE
+	tracer                       *tracer       // nil if ZDM_TRACING_OTLP_ENDPOINT is not set
+	slowQueryLog                 *slowQueryLog // nil if ZDM_SLOW_QUERY_LOG_THRESHOLD_MS is 0
+	auditLog                     *auditLog     // nil if ZDM_AUDIT_LOG_DESTINATION is not set
+	readMirroring                bool
 	forwardSystemQueriesToTarget bool
 	forwardAuthToTarget          bool
-	targetCredsOnClientRequest   bool
+	originCredentialsMode        common.CredentialsMode // CLIENT or CONFIGURED
+	targetCredentialsMode        common.CredentialsMode // CLIENT or CONFIGURED
 
 	queryModifier     *QueryModifier
 	parameterModifier *ParameterModifier
@@ -116,6 +230,11 @@ type ClientHandler struct {
 
 	clientHandlerShutdownRequestCancelFn context.CancelFunc
 	clientHandlerShutdownRequestContext  context.Context
+
+	explainRequests bool
+	explainLevel    log.Level
+
+	requestInterceptors []RequestInterceptor // see ZdmProxyOptions.RequestInterceptors
 }
 
 func NewClientHandler(
@@ -126,6 +245,7 @@ func NewClientHandler(
 	targetControlConn *ControlConn,
 	conf *config.Config,
 	topologyConfig *common.TopologyConfig,
+	proxyTopology *proxyTopology,
 	targetUsername string,
 	targetPassword string,
 	originUsername string,
@@ -142,13 +262,56 @@ func NewClientHandler(
 	targetHost *Host,
 	timeUuidGenerator TimeUuidGenerator,
 	readMode common.ReadMode,
+	dualWriteMode common.DualWriteMode,
+	lwtPolicy common.LwtPolicy,
+	counterWritesPolicy common.CounterWritesPolicy,
+	pagingStatePolicy common.PagingStatePolicy,
+	secondaryWriteFailureMode common.SecondaryWriteFailureMode,
+	secondaryWriteJournal *writeJournal,
+	tracer *tracer,
+	slowQueryLog *slowQueryLog,
+	auditLog *auditLog,
 	primaryCluster common.ClusterType,
-	systemQueriesMode common.SystemQueriesMode) (*ClientHandler, error) {
+	systemQueriesMode common.SystemQueriesMode,
+	maxProtocolVersion primitive.ProtocolVersion,
+	handshakeCache *handshakeCache,
+	targetWriteFilter *targetWriteFilter,
+	targetWriteSampler *targetWriteSampler,
+	writeComparisonSampler *writeComparisonSampler,
+	tableMetrics *tableMetrics,
+	migrationProgress *migrationProgress,
+	originConsistencyOverride *consistencyOverride,
+	targetConsistencyOverride *consistencyOverride,
+	originRequestRetryPolicy *requestRetryPolicy,
+	targetRequestRetryPolicy *requestRetryPolicy,
+	requestTimeoutPolicy *requestTimeoutPolicy,
+	tableRoutingRules *tableRoutingRules,
+	statementFilter *statementFilter,
+	targetDdlChecker *targetDdlChecker,
+	schemaStatementPolicies *schemaStatementPolicies,
+	destructiveStatementGuard *DestructiveStatementGuard,
+	applicationReadRouting *applicationReadRouting,
+	interceptedResponseCache *interceptedResponseCache,
+	connectedClients *connectedClients,
+	proxyVirtualTables *proxyVirtualTables,
+	adminKeyspace *adminKeyspace,
+	trafficCapture *TrafficCapture,
+	frameLogger *FrameLogger,
+	requestInterceptors []RequestInterceptor,
+	stallWatchdog *stallWatchdog,
+	inFlightBudget *inFlightBudget,
+	proxyAuthenticator *proxyAuthenticator) (*ClientHandler, error) {
 
 	originEndpointId := originCassandraConnInfo.endpoint.GetEndpointIdentifier()
 	targetEndpointId := targetCassandraConnInfo.endpoint.GetEndpointIdentifier()
+	asyncReads := readMode == common.ReadModeDualAsyncOnSecondary || conf.ReadMirroringEnabled
+	asyncWrites := dualWriteMode == common.DualWriteModeAsync
+	// the async connector also replays the writes that failed on the secondary cluster unless they are replayed from
+	// the write journal
+	replayWrites := secondaryWriteFailureMode == common.SecondaryWriteFailureModeQueueForReplay &&
+		secondaryWriteJournal == nil
 	asyncEndpointId := ""
-	if readMode == common.ReadModeDualAsyncOnSecondary {
+	if asyncReads || asyncWrites || replayWrites {
 		if primaryCluster == common.ClusterTypeTarget {
 			asyncEndpointId = originEndpointId
 		} else {
@@ -173,17 +336,20 @@ func NewClientHandler(
 		clientHandlerShutdownRequestCancelFn()
 		localClientHandlerWg.Wait()
 		requestsDoneCancelFn() // make sure this ctx is not leaked but it should be canceled before this
-		log.Debugf("Client Handler is shutdown.")
+		moduleLogger(LogModuleClientHandler).Debugf("Client Handler is shutdown.")
 	}()
 
 	respChannel := make(chan *Response, numWorkers)
 	clientHandlerRequestWg := &sync.WaitGroup{}
 	handshakeDone := &atomic.Value{}
+	connectionId := newConnectionId()
+	logger := &atomic.Value{}
+	logger.Store(newConnectionLogEntry(connectionId))
 
 	originConnector, err := NewClusterConnector(
 		originCassandraConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
 		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-		false, nil, handshakeDone)
+		false, nil, handshakeDone, maxProtocolVersion, stallWatchdog, connectionId)
 	if err != nil {
 		clientHandlerCancelFunc()
 		return nil, err
@@ -192,7 +358,7 @@ func NewClientHandler(
 	targetConnector, err := NewClusterConnector(
 		targetCassandraConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
 		clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-		false, nil, handshakeDone)
+		false, nil, handshakeDone, maxProtocolVersion, stallWatchdog, connectionId)
 	if err != nil {
 		clientHandlerCancelFunc()
 		return nil, err
@@ -200,7 +366,7 @@ func NewClientHandler(
 
 	asyncPendingRequests := newPendingRequests(MaxStreams, nodeMetrics)
 	var asyncConnector *ClusterConnector
-	if readMode == common.ReadModeDualAsyncOnSecondary {
+	if asyncReads || asyncWrites || replayWrites {
 		var asyncConnInfo *ClusterConnectionInfo
 		if primaryCluster == common.ClusterTypeTarget {
 			asyncConnInfo = originCassandraConnInfo
@@ -210,7 +376,7 @@ func NewClientHandler(
 		asyncConnector, err = NewClusterConnector(
 			asyncConnInfo, conf, psCache, nodeMetrics, localClientHandlerWg, clientHandlerRequestWg,
 			clientHandlerContext, clientHandlerCancelFunc, respChannel, readScheduler, writeScheduler, requestsDoneCtx,
-			true, asyncPendingRequests, handshakeDone)
+			true, asyncPendingRequests, handshakeDone, maxProtocolVersion, stallWatchdog, connectionId)
 		if err != nil {
 			log.Errorf("Could not create async cluster connector to %s, async requests will not be forwarded: %s", asyncConnInfo.connConfig.GetClusterType(), err.Error())
 			asyncConnector = nil
@@ -229,8 +395,28 @@ func NewClientHandler(
 		targetObserver = NewProtocolEventObserver(clientHandlerShutdownRequestCancelFn, targetHost)
 	}
 
-	forwardAuthToTarget, targetCredsOnClientRequest := forwardAuthToTarget(
-		originControlConn, targetControlConn, conf.ForwardClientCredentialsToOrigin)
+	originCredentialsMode, targetCredentialsMode, err := conf.ParseCredentialsModes()
+	if err != nil {
+		return nil, err
+	}
+	forwardAuthToTarget, originCredentialsMode, targetCredentialsMode := forwardAuthToTarget(
+		originControlConn, targetControlConn, conf.ForwardClientCredentialsToOrigin,
+		originCredentialsMode, targetCredentialsMode)
+
+	explainLevel, explainRequests := getRequestExplainLevel(conf, clientTcpConn.RemoteAddr())
+
+	requestLimitConfig, err := conf.ParseProxyClientRequestLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+	requestLimiter := newClientRequestLimiter(
+		requestLimitConfig, metricHandler.GetProxyMetrics().ClientLimitedRequests)
+
+	clientIdleTimeout, err := conf.ParseProxyClientIdleTimeout()
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout := newClientIdleTimeout(clientIdleTimeout, metricHandler.GetProxyMetrics().ClientIdleConnectionsClosed)
 
 	return &ClientHandler{
 		clientConnector: NewClientConnector(
@@ -246,7 +432,15 @@ func NewClientHandler(
 			readScheduler,
 			writeScheduler,
 			clientHandlerShutdownRequestContext,
-			clientHandlerShutdownRequestCancelFn),
+			clientHandlerShutdownRequestCancelFn,
+			maxProtocolVersion,
+			trafficCapture,
+			frameLogger,
+			stallWatchdog,
+			requestLimiter,
+			inFlightBudget,
+			idleTimeout,
+			connectionId),
 
 		asyncConnector:                       asyncConnector,
 		originCassandraConnector:             originConnector,
@@ -262,6 +456,37 @@ func NewClientHandler(
 		handshakeDone:                        handshakeDone,
 		authErrorMessage:                     nil,
 		startupRequest:                       nil,
+		clientIdentity:                       nil,
+		connectionId:                         connectionId,
+		logger:                               logger,
+		handshakeCache:                       handshakeCache,
+		targetWriteFilter:                    targetWriteFilter,
+		targetWriteSampler:                   targetWriteSampler,
+		writeComparisonSampler:               writeComparisonSampler,
+		tableMetrics:                         tableMetrics,
+		migrationProgress:                    migrationProgress,
+		originConsistencyOverride:            originConsistencyOverride,
+		targetConsistencyOverride:            targetConsistencyOverride,
+		originRequestRetryPolicy:             originRequestRetryPolicy,
+		targetRequestRetryPolicy:             targetRequestRetryPolicy,
+		requestTimeoutPolicy:                 requestTimeoutPolicy,
+		tableRoutingRules:                    tableRoutingRules,
+		statementFilter:                      statementFilter,
+		targetDdlChecker:                     targetDdlChecker,
+		schemaStatementPolicies:              schemaStatementPolicies,
+		destructiveStatementGuard:            destructiveStatementGuard,
+		requestLimiter:                       requestLimiter,
+		inFlightBudget:                       inFlightBudget,
+		applicationReadRouting:               applicationReadRouting,
+		sessionReadRoutingRules:              nil,
+		interceptedResponseCache:             interceptedResponseCache,
+		connectedClients:                     connectedClients,
+		proxyVirtualTables:                   proxyVirtualTables,
+		adminKeyspace:                        adminKeyspace,
+		proxyTopology:                        proxyTopology,
+		topologyEventsVersion:                0,
+		statusEventsVersion:                  0,
+		clientRole:                           "",
 		targetUsername:                       targetUsername,
 		targetPassword:                       targetPassword,
 		originUsername:                       originUsername,
@@ -286,14 +511,31 @@ func NewClientHandler(
 		originObserver:                       originObserver,
 		targetObserver:                       targetObserver,
 		primaryCluster:                       primaryCluster,
+		eventRouter:                          newEventRouter(primaryCluster, topologyConfig.VirtualizationEnabled),
+		asyncReads:                           asyncReads,
+		asyncWrites:                          asyncWrites,
+		lwtPolicy:                            lwtPolicy,
+		counterWritesPolicy:                  counterWritesPolicy,
+		pagingStatePolicy:                    pagingStatePolicy,
+		secondaryWriteFailureMode:            secondaryWriteFailureMode,
+		secondaryWriteJournal:                secondaryWriteJournal,
+		tracer:                               tracer,
+		slowQueryLog:                         slowQueryLog,
+		auditLog:                             auditLog,
+		readMirroring:                        conf.ReadMirroringEnabled,
 		forwardSystemQueriesToTarget:         systemQueriesMode == common.SystemQueriesModeTarget,
 		forwardAuthToTarget:                  forwardAuthToTarget,
-		targetCredsOnClientRequest:           targetCredsOnClientRequest,
+		originCredentialsMode:                originCredentialsMode,
+		targetCredentialsMode:                targetCredentialsMode,
+		proxyAuthenticator:                   proxyAuthenticator,
 		queryModifier:                        NewQueryModifier(timeUuidGenerator),
 		parameterModifier:                    NewParameterModifier(timeUuidGenerator),
 		timeUuidGenerator:                    timeUuidGenerator,
 		clientHandlerShutdownRequestCancelFn: clientHandlerShutdownRequestCancelFn,
 		clientHandlerShutdownRequestContext:  clientHandlerShutdownRequestContext,
+		explainRequests:                      explainRequests,
+		explainLevel:                         explainLevel,
+		requestInterceptors:                  requestInterceptors,
 	}, nil
 }
 
@@ -350,25 +592,46 @@ func removeObserver(observer *protocolEventObserverImpl, controlConn *ControlCon
 	}
 }
 
+// getLogger returns the logger of the client connection, its entries contain the identity of the client
+// (see clientIdentity) after the STARTUP request is received. It writes the debug logs if they are enabled for the
+// client handler module or for the client (see LogLevelController).
+func (ch *ClientHandler) getLogger() *log.Entry {
+	if ch.logger == nil {
+		return moduleLogger(LogModuleClientHandler)
+	}
+	var clientAddr net.Addr
+	if ch.clientConnector != nil {
+		clientAddr = ch.clientConnector.connection.RemoteAddr()
+	}
+	return logLevels.getLogger(ch.logger.Load().(*log.Entry), LogModuleClientHandler, clientAddr)
+}
+
+// getRequestLogger returns the logger of a request, its entries also have the correlation id of the request.
+func (ch *ClientHandler) getRequestLogger(streamId int16) *log.Entry {
+	return withRequestFields(ch.getLogger(), ch.connectionId, streamId)
+}
+
 // Infinite loop that blocks on receiving from the requests channel.
 func (ch *ClientHandler) requestLoop() {
 	ready := false
 	var err error
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("requestLoop starting now")
+	ch.getLogger().Debugf("requestLoop starting now")
 	go func() {
 		defer ch.localClientHandlerWg.Done()
 		connectionAddr := ch.clientConnector.connection.RemoteAddr().String()
-		defer log.Debugf("Client Handler request loop %v shutdown.", connectionAddr)
+		defer ch.getLogger().Debugf("Client Handler request loop %v shutdown.", connectionAddr)
 		defer ch.requestsDoneCancelFn()
 		defer ch.originCassandraConnector.writeCoalescer.Close()
-		defer log.Debugf("Waiting for origin write coalescer to finish...")
+		defer ch.getLogger().Debugf("Waiting for origin write coalescer to finish...")
 		defer ch.targetCassandraConnector.writeCoalescer.Close()
-		defer log.Debugf("Waiting for target write coalescer to finish...")
+		defer ch.getLogger().Debugf("Waiting for target write coalescer to finish...")
 		if ch.asyncConnector != nil {
 			defer ch.asyncConnector.writeCoalescer.Close()
-			defer log.Debugf("Waiting for async %s write coalescer to finish...", ch.asyncConnector.clusterType)
+			defer ch.getLogger().Debugf("Waiting for async %s write coalescer to finish...", ch.asyncConnector.clusterType)
 		}
+		defer ch.originCassandraConnector.closeRequestLimiter(false)
+		defer ch.targetCassandraConnector.closeRequestLimiter(false)
 
 		wg := &sync.WaitGroup{}
 		for {
@@ -382,21 +645,34 @@ func (ch *ClientHandler) requestLoop() {
 				continue
 			}
 
-			log.Tracef("Request received on client handler: %v", f.Header)
+			ch.getLogger().Tracef("Request received on client handler: %v", f.Header)
 			if !ready {
-				log.Tracef("not ready")
+				ch.getLogger().Tracef("not ready")
 				// Handle client authentication
 				ready, err = ch.handleHandshakeRequest(f, wg)
 				if err != nil && !errors.Is(err, ShutdownErr) {
-					log.Error(err)
+					ch.getLogger().Error(err)
 				}
 				if ready {
 					ch.handshakeDone.Store(true)
-					log.Infof(
+					ch.getLogger().Infof(
 						"Handshake successful with client %s", connectionAddr)
+					if ch.clientIdentity != nil {
+						untrackConnectedClient := trackConnectedClient(
+							ch.metricHandler, ch.startupRequest.Header.Version, ch.clientIdentity)
+						defer untrackConnectedClient()
+					}
+					unregisterClient := ch.connectedClients.register(ch)
+					defer unregisterClient()
 				}
-				log.Tracef("ready? %t", ready)
+				ch.getLogger().Tracef("ready? %t", ready)
 			} else {
+				if ch.requestLimiter != nil {
+					if reason := ch.requestLimiter.tryAcquire(f, time.Now()); reason != "" {
+						ch.rejectLimitedRequest(f, reason)
+						continue
+					}
+				}
 				wg.Add(1)
 				ch.requestResponseScheduler.Schedule(func() {
 					defer wg.Done()
@@ -405,7 +681,7 @@ func (ch *ClientHandler) requestLoop() {
 			}
 		}
 
-		log.Debugf("Shutting down client handler request listener %v.", connectionAddr)
+		ch.getLogger().Debugf("Shutting down client handler request listener %v.", connectionAddr)
 
 		wg.Wait()
 
@@ -417,7 +693,7 @@ func (ch *ClientHandler) requestLoop() {
 				ch.asyncPendingRequests.clear(func(ctx RequestContext) {
 					typedReqCtx, ok := ctx.(*asyncRequestContextImpl)
 					if !ok {
-						log.Errorf("Failed to cancel async request because request context conversion failed. "+
+						ch.getLogger().Errorf("Failed to cancel async request because request context conversion failed. "+
 							"This is most likely a bug, please report. AsyncRequestContext: %v", ctx)
 					} else {
 						if !typedReqCtx.expectedResponse {
@@ -428,7 +704,7 @@ func (ch *ClientHandler) requestLoop() {
 			}
 		}()
 
-		log.Debugf("Waiting for all in flight requests from %v to finish.", connectionAddr)
+		ch.getLogger().Debugf("Waiting for all in flight requests from %v to finish.", connectionAddr)
 		ch.clientHandlerRequestWaitGroup.Wait()
 	}()
 }
@@ -444,7 +720,7 @@ func (ch *ClientHandler) clearRequestContexts(contextHoldersMap *sync.Map) {
 		if canceled {
 			typedReqCtx, ok := reqCtx.(*requestContextImpl)
 			if !ok {
-				log.Errorf("Failed to cancel request because request context conversion failed. "+
+				ch.getLogger().Errorf("Failed to cancel request because request context conversion failed. "+
 					"This is most likely a bug, please report. RequestContext: %v", reqCtx)
 			} else {
 				ch.cancelRequest(reqCtxHolder, typedReqCtx)
@@ -456,17 +732,21 @@ func (ch *ClientHandler) clearRequestContexts(contextHoldersMap *sync.Map) {
 
 // Infinite loop that blocks on receiving from both cluster connector event channels.
 //
-// Event messages that come through will only be routed if
-//   - it's a schema change from origin
+// The eventRouter decides which event messages are sent to the client.
 func (ch *ClientHandler) listenForEventMessages() {
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("listenForEventMessages loop starting now")
+	ch.getLogger().Debugf("listenForEventMessages loop starting now")
 	go func() {
 		defer ch.localClientHandlerWg.Done()
 		defer close(ch.eventsDoneChan)
 		shutDownChannels := 0
 		targetChannel := ch.targetCassandraConnector.clusterConnEventsChan
 		originChannel := ch.originCassandraConnector.clusterConnEventsChan
+		var proxyTopologyChannel chan *message.TopologyChangeEvent
+		if ch.topologyConfig.VirtualizationEnabled {
+			proxyTopologyChannel = ch.proxyTopology.subscribe()
+			defer ch.proxyTopology.unsubscribe(proxyTopologyChannel)
+		}
 		for {
 			if shutDownChannels >= 2 {
 				break
@@ -480,7 +760,7 @@ func (ch *ClientHandler) listenForEventMessages() {
 			select {
 			case event, ok = <-targetChannel:
 				if !ok {
-					log.Debugf("Target event channel closed")
+					ch.getLogger().Debugf("Target event channel closed")
 					shutDownChannels++
 					targetChannel = nil
 					continue
@@ -488,65 +768,99 @@ func (ch *ClientHandler) listenForEventMessages() {
 				fromTarget = true
 			case event, ok = <-originChannel:
 				if !ok {
-					log.Debugf("Origin event channel closed")
+					ch.getLogger().Debugf("Origin event channel closed")
 					shutDownChannels++
 					originChannel = nil
 					continue
 				}
 				fromTarget = false
+			case proxyTopologyEvent := <-proxyTopologyChannel:
+				ch.sendProxyTopologyEvent(proxyTopologyEvent)
+				continue
 			}
 
-			log.Debugf("Message received (fromTarget: %v) on event listener of the client handler: %v", fromTarget, event.Header)
+			ch.getLogger().Debugf("Message received (fromTarget: %v) on event listener of the client handler: %v", fromTarget, event.Header)
 
 			body, err := defaultCodec.DecodeBody(event.Header, bytes.NewReader(event.Body))
 			if err != nil {
-				log.Warnf("Error decoding event response: %v", err)
+				ch.getLogger().Warnf("Error decoding event response: %v", err)
 				continue
 			}
 
-			switch msgType := body.Message.(type) {
-			case *message.ProtocolError:
-				log.Debug("Received protocol error on event body listener, forwarding to client: ", body.Message)
-			case *message.SchemaChangeEvent:
-				if fromTarget {
-					log.Infof("Received schema change event from target, skipping: %v", msgType)
-					continue
-				}
-			case *message.StatusChangeEvent:
-				if ch.topologyConfig.VirtualizationEnabled {
-					log.Infof("Received status change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
-					continue
-				}
-				if !fromTarget {
-					log.Infof("Received status change event from origin, skipping: %v", msgType)
-					continue
-				}
-			case *message.TopologyChangeEvent:
-				if ch.topologyConfig.VirtualizationEnabled {
-					log.Infof("Received topology change event (fromTarget=%v) but virtualization is enabled, skipping: %v", fromTarget, msgType)
-					continue
-				}
-				if !fromTarget {
-					log.Infof("Received topology change event from origin, skipping: %v", msgType)
-					continue
-				}
-			default:
-				log.Infof("Expected event body (fromTarget: %v) but got: %v", fromTarget, msgType)
+			cluster := common.ClusterTypeOrigin
+			if fromTarget {
+				cluster = common.ClusterTypeTarget
+			}
+			if forward, reason := ch.eventRouter.route(body.Message, cluster, time.Now()); !forward {
+				ch.getLogger().Infof("Received %v, skipping: %v", reason, body.Message)
 				continue
 			}
 
 			ch.clientConnector.sendResponseToClient(event)
 		}
 
-		log.Debugf("Shutting down client event messages listener.")
+		ch.getLogger().Debugf("Shutting down client event messages listener.")
 	}()
 }
 
+// trackRegisteredEvents stores the protocol version of the REGISTER request if the client registers for
+// TOPOLOGY_CHANGE or STATUS_CHANGE events so that the events of the proxy instances can be sent to the client.
+func (ch *ClientHandler) trackRegisteredEvents(request *frame.RawFrame) {
+	body, err := defaultCodec.DecodeBody(request.Header, bytes.NewReader(request.Body))
+	if err != nil {
+		ch.getLogger().Warnf("Could not decode REGISTER request: %v", err)
+		return
+	}
+	register, ok := body.Message.(*message.Register)
+	if !ok {
+		return
+	}
+	for _, eventType := range register.EventTypes {
+		switch eventType {
+		case primitive.EventTypeTopologyChange:
+			atomic.StoreInt32(&ch.topologyEventsVersion, int32(request.Header.Version))
+		case primitive.EventTypeStatusChange:
+			atomic.StoreInt32(&ch.statusEventsVersion, int32(request.Header.Version))
+		}
+	}
+}
+
+// sendProxyTopologyEvent sends a TOPOLOGY_CHANGE event of a proxy instance that was added or removed
+// if the client registered for these events.
+func (ch *ClientHandler) sendProxyTopologyEvent(event *message.TopologyChangeEvent) {
+	version := primitive.ProtocolVersion(atomic.LoadInt32(&ch.topologyEventsVersion))
+	if version == 0 || !version.SupportsTopologyChangeType(event.ChangeType) {
+		return
+	}
+	ch.getLogger().Infof("Sending proxy topology change event to client: %v", event)
+	eventFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, -1, event))
+	if err != nil {
+		ch.getLogger().Errorf("Could not encode proxy topology change event %v: %v", event, err)
+		return
+	}
+	ch.clientConnector.sendResponseToClient(eventFrame)
+}
+
+// sendProxyStatusEvent sends a STATUS_CHANGE event of this proxy instance if the client registered for these events.
+func (ch *ClientHandler) sendProxyStatusEvent(event *message.StatusChangeEvent) {
+	version := primitive.ProtocolVersion(atomic.LoadInt32(&ch.statusEventsVersion))
+	if version == 0 {
+		return
+	}
+	ch.getLogger().Infof("Sending proxy status change event to client: %v", event)
+	eventFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, -1, event))
+	if err != nil {
+		ch.getLogger().Errorf("Could not encode proxy status change event %v: %v", event, err)
+		return
+	}
+	ch.clientConnector.sendResponseToClient(eventFrame)
+}
+
 // Infinite loop that blocks on receiving from the response channel
 // (which is written by both cluster connectors).
 func (ch *ClientHandler) responseLoop() {
 	ch.localClientHandlerWg.Add(1)
-	log.Debugf("responseLoop starting now")
+	ch.getLogger().Debugf("responseLoop starting now")
 	go func() {
 		defer ch.localClientHandlerWg.Done()
 		defer close(ch.responsesDoneChan)
@@ -593,15 +907,34 @@ func (ch *ClientHandler) responseLoop() {
 				reqCtx := holder.Get()
 				if reqCtx == nil {
 					if ch.clientHandlerContext.Err() == nil {
-						log.Warnf("Could not find request context for stream id %d received from %v. "+
+						ch.getRequestLogger(streamId).Warnf("Could not find request context for stream id %d received from %v. "+
 							"It either timed out or a protocol error occurred.", streamId, response.connectorType)
 					}
 					return
 				}
 
+				if response.responseFrame != nil && response.connectorType != ClusterConnectorTypeAsync {
+					typedReqCtx, ok := reqCtx.(*requestContextImpl)
+					if ok && ch.retryRequest(typedReqCtx, response.responseFrame, responseClusterType) {
+						if reqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
+							trackClusterErrorMetrics(response.responseFrame, response.connectorType, ch.nodeMetrics)
+						}
+						return
+					}
+					if ok && typedReqCtx.continuousPaging != nil {
+						response.responseFrame = ch.forwardContinuousPages(typedReqCtx, response.responseFrame)
+						if response.responseFrame == nil {
+							return
+						}
+					}
+				}
+
 				finished := false
 				if response.responseFrame == nil {
 					finished = reqCtx.SetTimeout(ch.nodeMetrics, response.requestFrame)
+					if finished {
+						ch.recordCircuitBreakerTimeouts(reqCtx)
+					}
 				} else {
 					finished = reqCtx.SetResponse(ch.nodeMetrics, response.responseFrame, responseClusterType, response.connectorType)
 					if reqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
@@ -612,7 +945,7 @@ func (ch *ClientHandler) responseLoop() {
 				if finished {
 					typedReqCtx, ok := reqCtx.(*requestContextImpl)
 					if !ok {
-						log.Errorf("Failed to finish request because request context conversion failed. "+
+						ch.getLogger().Errorf("Failed to finish request because request context conversion failed. "+
 							"This is most likely a bug, please report. RequestContext: %v", reqCtx)
 					} else {
 						ch.finishRequest(holder, typedReqCtx)
@@ -621,7 +954,7 @@ func (ch *ClientHandler) responseLoop() {
 			})
 		}
 
-		log.Debugf("Shutting down responseLoop.")
+		ch.getLogger().Debugf("Shutting down responseLoop.")
 	}()
 }
 
@@ -630,16 +963,16 @@ func (ch *ClientHandler) responseLoop() {
 func (ch *ClientHandler) tryProcessProtocolError(response *Response, protocolErrOccurred *int32) bool {
 	errMsg, err := decodeError(response.responseFrame)
 	if err != nil {
-		log.Errorf("Could not check if error from %v was protocol error: %v, skipping it.",
+		ch.getLogger().Errorf("Could not check if error from %v was protocol error: %v, skipping it.",
 			response.connectorType, response.responseFrame.Header)
 		return false
 	} else if errMsg != nil && errMsg.GetErrorCode() == primitive.ErrorCodeProtocolError {
 		if atomic.CompareAndSwapInt32(protocolErrOccurred, 0, 1) {
 			if ch.handshakeDone.Load() != nil {
-				log.Errorf("[ClientHandler] Protocol error detected (%v) on %v, forwarding it to the client.",
+				ch.getLogger().Errorf("[ClientHandler] Protocol error detected (%v) on %v, forwarding it to the client.",
 					errMsg, response.connectorType)
 			} else {
-				log.Debugf("[ClientHandler] Protocol version downgrade detected (%v) on %v, forwarding it to the client.",
+				ch.getLogger().Debugf("[ClientHandler] Protocol version downgrade detected (%v) on %v, forwarding it to the client.",
 					errMsg, response.connectorType)
 			}
 			ch.clientConnector.sendResponseToClient(response.responseFrame)
@@ -671,54 +1004,122 @@ func decodeError(responseFrame *frame.RawFrame) (message.Error, error) {
 	return nil, nil
 }
 
+// retryRequest sends the request again to the cluster after the backoff delay if the response is a retryable error
+// and if the request has retries left on the cluster (see ZDM_<CLUSTER>_REQUEST_MAX_RETRIES). Returns false if the
+// response should be set on the request context.
+func (ch *ClientHandler) retryRequest(
+	reqCtx *requestContextImpl, response *frame.RawFrame, cluster common.ClusterType) bool {
+	if (reqCtx.originRetry == nil && reqCtx.targetRetry == nil) || !isRetryableResponse(response) {
+		return false
+	}
+
+	retry, delay, ok := reqCtx.nextRetry(cluster, ch.clientHandlerRequestWaitGroup)
+	if !ok {
+		return false
+	}
+
+	retry.policy.retriedRequests.Add(1)
+	ch.getRequestLogger(reqCtx.request.Header.StreamId).Debugf("Request with stream id %d failed on %v with a retryable error, retrying it in %v (retry %d of %d).",
+		response.Header.StreamId, cluster, delay, retry.retries, retry.policy.maxRetries)
+	connector := ch.originCassandraConnector
+	if cluster == common.ClusterTypeTarget {
+		connector = ch.targetCassandraConnector
+	}
+	go func() {
+		defer ch.clientHandlerRequestWaitGroup.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ch.clientHandlerContext.Done():
+			return
+		}
+		if reqCtx.isPending() {
+			connector.sendRequestToCluster(retry.newRequest())
+		}
+	}()
+	return true
+}
+
 // should only be called after SetTimeout or SetResponse returns true
 func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *requestContextImpl) {
 	defer ch.clientHandlerRequestWaitGroup.Done()
+	if ch.inFlightBudget != nil {
+		defer ch.inFlightBudget.release()
+	}
 
 	err := holder.Clear(reqCtx)
 	if err != nil {
-		log.Debugf("Could not free stream id: %v", err)
+		ch.getLogger().Debugf("Could not free stream id: %v", err)
 	}
 
 	if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
-		switch reqCtx.requestInfo.GetForwardDecision() {
+		switch getMetricsForwardDecision(reqCtx.requestInfo) {
 		case forwardToBoth:
 			proxyMetrics.ProxyWritesDuration.Track(reqCtx.startTime)
 			proxyMetrics.InFlightWrites.Subtract(1)
+			ch.migrationProgress.recordDualWrite()
 		case forwardToOrigin:
 			proxyMetrics.ProxyReadsOriginDuration.Track(reqCtx.startTime)
 			proxyMetrics.InFlightReadsOrigin.Subtract(1)
+			ch.migrationProgress.recordRead(common.ClusterTypeOrigin)
 		case forwardToTarget:
 			proxyMetrics.ProxyReadsTargetDuration.Track(reqCtx.startTime)
 			proxyMetrics.InFlightReadsTarget.Subtract(1)
+			ch.migrationProgress.recordRead(common.ClusterTypeTarget)
 		case forwardToAsyncOnly, forwardToNone:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
+			ch.getLogger().Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
 		}
+		reqCtx.tableRequestMetrics.track(reqCtx.startTime)
+	}
+	if reqCtx.statementDuration != nil {
+		reqCtx.statementDuration.Track(reqCtx.startTime)
 	}
 
+	aggregateSpan := reqCtx.trace.startSpan("aggregate responses")
 	aggregatedResponse, responseClusterType, err := ch.computeClientResponse(reqCtx)
+	// the responses are compared after the client response is sent
+	defer reqCtx.readComparison.setPrimaryResponse(aggregatedResponse)
+
 	finalResponse := aggregatedResponse
 	if err == nil && reqCtx.requestInfo.GetForwardDecision() != forwardToAsyncOnly {
 		// async only requests can't have "PREPARED", "SETKEYSPACE" or "UNPREPARED" responses so skip this
 		finalResponse, err = ch.processClientResponse(aggregatedResponse, responseClusterType, reqCtx)
 	}
+	if err == nil && ch.pagingStatePolicy != common.PagingStatePolicyNone {
+		if fwdDecision := reqCtx.requestInfo.GetForwardDecision(); fwdDecision == forwardToOrigin || fwdDecision == forwardToTarget {
+			finalResponse = tagResponsePagingState(finalResponse, responseClusterType)
+		}
+	}
+	aggregateSpan.setAttribute("zdm.response_cluster", string(responseClusterType))
+	aggregateSpan.end(err)
 
 	if err != nil {
+		reqCtx.explanation.setErrorOutcome(err)
+		reqCtx.explanation.log()
+		reqCtx.trace.end(nil, err)
 		if reqCtx.customResponseChannel != nil {
 			close(reqCtx.customResponseChannel)
 		}
-		log.Errorf("Error handling request (%v): %v", reqCtx.request.Header, err)
+		ch.getRequestLogger(reqCtx.request.Header.StreamId).Errorf("Error handling request (%v): %v", reqCtx.request.Header, err)
 		return
 	}
 
+	reqCtx.explanation.setResponseOutcome(finalResponse, responseClusterType)
+	reqCtx.explanation.log()
+	ch.observeSlowQuery(reqCtx, finalResponse)
+
 	reqCtx.request = nil
 	originResponse := reqCtx.originResponse
 	reqCtx.originResponse = nil
 	targetResponse := reqCtx.targetResponse
 	reqCtx.targetResponse = nil
+	// the responses are compared after the client response is sent
+	defer reqCtx.writeComparison.compare(originResponse, targetResponse)
 
+	writeSpan := reqCtx.trace.startSpan("write response")
 	if reqCtx.customResponseChannel != nil {
 		reqCtx.customResponseChannel <- &customResponse{
 			originResponse:     originResponse,
@@ -728,20 +1129,31 @@ func (ch *ClientHandler) finishRequest(holder *requestContextHolder, reqCtx *req
 	} else {
 		ch.clientConnector.sendResponseToClient(finalResponse)
 	}
+	writeSpan.end(nil)
+	reqCtx.trace.end(finalResponse, nil)
 }
 
 // should only be called after Cancel returns true
 func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *requestContextImpl) {
 	defer ch.clientHandlerRequestWaitGroup.Done()
+	if ch.inFlightBudget != nil {
+		defer ch.inFlightBudget.release()
+	}
 
 	err := holder.Clear(reqCtx)
 	if err != nil {
-		log.Debugf("Could not free stream id: %v", err)
+		ch.getLogger().Debugf("Could not free stream id: %v", err)
 	}
 
+	reqCtx.explanation.setCanceledOutcome()
+	reqCtx.explanation.log()
+
+	reqCtx.readComparison.abandon()
+	reqCtx.trace.end(nil, fmt.Errorf("request canceled"))
+
 	if reqCtx.requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
-		switch reqCtx.requestInfo.GetForwardDecision() {
+		switch getMetricsForwardDecision(reqCtx.requestInfo) {
 		case forwardToBoth:
 			proxyMetrics.InFlightWrites.Subtract(1)
 		case forwardToOrigin:
@@ -750,7 +1162,7 @@ func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *req
 			proxyMetrics.InFlightReadsTarget.Subtract(1)
 		case forwardToAsyncOnly, forwardToNone:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
+			ch.getLogger().Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", reqCtx.requestInfo.GetForwardDecision())
 		}
 	}
 
@@ -758,7 +1170,7 @@ func (ch *ClientHandler) cancelRequest(holder *requestContextHolder, reqCtx *req
 		close(reqCtx.customResponseChannel)
 	}
 
-	log.Tracef("Canceled request %v.", reqCtx.request.Header)
+	ch.getLogger().Tracef("Canceled request %v.", reqCtx.request.Header)
 }
 
 // Computes the response to be sent to the client based on the forward decision of the request.
@@ -771,11 +1183,15 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 				"did not receive response from origin cassandra channel, stream: %d",
 				requestContext.request.Header.StreamId)
 		}
-		log.Tracef("Forward to origin: just returning the response received from %v: %d",
+		ch.getLogger().Tracef("Forward to origin: just returning the response received from %v: %d",
 			common.ClusterTypeOrigin, requestContext.originResponse.Header.OpCode)
 
 		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !isResponseSuccessful(requestContext.originResponse) {
-			ch.metricHandler.GetProxyMetrics().FailedReadsOrigin.Add(1)
+			if isPrimaryOnlyWrite(requestContext.requestInfo) {
+				ch.metricHandler.GetProxyMetrics().FailedWritesOnOrigin.Add(1)
+			} else {
+				ch.metricHandler.GetProxyMetrics().FailedReadsOrigin.Add(1)
+			}
 		}
 		return requestContext.originResponse, common.ClusterTypeOrigin, nil
 	case forwardToTarget:
@@ -784,11 +1200,15 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 				"did not receive response from target cassandra channel, stream: %d",
 				requestContext.request.Header.StreamId)
 		}
-		log.Tracef("Forward to target: just returning the response received from %v: %d",
+		ch.getLogger().Tracef("Forward to target: just returning the response received from %v: %d",
 			common.ClusterTypeTarget, requestContext.targetResponse.Header.OpCode)
 
 		if requestContext.requestInfo.ShouldBeTrackedInMetrics() && !isResponseSuccessful(requestContext.targetResponse) {
-			ch.metricHandler.GetProxyMetrics().FailedReadsTarget.Add(1)
+			if isPrimaryOnlyWrite(requestContext.requestInfo) {
+				ch.metricHandler.GetProxyMetrics().FailedWritesOnTarget.Add(1)
+			} else {
+				ch.metricHandler.GetProxyMetrics().FailedReadsTarget.Add(1)
+			}
 		}
 		return requestContext.targetResponse, common.ClusterTypeTarget, nil
 	case forwardToBoth:
@@ -804,6 +1224,33 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 		}
 		aggregatedResponse, responseClusterType := ch.aggregateAndTrackResponses(
 			requestContext.requestInfo, requestContext.request, requestContext.originResponse, requestContext.targetResponse)
+		if requestContext.ignoreTargetFailure && responseClusterType == common.ClusterTypeTarget &&
+			isResponseSuccessful(requestContext.originResponse) && !isUnpreparedResponse(requestContext.targetResponse) {
+			ch.getRequestLogger(requestContext.request.Header.StreamId).Debugf("Ignoring %v failure of sampled write, sending back %v response with opcode %d",
+				common.ClusterTypeTarget, common.ClusterTypeOrigin, requestContext.originResponse.Header.OpCode)
+			return requestContext.originResponse, common.ClusterTypeOrigin, nil
+		}
+		if requestContext.primaryResponseOnly && responseClusterType != ch.primaryCluster {
+			primaryResponse, secondaryResponse := requestContext.originResponse, requestContext.targetResponse
+			if ch.primaryCluster == common.ClusterTypeTarget {
+				primaryResponse, secondaryResponse = secondaryResponse, primaryResponse
+			}
+			if !isUnpreparedResponse(secondaryResponse) {
+				ch.getRequestLogger(requestContext.request.Header.StreamId).Debugf("Ignoring %v failure of lightweight transaction, sending back %v response with opcode %d",
+					responseClusterType, ch.primaryCluster, primaryResponse.Header.OpCode)
+				return primaryResponse, ch.primaryCluster, nil
+			}
+		}
+		if requestContext.secondaryWrite != nil && responseClusterType != ch.primaryCluster {
+			primaryResponse, secondaryResponse := requestContext.originResponse, requestContext.targetResponse
+			if ch.primaryCluster == common.ClusterTypeTarget {
+				primaryResponse, secondaryResponse = secondaryResponse, primaryResponse
+			}
+			if isResponseSuccessful(primaryResponse) && !isUnpreparedResponse(secondaryResponse) {
+				ch.handleSecondaryWriteFailure(requestContext, responseClusterType, secondaryResponse)
+				return primaryResponse, ch.primaryCluster, nil
+			}
+		}
 		return aggregatedResponse, responseClusterType, nil
 	case forwardToAsyncOnly:
 		switch ch.asyncConnector.clusterType {
@@ -813,7 +1260,7 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 					"did not receive response from async target cassandra channel, stream: %d",
 					requestContext.request.Header.StreamId)
 			}
-			log.Tracef("Forward to async: just returning the response received from %v: %d",
+			ch.getLogger().Tracef("Forward to async: just returning the response received from %v: %d",
 				common.ClusterTypeTarget, requestContext.targetResponse.Header.OpCode)
 			return requestContext.targetResponse, common.ClusterTypeTarget, nil
 		case common.ClusterTypeOrigin:
@@ -822,11 +1269,11 @@ func (ch *ClientHandler) computeClientResponse(requestContext *requestContextImp
 					"did not receive response from async origin cassandra channel, stream: %d",
 					requestContext.request.Header.StreamId)
 			}
-			log.Tracef("Forward to async: just returning the response received from %v: %d",
+			ch.getLogger().Tracef("Forward to async: just returning the response received from %v: %d",
 				common.ClusterTypeOrigin, requestContext.originResponse.Header.OpCode)
 			return requestContext.originResponse, common.ClusterTypeOrigin, nil
 		default:
-			log.Errorf("Unknown cluster type: %v. This is a bug, please report.", ch.asyncConnector.clusterType)
+			ch.getLogger().Errorf("Unknown cluster type: %v. This is a bug, please report.", ch.asyncConnector.clusterType)
 			return nil, common.ClusterTypeNone, fmt.Errorf("unknown cluster type: %v; this is a bug, please report", ch.asyncConnector.clusterType)
 		}
 	case forwardToNone:
@@ -859,7 +1306,7 @@ func (ch *ClientHandler) processClientResponse(
 			}
 		case *message.SetKeyspaceResult:
 			if bodyMsg.Keyspace == "" {
-				log.Warnf("unexpected set keyspace empty")
+				ch.getLogger().Warnf("unexpected set keyspace empty")
 			} else {
 				ch.StoreCurrentKeyspace(bodyMsg.Keyspace)
 			}
@@ -887,7 +1334,7 @@ func (ch *ClientHandler) processClientResponse(
 			}
 			newFrame.Body.Message = newUnprepared
 
-			log.Infof("Received UNPREPARED from %v, generating UNPREPARED response with prepared ID %s. "+
+			ch.getRequestLogger(reqCtx.request.Header.StreamId).Infof("Received UNPREPARED from %v, generating UNPREPARED response with prepared ID %s. "+
 				"Prepared ID in response from %v: %v. Original error: %v",
 				responseClusterType, hex.EncodeToString(unpreparedId),
 				responseClusterType, hex.EncodeToString(bodyMsg.Id), bodyMsg.ErrorMessage)
@@ -989,7 +1436,8 @@ func (ch *ClientHandler) processPreparedResponse(
 						continue
 					}
 
-					if sort.SearchStrings(namedMarkersToRemove, col.Name) != len(namedMarkersToRemove) {
+					if markerIdx := sort.SearchStrings(namedMarkersToRemove, col.Name); markerIdx < len(namedMarkersToRemove) &&
+						namedMarkersToRemove[markerIdx] == col.Name {
 						indicesToRemove = append(indicesToRemove, idx)
 						newCols = append(newCols, newPreparedBody.VariablesMetadata.Columns[start:idx]...)
 						start = idx + 1
@@ -1033,6 +1481,18 @@ type handshakeRequestResult struct {
 // When the Origin handshake ends, this function blocks, waiting until Target handshake is done.
 // This ensures that the client connection is Ready only when both Cluster Connector connections are ready.
 func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *sync.WaitGroup) (bool, error) {
+	if ch.handshakeCache != nil && request.Header.OpCode == primitive.OpCodeOptions {
+		if cachedResponse := ch.handshakeCache.getSupportedResponse(request); cachedResponse != nil {
+			ch.getLogger().Tracef("Returning cached SUPPORTED response to OPTIONS request sent before STARTUP.")
+			ch.clientConnector.sendResponseToClient(cachedResponse)
+			return false, nil
+		}
+	}
+
+	if ch.proxyAuthenticator != nil && request.Header.OpCode == primitive.OpCodeAuthResponse {
+		return ch.handleProxyAuthResponse(request, wg)
+	}
+
 	scheduledTaskChannel := make(chan *handshakeRequestResult, 1)
 	wg.Add(1)
 	ch.requestResponseScheduler.Schedule(func() {
@@ -1063,6 +1523,10 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			if newAuthFrame != nil {
 				request = newAuthFrame
 			}
+
+			if ch.handshakeCache != nil {
+				ch.startEarlySecondaryHandshake(request)
+			}
 		}
 
 		responseChan := make(chan *customResponse, 1)
@@ -1104,6 +1568,10 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 
 	aggregatedResponse := response.aggregatedResponse
 
+	if ch.handshakeCache != nil && request.Header.OpCode == primitive.OpCodeOptions {
+		ch.handshakeCache.storeSupportedResponse(aggregatedResponse)
+	}
+
 	if request.Header.OpCode == primitive.OpCodeStartup {
 		var secondaryResponse *frame.RawFrame
 		var secondaryCluster common.ClusterType
@@ -1129,11 +1597,31 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 
 		ch.secondaryStartupResponse = secondaryResponse
 		ch.startupRequest = request
+		ch.clientAuthNegotiation = newClientAuthNegotiation(aggregatedResponse)
+		ch.clientIdentity = decodeClientIdentity(request)
+		ch.logger.Store(newConnectionLogEntry(ch.connectionId).WithFields(ch.clientIdentity.fields()))
+		if ch.applicationReadRouting != nil {
+			ch.sessionReadRoutingRules = ch.applicationReadRouting.getSessionRules(ch.clientIdentity.applicationName)
+			if ch.sessionReadRoutingRules != nil {
+				ch.getLogger().Infof("Reads of application %v are forwarded according to the rules %v.",
+					ch.clientIdentity.applicationName, ch.sessionReadRoutingRules)
+			}
+		}
 
 		err := validateSecondaryStartupResponse(secondaryResponse, secondaryCluster)
 		if err != nil {
 			return false, fmt.Errorf("unsuccessful startup on %v: %w", secondaryCluster, err)
 		}
+
+		if ch.proxyAuthenticator != nil && validateSecondaryStartupResponse(aggregatedResponse, secondaryCluster) == nil {
+			// the proxy authenticates the client and the primary handshake is performed once the client is authenticated
+			ch.primaryStartupResponse = aggregatedResponse
+			aggregatedResponse, err = ch.buildHandshakeResponse(
+				request, &message.Authenticate{Authenticator: passwordAuthenticator})
+			if err != nil {
+				return false, fmt.Errorf("could not create AUTHENTICATE response: %w", err)
+			}
+		}
 	}
 
 	scheduledTaskChannel = make(chan *handshakeRequestResult, 1)
@@ -1151,28 +1639,27 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			// to guarantee that no other request with the same
 			// stream id goes to target in the meantime
 
-			// if we add stream id mapping logic in the future, then
-			// we can start the secondary handshake earlier and wait for it to end here
+			// with the handshake fast path the secondary handshake was started earlier (with a different stream id)
+			// so we just wait for it to end here
 
 			secondaryClusterType := common.ClusterTypeTarget
 			if ch.forwardAuthToTarget {
 				secondaryClusterType = common.ClusterTypeOrigin
 			}
-			secondaryHandshakeChannel, err := ch.startSecondaryHandshake(false)
-			if err != nil {
-				tempResult.err = err
-				scheduledTaskChannel <- tempResult
-				return
-			}
+			var secondaryHandshakeChannel chan error
 			var asyncConnectorHandshakeChannel chan error
-			if ch.asyncConnector != nil {
-				asyncConnectorHandshakeChannel, err = ch.startSecondaryHandshake(true)
+			var err error
+			if ch.earlySecondaryHandshakeChannel != nil {
+				secondaryHandshakeChannel = ch.earlySecondaryHandshakeChannel
+				asyncConnectorHandshakeChannel = ch.earlyAsyncHandshakeChannel
+			} else {
+				secondaryHandshakeChannel, err = ch.startSecondaryHandshake(false, ch.startupRequest.Header.StreamId)
 				if err != nil {
-					log.Errorf("Error occured in async connector (%v) handshake: %v. "+
-						"Async requests will not be forwarded.", ch.asyncConnector.clusterType, err.Error())
-					ch.asyncConnector.Shutdown()
-					asyncConnectorHandshakeChannel = nil
+					tempResult.err = err
+					scheduledTaskChannel <- tempResult
+					return
 				}
+				asyncConnectorHandshakeChannel = ch.startAsyncConnectorHandshake(ch.startupRequest.Header.StreamId)
 			}
 			var errAsync error
 			for asyncConnectorHandshakeChannel != nil || secondaryHandshakeChannel != nil {
@@ -1185,7 +1672,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			}
 
 			if errAsync != nil {
-				log.Errorf("Async connector (%v) handshake failed, async requests will not be forwarded: %s",
+				ch.getLogger().Errorf("Async connector (%v) handshake failed, async requests will not be forwarded: %s",
 					ch.asyncConnector.clusterType, errAsync.Error())
 				ch.asyncConnector.Shutdown()
 			}
@@ -1199,7 +1686,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 					return
 				}
 
-				log.Errorf("secondary (%v) handshake failed, shutting down the client handler and connectors: %s", secondaryClusterType, err.Error())
+				ch.getLogger().Errorf("secondary (%v) handshake failed, shutting down the client handler and connectors: %s", secondaryClusterType, err.Error())
 				ch.clientHandlerCancelFunc()
 				tempResult.err = fmt.Errorf("handshake failed: %w", ShutdownErr)
 				scheduledTaskChannel <- tempResult
@@ -1212,6 +1699,10 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 			return
 		}
 
+		if aggregatedResponse.Header.OpCode == primitive.OpCodeError {
+			ch.resetEarlySecondaryHandshake(aggregatedResponse)
+		}
+
 		// send overall response back to client
 		ch.clientConnector.sendResponseToClient(aggregatedResponse)
 		scheduledTaskChannel <- tempResult
@@ -1228,7 +1719,7 @@ func (ch *ClientHandler) handleHandshakeRequest(request *frame.RawFrame, wg *syn
 func (ch *ClientHandler) sendAuthErrorToClient(requestFrame *frame.RawFrame, secondaryClusterType common.ClusterType) error {
 	authErrorResponse, err := ch.buildAuthErrorResponse(requestFrame, ch.authErrorMessage)
 	if err == nil {
-		log.Warnf("Secondary (%v) handshake failed with an auth error, returning %v to client.", secondaryClusterType, ch.authErrorMessage)
+		ch.getLogger().Warnf("Secondary (%v) handshake failed with an auth error, returning %v to client.", secondaryClusterType, ch.authErrorMessage)
 		ch.clientConnector.sendResponseToClient(authErrorResponse)
 		return nil
 	} else {
@@ -1239,7 +1730,13 @@ func (ch *ClientHandler) sendAuthErrorToClient(requestFrame *frame.RawFrame, sec
 // Build authentication error response to return to client
 func (ch *ClientHandler) buildAuthErrorResponse(
 	requestFrame *frame.RawFrame, authenticationError *message.AuthenticationError) (*frame.RawFrame, error) {
-	f := frame.NewFrame(requestFrame.Header.Version, requestFrame.Header.StreamId, authenticationError)
+	return ch.buildHandshakeResponse(requestFrame, authenticationError)
+}
+
+// Build a response to a handshake request of the client
+func (ch *ClientHandler) buildHandshakeResponse(
+	requestFrame *frame.RawFrame, msg message.Message) (*frame.RawFrame, error) {
+	f := frame.NewFrame(requestFrame.Header.Version, requestFrame.Header.StreamId, msg)
 	if requestFrame.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
 		f.SetCompress(true)
 	}
@@ -1247,6 +1744,134 @@ func (ch *ClientHandler) buildAuthErrorResponse(
 	return defaultCodec.ConvertToRawFrame(f)
 }
 
+// Validates the credentials of the client's AUTH_RESPONSE with the proxy credentials and, if they are valid,
+// performs the handshakes of the cluster connections with the configured credentials (see proxyAuthenticator).
+func (ch *ClientHandler) handleProxyAuthResponse(request *frame.RawFrame, wg *sync.WaitGroup) (bool, error) {
+	scheduledTaskChannel := make(chan *handshakeRequestResult, 1)
+	wg.Add(1)
+	ch.requestResponseScheduler.Schedule(func() {
+		defer wg.Done()
+		defer close(scheduledTaskChannel)
+		authSuccess, err := ch.authenticateWithProxyCredentials(request)
+		scheduledTaskChannel <- &handshakeRequestResult{
+			authSuccess: authSuccess,
+			err:         err,
+		}
+	})
+
+	result, ok := <-scheduledTaskChannel
+	if !ok {
+		return false, errors.New("unexpected scheduledTaskChannel closure in handle proxy auth response")
+	}
+	return result.authSuccess, result.err
+}
+
+func (ch *ClientHandler) authenticateWithProxyCredentials(request *frame.RawFrame) (bool, error) {
+	primaryCluster, secondaryCluster := common.ClusterTypeOrigin, common.ClusterTypeTarget
+	if ch.forwardAuthToTarget {
+		primaryCluster, secondaryCluster = common.ClusterTypeTarget, common.ClusterTypeOrigin
+	}
+	if ch.authErrorMessage != nil {
+		return false, ch.sendAuthErrorToClient(request, secondaryCluster)
+	}
+	if ch.startupRequest == nil || ch.primaryStartupResponse == nil {
+		return false, errors.New("can not authenticate the client before a Startup response was received")
+	}
+
+	var clientCreds *AuthCredentials
+	parsedAuthFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return false, fmt.Errorf("could not extract auth credentials from frame to authenticate the client: %w", err)
+	}
+	if authResponse, ok := parsedAuthFrame.Body.Message.(*message.AuthResponse); ok {
+		clientCreds, err = ParseCredentialsFromRequest(authResponse.Token)
+		if err != nil {
+			ch.getLogger().Debugf("Could not parse the credentials of the client: %v", err)
+		}
+	}
+	if !ch.proxyAuthenticator.authenticate(clientCreds) {
+		ch.getLogger().Warnf("Client credentials %v don't match the proxy credentials, returning an auth error.",
+			clientCreds)
+		authErrorResponse, err := ch.buildAuthErrorResponse(request, &message.AuthenticationError{
+			ErrorMessage: "Provided username and/or password are incorrect"})
+		if err != nil {
+			return false, fmt.Errorf("could not create auth error response: %w", err)
+		}
+		ch.clientConnector.sendResponseToClient(authErrorResponse)
+		return false, nil
+	}
+
+	ch.getLogger().Debugf("Client authenticated with the proxy credentials %v.", clientCreds)
+	ch.clientRole = clientCreds.Username
+	ch.secondaryHandshakeCreds = ch.handshakeCredentials(clientCreds, secondaryCluster)
+	if ch.asyncConnector != nil {
+		ch.asyncHandshakeCreds = ch.handshakeCredentials(clientCreds, ch.asyncConnector.clusterType)
+	}
+
+	// the client doesn't send other requests until the handshake is done but the handshakes of the primary and the
+	// secondary cluster can't use the same stream id
+	streamId := ch.startupRequest.Header.StreamId
+	secondaryStreamId := (streamId + 1) % maxStreamIdsV2
+	primaryCreds := ch.handshakeCredentials(clientCreds, primaryCluster)
+	primaryHandshakeChannel := make(chan error, 1)
+	ch.clientHandlerRequestWaitGroup.Add(1)
+	go func() {
+		defer ch.clientHandlerRequestWaitGroup.Done()
+		defer close(primaryHandshakeChannel)
+		primaryHandshakeChannel <- ch.handleClusterHandshakeStartup(
+			ch.startupRequest, ch.primaryStartupResponse, streamId, primaryCluster, false, primaryCreds)
+	}()
+	secondaryHandshakeChannel, err := ch.startSecondaryHandshake(false, secondaryStreamId)
+	if err != nil {
+		<-primaryHandshakeChannel
+		return false, err
+	}
+	asyncConnectorHandshakeChannel := ch.startAsyncConnectorHandshake(secondaryStreamId)
+
+	var errPrimary, errSecondary, errAsync error
+	for primaryHandshakeChannel != nil || secondaryHandshakeChannel != nil || asyncConnectorHandshakeChannel != nil {
+		select {
+		case errPrimary, _ = <-primaryHandshakeChannel:
+			primaryHandshakeChannel = nil
+		case errSecondary, _ = <-secondaryHandshakeChannel:
+			secondaryHandshakeChannel = nil
+		case errAsync, _ = <-asyncConnectorHandshakeChannel:
+			asyncConnectorHandshakeChannel = nil
+		}
+	}
+
+	if errAsync != nil {
+		ch.getLogger().Errorf("Async connector (%v) handshake failed, async requests will not be forwarded: %s",
+			ch.asyncConnector.clusterType, errAsync.Error())
+		ch.asyncConnector.Shutdown()
+	}
+
+	for _, handshake := range []struct {
+		clusterType common.ClusterType
+		err         error
+	}{{primaryCluster, errPrimary}, {secondaryCluster, errSecondary}} {
+		if handshake.err == nil {
+			continue
+		}
+		var authError *AuthError
+		if errors.As(handshake.err, &authError) {
+			ch.authErrorMessage = authError.errMsg
+			return false, ch.sendAuthErrorToClient(request, handshake.clusterType)
+		}
+		ch.getLogger().Errorf("%v handshake failed, shutting down the client handler and connectors: %s",
+			handshake.clusterType, handshake.err.Error())
+		ch.clientHandlerCancelFunc()
+		return false, fmt.Errorf("handshake failed: %w", ShutdownErr)
+	}
+
+	authSuccessResponse, err := ch.buildHandshakeResponse(request, &message.AuthSuccess{})
+	if err != nil {
+		return false, fmt.Errorf("could not create AUTH_SUCCESS response: %w", err)
+	}
+	ch.clientConnector.sendResponseToClient(authSuccessResponse)
+	return true, nil
+}
+
 // Starts the secondary handshake in the background (goroutine).
 //
 // Returns error if the handshake could not be started.
@@ -1256,7 +1881,7 @@ func (ch *ClientHandler) buildAuthErrorResponse(
 // If the returned channel is closed before a value could be read, then the handshake has failed as well.
 //
 // The handshake was successful if the returned channel contains a "nil" value.
-func (ch *ClientHandler) startSecondaryHandshake(asyncConnector bool) (chan error, error) {
+func (ch *ClientHandler) startSecondaryHandshake(asyncConnector bool, streamId int16) (chan error, error) {
 	startupFrame := ch.startupRequest
 	if startupFrame == nil {
 		return nil, errors.New("can not start secondary handshake before a Startup request was received")
@@ -1266,70 +1891,226 @@ func (ch *ClientHandler) startSecondaryHandshake(asyncConnector bool) (chan erro
 		return nil, errors.New("can not start secondary handshake before a Startup response was received")
 	}
 
-	channel := make(chan error)
+	channel := make(chan error, 1)
 	ch.clientHandlerRequestWaitGroup.Add(1)
 	go func() {
 		defer ch.clientHandlerRequestWaitGroup.Done()
 		defer close(channel)
 		var err error
-		err = ch.handleSecondaryHandshakeStartup(startupFrame, startupResponse, asyncConnector)
+		err = ch.handleSecondaryHandshakeStartup(startupFrame, startupResponse, streamId, asyncConnector)
 		channel <- err
 	}()
 	return channel, nil
 }
 
-// Handles a request, see the docs for the forwardRequest() function, as handleRequest is pretty much a wrapper
-// around forwardRequest.
-func (ch *ClientHandler) handleRequest(f *frame.RawFrame) {
-	err := ch.forwardRequest(f, nil)
-
+// Starts the async connector handshake in the background if there is an async connector.
+//
+// Returns nil if there is no async connector or if the handshake could not be started, in which case the
+// async connector is shut down.
+func (ch *ClientHandler) startAsyncConnectorHandshake(streamId int16) chan error {
+	if ch.asyncConnector == nil {
+		return nil
+	}
+	channel, err := ch.startSecondaryHandshake(true, streamId)
 	if err != nil {
-		log.Warnf("error sending request with opcode %02x and streamid %d: %s", f.Header.OpCode, f.Header.StreamId, err.Error())
-		return
+		ch.getLogger().Errorf("Error occured in async connector (%v) handshake: %v. "+
+			"Async requests will not be forwarded.", ch.asyncConnector.clusterType, err.Error())
+		ch.asyncConnector.Shutdown()
+		return nil
 	}
+	return channel
 }
 
-// Forwards the request, parsing it and enqueuing it to the appropriate cluster connector(s)' write queue(s).
-func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseChannel chan *customResponse) error {
-	overallRequestStartTime := time.Now()
-
-	log.Tracef("Request frame: %v", request)
+// Starts the secondary handshakes while the primary cluster is processing the provided AUTH_RESPONSE
+// instead of waiting for the primary handshake to finish (handshake fast path).
+//
+// The AUTH_RESPONSE is in flight so the secondary handshakes can't use its stream id. The client doesn't send
+// other requests until the handshake is done so any other stream id is safe to use.
+//
+// Nothing is started if the secondary credentials are not known yet, the next AUTH_RESPONSE will try again.
+func (ch *ClientHandler) startEarlySecondaryHandshake(authResponse *frame.RawFrame) {
+	if ch.earlySecondaryHandshakeChannel != nil || ch.secondaryHandshakeCreds == nil ||
+		ch.startupRequest == nil || ch.secondaryStartupResponse == nil {
+		return
+	}
 
-	currentKeyspace := ch.LoadCurrentKeyspace()
-	context := NewFrameDecodeContext(request)
-	var replacedTerms []*statementReplacedTerms
-	var err error
-	if ch.conf.ReplaceCqlFunctions {
-		context, replacedTerms, err = ch.queryModifier.replaceQueryString(currentKeyspace, context)
+	streamId := ch.startupRequest.Header.StreamId
+	if streamId == authResponse.Header.StreamId {
+		streamId = (streamId + 1) % maxStreamIdsV2
 	}
 
+	channel, err := ch.startSecondaryHandshake(false, streamId)
 	if err != nil {
-		return err
+		ch.getLogger().Debugf("Could not start secondary handshake before the primary handshake is done: %v", err)
+		return
 	}
-	requestInfo, err := buildRequestInfo(
-		context, replacedTerms, ch.preparedStatementCache, ch.metricHandler, currentKeyspace, ch.primaryCluster,
-		ch.forwardSystemQueriesToTarget, ch.topologyConfig.VirtualizationEnabled, ch.forwardAuthToTarget, ch.timeUuidGenerator)
-	if err != nil {
-		if errVal, ok := err.(*UnpreparedExecuteError); ok {
-			unpreparedFrame, err := createUnpreparedFrame(errVal)
-			if err != nil {
-				return err
+	ch.getLogger().Debugf("Started secondary handshake before the primary handshake is done using stream id %d.", streamId)
+	ch.earlySecondaryHandshakeChannel = channel
+	ch.earlyAsyncHandshakeChannel = ch.startAsyncConnectorHandshake(streamId)
+}
+
+// Called when the primary cluster returns an error to an AUTH_RESPONSE, waits for the secondary handshakes that were
+// started by startEarlySecondaryHandshake to finish (if any) and discards them.
+//
+// The secondary connections can't be authenticated again once their handshake ran so the next AUTH_RESPONSE requests
+// of the client get the error of the primary cluster, the client has to open a new connection.
+func (ch *ClientHandler) resetEarlySecondaryHandshake(primaryResponse *frame.RawFrame) {
+	secondaryHandshakeChannel := ch.earlySecondaryHandshakeChannel
+	asyncConnectorHandshakeChannel := ch.earlyAsyncHandshakeChannel
+	ch.earlySecondaryHandshakeChannel = nil
+	ch.earlyAsyncHandshakeChannel = nil
+	if secondaryHandshakeChannel == nil {
+		return
+	}
+
+	for asyncConnectorHandshakeChannel != nil || secondaryHandshakeChannel != nil {
+		select {
+		case <-asyncConnectorHandshakeChannel:
+			asyncConnectorHandshakeChannel = nil
+		case err := <-secondaryHandshakeChannel:
+			secondaryHandshakeChannel = nil
+			if err != nil {
+				ch.getLogger().Debugf("Secondary handshake that was started before the primary handshake failed: %v", err)
 			}
-			log.Debugf(
+		}
+	}
+
+	errMsg, err := decodeError(primaryResponse)
+	if authErrMsg, ok := errMsg.(*message.AuthenticationError); err == nil && ok {
+		ch.authErrorMessage = authErrMsg
+	} else {
+		ch.authErrorMessage = &message.AuthenticationError{ErrorMessage: "primary handshake failed, please reconnect"}
+	}
+}
+
+// Handles a request, see the docs for the forwardRequest() function, as handleRequest is pretty much a wrapper
+// around forwardRequest.
+func (ch *ClientHandler) handleRequest(f *frame.RawFrame) {
+	err := ch.forwardRequest(f, nil)
+
+	if err != nil {
+		ch.getRequestLogger(f.Header.StreamId).Warnf("error sending request with opcode %02x and streamid %d: %s", f.Header.OpCode, f.Header.StreamId, err.Error())
+		if ch.requestLimiter != nil {
+			// no response is sent to the client
+			ch.requestLimiter.release(f.Header.StreamId)
+		}
+		return
+	}
+}
+
+// rejectLimitedRequest sends an OVERLOADED response for a request that the request limiter of the client
+// connection rejected.
+func (ch *ClientHandler) rejectLimitedRequest(request *frame.RawFrame, reason string) {
+	ch.getRequestLogger(request.Header.StreamId).Debugf("Returning OVERLOADED for %v request: %v.", request.Header.OpCode, reason)
+	overloaded := frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Overloaded{
+		ErrorMessage: fmt.Sprintf("Proxy reached the limit of requests of this client connection (%v), please retry.", reason),
+	})
+	response, err := defaultCodec.ConvertToRawFrame(overloaded)
+	if err != nil {
+		ch.getLogger().Errorf("Could not encode OVERLOADED response: %v.", err)
+		return
+	}
+	ch.clientConnector.sendResponseToClient(response)
+}
+
+// Forwards the request, parsing it and enqueuing it to the appropriate cluster connector(s)' write queue(s).
+func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseChannel chan *customResponse) error {
+	overallRequestStartTime := time.Now()
+
+	ch.getLogger().Tracef("Request frame: %v", request)
+
+	currentKeyspace := ch.LoadCurrentKeyspace()
+	context := NewFrameDecodeContext(request)
+	explanation := ch.newRequestExplanation(request, currentKeyspace)
+	trace := ch.newRequestTrace(request, overallRequestStartTime)
+	if request.Header.OpCode == primitive.OpCodeRegister {
+		ch.trackRegisteredEvents(request)
+	}
+	interception, err := ch.interceptRequest(context, currentKeyspace)
+	if err != nil {
+		explanation.setErrorOutcome(err)
+		explanation.log()
+		trace.end(nil, err)
+		return err
+	}
+	if interception != nil {
+		if interception.response != nil {
+			explanation.addRequestInterceptorRejection()
+			ch.sendProxyResponse(interception.response, customResponseChannel, explanation, trace)
+			return nil
+		}
+		if interception.modified {
+			explanation.addRequestInterceptorModification()
+			context = interception.frameContext
+		}
+	}
+
+	var replacedTerms []*statementReplacedTerms
+	if ch.conf.ReplaceCqlFunctions {
+		context, replacedTerms, err = ch.queryModifier.replaceQueryString(currentKeyspace, context)
+	}
+
+	if err != nil {
+		explanation.setErrorOutcome(err)
+		explanation.log()
+		trace.end(nil, err)
+		return err
+	}
+	explanation.addRewrites(replacedTerms)
+	requestInfo, err := buildRequestInfo(
+		context, replacedTerms, ch.preparedStatementCache, ch.metricHandler, currentKeyspace, ch.primaryCluster,
+		ch.forwardSystemQueriesToTarget, ch.topologyConfig.VirtualizationEnabled, ch.proxyVirtualTables != nil,
+		ch.adminKeyspace != nil, ch.forwardAuthToTarget, ch.timeUuidGenerator, ch.schemaStatementPolicies,
+		ch.tableRoutingRules, ch.statementFilter)
+	if err != nil {
+		if errVal, ok := err.(*UnpreparedExecuteError); ok {
+			unpreparedFrame, err := createUnpreparedFrame(errVal)
+			if err != nil {
+				return err
+			}
+			ch.getLogger().Debugf(
 				"PS Cache miss, created unprepared response with version %v, streamId %v and preparedId %s",
 				errVal.Header.Version, errVal.Header.StreamId, errVal.preparedId)
 
+			if explanation != nil {
+				explanation.preparedCache = fmt.Sprintf("miss (prepared id %s)", hex.EncodeToString(errVal.preparedId))
+				explanation.destinations = string(forwardToNone)
+				explanation.setResponseOutcome(unpreparedFrame, common.ClusterTypeNone)
+				explanation.log()
+			}
+
 			// send it back to client
 			ch.clientConnector.sendResponseToClient(unpreparedFrame)
-			log.Debugf("Unprepared Response sent, exiting handleRequest now")
+			trace.end(unpreparedFrame, nil)
+			ch.getRequestLogger(request.Header.StreamId).Debugf("Unprepared Response sent, exiting handleRequest now")
 			return nil
 		}
+		explanation.setErrorOutcome(err)
+		explanation.log()
+		trace.end(nil, err)
 		return err
 	}
+	routedRequestInfo, routed := interception.applyRoute(requestInfo)
+	if routed {
+		ch.getLogger().Tracef("Request forwarded to %v by a request interceptor.", routedRequestInfo.GetForwardDecision())
+		requestInfo = routedRequestInfo
+	}
+	explanation.describe(context, requestInfo, ch.shouldAlsoBeSentAsync(requestInfo))
+	if routed {
+		explanation.addRequestInterceptorRoute(interception.route)
+	}
 
-	requestTimeout := time.Duration(ch.conf.ProxyRequestTimeoutMs) * time.Millisecond
-	err = ch.executeRequest(context, requestInfo, currentKeyspace, overallRequestStartTime, customResponseChannel, requestTimeout)
+	timeout, err := ch.requestTimeoutPolicy.getRequestTimeout(
+		context, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+	if err == nil {
+		err = ch.executeRequest(
+			context, requestInfo, currentKeyspace, overallRequestStartTime, customResponseChannel, timeout, explanation,
+			trace)
+	}
 	if err != nil {
+		explanation.setErrorOutcome(err)
+		explanation.log()
+		trace.end(nil, err)
 		return err
 	}
 	return nil
@@ -1339,9 +2120,10 @@ func (ch *ClientHandler) forwardRequest(request *frame.RawFrame, customResponseC
 // that should be sent back to the client.
 func (ch *ClientHandler) executeRequest(
 	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
-	overallRequestStartTime time.Time, customResponseChannel chan *customResponse, requestTimeout time.Duration) error {
+	overallRequestStartTime time.Time, customResponseChannel chan *customResponse, timeout requestTimeout,
+	explanation *requestExplanation, trace *requestTrace) error {
 	fwdDecision := requestInfo.GetForwardDecision()
-	log.Tracef("Opcode: %v, Forward decision: %v", frameContext.GetRawFrame().Header.OpCode, fwdDecision)
+	ch.getLogger().Tracef("Opcode: %v, Forward decision: %v", frameContext.GetRawFrame().Header.OpCode, fwdDecision)
 
 	f := frameContext.GetRawFrame()
 	originRequest := f
@@ -1349,9 +2131,68 @@ func (ch *ClientHandler) executeRequest(
 	var clientResponse *frame.RawFrame
 	var err error
 
+	if ch.destructiveStatementGuard != nil && fwdDecision != forwardToNone {
+		clientResponse, err = ch.checkDestructiveStatementConfirmation(frameContext, requestInfo, currentKeyspace)
+		if err != nil {
+			return err
+		}
+		if clientResponse != nil {
+			explanation.addDestructiveStatementRejection()
+			ch.sendProxyResponse(clientResponse, customResponseChannel, explanation, trace)
+			return nil
+		}
+	}
+
+	if ch.targetDdlChecker != nil && (fwdDecision == forwardToBoth || fwdDecision == forwardToTarget) {
+		clientResponse, err = ch.checkTargetDdlCompatibility(frameContext, currentKeyspace)
+		if err != nil {
+			return err
+		}
+		if clientResponse != nil {
+			explanation.addTargetDdlRejection()
+			ch.sendProxyResponse(clientResponse, customResponseChannel, explanation, trace)
+			return nil
+		}
+	}
+
+	if fwdDecision == forwardToOrigin || fwdDecision == forwardToTarget {
+		routedRequestInfo := requestInfo
+		if ch.sessionReadRoutingRules != nil {
+			routedRequestInfo, err = ch.applySessionReadRouting(frameContext, requestInfo, currentKeyspace, explanation)
+			if err != nil {
+				return err
+			}
+		}
+		if routedRequestInfo == requestInfo && ch.adminKeyspace != nil {
+			routedRequestInfo, err = ch.applyAdminReadRouting(frameContext, requestInfo, currentKeyspace, explanation)
+			if err != nil {
+				return err
+			}
+		}
+		if ch.pagingStatePolicy != common.PagingStatePolicyNone {
+			routedRequestInfo, frameContext, clientResponse, err = ch.applyPagingStatePolicy(
+				frameContext, routedRequestInfo, explanation)
+			if err != nil {
+				return err
+			}
+			if clientResponse != nil {
+				ch.sendProxyResponse(clientResponse, customResponseChannel, explanation, trace)
+				return nil
+			}
+			f = frameContext.GetRawFrame()
+			originRequest, targetRequest = f, f
+		}
+		requestInfo = routedRequestInfo
+		fwdDecision = requestInfo.GetForwardDecision()
+	}
+
 	switch castedRequestInfo := requestInfo.(type) {
 	case *InterceptedRequestInfo:
 		clientResponse, err = ch.handleInterceptedRequest(castedRequestInfo, frameContext, currentKeyspace)
+	case *RejectedRequestInfo:
+		clientResponse, err = ch.handleRejectedRequest(castedRequestInfo, frameContext)
+	case *AdminRequestInfo:
+		clientResponse, err = ch.handleAdminRequest(castedRequestInfo, frameContext)
 	case *PrepareRequestInfo:
 		clientResponse, originRequest, targetRequest, err = ch.handlePrepareRequest(castedRequestInfo, frameContext, currentKeyspace)
 	case *ExecuteRequestInfo:
@@ -1364,21 +2205,234 @@ func (ch *ClientHandler) executeRequest(
 		return err
 	}
 
+	if f.Header.Version.IsDse() && fwdDecision != forwardToNone {
+		requestInfo, err = ch.applyContinuousPaging(frameContext, requestInfo)
+		if err != nil {
+			return err
+		}
+		fwdDecision = requestInfo.GetForwardDecision()
+	}
+
+	if fwdDecision == forwardToBoth && ch.targetWriteFilter != nil {
+		requestInfo, targetRequest, err = ch.applyTargetWriteFilter(
+			frameContext, requestInfo, currentKeyspace, originRequest, targetRequest, explanation)
+		if err != nil {
+			return err
+		}
+		fwdDecision = requestInfo.GetForwardDecision()
+	}
+
+	primaryResponseOnly := false
+	if fwdDecision == forwardToBoth {
+		isLwt, err := isLightweightTransactionRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if isLwt {
+			ch.metricHandler.GetProxyMetrics().LwtRequests.Add(1)
+			explanation.addLwtPolicy(ch.lwtPolicy, ch.primaryCluster)
+			if ch.lwtPolicy == common.LwtPolicyPrimaryOnly {
+				ch.getLogger().Tracef("Forwarding lightweight transaction to %v only.", ch.primaryCluster)
+				requestInfo = NewPrimaryOnlyWriteRequestInfo(requestInfo, ch.primaryCluster)
+				fwdDecision = requestInfo.GetForwardDecision()
+			} else {
+				primaryResponseOnly = true
+			}
+		}
+	}
+
+	if fwdDecision == forwardToBoth && ch.counterWritesPolicy != common.CounterWritesPolicyDualWrite {
+		isCounterWrite, err := isCounterWriteRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if isCounterWrite {
+			ch.metricHandler.GetProxyMetrics().CounterWriteRequests.Add(1)
+			explanation.addCounterWritesPolicy(ch.counterWritesPolicy, ch.primaryCluster)
+			if ch.counterWritesPolicy == common.CounterWritesPolicyReject {
+				clientResponse, err = ch.handleRejectedRequest(NewRejectedRequestInfo(fmt.Sprintf(
+					"Counter updates are rejected by ZDM proxy (ZDM_COUNTER_WRITES_POLICY is %v)",
+					ch.counterWritesPolicy)), frameContext)
+				if err != nil {
+					return err
+				}
+				ch.sendProxyResponse(clientResponse, customResponseChannel, explanation, trace)
+				return nil
+			}
+			ch.getLogger().Tracef("Forwarding counter update to %v only.", ch.primaryCluster)
+			requestInfo = NewPrimaryOnlyWriteRequestInfo(requestInfo, ch.primaryCluster)
+			fwdDecision = requestInfo.GetForwardDecision()
+		}
+	}
+
+	sampledWrite := false
+	if fwdDecision == forwardToBoth && ch.targetWriteSampler != nil {
+		isWrite, err := isWriteRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if isWrite {
+			if ch.targetWriteSampler.isSampled() {
+				sampledWrite = true
+			} else {
+				ch.getLogger().Tracef("Write was not sampled, forwarding it to %v only.", common.ClusterTypeOrigin)
+				ch.metricHandler.GetProxyMetrics().TargetUnsampledWrites.Add(1)
+				explanation.addTargetWriteNotSampled()
+				requestInfo = NewFilteredWriteRequestInfo(requestInfo)
+				fwdDecision = requestInfo.GetForwardDecision()
+			}
+		}
+	}
+
+	asyncWrite := false
+	if fwdDecision == forwardToBoth && ch.asyncWrites && ch.asyncConnector != nil && ch.asyncConnector.IsReady() {
+		isWrite, err := isWriteRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if isWrite {
+			ch.getLogger().Tracef("Forwarding write to %v and to %v in the background.",
+				ch.primaryCluster, ch.asyncConnector.clusterType)
+			asyncWrite = true
+			explanation.addAsyncDualWrite(ch.primaryCluster, ch.asyncConnector.clusterType)
+			requestInfo = NewAsyncWriteRequestInfo(requestInfo, ch.primaryCluster)
+			fwdDecision = requestInfo.GetForwardDecision()
+		}
+	}
+
+	if fwdDecision != forwardToNone && fwdDecision != forwardToAsyncOnly && isRateLimitedRequest(f) {
+		openCluster, open := ch.checkCircuitBreakers(fwdDecision)
+		if open {
+			degraded := false
+			if fwdDecision == forwardToBoth && openCluster != ch.primaryCluster && ch.conf.CircuitBreakerDegradeToPrimaryOnly {
+				degraded, err = isWriteRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+				if err != nil {
+					return err
+				}
+			}
+			explanation.addCircuitBreaker(openCluster, degraded, ch.primaryCluster)
+			if !degraded {
+				clientResponse, err = ch.newCircuitBreakerOpenResponse(f, openCluster)
+				if err != nil {
+					return err
+				}
+				ch.sendProxyResponse(clientResponse, customResponseChannel, explanation, trace)
+				return nil
+			}
+			ch.getLogger().Tracef("Forwarding write to %v only because the circuit breaker of %v is open.",
+				ch.primaryCluster, openCluster)
+			requestInfo = NewPrimaryOnlyWriteRequestInfo(requestInfo, ch.primaryCluster)
+			fwdDecision = requestInfo.GetForwardDecision()
+		}
+	}
+
 	if fwdDecision == forwardToNone {
 		if clientResponse == nil {
 			return fmt.Errorf("forwardDecision is NONE but client response is nil")
 		}
 
-		if customResponseChannel != nil {
-			customResponseChannel <- &customResponse{aggregatedResponse: clientResponse}
-		} else {
-			ch.clientConnector.sendResponseToClient(clientResponse)
+		ch.sendProxyResponse(clientResponse, customResponseChannel, explanation, trace)
+		return nil
+	}
+
+	sendAlsoToAsync := ch.shouldAlsoBeSentAsync(requestInfo)
+	if ch.originConsistencyOverride != nil || ch.targetConsistencyOverride != nil {
+		originRequest, targetRequest, err = ch.applyConsistencyOverrides(
+			frameContext, fwdDecision, asyncWrite || sendAlsoToAsync, originRequest, targetRequest, explanation)
+		if err != nil {
+			return err
 		}
+	}
 
-		return nil
+	trace.endInspect(fwdDecision)
+	if fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin {
+		originRequest = trace.startForward(common.ClusterTypeOrigin, originRequest)
+	}
+	if fwdDecision == forwardToBoth || fwdDecision == forwardToTarget {
+		targetRequest = trace.startForward(common.ClusterTypeTarget, targetRequest)
+	}
+
+	var comparison *readComparison
+	if sendAlsoToAsync && ch.readMirroring && requestInfo.ShouldBeTrackedInMetrics() &&
+		(fwdDecision == forwardToOrigin || fwdDecision == forwardToTarget) {
+		query, compared, err := getReadComparisonQuery(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if compared {
+			comparison = newReadComparison(
+				query, ch.primaryCluster, ch.asyncConnector.clusterType, ch.metricHandler.GetProxyMetrics(),
+				ch.migrationProgress)
+		}
+	}
+
+	var writeComparison *writeComparison
+	if fwdDecision == forwardToBoth && ch.writeComparisonSampler != nil {
+		isWrite, err := isWriteRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if isWrite && ch.writeComparisonSampler.isSampled() {
+			writeComparison = newWriteComparison(f, requestInfo, currentKeyspace, ch.metricHandler.GetProxyMetrics())
+		}
+	}
+
+	var tableRequestMetrics *tableRequestMetrics
+	if ch.tableMetrics != nil && requestInfo.ShouldBeTrackedInMetrics() {
+		queryInfo, err := getRequestQueryInfo(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		tableRequestMetrics = ch.tableMetrics.getRequestMetrics(queryInfo, getMetricsForwardDecision(requestInfo))
+	}
+
+	statementDuration, err := getStatementDuration(
+		ch.metricHandler.GetProxyMetrics(), frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+	if err != nil {
+		return err
 	}
 
 	reqCtx := NewRequestContext(f, requestInfo, overallRequestStartTime, customResponseChannel)
+	reqCtx.explanation = explanation
+	reqCtx.trace = trace
+	reqCtx.keyspace = currentKeyspace
+	reqCtx.readComparison = comparison
+	reqCtx.writeComparison = writeComparison
+	reqCtx.tableRequestMetrics = tableRequestMetrics
+	reqCtx.statementDuration = statementDuration
+	reqCtx.ignoreTargetFailure = sampledWrite && ch.targetWriteSampler.ignoreTargetFailures
+	reqCtx.primaryResponseOnly = primaryResponseOnly
+	if _, ok := requestInfo.(*ContinuousPagingRequestInfo); ok {
+		reqCtx.continuousPaging = newContinuousPaging()
+	}
+	if fwdDecision == forwardToBoth && ch.secondaryWriteFailureMode != common.SecondaryWriteFailureModeReturnError &&
+		!primaryResponseOnly && !reqCtx.ignoreTargetFailure {
+		isWrite, err := isWriteRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if isWrite {
+			reqCtx.secondaryWrite = targetRequest
+			if ch.primaryCluster == common.ClusterTypeTarget {
+				reqCtx.secondaryWrite = originRequest
+			}
+		}
+	}
+	if (ch.originRequestRetryPolicy != nil || ch.targetRequestRetryPolicy != nil) &&
+		fwdDecision != forwardToAsyncOnly && reqCtx.continuousPaging == nil {
+		idempotent, err := isIdempotentRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return err
+		}
+		if idempotent {
+			if fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin {
+				reqCtx.originRetry = ch.originRequestRetryPolicy.newRequestRetry(originRequest)
+			}
+			if fwdDecision == forwardToBoth || fwdDecision == forwardToTarget {
+				reqCtx.targetRetry = ch.targetRequestRetryPolicy.newRequestRetry(targetRequest)
+			}
+		}
+	}
 	var contextHoldersMap *sync.Map
 	if fwdDecision == forwardToAsyncOnly {
 		contextHoldersMap = ch.asyncRequestContextHolders // different map because of stream id collision
@@ -1392,7 +2446,7 @@ func (ch *ClientHandler) executeRequest(
 
 	if requestInfo.ShouldBeTrackedInMetrics() {
 		proxyMetrics := ch.metricHandler.GetProxyMetrics()
-		switch fwdDecision {
+		switch getMetricsForwardDecision(requestInfo) {
 		case forwardToBoth:
 			proxyMetrics.InFlightWrites.Add(1)
 		case forwardToOrigin:
@@ -1401,13 +2455,27 @@ func (ch *ClientHandler) executeRequest(
 			proxyMetrics.InFlightReadsTarget.Add(1)
 		case forwardToAsyncOnly:
 		default:
-			log.Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", fwdDecision)
+			ch.getLogger().Errorf("unexpected forwardDecision %v, unable to track proxy level metrics", fwdDecision)
+		}
+		if fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin {
+			ch.nodeMetrics.OriginMetrics.InFlightRequests.Add(1)
+		}
+		if fwdDecision == forwardToBoth || fwdDecision == forwardToTarget {
+			ch.nodeMetrics.TargetMetrics.InFlightRequests.Add(1)
 		}
 	}
 
 	ch.clientHandlerRequestWaitGroup.Add(1)
+	if ch.inFlightBudget != nil {
+		ch.inFlightBudget.acquire()
+	}
 	if fwdDecision != forwardToAsyncOnly {
-		timer := time.AfterFunc(requestTimeout, func() {
+		reqCtx.timeout = timeout
+		reqCtx.timerStart = time.Now()
+		timer := time.AfterFunc(timeout.forDecision(fwdDecision), func() {
+			if reqCtx.extendTimer() {
+				return
+			}
 			ch.closedRespChannelLock.RLock()
 			defer ch.closedRespChannelLock.RUnlock()
 			if ch.closedRespChannel {
@@ -1422,19 +2490,18 @@ func (ch *ClientHandler) executeRequest(
 		reqCtx.SetTimer(timer)
 	}
 
-	sendAlsoToAsync := requestInfo.ShouldAlsoBeSentAsync() && ch.asyncConnector != nil
 	switch fwdDecision {
 	case forwardToBoth:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v and %v",
+		ch.getLogger().Tracef("Forwarding request with opcode %v for stream %v to %v and %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeOrigin, common.ClusterTypeTarget)
 		ch.originCassandraConnector.sendRequestToCluster(originRequest)
 		ch.targetCassandraConnector.sendRequestToCluster(targetRequest)
 	case forwardToOrigin:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v",
+		ch.getLogger().Tracef("Forwarding request with opcode %v for stream %v to %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeOrigin)
 		ch.originCassandraConnector.sendRequestToCluster(originRequest)
 	case forwardToTarget:
-		log.Tracef("Forwarding request with opcode %v for stream %v to %v",
+		ch.getLogger().Tracef("Forwarding request with opcode %v for stream %v to %v",
 			f.Header.OpCode, f.Header.StreamId, common.ClusterTypeTarget)
 		ch.targetCassandraConnector.sendRequestToCluster(targetRequest)
 	case forwardToAsyncOnly:
@@ -1442,6 +2509,11 @@ func (ch *ClientHandler) executeRequest(
 		return fmt.Errorf("unknown forward decision %v, stream: %d", fwdDecision, f.Header.StreamId)
 	}
 
+	if asyncWrite {
+		ch.sendAsyncWrite(requestInfo, originRequest, targetRequest)
+		return nil
+	}
+
 	if !sendAlsoToAsync && fwdDecision != forwardToAsyncOnly {
 		return nil
 	}
@@ -1453,7 +2525,7 @@ func (ch *ClientHandler) executeRequest(
 
 	return ch.sendToAsyncConnector(
 		frameContext, originRequest, targetRequest, fwdDecision, reqCtx, holder, sendAlsoToAsync,
-		overallRequestStartTime, requestTimeout)
+		overallRequestStartTime, timeout.get(ch.asyncConnector.clusterType))
 }
 
 func (ch *ClientHandler) handleInterceptedRequest(
@@ -1484,9 +2556,34 @@ func (ch *ClientHandler) handleInterceptedRequest(
 	} else {
 		controlConn = ch.originControlConn
 	}
-	virtualHosts, err := controlConn.GetVirtualHosts()
-	if err != nil {
-		return nil, err
+	// only QUERY requests on system.local and system.peers are cached, the PREPARED responses are stored in the
+	// prepared statement cache and the system_views.zdm_* tables reflect the current state of the proxy
+	var cachedQuery string
+	var topologyVersion int64
+	if ch.interceptedResponseCache != nil && f.Header.OpCode == primitive.OpCodeQuery &&
+		!isProxyVirtualTableQueryType(interceptedQueryType) {
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return nil, err
+		}
+		cachedQuery = stmtQueryData.queryData.getQuery()
+		topologyVersion = controlConn.GetTopologyVersion()
+		cachedResponse := ch.interceptedResponseCache.getResponse(f, cachedQuery, currentKeyspace, topologyVersion)
+		if cachedResponse != nil {
+			ch.metricHandler.GetProxyMetrics().InterceptedResponseCacheHits.Add(1)
+			return cachedResponse, nil
+		}
+		ch.metricHandler.GetProxyMetrics().InterceptedResponseCacheMisses.Add(1)
+	}
+
+	var virtualHosts []*VirtualHost
+	var localVirtualHostIndex int
+	var err error
+	if !isProxyVirtualTableQueryType(interceptedQueryType) {
+		virtualHosts, localVirtualHostIndex, err = controlConn.GetVirtualHosts()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	typeCodec := GetDefaultGenericTypeCodec()
@@ -1503,16 +2600,24 @@ func (ch *ClientHandler) handleInterceptedRequest(
 		}
 		interceptedQueryResponse, err = NewSystemPeersResult(prepareRequestInfo, currentKeyspace,
 			typeCodec, f.Header.Version, controlConn.GetSystemPeersColumnNames(), controlConn.GetSystemLocalColumnData(),
-			parsedSelectClause, virtualHosts, controlConn.GetLocalVirtualHostIndex(), ch.conf.ProxyListenPort)
+			parsedSelectClause, virtualHosts, localVirtualHostIndex, ch.conf.ProxyListenPort)
 	case local:
 		parsedSelectClause := interceptedRequestInfo.GetParsedSelectClause()
 		if parsedSelectClause == nil {
 			return nil, fmt.Errorf("unable to intercept system.local query (prepared=%v) because parsed select clause is nil", prepared)
 		}
-		localVirtualHost := virtualHosts[controlConn.GetLocalVirtualHostIndex()]
+		localVirtualHost := virtualHosts[localVirtualHostIndex]
 		interceptedQueryResponse, err = NewSystemLocalResult(prepareRequestInfo, currentKeyspace,
 			typeCodec, f.Header.Version, controlConn.GetSystemLocalColumnData(), parsedSelectClause,
 			localVirtualHost, ch.conf.ProxyListenPort)
+	case proxyClients, proxyPreparedStatements, proxyRoutingRules:
+		parsedSelectClause := interceptedRequestInfo.GetParsedSelectClause()
+		if parsedSelectClause == nil {
+			return nil, fmt.Errorf("unable to intercept %v query (prepared=%v) because parsed select clause is nil",
+				interceptedQueryType, prepared)
+		}
+		interceptedQueryResponse, err = ch.proxyVirtualTables.newResult(prepareRequestInfo, currentKeyspace,
+			typeCodec, f.Header.Version, interceptedQueryType, parsedSelectClause)
 	default:
 		return nil, fmt.Errorf("expected intercepted query type: %v", interceptedQueryType)
 	}
@@ -1538,6 +2643,10 @@ func (ch *ClientHandler) handleInterceptedRequest(
 		ch.preparedStatementCache.StoreIntercepted(preparedMsg, prepareRequestInfo)
 	}
 
+	if cachedQuery != "" {
+		ch.interceptedResponseCache.storeResponse(interceptedResponseRawFrame, cachedQuery, currentKeyspace, topologyVersion)
+	}
+
 	return interceptedResponseRawFrame, nil
 }
 
@@ -1557,6 +2666,18 @@ func (ch *ClientHandler) handlePrepareRequest(
 	default:
 		originRequest = f
 		targetRequest = f
+		// the statement was already inspected to build the request info
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		castedRequestInfo.statementType = stmtQueryData.queryData.getStatementType()
+		if ch.targetWriteFilter != nil || ch.targetWriteSampler != nil || ch.writeComparisonSampler != nil ||
+			ch.applicationReadRouting != nil || ch.tableMetrics != nil {
+			// the target write filter, the samplers, the read routing rules and the table level metrics need the
+			// inspected query to evaluate the EXECUTE requests of this statement
+			castedRequestInfo.queryInfo = stmtQueryData.queryData
+		}
 	}
 	return clientResponse, originRequest, targetRequest, nil
 }
@@ -1587,20 +2708,21 @@ func (ch *ClientHandler) handleExecuteRequest(
 		return clientResponse, nil, nil, err
 	}
 
-	sendToAsyncConnector := (castedRequestInfo.ShouldAlsoBeSentAsync() || fwdDecision == forwardToAsyncOnly) && ch.asyncConnector != nil
+	sendToAsyncConnector := ch.shouldAlsoBeSentAsync(castedRequestInfo) ||
+		(fwdDecision == forwardToAsyncOnly && ch.asyncConnector != nil)
 	replacedTerms := prepareRequestInfo.GetReplacedTerms()
 	asyncConnectorIsOrigin := ch.asyncConnector != nil && ch.asyncConnector.clusterType == common.ClusterTypeOrigin
-	var replacementTimeUuids []*uuid.UUID
+	var replacementValues []interface{}
 	if len(replacedTerms) > 0 && (fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin || (sendToAsyncConnector && asyncConnectorIsOrigin)) {
 		clientRequest, err := frameContext.GetOrDecodeFrame()
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("could not decode execute raw frame: %w", err)
 		}
 
-		replacementTimeUuids = ch.parameterModifier.generateTimeUuids(prepareRequestInfo)
+		replacementValues = ch.parameterModifier.generateReplacementValues(prepareRequestInfo)
 		newOriginRequest := clientRequest.Clone()
 		_, err = ch.parameterModifier.AddValuesToExecuteFrame(
-			newOriginRequest, prepareRequestInfo, preparedData.GetOriginVariablesMetadata(), replacementTimeUuids)
+			newOriginRequest, prepareRequestInfo, preparedData.GetOriginVariablesMetadata(), replacementValues)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("could not add values to origin EXECUTE: %w", err)
 		}
@@ -1614,7 +2736,16 @@ func (ch *ClientHandler) handleExecuteRequest(
 	}
 
 	asyncConnectorIsTarget := ch.asyncConnector != nil && ch.asyncConnector.clusterType == common.ClusterTypeTarget
-	if fwdDecision == forwardToBoth || fwdDecision == forwardToTarget || (sendToAsyncConnector && asyncConnectorIsTarget) {
+	rewriteTargetRequest := fwdDecision == forwardToBoth || fwdDecision == forwardToTarget || (sendToAsyncConnector && asyncConnectorIsTarget)
+	if rewriteTargetRequest && len(replacedTerms) == 0 && !f.Header.Version.SupportsResultMetadataId() {
+		// the raw frame is forwarded untouched if both clusters returned the same prepared id
+		queryId, err := frameContext.GetOrPeekQueryId()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rewriteTargetRequest = !bytes.Equal(queryId, preparedData.GetTargetPreparedId())
+	}
+	if rewriteTargetRequest {
 		clientRequest, err := frameContext.GetOrDecodeFrame()
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("could not decode execute raw frame: %w", err)
@@ -1623,11 +2754,11 @@ func (ch *ClientHandler) handleExecuteRequest(
 		newTargetRequest := clientRequest.Clone()
 		var newTargetExecuteMsg *message.Execute
 		if len(replacedTerms) > 0 {
-			if replacementTimeUuids == nil {
-				replacementTimeUuids = ch.parameterModifier.generateTimeUuids(prepareRequestInfo)
+			if replacementValues == nil {
+				replacementValues = ch.parameterModifier.generateReplacementValues(prepareRequestInfo)
 			}
 			newTargetExecuteMsg, err = ch.parameterModifier.AddValuesToExecuteFrame(
-				newTargetRequest, prepareRequestInfo, preparedData.GetTargetVariablesMetadata(), replacementTimeUuids)
+				newTargetRequest, prepareRequestInfo, preparedData.GetTargetVariablesMetadata(), replacementValues)
 			if err != nil {
 				return nil, nil, nil, fmt.Errorf("could not add values to target EXECUTE: %w", err)
 			}
@@ -1641,8 +2772,11 @@ func (ch *ClientHandler) handleExecuteRequest(
 
 		originalQueryId := newTargetExecuteMsg.QueryId
 		newTargetExecuteMsg.QueryId = preparedData.GetTargetPreparedId()
-		log.Tracef("Replacing prepared ID %s with %s for target cluster.",
+		ch.getLogger().Tracef("Replacing prepared ID %s with %s for target cluster.",
 			hex.EncodeToString(originalQueryId), hex.EncodeToString(newTargetExecuteMsg.QueryId))
+		if newTargetRequest.Header.Version.SupportsResultMetadataId() {
+			newTargetExecuteMsg.ResultMetadataId = preparedData.GetTargetResultMetadataId()
+		}
 
 		newTargetRequestRaw, err := defaultCodec.ConvertToRawFrame(newTargetRequest)
 		if err != nil {
@@ -1685,12 +2819,12 @@ func (ch *ClientHandler) handleBatchRequest(
 					return nil, nil, fmt.Errorf("expected Batch but got %v instead", newOriginRequest.Body.Message.GetOpCode())
 				}
 			}
-			replacementTimeUuids := ch.parameterModifier.generateTimeUuids(prepareRequestInfo)
+			replacementValues := ch.parameterModifier.generateReplacementValues(prepareRequestInfo)
 			err = ch.parameterModifier.addValuesToBatchChild(decodedFrame.Header.Version, newTargetBatchMsg.Children[stmtIdx],
-				preparedData.GetPrepareRequestInfo(), preparedData.GetTargetVariablesMetadata(), replacementTimeUuids)
+				preparedData.GetPrepareRequestInfo(), preparedData.GetTargetVariablesMetadata(), replacementValues)
 			if err == nil && newOriginBatchMsg != nil {
 				err = ch.parameterModifier.addValuesToBatchChild(decodedFrame.Header.Version, newOriginBatchMsg.Children[stmtIdx],
-					preparedData.GetPrepareRequestInfo(), preparedData.GetOriginVariablesMetadata(), replacementTimeUuids)
+					preparedData.GetPrepareRequestInfo(), preparedData.GetOriginVariablesMetadata(), replacementValues)
 			}
 			if err != nil {
 				return nil, nil, fmt.Errorf("could not add values to batch child statement: %w", err)
@@ -1699,7 +2833,7 @@ func (ch *ClientHandler) handleBatchRequest(
 
 		originalQueryId := newTargetBatchMsg.Children[stmtIdx].QueryOrId.([]byte)
 		newTargetBatchMsg.Children[stmtIdx].QueryOrId = preparedData.GetTargetPreparedId()
-		log.Tracef("Replacing prepared ID %s within a BATCH with %s for target cluster.",
+		ch.getLogger().Tracef("Replacing prepared ID %s within a BATCH with %s for target cluster.",
 			hex.EncodeToString(originalQueryId), hex.EncodeToString(preparedData.GetTargetPreparedId()))
 	}
 
@@ -1722,6 +2856,318 @@ func (ch *ClientHandler) handleBatchRequest(
 	return originRequest, targetRequest, nil
 }
 
+// sendProxyResponse sends a response that was generated by the proxy without forwarding the request to any cluster.
+func (ch *ClientHandler) sendProxyResponse(
+	clientResponse *frame.RawFrame, customResponseChannel chan *customResponse, explanation *requestExplanation,
+	trace *requestTrace) {
+	explanation.setResponseOutcome(clientResponse, common.ClusterTypeNone)
+	explanation.log()
+
+	if customResponseChannel != nil {
+		customResponseChannel <- &customResponse{aggregatedResponse: clientResponse}
+	} else {
+		ch.clientConnector.sendResponseToClient(clientResponse)
+	}
+	trace.end(clientResponse, nil)
+}
+
+// checkCircuitBreakers returns the cluster whose circuit breaker is open if the request can't be forwarded to
+// one of the clusters of the forward decision.
+func (ch *ClientHandler) checkCircuitBreakers(fwdDecision forwardDecision) (common.ClusterType, bool) {
+	if (fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin) &&
+		!ch.originCassandraConnector.circuitBreaker.allowRequest() {
+		return common.ClusterTypeOrigin, true
+	}
+	if (fwdDecision == forwardToBoth || fwdDecision == forwardToTarget) &&
+		!ch.targetCassandraConnector.circuitBreaker.allowRequest() {
+		return common.ClusterTypeTarget, true
+	}
+	return "", false
+}
+
+// newCircuitBreakerOpenResponse returns the OVERLOADED response of a request that was not forwarded because the
+// circuit breaker of a cluster is open.
+func (ch *ClientHandler) newCircuitBreakerOpenResponse(
+	request *frame.RawFrame, openCluster common.ClusterType) (*frame.RawFrame, error) {
+	ch.getRequestLogger(request.Header.StreamId).Debugf("Rejecting request with stream id %v because the circuit breaker of %v is open.",
+		request.Header.StreamId, openCluster)
+	overloaded := frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Overloaded{
+		ErrorMessage: fmt.Sprintf("Proxy circuit breaker of %v is open, please retry later.", openCluster),
+	})
+	rawFrame, err := defaultCodec.ConvertToRawFrame(overloaded)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert circuit breaker response to raw frame: %w", err)
+	}
+	return rawFrame, nil
+}
+
+// recordCircuitBreakerTimeouts counts a timeout in the circuit breakers of the clusters that didn't respond to
+// a request that timed out.
+func (ch *ClientHandler) recordCircuitBreakerTimeouts(reqCtx RequestContext) {
+	typedReqCtx, ok := reqCtx.(*requestContextImpl)
+	if !ok {
+		return
+	}
+	originTimedOut, targetTimedOut := typedReqCtx.getMissingResponses()
+	if originTimedOut {
+		ch.originCassandraConnector.circuitBreaker.recordResponse(nil)
+	}
+	if targetTimedOut {
+		ch.targetCassandraConnector.circuitBreaker.recordResponse(nil)
+	}
+}
+
+// handleRejectedRequest returns an error response for a request that the proxy doesn't forward to any cluster
+// because of a schema statement policy or ZDM_COUNTER_WRITES_POLICY.
+func (ch *ClientHandler) handleRejectedRequest(
+	requestInfo *RejectedRequestInfo, frameContext *frameDecodeContext) (*frame.RawFrame, error) {
+	f := frameContext.GetRawFrame()
+	ch.getRequestLogger(f.Header.StreamId).Debugf("Rejecting request with stream id %v: %v", f.Header.StreamId, requestInfo.GetErrorMessage())
+	errorFrame := frame.NewFrame(f.Header.Version, f.Header.StreamId, &message.Invalid{
+		ErrorMessage: requestInfo.GetErrorMessage(),
+	})
+	rawFrame, err := defaultCodec.ConvertToRawFrame(errorFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert rejected request response to raw frame: %w", err)
+	}
+	return rawFrame, nil
+}
+
+// handleAdminRequest executes a statement on the zdm_admin keyspace (see ZDM_ADMIN_KEYSPACE_ROLES).
+func (ch *ClientHandler) handleAdminRequest(
+	requestInfo *AdminRequestInfo, frameContext *frameDecodeContext) (*frame.RawFrame, error) {
+	f := frameContext.GetRawFrame()
+	response, err := ch.adminKeyspace.execute(requestInfo.GetQueryInfo(), ch.clientRole,
+		ch.clientConnector.connection.RemoteAddr().String(), GetDefaultGenericTypeCodec(), f.Header.Version)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute %v statement: %w", adminKeyspaceName, err)
+	}
+	rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(f.Header.Version, f.Header.StreamId, response))
+	if err != nil {
+		return nil, fmt.Errorf("could not convert %v statement response to raw frame: %w", adminKeyspaceName, err)
+	}
+	return rawFrame, nil
+}
+
+// checkDestructiveStatementConfirmation returns an error response if the request is a TRUNCATE or DROP statement
+// that was not confirmed through the admin API (ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED).
+func (ch *ClientHandler) checkDestructiveStatementConfirmation(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string) (*frame.RawFrame, error) {
+	f := frameContext.GetRawFrame()
+	var query string
+	switch f.Header.OpCode {
+	case primitive.OpCodeQuery:
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, ch.timeUuidGenerator)
+		if err != nil {
+			return nil, err
+		}
+		if stmtQueryData.queryData.getStatementType() != statementTypeOther {
+			return nil, nil
+		}
+		query = stmtQueryData.queryData.getQuery()
+	case primitive.OpCodeExecute:
+		executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo)
+		if !ok {
+			return nil, nil
+		}
+		query = executeRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetQuery()
+	default:
+		return nil, nil
+	}
+
+	verb := getDestructiveStatementVerb(query)
+	if verb == "" {
+		return nil, nil
+	}
+	if ch.destructiveStatementGuard.confirm(query) {
+		ch.getLogger().Infof("Forwarding confirmed %v statement from %v: %v", verb, ch.clientConnector.connection.RemoteAddr(), query)
+		return nil, nil
+	}
+
+	ch.metricHandler.GetProxyMetrics().DestructiveStatementsRejected.Add(1)
+	ch.getLogger().Warnf("Rejecting %v statement from %v that was not confirmed through the admin API: %v",
+		verb, ch.clientConnector.connection.RemoteAddr(), query)
+	errorFrame := frame.NewFrame(f.Header.Version, f.Header.StreamId, &message.Unauthorized{
+		ErrorMessage: fmt.Sprintf("%v statement rejected by ZDM proxy because it was not confirmed, "+
+			"create a confirmation token with the admin API and add it to the statement "+
+			"(/* zdm-confirmation-token: <token> */) or unlock destructive statements with the admin API", verb),
+	})
+	rawFrame, err := defaultCodec.ConvertToRawFrame(errorFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert destructive statement rejection response to raw frame: %w", err)
+	}
+	return rawFrame, nil
+}
+
+// checkTargetDdlCompatibility logs a warning if the request is a schema change that isn't compatible with TARGET
+// and returns an error response if it should be rejected (ZDM_TARGET_DDL_COMPATIBILITY_MODE=REJECT).
+func (ch *ClientHandler) checkTargetDdlCompatibility(
+	frameContext *frameDecodeContext, currentKeyspace string) (*frame.RawFrame, error) {
+	f := frameContext.GetRawFrame()
+	if f.Header.OpCode != primitive.OpCodeQuery && f.Header.OpCode != primitive.OpCodePrepare {
+		return nil, nil
+	}
+	stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, ch.timeUuidGenerator)
+	if err != nil {
+		return nil, err
+	}
+	queryInfo := stmtQueryData.queryData
+	if queryInfo.getStatementType() != statementTypeOther {
+		return nil, nil
+	}
+	incompatibilities := ch.targetDdlChecker.check(queryInfo.getQuery())
+	if len(incompatibilities) == 0 {
+		return nil, nil
+	}
+
+	ch.metricHandler.GetProxyMetrics().TargetIncompatibleSchemaChanges.Add(1)
+	if !ch.targetDdlChecker.shouldReject() {
+		ch.getLogger().Warnf("Schema change is not compatible with %v: %v. Forwarding it anyway "+
+			"(ZDM_TARGET_DDL_COMPATIBILITY_MODE=%v). Query: %v", common.ClusterTypeTarget,
+			strings.Join(incompatibilities, "; "), ch.targetDdlChecker.mode, queryInfo.getQuery())
+		return nil, nil
+	}
+
+	ch.getLogger().Warnf("Rejecting schema change that is not compatible with %v: %v. Query: %v",
+		common.ClusterTypeTarget, strings.Join(incompatibilities, "; "), queryInfo.getQuery())
+	errorFrame := frame.NewFrame(f.Header.Version, f.Header.StreamId, &message.Invalid{
+		ErrorMessage: fmt.Sprintf("Schema change rejected by ZDM proxy because it is not compatible with %v: %v",
+			common.ClusterTypeTarget, strings.Join(incompatibilities, "; ")),
+	})
+	rawFrame, err := defaultCodec.ConvertToRawFrame(errorFrame)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert schema change rejection response to raw frame: %w", err)
+	}
+	return rawFrame, nil
+}
+
+// applySessionReadRouting forwards a read to the cluster of the application read routing rule that applies to it
+// (see ZDM_APPLICATION_READ_ROUTING_RULES). Reads that are routed by a rule are not sent to the async connector.
+func (ch *ClientHandler) applySessionReadRouting(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	explanation *requestExplanation) (RequestInfo, error) {
+	rule, err := ch.sessionReadRoutingRules.matchRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+	if err != nil || rule == nil {
+		return requestInfo, err
+	}
+
+	ch.getLogger().Tracef("Read forwarded to %v by application read routing rule %v.", rule.Cluster, rule)
+	decision := getReadRoutingForwardDecision(rule.Cluster)
+	explanation.addApplicationReadRouting(decision)
+	if executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo); ok {
+		return NewRoutedReadExecuteRequestInfo(executeRequestInfo.GetPreparedData(), decision), nil
+	}
+	return NewGenericRequestInfo(decision, false, true), nil
+}
+
+// applyAdminReadRouting forwards a read to the cluster of the read_routing setting of the zdm_admin keyspace if it
+// is set. Reads that are routed by the setting are not sent to the async connector.
+func (ch *ClientHandler) applyAdminReadRouting(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	explanation *requestExplanation) (RequestInfo, error) {
+	cluster := ch.adminKeyspace.getReadRouting()
+	if cluster == common.ClusterTypeNone || getReadRoutingForwardDecision(cluster) == requestInfo.GetForwardDecision() {
+		return requestInfo, nil
+	}
+	rules := sessionReadRoutingRules{{Cluster: cluster}}
+	rule, err := rules.matchRequest(frameContext, requestInfo, currentKeyspace, ch.timeUuidGenerator)
+	if err != nil || rule == nil {
+		return requestInfo, err
+	}
+
+	ch.getLogger().Tracef("Read forwarded to %v by the %v setting of the %v keyspace.",
+		cluster, readRoutingSettingName, adminKeyspaceName)
+	decision := getReadRoutingForwardDecision(cluster)
+	explanation.addAdminReadRouting(decision)
+	if executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo); ok {
+		return NewRoutedReadExecuteRequestInfo(executeRequestInfo.GetPreparedData(), decision), nil
+	}
+	return NewGenericRequestInfo(decision, false, true), nil
+}
+
+// applyTargetWriteFilter returns a FilteredWriteRequestInfo if none of the statements of the request should be
+// forwarded to TARGET or a TARGET BATCH request without the statements that should not be forwarded to TARGET.
+func (ch *ClientHandler) applyTargetWriteFilter(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	originRequest *frame.RawFrame, targetRequest *frame.RawFrame, explanation *requestExplanation) (
+	RequestInfo, *frame.RawFrame, error) {
+
+	filtered, total, err := ch.targetWriteFilter.filteredStatements(
+		frameContext, requestInfo, currentKeyspace, originRequest, ch.timeUuidGenerator)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not evaluate target write filter rules: %w", err)
+	}
+	if len(filtered) == 0 {
+		return requestInfo, targetRequest, nil
+	}
+
+	ch.metricHandler.GetProxyMetrics().TargetFilteredWrites.Add(1)
+	if len(filtered) == total {
+		ch.getLogger().Tracef("Write matches a target write filter rule, forwarding it to %v only.", common.ClusterTypeOrigin)
+		explanation.addTargetWriteFilter(filtered, total)
+		return NewFilteredWriteRequestInfo(requestInfo), targetRequest, nil
+	}
+
+	decodedTargetRequest, err := defaultCodec.ConvertFromRawFrame(targetRequest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode target BATCH request: %w", err)
+	}
+	targetBatchMsg, ok := decodedTargetRequest.Body.Message.(*message.Batch)
+	if !ok {
+		return nil, nil, fmt.Errorf("expected Batch but got %v instead", decodedTargetRequest.Body.Message.GetOpCode())
+	}
+	filteredIdx := make(map[int]bool, len(filtered))
+	for _, stmtIdx := range filtered {
+		filteredIdx[stmtIdx] = true
+	}
+	children := make([]*message.BatchChild, 0, len(targetBatchMsg.Children)-len(filtered))
+	for stmtIdx, child := range targetBatchMsg.Children {
+		if !filteredIdx[stmtIdx] {
+			children = append(children, child)
+		}
+	}
+	targetBatchMsg.Children = children
+	newTargetRequest, err := defaultCodec.ConvertToRawFrame(decodedTargetRequest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not convert target BATCH request to raw frame: %w", err)
+	}
+
+	ch.getLogger().Tracef("%d of the %d BATCH child statements match a target write filter rule, "+
+		"removing them from the %v request.", len(filtered), total, common.ClusterTypeTarget)
+	explanation.addTargetWriteFilter(filtered, total)
+	return requestInfo, newTargetRequest, nil
+}
+
+// applyConsistencyOverrides overrides the consistency level of the requests that are forwarded to each cluster,
+// including the request that is sent to the async connector.
+func (ch *ClientHandler) applyConsistencyOverrides(
+	frameContext *frameDecodeContext, fwdDecision forwardDecision, sendToAsync bool,
+	originRequest *frame.RawFrame, targetRequest *frame.RawFrame, explanation *requestExplanation) (
+	*frame.RawFrame, *frame.RawFrame, error) {
+	asyncCluster := common.ClusterTypeTarget
+	if ch.primaryCluster == common.ClusterTypeTarget {
+		asyncCluster = common.ClusterTypeOrigin
+	}
+	sendToAsync = sendToAsync || fwdDecision == forwardToAsyncOnly
+
+	var err error
+	if ch.originConsistencyOverride != nil &&
+		(fwdDecision == forwardToBoth || fwdDecision == forwardToOrigin || (sendToAsync && asyncCluster == common.ClusterTypeOrigin)) {
+		originRequest, err = ch.originConsistencyOverride.apply(frameContext, originRequest, explanation)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if ch.targetConsistencyOverride != nil &&
+		(fwdDecision == forwardToBoth || fwdDecision == forwardToTarget || (sendToAsync && asyncCluster == common.ClusterTypeTarget)) {
+		targetRequest, err = ch.targetConsistencyOverride.apply(frameContext, targetRequest, explanation)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return originRequest, targetRequest, nil
+}
+
 func (ch *ClientHandler) sendToAsyncConnector(
 	frameContext *frameDecodeContext, originRequest *frame.RawFrame, targetRequest *frame.RawFrame,
 	fwdDecision forwardDecision, reqCtx *requestContextImpl, holder *requestContextHolder, sendAlsoToAsync bool,
@@ -1741,6 +3187,8 @@ func (ch *ClientHandler) sendToAsyncConnector(
 			if reqCtx.Cancel(ch.nodeMetrics) {
 				ch.cancelRequest(holder, reqCtx)
 			}
+		} else {
+			reqCtx.readComparison.abandon()
 		}
 		return nil
 	}
@@ -1755,8 +3203,13 @@ func (ch *ClientHandler) sendToAsyncConnector(
 
 	f := frameContext.GetRawFrame()
 
-	sent := ch.asyncConnector.sendAsyncRequest(
-		reqCtx.GetRequestInfo(), asyncRequest, !isFireAndForget, overallRequestStartTime, requestTimeout, func() {
+	asyncReqCtx := NewAsyncRequestContext(
+		reqCtx.GetRequestInfo(), asyncRequest.Header.StreamId, !isFireAndForget, overallRequestStartTime)
+	if isFireAndForget {
+		asyncReqCtx.readComparison = reqCtx.readComparison
+	}
+	sent := ch.asyncConnector.sendAsyncRequestContext(
+		asyncReqCtx, asyncRequest, requestTimeout, func() {
 			if !isFireAndForget {
 				ch.closedRespChannelLock.RLock()
 				defer ch.closedRespChannelLock.RUnlock()
@@ -1769,6 +3222,7 @@ func (ch *ClientHandler) sendToAsyncConnector(
 					ch.respChannel <- NewTimeoutResponse(f, true)
 				}
 			} else {
+				asyncReqCtx.readComparison.abandon()
 				ch.clientHandlerRequestWaitGroup.Done()
 			}
 		})
@@ -1779,6 +3233,7 @@ func (ch *ClientHandler) sendToAsyncConnector(
 				ch.cancelRequest(holder, reqCtx)
 			}
 		} else {
+			asyncReqCtx.readComparison.abandon()
 			ch.clientHandlerRequestWaitGroup.Done()
 		}
 	}
@@ -1786,6 +3241,37 @@ func (ch *ClientHandler) sendToAsyncConnector(
 	return nil
 }
 
+// shouldAlsoBeSentAsync returns true if the request should also be sent to the async connector as a fire and forget
+// request, reads are only sent to the async connector if ZDM_READ_MODE is DUAL_ASYNC_ON_SECONDARY (the async connector
+// can also exist because ZDM_DUAL_WRITE_MODE is ASYNC) but requests that are sent to both clusters (e.g. USE)
+// are always sent to it so its state matches the state of the other connectors.
+func (ch *ClientHandler) shouldAlsoBeSentAsync(requestInfo RequestInfo) bool {
+	if ch.asyncConnector == nil || !requestInfo.ShouldAlsoBeSentAsync() {
+		return false
+	}
+	return ch.asyncReads || requestInfo.GetForwardDecision() == forwardToBoth
+}
+
+// sendAsyncWrite sends the write to the secondary cluster in the background, the client handler doesn't wait
+// for its response but the request wait group tracks it so that it is not lost during a graceful shutdown.
+func (ch *ClientHandler) sendAsyncWrite(requestInfo RequestInfo, originRequest *frame.RawFrame, targetRequest *frame.RawFrame) {
+	request := targetRequest
+	if ch.primaryCluster == common.ClusterTypeTarget {
+		request = originRequest
+	}
+
+	write := &asyncWrite{
+		requestInfo:  requestInfo,
+		request:      request,
+		retriesLeft:  ch.conf.DualWriteAsyncMaxRetries,
+		proxyMetrics: ch.metricHandler.GetProxyMetrics(),
+	}
+	ch.clientHandlerRequestWaitGroup.Add(1)
+	if !ch.asyncConnector.sendAsyncWrite(write) {
+		ch.asyncConnector.failAsyncWrite(write, "could not be sent")
+	}
+}
+
 // Aggregates the responses received from the two clusters as follows:
 //   - if both responses are a success OR both responses are a failure: return responseFromOC
 //   - if either response is a failure, the failure "wins": return the failed response
@@ -1798,13 +3284,13 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 	responseFromTargetCassandra *frame.RawFrame) (*frame.RawFrame, common.ClusterType) {
 
 	originOpCode := responseFromOriginCassandra.Header.OpCode
-	log.Tracef("Aggregating responses. %v opcode %d, %v opcode %d",
+	ch.getLogger().Tracef("Aggregating responses. %v opcode %d, %v opcode %d",
 		common.ClusterTypeOrigin, originOpCode, common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
 
 	// aggregate responses and update relevant aggregate metrics for general failed or successful responses
 	if isResponseSuccessful(responseFromOriginCassandra) && isResponseSuccessful(responseFromTargetCassandra) {
 		if originOpCode == primitive.OpCodeSupported {
-			log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+			ch.getLogger().Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 				common.ClusterTypeTarget, originOpCode)
 			return responseFromTargetCassandra, common.ClusterTypeTarget
 		} else if request.Header.OpCode == primitive.OpCodePrepare {
@@ -1812,11 +3298,11 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 			return responseFromOriginCassandra, common.ClusterTypeOrigin
 		} else {
 			if ch.primaryCluster == common.ClusterTypeTarget {
-				log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+				ch.getLogger().Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 					common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
 				return responseFromTargetCassandra, common.ClusterTypeTarget
 			} else {
-				log.Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
+				ch.getLogger().Tracef("Aggregated response: both successes, sending back %v response with opcode %d",
 					common.ClusterTypeOrigin, originOpCode)
 				return responseFromOriginCassandra, common.ClusterTypeOrigin
 			}
@@ -1825,34 +3311,43 @@ func (ch *ClientHandler) aggregateAndTrackResponses(
 
 	proxyMetrics := ch.metricHandler.GetProxyMetrics()
 	if !isResponseSuccessful(responseFromOriginCassandra) && !isResponseSuccessful(responseFromTargetCassandra) {
-		log.Debugf("Aggregated response: both failures, sending back %v response with opcode %d",
-			common.ClusterTypeOrigin, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnBoth.Add(1)
+			ch.migrationProgress.recordDualWriteFailure()
+		}
+		// the error of the primary cluster is returned, PREPARE requests always get the ORIGIN response like above
+		if ch.primaryCluster == common.ClusterTypeTarget && request.Header.OpCode != primitive.OpCodePrepare {
+			ch.getRequestLogger(request.Header.StreamId).Debugf("Aggregated response: both failures, sending back %v response with opcode %d",
+				common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
+			return responseFromTargetCassandra, common.ClusterTypeTarget
 		}
+		ch.getRequestLogger(request.Header.StreamId).Debugf("Aggregated response: both failures, sending back %v response with opcode %d",
+			common.ClusterTypeOrigin, originOpCode)
 		return responseFromOriginCassandra, common.ClusterTypeOrigin
 	}
 
 	// if either response is a failure, the failure "wins" --> return the failed response
 	if !isResponseSuccessful(responseFromOriginCassandra) {
-		log.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
+		ch.getRequestLogger(request.Header.StreamId).Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
 			common.ClusterTypeOrigin, common.ClusterTypeOrigin, originOpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnOrigin.Add(1)
+			ch.migrationProgress.recordDualWriteFailure()
 		}
 		return responseFromOriginCassandra, common.ClusterTypeOrigin
 	} else {
-		log.Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
-			common.ClusterTypeTarget, common.ClusterTypeTarget, originOpCode)
+		ch.getRequestLogger(request.Header.StreamId).Debugf("Aggregated response: failure only on %v, sending back %v response with opcode %d",
+			common.ClusterTypeTarget, common.ClusterTypeTarget, responseFromTargetCassandra.Header.OpCode)
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			proxyMetrics.FailedWritesOnTarget.Add(1)
+			ch.migrationProgress.recordDualWriteFailure()
 		}
 		return responseFromTargetCassandra, common.ClusterTypeTarget
 	}
 }
 
-// Replaces the credentials in the provided auth frame (which are the Target credentials) with
-// the Origin credentials that are provided to the proxy in the configuration.
+// Replaces the credentials in the provided auth frame with the credentials of the primary handshake cluster (see
+// handshakeCredentials) and stores the credentials of the secondary and async handshakes.
 func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawFrame, error) {
 	parsedAuthFrame, err := defaultCodec.ConvertFromRawFrame(f)
 	if err != nil {
@@ -1866,69 +3361,50 @@ func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawF
 			parsedAuthFrame.Body.Message)
 	}
 
-	clientCreds, err := ParseCredentialsFromRequest(authResponse.Token)
+	negotiation := ch.clientAuthNegotiation
+	if negotiation == nil {
+		negotiation = &clientAuthNegotiation{}
+	}
+	clientCreds, plainText, err := negotiation.evaluateResponse(authResponse.Token)
 	if err != nil {
 		return nil, err
 	}
 
-	if clientCreds == nil {
-		log.Debugf("Found auth response frame without creds: %v", authResponse)
-		return f, nil
-	}
-
-	log.Debugf("Successfully extracted credentials from client auth frame: %v", clientCreds)
-
-	var primaryHandshakeCreds *AuthCredentials
+	primaryCluster, secondaryCluster := common.ClusterTypeOrigin, common.ClusterTypeTarget
 	if ch.forwardAuthToTarget {
-		// primary handshake is TARGET, secondary is ORIGIN
+		primaryCluster, secondaryCluster = common.ClusterTypeTarget, common.ClusterTypeOrigin
+	}
 
-		if ch.targetCredsOnClientRequest {
-			ch.secondaryHandshakeCreds = &AuthCredentials{
-				Username: ch.originUsername,
-				Password: ch.originPassword,
-			}
-		} else {
-			// unreachable code atm, if forwardAuthToTarget is true then targetCredsOnClientRequest is true
-			ch.secondaryHandshakeCreds = clientCreds
-			primaryHandshakeCreds = &AuthCredentials{
-				Username: ch.targetUsername,
-				Password: ch.targetPassword,
+	if !plainText {
+		// the client credentials are unknown so the secondary and async handshakes use the configured credentials
+		if ch.secondaryHandshakeCreds == nil {
+			ch.getLogger().Debugf("Relaying the SASL negotiation of the client (authenticator %v, mechanism %v) "+
+				"to %v, the configured credentials are used for the other handshakes.",
+				negotiation.authenticator, negotiation.mechanism, primaryCluster)
+			ch.secondaryHandshakeCreds = ch.configuredCredentials(secondaryCluster)
+			if ch.asyncConnector != nil {
+				ch.asyncHandshakeCreds = ch.configuredCredentials(ch.asyncConnector.clusterType)
 			}
 		}
-	} else {
-		// primary handshake is ORIGIN, secondary is TARGET
+		return f, nil
+	}
 
-		if ch.targetCredsOnClientRequest {
-			ch.secondaryHandshakeCreds = clientCreds
-			primaryHandshakeCreds = &AuthCredentials{
-				Username: ch.originUsername,
-				Password: ch.originPassword,
-			}
-		} else {
-			ch.secondaryHandshakeCreds = &AuthCredentials{
-				Username: ch.targetUsername,
-				Password: ch.targetPassword,
-			}
-		}
+	if clientCreds == nil {
+		ch.getLogger().Debugf("Found auth response frame without creds: %v", authResponse)
+		return f, nil
 	}
 
+	ch.getLogger().Debugf("Successfully extracted credentials from client auth frame: %v", clientCreds)
+	ch.clientRole = clientCreds.Username
+
+	ch.secondaryHandshakeCreds = ch.handshakeCredentials(clientCreds, secondaryCluster)
 	ch.asyncHandshakeCreds = clientCreds
 	if ch.asyncConnector != nil {
-		if ch.targetCredsOnClientRequest && ch.asyncConnector.clusterType == common.ClusterTypeOrigin {
-			ch.asyncHandshakeCreds = &AuthCredentials{
-				Username: ch.originUsername,
-				Password: ch.originPassword,
-			}
-		}
-		if !ch.targetCredsOnClientRequest && ch.asyncConnector.clusterType == common.ClusterTypeTarget {
-			ch.asyncHandshakeCreds = &AuthCredentials{
-				Username: ch.targetUsername,
-				Password: ch.targetPassword,
-			}
-		}
+		ch.asyncHandshakeCreds = ch.handshakeCredentials(clientCreds, ch.asyncConnector.clusterType)
 	}
+	primaryHandshakeCreds := ch.handshakeCredentials(clientCreds, primaryCluster)
 
-	if primaryHandshakeCreds == nil {
+	if primaryHandshakeCreds == clientCreds {
 		// client credentials don't need to be replaced
 		return f, nil
 	}
@@ -1943,6 +3419,74 @@ func (ch *ClientHandler) handleClientCredentials(f *frame.RawFrame) (*frame.RawF
 	return f, nil
 }
 
+// handshakeCredentials returns the credentials that are sent to the provided cluster depending on its credentials
+// mode: the client credentials (CLIENT) or the credentials of the proxy configuration (CONFIGURED). The authorization-id
+// of the client is only added to the credentials of the proxy configuration if it's explicitly enabled because it allows
+// the client to execute requests as any role that the configured user can authorize.
+func (ch *ClientHandler) handshakeCredentials(
+	clientCreds *AuthCredentials, clusterType common.ClusterType) *AuthCredentials {
+	if (clusterType == common.ClusterTypeOrigin && ch.originCredentialsMode == common.CredentialsModeConfigured) ||
+		(clusterType == common.ClusterTypeTarget && ch.targetCredentialsMode == common.CredentialsModeConfigured) {
+		authId := ""
+		if ch.isAuthorizationIdForwardedWithConfiguredCredentials(clusterType) {
+			authId = clientCreds.AuthId
+		} else if clientCreds.AuthId != "" {
+			ch.getLogger().Debugf("Removing authorization-id %v from the credentials sent to %v because "+
+				"ZDM_%v_FORWARD_AUTHORIZATION_ID_WITH_CONFIGURED_CREDENTIALS is false.",
+				clientCreds.AuthId, clusterType, clusterType)
+		}
+		return withAuthorizationId(ch.configuredCredentials(clusterType), authId)
+	}
+
+	if !ch.isAuthorizationIdForwarded(clusterType) && clientCreds.AuthId != "" {
+		ch.getLogger().Debugf("Removing authorization-id %v from the credentials sent to %v "+
+			"because ZDM_%v_FORWARD_AUTHORIZATION_ID is false.", clientCreds.AuthId, clusterType, clusterType)
+		return withAuthorizationId(clientCreds, "")
+	}
+	return clientCreds
+}
+
+// configuredCredentials returns the credentials of the proxy configuration for the provided cluster.
+func (ch *ClientHandler) configuredCredentials(clusterType common.ClusterType) *AuthCredentials {
+	if clusterType == common.ClusterTypeOrigin {
+		return &AuthCredentials{
+			Username: ch.originUsername,
+			Password: ch.originPassword,
+		}
+	}
+	return &AuthCredentials{
+		Username: ch.targetUsername,
+		Password: ch.targetPassword,
+	}
+}
+
+// withAuthorizationId returns the provided credentials with the provided authorization-id (DSE proxy authentication,
+// i.e., execute-as), the provided credentials are returned if they already have it.
+func withAuthorizationId(creds *AuthCredentials, authId string) *AuthCredentials {
+	if creds.AuthId == authId {
+		return creds
+	}
+	return &AuthCredentials{
+		AuthId:   authId,
+		Username: creds.Username,
+		Password: creds.Password,
+	}
+}
+
+func (ch *ClientHandler) isAuthorizationIdForwarded(clusterType common.ClusterType) bool {
+	if clusterType == common.ClusterTypeTarget {
+		return ch.conf.TargetForwardAuthorizationId
+	}
+	return ch.conf.OriginForwardAuthorizationId
+}
+
+func (ch *ClientHandler) isAuthorizationIdForwardedWithConfiguredCredentials(clusterType common.ClusterType) bool {
+	if clusterType == common.ClusterTypeTarget {
+		return ch.conf.TargetForwardAuthorizationIdWithConfiguredCredentials
+	}
+	return ch.conf.OriginForwardAuthorizationIdWithConfiguredCredentials
+}
+
 func (ch *ClientHandler) LoadCurrentKeyspace() string {
 	ks := ch.currentKeyspaceName.Load()
 	if ks != nil {
@@ -2062,16 +3606,26 @@ func trackClusterErrorMetricsFromErrorMessage(
 		nodeMetricsInstance.WriteFailures.Add(1)
 	case primitive.ErrorCodeUnavailable:
 		nodeMetricsInstance.UnavailableErrors.Add(1)
+	case primitive.ErrorCodeSyntaxError:
+		nodeMetricsInstance.SyntaxErrors.Add(1)
+	case primitive.ErrorCodeAuthenticationError, primitive.ErrorCodeUnauthorized:
+		nodeMetricsInstance.AuthErrors.Add(1)
 	default:
-		log.Debugf("Recording %v other error: %v", connectorType, errorMsg)
+		moduleLogger(LogModuleClientHandler).Debugf("Recording %v other error: %v", connectorType, errorMsg)
 		nodeMetricsInstance.OtherErrors.Add(1)
 	}
 }
 
+// forwardAuthToTarget returns whether the client handshake is forwarded to TARGET instead of ORIGIN and the resolved
+// credentials mode (CLIENT or CONFIGURED) of each cluster. The client credentials are forwarded to the only cluster
+// that has authentication enabled, the credentials modes only take effect if both clusters have it enabled (or none).
 func forwardAuthToTarget(
 	originControlConn *ControlConn,
 	targetControlConn *ControlConn,
-	forwardClientCredsToOrigin bool) (forwardAuthToTarget bool, targetCredsOnClientRequest bool) {
+	forwardClientCredsToOrigin bool,
+	originCredsMode common.CredentialsMode,
+	targetCredsMode common.CredentialsMode) (
+	forwardAuthToTarget bool, originCredentialsMode common.CredentialsMode, targetCredentialsMode common.CredentialsMode) {
 	authEnabledOnOrigin, err := originControlConn.IsAuthEnabled()
 	clusterType := common.ClusterTypeOrigin
 	var authEnabledOnTarget bool
@@ -2085,17 +3639,49 @@ func forwardAuthToTarget(
 			"receive the auth credentials from the client. Falling back to sending auth to %v and assuming "+
 			"that client credentials are meant for %v. "+
 			"This is a bug, please report: %v", clusterType, common.ClusterTypeOrigin, common.ClusterTypeTarget, err)
-		return false, true
+		return false, common.CredentialsModeConfigured, common.CredentialsModeClient
 	}
 
-	// only use forwardClientCredsToOrigin setting if we need creds for both,
+	// only use the credentials modes if we need creds for both,
 	// otherwise just forward the client creds to the only cluster that asked for them
 	if !authEnabledOnOrigin && authEnabledOnTarget {
-		return true, true
+		return true, common.CredentialsModeConfigured, common.CredentialsModeClient
 	} else if authEnabledOnOrigin && !authEnabledOnTarget {
-		return false, false
+		return false, common.CredentialsModeClient, common.CredentialsModeConfigured
 	} else {
-		return false, !forwardClientCredsToOrigin
+		originCredsMode, targetCredsMode = resolveCredentialsModes(
+			forwardClientCredsToOrigin, originCredsMode, targetCredsMode)
+		return false, originCredsMode, targetCredsMode
+	}
+}
+
+// resolveCredentialsModes replaces the AUTO credentials modes: if both clusters are AUTO the client credentials are
+// forwarded to TARGET (or ORIGIN with ZDM_FORWARD_CLIENT_CREDENTIALS_TO_ORIGIN), otherwise an AUTO cluster gets the
+// credentials that the other cluster doesn't get.
+func resolveCredentialsModes(
+	forwardClientCredsToOrigin bool,
+	originCredsMode common.CredentialsMode,
+	targetCredsMode common.CredentialsMode) (common.CredentialsMode, common.CredentialsMode) {
+	opposite := func(mode common.CredentialsMode) common.CredentialsMode {
+		if mode == common.CredentialsModeClient {
+			return common.CredentialsModeConfigured
+		}
+		return common.CredentialsModeClient
+	}
+	originAuto := originCredsMode == common.CredentialsModeAuto
+	targetAuto := targetCredsMode == common.CredentialsModeAuto
+	switch {
+	case originAuto && targetAuto:
+		if forwardClientCredsToOrigin {
+			return common.CredentialsModeClient, common.CredentialsModeConfigured
+		}
+		return common.CredentialsModeConfigured, common.CredentialsModeClient
+	case originAuto:
+		return opposite(targetCredsMode), targetCredsMode
+	case targetAuto:
+		return originCredsMode, opposite(originCredsMode)
+	default:
+		return originCredsMode, targetCredsMode
 	}
 }
 
@@ -2120,8 +3706,9 @@ func checkUnsupportedProtocolError(err error) *message.ProtocolError {
 }
 
 // checkProtocolVersion handles the case where the protocol library does not return an error but the proxy does not support a specific version
-func checkProtocolVersion(version primitive.ProtocolVersion) *message.ProtocolError {
-	if version < primitive.ProtocolVersion5 || version.IsDse() {
+// (i.e. versions that are higher than ZDM_PROXY_MAX_PROTOCOL_VERSION)
+func checkProtocolVersion(version primitive.ProtocolVersion, maxVersion primitive.ProtocolVersion) *message.ProtocolError {
+	if version <= maxVersion || version.IsDse() {
 		return nil
 	}
 
@@ -2156,6 +3743,10 @@ func (recv *protocolEventObserverImpl) OnHostRemoved(host *Host) {
 	}
 }
 
+// OnHostDown doesn't close the connection, the cluster connection fails on its own if the host is really down.
+func (recv *protocolEventObserverImpl) OnHostDown(host *Host) {
+}
+
 func (recv *protocolEventObserverImpl) GetHost() *Host {
 	return recv.connectionHost
 }
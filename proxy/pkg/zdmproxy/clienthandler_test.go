@@ -0,0 +1,54 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTrackClusterErrorMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		errorMsg message.Error
+		counter  func(instance *metrics.NodeMetricsInstance) metrics.Counter
+	}{
+		{"read timeout", &message.ReadTimeout{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.ReadTimeouts }},
+		{"write timeout", &message.WriteTimeout{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.WriteTimeouts }},
+		{"unavailable", &message.Unavailable{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.UnavailableErrors }},
+		{"overloaded", &message.Overloaded{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.OverloadedErrors }},
+		{"unprepared", &message.Unprepared{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.UnpreparedErrors }},
+		{"syntax error", &message.SyntaxError{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.SyntaxErrors }},
+		{"authentication error", &message.AuthenticationError{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.AuthErrors }},
+		{"unauthorized", &message.Unauthorized{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.AuthErrors }},
+		{"invalid", &message.Invalid{},
+			func(instance *metrics.NodeMetricsInstance) metrics.Counter { return instance.OtherErrors }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &metrics.NodeMetricsInstance{
+				ReadTimeouts:      &countingCounter{},
+				WriteTimeouts:     &countingCounter{},
+				UnavailableErrors: &countingCounter{},
+				OverloadedErrors:  &countingCounter{},
+				UnpreparedErrors:  &countingCounter{},
+				SyntaxErrors:      &countingCounter{},
+				AuthErrors:        &countingCounter{},
+				OtherErrors:       &countingCounter{},
+			}
+			trackClusterErrorMetricsFromErrorMessage(
+				tt.errorMsg, ClusterConnectorTypeTarget, &metrics.NodeMetrics{TargetMetrics: instance})
+			require.Equal(t, int64(1), atomic.LoadInt64(&tt.counter(instance).(*countingCounter).count))
+		})
+	}
+}
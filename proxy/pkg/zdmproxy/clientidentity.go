@@ -0,0 +1,59 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	log "github.com/sirupsen/logrus"
+)
+
+// clientIdentity is the application identity that modern drivers send in the STARTUP options, it is attached
+// to the logs of the client connection so that they can be attributed to applications without IP lookups.
+type clientIdentity struct {
+	applicationName    string
+	applicationVersion string
+	clientId           string
+	driverName         string
+	driverVersion      string
+}
+
+func newClientIdentity(startup *message.Startup) *clientIdentity {
+	return &clientIdentity{
+		applicationName:    startup.Options[message.StartupOptionApplicationName],
+		applicationVersion: startup.Options[message.StartupOptionApplicationVersion],
+		clientId:           startup.Options[message.StartupOptionClientId],
+		driverName:         startup.Options[message.StartupOptionDriverName],
+		driverVersion:      startup.Options[message.StartupOptionDriverVersion],
+	}
+}
+
+// decodeClientIdentity returns an empty identity if the STARTUP request can not be decoded.
+func decodeClientIdentity(startupRequest *frame.RawFrame) *clientIdentity {
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(startupRequest)
+	if err != nil {
+		log.Warnf("Could not decode STARTUP request to find the identity of the client: %v", err)
+		return &clientIdentity{}
+	}
+	startup, ok := decodedFrame.Body.Message.(*message.Startup)
+	if !ok {
+		log.Warnf("Expected STARTUP request but got %v, can not find the identity of the client.",
+			decodedFrame.Body.Message)
+		return &clientIdentity{}
+	}
+	return newClientIdentity(startup)
+}
+
+// fields returns the log fields of the options that were sent by the client.
+func (recv *clientIdentity) fields() log.Fields {
+	fields := log.Fields{}
+	addField := func(name string, value string) {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	addField("application", recv.applicationName)
+	addField("application_version", recv.applicationVersion)
+	addField("client_id", recv.clientId)
+	addField("driver", recv.driverName)
+	addField("driver_version", recv.driverVersion)
+	return fields
+}
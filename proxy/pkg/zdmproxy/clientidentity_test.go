@@ -0,0 +1,40 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDecodeClientIdentity(t *testing.T) {
+	startup := message.NewStartup(
+		message.StartupOptionApplicationName, "orders-service",
+		message.StartupOptionApplicationVersion, "1.2.0",
+		message.StartupOptionClientId, "2cc0d548-5a3a-4dde-a519-f0b43c2aa40a",
+		message.StartupOptionDriverName, "DataStax Java Driver",
+		message.StartupOptionDriverVersion, "4.14.1")
+	identity := decodeClientIdentity(mockFrame(t, startup, primitive.ProtocolVersion4))
+	require.Equal(t, &clientIdentity{
+		applicationName:    "orders-service",
+		applicationVersion: "1.2.0",
+		clientId:           "2cc0d548-5a3a-4dde-a519-f0b43c2aa40a",
+		driverName:         "DataStax Java Driver",
+		driverVersion:      "4.14.1",
+	}, identity)
+	require.Equal(t, log.Fields{
+		"application":         "orders-service",
+		"application_version": "1.2.0",
+		"client_id":           "2cc0d548-5a3a-4dde-a519-f0b43c2aa40a",
+		"driver":              "DataStax Java Driver",
+		"driver_version":      "4.14.1",
+	}, identity.fields())
+
+	identity = decodeClientIdentity(mockFrame(t, message.NewStartup(), primitive.ProtocolVersion4))
+	require.Equal(t, &clientIdentity{}, identity)
+	require.Equal(t, log.Fields{}, identity.fields())
+
+	identity = decodeClientIdentity(mockQueryFrame(t, "SELECT * FROM ks.tb"))
+	require.Equal(t, &clientIdentity{}, identity)
+}
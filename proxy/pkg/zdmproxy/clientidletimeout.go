@@ -0,0 +1,67 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"sync/atomic"
+	"time"
+)
+
+// clientIdleTimeoutChecksPerTimeout is the number of times a client connection is checked during the idle timeout.
+const clientIdleTimeoutChecksPerTimeout = 4
+
+// clientIdleTimeout tracks the last request of a client connection (ZDM_PROXY_CLIENT_IDLE_TIMEOUT_MS). The OPTIONS
+// requests are ignored because the drivers send them as heartbeats on their idle connections, so a connection that
+// only sends heartbeats is idle.
+type clientIdleTimeout struct {
+	timeout           time.Duration
+	closedConnections metrics.Counter
+
+	lastActivity int64 // unix nanos
+}
+
+// newClientIdleTimeout returns nil if the idle client connections are never closed.
+func newClientIdleTimeout(timeout time.Duration, closedConnections metrics.Counter) *clientIdleTimeout {
+	if timeout <= 0 {
+		return nil
+	}
+	return &clientIdleTimeout{
+		timeout:           timeout,
+		closedConnections: closedConnections,
+		lastActivity:      time.Now().UnixNano(),
+	}
+}
+
+// touch records the provided request of the client connection.
+func (recv *clientIdleTimeout) touch(request *frame.RawFrame, now time.Time) {
+	if request.Header.OpCode == primitive.OpCodeOptions {
+		return
+	}
+	atomic.StoreInt64(&recv.lastActivity, now.UnixNano())
+}
+
+func (recv *clientIdleTimeout) isIdle(now time.Time) bool {
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&recv.lastActivity))) >= recv.timeout
+}
+
+// run calls the provided function once the client connection is idle or returns when the context is canceled.
+func (recv *clientIdleTimeout) run(ctx context.Context, onIdle func()) {
+	ticker := time.NewTicker(recv.timeout / clientIdleTimeoutChecksPerTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if recv.isIdle(now) {
+				if recv.closedConnections != nil {
+					recv.closedConnections.Add(1)
+				}
+				onIdle()
+				return
+			}
+		}
+	}
+}
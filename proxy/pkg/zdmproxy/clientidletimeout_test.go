@@ -0,0 +1,52 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestClientIdleTimeout(t *testing.T) {
+	require.Nil(t, newClientIdleTimeout(0, nil))
+
+	idleTimeout := newClientIdleTimeout(time.Minute, &countingCounter{})
+	now := time.Now()
+	idleTimeout.touch(mockQueryFrame(t, "SELECT * FROM ks.tb"), now)
+	require.False(t, idleTimeout.isIdle(now.Add(59*time.Second)))
+
+	// the heartbeats of the client don't count
+	idleTimeout.touch(mockFrame(t, &message.Options{}, primitive.ProtocolVersion4), now.Add(30*time.Second))
+	require.True(t, idleTimeout.isIdle(now.Add(time.Minute)))
+
+	idleTimeout.touch(mockFrame(t, &message.Register{EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}},
+		primitive.ProtocolVersion4), now.Add(30*time.Second))
+	require.False(t, idleTimeout.isIdle(now.Add(time.Minute)))
+}
+
+func TestClientIdleTimeout_Run(t *testing.T) {
+	closed := &countingCounter{}
+	idleTimeout := newClientIdleTimeout(100*time.Millisecond, closed)
+
+	idle := make(chan bool, 1)
+	go idleTimeout.run(context.Background(), func() { idle <- true })
+	select {
+	case <-idle:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle client connection was not detected")
+	}
+	require.Equal(t, int64(1), closed.count)
+
+	// the client connection is closed before it becomes idle
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		newClientIdleTimeout(time.Hour, closed).run(ctx, func() { idle <- true })
+		close(done)
+	}()
+	cancelFn()
+	<-done
+	require.Equal(t, int64(1), closed.count)
+}
@@ -0,0 +1,80 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"math"
+	"sync"
+	"time"
+)
+
+// clientRequestLimiter limits the QUERY, EXECUTE and BATCH requests of a client connection
+// (ZDM_PROXY_MAX_CLIENT_REQUESTS_PER_SECOND and ZDM_PROXY_MAX_CLIENT_IN_FLIGHT_REQUESTS). The rate is limited with a
+// token bucket that holds up to one second of requests, the in flight requests are tracked by stream id until their
+// response is sent to the client.
+type clientRequestLimiter struct {
+	requestsPerSecond   float64 // 0 if the rate is unlimited
+	maxInFlightRequests int     // 0 if the in flight requests are unlimited
+	rejectedRequests    metrics.Counter
+
+	lock       *sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+	inFlight   map[int16]struct{}
+}
+
+// newClientRequestLimiter returns nil if the requests of the client connections are not limited.
+func newClientRequestLimiter(
+	config *common.ClientRequestLimitConfig, rejectedRequests metrics.Counter) *clientRequestLimiter {
+	if !config.Enabled() {
+		return nil
+	}
+	return &clientRequestLimiter{
+		requestsPerSecond:   float64(config.MaxRequestsPerSecond),
+		maxInFlightRequests: config.MaxInFlightRequests,
+		rejectedRequests:    rejectedRequests,
+		lock:                &sync.Mutex{},
+		tokens:              math.Max(float64(config.MaxRequestsPerSecond), 1), // the bucket starts full
+		lastRefill:          time.Now(),
+		inFlight:            make(map[int16]struct{}),
+	}
+}
+
+// tryAcquire returns the reason why the request must be rejected or an empty string if it can be sent,
+// in which case release must be called with its stream id once its response is sent to the client.
+func (recv *clientRequestLimiter) tryAcquire(request *frame.RawFrame, now time.Time) string {
+	if !isRateLimitedRequest(request) {
+		return ""
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.maxInFlightRequests > 0 && len(recv.inFlight) >= recv.maxInFlightRequests {
+		recv.rejectedRequests.Add(1)
+		return "maximum number of in flight requests reached"
+	}
+	if recv.requestsPerSecond > 0 {
+		burst := math.Max(recv.requestsPerSecond, 1)
+		recv.tokens = math.Min(burst, recv.tokens+now.Sub(recv.lastRefill).Seconds()*recv.requestsPerSecond)
+		recv.lastRefill = now
+		if recv.tokens < 1 {
+			recv.rejectedRequests.Add(1)
+			return "maximum rate of requests reached"
+		}
+		recv.tokens--
+	}
+	if recv.maxInFlightRequests > 0 {
+		recv.inFlight[request.Header.StreamId] = struct{}{}
+	}
+	return ""
+}
+
+// release frees the in flight slot of the request with the provided stream id, if any.
+func (recv *clientRequestLimiter) release(streamId int16) {
+	if recv.maxInFlightRequests <= 0 {
+		return
+	}
+	recv.lock.Lock()
+	delete(recv.inFlight, streamId)
+	recv.lock.Unlock()
+}
@@ -0,0 +1,62 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newLimitedRequest(t *testing.T, streamId int16, msg message.Message) *frame.RawFrame {
+	f, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, streamId, msg))
+	require.Nil(t, err)
+	return f
+}
+
+func TestClientRequestLimiter_Disabled(t *testing.T) {
+	require.Nil(t, newClientRequestLimiter(&common.ClientRequestLimitConfig{}, &countingCounter{}))
+}
+
+func TestClientRequestLimiter_RequestsPerSecond(t *testing.T) {
+	rejected := &countingCounter{}
+	limiter := newClientRequestLimiter(&common.ClientRequestLimitConfig{MaxRequestsPerSecond: 2}, rejected)
+	query := &message.Query{Query: "SELECT * FROM ks.tb"}
+
+	now := time.Now()
+	require.Equal(t, "", limiter.tryAcquire(newLimitedRequest(t, 1, query), now))
+	require.Equal(t, "", limiter.tryAcquire(newLimitedRequest(t, 2, query), now))
+	require.Equal(t, "maximum rate of requests reached", limiter.tryAcquire(newLimitedRequest(t, 3, query), now))
+	// the other requests are never rejected
+	require.Equal(t, "", limiter.tryAcquire(newLimitedRequest(t, 4, &message.Prepare{Query: "SELECT * FROM ks.tb"}), now))
+	require.Equal(t, int64(1), rejected.count)
+
+	require.Equal(t, "", limiter.tryAcquire(newLimitedRequest(t, 3, query), now.Add(500*time.Millisecond)))
+	require.Equal(t, "maximum rate of requests reached",
+		limiter.tryAcquire(newLimitedRequest(t, 5, query), now.Add(500*time.Millisecond)))
+	require.Equal(t, int64(2), rejected.count)
+}
+
+func TestClientRequestLimiter_InFlightRequests(t *testing.T) {
+	rejected := &countingCounter{}
+	limiter := newClientRequestLimiter(&common.ClientRequestLimitConfig{MaxInFlightRequests: 2}, rejected)
+	query := &message.Query{Query: "SELECT * FROM ks.tb"}
+
+	now := time.Now()
+	require.Equal(t, "", limiter.tryAcquire(newLimitedRequest(t, 1, query), now))
+	require.Equal(t, "", limiter.tryAcquire(newLimitedRequest(t, 2, query), now))
+	require.Equal(t, "maximum number of in flight requests reached",
+		limiter.tryAcquire(newLimitedRequest(t, 3, query), now))
+	require.Equal(t, int64(1), rejected.count)
+
+	// releasing a stream id that is not in flight (e.g. an event) does not free a slot
+	limiter.release(-1)
+	require.Equal(t, "maximum number of in flight requests reached",
+		limiter.tryAcquire(newLimitedRequest(t, 3, query), now))
+
+	limiter.release(1)
+	require.Equal(t, "", limiter.tryAcquire(newLimitedRequest(t, 3, query), now))
+	require.Equal(t, int64(2), rejected.count)
+}
@@ -24,6 +24,20 @@ type ClusterConnectionInfo struct {
 	connConfig        ConnectionConfig
 	endpoint          Endpoint
 	isOriginCassandra bool
+	connPool          *clusterConnPool // nil if cluster connections are not pooled
+	requestLimiter    *requestLimiter  // nil if there is no concurrent request limit for this cluster
+	rateLimiter       *rateLimiter     // nil if there is no request rate limit for this cluster
+	circuitBreaker    *circuitBreaker  // nil if the circuit breaker of this cluster is disabled
+
+	// failoverControlConn provides the nodes that the connection can fail over to,
+	// nil if the connection failover is disabled or if the cluster connections are pooled
+	failoverControlConn *ControlConn
+
+	tokenRouter *tokenRouter // nil if token aware routing is disabled (it requires pooled cluster connections)
+
+	// perClientConnections opens the connections of the cluster connector when it has more than one connection,
+	// nil if ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT is 1 or if the cluster connections are pooled
+	perClientConnections *perClientConnections
 }
 
 type ClusterConnectorType string
@@ -62,25 +76,66 @@ type ClusterConnector struct {
 
 	responseReadBufferSizeBytes int
 	writeCoalescer              *writeCoalescer
+	framing                     *connFraming
 	doneChan                    chan bool
 
 	handshakeDone *atomic.Value
 
+	maxProtocolVersion primitive.ProtocolVersion
+
 	asyncConnector       bool
 	asyncConnectorState  ConnectorState
 	asyncPendingRequests *pendingRequests
 
 	readScheduler *Scheduler
+
+	requestLimiter  *requestLimiter
+	limiterLock     *sync.RWMutex
+	limiterClosed   bool
+	limiterSlots    int32           // number of requests sent through requestLimiter that are waiting for a response
+	limiterSenders  *sync.WaitGroup // OVERLOADED responses that are being sent to the response channel
+	handOffLock     *sync.Mutex
+	handOffs        []*limitedRequest // requests that requestLimiter handed to this connector, see runRequestLimiterLoop
+	handOffNotify   chan bool
+	limiterStop     chan bool
+	limiterStopOnce *sync.Once
+	limiterDone     chan bool
+
+	rateLimiter *rateLimiter
+
+	circuitBreaker *circuitBreaker
+
+	startupCompression primitive.Compression // replaces the compression requested by the client if not empty
+
+	connectionId uint64 // correlation id of the client connection, see newConnectionLogEntry
+	logEntry     *log.Entry
 }
 
-func NewClusterConnectionInfo(connConfig ConnectionConfig, endpointConfig Endpoint, isOriginCassandra bool) *ClusterConnectionInfo {
+func NewClusterConnectionInfo(
+	connConfig ConnectionConfig, endpointConfig Endpoint, isOriginCassandra bool,
+	connPool *clusterConnPool, requestLimiter *requestLimiter, rateLimiter *rateLimiter,
+	circuitBreaker *circuitBreaker, failoverControlConn *ControlConn, tokenRouter *tokenRouter,
+	perClientConnections *perClientConnections) *ClusterConnectionInfo {
 	return &ClusterConnectionInfo{
-		connConfig:        connConfig,
-		endpoint:          endpointConfig,
-		isOriginCassandra: isOriginCassandra,
+		connConfig:           connConfig,
+		endpoint:             endpointConfig,
+		isOriginCassandra:    isOriginCassandra,
+		connPool:             connPool,
+		requestLimiter:       requestLimiter,
+		rateLimiter:          rateLimiter,
+		circuitBreaker:       circuitBreaker,
+		failoverControlConn:  failoverControlConn,
+		tokenRouter:          tokenRouter,
+		perClientConnections: perClientConnections,
 	}
 }
 
+// usesPipe returns true if the cluster connector is given one end of an in-memory pipe instead of the connection
+// to the cluster node, see clusterConnPool and failoverConn.
+func (recv *ClusterConnectionInfo) usesPipe() bool {
+	return recv.connPool != nil || recv.failoverControlConn != nil || recv.perClientConnections != nil
+}
+
 func NewClusterConnector(
 	connInfo *ClusterConnectionInfo,
 	conf *config.Config,
@@ -96,23 +151,33 @@ func NewClusterConnector(
 	requestsDoneCtx context.Context,
 	asyncConnector bool,
 	asyncPendingRequests *pendingRequests,
-	handshakeDone *atomic.Value) (*ClusterConnector, error) {
+	handshakeDone *atomic.Value,
+	maxProtocolVersion primitive.ProtocolVersion,
+	stallWatchdog *stallWatchdog,
+	connectionId uint64) (*ClusterConnector, error) {
 
 	var connectorType ClusterConnectorType
 	var clusterType common.ClusterType
+	var startupCompression primitive.Compression
+	var err error
 	if connInfo.isOriginCassandra {
 		clusterType = common.ClusterTypeOrigin
 		connectorType = ClusterConnectorTypeOrigin
+		startupCompression, err = conf.ParseOriginCompression()
 	} else {
 		clusterType = common.ClusterTypeTarget
 		connectorType = ClusterConnectorTypeTarget
+		startupCompression, err = conf.ParseTargetCompression()
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	if asyncConnector {
 		connectorType = ClusterConnectorTypeAsync
 	}
 
-	conn, timeoutCtx, err := openConnectionToCluster(connInfo, clientHandlerContext, connectorType, nodeMetrics)
+	conn, timeoutCtx, err := openConnectionToCluster(connInfo, conf, clientHandlerContext, connectorType, nodeMetrics)
 	if err != nil {
 		if errors.Is(err, ShutdownErr) {
 			if timeoutCtx.Err() != nil {
@@ -130,10 +195,16 @@ func NewClusterConnector(
 			clusterConnCancelFn()
 		case <-clusterConnCtx.Done():
 		}
-		closeConnectionToCluster(conn, clusterType, connectorType, nodeMetrics)
+		closeConnectionToCluster(conn, clusterType, connectorType, nodeMetrics, connInfo.usesPipe())
 	}()
 
 	cancelFn := clusterConnCancelFn
+	var framing *connFraming
+	if !connInfo.usesPipe() {
+		// the pool (or failoverConn) handles the framing layout of the cluster connections, the pipe always uses
+		// the legacy layout
+		framing = newConnFraming()
+	}
 	var clusterConnEventsChan chan *frame.RawFrame
 	if !asyncConnector {
 		cancelFn = clientHandlerCancelFunc
@@ -161,7 +232,10 @@ func NewClusterConnector(
 			string(connectorType),
 			true,
 			asyncConnector,
-			writeScheduler),
+			writeScheduler,
+			stallWatchdog,
+			framing),
+		framing:                     framing,
 		responseChan:                responseChan,
 		responseReadBufferSizeBytes: conf.ResponseReadBufferSizeBytes,
 		doneChan:                    make(chan bool),
@@ -170,17 +244,68 @@ func NewClusterConnector(
 		asyncConnectorState:         ConnectorStateHandshake,
 		asyncPendingRequests:        asyncPendingRequests,
 		handshakeDone:               handshakeDone,
+		maxProtocolVersion:          maxProtocolVersion,
+		requestLimiter:              connInfo.requestLimiter,
+		limiterLock:                 &sync.RWMutex{},
+		limiterClosed:               false,
+		limiterSlots:                0,
+		limiterSenders:              &sync.WaitGroup{},
+		handOffLock:                 &sync.Mutex{},
+		handOffs:                    nil,
+		handOffNotify:               make(chan bool, 1),
+		limiterStop:                 make(chan bool),
+		limiterStopOnce:             &sync.Once{},
+		limiterDone:                 make(chan bool),
+		rateLimiter:                 connInfo.rateLimiter,
+		circuitBreaker:              connInfo.circuitBreaker,
+		startupCompression:          startupCompression,
+		connectionId:                connectionId,
+		logEntry:                    newConnectionLogEntry(connectionId),
 	}, nil
 }
 
 func (cc *ClusterConnector) run() {
+	cc.runRequestLimiterLoop()
 	cc.runResponseListeningLoop()
 	cc.writeCoalescer.RunWriteQueueLoop()
 }
 
-func openConnectionToCluster(connInfo *ClusterConnectionInfo, context context.Context, connectorType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics) (net.Conn, context.Context, error) {
+func openConnectionToCluster(connInfo *ClusterConnectionInfo, conf *config.Config, context context.Context, connectorType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics) (net.Conn, context.Context, error) {
 	clusterType := connInfo.connConfig.GetClusterType()
 	log.Infof("[%s] Opening request connection to %v (%v).", connectorType, clusterType, connInfo.endpoint.GetEndpointIdentifier())
+	if connInfo.connPool != nil {
+		// the pool tracks the open connections metric because it owns the actual connections
+		conn, timeoutCtx, err := connInfo.connPool.connect(connInfo, context, connectorType, nodeMetrics)
+		if err != nil {
+			return nil, timeoutCtx, err
+		}
+		log.Infof("[%s] Pooled request connection to %v (%v) has been opened.", connectorType, clusterType, conn.RemoteAddr())
+		return conn, timeoutCtx, nil
+	}
+
+	if connInfo.perClientConnections != nil {
+		// the private pool tracks the open connections metric because it owns the actual connections
+		pool := connInfo.perClientConnections.newPool()
+		conn, timeoutCtx, err := pool.connect(connInfo, context, connectorType, nodeMetrics)
+		if err != nil {
+			pool.Shutdown()
+			return nil, timeoutCtx, err
+		}
+		log.Infof("[%s] Request connection to %v (%v) has been opened, %d connections will be used.",
+			connectorType, clusterType, conn.RemoteAddr(), connInfo.perClientConnections.size)
+		return conn, timeoutCtx, nil
+	}
+
+	if connInfo.failoverControlConn != nil {
+		// failoverConn tracks the open connections metric because it owns the actual connections
+		conn, timeoutCtx, err := connectWithFailover(connInfo, conf, context, connectorType, nodeMetrics)
+		if err != nil {
+			return nil, timeoutCtx, err
+		}
+		log.Infof("[%s] Request connection to %v (%v) has been opened with failover.", connectorType, clusterType, conn.RemoteAddr())
+		return conn, timeoutCtx, nil
+	}
+
 	conn, timeoutCtx, err := openConnection(connInfo.connConfig, connInfo.endpoint, context, true)
 	if err != nil {
 		return nil, timeoutCtx, err
@@ -197,13 +322,19 @@ func openConnectionToCluster(connInfo *ClusterConnectionInfo, context context.Co
 	return conn, timeoutCtx, nil
 }
 
-func closeConnectionToCluster(conn net.Conn, clusterType common.ClusterType, connectorClusterType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics) {
+func closeConnectionToCluster(
+	conn net.Conn, clusterType common.ClusterType, connectorClusterType ClusterConnectorType, nodeMetrics *metrics.NodeMetrics, pooled bool) {
 	log.Infof("[%s] Closing request connection to %v (%v)", connectorClusterType, clusterType, conn.RemoteAddr())
 	err := conn.Close()
 	if err != nil {
 		log.Warnf("[%s] Error closing connection to %v (%v)", connectorClusterType, clusterType, conn.RemoteAddr())
 	}
 
+	if pooled {
+		log.Infof("[%s] Pooled request connection to %v (%v) has been closed", connectorClusterType, clusterType, conn.RemoteAddr())
+		return
+	}
+
 	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorClusterType)
 	if err != nil {
 		log.Errorf("Failed to subtract open connection metrics for conn %v: %v.", conn.RemoteAddr().String(), err)
@@ -220,7 +351,7 @@ func closeConnectionToCluster(conn net.Conn, clusterType common.ClusterType, con
 func (cc *ClusterConnector) runResponseListeningLoop() {
 
 	cc.clientHandlerWg.Add(1)
-	log.Debugf("[%s] Listening to replies sent by node %v", cc.connectorType, cc.connection.RemoteAddr())
+	cc.getLogger().Debugf("[%s] Listening to replies sent by node %v", cc.connectorType, cc.connection.RemoteAddr())
 	go func() {
 		defer cc.clientHandlerWg.Done()
 		if cc.clusterConnEventsChan != nil {
@@ -228,6 +359,7 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 		}
 		defer close(cc.doneChan)
 		defer atomic.StoreInt32(&cc.asyncConnectorState, ConnectorStateShutdown)
+		defer cc.closeRequestLimiter(true)
 
 		bufferedReader := bufio.NewReaderSize(cc.connection, cc.responseReadBufferSizeBytes)
 		connectionAddr := cc.connection.RemoteAddr().String()
@@ -235,16 +367,16 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 		defer wg.Wait()
 		protocolErrOccurred := false
 		for {
-			response, err := readRawFrame(bufferedReader, connectionAddr, cc.clusterConnContext)
+			response, err := cc.framing.readFrame(bufferedReader, connectionAddr, cc.clusterConnContext)
 
-			protocolErrResponseFrame, err := checkProtocolError(response, err, protocolErrOccurred, string(cc.connectorType))
+			protocolErrResponseFrame, err := checkProtocolError(response, err, protocolErrOccurred, cc.maxProtocolVersion, string(cc.connectorType))
 			if err != nil {
 				handleConnectionError(
 					err, cc.clusterConnContext, cc.cancelFunc, string(cc.connectorType), "reading", connectionAddr)
 				break
 			} else {
 				if protocolErrOccurred {
-					log.Debugf("[%v] Data received after protocol error occured, ignoring it.", string(cc.connectorType))
+					cc.getLogger().Debugf("[%v] Data received after protocol error occured, ignoring it.", string(cc.connectorType))
 					continue
 				} else if protocolErrResponseFrame != nil {
 					response = protocolErrResponseFrame
@@ -252,12 +384,20 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 				}
 			}
 
+			if response.Header.OpCode != primitive.OpCodeEvent && !isIntermediateContinuousPage(response) {
+				cc.releaseRequestLimiterSlot()
+			}
+
 			wg.Add(1)
 			cc.readScheduler.Schedule(func() {
 				defer wg.Done()
 				log.Tracef("[%s] Received response from %v (%v): %v",
 					cc.connectorType, cc.clusterType, connectionAddr, response.Header)
 
+				if response.Header.OpCode == primitive.OpCodeResult || response.Header.OpCode == primitive.OpCodeError {
+					cc.circuitBreaker.recordResponse(response)
+				}
+
 				if cc.asyncConnector {
 					response = cc.handleAsyncResponse(response)
 					if response == nil {
@@ -273,23 +413,23 @@ func (cc *ClusterConnector) runResponseListeningLoop() {
 				log.Tracef("[%s] Response sent to response channel: %v", cc.connectorType, response.Header)
 			})
 		}
-		log.Debugf("[%s] Shutting down response listening loop from %v", cc.connectorType, connectionAddr)
+		cc.getLogger().Debugf("[%s] Shutting down response listening loop from %v", cc.connectorType, connectionAddr)
 	}()
 }
 
 func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame.RawFrame {
 	errMsg, err := decodeError(response)
 	if err != nil {
-		log.Errorf("[%s] Error occured while checking if error is a protocol error: %v.", cc.connectorType, err)
+		cc.getLogger().Errorf("[%s] Error occured while checking if error is a protocol error: %v.", cc.connectorType, err)
 		cc.Shutdown()
 		return nil
 	}
 
 	if errMsg != nil && errMsg.GetErrorCode() == primitive.ErrorCodeProtocolError {
 		if cc.handshakeDone.Load() != nil {
-			log.Errorf("[%s] Protocol error occured in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
+			cc.getLogger().Errorf("[%s] Protocol error occured in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
 		} else {
-			log.Debugf("[%s] Protocol version downgrade detected in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
+			cc.getLogger().Debugf("[%s] Protocol version downgrade detected in async connector, async requests will not be forwarded: %v.", cc.connectorType, errMsg)
 		}
 		cc.Shutdown()
 		return nil
@@ -303,12 +443,19 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 	if done {
 		typedReqCtx, ok := reqCtx.(*asyncRequestContextImpl)
 		if !ok {
-			log.Errorf("Failed to finish async request because request context conversion failed. "+
+			cc.getLogger().Errorf("Failed to finish async request because request context conversion failed. "+
 				"This is most likely a bug, please report. AsyncRequestContext: %v", reqCtx)
 		} else if typedReqCtx.expectedResponse {
 			response.Header.StreamId = typedReqCtx.requestStreamId
 			return response
+		} else if typedReqCtx.write != nil {
+			cc.handleAsyncWriteResponse(typedReqCtx, response, errMsg)
 		} else {
+			if _, unprepared := errMsg.(*message.Unprepared); unprepared {
+				typedReqCtx.readComparison.abandon()
+			} else {
+				typedReqCtx.readComparison.setSecondaryResponse(response)
+			}
 			callDone := true
 			if errMsg != nil {
 				if reqCtx.GetRequestInfo().ShouldBeTrackedInMetrics() {
@@ -316,39 +463,22 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 				}
 				switch msg := errMsg.(type) {
 				case *message.Unprepared:
-					var preparedData PreparedData
-					var ok bool
-					if cc.clusterType == common.ClusterTypeTarget {
-						preparedData, ok = cc.psCache.GetByTargetPreparedId(msg.Id)
-					} else {
-						preparedData, ok = cc.psCache.Get(msg.Id)
-					}
-					if !ok {
-						log.Warnf("Received UNPREPARED for async request with prepare ID %v "+
-							"but could not find prepared data.", hex.EncodeToString(msg.Id))
+					prepareRequestInfo, prepareRawFrame, err := cc.newAsyncPrepareRequest(msg, response)
+					if err != nil {
+						cc.getRequestLogger(typedReqCtx.requestStreamId).Warnf("Could not send async PREPARE: %v.", err)
 					} else {
-						prepare := &message.Prepare{
-							Query:    preparedData.GetPrepareRequestInfo().GetQuery(),
-							Keyspace: preparedData.GetPrepareRequestInfo().GetKeyspace(),
-						}
-						prepareFrame := frame.NewFrame(response.Header.Version, response.Header.StreamId, prepare)
-						prepareRawFrame, err := defaultCodec.ConvertToRawFrame(prepareFrame)
-						if err != nil {
-							log.Errorf("Could not send async PREPARE because convert raw frame failed: %v.", err.Error())
-						} else {
-							sent := cc.sendAsyncRequest(
-								preparedData.GetPrepareRequestInfo(), prepareRawFrame, false, time.Now(),
-								time.Duration(cc.conf.ProxyRequestTimeoutMs)*time.Millisecond,
-								func() {
-									cc.clientHandlerRequestWg.Done()
-								})
-							if sent {
-								callDone = false
-							}
+						sent := cc.sendAsyncRequest(
+							prepareRequestInfo, prepareRawFrame, false, time.Now(),
+							time.Duration(cc.conf.ProxyRequestTimeoutMs)*time.Millisecond,
+							func() {
+								cc.clientHandlerRequestWg.Done()
+							})
+						if sent {
+							callDone = false
 						}
 					}
 				default:
-					log.Warnf("Async Request failed with error code %v. Error message: %v", errMsg.GetErrorCode(), errMsg.GetErrorMessage())
+					cc.getRequestLogger(typedReqCtx.requestStreamId).Warnf("Async Request failed with error code %v. Error message: %v", errMsg.GetErrorCode(), errMsg.GetErrorMessage())
 				}
 			}
 
@@ -360,10 +490,61 @@ func (cc *ClusterConnector) handleAsyncResponse(response *frame.RawFrame) *frame
 	return nil
 }
 
+// newAsyncPrepareRequest returns the PREPARE request that prepares the statement of an UNPREPARED error that was
+// received by the async connector.
+func (cc *ClusterConnector) newAsyncPrepareRequest(
+	msg *message.Unprepared, response *frame.RawFrame) (*PrepareRequestInfo, *frame.RawFrame, error) {
+	var preparedData PreparedData
+	var ok bool
+	if cc.clusterType == common.ClusterTypeTarget {
+		preparedData, ok = cc.psCache.GetByTargetPreparedId(msg.Id)
+	} else {
+		preparedData, ok = cc.psCache.Get(msg.Id)
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("received UNPREPARED for async request with prepare ID %v "+
+			"but could not find prepared data", hex.EncodeToString(msg.Id))
+	}
+	prepare := &message.Prepare{
+		Query:    preparedData.GetPrepareRequestInfo().GetQuery(),
+		Keyspace: preparedData.GetPrepareRequestInfo().GetKeyspace(),
+	}
+	prepareFrame := frame.NewFrame(response.Header.Version, response.Header.StreamId, prepare)
+	prepareRawFrame, err := defaultCodec.ConvertToRawFrame(prepareFrame)
+	if err != nil {
+		return nil, nil, fmt.Errorf("convert raw frame failed: %w", err)
+	}
+	return preparedData.GetPrepareRequestInfo(), prepareRawFrame, nil
+}
+
 func (cc *ClusterConnector) sendRequestToCluster(frame *frame.RawFrame) {
+	frame = cc.overrideStartupCompression(frame)
+	if cc.rateLimiter != nil && isRateLimitedRequest(frame) && !cc.rateLimiter.tryAcquire() {
+		cc.rejectRateLimitedRequest(frame)
+		return
+	}
+	if cc.requestLimiter != nil {
+		cc.requestLimiter.send(cc, frame)
+		return
+	}
 	cc.writeCoalescer.Enqueue(frame)
 }
 
+// overrideStartupCompression returns a copy of the provided STARTUP request that requests the compression of
+// ZDM_<CLUSTER>_COMPRESSION, the other requests are returned as is.
+func (cc *ClusterConnector) overrideStartupCompression(request *frame.RawFrame) *frame.RawFrame {
+	if cc.startupCompression == "" || request.Header.OpCode != primitive.OpCodeStartup {
+		return request
+	}
+	newRequest, err := withStartupCompression(request, cc.startupCompression)
+	if err != nil {
+		cc.getLogger().Warnf("[%s] Could not override the compression of the STARTUP request to %v, "+
+			"forwarding it unchanged: %v.", cc.connectorType, cc.clusterType, err)
+		return request
+	}
+	return newRequest
+}
+
 func (cc *ClusterConnector) validateAsyncStateForRequest(frame *frame.RawFrame) bool {
 	state := atomic.LoadInt32(&cc.asyncConnectorState)
 	switch state {
@@ -376,26 +557,230 @@ func (cc *ClusterConnector) validateAsyncStateForRequest(frame *frame.RawFrame)
 		case primitive.OpCodeStartup, primitive.OpCodeAuthResponse, primitive.OpCodeOptions:
 			return true
 		default:
-			log.Debugf("[%s] Discarding async %v request because async connector is not ready.",
+			cc.getRequestLogger(frame.Header.StreamId).Debugf("[%s] Discarding async %v request because async connector is not ready.",
 				cc.connectorType, frame.Header.OpCode.String())
 			return false
 		}
 	case ConnectorStateReady:
 		return true
 	default:
-		log.Errorf("Unknown cluster connector state: %v. This is a bug, please report.", state)
+		cc.getLogger().Errorf("Unknown cluster connector state: %v. This is a bug, please report.", state)
 		return false
 	}
 }
 
 func (cc *ClusterConnector) sendAsyncRequestToCluster(frame *frame.RawFrame) bool {
+	frame = cc.overrideStartupCompression(frame)
+	if cc.rateLimiter != nil && isRateLimitedRequest(frame) && !cc.rateLimiter.tryAcquire() {
+		log.Tracef("[%s] Discarding async %v request because the maximum rate of requests to %v was reached.",
+			cc.connectorType, frame.Header.OpCode.String(), cc.clusterType)
+		return false
+	}
+	if isRateLimitedRequest(frame) && !cc.circuitBreaker.isClosed() {
+		log.Tracef("[%s] Discarding async %v request because the circuit breaker of %v is open.",
+			cc.connectorType, frame.Header.OpCode.String(), cc.clusterType)
+		return false
+	}
+	if cc.requestLimiter != nil {
+		return cc.requestLimiter.trySend(cc, frame)
+	}
 	return cc.writeCoalescer.EnqueueAsync(frame)
 }
 
+// dispatchLimitedRequest writes a request that was given a slot by the request limiter, it is only called by the
+// goroutines that are done before the write coalescer is closed (request handlers and runRequestLimiterLoop).
+// Returns false if the request was not written because this connector is shutting down.
+func (cc *ClusterConnector) dispatchLimitedRequest(frame *frame.RawFrame) bool {
+	cc.limiterLock.RLock()
+	if cc.limiterClosed {
+		cc.limiterLock.RUnlock()
+		return false
+	}
+	atomic.AddInt32(&cc.limiterSlots, 1)
+	cc.limiterLock.RUnlock()
+
+	cc.writeCoalescer.Enqueue(frame)
+	return true
+}
+
+// limitedRequest is a queued request that the request limiter handed to the connector that owns it.
+type limitedRequest struct {
+	request      *frame.RawFrame
+	rejectReason string // empty if the request was given a slot
+}
+
+// handOffLimitedRequest queues a request of the request limiter queue that was given a slot (or rejected if
+// rejectReason is not empty) for runRequestLimiterLoop, it never blocks.
+// Returns false if the request was not queued because this connector is shutting down.
+func (cc *ClusterConnector) handOffLimitedRequest(request *frame.RawFrame, rejectReason string) bool {
+	cc.limiterLock.RLock()
+	defer cc.limiterLock.RUnlock()
+	if cc.limiterClosed {
+		return false
+	}
+	cc.handOffLock.Lock()
+	cc.handOffs = append(cc.handOffs, &limitedRequest{request: request, rejectReason: rejectReason})
+	cc.handOffLock.Unlock()
+	select {
+	case cc.handOffNotify <- true:
+	default:
+	}
+	return true
+}
+
+func (cc *ClusterConnector) takeHandOffs() []*limitedRequest {
+	cc.handOffLock.Lock()
+	defer cc.handOffLock.Unlock()
+	handOffs := cc.handOffs
+	cc.handOffs = nil
+	return handOffs
+}
+
+// runRequestLimiterLoop writes the requests that the request limiter handed to this connector and sends the
+// OVERLOADED responses of the ones that it rejected until closeRequestLimiter is called.
+func (cc *ClusterConnector) runRequestLimiterLoop() {
+	if cc.requestLimiter == nil {
+		return
+	}
+	go func() {
+		defer close(cc.limiterDone)
+		for {
+			select {
+			case <-cc.handOffNotify:
+			case <-cc.limiterStop:
+				// the slots of the requests that won't be written are given to the other connectors
+				notSent := 0
+				for _, limited := range cc.takeHandOffs() {
+					if limited.rejectReason == "" {
+						notSent++
+					}
+				}
+				if notSent > 0 {
+					cc.requestLimiter.release(notSent)
+				}
+				return
+			}
+
+			for _, limited := range cc.takeHandOffs() {
+				if limited.rejectReason != "" {
+					cc.rejectLimitedRequest(limited.request, limited.rejectReason)
+				} else if !cc.dispatchLimitedRequest(limited.request) {
+					cc.requestLimiter.release(1)
+				}
+			}
+		}
+	}()
+}
+
+func (cc *ClusterConnector) dispatchLimitedAsyncRequest(frame *frame.RawFrame) bool {
+	cc.limiterLock.RLock()
+	defer cc.limiterLock.RUnlock()
+	if cc.limiterClosed {
+		return false
+	}
+	atomic.AddInt32(&cc.limiterSlots, 1)
+	if !cc.writeCoalescer.EnqueueAsync(frame) {
+		atomic.AddInt32(&cc.limiterSlots, -1)
+		return false
+	}
+	return true
+}
+
+// rejectLimitedRequest sends an OVERLOADED response for a request that the request limiter could not send.
+func (cc *ClusterConnector) rejectLimitedRequest(request *frame.RawFrame, reason string) {
+	cc.sendOverloaded(request, reason, fmt.Sprintf(
+		"Proxy reached the maximum number of concurrent requests to %v (%v), please retry.", cc.clusterType, reason))
+}
+
+// rejectRateLimitedRequest sends an OVERLOADED response for a request that the rate limiter rejected.
+func (cc *ClusterConnector) rejectRateLimitedRequest(request *frame.RawFrame) {
+	cc.sendOverloaded(request, "maximum rate of requests reached", fmt.Sprintf(
+		"Proxy reached the maximum rate of requests to %v, please retry.", cc.clusterType))
+}
+
+func (cc *ClusterConnector) sendOverloaded(request *frame.RawFrame, reason string, errorMessage string) {
+	cc.limiterLock.RLock()
+	if cc.limiterClosed {
+		cc.limiterLock.RUnlock()
+		return
+	}
+	// closeRequestLimiter waits for this response so it is sent before the response channel is closed
+	cc.limiterSenders.Add(1)
+	cc.limiterLock.RUnlock()
+	defer cc.limiterSenders.Done()
+
+	cc.getRequestLogger(request.Header.StreamId).Debugf("[%s] Returning OVERLOADED for %v request to %v: %v.",
+		cc.connectorType, request.Header.OpCode, cc.clusterType, reason)
+	overloaded := frame.NewFrame(request.Header.Version, request.Header.StreamId, &message.Overloaded{
+		ErrorMessage: errorMessage,
+	})
+	response, err := defaultCodec.ConvertToRawFrame(overloaded)
+	if err != nil {
+		cc.getLogger().Errorf("[%s] Could not encode OVERLOADED response: %v.", cc.connectorType, err)
+		return
+	}
+	cc.responseChan <- NewResponse(response, cc.connectorType)
+}
+
+func (cc *ClusterConnector) releaseRequestLimiterSlot() {
+	if cc.requestLimiter == nil {
+		return
+	}
+	for {
+		slots := atomic.LoadInt32(&cc.limiterSlots)
+		if slots <= 0 {
+			return
+		}
+		if atomic.CompareAndSwapInt32(&cc.limiterSlots, slots, slots-1) {
+			cc.requestLimiter.release(1)
+			return
+		}
+	}
+}
+
+// closeRequestLimiter stops this connector from sending queued requests and OVERLOADED responses, this must happen
+// before the write coalescer and the response channel are closed. If releaseInFlight is true then the slots of
+// requests that didn't get a response are released because no more responses will be received.
+func (cc *ClusterConnector) closeRequestLimiter(releaseInFlight bool) {
+	if cc.requestLimiter == nil {
+		return
+	}
+
+	cc.limiterLock.Lock()
+	cc.limiterClosed = true
+	cc.limiterLock.Unlock()
+	cc.limiterStopOnce.Do(func() {
+		close(cc.limiterStop)
+	})
+	<-cc.limiterDone
+	cc.limiterSenders.Wait()
+
+	cc.requestLimiter.removeConnector(cc)
+	if releaseInFlight {
+		if slots := atomic.SwapInt32(&cc.limiterSlots, 0); slots > 0 {
+			cc.requestLimiter.release(int(slots))
+		}
+	}
+}
+
+// getLogger returns the logger of the cluster connector, its entries have the id of the client connection.
+func (cc *ClusterConnector) getLogger() *log.Entry {
+	return logLevels.getLogger(cc.logEntry, LogModuleClusterConnector, nil)
+}
+
+// getRequestLogger returns the logger of a request, streamId is the stream id of the client request.
+func (cc *ClusterConnector) getRequestLogger(streamId int16) *log.Entry {
+	return withRequestFields(cc.getLogger(), cc.connectionId, streamId)
+}
+
 func (cc *ClusterConnector) SetReady() bool {
 	return atomic.CompareAndSwapInt32(&cc.asyncConnectorState, ConnectorStateHandshake, ConnectorStateReady)
 }
 
+func (cc *ClusterConnector) IsReady() bool {
+	return atomic.LoadInt32(&cc.asyncConnectorState) == ConnectorStateReady
+}
+
 func (cc *ClusterConnector) IsShutdown() bool {
 	return atomic.LoadInt32(&cc.asyncConnectorState) == ConnectorStateShutdown
 }
@@ -434,11 +819,21 @@ func (cc *ClusterConnector) sendAsyncRequest(
 	}
 
 	asyncReqCtx := NewAsyncRequestContext(requestInfo, asyncRequest.Header.StreamId, expectedResponse, overallRequestStartTime)
+	return cc.sendAsyncRequestContext(asyncReqCtx, asyncRequest, requestTimeout, onTimeout)
+}
+
+func (cc *ClusterConnector) sendAsyncRequestContext(
+	asyncReqCtx *asyncRequestContextImpl,
+	asyncRequest *frame.RawFrame,
+	requestTimeout time.Duration,
+	onTimeout func()) bool {
+
+	requestInfo := asyncReqCtx.GetRequestInfo()
 	var newStreamId int16
 	newStreamId, err := cc.asyncPendingRequests.store(asyncReqCtx)
 	storedAsync := err == nil
 	if err != nil {
-		log.Warnf("Could not send async request due to an error while storing the request state: %v.", err.Error())
+		cc.getLogger().Warnf("Could not send async request due to an error while storing the request state: %v.", err.Error())
 	} else {
 		if requestInfo.ShouldBeTrackedInMetrics() {
 			cc.nodeMetrics.AsyncMetrics.InFlightRequests.Add(1)
@@ -446,7 +841,7 @@ func (cc *ClusterConnector) sendAsyncRequest(
 		asyncRequest.Header.StreamId = newStreamId
 		timer := time.AfterFunc(requestTimeout, func() {
 			if cc.asyncPendingRequests.timeOut(newStreamId, asyncReqCtx, asyncRequest) {
-				log.Warnf(
+				cc.getRequestLogger(asyncReqCtx.requestStreamId).Warnf(
 					"Async Request (%v) timed out after %v ms.",
 					asyncRequest.Header.OpCode.String(), requestTimeout.Milliseconds())
 				onTimeout()
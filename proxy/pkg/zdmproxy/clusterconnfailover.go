@@ -0,0 +1,407 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sync"
+)
+
+// failoverConn allows the cluster connector of a client handler to survive the failure of the cluster node it is
+// connected to. It is only used when ZDM_PROXY_CLUSTER_CONNECTION_FAILOVER_ENABLED is true and the cluster connections
+// are not pooled.
+//
+// Like pooledConn, the cluster connector is given one end of an in-memory pipe instead of the network connection.
+// The frames are forwarded as is to the connection of the current node. When that connection breaks after the
+// handshake, or when the control connection reports that the node is DOWN or was removed, the in flight requests
+// get an OVERLOADED error and a connection is opened to another node of the local datacenter that is UP. The handshake,
+// the last USE request and the REGISTER request of the client are replayed on the new connection.
+// If no node can be reached the pipe is closed so the client connection is closed like it would be without failover.
+//
+// The node metrics of the connector keep the node that the connection was opened to.
+type failoverConn struct {
+	conf                *config.Config
+	connInfo            *ClusterConnectionInfo
+	connectorType       ClusterConnectorType
+	nodeMetricsInstance *metrics.NodeMetricsInstance
+
+	pipe net.Conn
+
+	lock            *sync.Mutex
+	conn            net.Conn // nil while failing over
+	framing         *connFraming
+	endpoint        Endpoint
+	remoteAddr      net.Addr
+	generation      int // incremented every time the connection is replaced
+	version         primitive.ProtocolVersion
+	handshakeDone   bool
+	handshakeFrames []*frame.RawFrame // STARTUP and AUTH_RESPONSE frames, replayed on the new connection
+	useRequest      *frame.RawFrame
+	registerRequest *frame.RawFrame
+	inFlight        map[int16]bool // stream ids of the requests sent to the current connection after the handshake
+
+	responses chan *frame.RawFrame
+
+	ctx       context.Context
+	cancelFn  context.CancelFunc
+	closeOnce *sync.Once
+}
+
+// connectWithFailover opens the connection to the endpoint of the provided ClusterConnectionInfo and returns the end
+// of the pipe that should be used by the cluster connector.
+func connectWithFailover(
+	connInfo *ClusterConnectionInfo, conf *config.Config, ctx context.Context, connectorType ClusterConnectorType,
+	nodeMetrics *metrics.NodeMetrics) (net.Conn, context.Context, error) {
+	conn, timeoutCtx, err := openConnection(connInfo.connConfig, connInfo.endpoint, ctx, true)
+	if err != nil {
+		return nil, timeoutCtx, err
+	}
+
+	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorType)
+	if err != nil {
+		log.Errorf("Failed to track open connection metrics for conn %v: %v.", conn.RemoteAddr().String(), err)
+	} else {
+		nodeMetricsInstance.OpenConnections.Add(1)
+	}
+
+	connectorSide, failoverSide := net.Pipe()
+	fcCtx, fcCancelFn := context.WithCancel(ctx)
+	fc := &failoverConn{
+		conf:                conf,
+		connInfo:            connInfo,
+		connectorType:       connectorType,
+		nodeMetricsInstance: nodeMetricsInstance,
+		pipe:                failoverSide,
+		lock:                &sync.Mutex{},
+		conn:                conn,
+		framing:             newConnFraming(),
+		endpoint:            connInfo.endpoint,
+		remoteAddr:          conn.RemoteAddr(),
+		inFlight:            make(map[int16]bool),
+		responses:           make(chan *frame.RawFrame, conf.ResponseWriteQueueSizeFrames),
+		ctx:                 fcCtx,
+		cancelFn:            fcCancelFn,
+		closeOnce:           &sync.Once{},
+	}
+
+	connInfo.failoverControlConn.RegisterObserver(fc)
+	fc.run(conn, bufio.NewReaderSize(conn, conf.ResponseReadBufferSizeBytes), fc.framing, 0)
+	return &failoverPipeConn{Conn: connectorSide, fc: fc}, timeoutCtx, nil
+}
+
+// failoverPipeConn is the end of the pipe that is used by the cluster connector,
+// it reports the address of the current cluster node as the remote address.
+type failoverPipeConn struct {
+	net.Conn
+	fc *failoverConn
+}
+
+func (recv *failoverPipeConn) RemoteAddr() net.Addr {
+	recv.fc.lock.Lock()
+	defer recv.fc.lock.Unlock()
+	return recv.fc.remoteAddr
+}
+
+func (recv *failoverConn) run(conn net.Conn, reader *bufio.Reader, framing *connFraming, generation int) {
+	go recv.runResponseLoop(conn, reader, framing, generation)
+	go recv.runRequestLoop()
+	go recv.runResponseWriteLoop()
+}
+
+// Reads the responses of the connection of the provided generation and fails over when the connection breaks.
+func (recv *failoverConn) runResponseLoop(conn net.Conn, reader *bufio.Reader, framing *connFraming, generation int) {
+	addr := conn.RemoteAddr().String()
+	for {
+		response, err := framing.readFrame(reader, addr, recv.ctx)
+		if err != nil {
+			if recv.ctx.Err() == nil {
+				log.Debugf("[%s] Error reading from cluster connection to %v: %v", recv.connectorType, addr, err)
+				recv.failover(generation)
+			}
+			return
+		}
+
+		recv.lock.Lock()
+		if recv.generation != generation {
+			recv.lock.Unlock()
+			return
+		}
+		if !recv.handshakeDone {
+			switch response.Header.OpCode {
+			case primitive.OpCodeReady, primitive.OpCodeAuthSuccess:
+				recv.handshakeDone = true
+			case primitive.OpCodeError:
+				if n := len(recv.handshakeFrames); n > 0 && recv.handshakeFrames[n-1].Header.OpCode == primitive.OpCodeAuthResponse {
+					// the client may send a different AUTH_RESPONSE, the failed one must not be replayed
+					recv.handshakeFrames = recv.handshakeFrames[:n-1]
+				}
+			}
+		} else if response.Header.OpCode != primitive.OpCodeEvent {
+			if !recv.inFlight[response.Header.StreamId] {
+				// the request was sent before a failover, it already got an OVERLOADED response
+				recv.lock.Unlock()
+				continue
+			}
+			if !isIntermediateContinuousPage(response) {
+				delete(recv.inFlight, response.Header.StreamId)
+			}
+		}
+		recv.lock.Unlock()
+		recv.sendResponse(response)
+	}
+}
+
+// Reads the frames that the cluster connector writes to the pipe and forwards them to the current connection.
+func (recv *failoverConn) runRequestLoop() {
+	defer recv.close()
+	reader := bufio.NewReaderSize(recv.pipe, recv.conf.RequestReadBufferSizeBytes)
+	for {
+		request, err := readRawFrame(reader, recv.pipe.RemoteAddr().String(), recv.ctx)
+		if err != nil {
+			if !errors.Is(err, ShutdownErr) {
+				log.Debugf("[%s] Failover connection closed by cluster connector: %v", recv.connectorType, err)
+			}
+			return
+		}
+
+		recv.lock.Lock()
+		conn, framing := recv.conn, recv.framing
+		if !recv.handshakeDone {
+			switch request.Header.OpCode {
+			case primitive.OpCodeStartup:
+				recv.version = request.Header.Version
+				recv.handshakeFrames = []*frame.RawFrame{request.Clone()}
+			case primitive.OpCodeAuthResponse:
+				recv.handshakeFrames = append(recv.handshakeFrames, request.Clone())
+			}
+		} else {
+			if request.Header.OpCode == primitive.OpCodeRegister {
+				recv.registerRequest = request.Clone()
+			} else if _, ok := readUseStatementKeyspace(request); ok {
+				recv.useRequest = request.Clone()
+			}
+			if conn != nil {
+				recv.inFlight[request.Header.StreamId] = true
+			}
+		}
+		recv.lock.Unlock()
+
+		if conn == nil {
+			recv.sendErrorResponse(request, &message.Overloaded{
+				ErrorMessage: "Proxy is reconnecting to another node, please retry on next host."})
+			continue
+		}
+
+		addr := conn.RemoteAddr().String()
+		err = framing.writeFrameToConn(conn, addr, recv.ctx, request)
+		if err != nil {
+			if recv.ctx.Err() != nil {
+				return
+			}
+			// the response loop fails over when the connection is closed
+			log.Debugf("[%s] Error writing to cluster connection to %v: %v", recv.connectorType, addr, err)
+			_ = conn.Close()
+		}
+	}
+}
+
+// Writes the responses to the pipe, they are read by the cluster connector.
+func (recv *failoverConn) runResponseWriteLoop() {
+	buffer := &bytes.Buffer{}
+	for {
+		select {
+		case response := <-recv.responses:
+			buffer.Reset()
+			err := writeRawFrame(buffer, "", recv.ctx, response)
+			if err == nil {
+				_, err = recv.pipe.Write(buffer.Bytes())
+			}
+			if err != nil {
+				if recv.ctx.Err() == nil {
+					log.Debugf("[%s] Could not write response to failover connection: %v", recv.connectorType, err)
+				}
+				recv.close()
+				return
+			}
+		case <-recv.ctx.Done():
+			recv.close()
+			return
+		}
+	}
+}
+
+// failover replaces the connection of the provided generation with a connection to another node, the pipe is closed
+// if the handshake was not done yet or if no other node can be reached.
+func (recv *failoverConn) failover(generation int) {
+	recv.lock.Lock()
+	if recv.generation != generation || recv.ctx.Err() != nil {
+		recv.lock.Unlock()
+		return
+	}
+	oldConn, oldEndpoint := recv.conn, recv.endpoint
+	recv.conn = nil
+	recv.generation++
+	inFlight := recv.inFlight
+	recv.inFlight = make(map[int16]bool)
+	handshakeDone, version := recv.handshakeDone, recv.version
+	handshakeFrames, useRequest, registerRequest := recv.handshakeFrames, recv.useRequest, recv.registerRequest
+	recv.lock.Unlock()
+
+	if oldConn != nil {
+		closePooledConnection(oldConn, string(recv.connectorType), recv.nodeMetricsInstance)
+	}
+	if !handshakeDone {
+		recv.close()
+		return
+	}
+
+	oldAddr := oldEndpoint.GetEndpointIdentifier()
+	log.Warnf("[%s] Cluster connection to %v failed, reconnecting to another node of %v.",
+		recv.connectorType, oldAddr, recv.connInfo.connConfig.GetClusterType())
+	for streamId := range inFlight {
+		recv.sendOverloaded(version, streamId, fmt.Sprintf("Proxy lost the connection to %v, please retry on next host.", oldAddr))
+	}
+
+	for _, host := range recv.connInfo.failoverControlConn.GetFailoverHosts(oldEndpoint) {
+		if recv.ctx.Err() != nil {
+			return
+		}
+		endpoint := recv.connInfo.connConfig.CreateEndpoint(host)
+		conn, reader, framing, err := recv.reconnect(endpoint, handshakeFrames, useRequest, registerRequest)
+		if err != nil {
+			log.Warnf("[%s] Could not reconnect to %v: %v", recv.connectorType, endpoint.GetEndpointIdentifier(), err)
+			continue
+		}
+
+		recv.lock.Lock()
+		if recv.ctx.Err() != nil {
+			recv.lock.Unlock()
+			closePooledConnection(conn, string(recv.connectorType), recv.nodeMetricsInstance)
+			return
+		}
+		recv.conn, recv.framing, recv.endpoint, recv.remoteAddr = conn, framing, endpoint, conn.RemoteAddr()
+		newGeneration := recv.generation
+		recv.lock.Unlock()
+
+		log.Infof("[%s] Cluster connection failed over from %v to %v.",
+			recv.connectorType, oldAddr, endpoint.GetEndpointIdentifier())
+		go recv.runResponseLoop(conn, reader, framing, newGeneration)
+		return
+	}
+
+	log.Errorf("[%s] Could not reconnect to any node of %v after the connection to %v failed, "+
+		"closing the client connection.", recv.connectorType, recv.connInfo.connConfig.GetClusterType(), oldAddr)
+	recv.close()
+}
+
+// Opens a connection to the provided endpoint and replays the handshake, the USE request and the REGISTER request.
+func (recv *failoverConn) reconnect(
+	endpoint Endpoint, handshakeFrames []*frame.RawFrame, useRequest *frame.RawFrame,
+	registerRequest *frame.RawFrame) (net.Conn, *bufio.Reader, *connFraming, error) {
+	conn, _, err := openConnection(recv.connInfo.connConfig, endpoint, recv.ctx, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if recv.nodeMetricsInstance != nil {
+		recv.nodeMetricsInstance.OpenConnections.Add(1)
+	}
+
+	reader := bufio.NewReaderSize(conn, recv.conf.ResponseReadBufferSizeBytes)
+	framing := newConnFraming()
+	err = replayHandshake(conn, reader, framing, recv.ctx, handshakeFrames)
+	if err == nil && useRequest != nil {
+		var useResponse *frame.RawFrame
+		useResponse, err = sendAndReceiveFrame(conn, reader, framing, recv.ctx, useRequest.Clone())
+		if err == nil && useResponse.Header.OpCode != primitive.OpCodeResult {
+			err = fmt.Errorf("unexpected %v response while replaying USE request", useResponse.Header.OpCode)
+		}
+	}
+	// REGISTER is replayed last because events can be received as soon as it succeeds
+	if err == nil && registerRequest != nil {
+		_, err = sendAndReceiveFrame(conn, reader, framing, recv.ctx, registerRequest.Clone())
+	}
+	if err != nil {
+		closePooledConnection(conn, string(recv.connectorType), recv.nodeMetricsInstance)
+		return nil, nil, nil, err
+	}
+	return conn, reader, framing, nil
+}
+
+// OnHostRemoved closes the connection if it is connected to the provided host, the response loop then fails over.
+func (recv *failoverConn) OnHostRemoved(host *Host) {
+	recv.closeConnToHost(host, "was removed")
+}
+
+// OnHostDown closes the connection if it is connected to the provided host, the response loop then fails over.
+func (recv *failoverConn) OnHostDown(host *Host) {
+	recv.closeConnToHost(host, "is DOWN")
+}
+
+func (recv *failoverConn) closeConnToHost(host *Host, reason string) {
+	endpointId := recv.connInfo.connConfig.CreateEndpoint(host).GetEndpointIdentifier()
+	recv.lock.Lock()
+	conn := recv.conn
+	if conn == nil || recv.endpoint.GetEndpointIdentifier() != endpointId {
+		recv.lock.Unlock()
+		return
+	}
+	recv.lock.Unlock()
+
+	log.Infof("[%s] Host %v of the cluster connection %v, closing the connection.", recv.connectorType, host, reason)
+	err := conn.Close()
+	if err != nil {
+		log.Debugf("[%s] Error closing cluster connection to %v: %v", recv.connectorType, endpointId, err)
+	}
+}
+
+func (recv *failoverConn) sendResponse(response *frame.RawFrame) {
+	select {
+	case recv.responses <- response:
+	case <-recv.ctx.Done():
+	}
+}
+
+func (recv *failoverConn) sendErrorResponse(request *frame.RawFrame, msg message.Message) {
+	recv.sendMessage(request.Header.Version, request.Header.StreamId, msg)
+}
+
+func (recv *failoverConn) sendOverloaded(version primitive.ProtocolVersion, streamId int16, errorMessage string) {
+	recv.sendMessage(version, streamId, &message.Overloaded{ErrorMessage: errorMessage})
+}
+
+func (recv *failoverConn) sendMessage(version primitive.ProtocolVersion, streamId int16, msg message.Message) {
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, streamId, msg))
+	if err != nil {
+		log.Errorf("[%s] Could not convert %v to raw frame: %v", recv.connectorType, msg, err)
+		return
+	}
+	recv.sendResponse(response)
+}
+
+func (recv *failoverConn) close() {
+	recv.closeOnce.Do(func() {
+		recv.cancelFn()
+		err := recv.pipe.Close()
+		if err != nil {
+			log.Debugf("[%s] Error closing failover connection pipe: %v", recv.connectorType, err)
+		}
+
+		recv.lock.Lock()
+		conn := recv.conn
+		recv.conn = nil
+		recv.lock.Unlock()
+		if conn != nil {
+			closePooledConnection(conn, string(recv.connectorType), recv.nodeMetricsInstance)
+		}
+		recv.connInfo.failoverControlConn.RemoveObserver(recv)
+	})
+}
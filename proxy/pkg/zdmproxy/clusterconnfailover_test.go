@@ -0,0 +1,233 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestControlConn_StatusChangeEvents(t *testing.T) {
+	cc, hosts := newFailoverTestControlConn(t, 9042, 9042, 9042)
+	hosts[0].Address, hosts[1].Address, hosts[2].Address = net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")
+	observer := &fakeHostObserver{}
+	cc.RegisterObserver(observer)
+
+	statusChange := func(changeType primitive.StatusChangeType, address string) {
+		cc.handleStatusChangeEvent(&message.StatusChangeEvent{
+			ChangeType: changeType, Address: &primitive.Inet{Addr: net.ParseIP(address), Port: 9042}}, nil)
+	}
+
+	statusChange(primitive.StatusChangeTypeDown, "10.0.0.2")
+	require.False(t, cc.IsHostUp(hosts[1]))
+	require.True(t, cc.IsHostUp(hosts[0]))
+	require.Equal(t, []*Host{hosts[1]}, observer.getDownHosts())
+	currentEndpoint := cc.connConfig.CreateEndpoint(hosts[0])
+	require.Equal(t, []*Host{hosts[2], hosts[0]}, cc.GetFailoverHosts(currentEndpoint))
+
+	// the hosts that are DOWN are not assigned to new connections
+	for i := 0; i < 4; i++ {
+		host, err := cc.NextAssignedHost()
+		require.Nil(t, err)
+		require.NotEqual(t, hosts[1], host)
+	}
+
+	statusChange(primitive.StatusChangeTypeDown, "10.0.0.2")
+	require.Len(t, observer.getDownHosts(), 1)
+	statusChange(primitive.StatusChangeTypeUp, "10.0.0.2")
+	require.True(t, cc.IsHostUp(hosts[1]))
+	require.Equal(t, []*Host{hosts[1], hosts[2], hosts[0]}, cc.GetFailoverHosts(currentEndpoint))
+
+	// unknown hosts trigger a topology refresh when they are UP
+	statusChange(primitive.StatusChangeTypeDown, "10.0.0.4")
+	require.Len(t, cc.refreshHostsDebouncer, 0)
+	statusChange(primitive.StatusChangeTypeUp, "10.0.0.4")
+	require.Len(t, cc.refreshHostsDebouncer, 1)
+}
+
+func TestFailoverConn_Failover(t *testing.T) {
+	node1 := newFakeFailoverNode(t)
+	defer node1.close()
+	node2 := newFakeFailoverNode(t)
+	defer node2.close()
+
+	cc, hosts := newFailoverTestControlConn(t, node1.port(), node2.port())
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	conf := config.New()
+	conf.RequestReadBufferSizeBytes = 1024
+	conf.ResponseReadBufferSizeBytes = 1024
+	conf.ResponseWriteQueueSizeFrames = 16
+	connInfo := NewClusterConnectionInfo(
+		cc.connConfig, cc.connConfig.CreateEndpoint(hosts[0]), true, nil, nil, nil, nil, cc, nil, nil)
+	nodeMetrics := &metrics.NodeMetrics{OriginMetrics: &metrics.NodeMetricsInstance{OpenConnections: newFakeGauge()}}
+	conn, _, err := openConnectionToCluster(connInfo, conf, ctx, ClusterConnectorTypeOrigin, nodeMetrics)
+	require.Nil(t, err)
+	defer conn.Close()
+	require.Equal(t, node1.listener.Addr().String(), conn.RemoteAddr().String())
+
+	send := func(streamId int16, msg message.Message) (message.Message, error) {
+		err := defaultCodec.EncodeFrame(frame.NewFrame(primitive.ProtocolVersion4, streamId, msg), conn)
+		if err != nil {
+			return nil, err
+		}
+		response, err := defaultCodec.DecodeFrame(conn)
+		if err != nil {
+			return nil, err
+		}
+		return response.Body.Message, nil
+	}
+	sendAndReceive := func(streamId int16, msg message.Message) message.Message {
+		response, err := send(streamId, msg)
+		require.Nil(t, err)
+		return response
+	}
+
+	require.IsType(t, &message.Ready{}, sendAndReceive(0, &message.Startup{}))
+	require.IsType(t, &message.Ready{}, sendAndReceive(1, &message.Register{
+		EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}}))
+	require.IsType(t, &message.VoidResult{}, sendAndReceive(2, &message.Query{Query: "USE ks"}))
+	require.IsType(t, &message.VoidResult{}, sendAndReceive(3, &message.Query{Query: "SELECT * FROM tb"}))
+
+	// node1 goes down, the requests get OVERLOADED until the connection to node2 is open
+	node1.close()
+	require.Eventually(t, func() bool {
+		response, err := send(4, &message.Query{Query: "SELECT * FROM tb"})
+		_, ok := response.(*message.VoidResult)
+		return err == nil && ok
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, node2.listener.Addr().String(), conn.RemoteAddr().String())
+	require.Equal(t, []string{
+		primitive.OpCodeStartup.String(), "USE ks", primitive.OpCodeRegister.String()}, node2.getRequests()[:3])
+
+	// the connection is closed when there is no other node to fail over to
+	node2.close()
+	require.Eventually(t, func() bool {
+		_, err := send(5, &message.Query{Query: "SELECT * FROM tb"})
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func newFailoverTestControlConn(t *testing.T, ports ...int) (*ControlConn, []*Host) {
+	conf := config.New()
+	connConfig := newGenericConnectionConfig(
+		nil, 1000, 0, nil, common.ClusterTypeOrigin, "dc1", []Endpoint{NewDefaultEndpoint("127.0.0.1", ports[0], nil)})
+	cc := NewControlConn(context.Background(), 9042, connConfig, "", "", conf,
+		&common.TopologyConfig{Count: 1}, rand.New(rand.NewSource(1)))
+
+	var hosts []*Host
+	for i, port := range ports {
+		hostId, err := uuid.NewRandom()
+		require.Nil(t, err)
+		host := NewHost(net.ParseIP("127.0.0.1"), port, hostId, "dc1", "rack1", nil, nil, nil)
+		hosts = append(hosts, host)
+		cc.hostsInLocalDcById[hostId] = host
+		if i < len(ports)-1 {
+			cc.assignedHosts = append(cc.assignedHosts, host)
+		}
+	}
+	cc.orderedHostsInLocalDc = hosts
+	return cc, hosts
+}
+
+type fakeHostObserver struct {
+	lock      sync.Mutex
+	downHosts []*Host
+}
+
+func (recv *fakeHostObserver) OnHostRemoved(host *Host) {
+}
+
+func (recv *fakeHostObserver) OnHostDown(host *Host) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.downHosts = append(recv.downHosts, host)
+}
+
+func (recv *fakeHostObserver) getDownHosts() []*Host {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.downHosts
+}
+
+// fakeFailoverNode answers STARTUP and REGISTER with READY and every QUERY with a void result.
+type fakeFailoverNode struct {
+	listener  net.Listener
+	lock      sync.Mutex
+	conns     []net.Conn
+	requests  []string
+	closeOnce sync.Once
+}
+
+func newFakeFailoverNode(t *testing.T) *fakeFailoverNode {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	node := &fakeFailoverNode{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			node.lock.Lock()
+			node.conns = append(node.conns, conn)
+			node.lock.Unlock()
+			go node.serve(conn)
+		}
+	}()
+	return node
+}
+
+func (recv *fakeFailoverNode) serve(conn net.Conn) {
+	for {
+		request, err := defaultCodec.DecodeFrame(conn)
+		if err != nil {
+			return
+		}
+		var response message.Message = &message.Ready{}
+		description := request.Header.OpCode.String()
+		if query, ok := request.Body.Message.(*message.Query); ok {
+			response = &message.VoidResult{}
+			description = query.Query
+		}
+		recv.lock.Lock()
+		recv.requests = append(recv.requests, description)
+		recv.lock.Unlock()
+		err = defaultCodec.EncodeFrame(frame.NewFrame(request.Header.Version, request.Header.StreamId, response), conn)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (recv *fakeFailoverNode) port() int {
+	return recv.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (recv *fakeFailoverNode) getRequests() []string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]string{}, recv.requests...)
+}
+
+func (recv *fakeFailoverNode) close() {
+	recv.closeOnce.Do(func() {
+		_ = recv.listener.Close()
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		for _, conn := range recv.conns {
+			_ = conn.Close()
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package zdmproxy
+
+import (
+	"errors"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync/atomic"
+	"time"
+)
+
+type heartbeatAction int
+
+const (
+	heartbeatActionNone = heartbeatAction(iota)
+	heartbeatActionSend
+	heartbeatActionReplace
+)
+
+// sharedConnHeartbeat tracks the last time that a shared cluster connection received a frame. A heartbeat is only sent
+// when the connection was idle for the whole interval so the busy connections don't get extra requests.
+//
+// Every frame that is received resets the heartbeats that are still unanswered because it proves that the node still
+// responds on this connection. Once maxMissed heartbeats are unanswered the connection should be replaced
+// (see clusterConnPool.replace).
+type sharedConnHeartbeat struct {
+	interval  time.Duration
+	maxMissed int32
+	sent      metrics.Counter
+	failed    metrics.Counter
+
+	lastActivity int64 // unix nanos
+	unanswered   int32
+}
+
+func newSharedConnHeartbeat(
+	conf *common.ClusterConnectionHeartbeatConfig, nodeMetricsInstance *metrics.NodeMetricsInstance) *sharedConnHeartbeat {
+	if conf == nil || !conf.Enabled {
+		return nil
+	}
+	heartbeat := &sharedConnHeartbeat{
+		interval:     time.Duration(conf.IntervalMs) * time.Millisecond,
+		maxMissed:    int32(conf.MaxMissed),
+		lastActivity: time.Now().UnixNano(),
+	}
+	if nodeMetricsInstance != nil {
+		heartbeat.sent = nodeMetricsInstance.PooledHeartbeatsSent
+		heartbeat.failed = nodeMetricsInstance.PooledHeartbeatsFailed
+	}
+	return heartbeat
+}
+
+// touch records that the connection received a frame.
+func (recv *sharedConnHeartbeat) touch(now time.Time) {
+	atomic.StoreInt64(&recv.lastActivity, now.UnixNano())
+	atomic.StoreInt32(&recv.unanswered, 0)
+}
+
+// check is called once per interval, it returns the action that the connection should take.
+func (recv *sharedConnHeartbeat) check(now time.Time) heartbeatAction {
+	if now.Sub(time.Unix(0, atomic.LoadInt64(&recv.lastActivity))) < recv.interval {
+		return heartbeatActionNone
+	}
+	unanswered := atomic.LoadInt32(&recv.unanswered)
+	if unanswered > 0 {
+		// the previous heartbeat wasn't answered within an interval
+		if recv.failed != nil {
+			recv.failed.Add(1)
+		}
+		if unanswered >= recv.maxMissed {
+			return heartbeatActionReplace
+		}
+	}
+	return heartbeatActionSend
+}
+
+// heartbeatSent records that a heartbeat was sent on the connection.
+func (recv *sharedConnHeartbeat) heartbeatSent() {
+	atomic.AddInt32(&recv.unanswered, 1)
+	if recv.sent != nil {
+		recv.sent.Add(1)
+	}
+}
+
+// runHeartbeatLoop sends heartbeats on this connection while it is idle and replaces it once it misses too many of them.
+func (recv *sharedConn) runHeartbeatLoop() {
+	ticker := time.NewTicker(recv.heartbeat.interval)
+	defer ticker.Stop()
+	addr := recv.conn.RemoteAddr()
+	for {
+		select {
+		case <-recv.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		switch recv.heartbeat.check(time.Now()) {
+		case heartbeatActionSend:
+			err := recv.sendHeartbeat()
+			if err != nil {
+				if !errors.Is(err, errSharedConnClosed) {
+					log.Debugf("[%s] Could not send heartbeat to %v: %v", pooledConnLogPrefix, addr, err)
+				}
+				continue
+			}
+			recv.heartbeat.heartbeatSent()
+			log.Tracef("[%s] Sent heartbeat to %v.", pooledConnLogPrefix, addr)
+		case heartbeatActionReplace:
+			log.Warnf("[%s] Connection to %v missed %d heartbeats in a row, replacing it.",
+				pooledConnLogPrefix, addr, recv.heartbeat.maxMissed)
+			recv.pool.replace(recv)
+			return
+		}
+	}
+}
+
+// sendHeartbeat sends an OPTIONS request that isn't assigned to any pooledConn, its response is dropped by the read loop.
+func (recv *sharedConn) sendHeartbeat() error {
+	options, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(recv.key.version, 0, &message.Options{}))
+	if err != nil {
+		return err
+	}
+	return recv.send(nil, options)
+}
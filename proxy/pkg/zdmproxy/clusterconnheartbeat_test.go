@@ -0,0 +1,43 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSharedConnHeartbeat(t *testing.T) {
+	require.Nil(t, newSharedConnHeartbeat(&common.ClusterConnectionHeartbeatConfig{}, nil))
+
+	sent, failed := &countingCounter{}, &countingCounter{}
+	heartbeat := newSharedConnHeartbeat(
+		&common.ClusterConnectionHeartbeatConfig{Enabled: true, IntervalMs: 1000, MaxMissed: 2},
+		&metrics.NodeMetricsInstance{PooledHeartbeatsSent: sent, PooledHeartbeatsFailed: failed})
+	now := time.Now()
+	heartbeat.touch(now)
+
+	// the connection received a frame within the interval
+	require.Equal(t, heartbeatActionNone, heartbeat.check(now.Add(500*time.Millisecond)))
+
+	// idle connection
+	require.Equal(t, heartbeatActionSend, heartbeat.check(now.Add(time.Second)))
+	heartbeat.heartbeatSent()
+	require.Equal(t, int64(1), sent.count)
+
+	// the heartbeat was answered
+	heartbeat.touch(now.Add(1100 * time.Millisecond))
+	require.Equal(t, heartbeatActionNone, heartbeat.check(now.Add(2*time.Second)))
+	require.Equal(t, heartbeatActionSend, heartbeat.check(now.Add(2100*time.Millisecond)))
+	heartbeat.heartbeatSent()
+	require.Equal(t, int64(0), failed.count)
+
+	// the heartbeats are not answered
+	require.Equal(t, heartbeatActionSend, heartbeat.check(now.Add(3100*time.Millisecond)))
+	heartbeat.heartbeatSent()
+	require.Equal(t, int64(1), failed.count)
+	require.Equal(t, heartbeatActionReplace, heartbeat.check(now.Add(4100*time.Millisecond)))
+	require.Equal(t, int64(3), sent.count)
+	require.Equal(t, int64(2), failed.count)
+}
@@ -0,0 +1,1579 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	pooledConnLogPrefix = "POOLED-CONNECTION"
+
+	routedSharedConnRetryDelay = 10 * time.Second
+
+	sharedConnClosedErrorMessage = "Proxy pooled connection to the cluster was closed, please retry on next host."
+)
+
+var errStreamIdsExhausted = errors.New("shared cluster connection ran out of stream ids")
+var errSharedConnClosed = errors.New("shared cluster connection is closed")
+
+// clusterConnPool allows the cluster connectors of different client handlers to share a bounded number of
+// connections per cluster node. It is only used when ZDM_PROXY_CLUSTER_CONNECTION_POOL_SIZE is greater than 0,
+// otherwise each cluster connector owns a dedicated connection.
+//
+// Each cluster connector is given one end of an in-memory pipe instead of a network connection.
+// The handshake is forwarded over a dedicated connection so that every client connection is still authenticated
+// by the cluster. Once the handshake succeeds, that connection either joins the pool or is closed and the requests
+// that the connector writes to the pipe are forwarded to one of the shared connections with a new stream id,
+// which is replaced with the original one when the response is routed back to the pipe (see streamIdMapper).
+//
+// Shared connections are grouped by node, protocol version, handshake (STARTUP options and credentials) and keyspace
+// so requests are never executed with a different user or keyspace than the one that the client negotiated.
+//
+// With token aware routing (see tokenRouter), a pooledConn also forwards requests to the shared connections of the
+// node that owns their partition key, those connections are opened in the background the first time they are needed.
+//
+// A private pool is owned by a single cluster connector (see perClientConnections): its groups are filled with size
+// connections in the background and the requests of the cluster connector are dispatched across them.
+//
+// The shared connections send heartbeats while they are idle and are replaced once they miss too many of them
+// (see sharedConnHeartbeat).
+type clusterConnPool struct {
+	conf           *config.Config
+	size           int
+	writeScheduler *Scheduler
+	stallWatchdog  *stallWatchdog
+	heartbeatConf  *common.ClusterConnectionHeartbeatConfig
+
+	private        bool
+	dispatchPolicy common.ConnectionDispatchPolicy // only used by private pools
+
+	lock          *sync.Mutex
+	groups        map[sharedConnGroupKey][]*sharedConn
+	opening       map[sharedConnGroupKey]bool // groups whose routed shared connection is being opened
+	routeFailures map[string]time.Time        // time of the last failure to open a routed shared connection per endpoint
+
+	ctx      context.Context
+	cancelFn context.CancelFunc
+	wg       *sync.WaitGroup
+}
+
+type sharedConnGroupKey struct {
+	endpoint  string
+	version   primitive.ProtocolVersion
+	handshake string
+	keyspace  string
+}
+
+// perClientConnections opens a private clusterConnPool for each cluster connector when
+// ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT is greater than 1 and the cluster connections are not pooled.
+type perClientConnections struct {
+	conf           *config.Config
+	size           int
+	dispatchPolicy common.ConnectionDispatchPolicy
+	writeScheduler *Scheduler
+	stallWatchdog  *stallWatchdog
+	heartbeatConf  *common.ClusterConnectionHeartbeatConfig
+}
+
+func newPerClientConnections(
+	conf *config.Config, size int, dispatchPolicy common.ConnectionDispatchPolicy, writeScheduler *Scheduler,
+	stallWatchdog *stallWatchdog, heartbeatConf *common.ClusterConnectionHeartbeatConfig) *perClientConnections {
+	return &perClientConnections{
+		conf:           conf,
+		size:           size,
+		dispatchPolicy: dispatchPolicy,
+		writeScheduler: writeScheduler,
+		stallWatchdog:  stallWatchdog,
+		heartbeatConf:  heartbeatConf,
+	}
+}
+
+func (recv *perClientConnections) newPool() *clusterConnPool {
+	pool := newClusterConnPool(recv.conf, recv.size, recv.writeScheduler, recv.stallWatchdog, recv.heartbeatConf)
+	pool.private = true
+	pool.dispatchPolicy = recv.dispatchPolicy
+	return pool
+}
+
+func newClusterConnPool(
+	conf *config.Config, size int, writeScheduler *Scheduler, stallWatchdog *stallWatchdog,
+	heartbeatConf *common.ClusterConnectionHeartbeatConfig) *clusterConnPool {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	return &clusterConnPool{
+		conf:           conf,
+		size:           size,
+		writeScheduler: writeScheduler,
+		stallWatchdog:  stallWatchdog,
+		heartbeatConf:  heartbeatConf,
+		lock:           &sync.Mutex{},
+		groups:         make(map[sharedConnGroupKey][]*sharedConn),
+		opening:        make(map[sharedConnGroupKey]bool),
+		routeFailures:  make(map[string]time.Time),
+		ctx:            ctx,
+		cancelFn:       cancelFn,
+		wg:             &sync.WaitGroup{},
+	}
+}
+
+// connect opens the dedicated connection that is used for the handshake and returns the end of the pipe
+// that should be used by the cluster connector.
+func (recv *clusterConnPool) connect(
+	connInfo *ClusterConnectionInfo, ctx context.Context, connectorType ClusterConnectorType,
+	nodeMetrics *metrics.NodeMetrics) (net.Conn, context.Context, error) {
+	conn, timeoutCtx, err := openConnection(connInfo.connConfig, connInfo.endpoint, ctx, true)
+	if err != nil {
+		return nil, timeoutCtx, err
+	}
+
+	nodeMetricsInstance, err := GetNodeMetricsByClusterConnector(nodeMetrics, connectorType)
+	if err != nil {
+		log.Errorf("Failed to track open connection metrics for conn %v: %v.", conn.RemoteAddr().String(), err)
+	} else {
+		nodeMetricsInstance.OpenConnections.Add(1)
+	}
+
+	connectorSide, poolSide := net.Pipe()
+	pcCtx, pcCancelFn := context.WithCancel(recv.ctx)
+	pc := &pooledConn{
+		pool:                recv,
+		connInfo:            connInfo,
+		connectorType:       connectorType,
+		nodeMetricsInstance: nodeMetricsInstance,
+		pipe:                poolSide,
+		remoteAddr:          conn.RemoteAddr(),
+		lock:                &sync.Mutex{},
+		handshakeConn:       conn,
+		handshakeFraming:    newConnFraming(),
+		events:              make(map[primitive.EventType]bool),
+		routes:              make(map[string]*sharedConn),
+		responses:           make(chan *frame.RawFrame, recv.conf.ResponseWriteQueueSizeFrames),
+		ctx:                 pcCtx,
+		cancelFn:            pcCancelFn,
+		closeOnce:           &sync.Once{},
+	}
+
+	log.Debugf("[%s] Opened handshake connection to %v for pooled connection.", connectorType, conn.RemoteAddr())
+	pc.run(bufio.NewReaderSize(conn, recv.conf.ResponseReadBufferSizeBytes))
+	return &pooledPipeConn{Conn: connectorSide, remoteAddr: conn.RemoteAddr()}, timeoutCtx, nil
+}
+
+// attach adds the handshake connection of the provided pooledConn to the pool if the group still has room,
+// otherwise the handshake connection is closed and one of the existing shared connections is used.
+func (recv *clusterConnPool) attach(pc *pooledConn, conn net.Conn, reader *bufio.Reader) error {
+	key := pc.groupKey("")
+
+	recv.lock.Lock()
+	if recv.ctx.Err() != nil {
+		recv.lock.Unlock()
+		pc.closeHandshakeConn()
+		return ShutdownErr
+	}
+	var sc *sharedConn
+	if len(recv.groups[key]) < recv.size {
+		sc = newSharedConn(recv, key, conn, reader, pc.handshakeFraming, pc.nodeMetricsInstance)
+		recv.groups[key] = append(recv.groups[key], sc)
+	} else {
+		sc = leastUsedSharedConn(recv.groups[key])
+	}
+	sc.clients[pc] = true
+	recv.lock.Unlock()
+
+	if sc.conn == conn {
+		pc.releaseHandshakeConn()
+		sc.run()
+		log.Infof("[%s] Connection to %v was added to the pool (%d/%d).",
+			pc.connectorType, sc.conn.RemoteAddr(), recv.countGroup(key), recv.size)
+	} else {
+		pc.closeHandshakeConn()
+		log.Debugf("[%s] Pool for %v is full, using shared connection.", pc.connectorType, sc.conn.RemoteAddr())
+	}
+	pc.setSharedConn(sc, "")
+	recv.fill(pc, key, nil)
+	return nil
+}
+
+// switchKeyspace moves the provided pooledConn to the group of shared connections that have the provided keyspace
+// set on them. A new shared connection is opened (replaying the handshake of pooledConn) if the group has room for it.
+//
+// Returns the response to the USE request if it was executed while opening a new shared connection, or nil if
+// the USE request still needs to be sent.
+func (recv *clusterConnPool) switchKeyspace(pc *pooledConn, useRequest *frame.RawFrame, keyspace string) (*frame.RawFrame, error) {
+	key := pc.groupKey(keyspace)
+
+	recv.lock.Lock()
+	existing := recv.groups[key]
+	if len(existing) >= recv.size {
+		sc := leastUsedSharedConn(existing)
+		sc.clients[pc] = true
+		recv.lock.Unlock()
+		pc.setSharedConn(sc, keyspace)
+		return nil, nil
+	}
+	recv.lock.Unlock()
+
+	conn, reader, framing, useResponse, err := pc.openSharedConn(pc.connInfo.endpoint, useRequest)
+	if err != nil {
+		recv.lock.Lock()
+		existing = recv.groups[key]
+		if len(existing) == 0 {
+			recv.lock.Unlock()
+			return nil, err
+		}
+		log.Warnf("[%s] Could not open new pooled connection for keyspace %v, using existing one: %v",
+			pc.connectorType, keyspace, err)
+		sc := leastUsedSharedConn(existing)
+		sc.clients[pc] = true
+		recv.lock.Unlock()
+		pc.setSharedConn(sc, keyspace)
+		return nil, nil
+	}
+
+	if useResponse.Header.OpCode != primitive.OpCodeResult {
+		// USE failed (e.g. keyspace does not exist), keep the current shared connection
+		closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+		return useResponse, nil
+	}
+
+	recv.lock.Lock()
+	if recv.ctx.Err() != nil {
+		recv.lock.Unlock()
+		closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+		return nil, ShutdownErr
+	}
+	var sc *sharedConn
+	if len(recv.groups[key]) < recv.size {
+		sc = newSharedConn(recv, key, conn, reader, framing, pc.nodeMetricsInstance)
+		recv.groups[key] = append(recv.groups[key], sc)
+	} else {
+		sc = leastUsedSharedConn(recv.groups[key])
+	}
+	sc.clients[pc] = true
+	recv.lock.Unlock()
+
+	if sc.conn == conn {
+		sc.run()
+		log.Infof("[%s] Connection to %v with keyspace %v was added to the pool (%d/%d).",
+			pc.connectorType, sc.conn.RemoteAddr(), keyspace, recv.countGroup(key), recv.size)
+	} else {
+		closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+	}
+	pc.setSharedConn(sc, keyspace)
+	recv.fill(pc, key, useRequest)
+	return useResponse, nil
+}
+
+// fill opens the connections of the group of a private pool in the background until it has size connections,
+// they are used by the provided pooledConn to dispatch its requests (see pooledConn.dispatch).
+func (recv *clusterConnPool) fill(pc *pooledConn, key sharedConnGroupKey, useRequest *frame.RawFrame) {
+	if !recv.private {
+		return
+	}
+	if useRequest != nil {
+		useRequest = useRequest.Clone()
+	}
+	recv.wg.Add(1)
+	go func() {
+		defer recv.wg.Done()
+		for recv.countGroup(key) < recv.size {
+			conn, reader, framing, useResponse, err := pc.openSharedConn(pc.connInfo.endpoint, useRequest)
+			if err != nil {
+				if pc.ctx.Err() == nil {
+					log.Warnf("[%s] Could not open additional connection to %v (%d/%d): %v",
+						pc.connectorType, pc.remoteAddr, recv.countGroup(key), recv.size, err)
+				}
+				return
+			}
+			if useResponse != nil && useResponse.Header.OpCode != primitive.OpCodeResult {
+				closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+				log.Warnf("[%s] Could not set keyspace on additional connection to %v: %v",
+					pc.connectorType, pc.remoteAddr, useResponse.Header.OpCode)
+				return
+			}
+
+			recv.lock.Lock()
+			if recv.ctx.Err() != nil || len(recv.groups[key]) == 0 || len(recv.groups[key]) >= recv.size {
+				// the pooledConn is closed or switched to another keyspace
+				recv.lock.Unlock()
+				closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+				return
+			}
+			sc := newSharedConn(recv, key, conn, reader, framing, pc.nodeMetricsInstance)
+			recv.groups[key] = append(recv.groups[key], sc)
+			sc.routedClients[pc] = true
+			recv.lock.Unlock()
+
+			sc.run()
+			if !pc.addLane(sc, key.keyspace) {
+				recv.release(pc, sc)
+				return
+			}
+			log.Debugf("[%s] Opened additional connection to %v (%d/%d).",
+				pc.connectorType, sc.conn.RemoteAddr(), recv.countGroup(key), recv.size)
+		}
+	}()
+}
+
+// release removes the provided pooledConn from the shared connection. Shared connections that are no longer used
+// by any pooledConn are removed from the pool and closed once their in flight requests are done.
+func (recv *clusterConnPool) release(pc *pooledConn, sc *sharedConn) {
+	recv.lock.Lock()
+	delete(sc.clients, pc)
+	delete(sc.routedClients, pc)
+	retire := len(sc.clients) == 0 && len(sc.routedClients) == 0
+	if retire {
+		recv.removeLocked(sc)
+	}
+	recv.lock.Unlock()
+
+	if retire {
+		sc.retire()
+	}
+}
+
+// routedSharedConn returns a shared connection to the provided endpoint that the provided pooledConn can use
+// (same handshake and keyspace). If the pool has no such connection, one is opened in the background and nil is
+// returned so the request is forwarded to the shared connection of the pooledConn instead.
+func (recv *clusterConnPool) routedSharedConn(pc *pooledConn, endpoint Endpoint) *sharedConn {
+	endpointId := endpoint.GetEndpointIdentifier()
+	if sc := pc.getRoute(endpointId); sc != nil {
+		return sc
+	}
+
+	keyspace := pc.getKeyspace()
+	key := pc.groupKeyFor(endpoint, keyspace)
+	recv.lock.Lock()
+	if existing := recv.groups[key]; len(existing) > 0 {
+		sc := leastUsedSharedConn(existing)
+		sc.routedClients[pc] = true
+		recv.lock.Unlock()
+		if !pc.addRoute(endpointId, sc, keyspace) {
+			recv.release(pc, sc)
+			return nil
+		}
+		return sc
+	}
+	lastFailure, failed := recv.routeFailures[endpointId]
+	if recv.ctx.Err() != nil || recv.opening[key] || (failed && time.Since(lastFailure) < routedSharedConnRetryDelay) {
+		recv.lock.Unlock()
+		return nil
+	}
+	recv.opening[key] = true
+	recv.wg.Add(1)
+	recv.lock.Unlock()
+
+	go func() {
+		defer recv.wg.Done()
+		recv.openRoutedSharedConn(pc, endpoint, key)
+	}()
+	return nil
+}
+
+// openRoutedSharedConn replays the handshake of the provided pooledConn on a new connection to the provided endpoint,
+// sets the keyspace of the group on it and adds it to the pool.
+func (recv *clusterConnPool) openRoutedSharedConn(pc *pooledConn, endpoint Endpoint, key sharedConnGroupKey) {
+	useRequest, err := newUseRequest(key)
+	var conn net.Conn
+	var reader *bufio.Reader
+	var framing *connFraming
+	if err == nil {
+		var useResponse *frame.RawFrame
+		conn, reader, framing, useResponse, err = pc.openSharedConn(endpoint, useRequest)
+		if err == nil && useResponse != nil && useResponse.Header.OpCode != primitive.OpCodeResult {
+			closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+			err = fmt.Errorf("unexpected %v response to USE request", useResponse.Header.OpCode)
+		}
+	}
+
+	recv.lock.Lock()
+	delete(recv.opening, key)
+	if err != nil {
+		recv.routeFailures[key.endpoint] = time.Now()
+		recv.lock.Unlock()
+		if pc.ctx.Err() == nil {
+			log.Warnf("[%s] Could not open pooled connection to %v for token aware routing: %v",
+				pc.connectorType, endpoint, err)
+		}
+		return
+	}
+	delete(recv.routeFailures, key.endpoint)
+	if recv.ctx.Err() != nil || len(recv.groups[key]) >= recv.size {
+		recv.lock.Unlock()
+		closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+		return
+	}
+	// the open connections metric of the node of the pooledConn tracks this connection
+	sc := newSharedConn(recv, key, conn, reader, framing, pc.nodeMetricsInstance)
+	recv.groups[key] = append(recv.groups[key], sc)
+	sc.routedClients[pc] = true
+	recv.lock.Unlock()
+
+	sc.run()
+	log.Infof("[%s] Connection to %v was added to the pool for token aware routing (%d/%d).",
+		pc.connectorType, sc.conn.RemoteAddr(), recv.countGroup(key), recv.size)
+	if !pc.addRoute(key.endpoint, sc, key.keyspace) {
+		recv.release(pc, sc)
+	}
+}
+
+// replace closes the provided shared connection, its clients are moved to another connection of its group
+// (see reassign) and the requests that were in flight on it get an error response.
+func (recv *clusterConnPool) replace(sc *sharedConn) {
+	recv.lock.Lock()
+	if recv.ctx.Err() != nil {
+		recv.lock.Unlock()
+		return
+	}
+	var owner *pooledConn
+	for client := range sc.clients {
+		owner = client
+	}
+	for client := range sc.routedClients {
+		owner = client
+	}
+	recv.lock.Unlock()
+
+	sc.close()
+
+	if recv.private && owner != nil {
+		// the private pool opens a new lane if the replaced connection was one of them
+		useRequest, err := newUseRequest(sc.key)
+		if err == nil {
+			recv.fill(owner, sc.key, useRequest)
+		}
+	}
+}
+
+// reassign moves the clients of the provided shared connection, which was closed, to a new connection of its group,
+// or to one of the other connections of the group if a new one can't be opened. The clients are closed if the group
+// has no other connection or if the pool is shut down.
+func (recv *clusterConnPool) reassign(sc *sharedConn, clients []*pooledConn) {
+	if recv.ctx.Err() == nil && recv.openReplacement(sc, clients) != nil {
+		return
+	}
+	for _, client := range clients {
+		client.close()
+	}
+}
+
+// openReplacement replays the handshake, the keyspace and the events of the provided clients on a new connection to
+// the node of the provided shared connection and moves the clients to it. Returns nil if the clients couldn't be moved.
+func (recv *clusterConnPool) openReplacement(sc *sharedConn, clients []*pooledConn) *sharedConn {
+	pc := clients[0]
+	for _, client := range clients {
+		if client.ctx.Err() == nil {
+			pc = client
+			break
+		}
+	}
+
+	useRequest, err := newUseRequest(sc.key)
+	var conn net.Conn
+	var reader *bufio.Reader
+	var framing *connFraming
+	if err == nil {
+		var useResponse *frame.RawFrame
+		conn, reader, framing, useResponse, err = pc.openSharedConn(pc.connInfo.endpoint, useRequest)
+		if err == nil && useResponse != nil && useResponse.Header.OpCode != primitive.OpCodeResult {
+			closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+			err = fmt.Errorf("unexpected %v response to USE request", useResponse.Header.OpCode)
+		}
+	}
+	if err == nil {
+		err = registerEvents(conn, reader, framing, pc.ctx, sc.key.version, clients)
+		if err != nil {
+			closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+		}
+	}
+	if err != nil && pc.ctx.Err() == nil {
+		log.Warnf("[%s] Could not open connection to %v to replace pooled connection: %v",
+			pc.connectorType, pc.remoteAddr, err)
+	}
+
+	recv.lock.Lock()
+	var replacement *sharedConn
+	opened := false
+	if recv.ctx.Err() == nil {
+		if err == nil && len(recv.groups[sc.key]) < recv.size {
+			replacement = newSharedConn(recv, sc.key, conn, reader, framing, pc.nodeMetricsInstance)
+			recv.groups[sc.key] = append(recv.groups[sc.key], replacement)
+			opened = true
+		} else if existing := recv.groups[sc.key]; len(existing) > 0 {
+			replacement = leastUsedSharedConn(existing)
+		}
+	}
+	if replacement != nil {
+		for _, client := range clients {
+			replacement.clients[client] = true
+		}
+	}
+	recv.lock.Unlock()
+
+	if err == nil && !opened {
+		closePooledConnection(conn, string(pc.connectorType), pc.nodeMetricsInstance)
+	}
+	if replacement == nil {
+		return nil
+	}
+	if opened {
+		replacement.run()
+		log.Infof("[%s] Connection to %v was replaced in the pool (%d/%d).",
+			pc.connectorType, replacement.conn.RemoteAddr(), recv.countGroup(sc.key), recv.size)
+	} else if err = replacement.replayEvents(clients); err != nil {
+		log.Warnf("[%s] Could not register events on pooled connection to %v: %v",
+			pc.connectorType, replacement.conn.RemoteAddr(), err)
+	}
+	for _, client := range clients {
+		client.replaceSharedConn(sc, replacement)
+	}
+	return replacement
+}
+
+func (recv *clusterConnPool) removeLocked(sc *sharedConn) {
+	conns := recv.groups[sc.key]
+	for i, conn := range conns {
+		if conn == sc {
+			conns = append(conns[:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(recv.groups, sc.key)
+	} else {
+		recv.groups[sc.key] = conns
+	}
+}
+
+// findPendingRequest returns the shared connection and its stream id that are assigned to the provided client request.
+func (recv *clusterConnPool) findPendingRequest(client *pooledConn, clientStreamId int16) (*sharedConn, int16, bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for _, group := range recv.groups {
+		for _, sc := range group {
+			if streamId, ok := sc.streamIds.find(client, clientStreamId); ok {
+				return sc, streamId, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// spareSharedConn returns the shared connection of the group of the provided one that has the most free stream ids,
+// or nil if none of them has free stream ids.
+func (recv *clusterConnPool) spareSharedConn(exhausted *sharedConn) *sharedConn {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	var spare *sharedConn
+	for _, sc := range recv.groups[exhausted.key] {
+		if sc == exhausted || sc.streamIds.available() == 0 {
+			continue
+		}
+		if spare == nil || sc.streamIds.available() > spare.streamIds.available() {
+			spare = sc
+		}
+	}
+	return spare
+}
+
+func (recv *clusterConnPool) countGroup(key sharedConnGroupKey) int {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return len(recv.groups[key])
+}
+
+// Shutdown closes all shared connections.
+func (recv *clusterConnPool) Shutdown() {
+	recv.lock.Lock()
+	recv.cancelFn()
+	var conns []*sharedConn
+	for _, group := range recv.groups {
+		conns = append(conns, group...)
+	}
+	recv.lock.Unlock()
+
+	for _, sc := range conns {
+		sc.close()
+	}
+	recv.wg.Wait()
+}
+
+// newUseRequest returns the USE request that sets the keyspace of the provided group on a new shared connection,
+// or nil if the group has no keyspace.
+func newUseRequest(key sharedConnGroupKey) (*frame.RawFrame, error) {
+	if key.keyspace == "" {
+		return nil, nil
+	}
+	return defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		key.version, 0, &message.Query{Query: fmt.Sprintf("USE \"%v\"", strings.ReplaceAll(key.keyspace, "\"", "\"\""))}))
+}
+
+// registerEvents sends a REGISTER request with the events that the provided clients registered for to a new shared
+// connection that has no other request in flight.
+func registerEvents(
+	conn net.Conn, reader *bufio.Reader, framing *connFraming, ctx context.Context, version primitive.ProtocolVersion,
+	clients []*pooledConn) error {
+	register := newRegisterMessage(clients)
+	if register == nil {
+		return nil
+	}
+	request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, 0, register))
+	if err != nil {
+		return err
+	}
+	response, err := sendAndReceiveFrame(conn, reader, framing, ctx, request)
+	if err != nil {
+		return err
+	}
+	if response.Header.OpCode != primitive.OpCodeReady {
+		return fmt.Errorf("unexpected %v response to REGISTER request", response.Header.OpCode)
+	}
+	return nil
+}
+
+// newRegisterMessage returns a REGISTER message with the events that the provided clients registered for,
+// or nil if they didn't register for any event.
+func newRegisterMessage(clients []*pooledConn) *message.Register {
+	events := make(map[primitive.EventType]bool)
+	for _, client := range clients {
+		client.lock.Lock()
+		for eventType := range client.events {
+			events[eventType] = true
+		}
+		client.lock.Unlock()
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	register := &message.Register{}
+	for eventType := range events {
+		register.EventTypes = append(register.EventTypes, eventType)
+	}
+	return register
+}
+
+func leastUsedSharedConn(conns []*sharedConn) *sharedConn {
+	var leastUsed *sharedConn
+	for _, sc := range conns {
+		if leastUsed == nil || len(sc.clients) < len(leastUsed.clients) {
+			leastUsed = sc
+		}
+	}
+	return leastUsed
+}
+
+func closePooledConnection(conn net.Conn, logPrefix string, nodeMetricsInstance *metrics.NodeMetricsInstance) {
+	err := conn.Close()
+	if err != nil {
+		log.Debugf("[%s] Error closing pooled connection to %v: %v", logPrefix, conn.RemoteAddr(), err)
+	}
+	if nodeMetricsInstance != nil {
+		nodeMetricsInstance.OpenConnections.Subtract(1)
+	}
+}
+
+// pooledPipeConn is the end of the pipe that is used by the cluster connector,
+// it reports the address of the cluster node as the remote address.
+type pooledPipeConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (recv *pooledPipeConn) RemoteAddr() net.Addr {
+	return recv.remoteAddr
+}
+
+// pooledConn forwards the frames written by a single cluster connector to a shared connection
+// and writes the responses routed back to it.
+type pooledConn struct {
+	pool                *clusterConnPool
+	connInfo            *ClusterConnectionInfo
+	connectorType       ClusterConnectorType
+	nodeMetricsInstance *metrics.NodeMetricsInstance
+
+	pipe       net.Conn
+	remoteAddr net.Addr
+
+	lock             *sync.Mutex
+	handshakeConn    net.Conn
+	handshakeFraming *connFraming
+	handshakeFrames  []*frame.RawFrame // STARTUP and AUTH_RESPONSE frames, replayed when a new shared connection is opened
+	version          primitive.ProtocolVersion
+	shared           *sharedConn
+	keyspace         string
+	events           map[primitive.EventType]bool
+	routes           map[string]*sharedConn // shared connections used for token aware routing, keyed on endpoint
+	lanes            []*sharedConn          // additional shared connections of a private pool, see dispatch
+	nextLane         uint32
+
+	responses chan *frame.RawFrame
+
+	ctx       context.Context
+	cancelFn  context.CancelFunc
+	closeOnce *sync.Once
+}
+
+func (recv *pooledConn) run(handshakeReader *bufio.Reader) {
+	recv.pool.wg.Add(3)
+	go func() {
+		defer recv.pool.wg.Done()
+		recv.runHandshakeResponseLoop(handshakeReader)
+	}()
+	go func() {
+		defer recv.pool.wg.Done()
+		recv.runRequestLoop()
+	}()
+	go func() {
+		defer recv.pool.wg.Done()
+		recv.runResponseWriteLoop()
+	}()
+}
+
+// Reads the handshake responses from the dedicated connection until the handshake is done.
+func (recv *pooledConn) runHandshakeResponseLoop(reader *bufio.Reader) {
+	recv.lock.Lock()
+	conn := recv.handshakeConn
+	recv.lock.Unlock()
+	if conn == nil {
+		return
+	}
+
+	addr := conn.RemoteAddr().String()
+	for {
+		response, err := recv.handshakeFraming.readFrame(reader, addr, recv.ctx)
+		if err != nil {
+			handleConnectionError(err, recv.ctx, recv.close, string(recv.connectorType), "reading handshake response", addr)
+			return
+		}
+
+		switch response.Header.OpCode {
+		case primitive.OpCodeReady, primitive.OpCodeAuthSuccess:
+			err = recv.pool.attach(recv, conn, reader)
+			if err != nil {
+				if !errors.Is(err, ShutdownErr) {
+					log.Errorf("[%s] Could not add connection to %v to the pool: %v", recv.connectorType, addr, err)
+				}
+				recv.close()
+				return
+			}
+			recv.sendResponse(response)
+			return
+		case primitive.OpCodeError:
+			recv.lock.Lock()
+			if n := len(recv.handshakeFrames); n > 0 && recv.handshakeFrames[n-1].Header.OpCode == primitive.OpCodeAuthResponse {
+				// the client may send a different AUTH_RESPONSE, the failed one must not be replayed
+				recv.handshakeFrames = recv.handshakeFrames[:n-1]
+			}
+			recv.lock.Unlock()
+		}
+		recv.sendResponse(response)
+	}
+}
+
+// Reads the frames that the cluster connector writes to the pipe and forwards them to the dedicated connection
+// (during the handshake) or to the shared connection.
+func (recv *pooledConn) runRequestLoop() {
+	defer recv.close()
+	reader := bufio.NewReaderSize(recv.pipe, recv.pool.conf.RequestReadBufferSizeBytes)
+	addr := recv.remoteAddr.String()
+	for {
+		request, err := readPooledRawFrame(reader, addr, recv.ctx)
+		if err != nil {
+			if !errors.Is(err, ShutdownErr) {
+				log.Debugf("[%s] Pooled connection to %v closed by cluster connector: %v", recv.connectorType, addr, err)
+			}
+			return
+		}
+
+		recv.lock.Lock()
+		sc := recv.shared
+		handshakeConn := recv.handshakeConn
+		if sc == nil {
+			switch request.Header.OpCode {
+			case primitive.OpCodeStartup:
+				recv.version = request.Header.Version
+				recv.handshakeFrames = []*frame.RawFrame{request.Clone()}
+			case primitive.OpCodeAuthResponse:
+				recv.handshakeFrames = append(recv.handshakeFrames, request.Clone())
+			}
+		}
+		recv.lock.Unlock()
+
+		if sc == nil {
+			if handshakeConn == nil {
+				log.Errorf("[%s] Received %v before handshake was done but handshake connection is closed.",
+					recv.connectorType, request.Header.OpCode)
+				return
+			}
+			err = recv.handshakeFraming.writeFrameToConn(handshakeConn, addr, recv.ctx, request)
+			releaseFrameBody(request)
+			if err != nil {
+				handleConnectionError(err, recv.ctx, recv.close, string(recv.connectorType), "writing handshake request", addr)
+				return
+			}
+			continue
+		}
+
+		if request.Header.OpCode == primitive.OpCodeRegister {
+			recv.trackRegisteredEvents(request)
+		} else if keyspace, ok := readUseStatementKeyspace(request); ok && keyspace != recv.getKeyspace() {
+			useResponse, err := recv.pool.switchKeyspace(recv, request, keyspace)
+			if err != nil {
+				if errors.Is(err, ShutdownErr) {
+					return
+				}
+				log.Warnf("[%s] Could not open pooled connection to %v for keyspace %v: %v",
+					recv.connectorType, addr, keyspace, err)
+				recv.sendErrorResponse(request, &message.ServerError{
+					ErrorMessage: fmt.Sprintf("Proxy could not open pooled connection for keyspace %v: %v", keyspace, err)})
+				continue
+			}
+			if useResponse != nil {
+				useResponse.Header.StreamId = request.Header.StreamId
+				recv.sendResponse(useResponse)
+				continue
+			}
+			sc = recv.getSharedConn()
+		} else if request.Header.OpCode == primitive.OpCodeDseRevise {
+			sc = recv.reviseSharedConn(sc, request)
+		} else if router := recv.connInfo.tokenRouter; router != nil && isTokenRoutableRequest(request) {
+			if recv.sendToReplica(router, request) {
+				continue
+			}
+		}
+
+		err = recv.send(recv.dispatch(sc, request), request)
+		if err != nil {
+			if errors.Is(err, errStreamIdsExhausted) {
+				log.Warnf("[%s] %v, returning OVERLOADED.", recv.connectorType, err)
+				recv.sendErrorResponse(request, &message.Overloaded{
+					ErrorMessage: "Proxy pooled connection ran out of stream ids, please retry on next host."})
+				continue
+			}
+			if errors.Is(err, errSharedConnClosed) && recv.ctx.Err() == nil {
+				// the clients of the shared connection are being moved to another one or closed (see reassign)
+				recv.sendErrorResponse(request, &message.Overloaded{ErrorMessage: sharedConnClosedErrorMessage})
+				continue
+			}
+			return
+		}
+	}
+}
+
+// Writes the responses to the pipe, they are read by the cluster connector.
+func (recv *pooledConn) runResponseWriteLoop() {
+	addr := recv.remoteAddr.String()
+	buffer := &bytes.Buffer{}
+	for {
+		select {
+		case response := <-recv.responses:
+			buffer.Reset()
+			err := writeRawFrame(buffer, addr, recv.ctx, response)
+			if response.Header.OpCode != primitive.OpCodeEvent {
+				// events are sent to every pooledConn that registered for them
+				releaseFrameBody(response)
+			}
+			if err == nil {
+				_, err = recv.pipe.Write(buffer.Bytes())
+			}
+			if err != nil {
+				if recv.ctx.Err() == nil {
+					log.Debugf("[%s] Could not write response to pooled connection: %v", recv.connectorType, err)
+				}
+				recv.close()
+				return
+			}
+		case <-recv.ctx.Done():
+			recv.close()
+			return
+		}
+	}
+}
+
+// dispatch returns the shared connection of a private pool that the provided request should be sent to according to
+// ZDM_PROXY_CLUSTER_CONNECTION_DISPATCH_POLICY. The other requests (e.g. REGISTER) are always sent to the provided
+// shared connection so that events are received once.
+func (recv *pooledConn) dispatch(sc *sharedConn, request *frame.RawFrame) *sharedConn {
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute, primitive.OpCodeBatch, primitive.OpCodePrepare:
+	default:
+		return sc
+	}
+	recv.lock.Lock()
+	lanes := recv.lanes
+	recv.lock.Unlock()
+	if len(lanes) == 0 {
+		return sc
+	}
+
+	if recv.pool.dispatchPolicy == common.ConnectionDispatchPolicyLeastInFlight {
+		leastInFlight := sc
+		for _, lane := range lanes {
+			if atomic.LoadInt32(&lane.inFlight) < atomic.LoadInt32(&leastInFlight.inFlight) {
+				leastInFlight = lane
+			}
+		}
+		return leastInFlight
+	}
+	next := int(atomic.AddUint32(&recv.nextLane, 1) % uint32(len(lanes)+1))
+	if next == len(lanes) {
+		return sc
+	}
+	return lanes[next]
+}
+
+// Forwards the provided request to the provided shared connection, if it ran out of stream ids the request is forwarded
+// to another shared connection of the same group instead.
+func (recv *pooledConn) send(sc *sharedConn, request *frame.RawFrame) error {
+	streamId := request.Header.StreamId
+	err := sc.send(recv, request)
+	if !errors.Is(err, errStreamIdsExhausted) {
+		if err != nil {
+			// send replaces the stream id before it finds out that the connection is closed
+			request.Header.StreamId = streamId
+		}
+		return err
+	}
+	spare := recv.pool.spareSharedConn(sc)
+	if spare == nil {
+		return err
+	}
+	if spareErr := spare.send(recv, request); spareErr != nil {
+		request.Header.StreamId = streamId
+		return err
+	}
+	log.Debugf("[%s] %v, request was sent to %v instead.", recv.connectorType, err, spare.conn.LocalAddr())
+	return nil
+}
+
+// reviseSharedConn returns the shared connection of the continuous paging request that the provided REVISE request
+// targets and replaces the target stream id of the REVISE request with the stream id of that shared connection.
+// Returns the provided shared connection if the continuous paging request is not in flight anymore.
+func (recv *pooledConn) reviseSharedConn(sc *sharedConn, request *frame.RawFrame) *sharedConn {
+	clientStreamId, ok := peekReviseTargetStreamId(request)
+	if !ok {
+		return sc
+	}
+	pending, streamId, ok := recv.pool.findPendingRequest(recv, clientStreamId)
+	if !ok {
+		return sc
+	}
+	setReviseTargetStreamId(request, streamId)
+	return pending
+}
+
+// Forwards the provided request to a shared connection of the node that owns its partition key.
+// Returns false if the request should be sent to the shared connection of this pooledConn instead.
+func (recv *pooledConn) sendToReplica(router *tokenRouter, request *frame.RawFrame) bool {
+	host := router.replica(request, recv.getKeyspace())
+	if host == nil {
+		return false
+	}
+	endpoint := recv.connInfo.connConfig.CreateEndpoint(host)
+	if endpoint.GetEndpointIdentifier() == recv.connInfo.endpoint.GetEndpointIdentifier() {
+		return false
+	}
+	sc := recv.pool.routedSharedConn(recv, endpoint)
+	if sc == nil {
+		return false
+	}
+
+	streamId := request.Header.StreamId
+	err := sc.send(recv, request)
+	if err != nil {
+		// send replaces the stream id before it finds out that the connection is closed
+		request.Header.StreamId = streamId
+		if errors.Is(err, errSharedConnClosed) {
+			recv.removeRoute(sc)
+		}
+		return false
+	}
+	return true
+}
+
+// Opens a new connection to the provided endpoint, replays the handshake of this pooledConn and sends the provided
+// USE request (if any).
+func (recv *pooledConn) openSharedConn(
+	endpoint Endpoint, useRequest *frame.RawFrame) (net.Conn, *bufio.Reader, *connFraming, *frame.RawFrame, error) {
+	recv.lock.Lock()
+	handshakeFrames := recv.handshakeFrames
+	recv.lock.Unlock()
+
+	conn, _, err := openConnection(recv.connInfo.connConfig, endpoint, recv.ctx, false)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if recv.nodeMetricsInstance != nil {
+		recv.nodeMetricsInstance.OpenConnections.Add(1)
+	}
+
+	reader := bufio.NewReaderSize(conn, recv.pool.conf.ResponseReadBufferSizeBytes)
+	framing := newConnFraming()
+	err = replayHandshake(conn, reader, framing, recv.ctx, handshakeFrames)
+	if err != nil {
+		closePooledConnection(conn, string(recv.connectorType), recv.nodeMetricsInstance)
+		return nil, nil, nil, nil, err
+	}
+	if useRequest == nil {
+		return conn, reader, framing, nil, nil
+	}
+
+	useResponse, err := sendAndReceiveFrame(conn, reader, framing, recv.ctx, useRequest.Clone())
+	if err != nil {
+		closePooledConnection(conn, string(recv.connectorType), recv.nodeMetricsInstance)
+		return nil, nil, nil, nil, err
+	}
+	return conn, reader, framing, useResponse, nil
+}
+
+// replayHandshake sends the STARTUP and AUTH_RESPONSE frames of a handshake that succeeded on another connection
+// to the provided connection until the cluster returns READY or AUTH_SUCCESS.
+func replayHandshake(
+	conn net.Conn, reader *bufio.Reader, framing *connFraming, ctx context.Context, handshakeFrames []*frame.RawFrame) error {
+	for _, request := range handshakeFrames {
+		response, err := sendAndReceiveFrame(conn, reader, framing, ctx, request)
+		if err != nil {
+			return err
+		}
+		opCode := response.Header.OpCode
+		if opCode == primitive.OpCodeReady || opCode == primitive.OpCodeAuthSuccess {
+			return nil
+		}
+		if opCode != primitive.OpCodeAuthenticate && opCode != primitive.OpCodeAuthChallenge {
+			return fmt.Errorf("unexpected %v response while replaying handshake", opCode)
+		}
+	}
+	return errors.New("handshake could not be replayed")
+}
+
+// sendAndReceiveFrame writes the provided request to a connection that has no other request in flight and reads
+// its response.
+func sendAndReceiveFrame(
+	conn net.Conn, reader *bufio.Reader, framing *connFraming, ctx context.Context, request *frame.RawFrame) (*frame.RawFrame, error) {
+	addr := conn.RemoteAddr().String()
+	err := framing.writeFrameToConn(conn, addr, ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return framing.readFrame(reader, addr, ctx)
+}
+
+func (recv *pooledConn) trackRegisteredEvents(request *frame.RawFrame) {
+	body, err := defaultCodec.DecodeBody(request.Header, bytes.NewReader(request.Body))
+	if err != nil {
+		log.Warnf("[%s] Could not decode REGISTER request: %v", recv.connectorType, err)
+		return
+	}
+	register, ok := body.Message.(*message.Register)
+	if !ok {
+		return
+	}
+	recv.lock.Lock()
+	for _, eventType := range register.EventTypes {
+		recv.events[eventType] = true
+	}
+	recv.lock.Unlock()
+}
+
+func (recv *pooledConn) isRegisteredFor(eventType primitive.EventType) bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.events[eventType]
+}
+
+// Computes the key of the group of shared connections that this pooledConn can use.
+func (recv *pooledConn) groupKey(keyspace string) sharedConnGroupKey {
+	return recv.groupKeyFor(recv.connInfo.endpoint, keyspace)
+}
+
+// Computes the key of the group of shared connections to the provided endpoint that this pooledConn can use.
+func (recv *pooledConn) groupKeyFor(endpoint Endpoint, keyspace string) sharedConnGroupKey {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	hash := sha256.New()
+	for _, f := range recv.handshakeFrames {
+		hash.Write([]byte{byte(f.Header.OpCode)})
+		if options, ok := readStartupOptions(f); ok {
+			// map encoding order is not deterministic so the STARTUP body can't be hashed directly
+			keys := make([]string, 0, len(options))
+			for k := range options {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				hash.Write([]byte(k + "=" + options[k] + ";"))
+			}
+			continue
+		}
+		hash.Write(f.Body)
+	}
+	return sharedConnGroupKey{
+		endpoint:  endpoint.GetEndpointIdentifier(),
+		version:   recv.version,
+		handshake: hex.EncodeToString(hash.Sum(nil)),
+		keyspace:  keyspace,
+	}
+}
+
+func (recv *pooledConn) setSharedConn(sc *sharedConn, keyspace string) {
+	recv.lock.Lock()
+	previous := recv.shared
+	recv.shared = sc
+	var routes map[string]*sharedConn
+	var lanes []*sharedConn
+	if keyspace != recv.keyspace {
+		// the routed shared connections and the lanes have the previous keyspace set on them
+		routes = recv.routes
+		recv.routes = make(map[string]*sharedConn)
+		lanes = recv.lanes
+		recv.lanes = nil
+	}
+	recv.keyspace = keyspace
+	recv.lock.Unlock()
+
+	if previous != nil && previous != sc {
+		recv.pool.release(recv, previous)
+		// the events that this pooledConn registered for may not be registered on the new shared connection
+		if err := sc.replayEvents([]*pooledConn{recv}); err != nil {
+			log.Warnf("[%s] Could not register events on pooled connection to %v: %v",
+				recv.connectorType, sc.conn.RemoteAddr(), err)
+		}
+	}
+	for _, routed := range routes {
+		recv.pool.release(recv, routed)
+	}
+	for _, lane := range lanes {
+		recv.pool.release(recv, lane)
+	}
+}
+
+// addLane returns false if the lane can't be used because this pooledConn is closed or switched to another keyspace.
+func (recv *pooledConn) addLane(sc *sharedConn, keyspace string) bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.ctx.Err() != nil || recv.keyspace != keyspace {
+		return false
+	}
+	recv.lanes = append(recv.lanes, sc)
+	return true
+}
+
+func (recv *pooledConn) getRoute(endpointId string) *sharedConn {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.routes[endpointId]
+}
+
+// addRoute returns false if the route can't be used because this pooledConn is closed or switched to another keyspace.
+func (recv *pooledConn) addRoute(endpointId string, sc *sharedConn, keyspace string) bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.ctx.Err() != nil || recv.keyspace != keyspace {
+		return false
+	}
+	if existing, ok := recv.routes[endpointId]; ok && existing != sc {
+		return false
+	}
+	recv.routes[endpointId] = sc
+	return true
+}
+
+// removeRoute stops using the provided shared connection for token aware routing and for dispatching requests.
+func (recv *pooledConn) removeRoute(sc *sharedConn) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for endpointId, routed := range recv.routes {
+		if routed == sc {
+			delete(recv.routes, endpointId)
+		}
+	}
+	for i, lane := range recv.lanes {
+		if lane == sc {
+			lanes := make([]*sharedConn, 0, len(recv.lanes)-1)
+			lanes = append(lanes, recv.lanes[:i]...)
+			recv.lanes = append(lanes, recv.lanes[i+1:]...)
+			break
+		}
+	}
+}
+
+// replaceSharedConn switches this pooledConn from a shared connection that is being replaced to its replacement.
+func (recv *pooledConn) replaceSharedConn(previous *sharedConn, sc *sharedConn) {
+	recv.lock.Lock()
+	replaced := recv.shared == previous
+	if replaced {
+		recv.shared = sc
+	}
+	recv.lock.Unlock()
+	if !replaced {
+		// closed or switched to another keyspace in the meantime
+		recv.pool.release(recv, sc)
+	}
+}
+
+func (recv *pooledConn) getSharedConn() *sharedConn {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.shared
+}
+
+func (recv *pooledConn) getKeyspace() string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.keyspace
+}
+
+func (recv *pooledConn) releaseHandshakeConn() {
+	recv.lock.Lock()
+	recv.handshakeConn = nil
+	recv.lock.Unlock()
+}
+
+func (recv *pooledConn) closeHandshakeConn() {
+	recv.lock.Lock()
+	conn := recv.handshakeConn
+	recv.handshakeConn = nil
+	recv.lock.Unlock()
+	if conn != nil {
+		closePooledConnection(conn, string(recv.connectorType), recv.nodeMetricsInstance)
+	}
+}
+
+func (recv *pooledConn) sendResponse(response *frame.RawFrame) {
+	select {
+	case recv.responses <- response:
+	case <-recv.ctx.Done():
+	}
+}
+
+func (recv *pooledConn) sendErrorResponse(request *frame.RawFrame, msg message.Message) {
+	recv.sendError(request.Header.Version, request.Header.StreamId, msg)
+}
+
+func (recv *pooledConn) sendError(version primitive.ProtocolVersion, streamId int16, msg message.Message) {
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, streamId, msg))
+	if err != nil {
+		log.Errorf("[%s] Could not convert %v to raw frame: %v", recv.connectorType, msg, err)
+		return
+	}
+	recv.sendResponse(response)
+}
+
+func (recv *pooledConn) close() {
+	recv.closeOnce.Do(func() {
+		recv.cancelFn()
+		err := recv.pipe.Close()
+		if err != nil {
+			log.Debugf("[%s] Error closing pooled connection pipe: %v", recv.connectorType, err)
+		}
+		recv.closeHandshakeConn()
+
+		recv.lock.Lock()
+		sc := recv.shared
+		recv.shared = nil
+		routes := recv.routes
+		recv.routes = make(map[string]*sharedConn)
+		lanes := recv.lanes
+		recv.lanes = nil
+		recv.lock.Unlock()
+		if sc != nil {
+			recv.pool.release(recv, sc)
+		}
+		for _, routed := range routes {
+			recv.pool.release(recv, routed)
+		}
+		for _, lane := range lanes {
+			recv.pool.release(recv, lane)
+		}
+		if recv.pool.private {
+			// stops the connections of the private pool that are still being opened
+			recv.pool.cancelFn()
+		}
+	})
+}
+
+// sharedConn is a cluster connection that is shared by multiple pooledConn objects.
+type sharedConn struct {
+	pool                *clusterConnPool
+	key                 sharedConnGroupKey
+	conn                net.Conn
+	reader              *bufio.Reader
+	framing             *connFraming
+	nodeMetricsInstance *metrics.NodeMetricsInstance
+	writeCoalescer      *writeCoalescer
+	heartbeat           *sharedConnHeartbeat // nil if the heartbeats are disabled
+
+	// guarded by pool.lock
+	clients       map[*pooledConn]bool
+	routedClients map[*pooledConn]bool // pooledConn objects that forward token aware requests or that dispatch their
+	// requests to this connection (see clusterConnPool.fill), events are not sent to them
+
+	streamIds *streamIdMapper
+	inFlight  int32
+	retired   int32
+
+	closedLock *sync.RWMutex
+	closed     bool
+
+	ctx      context.Context
+	cancelFn context.CancelFunc
+}
+
+func newSharedConn(
+	pool *clusterConnPool, key sharedConnGroupKey, conn net.Conn, reader *bufio.Reader, framing *connFraming,
+	nodeMetricsInstance *metrics.NodeMetricsInstance) *sharedConn {
+	ctx, cancelFn := context.WithCancel(pool.ctx)
+	sc := &sharedConn{
+		pool:                pool,
+		key:                 key,
+		conn:                conn,
+		reader:              reader,
+		framing:             framing,
+		nodeMetricsInstance: nodeMetricsInstance,
+		clients:             make(map[*pooledConn]bool),
+		routedClients:       make(map[*pooledConn]bool),
+		streamIds:           newStreamIdMapper(key.version, nodeMetricsInstance),
+		heartbeat:           newSharedConnHeartbeat(pool.heartbeatConf, nodeMetricsInstance),
+		closedLock:          &sync.RWMutex{},
+		ctx:                 ctx,
+		cancelFn:            cancelFn,
+	}
+	sc.writeCoalescer = NewWriteCoalescer(
+		pool.conf, conn, &sync.WaitGroup{}, ctx, cancelFn, pooledConnLogPrefix, true, false, pool.writeScheduler,
+		pool.stallWatchdog, framing)
+	// the requests are read from the pipes of the pooledConn objects and the responses are only written to a pipe
+	// so their bodies are pooled
+	sc.writeCoalescer.releaseFrameBodies = true
+	if framing != nil {
+		framing.pooledFrameBodies = true
+	}
+	return sc
+}
+
+func (recv *sharedConn) run() {
+	recv.writeCoalescer.RunWriteQueueLoop()
+	recv.pool.wg.Add(2)
+	go func() {
+		defer recv.pool.wg.Done()
+		<-recv.ctx.Done()
+		recv.close()
+	}()
+	if recv.heartbeat != nil {
+		recv.pool.wg.Add(1)
+		go func() {
+			defer recv.pool.wg.Done()
+			recv.runHeartbeatLoop()
+		}()
+	}
+	go func() {
+		defer recv.pool.wg.Done()
+		defer recv.close()
+		addr := recv.conn.RemoteAddr().String()
+		for {
+			response, err := recv.framing.readFrame(recv.reader, addr, recv.ctx)
+			if err != nil {
+				handleConnectionError(err, recv.ctx, recv.cancelFn, pooledConnLogPrefix, "reading", addr)
+				return
+			}
+			if recv.heartbeat != nil {
+				recv.heartbeat.touch(time.Now())
+			}
+
+			if response.Header.OpCode == primitive.OpCodeEvent {
+				recv.dispatchEvent(response)
+				continue
+			}
+
+			streamId := response.Header.StreamId
+			if isIntermediateContinuousPage(response) {
+				// the stream id is released with the last page
+				if request := recv.streamIds.get(streamId); request != nil && request.client != nil {
+					response.Header.StreamId = request.streamId
+					request.client.sendResponse(response)
+				}
+				continue
+			}
+			request := recv.streamIds.release(streamId)
+			if request == nil {
+				log.Warnf("[%s] Could not find pending request for stream id %d received from %v.", pooledConnLogPrefix, streamId, addr)
+				continue
+			}
+
+			if request.client != nil {
+				response.Header.StreamId = request.streamId
+				request.client.sendResponse(response)
+			} else {
+				// heartbeat or replayed REGISTER response
+				releaseFrameBody(response)
+			}
+
+			if atomic.AddInt32(&recv.inFlight, -1) == 0 && atomic.LoadInt32(&recv.retired) == 1 {
+				return
+			}
+		}
+	}()
+}
+
+func (recv *sharedConn) send(client *pooledConn, request *frame.RawFrame) error {
+	streamId, err := recv.streamIds.acquire(client, request.Header.StreamId)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt32(&recv.inFlight, 1)
+
+	request.Header.StreamId = streamId
+	recv.closedLock.RLock()
+	defer recv.closedLock.RUnlock()
+	if recv.closed {
+		return errSharedConnClosed
+	}
+	recv.writeCoalescer.Enqueue(request)
+	return nil
+}
+
+func (recv *sharedConn) dispatchEvent(event *frame.RawFrame) {
+	body, err := defaultCodec.DecodeBody(event.Header, bytes.NewReader(event.Body))
+	if err != nil {
+		log.Warnf("[%s] Error decoding event: %v", pooledConnLogPrefix, err)
+		return
+	}
+	eventMsg, ok := body.Message.(message.Event)
+	if !ok {
+		log.Warnf("[%s] Expected event body but got: %v", pooledConnLogPrefix, body.Message)
+		return
+	}
+
+	recv.pool.lock.Lock()
+	clients := make([]*pooledConn, 0, len(recv.clients))
+	for client := range recv.clients {
+		clients = append(clients, client)
+	}
+	recv.pool.lock.Unlock()
+
+	for _, client := range clients {
+		if client.isRegisteredFor(eventMsg.GetEventType()) {
+			client.sendResponse(event)
+		}
+	}
+}
+
+// replayEvents sends a REGISTER request with the events that the provided clients registered for to this connection,
+// it isn't assigned to any pooledConn so its response is dropped by the read loop.
+func (recv *sharedConn) replayEvents(clients []*pooledConn) error {
+	register := newRegisterMessage(clients)
+	if register == nil {
+		return nil
+	}
+	request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(recv.key.version, 0, register))
+	if err != nil {
+		return err
+	}
+	return recv.send(nil, request)
+}
+
+// Marks this connection as unused, it is closed once all in flight requests are done.
+func (recv *sharedConn) retire() {
+	atomic.StoreInt32(&recv.retired, 1)
+	if atomic.LoadInt32(&recv.inFlight) == 0 {
+		recv.close()
+	}
+}
+
+func (recv *sharedConn) close() {
+	recv.closedLock.Lock()
+	if recv.closed {
+		recv.closedLock.Unlock()
+		return
+	}
+	recv.closed = true
+	recv.closedLock.Unlock()
+
+	recv.cancelFn()
+	recv.writeCoalescer.Close()
+	pending := recv.streamIds.close()
+	closePooledConnection(recv.conn, pooledConnLogPrefix, recv.nodeMetricsInstance)
+	log.Infof("[%s] Connection to %v was removed from the pool.", pooledConnLogPrefix, recv.conn.RemoteAddr())
+
+	recv.pool.lock.Lock()
+	recv.pool.removeLocked(recv)
+	clients := make([]*pooledConn, 0, len(recv.clients))
+	for client := range recv.clients {
+		clients = append(clients, client)
+	}
+	recv.clients = make(map[*pooledConn]bool)
+	routedClients := make([]*pooledConn, 0, len(recv.routedClients))
+	for client := range recv.routedClients {
+		routedClients = append(routedClients, client)
+	}
+	recv.routedClients = make(map[*pooledConn]bool)
+	recv.pool.lock.Unlock()
+
+	// only the requests that were in flight on this connection fail, the drivers can retry them
+	for _, request := range pending {
+		if request.client != nil {
+			request.client.sendError(recv.key.version, request.streamId,
+				&message.Overloaded{ErrorMessage: sharedConnClosedErrorMessage})
+		}
+	}
+	for _, client := range routedClients {
+		client.removeRoute(recv)
+	}
+	if len(clients) > 0 {
+		recv.pool.reassign(recv, clients)
+	}
+}
+
+func readStartupOptions(request *frame.RawFrame) (map[string]string, bool) {
+	if request.Header.OpCode != primitive.OpCodeStartup {
+		return nil, false
+	}
+	options, err := primitive.ReadStringMap(bytes.NewReader(request.Body))
+	if err != nil {
+		return nil, false
+	}
+	return options, true
+}
+
+// Returns the keyspace of the provided QUERY request if it is a USE statement.
+func readUseStatementKeyspace(request *frame.RawFrame) (string, bool) {
+	if request.Header.OpCode != primitive.OpCodeQuery || request.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return "", false
+	}
+
+	reader := bytes.NewReader(request.Body)
+	if request.Header.Flags.Contains(primitive.HeaderFlagCustomPayload) {
+		if _, err := primitive.ReadBytesMap(reader); err != nil {
+			return "", false
+		}
+	}
+	query, err := primitive.ReadLongString(reader)
+	if err != nil {
+		return "", false
+	}
+	trimmed := strings.TrimSpace(query)
+	if len(trimmed) < 4 || !strings.EqualFold(trimmed[:3], "use") {
+		return "", false
+	}
+
+	queryInfo := inspectCqlQuery(query, "", nil)
+	if queryInfo.getStatementType() != statementTypeUse || queryInfo.getKeyspaceName() == "" {
+		return "", false
+	}
+	return queryInfo.getKeyspaceName(), true
+}
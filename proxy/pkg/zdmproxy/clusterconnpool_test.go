@@ -0,0 +1,51 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestPooledConn_Dispatch(t *testing.T) {
+	shared, lane1, lane2 := &sharedConn{}, &sharedConn{}, &sharedConn{}
+	newRequest := func(opCode primitive.OpCode) *frame.RawFrame {
+		return &frame.RawFrame{Header: &frame.Header{Version: primitive.ProtocolVersion4, OpCode: opCode}}
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		pc := &pooledConn{
+			pool:  &clusterConnPool{dispatchPolicy: common.ConnectionDispatchPolicyRoundRobin},
+			lock:  &sync.Mutex{},
+			lanes: []*sharedConn{lane1, lane2},
+		}
+		dispatched := make(map[*sharedConn]int)
+		for i := 0; i < 6; i++ {
+			dispatched[pc.dispatch(shared, newRequest(primitive.OpCodeQuery))]++
+		}
+		require.Equal(t, map[*sharedConn]int{shared: 2, lane1: 2, lane2: 2}, dispatched)
+
+		// requests that are not queries stay on the connection that was used for the handshake
+		require.Same(t, shared, pc.dispatch(shared, newRequest(primitive.OpCodeRegister)))
+		require.Same(t, shared, pc.dispatch(shared, newRequest(primitive.OpCodeOptions)))
+	})
+
+	t.Run("least in flight", func(t *testing.T) {
+		pc := &pooledConn{
+			pool:  &clusterConnPool{dispatchPolicy: common.ConnectionDispatchPolicyLeastInFlight},
+			lock:  &sync.Mutex{},
+			lanes: []*sharedConn{lane1, lane2},
+		}
+		shared.inFlight, lane1.inFlight, lane2.inFlight = 3, 5, 1
+		require.Same(t, lane2, pc.dispatch(shared, newRequest(primitive.OpCodeExecute)))
+		lane2.inFlight = 4
+		require.Same(t, shared, pc.dispatch(shared, newRequest(primitive.OpCodeBatch)))
+	})
+
+	t.Run("no lanes", func(t *testing.T) {
+		pc := &pooledConn{pool: &clusterConnPool{}, lock: &sync.Mutex{}}
+		require.Same(t, shared, pc.dispatch(shared, newRequest(primitive.OpCodeQuery)))
+	})
+}
@@ -3,11 +3,13 @@ package zdmproxy
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
 	log "github.com/sirupsen/logrus"
 	"net"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -16,6 +18,9 @@ const (
 
 // Coalesces writes using a write buffer
 type writeCoalescer struct {
+	// number of frames that were taken from the write queue, it is checked by the stall watchdog
+	dequeuedFrames int64
+
 	connection net.Conn
 	conf       *config.Config
 
@@ -32,6 +37,13 @@ type writeCoalescer struct {
 	writeBufferSizeBytes int
 
 	scheduler *Scheduler
+
+	stallWatchdog *stallWatchdog
+
+	framing *connFraming
+
+	// releaseFrameBodies is true if the frames are not referenced anymore once they are written (see releaseFrameBody)
+	releaseFrameBodies bool
 }
 
 func NewWriteCoalescer(
@@ -43,7 +55,9 @@ func NewWriteCoalescer(
 	logPrefix string,
 	isRequest bool,
 	isAsync bool,
-	scheduler *Scheduler) *writeCoalescer {
+	scheduler *Scheduler,
+	stallWatchdog *stallWatchdog,
+	framing *connFraming) *writeCoalescer {
 
 	writeQueueSizeFrames := conf.RequestWriteQueueSizeFrames
 	if !isRequest {
@@ -71,6 +85,8 @@ func NewWriteCoalescer(
 		waitGroup:              &sync.WaitGroup{},
 		writeBufferSizeBytes:   writeBufferSizeBytes,
 		scheduler:              scheduler,
+		stallWatchdog:          stallWatchdog,
+		framing:                framing,
 	}
 }
 
@@ -78,16 +94,26 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 	connectionAddr := recv.connection.RemoteAddr().String()
 	log.Tracef("[%v] WriteQueueLoop starting for %v", recv.logPrefix, connectionAddr)
 
+	stallProbe := recv.stallWatchdog.register(
+		fmt.Sprintf("%v write queue of %v", recv.logPrefix, connectionAddr),
+		func() (int, int) { return len(recv.writeQueue), cap(recv.writeQueue) },
+		func() int64 { return atomic.LoadInt64(&recv.dequeuedFrames) })
+
 	recv.clientHandlerWaitGroup.Add(1)
 	recv.waitGroup.Add(1)
 	go func() {
 		defer recv.clientHandlerWaitGroup.Done()
 		defer recv.waitGroup.Done()
+		defer recv.stallWatchdog.unregister(stallProbe)
 
 		draining := false
-		bufferedWriter := bytes.NewBuffer(make([]byte, 0, initialBufferSize))
+		bufferedWriter := getBuffer()
+		bufferedWriter.Grow(initialBufferSize)
 		wg := &sync.WaitGroup{}
-		defer wg.Wait()
+		defer func() {
+			wg.Wait()
+			putBuffer(bufferedWriter)
+		}()
 
 		for {
 			var resultOk bool
@@ -97,6 +123,7 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 			if !firstFrameOk {
 				break
 			}
+			atomic.AddInt64(&recv.dequeuedFrames, 1)
 
 			resultChannel := make(chan *coalescerIterationResult, 1)
 			tempDraining := draining
@@ -116,6 +143,13 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 						}
 
 						if !ok {
+							if !tempDraining {
+								err := recv.framing.flush(tempBuffer, connectionAddr, recv.shutdownContext)
+								if err != nil {
+									tempDraining = true
+									handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
+								}
+							}
 							t := &coalescerIterationResult{
 								buffer:   tempBuffer,
 								draining: tempDraining,
@@ -124,10 +158,14 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 							close(resultChannel)
 							return
 						}
+						atomic.AddInt64(&recv.dequeuedFrames, 1)
 
 						if tempDraining {
 							// continue draining the write queue without writing on connection until it is closed
 							log.Tracef("[%v] Discarding frame from write queue because shutdown was requested: %v", recv.logPrefix, f.Header)
+							if recv.releaseFrameBodies {
+								releaseFrameBody(f)
+							}
 							continue
 						}
 					} else {
@@ -137,12 +175,13 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 					}
 
 					log.Tracef("[%v] Writing %v on %v", recv.logPrefix, f.Header, connectionAddr)
-					err := writeRawFrame(tempBuffer, connectionAddr, recv.shutdownContext, f)
-					if err != nil {
-						tempDraining = true
-						handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
-					} else {
-						if tempBuffer.Len() >= recv.writeBufferSizeBytes {
+					err := recv.framing.writeFrame(tempBuffer, connectionAddr, recv.shutdownContext, f)
+					if recv.releaseFrameBodies {
+						releaseFrameBody(f)
+					}
+					if err == nil && tempBuffer.Len()+recv.framing.bufferedLen() >= recv.writeBufferSizeBytes {
+						err = recv.framing.flush(tempBuffer, connectionAddr, recv.shutdownContext)
+						if err == nil {
 							t := &coalescerIterationResult{
 								buffer:   tempBuffer,
 								draining: tempDraining,
@@ -152,6 +191,10 @@ func (recv *writeCoalescer) RunWriteQueueLoop() {
 							return
 						}
 					}
+					if err != nil {
+						tempDraining = true
+						handleConnectionError(err, recv.shutdownContext, recv.cancelFunc, recv.logPrefix, "writing", connectionAddr)
+					}
 				}
 			})
 
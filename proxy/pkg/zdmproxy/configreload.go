@@ -0,0 +1,232 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+	"time"
+)
+
+// configReloader applies the changes of a group of settings that can be changed at runtime.
+type configReloader struct {
+	settings []string
+	reload   func(conf *config.Config) error
+}
+
+func (p *ZdmProxy) getConfigReloaders() []*configReloader {
+	return []*configReloader{
+		{[]string{"ZDM_LOG_LEVEL"}, p.reloadLogLevel},
+		{[]string{"ZDM_PRIMARY_CLUSTER"}, p.reloadPrimaryCluster},
+		{[]string{"ZDM_READ_MAX_WORKERS", "ZDM_WRITE_MAX_WORKERS"}, p.reloadNumWorkers},
+		{[]string{
+			"ZDM_SLOW_QUERY_LOG_THRESHOLD_MS", "ZDM_SLOW_QUERY_LOG_QUERY_FORMAT", "ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO",
+			"ZDM_SLOW_QUERY_LOG_MAX_PER_SECOND"}, p.reloadSlowQueryLog},
+		{[]string{"ZDM_ORIGIN_MAX_REQUESTS_PER_SECOND"}, func(conf *config.Config) error {
+			maxRequestsPerSecond, err := conf.ParseOriginMaxRequestsPerSecond()
+			if err != nil {
+				return err
+			}
+			return reloadRateLimit(common.ClusterTypeOrigin, p.originRateLimiter, maxRequestsPerSecond)
+		}},
+		{[]string{"ZDM_TARGET_MAX_REQUESTS_PER_SECOND"}, func(conf *config.Config) error {
+			maxRequestsPerSecond, err := conf.ParseTargetMaxRequestsPerSecond()
+			if err != nil {
+				return err
+			}
+			return reloadRateLimit(common.ClusterTypeTarget, p.targetRateLimiter, maxRequestsPerSecond)
+		}},
+	}
+}
+
+// ReloadConfig parses the config file (ZDM_CONFIG_FILE or -config) and the environment variables again and applies
+// the changes of the settings that can be changed at runtime (see config.ReloadableSettings) without restarting the
+// proxy. The changes of the other settings, e.g. the listener addresses and the cluster endpoints, are rejected and
+// logged. It is called when the config file changes (ZDM_CONFIG_FILE_POLL_INTERVAL_MS) and when the proxy receives
+// a SIGHUP signal.
+func (p *ZdmProxy) ReloadConfig() error {
+	p.reloadLock.Lock()
+	defer p.reloadLock.Unlock()
+
+	p.lock.RLock()
+	currentConf := p.runtimeConf
+	p.lock.RUnlock()
+
+	reloadedConf, changes, err := currentConf.Reload()
+	if err != nil {
+		return fmt.Errorf("could not reload the configuration: %w", err)
+	}
+	if changes.IsEmpty() {
+		log.Infof("Reloaded the config file %v, no setting changed.", currentConf.ConfigFile)
+		return nil
+	}
+
+	for _, setting := range changes.Endpoints {
+		log.Errorf("Rejected the change of %v in the config file: the listener addresses and the cluster endpoints "+
+			"can not be changed at runtime, restart the proxy to apply it.", setting)
+	}
+	for _, setting := range changes.RestartRequired {
+		log.Warnf("Rejected the change of %v in the config file: it can not be changed at runtime, "+
+			"restart the proxy to apply it.", setting)
+	}
+
+	changed := make(map[string]bool, len(changes.Reloadable))
+	for _, setting := range changes.Reloadable {
+		changed[setting] = true
+	}
+	var applied []string
+	for _, reloader := range p.getConfigReloaders() {
+		var changedSettings []string
+		for _, setting := range reloader.settings {
+			if changed[setting] {
+				changedSettings = append(changedSettings, setting)
+			}
+		}
+		if len(changedSettings) == 0 {
+			continue
+		}
+		err = reloader.reload(reloadedConf)
+		if err != nil {
+			log.Errorf("Rejected the change of %v in the config file: %v.", strings.Join(changedSettings, ", "), err)
+			continue
+		}
+		applied = append(applied, changedSettings...)
+	}
+
+	p.lock.Lock()
+	p.runtimeConf = currentConf.WithSettings(reloadedConf, applied)
+	p.lock.Unlock()
+	return nil
+}
+
+func (p *ZdmProxy) reloadLogLevel(conf *config.Config) error {
+	level, err := conf.ParseLogLevel()
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+	log.Infof("Log level changed to %v.", level)
+	return nil
+}
+
+// ToggleDebugLogLevel enables the DEBUG log level or, if it is already enabled, restores the level of ZDM_LOG_LEVEL.
+// It is called when the proxy receives a SIGUSR1 signal.
+func (p *ZdmProxy) ToggleDebugLogLevel() {
+	p.lock.RLock()
+	conf := p.runtimeConf
+	p.lock.RUnlock()
+
+	configuredLevel, err := conf.ParseLogLevel()
+	if err != nil {
+		configuredLevel = log.InfoLevel
+	}
+	level := logLevels.ToggleDebugLevel(configuredLevel)
+	log.Infof("Log level changed to %v.", level)
+}
+
+// reloadPrimaryCluster forwards the reads to the new primary cluster through the read_routing setting of the
+// zdm_admin keyspace, the other behaviors that depend on the primary cluster (e.g. the responses of the writes that
+// are returned to the clients) only change when the proxy restarts.
+func (p *ZdmProxy) reloadPrimaryCluster(conf *config.Config) error {
+	primaryCluster, err := conf.ParsePrimaryCluster()
+	if err != nil {
+		return err
+	}
+
+	p.lock.RLock()
+	adminKeyspace := p.adminKeyspace
+	p.lock.RUnlock()
+
+	if adminKeyspace == nil {
+		return fmt.Errorf("the reads can only be forwarded to another cluster at runtime with the %v setting "+
+			"of the %v keyspace which is disabled (ZDM_ADMIN_KEYSPACE_ROLES)", readRoutingSettingName, adminKeyspaceName)
+	}
+	readRouting := primaryCluster
+	if primaryCluster == p.primaryCluster {
+		readRouting = common.ClusterTypeNone
+	}
+	err = adminKeyspace.updateReadRouting(readRouting, "config file reload")
+	if err != nil {
+		return err
+	}
+	log.Warnf("The reads are forwarded to %v, the primary cluster of the other requests changes when the proxy "+
+		"restarts.", primaryCluster)
+	return nil
+}
+
+func (p *ZdmProxy) reloadNumWorkers(conf *config.Config) error {
+	readNumWorkers, writeNumWorkers := p.getReadWriteNumWorkers(conf)
+	p.readScheduler.Resize(readNumWorkers)
+	p.writeScheduler.Resize(writeNumWorkers)
+
+	p.lock.Lock()
+	p.readNumWorkers, p.writeNumWorkers = readNumWorkers, writeNumWorkers
+	p.lock.Unlock()
+	log.Infof("Using %d read workers and %d write workers.", readNumWorkers, writeNumWorkers)
+	return nil
+}
+
+func (p *ZdmProxy) reloadSlowQueryLog(conf *config.Config) error {
+	slowQueryLogConfig, err := conf.ParseSlowQueryLogConfig()
+	if err != nil {
+		return err
+	}
+	if p.slowQueryLog == nil || !slowQueryLogConfig.Enabled {
+		return fmt.Errorf("the slow query log can only be enabled or disabled (ZDM_SLOW_QUERY_LOG_THRESHOLD_MS) " +
+			"when the proxy restarts")
+	}
+	p.slowQueryLog.setConfig(slowQueryLogConfig)
+	log.Infof("Slow query log changed: %v.", slowQueryLogConfig)
+	return nil
+}
+
+func reloadRateLimit(clusterType common.ClusterType, rateLimiter *rateLimiter, maxRequestsPerSecond int) error {
+	if rateLimiter == nil || maxRequestsPerSecond == 0 {
+		return fmt.Errorf("the rate limit of %v can only be enabled or disabled (ZDM_%v_MAX_REQUESTS_PER_SECOND) "+
+			"when the proxy restarts", clusterType, clusterType)
+	}
+	rateLimiter.setLimit(maxRequestsPerSecond)
+	log.Infof("Limiting the rate of requests to %v: %v requests per second.", clusterType, maxRequestsPerSecond)
+	return nil
+}
+
+// watchConfigFile reloads the configuration when the config file changes until the control connections are shut
+// down.
+func (p *ZdmProxy) watchConfigFile(pollInterval time.Duration) {
+	contents, err := os.ReadFile(p.Conf.ConfigFile)
+	if err != nil {
+		log.Warnf("Could not read the config file %v: %v.", p.Conf.ConfigFile, err)
+	}
+
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.controlConnShutdownCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			newContents, err := os.ReadFile(p.Conf.ConfigFile)
+			if err != nil {
+				log.Warnf("Could not check the config file %v for changes: %v.", p.Conf.ConfigFile, err)
+				continue
+			}
+			if bytes.Equal(contents, newContents) {
+				continue
+			}
+			contents = newContents
+			log.Infof("The config file %v changed, reloading the configuration.", p.Conf.ConfigFile)
+			err = p.ReloadConfig()
+			if err != nil {
+				log.Errorf("%v, keeping the current configuration.", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestZdmProxy_ReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zdm-proxy.yaml")
+	writeConfigFile := func(contents string) {
+		require.Nil(t, os.WriteFile(path, []byte(
+			"origin_contact_points: origin\norigin_username: user\norigin_password: password\n"+
+				"target_contact_points: target\ntarget_username: user\ntarget_password: password\n"+contents), 0600))
+	}
+
+	os.Clearenv()
+	writeConfigFile("read_max_workers: 4\nwrite_max_workers: 2\nslow_query_log_threshold_ms: 100\n" +
+		"origin_max_requests_per_second: 1000\n")
+	conf, err := config.New().ParseConfigFileAndEnvVars(path)
+	require.Nil(t, err)
+	slowQueryLogConfig, err := conf.ParseSlowQueryLogConfig()
+	require.Nil(t, err)
+
+	defer log.SetLevel(log.GetLevel())
+	proxy := &ZdmProxy{
+		Conf:              conf,
+		runtimeConf:       conf,
+		reloadLock:        &sync.Mutex{},
+		lock:              &sync.RWMutex{},
+		primaryCluster:    common.ClusterTypeOrigin,
+		readScheduler:     NewScheduler(4),
+		writeScheduler:    NewScheduler(2),
+		slowQueryLog:      newSlowQueryLog(slowQueryLogConfig, newFakeCounter()),
+		originRateLimiter: newRateLimiter(common.ClusterTypeOrigin, 1000, newFakeCounter(), nil, ""),
+		adminKeyspace:     newAdminKeyspace([]string{"admin"}, common.ClusterTypeOrigin),
+	}
+	defer proxy.readScheduler.Shutdown()
+	defer proxy.writeScheduler.Shutdown()
+
+	writeConfigFile("read_max_workers: 8\nwrite_max_workers: 1\nslow_query_log_threshold_ms: 250\n" +
+		"origin_max_requests_per_second: 500\nlog_level: DEBUG\nprimary_cluster: TARGET\n" +
+		"target_max_requests_per_second: 100\norigin_port: 9043\nheartbeat_interval_ms: 1000\n")
+	require.Nil(t, proxy.ReloadConfig())
+
+	require.Equal(t, log.DebugLevel, log.GetLevel())
+	require.Equal(t, common.ClusterTypeTarget, proxy.adminKeyspace.getReadRouting())
+	require.Equal(t, 8, proxy.readScheduler.GetWorkers())
+	require.Equal(t, 1, proxy.writeScheduler.GetWorkers())
+	require.Equal(t, 250, proxy.slowQueryLog.getConfig().ThresholdMs)
+	require.Equal(t, 500.0, proxy.originRateLimiter.getLimit())
+	require.Equal(t, 500.0, proxy.originRateLimiter.getRate())
+
+	// the rejected changes are not applied
+	effectiveConf := proxy.GetEffectiveConfig()
+	require.Equal(t, "DEBUG", effectiveConf.LogLevel)
+	require.Equal(t, "TARGET", effectiveConf.PrimaryCluster)
+	require.Equal(t, 0, effectiveConf.TargetMaxRequestsPerSecond)
+	require.Equal(t, 9042, effectiveConf.OriginPort)
+	require.Equal(t, 30000, effectiveConf.HeartbeatIntervalMs)
+	require.Equal(t, "INFO", proxy.Conf.LogLevel)
+
+	// the reads are forwarded to the primary cluster again
+	writeConfigFile("read_max_workers: 8\nwrite_max_workers: 1\nslow_query_log_threshold_ms: 0\n" +
+		"origin_max_requests_per_second: 500\nlog_level: DEBUG\n")
+	require.Nil(t, proxy.ReloadConfig())
+	require.Equal(t, common.ClusterTypeNone, proxy.adminKeyspace.getReadRouting())
+	require.Equal(t, 250, proxy.slowQueryLog.getConfig().ThresholdMs)
+	require.Equal(t, 250, proxy.GetEffectiveConfig().SlowQueryLogThresholdMs)
+
+	writeConfigFile("primary_cluster: ASYNC\n")
+	require.NotNil(t, proxy.ReloadConfig())
+	require.Equal(t, "DEBUG", proxy.GetEffectiveConfig().LogLevel)
+}
+
+func TestScheduler_Resize(t *testing.T) {
+	scheduler := NewScheduler(2)
+	defer scheduler.Shutdown()
+	require.Equal(t, 2, scheduler.GetWorkers())
+
+	scheduler.Resize(5)
+	require.Equal(t, 5, scheduler.GetWorkers())
+	scheduler.Resize(1)
+	require.Equal(t, 1, scheduler.GetWorkers())
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		scheduler.Schedule(wg.Done)
+	}
+	wg.Wait()
+}
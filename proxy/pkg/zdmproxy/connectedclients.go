@@ -0,0 +1,133 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// connectedClients tracks the client handlers whose connection finished the handshake, they are listed by the
+// system_views.zdm_clients table and by the admin API (/admin/clients).
+type connectedClients struct {
+	clients map[*ClientHandler]time.Time // connection time of the client handlers, keyed on client handler
+	lock    *sync.RWMutex
+	now     func() time.Time
+}
+
+// ConnectedClient is a client connection that is reported by the admin API.
+type ConnectedClient struct {
+	Address            string
+	ConnectedAt        time.Time
+	ProtocolVersion    int
+	ApplicationName    string `json:",omitempty"`
+	ApplicationVersion string `json:",omitempty"`
+	ClientId           string `json:",omitempty"`
+	DriverName         string `json:",omitempty"`
+	DriverVersion      string `json:",omitempty"`
+	Keyspace           string `json:",omitempty"`
+	InFlightRequests   int
+	Draining           bool
+}
+
+func newConnectedClients() *connectedClients {
+	return &connectedClients{
+		clients: make(map[*ClientHandler]time.Time),
+		lock:    &sync.RWMutex{},
+		now:     time.Now,
+	}
+}
+
+// register adds the client handler to the connected clients until the returned function is called.
+func (recv *connectedClients) register(ch *ClientHandler) func() {
+	connectedAt := recv.now()
+	recv.lock.Lock()
+	recv.clients[ch] = connectedAt
+	recv.lock.Unlock()
+	return func() {
+		recv.lock.Lock()
+		delete(recv.clients, ch)
+		recv.lock.Unlock()
+	}
+}
+
+// get returns a copy of the connection time of the connected client handlers.
+func (recv *connectedClients) get() map[*ClientHandler]time.Time {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	connectedAt := make(map[*ClientHandler]time.Time, len(recv.clients))
+	for ch, t := range recv.clients {
+		connectedAt[ch] = t
+	}
+	return connectedAt
+}
+
+// list returns the connected clients ordered by connection time.
+func (recv *connectedClients) list() []*ConnectedClient {
+	connectedAt := recv.get()
+	clients := make([]*ConnectedClient, 0, len(connectedAt))
+	for ch, t := range connectedAt {
+		clients = append(clients, newConnectedClient(ch, t))
+	}
+	sortConnectedClients(clients)
+	return clients
+}
+
+// drain stops accepting requests from the connections of the provided clients (IPs or IP:port addresses), the in
+// flight requests are completed before the connections are closed and the drivers reconnect to another proxy
+// instance. Every connection is drained if clients is empty. It returns the drained clients.
+func (recv *connectedClients) drain(clients []string) ([]*ConnectedClient, error) {
+	normalizedClients, err := normalizeClientAddresses(clients)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(normalizedClients))
+	for _, client := range normalizedClients {
+		selected[client] = true
+	}
+
+	drained := make([]*ConnectedClient, 0)
+	for ch, t := range recv.get() {
+		client := tcpAddrOf(ch.clientConnector.connection.RemoteAddr())
+		if len(selected) > 0 && !selected[client.String()] && !selected[client.IP.String()] {
+			continue
+		}
+		ch.getLogger().Infof("Draining client connection %v.", client)
+		ch.clientHandlerShutdownRequestCancelFn()
+		drained = append(drained, newConnectedClient(ch, t))
+	}
+	if len(selected) > 0 && len(drained) == 0 {
+		return nil, fmt.Errorf("there are no connections of the clients %v", normalizedClients)
+	}
+	sortConnectedClients(drained)
+	return drained, nil
+}
+
+func newConnectedClient(ch *ClientHandler, connectedAt time.Time) *ConnectedClient {
+	identity := ch.clientIdentity
+	if identity == nil {
+		identity = &clientIdentity{}
+	}
+	return &ConnectedClient{
+		Address:            ch.clientConnector.connection.RemoteAddr().String(),
+		ConnectedAt:        connectedAt,
+		ProtocolVersion:    int(ch.startupRequest.Header.Version),
+		ApplicationName:    identity.applicationName,
+		ApplicationVersion: identity.applicationVersion,
+		ClientId:           identity.clientId,
+		DriverName:         identity.driverName,
+		DriverVersion:      identity.driverVersion,
+		Keyspace:           ch.LoadCurrentKeyspace(),
+		InFlightRequests:   countInFlightRequests(ch.requestContextHolders),
+		Draining:           ch.clientHandlerShutdownRequestContext.Err() != nil,
+	}
+}
+
+func sortConnectedClients(clients []*ConnectedClient) {
+	sort.SliceStable(clients, func(i, j int) bool {
+		if clients[i].ConnectedAt.Equal(clients[j].ConnectedAt) {
+			return clients[i].Address < clients[j].Address
+		}
+		return clients[i].ConnectedAt.Before(clients[j].ConnectedAt)
+	})
+}
@@ -16,10 +16,11 @@ func openConnection(cc ConnectionConfig, ec Endpoint, ctx context.Context, useBa
 
 	timeout := time.Duration(cc.GetConnectionTimeoutMs()) * time.Millisecond
 	openConnectionTimeoutCtx, _ := context.WithTimeout(ctx, timeout)
+	dialer := newClusterDialer(time.Duration(cc.GetConnectionAttemptDelayMs())*time.Millisecond, cc.GetSocketOptions())
 
 	if cc.GetTlsConfig() != nil {
 		// open connection using TLS
-		connection, err = openTLSConnection(ec, openConnectionTimeoutCtx, useBackoff)
+		connection, err = openTLSConnection(ec, dialer, openConnectionTimeoutCtx, useBackoff)
 		if err != nil {
 			return nil, openConnectionTimeoutCtx, err
 		}
@@ -28,15 +29,15 @@ func openConnection(cc ConnectionConfig, ec Endpoint, ctx context.Context, useBa
 
 	// open plain TCP connection using contact points
 	if useBackoff {
-		connection, err = openTCPConnectionWithBackoff(ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
+		connection, err = openTCPConnectionWithBackoff(ec.GetSocketEndpoint(), dialer, openConnectionTimeoutCtx)
 	} else {
-		connection, err = openTCPConnection(ec.GetSocketEndpoint(), openConnectionTimeoutCtx)
+		connection, err = openTCPConnection(ec.GetSocketEndpoint(), dialer, openConnectionTimeoutCtx)
 	}
 
 	return connection, openConnectionTimeoutCtx, err
 }
 
-func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, error) {
+func openTCPConnectionWithBackoff(addr string, dialer *clusterDialer, ctx context.Context) (net.Conn, error) {
 	b := &backoff.Backoff{
 		Min:    100 * time.Millisecond,
 		Max:    10 * time.Second,
@@ -45,9 +46,8 @@ func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, e
 	}
 
 	log.Debugf("[openTCPConnectionWithBackoff] Attempting to connect to %v...", addr)
-	dialer := net.Dialer{}
 	for {
-		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		conn, err := dialer.DialContext(ctx, addr)
 		if err != nil {
 			if ctx.Err() != nil {
 				return nil, ShutdownErr
@@ -62,12 +62,11 @@ func openTCPConnectionWithBackoff(addr string, ctx context.Context) (net.Conn, e
 	}
 }
 
-func openTCPConnection(addr string, ctx context.Context) (net.Conn, error) {
+func openTCPConnection(addr string, dialer *clusterDialer, ctx context.Context) (net.Conn, error) {
 	log.Infof("[openTCPConnection] Opening connection to %v", addr)
 
 	// Wait until the source database is up and ready to accept TCP connections.
-	dialer := net.Dialer{}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	conn, err := dialer.DialContext(ctx, addr)
 	if err != nil {
 		if ctx.Err() == context.Canceled {
 			return nil, fmt.Errorf("[openTCPConnection] Connection error (%v) but context was canceled (%v): %w", err, ctx.Err(), ShutdownErr)
@@ -79,14 +78,14 @@ func openTCPConnection(addr string, ctx context.Context) (net.Conn, error) {
 	return conn, nil
 }
 
-func openTLSConnection(endpoint Endpoint, ctx context.Context, useBackoff bool) (*tls.Conn, error) {
+func openTLSConnection(endpoint Endpoint, dialer *clusterDialer, ctx context.Context, useBackoff bool) (*tls.Conn, error) {
 
 	var tcpConn net.Conn
 	var err error
 	if useBackoff {
-		tcpConn, err = openTCPConnectionWithBackoff(endpoint.GetSocketEndpoint(), ctx)
+		tcpConn, err = openTCPConnectionWithBackoff(endpoint.GetSocketEndpoint(), dialer, ctx)
 	} else {
-		tcpConn, err = openTCPConnection(endpoint.GetSocketEndpoint(), ctx)
+		tcpConn, err = openTCPConnection(endpoint.GetSocketEndpoint(), dialer, ctx)
 	}
 	if err != nil {
 		return nil, err
@@ -16,19 +16,22 @@ type ConnectionConfig interface {
 	GetTlsConfig() *tls.Config
 	UsesSNI() bool
 	GetConnectionTimeoutMs() int
+	GetConnectionAttemptDelayMs() int
+	GetSocketOptions() *common.SocketOptions
 	GetContactPoints() []Endpoint
 	RefreshContactPoints(ctx context.Context) ([]Endpoint, error)
 	CreateEndpoint(h *Host) Endpoint
 }
 
 func InitializeConnectionConfig(clusterTlsConfig *common.ClusterTlsConfig, contactPointsFromConfig []string, port int,
-	connTimeoutInMs int, clusterType common.ClusterType, datacenterFromConfig string, ctx context.Context) (ConnectionConfig, error) {
+	connTimeoutInMs int, connAttemptDelayInMs int, socketOptions *common.SocketOptions, clusterType common.ClusterType,
+	datacenterFromConfig string, ctx context.Context) (ConnectionConfig, error) {
 
 	var tlsConfig *tls.Config
 	var err error
 	if clusterTlsConfig.TlsEnabled {
 		if clusterTlsConfig.SecureConnectBundlePath != "" {
-			return initializeAstraConnectionConfig(connTimeoutInMs, clusterType, clusterTlsConfig.SecureConnectBundlePath, ctx)
+			return initializeAstraConnectionConfig(connTimeoutInMs, connAttemptDelayInMs, socketOptions, clusterType, clusterTlsConfig.SecureConnectBundlePath, ctx)
 		} else {
 			tlsConfig, err = getClientSideTlsConfigFromProxyClusterTlsConfig(clusterTlsConfig, clusterType)
 			if err != nil {
@@ -41,22 +44,28 @@ func InitializeConnectionConfig(clusterTlsConfig *common.ClusterTlsConfig, conta
 	for _, contactPoint := range contactPointsFromConfig {
 		contactPoints = append(contactPoints, NewDefaultEndpoint(contactPoint, port, tlsConfig))
 	}
-	return newGenericConnectionConfig(tlsConfig, connTimeoutInMs, clusterType, datacenterFromConfig, contactPoints), nil
+	return newGenericConnectionConfig(
+		tlsConfig, connTimeoutInMs, connAttemptDelayInMs, socketOptions, clusterType, datacenterFromConfig, contactPoints), nil
 
 }
 
 type baseConnectionConfig struct {
-	tlsConfig           *tls.Config
-	connectionTimeoutMs int
-	clusterType         common.ClusterType
+	tlsConfig                *tls.Config
+	connectionTimeoutMs      int
+	connectionAttemptDelayMs int
+	socketOptions            *common.SocketOptions
+	clusterType              common.ClusterType
 }
 
 func newBaseConnectionConfig(
-	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType) *baseConnectionConfig {
+	tlsConfig *tls.Config, connectionTimeoutMs int, connectionAttemptDelayMs int, socketOptions *common.SocketOptions,
+	clusterType common.ClusterType) *baseConnectionConfig {
 	return &baseConnectionConfig{
-		tlsConfig:           tlsConfig,
-		connectionTimeoutMs: connectionTimeoutMs,
-		clusterType:         clusterType,
+		tlsConfig:                tlsConfig,
+		connectionTimeoutMs:      connectionTimeoutMs,
+		connectionAttemptDelayMs: connectionAttemptDelayMs,
+		socketOptions:            socketOptions,
+		clusterType:              clusterType,
 	}
 }
 
@@ -64,6 +73,16 @@ func (cc *baseConnectionConfig) GetConnectionTimeoutMs() int {
 	return cc.connectionTimeoutMs
 }
 
+// GetConnectionAttemptDelayMs returns the delay between the staggered connection attempts to the addresses of a
+// node, see clusterDialer.
+func (cc *baseConnectionConfig) GetConnectionAttemptDelayMs() int {
+	return cc.connectionAttemptDelayMs
+}
+
+func (cc *baseConnectionConfig) GetSocketOptions() *common.SocketOptions {
+	return cc.socketOptions
+}
+
 func (cc *baseConnectionConfig) GetTlsConfig() *tls.Config {
 	return cc.tlsConfig
 }
@@ -79,9 +98,10 @@ type genericConnectionConfig struct {
 }
 
 func newGenericConnectionConfig(
-	tlsConfig *tls.Config, connectionTimeoutMs int, clusterType common.ClusterType, datacenter string, contactPoints []Endpoint) *genericConnectionConfig {
+	tlsConfig *tls.Config, connectionTimeoutMs int, connectionAttemptDelayMs int, socketOptions *common.SocketOptions,
+	clusterType common.ClusterType, datacenter string, contactPoints []Endpoint) *genericConnectionConfig {
 	return &genericConnectionConfig{
-		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType),
+		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, connectionAttemptDelayMs, socketOptions, clusterType),
 		datacenter:           datacenter,
 		contactPoints:        contactPoints,
 	}
@@ -127,7 +147,8 @@ type astraConnectionConfigImpl struct {
 }
 
 func initializeAstraConnectionConfig(
-	connectionTimeoutMs int, clusterType common.ClusterType, secureConnectBundlePath string, ctx context.Context) (*astraConnectionConfigImpl, error) {
+	connectionTimeoutMs int, connectionAttemptDelayMs int, socketOptions *common.SocketOptions,
+	clusterType common.ClusterType, secureConnectBundlePath string, ctx context.Context) (*astraConnectionConfigImpl, error) {
 	fileMap, err := extractFilesFromZipArchive(secureConnectBundlePath)
 	if err != nil {
 		return nil, err
@@ -148,7 +169,7 @@ func initializeAstraConnectionConfig(
 	}
 
 	connConfig := &astraConnectionConfigImpl{
-		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, clusterType),
+		baseConnectionConfig: newBaseConnectionConfig(tlsConfig, connectionTimeoutMs, connectionAttemptDelayMs, socketOptions, clusterType),
 		datacenter:           "",
 		metadataServiceName:  metadataServiceHostName,
 		metadataServicePort:  metadataServicePort,
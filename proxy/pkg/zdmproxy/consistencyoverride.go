@@ -0,0 +1,86 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+// consistencyOverride rewrites the consistency level of the QUERY, EXECUTE and BATCH requests that are forwarded to
+// a cluster (see ZDM_ORIGIN_CONSISTENCY_OVERRIDE and ZDM_TARGET_CONSISTENCY_OVERRIDE), e.g. when TARGET has a lower
+// replication factor than ORIGIN during the migration. The requests that are forwarded to the other cluster are
+// not changed.
+type consistencyOverride struct {
+	clusterType common.ClusterType
+	levels      map[primitive.ConsistencyLevel]primitive.ConsistencyLevel
+}
+
+// newConsistencyOverride returns nil if no consistency level is overridden.
+func newConsistencyOverride(
+	clusterType common.ClusterType, levels map[primitive.ConsistencyLevel]primitive.ConsistencyLevel) *consistencyOverride {
+	if len(levels) == 0 {
+		return nil
+	}
+	return &consistencyOverride{
+		clusterType: clusterType,
+		levels:      levels,
+	}
+}
+
+// apply returns the request with the overridden consistency level or the same request if its consistency level is
+// not overridden. The request is decoded from frameContext if it is the request that was sent by the client.
+func (recv *consistencyOverride) apply(
+	frameContext *frameDecodeContext, request *frame.RawFrame, explanation *requestExplanation) (*frame.RawFrame, error) {
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return request, nil
+	}
+
+	var decodedRequest *frame.Frame
+	var err error
+	if request == frameContext.GetRawFrame() {
+		decodedRequest, err = frameContext.GetOrDecodeFrame()
+		if err == nil {
+			decodedRequest = decodedRequest.Clone()
+		}
+	} else {
+		decodedRequest, err = defaultCodec.ConvertFromRawFrame(request)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %v request: %w", recv.clusterType, err)
+	}
+
+	var consistency *primitive.ConsistencyLevel
+	switch msg := decodedRequest.Body.Message.(type) {
+	case *message.Query:
+		if msg.Options == nil {
+			msg.Options = &message.QueryOptions{}
+		}
+		consistency = &msg.Options.Consistency
+	case *message.Execute:
+		if msg.Options == nil {
+			msg.Options = &message.QueryOptions{}
+		}
+		consistency = &msg.Options.Consistency
+	case *message.Batch:
+		consistency = &msg.Consistency
+	default:
+		return nil, fmt.Errorf("expected Query, Execute or Batch but got %v instead", decodedRequest.Body.Message.GetOpCode())
+	}
+
+	newConsistency, ok := recv.levels[*consistency]
+	if !ok || newConsistency == *consistency {
+		return request, nil
+	}
+	explanation.addConsistencyOverride(recv.clusterType, *consistency, newConsistency)
+	*consistency = newConsistency
+
+	newRequest, err := defaultCodec.ConvertToRawFrame(decodedRequest)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert %v request to raw frame: %w", recv.clusterType, err)
+	}
+	return newRequest, nil
+}
@@ -0,0 +1,85 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConsistencyOverride(t *testing.T) {
+	require.Nil(t, newConsistencyOverride(common.ClusterTypeTarget, map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{}))
+
+	override := newConsistencyOverride(common.ClusterTypeTarget, map[primitive.ConsistencyLevel]primitive.ConsistencyLevel{
+		primitive.ConsistencyLevelQuorum:   primitive.ConsistencyLevelLocalOne,
+		primitive.ConsistencyLevelLocalOne: primitive.ConsistencyLevelLocalOne,
+	})
+
+	tests := []struct {
+		name                string
+		msg                 message.Message
+		expectedConsistency primitive.ConsistencyLevel
+		expectedChanged     bool
+	}{
+		{"query",
+			&message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)",
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelQuorum}},
+			primitive.ConsistencyLevelLocalOne, true},
+		{"query with level that is not overridden",
+			&message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)",
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelAll}},
+			primitive.ConsistencyLevelAll, false},
+		{"query with level that is overridden with the same level",
+			&message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)",
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalOne}},
+			primitive.ConsistencyLevelLocalOne, false},
+		{"execute",
+			&message.Execute{QueryId: []byte("ID"),
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelQuorum}},
+			primitive.ConsistencyLevelLocalOne, true},
+		{"batch",
+			&message.Batch{Children: []*message.BatchChild{{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"}},
+				Consistency: primitive.ConsistencyLevelQuorum},
+			primitive.ConsistencyLevelLocalOne, true},
+		{"register",
+			&message.Register{EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}},
+			0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			frameContext := NewFrameDecodeContext(f)
+			newRequest, err := override.apply(frameContext, f, nil)
+			require.Nil(t, err)
+			if !tt.expectedChanged {
+				require.Same(t, f, newRequest)
+				return
+			}
+			require.NotSame(t, f, newRequest)
+			require.Equal(t, f.Header.StreamId, newRequest.Header.StreamId)
+
+			decodedRequest, err := defaultCodec.ConvertFromRawFrame(newRequest)
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedConsistency, getRequestConsistency(decodedRequest.Body.Message))
+
+			// the decoded request of the client is not changed
+			decodedClientRequest, err := frameContext.GetOrDecodeFrame()
+			require.Nil(t, err)
+			require.Equal(t, getRequestConsistency(tt.msg), getRequestConsistency(decodedClientRequest.Body.Message))
+		})
+	}
+}
+
+func getRequestConsistency(msg message.Message) primitive.ConsistencyLevel {
+	switch typedMsg := msg.(type) {
+	case *message.Query:
+		return typedMsg.Options.Consistency
+	case *message.Execute:
+		return typedMsg.Options.Consistency
+	case *message.Batch:
+		return typedMsg.Consistency
+	}
+	return 0
+}
@@ -0,0 +1,202 @@
+package zdmproxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"sync"
+)
+
+// DSE continuous paging: a QUERY or EXECUTE request with continuous paging options gets one RESULT per page on the same
+// stream id, the last page has the RowsFlagDseLastContinuousPage flag. The pages of the two clusters can't be merged
+// so these requests are only forwarded to one cluster (see ContinuousPagingRequestInfo) and the REVISE requests that
+// cancel them or ask for more pages are forwarded to the cluster of the request that they target.
+//
+// The intermediate pages don't finish the request so they don't release the stream id of the request on the cluster
+// connection, on the pooled cluster connections or on the request limiters.
+
+// isContinuousPagingRequest returns true if the request is a QUERY or EXECUTE with DSE continuous paging options.
+func isContinuousPagingRequest(frameContext *frameDecodeContext) (bool, error) {
+	f := frameContext.GetRawFrame()
+	if !f.Header.Version.IsDse() {
+		return false, nil
+	}
+	if f.Header.OpCode != primitive.OpCodeQuery && f.Header.OpCode != primitive.OpCodeExecute {
+		return false, nil
+	}
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return false, fmt.Errorf("could not check if request is a continuous paging request: %w", err)
+	}
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		return msg.Options != nil && msg.Options.ContinuousPagingOptions != nil, nil
+	case *message.Execute:
+		return msg.Options != nil && msg.Options.ContinuousPagingOptions != nil, nil
+	}
+	return false, nil
+}
+
+// peekContinuousPage returns the page number of a RESULT of a continuous paging request and whether it is the last
+// page, false if the response is not a continuous page.
+func peekContinuousPage(f *frame.RawFrame) (page int32, last bool, ok bool) {
+	if !f.Header.Version.IsDse() || f.Header.OpCode != primitive.OpCodeResult {
+		return 0, false, false
+	}
+	offset, ok := peekMessageOffset(f)
+	// [int] kind, [int] flags and [int] column count
+	if !ok || offset+12 > len(f.Body) {
+		return 0, false, false
+	}
+	body := f.Body
+	if primitive.ResultType(binary.BigEndian.Uint32(body[offset:])) != primitive.ResultTypeRows {
+		return 0, false, false
+	}
+	flags := primitive.RowsFlag(binary.BigEndian.Uint32(body[offset+4:]))
+	if !flags.Contains(primitive.RowsFlagDseContinuousPaging) {
+		return 0, false, false
+	}
+	offset += 12
+	if flags.Contains(primitive.RowsFlagHasMorePages) {
+		// [bytes] paging state
+		if offset+4 > len(body) {
+			return 0, false, false
+		}
+		length := int(int32(binary.BigEndian.Uint32(body[offset:])))
+		offset += 4
+		if length > 0 {
+			offset += length
+		}
+	}
+	if flags.Contains(primitive.RowsFlagMetadataChanged) {
+		// [short bytes] new metadata id
+		if offset+2 > len(body) {
+			return 0, false, false
+		}
+		offset += 2 + int(binary.BigEndian.Uint16(body[offset:]))
+	}
+	if offset+4 > len(body) {
+		return 0, false, false
+	}
+	page = int32(binary.BigEndian.Uint32(body[offset:]))
+	return page, flags.Contains(primitive.RowsFlagDseLastContinuousPage), true
+}
+
+// isIntermediateContinuousPage returns true if the response is a page of a continuous paging request that is followed
+// by more pages.
+func isIntermediateContinuousPage(f *frame.RawFrame) bool {
+	_, last, ok := peekContinuousPage(f)
+	return ok && !last
+}
+
+// reviseTargetStreamIdOffset returns the offset of the target stream id in the raw body of a REVISE request.
+func reviseTargetStreamIdOffset(f *frame.RawFrame) (int, bool) {
+	if f.Header.OpCode != primitive.OpCodeDseRevise {
+		return 0, false
+	}
+	offset, ok := peekMessageOffset(f)
+	// [int] revision type followed by the [int] target stream id
+	if !ok || offset+8 > len(f.Body) {
+		return 0, false
+	}
+	return offset + 4, true
+}
+
+// peekReviseTargetStreamId returns the stream id of the continuous paging request that a REVISE request targets.
+func peekReviseTargetStreamId(f *frame.RawFrame) (int16, bool) {
+	offset, ok := reviseTargetStreamIdOffset(f)
+	if !ok {
+		return 0, false
+	}
+	return int16(int32(binary.BigEndian.Uint32(f.Body[offset:]))), true
+}
+
+// setReviseTargetStreamId replaces the target stream id of a REVISE request in place.
+func setReviseTargetStreamId(f *frame.RawFrame, streamId int16) bool {
+	offset, ok := reviseTargetStreamIdOffset(f)
+	if !ok {
+		return false
+	}
+	binary.BigEndian.PutUint32(f.Body[offset:], uint32(int32(streamId)))
+	return true
+}
+
+// continuousPaging holds the pages of a continuous paging request that were received before the previous pages.
+// The responses of a cluster connection are processed by several goroutines so the pages can be received out of
+// order but they have to be sent to the client in order.
+type continuousPaging struct {
+	lock     *sync.Mutex
+	nextPage int32
+	pages    map[int32]*frame.RawFrame
+}
+
+func newContinuousPaging() *continuousPaging {
+	return &continuousPaging{
+		lock:     &sync.Mutex{},
+		nextPage: 1,
+		pages:    make(map[int32]*frame.RawFrame),
+	}
+}
+
+// applyContinuousPaging forwards continuous paging requests to a single cluster and the REVISE requests to the cluster
+// of the continuous paging request that they target (or the primary cluster if it is not in flight anymore).
+func (ch *ClientHandler) applyContinuousPaging(
+	frameContext *frameDecodeContext, requestInfo RequestInfo) (RequestInfo, error) {
+	f := frameContext.GetRawFrame()
+	if f.Header.OpCode == primitive.OpCodeDseRevise {
+		decision := forwardToOrigin
+		if ch.primaryCluster == common.ClusterTypeTarget {
+			decision = forwardToTarget
+		}
+		if streamId, ok := peekReviseTargetStreamId(f); ok {
+			holder := getOrCreateRequestContextHolder(ch.requestContextHolders, streamId)
+			if reqCtx, ok := holder.Get().(*requestContextImpl); ok && reqCtx.continuousPaging != nil {
+				decision = reqCtx.requestInfo.GetForwardDecision()
+			}
+		}
+		return NewGenericRequestInfo(decision, false, false), nil
+	}
+
+	continuous, err := isContinuousPagingRequest(frameContext)
+	if err != nil || !continuous {
+		return requestInfo, err
+	}
+	ch.getLogger().Tracef("Forwarding continuous paging request with stream id %d to a single cluster.",
+		f.Header.StreamId)
+	return NewContinuousPagingRequestInfo(requestInfo, ch.primaryCluster), nil
+}
+
+// forwardContinuousPages sends the intermediate pages of a continuous paging request to the client in the order of
+// their page numbers and resets the timeout of the request for each page. It returns the response that finishes the
+// request, i.e. the last page once the previous pages were sent or any other response (e.g. an ERROR), nil if the
+// request is still waiting for pages.
+func (ch *ClientHandler) forwardContinuousPages(reqCtx *requestContextImpl, response *frame.RawFrame) *frame.RawFrame {
+	pageNumber, _, ok := peekContinuousPage(response)
+	if !ok {
+		return response
+	}
+
+	paging := reqCtx.continuousPaging
+	paging.lock.Lock()
+	defer paging.lock.Unlock()
+	paging.pages[pageNumber] = response
+	for {
+		page, ok := paging.pages[paging.nextPage]
+		if !ok {
+			return nil
+		}
+		delete(paging.pages, paging.nextPage)
+		paging.nextPage++
+		if _, last, _ := peekContinuousPage(page); last {
+			return page
+		}
+		if !reqCtx.resetTimer() {
+			// the request timed out
+			return nil
+		}
+		ch.clientConnector.sendResponseToClient(page)
+	}
+}
@@ -0,0 +1,120 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newContinuousPageFrame(page int32, last bool, pagingState []byte) *frame.Frame {
+	return frame.NewFrame(primitive.ProtocolVersionDse2, 1, &message.RowsResult{
+		Metadata: &message.RowsMetadata{
+			ColumnCount:          1,
+			Columns:              []*message.ColumnMetadata{{Keyspace: "ks1", Table: "tb1", Name: "v", Type: datatype.Int}},
+			PagingState:          pagingState,
+			ContinuousPageNumber: page,
+			LastContinuousPage:   last,
+		},
+		Data: message.RowSet{{{0, 0, 0, 1}}},
+	})
+}
+
+func TestPeekContinuousPage(t *testing.T) {
+	convert := func(f *frame.Frame) *frame.RawFrame {
+		rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+		require.Nil(t, err)
+		return rawFrame
+	}
+
+	page, last, ok := peekContinuousPage(convert(newContinuousPageFrame(3, false, nil)))
+	require.True(t, ok)
+	require.Equal(t, int32(3), page)
+	require.False(t, last)
+
+	f := newContinuousPageFrame(7, true, []byte("paging-state"))
+	f.SetTracingId(&primitive.UUID{0x01})
+	f.SetWarnings([]string{"warning1", "warning2"})
+	page, last, ok = peekContinuousPage(convert(f))
+	require.True(t, ok)
+	require.Equal(t, int32(7), page)
+	require.True(t, last)
+	require.False(t, isIntermediateContinuousPage(convert(f)))
+
+	f = newContinuousPageFrame(2, false, nil)
+	f.SetCustomPayload(map[string][]byte{"key1": []byte("value1")})
+	page, _, ok = peekContinuousPage(convert(f))
+	require.True(t, ok)
+	require.Equal(t, int32(2), page)
+	require.True(t, isIntermediateContinuousPage(convert(newContinuousPageFrame(1, false, nil))))
+
+	// rows of a request without continuous paging
+	_, _, ok = peekContinuousPage(convert(newContinuousPageFrame(0, false, nil)))
+	require.False(t, ok)
+	_, _, ok = peekContinuousPage(convert(frame.NewFrame(primitive.ProtocolVersionDse2, 1, &message.VoidResult{})))
+	require.False(t, ok)
+	v4Frame := convert(newContinuousPageFrame(1, false, nil))
+	v4Frame.Header.Version = primitive.ProtocolVersion4
+	_, _, ok = peekContinuousPage(v4Frame)
+	require.False(t, ok)
+}
+
+func TestIsContinuousPagingRequest(t *testing.T) {
+	continuousOptions := &message.QueryOptions{ContinuousPagingOptions: &message.ContinuousPagingOptions{MaxPages: 10}}
+	tests := []struct {
+		name     string
+		request  *frame.RawFrame
+		expected bool
+	}{
+		{"query", mockFrame(t, &message.Query{Query: "SELECT * FROM ks1.tb1", Options: continuousOptions},
+			primitive.ProtocolVersionDse2), true},
+		{"execute", mockFrame(t, &message.Execute{QueryId: []byte("id"), ResultMetadataId: []byte("id"), Options: continuousOptions},
+			primitive.ProtocolVersionDse2), true},
+		{"query without continuous paging", mockFrame(t, &message.Query{Query: "SELECT * FROM ks1.tb1"},
+			primitive.ProtocolVersionDse2), false},
+		{"protocol v4", mockQueryFrame(t, "SELECT * FROM ks1.tb1"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := isContinuousPagingRequest(NewFrameDecodeContext(tt.request))
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestReviseTargetStreamId(t *testing.T) {
+	request := mockFrame(t, &message.Revise{
+		RevisionType:   primitive.DseRevisionTypeMoreContinuousPages,
+		TargetStreamId: 12,
+		NextPages:      4,
+	}, primitive.ProtocolVersionDse2)
+	streamId, ok := peekReviseTargetStreamId(request)
+	require.True(t, ok)
+	require.Equal(t, int16(12), streamId)
+
+	require.True(t, setReviseTargetStreamId(request, 300))
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	require.Nil(t, err)
+	require.Equal(t, &message.Revise{
+		RevisionType:   primitive.DseRevisionTypeMoreContinuousPages,
+		TargetStreamId: 300,
+		NextPages:      4,
+	}, decodedFrame.Body.Message)
+
+	_, ok = peekReviseTargetStreamId(mockQueryFrame(t, "SELECT * FROM ks1.tb1"))
+	require.False(t, ok)
+}
+
+func TestContinuousPagingRequestInfo(t *testing.T) {
+	requestInfo := NewContinuousPagingRequestInfo(NewGenericRequestInfo(forwardToTarget, true, true), common.ClusterTypeOrigin)
+	require.Equal(t, forwardToTarget, requestInfo.GetForwardDecision())
+	require.False(t, requestInfo.ShouldAlsoBeSentAsync())
+	require.True(t, requestInfo.ShouldBeTrackedInMetrics())
+
+	requestInfo = NewContinuousPagingRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), common.ClusterTypeTarget)
+	require.Equal(t, forwardToTarget, requestInfo.GetForwardDecision())
+}
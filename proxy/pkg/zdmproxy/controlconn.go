@@ -34,6 +34,7 @@ type ControlConn struct {
 	currentContactPoint      Endpoint
 	username                 string
 	password                 string
+	credentialsLock          *sync.RWMutex
 	counterLock              *sync.RWMutex
 	consecutiveFailures      int
 	OpenConnectionTimeout    time.Duration
@@ -42,12 +43,14 @@ type ControlConn struct {
 	datacenter               string
 	orderedHostsInLocalDc    []*Host
 	hostsInLocalDcById       map[uuid.UUID]*Host
+	downHostIds              map[uuid.UUID]bool // hosts of the local DC that are DOWN according to STATUS_CHANGE events
 	assignedHosts            []*Host
 	currentAssignment        int64
 	refreshHostsDebouncer    chan CqlConnection
 	systemLocalColumnData    map[string]*optionalColumn
 	systemPeersColumnNames   map[string]bool
 	virtualHosts             []*VirtualHost
+	topologyVersion          int64 // incremented every time the hosts are refreshed
 	proxyRand                *rand.Rand
 	reconnectCh              chan bool
 	protocolEventSubscribers map[ProtocolEventObserver]interface{}
@@ -81,6 +84,7 @@ func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionC
 		currentContactPoint:      nil,
 		username:                 username,
 		password:                 password,
+		credentialsLock:          &sync.RWMutex{},
 		counterLock:              &sync.RWMutex{},
 		consecutiveFailures:      0,
 		OpenConnectionTimeout:    time.Duration(connConfig.GetConnectionTimeoutMs()) * time.Millisecond,
@@ -88,12 +92,14 @@ func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionC
 		topologyLock:             &sync.RWMutex{},
 		orderedHostsInLocalDc:    nil,
 		hostsInLocalDcById:       map[uuid.UUID]*Host{},
+		downHostIds:              map[uuid.UUID]bool{},
 		assignedHosts:            nil,
 		currentAssignment:        0,
 		refreshHostsDebouncer:    make(chan CqlConnection, 1),
 		systemLocalColumnData:    nil,
 		systemPeersColumnNames:   nil,
 		virtualHosts:             nil,
+		topologyVersion:          0,
 		proxyRand:                proxyRand,
 		reconnectCh:              make(chan bool, 1),
 		protocolEventSubscribers: map[ProtocolEventObserver]interface{}{},
@@ -101,6 +107,21 @@ func NewControlConn(ctx context.Context, defaultPort int, connConfig ConnectionC
 	}
 }
 
+// SetCredentials changes the credentials of the control connections that are opened after this call,
+// the current control connection is not reopened.
+func (cc *ControlConn) SetCredentials(username string, password string) {
+	cc.credentialsLock.Lock()
+	defer cc.credentialsLock.Unlock()
+	cc.username = username
+	cc.password = password
+}
+
+func (cc *ControlConn) getCredentials() (string, string) {
+	cc.credentialsLock.RLock()
+	defer cc.credentialsLock.RUnlock()
+	return cc.username, cc.password
+}
+
 func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 	_, err := cc.Open(true, ctx)
 	if err != nil {
@@ -123,7 +144,7 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 
 			conn, _ := cc.getConnAndContactPoint()
 			if conn == nil {
-				log.Debugf("Topology refresh scheduled but the control connection isn't open. " +
+				moduleLogger(LogModuleControlConnection).Debugf("Topology refresh scheduled but the control connection isn't open. " +
 					"Falling back to the connection where the event was received.")
 				conn = eventConnection
 			}
@@ -207,7 +228,7 @@ func (cc *ControlConn) Start(wg *sync.WaitGroup, ctx context.Context) error {
 					log.Infof(logMsg, conn, cc.heartbeatPeriod)
 					cc.ResetFailureCounter()
 				} else {
-					log.Debugf(logMsg, conn, cc.heartbeatPeriod)
+					moduleLogger(LogModuleControlConnection).Debugf(logMsg, conn, cc.heartbeatPeriod)
 				}
 				_, reconnect = sleepWithContext(cc.heartbeatPeriod, cc.context, cc.reconnectCh)
 			}
@@ -323,24 +344,28 @@ func (cc *ControlConn) openInternal(endpoints []Endpoint, ctx context.Context) (
 			continue
 		}
 
-		newConn := NewCqlConnection(tcpConn, cc.username, cc.password, ccReadTimeout, ccWriteTimeout)
+		username, password := cc.getCredentials()
+		newConn := NewCqlConnection(tcpConn, username, password, ccReadTimeout, ccWriteTimeout)
 		err = newConn.InitializeContext(ccProtocolVersion, ctx)
 		if err == nil {
 			newConn.SetEventHandler(func(f *frame.Frame, c CqlConnection) {
-				switch f.Body.Message.(type) {
+				switch msg := f.Body.Message.(type) {
 				case *message.TopologyChangeEvent:
 					select {
 					case cc.refreshHostsDebouncer <- c:
 					default:
-						log.Debugf("Discarding event %v in %v because a topology refresh is already scheduled.",
+						moduleLogger(LogModuleControlConnection).Debugf("Discarding event %v in %v because a topology refresh is already scheduled.",
 							cc.connConfig.GetClusterType(), f.Body.Message)
 					}
+				case *message.StatusChangeEvent:
+					cc.handleStatusChangeEvent(msg, c)
 				default:
 					return
 				}
 			})
 
-			err = newConn.SubscribeToProtocolEvents(ctx, []primitive.EventType{primitive.EventTypeTopologyChange})
+			err = newConn.SubscribeToProtocolEvents(
+				ctx, []primitive.EventType{primitive.EventTypeTopologyChange, primitive.EventTypeStatusChange})
 			if err == nil {
 				_, err = cc.RefreshHosts(newConn, ctx)
 			}
@@ -368,6 +393,51 @@ func (cc *ControlConn) openInternal(endpoints []Endpoint, ctx context.Context) (
 	return conn, endpoint
 }
 
+// OpenDedicatedConnection opens a connection with the provided protocol version to one of the assigned hosts (or to
+// one of the contact points if the hosts are not known yet). Unlike the control connection it is not subscribed to
+// protocol events and the caller is responsible for closing it.
+func (cc *ControlConn) OpenDedicatedConnection(
+	version primitive.ProtocolVersion, ctx context.Context) (CqlConnection, Endpoint, error) {
+	var endpoints []Endpoint
+	if hosts, err := cc.GetAssignedHosts(); err == nil {
+		for _, h := range hosts {
+			endpoints = append(endpoints, cc.connConfig.CreateEndpoint(h))
+		}
+	}
+	if len(endpoints) == 0 {
+		endpoints = cc.connConfig.GetContactPoints()
+	}
+
+	var lastErr error
+	firstEndpointIndex := cc.proxyRand.Intn(len(endpoints))
+	for i := 0; i < len(endpoints) && ctx.Err() == nil; i++ {
+		endpoint := endpoints[(firstEndpointIndex+i)%len(endpoints)]
+		tcpConn, _, err := openConnection(cc.connConfig, endpoint, ctx, false)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		username, password := cc.getCredentials()
+		newConn := NewCqlConnection(tcpConn, username, password, ccReadTimeout, ccWriteTimeout)
+		err = newConn.InitializeContext(version, ctx)
+		if err != nil {
+			lastErr = err
+			if err2 := newConn.Close(); err2 != nil {
+				log.Errorf("Failed to close cql connection: %v", err2)
+			}
+			continue
+		}
+		return newConn, endpoint, nil
+	}
+
+	if lastErr == nil {
+		lastErr = ctx.Err()
+	}
+	return nil, nil, fmt.Errorf("could not open connection to %v, tried endpoints: %v: %w",
+		cc.connConfig.GetClusterType(), endpoints, lastErr)
+}
+
 func (cc *ControlConn) Close() {
 	cc.cqlConnLock.Lock()
 	conn := cc.cqlConn
@@ -398,9 +468,9 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 	if partitionerExists {
 		partitioner = partitionerColValue.AsNillableString()
 	}
-	if partitioner != nil && !strings.Contains(*partitioner, "Murmur3Partitioner") && cc.topologyConfig.VirtualizationEnabled {
+	if partitioner != nil && !strings.Contains(*partitioner, "Murmur3Partitioner") && cc.getTopologyConfig().VirtualizationEnabled {
 		if strings.Contains(*partitioner, "RandomPartitioner") {
-			log.Debugf("Cluster %v uses the Random partitioner, but the proxy will return Murmur3 to the client instead. This is the expected behaviour.", cc.connConfig.GetClusterType())
+			moduleLogger(LogModuleControlConnection).Debugf("Cluster %v uses the Random partitioner, but the proxy will return Murmur3 to the client instead. This is the expected behaviour.", cc.connConfig.GetClusterType())
 		} else {
 			return nil, fmt.Errorf("virtualization is enabled and partitioner is not Murmur3 or Random but instead %v", *partitioner)
 		}
@@ -455,23 +525,16 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 		return orderedLocalHosts[i].Rack < orderedLocalHosts[j].Rack
 	})
 
-	assignedHosts := computeAssignedHosts(cc.topologyConfig.Index, cc.topologyConfig.Count, orderedLocalHosts)
-	shuffleHosts(cc.proxyRand, assignedHosts)
-
-	var virtualHosts []*VirtualHost
-	if cc.topologyConfig.VirtualizationEnabled {
-		virtualHosts, err = computeVirtualHosts(cc.topologyConfig, orderedLocalHosts)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		virtualHosts = make([]*VirtualHost, 0)
+	cc.topologyLock.Lock()
+	assignedHosts, virtualHosts, err := cc.computeProxyTopology(cc.topologyConfig, orderedLocalHosts)
+	if err != nil {
+		cc.topologyLock.Unlock()
+		return nil, err
 	}
 
 	log.Infof("Refreshed %v orderedHostsInLocalDc. Assigned Hosts: %v, VirtualHosts: %v, ProxyTopologyIndex: %v",
 		cc.connConfig.GetClusterType(), assignedHosts, virtualHosts, cc.topologyConfig.Index)
 
-	cc.topologyLock.Lock()
 	if cc.datacenter == "" {
 		cc.datacenter = currentDc
 	}
@@ -482,6 +545,12 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 	cc.systemLocalColumnData = localInfo
 	cc.systemPeersColumnNames = peersColumns
 	cc.virtualHosts = virtualHosts
+	cc.topologyVersion++
+	for hostId := range cc.downHostIds {
+		if _, found := hostsById[hostId]; !found {
+			delete(cc.downHostIds, hostId)
+		}
+	}
 
 	if oldHosts != nil && len(oldHosts) > 0 {
 		removedHosts := make([]*Host, 0)
@@ -502,6 +571,50 @@ func (cc *ControlConn) RefreshHosts(conn CqlConnection, ctx context.Context) ([]
 	return orderedLocalHosts, nil
 }
 
+// computeProxyTopology returns the hosts that are assigned to this proxy instance and the virtual hosts of the proxy
+// instances, it must be called with the topology lock held.
+func (cc *ControlConn) computeProxyTopology(
+	topologyConfig *common.TopologyConfig, orderedLocalHosts []*Host) ([]*Host, []*VirtualHost, error) {
+	assignedHosts := computeAssignedHosts(topologyConfig.Index, topologyConfig.Count, orderedLocalHosts)
+	shuffleHosts(cc.proxyRand, assignedHosts)
+
+	if !topologyConfig.VirtualizationEnabled {
+		return assignedHosts, make([]*VirtualHost, 0), nil
+	}
+	virtualHosts, err := computeVirtualHosts(topologyConfig, orderedLocalHosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return assignedHosts, virtualHosts, nil
+}
+
+// SetTopologyConfig replaces the topology of the proxy instances (e.g. an instance was added to
+// ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE) and recomputes the assigned hosts and the virtual hosts.
+func (cc *ControlConn) SetTopologyConfig(topologyConfig *common.TopologyConfig) error {
+	cc.topologyLock.Lock()
+	defer cc.topologyLock.Unlock()
+
+	if cc.orderedHostsInLocalDc != nil {
+		assignedHosts, virtualHosts, err := cc.computeProxyTopology(topologyConfig, cc.orderedHostsInLocalDc)
+		if err != nil {
+			return err
+		}
+		log.Infof("Proxy topology of %v changed. Assigned Hosts: %v, VirtualHosts: %v, ProxyTopologyIndex: %v",
+			cc.connConfig.GetClusterType(), assignedHosts, virtualHosts, topologyConfig.Index)
+		cc.assignedHosts = assignedHosts
+		cc.virtualHosts = virtualHosts
+	}
+	cc.topologyConfig = topologyConfig
+	cc.topologyVersion++
+	return nil
+}
+
+func (cc *ControlConn) getTopologyConfig() *common.TopologyConfig {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+	return cc.topologyConfig
+}
+
 func (cc *ControlConn) GetHostsInLocalDatacenter() (map[uuid.UUID]*Host, error) {
 	cc.topologyLock.RLock()
 	defer cc.topologyLock.RUnlock()
@@ -524,23 +637,33 @@ func (cc *ControlConn) GetOrderedHostsInLocalDatacenter() ([]*Host, error) {
 	return cc.orderedHostsInLocalDc, nil
 }
 
-func (cc *ControlConn) GetVirtualHosts() ([]*VirtualHost, error) {
+// GetVirtualHosts returns the virtual hosts and the index of the virtual host of this proxy instance.
+func (cc *ControlConn) GetVirtualHosts() ([]*VirtualHost, int, error) {
 	cc.topologyLock.RLock()
 	defer cc.topologyLock.RUnlock()
 
 	if !cc.topologyConfig.VirtualizationEnabled {
-		return nil, fmt.Errorf("could not get virtual hosts because virtualization is not enabled")
+		return nil, 0, fmt.Errorf("could not get virtual hosts because virtualization is not enabled")
 	}
 
 	if cc.virtualHosts == nil {
-		return nil, fmt.Errorf("could not get virtual hosts because topology information has not been retrieved yet")
+		return nil, 0, fmt.Errorf("could not get virtual hosts because topology information has not been retrieved yet")
 	}
 
-	return cc.virtualHosts, nil
+	return cc.virtualHosts, cc.topologyConfig.Index, nil
+}
+
+// GetTopologyVersion returns a number that changes every time the hosts are refreshed, it is used to invalidate
+// the data that is computed from the topology.
+func (cc *ControlConn) GetTopologyVersion() int64 {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+
+	return cc.topologyVersion
 }
 
 func (cc *ControlConn) GetLocalVirtualHostIndex() int {
-	return cc.topologyConfig.Index
+	return cc.getTopologyConfig().Index
 }
 
 func (cc *ControlConn) GetAssignedHosts() ([]*Host, error) {
@@ -562,9 +685,102 @@ func (cc *ControlConn) NextAssignedHost() (*Host, error) {
 		return nil, fmt.Errorf("could not get assigned hosts because topology information has not been retrieved yet")
 	}
 
-	assignment := cc.incCurrentAssignmentCounter(len(cc.assignedHosts))
+	// the hosts that are DOWN are skipped unless all of them are DOWN
+	var host *Host
+	for i := 0; i < len(cc.assignedHosts); i++ {
+		assignment := cc.incCurrentAssignmentCounter(len(cc.assignedHosts))
+		if host == nil || !cc.downHostIds[cc.assignedHosts[assignment].HostId] {
+			host = cc.assignedHosts[assignment]
+		}
+		if !cc.downHostIds[host.HostId] {
+			break
+		}
+	}
+
+	return host, nil
+}
+
+// IsHostUp returns false if the provided host was reported DOWN by a STATUS_CHANGE event and hasn't been reported UP
+// since then.
+func (cc *ControlConn) IsHostUp(host *Host) bool {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+	return !cc.downHostIds[host.HostId]
+}
+
+// GetFailoverHosts returns the hosts of the local datacenter that are UP and whose endpoint is not the provided one,
+// the assigned hosts come first. The host of the provided endpoint is returned last if it is still UP.
+func (cc *ControlConn) GetFailoverHosts(currentEndpoint Endpoint) []*Host {
+	cc.topologyLock.RLock()
+	defer cc.topologyLock.RUnlock()
+
+	var currentHost *Host
+	hosts := make([]*Host, 0, len(cc.orderedHostsInLocalDc))
+	added := make(map[uuid.UUID]bool, len(cc.orderedHostsInLocalDc))
+	for _, candidates := range [][]*Host{cc.assignedHosts, cc.orderedHostsInLocalDc} {
+		for _, host := range candidates {
+			if added[host.HostId] || cc.downHostIds[host.HostId] {
+				continue
+			}
+			added[host.HostId] = true
+			if currentEndpoint != nil &&
+				cc.connConfig.CreateEndpoint(host).GetEndpointIdentifier() == currentEndpoint.GetEndpointIdentifier() {
+				currentHost = host
+				continue
+			}
+			hosts = append(hosts, host)
+		}
+	}
+	if currentHost != nil {
+		hosts = append(hosts, currentHost)
+	}
+	return hosts
+}
+
+// handleStatusChangeEvent marks the host of the provided event as UP or DOWN and notifies the observers when a host
+// goes DOWN. A topology refresh is scheduled if the host is not known yet.
+func (cc *ControlConn) handleStatusChangeEvent(event *message.StatusChangeEvent, conn CqlConnection) {
+	if event.Address == nil {
+		return
+	}
 
-	return cc.assignedHosts[assignment], nil
+	cc.topologyLock.Lock()
+	var host *Host
+	for _, h := range cc.hostsInLocalDcById {
+		if h.Address.Equal(event.Address.Addr) {
+			host = h
+			break
+		}
+	}
+	if host == nil {
+		cc.topologyLock.Unlock()
+		if event.ChangeType == primitive.StatusChangeTypeUp {
+			select {
+			case cc.refreshHostsDebouncer <- conn:
+			default:
+			}
+		}
+		return
+	}
+
+	wasDown := cc.downHostIds[host.HostId]
+	switch event.ChangeType {
+	case primitive.StatusChangeTypeUp:
+		delete(cc.downHostIds, host.HostId)
+	case primitive.StatusChangeTypeDown:
+		cc.downHostIds[host.HostId] = true
+	}
+	isDown := cc.downHostIds[host.HostId]
+	if isDown && !wasDown {
+		for observer := range cc.protocolEventSubscribers {
+			observer.OnHostDown(host)
+		}
+	}
+	cc.topologyLock.Unlock()
+
+	if isDown != wasDown {
+		log.Infof("Host %v of %v is %v.", host, cc.connConfig.GetClusterType(), event.ChangeType)
+	}
 }
 
 func (cc *ControlConn) GetClusterName() string {
@@ -806,4 +1022,6 @@ func (recv *VirtualHost) String() string {
 
 type ProtocolEventObserver interface {
 	OnHostRemoved(host *Host)
+	// OnHostDown is called when a STATUS_CHANGE event reports that a host is DOWN.
+	OnHostDown(host *Host)
 }
@@ -0,0 +1,69 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+)
+
+// isCounterWriteRequest returns true if the request updates counter columns (see ZDM_COUNTER_WRITES_POLICY).
+// The proxy doesn't have the schema so a QUERY is only detected if it increments or decrements a counter by an
+// integer literal (e.g. "SET c = c + 1"), prepared statements are also detected with the types of their bind
+// variables and a BATCH is a counter update if it is a COUNTER BATCH or if one of its child statements is one.
+func isCounterWriteRequest(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (bool, error) {
+	switch typedRequestInfo := requestInfo.(type) {
+	case *BatchRequestInfo:
+		decodedFrame, stmtsQueryData, err := frameContext.GetOrDecodeAndInspect(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		if batchMsg, ok := decodedFrame.Body.Message.(*message.Batch); ok && batchMsg.Type == primitive.BatchTypeCounter {
+			return true, nil
+		}
+		for _, preparedData := range typedRequestInfo.GetPreparedDataByStmtIdx() {
+			if isCounterWritePreparedStatement(preparedData) {
+				return true, nil
+			}
+		}
+		for _, stmtQueryData := range stmtsQueryData {
+			if stmtQueryData.queryData.hasCounterUpdates() {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *ExecuteRequestInfo:
+		return isCounterWritePreparedStatement(typedRequestInfo.GetPreparedData()), nil
+	case *GenericRequestInfo:
+		if frameContext.GetRawFrame().Header.OpCode != primitive.OpCodeQuery {
+			return false, nil
+		}
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		return stmtQueryData.queryData.hasCounterUpdates(), nil
+	}
+	return false, nil
+}
+
+// isCounterWritePreparedStatement returns true if the prepared statement updates counter columns. The primary key
+// columns can't be counters so a bind variable of type counter is always the value of a counter update.
+func isCounterWritePreparedStatement(preparedData PreparedData) bool {
+	queryInfo := preparedData.GetPrepareRequestInfo().GetQueryInfo()
+	if queryInfo != nil && queryInfo.hasCounterUpdates() {
+		return true
+	}
+	for _, variablesMetadata := range []*message.VariablesMetadata{
+		preparedData.GetOriginVariablesMetadata(), preparedData.GetTargetVariablesMetadata()} {
+		if variablesMetadata == nil {
+			continue
+		}
+		for _, column := range variablesMetadata.Columns {
+			if column.Type != nil && column.Type.GetDataTypeCode() == primitive.DataTypeCodeCounter {
+				return true
+			}
+		}
+	}
+	return false
+}
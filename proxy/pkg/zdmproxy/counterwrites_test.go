@@ -0,0 +1,85 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsCounterWriteRequest(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newPreparedData := func(query string, variableTypes ...datatype.DataType) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, query, "")
+		prepareRequestInfo.queryInfo = inspectCqlQuery(query, "", generator)
+		variablesMetadata := &message.VariablesMetadata{}
+		for i, variableType := range variableTypes {
+			variablesMetadata.Columns = append(variablesMetadata.Columns, &message.ColumnMetadata{
+				Keyspace: "ks", Table: "tb", Name: "c", Index: int32(i), Type: variableType})
+		}
+		return &preparedDataImpl{
+			prepareRequestInfo:      prepareRequestInfo,
+			originVariablesMetadata: variablesMetadata,
+			targetVariablesMetadata: variablesMetadata,
+		}
+	}
+	newQuery := func(query string) message.Message {
+		return &message.Query{Query: query}
+	}
+	genericRequestInfo := NewGenericRequestInfo(forwardToBoth, false, true)
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    bool
+	}{
+		{"increment", newQuery("UPDATE ks.tb SET c = c + 1 WHERE a = 1"), genericRequestInfo, true},
+		{"decrement", newQuery("update ks.tb set c = c - 2 where a = 1"), genericRequestInfo, true},
+		{"increment with shorthand", newQuery("UPDATE ks.tb SET c += 1 WHERE a = 1"), genericRequestInfo, true},
+		{"decrement with shorthand", newQuery("UPDATE ks.tb SET c -= -5 WHERE a = 1"), genericRequestInfo, true},
+		{"quoted counter", newQuery("UPDATE ks.tb SET \"C\" = \"C\" + 1 WHERE a = 1"), genericRequestInfo, true},
+		{"increment with bind marker", newQuery("UPDATE ks.tb SET c = c + ? WHERE a = 1"), genericRequestInfo, false},
+		{"append to list", newQuery("UPDATE ks.tb SET l = l + [1] WHERE a = 1"), genericRequestInfo, false},
+		{"add other column", newQuery("UPDATE ks.tb SET c = d + 1 WHERE a = 1"), genericRequestInfo, false},
+		{"regular update", newQuery("UPDATE ks.tb SET c = 1 WHERE a = 1"), genericRequestInfo, false},
+		{"insert", newQuery("INSERT INTO ks.tb (a, c) VALUES (1, 1)"), genericRequestInfo, false},
+		{"select", newQuery("SELECT * FROM ks.tb WHERE a = 1"), genericRequestInfo, false},
+		{"execute increment with bind marker", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData(
+				"UPDATE ks.tb SET c = c + ? WHERE a = ?", datatype.Counter, datatype.Int)), true},
+		{"execute increment", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("UPDATE ks.tb SET c = c + 1 WHERE a = ?", datatype.Int)), true},
+		{"execute regular update", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData(
+				"UPDATE ks.tb SET b = ? WHERE a = ?", datatype.Int, datatype.Int)), false},
+		{"counter batch", &message.Batch{Type: primitive.BatchTypeCounter, Children: []*message.BatchChild{
+			{QueryOrId: "UPDATE ks.tb SET c = c + ? WHERE a = 1"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), true},
+		{"batch with counter child", &message.Batch{Type: primitive.BatchTypeUnlogged, Children: []*message.BatchChild{
+			{QueryOrId: "UPDATE ks.tb SET b = 1 WHERE a = 2"},
+			{QueryOrId: "UPDATE ks.tb SET c = c + 1 WHERE a = 1"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), true},
+		{"batch with counter prepared child", &message.Batch{Type: primitive.BatchTypeUnlogged, Children: []*message.BatchChild{
+			{QueryOrId: "UPDATE ks.tb SET b = 1 WHERE a = 2"},
+			{QueryOrId: []byte("ID")}}},
+			NewBatchRequestInfo(map[int]PreparedData{
+				1: newPreparedData("UPDATE ks.tb SET c = c + ? WHERE a = 1", datatype.Counter)}, forwardToBoth), true},
+		{"logged batch", &message.Batch{Type: primitive.BatchTypeLogged, Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+			{QueryOrId: "UPDATE ks.tb SET b = 1 WHERE a = 2"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			isCounterWrite, err := isCounterWriteRequest(NewFrameDecodeContext(f), tt.requestInfo, "", generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, isCounterWrite)
+		})
+	}
+}
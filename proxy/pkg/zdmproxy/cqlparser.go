@@ -29,6 +29,11 @@ const (
 	peersV2 = interceptedQueryType("peersV2")
 	peersV1 = interceptedQueryType("peersV1")
 	local   = interceptedQueryType("local")
+
+	// system_views.zdm_* tables, see proxyVirtualTables
+	proxyClients            = interceptedQueryType("proxyClients")
+	proxyPreparedStatements = interceptedQueryType("proxyPreparedStatements")
+	proxyRoutingRules       = interceptedQueryType("proxyRoutingRules")
 )
 
 const (
@@ -68,8 +73,13 @@ func buildRequestInfo(
 	primaryCluster common.ClusterType,
 	forwardSystemQueriesToTarget bool,
 	virtualizationEnabled bool,
+	proxyVirtualTablesEnabled bool,
+	adminKeyspaceEnabled bool,
 	forwardAuthToTarget bool,
-	timeUuidGenerator TimeUuidGenerator) (RequestInfo, error) {
+	timeUuidGenerator TimeUuidGenerator,
+	schemaStatementPolicies *schemaStatementPolicies,
+	tableRoutingRules *tableRoutingRules,
+	statementFilter *statementFilter) (RequestInfo, error) {
 
 	f := frameContext.GetRawFrame()
 	switch f.Header.OpCode {
@@ -80,30 +90,37 @@ func buildRequestInfo(
 		}
 		return getRequestInfoFromQueryInfo(
 			frameContext.GetRawFrame(), primaryCluster,
-			forwardSystemQueriesToTarget, virtualizationEnabled, stmtQueryData.queryData), nil
+			forwardSystemQueriesToTarget, virtualizationEnabled, proxyVirtualTablesEnabled, adminKeyspaceEnabled,
+			stmtQueryData.queryData,
+			schemaStatementPolicies, tableRoutingRules, statementFilter), nil
 	case primitive.OpCodePrepare:
 		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspaceName, timeUuidGenerator)
 		if err != nil {
 			return nil, fmt.Errorf("could not inspect PREPARE frame: %w", err)
 		}
-		decodedFrame, err := frameContext.GetOrDecodeFrame()
+		prepareQuery, prepareKeyspace, err := frameContext.GetOrPeekPrepare()
 		if err != nil {
-			return nil, fmt.Errorf("could not decode frame: %w", err)
-		}
-		prepareMsg, ok := decodedFrame.Body.Message.(*message.Prepare)
-		if !ok {
-			return nil, fmt.Errorf("unexpected message type when decoding PREPARE message: %v", decodedFrame.Body.Message)
+			return nil, err
 		}
 		baseRequestInfo := getRequestInfoFromQueryInfo(
 			frameContext.GetRawFrame(), primaryCluster,
-			forwardSystemQueriesToTarget, virtualizationEnabled, stmtQueryData.queryData)
+			forwardSystemQueriesToTarget, virtualizationEnabled, proxyVirtualTablesEnabled, adminKeyspaceEnabled,
+			stmtQueryData.queryData,
+			schemaStatementPolicies, tableRoutingRules, statementFilter)
+		if rejectedRequestInfo, ok := baseRequestInfo.(*RejectedRequestInfo); ok {
+			return rejectedRequestInfo, nil
+		}
+		if _, ok := baseRequestInfo.(*AdminRequestInfo); ok {
+			return NewRejectedRequestInfo(
+				fmt.Sprintf("Statements on the %v keyspace can not be prepared", adminKeyspaceName)), nil
+		}
 		replacedTerms := make([]*term, 0)
 		if len(stmtsReplacedTerms) > 1 {
 			return nil, fmt.Errorf("expected single list of replaced terms for prepare message but got %v", len(stmtsReplacedTerms))
 		} else if len(stmtsReplacedTerms) == 1 {
 			replacedTerms = stmtsReplacedTerms[0].replacedTerms
 		}
-		return NewPrepareRequestInfo(baseRequestInfo, replacedTerms, stmtQueryData.queryData.hasPositionalBindMarkers(), prepareMsg.Query, prepareMsg.Keyspace), nil
+		return NewPrepareRequestInfo(baseRequestInfo, replacedTerms, stmtQueryData.queryData.hasPositionalBindMarkers(), prepareQuery, prepareKeyspace), nil
 	case primitive.OpCodeBatch:
 		decodedFrame, err := frameContext.GetOrDecodeFrame()
 		if err != nil {
@@ -113,30 +130,52 @@ func buildRequestInfo(
 		if !ok {
 			return nil, fmt.Errorf("could not convert message with batch op code to batch type, got %v instead", decodedFrame.Body.Message)
 		}
+		stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspaceName, timeUuidGenerator)
+		if err != nil {
+			return nil, fmt.Errorf("could not inspect BATCH frame: %w", err)
+		}
+		queryInfoByStmtIdx := make(map[int]QueryInfo, len(stmtsQueryData))
+		for _, stmtQueryData := range stmtsQueryData {
+			queryInfoByStmtIdx[stmtQueryData.statementIndex] = stmtQueryData.queryData
+		}
 		preparedDataByStmtIdxMap := make(map[int]PreparedData)
+		childDecisions := make([]forwardDecision, 0, len(batchMsg.Children))
 		for childIdx, child := range batchMsg.Children {
+			var childRequestInfo RequestInfo
 			switch queryOrId := child.QueryOrId.(type) {
 			case []byte:
-				preparedData, err := getPreparedData(psCache, mh, queryOrId, primitive.OpCodeBatch, decodedFrame)
+				preparedData, err := getPreparedData(psCache, mh, queryOrId, primitive.OpCodeBatch, frameContext)
 				if err != nil {
 					return nil, err
 				} else {
 					preparedDataByStmtIdxMap[childIdx] = preparedData
 				}
+				childRequestInfo = preparedData.GetPrepareRequestInfo().GetBaseRequestInfo()
+			case string:
+				queryInfo, ok := queryInfoByStmtIdx[childIdx]
+				if !ok {
+					return nil, fmt.Errorf("could not find inspected query of BATCH child statement %d", childIdx)
+				}
+				// a BATCH can not be intercepted so the reads of the intercepted tables are handled as system queries
+				childRequestInfo = getRequestInfoFromQueryInfo(
+					f, primaryCluster, forwardSystemQueriesToTarget, false, false, false, queryInfo, nil, tableRoutingRules,
+					statementFilter)
+				if rejectedRequestInfo, ok := childRequestInfo.(*RejectedRequestInfo); ok {
+					return rejectedRequestInfo, nil
+				}
 			default:
+				return nil, fmt.Errorf("unexpected query or id type of BATCH child statement %d: %T", childIdx, queryOrId)
 			}
+			childDecisions = append(childDecisions,
+				getBatchChildForwardDecision(childRequestInfo.GetForwardDecision(), forwardSystemQueriesToTarget))
 		}
-		return NewBatchRequestInfo(preparedDataByStmtIdxMap), nil
+		return NewBatchRequestInfo(preparedDataByStmtIdxMap, getBatchForwardDecision(childDecisions)), nil
 	case primitive.OpCodeExecute:
-		decodedFrame, err := frameContext.GetOrDecodeFrame()
+		queryId, err := frameContext.GetOrPeekQueryId()
 		if err != nil {
-			return nil, fmt.Errorf("could not decode execute raw frame: %w", err)
-		}
-		executeMsg, ok := decodedFrame.Body.Message.(*message.Execute)
-		if !ok {
-			return nil, fmt.Errorf("expected Execute but got %v instead", decodedFrame.Body.Message.GetOpCode())
+			return nil, err
 		}
-		preparedData, err := getPreparedData(psCache, mh, executeMsg.QueryId, primitive.OpCodeExecute, decodedFrame)
+		preparedData, err := getPreparedData(psCache, mh, queryId, primitive.OpCodeExecute, frameContext)
 		if err != nil {
 			return nil, err
 		} else {
@@ -155,12 +194,40 @@ func buildRequestInfo(
 	}
 }
 
+// getBatchChildForwardDecision returns the forward decision of a BATCH child statement, the prepared statements that
+// are intercepted (forwardToNone) are forwarded like the other system queries.
+func getBatchChildForwardDecision(decision forwardDecision, forwardSystemQueriesToTarget bool) forwardDecision {
+	if decision != forwardToNone {
+		return decision
+	}
+	if forwardSystemQueriesToTarget {
+		return forwardToTarget
+	}
+	return forwardToOrigin
+}
+
+// getBatchForwardDecision returns the forward decision of a BATCH based on the decisions of its child statements.
+// A BATCH is only forwarded to a single cluster if all of its child statements would be forwarded to that cluster
+// (e.g. a BATCH that only contains reads), otherwise it is forwarded to both clusters.
+func getBatchForwardDecision(childDecisions []forwardDecision) forwardDecision {
+	if len(childDecisions) == 0 {
+		return forwardToBoth
+	}
+	decision := childDecisions[0]
+	for _, childDecision := range childDecisions[1:] {
+		if childDecision != decision {
+			return forwardToBoth
+		}
+	}
+	return decision
+}
+
 func getPreparedData(
 	psCache *PreparedStatementCache,
 	mh *metrics.MetricHandler,
 	preparedId []byte,
 	code primitive.OpCode,
-	decodedFrame *frame.Frame) (PreparedData, error) {
+	frameContext *frameDecodeContext) (PreparedData, error) {
 	if preparedData, ok := psCache.Get(preparedId); ok {
 		log.Tracef("%v with prepared-id = '%s' has prepared-data = %v", code.String(), hex.EncodeToString(preparedId), preparedData)
 		// The forward decision was set in the cache when handling the corresponding PREPARE request
@@ -168,6 +235,10 @@ func getPreparedData(
 	} else {
 		log.Warnf("No cached entry for prepared-id = '%s' for %v.", hex.EncodeToString(preparedId), code.String())
 		mh.GetProxyMetrics().PSCacheMissCount.Add(1)
+		decodedFrame, err := frameContext.GetOrDecodeFrame()
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %v raw frame: %w", code.String(), err)
+		}
 		// return meaningful error to caller so it can generate an unprepared response
 		return nil, &UnpreparedExecuteError{Header: decodedFrame.Header, Body: decodedFrame.Body, preparedId: preparedId}
 	}
@@ -178,7 +249,28 @@ func getRequestInfoFromQueryInfo(
 	primaryCluster common.ClusterType,
 	forwardSystemQueriesToTarget bool,
 	virtualizationEnabled bool,
-	queryInfo QueryInfo) RequestInfo {
+	proxyVirtualTablesEnabled bool,
+	adminKeyspaceEnabled bool,
+	queryInfo QueryInfo,
+	schemaStatementPolicies *schemaStatementPolicies,
+	tableRoutingRules *tableRoutingRules,
+	statementFilter *statementFilter) RequestInfo {
+
+	if adminKeyspaceEnabled && isAdminKeyspaceStatement(queryInfo) {
+		moduleLogger(LogModuleParser).Debugf("Detected %v statement: %v with stream id: %v", adminKeyspaceName, queryInfo.getQuery(), f.Header.StreamId)
+		return NewAdminRequestInfo(queryInfo)
+	}
+
+	if filterRequestInfo := statementFilter.getRequestInfo(queryInfo); filterRequestInfo != nil {
+		moduleLogger(LogModuleParser).Debugf("Statement filter rule applied to query: %v with stream id: %v, result: %v",
+			queryInfo.getQuery(), f.Header.StreamId, filterRequestInfo)
+		return filterRequestInfo
+	}
+
+	if rule := tableRoutingRules.match(queryInfo); rule != nil {
+		moduleLogger(LogModuleParser).Debugf("Table routing rule %v applied to query: %v with stream id: %v", rule, queryInfo.getQuery(), f.Header.StreamId)
+		return NewTableRoutingRequestInfo(rule, queryInfo.getStatementType() == statementTypeSelect)
+	}
 
 	var sendAlsoToAsync bool
 	forwardDecision := forwardToBoth
@@ -186,20 +278,27 @@ func getRequestInfoFromQueryInfo(
 		if virtualizationEnabled {
 			parsedSelectClause := queryInfo.getParsedSelectClause()
 			if isSystemLocal(queryInfo) {
-				log.Debugf("Detected system local query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
+				moduleLogger(LogModuleParser).Debugf("Detected system local query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
 				return NewInterceptedRequestInfo(local, parsedSelectClause)
 			} else if isSystemPeersV1(queryInfo) {
-				log.Debugf("Detected system peers query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
+				moduleLogger(LogModuleParser).Debugf("Detected system peers query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
 				return NewInterceptedRequestInfo(peersV1, parsedSelectClause)
 			} else if isSystemPeersV2(queryInfo) {
-				log.Debugf("Detected system peers_v2 query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
+				moduleLogger(LogModuleParser).Debugf("Detected system peers_v2 query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
 				return NewInterceptedRequestInfo(peersV2, parsedSelectClause)
 			}
 		}
 
+		if proxyVirtualTablesEnabled {
+			if queryType, ok := getProxyVirtualTableQueryType(queryInfo.getApplicableKeyspace(), queryInfo.getTableName()); ok {
+				moduleLogger(LogModuleParser).Debugf("Detected proxy virtual table query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
+				return NewInterceptedRequestInfo(queryType, queryInfo.getParsedSelectClause())
+			}
+		}
+
 		if isSystemQuery(queryInfo) {
 			sendAlsoToAsync = false
-			log.Debugf("Detected system query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
+			moduleLogger(LogModuleParser).Debugf("Detected system query: %v with stream id: %v", queryInfo.getQuery(), f.Header.StreamId)
 			if forwardSystemQueriesToTarget {
 				forwardDecision = forwardToTarget
 			} else {
@@ -217,6 +316,13 @@ func getRequestInfoFromQueryInfo(
 		sendAlsoToAsync = true
 	} else {
 		sendAlsoToAsync = false
+		if schemaStatementPolicies != nil {
+			if policyRequestInfo := schemaStatementPolicies.getRequestInfo(queryInfo.getQuery()); policyRequestInfo != nil {
+				moduleLogger(LogModuleParser).Debugf("Schema statement policy applied to query: %v with stream id: %v, result: %v",
+					queryInfo.getQuery(), f.Header.StreamId, policyRequestInfo)
+				return policyRequestInfo
+			}
+		}
 	}
 
 	log.Tracef("Forward decision: %s", forwardDecision)
@@ -296,6 +402,44 @@ func (recv *frameDecodeContext) GetOrDecodeFrame() (*frame.Frame, error) {
 	return decodedFrame, nil
 }
 
+// GetOrPeekQueryId returns the prepared id of an EXECUTE request, the frame is only decoded if the id can not be read
+// from the raw body.
+func (recv *frameDecodeContext) GetOrPeekQueryId() ([]byte, error) {
+	if recv.decodedFrame == nil {
+		if queryId, ok := peekQueryId(recv.frame); ok {
+			return queryId, nil
+		}
+	}
+	decodedFrame, err := recv.GetOrDecodeFrame()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode execute raw frame: %w", err)
+	}
+	executeMsg, ok := decodedFrame.Body.Message.(*message.Execute)
+	if !ok {
+		return nil, fmt.Errorf("expected Execute but got %v instead", decodedFrame.Body.Message.GetOpCode())
+	}
+	return executeMsg.QueryId, nil
+}
+
+// GetOrPeekPrepare returns the query string and the keyspace of a PREPARE request, the frame is only decoded if the
+// protocol version allows the keyspace to be set in the request or if the query string can not be read from the raw body.
+func (recv *frameDecodeContext) GetOrPeekPrepare() (query string, keyspace string, err error) {
+	if recv.decodedFrame == nil && !protocolSupportsKeyspaceInRequest(recv.frame.Header.Version) {
+		if query, ok := peekQueryString(recv.frame); ok {
+			return query, "", nil
+		}
+	}
+	decodedFrame, err := recv.GetOrDecodeFrame()
+	if err != nil {
+		return "", "", fmt.Errorf("could not decode frame: %w", err)
+	}
+	prepareMsg, ok := decodedFrame.Body.Message.(*message.Prepare)
+	if !ok {
+		return "", "", fmt.Errorf("unexpected message type when decoding PREPARE message: %v", decodedFrame.Body.Message)
+	}
+	return prepareMsg.Query, prepareMsg.Keyspace, nil
+}
+
 func (recv *frameDecodeContext) GetOrInspectStatement(currentKeyspace string, timeUuidGenerator TimeUuidGenerator) (*statementQueryData, error) {
 	err := recv.inspectStatements(currentKeyspace, timeUuidGenerator)
 	if err != nil {
@@ -323,6 +467,15 @@ func (recv *frameDecodeContext) inspectStatements(currentKeyspace string, timeUu
 		return nil
 	}
 
+	if recv.decodedFrame == nil && !protocolSupportsKeyspaceInRequest(recv.frame.Header.Version) {
+		// the keyspace can not be set in the request so the query string is all that is needed
+		if query, ok := peekQueryString(recv.frame); ok {
+			recv.statementsQueryData = []*statementQueryData{
+				{statementIndex: 0, queryData: inspectCqlQuery(query, currentKeyspace, timeUuidGenerator)}}
+			return nil
+		}
+	}
+
 	decodedFrame, err := recv.GetOrDecodeFrame()
 	if err != nil {
 		return fmt.Errorf("could not decode frame: %w", err)
@@ -80,8 +80,13 @@ func parseEncodedRequestForTests(queryRawFrame *frame.RawFrame, t *testing.T) (R
 		generalParams.primaryCluster,
 		generalParams.forwardSystemQueriesToTarget,
 		generalParams.virtualizationEnabled,
+		false,
+		false,
 		generalParams.forwardAuthToTarget,
-		generalParams.timeUuidGenerator)
+		generalParams.timeUuidGenerator,
+		nil,
+		nil,
+		nil)
 }
 
 func checkExpectedForwardDecisionOrErrorForTests(actualRequestInfo RequestInfo, actualError error, expected interface{}, t *testing.T) {
@@ -0,0 +1,33 @@
+package zdmproxy
+
+import (
+	"regexp"
+	"strings"
+)
+
+// cqlLiteralRegex matches the literals of a CQL statement, i.e. the string literals ('value' or $$value$$), the uuid
+// and timeuuid literals (5132b130-ae79-11e4-ab27-0800200c9a66), the blob literals (0xcafe), the duration literals
+// (1h30m), the integer and float literals (42, 1.5e10, NaN or Infinity) and the boolean literals. The alternatives are
+// tried in order at each position. The quoted identifiers ("Column 1") are matched as well so that their content is
+// not masked.
+var cqlLiteralRegex = regexp.MustCompile(strings.Join([]string{
+	`"(?:[^"]|"")*"`,
+	`'(?:[^']|'')*'|\$\$(?s:.*?)\$\$`,
+	`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`,
+	`\b0[xX][0-9a-fA-F]*\b`,
+	`\b(?:[0-9]+(?i:mo|ms|us|µs|ns|y|w|d|h|m|s))+\b`,
+	`\b[0-9]+(?:\.[0-9]+)?(?:[eE][+-]?[0-9]+)?\b|\b(?:NaN|Infinity)\b`,
+	`\b(?i:true|false)\b`,
+}, "|"))
+
+// maskCqlLiterals replaces every literal of the query with ? so that the bound data of the statements is never
+// logged or captured and the queries that only differ by their literals are the same. Every component that logs or
+// captures statements masks them with it.
+func maskCqlLiterals(query string) string {
+	return cqlLiteralRegex.ReplaceAllStringFunc(query, func(match string) string {
+		if strings.HasPrefix(match, `"`) {
+			return match
+		}
+		return "?"
+	})
+}
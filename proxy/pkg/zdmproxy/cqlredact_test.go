@@ -0,0 +1,26 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMaskCqlLiterals(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM ks.tb", "SELECT * FROM ks.tb"},
+		{"SELECT * FROM ks.tb2 WHERE a = 'it''s' AND b = 42", "SELECT * FROM ks.tb2 WHERE a = ? AND b = ?"},
+		{"INSERT INTO tb (id, c, d) VALUES (5132b130-ae79-11e4-ab27-0800200c9a66, 0xcafe, 1.5e10)",
+			"INSERT INTO tb (id, c, d) VALUES (?, ?, ?)"},
+		{"UPDATE tb SET a = $$x 'y' z$$ WHERE id = ?", "UPDATE tb SET a = ? WHERE id = ?"},
+		{"UPDATE tb SET a = true, b = FALSE, c = 0x, d = 1h30m, e = NaN WHERE id = e1b2c3d4-0000-1000-8000-00a0c91e6bf6",
+			"UPDATE tb SET a = ?, b = ?, c = ?, d = ?, e = ? WHERE id = ?"},
+		{`SELECT "Column 1" FROM ks.tb WHERE "k2" IN (1, 2) LIMIT 10`,
+			`SELECT "Column 1" FROM ks.tb WHERE "k2" IN (?, ?) LIMIT ?`},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, maskCqlLiterals(tt.query))
+	}
+}
@@ -0,0 +1,87 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"sync"
+)
+
+// clusterCredentials holds the credentials that the proxy uses to open new connections to the clusters. The
+// credentials that are read from files (ZDM_*_USERNAME_FILE and ZDM_*_PASSWORD_FILE) can change at runtime,
+// see refresh.
+type clusterCredentials struct {
+	lock   *sync.RWMutex
+	origin *AuthCredentials
+	target *AuthCredentials
+}
+
+func newClusterCredentials(conf *config.Config) *clusterCredentials {
+	return &clusterCredentials{
+		lock:   &sync.RWMutex{},
+		origin: &AuthCredentials{Username: conf.OriginUsername, Password: conf.OriginPassword},
+		target: &AuthCredentials{Username: conf.TargetUsername, Password: conf.TargetPassword},
+	}
+}
+
+func hasCredentialFiles(conf *config.Config) bool {
+	return conf.OriginUsernameFile != "" || conf.OriginPasswordFile != "" ||
+		conf.TargetUsernameFile != "" || conf.TargetPasswordFile != ""
+}
+
+// get returns the current credentials of the cluster, the returned object must not be modified.
+func (recv *clusterCredentials) get(clusterType common.ClusterType) *AuthCredentials {
+	recv.lock.RLock()
+	defer recv.lock.RUnlock()
+	if clusterType == common.ClusterTypeTarget {
+		return recv.target
+	}
+	return recv.origin
+}
+
+// refresh reads the credential files again and returns the clusters whose credentials changed.
+func (recv *clusterCredentials) refresh(conf *config.Config) ([]common.ClusterType, error) {
+	origin, err := readClusterCredentialFiles(conf, recv.get(common.ClusterTypeOrigin),
+		conf.OriginUsernameFile, conf.OriginPasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %v credential files: %w", common.ClusterTypeOrigin, err)
+	}
+	target, err := readClusterCredentialFiles(conf, recv.get(common.ClusterTypeTarget),
+		conf.TargetUsernameFile, conf.TargetPasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %v credential files: %w", common.ClusterTypeTarget, err)
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	var changed []common.ClusterType
+	if *origin != *recv.origin {
+		recv.origin = origin
+		changed = append(changed, common.ClusterTypeOrigin)
+	}
+	if *target != *recv.target {
+		recv.target = target
+		changed = append(changed, common.ClusterTypeTarget)
+	}
+	return changed, nil
+}
+
+// readClusterCredentialFiles returns a copy of the current credentials with the values of the files that are set.
+func readClusterCredentialFiles(
+	conf *config.Config, current *AuthCredentials, usernameFile string, passwordFile string) (*AuthCredentials, error) {
+	creds := *current
+	var err error
+	if usernameFile != "" {
+		creds.Username, err = conf.ReadCredentialFile(usernameFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if passwordFile != "" {
+		creds.Password, err = conf.ReadCredentialFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &creds, nil
+}
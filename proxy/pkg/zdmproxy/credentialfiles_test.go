@@ -0,0 +1,40 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClusterCredentials_Refresh(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.Nil(t, os.WriteFile(passwordFile, []byte("password1\n"), 0600))
+
+	conf := config.New()
+	conf.OriginUsername = "originUser"
+	conf.OriginPassword = "originPassword"
+	conf.TargetUsername = "targetUser"
+	conf.TargetPasswordFile = passwordFile
+	conf.TargetPassword = "password1"
+	require.True(t, hasCredentialFiles(conf))
+
+	creds := newClusterCredentials(conf)
+	changed, err := creds.refresh(conf)
+	require.Nil(t, err)
+	require.Empty(t, changed)
+
+	require.Nil(t, os.WriteFile(passwordFile, []byte("password2\n"), 0600))
+	changed, err = creds.refresh(conf)
+	require.Nil(t, err)
+	require.Equal(t, []common.ClusterType{common.ClusterTypeTarget}, changed)
+	require.Equal(t, &AuthCredentials{Username: "targetUser", Password: "password2"}, creds.get(common.ClusterTypeTarget))
+	require.Equal(t, &AuthCredentials{Username: "originUser", Password: "originPassword"}, creds.get(common.ClusterTypeOrigin))
+
+	require.Nil(t, os.Remove(passwordFile))
+	_, err = creds.refresh(conf)
+	require.NotNil(t, err)
+	require.Equal(t, "password2", creds.get(common.ClusterTypeTarget).Password)
+}
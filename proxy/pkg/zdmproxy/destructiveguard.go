@@ -0,0 +1,155 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/google/uuid"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// confirmationTokenRegex matches the confirmation token of a destructive statement,
+// e.g. /* zdm-confirmation-token: 3f1c0a4e-... */ DROP TABLE ks.tb
+var confirmationTokenRegex = regexp.MustCompile(`(?i)zdm-confirmation-token\s*[:=]\s*([0-9a-f-]+)`)
+
+// destructiveKeywordRegex avoids tokenizing queries that can't be destructive statements
+var destructiveKeywordRegex = regexp.MustCompile(`(?i)\b(truncate|drop)\b`)
+
+// DestructiveStatementGuard requires TRUNCATE and DROP statements to be confirmed through the admin API before
+// they are forwarded (see ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED). A statement is confirmed if destructive
+// statements are unlocked or if it contains a one-time token that was created with the admin API in a comment.
+type DestructiveStatementGuard struct {
+	lock              *sync.Mutex
+	tokens            map[string]time.Time // expiration of the tokens that were not used yet
+	unlockedUntil     time.Time
+	tokenTtl          time.Duration
+	maxUnlockDuration time.Duration
+	now               func() time.Time
+}
+
+// DestructiveStatementGuardStatus is the state of the guard that is reported by the admin API.
+type DestructiveStatementGuardStatus struct {
+	Unlocked      bool
+	UnlockedUntil *time.Time
+	PendingTokens int
+}
+
+// newDestructiveStatementGuard returns nil if destructive statements don't need to be confirmed.
+func newDestructiveStatementGuard(conf *common.DestructiveStatementsConfirmationConfig) *DestructiveStatementGuard {
+	if !conf.Enabled {
+		return nil
+	}
+	return &DestructiveStatementGuard{
+		lock:              &sync.Mutex{},
+		tokens:            make(map[string]time.Time),
+		tokenTtl:          time.Duration(conf.TokenTtlMs) * time.Millisecond,
+		maxUnlockDuration: time.Duration(conf.MaxUnlockDurationMs) * time.Millisecond,
+		now:               time.Now,
+	}
+}
+
+// CreateToken returns a new one-time confirmation token and its expiration.
+func (recv *DestructiveStatementGuard) CreateToken() (string, time.Time) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	now := recv.now()
+	for token, expiration := range recv.tokens {
+		if !now.Before(expiration) {
+			delete(recv.tokens, token)
+		}
+	}
+
+	token := uuid.New().String()
+	expiration := now.Add(recv.tokenTtl)
+	recv.tokens[token] = expiration
+	return token, expiration
+}
+
+// Unlock allows every destructive statement to be forwarded for the provided duration.
+func (recv *DestructiveStatementGuard) Unlock(duration time.Duration) (time.Time, error) {
+	if duration <= 0 {
+		return time.Time{}, fmt.Errorf("unlock duration must be positive but was %v", duration)
+	}
+	if duration > recv.maxUnlockDuration {
+		return time.Time{}, fmt.Errorf("unlock duration %v exceeds the maximum of %v "+
+			"(ZDM_DESTRUCTIVE_STATEMENTS_MAX_UNLOCK_DURATION_MS)", duration, recv.maxUnlockDuration)
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.unlockedUntil = recv.now().Add(duration)
+	return recv.unlockedUntil, nil
+}
+
+// Lock ends the unlock window and revokes the tokens that were not used yet.
+func (recv *DestructiveStatementGuard) Lock() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.unlockedUntil = time.Time{}
+	recv.tokens = make(map[string]time.Time)
+}
+
+func (recv *DestructiveStatementGuard) GetStatus() *DestructiveStatementGuardStatus {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	now := recv.now()
+	status := &DestructiveStatementGuardStatus{}
+	if now.Before(recv.unlockedUntil) {
+		unlockedUntil := recv.unlockedUntil
+		status.Unlocked = true
+		status.UnlockedUntil = &unlockedUntil
+	}
+	for _, expiration := range recv.tokens {
+		if now.Before(expiration) {
+			status.PendingTokens++
+		}
+	}
+	return status
+}
+
+// confirm returns true if the destructive statement can be forwarded, the token of the statement is used up if
+// destructive statements are not unlocked.
+func (recv *DestructiveStatementGuard) confirm(query string) bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	now := recv.now()
+	if now.Before(recv.unlockedUntil) {
+		return true
+	}
+
+	for _, match := range confirmationTokenRegex.FindAllStringSubmatch(query, -1) {
+		token := strings.ToLower(match[1])
+		expiration, ok := recv.tokens[token]
+		if !ok {
+			continue
+		}
+		delete(recv.tokens, token)
+		if now.Before(expiration) {
+			return true
+		}
+	}
+	return false
+}
+
+// getDestructiveStatementVerb returns TRUNCATE or DROP if the query is a destructive statement
+// or an empty string otherwise.
+func getDestructiveStatementVerb(query string) string {
+	if !destructiveKeywordRegex.MatchString(query) {
+		return ""
+	}
+	tokens := tokenizeDdl(query)
+	if len(tokens) == 0 {
+		return ""
+	}
+	switch tokens[0].text {
+	case "TRUNCATE", "DROP":
+		return tokens[0].text
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,73 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDestructiveStatementGuard(t *testing.T) {
+	require.Nil(t, newDestructiveStatementGuard(&common.DestructiveStatementsConfirmationConfig{Enabled: false}))
+
+	guard := newDestructiveStatementGuard(&common.DestructiveStatementsConfirmationConfig{
+		Enabled: true, TokenTtlMs: 60000, MaxUnlockDurationMs: 600000})
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	guard.now = func() time.Time { return now }
+
+	require.False(t, guard.confirm("DROP TABLE ks.tb"))
+
+	token, expiration := guard.CreateToken()
+	require.Equal(t, now.Add(time.Minute), expiration)
+	require.Equal(t, &DestructiveStatementGuardStatus{PendingTokens: 1}, guard.GetStatus())
+	require.False(t, guard.confirm("/* zdm-confirmation-token: 00000000-0000-0000-0000-000000000000 */ DROP TABLE ks.tb"))
+	require.True(t, guard.confirm("/* zdm-confirmation-token: "+strings.ToUpper(token)+" */ DROP TABLE ks.tb"))
+	require.False(t, guard.confirm("/* zdm-confirmation-token: "+token+" */ DROP TABLE ks.tb"), "token is used up")
+
+	token, _ = guard.CreateToken()
+	now = now.Add(time.Minute)
+	require.Equal(t, &DestructiveStatementGuardStatus{PendingTokens: 0}, guard.GetStatus())
+	require.False(t, guard.confirm("TRUNCATE ks.tb -- zdm-confirmation-token="+token), "token is expired")
+
+	_, err := guard.Unlock(time.Hour)
+	require.NotNil(t, err)
+	unlockedUntil, err := guard.Unlock(5 * time.Minute)
+	require.Nil(t, err)
+	require.Equal(t, now.Add(5*time.Minute), unlockedUntil)
+	require.Equal(t, &DestructiveStatementGuardStatus{Unlocked: true, UnlockedUntil: &unlockedUntil}, guard.GetStatus())
+	require.True(t, guard.confirm("TRUNCATE ks.tb"))
+	require.True(t, guard.confirm("TRUNCATE ks.tb"))
+
+	now = now.Add(5 * time.Minute)
+	require.False(t, guard.confirm("TRUNCATE ks.tb"), "unlock window is over")
+
+	_, err = guard.Unlock(time.Minute)
+	require.Nil(t, err)
+	guard.CreateToken()
+	guard.Lock()
+	require.Equal(t, &DestructiveStatementGuardStatus{}, guard.GetStatus())
+	require.False(t, guard.confirm("TRUNCATE ks.tb"))
+}
+
+func TestGetDestructiveStatementVerb(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"DROP TABLE ks.tb", "DROP"},
+		{"drop keyspace if exists ks", "DROP"},
+		{"/* zdm-confirmation-token: abc */ TRUNCATE TABLE ks.tb", "TRUNCATE"},
+		{"truncate ks.tb", "TRUNCATE"},
+		{"ALTER TABLE ks.tb DROP b", ""},
+		{"INSERT INTO ks.drop (a) VALUES (1)", ""},
+		{"SELECT * FROM ks.tb", ""},
+		{"CREATE TABLE ks.tb (a int PRIMARY KEY)", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			require.Equal(t, tt.expected, getDestructiveStatementVerb(tt.query))
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"time"
+)
+
+// clusterDialer opens the TCP connections to the cluster nodes. When the host of a node resolves to several addresses
+// (e.g. both A and AAAA records) the connection attempts are staggered across the addresses alternating between IPv6
+// and IPv4 (RFC 8305 "Happy Eyeballs v2"): a new attempt starts every attemptDelay or as soon as the previous one
+// fails and the first connection that is established wins. The staggered attempts are disabled if attemptDelay is 0,
+// the Go dialer is used as is in that case. The socket options are set on the connection that is returned.
+type clusterDialer struct {
+	attemptDelay  time.Duration
+	socketOptions *common.SocketOptions
+
+	lookupIPAddr func(ctx context.Context, host string) ([]net.IPAddr, error)
+	dial         func(ctx context.Context, network string, addr string) (net.Conn, error)
+}
+
+func newClusterDialer(attemptDelay time.Duration, socketOptions *common.SocketOptions) *clusterDialer {
+	dialer := &net.Dialer{}
+	return &clusterDialer{
+		attemptDelay:  attemptDelay,
+		socketOptions: socketOptions,
+		lookupIPAddr:  net.DefaultResolver.LookupIPAddr,
+		dial:          dialer.DialContext,
+	}
+}
+
+type dialAttemptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func (recv *clusterDialer) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	conn, err := recv.dialStaggered(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	err = applySocketOptions(conn, recv.socketOptions)
+	if err != nil {
+		log.Warnf("Could not set the socket options of the connection to %v: %v", conn.RemoteAddr(), err)
+	}
+	return conn, nil
+}
+
+func (recv *clusterDialer) dialStaggered(ctx context.Context, addr string) (net.Conn, error) {
+	if recv.attemptDelay <= 0 {
+		return recv.dial(ctx, "tcp", addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return recv.dial(ctx, "tcp", addr)
+	}
+	ipAddrs, err := recv.lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := interleaveAddressFamilies(ipAddrs)
+	if len(ips) <= 1 {
+		return recv.dial(ctx, "tcp", addr)
+	}
+
+	attemptCtx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+
+	results := make(chan dialAttemptResult, len(ips))
+	attemptTimer := time.NewTimer(recv.attemptDelay)
+	defer func() {
+		attemptTimer.Stop()
+	}()
+	startAttempt := func(ip net.IP) {
+		attemptTimer.Stop()
+		attemptTimer = time.NewTimer(recv.attemptDelay)
+		go func() {
+			conn, err := recv.dial(attemptCtx, "tcp", net.JoinHostPort(ip.String(), port))
+			results <- dialAttemptResult{conn: conn, err: err}
+		}()
+	}
+
+	startAttempt(ips[0])
+	next, pending := 1, 1
+	var firstErr error
+	for {
+		var attemptTimerC <-chan time.Time
+		if next < len(ips) {
+			attemptTimerC = attemptTimer.C
+		}
+
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				cancelFn()
+				go closeDialAttempts(results, pending)
+				return result.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			if ctx.Err() != nil {
+				next = len(ips)
+			}
+			if next == len(ips) {
+				if pending == 0 {
+					return nil, firstErr
+				}
+				// wait for the attempts that are still in progress
+				continue
+			}
+			// the previous attempt failed so the next one starts right away
+		case <-attemptTimerC:
+		}
+
+		startAttempt(ips[next])
+		next++
+		pending++
+	}
+}
+
+// closeDialAttempts closes the connections of the attempts that were still in progress when another attempt won.
+func closeDialAttempts(results chan dialAttemptResult, pending int) {
+	for i := 0; i < pending; i++ {
+		result := <-results
+		if result.err == nil {
+			_ = result.conn.Close()
+		}
+	}
+}
+
+// interleaveAddressFamilies returns the addresses alternating between the address family of the first address and
+// the other family while preserving the order of the resolver within each family.
+func interleaveAddressFamilies(ipAddrs []net.IPAddr) []net.IP {
+	var primary, secondary []net.IP
+	for _, ipAddr := range ipAddrs {
+		if len(primary) == 0 || (ipAddr.IP.To4() == nil) == (primary[0].To4() == nil) {
+			primary = append(primary, ipAddr.IP)
+		} else {
+			secondary = append(secondary, ipAddr.IP)
+		}
+	}
+
+	ips := make([]net.IP, 0, len(ipAddrs))
+	for i := 0; i < len(primary) || i < len(secondary); i++ {
+		if i < len(primary) {
+			ips = append(ips, primary[i])
+		}
+		if i < len(secondary) {
+			ips = append(ips, secondary[i])
+		}
+	}
+	return ips
+}
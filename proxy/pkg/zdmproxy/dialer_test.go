@@ -0,0 +1,188 @@
+package zdmproxy
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInterleaveAddressFamilies(t *testing.T) {
+	ipAddrs := func(addrs ...string) []net.IPAddr {
+		var result []net.IPAddr
+		for _, addr := range addrs {
+			result = append(result, net.IPAddr{IP: net.ParseIP(addr)})
+		}
+		return result
+	}
+	ips := func(addrs ...string) []net.IP {
+		var result []net.IP
+		for _, addr := range addrs {
+			result = append(result, net.ParseIP(addr))
+		}
+		return result
+	}
+
+	require.Equal(t,
+		ips("fd00::1", "10.0.0.1", "fd00::2", "10.0.0.2"),
+		interleaveAddressFamilies(ipAddrs("fd00::1", "fd00::2", "10.0.0.1", "10.0.0.2")))
+	require.Equal(t,
+		ips("10.0.0.1", "fd00::1", "10.0.0.2", "10.0.0.3"),
+		interleaveAddressFamilies(ipAddrs("10.0.0.1", "10.0.0.2", "fd00::1", "10.0.0.3")))
+	require.Equal(t,
+		ips("fd00::1", "fd00::2"),
+		interleaveAddressFamilies(ipAddrs("fd00::1", "fd00::2")))
+	require.Empty(t, interleaveAddressFamilies(nil))
+}
+
+// testDialer records the connection attempts of a clusterDialer, the attempts to the addresses in blocked only return
+// when their context is canceled and the attempts to the addresses in failed fail right away.
+type testDialer struct {
+	lock     *sync.Mutex
+	attempts []string
+	canceled []string
+	blocked  map[string]bool
+	failed   map[string]bool
+	listener net.Listener
+}
+
+func newTestDialer(t *testing.T, attemptDelay time.Duration, blocked []string, failed []string) (*clusterDialer, *testDialer) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	td := &testDialer{
+		lock:     &sync.Mutex{},
+		blocked:  map[string]bool{},
+		failed:   map[string]bool{},
+		listener: listener,
+	}
+	for _, addr := range blocked {
+		td.blocked[addr] = true
+	}
+	for _, addr := range failed {
+		td.failed[addr] = true
+	}
+	return &clusterDialer{
+		attemptDelay: attemptDelay,
+		lookupIPAddr: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			require.Equal(t, "cluster.local", host)
+			return []net.IPAddr{{IP: net.ParseIP("fd00::1")}, {IP: net.ParseIP("fd00::2")}, {IP: net.ParseIP("10.0.0.1")}}, nil
+		},
+		dial: td.dial,
+	}, td
+}
+
+func (recv *testDialer) dial(ctx context.Context, network string, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	recv.lock.Lock()
+	recv.attempts = append(recv.attempts, host)
+	recv.lock.Unlock()
+
+	if recv.blocked[host] {
+		<-ctx.Done()
+		recv.lock.Lock()
+		recv.canceled = append(recv.canceled, host)
+		recv.lock.Unlock()
+		return nil, ctx.Err()
+	}
+	if recv.failed[host] {
+		return nil, errors.New("connection refused by " + host)
+	}
+	return (&net.Dialer{}).DialContext(ctx, network, recv.listener.Addr().String())
+}
+
+func (recv *testDialer) getAttempts() ([]string, []string) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return append([]string{}, recv.attempts...), append([]string{}, recv.canceled...)
+}
+
+func TestClusterDialer_StaggeredAttempts(t *testing.T) {
+	dialer, td := newTestDialer(t, 50*time.Millisecond, []string{"fd00::1", "fd00::2"}, nil)
+
+	start := time.Now()
+	conn, err := dialer.DialContext(context.Background(), "cluster.local:9042")
+	require.Nil(t, err)
+	_ = conn.Close()
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// the attempt to the IPv4 address starts after the first IPv6 attempt and the blocked attempt is canceled
+	require.Eventually(t, func() bool {
+		attempts, canceled := td.getAttempts()
+		return len(attempts) == 2 && len(canceled) == 1
+	}, time.Second, 10*time.Millisecond)
+	attempts, canceled := td.getAttempts()
+	require.Equal(t, []string{"fd00::1", "10.0.0.1"}, attempts)
+	require.Equal(t, []string{"fd00::1"}, canceled)
+}
+
+func TestClusterDialer_NextAttemptStartsWhenPreviousFails(t *testing.T) {
+	dialer, td := newTestDialer(t, time.Hour, nil, []string{"fd00::1", "10.0.0.1"})
+
+	conn, err := dialer.DialContext(context.Background(), "cluster.local:9042")
+	require.Nil(t, err)
+	_ = conn.Close()
+
+	attempts, _ := td.getAttempts()
+	require.Equal(t, []string{"fd00::1", "10.0.0.1", "fd00::2"}, attempts)
+}
+
+func TestClusterDialer_AllAttemptsFail(t *testing.T) {
+	dialer, td := newTestDialer(t, time.Hour, nil, []string{"fd00::1", "fd00::2", "10.0.0.1"})
+
+	_, err := dialer.DialContext(context.Background(), "cluster.local:9042")
+	require.EqualError(t, err, "connection refused by fd00::1")
+
+	attempts, _ := td.getAttempts()
+	require.Equal(t, []string{"fd00::1", "10.0.0.1", "fd00::2"}, attempts)
+}
+
+func TestClusterDialer_ContextCanceled(t *testing.T) {
+	dialer, td := newTestDialer(t, 10*time.Millisecond, []string{"fd00::1", "fd00::2", "10.0.0.1"}, nil)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFn()
+	_, err := dialer.DialContext(ctx, "cluster.local:9042")
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	attempts, canceled := td.getAttempts()
+	require.Equal(t, []string{"fd00::1", "10.0.0.1", "fd00::2"}, attempts)
+	require.Equal(t, 3, len(canceled))
+}
+
+func TestClusterDialer_Disabled(t *testing.T) {
+	dialer, td := newTestDialer(t, 0, nil, nil)
+	dialer.lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return nil, errors.New("unexpected lookup")
+	}
+
+	conn, err := dialer.DialContext(context.Background(), "cluster.local:9042")
+	require.Nil(t, err)
+	_ = conn.Close()
+
+	attempts, _ := td.getAttempts()
+	require.Equal(t, []string{"cluster.local"}, attempts)
+
+	// IP literals are not resolved either
+	dialer.attemptDelay = time.Second
+	conn, err = dialer.DialContext(context.Background(), "10.0.0.1:9042")
+	require.Nil(t, err)
+	_ = conn.Close()
+}
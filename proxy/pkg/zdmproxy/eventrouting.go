@@ -0,0 +1,92 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"strings"
+	"sync"
+	"time"
+)
+
+// schemaEventDedupWindow is the period during which a schema change event that is equivalent to the last one that was
+// sent to the client for the same schema object is not sent again. Drivers refresh their schema metadata once for
+// equivalent events that are close to each other anyway.
+const schemaEventDedupWindow = time.Second
+
+// eventRouter decides which of the events that the cluster connections of a client connection receive are
+// sent to the client:
+//   - SCHEMA_CHANGE events are sent if they come from the primary cluster, the events that are equivalent to the last
+//     one that was sent for the same schema object within schemaEventDedupWindow are dropped (e.g. the events that
+//     both nodes deliver when the cluster connection fails over to another node);
+//   - TOPOLOGY_CHANGE and STATUS_CHANGE events describe the nodes of the clusters so they are dropped when the topology
+//     is virtualized, the client gets the TOPOLOGY_CHANGE events of the proxy instances instead
+//     (see ClientHandler.sendProxyTopologyEvent). Otherwise they are sent if they come from Target.
+type eventRouter struct {
+	primaryCluster        common.ClusterType
+	virtualizationEnabled bool
+
+	lock             *sync.Mutex
+	lastSchemaEvents map[string]*sentSchemaEvent // keyed on the schema object of the event
+}
+
+type sentSchemaEvent struct {
+	event  string
+	sentAt time.Time
+}
+
+func newEventRouter(primaryCluster common.ClusterType, virtualizationEnabled bool) *eventRouter {
+	return &eventRouter{
+		primaryCluster:        primaryCluster,
+		virtualizationEnabled: virtualizationEnabled,
+		lock:                  &sync.Mutex{},
+		lastSchemaEvents:      make(map[string]*sentSchemaEvent),
+	}
+}
+
+// route returns true if the provided event of the provided cluster should be sent to the client,
+// otherwise it returns the reason why it is dropped.
+func (recv *eventRouter) route(event message.Message, cluster common.ClusterType, now time.Time) (bool, string) {
+	switch typedEvent := event.(type) {
+	case *message.ProtocolError:
+		return true, ""
+	case *message.SchemaChangeEvent:
+		if cluster != recv.primaryCluster {
+			return false, fmt.Sprintf("schema change event from %v (primary cluster is %v)", cluster, recv.primaryCluster)
+		}
+		if !recv.trackSchemaEvent(typedEvent, now) {
+			return false, fmt.Sprintf("duplicate schema change event from %v", cluster)
+		}
+		return true, ""
+	case *message.StatusChangeEvent, *message.TopologyChangeEvent:
+		if recv.virtualizationEnabled {
+			return false, fmt.Sprintf("%v event from %v with virtualization enabled", event.GetOpCode(), cluster)
+		}
+		if cluster != common.ClusterTypeTarget {
+			return false, fmt.Sprintf("topology or status change event from %v", cluster)
+		}
+		return true, ""
+	}
+	return false, fmt.Sprintf("unexpected event body from %v", cluster)
+}
+
+// trackSchemaEvent returns false if the provided event is equivalent to the last one that was sent for the same
+// schema object within schemaEventDedupWindow.
+func (recv *eventRouter) trackSchemaEvent(event *message.SchemaChangeEvent, now time.Time) bool {
+	object := fmt.Sprintf("%v|%v|%v|%v", event.Target, event.Keyspace, event.Object, strings.Join(event.Arguments, ","))
+	description := fmt.Sprintf("%v|%v", event.ChangeType, object)
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	last, ok := recv.lastSchemaEvents[object]
+	if ok && last.event == description && now.Sub(last.sentAt) < schemaEventDedupWindow {
+		return false
+	}
+	for key, sent := range recv.lastSchemaEvents {
+		if now.Sub(sent.sentAt) >= schemaEventDedupWindow {
+			delete(recv.lastSchemaEvents, key)
+		}
+	}
+	recv.lastSchemaEvents[object] = &sentSchemaEvent{event: description, sentAt: now}
+	return true
+}
@@ -0,0 +1,75 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEventRouter_Route(t *testing.T) {
+	schemaEvent := &message.SchemaChangeEvent{
+		ChangeType: primitive.SchemaChangeTypeCreated, Target: primitive.SchemaChangeTargetTable, Keyspace: "ks", Object: "tb"}
+	statusEvent := &message.StatusChangeEvent{
+		ChangeType: primitive.StatusChangeTypeDown, Address: &primitive.Inet{Addr: net.ParseIP("127.0.0.1"), Port: 9042}}
+	topologyEvent := &message.TopologyChangeEvent{
+		ChangeType: primitive.TopologyChangeTypeNewNode, Address: &primitive.Inet{Addr: net.ParseIP("127.0.0.1"), Port: 9042}}
+
+	tests := []struct {
+		name                  string
+		primaryCluster        common.ClusterType
+		virtualizationEnabled bool
+		event                 message.Message
+		cluster               common.ClusterType
+		expected              bool
+	}{
+		{"schema change from origin", common.ClusterTypeOrigin, false, schemaEvent, common.ClusterTypeOrigin, true},
+		{"schema change from target", common.ClusterTypeOrigin, false, schemaEvent, common.ClusterTypeTarget, false},
+		{"schema change from primary target", common.ClusterTypeTarget, true, schemaEvent, common.ClusterTypeTarget, true},
+		{"schema change from secondary origin", common.ClusterTypeTarget, true, schemaEvent, common.ClusterTypeOrigin, false},
+		{"status change from target", common.ClusterTypeOrigin, false, statusEvent, common.ClusterTypeTarget, true},
+		{"status change from origin", common.ClusterTypeOrigin, false, statusEvent, common.ClusterTypeOrigin, false},
+		{"status change with virtualization", common.ClusterTypeOrigin, true, statusEvent, common.ClusterTypeTarget, false},
+		{"topology change from target", common.ClusterTypeTarget, false, topologyEvent, common.ClusterTypeTarget, true},
+		{"topology change from origin", common.ClusterTypeTarget, false, topologyEvent, common.ClusterTypeOrigin, false},
+		{"topology change with virtualization", common.ClusterTypeOrigin, true, topologyEvent, common.ClusterTypeTarget, false},
+		{"protocol error", common.ClusterTypeOrigin, true, &message.ProtocolError{}, common.ClusterTypeTarget, true},
+		{"not an event", common.ClusterTypeOrigin, false, &message.Ready{}, common.ClusterTypeOrigin, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newEventRouter(tt.primaryCluster, tt.virtualizationEnabled)
+			forward, reason := router.route(tt.event, tt.cluster, time.Now())
+			require.Equal(t, tt.expected, forward)
+			require.Equal(t, tt.expected, reason == "")
+		})
+	}
+}
+
+func TestEventRouter_SchemaEventDedup(t *testing.T) {
+	router := newEventRouter(common.ClusterTypeOrigin, false)
+	created := &message.SchemaChangeEvent{
+		ChangeType: primitive.SchemaChangeTypeCreated, Target: primitive.SchemaChangeTargetTable, Keyspace: "ks", Object: "tb"}
+	dropped := &message.SchemaChangeEvent{
+		ChangeType: primitive.SchemaChangeTypeDropped, Target: primitive.SchemaChangeTargetTable, Keyspace: "ks", Object: "tb"}
+	otherTable := &message.SchemaChangeEvent{
+		ChangeType: primitive.SchemaChangeTypeCreated, Target: primitive.SchemaChangeTargetTable, Keyspace: "ks", Object: "tb2"}
+
+	route := func(event message.Message, now time.Time) bool {
+		forward, _ := router.route(event, common.ClusterTypeOrigin, now)
+		return forward
+	}
+
+	now := time.Now()
+	require.True(t, route(created, now))
+	require.False(t, route(created, now.Add(100*time.Millisecond)))
+	require.True(t, route(otherTable, now.Add(100*time.Millisecond)))
+	// a different change of the same object is not a duplicate
+	require.True(t, route(dropped, now.Add(200*time.Millisecond)))
+	require.True(t, route(created, now.Add(300*time.Millisecond)))
+	// equivalent events are sent again after the window
+	require.True(t, route(created, now.Add(300*time.Millisecond+schemaEventDedupWindow)))
+}
@@ -0,0 +1,476 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	fleetStoreTypeEtcd   = "etcd"
+	fleetStoreTypeConsul = "consul"
+
+	fleetStoreRequestTimeout = 10 * time.Second
+	fleetStoreRetryInterval  = 5 * time.Second
+)
+
+var (
+	// consulWatchWaitTime is the maximum duration of the blocking queries of consul
+	consulWatchWaitTime = 30 * time.Second
+	// etcdWatchPollInterval is how often the key is read from etcd while it is watched
+	etcdWatchPollInterval = 1 * time.Second
+)
+
+// fleetStore is the key of a shared store (etcd or consul) that every proxy instance of the fleet watches.
+type fleetStore interface {
+	// watch returns the value of the key (nil if it does not exist) and its revision once the revision is different
+	// from the provided one, it returns immediately if the provided revision is 0. It can also return the same
+	// revision after a store specific wait time.
+	watch(ctx context.Context, revision uint64) ([]byte, uint64, error)
+	put(ctx context.Context, value []byte) error
+}
+
+// newFleetStore returns nil if the fleet store is disabled.
+func newFleetStore(conf *common.FleetStoreConfig) fleetStore {
+	client := newFleetStoreClient(conf)
+	switch conf.Type {
+	case fleetStoreTypeEtcd:
+		return &etcdFleetStore{client: client, key: conf.Key}
+	case fleetStoreTypeConsul:
+		return &consulFleetStore{client: client, key: conf.Key}
+	default:
+		return nil
+	}
+}
+
+// fleetStoreClient sends the requests to the first endpoint that is reachable.
+type fleetStoreClient struct {
+	endpoints  []string
+	httpClient *http.Client
+}
+
+func newFleetStoreClient(conf *common.FleetStoreConfig) *fleetStoreClient {
+	return &fleetStoreClient{endpoints: conf.Endpoints, httpClient: &http.Client{}}
+}
+
+// do returns the status code, the body and the headers of the response.
+func (recv *fleetStoreClient) do(ctx context.Context, method string, path string, body []byte) (int, []byte, http.Header, error) {
+	var lastErr error
+	for _, endpoint := range recv.endpoints {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		rsp, err := recv.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, nil, nil, err
+			}
+			lastErr = err
+			continue
+		}
+		rspBody, err := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("could not read the response of %v: %w", endpoint, err)
+			continue
+		}
+		if rsp.StatusCode >= http.StatusInternalServerError {
+			lastErr = fmt.Errorf("%v returned %v: %v", endpoint, rsp.Status, strings.TrimSpace(string(rspBody)))
+			continue
+		}
+		return rsp.StatusCode, rspBody, rsp.Header, nil
+	}
+	return 0, nil, nil, lastErr
+}
+
+// postEtcd sends a request to the HTTP API of etcd and parses the response if it is not nil.
+func (recv *fleetStoreClient) postEtcd(ctx context.Context, path string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	statusCode, rspBody, _, err := recv.do(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("etcd returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	if response == nil {
+		return nil
+	}
+	err = json.Unmarshal(rspBody, response)
+	if err != nil {
+		return fmt.Errorf("could not parse the response of etcd: %w", err)
+	}
+	return nil
+}
+
+// etcdFleetStore uses the HTTP API (gRPC gateway) of etcd v3, the key is read every etcdWatchPollInterval while it
+// is watched.
+type etcdFleetStore struct {
+	client *fleetStoreClient
+	key    string
+}
+
+type etcdRangeResponse struct {
+	Header struct {
+		Revision uint64 `json:"revision,string"`
+	} `json:"header"`
+	Kvs []struct {
+		Value       []byte `json:"value"`
+		ModRevision uint64 `json:"mod_revision,string"`
+	} `json:"kvs"`
+}
+
+func (recv *etcdFleetStore) watch(ctx context.Context, revision uint64) ([]byte, uint64, error) {
+	for {
+		value, newRevision, err := recv.get(ctx)
+		if err != nil || revision == 0 || newRevision != revision {
+			return value, newRevision, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(etcdWatchPollInterval):
+		}
+	}
+}
+
+// get returns the revision of the store if the key does not exist.
+func (recv *etcdFleetStore) get(ctx context.Context) ([]byte, uint64, error) {
+	requestCtx, cancelFn := context.WithTimeout(ctx, fleetStoreRequestTimeout)
+	defer cancelFn()
+
+	body, _ := json.Marshal(map[string][]byte{"key": []byte(recv.key)})
+	statusCode, rspBody, _, err := recv.client.do(requestCtx, http.MethodPost, "/v3/kv/range", body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("etcd returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	var rangeResponse etcdRangeResponse
+	err = json.Unmarshal(rspBody, &rangeResponse)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not parse the response of etcd: %w", err)
+	}
+	if len(rangeResponse.Kvs) == 0 {
+		return nil, rangeResponse.Header.Revision, nil
+	}
+	return rangeResponse.Kvs[0].Value, rangeResponse.Kvs[0].ModRevision, nil
+}
+
+func (recv *etcdFleetStore) put(ctx context.Context, value []byte) error {
+	body, _ := json.Marshal(map[string][]byte{"key": []byte(recv.key), "value": value})
+	statusCode, rspBody, _, err := recv.client.do(ctx, http.MethodPost, "/v3/kv/put", body)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("etcd returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	return nil
+}
+
+// consulFleetStore uses the KV HTTP API of consul, the key is watched with blocking queries.
+type consulFleetStore struct {
+	client *fleetStoreClient
+	key    string
+}
+
+func (recv *consulFleetStore) watch(ctx context.Context, revision uint64) ([]byte, uint64, error) {
+	requestCtx, cancelFn := context.WithTimeout(ctx, consulWatchWaitTime+fleetStoreRequestTimeout)
+	defer cancelFn()
+
+	path := fmt.Sprintf("/v1/kv/%v?index=%v&wait=%vms", consulKeyPath(recv.key), revision,
+		consulWatchWaitTime.Milliseconds())
+	statusCode, rspBody, header, err := recv.client.do(requestCtx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if statusCode != http.StatusOK && statusCode != http.StatusNotFound {
+		return nil, 0, fmt.Errorf("consul returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	newRevision, err := strconv.ParseUint(header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul returned an invalid X-Consul-Index header: %w", err)
+	}
+	if newRevision < revision {
+		// the index went backwards, e.g. the raft snapshot was restored, the next query must start from 0
+		newRevision = 0
+	}
+	if statusCode == http.StatusNotFound {
+		return nil, newRevision, nil
+	}
+
+	var entries []struct {
+		Value []byte
+	}
+	err = json.Unmarshal(rspBody, &entries)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not parse the response of consul: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, newRevision, nil
+	}
+	return entries[0].Value, newRevision, nil
+}
+
+func (recv *consulFleetStore) put(ctx context.Context, value []byte) error {
+	statusCode, rspBody, _, err := recv.client.do(ctx, http.MethodPut, "/v1/kv/"+consulKeyPath(recv.key), value)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("consul returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	return nil
+}
+
+// consulKeyPath escapes the key without escaping the slashes that separate the segments of the key.
+func consulKeyPath(key string) string {
+	return (&url.URL{Path: key}).EscapedPath()
+}
+
+// fleetCoordinator propagates the settings of the zdm_admin keyspace to every proxy instance of the fleet. The
+// value of the key of the fleet store is a JSON object with the settings, e.g. {"read_routing":"target"}, that
+// can also be written directly to the store (e.g. with etcdctl or consul kv put).
+type fleetCoordinator struct {
+	store         fleetStore
+	adminKeyspace *adminKeyspace
+	description   string // e.g. etcd key zdm-proxy/settings
+}
+
+func newFleetCoordinator(conf *common.FleetStoreConfig, adminKeyspace *adminKeyspace) *fleetCoordinator {
+	store := newFleetStore(conf)
+	if store == nil || adminKeyspace == nil {
+		return nil
+	}
+	coordinator := &fleetCoordinator{
+		store:         store,
+		adminKeyspace: adminKeyspace,
+		description:   fmt.Sprintf("%v key %v", conf.Type, conf.Key),
+	}
+	adminKeyspace.fleet = coordinator
+	return coordinator
+}
+
+// publish writes every setting of the zdm_admin keyspace with the changes of an UPDATE statement to the store.
+func (recv *fleetCoordinator) publish(changes map[string]common.ClusterType) error {
+	settings := map[string]string{
+		readRoutingSettingName: readRoutingSettingValue(recv.adminKeyspace.getReadRouting()),
+	}
+	for name, value := range changes {
+		settings[name] = readRoutingSettingValue(value)
+	}
+	value, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), fleetStoreRequestTimeout)
+	defer cancelFn()
+	return recv.store.put(ctx, value)
+}
+
+// load applies the settings that are in the store when the proxy starts, it returns the revision to watch.
+func (recv *fleetCoordinator) load(ctx context.Context) (uint64, []byte, error) {
+	requestCtx, cancelFn := context.WithTimeout(ctx, fleetStoreRequestTimeout)
+	defer cancelFn()
+	value, revision, err := recv.store.watch(requestCtx, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+	recv.apply(value)
+	return revision, value, nil
+}
+
+// run applies the changes of the store until ctx is done.
+func (recv *fleetCoordinator) run(ctx context.Context, revision uint64, lastValue []byte) {
+	for {
+		value, newRevision, err := recv.store.watch(ctx, revision)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warnf("Could not watch the %v for changes, retrying in %v: %v.",
+				recv.description, fleetStoreRetryInterval, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(fleetStoreRetryInterval):
+			}
+			continue
+		}
+		revision = newRevision
+		if !bytes.Equal(value, lastValue) {
+			lastValue = value
+			recv.apply(value)
+		}
+	}
+}
+
+func (recv *fleetCoordinator) apply(value []byte) {
+	if value == nil {
+		return
+	}
+	var settings map[string]string
+	err := json.Unmarshal(value, &settings)
+	if err != nil {
+		log.Warnf("Ignoring the value of the %v because it is not a valid JSON object of settings: %v.",
+			recv.description, err)
+		return
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name != readRoutingSettingName {
+			log.Warnf("Ignoring unknown setting %v of the %v.", name, recv.description)
+			continue
+		}
+		readRouting, err := parseFleetReadRoutingSetting(settings[name])
+		if err != nil {
+			log.Warnf("Ignoring setting %v of the %v: %v.", name, recv.description, err)
+			continue
+		}
+		previousReadRouting := recv.adminKeyspace.setReadRouting(readRouting)
+		if previousReadRouting != readRouting {
+			log.Warnf("Setting %v of the %v keyspace changed from %v to %v through the %v.",
+				readRoutingSettingName, adminKeyspaceName, readRoutingSettingValue(previousReadRouting),
+				readRoutingSettingValue(readRouting), recv.description)
+		}
+	}
+}
+
+// parseFleetReadRoutingSetting parses the values that readRoutingSettingValue returns.
+func parseFleetReadRoutingSetting(value string) (common.ClusterType, error) {
+	if strings.EqualFold(value, "null") || value == "" {
+		return common.ClusterTypeNone, nil
+	}
+	return parseReadRoutingSetting(ddlToken{text: value, literal: true})
+}
+
+// fleetReports holds a value per proxy instance under a prefix of the fleet store, e.g. the request rates that
+// are shared by the rate limiters.
+type fleetReports interface {
+	put(ctx context.Context, instance string, value []byte) error
+	// list returns the value of every instance
+	list(ctx context.Context) (map[string][]byte, error)
+	delete(ctx context.Context, instance string) error
+}
+
+// newFleetReports returns nil if the fleet store is disabled.
+func newFleetReports(conf *common.FleetStoreConfig, prefix string) fleetReports {
+	client := newFleetStoreClient(conf)
+	switch conf.Type {
+	case fleetStoreTypeEtcd:
+		return &etcdFleetReports{client: client, prefix: prefix + "/"}
+	case fleetStoreTypeConsul:
+		return &consulFleetReports{client: client, prefix: prefix + "/"}
+	default:
+		return nil
+	}
+}
+
+type etcdFleetReports struct {
+	client *fleetStoreClient
+	prefix string
+}
+
+func (recv *etcdFleetReports) put(ctx context.Context, instance string, value []byte) error {
+	return recv.client.postEtcd(ctx, "/v3/kv/put", map[string][]byte{"key": []byte(recv.prefix + instance), "value": value}, nil)
+}
+
+func (recv *etcdFleetReports) list(ctx context.Context) (map[string][]byte, error) {
+	// the range end of a prefix is the prefix with its last byte incremented
+	rangeEnd := []byte(recv.prefix)
+	rangeEnd[len(rangeEnd)-1]++
+	var rangeResponse struct {
+		Kvs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	err := recv.client.postEtcd(ctx, "/v3/kv/range",
+		map[string][]byte{"key": []byte(recv.prefix), "range_end": rangeEnd}, &rangeResponse)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(rangeResponse.Kvs))
+	for _, kv := range rangeResponse.Kvs {
+		values[strings.TrimPrefix(string(kv.Key), recv.prefix)] = kv.Value
+	}
+	return values, nil
+}
+
+func (recv *etcdFleetReports) delete(ctx context.Context, instance string) error {
+	return recv.client.postEtcd(ctx, "/v3/kv/deleterange", map[string][]byte{"key": []byte(recv.prefix + instance)}, nil)
+}
+
+type consulFleetReports struct {
+	client *fleetStoreClient
+	prefix string
+}
+
+func (recv *consulFleetReports) put(ctx context.Context, instance string, value []byte) error {
+	return recv.do(ctx, http.MethodPut, "/v1/kv/"+consulKeyPath(recv.prefix+instance), value, nil)
+}
+
+func (recv *consulFleetReports) list(ctx context.Context) (map[string][]byte, error) {
+	var entries []struct {
+		Key   string
+		Value []byte
+	}
+	err := recv.do(ctx, http.MethodGet, "/v1/kv/"+consulKeyPath(recv.prefix)+"?recurse=true", nil, &entries)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		values[strings.TrimPrefix(entry.Key, recv.prefix)] = entry.Value
+	}
+	return values, nil
+}
+
+func (recv *consulFleetReports) delete(ctx context.Context, instance string) error {
+	return recv.do(ctx, http.MethodDelete, "/v1/kv/"+consulKeyPath(recv.prefix+instance), nil, nil)
+}
+
+// do ignores the 404 responses, consul returns them if there are no keys with the prefix.
+func (recv *consulFleetReports) do(
+	ctx context.Context, method string, path string, body []byte, response interface{}) error {
+	statusCode, rspBody, _, err := recv.client.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if statusCode == http.StatusNotFound {
+		return nil
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("consul returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	if response == nil {
+		return nil
+	}
+	err = json.Unmarshal(rspBody, response)
+	if err != nil {
+		return fmt.Errorf("could not parse the response of consul: %w", err)
+	}
+	return nil
+}
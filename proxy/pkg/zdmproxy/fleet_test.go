@@ -0,0 +1,199 @@
+package zdmproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKvStore emulates the subset of the HTTP APIs of etcd and consul that the fleet stores use.
+type fakeKvStore struct {
+	lock     *sync.Mutex
+	value    []byte
+	revision uint64
+}
+
+func newFakeKvStore() *fakeKvStore {
+	return &fakeKvStore{lock: &sync.Mutex{}, revision: 1}
+}
+
+func (recv *fakeKvStore) get() ([]byte, uint64) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.value, recv.revision
+}
+
+func (recv *fakeKvStore) put(value []byte) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.value = value
+	recv.revision++
+}
+
+func (recv *fakeKvStore) etcdHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		var body map[string][]byte
+		require.Nil(t, json.NewDecoder(req.Body).Decode(&body))
+		require.Equal(t, "zdm/settings", string(body["key"]))
+		switch req.URL.Path {
+		case "/v3/kv/range":
+			value, revision := recv.get()
+			kvs := "[]"
+			if value != nil {
+				valueJson, _ := json.Marshal(value)
+				kvs = fmt.Sprintf(`[{"value":%s,"mod_revision":"%d"}]`, valueJson, revision)
+			}
+			fmt.Fprintf(rsp, `{"header":{"revision":"%d"},"kvs":%s}`, revision, kvs)
+		case "/v3/kv/put":
+			recv.put(body["value"])
+			fmt.Fprint(rsp, `{"header":{}}`)
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
+
+func (recv *fakeKvStore) consulHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/kv/zdm/settings", req.URL.Path)
+		switch req.Method {
+		case http.MethodGet:
+			index, err := strconv.ParseUint(req.URL.Query().Get("index"), 10, 64)
+			require.Nil(t, err)
+			value, revision := recv.get()
+			for deadline := time.Now().Add(time.Second); revision <= index && time.Now().Before(deadline); {
+				time.Sleep(5 * time.Millisecond)
+				value, revision = recv.get()
+			}
+			rsp.Header().Set("X-Consul-Index", strconv.FormatUint(revision, 10))
+			if value == nil {
+				rsp.WriteHeader(http.StatusNotFound)
+				return
+			}
+			valueJson, _ := json.Marshal(value)
+			fmt.Fprintf(rsp, `[{"Key":"zdm/settings","Value":%s}]`, valueJson)
+		case http.MethodPut:
+			value, err := io.ReadAll(req.Body)
+			require.Nil(t, err)
+			recv.put(value)
+			fmt.Fprint(rsp, "true")
+		}
+	})
+}
+
+func TestFleetStore(t *testing.T) {
+	etcdWatchPollInterval = 10 * time.Millisecond
+	defer func() { etcdWatchPollInterval = 1 * time.Second }()
+
+	for _, storeType := range []string{fleetStoreTypeEtcd, fleetStoreTypeConsul} {
+		t.Run(storeType, func(t *testing.T) {
+			kvStore := newFakeKvStore()
+			handler := kvStore.etcdHandler(t)
+			if storeType == fleetStoreTypeConsul {
+				handler = kvStore.consulHandler(t)
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			// the first endpoint is not reachable
+			store := newFleetStore(&common.FleetStoreConfig{
+				Type: storeType, Endpoints: []string{"http://127.0.0.1:1", server.URL}, Key: "zdm/settings"})
+			ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelFn()
+
+			value, revision, err := store.watch(ctx, 0)
+			require.Nil(t, err)
+			require.Nil(t, value)
+
+			require.Nil(t, store.put(ctx, []byte("a")))
+			value, revision, err = store.watch(ctx, revision)
+			require.Nil(t, err)
+			require.Equal(t, "a", string(value))
+
+			watchResult := make(chan []byte, 1)
+			go func() {
+				value, _, err := store.watch(ctx, revision)
+				require.Nil(t, err)
+				watchResult <- value
+			}()
+			time.Sleep(50 * time.Millisecond)
+			kvStore.put([]byte("b"))
+			require.Equal(t, "b", string(<-watchResult))
+		})
+	}
+}
+
+func TestFleetCoordinator(t *testing.T) {
+	etcdWatchPollInterval = 10 * time.Millisecond
+	defer func() { etcdWatchPollInterval = 1 * time.Second }()
+
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	kvStore := newFakeKvStore()
+	kvStore.put([]byte(`{"read_routing":"target"}`))
+	server := httptest.NewServer(kvStore.etcdHandler(t))
+	defer server.Close()
+	fleetStoreConfig := &common.FleetStoreConfig{Type: fleetStoreTypeEtcd, Endpoints: []string{server.URL}, Key: "zdm/settings"}
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancelFn()
+
+	adminKeyspaces := make([]*adminKeyspace, 2)
+	for i := range adminKeyspaces {
+		adminKeyspaces[i] = newAdminKeyspace([]string{"ops"}, common.ClusterTypeOrigin)
+		coordinator := newFleetCoordinator(fleetStoreConfig, adminKeyspaces[i])
+		revision, value, err := coordinator.load(ctx)
+		require.Nil(t, err)
+		require.Equal(t, common.ClusterTypeTarget, adminKeyspaces[i].getReadRouting())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			coordinator.run(ctx, revision, value)
+		}()
+	}
+
+	execute := func(adminKeyspace *adminKeyspace, query string) message.Message {
+		response, err := adminKeyspace.execute(inspectCqlQuery(query, "", generator), "ops", "127.0.0.1:9042",
+			GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4)
+		require.Nil(t, err)
+		return response
+	}
+
+	require.Equal(t, &message.VoidResult{}, execute(adminKeyspaces[0], "UPDATE zdm_admin.settings SET read_routing = null"))
+	require.Equal(t, common.ClusterTypeNone, adminKeyspaces[0].getReadRouting())
+	value, _ := kvStore.get()
+	require.JSONEq(t, `{"read_routing":"null"}`, string(value))
+	require.Eventually(t, func() bool {
+		return adminKeyspaces[1].getReadRouting() == common.ClusterTypeNone
+	}, 5*time.Second, 10*time.Millisecond)
+
+	kvStore.put([]byte(`{"read_routing":"ORIGIN","unknown":"x"}`))
+	for _, adminKeyspace := range adminKeyspaces {
+		require.Eventually(t, func() bool {
+			return adminKeyspace.getReadRouting() == common.ClusterTypeOrigin
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// invalid values are ignored
+	kvStore.put([]byte(`{"read_routing":"both"}`))
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, common.ClusterTypeOrigin, adminKeyspaces[1].getReadRouting())
+
+	// the setting is not changed if it can not be written to the store
+	server.Close()
+	require.IsType(t, &message.ServerError{}, execute(adminKeyspaces[0], "UPDATE zdm_admin.settings SET read_routing = 'target'"))
+	require.Equal(t, common.ClusterTypeOrigin, adminKeyspaces[0].getReadRouting())
+}
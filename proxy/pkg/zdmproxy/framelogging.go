@@ -0,0 +1,172 @@
+package zdmproxy
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// frameLoggingDefaultDuration is how long the frames of a client are logged if the admin API request doesn't have
+// a duration
+const frameLoggingDefaultDuration = 15 * time.Minute
+
+// FrameLogger logs the header and a hex dump of the body of every frame that is exchanged with the client
+// connections that were selected with the admin API, it replaces ad-hoc print debugging when diagnosing codec
+// issues. The frames are redacted like the frames of a redacted traffic capture (see redactFrame), i.e. without the
+// bound values, the literals of the statements and the rows of the results, and the body of the frames that can't be
+// redacted is not logged.
+type FrameLogger struct {
+	lock    *sync.Mutex
+	active  int32                // number of clients, it is read without holding the lock
+	clients map[string]time.Time // expiration keyed by client IP or address (IP:port)
+	now     func() time.Time
+}
+
+// FrameLoggingStatus is the state of the frame logging that is reported by the admin API.
+type FrameLoggingStatus struct {
+	Clients []*FrameLoggingClient
+}
+
+type FrameLoggingClient struct {
+	Client    string
+	ExpiresAt time.Time
+}
+
+func newFrameLogger() *FrameLogger {
+	return &FrameLogger{
+		lock:    &sync.Mutex{},
+		clients: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Enable logs the frames of the provided clients (IPs or IP:port addresses) for the provided duration,
+// frameLoggingDefaultDuration if 0.
+func (recv *FrameLogger) Enable(clients []string, duration time.Duration) (*FrameLoggingStatus, error) {
+	if duration == 0 {
+		duration = frameLoggingDefaultDuration
+	} else if duration < 0 {
+		return nil, fmt.Errorf("frame logging duration must be positive but was %v", duration)
+	}
+	normalizedClients, err := normalizeClientAddresses(clients)
+	if err != nil {
+		return nil, err
+	}
+	if len(normalizedClients) == 0 {
+		return nil, fmt.Errorf("at least one client is required")
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	expiration := recv.now().Add(duration)
+	for _, client := range normalizedClients {
+		recv.clients[client] = expiration
+	}
+	return recv.getStatusLocked(), nil
+}
+
+// Disable stops logging the frames of the provided clients, every client if empty.
+func (recv *FrameLogger) Disable(clients []string) (*FrameLoggingStatus, error) {
+	normalizedClients, err := normalizeClientAddresses(clients)
+	if err != nil {
+		return nil, err
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if len(normalizedClients) == 0 {
+		recv.clients = make(map[string]time.Time)
+	}
+	for _, client := range normalizedClients {
+		delete(recv.clients, client)
+	}
+	return recv.getStatusLocked(), nil
+}
+
+func (recv *FrameLogger) GetStatus() *FrameLoggingStatus {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.getStatusLocked()
+}
+
+// getStatusLocked also removes the clients that expired.
+func (recv *FrameLogger) getStatusLocked() *FrameLoggingStatus {
+	now := recv.now()
+	status := &FrameLoggingStatus{Clients: make([]*FrameLoggingClient, 0, len(recv.clients))}
+	for client, expiration := range recv.clients {
+		if !now.Before(expiration) {
+			delete(recv.clients, client)
+			continue
+		}
+		status.Clients = append(status.Clients, &FrameLoggingClient{Client: client, ExpiresAt: expiration})
+	}
+	sort.Slice(status.Clients, func(i, j int) bool {
+		return status.Clients[i].Client < status.Clients[j].Client
+	})
+	atomic.StoreInt32(&recv.active, int32(len(recv.clients)))
+	return status
+}
+
+// isEnabled returns true if the frames of the client are logged.
+func (recv *FrameLogger) isEnabled(clientAddr net.Addr) bool {
+	if recv == nil || atomic.LoadInt32(&recv.active) == 0 {
+		return false
+	}
+
+	client := tcpAddrOf(clientAddr)
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	now := recv.now()
+	for _, key := range []string{client.String(), client.IP.String()} {
+		if expiration, ok := recv.clients[key]; ok && now.Before(expiration) {
+			return true
+		}
+	}
+	return false
+}
+
+// log logs the frame if the frames of the client are logged, fromClient is true for the requests and false for the
+// responses and events.
+func (recv *FrameLogger) log(clientAddr net.Addr, fromClient bool, f *frame.RawFrame) {
+	if !recv.isEnabled(clientAddr) {
+		return
+	}
+
+	direction := "Received frame from"
+	if !fromClient {
+		direction = "Sending frame to"
+	}
+	redactedFrame, err := redactFrame(f, true)
+	if err != nil {
+		log.Infof("[FRAME-LOGGING] %v client %v: %v (%v bytes), the body is not logged because it can't be redacted: %v.",
+			direction, clientAddr, f.Header, len(f.Body), err)
+		return
+	}
+	log.Infof("[FRAME-LOGGING] %v client %v: %v (%v bytes, redacted body has %v bytes)\n%v",
+		direction, clientAddr, f.Header, len(f.Body), len(redactedFrame.Body),
+		strings.TrimSuffix(hex.Dump(redactedFrame.Body), "\n"))
+}
+
+// normalizeClientAddresses returns the canonical form of the client IPs and IP:port addresses without the empty ones.
+func normalizeClientAddresses(clients []string) ([]string, error) {
+	var normalizedClients []string
+	for _, client := range clients {
+		client = strings.TrimSpace(client)
+		if client == "" {
+			continue
+		}
+		normalizedClient, err := normalizeClientAddress(client)
+		if err != nil {
+			return nil, err
+		}
+		normalizedClients = append(normalizedClients, normalizedClient)
+	}
+	return normalizedClients, nil
+}
@@ -0,0 +1,93 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFrameLogger(t *testing.T) {
+	var disabledLogger *FrameLogger
+	disabledLogger.log(nil, true, nil)
+
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	now := time.Now()
+	frameLogger := newFrameLogger()
+	frameLogger.now = func() time.Time { return now }
+
+	_, err := frameLogger.Enable(nil, time.Minute)
+	require.Equal(t, "at least one client is required", err.Error())
+	_, err = frameLogger.Enable([]string{"proxy1"}, time.Minute)
+	require.Equal(t, "invalid client proxy1, it must be an IP or an IP:port address", err.Error())
+	_, err = frameLogger.Enable([]string{"127.0.0.1"}, -time.Minute)
+	require.Equal(t, "frame logging duration must be positive but was -1m0s", err.Error())
+
+	status, err := frameLogger.Enable([]string{"127.0.0.1", "10.0.0.1:5000"}, 0)
+	require.Nil(t, err)
+	require.Equal(t, &FrameLoggingStatus{Clients: []*FrameLoggingClient{
+		{Client: "10.0.0.1:5000", ExpiresAt: now.Add(frameLoggingDefaultDuration)},
+		{Client: "127.0.0.1", ExpiresAt: now.Add(frameLoggingDefaultDuration)},
+	}}, status)
+
+	client := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 6000}
+	otherClient := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5001}
+	query := &message.Query{Query: "SELECT * FROM ks.tb WHERE k = 'secret'", Options: &message.QueryOptions{
+		PositionalValues: []*primitive.Value{primitive.NewValue([]byte("value"))}}}
+	frameLogger.log(otherClient, true, newCaptureTestFrame(t, 1, query))
+	require.Empty(t, logs.String())
+
+	frameLogger.log(client, true, newCaptureTestFrame(t, 2, query))
+	require.Contains(t, logs.String(), "Received frame from client 127.0.0.1:6000: {response: false, version: ProtocolVersion OSS 4, flags: 00000000, stream id: 2, opcode: OpCode QUERY [0x07], body length: 56} (56 bytes, redacted body has 44 bytes)")
+	require.Contains(t, logs.String(), "|= ?.........|")
+	require.NotContains(t, logs.String(), "secret")
+	require.NotContains(t, logs.String(), "value")
+	logs.Reset()
+
+	// the numeric literals are masked as well
+	frameLogger.log(client, true, newCaptureTestFrame(t, 2, &message.Query{Query: "SELECT * FROM ks.tb WHERE k = 987654"}))
+	require.Contains(t, logs.String(), "(43 bytes, redacted body has 38 bytes)")
+	require.Contains(t, logs.String(), "|= ?...|")
+	require.NotContains(t, logs.String(), "987654")
+	logs.Reset()
+
+	frameLogger.log(client, true, newCaptureTestFrame(t, 3, &message.AuthResponse{Token: []byte("\x00user\x00password")}))
+	require.Contains(t, logs.String(), "redacted body has 4 bytes")
+	require.NotContains(t, logs.String(), "password")
+	logs.Reset()
+
+	compressedFrame := newCaptureTestFrame(t, 3, &message.RowsResult{
+		Metadata: &message.RowsMetadata{ColumnCount: 1}, Data: message.RowSet{{[]byte("secret")}}})
+	compressedFrame.Header.Flags = compressedFrame.Header.Flags.Add(primitive.HeaderFlagCompressed)
+	frameLogger.log(client, false, compressedFrame)
+	require.Contains(t, logs.String(), "Sending frame to client 127.0.0.1:6000")
+	require.Contains(t, logs.String(), "the body is not logged because it can't be redacted")
+	require.NotContains(t, logs.String(), "secret")
+	logs.Reset()
+
+	status, err = frameLogger.Disable([]string{"127.0.0.1"})
+	require.Nil(t, err)
+	require.Len(t, status.Clients, 1)
+	frameLogger.log(client, true, newCaptureTestFrame(t, 4, query))
+	require.Empty(t, logs.String())
+
+	// the clients expire
+	now = now.Add(frameLoggingDefaultDuration)
+	frameLogger.log(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}, true, newCaptureTestFrame(t, 5, query))
+	require.Empty(t, logs.String())
+	require.Empty(t, frameLogger.GetStatus().Clients)
+
+	_, err = frameLogger.Enable([]string{"127.0.0.1"}, time.Minute)
+	require.Nil(t, err)
+	status, err = frameLogger.Disable(nil)
+	require.Nil(t, err)
+	require.Empty(t, status.Clients)
+}
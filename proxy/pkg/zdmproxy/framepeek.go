@@ -0,0 +1,104 @@
+package zdmproxy
+
+import (
+	"encoding/binary"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+)
+
+// The functions in this file read the few fields that the proxy needs to route QUERY, PREPARE and EXECUTE requests
+// directly from the raw body so that the frames that are forwarded untouched are never fully decoded.
+// They return false when the prefix can not be read without the codec (e.g. compressed bodies), the callers
+// must then fall back to frameDecodeContext.GetOrDecodeFrame.
+
+// peekQueryString returns the query string of a QUERY or PREPARE request.
+func peekQueryString(f *frame.RawFrame) (string, bool) {
+	if f.Header.OpCode != primitive.OpCodeQuery && f.Header.OpCode != primitive.OpCodePrepare {
+		return "", false
+	}
+	offset, ok := peekMessageOffset(f)
+	if !ok || offset+4 > len(f.Body) {
+		return "", false
+	}
+	length := int(int32(binary.BigEndian.Uint32(f.Body[offset:])))
+	offset += 4
+	if length < 0 || offset+length > len(f.Body) {
+		return "", false
+	}
+	return string(f.Body[offset : offset+length]), true
+}
+
+// peekQueryId returns the prepared id of an EXECUTE request, the returned slice shares the raw body of the frame.
+func peekQueryId(f *frame.RawFrame) ([]byte, bool) {
+	if f.Header.OpCode != primitive.OpCodeExecute {
+		return nil, false
+	}
+	offset, ok := peekMessageOffset(f)
+	if !ok || offset+2 > len(f.Body) {
+		return nil, false
+	}
+	length := int(binary.BigEndian.Uint16(f.Body[offset:]))
+	offset += 2
+	if offset+length > len(f.Body) {
+		return nil, false
+	}
+	return f.Body[offset : offset+length : offset+length], true
+}
+
+// peekMessageOffset returns the offset of the message in the raw body of a frame, i.e. after the tracing id and the
+// warnings of a response and after the custom payload.
+func peekMessageOffset(f *frame.RawFrame) (int, bool) {
+	if f.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return 0, false
+	}
+	body := f.Body
+	offset := 0
+	if f.Header.IsResponse && f.Header.Flags.Contains(primitive.HeaderFlagTracing) {
+		// [uuid]
+		offset += 16
+	}
+	if f.Header.IsResponse && f.Header.Flags.Contains(primitive.HeaderFlagWarning) {
+		// [string list]: a [short] n followed by n [string]
+		if offset+2 > len(body) {
+			return 0, false
+		}
+		warnings := int(binary.BigEndian.Uint16(body[offset:]))
+		offset += 2
+		for i := 0; i < warnings; i++ {
+			if offset+2 > len(body) {
+				return 0, false
+			}
+			offset += 2 + int(binary.BigEndian.Uint16(body[offset:]))
+		}
+	}
+	if offset > len(body) {
+		return 0, false
+	}
+	if !f.Header.Flags.Contains(primitive.HeaderFlagCustomPayload) {
+		return offset, true
+	}
+	// [bytes map]: a [short] n followed by n [string] keys and [bytes] values
+	if offset+2 > len(body) {
+		return 0, false
+	}
+	entries := int(binary.BigEndian.Uint16(body[offset:]))
+	offset += 2
+	for i := 0; i < entries; i++ {
+		if offset+2 > len(body) {
+			return 0, false
+		}
+		offset += 2 + int(binary.BigEndian.Uint16(body[offset:]))
+		if offset+4 > len(body) {
+			return 0, false
+		}
+		valueLength := int(int32(binary.BigEndian.Uint32(body[offset:])))
+		offset += 4
+		if valueLength > 0 {
+			offset += valueLength
+		}
+		if offset > len(body) {
+			return 0, false
+		}
+	}
+	return offset, true
+}
@@ -0,0 +1,144 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPeekQueryString(t *testing.T) {
+	query := "SELECT * FROM ks1.tb1 WHERE id = ?"
+	queryFrame := mockQueryFrame(t, query)
+	actual, ok := peekQueryString(queryFrame)
+	require.True(t, ok)
+	require.Equal(t, query, actual)
+
+	actual, ok = peekQueryString(mockPrepareFrame(t, query))
+	require.True(t, ok)
+	require.Equal(t, query, actual)
+
+	f := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{Query: query})
+	f.SetCustomPayload(map[string][]byte{"key1": []byte("value1"), "key2": nil})
+	customPayloadFrame, err := defaultCodec.ConvertToRawFrame(f)
+	require.Nil(t, err)
+	actual, ok = peekQueryString(customPayloadFrame)
+	require.True(t, ok)
+	require.Equal(t, query, actual)
+
+	compressedFrame := queryFrame.Clone()
+	compressedFrame.Header.Flags = compressedFrame.Header.Flags.Add(primitive.HeaderFlagCompressed)
+	_, ok = peekQueryString(compressedFrame)
+	require.False(t, ok)
+
+	truncatedFrame := queryFrame.Clone()
+	truncatedFrame.Body = truncatedFrame.Body[:10]
+	_, ok = peekQueryString(truncatedFrame)
+	require.False(t, ok)
+
+	_, ok = peekQueryString(mockExecuteFrame(t, "id"))
+	require.False(t, ok)
+}
+
+func TestPeekQueryId(t *testing.T) {
+	actual, ok := peekQueryId(mockExecuteFrame(t, "prepared-id"))
+	require.True(t, ok)
+	require.Equal(t, []byte("prepared-id"), actual)
+
+	_, ok = peekQueryId(mockQueryFrame(t, "SELECT * FROM ks1.tb1"))
+	require.False(t, ok)
+}
+
+func TestFrameDecodeContext_FastPath(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	queryContext := NewFrameDecodeContext(mockQueryFrame(t, "SELECT * FROM tb1"))
+	stmtQueryData, err := queryContext.GetOrInspectStatement("ks1", generator)
+	require.Nil(t, err)
+	require.Equal(t, "ks1", stmtQueryData.queryData.getApplicableKeyspace())
+	require.Equal(t, "tb1", stmtQueryData.queryData.getTableName())
+	require.Nil(t, queryContext.decodedFrame)
+
+	executeContext := NewFrameDecodeContext(mockExecuteFrame(t, "prepared-id"))
+	queryId, err := executeContext.GetOrPeekQueryId()
+	require.Nil(t, err)
+	require.Equal(t, []byte("prepared-id"), queryId)
+	require.Nil(t, executeContext.decodedFrame)
+
+	// the keyspace can be set in the request of this protocol version so the frame is decoded
+	prepareContext := NewFrameDecodeContext(mockPrepareFrameWithKeyspace(t, "SELECT * FROM tb1", "ks2"))
+	query, keyspace, err := prepareContext.GetOrPeekPrepare()
+	require.Nil(t, err)
+	require.Equal(t, "SELECT * FROM tb1", query)
+	require.Equal(t, "ks2", keyspace)
+	require.NotNil(t, prepareContext.decodedFrame)
+}
+
+func newBenchmarkFrame(b *testing.B, msg message.Message) *frame.RawFrame {
+	rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, msg))
+	require.Nil(b, err)
+	return rawFrame
+}
+
+func BenchmarkQueryString(b *testing.B) {
+	f := newBenchmarkFrame(b, &message.Query{
+		Query: "INSERT INTO ks1.tb1 (id, name, value) VALUES (?, ?, ?)",
+		Options: &message.QueryOptions{
+			Consistency: primitive.ConsistencyLevelLocalQuorum,
+			PositionalValues: []*primitive.Value{
+				primitive.NewValue([]byte{0, 0, 0, 1}),
+				primitive.NewValue([]byte("name")),
+				primitive.NewValue(make([]byte, 512)),
+			},
+		},
+	})
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			decodedFrame, err := NewFrameDecodeContext(f).GetOrDecodeFrame()
+			if err != nil || decodedFrame.Body.Message.(*message.Query).Query == "" {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("peek", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if query, ok := peekQueryString(f); !ok || query == "" {
+				b.Fatal("could not peek query string")
+			}
+		}
+	})
+}
+
+func BenchmarkExecuteQueryId(b *testing.B) {
+	f := newBenchmarkFrame(b, &message.Execute{
+		QueryId: []byte("0123456789abcdef"),
+		Options: &message.QueryOptions{
+			Consistency: primitive.ConsistencyLevelLocalQuorum,
+			PositionalValues: []*primitive.Value{
+				primitive.NewValue([]byte{0, 0, 0, 1}),
+				primitive.NewValue([]byte("name")),
+				primitive.NewValue(make([]byte, 512)),
+			},
+		},
+	})
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			decodedFrame, err := NewFrameDecodeContext(f).GetOrDecodeFrame()
+			if err != nil || len(decodedFrame.Body.Message.(*message.Execute).QueryId) == 0 {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("peek", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if queryId, err := NewFrameDecodeContext(f).GetOrPeekQueryId(); err != nil || len(queryId) == 0 {
+				b.Fatal(err)
+			}
+		}
+	})
+}
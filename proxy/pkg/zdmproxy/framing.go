@@ -0,0 +1,459 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	lz4compression "github.com/datastax/go-cassandra-native-protocol/compression/lz4"
+	snappycompression "github.com/datastax/go-cassandra-native-protocol/compression/snappy"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/go-cassandra-native-protocol/segment"
+	"github.com/pierrec/lz4/v4"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+var defaultSegmentCodec = segment.NewCodec()
+var lz4SegmentCodec = segment.NewCodecWithCompression(&segmentLz4Compressor{})
+
+// frameCompressors are the compressors of the frame bodies of the legacy layout by the algorithm that is requested in
+// the STARTUP request.
+var frameCompressors = map[primitive.Compression]frame.BodyCompressor{
+	primitive.CompressionLz4:    &frameLz4Compressor{},
+	primitive.CompressionSnappy: snappycompression.Compressor{},
+}
+
+// frameCompression holds the compressor of the frame bodies in an atomic.Value, the compressor is nil if the frames
+// are not compressed.
+type frameCompression struct {
+	compressor frame.BodyCompressor
+}
+
+// connFraming tracks the framing layout of a connection.
+//
+// Up to protocol v4 (and during the handshake of protocol v5) the frames are written one after another on the
+// connection (legacy layout). Once a v5 READY or AUTHENTICATE response is sent or received, the frames are wrapped in
+// segments (modern layout) whose header and payload are protected by checksums and whose payload is compressed if
+// the client requested LZ4 compression in the STARTUP request. A frame that doesn't fit in a single segment is split
+// across several segments that are not self-contained.
+//
+// With the legacy layout the body of each frame is compressed (and the COMPRESSED flag set) if the client requested
+// LZ4 or SNAPPY compression in the STARTUP request, the frames are only compressed once the handshake was
+// accepted (i.e. after the READY or AUTHENTICATE response) but the compressed frames that are read are always
+// decompressed.
+//
+// The frames that are read and written by the proxy are always the decoded (uncompressed) frames so the rest of
+// the pipeline doesn't need to know which layout (or compression) is used by a connection, the proxy can inspect
+// and modify them and each side of the proxy compresses the frames with the algorithm that was negotiated on it.
+//
+// The reading side and the writing side can be used by different goroutines but each side must only be used by
+// one goroutine at a time.
+type connFraming struct {
+	modern       int32
+	segmentCodec *atomic.Value
+
+	requestedFrameCompression *atomic.Value // requested in the STARTUP request
+	frameCompression          *atomic.Value // used by the writing side once the handshake was accepted
+
+	// reading side
+	pooledFrameBodies   bool // the bodies of the frames that are read are taken from the frame body pools
+	decodedFrames       []*frame.RawFrame
+	multiSegmentPayload []byte
+	multiSegmentLength  int
+
+	// writing side
+	segmentPayload *bytes.Buffer
+}
+
+func newConnFraming() *connFraming {
+	segmentCodec := &atomic.Value{}
+	segmentCodec.Store(defaultSegmentCodec)
+	requestedFrameCompression := &atomic.Value{}
+	requestedFrameCompression.Store(frameCompression{})
+	activeFrameCompression := &atomic.Value{}
+	activeFrameCompression.Store(frameCompression{})
+	return &connFraming{
+		segmentCodec:              segmentCodec,
+		requestedFrameCompression: requestedFrameCompression,
+		frameCompression:          activeFrameCompression,
+		segmentPayload:            &bytes.Buffer{},
+	}
+}
+
+func (recv *connFraming) isModern() bool {
+	return recv != nil && atomic.LoadInt32(&recv.modern) == 1
+}
+
+// observe switches the layout of the connection if the provided frame is the last frame of the legacy layout and
+// records the compression that is requested in the STARTUP request, the frame compression of the legacy layout is
+// enabled by the response that accepts the STARTUP request.
+func (recv *connFraming) observe(f *frame.RawFrame) {
+	if recv == nil {
+		return
+	}
+	switch f.Header.OpCode {
+	case primitive.OpCodeStartup:
+		options, ok := readStartupOptions(f)
+		if !ok {
+			return
+		}
+		compression := primitive.Compression(strings.ToUpper(options[message.StartupOptionCompression]))
+		if compression == primitive.CompressionLz4 {
+			recv.segmentCodec.Store(lz4SegmentCodec)
+		} else {
+			recv.segmentCodec.Store(defaultSegmentCodec)
+		}
+		if f.Header.Version.SupportsModernFramingLayout() {
+			// the segments are compressed instead of the frames
+			recv.requestedFrameCompression.Store(frameCompression{})
+		} else {
+			recv.requestedFrameCompression.Store(frameCompression{compressor: frameCompressors[compression]})
+		}
+		recv.frameCompression.Store(frameCompression{})
+	case primitive.OpCodeReady, primitive.OpCodeAuthenticate:
+		if f.Header.Version.SupportsModernFramingLayout() {
+			atomic.StoreInt32(&recv.modern, 1)
+		} else {
+			recv.frameCompression.Store(recv.requestedFrameCompression.Load())
+		}
+	}
+}
+
+func (recv *connFraming) getSegmentCodec() segment.Codec {
+	return recv.segmentCodec.Load().(segment.Codec)
+}
+
+func (recv *connFraming) getRequestedFrameCompressor() frame.BodyCompressor {
+	return recv.requestedFrameCompression.Load().(frameCompression).compressor
+}
+
+func (recv *connFraming) getFrameCompressor() frame.BodyCompressor {
+	if recv == nil {
+		return nil
+	}
+	return recv.frameCompression.Load().(frameCompression).compressor
+}
+
+// readFrame reads the next frame from the connection, the segments of the modern layout are decoded (and their
+// checksums verified) and the frames that they contain are returned one at a time.
+func (recv *connFraming) readFrame(
+	reader *bufio.Reader, connectionAddr string, clientHandlerContext context.Context) (*frame.RawFrame, error) {
+	if recv == nil {
+		return readRawFrame(reader, connectionAddr, clientHandlerContext)
+	}
+	readFrame := readRawFrame
+	if recv.pooledFrameBodies {
+		readFrame = readPooledRawFrame
+	}
+
+	for len(recv.decodedFrames) == 0 {
+		// the layout is only checked once the first byte is available because the peer only switches to the modern
+		// layout after receiving the frame that switches the layout of this connection
+		_, err := reader.Peek(1)
+		if err != nil {
+			return nil, adaptConnErr(connectionAddr, clientHandlerContext, err)
+		}
+
+		if !recv.isModern() {
+			f, err := readFrame(reader, connectionAddr, clientHandlerContext)
+			if err != nil {
+				return nil, err
+			}
+			if f.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+				err = recv.decompressFrame(f)
+				if err != nil {
+					return nil, err
+				}
+			}
+			recv.observe(f)
+			return f, nil
+		}
+
+		err = recv.readSegment(reader)
+		if err != nil {
+			return nil, adaptConnErr(connectionAddr, clientHandlerContext, err)
+		}
+	}
+
+	f := recv.decodedFrames[0]
+	recv.decodedFrames[0] = nil
+	recv.decodedFrames = recv.decodedFrames[1:]
+	return f, nil
+}
+
+func (recv *connFraming) readSegment(reader io.Reader) error {
+	seg, err := recv.getSegmentCodec().DecodeSegment(reader)
+	if err != nil {
+		return err
+	}
+
+	if seg.Header.IsSelfContained {
+		if recv.multiSegmentLength != 0 {
+			return fmt.Errorf("received self-contained segment before the end of a multi-segment frame")
+		}
+		payloadReader := bytes.NewReader(seg.Payload.UncompressedData)
+		for payloadReader.Len() > 0 {
+			f, err := recv.decodeRawFrame(payloadReader)
+			if err != nil {
+				return fmt.Errorf("could not decode frame in self-contained segment: %w", err)
+			}
+			recv.decodedFrames = append(recv.decodedFrames, f)
+		}
+		return nil
+	}
+
+	if recv.multiSegmentLength == 0 {
+		header, err := defaultCodec.DecodeHeader(bytes.NewReader(seg.Payload.UncompressedData))
+		if err != nil {
+			return fmt.Errorf("could not decode frame header in multi-segment payload: %w", err)
+		}
+		recv.multiSegmentLength = primitive.FrameHeaderLengthV3AndHigher + int(header.BodyLength)
+	}
+	recv.multiSegmentPayload = append(recv.multiSegmentPayload, seg.Payload.UncompressedData...)
+	if len(recv.multiSegmentPayload) < recv.multiSegmentLength {
+		return nil
+	}
+	if len(recv.multiSegmentPayload) > recv.multiSegmentLength {
+		return fmt.Errorf("multi-segment payload is longer (%d bytes) than the frame (%d bytes)",
+			len(recv.multiSegmentPayload), recv.multiSegmentLength)
+	}
+
+	f, err := recv.decodeRawFrame(bytes.NewReader(recv.multiSegmentPayload))
+	recv.multiSegmentPayload = nil
+	recv.multiSegmentLength = 0
+	if err != nil {
+		return fmt.Errorf("could not decode frame in multi-segment payload: %w", err)
+	}
+	recv.decodedFrames = append(recv.decodedFrames, f)
+	return nil
+}
+
+// decompressFrame replaces the compressed body of the provided frame with the decompressed body.
+func (recv *connFraming) decompressFrame(f *frame.RawFrame) error {
+	compressor := recv.getRequestedFrameCompressor()
+	if compressor == nil {
+		return fmt.Errorf("received compressed %v frame but no compression was requested in the STARTUP request",
+			f.Header.OpCode)
+	}
+	decompressedBody := getBuffer()
+	defer putBuffer(decompressedBody)
+	err := compressor.DecompressWithLength(bytes.NewBuffer(f.Body), decompressedBody)
+	if err != nil {
+		return fmt.Errorf("could not decompress body of %v frame: %w", f.Header.OpCode, err)
+	}
+	if recv.pooledFrameBodies {
+		releaseFrameBody(f)
+		f.Body = getFrameBody(decompressedBody.Len())
+	} else {
+		f.Body = make([]byte, decompressedBody.Len())
+	}
+	copy(f.Body, decompressedBody.Bytes())
+	f.Header.BodyLength = int32(len(f.Body))
+	f.Header.Flags = f.Header.Flags.Remove(primitive.HeaderFlagCompressed)
+	return nil
+}
+
+func (recv *connFraming) decodeRawFrame(reader io.Reader) (*frame.RawFrame, error) {
+	if recv.pooledFrameBodies {
+		return decodePooledRawFrame(reader)
+	}
+	return defaultCodec.DecodeRawFrame(reader)
+}
+
+// writeFrame encodes the provided frame, with the modern layout the frame is added to the payload of the current
+// segment which is only written to dest when it is full or when flush is called.
+func (recv *connFraming) writeFrame(
+	dest *bytes.Buffer, connectionAddr string, clientHandlerContext context.Context, f *frame.RawFrame) error {
+	if !recv.isModern() {
+		var err error
+		if compressor := recv.getFrameCompressor(); compressor != nil && isCompressibleFrame(f) {
+			err = writeCompressedFrame(dest, connectionAddr, clientHandlerContext, f, compressor)
+		} else {
+			err = writeRawFrame(dest, connectionAddr, clientHandlerContext, f)
+		}
+		if err == nil {
+			recv.observe(f)
+		}
+		return err
+	}
+
+	encodedLength := f.Header.Version.FrameHeaderLengthInBytes() + len(f.Body)
+	if recv.segmentPayload.Len()+encodedLength > segment.MaxPayloadLength {
+		err := recv.flush(dest, connectionAddr, clientHandlerContext)
+		if err != nil {
+			return err
+		}
+	}
+
+	if encodedLength <= segment.MaxPayloadLength {
+		return writeRawFrame(recv.segmentPayload, connectionAddr, clientHandlerContext, f)
+	}
+
+	encodedFrame := getBuffer()
+	defer putBuffer(encodedFrame)
+	err := writeRawFrame(encodedFrame, connectionAddr, clientHandlerContext, f)
+	if err != nil {
+		return err
+	}
+	for encodedFrame.Len() > 0 {
+		err = recv.writeSegment(dest, encodedFrame.Next(segment.MaxPayloadLength), false)
+		if err != nil {
+			return adaptConnErr(connectionAddr, clientHandlerContext, err)
+		}
+	}
+	return nil
+}
+
+// isCompressibleFrame returns true if the body of the provided frame can be compressed, the frames that are never
+// compressed are the same as the ones of the protocol library.
+func isCompressibleFrame(f *frame.RawFrame) bool {
+	return len(f.Body) > 0 && !f.Header.Flags.Contains(primitive.HeaderFlagCompressed) &&
+		f.Header.OpCode != primitive.OpCodeStartup &&
+		f.Header.OpCode != primitive.OpCodeOptions &&
+		f.Header.OpCode != primitive.OpCodeReady
+}
+
+// writeCompressedFrame writes the provided frame with a compressed body, the frame itself is not modified because
+// it can be shared (e.g. the events that are sent to every client).
+func writeCompressedFrame(
+	dest io.Writer, connectionAddr string, clientHandlerContext context.Context, f *frame.RawFrame,
+	compressor frame.BodyCompressor) error {
+	compressedBody := getBuffer()
+	defer putBuffer(compressedBody)
+	err := compressor.CompressWithLength(bytes.NewBuffer(f.Body), compressedBody)
+	if err != nil {
+		return fmt.Errorf("could not compress body of %v frame: %w", f.Header.OpCode, err)
+	}
+	header := f.Header.Clone()
+	header.Flags = header.Flags.Add(primitive.HeaderFlagCompressed)
+	compressedFrame := &frame.RawFrame{Header: header, Body: compressedBody.Bytes()}
+	return writeRawFrame(dest, connectionAddr, clientHandlerContext, compressedFrame)
+}
+
+// withStartupCompression returns a copy of the provided STARTUP request with the provided compression, NONE is used
+// instead of a compression that is not supported by the protocol version of the request.
+func withStartupCompression(request *frame.RawFrame, compression primitive.Compression) (*frame.RawFrame, error) {
+	options, ok := readStartupOptions(request)
+	if !ok {
+		return nil, fmt.Errorf("could not read the options of the STARTUP request")
+	}
+	if compression == primitive.CompressionNone || !request.Header.Version.SupportsCompression(compression) {
+		delete(options, message.StartupOptionCompression)
+	} else {
+		options[message.StartupOptionCompression] = string(compression)
+	}
+	body := &bytes.Buffer{}
+	err := primitive.WriteStringMap(options, body)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the options of the STARTUP request: %w", err)
+	}
+	return &frame.RawFrame{Header: request.Header.Clone(), Body: body.Bytes()}, nil
+}
+
+// bufferedLen returns the length of the frames that were written with writeFrame but not flushed to dest yet.
+func (recv *connFraming) bufferedLen() int {
+	if recv == nil {
+		return 0
+	}
+	return recv.segmentPayload.Len()
+}
+
+// flush writes the current segment to dest.
+func (recv *connFraming) flush(dest *bytes.Buffer, connectionAddr string, clientHandlerContext context.Context) error {
+	if recv.bufferedLen() == 0 {
+		return nil
+	}
+	err := recv.writeSegment(dest, recv.segmentPayload.Bytes(), true)
+	recv.segmentPayload.Reset()
+	return adaptConnErr(connectionAddr, clientHandlerContext, err)
+}
+
+func (recv *connFraming) writeSegment(dest io.Writer, payload []byte, selfContained bool) error {
+	seg := &segment.Segment{
+		Header:  &segment.Header{IsSelfContained: selfContained},
+		Payload: &segment.Payload{UncompressedData: payload},
+	}
+	return recv.getSegmentCodec().EncodeSegment(seg, dest)
+}
+
+// writeFrameToConn writes a single frame to the connection with the layout that is currently used by it.
+func (recv *connFraming) writeFrameToConn(
+	writer io.Writer, connectionAddr string, clientHandlerContext context.Context, f *frame.RawFrame) error {
+	if recv == nil {
+		return writeRawFrame(writer, connectionAddr, clientHandlerContext, f)
+	}
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+	err := recv.writeFrame(buffer, connectionAddr, clientHandlerContext, f)
+	if err == nil {
+		err = recv.flush(buffer, connectionAddr, clientHandlerContext)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(buffer.Bytes())
+	return adaptConnErr(connectionAddr, clientHandlerContext, err)
+}
+
+// segmentLz4Compressor compresses the segment payloads with LZ4. The payloads are decompressed into a buffer that can
+// hold the largest payload of a segment because the compressor of the protocol library gives up on payloads that
+// are compressed more than 8 times (e.g. a frame with a large blob of zeros).
+type segmentLz4Compressor struct {
+	lz4compression.Compressor
+}
+
+func (recv *segmentLz4Compressor) Decompress(source io.Reader, dest io.Writer) error {
+	compressedPayload := getBuffer()
+	defer putBuffer(compressedPayload)
+	_, err := compressedPayload.ReadFrom(source)
+	if err != nil {
+		return fmt.Errorf("cannot read compressed payload: %w", err)
+	}
+	payload := segmentPayloadPool.Get().(*[]byte)
+	defer segmentPayloadPool.Put(payload)
+	written, err := lz4.UncompressBlock(compressedPayload.Bytes(), *payload)
+	if err != nil {
+		return fmt.Errorf("cannot decompress payload: %w", err)
+	}
+	_, err = dest.Write((*payload)[:written])
+	return err
+}
+
+// frameLz4Compressor compresses the frame bodies with LZ4. The bodies are decompressed into a buffer of the length
+// that is written before the compressed body because the compressor of the protocol library ignores it and gives up
+// on bodies that are compressed more than 8 times.
+type frameLz4Compressor struct {
+	lz4compression.Compressor
+}
+
+func (recv *frameLz4Compressor) DecompressWithLength(source io.Reader, dest io.Writer) error {
+	compressedBody := getBuffer()
+	defer putBuffer(compressedBody)
+	_, err := compressedBody.ReadFrom(source)
+	if err != nil {
+		return fmt.Errorf("cannot read compressed body: %w", err)
+	}
+	if compressedBody.Len() < 4 {
+		return fmt.Errorf("cannot read decompressed length: compressed body is only %d bytes", compressedBody.Len())
+	}
+	decompressedLength := binary.BigEndian.Uint32(compressedBody.Next(4))
+	if decompressedLength == 0 {
+		// the compressed body of an empty body is a single byte that is discarded
+		return nil
+	}
+	body := make([]byte, decompressedLength)
+	written, err := lz4.UncompressBlock(compressedBody.Bytes(), body)
+	if err != nil {
+		return fmt.Errorf("cannot decompress body: %w", err)
+	}
+	if written != len(body) {
+		return fmt.Errorf("decompressed body is %d bytes instead of %d bytes", written, len(body))
+	}
+	_, err = dest.Write(body)
+	return err
+}
@@ -0,0 +1,225 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/go-cassandra-native-protocol/segment"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestRawFrame(t *testing.T, version primitive.ProtocolVersion, streamId int16, msg message.Message) *frame.RawFrame {
+	rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, streamId, msg))
+	require.Nil(t, err)
+	return rawFrame
+}
+
+func requireSameRawFrame(t *testing.T, expected *frame.RawFrame, actual *frame.RawFrame) {
+	require.Equal(t, expected.Header, actual.Header)
+	require.True(t, bytes.Equal(expected.Body, actual.Body))
+}
+
+func TestConnFraming_ModernLayout(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression primitive.Compression
+	}{
+		{"no compression", primitive.CompressionNone},
+		{"lz4 compression", primitive.CompressionLz4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startup := message.NewStartup()
+			startup.SetCompression(tt.compression)
+			handshake := []*frame.RawFrame{
+				newTestRawFrame(t, primitive.ProtocolVersion5, 0, startup),
+				newTestRawFrame(t, primitive.ProtocolVersion5, 0, &message.Ready{}),
+			}
+			frames := []*frame.RawFrame{
+				newTestRawFrame(t, primitive.ProtocolVersion5, 1, &message.Query{Query: "SELECT * FROM ks.tb"}),
+				// bigger than the payload of a segment so it is split across several segments
+				newTestRawFrame(t, primitive.ProtocolVersion5, 2, &message.Query{
+					Query: "INSERT INTO ks.tb (k, v) VALUES (0, ?)",
+					Options: &message.QueryOptions{
+						PositionalValues: []*primitive.Value{primitive.NewValue(bytes.Repeat([]byte{1}, 300000))}},
+				}),
+				newTestRawFrame(t, primitive.ProtocolVersion5, 3, &message.Options{}),
+			}
+
+			writer := newConnFraming()
+			buffer := &bytes.Buffer{}
+			for _, f := range append(handshake, frames...) {
+				require.Nil(t, writer.writeFrame(buffer, "", context.Background(), f))
+			}
+			require.Nil(t, writer.flush(buffer, "", context.Background()))
+			require.True(t, writer.isModern())
+
+			reader := newConnFraming()
+			bufferedReader := bufio.NewReader(buffer)
+			for _, expected := range append(handshake, frames...) {
+				f, err := reader.readFrame(bufferedReader, "", context.Background())
+				require.Nil(t, err)
+				requireSameRawFrame(t, expected, f)
+			}
+			require.True(t, reader.isModern())
+			require.Equal(t, 0, buffer.Len())
+		})
+	}
+}
+
+func TestConnFraming_LegacyLayout(t *testing.T) {
+	frames := []*frame.RawFrame{
+		newTestRawFrame(t, primitive.ProtocolVersion4, 0, message.NewStartup()),
+		newTestRawFrame(t, primitive.ProtocolVersion4, 0, &message.Ready{}),
+		newTestRawFrame(t, primitive.ProtocolVersion4, 1, &message.Query{Query: "SELECT * FROM ks.tb"}),
+		// DSE v2 doesn't use the modern layout
+		newTestRawFrame(t, primitive.ProtocolVersionDse2, 0, &message.Ready{}),
+		newTestRawFrame(t, primitive.ProtocolVersionDse2, 1, &message.Options{}),
+	}
+
+	writer := newConnFraming()
+	buffer := &bytes.Buffer{}
+	expectedBuffer := &bytes.Buffer{}
+	for _, f := range frames {
+		require.Nil(t, writer.writeFrame(buffer, "", context.Background(), f))
+		require.Nil(t, defaultCodec.EncodeRawFrame(f, expectedBuffer))
+	}
+	require.Nil(t, writer.flush(buffer, "", context.Background()))
+	require.False(t, writer.isModern())
+	require.Equal(t, expectedBuffer.Bytes(), buffer.Bytes())
+
+	reader := newConnFraming()
+	bufferedReader := bufio.NewReader(buffer)
+	for _, expected := range frames {
+		f, err := reader.readFrame(bufferedReader, "", context.Background())
+		require.Nil(t, err)
+		requireSameRawFrame(t, expected, f)
+	}
+	require.False(t, reader.isModern())
+}
+
+func TestConnFraming_FrameCompression(t *testing.T) {
+	for _, compression := range []primitive.Compression{primitive.CompressionLz4, primitive.CompressionSnappy} {
+		t.Run(string(compression), func(t *testing.T) {
+			startup := message.NewStartup()
+			startup.SetCompression(compression)
+			frames := []*frame.RawFrame{
+				newTestRawFrame(t, primitive.ProtocolVersion4, 0, startup),
+				newTestRawFrame(t, primitive.ProtocolVersion4, 0, &message.Ready{}),
+				newTestRawFrame(t, primitive.ProtocolVersion4, 1, &message.Query{Query: "SELECT * FROM ks.tb"}),
+				// compressed more than 8 times
+				newTestRawFrame(t, primitive.ProtocolVersion4, 2, &message.Query{
+					Query: "INSERT INTO ks.tb (k, v) VALUES (0, ?)",
+					Options: &message.QueryOptions{
+						PositionalValues: []*primitive.Value{primitive.NewValue(make([]byte, 100000))}},
+				}),
+				newTestRawFrame(t, primitive.ProtocolVersion4, 3, &message.Options{}),
+			}
+			expectedCompressed := []bool{false, false, true, true, false}
+
+			writer := newConnFraming()
+			buffer := &bytes.Buffer{}
+			for _, f := range frames {
+				original := f.Clone()
+				require.Nil(t, writer.writeFrame(buffer, "", context.Background(), f))
+				require.Equal(t, original, f)
+			}
+
+			encoded := bytes.NewReader(buffer.Bytes())
+			for i := range frames {
+				f, err := defaultCodec.DecodeRawFrame(encoded)
+				require.Nil(t, err)
+				require.Equal(t, expectedCompressed[i], f.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+			}
+
+			reader := newConnFraming()
+			bufferedReader := bufio.NewReader(buffer)
+			for _, expected := range frames {
+				f, err := reader.readFrame(bufferedReader, "", context.Background())
+				require.Nil(t, err)
+				requireSameRawFrame(t, expected, f)
+			}
+		})
+	}
+}
+
+func TestConnFraming_FrameCompressionNotAccepted(t *testing.T) {
+	startup := message.NewStartup()
+	startup.SetCompression(primitive.CompressionLz4)
+	writer := newConnFraming()
+	writer.observe(newTestRawFrame(t, primitive.ProtocolVersion4, 0, startup))
+
+	// the STARTUP request was not accepted so the error is not compressed
+	buffer := &bytes.Buffer{}
+	errorFrame := newTestRawFrame(t, primitive.ProtocolVersion4, 0, &message.ProtocolError{ErrorMessage: "error"})
+	require.Nil(t, writer.writeFrame(buffer, "", context.Background(), errorFrame))
+	f, err := defaultCodec.DecodeRawFrame(buffer)
+	require.Nil(t, err)
+	require.False(t, f.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+
+	// compressed frames can't be read if no compression was requested
+	compressedFrame := errorFrame.Clone()
+	compressedFrame.Header.Flags = compressedFrame.Header.Flags.Add(primitive.HeaderFlagCompressed)
+	require.Nil(t, defaultCodec.EncodeRawFrame(compressedFrame, buffer))
+	_, err = newConnFraming().readFrame(bufio.NewReader(buffer), "", context.Background())
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "no compression was requested")
+}
+
+func TestWithStartupCompression(t *testing.T) {
+	startup := message.NewStartup()
+	startup.SetCompression(primitive.CompressionSnappy)
+	startup.Options[message.StartupOptionDriverName] = "driver"
+
+	tests := []struct {
+		name        string
+		version     primitive.ProtocolVersion
+		compression primitive.Compression
+		expected    string
+	}{
+		{"lz4", primitive.ProtocolVersion4, primitive.CompressionLz4, "LZ4"},
+		{"none", primitive.ProtocolVersion4, primitive.CompressionNone, ""},
+		{"snappy not supported by v5", primitive.ProtocolVersion5, primitive.CompressionSnappy, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := newTestRawFrame(t, tt.version, 0, startup)
+			original := request.Clone()
+			newRequest, err := withStartupCompression(request, tt.compression)
+			require.Nil(t, err)
+			require.Equal(t, original, request)
+
+			options, ok := readStartupOptions(newRequest)
+			require.True(t, ok)
+			require.Equal(t, tt.expected, options[message.StartupOptionCompression])
+			require.Equal(t, "driver", options[message.StartupOptionDriverName])
+		})
+	}
+}
+
+func TestConnFraming_CorruptedSegment(t *testing.T) {
+	reader := newConnFraming()
+	reader.observe(newTestRawFrame(t, primitive.ProtocolVersion5, 0, &message.Ready{}))
+	require.True(t, reader.isModern())
+
+	payload := &bytes.Buffer{}
+	require.Nil(t, defaultCodec.EncodeRawFrame(
+		newTestRawFrame(t, primitive.ProtocolVersion5, 1, &message.Query{Query: "SELECT * FROM ks.tb"}), payload))
+	encodedSegment := &bytes.Buffer{}
+	require.Nil(t, segment.NewCodec().EncodeSegment(&segment.Segment{
+		Header:  &segment.Header{IsSelfContained: true},
+		Payload: &segment.Payload{UncompressedData: payload.Bytes()},
+	}, encodedSegment))
+
+	corrupted := encodedSegment.Bytes()
+	corrupted[len(corrupted)-6] ^= 0xFF
+	_, err := reader.readFrame(bufio.NewReader(bytes.NewReader(corrupted)), "", context.Background())
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "crc")
+}
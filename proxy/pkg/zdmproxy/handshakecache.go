@@ -0,0 +1,64 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"sync"
+	"time"
+)
+
+// How long a cached SUPPORTED response is used before the next OPTIONS request is forwarded to the clusters again.
+// This makes sure that changes to the clusters' supported options (e.g. after an upgrade) are eventually picked up.
+const supportedResponseCacheTtl = time.Minute
+
+// handshakeCache stores the results of the handshake negotiation with the clusters so that they can be reused
+// by new client connections.
+//
+// At the moment only the SUPPORTED responses to OPTIONS requests that clients send before STARTUP are cached
+// (per protocol version) because they are the same for every client connection.
+type handshakeCache struct {
+	lock      *sync.RWMutex
+	supported map[primitive.ProtocolVersion]*cachedSupportedResponse
+}
+
+type cachedSupportedResponse struct {
+	response *frame.RawFrame
+	storedAt time.Time
+}
+
+func newHandshakeCache() *handshakeCache {
+	return &handshakeCache{
+		lock:      &sync.RWMutex{},
+		supported: make(map[primitive.ProtocolVersion]*cachedSupportedResponse),
+	}
+}
+
+// getSupportedResponse returns a copy of the cached SUPPORTED response for the protocol version of the provided
+// OPTIONS request with its stream id, or nil if there isn't a valid cached response.
+func (recv *handshakeCache) getSupportedResponse(request *frame.RawFrame) *frame.RawFrame {
+	recv.lock.RLock()
+	cached, ok := recv.supported[request.Header.Version]
+	recv.lock.RUnlock()
+
+	if !ok || time.Since(cached.storedAt) > supportedResponseCacheTtl {
+		return nil
+	}
+
+	response := cached.response.Clone()
+	response.Header.StreamId = request.Header.StreamId
+	return response
+}
+
+// storeSupportedResponse caches the response if it is a SUPPORTED response, other responses are ignored.
+func (recv *handshakeCache) storeSupportedResponse(response *frame.RawFrame) {
+	if response == nil || response.Header.OpCode != primitive.OpCodeSupported {
+		return
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.supported[response.Header.Version] = &cachedSupportedResponse{
+		response: response.Clone(),
+		storedAt: time.Now(),
+	}
+}
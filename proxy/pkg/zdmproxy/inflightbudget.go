@@ -0,0 +1,80 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// inFlightBudget caps the requests that are in flight across all client connections (ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS).
+// The client connections stop reading requests from their sockets while the budget is exhausted so that the clients
+// get TCP backpressure instead of the proxy queueing their requests.
+type inFlightBudget struct {
+	maxInFlightRequests   int
+	backpressureActivated metrics.Counter
+
+	lock     *sync.Mutex
+	inFlight int
+	resumed  chan struct{} // nil if the backpressure is not active, closed when it ends
+}
+
+// newInFlightBudget returns nil if the in flight requests are not capped.
+func newInFlightBudget(maxInFlightRequests int, backpressureActivated metrics.Counter) *inFlightBudget {
+	if maxInFlightRequests <= 0 {
+		return nil
+	}
+	return &inFlightBudget{
+		maxInFlightRequests:   maxInFlightRequests,
+		backpressureActivated: backpressureActivated,
+		lock:                  &sync.Mutex{},
+	}
+}
+
+// acquire tracks a request that was sent to the clusters, the backpressure is activated if the budget is exhausted.
+// The request is never rejected, it is the next requests of the clients that wait.
+func (recv *inFlightBudget) acquire() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.inFlight++
+	if recv.resumed == nil && recv.inFlight >= recv.maxInFlightRequests {
+		log.Debugf("%d requests are in flight, client connections stop reading requests until some of them are done.",
+			recv.inFlight)
+		recv.resumed = make(chan struct{})
+		recv.backpressureActivated.Add(1)
+	}
+}
+
+// release must be called once for every acquire when the request is done.
+func (recv *inFlightBudget) release() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.inFlight--
+	if recv.resumed != nil && recv.inFlight < recv.maxInFlightRequests {
+		log.Debugf("%d requests are in flight, client connections resume reading requests.", recv.inFlight)
+		close(recv.resumed)
+		recv.resumed = nil
+	}
+}
+
+// wait blocks while the budget is exhausted, it returns false if ctx is done first.
+func (recv *inFlightBudget) wait(ctx context.Context) bool {
+	recv.lock.Lock()
+	resumed := recv.resumed
+	recv.lock.Unlock()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (recv *inFlightBudget) getInFlight() int {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.inFlight
+}
@@ -0,0 +1,60 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestInFlightBudget_Disabled(t *testing.T) {
+	require.Nil(t, newInFlightBudget(0, &countingCounter{}))
+}
+
+func TestInFlightBudget_Backpressure(t *testing.T) {
+	activations := &countingCounter{}
+	budget := newInFlightBudget(2, activations)
+	ctx := context.Background()
+
+	budget.acquire()
+	require.True(t, budget.wait(ctx))
+	budget.acquire()
+	require.Equal(t, 2, budget.getInFlight())
+	require.Equal(t, int64(1), activations.count)
+
+	waitDone := make(chan bool, 1)
+	go func() {
+		waitDone <- budget.wait(ctx)
+	}()
+	select {
+	case <-waitDone:
+		t.Fatal("wait returned while the budget is exhausted")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// requests that were already read are still sent
+	budget.acquire()
+	require.Equal(t, int64(1), activations.count)
+
+	budget.release()
+	select {
+	case <-waitDone:
+		t.Fatal("wait returned while the budget is exhausted")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	budget.release()
+	select {
+	case resumed := <-waitDone:
+		require.True(t, resumed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after the in flight requests were released")
+	}
+	require.Equal(t, 1, budget.getInFlight())
+
+	budget.acquire()
+	require.Equal(t, int64(2), activations.count)
+	canceledCtx, cancelFn := context.WithCancel(ctx)
+	cancelFn()
+	require.False(t, budget.wait(canceledCtx))
+}
@@ -0,0 +1,90 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"sync"
+	"time"
+)
+
+// Maximum number of cached responses, different queries are only cached while there is room for them.
+const interceptedResponseCacheMaxEntries = 1024
+
+// interceptedResponseCache stores the responses of the intercepted system.local and system.peers queries
+// (see ZDM_INTERCEPTED_QUERIES_CACHE_TTL_MS) so that they are not built again for every client connection
+// when drivers reconnect at the same time.
+//
+// Responses are cached per protocol version, query and keyspace. They expire after the TTL and they are invalidated
+// when the topology of the control connection is refreshed because they are built from it.
+type interceptedResponseCache struct {
+	ttl     time.Duration
+	lock    *sync.RWMutex
+	entries map[interceptedResponseCacheKey]*cachedInterceptedResponse
+	now     func() time.Time
+}
+
+type interceptedResponseCacheKey struct {
+	version  primitive.ProtocolVersion
+	query    string
+	keyspace string
+}
+
+type cachedInterceptedResponse struct {
+	response        *frame.RawFrame
+	topologyVersion int64
+	expiresAt       time.Time
+}
+
+// newInterceptedResponseCache returns nil if the TTL is not positive.
+func newInterceptedResponseCache(ttl time.Duration) *interceptedResponseCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &interceptedResponseCache{
+		ttl:     ttl,
+		lock:    &sync.RWMutex{},
+		entries: make(map[interceptedResponseCacheKey]*cachedInterceptedResponse),
+		now:     time.Now,
+	}
+}
+
+// getResponse returns a copy of the cached response of the query with the stream id of the request,
+// or nil if there isn't a valid cached response for the current topology version.
+func (recv *interceptedResponseCache) getResponse(
+	request *frame.RawFrame, query string, keyspace string, topologyVersion int64) *frame.RawFrame {
+	recv.lock.RLock()
+	cached, ok := recv.entries[interceptedResponseCacheKey{request.Header.Version, query, keyspace}]
+	recv.lock.RUnlock()
+
+	if !ok || cached.topologyVersion != topologyVersion || !recv.now().Before(cached.expiresAt) {
+		return nil
+	}
+
+	response := cached.response.Clone()
+	response.Header.StreamId = request.Header.StreamId
+	return response
+}
+
+func (recv *interceptedResponseCache) storeResponse(
+	response *frame.RawFrame, query string, keyspace string, topologyVersion int64) {
+	now := recv.now()
+	key := interceptedResponseCacheKey{response.Header.Version, query, keyspace}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if _, exists := recv.entries[key]; !exists && len(recv.entries) >= interceptedResponseCacheMaxEntries {
+		for otherKey, cached := range recv.entries {
+			if !now.Before(cached.expiresAt) {
+				delete(recv.entries, otherKey)
+			}
+		}
+		if len(recv.entries) >= interceptedResponseCacheMaxEntries {
+			return
+		}
+	}
+	recv.entries[key] = &cachedInterceptedResponse{
+		response:        response.Clone(),
+		topologyVersion: topologyVersion,
+		expiresAt:       now.Add(recv.ttl),
+	}
+}
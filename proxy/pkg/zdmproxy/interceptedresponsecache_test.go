@@ -0,0 +1,63 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestInterceptedResponseCache(t *testing.T) {
+	require.Nil(t, newInterceptedResponseCache(0))
+
+	cache := newInterceptedResponseCache(time.Second)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	query := "SELECT * FROM system.local"
+	newRawFrame := func(version primitive.ProtocolVersion, streamId int16, msg message.Message) *frame.RawFrame {
+		rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(version, streamId, msg))
+		require.Nil(t, err)
+		return rawFrame
+	}
+	request := newRawFrame(primitive.ProtocolVersion4, 5, &message.Query{Query: query})
+	response := newRawFrame(primitive.ProtocolVersion4, 1, &message.VoidResult{})
+
+	require.Nil(t, cache.getResponse(request, query, "", 1))
+	cache.storeResponse(response, query, "", 1)
+
+	cachedResponse := cache.getResponse(request, query, "", 1)
+	require.NotNil(t, cachedResponse)
+	require.Equal(t, int16(5), cachedResponse.Header.StreamId)
+	require.Equal(t, response.Body, cachedResponse.Body)
+	require.Equal(t, int16(1), response.Header.StreamId, "the cached response is a copy")
+
+	require.Nil(t, cache.getResponse(request, query, "ks", 1), "other keyspace")
+	require.Nil(t, cache.getResponse(request, "SELECT * FROM system.peers", "", 1), "other query")
+	require.Nil(t, cache.getResponse(
+		newRawFrame(primitive.ProtocolVersion3, 5, &message.Query{Query: query}), query, "", 1), "other protocol version")
+	require.Nil(t, cache.getResponse(request, query, "", 2), "topology was refreshed")
+
+	now = now.Add(time.Second)
+	require.Nil(t, cache.getResponse(request, query, "", 1), "response expired")
+}
+
+func TestInterceptedResponseCache_MaxEntries(t *testing.T) {
+	cache := newInterceptedResponseCache(time.Second)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.VoidResult{}))
+	require.Nil(t, err)
+	for i := 0; i < interceptedResponseCacheMaxEntries+1; i++ {
+		cache.storeResponse(response, fmt.Sprintf("SELECT * FROM system.local WHERE key = '%d'", i), "", 1)
+	}
+	require.Equal(t, interceptedResponseCacheMaxEntries, len(cache.entries))
+
+	now = now.Add(time.Second)
+	cache.storeResponse(response, "SELECT * FROM system.peers", "", 1)
+	require.Equal(t, 1, len(cache.entries), "expired entries are removed when the cache is full")
+}
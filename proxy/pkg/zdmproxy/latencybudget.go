@@ -0,0 +1,193 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// latencyBudgetSlots is the number of slots of the sliding window of a latency budget, the budget is evaluated every
+// time the window slides by one slot.
+const latencyBudgetSlots = 12
+
+var latencyBudgetWebhookTimeout = 5 * time.Second
+
+// latencyBudget raises an alert when the latency of the requests to a cluster exceeds its budget (SLO), i.e. when more
+// than 100 - Percentile percent of the requests of the sliding window took longer than BudgetMs. An alert is a
+// structured log event, an increment of the proxy_latency_budget_breaches_total metric and a POST request to the
+// webhook if ZDM_LATENCY_BUDGET_WEBHOOK_URL is set. The recovery is reported to the log and to the webhook too.
+type latencyBudget struct {
+	cluster    common.ClusterType
+	conf       *common.LatencyBudgetConfig
+	breaches   metrics.Counter
+	webhookUrl string
+	httpClient *http.Client
+
+	window *slidingWindow
+
+	// only accessed by the goroutine that evaluates the budget
+	breached bool
+}
+
+// counters of the sliding window of a latency budget
+const (
+	latencyBudgetRequests = iota
+	latencyBudgetSlowRequests
+	latencyBudgetCounters
+)
+
+// LatencyBudgetEvent is the body of the webhook requests.
+type LatencyBudgetEvent struct {
+	Event        string  `json:"event"`
+	Cluster      string  `json:"cluster"`
+	BudgetMs     int     `json:"budgetMs"`
+	Percentile   float64 `json:"percentile"`
+	WindowMs     int     `json:"windowMs"`
+	Requests     int64   `json:"requests"`
+	SlowRequests int64   `json:"slowRequests"`
+	Time         string  `json:"time"`
+}
+
+const (
+	latencyBudgetEventBreached  = "latency_budget_breached"
+	latencyBudgetEventRecovered = "latency_budget_recovered"
+)
+
+// newLatencyBudget returns nil if the latency budget of the cluster is disabled.
+func newLatencyBudget(
+	cluster common.ClusterType, conf *common.LatencyBudgetConfig, breaches metrics.Counter, webhookUrl string) *latencyBudget {
+	if !conf.Enabled {
+		return nil
+	}
+	return &latencyBudget{
+		cluster:    cluster,
+		conf:       conf,
+		breaches:   breaches,
+		webhookUrl: webhookUrl,
+		httpClient: &http.Client{Timeout: latencyBudgetWebhookTimeout},
+		window:     newSlidingWindow(latencyBudgetSlots, latencyBudgetCounters),
+	}
+}
+
+// wrapHistogram returns a histogram that also counts the requests of the provided request duration histogram in the
+// latency budget, it returns the provided histogram if the latency budget is disabled.
+func (recv *latencyBudget) wrapHistogram(histogram metrics.Histogram) metrics.Histogram {
+	if recv == nil {
+		return histogram
+	}
+	return &latencyBudgetHistogram{histogram: histogram, latencyBudget: recv}
+}
+
+func (recv *latencyBudget) observe(duration time.Duration) {
+	recv.window.add(latencyBudgetRequests, 1)
+	if duration > time.Duration(recv.conf.BudgetMs)*time.Millisecond {
+		recv.window.add(latencyBudgetSlowRequests, 1)
+	}
+}
+
+// run evaluates the budget every time the window slides by one slot until the context is canceled.
+func (recv *latencyBudget) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(recv.conf.WindowMs) * time.Millisecond / latencyBudgetSlots)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recv.evaluate(ctx)
+			recv.window.slide()
+		}
+	}
+}
+
+// evaluate raises an alert if the budget was breached over the window and reports the recovery of a previous breach.
+func (recv *latencyBudget) evaluate(ctx context.Context) {
+	sums := recv.window.sums()
+	requests, slowRequests := sums[latencyBudgetRequests], sums[latencyBudgetSlowRequests]
+	if requests == 0 {
+		return
+	}
+
+	breached := float64(slowRequests) > float64(requests)*(100-recv.conf.Percentile)/100
+	if breached == recv.breached {
+		return
+	}
+	recv.breached = breached
+
+	eventName := latencyBudgetEventRecovered
+	if breached {
+		eventName = latencyBudgetEventBreached
+	}
+	event := &LatencyBudgetEvent{
+		Event:        eventName,
+		Cluster:      string(recv.cluster),
+		BudgetMs:     recv.conf.BudgetMs,
+		Percentile:   recv.conf.Percentile,
+		WindowMs:     recv.conf.WindowMs,
+		Requests:     requests,
+		SlowRequests: slowRequests,
+		Time:         time.Now().UTC().Format(time.RFC3339),
+	}
+	logger := log.WithFields(log.Fields{
+		"event":         event.Event,
+		"cluster":       event.Cluster,
+		"budget_ms":     event.BudgetMs,
+		"percentile":    event.Percentile,
+		"window_ms":     event.WindowMs,
+		"requests":      event.Requests,
+		"slow_requests": event.SlowRequests,
+	})
+	if breached {
+		logger.Warnf("The p%v latency of %v exceeded the latency budget of %vms: %v of the last %v requests took longer.",
+			recv.conf.Percentile, recv.cluster, recv.conf.BudgetMs, slowRequests, requests)
+		recv.breaches.Add(1)
+	} else {
+		logger.Infof("The p%v latency of %v is within the latency budget of %vms again.",
+			recv.conf.Percentile, recv.cluster, recv.conf.BudgetMs)
+	}
+	recv.callWebhook(ctx, event)
+}
+
+func (recv *latencyBudget) callWebhook(ctx context.Context, event *LatencyBudgetEvent) {
+	if recv.webhookUrl == "" {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("Could not encode latency budget event: %v.", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, recv.webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("Could not call latency budget webhook: %v.", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err := recv.httpClient.Do(req)
+	if err == nil {
+		_ = rsp.Body.Close()
+		if rsp.StatusCode/100 != 2 {
+			err = fmt.Errorf("unexpected status %v", rsp.Status)
+		}
+	}
+	if err != nil {
+		log.Warnf("Could not call latency budget webhook: %v.", err)
+	}
+}
+
+type latencyBudgetHistogram struct {
+	histogram     metrics.Histogram
+	latencyBudget *latencyBudget
+}
+
+func (recv *latencyBudgetHistogram) Track(begin time.Time) {
+	recv.histogram.Track(begin)
+	recv.latencyBudget.observe(time.Since(begin))
+}
@@ -0,0 +1,87 @@
+package zdmproxy
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingHistogram struct {
+	count int64
+}
+
+func (recv *countingHistogram) Track(_ time.Time) {
+	atomic.AddInt64(&recv.count, 1)
+}
+
+func TestLatencyBudget(t *testing.T) {
+	histogram := &countingHistogram{}
+	require.Nil(t, newLatencyBudget(common.ClusterTypeOrigin, &common.LatencyBudgetConfig{}, &countingCounter{}, ""))
+	var disabledBudget *latencyBudget
+	require.Equal(t, histogram, disabledBudget.wrapHistogram(histogram))
+
+	eventsLock := &sync.Mutex{}
+	var events []*LatencyBudgetEvent
+	server := httptest.NewServer(http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		var event *LatencyBudgetEvent
+		require.Nil(t, json.NewDecoder(req.Body).Decode(&event))
+		eventsLock.Lock()
+		defer eventsLock.Unlock()
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	breaches := &countingCounter{}
+	budget := newLatencyBudget(common.ClusterTypeTarget,
+		&common.LatencyBudgetConfig{Enabled: true, BudgetMs: 50, Percentile: 90, WindowMs: 60000}, breaches, server.URL)
+	wrappedHistogram := budget.wrapHistogram(histogram)
+	wrappedHistogram.Track(time.Now())
+	require.Equal(t, int64(1), atomic.LoadInt64(&histogram.count))
+
+	// 1 slow request out of 10 is within the p90 budget
+	for i := 0; i < 8; i++ {
+		budget.observe(10 * time.Millisecond)
+	}
+	budget.observe(100 * time.Millisecond)
+	ctx := context.Background()
+	budget.evaluate(ctx)
+	require.Empty(t, events)
+
+	budget.window.slide()
+	budget.observe(100 * time.Millisecond)
+	budget.evaluate(ctx)
+	require.Equal(t, int64(1), atomic.LoadInt64(&breaches.count))
+	require.Len(t, events, 1)
+	require.Equal(t, "latency_budget_breached", events[0].Event)
+	require.Equal(t, "TARGET", events[0].Cluster)
+	require.Equal(t, int64(11), events[0].Requests)
+	require.Equal(t, int64(2), events[0].SlowRequests)
+
+	// the breach is only reported once
+	budget.observe(100 * time.Millisecond)
+	budget.evaluate(ctx)
+	require.Len(t, events, 1)
+
+	// the slow requests leave the window
+	for i := 0; i < latencyBudgetSlots; i++ {
+		budget.window.slide()
+	}
+	for i := 0; i < 10; i++ {
+		budget.observe(10 * time.Millisecond)
+	}
+	budget.evaluate(ctx)
+	require.Equal(t, int64(1), atomic.LoadInt64(&breaches.count))
+	require.Len(t, events, 2)
+	require.Equal(t, "latency_budget_recovered", events[1].Event)
+	require.Equal(t, int64(10), events[1].Requests)
+	require.Equal(t, int64(0), events[1].SlowRequests)
+}
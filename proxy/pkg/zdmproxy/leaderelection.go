@@ -0,0 +1,334 @@
+package zdmproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fleetLock is a lock of the fleet store that expires if its holder stops renewing it.
+type fleetLock interface {
+	// tryAcquire acquires the lock if it is free and renews it if it is held by this instance, it returns true if
+	// this instance holds the lock.
+	tryAcquire(ctx context.Context) (bool, error)
+	release(ctx context.Context) error
+}
+
+// newFleetLock returns nil if the fleet store is disabled.
+func newFleetLock(fleetStoreConfig *common.FleetStoreConfig, key string, ttl time.Duration, holder string) fleetLock {
+	client := newFleetStoreClient(fleetStoreConfig)
+	switch fleetStoreConfig.Type {
+	case fleetStoreTypeEtcd:
+		return &etcdFleetLock{client: client, key: key, ttl: ttl, holder: holder}
+	case fleetStoreTypeConsul:
+		return &consulFleetLock{client: client, key: key, ttl: ttl, holder: holder}
+	default:
+		return nil
+	}
+}
+
+// etcdFleetLock creates the key with a lease of etcd if it does not exist, the lease is kept alive while the lock
+// is held.
+type etcdFleetLock struct {
+	client  *fleetStoreClient
+	key     string
+	ttl     time.Duration
+	holder  string
+	leaseId string // empty if there is no lease
+}
+
+func (recv *etcdFleetLock) tryAcquire(ctx context.Context) (bool, error) {
+	if recv.leaseId != "" {
+		var keepAliveResponse struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		err := recv.client.postEtcd(ctx, "/v3/lease/keepalive", map[string]interface{}{"ID": recv.leaseId}, &keepAliveResponse)
+		if err != nil {
+			return false, err
+		}
+		if ttl, _ := strconv.Atoi(keepAliveResponse.Result.TTL); ttl <= 0 {
+			// the lease expired, the key was deleted with it
+			recv.leaseId = ""
+		}
+	}
+	if recv.leaseId == "" {
+		var grantResponse struct {
+			ID string `json:"ID"`
+		}
+		err := recv.client.postEtcd(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": int64(recv.ttl.Seconds())}, &grantResponse)
+		if err != nil {
+			return false, err
+		}
+		if grantResponse.ID == "" {
+			return false, fmt.Errorf("etcd did not return the ID of the lease")
+		}
+		recv.leaseId = grantResponse.ID
+	}
+
+	key := []byte(recv.key)
+	txnRequest := map[string]interface{}{
+		"compare": []interface{}{
+			map[string]interface{}{"key": key, "result": "EQUAL", "target": "CREATE", "create_revision": "0"}},
+		"success": []interface{}{
+			map[string]interface{}{"request_put": map[string]interface{}{
+				"key": key, "value": []byte(recv.holder), "lease": recv.leaseId}}},
+		"failure": []interface{}{
+			map[string]interface{}{"request_range": map[string]interface{}{"key": key}}},
+	}
+	var txnResponse struct {
+		Succeeded bool `json:"succeeded"`
+		Responses []struct {
+			ResponseRange struct {
+				Kvs []struct {
+					Lease string `json:"lease"`
+				} `json:"kvs"`
+			} `json:"response_range"`
+		} `json:"responses"`
+	}
+	err := recv.client.postEtcd(ctx, "/v3/kv/txn", txnRequest, &txnResponse)
+	if err != nil {
+		return false, err
+	}
+	if txnResponse.Succeeded {
+		return true, nil
+	}
+	for _, response := range txnResponse.Responses {
+		for _, kv := range response.ResponseRange.Kvs {
+			if kv.Lease == recv.leaseId {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (recv *etcdFleetLock) release(ctx context.Context) error {
+	if recv.leaseId == "" {
+		return nil
+	}
+	// the key is deleted with the lease
+	err := recv.client.postEtcd(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": recv.leaseId}, nil)
+	if err != nil {
+		return err
+	}
+	recv.leaseId = ""
+	return nil
+}
+
+// consulFleetLock acquires the key with a session of consul, the session is renewed while the lock is held.
+type consulFleetLock struct {
+	client    *fleetStoreClient
+	key       string
+	ttl       time.Duration
+	holder    string
+	sessionId string // empty if there is no session
+}
+
+func (recv *consulFleetLock) tryAcquire(ctx context.Context) (bool, error) {
+	if recv.sessionId != "" {
+		statusCode, rspBody, err := recv.put(ctx, "/v1/session/renew/"+recv.sessionId, nil)
+		if err != nil {
+			return false, err
+		}
+		if statusCode == http.StatusNotFound {
+			// the session expired, the lock was released with it
+			recv.sessionId = ""
+		} else if statusCode != http.StatusOK {
+			return false, fmt.Errorf("consul returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+		}
+	}
+	if recv.sessionId == "" {
+		body, _ := json.Marshal(map[string]string{
+			"Name":      "zdm-proxy leader election",
+			"TTL":       fmt.Sprintf("%vs", int64(recv.ttl.Seconds())),
+			"Behavior":  "release",
+			"LockDelay": "0s",
+		})
+		statusCode, rspBody, err := recv.put(ctx, "/v1/session/create", body)
+		if err != nil {
+			return false, err
+		}
+		if statusCode != http.StatusOK {
+			return false, fmt.Errorf("consul returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+		}
+		var createResponse struct {
+			ID string
+		}
+		err = json.Unmarshal(rspBody, &createResponse)
+		if err != nil || createResponse.ID == "" {
+			return false, fmt.Errorf("could not parse the session of consul: %v", strings.TrimSpace(string(rspBody)))
+		}
+		recv.sessionId = createResponse.ID
+	}
+
+	statusCode, rspBody, err := recv.put(ctx, "/v1/kv/"+consulKeyPath(recv.key)+"?acquire="+recv.sessionId, []byte(recv.holder))
+	if err != nil {
+		return false, err
+	}
+	if statusCode != http.StatusOK {
+		return false, fmt.Errorf("consul returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	return strings.TrimSpace(string(rspBody)) == "true", nil
+}
+
+func (recv *consulFleetLock) release(ctx context.Context) error {
+	if recv.sessionId == "" {
+		return nil
+	}
+	// the lock is released with the session
+	statusCode, rspBody, err := recv.put(ctx, "/v1/session/destroy/"+recv.sessionId, nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("consul returned status code %v: %v", statusCode, strings.TrimSpace(string(rspBody)))
+	}
+	recv.sessionId = ""
+	return nil
+}
+
+func (recv *consulFleetLock) put(ctx context.Context, path string, body []byte) (int, []byte, error) {
+	statusCode, rspBody, _, err := recv.client.do(ctx, http.MethodPut, path, body)
+	return statusCode, rspBody, err
+}
+
+// singletonTask is a background task that must run on a single proxy instance of the fleet, see leaderElection.
+type singletonTask struct {
+	name     string
+	run      func(ctx context.Context)
+	cancelFn context.CancelFunc // nil if the task is not running
+	wg       *sync.WaitGroup
+}
+
+// leaderElection elects the proxy instance of the fleet that runs the singleton tasks. The leadership is a lock of the
+// fleet store that is renewed every third of its TTL, the leader steps down as soon as it can not renew it. Every
+// instance is the leader if leader election is disabled (a nil leaderElection).
+type leaderElection struct {
+	lock        fleetLock
+	ttl         time.Duration
+	description string // e.g. etcd key zdm-proxy/leader
+	leader      bool
+	tasks       []*singletonTask
+	tasksLock   *sync.Mutex
+}
+
+// newLeaderElection returns nil if leader election is disabled.
+func newLeaderElection(
+	conf *common.LeaderElectionConfig, fleetStoreConfig *common.FleetStoreConfig, holder string) *leaderElection {
+	if !conf.Enabled {
+		return nil
+	}
+	ttl := time.Duration(conf.TtlMs) * time.Millisecond
+	lock := newFleetLock(fleetStoreConfig, conf.Key, ttl, holder)
+	if lock == nil {
+		return nil
+	}
+	return &leaderElection{
+		lock:        lock,
+		ttl:         ttl,
+		description: fmt.Sprintf("%v key %v", fleetStoreConfig.Type, conf.Key),
+		tasksLock:   &sync.Mutex{},
+	}
+}
+
+func (recv *leaderElection) isLeader() bool {
+	if recv == nil {
+		return true
+	}
+	recv.tasksLock.Lock()
+	defer recv.tasksLock.Unlock()
+	return recv.leader
+}
+
+// addSingletonTask runs the task while this instance is the leader, the context of the task is cancelled when the
+// leadership is lost.
+func (recv *leaderElection) addSingletonTask(name string, run func(ctx context.Context)) {
+	recv.tasksLock.Lock()
+	defer recv.tasksLock.Unlock()
+	task := &singletonTask{name: name, run: run, wg: &sync.WaitGroup{}}
+	recv.tasks = append(recv.tasks, task)
+	if recv.leader {
+		recv.startTask(task)
+	}
+}
+
+// run takes part in the election until ctx is done, the leadership is released before it returns.
+func (recv *leaderElection) run(ctx context.Context) {
+	renewInterval := recv.ttl / 3
+	for {
+		requestCtx, cancelFn := context.WithTimeout(ctx, renewInterval)
+		leader, err := recv.lock.tryAcquire(requestCtx)
+		cancelFn()
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Warnf("Could not acquire or renew the leadership through the %v: %v.", recv.description, err)
+		}
+		recv.setLeader(leader && err == nil)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(renewInterval):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	recv.setLeader(false)
+	releaseCtx, cancelFn := context.WithTimeout(context.Background(), fleetStoreRequestTimeout)
+	defer cancelFn()
+	err := recv.lock.release(releaseCtx)
+	if err != nil {
+		log.Warnf("Could not release the leadership of the %v, it expires in %v: %v.", recv.description, recv.ttl, err)
+	}
+}
+
+func (recv *leaderElection) setLeader(leader bool) {
+	recv.tasksLock.Lock()
+	defer recv.tasksLock.Unlock()
+	if recv.leader == leader {
+		return
+	}
+	recv.leader = leader
+	if leader {
+		log.Infof("This proxy instance is now the leader (%v), starting %v singleton tasks.",
+			recv.description, len(recv.tasks))
+		for _, task := range recv.tasks {
+			recv.startTask(task)
+		}
+		return
+	}
+
+	log.Warnf("This proxy instance is not the leader (%v) anymore, stopping %v singleton tasks.",
+		recv.description, len(recv.tasks))
+	for _, task := range recv.tasks {
+		task.cancelFn()
+		task.cancelFn = nil
+	}
+	for _, task := range recv.tasks {
+		task.wg.Wait()
+	}
+}
+
+func (recv *leaderElection) startTask(task *singletonTask) {
+	var ctx context.Context
+	ctx, task.cancelFn = context.WithCancel(context.Background())
+	task.wg.Add(1)
+	go func() {
+		defer task.wg.Done()
+		log.Debugf("Starting singleton task %v.", task.name)
+		task.run(ctx)
+		log.Debugf("Singleton task %v stopped.", task.name)
+	}()
+}
@@ -0,0 +1,190 @@
+package zdmproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLockServer emulates the leases of etcd and the sessions of consul, the lock is held by a lease or a session.
+type fakeLockServer struct {
+	lock     *sync.Mutex
+	owners   map[string]bool // valid leases or sessions
+	holder   string          // lease or session that holds the lock
+	sequence int
+}
+
+func newFakeLockServer() *fakeLockServer {
+	return &fakeLockServer{lock: &sync.Mutex{}, owners: map[string]bool{}}
+}
+
+func (recv *fakeLockServer) newOwner() string {
+	recv.sequence++
+	owner := fmt.Sprintf("%d", recv.sequence)
+	recv.owners[owner] = true
+	return owner
+}
+
+func (recv *fakeLockServer) deleteOwner(owner string) {
+	delete(recv.owners, owner)
+	if recv.holder == owner {
+		recv.holder = ""
+	}
+}
+
+func (recv *fakeLockServer) etcdHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		var body map[string]interface{}
+		require.Nil(t, json.NewDecoder(req.Body).Decode(&body))
+		switch req.URL.Path {
+		case "/v3/lease/grant":
+			fmt.Fprintf(rsp, `{"ID":"%v","TTL":"10"}`, recv.newOwner())
+		case "/v3/lease/keepalive":
+			if recv.owners[body["ID"].(string)] {
+				fmt.Fprintf(rsp, `{"result":{"ID":"%v","TTL":"10"}}`, body["ID"])
+			} else {
+				fmt.Fprintf(rsp, `{"result":{"ID":"%v"}}`, body["ID"])
+			}
+		case "/v3/lease/revoke":
+			recv.deleteOwner(body["ID"].(string))
+			fmt.Fprint(rsp, `{}`)
+		case "/v3/kv/txn":
+			put := body["success"].([]interface{})[0].(map[string]interface{})["request_put"].(map[string]interface{})
+			if recv.holder == "" {
+				recv.holder = put["lease"].(string)
+				fmt.Fprint(rsp, `{"succeeded":true}`)
+			} else {
+				fmt.Fprintf(rsp, `{"responses":[{"response_range":{"kvs":[{"lease":"%v"}]}}]}`, recv.holder)
+			}
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
+
+func (recv *fakeLockServer) consulHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		require.Equal(t, http.MethodPut, req.Method)
+		switch {
+		case req.URL.Path == "/v1/session/create":
+			fmt.Fprintf(rsp, `{"ID":"%v"}`, recv.newOwner())
+		case strings.HasPrefix(req.URL.Path, "/v1/session/renew/"):
+			if !recv.owners[strings.TrimPrefix(req.URL.Path, "/v1/session/renew/")] {
+				http.NotFound(rsp, req)
+				return
+			}
+			fmt.Fprint(rsp, `[{}]`)
+		case strings.HasPrefix(req.URL.Path, "/v1/session/destroy/"):
+			recv.deleteOwner(strings.TrimPrefix(req.URL.Path, "/v1/session/destroy/"))
+			fmt.Fprint(rsp, "true")
+		case req.URL.Path == "/v1/kv/zdm/leader":
+			session := req.URL.Query().Get("acquire")
+			if recv.holder == "" && recv.owners[session] {
+				recv.holder = session
+			}
+			fmt.Fprint(rsp, recv.holder == session)
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
+
+// expireHolder emulates the expiration of the lease or session of the leader.
+func (recv *fakeLockServer) expireHolder() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.deleteOwner(recv.holder)
+}
+
+func TestLeaderElection(t *testing.T) {
+	require.True(t, (*leaderElection)(nil).isLeader())
+	require.Nil(t, newLeaderElection(&common.LeaderElectionConfig{}, &common.FleetStoreConfig{Type: fleetStoreTypeEtcd}, ""))
+
+	for _, storeType := range []string{fleetStoreTypeEtcd, fleetStoreTypeConsul} {
+		t.Run(storeType, func(t *testing.T) {
+			lockServer := newFakeLockServer()
+			handler := lockServer.etcdHandler(t)
+			if storeType == fleetStoreTypeConsul {
+				handler = lockServer.consulHandler(t)
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			fleetStoreConfig := &common.FleetStoreConfig{Type: storeType, Endpoints: []string{server.URL}, Key: "zdm/settings"}
+			leaderElectionConfig := &common.LeaderElectionConfig{Enabled: true, Key: "zdm/leader", TtlMs: 150}
+
+			wg := &sync.WaitGroup{}
+			defer wg.Wait()
+			elections := make([]*leaderElection, 2)
+			cancelFns := make([]context.CancelFunc, 2)
+			runningTasks := make([]int32, 2)
+			for i := range elections {
+				elections[i] = newLeaderElection(leaderElectionConfig, fleetStoreConfig, fmt.Sprintf("proxy%d", i))
+				running := &runningTasks[i]
+				elections[i].addSingletonTask("test", func(ctx context.Context) {
+					atomic.AddInt32(running, 1)
+					<-ctx.Done()
+					atomic.AddInt32(running, -1)
+				})
+				var ctx context.Context
+				ctx, cancelFns[i] = context.WithCancel(context.Background())
+				defer cancelFns[i]()
+				wg.Add(1)
+				go func(election *leaderElection) {
+					defer wg.Done()
+					election.run(ctx)
+				}(elections[i])
+			}
+
+			// exactly one instance is the leader and runs the task
+			var leader int
+			require.Eventually(t, func() bool {
+				return elections[0].isLeader() != elections[1].isLeader()
+			}, 5*time.Second, 10*time.Millisecond)
+			if elections[1].isLeader() {
+				leader = 1
+			}
+			follower := 1 - leader
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt32(&runningTasks[leader]) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+			time.Sleep(200 * time.Millisecond)
+			require.True(t, elections[leader].isLeader())
+			require.False(t, elections[follower].isLeader())
+			require.Equal(t, int32(0), atomic.LoadInt32(&runningTasks[follower]))
+
+			// the leader steps down when its lease or session expires
+			lockServer.expireHolder()
+			require.Eventually(t, func() bool {
+				return elections[0].isLeader() != elections[1].isLeader()
+			}, 5*time.Second, 10*time.Millisecond)
+
+			// the leadership is released on shutdown
+			for i := range elections {
+				if elections[i].isLeader() {
+					leader = i
+				}
+			}
+			follower = 1 - leader
+			cancelFns[leader]()
+			require.Eventually(t, func() bool {
+				return elections[follower].isLeader() && atomic.LoadInt32(&runningTasks[follower]) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+			require.Eventually(t, func() bool {
+				return !elections[leader].isLeader() && atomic.LoadInt32(&runningTasks[leader]) == 0
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
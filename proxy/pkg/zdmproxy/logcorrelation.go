@@ -0,0 +1,47 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	logFieldConnectionId = "connection_id"
+	logFieldStreamId     = "stream_id"
+	logFieldRequestId    = "request_id"
+)
+
+// lastConnectionId is the correlation id of the last client connection that was accepted by the process.
+var lastConnectionId uint64
+
+func newConnectionId() uint64 {
+	return atomic.AddUint64(&lastConnectionId, 1)
+}
+
+// newConnectionLogEntry returns the entry of the logs of a client connection, the logs of the client handler, of the
+// client connector and of the cluster connectors of the connection have the same connection_id.
+func newConnectionLogEntry(connectionId uint64) *log.Entry {
+	return log.WithField(logFieldConnectionId, connectionId)
+}
+
+// withRequestFields adds the correlation id of a request, i.e. the connection id and the stream id of the client
+// request, to the entry. The stream ids are reused after the responses are sent so a request id only identifies a
+// request while it is in flight.
+func withRequestFields(entry *log.Entry, connectionId uint64, streamId int16) *log.Entry {
+	return entry.WithFields(log.Fields{
+		logFieldStreamId:  streamId,
+		logFieldRequestId: fmt.Sprintf("%v-%v", connectionId, streamId),
+	})
+}
+
+// NewLogFormatter returns the formatter of the logs for ZDM_LOG_FORMAT, the JSON logs have the time, level and msg
+// keys and a key for each field.
+func NewLogFormatter(format common.LogFormat) log.Formatter {
+	if format == common.LogFormatJson {
+		return &log.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	}
+	return &log.TextFormatter{}
+}
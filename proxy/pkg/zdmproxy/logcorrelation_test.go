@@ -0,0 +1,40 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestLogCorrelation(t *testing.T) {
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFormatter(log.StandardLogger().Formatter)
+	log.SetFormatter(NewLogFormatter(common.LogFormatJson))
+
+	connectionId := newConnectionId()
+	require.Equal(t, connectionId+1, newConnectionId())
+
+	connector := &ClusterConnector{connectionId: connectionId, logEntry: newConnectionLogEntry(connectionId)}
+	connector.getRequestLogger(12).Warnf("request %v", "failed")
+
+	var entry map[string]interface{}
+	require.Nil(t, json.Unmarshal(logs.Bytes(), &entry))
+	require.Equal(t, "request failed", entry["msg"])
+	require.Equal(t, "warning", entry["level"])
+	require.Equal(t, float64(connectionId), entry[logFieldConnectionId])
+	require.Equal(t, float64(12), entry[logFieldStreamId])
+	require.Equal(t, fmt.Sprintf("%v-12", connectionId), entry[logFieldRequestId])
+	require.Contains(t, entry, "time")
+	logs.Reset()
+
+	log.SetFormatter(NewLogFormatter(common.LogFormatText))
+	connector.getLogger().Warnf("connector")
+	require.Contains(t, logs.String(), "level=warning msg=connector connection_id=")
+}
@@ -0,0 +1,261 @@
+package zdmproxy
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// debugLoggingDefaultDuration is how long the debug logs of a module or of a client are enabled if the admin API
+// request doesn't have a duration
+const debugLoggingDefaultDuration = 15 * time.Minute
+
+// LogModule is a component of the proxy whose debug logs can be enabled without changing the global log level.
+type LogModule string
+
+const (
+	LogModuleClientHandler     = LogModule("client_handler")
+	LogModuleClusterConnector  = LogModule("cluster_connector")
+	LogModuleParser            = LogModule("parser")
+	LogModuleControlConnection = LogModule("control_connection")
+)
+
+var logModules = []LogModule{
+	LogModuleClientHandler, LogModuleClusterConnector, LogModuleParser, LogModuleControlConnection}
+
+// logLevels is shared by every proxy of the process like the logrus standard logger.
+var logLevels = newLogLevelController()
+
+// LogLevelController changes the level of the logrus standard logger at runtime and enables the debug logs of some
+// modules or of the connections of some clients for a limited time, enabling the debug logs globally is too noisy
+// in production. The scoped debug logs are written with a copy of the standard logger (same output, formatter and
+// hooks) whose level is DEBUG.
+type LogLevelController struct {
+	lock        *sync.Mutex
+	active      int32                   // number of modules and clients, it is read without holding the lock
+	modules     map[LogModule]time.Time // expiration keyed by module
+	clients     map[string]time.Time    // expiration keyed by client IP or address (IP:port)
+	debugLogger *log.Logger
+	now         func() time.Time
+}
+
+// LogLevelStatus is the state of the logging that is reported by the admin API.
+type LogLevelStatus struct {
+	Level   string
+	Modules []*DebugLoggingScope
+	Clients []*DebugLoggingScope
+}
+
+// DebugLoggingScope is a module or a client whose debug logs are enabled.
+type DebugLoggingScope struct {
+	Name      string
+	ExpiresAt time.Time
+}
+
+func newLogLevelController() *LogLevelController {
+	return &LogLevelController{
+		lock:    &sync.Mutex{},
+		modules: make(map[LogModule]time.Time),
+		clients: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// SetLevel changes the level of the standard logger, it is also changed when ZDM_LOG_LEVEL is reloaded.
+func (recv *LogLevelController) SetLevel(level log.Level) *LogLevelStatus {
+	log.SetLevel(level)
+	return recv.GetStatus()
+}
+
+// ToggleDebugLevel sets the level of the standard logger to DEBUG or, if DEBUG is already enabled, back to the
+// provided level (INFO if the provided level also enables DEBUG). It returns the new level.
+func (recv *LogLevelController) ToggleDebugLevel(configuredLevel log.Level) log.Level {
+	level := log.DebugLevel
+	if log.IsLevelEnabled(log.DebugLevel) {
+		level = configuredLevel
+		if level >= log.DebugLevel {
+			level = log.InfoLevel
+		}
+	}
+	log.SetLevel(level)
+	return level
+}
+
+// EnableDebug enables the debug logs of the provided modules and of the connections of the provided clients (IPs or
+// IP:port addresses) for the provided duration, debugLoggingDefaultDuration if 0.
+func (recv *LogLevelController) EnableDebug(
+	modules []string, clients []string, duration time.Duration) (*LogLevelStatus, error) {
+	if duration == 0 {
+		duration = debugLoggingDefaultDuration
+	} else if duration < 0 {
+		return nil, fmt.Errorf("debug logging duration must be positive but was %v", duration)
+	}
+	parsedModules, err := parseLogModules(modules)
+	if err != nil {
+		return nil, err
+	}
+	normalizedClients, err := normalizeClientAddresses(clients)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsedModules) == 0 && len(normalizedClients) == 0 {
+		return nil, fmt.Errorf("at least one module or client is required")
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	expiration := recv.now().Add(duration)
+	for _, module := range parsedModules {
+		recv.modules[module] = expiration
+	}
+	for _, client := range normalizedClients {
+		recv.clients[client] = expiration
+	}
+	recv.debugLogger = newDebugLogger()
+	return recv.getStatusLocked(), nil
+}
+
+// DisableDebug disables the debug logs of the provided modules and clients, every module and client if both are
+// empty.
+func (recv *LogLevelController) DisableDebug(modules []string, clients []string) (*LogLevelStatus, error) {
+	parsedModules, err := parseLogModules(modules)
+	if err != nil {
+		return nil, err
+	}
+	normalizedClients, err := normalizeClientAddresses(clients)
+	if err != nil {
+		return nil, err
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if len(parsedModules) == 0 && len(normalizedClients) == 0 {
+		recv.modules = make(map[LogModule]time.Time)
+		recv.clients = make(map[string]time.Time)
+	}
+	for _, module := range parsedModules {
+		delete(recv.modules, module)
+	}
+	for _, client := range normalizedClients {
+		delete(recv.clients, client)
+	}
+	return recv.getStatusLocked(), nil
+}
+
+func (recv *LogLevelController) GetStatus() *LogLevelStatus {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.getStatusLocked()
+}
+
+// getStatusLocked also removes the modules and the clients that expired.
+func (recv *LogLevelController) getStatusLocked() *LogLevelStatus {
+	now := recv.now()
+	status := &LogLevelStatus{
+		Level:   log.GetLevel().String(),
+		Modules: make([]*DebugLoggingScope, 0, len(recv.modules)),
+		Clients: make([]*DebugLoggingScope, 0, len(recv.clients)),
+	}
+	for module, expiration := range recv.modules {
+		if !now.Before(expiration) {
+			delete(recv.modules, module)
+			continue
+		}
+		status.Modules = append(status.Modules, &DebugLoggingScope{Name: string(module), ExpiresAt: expiration})
+	}
+	for client, expiration := range recv.clients {
+		if !now.Before(expiration) {
+			delete(recv.clients, client)
+			continue
+		}
+		status.Clients = append(status.Clients, &DebugLoggingScope{Name: client, ExpiresAt: expiration})
+	}
+	for _, scopes := range [][]*DebugLoggingScope{status.Modules, status.Clients} {
+		sort.Slice(scopes, func(i, j int) bool {
+			return scopes[i].Name < scopes[j].Name
+		})
+	}
+	atomic.StoreInt32(&recv.active, int32(len(recv.modules)+len(recv.clients)))
+	return status
+}
+
+// getLogger returns the provided entry or a copy of it that writes the debug logs if they are enabled for the module
+// or for the client, clientAddr is nil if the logs are not related to a client connection.
+func (recv *LogLevelController) getLogger(entry *log.Entry, module LogModule, clientAddr net.Addr) *log.Entry {
+	if atomic.LoadInt32(&recv.active) == 0 || log.IsLevelEnabled(log.DebugLevel) {
+		return entry
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	now := recv.now()
+	enabled := false
+	if expiration, ok := recv.modules[module]; ok && now.Before(expiration) {
+		enabled = true
+	} else if clientAddr != nil {
+		client := tcpAddrOf(clientAddr)
+		for _, key := range []string{client.String(), client.IP.String()} {
+			if expiration, ok := recv.clients[key]; ok && now.Before(expiration) {
+				enabled = true
+				break
+			}
+		}
+	}
+	if !enabled {
+		return entry
+	}
+	debugEntry := *entry
+	debugEntry.Logger = recv.debugLogger
+	return &debugEntry
+}
+
+// newDebugLogger returns a copy of the standard logger whose level is DEBUG.
+func newDebugLogger() *log.Logger {
+	standardLogger := log.StandardLogger()
+	debugLogger := log.New()
+	debugLogger.SetOutput(standardLogger.Out)
+	debugLogger.SetFormatter(standardLogger.Formatter)
+	debugLogger.SetReportCaller(standardLogger.ReportCaller)
+	debugLogger.ReplaceHooks(standardLogger.Hooks)
+	debugLogger.SetLevel(log.DebugLevel)
+	return debugLogger
+}
+
+func parseLogModules(modules []string) ([]LogModule, error) {
+	var parsedModules []LogModule
+	for _, module := range modules {
+		module = strings.ToLower(strings.TrimSpace(module))
+		if module == "" {
+			continue
+		}
+		found := false
+		for _, logModule := range logModules {
+			if module == string(logModule) {
+				parsedModules = append(parsedModules, logModule)
+				found = true
+				break
+			}
+		}
+		if !found {
+			validModules := make([]string, 0, len(logModules))
+			for _, logModule := range logModules {
+				validModules = append(validModules, string(logModule))
+			}
+			return nil, fmt.Errorf("invalid module %v, it must be one of %v", module, strings.Join(validModules, ", "))
+		}
+	}
+	return parsedModules, nil
+}
+
+var standardLogEntry = log.NewEntry(log.StandardLogger())
+
+// moduleLogger returns the logger of the debug and trace logs of a module that are not related to a client
+// connection.
+func moduleLogger(module LogModule) *log.Entry {
+	return logLevels.getLogger(standardLogEntry, module, nil)
+}
@@ -0,0 +1,82 @@
+package zdmproxy
+
+import (
+	"bytes"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLogLevelController(t *testing.T) {
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.InfoLevel)
+
+	now := time.Now()
+	controller := newLogLevelController()
+	controller.now = func() time.Time { return now }
+
+	_, err := controller.EnableDebug(nil, nil, time.Minute)
+	require.Equal(t, "at least one module or client is required", err.Error())
+	_, err = controller.EnableDebug([]string{"coalescer"}, nil, time.Minute)
+	require.Equal(t, "invalid module coalescer, it must be one of "+
+		"client_handler, cluster_connector, parser, control_connection", err.Error())
+	_, err = controller.EnableDebug(nil, []string{"proxy1"}, time.Minute)
+	require.Equal(t, "invalid client proxy1, it must be an IP or an IP:port address", err.Error())
+	_, err = controller.EnableDebug([]string{"parser"}, nil, -time.Minute)
+	require.Equal(t, "debug logging duration must be positive but was -1m0s", err.Error())
+
+	status, err := controller.EnableDebug([]string{"Parser "}, []string{"127.0.0.1"}, 0)
+	require.Nil(t, err)
+	require.Equal(t, &LogLevelStatus{
+		Level:   "info",
+		Modules: []*DebugLoggingScope{{Name: "parser", ExpiresAt: now.Add(debugLoggingDefaultDuration)}},
+		Clients: []*DebugLoggingScope{{Name: "127.0.0.1", ExpiresAt: now.Add(debugLoggingDefaultDuration)}},
+	}, status)
+
+	client := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 6000}
+	otherClient := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5001}
+	entry := log.WithField("client", "app")
+
+	controller.getLogger(standardLogEntry, LogModuleClusterConnector, otherClient).Debugf("cluster connector")
+	controller.getLogger(entry, LogModuleClientHandler, otherClient).Debugf("other client")
+	require.Empty(t, logs.String())
+
+	controller.getLogger(standardLogEntry, LogModuleParser, nil).Debugf("parser")
+	require.Contains(t, logs.String(), "level=debug msg=parser")
+	logs.Reset()
+	controller.getLogger(entry, LogModuleClientHandler, client).Debugf("client")
+	require.Contains(t, logs.String(), "level=debug msg=client client=app")
+	logs.Reset()
+
+	status, err = controller.DisableDebug([]string{"parser"}, nil)
+	require.Nil(t, err)
+	require.Empty(t, status.Modules)
+	require.Len(t, status.Clients, 1)
+	controller.getLogger(standardLogEntry, LogModuleParser, nil).Debugf("parser")
+	require.Empty(t, logs.String())
+
+	now = now.Add(debugLoggingDefaultDuration)
+	controller.getLogger(entry, LogModuleClientHandler, client).Debugf("client")
+	require.Empty(t, logs.String())
+	require.Empty(t, controller.GetStatus().Clients)
+
+	_, err = controller.EnableDebug([]string{"cluster_connector"}, []string{"10.0.0.1"}, time.Minute)
+	require.Nil(t, err)
+	status, err = controller.DisableDebug(nil, nil)
+	require.Nil(t, err)
+	require.Empty(t, status.Modules)
+	require.Empty(t, status.Clients)
+
+	require.Equal(t, "warning", controller.SetLevel(log.WarnLevel).Level)
+	require.Equal(t, log.DebugLevel, controller.ToggleDebugLevel(log.InfoLevel))
+	require.Equal(t, log.DebugLevel, log.GetLevel())
+	require.Equal(t, log.InfoLevel, controller.ToggleDebugLevel(log.InfoLevel))
+	require.Equal(t, log.DebugLevel, controller.ToggleDebugLevel(log.DebugLevel))
+	require.Equal(t, log.InfoLevel, controller.ToggleDebugLevel(log.DebugLevel))
+}
@@ -0,0 +1,44 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+)
+
+// isLightweightTransactionRequest returns true if the request is a lightweight transaction (see ZDM_LWT_POLICY).
+// A BATCH is a lightweight transaction if at least one of its child statements is a conditional write.
+func isLightweightTransactionRequest(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (bool, error) {
+	switch typedRequestInfo := requestInfo.(type) {
+	case *BatchRequestInfo:
+		for _, preparedData := range typedRequestInfo.GetPreparedDataByStmtIdx() {
+			queryInfo := preparedData.GetPrepareRequestInfo().GetQueryInfo()
+			if queryInfo != nil && queryInfo.isLightweightTransaction() {
+				return true, nil
+			}
+		}
+		stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		for _, stmtQueryData := range stmtsQueryData {
+			if stmtQueryData.queryData.isLightweightTransaction() {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *ExecuteRequestInfo:
+		queryInfo := typedRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetQueryInfo()
+		return queryInfo != nil && queryInfo.isLightweightTransaction(), nil
+	case *GenericRequestInfo:
+		if frameContext.GetRawFrame().Header.OpCode != primitive.OpCodeQuery {
+			return false, nil
+		}
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		return stmtQueryData.queryData.isLightweightTransaction(), nil
+	}
+	return false, nil
+}
@@ -0,0 +1,66 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsLightweightTransactionRequest(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newPreparedData := func(query string) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, query, "")
+		prepareRequestInfo.queryInfo = inspectCqlQuery(query, "", generator)
+		return &preparedDataImpl{prepareRequestInfo: prepareRequestInfo}
+	}
+	newQuery := func(query string) message.Message {
+		return &message.Query{Query: query}
+	}
+	genericRequestInfo := NewGenericRequestInfo(forwardToBoth, false, true)
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    bool
+	}{
+		{"insert", newQuery("INSERT INTO ks.tb (a) VALUES (1)"), genericRequestInfo, false},
+		{"insert if not exists", newQuery("INSERT INTO ks.tb (a) VALUES (1) IF NOT EXISTS"), genericRequestInfo, true},
+		{"insert if not exists with ttl", newQuery("insert into ks.tb (a) values (1) if not exists using ttl 10"), genericRequestInfo, true},
+		{"update", newQuery("UPDATE ks.tb SET b = 1 WHERE a = 1"), genericRequestInfo, false},
+		{"update if exists", newQuery("UPDATE ks.tb SET b = 1 WHERE a = 1 IF EXISTS"), genericRequestInfo, true},
+		{"update if condition", newQuery("UPDATE ks.tb SET b = 2 WHERE a = 1 IF b = 1"), genericRequestInfo, true},
+		{"delete", newQuery("DELETE FROM ks.tb WHERE a = 1"), genericRequestInfo, false},
+		{"delete if condition", newQuery("DELETE FROM ks.tb WHERE a = 1 IF b = ?"), genericRequestInfo, true},
+		{"select", newQuery("SELECT * FROM ks.tb WHERE a = 1"), genericRequestInfo, false},
+		{"execute insert if not exists", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("INSERT INTO ks.tb (a) VALUES (?) IF NOT EXISTS")), true},
+		{"execute insert", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("INSERT INTO ks.tb (a) VALUES (?)")), false},
+		{"batch without conditions", &message.Batch{Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+			{QueryOrId: "UPDATE ks.tb SET b = 1 WHERE a = 2"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), false},
+		{"batch with conditional child", &message.Batch{Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+			{QueryOrId: "UPDATE ks.tb SET b = 1 WHERE a = 1 IF b = 0"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), true},
+		{"batch with conditional prepared child", &message.Batch{Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+			{QueryOrId: []byte("ID")}}},
+			NewBatchRequestInfo(map[int]PreparedData{
+				1: newPreparedData("INSERT INTO ks.tb (a) VALUES (?) IF NOT EXISTS")}, forwardToBoth), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			isLwt, err := isLightweightTransactionRequest(NewFrameDecodeContext(f), tt.requestInfo, "", generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, isLwt)
+		})
+	}
+}
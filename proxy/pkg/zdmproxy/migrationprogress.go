@@ -0,0 +1,167 @@
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"sync"
+	"time"
+)
+
+// migrationProgress estimates the progress of the migration from the requests of a sliding window
+// (ZDM_MIGRATION_PROGRESS_ENABLED): the fraction of the reads that are still answered by ORIGIN, the fraction of the
+// dual writes that failed on at least one cluster, the fraction of the read comparisons (ZDM_READ_MIRRORING_ENABLED)
+// that found a mismatch and the number of writes of the write journal that are waiting to be replayed.
+//
+// The requests are counted in the current slot of the window, the estimation is computed every SampleIntervalMs and
+// then the window slides by one slot so the gauges and the admin API don't compute anything.
+type migrationProgress struct {
+	conf         *common.MigrationProgressConfig
+	writeJournal *writeJournal // nil if ZDM_SECONDARY_WRITE_JOURNAL_TYPE is NONE
+
+	window *slidingWindow
+
+	lock   *sync.Mutex
+	status *MigrationProgressStatus
+}
+
+// counters of the sliding window of the migration progress estimation
+const (
+	migrationProgressOriginReads = iota
+	migrationProgressTargetReads
+	migrationProgressDualWrites
+	migrationProgressDualWriteFailures
+	migrationProgressReadComparisons
+	migrationProgressReadMismatches
+	migrationProgressCounters
+)
+
+// MigrationProgressStatus is the migration progress estimation that is reported by the admin API, the ratios are 0
+// if there were no requests of their kind in the window.
+type MigrationProgressStatus struct {
+	SampledAt time.Time
+	WindowMs  int
+
+	OriginReads      int64
+	TargetReads      int64
+	OriginReadsRatio float64
+
+	DualWrites             int64
+	DualWriteFailures      int64
+	DualWriteFailuresRatio float64
+
+	ReadComparisons     int64
+	ReadMismatches      int64
+	ReadMismatchesRatio float64
+
+	ReplayQueueDepth int
+}
+
+// newMigrationProgress returns nil if the migration progress estimation is disabled.
+func newMigrationProgress(conf *common.MigrationProgressConfig, writeJournal *writeJournal) *migrationProgress {
+	if !conf.Enabled {
+		return nil
+	}
+	slots := (conf.WindowMs + conf.SampleIntervalMs - 1) / conf.SampleIntervalMs
+	return &migrationProgress{
+		conf:         conf,
+		writeJournal: writeJournal,
+		window:       newSlidingWindow(slots, migrationProgressCounters),
+		lock:         &sync.Mutex{},
+		status:       &MigrationProgressStatus{SampledAt: time.Now(), WindowMs: conf.WindowMs},
+	}
+}
+
+// recordRead counts a read that was answered by the provided cluster.
+func (recv *migrationProgress) recordRead(cluster common.ClusterType) {
+	if recv == nil {
+		return
+	}
+	if cluster == common.ClusterTypeTarget {
+		recv.window.add(migrationProgressTargetReads, 1)
+	} else {
+		recv.window.add(migrationProgressOriginReads, 1)
+	}
+}
+
+// recordDualWrite counts a write that was forwarded to both clusters.
+func (recv *migrationProgress) recordDualWrite() {
+	if recv == nil {
+		return
+	}
+	recv.window.add(migrationProgressDualWrites, 1)
+}
+
+// recordDualWriteFailure counts a write that was forwarded to both clusters and failed on at least one of them.
+func (recv *migrationProgress) recordDualWriteFailure() {
+	if recv == nil {
+		return
+	}
+	recv.window.add(migrationProgressDualWriteFailures, 1)
+}
+
+func (recv *migrationProgress) recordReadComparison(mismatch bool) {
+	if recv == nil {
+		return
+	}
+	recv.window.add(migrationProgressReadComparisons, 1)
+	if mismatch {
+		recv.window.add(migrationProgressReadMismatches, 1)
+	}
+}
+
+// run samples the window every SampleIntervalMs until the context is canceled.
+func (recv *migrationProgress) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(recv.conf.SampleIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recv.sample()
+			recv.window.slide()
+		}
+	}
+}
+
+// sample computes the estimation from the requests of every slot of the window.
+func (recv *migrationProgress) sample() {
+	sums := recv.window.sums()
+	status := &MigrationProgressStatus{
+		SampledAt:         time.Now(),
+		WindowMs:          recv.conf.WindowMs,
+		OriginReads:       sums[migrationProgressOriginReads],
+		TargetReads:       sums[migrationProgressTargetReads],
+		DualWrites:        sums[migrationProgressDualWrites],
+		DualWriteFailures: sums[migrationProgressDualWriteFailures],
+		ReadComparisons:   sums[migrationProgressReadComparisons],
+		ReadMismatches:    sums[migrationProgressReadMismatches],
+		ReplayQueueDepth:  recv.writeJournal.pendingWrites(),
+	}
+	status.OriginReadsRatio = migrationProgressRatio(status.OriginReads, status.OriginReads+status.TargetReads)
+	status.DualWriteFailuresRatio = migrationProgressRatio(status.DualWriteFailures, status.DualWrites)
+	status.ReadMismatchesRatio = migrationProgressRatio(status.ReadMismatches, status.ReadComparisons)
+
+	recv.lock.Lock()
+	recv.status = status
+	recv.lock.Unlock()
+}
+
+// getStatus returns the estimation of the last sample.
+func (recv *migrationProgress) getStatus() *MigrationProgressStatus {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.status
+}
+
+// migrationProgressRatio returns 0 if the total is 0, a request and its failure can be counted in different slots
+// when the window slides so the ratio is capped at 1.
+func migrationProgressRatio(count int64, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	if count >= total {
+		return 1
+	}
+	return float64(count) / float64(total)
+}
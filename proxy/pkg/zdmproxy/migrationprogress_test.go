@@ -0,0 +1,73 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMigrationProgress(t *testing.T) {
+	require.Nil(t, newMigrationProgress(&common.MigrationProgressConfig{}, nil))
+	var disabledProgress *migrationProgress
+	disabledProgress.recordRead(common.ClusterTypeOrigin)
+	disabledProgress.recordDualWrite()
+	disabledProgress.recordDualWriteFailure()
+	disabledProgress.recordReadComparison(true)
+
+	// 3 slots
+	progress := newMigrationProgress(&common.MigrationProgressConfig{
+		Enabled: true, SampleIntervalMs: 100, WindowMs: 250}, nil)
+	require.Equal(t, 3, progress.window.getSlots())
+	require.Equal(t, &MigrationProgressStatus{SampledAt: progress.getStatus().SampledAt, WindowMs: 250},
+		progress.getStatus())
+
+	for i := 0; i < 3; i++ {
+		progress.recordRead(common.ClusterTypeOrigin)
+	}
+	progress.recordRead(common.ClusterTypeTarget)
+	for i := 0; i < 4; i++ {
+		progress.recordDualWrite()
+	}
+	progress.recordDualWriteFailure()
+	progress.recordReadComparison(false)
+	progress.recordReadComparison(true)
+	progress.sample()
+	progress.window.slide()
+	status := progress.getStatus()
+	require.Equal(t, int64(3), status.OriginReads)
+	require.Equal(t, int64(1), status.TargetReads)
+	require.Equal(t, 0.75, status.OriginReadsRatio)
+	require.Equal(t, int64(4), status.DualWrites)
+	require.Equal(t, int64(1), status.DualWriteFailures)
+	require.Equal(t, 0.25, status.DualWriteFailuresRatio)
+	require.Equal(t, int64(2), status.ReadComparisons)
+	require.Equal(t, int64(1), status.ReadMismatches)
+	require.Equal(t, 0.5, status.ReadMismatchesRatio)
+	require.Equal(t, 0, status.ReplayQueueDepth)
+
+	// the requests of the first slot are still in the window
+	progress.recordRead(common.ClusterTypeTarget)
+	progress.sample()
+	progress.window.slide()
+	require.Equal(t, 0.6, progress.getStatus().OriginReadsRatio)
+
+	// the first slot is cleared when the window slides over it
+	progress.sample()
+	progress.window.slide()
+	require.Equal(t, 0.6, progress.getStatus().OriginReadsRatio)
+	progress.sample()
+	progress.window.slide()
+	status = progress.getStatus()
+	require.Equal(t, int64(0), status.OriginReads)
+	require.Equal(t, int64(1), status.TargetReads)
+	require.Equal(t, float64(0), status.OriginReadsRatio)
+	require.Equal(t, float64(0), status.DualWriteFailuresRatio)
+	require.Equal(t, float64(0), status.ReadMismatchesRatio)
+
+	// the failures and the writes can be counted in different slots
+	progress.recordDualWrite()
+	progress.recordDualWriteFailure()
+	progress.recordDualWriteFailure()
+	progress.sample()
+	require.Equal(t, float64(1), progress.getStatus().DualWriteFailuresRatio)
+}
@@ -0,0 +1,176 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+// Paging states (ZDM_PAGING_STATE_POLICY): the paging state of a ROWS result can only be used by the cluster that
+// returned it so the next page of a paged read fails if the reads were forwarded to the other cluster in the meantime
+// (e.g. after a ZDM_PRIMARY_CLUSTER reload). The paging states of the reads are tagged with the cluster that returned
+// them before the results are sent to the client and the tag is removed from the QUERY and EXECUTE requests before
+// they are forwarded, the paging states without a tag (e.g. returned before the policy was enabled) are forwarded
+// untouched.
+//
+// A tagged paging state is pagingStateTag followed by the cluster byte and the paging state of the cluster.
+
+var pagingStateTag = []byte{0x00, 'Z', 'D', 'M', 'P', 'S'}
+
+const (
+	pagingStateClusterOrigin = byte('O')
+	pagingStateClusterTarget = byte('T')
+)
+
+// tagPagingState returns the paging state with the tag of the cluster that returned it.
+func tagPagingState(pagingState []byte, cluster common.ClusterType) []byte {
+	clusterByte := pagingStateClusterOrigin
+	if cluster == common.ClusterTypeTarget {
+		clusterByte = pagingStateClusterTarget
+	}
+	tagged := make([]byte, 0, len(pagingStateTag)+1+len(pagingState))
+	tagged = append(tagged, pagingStateTag...)
+	tagged = append(tagged, clusterByte)
+	return append(tagged, pagingState...)
+}
+
+// untagPagingState returns the paging state of the cluster and the cluster that returned it, false if the paging
+// state is not tagged.
+func untagPagingState(pagingState []byte) ([]byte, common.ClusterType, bool) {
+	if len(pagingState) < len(pagingStateTag)+1 || !bytes.HasPrefix(pagingState, pagingStateTag) {
+		return nil, common.ClusterTypeNone, false
+	}
+	switch pagingState[len(pagingStateTag)] {
+	case pagingStateClusterOrigin:
+		return pagingState[len(pagingStateTag)+1:], common.ClusterTypeOrigin, true
+	case pagingStateClusterTarget:
+		return pagingState[len(pagingStateTag)+1:], common.ClusterTypeTarget, true
+	}
+	return nil, common.ClusterTypeNone, false
+}
+
+// rowsPagingStateOffset returns the offset of the [bytes] paging state in the raw body of a ROWS result, false if the
+// response is not a ROWS result with more pages. The pages of the DSE continuous paging requests are ignored because
+// they are always forwarded to the primary cluster.
+func rowsPagingStateOffset(f *frame.RawFrame) (int, bool) {
+	if f.Header.OpCode != primitive.OpCodeResult {
+		return 0, false
+	}
+	offset, ok := peekMessageOffset(f)
+	// [int] kind, [int] flags and [int] column count
+	if !ok || offset+12 > len(f.Body) {
+		return 0, false
+	}
+	body := f.Body
+	if primitive.ResultType(binary.BigEndian.Uint32(body[offset:])) != primitive.ResultTypeRows {
+		return 0, false
+	}
+	flags := primitive.RowsFlag(binary.BigEndian.Uint32(body[offset+4:]))
+	if !flags.Contains(primitive.RowsFlagHasMorePages) ||
+		(f.Header.Version.IsDse() && flags.Contains(primitive.RowsFlagDseContinuousPaging)) {
+		return 0, false
+	}
+	offset += 12
+	if offset+4 > len(body) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// tagResponsePagingState returns the ROWS result with the paging state tagged with the cluster that returned it or
+// the same response if it has no paging state (or if it is compressed).
+func tagResponsePagingState(response *frame.RawFrame, cluster common.ClusterType) *frame.RawFrame {
+	offset, ok := rowsPagingStateOffset(response)
+	if !ok {
+		return response
+	}
+	body := response.Body
+	length := int(int32(binary.BigEndian.Uint32(body[offset:])))
+	if length < 0 || offset+4+length > len(body) {
+		return response
+	}
+	tagged := tagPagingState(body[offset+4:offset+4+length], cluster)
+
+	newBody := make([]byte, 0, len(body)+len(tagged)-length)
+	newBody = append(newBody, body[:offset]...)
+	var taggedLength [4]byte
+	binary.BigEndian.PutUint32(taggedLength[:], uint32(len(tagged)))
+	newBody = append(newBody, taggedLength[:]...)
+	newBody = append(newBody, tagged...)
+	newBody = append(newBody, body[offset+4+length:]...)
+
+	header := response.Header.Clone()
+	header.BodyLength = int32(len(newBody))
+	return &frame.RawFrame{Header: header, Body: newBody}
+}
+
+// applyPagingStatePolicy removes the tag of the paging state of a read and forwards the read to the cluster that
+// returned the paging state (ORIGINATING_CLUSTER) or returns the error response that should be sent to the client
+// (REJECT) if the read would be forwarded to the other cluster. The frame decode context of the request without the
+// tag is returned if the paging state was tagged.
+func (ch *ClientHandler) applyPagingStatePolicy(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, explanation *requestExplanation) (
+	RequestInfo, *frameDecodeContext, *frame.RawFrame, error) {
+	f := frameContext.GetRawFrame()
+	if f.Header.OpCode != primitive.OpCodeQuery && f.Header.OpCode != primitive.OpCodeExecute {
+		return requestInfo, frameContext, nil, nil
+	}
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not check the paging state of the request: %w", err)
+	}
+	var options *message.QueryOptions
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		options = msg.Options
+	case *message.Execute:
+		options = msg.Options
+	}
+	if options == nil {
+		return requestInfo, frameContext, nil, nil
+	}
+	pagingState, cluster, ok := untagPagingState(options.PagingState)
+	if !ok {
+		return requestInfo, frameContext, nil, nil
+	}
+
+	newFrame := decodedFrame.Clone()
+	switch msg := newFrame.Body.Message.(type) {
+	case *message.Query:
+		msg.Options.PagingState = pagingState
+	case *message.Execute:
+		msg.Options.PagingState = pagingState
+	}
+	newRawFrame, err := defaultCodec.ConvertToRawFrame(newFrame)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not convert request without paging state tag to raw frame: %w", err)
+	}
+	newFrameContext := NewInitializedFrameDecodeContext(newRawFrame, newFrame, frameContext.statementsQueryData)
+
+	decision := getReadRoutingForwardDecision(cluster)
+	if decision == requestInfo.GetForwardDecision() {
+		return requestInfo, newFrameContext, nil, nil
+	}
+
+	ch.metricHandler.GetProxyMetrics().CrossClusterPagingStates.Add(1)
+	explanation.addPagingStatePolicy(ch.pagingStatePolicy, cluster)
+	if ch.pagingStatePolicy == common.PagingStatePolicyReject {
+		ch.getLogger().Debugf("Rejecting read with a paging state of %v because it would be forwarded to %v.",
+			cluster, requestInfo.GetForwardDecision())
+		clientResponse, err := ch.handleRejectedRequest(NewRejectedRequestInfo(fmt.Sprintf(
+			"Paging state rejected by ZDM proxy because it was returned by %v but the read is now forwarded to "+
+				"another cluster (ZDM_PAGING_STATE_POLICY is %v), restart the query without a paging state",
+			cluster, ch.pagingStatePolicy)), frameContext)
+		return requestInfo, frameContext, clientResponse, err
+	}
+
+	ch.getLogger().Tracef("Read forwarded to %v because its paging state was returned by %v.", cluster, cluster)
+	if executeRequestInfo, ok := requestInfo.(*ExecuteRequestInfo); ok {
+		return NewRoutedReadExecuteRequestInfo(executeRequestInfo.GetPreparedData(), decision), newFrameContext, nil, nil
+	}
+	return NewGenericRequestInfo(decision, false, true), newFrameContext, nil, nil
+}
@@ -0,0 +1,142 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newRowsFrame(version primitive.ProtocolVersion, pagingState []byte) *frame.Frame {
+	return frame.NewFrame(version, 1, &message.RowsResult{
+		Metadata: &message.RowsMetadata{
+			ColumnCount: 1,
+			Columns:     []*message.ColumnMetadata{{Keyspace: "ks1", Table: "tb1", Name: "v", Type: datatype.Int}},
+			PagingState: pagingState,
+		},
+		Data: message.RowSet{{{0, 0, 0, 1}}, {{0, 0, 0, 2}}},
+	})
+}
+
+func TestTagPagingState(t *testing.T) {
+	tagged := tagPagingState([]byte("paging-state"), common.ClusterTypeTarget)
+	pagingState, cluster, ok := untagPagingState(tagged)
+	require.True(t, ok)
+	require.Equal(t, []byte("paging-state"), pagingState)
+	require.Equal(t, common.ClusterTypeTarget, cluster)
+
+	pagingState, cluster, ok = untagPagingState(tagPagingState([]byte{}, common.ClusterTypeOrigin))
+	require.True(t, ok)
+	require.Empty(t, pagingState)
+	require.Equal(t, common.ClusterTypeOrigin, cluster)
+
+	_, _, ok = untagPagingState([]byte("paging-state"))
+	require.False(t, ok)
+	_, _, ok = untagPagingState(nil)
+	require.False(t, ok)
+	_, _, ok = untagPagingState(append(append([]byte{}, pagingStateTag...), 'X', 1, 2))
+	require.False(t, ok)
+}
+
+func TestTagResponsePagingState(t *testing.T) {
+	convert := func(f *frame.Frame) *frame.RawFrame {
+		rawFrame, err := defaultCodec.ConvertToRawFrame(f)
+		require.Nil(t, err)
+		return rawFrame
+	}
+	decode := func(f *frame.RawFrame) *message.RowsResult {
+		decodedFrame, err := defaultCodec.ConvertFromRawFrame(f)
+		require.Nil(t, err)
+		return decodedFrame.Body.Message.(*message.RowsResult)
+	}
+
+	for _, version := range []primitive.ProtocolVersion{
+		primitive.ProtocolVersion3, primitive.ProtocolVersion4, primitive.ProtocolVersion5} {
+		t.Run(version.String(), func(t *testing.T) {
+			f := newRowsFrame(version, []byte("paging-state"))
+			if version >= primitive.ProtocolVersion4 {
+				f.SetTracingId(&primitive.UUID{0x01})
+				f.SetWarnings([]string{"warning1"})
+			}
+			tagged := tagResponsePagingState(convert(f), common.ClusterTypeOrigin)
+			require.Equal(t, int32(len(tagged.Body)), tagged.Header.BodyLength)
+			rows := decode(tagged)
+			require.Equal(t, tagPagingState([]byte("paging-state"), common.ClusterTypeOrigin), rows.Metadata.PagingState)
+			require.Equal(t, message.RowSet{{{0, 0, 0, 1}}, {{0, 0, 0, 2}}}, rows.Data)
+			require.Equal(t, "v", rows.Metadata.Columns[0].Name)
+		})
+	}
+
+	// the responses without a paging state are not changed
+	lastPage := convert(newRowsFrame(primitive.ProtocolVersion4, nil))
+	require.Same(t, lastPage, tagResponsePagingState(lastPage, common.ClusterTypeOrigin))
+	void := convert(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.VoidResult{}))
+	require.Same(t, void, tagResponsePagingState(void, common.ClusterTypeOrigin))
+	continuousPage := convert(newContinuousPageFrame(1, false, []byte("paging-state")))
+	require.Same(t, continuousPage, tagResponsePagingState(continuousPage, common.ClusterTypeOrigin))
+}
+
+func TestApplyPagingStatePolicy(t *testing.T) {
+	newClientHandler := func(policy common.PagingStatePolicy) *ClientHandler {
+		return &ClientHandler{
+			pagingStatePolicy: policy,
+			metricHandler: metrics.NewMetricHandler(noopmetrics.NewNoopMetricFactory(), []float64{}, []float64{},
+				[]float64{}, &metrics.ProxyMetrics{CrossClusterPagingStates: newFakeCounter()}, nil, nil, nil),
+		}
+	}
+	newQuery := func(pagingState []byte) *frameDecodeContext {
+		return NewFrameDecodeContext(mockFrame(t, &message.Query{
+			Query:   "SELECT * FROM ks1.tb1",
+			Options: &message.QueryOptions{PageSize: 100, PagingState: pagingState},
+		}, primitive.ProtocolVersion4))
+	}
+	getPagingState := func(frameContext *frameDecodeContext) []byte {
+		decodedFrame, err := defaultCodec.ConvertFromRawFrame(frameContext.GetRawFrame())
+		require.Nil(t, err)
+		return decodedFrame.Body.Message.(*message.Query).Options.PagingState
+	}
+	readTarget := NewGenericRequestInfo(forwardToTarget, false, true)
+	originPagingState := tagPagingState([]byte("paging-state"), common.ClusterTypeOrigin)
+
+	// untagged paging states are forwarded untouched
+	ch := newClientHandler(common.PagingStatePolicyOriginatingCluster)
+	frameContext := newQuery([]byte("paging-state"))
+	requestInfo, newFrameContext, response, err := ch.applyPagingStatePolicy(frameContext, readTarget, nil)
+	require.Nil(t, err)
+	require.Nil(t, response)
+	require.Same(t, readTarget, requestInfo)
+	require.Same(t, frameContext, newFrameContext)
+
+	// the tag is removed if the paging state belongs to the cluster of the read
+	requestInfo, newFrameContext, response, err = ch.applyPagingStatePolicy(
+		newQuery(tagPagingState([]byte("paging-state"), common.ClusterTypeTarget)), readTarget, nil)
+	require.Nil(t, err)
+	require.Nil(t, response)
+	require.Same(t, readTarget, requestInfo)
+	require.Equal(t, []byte("paging-state"), getPagingState(newFrameContext))
+
+	// the read is forwarded to the cluster of the paging state
+	requestInfo, newFrameContext, response, err = ch.applyPagingStatePolicy(newQuery(originPagingState), readTarget, nil)
+	require.Nil(t, err)
+	require.Nil(t, response)
+	require.Equal(t, forwardToOrigin, requestInfo.GetForwardDecision())
+	require.Equal(t, []byte("paging-state"), getPagingState(newFrameContext))
+
+	// the read is rejected
+	ch = newClientHandler(common.PagingStatePolicyReject)
+	frameContext = newQuery(originPagingState)
+	requestInfo, newFrameContext, response, err = ch.applyPagingStatePolicy(frameContext, readTarget, nil)
+	require.Nil(t, err)
+	require.Same(t, readTarget, requestInfo)
+	require.Same(t, frameContext, newFrameContext)
+	decodedResponse, err := defaultCodec.ConvertFromRawFrame(response)
+	require.Nil(t, err)
+	require.IsType(t, &message.Invalid{}, decodedResponse.Body.Message)
+	require.Contains(t, decodedResponse.Body.Message.(*message.Invalid).ErrorMessage,
+		"Paging state rejected by ZDM proxy because it was returned by ORIGIN")
+}
@@ -2,12 +2,9 @@ package zdmproxy
 
 import (
 	"fmt"
-	"github.com/datastax/go-cassandra-native-protocol/datacodec"
-	"github.com/datastax/go-cassandra-native-protocol/datatype"
 	"github.com/datastax/go-cassandra-native-protocol/frame"
 	"github.com/datastax/go-cassandra-native-protocol/message"
 	"github.com/datastax/go-cassandra-native-protocol/primitive"
-	"github.com/google/uuid"
 )
 
 type ParameterModifier struct {
@@ -31,7 +28,7 @@ func NewParameterModifier(generator TimeUuidGenerator) *ParameterModifier {
 func (recv *ParameterModifier) AddValuesToExecuteFrame(
 	newFrame *frame.Frame, prepareRequestInfo *PrepareRequestInfo,
 	variablesMetadata *message.VariablesMetadata,
-	replacementTimeUuids []*uuid.UUID) (*message.Execute, error) {
+	replacementValues []interface{}) (*message.Execute, error) {
 	newExecuteMsg, ok := newFrame.Body.Message.(*message.Execute)
 	if !ok {
 		return nil, fmt.Errorf("expected Execute but got %v instead", newFrame.Body.Message.GetOpCode())
@@ -39,7 +36,7 @@ func (recv *ParameterModifier) AddValuesToExecuteFrame(
 
 	if len(prepareRequestInfo.GetReplacedTerms()) > 0 {
 		err := recv.addValuesToExecuteMessage(
-			newFrame.Header.Version, newExecuteMsg, prepareRequestInfo, variablesMetadata, replacementTimeUuids)
+			newFrame.Header.Version, newExecuteMsg, prepareRequestInfo, variablesMetadata, replacementValues)
 		if err != nil {
 			return nil, err
 		}
@@ -51,7 +48,7 @@ func (recv *ParameterModifier) AddValuesToExecuteFrame(
 func (recv *ParameterModifier) addValuesToExecuteMessage(
 	version primitive.ProtocolVersion, executeMsg *message.Execute,
 	prepareRequestInfo *PrepareRequestInfo, variablesMetadata *message.VariablesMetadata,
-	replacementTimeUuids []*uuid.UUID) error {
+	replacementValues []interface{}) error {
 	if executeMsg.Options == nil {
 		executeMsg.Options = &message.QueryOptions{}
 	}
@@ -60,12 +57,12 @@ func (recv *ParameterModifier) addValuesToExecuteMessage(
 	if len(executeMsg.Options.NamedValues) == 0 {
 		var newPositionalValues []*primitive.Value
 		newPositionalValues, err = recv.addPositionalValuesForReplacedTerms(
-			version, executeMsg.Options.PositionalValues, prepareRequestInfo, variablesMetadata, replacementTimeUuids)
+			version, executeMsg.Options.PositionalValues, prepareRequestInfo, variablesMetadata, replacementValues)
 		if err == nil {
 			executeMsg.Options.PositionalValues = newPositionalValues
 		}
 	} else {
-		err = recv.addNamedValuesForReplacedNamedMarkers(version, executeMsg, variablesMetadata, replacementTimeUuids)
+		err = recv.addNamedValuesForReplacedNamedMarkers(version, executeMsg, variablesMetadata, replacementValues)
 	}
 
 	return err
@@ -74,10 +71,10 @@ func (recv *ParameterModifier) addValuesToExecuteMessage(
 func (recv *ParameterModifier) addValuesToBatchChild(
 	version primitive.ProtocolVersion, batchChild *message.BatchChild,
 	prepareRequestInfo *PrepareRequestInfo, variablesMetadata *message.VariablesMetadata,
-	replacementTimeUuids []*uuid.UUID) error {
+	replacementValues []interface{}) error {
 
 	newPositionalValues, err := recv.addPositionalValuesForReplacedTerms(
-		version, batchChild.Values, prepareRequestInfo, variablesMetadata, replacementTimeUuids)
+		version, batchChild.Values, prepareRequestInfo, variablesMetadata, replacementValues)
 	if err == nil {
 		batchChild.Values = newPositionalValues
 	}
@@ -85,32 +82,34 @@ func (recv *ParameterModifier) addValuesToBatchChild(
 	return err
 }
 
-func (recv *ParameterModifier) generateTimeUuids(prepareRequestInfo *PrepareRequestInfo) []*uuid.UUID {
-	generatedUuids := make([]*uuid.UUID, 0, len(prepareRequestInfo.GetReplacedTerms()))
+// generateReplacementValues generates the values of the function calls that were replaced in the prior PREPARE
+// message, the same values are added to the requests of both clusters.
+func (recv *ParameterModifier) generateReplacementValues(prepareRequestInfo *PrepareRequestInfo) []interface{} {
+	generatedValues := make([]interface{}, 0, len(prepareRequestInfo.GetReplacedTerms()))
 	for _, currentTerm := range prepareRequestInfo.GetReplacedTerms() {
-		if currentTerm.isFunctionCall() == currentTerm.functionCall.isNow() {
-			newUuid := recv.timeUuidGenerator.GetTimeUuid()
-			generatedUuids = append(generatedUuids, &newUuid)
+		if currentTerm.isFunctionCall() && currentTerm.functionCall.isReplaceable() {
+			generatedValues = append(generatedValues,
+				generateReplacementValue(currentTerm.functionCall.getReplaceableFunction(), recv.timeUuidGenerator))
 		}
 	}
-	return generatedUuids
+	return generatedValues
 }
 
 func (recv *ParameterModifier) addPositionalValuesForReplacedTerms(
 	version primitive.ProtocolVersion, originalPositionalValues []*primitive.Value,
 	prepareRequestInfo *PrepareRequestInfo, variablesMetadata *message.VariablesMetadata,
-	replacementTimeUuids []*uuid.UUID) ([]*primitive.Value, error) {
+	replacementValues []interface{}) ([]*primitive.Value, error) {
 	if prepareRequestInfo.ContainsPositionalMarkers() {
 		return recv.addPositionalValuesForReplacedPositionalMarkers(
-			version, originalPositionalValues, prepareRequestInfo.GetReplacedTerms(), variablesMetadata, replacementTimeUuids)
+			version, originalPositionalValues, prepareRequestInfo.GetReplacedTerms(), variablesMetadata, replacementValues)
 	} else {
-		return recv.addPositionalValuesForReplacedNamedMarkers(version, originalPositionalValues, variablesMetadata, replacementTimeUuids)
+		return recv.addPositionalValuesForReplacedNamedMarkers(version, originalPositionalValues, variablesMetadata, replacementValues)
 	}
 }
 
 func (recv *ParameterModifier) addPositionalValuesForReplacedPositionalMarkers(version primitive.ProtocolVersion,
 	originalPositionalValues []*primitive.Value, replacedTerms []*term, variablesMetadata *message.VariablesMetadata,
-	replacementTimeUuids []*uuid.UUID) ([]*primitive.Value, error) {
+	replacementValues []interface{}) ([]*primitive.Value, error) {
 	newPositionalValues := make([]*primitive.Value, 0, len(originalPositionalValues)+len(replacedTerms))
 	start := 0
 	offset := 0
@@ -131,24 +130,24 @@ func (recv *ParameterModifier) addPositionalValuesForReplacedPositionalMarkers(v
 			start = end
 		}
 
-		if currentTerm.isFunctionCall() && currentTerm.functionCall.isNow() {
+		if currentTerm.isFunctionCall() && currentTerm.functionCall.isReplaceable() {
 			if newValueIdx >= len(variablesMetadata.Columns) {
 				return nil, fmt.Errorf("could not insert positional value (%v) because columns metadata "+
 					"has unexpected length; variablesmetadata: %v", newValueIdx, variablesMetadata)
 			}
-			if replacementIdx >= len(replacementTimeUuids) {
-				return nil, fmt.Errorf("could not replace positional value (%v) with index %v because replacement timeuuids "+
-					"has unexpected length: %v", newValueIdx, replacementIdx, replacementTimeUuids)
+			if replacementIdx >= len(replacementValues) {
+				return nil, fmt.Errorf("could not replace positional value (%v) with index %v because replacement values "+
+					"has unexpected length: %v", newValueIdx, replacementIdx, replacementValues)
 			}
 
-			generatedTimeUuidValue, err := recv.generateTimeUuidValue(
-				replacementTimeUuids[replacementIdx], version, variablesMetadata.Columns[newValueIdx].Type)
+			generatedValue, err := encodeReplacementValue(
+				replacementValues[replacementIdx], version, variablesMetadata.Columns[newValueIdx].Type)
 			if err != nil {
-				return nil, fmt.Errorf("could not generate new timeuuid value: %w", err)
+				return nil, fmt.Errorf("could not generate new value for %v(): %w", currentTerm.functionCall.name, err)
 			}
 			replacementIdx++
 
-			newPositionalValues = append(newPositionalValues, generatedTimeUuidValue)
+			newPositionalValues = append(newPositionalValues, generatedValue)
 			offset++
 		}
 	}
@@ -162,7 +161,7 @@ func (recv *ParameterModifier) addPositionalValuesForReplacedPositionalMarkers(v
 }
 
 func (recv *ParameterModifier) addPositionalValuesForReplacedNamedMarkers(version primitive.ProtocolVersion,
-	originalPositionalValues []*primitive.Value, variablesMetadata *message.VariablesMetadata, replacementTimeUuids []*uuid.UUID) ([]*primitive.Value, error) {
+	originalPositionalValues []*primitive.Value, variablesMetadata *message.VariablesMetadata, replacementValues []interface{}) ([]*primitive.Value, error) {
 	colLength := len(variablesMetadata.Columns)
 	originalPositionalValuesLength := len(originalPositionalValues)
 	newPositionalValues := make([]*primitive.Value, 0, colLength)
@@ -171,22 +170,18 @@ func (recv *ParameterModifier) addPositionalValuesForReplacedNamedMarkers(versio
 	currentIdx := 0
 	for _, col := range variablesMetadata.Columns {
 		replaced := false
-		if col.Name != "" {
-			switch col.Name {
-			case zdmNowNamedMarker:
-				if replacementIdx >= len(replacementTimeUuids) {
-					return nil, fmt.Errorf("could not replace positional value with index %v because replacement timeuuids "+
-						"has unexpected length: %v", replacementIdx, replacementTimeUuids)
-				}
-				generatedTimeUuidValue, err := recv.generateTimeUuidValue(replacementTimeUuids[replacementIdx], version, col.Type)
-				if err != nil {
-					return nil, fmt.Errorf("could not generate new timeuuid value: %w", err)
-				}
-				replacementIdx++
-				newPositionalValues = append(newPositionalValues, generatedTimeUuidValue)
-				replaced = true
-			default:
+		if col.Name != "" && getReplaceableFunctionOfNamedMarker(col.Name) != replaceableFunctionNone {
+			if replacementIdx >= len(replacementValues) {
+				return nil, fmt.Errorf("could not replace positional value with index %v because replacement values "+
+					"has unexpected length: %v", replacementIdx, replacementValues)
+			}
+			generatedValue, err := encodeReplacementValue(replacementValues[replacementIdx], version, col.Type)
+			if err != nil {
+				return nil, fmt.Errorf("could not generate new value for %v: %w", col.Name, err)
 			}
+			replacementIdx++
+			newPositionalValues = append(newPositionalValues, generatedValue)
+			replaced = true
 		}
 
 		if !replaced {
@@ -202,7 +197,7 @@ func (recv *ParameterModifier) addPositionalValuesForReplacedNamedMarkers(versio
 }
 
 func (recv *ParameterModifier) addNamedValuesForReplacedNamedMarkers(version primitive.ProtocolVersion,
-	executeMsg *message.Execute, variablesMetadata *message.VariablesMetadata, replacementTimeUuids []*uuid.UUID) error {
+	executeMsg *message.Execute, variablesMetadata *message.VariablesMetadata, replacementValues []interface{}) error {
 	if executeMsg.Options.NamedValues == nil {
 		executeMsg.Options.NamedValues = make(map[string]*primitive.Value, len(variablesMetadata.Columns))
 	}
@@ -215,40 +210,22 @@ func (recv *ParameterModifier) addNamedValuesForReplacedNamedMarkers(version pri
 				continue
 			}
 
-			switch col.Name {
-			case zdmNowNamedMarker:
-				if replacementIdx >= len(replacementTimeUuids) {
-					return fmt.Errorf("could not replace named value (%v) with index (%v) because "+
-						"replacement timeuuids has unexpected length: %v",
-						zdmNowNamedMarker, replacementIdx, replacementTimeUuids)
-				}
-				generatedTimeUuidValue, err := recv.generateTimeUuidValue(replacementTimeUuids[replacementIdx], version, col.Type)
-				if err != nil {
-					return err
-				}
-				replacementIdx++
-				executeMsg.Options.NamedValues[zdmNowNamedMarker] = generatedTimeUuidValue
-			default:
+			if getReplaceableFunctionOfNamedMarker(col.Name) == replaceableFunctionNone {
 				return fmt.Errorf("could not generate value for column %v", col.Name)
 			}
+			if replacementIdx >= len(replacementValues) {
+				return fmt.Errorf("could not replace named value (%v) with index (%v) because "+
+					"replacement values has unexpected length: %v",
+					col.Name, replacementIdx, replacementValues)
+			}
+			generatedValue, err := encodeReplacementValue(replacementValues[replacementIdx], version, col.Type)
+			if err != nil {
+				return err
+			}
+			replacementIdx++
+			executeMsg.Options.NamedValues[col.Name] = generatedValue
 		}
 	}
 
 	return nil
 }
-
-func (recv *ParameterModifier) generateTimeUuidValue(
-	timeUuid *uuid.UUID, version primitive.ProtocolVersion, valueType datatype.DataType) (*primitive.Value, error) {
-	newValueCodec, err := datacodec.NewCodec(valueType)
-	if err != nil {
-		return nil, fmt.Errorf("could not create codec for new timeuuid value: %w", err)
-	}
-
-	generatedTimeUuidPrimitive := primitive.UUID(*timeUuid)
-	generatedTimeUuidValue, err := newValueCodec.Encode(&generatedTimeUuidPrimitive, version)
-	if err != nil {
-		return nil, fmt.Errorf("could not encode new timeuuid value: %w", err)
-	}
-
-	return primitive.NewValue(generatedTimeUuidValue), nil
-}
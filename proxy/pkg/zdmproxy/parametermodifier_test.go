@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestAddValuesToExecuteFrame_NoReplacedTerms(t *testing.T) {
@@ -26,8 +27,8 @@ func TestAddValuesToExecuteFrame_NoReplacedTerms(t *testing.T) {
 		Columns:   nil,
 	}
 	fClone := f.Clone()
-	replacementTimeUuids := parameterModifier.generateTimeUuids(prepareRequestInfo)
-	newMsg, err := parameterModifier.AddValuesToExecuteFrame(fClone, prepareRequestInfo, variablesMetadata, replacementTimeUuids)
+	replacementValues := parameterModifier.generateReplacementValues(prepareRequestInfo)
+	newMsg, err := parameterModifier.AddValuesToExecuteFrame(fClone, prepareRequestInfo, variablesMetadata, replacementValues)
 	require.Same(t, fClone.Body.Message, newMsg)
 	require.NotSame(t, f.Body.Message, newMsg)
 	require.Equal(t, f.Body.Message, newMsg)
@@ -47,8 +48,8 @@ func TestAddValuesToExecuteFrame_InvalidMessageType(t *testing.T) {
 		PkIndices: nil,
 		Columns:   nil,
 	}
-	replacementTimeUuids := parameterModifier.generateTimeUuids(prepareRequestInfo)
-	_, err = parameterModifier.AddValuesToExecuteFrame(f, prepareRequestInfo, variablesMetadata, replacementTimeUuids)
+	replacementValues := parameterModifier.generateReplacementValues(prepareRequestInfo)
+	_, err = parameterModifier.AddValuesToExecuteFrame(f, prepareRequestInfo, variablesMetadata, replacementValues)
 	require.NotNil(t, err)
 }
 
@@ -207,8 +208,8 @@ func TestAddValuesToExecuteFrame_PositionalValues(t *testing.T) {
 			containsPositionalMarkers := ((len(requestPosVals) + len(replacedTerms)) > 0) && !test.prepareContainsNamedValues
 			prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), replacedTerms, containsPositionalMarkers, "", "")
 
-			replacementTimeUuids := parameterModifier.generateTimeUuids(prepareRequestInfo)
-			executeMsg, err := parameterModifier.AddValuesToExecuteFrame(f, prepareRequestInfo, vm, replacementTimeUuids)
+			replacementValues := parameterModifier.generateReplacementValues(prepareRequestInfo)
+			executeMsg, err := parameterModifier.AddValuesToExecuteFrame(f, prepareRequestInfo, vm, replacementValues)
 
 			require.Nil(t, err)
 			require.Equal(t, len(requestPosVals)+len(replacedTerms), len(executeMsg.Options.PositionalValues))
@@ -352,8 +353,8 @@ func TestAddValuesToExecuteFrame_NamedValues(t *testing.T) {
 			})
 			prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), replacedTerms, false, "", "")
 
-			replacementTimeUuids := parameterModifier.generateTimeUuids(prepareRequestInfo)
-			executeMsg, err := parameterModifier.AddValuesToExecuteFrame(f, prepareRequestInfo, vm, replacementTimeUuids)
+			replacementValues := parameterModifier.generateReplacementValues(prepareRequestInfo)
+			executeMsg, err := parameterModifier.AddValuesToExecuteFrame(f, prepareRequestInfo, vm, replacementValues)
 
 			require.Nil(t, err)
 			if len(replacedTerms) == 0 {
@@ -390,3 +391,76 @@ func RequireValidGeneratedTime(t *testing.T, beforeTestTimeUuid uuid.UUID, gener
 	require.Nil(t, err)
 	require.LessOrEqual(t, int64(newParsedTimeUuid.Time()), int64(now.Time()))
 }
+
+func TestAddValuesToExecuteFrame_ReplaceableFunctions(t *testing.T) {
+	uid, err := uuid.Parse("7872e70a-5a68-11eb-ae93-0242ac130002")
+	require.Nil(t, err)
+	uidTime := time.Date(2021, time.January, 19, 15, 10, 30, 882000000, time.UTC)
+
+	replacedTerms := []*term{
+		NewFunctionCallTerm(NewFunctionCall("", "currenttimestamp", 0, 0, 0), -1),
+		NewFunctionCallTerm(NewFunctionCall("", "currentdate", 0, 0, 0), -1),
+		NewFunctionCallTerm(NewFunctionCall("", "currenttime", 0, 0, 0), -1),
+		NewFunctionCallTerm(newFunctionCallWithArgs(
+			NewFunctionCall("", "totimestamp", 1, 0, 0), NewFunctionCall("", "now", 0, 0, 0)), -1),
+		NewFunctionCallTerm(NewFunctionCall("", "uuid", 0, 0, 0), -1),
+	}
+
+	tests := []struct {
+		name                      string
+		containsPositionalMarkers bool
+		markerNames               []string
+	}{
+		{"positional markers", true, []string{"p0", "p1", "p2", "p3", "p4"}},
+		{"named markers", false, []string{
+			zdmCurrentTimestampNamedMarker, zdmCurrentDateNamedMarker, zdmCurrentTimeNamedMarker,
+			zdmCurrentTimestampNamedMarker, zdmUuidNamedMarker}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := &message.VariablesMetadata{}
+			for idx, dataType := range []datatype.DataType{
+				datatype.Timestamp, datatype.Date, datatype.Time, datatype.Timestamp, datatype.Uuid} {
+				vm.Columns = append(vm.Columns, &message.ColumnMetadata{
+					Name: tt.markerNames[idx], Index: int32(idx), Type: dataType})
+			}
+			prepareRequestInfo := NewPrepareRequestInfo(
+				NewGenericRequestInfo(forwardToBoth, false, true), replacedTerms, tt.containsPositionalMarkers, "", "")
+			parameterModifier := NewParameterModifier(&fakeTimeUuidGenerator{uid: uid})
+			replacementValues := parameterModifier.generateReplacementValues(prepareRequestInfo)
+
+			// the same values are added to the requests of both clusters
+			var executeMsgs []*message.Execute
+			for i := 0; i < 2; i++ {
+				f := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Execute{Options: &message.QueryOptions{}})
+				executeMsg, err := parameterModifier.AddValuesToExecuteFrame(f, prepareRequestInfo, vm, replacementValues)
+				require.Nil(t, err)
+				require.Equal(t, len(replacedTerms), len(executeMsg.Options.PositionalValues))
+				executeMsgs = append(executeMsgs, executeMsg)
+			}
+			require.Equal(t, executeMsgs[0], executeMsgs[1])
+
+			values := executeMsgs[0].Options.PositionalValues
+			var timestamp time.Time
+			_, err = datacodec.Timestamp.Decode(values[0].Contents, &timestamp, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			require.Equal(t, uidTime, timestamp)
+			var date time.Time
+			_, err = datacodec.Date.Decode(values[1].Contents, &date, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			require.Equal(t, time.Date(2021, time.January, 19, 0, 0, 0, 0, time.UTC), date)
+			var timeOfDay time.Duration
+			_, err = datacodec.Time.Decode(values[2].Contents, &timeOfDay, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			require.Equal(t, 15*time.Hour+10*time.Minute+30*time.Second+882*time.Millisecond, timeOfDay)
+			_, err = datacodec.Timestamp.Decode(values[3].Contents, &timestamp, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			require.Equal(t, uidTime, timestamp)
+			var randomUuid primitive.UUID
+			_, err = datacodec.Uuid.Decode(values[4].Contents, &randomUuid, primitive.ProtocolVersion4)
+			require.Nil(t, err)
+			require.NotEqual(t, primitive.UUID(uid), randomUuid)
+		})
+	}
+}
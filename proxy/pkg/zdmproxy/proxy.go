@@ -5,17 +5,22 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
 	"github.com/datastax/zdm-proxy/proxy/pkg/common"
 	"github.com/datastax/zdm-proxy/proxy/pkg/config"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/noopmetrics"
 	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/prommetrics"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics/statsdmetrics"
+	"github.com/google/uuid"
 	"github.com/jpillora/backoff"
-	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"math/rand"
 	"net"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,25 +30,47 @@ type ZdmProxy struct {
 	Conf           *config.Config
 	TopologyConfig *common.TopologyConfig
 
+	// Conf with the settings that were changed at runtime by reloading the config file, see ReloadConfig
+	runtimeConf *config.Config
+	reloadLock  *sync.Mutex
+
 	originConnectionConfig ConnectionConfig
 	targetConnectionConfig ConnectionConfig
 
 	proxyTlsConfig *common.ProxyTlsConfig
 
+	// nil if ZDM_PROXY_TLS_DEV_MODE_ENABLED is false
+	devModeTlsMaterial *devModeTlsMaterial
+
+	// credentials of the new cluster connections, they change if the credential files change
+	clusterCredentials *clusterCredentials
+
+	// addresses of the proxy instances, they change if ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE changes
+	proxyTopology *proxyTopology
+
 	timeUuidGenerator TimeUuidGenerator
 
-	primaryCluster    common.ClusterType
-	readMode          common.ReadMode
-	systemQueriesMode common.SystemQueriesMode
+	primaryCluster      common.ClusterType
+	readMode            common.ReadMode
+	dualWriteMode       common.DualWriteMode
+	lwtPolicy           common.LwtPolicy
+	counterWritesPolicy common.CounterWritesPolicy
+	pagingStatePolicy   common.PagingStatePolicy
+	systemQueriesMode   common.SystemQueriesMode
+	maxProtocolVersion  primitive.ProtocolVersion
+
+	secondaryWriteFailureMode common.SecondaryWriteFailureMode
 
 	proxyRand *rand.Rand
 
 	lock *sync.RWMutex
 
-	// Listener that enables the proxy to listen for clients on the port specified in the configuration
-	clientListener net.Listener
-	listenerLock   *sync.Mutex
-	listenerClosed bool
+	// Listeners that enable the proxy to listen for clients on the port specified in the configuration
+	// (or the listeners injected with ZdmProxyOptions)
+	clientListeners []net.Listener
+	listenerLock    *sync.Mutex
+	listenerClosed  bool
+	activeListeners int32 // number of client listeners that are accepting connections
 
 	PreparedStatementCache *PreparedStatementCache
 
@@ -60,6 +87,8 @@ type ZdmProxy struct {
 	targetBuckets []float64
 	asyncBuckets  []float64
 
+	histogramConfig *metrics.HistogramConfig
+
 	activeClients int32
 
 	requestResponseNumWorkers int
@@ -77,11 +106,129 @@ type ZdmProxy struct {
 	globalClientHandlersWg                *sync.WaitGroup
 
 	metricHandler *metrics.MetricHandler
+
+	clusterConnPool *clusterConnPool
+
+	// nil if ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT is 1, if the cluster connections are pooled or if they fail over
+	perClientConnections *perClientConnections
+
+	handshakeCache *handshakeCache
+
+	// nil if ZDM_PROXY_AUTH_CREDENTIALS and ZDM_PROXY_AUTH_CREDENTIALS_FILE are not set
+	proxyAuthenticator *proxyAuthenticator
+
+	// nil if there are no target write filter rules
+	targetWriteFilter *targetWriteFilter
+
+	// nil if ZDM_<CLUSTER>_CONSISTENCY_OVERRIDE is not set
+	originConsistencyOverride *consistencyOverride
+	targetConsistencyOverride *consistencyOverride
+
+	// nil if ZDM_TABLE_ROUTING_RULES is not set
+	tableRoutingRules *tableRoutingRules
+
+	// nil if ZDM_STATEMENT_FILTER_RULES is not set
+	statementFilter *statementFilter
+
+	// nil if every write is forwarded to target
+	targetWriteSampler *targetWriteSampler
+
+	// nil if ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE is 0
+	writeComparisonSampler *writeComparisonSampler
+
+	// nil if ZDM_METRICS_TABLE_LEVEL_ENABLED is false
+	tableMetrics *tableMetrics
+
+	// nil if ZDM_MIGRATION_PROGRESS_ENABLED is false
+	migrationProgress *migrationProgress
+
+	// nil if there are no unsupported target schema features
+	targetDdlChecker *targetDdlChecker
+
+	// nil if every schema statement is forwarded to both clusters
+	schemaStatementPolicies *schemaStatementPolicies
+
+	// nil if destructive statements don't need to be confirmed
+	destructiveStatementGuard *DestructiveStatementGuard
+
+	// nil if there are no application read routing rules
+	applicationReadRouting *applicationReadRouting
+
+	// nil if the responses of intercepted queries are not cached
+	interceptedResponseCache *interceptedResponseCache
+
+	connectedClients *connectedClients
+
+	// nil if the system_views.zdm_* tables are disabled
+	proxyVirtualTables *proxyVirtualTables
+
+	// nil if the zdm_admin keyspace is disabled
+	adminKeyspace *adminKeyspace
+
+	// nil if ZDM_FLEET_STORE_TYPE is not set
+	fleetCoordinator *fleetCoordinator
+
+	// nil if ZDM_LEADER_ELECTION_ENABLED is false
+	leaderElection *leaderElection
+
+	// nil if ZDM_TRAFFIC_CAPTURE_DIRECTORY is not set
+	trafficCapture *TrafficCapture
+
+	frameLogger *FrameLogger
+
+	originRequestLimiter *requestLimiter
+	targetRequestLimiter *requestLimiter
+
+	// nil if there is no request rate limit for the cluster
+	originRateLimiter *rateLimiter
+	targetRateLimiter *rateLimiter
+
+	inFlightBudget *inFlightBudget // nil if ZDM_PROXY_MAX_IN_FLIGHT_REQUESTS is 0
+
+	originCircuitBreaker *circuitBreaker // nil if ZDM_ORIGIN_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT is 0
+	targetCircuitBreaker *circuitBreaker // nil if ZDM_TARGET_CIRCUIT_BREAKER_ERROR_THRESHOLD_PERCENT is 0
+
+	// nil if ZDM_TOKEN_AWARE_ROUTING_ENABLED is false or if the cluster connections are not pooled
+	originTokenRouter *tokenRouter
+	targetTokenRouter *tokenRouter
+
+	secondaryWriteJournal *writeJournal // nil if ZDM_SECONDARY_WRITE_JOURNAL_TYPE is NONE
+
+	tracer *tracer // nil if ZDM_TRACING_OTLP_ENDPOINT is not set
+
+	slowQueryLog *slowQueryLog // nil if ZDM_SLOW_QUERY_LOG_THRESHOLD_MS is 0
+
+	auditLog *auditLog // nil if ZDM_AUDIT_LOG_DESTINATION is not set
+
+	// nil if ZDM_<CLUSTER>_LATENCY_BUDGET_MS is 0
+	originLatencyBudget *latencyBudget
+	targetLatencyBudget *latencyBudget
+
+	// nil if ZDM_<CLUSTER>_REQUEST_MAX_RETRIES is 0
+	originRequestRetryPolicy *requestRetryPolicy
+	targetRequestRetryPolicy *requestRetryPolicy
+
+	requestTimeoutPolicy *requestTimeoutPolicy
+
+	// nil if ZDM_STALL_DETECTION_THRESHOLD_MS is 0
+	stallWatchdog *stallWatchdog
+
+	runtimeStatsLogger *runtimeStatsLogger // nil if ZDM_RUNTIME_STATS_LOG_INTERVAL_MS is 0
+
+	options *ZdmProxyOptions
 }
 
 func NewZdmProxy(conf *config.Config) (*ZdmProxy, error) {
+	return NewZdmProxyWithOptions(conf, nil)
+}
+
+// NewZdmProxyWithOptions creates a proxy that can be embedded in other programs, see ZdmProxyOptions.
+// The options can be nil.
+func NewZdmProxyWithOptions(conf *config.Config, options *ZdmProxyOptions) (*ZdmProxy, error) {
+	options.applyGlobalLogger()
 	zdmProxy := &ZdmProxy{
-		Conf: conf,
+		Conf:    conf,
+		options: options,
 	}
 	err := zdmProxy.initializeGlobalStructures()
 	if err != nil {
@@ -95,6 +242,27 @@ func (p *ZdmProxy) GetMetricHandler() *metrics.MetricHandler {
 	return p.metricHandler
 }
 
+// GetListenAddr returns the address on which the proxy accepts client connections or nil if it is not listening.
+// If multiple listeners were injected then the address of the first one is returned, see GetListenAddrs.
+func (p *ZdmProxy) GetListenAddr() net.Addr {
+	addrs := p.GetListenAddrs()
+	if len(addrs) == 0 {
+		return nil
+	}
+	return addrs[0]
+}
+
+// GetListenAddrs returns the addresses on which the proxy accepts client connections.
+func (p *ZdmProxy) GetListenAddrs() []net.Addr {
+	p.listenerLock.Lock()
+	defer p.listenerLock.Unlock()
+	addrs := make([]net.Addr, 0, len(p.clientListeners))
+	for _, l := range p.clientListeners {
+		addrs = append(addrs, l.Addr())
+	}
+	return addrs
+}
+
 // Start starts up the proxy and start listening for client connections.
 func (p *ZdmProxy) Start(ctx context.Context) error {
 	log.Infof("Validating config...")
@@ -120,7 +288,12 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 	}
 
 	var serverSideTlsConfig *tls.Config
-	if p.proxyTlsConfig.TlsEnabled {
+	if p.proxyTlsConfig.DevMode {
+		serverSideTlsConfig, err = p.initializeDevModeTls()
+		if err != nil {
+			return fmt.Errorf("could not create dev mode server side tls.Config object: %w", err)
+		}
+	} else if p.proxyTlsConfig.TlsEnabled {
 		serverSideTlsConfig, err = getServerSideTlsConfigFromProxyClusterTlsConfig(p.proxyTlsConfig)
 
 		if err != nil {
@@ -166,12 +339,62 @@ func (p *ZdmProxy) Start(ctx context.Context) error {
 		return err
 	}
 
+	err = p.initializeRequestLimiters()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeLatencyBudgets()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeRequestRetryPolicies()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeRequestTimeoutPolicy()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeCircuitBreakers()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeSecondaryWriteJournal()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeMigrationProgress()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeTracing()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeSlowQueryLog()
+	if err != nil {
+		return err
+	}
+
+	err = p.initializeAuditLog()
+	if err != nil {
+		return err
+	}
+
 	err = p.acceptConnectionsFromClients(p.Conf.ProxyListenAddress, p.Conf.ProxyListenPort, serverSideTlsConfig)
 	if err != nil {
 		return err
 	}
 
-	log.Infof("Proxy connected and ready to accept queries on %v:%d", p.Conf.ProxyListenAddress, p.Conf.ProxyListenPort)
+	log.Infof("Proxy connected and ready to accept queries on %v", p.GetListenAddrs())
 	return nil
 }
 
@@ -186,6 +409,8 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	log.Infof("Parsed Topology Config: %v", topologyConfig)
 	p.lock.Lock()
 	p.TopologyConfig = topologyConfig
+	p.proxyTopology = newProxyTopology(topologyConfig, p.Conf.ProxyListenPort)
+	p.clusterCredentials = newClusterCredentials(p.Conf)
 	p.lock.Unlock()
 
 	parsedOriginContactPoints, err := p.Conf.ParseOriginContactPoints()
@@ -211,11 +436,18 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		return err
 	}
 
+	originSocketOptions, err := p.Conf.ParseOriginSocketOptions()
+	if err != nil {
+		return err
+	}
+
 	// Initialize origin connection configuration and control connection endpoint configuration
 	originConnectionConfig, err := InitializeConnectionConfig(originTlsConfig,
 		parsedOriginContactPoints,
 		p.Conf.OriginPort,
 		p.Conf.OriginConnectionTimeoutMs,
+		p.Conf.OriginConnectionAttemptDelayMs,
+		originSocketOptions,
 		common.ClusterTypeOrigin,
 		p.Conf.OriginLocalDatacenter,
 		ctx)
@@ -232,11 +464,18 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 		return err
 	}
 
+	targetSocketOptions, err := p.Conf.ParseTargetSocketOptions()
+	if err != nil {
+		return err
+	}
+
 	// Initialize target connection configuration and control connection endpoint configuration
 	targetConnectionConfig, err := InitializeConnectionConfig(targetTlsConfig,
 		parsedTargetContactPoints,
 		p.Conf.TargetPort,
 		p.Conf.TargetConnectionTimeoutMs,
+		p.Conf.TargetConnectionAttemptDelayMs,
+		targetSocketOptions,
 		common.ClusterTypeTarget,
 		p.Conf.TargetLocalDatacenter,
 		ctx)
@@ -247,9 +486,10 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	p.targetConnectionConfig = targetConnectionConfig
 	p.lock.Unlock()
 
+	originCredentials := p.clusterCredentials.get(common.ClusterTypeOrigin)
 	originControlConn := NewControlConn(
 		p.controlConnShutdownCtx, p.Conf.OriginPort, p.originConnectionConfig,
-		p.Conf.OriginUsername, p.Conf.OriginPassword, p.Conf, topologyConfig, p.proxyRand)
+		originCredentials.Username, originCredentials.Password, p.Conf, topologyConfig, p.proxyRand)
 
 	if err := originControlConn.Start(p.controlConnShutdownWg, ctx); err != nil {
 		return fmt.Errorf("failed to initialize origin control connection: %w", err)
@@ -259,9 +499,10 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 	p.originControlConn = originControlConn
 	p.lock.Unlock()
 
+	targetCredentials := p.clusterCredentials.get(common.ClusterTypeTarget)
 	targetControlConn := NewControlConn(
 		p.controlConnShutdownCtx, p.Conf.TargetPort, p.targetConnectionConfig,
-		p.Conf.TargetUsername, p.Conf.TargetPassword, p.Conf, topologyConfig, p.proxyRand)
+		targetCredentials.Username, targetCredentials.Password, p.Conf, topologyConfig, p.proxyRand)
 
 	if err := targetControlConn.Start(p.controlConnShutdownWg, ctx); err != nil {
 		return fmt.Errorf("failed to initialize target control connection: %w", err)
@@ -269,25 +510,214 @@ func (p *ZdmProxy) initializeControlConnections(ctx context.Context) error {
 
 	p.lock.Lock()
 	p.targetControlConn = targetControlConn
+	if p.clusterConnPool != nil && p.Conf.TokenAwareRoutingEnabled {
+		p.originTokenRouter = newTokenRouter(p.controlConnShutdownCtx, originControlConn, p.PreparedStatementCache, true)
+		p.targetTokenRouter = newTokenRouter(p.controlConnShutdownCtx, targetControlConn, p.PreparedStatementCache, false)
+	}
 	p.lock.Unlock()
 
+	pollIntervalMs, err := p.Conf.ParseCredentialFilesPollInterval()
+	if err != nil {
+		return err
+	}
+	if (hasCredentialFiles(p.Conf) || p.Conf.ProxyAuthCredentialsFile != "") && pollIntervalMs > 0 {
+		log.Infof("Checking the credential files for changes every %v ms.", pollIntervalMs)
+		p.watchCredentialFiles(time.Duration(pollIntervalMs) * time.Millisecond)
+	}
+
+	configFilePollIntervalMs, err := p.Conf.ParseConfigFilePollInterval()
+	if err != nil {
+		return err
+	}
+	if p.Conf.ConfigFile != "" && configFilePollIntervalMs > 0 {
+		log.Infof("Checking the config file for changes every %v ms.", configFilePollIntervalMs)
+		p.watchConfigFile(time.Duration(configFilePollIntervalMs) * time.Millisecond)
+	}
+
+	topologyPollIntervalMs, err := p.Conf.ParseProxyTopologyAddressesFilePollInterval()
+	if err != nil {
+		return err
+	}
+	if p.Conf.ProxyTopologyAddressesFile != "" && topologyPollIntervalMs > 0 {
+		log.Infof("Checking the proxy topology addresses file for changes every %v ms.", topologyPollIntervalMs)
+		p.watchProxyTopologyAddressesFile(time.Duration(topologyPollIntervalMs) * time.Millisecond)
+	}
+
+	if p.fleetCoordinator != nil {
+		p.watchFleetStore()
+	}
+
+	if p.leaderElection != nil {
+		p.controlConnShutdownWg.Add(1)
+		go func() {
+			defer p.controlConnShutdownWg.Done()
+			p.leaderElection.run(p.controlConnShutdownCtx)
+		}()
+	}
+
 	return nil
 }
 
+// watchFleetStore applies the settings of the fleet store before the proxy accepts connections and then applies
+// their changes until the control connections are shut down.
+func (p *ZdmProxy) watchFleetStore() {
+	revision, value, err := p.fleetCoordinator.load(p.controlConnShutdownCtx)
+	if err != nil {
+		log.Warnf("Could not read the %v, the settings of the %v keyspace will be applied once it is reachable: %v.",
+			p.fleetCoordinator.description, adminKeyspaceName, err)
+	}
+
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		p.fleetCoordinator.run(p.controlConnShutdownCtx, revision, value)
+	}()
+}
+
+// watchCredentialFiles updates the credentials of the new cluster connections when the credential files change
+// until the control connections are shut down.
+func (p *ZdmProxy) watchCredentialFiles(pollInterval time.Duration) {
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.controlConnShutdownCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if p.proxyAuthenticator != nil && p.Conf.ProxyAuthCredentialsFile != "" {
+				changed, err := p.proxyAuthenticator.refresh(p.Conf)
+				if err != nil {
+					log.Warnf("Could not check the proxy credentials file for changes: %v.", err)
+				} else if changed {
+					log.Infof("The proxy credentials changed, they will be used by the new client handshakes.")
+				}
+			}
+
+			changedClusters, err := p.clusterCredentials.refresh(p.Conf)
+			if err != nil {
+				log.Warnf("Could not check the credential files for changes: %v.", err)
+				continue
+			}
+			for _, clusterType := range changedClusters {
+				log.Infof("The %v credentials changed, they will be used by the new %v connections.",
+					clusterType, clusterType)
+				creds := p.clusterCredentials.get(clusterType)
+				controlConn := p.GetOriginControlConn()
+				if clusterType == common.ClusterTypeTarget {
+					controlConn = p.GetTargetControlConn()
+				}
+				controlConn.SetCredentials(creds.Username, creds.Password)
+			}
+		}
+	}()
+}
+
+// watchProxyTopologyAddressesFile updates the topology of the proxy instances when the addresses file changes until
+// the control connections are shut down, the clients are notified after the control connections are updated so that
+// the system.peers queries that the drivers send when they receive the events return the new proxy instances.
+func (p *ZdmProxy) watchProxyTopologyAddressesFile(pollInterval time.Duration) {
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.controlConnShutdownCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			addresses, err := p.Conf.ReadProxyTopologyAddressesFile()
+			if err != nil {
+				log.Warnf("Could not check the proxy topology addresses file for changes: %v.", err)
+				continue
+			}
+			topologyConfig, events, err := p.proxyTopology.update(addresses)
+			if err != nil {
+				log.Warnf("Ignoring the change of the proxy topology addresses file: %v.", err)
+				continue
+			}
+			if topologyConfig == nil {
+				continue
+			}
+			log.Infof("The proxy topology addresses changed: %v", topologyConfig)
+			for _, controlConn := range []*ControlConn{p.GetOriginControlConn(), p.GetTargetControlConn()} {
+				err = controlConn.SetTopologyConfig(topologyConfig)
+				if err != nil {
+					log.Errorf("Could not apply the new proxy topology to the %v control connection: %v.",
+						controlConn.connConfig.GetClusterType(), err)
+				}
+			}
+			p.proxyTopology.publish(events)
+		}
+	}()
+}
+
+// runSingletonTask runs a background task that must run on a single proxy instance of the fleet, it runs while this
+// instance is the leader (or always if leader election is disabled) until the control connections are shut down.
+func (p *ZdmProxy) runSingletonTask(name string, run func(ctx context.Context)) {
+	if p.leaderElection != nil {
+		p.leaderElection.addSingletonTask(name, run)
+		return
+	}
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		run(p.controlConnShutdownCtx)
+	}()
+}
+
 func (p *ZdmProxy) initializeMetricHandler() error {
+	tableLevelMaxTables, err := p.Conf.ParseMetricsTableLevelMaxTables()
+	if err != nil {
+		return err
+	}
+
+	metricsBackend, err := p.Conf.ParseMetricsBackend()
+	if err != nil {
+		return err
+	}
+
+	statsdAddress, err := p.Conf.ParseMetricsStatsdAddress()
+	if err != nil {
+		return err
+	}
+
+	statsdFlushInterval, err := p.Conf.ParseMetricsStatsdFlushInterval()
+	if err != nil {
+		return err
+	}
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	// This is the Prometheus-specific implementation of the MetricFactory object that will be provided to the global MetricHandler object
-	// To switch to a different implementation, change the type instantiated here to another one that implements
-	// metrics.MetricFactory.
-	// You will also need to change the HTTP handler, see runner.go.
+	// The MetricFactory implementation is selected by ZDM_METRICS_BACKEND, the HTTP handler of the metrics endpoint is
+	// provided by the factory (see runner.go).
 
 	var metricFactory metrics.MetricFactory
-	if p.Conf.MetricsEnabled {
-		metricFactory = prommetrics.NewPrometheusMetricFactory(prometheus.DefaultRegisterer)
-	} else {
+	if !p.Conf.MetricsEnabled {
 		metricFactory = noopmetrics.NewNoopMetricFactory()
+	} else if metricsBackend == common.MetricsBackendStatsd {
+		statsdMetricFactory, err := statsdmetrics.NewStatsdMetricFactory(
+			statsdAddress, p.Conf.MetricsStatsdPrefix, p.Conf.MetricsStatsdTagsEnabled, statsdFlushInterval)
+		if err != nil {
+			return err
+		}
+		log.Infof("Metrics are sent to the StatsD server %v every %v.", statsdAddress, statsdFlushInterval)
+		p.controlConnShutdownWg.Add(1)
+		go func() {
+			defer p.controlConnShutdownWg.Done()
+			statsdMetricFactory.Run(p.controlConnShutdownCtx)
+		}()
+		metricFactory = statsdMetricFactory
+	} else {
+		metricFactory = prommetrics.NewPrometheusMetricFactory(p.options.getMetricsRegisterer(), p.histogramConfig)
 	}
 
 	proxyMetrics, err := p.CreateProxyMetrics(metricFactory)
@@ -299,461 +729,1735 @@ func (p *ZdmProxy) initializeMetricHandler() error {
 		metricFactory, p.originBuckets, p.targetBuckets, p.asyncBuckets, proxyMetrics,
 		p.CreateOriginNodeMetrics, p.CreateTargetNodeMetrics, p.CreateAsyncNodeMetrics)
 
+	p.tableMetrics = newTableMetrics(tableLevelMaxTables, p.metricHandler)
+	if p.tableMetrics != nil {
+		log.Infof("Table level metrics enabled for up to %d tables.", tableLevelMaxTables)
+	}
+
 	return nil
 }
 
-func (p *ZdmProxy) initializeGlobalStructures() error {
-	p.lock = &sync.RWMutex{}
-
-	p.listenerLock = &sync.Mutex{}
-	p.listenerClosed = false
-	p.proxyRand = NewThreadSafeRand()
-
-	maxProcs := runtime.GOMAXPROCS(0)
+func (p *ZdmProxy) initializeRequestLimiters() error {
+	originLimitConfig, err := p.Conf.ParseOriginRequestLimitConfig()
+	if err != nil {
+		return err
+	}
 
-	var err error
-	p.readMode, err = p.Conf.ParseReadMode()
+	targetLimitConfig, err := p.Conf.ParseTargetRequestLimitConfig()
 	if err != nil {
 		return err
 	}
 
-	p.primaryCluster, err = p.Conf.ParsePrimaryCluster()
+	originMaxRequestsPerSecond, err := p.Conf.ParseOriginMaxRequestsPerSecond()
 	if err != nil {
 		return err
 	}
 
-	p.systemQueriesMode, err = p.Conf.ParseSystemQueriesMode()
+	targetMaxRequestsPerSecond, err := p.Conf.ParseTargetMaxRequestsPerSecond()
 	if err != nil {
 		return err
 	}
 
-	defaultReadWorkers := maxProcs * 8
-	defaultWriteWorkers := maxProcs * 4
-	if p.readMode == common.ReadModeDualAsyncOnSecondary {
-		defaultReadWorkers = maxProcs * 12
-		defaultWriteWorkers = maxProcs * 6
+	fleetStoreConfig, err := p.Conf.ParseFleetStoreConfig()
+	if err != nil {
+		return err
 	}
 
-	p.requestResponseNumWorkers = p.Conf.RequestResponseMaxWorkers
-	if p.requestResponseNumWorkers == -1 {
-		p.requestResponseNumWorkers = maxProcs * 4 // default
-	} else if p.requestResponseNumWorkers <= 0 {
-		log.Warnf("Invalid number of request / response workers %d, using GOMAXPROCS * 4 (%d).", p.requestResponseNumWorkers, maxProcs*4)
-		p.requestResponseNumWorkers = maxProcs * 4
+	maxInFlightRequests, err := p.Conf.ParseProxyMaxInFlightRequests()
+	if err != nil {
+		return err
 	}
-	log.Infof("Using %d request / response workers.", p.requestResponseNumWorkers)
 
-	p.writeNumWorkers = p.Conf.WriteMaxWorkers
-	if p.writeNumWorkers == -1 {
-		p.writeNumWorkers = defaultWriteWorkers // default
-	} else if p.writeNumWorkers <= 0 {
-		log.Warnf("Invalid number of write workers %d, using default (%d).", p.writeNumWorkers, defaultWriteWorkers)
-		p.writeNumWorkers = defaultWriteWorkers
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	proxyMetrics := p.metricHandler.GetProxyMetrics()
+	p.inFlightBudget = newInFlightBudget(maxInFlightRequests, proxyMetrics.BackpressureActivations)
+	if p.inFlightBudget != nil {
+		log.Infof("Limiting in flight requests across all client connections to %d.", maxInFlightRequests)
 	}
-	log.Infof("Using %d write workers.", p.writeNumWorkers)
+	if originLimitConfig.Enabled {
+		log.Infof("Limiting concurrent requests to ORIGIN: %v", originLimitConfig)
+		p.originRequestLimiter = newRequestLimiter(
+			common.ClusterTypeOrigin, originLimitConfig, proxyMetrics.QueuedRequestsOrigin,
+			proxyMetrics.RequestQueueDurationOrigin, proxyMetrics.RejectedQueuedRequestsOrigin)
+	}
+	if targetLimitConfig.Enabled {
+		log.Infof("Limiting concurrent requests to TARGET: %v", targetLimitConfig)
+		p.targetRequestLimiter = newRequestLimiter(
+			common.ClusterTypeTarget, targetLimitConfig, proxyMetrics.QueuedRequestsTarget,
+			proxyMetrics.RequestQueueDurationTarget, proxyMetrics.RejectedQueuedRequestsTarget)
+	}
+
+	instance := uuid.New().String()
+	p.originRateLimiter = p.newRateLimiter(
+		common.ClusterTypeOrigin, originMaxRequestsPerSecond, proxyMetrics.RateLimitedRequestsOrigin,
+		fleetStoreConfig, instance)
+	p.targetRateLimiter = p.newRateLimiter(
+		common.ClusterTypeTarget, targetMaxRequestsPerSecond, proxyMetrics.RateLimitedRequestsTarget,
+		fleetStoreConfig, instance)
+
+	return nil
+}
 
-	p.readNumWorkers = p.Conf.ReadMaxWorkers
-	if p.readNumWorkers == -1 {
-		p.readNumWorkers = defaultReadWorkers // default
-	} else if p.readNumWorkers <= 0 {
-		log.Warnf("Invalid number of read workers %d, using default (%d).", p.readNumWorkers, defaultReadWorkers)
-		p.readNumWorkers = defaultReadWorkers
+func (p *ZdmProxy) initializeLatencyBudgets() error {
+	originLatencyBudgetConfig, err := p.Conf.ParseOriginLatencyBudgetConfig()
+	if err != nil {
+		return err
 	}
-	log.Infof("Using %d read workers.", p.readNumWorkers)
 
-	p.listenerNumWorkers = p.Conf.ListenerMaxWorkers
-	if p.listenerNumWorkers == -1 {
-		p.listenerNumWorkers = maxProcs // default
-	} else if p.listenerNumWorkers <= 0 {
-		log.Warnf("Invalid number of connection listener workers %d, using GOMAXPROCS (%d).", p.listenerNumWorkers, maxProcs)
-		p.listenerNumWorkers = maxProcs
+	targetLatencyBudgetConfig, err := p.Conf.ParseTargetLatencyBudgetConfig()
+	if err != nil {
+		return err
 	}
-	log.Infof("Using %d listener workers.", p.listenerNumWorkers)
 
-	p.requestResponseScheduler = NewScheduler(p.requestResponseNumWorkers)
-	p.writeScheduler = NewScheduler(p.writeNumWorkers)
-	p.readScheduler = NewScheduler(p.readNumWorkers)
-	p.listenerScheduler = NewScheduler(p.listenerNumWorkers)
+	webhookUrl, err := p.Conf.ParseLatencyBudgetWebhookUrl()
+	if err != nil {
+		return err
+	}
 
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	p.globalClientHandlersWg = &sync.WaitGroup{}
-	p.clientHandlersShutdownRequestCtx, p.clientHandlersShutdownRequestCancelFn = context.WithCancel(context.Background())
+	proxyMetrics := p.metricHandler.GetProxyMetrics()
+	p.originLatencyBudget = p.newLatencyBudget(
+		common.ClusterTypeOrigin, originLatencyBudgetConfig, proxyMetrics.LatencyBudgetBreachesOrigin, webhookUrl)
+	p.targetLatencyBudget = p.newLatencyBudget(
+		common.ClusterTypeTarget, targetLatencyBudgetConfig, proxyMetrics.LatencyBudgetBreachesTarget, webhookUrl)
+	return nil
+}
 
-	p.PreparedStatementCache = NewPreparedStatementCache()
+func (p *ZdmProxy) initializeRequestRetryPolicies() error {
+	originRetryConfig, err := p.Conf.ParseOriginRequestRetryConfig()
+	if err != nil {
+		return err
+	}
 
-	p.controlConnShutdownCtx, p.controlConnCancelFn = context.WithCancel(context.Background())
-	p.controlConnShutdownWg = &sync.WaitGroup{}
-	p.listenerShutdownWg = &sync.WaitGroup{}
-	p.shutdownClientListenerChan = make(chan bool)
+	targetRetryConfig, err := p.Conf.ParseTargetRequestRetryConfig()
+	if err != nil {
+		return err
+	}
 
-	p.originBuckets, err = p.Conf.ParseOriginBuckets()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	proxyMetrics := p.metricHandler.GetProxyMetrics()
+	if originRetryConfig.Enabled {
+		log.Infof("Retrying idempotent requests on %v: %v", common.ClusterTypeOrigin, originRetryConfig)
+	}
+	p.originRequestRetryPolicy = newRequestRetryPolicy(originRetryConfig, proxyMetrics.RetriedRequestsOrigin)
+	if targetRetryConfig.Enabled {
+		log.Infof("Retrying idempotent requests on %v: %v", common.ClusterTypeTarget, targetRetryConfig)
+	}
+	p.targetRequestRetryPolicy = newRequestRetryPolicy(targetRetryConfig, proxyMetrics.RetriedRequestsTarget)
+	return nil
+}
+
+func (p *ZdmProxy) initializeRequestTimeoutPolicy() error {
+	timeoutConfig, err := p.Conf.ParseRequestTimeoutConfig()
 	if err != nil {
-		return fmt.Errorf("failed to parse origin latency buckets: %w", err)
-	} else {
-		log.Infof("Parsed Origin latency buckets: %v", p.originBuckets)
+		return err
 	}
 
-	p.targetBuckets, err = p.Conf.ParseTargetBuckets()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if timeoutConfig.OriginMs != timeoutConfig.TargetMs ||
+		timeoutConfig.ReadMs > 0 || timeoutConfig.WriteMs > 0 || timeoutConfig.DdlMs > 0 {
+		log.Infof("Request timeouts: %v", timeoutConfig)
+	}
+	p.requestTimeoutPolicy = newRequestTimeoutPolicy(timeoutConfig)
+	return nil
+}
+
+func (p *ZdmProxy) initializeCircuitBreakers() error {
+	originCircuitBreakerConfig, err := p.Conf.ParseOriginCircuitBreakerConfig()
 	if err != nil {
-		return fmt.Errorf("failed to parse target latency buckets: %w", err)
-	} else {
-		log.Infof("Parsed Target latency buckets: %v", p.targetBuckets)
+		return err
 	}
 
-	p.asyncBuckets, err = p.Conf.ParseAsyncBuckets()
+	targetCircuitBreakerConfig, err := p.Conf.ParseTargetCircuitBreakerConfig()
 	if err != nil {
-		return fmt.Errorf("failed to parse async latency buckets: %w", err)
-	} else {
-		log.Infof("Parsed Async latency buckets: %v", p.asyncBuckets)
+		return err
 	}
 
-	p.activeClients = 0
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	proxyMetrics := p.metricHandler.GetProxyMetrics()
+	p.originCircuitBreaker = p.newCircuitBreaker(
+		common.ClusterTypeOrigin, originCircuitBreakerConfig, proxyMetrics.CircuitBreakerStateOrigin,
+		proxyMetrics.CircuitBreakerRejectedRequestsOrigin)
+	p.targetCircuitBreaker = p.newCircuitBreaker(
+		common.ClusterTypeTarget, targetCircuitBreakerConfig, proxyMetrics.CircuitBreakerStateTarget,
+		proxyMetrics.CircuitBreakerRejectedRequestsTarget)
 	return nil
 }
 
-// acceptConnectionsFromClients creates a listener on the passed in port argument, and every connection
-// that is received over that port instantiates a ClientHandler that then takes over managing that connection
-func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, serverSideTlsConfig *tls.Config) error {
+// initializeSecondaryWriteJournal creates the journal of the writes that failed on the secondary cluster, its writes
+// are replayed until the control connections are shut down.
+func (p *ZdmProxy) initializeSecondaryWriteJournal() error {
+	writeJournalConfig, err := p.Conf.ParseSecondaryWriteJournalConfig()
+	if err != nil {
+		return err
+	}
 
-	protocol := "tcp"
-	listenAddr := fmt.Sprintf("%s:%d", address, port)
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
-	var l net.Listener
-	var err error
-	if serverSideTlsConfig == nil {
-		l, err = net.Listen(protocol, listenAddr)
-	} else {
-		l, err = tls.Listen(protocol, listenAddr, serverSideTlsConfig)
+	secondaryCluster, controlConn, circuitBreaker := common.ClusterTypeTarget, p.targetControlConn, p.targetCircuitBreaker
+	if p.primaryCluster == common.ClusterTypeTarget {
+		secondaryCluster, controlConn, circuitBreaker = common.ClusterTypeOrigin, p.originControlConn, p.originCircuitBreaker
+	}
+	p.secondaryWriteJournal, err = newWriteJournal(
+		secondaryCluster, writeJournalConfig, controlConn, circuitBreaker, p.metricHandler.GetProxyMetrics())
+	if err != nil {
+		return err
+	}
+	if p.secondaryWriteJournal == nil {
+		return nil
 	}
 
+	log.Infof("The writes that fail on %v are replayed from a journal: %v.", secondaryCluster, writeJournalConfig)
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		p.secondaryWriteJournal.run(p.controlConnShutdownCtx)
+	}()
+	return nil
+}
+
+// initializeMigrationProgress creates the migration progress estimation and its gauges, the estimation is updated
+// until the control connections are shut down.
+func (p *ZdmProxy) initializeMigrationProgress() error {
+	migrationProgressConfig, err := p.Conf.ParseMigrationProgressConfig()
 	if err != nil {
 		return err
 	}
 
-	p.listenerLock.Lock()
-	p.clientListener = l
-	p.listenerLock.Unlock()
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
-	p.listenerShutdownWg.Add(1)
+	p.migrationProgress = newMigrationProgress(migrationProgressConfig, p.secondaryWriteJournal)
+	if p.migrationProgress == nil {
+		return nil
+	}
 
-	go func() {
-		defer p.listenerShutdownWg.Done()
-		defer func() {
-			p.listenerLock.Lock()
-			defer p.listenerLock.Unlock()
-			if !p.listenerClosed {
-				p.listenerClosed = true
-				_ = l.Close()
-			}
-		}()
-		wg := &sync.WaitGroup{}
-		defer wg.Wait()
-		for {
-			conn, err := l.Accept()
-			if err != nil {
-				p.listenerLock.Lock()
-				listenerClosed := p.listenerClosed
-				p.listenerLock.Unlock()
+	migrationProgress := p.migrationProgress
+	err = p.metricHandler.CreateMigrationProgressGauges(
+		func() float64 {
+			return migrationProgress.getStatus().OriginReadsRatio
+		},
+		func() float64 {
+			return migrationProgress.getStatus().DualWriteFailuresRatio
+		},
+		func() float64 {
+			return migrationProgress.getStatus().ReadMismatchesRatio
+		},
+		func() float64 {
+			return float64(migrationProgress.getStatus().ReplayQueueDepth)
+		})
+	if err != nil {
+		return fmt.Errorf("could not create the migration progress metrics: %w", err)
+	}
 
-				if listenerClosed {
-					log.Debugf("Shutting down client listener on port %d", port)
-					return
-				}
+	log.Infof("Migration progress estimation enabled: %v.", migrationProgressConfig)
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		migrationProgress.run(p.controlConnShutdownCtx)
+	}()
+	return nil
+}
 
-				log.Errorf("Error while listening for new connections: %v", err)
-				continue
-			}
+// initializeTracing creates the tracer of the requests, its spans are exported until the control connections are
+// shut down.
+func (p *ZdmProxy) initializeTracing() error {
+	tracingConfig, err := p.Conf.ParseTracingConfig()
+	if err != nil {
+		return err
+	}
 
-			currentClients := atomic.LoadInt32(&p.activeClients)
-			if int(currentClients) >= p.Conf.ProxyMaxClientConnections {
-				log.Warnf(
-					"Refusing client connection from %v because max clients threshold has been hit (%v).",
-					conn.RemoteAddr(), p.Conf.ProxyMaxClientConnections)
-				err = conn.Close()
-				if err != nil {
-					log.Warnf("Error closing client connection from %v: %v", conn.RemoteAddr(), err)
-				}
-				continue
-			}
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
-			atomic.AddInt32(&p.activeClients, 1)
-			log.Infof("Accepted connection from %v", conn.RemoteAddr())
+	p.tracer = newTracer(tracingConfig)
+	if p.tracer == nil {
+		return nil
+	}
 
-			wg.Add(1)
-			p.listenerScheduler.Schedule(func() {
-				defer wg.Done()
-				p.handleNewConnection(conn)
-			})
-		}
+	log.Infof("Tracing of the requests enabled: %v.", tracingConfig)
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		p.tracer.run(p.controlConnShutdownCtx)
 	}()
+	return nil
+}
+
+// initializeSlowQueryLog creates the log of the requests that take longer than ZDM_SLOW_QUERY_LOG_THRESHOLD_MS.
+func (p *ZdmProxy) initializeSlowQueryLog() error {
+	slowQueryLogConfig, err := p.Conf.ParseSlowQueryLogConfig()
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
+	p.slowQueryLog = newSlowQueryLog(slowQueryLogConfig, p.metricHandler.GetProxyMetrics().SlowQueries)
+	if p.slowQueryLog != nil {
+		log.Infof("Slow query log enabled: %v.", slowQueryLogConfig)
+	}
 	return nil
 }
 
-// handleNewConnection creates the client handler and connectors for the new client connection
-func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
+// initializeAuditLog opens the audit log of the requests if ZDM_AUDIT_LOG_DESTINATION is set.
+func (p *ZdmProxy) initializeAuditLog() error {
+	auditLogConfig, err := p.Conf.ParseAuditLogConfig()
+	if err != nil {
+		return err
+	}
 
-	errFunc := func(e error) {
-		log.Errorf("Client Handler could not be created: %v", e)
-		clientConn.Close()
-		atomic.AddInt32(&p.activeClients, -1)
+	auditLog, err := newAuditLog(auditLogConfig)
+	if err != nil {
+		return err
 	}
 
-	// there is a ClientHandler for each connection made by a client
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.auditLog = auditLog
+	if p.auditLog != nil {
+		log.Infof("Audit log enabled: %v.", auditLogConfig)
+	}
+	return nil
+}
+
+// newCircuitBreaker returns nil if the circuit breaker is disabled, otherwise the error rate is evaluated until the
+// control connections are shut down.
+func (p *ZdmProxy) newCircuitBreaker(
+	clusterType common.ClusterType, conf *common.CircuitBreakerConfig, stateGauge metrics.Gauge,
+	rejectedRequests metrics.Counter) *circuitBreaker {
+	circuitBreaker := newCircuitBreaker(clusterType, conf, stateGauge, rejectedRequests)
+	if circuitBreaker == nil {
+		return nil
+	}
+
+	log.Infof("Circuit breaker of %v enabled: %v (degrade to primary only: %v).",
+		clusterType, conf, p.Conf.CircuitBreakerDegradeToPrimaryOnly)
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		circuitBreaker.run(p.controlConnShutdownCtx)
+	}()
+	return circuitBreaker
+}
+
+// newLatencyBudget returns nil if the latency budget is disabled, otherwise the budget is evaluated until the control
+// connections are shut down.
+func (p *ZdmProxy) newLatencyBudget(
+	clusterType common.ClusterType, conf *common.LatencyBudgetConfig, breaches metrics.Counter, webhookUrl string) *latencyBudget {
+	latencyBudget := newLatencyBudget(clusterType, conf, breaches, webhookUrl)
+	if latencyBudget == nil {
+		return nil
+	}
+
+	log.Infof("Alerting when the latency of %v exceeds its budget: %v (webhook enabled: %v).",
+		clusterType, conf, webhookUrl != "")
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		latencyBudget.run(p.controlConnShutdownCtx)
+	}()
+	return latencyBudget
+}
+
+// newRateLimiter returns nil if maxRequestsPerSecond is 0. If the fleet store is enabled then the limit is shared
+// with the other proxy instances until the control connections are shut down.
+func (p *ZdmProxy) newRateLimiter(
+	clusterType common.ClusterType, maxRequestsPerSecond int, rateLimitedRequests metrics.Counter,
+	fleetStoreConfig *common.FleetStoreConfig, instance string) *rateLimiter {
+	if maxRequestsPerSecond == 0 {
+		return nil
+	}
+
+	reports := newFleetReports(fleetStoreConfig, fmt.Sprintf("%v/rates/%v", fleetStoreConfig.Key, strings.ToLower(string(clusterType))))
+	rateLimiter := newRateLimiter(clusterType, maxRequestsPerSecond, rateLimitedRequests, reports, instance)
+	if reports == nil {
+		log.Infof("Limiting the rate of requests to %v: %v requests per second.", clusterType, maxRequestsPerSecond)
+		return rateLimiter
+	}
+
+	log.Infof("Limiting the rate of requests to %v: %v requests per second shared with the other proxy instances "+
+		"through the %v fleet store (%v).", clusterType, maxRequestsPerSecond, fleetStoreConfig.Type, fleetStoreConfig.Endpoints)
+	p.controlConnShutdownWg.Add(1)
+	go func() {
+		defer p.controlConnShutdownWg.Done()
+		rateLimiter.run(p.controlConnShutdownCtx)
+	}()
+	return rateLimiter
+}
+
+// getReadWriteNumWorkers returns the number of read and write workers (ZDM_READ_MAX_WORKERS and
+// ZDM_WRITE_MAX_WORKERS), the defaults are higher if the requests are also forwarded asynchronously.
+func (p *ZdmProxy) getReadWriteNumWorkers(conf *config.Config) (int, int) {
+	maxProcs := runtime.GOMAXPROCS(0)
+	defaultReadWorkers := maxProcs * 8
+	defaultWriteWorkers := maxProcs * 4
+	if p.readMode == common.ReadModeDualAsyncOnSecondary || p.dualWriteMode == common.DualWriteModeAsync ||
+		conf.ReadMirroringEnabled {
+		defaultReadWorkers = maxProcs * 12
+		defaultWriteWorkers = maxProcs * 6
+	}
+
+	writeNumWorkers := conf.WriteMaxWorkers
+	if writeNumWorkers == -1 {
+		writeNumWorkers = defaultWriteWorkers // default
+	} else if writeNumWorkers <= 0 {
+		log.Warnf("Invalid number of write workers %d, using default (%d).", writeNumWorkers, defaultWriteWorkers)
+		writeNumWorkers = defaultWriteWorkers
+	}
+
+	readNumWorkers := conf.ReadMaxWorkers
+	if readNumWorkers == -1 {
+		readNumWorkers = defaultReadWorkers // default
+	} else if readNumWorkers <= 0 {
+		log.Warnf("Invalid number of read workers %d, using default (%d).", readNumWorkers, defaultReadWorkers)
+		readNumWorkers = defaultReadWorkers
+	}
+	return readNumWorkers, writeNumWorkers
+}
+
+func (p *ZdmProxy) initializeGlobalStructures() error {
+	p.lock = &sync.RWMutex{}
+	p.runtimeConf = p.Conf
+	p.reloadLock = &sync.Mutex{}
+
+	p.listenerLock = &sync.Mutex{}
+	p.listenerClosed = false
+	p.proxyRand = NewThreadSafeRand()
+
+	maxProcs := runtime.GOMAXPROCS(0)
 
-	var originEndpoint Endpoint
-	var originHost *Host
 	var err error
-	if p.Conf.OriginEnableHostAssignment {
-		originHost, err = p.originControlConn.NextAssignedHost()
-		if err != nil {
-			errFunc(err)
-			return
-		}
-		originEndpoint = p.originConnectionConfig.CreateEndpoint(originHost)
-	} else {
-		originEndpoint = p.originControlConn.GetCurrentContactPoint()
-		if originEndpoint == nil {
-			log.Warnf("Origin ControlConnection current endpoint is nil, "+
-				"falling back to first origin contact point (%v) for client connection %v.",
-				p.originConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
-		}
+	p.readMode, err = p.Conf.ParseReadMode()
+	if err != nil {
+		return err
 	}
 
-	var targetEndpoint Endpoint
-	var targetHost *Host
-	if p.Conf.TargetEnableHostAssignment {
-		targetHost, err = p.targetControlConn.NextAssignedHost()
-		if err != nil {
-			errFunc(err)
-			return
-		}
-		targetEndpoint = p.targetConnectionConfig.CreateEndpoint(targetHost)
-	} else {
-		targetEndpoint = p.targetControlConn.GetCurrentContactPoint()
-		if targetEndpoint == nil {
-			log.Warnf("Target ControlConnection current endpoint is nil, "+
-				"falling back to first target contact point (%v) for client connection %v.",
-				p.targetConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
-		}
+	p.dualWriteMode, err = p.Conf.ParseDualWriteMode()
+	if err != nil {
+		return err
 	}
 
-	originCassandraConnInfo := NewClusterConnectionInfo(p.originConnectionConfig, originEndpoint, true)
-	targetCassandraConnInfo := NewClusterConnectionInfo(p.targetConnectionConfig, targetEndpoint, false)
-	clientHandler, err := NewClientHandler(
-		clientConn,
-		originCassandraConnInfo,
-		targetCassandraConnInfo,
-		p.originControlConn,
-		p.targetControlConn,
-		p.Conf,
-		p.TopologyConfig,
-		p.Conf.TargetUsername,
-		p.Conf.TargetPassword,
-		p.Conf.OriginUsername,
-		p.Conf.OriginPassword,
-		p.PreparedStatementCache,
-		p.metricHandler,
-		p.globalClientHandlersWg,
-		p.requestResponseScheduler,
-		p.readScheduler,
-		p.writeScheduler,
-		p.requestResponseNumWorkers,
-		p.clientHandlersShutdownRequestCtx,
-		originHost,
-		targetHost,
-		p.timeUuidGenerator,
-		p.readMode,
-		p.primaryCluster,
-		p.systemQueriesMode)
+	p.lwtPolicy, err = p.Conf.ParseLwtPolicy()
+	if err != nil {
+		return err
+	}
+
+	p.counterWritesPolicy, err = p.Conf.ParseCounterWritesPolicy()
+	if err != nil {
+		return err
+	}
+
+	p.pagingStatePolicy, err = p.Conf.ParsePagingStatePolicy()
+	if err != nil {
+		return err
+	}
+
+	p.secondaryWriteFailureMode, err = p.Conf.ParseSecondaryWriteFailureMode()
+	if err != nil {
+		return err
+	}
+
+	p.primaryCluster, err = p.Conf.ParsePrimaryCluster()
+	if err != nil {
+		return err
+	}
+
+	p.systemQueriesMode, err = p.Conf.ParseSystemQueriesMode()
+	if err != nil {
+		return err
+	}
+
+	p.maxProtocolVersion, err = p.Conf.ParseProxyMaxProtocolVersion()
+	if err != nil {
+		return err
+	}
+
+	p.requestResponseNumWorkers = p.Conf.RequestResponseMaxWorkers
+	if p.requestResponseNumWorkers == -1 {
+		p.requestResponseNumWorkers = maxProcs * 4 // default
+	} else if p.requestResponseNumWorkers <= 0 {
+		log.Warnf("Invalid number of request / response workers %d, using GOMAXPROCS * 4 (%d).", p.requestResponseNumWorkers, maxProcs*4)
+		p.requestResponseNumWorkers = maxProcs * 4
+	}
+	log.Infof("Using %d request / response workers.", p.requestResponseNumWorkers)
+
+	p.readNumWorkers, p.writeNumWorkers = p.getReadWriteNumWorkers(p.Conf)
+	log.Infof("Using %d write workers.", p.writeNumWorkers)
+	log.Infof("Using %d read workers.", p.readNumWorkers)
+
+	p.listenerNumWorkers = p.Conf.ListenerMaxWorkers
+	if p.listenerNumWorkers == -1 {
+		p.listenerNumWorkers = maxProcs // default
+	} else if p.listenerNumWorkers <= 0 {
+		log.Warnf("Invalid number of connection listener workers %d, using GOMAXPROCS (%d).", p.listenerNumWorkers, maxProcs)
+		p.listenerNumWorkers = maxProcs
+	}
+	log.Infof("Using %d listener workers.", p.listenerNumWorkers)
+
+	p.requestResponseScheduler = NewScheduler(p.requestResponseNumWorkers)
+	p.writeScheduler = NewScheduler(p.writeNumWorkers)
+	p.readScheduler = NewScheduler(p.readNumWorkers)
+	p.listenerScheduler = NewScheduler(p.listenerNumWorkers)
+
+	stallDetectionConfig, err := p.Conf.ParseStallDetectionConfig()
+	if err != nil {
+		return err
+	}
+	p.stallWatchdog = newStallWatchdog(stallDetectionConfig, &p.activeClients)
+	if p.stallWatchdog != nil {
+		log.Infof("Logging diagnostics when an internal queue is stalled: %v.", stallDetectionConfig)
+		p.stallWatchdog.registerScheduler("request/response worker pool", p.requestResponseScheduler)
+		p.stallWatchdog.registerScheduler("write worker pool", p.writeScheduler)
+		p.stallWatchdog.registerScheduler("read worker pool", p.readScheduler)
+		p.stallWatchdog.registerScheduler("listener worker pool", p.listenerScheduler)
+	}
+
+	runtimeStatsLogInterval, err := p.Conf.ParseRuntimeStatsLogInterval()
+	if err != nil {
+		return err
+	}
+	p.runtimeStatsLogger = newRuntimeStatsLogger(runtimeStatsLogInterval)
+	if p.runtimeStatsLogger != nil {
+		log.Infof("Logging the runtime stats every %v ms.", runtimeStatsLogInterval)
+	}
+	if p.Conf.DiagnosticsEnabled {
+		log.Infof("Serving the runtime profiles and diagnostics on the admin API.")
+	}
+
+	clusterConnPoolSize, err := p.Conf.ParseProxyClusterConnectionPoolSize()
+	if err != nil {
+		return err
+	}
+	clusterConnectionsPerClient, err := p.Conf.ParseProxyClusterConnectionsPerClient()
+	if err != nil {
+		return err
+	}
+	clusterConnectionDispatchPolicy, err := p.Conf.ParseProxyClusterConnectionDispatchPolicy()
+	if err != nil {
+		return err
+	}
+	heartbeatConfig, err := p.Conf.ParseClusterConnectionHeartbeatConfig()
+	if err != nil {
+		return err
+	}
+	if clusterConnPoolSize > 0 && clusterConnectionsPerClient > 1 {
+		log.Warnf("ZDM_PROXY_CLUSTER_CONNECTIONS_PER_CLIENT is ignored because the cluster connections are pooled.")
+	} else if clusterConnectionsPerClient > 1 {
+		log.Infof("Client connections will open %d connections per cluster, requests are dispatched with the %v policy.",
+			clusterConnectionsPerClient, clusterConnectionDispatchPolicy)
+		p.perClientConnections = newPerClientConnections(
+			p.Conf, clusterConnectionsPerClient, clusterConnectionDispatchPolicy, p.writeScheduler, p.stallWatchdog,
+			heartbeatConfig)
+	}
+	if (clusterConnPoolSize > 0 || p.perClientConnections != nil) && heartbeatConfig.Enabled {
+		log.Infof("Heartbeats will be sent on the idle shared cluster connections: %v.", heartbeatConfig)
+	}
+	if clusterConnPoolSize > 0 {
+		log.Infof("Client connections will share up to %d connections per cluster node.", clusterConnPoolSize)
+		p.clusterConnPool = newClusterConnPool(
+			p.Conf, clusterConnPoolSize, p.writeScheduler, p.stallWatchdog, heartbeatConfig)
+		if p.Conf.ProxyClusterConnectionFailoverEnabled {
+			log.Warnf("ZDM_PROXY_CLUSTER_CONNECTION_FAILOVER_ENABLED is ignored because the cluster connections are pooled.")
+		}
+		if p.Conf.TokenAwareRoutingEnabled {
+			log.Info("Requests will be forwarded to the node that owns their partition key.")
+		}
+	} else if p.Conf.ProxyClusterConnectionFailoverEnabled {
+		log.Info("Cluster connections will fail over to another node when their node goes down.")
+	}
+
+	if p.Conf.ProxyHandshakeFastPathEnabled {
+		log.Info("Handshake fast path is enabled.")
+		p.handshakeCache = newHandshakeCache()
+	}
+
+	p.proxyAuthenticator, err = newProxyAuthenticator(p.Conf)
+	if err != nil {
+		return err
+	}
+	if p.proxyAuthenticator != nil {
+		log.Info("The clients are authenticated by the proxy, the configured credentials are used for both clusters.")
+	}
+
+	p.interceptedResponseCache = newInterceptedResponseCache(
+		time.Duration(p.Conf.InterceptedQueriesCacheTtlMs) * time.Millisecond)
+
+	targetWriteFilterRules, err := p.Conf.ParseTargetWriteFilterRules()
+	if err != nil {
+		return err
+	}
+	if len(targetWriteFilterRules) > 0 {
+		log.Infof("Writes that match any of the following rules will not be forwarded to %v: %v",
+			common.ClusterTypeTarget, targetWriteFilterRules)
+		p.targetWriteFilter = newTargetWriteFilter(targetWriteFilterRules)
+	}
+
+	originConsistencyOverride, err := p.Conf.ParseOriginConsistencyOverride()
+	if err != nil {
+		return err
+	}
+	p.originConsistencyOverride = newConsistencyOverride(common.ClusterTypeOrigin, originConsistencyOverride)
+	if p.originConsistencyOverride != nil {
+		log.Infof("The consistency level of the requests forwarded to %v will be overridden: %v",
+			common.ClusterTypeOrigin, p.Conf.OriginConsistencyOverride)
+	}
+
+	targetConsistencyOverride, err := p.Conf.ParseTargetConsistencyOverride()
+	if err != nil {
+		return err
+	}
+	p.targetConsistencyOverride = newConsistencyOverride(common.ClusterTypeTarget, targetConsistencyOverride)
+	if p.targetConsistencyOverride != nil {
+		log.Infof("The consistency level of the requests forwarded to %v will be overridden: %v",
+			common.ClusterTypeTarget, p.Conf.TargetConsistencyOverride)
+	}
+
+	targetWriteSamplingPercentage, err := p.Conf.ParseTargetWriteSamplingPercentage()
+	if err != nil {
+		return err
+	}
+	p.targetWriteSampler = newTargetWriteSampler(
+		targetWriteSamplingPercentage, p.Conf.TargetWriteSamplingIgnoreTargetFailures, p.proxyRand)
+	if p.targetWriteSampler != nil {
+		log.Infof("Only %v%% of the writes will be forwarded to %v (ignore %v failures: %v).",
+			targetWriteSamplingPercentage, common.ClusterTypeTarget, common.ClusterTypeTarget,
+			p.Conf.TargetWriteSamplingIgnoreTargetFailures)
+	}
+
+	writeComparisonSamplePercentage, err := p.Conf.ParseWriteComparisonSamplePercentage()
+	if err != nil {
+		return err
+	}
+	p.writeComparisonSampler = newWriteComparisonSampler(writeComparisonSamplePercentage, p.proxyRand)
+	if p.writeComparisonSampler != nil {
+		log.Infof("The %v and %v responses of %v%% of the dual writes will be compared.",
+			common.ClusterTypeOrigin, common.ClusterTypeTarget, writeComparisonSamplePercentage)
+	}
+
+	targetDdlUnsupportedFeatures, err := p.Conf.ParseTargetDdlUnsupportedFeatures()
+	if err != nil {
+		return err
+	}
+	targetDdlCompatibilityMode, err := p.Conf.ParseTargetDdlCompatibilityMode()
+	if err != nil {
+		return err
+	}
+	targetDdlUnsupportedCompactionStrategies := p.Conf.ParseTargetDdlUnsupportedCompactionStrategies()
+	p.targetDdlChecker = newTargetDdlChecker(
+		targetDdlUnsupportedFeatures, targetDdlUnsupportedCompactionStrategies, targetDdlCompatibilityMode)
+	if p.targetDdlChecker != nil {
+		log.Infof("Schema changes that use features that %v doesn't support (%v, compaction strategies %v) "+
+			"will be handled with mode %v.", common.ClusterTypeTarget, targetDdlUnsupportedFeatures,
+			targetDdlUnsupportedCompactionStrategies, targetDdlCompatibilityMode)
+	}
+
+	materializedViewStatementsPolicy, err := p.Conf.ParseMaterializedViewStatementsPolicy()
+	if err != nil {
+		return err
+	}
+	indexStatementsPolicy, err := p.Conf.ParseIndexStatementsPolicy()
+	if err != nil {
+		return err
+	}
+	customIndexStatementsPolicy, err := p.Conf.ParseCustomIndexStatementsPolicy()
+	if err != nil {
+		return err
+	}
+	p.schemaStatementPolicies = newSchemaStatementPolicies(
+		materializedViewStatementsPolicy, indexStatementsPolicy, customIndexStatementsPolicy)
+	if p.schemaStatementPolicies != nil {
+		log.Infof("Schema statement policies: materialized views %v, indexes %v, custom indexes %v.",
+			materializedViewStatementsPolicy, indexStatementsPolicy, customIndexStatementsPolicy)
+	}
+
+	destructiveStatementsConfirmationConfig, err := p.Conf.ParseDestructiveStatementsConfirmationConfig()
+	if err != nil {
+		return err
+	}
+	p.destructiveStatementGuard = newDestructiveStatementGuard(destructiveStatementsConfirmationConfig)
+	if p.destructiveStatementGuard != nil {
+		log.Infof("TRUNCATE and DROP statements must be confirmed through the admin API: %v.",
+			destructiveStatementsConfirmationConfig)
+	}
+
+	applicationReadRoutingRules, err := p.Conf.ParseApplicationReadRoutingRules()
+	if err != nil {
+		return err
+	}
+	p.applicationReadRouting = newApplicationReadRouting(applicationReadRoutingRules)
+	if p.applicationReadRouting != nil {
+		log.Infof("Application read routing rules: %v.", applicationReadRoutingRules)
+	}
+
+	tableRoutingRules, err := p.Conf.ParseTableRoutingRules()
+	if err != nil {
+		return err
+	}
+	if len(tableRoutingRules) > 0 {
+		log.Infof("The statements on the tables of the following rules will only be forwarded to the cluster of the rule: %v",
+			tableRoutingRules)
+		p.tableRoutingRules = newTableRoutingRules(tableRoutingRules)
+	}
+
+	statementFilterRules, err := p.Conf.ParseStatementFilterRules()
+	if err != nil {
+		return err
+	}
+	p.statementFilter = newStatementFilter(statementFilterRules)
+	if p.statementFilter != nil {
+		log.Infof("The statements that match the following rules will be rejected or only forwarded to the cluster of the rule: %v",
+			statementFilterRules)
+	}
+
+	adminKeyspaceRoles, err := p.Conf.ParseAdminKeyspaceRoles()
+	if err != nil {
+		return err
+	}
+	p.adminKeyspace = newAdminKeyspace(adminKeyspaceRoles, p.primaryCluster)
+	if p.adminKeyspace != nil {
+		log.Infof("The %v keyspace can be used by the roles %v.", adminKeyspaceName, adminKeyspaceRoles)
+	}
+
+	fleetStoreConfig, err := p.Conf.ParseFleetStoreConfig()
+	if err != nil {
+		return err
+	}
+	p.fleetCoordinator = newFleetCoordinator(fleetStoreConfig, p.adminKeyspace)
+	if p.fleetCoordinator != nil {
+		log.Infof("The settings of the %v keyspace are shared with the other proxy instances through the %v (%v).",
+			adminKeyspaceName, p.fleetCoordinator.description, fleetStoreConfig.Endpoints)
+	}
+
+	leaderElectionConfig, err := p.Conf.ParseLeaderElectionConfig()
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	p.leaderElection = newLeaderElection(leaderElectionConfig, fleetStoreConfig,
+		fmt.Sprintf("%v (%v)", hostname, net.JoinHostPort(p.Conf.ProxyListenAddress, strconv.Itoa(p.Conf.ProxyListenPort))))
+	if p.leaderElection != nil {
+		log.Infof("The singleton background tasks run on the proxy instance that is elected through the %v.",
+			p.leaderElection.description)
+	}
+
+	trafficCaptureConfig, err := p.Conf.ParseTrafficCaptureConfig()
+	if err != nil {
+		return err
+	}
+	p.trafficCapture = newTrafficCapture(trafficCaptureConfig)
+	if p.trafficCapture != nil {
+		log.Infof("Traffic captures can be started with the admin API: %v.", trafficCaptureConfig)
+	}
+	p.frameLogger = newFrameLogger()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.globalClientHandlersWg = &sync.WaitGroup{}
+	p.clientHandlersShutdownRequestCtx, p.clientHandlersShutdownRequestCancelFn = context.WithCancel(context.Background())
+
+	p.PreparedStatementCache = NewPreparedStatementCache()
+	p.connectedClients = newConnectedClients()
+
+	if p.Conf.ProxyVirtualTablesEnabled {
+		log.Infof("The state of the proxy can be queried through the %v.%v, %v.%v and %v.%v tables.",
+			systemViewsKeyspaceName, proxyClientsTableName, systemViewsKeyspaceName, proxyPreparedStatementsTableName,
+			systemViewsKeyspaceName, proxyRoutingRulesTableName)
+		p.proxyVirtualTables = newProxyVirtualTables(
+			p.connectedClients, p.PreparedStatementCache, targetWriteFilterRules, applicationReadRoutingRules, tableRoutingRules)
+	}
+
+	p.controlConnShutdownCtx, p.controlConnCancelFn = context.WithCancel(context.Background())
+	p.controlConnShutdownWg = &sync.WaitGroup{}
+	p.listenerShutdownWg = &sync.WaitGroup{}
+	p.shutdownClientListenerChan = make(chan bool)
+
+	if p.stallWatchdog != nil {
+		p.controlConnShutdownWg.Add(1)
+		go func() {
+			defer p.controlConnShutdownWg.Done()
+			p.stallWatchdog.run(p.controlConnShutdownCtx)
+		}()
+	}
+
+	if p.runtimeStatsLogger != nil {
+		p.controlConnShutdownWg.Add(1)
+		go func() {
+			defer p.controlConnShutdownWg.Done()
+			p.runtimeStatsLogger.run(p.controlConnShutdownCtx)
+		}()
+	}
+
+	p.originBuckets, err = p.Conf.ParseOriginBuckets()
+	if err != nil {
+		return fmt.Errorf("failed to parse origin latency buckets: %w", err)
+	} else {
+		log.Infof("Parsed Origin latency buckets: %v", p.originBuckets)
+	}
+
+	p.targetBuckets, err = p.Conf.ParseTargetBuckets()
+	if err != nil {
+		return fmt.Errorf("failed to parse target latency buckets: %w", err)
+	} else {
+		log.Infof("Parsed Target latency buckets: %v", p.targetBuckets)
+	}
+
+	p.asyncBuckets, err = p.Conf.ParseAsyncBuckets()
+	if err != nil {
+		return fmt.Errorf("failed to parse async latency buckets: %w", err)
+	} else {
+		log.Infof("Parsed Async latency buckets: %v", p.asyncBuckets)
+	}
+
+	bucketOverrides, err := p.Conf.ParseHistogramBucketsOverrides()
+	if err != nil {
+		return fmt.Errorf("failed to parse histogram buckets overrides: %w", err)
+	}
+	for name, buckets := range bucketOverrides {
+		log.Infof("Parsed %v buckets: %v", name, buckets)
+	}
+
+	nativeHistogramBucketFactor, err := p.Conf.ParseNativeHistogramBucketFactor()
+	if err != nil {
+		return err
+	}
+	if nativeHistogramBucketFactor > 0 {
+		log.Infof("Native histograms enabled with bucket factor %v.", nativeHistogramBucketFactor)
+	}
+
+	p.histogramConfig = &metrics.HistogramConfig{
+		BucketOverrides:             bucketOverrides,
+		NativeHistogramBucketFactor: nativeHistogramBucketFactor,
+	}
+
+	p.activeClients = 0
+	return nil
+}
+
+// acceptConnectionsFromClients creates a listener on the passed in port argument (or uses the injected listeners),
+// and every connection that is received over it instantiates a ClientHandler that then takes over managing that connection
+func (p *ZdmProxy) acceptConnectionsFromClients(address string, port int, serverSideTlsConfig *tls.Config) error {
+
+	protocol := "tcp"
+	listenAddr := net.JoinHostPort(address, strconv.Itoa(port))
+
+	socketOptions, err := p.Conf.ParseProxySocketOptions()
+	if err != nil {
+		return err
+	}
+
+	var listeners []net.Listener
+	if p.options != nil && len(p.options.Listeners) > 0 {
+		for _, l := range p.options.Listeners {
+			l = newSocketOptionsListener(l, socketOptions)
+			if serverSideTlsConfig != nil {
+				l = tls.NewListener(l, serverSideTlsConfig)
+			}
+			listeners = append(listeners, l)
+		}
+	} else {
+		l, err := net.Listen(protocol, listenAddr)
+		if err != nil {
+			return err
+		}
+		l = newSocketOptionsListener(l, socketOptions)
+		if serverSideTlsConfig != nil {
+			l = tls.NewListener(l, serverSideTlsConfig)
+		}
+		listeners = append(listeners, l)
+	}
+
+	p.listenerLock.Lock()
+	p.clientListeners = listeners
+	p.listenerLock.Unlock()
+
+	for _, l := range listeners {
+		p.listenerShutdownWg.Add(1)
+		atomic.AddInt32(&p.activeListeners, 1)
+		go p.acceptLoop(l)
+	}
+
+	return nil
+}
+
+func (p *ZdmProxy) acceptLoop(l net.Listener) {
+	defer p.listenerShutdownWg.Done()
+	defer atomic.AddInt32(&p.activeListeners, -1)
+	defer func() {
+		p.listenerLock.Lock()
+		defer p.listenerLock.Unlock()
+		if !p.listenerClosed {
+			_ = l.Close()
+		}
+	}()
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			p.listenerLock.Lock()
+			listenerClosed := p.listenerClosed
+			p.listenerLock.Unlock()
+
+			if listenerClosed {
+				log.Debugf("Shutting down client listener on %v", l.Addr())
+				return
+			}
+
+			if errors.Is(err, net.ErrClosed) {
+				log.Warnf("Client listener on %v was closed, no longer accepting connections on it.", l.Addr())
+				return
+			}
+
+			log.Errorf("Error while listening for new connections: %v", err)
+			continue
+		}
+
+		currentClients := atomic.LoadInt32(&p.activeClients)
+		if int(currentClients) >= p.Conf.ProxyMaxClientConnections {
+			log.Warnf(
+				"Refusing client connection from %v because max clients threshold has been hit (%v).",
+				conn.RemoteAddr(), p.Conf.ProxyMaxClientConnections)
+			err = conn.Close()
+			if err != nil {
+				log.Warnf("Error closing client connection from %v: %v", conn.RemoteAddr(), err)
+			}
+			continue
+		}
+
+		atomic.AddInt32(&p.activeClients, 1)
+		wg.Add(1)
+		if p.options == nil || p.options.OnClientConnect == nil {
+			p.scheduleNewConnection(conn, wg)
+			continue
+		}
+		// the callback can be slow (e.g. it calls an external service) so it is not called by the accept loop
+		go p.scheduleNewConnection(conn, wg)
+	}
+}
+
+// scheduleNewConnection calls the OnClientConnect callback of the options and schedules the creation of the client
+// handler if the connection is not refused, wg is done once the connection is handled or refused.
+func (p *ZdmProxy) scheduleNewConnection(conn net.Conn, wg *sync.WaitGroup) {
+	if p.options != nil && p.options.OnClientConnect != nil {
+		err := p.options.OnClientConnect(conn.RemoteAddr())
+		if err != nil {
+			log.Infof("Refusing client connection from %v: %v", conn.RemoteAddr(), err)
+			_ = conn.Close()
+			atomic.AddInt32(&p.activeClients, -1)
+			wg.Done()
+			return
+		}
+	}
+
+	if p.options != nil {
+		conn = newHookedConn(conn, p.options.OnClientDisconnect)
+	}
+	log.Infof("Accepted connection from %v", conn.RemoteAddr())
+
+	p.listenerScheduler.Schedule(func() {
+		defer wg.Done()
+		p.handleNewConnection(conn)
+	})
+}
+
+// handleNewConnection creates the client handler and connectors for the new client connection
+func (p *ZdmProxy) handleNewConnection(clientConn net.Conn) {
+
+	errFunc := func(e error) {
+		log.Errorf("Client Handler could not be created: %v", e)
+		clientConn.Close()
+		atomic.AddInt32(&p.activeClients, -1)
+	}
+
+	// there is a ClientHandler for each connection made by a client
+
+	var originEndpoint Endpoint
+	var originHost *Host
+	var err error
+	if p.Conf.OriginEnableHostAssignment {
+		originHost, err = p.originControlConn.NextAssignedHost()
+		if err != nil {
+			errFunc(err)
+			return
+		}
+		originEndpoint = p.originConnectionConfig.CreateEndpoint(originHost)
+	} else {
+		originEndpoint = p.originControlConn.GetCurrentContactPoint()
+		if originEndpoint == nil {
+			log.Warnf("Origin ControlConnection current endpoint is nil, "+
+				"falling back to first origin contact point (%v) for client connection %v.",
+				p.originConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
+		}
+	}
+
+	var targetEndpoint Endpoint
+	var targetHost *Host
+	if p.Conf.TargetEnableHostAssignment {
+		targetHost, err = p.targetControlConn.NextAssignedHost()
+		if err != nil {
+			errFunc(err)
+			return
+		}
+		targetEndpoint = p.targetConnectionConfig.CreateEndpoint(targetHost)
+	} else {
+		targetEndpoint = p.targetControlConn.GetCurrentContactPoint()
+		if targetEndpoint == nil {
+			log.Warnf("Target ControlConnection current endpoint is nil, "+
+				"falling back to first target contact point (%v) for client connection %v.",
+				p.targetConnectionConfig.GetContactPoints()[0].String(), clientConn.RemoteAddr().String())
+		}
+	}
+
+	var originFailoverControlConn, targetFailoverControlConn *ControlConn
+	if p.Conf.ProxyClusterConnectionFailoverEnabled && p.clusterConnPool == nil {
+		originFailoverControlConn, targetFailoverControlConn = p.originControlConn, p.targetControlConn
+		// the cluster connections fail over to another host instead of closing the client connection
+		originHost, targetHost = nil, nil
+	}
+	var perClientConns *perClientConnections
+	if originFailoverControlConn == nil {
+		perClientConns = p.perClientConnections
+	}
+
+	originCredentials := p.clusterCredentials.get(common.ClusterTypeOrigin)
+	targetCredentials := p.clusterCredentials.get(common.ClusterTypeTarget)
+	originCassandraConnInfo := NewClusterConnectionInfo(
+		p.originConnectionConfig, originEndpoint, true, p.clusterConnPool, p.originRequestLimiter, p.originRateLimiter,
+		p.originCircuitBreaker, originFailoverControlConn, p.originTokenRouter, perClientConns)
+	targetCassandraConnInfo := NewClusterConnectionInfo(
+		p.targetConnectionConfig, targetEndpoint, false, p.clusterConnPool, p.targetRequestLimiter, p.targetRateLimiter,
+		p.targetCircuitBreaker, targetFailoverControlConn, p.targetTokenRouter, perClientConns)
+	clientHandler, err := NewClientHandler(
+		clientConn,
+		originCassandraConnInfo,
+		targetCassandraConnInfo,
+		p.originControlConn,
+		p.targetControlConn,
+		p.Conf,
+		p.TopologyConfig,
+		p.proxyTopology,
+		targetCredentials.Username,
+		targetCredentials.Password,
+		originCredentials.Username,
+		originCredentials.Password,
+		p.PreparedStatementCache,
+		p.metricHandler,
+		p.globalClientHandlersWg,
+		p.requestResponseScheduler,
+		p.readScheduler,
+		p.writeScheduler,
+		p.requestResponseNumWorkers,
+		p.clientHandlersShutdownRequestCtx,
+		originHost,
+		targetHost,
+		p.timeUuidGenerator,
+		p.readMode,
+		p.dualWriteMode,
+		p.lwtPolicy,
+		p.counterWritesPolicy,
+		p.pagingStatePolicy,
+		p.secondaryWriteFailureMode,
+		p.secondaryWriteJournal,
+		p.tracer,
+		p.slowQueryLog,
+		p.auditLog,
+		p.primaryCluster,
+		p.systemQueriesMode,
+		p.maxProtocolVersion,
+		p.handshakeCache,
+		p.targetWriteFilter,
+		p.targetWriteSampler,
+		p.writeComparisonSampler,
+		p.tableMetrics,
+		p.migrationProgress,
+		p.originConsistencyOverride,
+		p.targetConsistencyOverride,
+		p.originRequestRetryPolicy,
+		p.targetRequestRetryPolicy,
+		p.requestTimeoutPolicy,
+		p.tableRoutingRules,
+		p.statementFilter,
+		p.targetDdlChecker,
+		p.schemaStatementPolicies,
+		p.destructiveStatementGuard,
+		p.applicationReadRouting,
+		p.interceptedResponseCache,
+		p.connectedClients,
+		p.proxyVirtualTables,
+		p.adminKeyspace,
+		p.trafficCapture,
+		p.frameLogger,
+		p.options.getRequestInterceptors(),
+		p.stallWatchdog,
+		p.inFlightBudget,
+		p.proxyAuthenticator)
+
+	if err != nil {
+		errFunc(err)
+		return
+	}
+
+	log.Tracef("ClientHandler created")
+	clientHandler.run(&p.activeClients)
+}
+
+// Drain stops accepting client connections and closes the existing ones once their in flight requests are done, their
+// new requests get an OVERLOADED error so that the drivers retry them on another proxy instance. With topology
+// virtualization, the clients that registered for STATUS_CHANGE events are first told that this proxy instance is DOWN.
+//
+// The connections that still have in flight requests after ZDM_PROXY_SHUTDOWN_DRAIN_TIMEOUT_MS are closed. The cluster
+// connections are still open when Drain returns, they are closed by Shutdown.
+func (p *ZdmProxy) Drain() {
+	drainTimeout, err := p.Conf.ParseProxyShutdownDrainTimeout()
+	if err != nil {
+		log.Warnf("Could not parse the drain timeout, closing the client connections without waiting: %v", err)
+	}
+	log.Infof("Draining the client connections, waiting up to %v for their in flight requests...", drainTimeout)
+
+	p.closeClientListeners()
+	p.listenerShutdownWg.Wait()
+
+	p.lock.Lock()
+	proxyTopology := p.proxyTopology
+	virtualizationEnabled := p.TopologyConfig != nil && p.TopologyConfig.VirtualizationEnabled
+	connectedClients := p.connectedClients
+	p.lock.Unlock()
+	if connectedClients == nil {
+		return
+	}
+	if virtualizationEnabled && proxyTopology != nil && drainTimeout > 0 {
+		event := proxyTopology.localStatusEvent(primitive.StatusChangeTypeDown)
+		for ch := range connectedClients.get() {
+			ch.sendProxyStatusEvent(event)
+		}
+	}
+
+	p.clientHandlersShutdownRequestCancelFn()
+
+	done := make(chan struct{})
+	go func() {
+		p.globalClientHandlersWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Info("Client connections drained.")
+		return
+	case <-time.After(drainTimeout):
+	}
+
+	remaining := connectedClients.get()
+	if drainTimeout > 0 {
+		log.Warnf("%d client connections still have in flight requests after %v, closing them.",
+			len(remaining), drainTimeout)
+	}
+	for ch := range remaining {
+		ch.clientHandlerCancelFunc()
+	}
+}
+
+func (p *ZdmProxy) Shutdown() {
+	log.Info("Initiating proxy shutdown...")
+
+	log.Debug("Requesting shutdown of the client listener...")
+	started := p.closeClientListeners()
+	p.listenerShutdownWg.Wait()
+
+	log.Debug("Requesting shutdown of the client handlers...")
+	p.clientHandlersShutdownRequestCancelFn()
+
+	log.Debug("Waiting until all client handlers are done...")
+	p.globalClientHandlersWg.Wait()
+
+	p.trafficCapture.shutdown()
+	p.auditLog.close()
+
+	if p.clusterConnPool != nil {
+		log.Debug("Closing pooled cluster connections...")
+		p.clusterConnPool.Shutdown()
+	}
+
+	log.Debug("Requesting shutdown of the control connections...")
+	p.controlConnCancelFn()
+
+	log.Debug("Waiting until control connections done...")
+	p.controlConnShutdownWg.Wait()
+
+	log.Debug("Shutting down the schedulers and metrics handler...")
+	p.requestResponseScheduler.Shutdown()
+	p.writeScheduler.Shutdown()
+	p.readScheduler.Shutdown()
+	p.listenerScheduler.Shutdown()
+
+	p.lock.Lock()
+	if p.metricHandler != nil {
+		err := p.metricHandler.UnregisterAllMetrics()
+		if err != nil {
+			log.Warnf("Failed to unregister metrics: %v.", err)
+		}
+	}
+	p.lock.Unlock()
+
+	log.Info("Proxy shutdown complete.")
+
+	if started && p.options != nil && p.options.OnShutdown != nil {
+		p.options.OnShutdown()
+	}
+}
+
+// closeClientListeners stops accepting client connections, it returns false if the listeners were never started.
+func (p *ZdmProxy) closeClientListeners() bool {
+	p.listenerLock.Lock()
+	defer p.listenerLock.Unlock()
+	started := p.clientListeners != nil
+	if !p.listenerClosed {
+		p.listenerClosed = true
+		for _, l := range p.clientListeners {
+			_ = l.Close()
+		}
+	}
+	return started
+}
+
+// initializeDevModeTls generates the dev mode TLS material and prints the certificates that the clients need
+// to connect to the proxy.
+func (p *ZdmProxy) initializeDevModeTls() (*tls.Config, error) {
+	tlsMaterial, err := generateDevModeTlsMaterial(p.Conf.ProxyListenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Proxy TLS dev mode CA certificate (use it as the CA of the client):\n%s", tlsMaterial.caCert)
+	if p.proxyTlsConfig.ClientAuth {
+		log.Infof("Proxy TLS dev mode client certificate:\n%s", tlsMaterial.clientCert)
+		log.Infof("Proxy TLS dev mode client key:\n%s", tlsMaterial.clientKey)
+	}
+
+	p.lock.Lock()
+	p.devModeTlsMaterial = tlsMaterial
+	p.lock.Unlock()
+
+	return getServerSideTlsConfig(
+		tlsMaterial.caCert, tlsMaterial.serverCert, tlsMaterial.serverKey, p.proxyTlsConfig.ClientAuth)
+}
+
+// GetDevModeTlsCertificates returns the PEM encoded CA certificate and the client certificate and key that were
+// generated at startup, they are nil if ZDM_PROXY_TLS_DEV_MODE_ENABLED is false.
+func (p *ZdmProxy) GetDevModeTlsCertificates() (caCert []byte, clientCert []byte, clientKey []byte) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.devModeTlsMaterial == nil {
+		return nil, nil, nil
+	}
+	return p.devModeTlsMaterial.caCert, p.devModeTlsMaterial.clientCert, p.devModeTlsMaterial.clientKey
+}
+
+// IsAcceptingConnections returns true if the proxy started and at least one of its client listeners is accepting
+// connections.
+func (p *ZdmProxy) IsAcceptingConnections() bool {
+	p.listenerLock.Lock()
+	defer p.listenerLock.Unlock()
+
+	return p.clientListeners != nil && !p.listenerClosed && atomic.LoadInt32(&p.activeListeners) > 0
+}
+
+// GetDestructiveStatementGuard returns nil if destructive statements don't need to be confirmed.
+func (p *ZdmProxy) GetDestructiveStatementGuard() *DestructiveStatementGuard {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.destructiveStatementGuard
+}
+
+// GetTrafficCapture returns nil if the traffic capture is disabled.
+func (p *ZdmProxy) GetTrafficCapture() *TrafficCapture {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.trafficCapture
+}
+
+// GetMigrationProgress returns the last migration progress estimation, nil if it is disabled
+// (ZDM_MIGRATION_PROGRESS_ENABLED).
+func (p *ZdmProxy) GetMigrationProgress() *MigrationProgressStatus {
+	p.lock.Lock()
+	migrationProgress := p.migrationProgress
+	p.lock.Unlock()
+	if migrationProgress == nil {
+		return nil
+	}
+	return migrationProgress.getStatus()
+}
+
+// IsDiagnosticsEnabled returns whether the runtime profiles and diagnostics are served on the admin API.
+func (p *ZdmProxy) IsDiagnosticsEnabled() bool {
+	return p.Conf.DiagnosticsEnabled
+}
+
+// GetLogLevelController returns the controller of the log level, it is shared by every proxy of the process.
+func (p *ZdmProxy) GetLogLevelController() *LogLevelController {
+	return logLevels
+}
+
+func (p *ZdmProxy) GetFrameLogger() *FrameLogger {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.frameLogger
+}
+
+// GetEffectiveConfig returns a copy of the configuration with the changes that were applied at runtime, i.e. the
+// credentials that were read again from the credential files and the settings of the reloaded config file.
+func (p *ZdmProxy) GetEffectiveConfig() *config.Config {
+	p.lock.RLock()
+	credentials := p.clusterCredentials
+	conf := *p.runtimeConf
+	p.lock.RUnlock()
+
+	if credentials != nil {
+		originCredentials := credentials.get(common.ClusterTypeOrigin)
+		targetCredentials := credentials.get(common.ClusterTypeTarget)
+		conf.OriginUsername, conf.OriginPassword = originCredentials.Username, originCredentials.Password
+		conf.TargetUsername, conf.TargetPassword = targetCredentials.Username, targetCredentials.Password
+	}
+	return &conf
+}
+
+// GetRuntimeSettings returns the settings that were changed at runtime with the zdm_admin keyspace, it is empty if
+// ZDM_ADMIN_KEYSPACE_ROLES is not set.
+func (p *ZdmProxy) GetRuntimeSettings() map[string]string {
+	p.lock.RLock()
+	adminKeyspace := p.adminKeyspace
+	p.lock.RUnlock()
+
+	settings := make(map[string]string)
+	if adminKeyspace != nil {
+		settings[adminKeyspaceName+"."+adminSettingsTableName+"."+readRoutingSettingName] =
+			readRoutingSettingValue(adminKeyspace.getReadRouting())
+	}
+	return settings
+}
+
+func (p *ZdmProxy) GetOriginControlConn() *ControlConn {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.originControlConn
+}
+
+func (p *ZdmProxy) GetTargetControlConn() *ControlConn {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.targetControlConn
+}
+
+func Run(conf *config.Config, ctx context.Context) (*ZdmProxy, error) {
+	return RunWithOptions(conf, ctx, nil)
+}
+
+// RunWithOptions creates and starts a proxy with the provided options (can be nil), see ZdmProxyOptions.
+func RunWithOptions(conf *config.Config, ctx context.Context, options *ZdmProxyOptions) (*ZdmProxy, error) {
+	zdmProxy, err := NewZdmProxyWithOptions(conf, options)
+	if err != nil {
+		log.Errorf("Couldn't create proxy: %v.", err)
+		return nil, err
+	}
+
+	err = zdmProxy.Start(ctx)
+	if err != nil {
+		log.Errorf("Couldn't start proxy: %v.", err)
+		zdmProxy.Shutdown()
+		return nil, err
+	}
+
+	return zdmProxy, nil
+}
+
+func RunWithRetries(conf *config.Config, ctx context.Context, b *backoff.Backoff) (*ZdmProxy, error) {
+	return RunWithRetriesAndOptions(conf, ctx, b, nil)
+}
+
+// RunWithRetriesAndOptions is the same as RunWithRetries but with options (can be nil), see ZdmProxyOptions.
+func RunWithRetriesAndOptions(
+	conf *config.Config, ctx context.Context, b *backoff.Backoff, options *ZdmProxyOptions) (*ZdmProxy, error) {
+	log.Info("Attempting to start the proxy...")
+	for {
+		zdmProxy, err := RunWithOptions(conf, ctx, options)
+		if zdmProxy != nil {
+			return zdmProxy, nil
+		}
+
+		nextDuration := b.Duration()
+		if !errors.Is(err, ShutdownErr) {
+			log.Errorf("Couldn't start proxy, retrying in %v: %v.", nextDuration, err)
+		}
+		timedOut, _ := sleepWithContext(nextDuration, ctx, nil)
+		if !timedOut {
+			log.Info("Cancellation detected. Aborting proxy startup...")
+			return nil, ShutdownErr
+		}
+	}
+}
+
+// sleepWithContext returns false if context Done() returns
+func sleepWithContext(d time.Duration, ctx context.Context, reconnectCh chan bool) (timedOut bool, reconnect bool) {
+	select {
+	case <-time.After(d):
+		return true, false
+	case <-ctx.Done():
+		return false, false
+	case <-reconnectCh:
+		return false, true
+	}
+}
+
+func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*metrics.ProxyMetrics, error) {
+	failedReadsOrigin, err := metricFactory.GetOrCreateCounter(metrics.FailedReadsOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	failedReadsTarget, err := metricFactory.GetOrCreateCounter(metrics.FailedReadsTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	failedWritesOnOrigin, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesOnOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	failedWritesOnTarget, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesOnTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	failedWritesOnBoth, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesOnBoth)
+	if err != nil {
+		return nil, err
+	}
+
+	psCacheSize, err := metricFactory.GetOrCreateGaugeFunc(metrics.PSCacheSize, p.PreparedStatementCache.GetPreparedStatementCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	psCacheMissCount, err := metricFactory.GetOrCreateCounter(metrics.PSCacheMissCount)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyReadsOriginDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyReadsOriginDuration, p.originBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyReadsTargetDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyReadsTargetDuration, p.targetBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyWritesDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyWritesDuration, p.originBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	statementDurationReads, err := p.createStatementDurations(metricFactory,
+		metrics.StatementDurationReadsOrigin, metrics.StatementDurationReadsTarget, metrics.StatementDurationReadsBoth)
+	if err != nil {
+		return nil, err
+	}
+
+	statementDurationWrites, err := p.createStatementDurations(metricFactory,
+		metrics.StatementDurationWritesOrigin, metrics.StatementDurationWritesTarget, metrics.StatementDurationWritesBoth)
+	if err != nil {
+		return nil, err
+	}
+
+	statementDurationAuth, err := p.createStatementDurations(metricFactory,
+		metrics.StatementDurationAuthOrigin, metrics.StatementDurationAuthTarget, metrics.StatementDurationAuthBoth)
+	if err != nil {
+		return nil, err
+	}
+
+	statementDurationControl, err := p.createStatementDurations(metricFactory,
+		metrics.StatementDurationControlOrigin, metrics.StatementDurationControlTarget, metrics.StatementDurationControlBoth)
+	if err != nil {
+		return nil, err
+	}
+
+	inFlightReadsOrigin, err := metricFactory.GetOrCreateGauge(metrics.InFlightReadsOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	inFlightReadsTarget, err := metricFactory.GetOrCreateGauge(metrics.InFlightReadsTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	inFlightWrites, err := metricFactory.GetOrCreateGauge(metrics.InFlightWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	openClientConnections, err := metricFactory.GetOrCreateGaugeFunc(metrics.OpenClientConnections, func() float64 {
+		return float64(atomic.LoadInt32(&p.activeClients))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queuedRequestsOrigin, err := metricFactory.GetOrCreateGauge(metrics.QueuedRequestsOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	queuedRequestsTarget, err := metricFactory.GetOrCreateGauge(metrics.QueuedRequestsTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	requestQueueDurationOrigin, err := metricFactory.GetOrCreateHistogram(metrics.RequestQueueDurationOrigin, p.originBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	requestQueueDurationTarget, err := metricFactory.GetOrCreateHistogram(metrics.RequestQueueDurationTarget, p.targetBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	rejectedQueuedRequestsOrigin, err := metricFactory.GetOrCreateCounter(metrics.RejectedQueuedRequestsOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	rejectedQueuedRequestsTarget, err := metricFactory.GetOrCreateCounter(metrics.RejectedQueuedRequestsTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	targetFilteredWrites, err := metricFactory.GetOrCreateCounter(metrics.TargetFilteredWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	targetUnsampledWrites, err := metricFactory.GetOrCreateCounter(metrics.TargetUnsampledWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	lwtRequests, err := metricFactory.GetOrCreateCounter(metrics.LwtRequests)
+	if err != nil {
+		return nil, err
+	}
 
+	counterWriteRequests, err := metricFactory.GetOrCreateCounter(metrics.CounterWriteRequests)
 	if err != nil {
-		errFunc(err)
-		return
+		return nil, err
 	}
 
-	log.Tracef("ClientHandler created")
-	clientHandler.run(&p.activeClients)
-}
+	crossClusterPagingStates, err := metricFactory.GetOrCreateCounter(metrics.CrossClusterPagingStates)
+	if err != nil {
+		return nil, err
+	}
 
-func (p *ZdmProxy) Shutdown() {
-	log.Info("Initiating proxy shutdown...")
+	secondaryWriteFailures, err := metricFactory.GetOrCreateCounter(metrics.SecondaryWriteFailures)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debug("Requesting shutdown of the client listener...")
-	p.listenerLock.Lock()
-	if !p.listenerClosed {
-		p.listenerClosed = true
-		if p.clientListener != nil {
-			p.clientListener.Close()
-		}
+	writeJournalQueued, err := metricFactory.GetOrCreateCounter(metrics.WriteJournalQueued)
+	if err != nil {
+		return nil, err
 	}
-	p.listenerLock.Unlock()
 
-	p.listenerShutdownWg.Wait()
+	writeJournalReplayed, err := metricFactory.GetOrCreateCounter(metrics.WriteJournalReplayed)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debug("Requesting shutdown of the client handlers...")
-	p.clientHandlersShutdownRequestCancelFn()
+	writeJournalDropped, err := metricFactory.GetOrCreateCounter(metrics.WriteJournalDropped)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debug("Waiting until all client handlers are done...")
-	p.globalClientHandlersWg.Wait()
+	writeJournalPendingEntries, err := metricFactory.GetOrCreateGauge(metrics.WriteJournalPendingEntries)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debug("Requesting shutdown of the control connections...")
-	p.controlConnCancelFn()
+	slowQueries, err := metricFactory.GetOrCreateCounter(metrics.SlowQueries)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debug("Waiting until control connections done...")
-	p.controlConnShutdownWg.Wait()
+	asyncWritesRetried, err := metricFactory.GetOrCreateCounter(metrics.AsyncWritesRetried)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debug("Shutting down the schedulers and metrics handler...")
-	p.requestResponseScheduler.Shutdown()
-	p.writeScheduler.Shutdown()
-	p.readScheduler.Shutdown()
-	p.listenerScheduler.Shutdown()
+	asyncWritesFailed, err := metricFactory.GetOrCreateCounter(metrics.AsyncWritesFailed)
+	if err != nil {
+		return nil, err
+	}
 
-	p.lock.Lock()
-	if p.metricHandler != nil {
-		err := p.metricHandler.UnregisterAllMetrics()
-		if err != nil {
-			log.Warnf("Failed to unregister metrics: %v.", err)
-		}
+	readComparisons, err := metricFactory.GetOrCreateCounter(metrics.ReadComparisons)
+	if err != nil {
+		return nil, err
 	}
-	p.lock.Unlock()
 
-	log.Info("Proxy shutdown complete.")
-}
+	readComparisonMismatchesRowCount, err := metricFactory.GetOrCreateCounter(metrics.ReadComparisonMismatchesRowCount)
+	if err != nil {
+		return nil, err
+	}
 
-func (p *ZdmProxy) GetOriginControlConn() *ControlConn {
-	p.lock.RLock()
-	defer p.lock.RUnlock()
+	readComparisonMismatchesChecksum, err := metricFactory.GetOrCreateCounter(metrics.ReadComparisonMismatchesChecksum)
+	if err != nil {
+		return nil, err
+	}
 
-	return p.originControlConn
-}
+	readComparisonMismatchesError, err := metricFactory.GetOrCreateCounter(metrics.ReadComparisonMismatchesError)
+	if err != nil {
+		return nil, err
+	}
 
-func (p *ZdmProxy) GetTargetControlConn() *ControlConn {
-	p.lock.RLock()
-	defer p.lock.RUnlock()
+	writeComparisons, err := metricFactory.GetOrCreateCounter(metrics.WriteComparisons)
+	if err != nil {
+		return nil, err
+	}
 
-	return p.targetControlConn
-}
+	writeComparisonMismatchesResponse, err := metricFactory.GetOrCreateCounter(metrics.WriteComparisonMismatchesResponse)
+	if err != nil {
+		return nil, err
+	}
 
-func Run(conf *config.Config, ctx context.Context) (*ZdmProxy, error) {
-	zdmProxy, err := NewZdmProxy(conf)
+	writeComparisonMismatchesApplied, err := metricFactory.GetOrCreateCounter(metrics.WriteComparisonMismatchesApplied)
 	if err != nil {
-		log.Errorf("Couldn't create proxy: %v.", err)
 		return nil, err
 	}
 
-	err = zdmProxy.Start(ctx)
+	targetIncompatibleSchemaChanges, err := metricFactory.GetOrCreateCounter(metrics.TargetIncompatibleSchemaChanges)
 	if err != nil {
-		log.Errorf("Couldn't start proxy: %v.", err)
-		zdmProxy.Shutdown()
 		return nil, err
 	}
 
-	return zdmProxy, nil
-}
+	destructiveStatementsRejected, err := metricFactory.GetOrCreateCounter(metrics.DestructiveStatementsRejected)
+	if err != nil {
+		return nil, err
+	}
 
-func RunWithRetries(conf *config.Config, ctx context.Context, b *backoff.Backoff) (*ZdmProxy, error) {
-	log.Info("Attempting to start the proxy...")
-	for {
-		zdmProxy, err := Run(conf, ctx)
-		if zdmProxy != nil {
-			return zdmProxy, nil
-		}
+	clientLimitedRequests, err := metricFactory.GetOrCreateCounter(metrics.ClientLimitedRequests)
+	if err != nil {
+		return nil, err
+	}
 
-		nextDuration := b.Duration()
-		if !errors.Is(err, ShutdownErr) {
-			log.Errorf("Couldn't start proxy, retrying in %v: %v.", nextDuration, err)
-		}
-		timedOut, _ := sleepWithContext(nextDuration, ctx, nil)
-		if !timedOut {
-			log.Info("Cancellation detected. Aborting proxy startup...")
-			return nil, ShutdownErr
+	inFlightRequests, err := metricFactory.GetOrCreateGaugeFunc(metrics.InFlightRequests, func() float64 {
+		p.lock.Lock()
+		inFlightBudget := p.inFlightBudget
+		p.lock.Unlock()
+		if inFlightBudget == nil {
+			return 0
 		}
+		return float64(inFlightBudget.getInFlight())
+	})
+	if err != nil {
+		return nil, err
 	}
-}
 
-// sleepWithContext returns false if context Done() returns
-func sleepWithContext(d time.Duration, ctx context.Context, reconnectCh chan bool) (timedOut bool, reconnect bool) {
-	select {
-	case <-time.After(d):
-		return true, false
-	case <-ctx.Done():
-		return false, false
-	case <-reconnectCh:
-		return false, true
+	backpressureActivations, err := metricFactory.GetOrCreateCounter(metrics.BackpressureActivations)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*metrics.ProxyMetrics, error) {
-	failedReadsOrigin, err := metricFactory.GetOrCreateCounter(metrics.FailedReadsOrigin)
+	clientIdleConnectionsClosed, err := metricFactory.GetOrCreateCounter(metrics.ClientIdleConnectionsClosed)
 	if err != nil {
 		return nil, err
 	}
 
-	failedReadsTarget, err := metricFactory.GetOrCreateCounter(metrics.FailedReadsTarget)
+	rateLimitedRequestsOrigin, err := metricFactory.GetOrCreateCounter(metrics.RateLimitedRequestsOrigin)
 	if err != nil {
 		return nil, err
 	}
 
-	failedWritesOnOrigin, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesOnOrigin)
+	rateLimitedRequestsTarget, err := metricFactory.GetOrCreateCounter(metrics.RateLimitedRequestsTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	failedWritesOnTarget, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesOnTarget)
+	retriedRequestsOrigin, err := metricFactory.GetOrCreateCounter(metrics.RetriedRequestsOrigin)
 	if err != nil {
 		return nil, err
 	}
 
-	failedWritesOnBoth, err := metricFactory.GetOrCreateCounter(metrics.FailedWritesOnBoth)
+	retriedRequestsTarget, err := metricFactory.GetOrCreateCounter(metrics.RetriedRequestsTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	psCacheSize, err := metricFactory.GetOrCreateGaugeFunc(metrics.PSCacheSize, p.PreparedStatementCache.GetPreparedStatementCacheSize)
+	circuitBreakerStateOrigin, err := metricFactory.GetOrCreateGauge(metrics.CircuitBreakerStateOrigin)
 	if err != nil {
 		return nil, err
 	}
 
-	psCacheMissCount, err := metricFactory.GetOrCreateCounter(metrics.PSCacheMissCount)
+	circuitBreakerStateTarget, err := metricFactory.GetOrCreateGauge(metrics.CircuitBreakerStateTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	proxyReadsOriginDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyReadsOriginDuration, p.originBuckets)
+	circuitBreakerRejectedRequestsOrigin, err := metricFactory.GetOrCreateCounter(metrics.CircuitBreakerRejectedRequestsOrigin)
 	if err != nil {
 		return nil, err
 	}
 
-	proxyReadsTargetDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyReadsTargetDuration, p.targetBuckets)
+	circuitBreakerRejectedRequestsTarget, err := metricFactory.GetOrCreateCounter(metrics.CircuitBreakerRejectedRequestsTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	proxyWritesDuration, err := metricFactory.GetOrCreateHistogram(metrics.ProxyWritesDuration, p.originBuckets)
+	latencyBudgetBreachesOrigin, err := metricFactory.GetOrCreateCounter(metrics.LatencyBudgetBreachesOrigin)
 	if err != nil {
 		return nil, err
 	}
 
-	inFlightReadsOrigin, err := metricFactory.GetOrCreateGauge(metrics.InFlightReadsOrigin)
+	latencyBudgetBreachesTarget, err := metricFactory.GetOrCreateCounter(metrics.LatencyBudgetBreachesTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	inFlightReadsTarget, err := metricFactory.GetOrCreateGauge(metrics.InFlightReadsTarget)
+	interceptedResponseCacheHits, err := metricFactory.GetOrCreateCounter(metrics.InterceptedResponseCacheHits)
 	if err != nil {
 		return nil, err
 	}
 
-	inFlightWrites, err := metricFactory.GetOrCreateGauge(metrics.InFlightWrites)
+	interceptedResponseCacheMisses, err := metricFactory.GetOrCreateCounter(metrics.InterceptedResponseCacheMisses)
 	if err != nil {
 		return nil, err
 	}
 
-	openClientConnections, err := metricFactory.GetOrCreateGaugeFunc(metrics.OpenClientConnections, func() float64 {
-		return float64(atomic.LoadInt32(&p.activeClients))
+	fleetLeader, err := metricFactory.GetOrCreateGaugeFunc(metrics.FleetLeader, func() float64 {
+		if p.leaderElection.isLeader() {
+			return 1
+		}
+		return 0
 	})
 	if err != nil {
 		return nil, err
@@ -774,11 +2478,92 @@ func (p *ZdmProxy) CreateProxyMetrics(metricFactory metrics.MetricFactory) (*met
 		InFlightReadsTarget:      inFlightReadsTarget,
 		InFlightWrites:           inFlightWrites,
 		OpenClientConnections:    openClientConnections,
+
+		StatementDurationReads:   statementDurationReads,
+		StatementDurationWrites:  statementDurationWrites,
+		StatementDurationAuth:    statementDurationAuth,
+		StatementDurationControl: statementDurationControl,
+
+		QueuedRequestsOrigin:         queuedRequestsOrigin,
+		QueuedRequestsTarget:         queuedRequestsTarget,
+		RequestQueueDurationOrigin:   requestQueueDurationOrigin,
+		RequestQueueDurationTarget:   requestQueueDurationTarget,
+		RejectedQueuedRequestsOrigin: rejectedQueuedRequestsOrigin,
+		RejectedQueuedRequestsTarget: rejectedQueuedRequestsTarget,
+
+		RateLimitedRequestsOrigin: rateLimitedRequestsOrigin,
+		RateLimitedRequestsTarget: rateLimitedRequestsTarget,
+
+		RetriedRequestsOrigin: retriedRequestsOrigin,
+		RetriedRequestsTarget: retriedRequestsTarget,
+
+		CircuitBreakerStateOrigin:            circuitBreakerStateOrigin,
+		CircuitBreakerStateTarget:            circuitBreakerStateTarget,
+		CircuitBreakerRejectedRequestsOrigin: circuitBreakerRejectedRequestsOrigin,
+		CircuitBreakerRejectedRequestsTarget: circuitBreakerRejectedRequestsTarget,
+
+		LatencyBudgetBreachesOrigin: latencyBudgetBreachesOrigin,
+		LatencyBudgetBreachesTarget: latencyBudgetBreachesTarget,
+
+		TargetFilteredWrites:              targetFilteredWrites,
+		TargetUnsampledWrites:             targetUnsampledWrites,
+		LwtRequests:                       lwtRequests,
+		CounterWriteRequests:              counterWriteRequests,
+		CrossClusterPagingStates:          crossClusterPagingStates,
+		SecondaryWriteFailures:            secondaryWriteFailures,
+		WriteJournalQueued:                writeJournalQueued,
+		WriteJournalReplayed:              writeJournalReplayed,
+		WriteJournalDropped:               writeJournalDropped,
+		WriteJournalPendingEntries:        writeJournalPendingEntries,
+		SlowQueries:                       slowQueries,
+		AsyncWritesRetried:                asyncWritesRetried,
+		AsyncWritesFailed:                 asyncWritesFailed,
+		ReadComparisons:                   readComparisons,
+		ReadComparisonMismatchesRowCount:  readComparisonMismatchesRowCount,
+		ReadComparisonMismatchesChecksum:  readComparisonMismatchesChecksum,
+		ReadComparisonMismatchesError:     readComparisonMismatchesError,
+		WriteComparisons:                  writeComparisons,
+		WriteComparisonMismatchesResponse: writeComparisonMismatchesResponse,
+		WriteComparisonMismatchesApplied:  writeComparisonMismatchesApplied,
+		TargetIncompatibleSchemaChanges:   targetIncompatibleSchemaChanges,
+		DestructiveStatementsRejected:     destructiveStatementsRejected,
+		ClientLimitedRequests:             clientLimitedRequests,
+		InFlightRequests:                  inFlightRequests,
+		BackpressureActivations:           backpressureActivations,
+		ClientIdleConnectionsClosed:       clientIdleConnectionsClosed,
+
+		InterceptedResponseCacheHits:   interceptedResponseCacheHits,
+		InterceptedResponseCacheMisses: interceptedResponseCacheMisses,
+
+		FleetLeader: fleetLeader,
 	}
 
 	return proxyMetrics, nil
 }
 
+// createStatementDurations creates the latency histograms of a statement type with the same buckets as the
+// proxy_request_duration_seconds histograms, i.e. the TARGET buckets for the requests that are only forwarded to TARGET.
+func (p *ZdmProxy) createStatementDurations(
+	metricFactory metrics.MetricFactory, origin metrics.Metric, target metrics.Metric,
+	both metrics.Metric) (*metrics.StatementDurations, error) {
+	originDuration, err := metricFactory.GetOrCreateHistogram(origin, p.originBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	targetDuration, err := metricFactory.GetOrCreateHistogram(target, p.targetBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	bothDuration, err := metricFactory.GetOrCreateHistogram(both, p.originBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metrics.StatementDurations{Origin: originDuration, Target: targetDuration, Both: bothDuration}, nil
+}
+
 func (p *ZdmProxy) CreateOriginNodeMetrics(
 	metricFactory metrics.MetricFactory, originNodeDescription string, originBuckets []float64) (*metrics.NodeMetricsInstance, error) {
 	originClientTimeouts, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginClientTimeouts)
@@ -821,6 +2606,16 @@ func (p *ZdmProxy) CreateOriginNodeMetrics(
 		return nil, err
 	}
 
+	originSyntaxErrors, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginSyntaxErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	originAuthErrors, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginAuthErrors)
+	if err != nil {
+		return nil, err
+	}
+
 	originOtherErrors, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.OriginOtherErrors)
 	if err != nil {
 		return nil, err
@@ -836,8 +2631,32 @@ func (p *ZdmProxy) CreateOriginNodeMetrics(
 		return nil, err
 	}
 
-	// inflight requests metric for non async requests are implemented as proxy level metrics (not node metrics)
-	inflightRequests, err := noopmetrics.NewNoopMetricFactory().GetOrCreateGauge(nil)
+	inflightRequests, err := metrics.CreateGaugeNodeMetric(metricFactory, originNodeDescription, metrics.InFlightRequestsOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	originPooledStreamIdsInUse, err := metrics.CreateGaugeNodeMetric(metricFactory, originNodeDescription, metrics.PooledStreamIdsInUseOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	originPooledStreamIdsCapacity, err := metrics.CreateGaugeNodeMetric(metricFactory, originNodeDescription, metrics.PooledStreamIdsCapacityOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	originPooledStreamIdsExhausted, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.PooledStreamIdsExhaustedOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	originPooledHeartbeatsSent, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.PooledHeartbeatsSentOrigin)
+	if err != nil {
+		return nil, err
+	}
+
+	originPooledHeartbeatsFailed, err := metrics.CreateCounterNodeMetric(metricFactory, originNodeDescription, metrics.PooledHeartbeatsFailedOrigin)
 	if err != nil {
 		return nil, err
 	}
@@ -851,10 +2670,19 @@ func (p *ZdmProxy) CreateOriginNodeMetrics(
 		UnpreparedErrors:  originUnpreparedErrors,
 		OverloadedErrors:  originOverloadedErrors,
 		UnavailableErrors: originUnavailableErrors,
+		SyntaxErrors:      originSyntaxErrors,
+		AuthErrors:        originAuthErrors,
 		OtherErrors:       originOtherErrors,
-		RequestDuration:   originRequestDuration,
+		RequestDuration:   p.originLatencyBudget.wrapHistogram(originRequestDuration),
 		OpenConnections:   openOriginConnections,
 		InFlightRequests:  inflightRequests,
+
+		PooledStreamIdsInUse:     originPooledStreamIdsInUse,
+		PooledStreamIdsCapacity:  originPooledStreamIdsCapacity,
+		PooledStreamIdsExhausted: originPooledStreamIdsExhausted,
+
+		PooledHeartbeatsSent:   originPooledHeartbeatsSent,
+		PooledHeartbeatsFailed: originPooledHeartbeatsFailed,
 	}, nil
 }
 
@@ -900,6 +2728,16 @@ func (p *ZdmProxy) CreateAsyncNodeMetrics(
 		return nil, err
 	}
 
+	asyncSyntaxErrors, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncSyntaxErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncAuthErrors, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncAuthErrors)
+	if err != nil {
+		return nil, err
+	}
+
 	asyncOtherErrors, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.AsyncOtherErrors)
 	if err != nil {
 		return nil, err
@@ -920,6 +2758,31 @@ func (p *ZdmProxy) CreateAsyncNodeMetrics(
 		return nil, err
 	}
 
+	asyncPooledStreamIdsInUse, err := metrics.CreateGaugeNodeMetric(metricFactory, asyncNodeDescription, metrics.PooledStreamIdsInUseAsync)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncPooledStreamIdsCapacity, err := metrics.CreateGaugeNodeMetric(metricFactory, asyncNodeDescription, metrics.PooledStreamIdsCapacityAsync)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncPooledStreamIdsExhausted, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.PooledStreamIdsExhaustedAsync)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncPooledHeartbeatsSent, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.PooledHeartbeatsSentAsync)
+	if err != nil {
+		return nil, err
+	}
+
+	asyncPooledHeartbeatsFailed, err := metrics.CreateCounterNodeMetric(metricFactory, asyncNodeDescription, metrics.PooledHeartbeatsFailedAsync)
+	if err != nil {
+		return nil, err
+	}
+
 	return &metrics.NodeMetricsInstance{
 		ClientTimeouts:    asyncClientTimeouts,
 		ReadTimeouts:      asyncReadTimeouts,
@@ -929,10 +2792,19 @@ func (p *ZdmProxy) CreateAsyncNodeMetrics(
 		UnpreparedErrors:  asyncUnpreparedErrors,
 		OverloadedErrors:  asyncOverloadedErrors,
 		UnavailableErrors: asyncUnavailableErrors,
+		SyntaxErrors:      asyncSyntaxErrors,
+		AuthErrors:        asyncAuthErrors,
 		OtherErrors:       asyncOtherErrors,
 		RequestDuration:   asyncRequestDuration,
 		OpenConnections:   openAsyncConnections,
 		InFlightRequests:  inflightRequestsAsync,
+
+		PooledStreamIdsInUse:     asyncPooledStreamIdsInUse,
+		PooledStreamIdsCapacity:  asyncPooledStreamIdsCapacity,
+		PooledStreamIdsExhausted: asyncPooledStreamIdsExhausted,
+
+		PooledHeartbeatsSent:   asyncPooledHeartbeatsSent,
+		PooledHeartbeatsFailed: asyncPooledHeartbeatsFailed,
 	}, nil
 }
 
@@ -978,6 +2850,16 @@ func (p *ZdmProxy) CreateTargetNodeMetrics(
 		return nil, err
 	}
 
+	targetSyntaxErrors, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetSyntaxErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	targetAuthErrors, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetAuthErrors)
+	if err != nil {
+		return nil, err
+	}
+
 	targetOtherErrors, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.TargetOtherErrors)
 	if err != nil {
 		return nil, err
@@ -993,8 +2875,32 @@ func (p *ZdmProxy) CreateTargetNodeMetrics(
 		return nil, err
 	}
 
-	// inflight requests metric for non async requests are implemented as proxy level metrics (not node metrics)
-	inflightRequests, err := noopmetrics.NewNoopMetricFactory().GetOrCreateGauge(nil)
+	inflightRequests, err := metrics.CreateGaugeNodeMetric(metricFactory, targetNodeDescription, metrics.InFlightRequestsTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPooledStreamIdsInUse, err := metrics.CreateGaugeNodeMetric(metricFactory, targetNodeDescription, metrics.PooledStreamIdsInUseTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPooledStreamIdsCapacity, err := metrics.CreateGaugeNodeMetric(metricFactory, targetNodeDescription, metrics.PooledStreamIdsCapacityTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPooledStreamIdsExhausted, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.PooledStreamIdsExhaustedTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPooledHeartbeatsSent, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.PooledHeartbeatsSentTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	targetPooledHeartbeatsFailed, err := metrics.CreateCounterNodeMetric(metricFactory, targetNodeDescription, metrics.PooledHeartbeatsFailedTarget)
 	if err != nil {
 		return nil, err
 	}
@@ -1008,9 +2914,18 @@ func (p *ZdmProxy) CreateTargetNodeMetrics(
 		UnpreparedErrors:  targetUnpreparedErrors,
 		OverloadedErrors:  targetOverloadedErrors,
 		UnavailableErrors: targetUnavailableErrors,
+		SyntaxErrors:      targetSyntaxErrors,
+		AuthErrors:        targetAuthErrors,
 		OtherErrors:       targetOtherErrors,
-		RequestDuration:   targetRequestDuration,
+		RequestDuration:   p.targetLatencyBudget.wrapHistogram(targetRequestDuration),
 		OpenConnections:   openTargetConnections,
 		InFlightRequests:  inflightRequests,
+
+		PooledStreamIdsInUse:     targetPooledStreamIdsInUse,
+		PooledStreamIdsCapacity:  targetPooledStreamIdsCapacity,
+		PooledStreamIdsExhausted: targetPooledStreamIdsExhausted,
+
+		PooledHeartbeatsSent:   targetPooledHeartbeatsSent,
+		PooledHeartbeatsFailed: targetPooledHeartbeatsFailed,
 	}, nil
 }
@@ -0,0 +1,60 @@
+package zdmproxy
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"sync"
+)
+
+// proxyAuthenticator validates the credentials of the clients when the proxy authenticates them itself (see
+// ZDM_PROXY_AUTH_CREDENTIALS), the clients get an AUTHENTICATE response from the proxy and the proxy authenticates to
+// the clusters with the configured credentials once the client credentials are valid.
+type proxyAuthenticator struct {
+	lock        *sync.RWMutex
+	credentials map[string]string
+}
+
+// newProxyAuthenticator returns nil if the proxy doesn't authenticate the clients itself.
+func newProxyAuthenticator(conf *config.Config) (*proxyAuthenticator, error) {
+	credentials, err := conf.ParseProxyAuthCredentials()
+	if err != nil || credentials == nil {
+		return nil, err
+	}
+	return &proxyAuthenticator{
+		lock:        &sync.RWMutex{},
+		credentials: credentials,
+	}, nil
+}
+
+// authenticate returns true if the provided credentials match one of the proxy credentials.
+func (recv *proxyAuthenticator) authenticate(creds *AuthCredentials) bool {
+	if creds == nil {
+		return false
+	}
+	recv.lock.RLock()
+	password, ok := recv.credentials[creds.Username]
+	recv.lock.RUnlock()
+	// the password is compared even if the username is unknown so that the response time doesn't reveal the usernames
+	match := subtle.ConstantTimeCompare([]byte(password), []byte(creds.Password)) == 1
+	return ok && match
+}
+
+// refresh reads ZDM_PROXY_AUTH_CREDENTIALS_FILE again and returns true if the credentials changed.
+func (recv *proxyAuthenticator) refresh(conf *config.Config) (bool, error) {
+	credentials, err := conf.ParseProxyAuthCredentials()
+	if err != nil {
+		return false, fmt.Errorf("could not read the proxy credentials: %w", err)
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	changed := len(credentials) != len(recv.credentials)
+	for username, password := range credentials {
+		if currentPassword, ok := recv.credentials[username]; !ok || currentPassword != password {
+			changed = true
+		}
+	}
+	recv.credentials = credentials
+	return changed, nil
+}
@@ -0,0 +1,44 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProxyAuthenticator(t *testing.T) {
+	conf := config.New()
+	authenticator, err := newProxyAuthenticator(conf)
+	require.Nil(t, err)
+	require.Nil(t, authenticator)
+
+	credentialsFile := filepath.Join(t.TempDir(), "credentials")
+	require.Nil(t, os.WriteFile(credentialsFile, []byte("app1:pass1\n"), 0600))
+	conf.ProxyAuthCredentialsFile = credentialsFile
+	authenticator, err = newProxyAuthenticator(conf)
+	require.Nil(t, err)
+
+	require.True(t, authenticator.authenticate(&AuthCredentials{Username: "app1", Password: "pass1"}))
+	require.False(t, authenticator.authenticate(&AuthCredentials{Username: "app1", Password: "pass2"}))
+	require.False(t, authenticator.authenticate(&AuthCredentials{Username: "app2", Password: ""}))
+	require.False(t, authenticator.authenticate(nil))
+
+	changed, err := authenticator.refresh(conf)
+	require.Nil(t, err)
+	require.False(t, changed)
+
+	require.Nil(t, os.WriteFile(credentialsFile, []byte("app1:pass2\napp2:pass1\n"), 0600))
+	changed, err = authenticator.refresh(conf)
+	require.Nil(t, err)
+	require.True(t, changed)
+	require.False(t, authenticator.authenticate(&AuthCredentials{Username: "app1", Password: "pass1"}))
+	require.True(t, authenticator.authenticate(&AuthCredentials{Username: "app2", Password: "pass1"}))
+
+	// the previous credentials are kept if the file can't be read
+	require.Nil(t, os.Remove(credentialsFile))
+	_, err = authenticator.refresh(conf)
+	require.NotNil(t, err)
+	require.True(t, authenticator.authenticate(&AuthCredentials{Username: "app1", Password: "pass2"}))
+}
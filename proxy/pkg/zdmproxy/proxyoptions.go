@@ -0,0 +1,96 @@
+package zdmproxy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sync"
+)
+
+// ZdmProxyOptions allow programs that embed the proxy to customize how it is wired,
+// a nil (or zero value) ZdmProxyOptions results in the same behavior as the standalone proxy.
+type ZdmProxyOptions struct {
+	// Listeners are used to accept client connections instead of listening on ZDM_PROXY_LISTEN_ADDRESS and
+	// ZDM_PROXY_LISTEN_PORT, e.g. sockets passed by systemd, in-memory listeners in tests or listeners that handle
+	// TLS themselves (don't configure proxy TLS in that case, it is applied on top of these listeners if configured).
+	// The proxy takes ownership of the listeners and closes them on shutdown.
+	Listeners []net.Listener
+
+	// GlobalLogger replaces the output, formatter, level and hooks of the logrus standard logger which is used by the
+	// proxy. This is a process-wide setting: the standard logger is shared by every proxy instance and by every other
+	// user of logrus in the same process, so it also changes their logs and the last proxy that is created with
+	// a GlobalLogger wins. Leave it nil and configure logrus directly if the program already does that.
+	GlobalLogger *log.Logger
+
+	// MetricsRegisterer is used to register the proxy metrics instead of prometheus.DefaultRegisterer.
+	// Use a separate registry for each proxy to run multiple proxies in the same process with isolated metrics.
+	// If it is also a prometheus.Gatherer (e.g. *prometheus.Registry) then the metrics HTTP handler
+	// (see MetricHandler.GetHttpHandler) serves the metrics of this registry.
+	MetricsRegisterer prometheus.Registerer
+
+	// OnClientConnect is called when a client connection is accepted, before any request is read from it.
+	// It is called in the goroutine of the connection so a slow callback doesn't delay the other client connections.
+	// The connection is closed if an error is returned.
+	OnClientConnect func(clientAddress net.Addr) error
+
+	// OnClientDisconnect is called when a client connection that was accepted by the proxy is closed.
+	OnClientDisconnect func(clientAddress net.Addr)
+
+	// RequestInterceptors are called in order for the QUERY, PREPARE, EXECUTE and BATCH requests of the client
+	// connections and can modify, route or reject them (see RequestInterceptor).
+	RequestInterceptors []RequestInterceptor
+
+	// OnShutdown is called after the shutdown of a proxy that was successfully started is complete.
+	OnShutdown func()
+}
+
+func (recv *ZdmProxyOptions) getMetricsRegisterer() prometheus.Registerer {
+	if recv == nil || recv.MetricsRegisterer == nil {
+		return prometheus.DefaultRegisterer
+	}
+	return recv.MetricsRegisterer
+}
+
+func (recv *ZdmProxyOptions) getRequestInterceptors() []RequestInterceptor {
+	if recv == nil {
+		return nil
+	}
+	return recv.RequestInterceptors
+}
+
+func (recv *ZdmProxyOptions) applyGlobalLogger() {
+	if recv == nil || recv.GlobalLogger == nil {
+		return
+	}
+	log.SetOutput(recv.GlobalLogger.Out)
+	log.SetFormatter(recv.GlobalLogger.Formatter)
+	log.SetLevel(recv.GlobalLogger.GetLevel())
+	log.SetReportCaller(recv.GlobalLogger.ReportCaller)
+	log.StandardLogger().ReplaceHooks(recv.GlobalLogger.Hooks)
+}
+
+// hookedConn calls the OnClientDisconnect callback the first time that the client connection is closed.
+type hookedConn struct {
+	net.Conn
+	onClose   func(clientAddress net.Addr)
+	closeOnce *sync.Once
+}
+
+func newHookedConn(conn net.Conn, onClose func(clientAddress net.Addr)) net.Conn {
+	if onClose == nil {
+		return conn
+	}
+	return &hookedConn{
+		Conn:      conn,
+		onClose:   onClose,
+		closeOnce: &sync.Once{},
+	}
+}
+
+func (recv *hookedConn) Close() error {
+	err := recv.Conn.Close()
+	recv.closeOnce.Do(func() {
+		recv.onClose(recv.Conn.RemoteAddr())
+	})
+	return err
+}
@@ -0,0 +1,149 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sync"
+)
+
+const proxyTopologyEventsBufferSize = 16
+
+// proxyTopology holds the topology of the proxy instances that can change at runtime when the addresses are read from
+// ZDM_PROXY_TOPOLOGY_ADDRESSES_FILE. The client handlers subscribe to its changes to send TOPOLOGY_CHANGE events to
+// the drivers when proxy instances are added or removed.
+//
+// Each instance is identified by the address that ZDM_PROXY_TOPOLOGY_INDEX pointed to when it started, the index
+// follows that address when the list changes.
+type proxyTopology struct {
+	lock        *sync.Mutex
+	config      *common.TopologyConfig
+	port        int
+	subscribers map[chan *message.TopologyChangeEvent]bool
+}
+
+func newProxyTopology(config *common.TopologyConfig, port int) *proxyTopology {
+	return &proxyTopology{
+		lock:        &sync.Mutex{},
+		config:      config,
+		port:        port,
+		subscribers: map[chan *message.TopologyChangeEvent]bool{},
+	}
+}
+
+// subscribe returns a channel with the events of the proxy instances that are added or removed, the events are
+// dropped if the channel is full.
+func (recv *proxyTopology) subscribe() chan *message.TopologyChangeEvent {
+	events := make(chan *message.TopologyChangeEvent, proxyTopologyEventsBufferSize)
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.subscribers[events] = true
+	return events
+}
+
+func (recv *proxyTopology) unsubscribe(events chan *message.TopologyChangeEvent) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	delete(recv.subscribers, events)
+}
+
+// update replaces the addresses of the proxy instances, it returns the new topology config and the events of the
+// instances that were added or removed. The tokens of a virtual host depend on its position in the list and on the
+// number of instances so the instances that are still there but whose tokens changed are MOVED_NODE events.
+// The returned config is nil if the addresses didn't change.
+func (recv *proxyTopology) update(addresses []net.IP) (*common.TopologyConfig, []*message.TopologyChangeEvent, error) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	current := recv.config
+	if equalAddresses(current.Addresses, addresses) {
+		return nil, nil, nil
+	}
+
+	localAddress := current.Addresses[current.Index]
+	index := indexOfAddress(addresses, localAddress)
+	if index < 0 {
+		return nil, nil, fmt.Errorf("the address of this proxy instance (%v) is not in the new addresses %v",
+			localAddress, addresses)
+	}
+
+	var events []*message.TopologyChangeEvent
+	for _, address := range current.Addresses {
+		if indexOfAddress(addresses, address) < 0 {
+			events = append(events, recv.newEvent(primitive.TopologyChangeTypeRemovedNode, address))
+		}
+	}
+	for i, address := range addresses {
+		previousIndex := indexOfAddress(current.Addresses, address)
+		if previousIndex < 0 {
+			events = append(events, recv.newEvent(primitive.TopologyChangeTypeNewNode, address))
+		} else if previousIndex != i || len(current.Addresses) != len(addresses) {
+			events = append(events, recv.newEvent(primitive.TopologyChangeTypeMovedNode, address))
+		}
+	}
+
+	recv.config = &common.TopologyConfig{
+		VirtualizationEnabled: current.VirtualizationEnabled,
+		Addresses:             addresses,
+		Count:                 len(addresses),
+		Index:                 index,
+		NumTokens:             current.NumTokens,
+	}
+	return recv.config, events, nil
+}
+
+// publish sends the events to every subscriber.
+func (recv *proxyTopology) publish(events []*message.TopologyChangeEvent) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for subscriber := range recv.subscribers {
+		for _, event := range events {
+			select {
+			case subscriber <- event:
+			default:
+				log.Warnf("Dropping proxy topology event %v because the events channel of a client handler is full.", event)
+			}
+		}
+	}
+}
+
+// localStatusEvent returns a STATUS_CHANGE event of this proxy instance.
+func (recv *proxyTopology) localStatusEvent(changeType primitive.StatusChangeType) *message.StatusChangeEvent {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return &message.StatusChangeEvent{
+		ChangeType: changeType,
+		Address:    &primitive.Inet{Addr: recv.config.Addresses[recv.config.Index], Port: int32(recv.port)},
+	}
+}
+
+func (recv *proxyTopology) newEvent(changeType primitive.TopologyChangeType, address net.IP) *message.TopologyChangeEvent {
+	return &message.TopologyChangeEvent{
+		ChangeType: changeType,
+		Address:    &primitive.Inet{Addr: address, Port: int32(recv.port)},
+	}
+}
+
+func equalAddresses(a []net.IP, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOfAddress(addresses []net.IP, address net.IP) int {
+	for i, a := range addresses {
+		if a.Equal(address) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,90 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestProxyTopologyUpdate(t *testing.T) {
+	ip1, ip2, ip3 := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")
+	event := func(changeType primitive.TopologyChangeType, address net.IP) *message.TopologyChangeEvent {
+		return &message.TopologyChangeEvent{ChangeType: changeType, Address: &primitive.Inet{Addr: address, Port: 9042}}
+	}
+
+	topology := newProxyTopology(&common.TopologyConfig{
+		VirtualizationEnabled: true,
+		Addresses:             []net.IP{ip1, ip2},
+		Count:                 2,
+		Index:                 1,
+		NumTokens:             8,
+	}, 9042)
+	events := topology.subscribe()
+	defer topology.unsubscribe(events)
+
+	config, changes, err := topology.update([]net.IP{ip1, ip2})
+	require.Nil(t, err)
+	require.Nil(t, config)
+	require.Nil(t, changes)
+
+	config, changes, err = topology.update([]net.IP{ip2, ip3})
+	require.Nil(t, err)
+	require.Equal(t, &common.TopologyConfig{
+		VirtualizationEnabled: true,
+		Addresses:             []net.IP{ip2, ip3},
+		Count:                 2,
+		Index:                 0,
+		NumTokens:             8,
+	}, config)
+	require.Equal(t, []*message.TopologyChangeEvent{
+		event(primitive.TopologyChangeTypeRemovedNode, ip1),
+		event(primitive.TopologyChangeTypeMovedNode, ip2),
+		event(primitive.TopologyChangeTypeNewNode, ip3),
+	}, changes)
+
+	topology.publish(changes)
+	for _, expected := range changes {
+		require.Equal(t, expected, <-events)
+	}
+
+	// the address of this instance (ip2) must be in the new addresses
+	config, changes, err = topology.update([]net.IP{ip1, ip3})
+	require.NotNil(t, err)
+	require.Nil(t, config)
+	require.Nil(t, changes)
+
+	config, changes, err = topology.update([]net.IP{ip1, ip2, ip3})
+	require.Nil(t, err)
+	require.Equal(t, 1, config.Index)
+	require.Equal(t, 3, config.Count)
+	require.Equal(t, []*message.TopologyChangeEvent{
+		event(primitive.TopologyChangeTypeNewNode, ip1),
+		event(primitive.TopologyChangeTypeMovedNode, ip2),
+		event(primitive.TopologyChangeTypeMovedNode, ip3),
+	}, changes)
+}
+
+func TestProxyTopologyLocalStatusEvent(t *testing.T) {
+	ip1, ip2 := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	topology := newProxyTopology(&common.TopologyConfig{
+		VirtualizationEnabled: true,
+		Addresses:             []net.IP{ip1, ip2},
+		Count:                 2,
+		Index:                 1,
+		NumTokens:             8,
+	}, 9042)
+	require.Equal(t, &message.StatusChangeEvent{
+		ChangeType: primitive.StatusChangeTypeDown,
+		Address:    &primitive.Inet{Addr: ip2, Port: 9042},
+	}, topology.localStatusEvent(primitive.StatusChangeTypeDown))
+
+	_, _, err := topology.update([]net.IP{ip2})
+	require.Nil(t, err)
+	require.Equal(t, &message.StatusChangeEvent{
+		ChangeType: primitive.StatusChangeTypeDown,
+		Address:    &primitive.Inet{Addr: ip2, Port: 9042},
+	}, topology.localStatusEvent(primitive.StatusChangeTypeDown))
+}
@@ -91,9 +91,33 @@ func (psc *PreparedStatementCache) GetByTargetPreparedId(targetPreparedId []byte
 	return data, true
 }
 
+// GetEntries returns the prepared data of every cached statement, including the intercepted statements.
+func (psc *PreparedStatementCache) GetEntries() []PreparedData {
+	psc.lock.RLock()
+	defer psc.lock.RUnlock()
+
+	entries := make([]PreparedData, 0, len(psc.cache)+len(psc.interceptedCache))
+	for _, data := range psc.cache {
+		entries = append(entries, data)
+	}
+	for _, data := range psc.interceptedCache {
+		entries = append(entries, data)
+	}
+	return entries
+}
+
+// GetEntryCounts returns the number of cached statements and the number of cached intercepted statements.
+func (psc *PreparedStatementCache) GetEntryCounts() (statements int, interceptedStatements int) {
+	psc.lock.RLock()
+	defer psc.lock.RUnlock()
+
+	return len(psc.cache), len(psc.interceptedCache)
+}
+
 type PreparedData interface {
 	GetOriginPreparedId() []byte
 	GetTargetPreparedId() []byte
+	GetTargetResultMetadataId() []byte
 	GetPrepareRequestInfo() *PrepareRequestInfo
 	GetOriginVariablesMetadata() *message.VariablesMetadata
 	GetTargetVariablesMetadata() *message.VariablesMetadata
@@ -102,6 +126,7 @@ type PreparedData interface {
 type preparedDataImpl struct {
 	originPreparedId        []byte
 	targetPreparedId        []byte
+	targetResultMetadataId  []byte
 	prepareRequestInfo      *PrepareRequestInfo
 	originVariablesMetadata *message.VariablesMetadata
 	targetVariablesMetadata *message.VariablesMetadata
@@ -113,6 +138,7 @@ func NewPreparedData(
 	return &preparedDataImpl{
 		originPreparedId:        originPreparedResult.PreparedQueryId,
 		targetPreparedId:        targetPreparedResult.PreparedQueryId,
+		targetResultMetadataId:  targetPreparedResult.ResultMetadataId,
 		prepareRequestInfo:      prepareRequestInfo,
 		originVariablesMetadata: originPreparedResult.VariablesMetadata,
 		targetVariablesMetadata: targetPreparedResult.VariablesMetadata,
@@ -127,6 +153,10 @@ func (recv *preparedDataImpl) GetTargetPreparedId() []byte {
 	return recv.targetPreparedId
 }
 
+func (recv *preparedDataImpl) GetTargetResultMetadataId() []byte {
+	return recv.targetResultMetadataId
+}
+
 func (recv *preparedDataImpl) GetPrepareRequestInfo() *PrepareRequestInfo {
 	return recv.prepareRequestInfo
 }
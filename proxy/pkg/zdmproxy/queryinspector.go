@@ -22,7 +22,11 @@ const (
 	statementTypeUse    = statementType("use")
 	statementTypeOther  = statementType("other")
 
-	zdmNowNamedMarker = "zdm__now"
+	zdmNowNamedMarker              = "zdm__now"
+	zdmUuidNamedMarker             = "zdm__uuid"
+	zdmCurrentTimestampNamedMarker = "zdm__current_timestamp"
+	zdmCurrentDateNamedMarker      = "zdm__current_date"
+	zdmCurrentTimeNamedMarker      = "zdm__current_time"
 )
 
 const (
@@ -33,8 +37,10 @@ const (
 )
 
 var (
-	sortedZdmNamedMarkers = []string{zdmNowNamedMarker}
-	parserPool            = sync.Pool{New: func() interface{} {
+	sortedZdmNamedMarkers = []string{
+		zdmCurrentDateNamedMarker, zdmCurrentTimeNamedMarker, zdmCurrentTimestampNamedMarker,
+		zdmNowNamedMarker, zdmUuidNamedMarker}
+	parserPool = sync.Pool{New: func() interface{} {
 		p := parser.NewSimplifiedCqlParser(nil)
 		p.RemoveErrorListeners()
 		p.SetErrorHandler(antlr.NewBailErrorStrategy())
@@ -82,17 +88,34 @@ type QueryInfo interface {
 	// This will always be false for non-INSERT statements or batches not containing INSERT statements.
 	hasNamedBindMarkers() bool
 
-	// Whether the query contains at least one now() function call.
-	// This will always be false for non-INSERT statements or batches not containing INSERT statements.
-	hasNowFunctionCalls() bool
+	// Whether the query contains at least one call of a function that is replaced by the proxy: now(), uuid(),
+	// currentTimestamp(), currentDate(), currentTime() or toTimestamp(now()).
+	// This will always be false for statements other than INSERT, UPDATE, DELETE and BATCH.
+	hasReplaceableFunctionCalls() bool
+
+	// Whether the statement is a lightweight transaction, i.e. a conditional INSERT, UPDATE or DELETE (IF NOT EXISTS,
+	// IF EXISTS or IF <conditions>) or a BATCH that contains at least one of them.
+	isLightweightTransaction() bool
 
-	replaceNowFunctionCallsWithLiteral() (QueryInfo, []*term)
-	replaceNowFunctionCallsWithPositionalBindMarkers() (QueryInfo, []*term)
-	replaceNowFunctionCallsWithNamedBindMarkers() (QueryInfo, []*term)
+	// Whether the statement increments or decrements a counter column by an integer literal (c = c + 1, c -= 1, etc.)
+	// or is a COUNTER BATCH. Counter updates with bind markers can only be detected with the variables metadata
+	// of the prepared statement because collections can be updated with the same operators.
+	hasCounterUpdates() bool
+
+	replaceFunctionCallsWithLiteral() (QueryInfo, []*term)
+	replaceFunctionCallsWithPositionalBindMarkers() (QueryInfo, []*term)
+	replaceFunctionCallsWithNamedBindMarkers() (QueryInfo, []*term)
+}
+
+// newCqlInputStream returns the input of the lexer for a query. The COMMENT rule of the grammar ends a single line
+// comment with a line break so one is appended, otherwise a statement that ends with a comment (e.g. "USE ks -- blah")
+// can not be parsed. The positions of the tokens of the query are the same.
+func newCqlInputStream(query string) *antlr.InputStream {
+	return antlr.NewInputStream(query + "\n")
 }
 
 func inspectCqlQuery(query string, currentKeyspace string, timeUuidGenerator TimeUuidGenerator) QueryInfo {
-	is := antlr.NewInputStream(query)
+	is := newCqlInputStream(query)
 	lexer := lexerPool.Get().(*parser.SimplifiedCqlLexer)
 	defer lexerPool.Put(lexer)
 	lexer.SetInputStream(is)
@@ -116,6 +139,10 @@ type functionCall struct {
 	arity      int
 	startIndex int
 	stopIndex  int
+
+	// The function calls in the arguments of this function call (nil for the arguments that are not function calls),
+	// nil if this function call has no arguments.
+	args []*functionCall
 }
 
 func NewFunctionCall(keyspace string, name string, arity int, startIndex int, stopIndex int) *functionCall {
@@ -129,7 +156,35 @@ func NewFunctionCall(keyspace string, name string, arity int, startIndex int, st
 }
 
 func (f *functionCall) isNow() bool {
-	return (f.keyspace == "" || f.keyspace == systemKeyspaceName) && f.name == nowFunctionName && f.arity == 0
+	return f.isSystemFunction(nowFunctionName, 0)
+}
+
+func (f *functionCall) isSystemFunction(name string, arity int) bool {
+	return (f.keyspace == "" || f.keyspace == systemKeyspaceName) && f.name == name && f.arity == arity
+}
+
+// getReplaceableFunction returns the function that the proxy replaces for this function call or
+// replaceableFunctionNone if this function call is not replaced. toTimestamp(now()) is replaced like currentTimestamp().
+func (f *functionCall) getReplaceableFunction() replaceableFunction {
+	switch {
+	case f.isNow():
+		return replaceableFunctionNow
+	case f.isSystemFunction(uuidFunctionName, 0):
+		return replaceableFunctionUuid
+	case f.isSystemFunction(currentTimestampFunctionName, 0):
+		return replaceableFunctionCurrentTimestamp
+	case f.isSystemFunction(currentDateFunctionName, 0):
+		return replaceableFunctionCurrentDate
+	case f.isSystemFunction(currentTimeFunctionName, 0):
+		return replaceableFunctionCurrentTime
+	case f.isSystemFunction(toTimestampFunctionName, 1) && f.args[0] != nil && f.args[0].isNow():
+		return replaceableFunctionCurrentTimestamp
+	}
+	return replaceableFunctionNone
+}
+
+func (f *functionCall) isReplaceable() bool {
+	return f.getReplaceableFunction() != replaceableFunctionNone
 }
 
 // parsedStatement contains all the information stored by the cqlListener while processing a particular statement.
@@ -139,6 +194,14 @@ type parsedStatement struct {
 	statementIndex int
 	statementType  statementType
 	terms          []*term
+
+	// The keyspace (empty if not present in the query string) and table of this statement.
+	keyspaceName string
+	tableName    string
+
+	// The terms assigned to each column (INSERT values and UPDATE SET col = term)
+	// or that restrict each column with an equality relation (WHERE col = term), keyed on the column name.
+	columnTerms map[string]*term
 }
 
 func (recv *parsedStatement) ShallowClone() *parsedStatement {
@@ -146,9 +209,22 @@ func (recv *parsedStatement) ShallowClone() *parsedStatement {
 		statementIndex: recv.statementIndex,
 		statementType:  recv.statementType,
 		terms:          recv.terms,
+		keyspaceName:   recv.keyspaceName,
+		tableName:      recv.tableName,
+		columnTerms:    recv.columnTerms,
 	}
 }
 
+func (recv *parsedStatement) addColumnTerm(column string, t *term) {
+	if t == nil {
+		return
+	}
+	if recv.columnTerms == nil {
+		recv.columnTerms = make(map[string]*term)
+	}
+	recv.columnTerms[column] = t
+}
+
 type selectClause struct {
 	selectors []selector
 }
@@ -310,10 +386,12 @@ type cqlListener struct {
 	parsedSelectClause *selectClause
 
 	// Only filled in for INSERT, DELETE, UPDATE and BATCH statements
-	parsedStatements      []*parsedStatement
-	positionalBindMarkers bool
-	namedBindMarkers      bool
-	nowFunctionCalls      bool
+	parsedStatements         []*parsedStatement
+	positionalBindMarkers    bool
+	namedBindMarkers         bool
+	replaceableFunctionCalls bool
+	lightweightTransaction   bool
+	counterUpdates           bool
 
 	// internal counters
 	currentPositionalIndex int
@@ -368,8 +446,16 @@ func (l *cqlListener) hasNamedBindMarkers() bool {
 	return l.namedBindMarkers
 }
 
-func (l *cqlListener) hasNowFunctionCalls() bool {
-	return l.nowFunctionCalls
+func (l *cqlListener) hasReplaceableFunctionCalls() bool {
+	return l.replaceableFunctionCalls
+}
+
+func (l *cqlListener) isLightweightTransaction() bool {
+	return l.lightweightTransaction
+}
+
+func (l *cqlListener) hasCounterUpdates() bool {
+	return l.counterUpdates
 }
 
 func (l *cqlListener) EnterCqlStatement(ctx *parser.CqlStatementContext) {
@@ -395,11 +481,11 @@ func (l *cqlListener) EnterCqlStatement(ctx *parser.CqlStatementContext) {
 }
 
 func (l *cqlListener) ExitSelectStatement(ctx *parser.SelectStatementContext) {
-	if !isSystemKeyspace(l.getApplicableKeyspace()) {
-		return
-	}
-
-	if !isLocalTable(l.getTableName()) && !isPeersV1Table(l.getTableName()) && !isPeersV2Table(l.getTableName()) {
+	keyspace := l.getApplicableKeyspace()
+	tableName := l.getTableName()
+	isInterceptedSystemTable := isSystemKeyspace(keyspace) &&
+		(isLocalTable(tableName) || isPeersV1Table(tableName) || isPeersV2Table(tableName))
+	if !isInterceptedSystemTable && !isProxyVirtualTable(keyspace, tableName) && keyspace != adminKeyspaceName {
 		return
 	}
 
@@ -432,10 +518,26 @@ func (l *cqlListener) ExitSelectStatement(ctx *parser.SelectStatementContext) {
 
 func (l *cqlListener) EnterInsertStatement(ctx *parser.InsertStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeInsert}
+	if ctx.K_IF() != nil {
+		l.lightweightTransaction = true
+	}
+	var columns []string
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
+		case parser.IIdentifiersContext:
+			for _, identifierCtx := range childCtx.GetChildren() {
+				if typedIdentifierCtx, ok := identifierCtx.(*parser.IdentifierContext); ok {
+					columns = append(columns, extractIdentifier(typedIdentifierCtx))
+				}
+			}
 		case parser.ITermsContext:
-			parsedStmt.terms = append(parsedStmt.terms, l.extractTerms(childCtx)...)
+			terms := l.extractTerms(childCtx)
+			for i, t := range terms {
+				if i < len(columns) {
+					parsedStmt.addColumnTerm(columns[i], t)
+				}
+			}
+			parsedStmt.terms = append(parsedStmt.terms, terms...)
 		case parser.IUsingClauseContext:
 			parsedStmt.terms = append(parsedStmt.terms, l.extractUsingClauseBindMarkers(childCtx)...)
 		}
@@ -447,6 +549,9 @@ func (l *cqlListener) EnterInsertStatement(ctx *parser.InsertStatementContext) {
 
 func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeUpdate}
+	if ctx.K_IF() != nil {
+		l.lightweightTransaction = true
+	}
 
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
@@ -454,15 +559,25 @@ func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 			parsedStmt.terms = append(parsedStmt.terms, l.extractUsingClauseBindMarkers(childCtx)...)
 		case parser.IUpdateOperationsContext:
 			for _, updateOperation := range childCtx.GetChildren() {
+				if isCounterUpdateOperation(updateOperation) {
+					l.counterUpdates = true
+				}
+				// identifier '=' term
+				isAssignment := isColumnEqualityRelation(updateOperation)
 				for _, termCtx := range updateOperation.GetChildren() {
 					typedTermCtx, ok := termCtx.(*parser.TermContext)
 					if ok {
-						parsedStmt.terms = append(parsedStmt.terms, l.extractTerm(typedTermCtx))
+						t := l.extractTerm(typedTermCtx)
+						if isAssignment {
+							parsedStmt.addColumnTerm(
+								extractIdentifier(updateOperation.GetChild(0).(*parser.IdentifierContext)), t)
+						}
+						parsedStmt.terms = append(parsedStmt.terms, t)
 					}
 				}
 			}
 		case parser.IWhereClauseContext:
-			whereClauseTerms := l.extractWhereClauseTerms(childCtx)
+			whereClauseTerms := l.extractWhereClauseTerms(childCtx, parsedStmt)
 			parsedStmt.terms = append(parsedStmt.terms, whereClauseTerms...)
 		case parser.IConditionsContext:
 			conditionTerms := l.extractConditionsTerms(childCtx)
@@ -476,6 +591,9 @@ func (l *cqlListener) EnterUpdateStatement(ctx *parser.UpdateStatementContext) {
 
 func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 	parsedStmt := &parsedStatement{statementIndex: l.currentBatchChildIndex, statementType: statementTypeDelete}
+	if ctx.K_IF() != nil {
+		l.lightweightTransaction = true
+	}
 
 	for _, childCtx := range ctx.GetChildren() {
 		switch childCtx.(type) {
@@ -496,7 +614,7 @@ func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 				parsedStmt.terms = append(parsedStmt.terms, timeStampTerm)
 			}
 		case parser.IWhereClauseContext:
-			whereClauseTerms := l.extractWhereClauseTerms(childCtx)
+			whereClauseTerms := l.extractWhereClauseTerms(childCtx, parsedStmt)
 			parsedStmt.terms = append(parsedStmt.terms, whereClauseTerms...)
 		case parser.IConditionsContext:
 			conditionTerms := l.extractConditionsTerms(childCtx)
@@ -509,6 +627,9 @@ func (l *cqlListener) EnterDeleteStatement(ctx *parser.DeleteStatementContext) {
 }
 
 func (l *cqlListener) EnterBatchStatement(ctx *parser.BatchStatementContext) {
+	if ctx.K_COUNTER() != nil {
+		l.counterUpdates = true
+	}
 	usingClauseCtx := ctx.UsingClause()
 	if usingClauseCtx != nil {
 		// ignore terms, just process the clause to update the current positional marker position that is used in the actual child statements
@@ -533,6 +654,17 @@ func (l *cqlListener) EnterTableName(ctx *parser.TableNameContext) {
 		identifierContext := qualifiedId.GetChild(2).(*parser.IdentifierContext)
 		l.tableName = extractIdentifier(identifierContext)
 	}
+
+	// the statement that contains this table name is the last one that was added
+	if len(l.parsedStatements) > 0 {
+		parsedStmt := l.parsedStatements[len(l.parsedStatements)-1]
+		if parsedStmt.tableName == "" {
+			if qualifiedId.GetChildCount() != 1 {
+				parsedStmt.keyspaceName = l.keyspaceName
+			}
+			parsedStmt.tableName = l.tableName
+		}
+	}
 }
 
 func extractSelectClause(selectClauseCtx *parser.SelectClauseContext) (*selectClause, error) {
@@ -616,8 +748,8 @@ func (l *cqlListener) extractTerm(termCtx antlr.Tree) *term {
 			return NewLiteralTerm(typedCtx.GetText(), l.currentPositionalIndex-1)
 		case parser.IFunctionCallContext:
 			fCall := extractFunctionCall(childCtx.(*parser.FunctionCallContext))
-			if fCall.isNow() {
-				l.nowFunctionCalls = true
+			if fCall.isReplaceable() {
+				l.replaceableFunctionCalls = true
 			}
 			return NewFunctionCallTerm(fCall, l.currentPositionalIndex-1)
 		case parser.IBindMarkerContext:
@@ -661,19 +793,78 @@ func (l *cqlListener) extractBindMarker(bindMarkerCtx antlr.Tree) *term {
 	return nil
 }
 
-func (l *cqlListener) extractWhereClauseTerms(ctx antlr.Tree) []*term {
+func (l *cqlListener) extractWhereClauseTerms(ctx antlr.Tree, parsedStmt *parsedStatement) []*term {
 	var terms []*term
 
 	for _, relationCtx := range ctx.GetChildren() {
 		relationTyped, ok := relationCtx.(*parser.RelationContext)
 		if ok {
-			terms = append(terms, l.extractRelationTerms(relationTyped)...)
+			relationTerms := l.extractRelationTerms(relationTyped)
+			// identifier '=' term
+			if isColumnEqualityRelation(relationTyped) && len(relationTerms) == 1 {
+				parsedStmt.addColumnTerm(extractIdentifier(relationTyped.GetChild(0).(*parser.IdentifierContext)), relationTerms[0])
+			}
+			terms = append(terms, relationTerms...)
 		}
 	}
 
 	return terms
 }
 
+// isColumnEqualityRelation returns true if the relation (or update operation) has the form identifier '=' term.
+func isColumnEqualityRelation(ctx antlr.Tree) bool {
+	if ctx.GetChildCount() != 3 {
+		return false
+	}
+	if _, ok := ctx.GetChild(0).(*parser.IdentifierContext); !ok {
+		return false
+	}
+	if _, ok := ctx.GetChild(2).(*parser.TermContext); !ok {
+		return false
+	}
+	switch typedCtx := ctx.GetChild(1).(type) {
+	case *parser.OperatorContext:
+		return typedCtx.GetText() == "="
+	case antlr.TerminalNode:
+		return typedCtx.GetText() == "="
+	}
+	return false
+}
+
+// isCounterUpdateOperation returns true if the update operation increments or decrements a column by an integer
+// literal: c = c + 1, c = c - 1, c += 1 or c -= 1. Collections can not be updated with an integer so only counters
+// are updated like that.
+func isCounterUpdateOperation(ctx antlr.Tree) bool {
+	var termCtx antlr.Tree
+	switch ctx.GetChildCount() {
+	case 5: // identifier '=' identifier ( '+' | '-' ) term
+		column, ok := ctx.GetChild(0).(*parser.IdentifierContext)
+		if !ok {
+			return false
+		}
+		operand, ok := ctx.GetChild(2).(*parser.IdentifierContext)
+		if !ok || extractIdentifier(column) != extractIdentifier(operand) {
+			return false
+		}
+		operator, ok := ctx.GetChild(3).(antlr.TerminalNode)
+		if !ok || (operator.GetText() != "+" && operator.GetText() != "-") {
+			return false
+		}
+		termCtx = ctx.GetChild(4)
+	case 3: // identifier ( '+=' | '-=' ) term
+		operator, ok := ctx.GetChild(1).(antlr.TerminalNode)
+		if !ok || (operator.GetText() != "+=" && operator.GetText() != "-=") {
+			return false
+		}
+		termCtx = ctx.GetChild(2)
+	default:
+		return false
+	}
+	typedTermCtx, ok := termCtx.(*parser.TermContext)
+	return ok && typedTermCtx.GetStart() == typedTermCtx.GetStop() &&
+		typedTermCtx.GetStart().GetTokenType() == parser.SimplifiedCqlParserINTEGER
+}
+
 func (l *cqlListener) extractRelationTerms(ctx antlr.Tree) []*term {
 	terms := make([]*term, 0)
 	for _, childCtx := range ctx.GetChildren() {
@@ -761,19 +952,35 @@ func extractFunctionCall(ctx *parser.FunctionCallContext) *functionCall {
 		functionNameChildIdx = 2
 	}
 	functionName := extractIdentifier(qualifiedIdentifierCtx.GetChild(functionNameChildIdx).(*parser.IdentifierContext))
-	// For now we only record the function arity, not the actual function arguments
+	// For now we only record the function arity and the function calls in the arguments,
+	// not the other function arguments
 	functionArity := 0
+	var functionArgs []*functionCall
 	if ctx.GetChildCount() == 4 {
-		functionArity = ctx.GetChild(2).GetChildCount()
+		for _, argCtx := range ctx.GetChild(2).GetChildren() {
+			if _, ok := argCtx.(*parser.FunctionArgContext); !ok {
+				continue
+			}
+			functionArity++
+			var argFunctionCall *functionCall
+			if termCtx, ok := argCtx.GetChild(0).(*parser.TermContext); ok {
+				if argFunctionCallCtx, ok := termCtx.GetChild(0).(*parser.FunctionCallContext); ok {
+					argFunctionCall = extractFunctionCall(argFunctionCallCtx)
+				}
+			}
+			functionArgs = append(functionArgs, argFunctionCall)
+		}
 	}
 	start := ctx.GetStart().GetStart()
 	stop := ctx.GetStop().GetStop()
-	return NewFunctionCall(
+	fCall := NewFunctionCall(
 		keyspaceName,
 		functionName,
 		functionArity,
 		start,
 		stop)
+	fCall.args = functionArgs
+	return fCall
 }
 
 // Returns the identifier in the context object, in its internal form.
@@ -800,7 +1007,7 @@ func extractIdentifier(identifierContext *parser.IdentifierContext) string {
 }
 
 func (l *cqlListener) replaceFunctionCalls(replacementFunc func(query string, functionCall *functionCall) (string, replacementType)) (QueryInfo, []*term) {
-	if !l.hasNowFunctionCalls() {
+	if !l.hasReplaceableFunctionCalls() {
 		return l, make([]*term, 0)
 	}
 	var result string
@@ -848,26 +1055,27 @@ func (l *cqlListener) replaceFunctionCalls(replacementFunc func(query string, fu
 	result = result + l.query[i:len(l.query)]
 	newQueryInfo := l.shallowClone()
 	newQueryInfo.query = result
-	newQueryInfo.nowFunctionCalls = false
+	newQueryInfo.replaceableFunctionCalls = false
 	newQueryInfo.parsedStatements = newParsedStatements
 	newQueryInfo.namedBindMarkers = namedMarkers
 	newQueryInfo.positionalBindMarkers = positionalMarkers
 	return newQueryInfo, replacedTerms
 }
 
-func (l *cqlListener) replaceNowFunctionCallsWithLiteral() (QueryInfo, []*term) {
+func (l *cqlListener) replaceFunctionCallsWithLiteral() (QueryInfo, []*term) {
 	return l.replaceFunctionCalls(func(query string, functionCall *functionCall) (string, replacementType) {
-		if functionCall.isNow() {
-			return l.timeUuidGenerator.GetTimeUuid().String(), literalReplacement
+		function := functionCall.getReplaceableFunction()
+		if function != replaceableFunctionNone {
+			return getReplacementLiteral(function, generateReplacementValue(function, l.timeUuidGenerator)), literalReplacement
 		} else {
 			return "", noReplacement
 		}
 	})
 }
 
-func (l *cqlListener) replaceNowFunctionCallsWithPositionalBindMarkers() (QueryInfo, []*term) {
+func (l *cqlListener) replaceFunctionCallsWithPositionalBindMarkers() (QueryInfo, []*term) {
 	return l.replaceFunctionCalls(func(query string, functionCall *functionCall) (string, replacementType) {
-		if functionCall.isNow() {
+		if functionCall.isReplaceable() {
 			return "?", positionalMarkerReplacement
 		} else {
 			return "", noReplacement
@@ -875,10 +1083,11 @@ func (l *cqlListener) replaceNowFunctionCallsWithPositionalBindMarkers() (QueryI
 	})
 }
 
-func (l *cqlListener) replaceNowFunctionCallsWithNamedBindMarkers() (QueryInfo, []*term) {
+func (l *cqlListener) replaceFunctionCallsWithNamedBindMarkers() (QueryInfo, []*term) {
 	return l.replaceFunctionCalls(func(query string, functionCall *functionCall) (string, replacementType) {
-		if functionCall.isNow() {
-			return fmt.Sprintf(":%s", zdmNowNamedMarker), namedMarkerReplacement
+		function := functionCall.getReplaceableFunction()
+		if function != replaceableFunctionNone {
+			return fmt.Sprintf(":%s", zdmNamedMarkersByFunction[function]), namedMarkerReplacement
 		} else {
 			return "", noReplacement
 		}
@@ -895,7 +1104,9 @@ func (l *cqlListener) shallowClone() *cqlListener {
 		parsedStatements:          l.parsedStatements,
 		positionalBindMarkers:     l.positionalBindMarkers,
 		namedBindMarkers:          l.namedBindMarkers,
-		nowFunctionCalls:          l.nowFunctionCalls,
+		replaceableFunctionCalls:  l.replaceableFunctionCalls,
+		lightweightTransaction:    l.lightweightTransaction,
+		counterUpdates:            l.counterUpdates,
 		currentPositionalIndex:    l.currentPositionalIndex,
 		currentBatchChildIndex:    l.currentBatchChildIndex,
 		timeUuidGenerator:         l.timeUuidGenerator,
@@ -175,6 +175,49 @@ func TestInspectCqlQuery(t *testing.T) {
 			"",
 			"table1",
 		},
+		// comments at the end of the statement
+		{
+			"trailing single line comment dash",
+			"SELECT foo, bar FROM ks1.table1 -- blah",
+			statementTypeSelect,
+			"ks1",
+			"table1",
+		},
+		{
+			"trailing single line comment slash",
+			"SELECT foo, bar FROM ks1.table1 WHERE foo = 1; // blah",
+			statementTypeSelect,
+			"ks1",
+			"table1",
+		},
+		{
+			"trailing single line comment with line break",
+			"SELECT foo, bar FROM ks1.table1 -- blah\n",
+			statementTypeSelect,
+			"ks1",
+			"table1",
+		},
+		{
+			"trailing multi line comment",
+			"SELECT foo, bar FROM ks1.table1 /* blah */",
+			statementTypeSelect,
+			"ks1",
+			"table1",
+		},
+		{
+			"USE with trailing comment",
+			"USE ks1 -- blah",
+			statementTypeUse,
+			"ks1",
+			"",
+		},
+		{
+			"DELETE with trailing comment",
+			"DELETE FROM ks1.table1 WHERE foo = 1 // blah",
+			statementTypeDelete,
+			"ks1",
+			"table1",
+		},
 		// USE
 		{
 			"simple USE",
@@ -183,6 +226,27 @@ func TestInspectCqlQuery(t *testing.T) {
 			"ks1",
 			"",
 		},
+		{
+			"USE case insensitive",
+			"use MyKeyspace;",
+			statementTypeUse,
+			"mykeyspace",
+			"",
+		},
+		{
+			"USE quoted",
+			"USE \"MyKeyspace\"",
+			statementTypeUse,
+			"MyKeyspace",
+			"",
+		},
+		{
+			"USE quoted with escaped quotes",
+			"USE \"My\"\"Keyspace\"",
+			statementTypeUse,
+			"My\"Keyspace",
+			"",
+		},
 		// INSERT
 		{
 			"simple INSERT",
@@ -402,6 +466,61 @@ func TestNowFunctionCalls(t *testing.T) {
 			[]*term{
 				NewFunctionCallTerm(NewFunctionCall("", "now", 0, 47, 51), -1)},
 		},
+		{
+			"time functions INSERT",
+			"INSERT INTO ks1.table1 (a, b, c, d) VALUES (currentTimestamp(), currentDate(), currentTime(), toTimestamp(now()))",
+			statementTypeInsert,
+			uid,
+			true,
+			"INSERT INTO ks1.table1 (a, b, c, d) VALUES (1611069030882, '2021-01-19', '15:10:30.882', 1611069030882)",
+			"INSERT INTO ks1.table1 (a, b, c, d) VALUES (?, ?, ?, ?)",
+			"INSERT INTO ks1.table1 (a, b, c, d) VALUES (:zdm__current_timestamp, :zdm__current_date, :zdm__current_time, :zdm__current_timestamp)",
+			[]*term{
+				NewFunctionCallTerm(NewFunctionCall("", "currenttimestamp", 0, 44, 61), -1),
+				NewFunctionCallTerm(NewFunctionCall("", "currentdate", 0, 64, 76), -1),
+				NewFunctionCallTerm(NewFunctionCall("", "currenttime", 0, 79, 91), -1),
+				NewFunctionCallTerm(newFunctionCallWithArgs(
+					NewFunctionCall("", "totimestamp", 1, 94, 111), NewFunctionCall("", "now", 0, 106, 110)), -1)},
+		},
+		{
+			"time functions UPDATE",
+			"UPDATE ks1.table1 SET a = currentTimestamp(), b = toTimestamp(now()) WHERE c = ? AND d = currentDate()",
+			statementTypeUpdate,
+			uid,
+			true,
+			"UPDATE ks1.table1 SET a = 1611069030882, b = 1611069030882 WHERE c = ? AND d = '2021-01-19'",
+			"UPDATE ks1.table1 SET a = ?, b = ? WHERE c = ? AND d = ?",
+			"UPDATE ks1.table1 SET a = :zdm__current_timestamp, b = :zdm__current_timestamp WHERE c = ? AND d = :zdm__current_date", // invalid but doesn't matter here
+			[]*term{
+				NewFunctionCallTerm(NewFunctionCall("", "currenttimestamp", 0, 26, 43), -1),
+				NewFunctionCallTerm(newFunctionCallWithArgs(
+					NewFunctionCall("", "totimestamp", 1, 50, 67), NewFunctionCall("", "now", 0, 62, 66)), -1),
+				NewFunctionCallTerm(NewFunctionCall("", "currentdate", 0, 89, 101), 0)},
+		},
+		{
+			"time functions inside BATCH",
+			"BEGIN BATCH INSERT INTO ks1.table1 (c1, c2) VALUES (42, system.currentTimestamp()) UPDATE ks1.table1 SET c2 = currentTime() WHERE c1 = 42 APPLY BATCH",
+			statementTypeBatch,
+			uid,
+			true,
+			"BEGIN BATCH INSERT INTO ks1.table1 (c1, c2) VALUES (42, 1611069030882) UPDATE ks1.table1 SET c2 = '15:10:30.882' WHERE c1 = 42 APPLY BATCH",
+			"BEGIN BATCH INSERT INTO ks1.table1 (c1, c2) VALUES (42, ?) UPDATE ks1.table1 SET c2 = ? WHERE c1 = 42 APPLY BATCH",
+			"BEGIN BATCH INSERT INTO ks1.table1 (c1, c2) VALUES (42, :zdm__current_timestamp) UPDATE ks1.table1 SET c2 = :zdm__current_time WHERE c1 = 42 APPLY BATCH",
+			[]*term{
+				NewFunctionCallTerm(NewFunctionCall("system", "currenttimestamp", 0, 56, 80), -1),
+				NewFunctionCallTerm(NewFunctionCall("", "currenttime", 0, 110, 122), -1)},
+		},
+		{
+			"functions that are not replaced INSERT",
+			"INSERT INTO ks1.table1 (a, b, c) VALUES (toTimestamp(?), toDate(now()), currentTimestamp(1))",
+			statementTypeInsert,
+			uid,
+			false,
+			"INSERT INTO ks1.table1 (a, b, c) VALUES (toTimestamp(?), toDate(now()), currentTimestamp(1))",
+			"INSERT INTO ks1.table1 (a, b, c) VALUES (toTimestamp(?), toDate(now()), currentTimestamp(1))",
+			"INSERT INTO ks1.table1 (a, b, c) VALUES (toTimestamp(?), toDate(now()), currentTimestamp(1))",
+			[]*term{},
+		},
 		{
 			"multiple occurrences INSERT",
 			"INSERT INTO ks1.table1 (c1, c2, c3, c4) VALUES ( now(), now ( ), system.now(), \"system\" . \"now\" ( ))",
@@ -555,11 +674,11 @@ func TestNowFunctionCalls(t *testing.T) {
 
 			info := inspectCqlQuery(tt.query, "", &fakeTimeUuidGenerator{uid: tt.replacement})
 			assert.Equal(t, tt.statementType, info.getStatementType())
-			assert.Equal(t, tt.hasNow, info.hasNowFunctionCalls())
+			assert.Equal(t, tt.hasNow, info.hasReplaceableFunctionCalls())
 
-			modifiedWithLiteral, replacedTerms1 := info.replaceNowFunctionCallsWithLiteral()
-			modifiedWithPositional, replacedTerms2 := info.replaceNowFunctionCallsWithPositionalBindMarkers()
-			modifiedWithNamed, replacedTerms3 := info.replaceNowFunctionCallsWithNamedBindMarkers()
+			modifiedWithLiteral, replacedTerms1 := info.replaceFunctionCallsWithLiteral()
+			modifiedWithPositional, replacedTerms2 := info.replaceFunctionCallsWithPositionalBindMarkers()
+			modifiedWithNamed, replacedTerms3 := info.replaceFunctionCallsWithNamedBindMarkers()
 
 			// check modified queries
 			assert.Equal(t, tt.expectedWithLiteral, modifiedWithLiteral.getQuery())
@@ -567,9 +686,9 @@ func TestNowFunctionCalls(t *testing.T) {
 			assert.Equal(t, tt.expectedWithNamed, modifiedWithNamed.getQuery())
 
 			// modified queries should not have now() calls anymore
-			assert.False(t, modifiedWithLiteral.hasNowFunctionCalls())
-			assert.False(t, modifiedWithPositional.hasNowFunctionCalls())
-			assert.False(t, modifiedWithNamed.hasNowFunctionCalls())
+			assert.False(t, modifiedWithLiteral.hasReplaceableFunctionCalls())
+			assert.False(t, modifiedWithPositional.hasReplaceableFunctionCalls())
+			assert.False(t, modifiedWithNamed.hasReplaceableFunctionCalls())
 
 			// statement type should not change in modified queries
 			assert.Equal(t, tt.statementType, modifiedWithLiteral.getStatementType())
@@ -583,6 +702,29 @@ func TestNowFunctionCalls(t *testing.T) {
 	}
 }
 
+// TestUuidFunctionCalls tests the replacement of uuid() calls separately because the replacement is a random uuid.
+func TestUuidFunctionCalls(t *testing.T) {
+	query := "INSERT INTO ks1.table1 (a, b) VALUES (uuid(), system.uuid())"
+	info := inspectCqlQuery(query, "", &fakeTimeUuidGenerator{})
+	require.True(t, info.hasReplaceableFunctionCalls())
+
+	modifiedWithLiteral, replacedTerms := info.replaceFunctionCallsWithLiteral()
+	require.Equal(t, []*term{
+		NewFunctionCallTerm(NewFunctionCall("", "uuid", 0, 38, 43), -1),
+		NewFunctionCallTerm(NewFunctionCall("system", "uuid", 0, 46, 58), -1)}, replacedTerms)
+	require.Regexp(t,
+		"^INSERT INTO ks1.table1 \\(a, b\\) VALUES \\([0-9a-f-]{36}, [0-9a-f-]{36}\\)$", modifiedWithLiteral.getQuery())
+	require.NotEqual(t, modifiedWithLiteral.getQuery()[38:74], modifiedWithLiteral.getQuery()[76:112])
+
+	modifiedWithNamed, _ := info.replaceFunctionCallsWithNamedBindMarkers()
+	require.Equal(t, "INSERT INTO ks1.table1 (a, b) VALUES (:zdm__uuid, :zdm__uuid)", modifiedWithNamed.getQuery())
+}
+
+func newFunctionCallWithArgs(fCall *functionCall, args ...*functionCall) *functionCall {
+	fCall.args = args
+	return fCall
+}
+
 type fakeTimeUuidGenerator struct {
 	uid uuid.UUID
 }
@@ -18,7 +18,7 @@ func NewQueryModifier(timeUuidGenerator TimeUuidGenerator) *QueryModifier {
 
 // replaceQueryString modifies the incoming request in certain conditions:
 //   * the request is a QUERY or PREPARE
-//   * and it contains now() function calls
+//   * and it contains calls of functions that are replaced by the proxy (e.g. now())
 func (recv *QueryModifier) replaceQueryString(currentKeyspace string, context *frameDecodeContext) (*frameDecodeContext, []*statementReplacedTerms, error) {
 	decodedFrame, statementsQueryData, err := context.GetOrDecodeAndInspect(currentKeyspace, recv.timeUuidGenerator)
 	if err != nil {
@@ -71,8 +71,8 @@ func (recv *QueryModifier) replaceQueryInBatchMessage(
 	replacedStatementIndexes := make([]int, 0)
 
 	for idx, stmtQueryData := range statementsQueryData {
-		if stmtQueryData.queryData.hasNowFunctionCalls() {
-			newQueryData, replacedTerms := stmtQueryData.queryData.replaceNowFunctionCallsWithLiteral()
+		if stmtQueryData.queryData.hasReplaceableFunctionCalls() {
+			newQueryData, replacedTerms := stmtQueryData.queryData.replaceFunctionCallsWithLiteral()
 			newStatementsQueryData = append(
 				newStatementsQueryData,
 				&statementQueryData{statementIndex: stmtQueryData.statementIndex, queryData: newQueryData})
@@ -116,7 +116,7 @@ func (recv *QueryModifier) replaceQueryInQueryMessage(
 	if !requiresReplacement {
 		return decodedFrame, []*statementReplacedTerms{}, statementsQueryData, nil
 	}
-	newQueryData, replacedTerms := stmtQueryData.queryData.replaceNowFunctionCallsWithLiteral()
+	newQueryData, replacedTerms := stmtQueryData.queryData.replaceFunctionCallsWithLiteral()
 	newFrame := decodedFrame.Clone()
 	newQueryMsg, ok := newFrame.Body.Message.(*message.Query)
 	if !ok {
@@ -139,9 +139,9 @@ func (recv *QueryModifier) replaceQueryInPrepareMessage(
 	var newQueryData QueryInfo
 	var replacedTerms []*term
 	if stmtQueryData.queryData.hasNamedBindMarkers() {
-		newQueryData, replacedTerms = stmtQueryData.queryData.replaceNowFunctionCallsWithNamedBindMarkers()
+		newQueryData, replacedTerms = stmtQueryData.queryData.replaceFunctionCallsWithNamedBindMarkers()
 	} else {
-		newQueryData, replacedTerms = stmtQueryData.queryData.replaceNowFunctionCallsWithPositionalBindMarkers()
+		newQueryData, replacedTerms = stmtQueryData.queryData.replaceFunctionCallsWithPositionalBindMarkers()
 	}
 	newFrame := decodedFrame.Clone()
 	newPrepareMsg, ok := newFrame.Body.Message.(*message.Prepare)
@@ -153,7 +153,7 @@ func (recv *QueryModifier) replaceQueryInPrepareMessage(
 }
 
 func requiresQueryReplacement(stmtQueryData *statementQueryData) bool {
-	return stmtQueryData.queryData.hasNowFunctionCalls()
+	return stmtQueryData.queryData.hasReplaceableFunctionCalls()
 }
 
 func queryOrPrepareRequiresQueryReplacement(statementsQueryData []*statementQueryData) (bool, *statementQueryData, error) {
@@ -0,0 +1,237 @@
+package zdmproxy
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// fleetRateLimitSyncInterval is how often the rate limiters report their request rate to the fleet store and
+	// update their share of the limit
+	fleetRateLimitSyncInterval = 1 * time.Second
+	// reports that are older than this are not used (e.g. the instance was shut down) and they are deleted once
+	// they are older than fleetRateReportDeleteAfter
+	fleetRateReportStaleAfter  = 3 * fleetRateLimitSyncInterval
+	fleetRateReportDeleteAfter = 1 * time.Minute
+)
+
+// rateReport is the value that a rate limiter reports to the fleet store.
+type rateReport struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	UpdatedAtMs       int64   `json:"updatedAtMs"`
+}
+
+// rateLimiter is a token bucket that limits the rate of QUERY, EXECUTE and BATCH requests to a cluster
+// (ZDM_ORIGIN_MAX_REQUESTS_PER_SECOND and ZDM_TARGET_MAX_REQUESTS_PER_SECOND), the bucket holds up to one second
+// of requests.
+//
+// If the fleet store is enabled then the limit is shared by the proxy instances of the fleet: every instance
+// reports its request rate (including the rejected requests) to the store every fleetRateLimitSyncInterval and
+// gets a share of the limit, see computeRateShare.
+type rateLimiter struct {
+	clusterType      common.ClusterType
+	limit            float64
+	rejectedRequests metrics.Counter
+
+	lock       *sync.Mutex
+	rate       float64 // share of the limit of this instance
+	tokens     float64
+	lastRefill time.Time
+	requests   int64 // requests since the last sync
+	lastSync   time.Time
+
+	reports        fleetReports // nil if the fleet store is disabled
+	instance       string
+	reportsFailing bool
+}
+
+func newRateLimiter(
+	clusterType common.ClusterType, limit int, rejectedRequests metrics.Counter, reports fleetReports,
+	instance string) *rateLimiter {
+	now := time.Now()
+	rateLimiter := &rateLimiter{
+		clusterType:      clusterType,
+		limit:            float64(limit),
+		rejectedRequests: rejectedRequests,
+		lock:             &sync.Mutex{},
+		lastRefill:       now,
+		lastSync:         now,
+		rate:             float64(limit),
+		reports:          reports,
+		instance:         instance,
+	}
+	// the bucket starts full
+	rateLimiter.tokens = rateLimiter.burst()
+	return rateLimiter
+}
+
+// isRateLimitedRequest returns true if the rate limiter applies to the request, the other requests (e.g. the
+// handshake and PREPARE requests) are never rejected.
+func isRateLimitedRequest(request *frame.RawFrame) bool {
+	switch request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodeExecute, primitive.OpCodeBatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// tryAcquire returns false if the request must be rejected.
+func (recv *rateLimiter) tryAcquire() bool {
+	recv.lock.Lock()
+	recv.requests++
+	recv.refill(time.Now())
+	if recv.tokens < 1 {
+		recv.lock.Unlock()
+		recv.rejectedRequests.Add(1)
+		return false
+	}
+	recv.tokens--
+	recv.lock.Unlock()
+	return true
+}
+
+func (recv *rateLimiter) getRate() float64 {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.rate
+}
+
+func (recv *rateLimiter) setRate(rate float64) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.refill(time.Now())
+	recv.rate = rate
+	recv.tokens = math.Min(recv.tokens, recv.burst())
+}
+
+// setLimit changes the limit of the fleet (or of this instance if the fleet store is disabled), the share of the
+// limit of this instance is scaled accordingly until its next sync with the fleet store.
+func (recv *rateLimiter) setLimit(limit int) {
+	recv.lock.Lock()
+	previousLimit := recv.limit
+	recv.limit = float64(limit)
+	rate := recv.rate * recv.limit / previousLimit
+	recv.lock.Unlock()
+	recv.setRate(rate)
+}
+
+func (recv *rateLimiter) getLimit() float64 {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.limit
+}
+
+// burst is the capacity of the bucket, at least one token so that a small share still allows some requests.
+func (recv *rateLimiter) burst() float64 {
+	return math.Max(recv.rate, 1)
+}
+
+func (recv *rateLimiter) refill(now time.Time) {
+	recv.tokens = math.Min(recv.burst(), recv.tokens+now.Sub(recv.lastRefill).Seconds()*recv.rate)
+	recv.lastRefill = now
+}
+
+// run shares the limit with the other proxy instances until ctx is done, the report of this instance is deleted
+// before it returns.
+func (recv *rateLimiter) run(ctx context.Context) {
+	ticker := time.NewTicker(fleetRateLimitSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			deleteCtx, cancelFn := context.WithTimeout(context.Background(), fleetStoreRequestTimeout)
+			defer cancelFn()
+			err := recv.reports.delete(deleteCtx, recv.instance)
+			if err != nil {
+				log.Debugf("Could not delete the %v request rate of this instance from the fleet store: %v.",
+					recv.clusterType, err)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		requestCtx, cancelFn := context.WithTimeout(ctx, fleetRateLimitSyncInterval)
+		err := recv.sync(requestCtx)
+		cancelFn()
+		if err != nil && ctx.Err() == nil && !recv.reportsFailing {
+			log.Warnf("Could not share the %v request rate with the other proxy instances through the fleet store, "+
+				"keeping the current share of the limit (%.1f requests per second): %v.",
+				recv.clusterType, recv.getRate(), err)
+		} else if err == nil && recv.reportsFailing {
+			log.Infof("The %v request rate is shared with the other proxy instances through the fleet store again.",
+				recv.clusterType)
+		}
+		recv.reportsFailing = err != nil
+	}
+}
+
+// sync reports the request rate of this instance and updates its share of the limit with the reports of the
+// other instances.
+func (recv *rateLimiter) sync(ctx context.Context) error {
+	recv.lock.Lock()
+	now := time.Now()
+	requestsPerSecond := 0.0
+	if elapsed := now.Sub(recv.lastSync).Seconds(); elapsed > 0 {
+		requestsPerSecond = float64(recv.requests) / elapsed
+	}
+	recv.requests = 0
+	recv.lastSync = now
+	recv.lock.Unlock()
+
+	report, _ := json.Marshal(&rateReport{RequestsPerSecond: requestsPerSecond, UpdatedAtMs: now.UnixMilli()})
+	err := recv.reports.put(ctx, recv.instance, report)
+	if err != nil {
+		return err
+	}
+	reports, err := recv.reports.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	var otherRequestsPerSecond []float64
+	for instance, value := range reports {
+		if instance == recv.instance {
+			continue
+		}
+		var otherReport rateReport
+		err = json.Unmarshal(value, &otherReport)
+		age := now.Sub(time.UnixMilli(otherReport.UpdatedAtMs))
+		if err != nil || age > fleetRateReportDeleteAfter {
+			err = recv.reports.delete(ctx, instance)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if age <= fleetRateReportStaleAfter {
+			otherRequestsPerSecond = append(otherRequestsPerSecond, otherReport.RequestsPerSecond)
+		}
+	}
+
+	recv.setRate(computeRateShare(recv.getLimit(), requestsPerSecond, otherRequestsPerSecond))
+	return nil
+}
+
+// computeRateShare returns the share of the limit of an instance: half of the limit is split evenly between the
+// instances and the other half in proportion to their request rates. The shares add up to the limit regardless of
+// the number of instances and an instance that was idle can still serve requests until its next report.
+func computeRateShare(limit float64, requestsPerSecond float64, otherRequestsPerSecond []float64) float64 {
+	instances := float64(len(otherRequestsPerSecond) + 1)
+	totalRequestsPerSecond := requestsPerSecond
+	for _, other := range otherRequestsPerSecond {
+		totalRequestsPerSecond += other
+	}
+	if totalRequestsPerSecond <= 0 {
+		return limit / instances
+	}
+	return limit/2/instances + limit/2*requestsPerSecond/totalRequestsPerSecond
+}
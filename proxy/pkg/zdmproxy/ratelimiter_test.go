@@ -0,0 +1,243 @@
+package zdmproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingCounter struct {
+	count int64
+}
+
+func (recv *countingCounter) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.count, int64(valueToAdd))
+}
+
+// fakeReportsStore emulates the prefix requests of the HTTP APIs of etcd and consul that the fleet reports use.
+type fakeReportsStore struct {
+	lock   *sync.Mutex
+	values map[string][]byte
+}
+
+func newFakeReportsStore() *fakeReportsStore {
+	return &fakeReportsStore{lock: &sync.Mutex{}, values: map[string][]byte{}}
+}
+
+func (recv *fakeReportsStore) keys() []string {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	var keys []string
+	for key := range recv.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (recv *fakeReportsStore) put(key string, value []byte) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.values[key] = value
+}
+
+func (recv *fakeReportsStore) etcdHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		var body map[string][]byte
+		require.Nil(t, json.NewDecoder(req.Body).Decode(&body))
+		key := string(body["key"])
+		switch req.URL.Path {
+		case "/v3/kv/range":
+			var kvs []map[string][]byte
+			for otherKey, value := range recv.values {
+				if otherKey >= key && otherKey < string(body["range_end"]) {
+					kvs = append(kvs, map[string][]byte{"key": []byte(otherKey), "value": value})
+				}
+			}
+			rspBody, _ := json.Marshal(map[string]interface{}{"kvs": kvs})
+			rsp.Write(rspBody)
+		case "/v3/kv/put":
+			recv.values[key] = body["value"]
+			fmt.Fprint(rsp, `{}`)
+		case "/v3/kv/deleterange":
+			delete(recv.values, key)
+			fmt.Fprint(rsp, `{}`)
+		default:
+			http.NotFound(rsp, req)
+		}
+	})
+}
+
+func (recv *fakeReportsStore) consulHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		recv.lock.Lock()
+		defer recv.lock.Unlock()
+		key, err := url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), "/v1/kv/"))
+		require.Nil(t, err)
+		switch req.Method {
+		case http.MethodGet:
+			require.Equal(t, "true", req.URL.Query().Get("recurse"))
+			var entries []map[string]interface{}
+			for otherKey, value := range recv.values {
+				if strings.HasPrefix(otherKey, key) {
+					entries = append(entries, map[string]interface{}{"Key": otherKey, "Value": value})
+				}
+			}
+			if len(entries) == 0 {
+				http.NotFound(rsp, req)
+				return
+			}
+			rspBody, _ := json.Marshal(entries)
+			rsp.Write(rspBody)
+		case http.MethodPut:
+			value, err := io.ReadAll(req.Body)
+			require.Nil(t, err)
+			recv.values[key] = value
+			fmt.Fprint(rsp, "true")
+		case http.MethodDelete:
+			delete(recv.values, key)
+			fmt.Fprint(rsp, "true")
+		}
+	})
+}
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	rejected := &countingCounter{}
+	limiter := newRateLimiter(common.ClusterTypeTarget, 10, rejected, nil, "")
+
+	// the bucket starts full
+	for i := 0; i < 10; i++ {
+		require.True(t, limiter.tryAcquire())
+	}
+	require.False(t, limiter.tryAcquire())
+	require.Equal(t, int64(1), atomic.LoadInt64(&rejected.count))
+
+	time.Sleep(250 * time.Millisecond)
+	require.True(t, limiter.tryAcquire())
+	require.True(t, limiter.tryAcquire())
+
+	// a share below one request per second still allows a request
+	limiter.setRate(0.1)
+	limiter.lock.Lock()
+	limiter.tokens = 1
+	limiter.lock.Unlock()
+	require.True(t, limiter.tryAcquire())
+	require.False(t, limiter.tryAcquire())
+}
+
+func TestRateLimiter_ComputeRateShare(t *testing.T) {
+	tests := []struct {
+		name                   string
+		requestsPerSecond      float64
+		otherRequestsPerSecond []float64
+		expected               float64
+	}{
+		{"single instance", 500, nil, 1000},
+		{"idle fleet", 0, []float64{0, 0, 0}, 250},
+		{"even load", 300, []float64{300}, 500},
+		{"busy instance", 900, []float64{100}, 700},
+		{"idle instance", 0, []float64{1000}, 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.InDelta(t, tt.expected, computeRateShare(1000, tt.requestsPerSecond, tt.otherRequestsPerSecond), 0.001)
+		})
+	}
+}
+
+func TestRateLimiter_FleetReports(t *testing.T) {
+	for _, storeType := range []string{fleetStoreTypeEtcd, fleetStoreTypeConsul} {
+		t.Run(storeType, func(t *testing.T) {
+			reportsStore := newFakeReportsStore()
+			handler := reportsStore.etcdHandler(t)
+			if storeType == fleetStoreTypeConsul {
+				handler = reportsStore.consulHandler(t)
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			fleetStoreConfig := &common.FleetStoreConfig{Type: storeType, Endpoints: []string{server.URL}, Key: "zdm/settings"}
+			ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelFn()
+
+			reports := newFleetReports(fleetStoreConfig, "zdm/settings/rates/target")
+			values, err := reports.list(ctx)
+			require.Nil(t, err)
+			require.Empty(t, values)
+
+			reportsStore.put("zdm/settings/rates/origin/a", []byte("1"))
+			require.Nil(t, reports.put(ctx, "a", []byte("2")))
+			require.Nil(t, reports.put(ctx, "b", []byte("3")))
+			values, err = reports.list(ctx)
+			require.Nil(t, err)
+			require.Equal(t, map[string][]byte{"a": []byte("2"), "b": []byte("3")}, values)
+
+			require.Nil(t, reports.delete(ctx, "a"))
+			require.Equal(t, []string{"zdm/settings/rates/origin/a", "zdm/settings/rates/target/b"}, reportsStore.keys())
+		})
+	}
+}
+
+func TestRateLimiter_Sync(t *testing.T) {
+	reportsStore := newFakeReportsStore()
+	server := httptest.NewServer(reportsStore.etcdHandler(t))
+	defer server.Close()
+	fleetStoreConfig := &common.FleetStoreConfig{Type: fleetStoreTypeEtcd, Endpoints: []string{server.URL}, Key: "zdm/settings"}
+	newLimiter := func(instance string) *rateLimiter {
+		return newRateLimiter(common.ClusterTypeOrigin, 1000, &countingCounter{},
+			newFleetReports(fleetStoreConfig, "zdm/settings/rates/origin"), instance)
+	}
+
+	now := time.Now()
+	stale, _ := json.Marshal(&rateReport{RequestsPerSecond: 1000, UpdatedAtMs: now.Add(-10 * time.Second).UnixMilli()})
+	reportsStore.put("zdm/settings/rates/origin/stale", stale)
+	expired, _ := json.Marshal(&rateReport{RequestsPerSecond: 1000, UpdatedAtMs: now.Add(-2 * time.Minute).UnixMilli()})
+	reportsStore.put("zdm/settings/rates/origin/expired", expired)
+	reportsStore.put("zdm/settings/rates/origin/invalid", []byte("x"))
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFn()
+	busy := newLimiter("busy")
+	idle := newLimiter("idle")
+
+	// the idle instance is the only one that reported a rate, the stale report is not used
+	require.Nil(t, idle.sync(ctx))
+	require.InDelta(t, 1000, idle.getRate(), 0.001)
+	require.Equal(t, []string{"zdm/settings/rates/origin/idle", "zdm/settings/rates/origin/stale"}, reportsStore.keys())
+
+	for i := 0; i < 100; i++ {
+		busy.tryAcquire()
+	}
+	require.Nil(t, busy.sync(ctx))
+	require.InDelta(t, 750, busy.getRate(), 0.001)
+	require.Nil(t, idle.sync(ctx))
+	require.InDelta(t, 250, idle.getRate(), 0.001)
+
+	// the report is deleted on shutdown and the limit is not shared anymore
+	fleetRateLimitSyncInterval = 10 * time.Millisecond
+	defer func() { fleetRateLimitSyncInterval = 1 * time.Second }()
+	runCtx, runCancelFn := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		busy.run(runCtx)
+		close(done)
+	}()
+	runCancelFn()
+	<-done
+	require.Nil(t, idle.sync(ctx))
+	require.InDelta(t, 1000, idle.getRate(), 0.001)
+	require.Equal(t, []string{"zdm/settings/rates/origin/idle", "zdm/settings/rates/origin/stale"}, reportsStore.keys())
+}
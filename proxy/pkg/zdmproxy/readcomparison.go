@@ -0,0 +1,210 @@
+package zdmproxy
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"hash/fnv"
+	"sync"
+)
+
+// readComparison compares the response of a read that is returned to the client (primary cluster) with the response
+// of the same read that is forwarded to the secondary cluster by the async connector (ZDM_READ_MIRRORING_ENABLED).
+//
+// Each side provides its response once (or nil if it didn't get one, e.g. timeout) and the responses are compared
+// by whichever side provides the last one so the client response never waits for the comparison.
+type readComparison struct {
+	lock              *sync.Mutex
+	query             string
+	queryDigest       string
+	primaryCluster    common.ClusterType
+	secondaryCluster  common.ClusterType
+	primaryResponse   *frame.RawFrame
+	secondaryResponse *frame.RawFrame
+	pending           int
+	abandoned         bool
+	proxyMetrics      *metrics.ProxyMetrics
+	migrationProgress *migrationProgress
+}
+
+func newReadComparison(
+	query string, primaryCluster common.ClusterType, secondaryCluster common.ClusterType,
+	proxyMetrics *metrics.ProxyMetrics, migrationProgress *migrationProgress) *readComparison {
+	digest := md5.Sum([]byte(query))
+	return &readComparison{
+		lock:              &sync.Mutex{},
+		query:             query,
+		queryDigest:       hex.EncodeToString(digest[:]),
+		primaryCluster:    primaryCluster,
+		secondaryCluster:  secondaryCluster,
+		pending:           2,
+		proxyMetrics:      proxyMetrics,
+		migrationProgress: migrationProgress,
+	}
+}
+
+// getReadComparisonQuery returns the query of the request if it is a read whose responses can be compared,
+// i.e., a SELECT that is sent in a QUERY or EXECUTE request.
+func getReadComparisonQuery(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (string, bool, error) {
+	switch typedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		prepareRequestInfo := typedRequestInfo.GetPreparedData().GetPrepareRequestInfo()
+		queryInfo := prepareRequestInfo.GetQueryInfo()
+		if queryInfo == nil || queryInfo.getStatementType() != statementTypeSelect {
+			return "", false, nil
+		}
+		return prepareRequestInfo.GetQuery(), true, nil
+	case *GenericRequestInfo:
+		if frameContext.GetRawFrame().Header.OpCode != primitive.OpCodeQuery {
+			return "", false, nil
+		}
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return "", false, err
+		}
+		if stmtQueryData.queryData.getStatementType() != statementTypeSelect {
+			return "", false, nil
+		}
+		return stmtQueryData.queryData.getQuery(), true, nil
+	}
+	return "", false, nil
+}
+
+func (recv *readComparison) setPrimaryResponse(response *frame.RawFrame) {
+	recv.setResponse(true, response)
+}
+
+func (recv *readComparison) setSecondaryResponse(response *frame.RawFrame) {
+	recv.setResponse(false, response)
+}
+
+// abandon is called by a side that will not provide a response, the responses are not compared.
+func (recv *readComparison) abandon() {
+	recv.setResponse(false, nil)
+}
+
+func (recv *readComparison) setResponse(primary bool, response *frame.RawFrame) {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	if response == nil {
+		recv.abandoned = true
+	} else if primary {
+		recv.primaryResponse = response
+	} else {
+		recv.secondaryResponse = response
+	}
+	recv.pending--
+	compare := recv.pending == 0 && !recv.abandoned
+	recv.lock.Unlock()
+
+	if compare {
+		recv.compare()
+	}
+}
+
+func (recv *readComparison) compare() {
+	primarySuccessful := isResponseSuccessful(recv.primaryResponse)
+	secondarySuccessful := isResponseSuccessful(recv.secondaryResponse)
+	if !primarySuccessful && !secondarySuccessful {
+		return
+	}
+
+	recv.proxyMetrics.ReadComparisons.Add(1)
+	if primarySuccessful != secondarySuccessful {
+		recv.proxyMetrics.ReadComparisonMismatchesError.Add(1)
+		recv.migrationProgress.recordReadComparison(true)
+		recv.logMismatch("error", fmt.Sprintf("%v response is %v and %v response is %v",
+			recv.primaryCluster, describeComparedResponse(recv.primaryResponse),
+			recv.secondaryCluster, describeComparedResponse(recv.secondaryResponse)))
+		return
+	}
+
+	primarySummary, err := summarizeRows(recv.primaryResponse)
+	if err != nil {
+		log.Debugf("Could not compare the responses of query with digest %v: %v.", recv.queryDigest, err)
+		return
+	}
+	secondarySummary, err := summarizeRows(recv.secondaryResponse)
+	if err != nil {
+		log.Debugf("Could not compare the responses of query with digest %v: %v.", recv.queryDigest, err)
+		return
+	}
+
+	recv.migrationProgress.recordReadComparison(primarySummary.rowCount != secondarySummary.rowCount ||
+		primarySummary.checksum != secondarySummary.checksum)
+	if primarySummary.rowCount != secondarySummary.rowCount {
+		recv.proxyMetrics.ReadComparisonMismatchesRowCount.Add(1)
+		recv.logMismatch("row count", fmt.Sprintf("%v returned %d row(s) and %v returned %d row(s)",
+			recv.primaryCluster, primarySummary.rowCount, recv.secondaryCluster, secondarySummary.rowCount))
+	} else if primarySummary.checksum != secondarySummary.checksum {
+		recv.proxyMetrics.ReadComparisonMismatchesChecksum.Add(1)
+		recv.logMismatch("checksum", fmt.Sprintf("%v and %v returned %d row(s) with different values",
+			recv.primaryCluster, recv.secondaryCluster, primarySummary.rowCount))
+	}
+}
+
+func (recv *readComparison) logMismatch(mismatchType string, details string) {
+	log.Warnf("Read comparison mismatch (%v) for query with digest %v: %v.", mismatchType, recv.queryDigest, details)
+	log.Debugf("Query with digest %v: %v", recv.queryDigest, recv.query)
+}
+
+func describeComparedResponse(response *frame.RawFrame) string {
+	if isResponseSuccessful(response) {
+		return "successful"
+	}
+	errMsg, err := decodeErrorResult(response)
+	if err != nil {
+		return "an error"
+	}
+	return fmt.Sprintf("an error (%v)", errMsg.GetErrorCode())
+}
+
+type rowsSummary struct {
+	rowCount int
+	checksum uint64
+}
+
+// summarizeRows returns the number of rows of a ROWS result and a checksum of their values. The checksum doesn't
+// depend on the order of the rows because the clusters can return the rows of different partitions in a different
+// order. Results that are not ROWS results (e.g. VOID) have 0 rows.
+func summarizeRows(response *frame.RawFrame) (*rowsSummary, error) {
+	summary := &rowsSummary{}
+	if response.Header.OpCode != primitive.OpCodeResult {
+		return summary, nil
+	}
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(response)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode response: %w", err)
+	}
+	rows, ok := decodedFrame.Body.Message.(*message.RowsResult)
+	if !ok {
+		return summary, nil
+	}
+	summary.rowCount = len(rows.Data)
+	lengthBytes := make([]byte, 4)
+	for _, row := range rows.Data {
+		hash := fnv.New64a()
+		for _, column := range row {
+			length := int32(-1)
+			if column != nil {
+				length = int32(len(column))
+			}
+			binary.BigEndian.PutUint32(lengthBytes, uint32(length))
+			hash.Write(lengthBytes)
+			hash.Write(column)
+		}
+		summary.checksum += hash.Sum64()
+	}
+	return summary, nil
+}
@@ -0,0 +1,49 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSummarizeRows(t *testing.T) {
+	newRowsResponse := func(rows message.RowSet) *rowsSummary {
+		rawFrame := newTestRawFrame(t, primitive.ProtocolVersion4, 1, &message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 2,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "tb", Name: "k", Index: 0, Type: datatype.Varchar},
+					{Keyspace: "ks", Table: "tb", Name: "v", Index: 1, Type: datatype.Varchar},
+				},
+			},
+			Data: rows,
+		})
+		summary, err := summarizeRows(rawFrame)
+		require.Nil(t, err)
+		return summary
+	}
+
+	rows := newRowsResponse(message.RowSet{{[]byte("a"), []byte("1")}, {[]byte("b"), []byte("2")}})
+	require.Equal(t, 2, rows.rowCount)
+
+	reordered := newRowsResponse(message.RowSet{{[]byte("b"), []byte("2")}, {[]byte("a"), []byte("1")}})
+	require.Equal(t, rows, reordered)
+
+	differentValue := newRowsResponse(message.RowSet{{[]byte("a"), []byte("1")}, {[]byte("b"), []byte("3")}})
+	require.Equal(t, 2, differentValue.rowCount)
+	require.NotEqual(t, rows.checksum, differentValue.checksum)
+
+	// the column boundaries are part of the checksum
+	shiftedValue := newRowsResponse(message.RowSet{{[]byte("a1"), []byte("")}, {[]byte("b"), []byte("2")}})
+	require.NotEqual(t, rows.checksum, shiftedValue.checksum)
+
+	nullValue := newRowsResponse(message.RowSet{{[]byte("a"), nil}, {[]byte("b"), []byte("2")}})
+	emptyValue := newRowsResponse(message.RowSet{{[]byte("a"), []byte{}}, {[]byte("b"), []byte("2")}})
+	require.NotEqual(t, nullValue.checksum, emptyValue.checksum)
+
+	void, err := summarizeRows(newTestRawFrame(t, primitive.ProtocolVersion4, 1, &message.VoidResult{}))
+	require.Nil(t, err)
+	require.Equal(t, &rowsSummary{}, void)
+}
@@ -0,0 +1,106 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"sort"
+)
+
+// applicationReadRouting forwards the reads of specific applications to a cluster (see
+// ZDM_APPLICATION_READ_ROUTING_RULES). Applications are identified by the APPLICATION_NAME option of the STARTUP
+// request so the rules of a client connection are selected once during the handshake, see getSessionRules.
+type applicationReadRouting struct {
+	rulesByApplication map[string]sessionReadRoutingRules
+}
+
+// sessionReadRoutingRules are the rules of the application of a client connection, sorted from the most specific
+// (table) to the least specific (every keyspace).
+type sessionReadRoutingRules []*common.ApplicationReadRoutingRule
+
+// newApplicationReadRouting returns nil if there are no rules.
+func newApplicationReadRouting(rules []*common.ApplicationReadRoutingRule) *applicationReadRouting {
+	if len(rules) == 0 {
+		return nil
+	}
+	rulesByApplication := make(map[string]sessionReadRoutingRules)
+	for _, rule := range rules {
+		rulesByApplication[rule.Application] = append(rulesByApplication[rule.Application], rule)
+	}
+	for _, applicationRules := range rulesByApplication {
+		sort.SliceStable(applicationRules, func(i, j int) bool {
+			return readRoutingRuleSpecificity(applicationRules[i]) > readRoutingRuleSpecificity(applicationRules[j])
+		})
+	}
+	return &applicationReadRouting{rulesByApplication: rulesByApplication}
+}
+
+func readRoutingRuleSpecificity(rule *common.ApplicationReadRoutingRule) int {
+	if rule.Table != "" {
+		return 2
+	}
+	if rule.Keyspace != "" {
+		return 1
+	}
+	return 0
+}
+
+// getSessionRules returns nil if there are no rules for the application.
+func (recv *applicationReadRouting) getSessionRules(applicationName string) sessionReadRoutingRules {
+	if applicationName == "" {
+		return nil
+	}
+	return recv.rulesByApplication[applicationName]
+}
+
+// match returns the most specific rule that applies to a read of the table and nil if there is none.
+func (recv sessionReadRoutingRules) match(keyspace string, table string) *common.ApplicationReadRoutingRule {
+	for _, rule := range recv {
+		if rule.Keyspace != "" && rule.Keyspace != keyspace {
+			continue
+		}
+		if rule.Table != "" && rule.Table != table {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// matchRequest returns the rule that applies to the request and nil if the request is not a read of a non system
+// table or if it is pinned to a cluster by a table routing rule or by a statement filter rule. Only QUERY and EXECUTE requests are routed, the forward decision of a PREPARE request is stored in
+// the prepared statement cache which is shared by every application.
+func (recv sessionReadRoutingRules) matchRequest(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (*common.ApplicationReadRoutingRule, error) {
+	var queryInfo QueryInfo
+	switch typedRequestInfo := requestInfo.(type) {
+	case *GenericRequestInfo:
+		if frameContext.GetRawFrame().Header.OpCode != primitive.OpCodeQuery {
+			return nil, nil
+		}
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return nil, err
+		}
+		queryInfo = stmtQueryData.queryData
+	case *ExecuteRequestInfo:
+		prepareRequestInfo := typedRequestInfo.GetPreparedData().GetPrepareRequestInfo()
+		switch prepareRequestInfo.GetBaseRequestInfo().(type) {
+		case *TableRoutingRequestInfo, *StatementFilterRequestInfo:
+			return nil, nil // pinned by a table routing rule or by a statement filter rule
+		}
+		queryInfo = prepareRequestInfo.GetQueryInfo()
+	}
+
+	if queryInfo == nil || queryInfo.getStatementType() != statementTypeSelect || isSystemQuery(queryInfo) {
+		return nil, nil
+	}
+	return recv.match(queryInfo.getApplicableKeyspace(), queryInfo.getTableName()), nil
+}
+
+func getReadRoutingForwardDecision(cluster common.ClusterType) forwardDecision {
+	if cluster == common.ClusterTypeTarget {
+		return forwardToTarget
+	}
+	return forwardToOrigin
+}
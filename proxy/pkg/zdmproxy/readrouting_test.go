@@ -0,0 +1,84 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestApplicationReadRouting(t *testing.T) {
+	require.Nil(t, newApplicationReadRouting(nil))
+
+	anyKeyspaceRule := &common.ApplicationReadRoutingRule{Application: "analytics", Cluster: common.ClusterTypeOrigin}
+	keyspaceRule := &common.ApplicationReadRoutingRule{Application: "analytics", Keyspace: "ks", Cluster: common.ClusterTypeTarget}
+	tableRule := &common.ApplicationReadRoutingRule{Application: "analytics", Keyspace: "ks", Table: "tb", Cluster: common.ClusterTypeOrigin}
+	otherRule := &common.ApplicationReadRoutingRule{Application: "reporting", Keyspace: "ks", Cluster: common.ClusterTypeTarget}
+	routing := newApplicationReadRouting(
+		[]*common.ApplicationReadRoutingRule{anyKeyspaceRule, keyspaceRule, otherRule, tableRule})
+
+	require.Nil(t, routing.getSessionRules(""))
+	require.Nil(t, routing.getSessionRules("billing"))
+	require.Equal(t, sessionReadRoutingRules{otherRule}, routing.getSessionRules("reporting"))
+
+	rules := routing.getSessionRules("analytics")
+	require.Equal(t, sessionReadRoutingRules{tableRule, keyspaceRule, anyKeyspaceRule}, rules)
+	require.Equal(t, tableRule, rules.match("ks", "tb"))
+	require.Equal(t, keyspaceRule, rules.match("ks", "tb2"))
+	require.Equal(t, anyKeyspaceRule, rules.match("ks2", "tb"))
+	require.Nil(t, routing.getSessionRules("reporting").match("ks2", "tb"))
+}
+
+func TestSessionReadRoutingRules_MatchRequest(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newPreparedData := func(query string) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToOrigin, true, true), nil, false, query, "")
+		prepareRequestInfo.queryInfo = inspectCqlQuery(query, "", generator)
+		return &preparedDataImpl{prepareRequestInfo: prepareRequestInfo}
+	}
+
+	rule := &common.ApplicationReadRoutingRule{Application: "analytics", Keyspace: "ks", Cluster: common.ClusterTypeTarget}
+	rules := sessionReadRoutingRules{rule}
+
+	tests := []struct {
+		name            string
+		msg             message.Message
+		requestInfo     RequestInfo
+		currentKeyspace string
+		expected        *common.ApplicationReadRoutingRule
+	}{
+		{"select", &message.Query{Query: "SELECT * FROM ks.tb"}, NewGenericRequestInfo(forwardToOrigin, true, true), "", rule},
+		{"select current keyspace", &message.Query{Query: "SELECT * FROM tb"}, NewGenericRequestInfo(forwardToOrigin, true, true), "ks", rule},
+		{"select other keyspace", &message.Query{Query: "SELECT * FROM ks2.tb"}, NewGenericRequestInfo(forwardToOrigin, true, true), "", nil},
+		{"system query", &message.Query{Query: "SELECT * FROM system.local"}, NewGenericRequestInfo(forwardToOrigin, false, true), "ks", nil},
+		{"insert", &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"}, NewGenericRequestInfo(forwardToBoth, false, true), "", nil},
+		{"execute select", &message.Execute{QueryId: []byte("ID")}, NewExecuteRequestInfo(newPreparedData("SELECT * FROM ks.tb WHERE a = ?")), "", rule},
+		{"execute insert", &message.Execute{QueryId: []byte("ID")}, NewExecuteRequestInfo(newPreparedData("INSERT INTO ks.tb (a) VALUES (?)")), "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			matched, err := rules.matchRequest(NewFrameDecodeContext(f), tt.requestInfo, tt.currentKeyspace, generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, matched)
+		})
+	}
+}
+
+func TestNewRoutedReadExecuteRequestInfo(t *testing.T) {
+	prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToOrigin, true, true), nil, false, "SELECT * FROM ks.tb", "")
+	preparedData := &preparedDataImpl{prepareRequestInfo: prepareRequestInfo}
+
+	requestInfo := NewExecuteRequestInfo(preparedData)
+	require.Equal(t, forwardToOrigin, requestInfo.GetForwardDecision())
+	require.True(t, requestInfo.ShouldAlsoBeSentAsync())
+
+	requestInfo = NewRoutedReadExecuteRequestInfo(preparedData, forwardToTarget)
+	require.Equal(t, forwardToTarget, requestInfo.GetForwardDecision())
+	require.False(t, requestInfo.ShouldAlsoBeSentAsync())
+	require.True(t, requestInfo.ShouldBeTrackedInMetrics())
+}
@@ -0,0 +1,104 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/datacodec"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/google/uuid"
+	"strconv"
+	"time"
+)
+
+// replaceableFunction is a non-deterministic CQL function whose calls are replaced by the proxy with a value that
+// the proxy generates so that both clusters write the same value (see ZDM_REPLACE_CQL_FUNCTIONS).
+type replaceableFunction string
+
+const (
+	replaceableFunctionNone             = replaceableFunction("")
+	replaceableFunctionNow              = replaceableFunction("now")
+	replaceableFunctionUuid             = replaceableFunction("uuid")
+	replaceableFunctionCurrentTimestamp = replaceableFunction("currenttimestamp")
+	replaceableFunctionCurrentDate      = replaceableFunction("currentdate")
+	replaceableFunctionCurrentTime      = replaceableFunction("currenttime")
+)
+
+const (
+	uuidFunctionName             = "uuid"
+	currentTimestampFunctionName = "currenttimestamp"
+	currentDateFunctionName      = "currentdate"
+	currentTimeFunctionName      = "currenttime"
+	toTimestampFunctionName      = "totimestamp"
+)
+
+var zdmNamedMarkersByFunction = map[replaceableFunction]string{
+	replaceableFunctionNow:              zdmNowNamedMarker,
+	replaceableFunctionUuid:             zdmUuidNamedMarker,
+	replaceableFunctionCurrentTimestamp: zdmCurrentTimestampNamedMarker,
+	replaceableFunctionCurrentDate:      zdmCurrentDateNamedMarker,
+	replaceableFunctionCurrentTime:      zdmCurrentTimeNamedMarker,
+}
+
+// getReplaceableFunctionOfNamedMarker returns the function that was replaced by a zdm named bind marker or
+// replaceableFunctionNone if the name is not one of the zdm named bind markers.
+func getReplaceableFunctionOfNamedMarker(name string) replaceableFunction {
+	for function, namedMarker := range zdmNamedMarkersByFunction {
+		if namedMarker == name {
+			return function
+		}
+	}
+	return replaceableFunctionNone
+}
+
+// generateReplacementValue returns the value of a replaced function call: a uuid.UUID for now() and uuid() and
+// a time.Time in UTC for currentTimestamp(), currentDate(), currentTime() and toTimestamp(now()).
+// The time is the time of a new timeuuid (truncated to milliseconds) like toTimestamp(now()) on the server.
+func generateReplacementValue(function replaceableFunction, timeUuidGenerator TimeUuidGenerator) interface{} {
+	switch function {
+	case replaceableFunctionNow:
+		return timeUuidGenerator.GetTimeUuid()
+	case replaceableFunctionUuid:
+		return uuid.New()
+	default:
+		sec, nsec := timeUuidGenerator.GetTimeUuid().Time().UnixTime()
+		return time.Unix(sec, nsec).UTC().Truncate(time.Millisecond)
+	}
+}
+
+// getReplacementLiteral returns the CQL literal of a value that was returned by generateReplacementValue.
+func getReplacementLiteral(function replaceableFunction, value interface{}) string {
+	switch typedValue := value.(type) {
+	case uuid.UUID:
+		return typedValue.String()
+	case time.Time:
+		switch function {
+		case replaceableFunctionCurrentDate:
+			return "'" + typedValue.Format("2006-01-02") + "'"
+		case replaceableFunctionCurrentTime:
+			return "'" + typedValue.Format("15:04:05.000") + "'"
+		default:
+			return strconv.FormatInt(typedValue.UnixNano()/int64(time.Millisecond), 10)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// encodeReplacementValue encodes a value that was returned by generateReplacementValue with the type of the bind marker
+// that replaced the function call.
+func encodeReplacementValue(
+	value interface{}, version primitive.ProtocolVersion, valueType datatype.DataType) (*primitive.Value, error) {
+	newValueCodec, err := datacodec.NewCodec(valueType)
+	if err != nil {
+		return nil, fmt.Errorf("could not create codec for new %v value: %w", valueType, err)
+	}
+
+	if uuidValue, ok := value.(uuid.UUID); ok {
+		value = primitive.UUID(uuidValue)
+	}
+	encodedValue, err := newValueCodec.Encode(value, version)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode new %v value: %w", valueType, err)
+	}
+
+	return primitive.NewValue(encodedValue), nil
+}
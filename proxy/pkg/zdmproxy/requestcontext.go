@@ -96,9 +96,29 @@ type requestContextImpl struct {
 	targetResponse        *frame.RawFrame
 	state                 int
 	timer                 *time.Timer
+	timeout               requestTimeout // timeout of the request on each cluster
+	timerStart            time.Time      // when the timeout of the request started
 	lock                  *sync.Mutex
 	startTime             time.Time
 	customResponseChannel chan *customResponse
+	explanation           *requestExplanation  // nil if the request is not being explained
+	trace                 *requestTrace        // nil if the request is not traced
+	ignoreTargetFailure   bool                 // sampled write with ZDM_TARGET_WRITE_SAMPLING_IGNORE_TARGET_FAILURES
+	primaryResponseOnly   bool                 // lightweight transaction with ZDM_LWT_POLICY DUAL_WRITE
+	secondaryWrite        *frame.RawFrame      // write of the secondary cluster if its failure is not returned to the client
+	keyspace              string               // keyspace of the client connection when the request was sent
+	readComparison        *readComparison      // nil if the responses of the request are not compared
+	writeComparison       *writeComparison     // nil if the responses of the dual write are not compared
+	tableRequestMetrics   *tableRequestMetrics // nil if the request is not tracked in the table level metrics
+	statementDuration     metrics.Histogram    // nil if the request is not forwarded to a cluster
+	originLatency         time.Duration        // 0 until the response of ORIGIN is received
+	targetLatency         time.Duration        // 0 until the response of TARGET is received
+	continuousPaging      *continuousPaging    // nil if the request is not a DSE continuous paging request
+
+	// nil if the request is not retried on the cluster (see ZDM_ORIGIN_REQUEST_MAX_RETRIES and
+	// ZDM_TARGET_REQUEST_MAX_RETRIES)
+	originRetry *requestRetry
+	targetRetry *requestRetry
 }
 
 func NewRequestContext(req *frame.RawFrame, requestInfo RequestInfo, startTime time.Time, customResponseChannel chan *customResponse) *requestContextImpl {
@@ -123,6 +143,56 @@ func (recv *requestContextImpl) SetTimer(timer *time.Timer) {
 	recv.timer = timer
 }
 
+// resetTimer restarts the timeout of a pending request, e.g. when a page of a continuous paging request is received.
+// Returns false if the request is no longer pending.
+func (recv *requestContextImpl) resetTimer() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending {
+		return false
+	}
+	if recv.timer != nil {
+		recv.timerStart = time.Now()
+		recv.timer.Reset(recv.timeout.forDecision(recv.requestInfo.GetForwardDecision()))
+	}
+	return true
+}
+
+// extendTimer is called when the timer of the request fires, it restarts the timer and returns true if the clusters
+// that didn't send their response yet are still within their timeout (i.e. the timer fired after the timeout of a
+// cluster whose response was already received).
+func (recv *requestContextImpl) extendTimer() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending || recv.timer == nil {
+		return false
+	}
+	missingOrigin, missingTarget := recv.missingResponses()
+	elapsed := time.Since(recv.timerStart)
+	remaining := time.Duration(0)
+	for _, cluster := range []struct {
+		missing bool
+		timeout time.Duration
+	}{{missingOrigin, recv.timeout.origin}, {missingTarget, recv.timeout.target}} {
+		if !cluster.missing {
+			continue
+		}
+		if cluster.timeout <= elapsed {
+			return false
+		}
+		if remaining == 0 || cluster.timeout-elapsed < remaining {
+			remaining = cluster.timeout - elapsed
+		}
+	}
+	if remaining == 0 {
+		return false
+	}
+	recv.timer.Reset(remaining)
+	return true
+}
+
 func (recv *requestContextImpl) SetTimeout(nodeMetrics *metrics.NodeMetrics, req *frame.RawFrame) bool {
 	recv.lock.Lock()
 	defer recv.lock.Unlock()
@@ -135,6 +205,7 @@ func (recv *requestContextImpl) SetTimeout(nodeMetrics *metrics.NodeMetrics, req
 	// check if it's the same request (could be a timeout for a previous one that has since completed)
 	if recv.request == req {
 		recv.state = RequestTimedOut
+		recv.trace.endForwardTimeouts()
 		if recv.requestInfo.ShouldBeTrackedInMetrics() {
 			sentOrigin := false
 			sentTarget := false
@@ -149,9 +220,11 @@ func (recv *requestContextImpl) SetTimeout(nodeMetrics *metrics.NodeMetrics, req
 			}
 			if sentOrigin && recv.originResponse == nil {
 				nodeMetrics.OriginMetrics.ClientTimeouts.Add(1)
+				nodeMetrics.OriginMetrics.InFlightRequests.Subtract(1)
 			}
 			if sentTarget && recv.targetResponse == nil {
 				nodeMetrics.TargetMetrics.ClientTimeouts.Add(1)
+				nodeMetrics.TargetMetrics.InFlightRequests.Subtract(1)
 			}
 		}
 		return true
@@ -160,7 +233,28 @@ func (recv *requestContextImpl) SetTimeout(nodeMetrics *metrics.NodeMetrics, req
 	return false
 }
 
-func (recv *requestContextImpl) Cancel(_ *metrics.NodeMetrics) bool {
+// getMissingResponses returns which clusters the request was forwarded to and didn't send a response.
+func (recv *requestContextImpl) getMissingResponses() (origin bool, target bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.missingResponses()
+}
+
+// missingResponses should only be called while holding the lock.
+func (recv *requestContextImpl) missingResponses() (origin bool, target bool) {
+	switch recv.requestInfo.GetForwardDecision() {
+	case forwardToBoth:
+		return recv.originResponse == nil, recv.targetResponse == nil
+	case forwardToOrigin:
+		return recv.originResponse == nil, false
+	case forwardToTarget:
+		return false, recv.targetResponse == nil
+	default:
+		return false, false
+	}
+}
+
+func (recv *requestContextImpl) Cancel(nodeMetrics *metrics.NodeMetrics) bool {
 	recv.lock.Lock()
 	defer recv.lock.Unlock()
 
@@ -173,6 +267,15 @@ func (recv *requestContextImpl) Cancel(_ *metrics.NodeMetrics) bool {
 	if recv.timer != nil {
 		recv.timer.Stop()
 	}
+	if recv.requestInfo.ShouldBeTrackedInMetrics() {
+		missingOrigin, missingTarget := recv.missingResponses()
+		if missingOrigin {
+			nodeMetrics.OriginMetrics.InFlightRequests.Subtract(1)
+		}
+		if missingTarget {
+			nodeMetrics.TargetMetrics.InFlightRequests.Subtract(1)
+		}
+	}
 	return true
 }
 
@@ -182,6 +285,7 @@ func (recv *requestContextImpl) SetResponse(nodeMetrics *metrics.NodeMetrics, f
 	if !updated {
 		return false
 	}
+	recv.trace.endForward(cluster, f)
 
 	finished := state == RequestDone
 	if finished && recv.timer != nil {
@@ -194,8 +298,10 @@ func (recv *requestContextImpl) SetResponse(nodeMetrics *metrics.NodeMetrics, f
 		switch connectorType {
 		case ClusterConnectorTypeOrigin:
 			nodeMetrics.OriginMetrics.RequestDuration.Track(recv.startTime)
+			nodeMetrics.OriginMetrics.InFlightRequests.Subtract(1)
 		case ClusterConnectorTypeTarget:
 			nodeMetrics.TargetMetrics.RequestDuration.Track(recv.startTime)
+			nodeMetrics.TargetMetrics.InFlightRequests.Subtract(1)
 		case ClusterConnectorTypeAsync:
 		default:
 			log.Errorf("could not recognize connector type %v", connectorType)
@@ -205,6 +311,40 @@ func (recv *requestContextImpl) SetResponse(nodeMetrics *metrics.NodeMetrics, f
 	return finished
 }
 
+// nextRetry returns the retry state of the cluster and the delay before the request is sent again, false if the
+// request is no longer pending or if it has no retries left on the cluster. The wait group is incremented while the
+// request is still pending so that the retry is tracked until it is sent.
+func (recv *requestContextImpl) nextRetry(
+	cluster common.ClusterType, wg *sync.WaitGroup) (*requestRetry, time.Duration, bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	if recv.state != RequestPending {
+		return nil, 0, false
+	}
+
+	retry := recv.originRetry
+	if cluster == common.ClusterTypeTarget {
+		retry = recv.targetRetry
+	}
+	if retry == nil || retry.retries >= retry.policy.maxRetries {
+		return nil, 0, false
+	}
+
+	retry.retries++
+	delay := retry.backoff.Duration()
+	recv.explanation.addRequestRetry(cluster, retry.retries)
+	wg.Add(1)
+	return retry, delay, true
+}
+
+func (recv *requestContextImpl) isPending() bool {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	return recv.state == RequestPending
+}
+
 func (recv *requestContextImpl) updateInternalState(f *frame.RawFrame, cluster common.ClusterType) (state int, updated bool) {
 	recv.lock.Lock()
 	defer recv.lock.Unlock()
@@ -217,8 +357,10 @@ func (recv *requestContextImpl) updateInternalState(f *frame.RawFrame, cluster c
 	switch cluster {
 	case common.ClusterTypeOrigin:
 		recv.originResponse = f
+		recv.originLatency = time.Since(recv.startTime)
 	case common.ClusterTypeTarget:
 		recv.targetResponse = f
+		recv.targetLatency = time.Since(recv.startTime)
 	default:
 		log.Errorf("could not recognize cluster type %v", cluster)
 	}
@@ -246,6 +388,14 @@ func (recv *requestContextImpl) updateInternalState(f *frame.RawFrame, cluster c
 	return recv.state, true
 }
 
+// getClusterLatencies returns the time it took to receive the response of each cluster, 0 if it wasn't received.
+func (recv *requestContextImpl) getClusterLatencies() (originLatency time.Duration, targetLatency time.Duration) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	return recv.originLatency, recv.targetLatency
+}
+
 type asyncRequestContextImpl struct {
 	state            int
 	timer            *time.Timer
@@ -254,6 +404,12 @@ type asyncRequestContextImpl struct {
 	expectedResponse bool
 	startTime        time.Time
 	requestInfo      RequestInfo
+
+	// write is set when the request is (or re-prepares the statement of) an asynchronous dual write
+	write     *asyncWrite
+	reprepare bool
+
+	readComparison *readComparison // nil if the response is not compared with the primary response
 }
 
 func NewAsyncRequestContext(requestInfo RequestInfo, streamId int16, expectedResponse bool, startTime time.Time) *asyncRequestContextImpl {
@@ -0,0 +1,78 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testGauge struct {
+	value int64
+}
+
+func (recv *testGauge) Add(valueToAdd int) {
+	atomic.AddInt64(&recv.value, int64(valueToAdd))
+}
+
+func (recv *testGauge) Subtract(valueToSubtract int) {
+	atomic.AddInt64(&recv.value, -int64(valueToSubtract))
+}
+
+func newTestNodeMetricsInstance() *metrics.NodeMetricsInstance {
+	return &metrics.NodeMetricsInstance{
+		ClientTimeouts:   newFakeCounter(),
+		RequestDuration:  newFakeHistogram(),
+		InFlightRequests: &testGauge{},
+	}
+}
+
+func TestRequestContext_InFlightRequests(t *testing.T) {
+	nodeMetrics := &metrics.NodeMetrics{
+		OriginMetrics: newTestNodeMetricsInstance(),
+		TargetMetrics: newTestNodeMetricsInstance(),
+	}
+	originInFlight := nodeMetrics.OriginMetrics.InFlightRequests.(*testGauge)
+	targetInFlight := nodeMetrics.TargetMetrics.InFlightRequests.(*testGauge)
+	request, err := defaultCodec.ConvertToRawFrame(
+		frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"}))
+	require.Nil(t, err)
+	response, err := defaultCodec.ConvertToRawFrame(
+		frame.NewFrame(primitive.ProtocolVersion4, 1, &message.VoidResult{}))
+	require.Nil(t, err)
+
+	// the client handler increments the gauges when the request is forwarded
+	newWrite := func() *requestContextImpl {
+		nodeMetrics.OriginMetrics.InFlightRequests.Add(1)
+		nodeMetrics.TargetMetrics.InFlightRequests.Add(1)
+		return NewRequestContext(request, NewGenericRequestInfo(forwardToBoth, false, true), time.Now(), nil)
+	}
+
+	reqCtx := newWrite()
+	require.False(t, reqCtx.SetResponse(nodeMetrics, response, common.ClusterTypeOrigin, ClusterConnectorTypeOrigin))
+	require.Equal(t, int64(0), originInFlight.value)
+	require.Equal(t, int64(1), targetInFlight.value)
+	require.True(t, reqCtx.SetResponse(nodeMetrics, response, common.ClusterTypeTarget, ClusterConnectorTypeTarget))
+	require.Equal(t, int64(0), targetInFlight.value)
+
+	reqCtx = newWrite()
+	reqCtx.SetResponse(nodeMetrics, response, common.ClusterTypeTarget, ClusterConnectorTypeTarget)
+	require.True(t, reqCtx.SetTimeout(nodeMetrics, request))
+	require.Equal(t, int64(0), originInFlight.value)
+	require.Equal(t, int64(0), targetInFlight.value)
+	// late responses are ignored
+	require.False(t, reqCtx.SetResponse(nodeMetrics, response, common.ClusterTypeOrigin, ClusterConnectorTypeOrigin))
+	require.Equal(t, int64(0), originInFlight.value)
+
+	reqCtx = newWrite()
+	require.True(t, reqCtx.Cancel(nodeMetrics))
+	require.Equal(t, int64(0), originInFlight.value)
+	require.Equal(t, int64(0), targetInFlight.value)
+	require.False(t, reqCtx.Cancel(nodeMetrics))
+	require.Equal(t, int64(0), originInFlight.value)
+}
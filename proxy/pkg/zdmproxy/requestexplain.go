@@ -0,0 +1,490 @@
+package zdmproxy
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"strings"
+	"time"
+)
+
+// requestExplanation collects the decisions that the proxy makes while handling a request so that they can be logged
+// in a single line when the request is done. Used to troubleshoot routing decisions (see ZDM_EXPLAIN_REQUESTS).
+//
+// The audit log (ZDM_AUDIT_LOG_DESTINATION) records the requests with the same decisions and outcomes, so the
+// requests are tracked if they are explained, audited or both.
+//
+// A nil *requestExplanation means that the request is neither explained nor audited, all methods are no-ops in that
+// case.
+type requestExplanation struct {
+	logger        *log.Entry // has the fields of the client identity, nil if the request is not explained
+	level         log.Level
+	auditLog      *auditLog // nil if the request is not audited
+	clientAddress string
+	user          string
+	identity      *clientIdentity // nil if the STARTUP request was not received yet
+	startTime     time.Time
+
+	// used by the audit log to decode the statements of the request when it is done
+	request            *frame.RawFrame
+	requestInfo        RequestInfo
+	connectionKeyspace string
+
+	opCode        primitive.OpCode
+	streamId      int16
+	statementType string
+	keyspace      string
+	table         string
+	rule          string
+	preparedCache string
+	rewrites      []string
+	destinations  string
+	outcome       string
+
+	response     *frame.RawFrame // response sent to the client, nil if the request failed or was canceled
+	responseFrom string
+	failed       bool
+	canceled     bool
+}
+
+// getRequestExplainLevel returns the log level at which the requests of a client connection should be explained
+// and false if they should not be explained at all.
+func getRequestExplainLevel(conf *config.Config, clientAddress net.Addr) (log.Level, bool) {
+	clientAddresses, err := conf.ParseExplainRequestsClientAddresses()
+	if err != nil {
+		log.Warnf("Could not parse client addresses of requests to explain: %v", err)
+	}
+
+	if tcpAddr, ok := clientAddress.(*net.TCPAddr); ok {
+		for _, addr := range clientAddresses {
+			if addr.Equal(tcpAddr.IP) {
+				return log.InfoLevel, true
+			}
+		}
+	}
+
+	if conf.ExplainRequests {
+		return log.DebugLevel, true
+	}
+
+	return log.DebugLevel, false
+}
+
+func (ch *ClientHandler) newRequestExplanation(request *frame.RawFrame, currentKeyspace string) *requestExplanation {
+	explain := ch.explainRequests && log.IsLevelEnabled(ch.explainLevel)
+	if !explain && ch.auditLog == nil {
+		return nil
+	}
+	explanation := &requestExplanation{
+		level:         ch.explainLevel,
+		auditLog:      ch.auditLog,
+		clientAddress: ch.clientConnector.connection.RemoteAddr().String(),
+		user:          ch.clientRole,
+		identity:      ch.clientIdentity,
+		startTime:     time.Now(),
+		opCode:        request.Header.OpCode,
+		streamId:      request.Header.StreamId,
+	}
+	if explain {
+		explanation.logger = ch.getLogger()
+	}
+	if ch.auditLog != nil {
+		explanation.request = request
+		explanation.connectionKeyspace = currentKeyspace
+	}
+	return explanation
+}
+
+func (recv *requestExplanation) addRewrites(stmtsReplacedTerms []*statementReplacedTerms) {
+	if recv == nil {
+		return
+	}
+	for _, stmtReplacedTerms := range stmtsReplacedTerms {
+		functionNames := make([]string, 0, len(stmtReplacedTerms.replacedTerms))
+		for _, replacedTerm := range stmtReplacedTerms.replacedTerms {
+			if replacedTerm.functionCall != nil {
+				functionNames = append(functionNames, replacedTerm.functionCall.name+"()")
+			}
+		}
+		if len(functionNames) > 0 {
+			recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+				"statement %d: replaced %v", stmtReplacedTerms.statementIndex, strings.Join(functionNames, ", ")))
+		}
+	}
+}
+
+func (recv *requestExplanation) addTargetWriteFilter(filtered []int, total int) {
+	if recv == nil {
+		return
+	}
+	if len(filtered) == total {
+		recv.rewrites = append(recv.rewrites, "not forwarded to TARGET (ZDM_TARGET_WRITE_FILTER_RULES)")
+		recv.destinations = string(forwardToOrigin)
+	} else {
+		recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+			"removed child statements %v from the TARGET BATCH (ZDM_TARGET_WRITE_FILTER_RULES)", filtered))
+	}
+}
+
+func (recv *requestExplanation) addTargetWriteNotSampled() {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, "not forwarded to TARGET (ZDM_TARGET_WRITE_SAMPLING_PERCENTAGE)")
+	recv.destinations = string(forwardToOrigin)
+}
+
+func (recv *requestExplanation) addLwtPolicy(lwtPolicy common.LwtPolicy, primaryCluster common.ClusterType) {
+	if recv == nil {
+		return
+	}
+	if lwtPolicy == common.LwtPolicyPrimaryOnly {
+		recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+			"lightweight transaction forwarded to %v only (ZDM_LWT_POLICY)", primaryCluster))
+		if primaryCluster == common.ClusterTypeTarget {
+			recv.destinations = string(forwardToTarget)
+		} else {
+			recv.destinations = string(forwardToOrigin)
+		}
+	} else {
+		recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+			"lightweight transaction, %v response returned (ZDM_LWT_POLICY)", primaryCluster))
+	}
+}
+
+func (recv *requestExplanation) addRequestRetry(cluster common.ClusterType, retry int) {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+		"retry %v on %v (ZDM_%v_REQUEST_MAX_RETRIES)", retry, cluster, cluster))
+}
+
+func (recv *requestExplanation) addCounterWritesPolicy(
+	counterWritesPolicy common.CounterWritesPolicy, primaryCluster common.ClusterType) {
+	if recv == nil {
+		return
+	}
+	if counterWritesPolicy == common.CounterWritesPolicyReject {
+		recv.rewrites = append(recv.rewrites, "counter update rejected (ZDM_COUNTER_WRITES_POLICY)")
+		recv.destinations = string(forwardToNone)
+		return
+	}
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+		"counter update forwarded to %v only (ZDM_COUNTER_WRITES_POLICY)", primaryCluster))
+	if primaryCluster == common.ClusterTypeTarget {
+		recv.destinations = string(forwardToTarget)
+	} else {
+		recv.destinations = string(forwardToOrigin)
+	}
+}
+
+func (recv *requestExplanation) addCircuitBreaker(
+	openCluster common.ClusterType, degraded bool, primaryCluster common.ClusterType) {
+	if recv == nil {
+		return
+	}
+	if !degraded {
+		recv.rewrites = append(recv.rewrites, fmt.Sprintf("rejected, the circuit breaker of %v is open", openCluster))
+		recv.destinations = string(forwardToNone)
+		return
+	}
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+		"write forwarded to %v only, the circuit breaker of %v is open (ZDM_CIRCUIT_BREAKER_DEGRADE_TO_PRIMARY_ONLY)",
+		primaryCluster, openCluster))
+	if primaryCluster == common.ClusterTypeTarget {
+		recv.destinations = string(forwardToTarget)
+	} else {
+		recv.destinations = string(forwardToOrigin)
+	}
+}
+
+func (recv *requestExplanation) addConsistencyOverride(
+	clusterType common.ClusterType, consistency primitive.ConsistencyLevel, newConsistency primitive.ConsistencyLevel) {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf("consistency %v replaced with %v on %v (ZDM_%v_CONSISTENCY_OVERRIDE)",
+		consistencyLevelName(consistency), consistencyLevelName(newConsistency), clusterType, clusterType))
+}
+
+// consistencyLevelName returns the name of the consistency level without the "ConsistencyLevel" prefix and the
+// code of primitive.ConsistencyLevel.String, e.g. LOCAL_ONE.
+func consistencyLevelName(consistency primitive.ConsistencyLevel) string {
+	fields := strings.Fields(consistency.String())
+	if len(fields) != 3 {
+		return consistency.String()
+	}
+	return fields[1]
+}
+
+func (recv *requestExplanation) addAsyncDualWrite(primaryCluster common.ClusterType, asyncCluster common.ClusterType) {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf("forwarded to %v in the background (ZDM_DUAL_WRITE_MODE)", asyncCluster))
+	if primaryCluster == common.ClusterTypeTarget {
+		recv.destinations = string(forwardToTarget) + "+async"
+	} else {
+		recv.destinations = string(forwardToOrigin) + "+async"
+	}
+}
+
+func (recv *requestExplanation) addTargetDdlRejection() {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, "schema change rejected (ZDM_TARGET_DDL_COMPATIBILITY_MODE)")
+	recv.destinations = string(forwardToNone)
+}
+
+func (recv *requestExplanation) addDestructiveStatementRejection() {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, "destructive statement rejected (ZDM_DESTRUCTIVE_STATEMENTS_CONFIRMATION_ENABLED)")
+	recv.destinations = string(forwardToNone)
+}
+
+func (recv *requestExplanation) addApplicationReadRouting(decision forwardDecision) {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+		"read forwarded to %v (ZDM_APPLICATION_READ_ROUTING_RULES)", strings.ToUpper(string(decision))))
+	recv.destinations = string(decision)
+}
+
+func (recv *requestExplanation) addAdminReadRouting(decision forwardDecision) {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+		"read forwarded to %v (%v.%v %v)", strings.ToUpper(string(decision)),
+		adminKeyspaceName, adminSettingsTableName, readRoutingSettingName))
+	recv.destinations = string(decision)
+}
+
+func (recv *requestExplanation) addPagingStatePolicy(
+	pagingStatePolicy common.PagingStatePolicy, pagingStateCluster common.ClusterType) {
+	if recv == nil {
+		return
+	}
+	if pagingStatePolicy == common.PagingStatePolicyReject {
+		recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+			"read with a paging state of %v rejected (ZDM_PAGING_STATE_POLICY)", pagingStateCluster))
+		recv.destinations = string(forwardToNone)
+		return
+	}
+	decision := getReadRoutingForwardDecision(pagingStateCluster)
+	recv.rewrites = append(recv.rewrites, fmt.Sprintf(
+		"read forwarded to %v because of its paging state (ZDM_PAGING_STATE_POLICY)", strings.ToUpper(string(decision))))
+	recv.destinations = string(decision)
+}
+
+func (recv *requestExplanation) addRequestInterceptorRejection() {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, "rejected by a request interceptor")
+	recv.destinations = string(forwardToNone)
+}
+
+func (recv *requestExplanation) addRequestInterceptorModification() {
+	if recv == nil {
+		return
+	}
+	recv.rewrites = append(recv.rewrites, "modified by a request interceptor")
+}
+
+func (recv *requestExplanation) addRequestInterceptorRoute(route RequestRoute) {
+	if recv == nil {
+		return
+	}
+	recv.rule = fmt.Sprintf("route %v requested by a request interceptor", route)
+}
+
+// describe records the statement details and the routing decision of the request.
+func (recv *requestExplanation) describe(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, sendAlsoToAsync bool) {
+	if recv == nil {
+		return
+	}
+
+	var queryInfo QueryInfo
+	if len(frameContext.statementsQueryData) == 1 {
+		queryInfo = frameContext.statementsQueryData[0].queryData
+		recv.statementType = string(queryInfo.getStatementType())
+		recv.keyspace = queryInfo.getApplicableKeyspace()
+		recv.table = queryInfo.getTableName()
+	}
+
+	recv.rule = explainRoutingRule(recv.opCode, requestInfo, queryInfo)
+
+	switch typedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		preparedData := typedRequestInfo.GetPreparedData()
+		recv.preparedCache = fmt.Sprintf("hit (prepared id %s)", hex.EncodeToString(preparedData.GetOriginPreparedId()))
+		if len(preparedData.GetPrepareRequestInfo().GetReplacedTerms()) > 0 {
+			recv.rewrites = append(recv.rewrites, "added bind values for the function calls replaced at PREPARE time")
+		}
+	case *BatchRequestInfo:
+		if prepared := len(typedRequestInfo.GetPreparedDataByStmtIdx()); prepared > 0 {
+			recv.preparedCache = fmt.Sprintf("hit for %d child statement(s)", prepared)
+		}
+	case *PrepareRequestInfo:
+		recv.preparedCache = "stored when PREPARED responses are received"
+	}
+
+	recv.requestInfo = requestInfo
+	recv.destinations = string(requestInfo.GetForwardDecision())
+	if sendAlsoToAsync {
+		recv.destinations += "+async"
+	}
+}
+
+func explainRoutingRule(opCode primitive.OpCode, requestInfo RequestInfo, queryInfo QueryInfo) string {
+	switch typedRequestInfo := requestInfo.(type) {
+	case *InterceptedRequestInfo:
+		return fmt.Sprintf("system table virtualization (%v)", typedRequestInfo.GetQueryType())
+	case *RejectedRequestInfo:
+		return fmt.Sprintf("rejected by the proxy (%v)", typedRequestInfo.GetErrorMessage())
+	case *AdminRequestInfo:
+		return fmt.Sprintf("%v statement executed by the proxy (ZDM_ADMIN_KEYSPACE_ROLES)", adminKeyspaceName)
+	case *TableRoutingRequestInfo:
+		return fmt.Sprintf("table routing rule for %v.%v (ZDM_TABLE_ROUTING_RULES)",
+			typedRequestInfo.GetRule().Keyspace, typedRequestInfo.GetRule().Table)
+	case *StatementFilterRequestInfo:
+		return fmt.Sprintf("statement filter rule %v (ZDM_STATEMENT_FILTER_RULES)", typedRequestInfo.GetRule().Definition)
+	case *SchemaStatementPolicyRequestInfo:
+		return fmt.Sprintf("schema statement policy (%v is %v)",
+			typedRequestInfo.GetEnvVarName(), common.SchemaStatementPolicyOriginOnly)
+	case *PrepareRequestInfo:
+		return "PREPARE of " + explainRoutingRule(opCode, typedRequestInfo.GetBaseRequestInfo(), queryInfo)
+	case *ExecuteRequestInfo:
+		return "prepared statement (routing decided when it was prepared)"
+	case *BatchRequestInfo:
+		if typedRequestInfo.GetForwardDecision() == forwardToBoth {
+			return "BATCH is a write (forwarded to both clusters)"
+		}
+		return fmt.Sprintf("BATCH without writes (%v)", explainForwardDecision(typedRequestInfo.GetForwardDecision()))
+	}
+
+	if queryInfo == nil {
+		return fmt.Sprintf("%v request forwarded to %v", opCode, requestInfo.GetForwardDecision())
+	}
+
+	switch queryInfo.getStatementType() {
+	case statementTypeSelect:
+		if isSystemQuery(queryInfo) {
+			return "system query (ZDM_SYSTEM_QUERIES_MODE)"
+		}
+		return "read (ZDM_PRIMARY_CLUSTER, ZDM_READ_MODE)"
+	case statementTypeUse:
+		return "USE statement (forwarded to all clusters)"
+	case statementTypeInsert, statementTypeUpdate, statementTypeDelete, statementTypeBatch:
+		return fmt.Sprintf("write (%v)", explainForwardDecision(requestInfo.GetForwardDecision()))
+	default:
+		return fmt.Sprintf("schema or other statement (%v)", explainForwardDecision(requestInfo.GetForwardDecision()))
+	}
+}
+
+func explainForwardDecision(decision forwardDecision) string {
+	if decision == forwardToBoth {
+		return "forwarded to both clusters"
+	}
+	return fmt.Sprintf("forwarded to %v only", decision)
+}
+
+func (recv *requestExplanation) setResponseOutcome(response *frame.RawFrame, clusterType common.ClusterType) {
+	if recv == nil || response == nil {
+		return
+	}
+
+	from := "proxy"
+	if clusterType != common.ClusterTypeNone {
+		from = string(clusterType)
+	}
+	recv.response = response
+	recv.responseFrom = from
+
+	errMsg, err := decodeError(response)
+	if err == nil && errMsg != nil {
+		recv.outcome = fmt.Sprintf("%v from %v (%v)", errMsg.GetErrorCode(), from, errMsg.GetErrorMessage())
+	} else {
+		recv.outcome = fmt.Sprintf("%v from %v", response.Header.OpCode, from)
+	}
+}
+
+func (recv *requestExplanation) setErrorOutcome(err error) {
+	if recv == nil {
+		return
+	}
+	recv.failed = true
+	recv.outcome = fmt.Sprintf("failed (%v)", err)
+}
+
+func (recv *requestExplanation) setCanceledOutcome() {
+	if recv == nil {
+		return
+	}
+	recv.canceled = true
+	recv.outcome = "canceled"
+}
+
+// auditOutcome is the outcome of the audit record, the errors of the proxy and of the clusters are not included
+// because their messages can contain the literals of the statements.
+func (recv *requestExplanation) auditOutcome() string {
+	switch {
+	case recv.failed:
+		return "failed"
+	case recv.canceled:
+		return "canceled"
+	case recv.response != nil && recv.response.Header.OpCode == primitive.OpCodeError:
+		return "error"
+	case recv.response != nil:
+		return "success"
+	default:
+		return "unknown"
+	}
+}
+
+// log logs the explanation and records the request in the audit log.
+func (recv *requestExplanation) log() {
+	if recv == nil {
+		return
+	}
+	if recv.auditLog != nil {
+		recv.auditLog.record(recv)
+	}
+	if recv.logger == nil {
+		return
+	}
+
+	fields := log.Fields{
+		"client":      recv.clientAddress,
+		"opcode":      recv.opCode.String(),
+		"stream":      recv.streamId,
+		"rule":        recv.rule,
+		"destination": recv.destinations,
+		"outcome":     recv.outcome,
+		"duration":    time.Since(recv.startTime).String(),
+	}
+	if recv.statementType != "" {
+		fields["statement"] = recv.statementType
+		fields["keyspace"] = recv.keyspace
+		fields["table"] = recv.table
+	}
+	if recv.preparedCache != "" {
+		fields["prepared_cache"] = recv.preparedCache
+	}
+	if len(recv.rewrites) > 0 {
+		fields["rewrites"] = strings.Join(recv.rewrites, "; ")
+	}
+	recv.logger.WithFields(fields).Log(recv.level, "Request explanation")
+}
@@ -0,0 +1,160 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestExplainRoutingRule(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	prepared := &preparedDataImpl{
+		originPreparedId:   []byte("ORIGIN"),
+		targetPreparedId:   []byte("TARGET"),
+		prepareRequestInfo: NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, false), nil, false, "", ""),
+	}
+
+	tests := []struct {
+		name        string
+		opCode      primitive.OpCode
+		requestInfo RequestInfo
+		queryInfo   QueryInfo
+		expected    string
+	}{
+		{
+			name:        "read",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewGenericRequestInfo(forwardToOrigin, true, true),
+			queryInfo:   inspectCqlQuery("SELECT * FROM ks.tb", "", generator),
+			expected:    "read (ZDM_PRIMARY_CLUSTER, ZDM_READ_MODE)",
+		},
+		{
+			name:        "system query",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewGenericRequestInfo(forwardToOrigin, false, true),
+			queryInfo:   inspectCqlQuery("SELECT * FROM system_schema.tables", "", generator),
+			expected:    "system query (ZDM_SYSTEM_QUERIES_MODE)",
+		},
+		{
+			name:        "write",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewGenericRequestInfo(forwardToBoth, false, true),
+			queryInfo:   inspectCqlQuery("INSERT INTO ks.tb (a) VALUES (1)", "", generator),
+			expected:    "write (forwarded to both clusters)",
+		},
+		{
+			name:        "prepare of read",
+			opCode:      primitive.OpCodePrepare,
+			requestInfo: NewPrepareRequestInfo(NewGenericRequestInfo(forwardToTarget, true, true), nil, false, "", ""),
+			queryInfo:   inspectCqlQuery("SELECT * FROM tb", "ks", generator),
+			expected:    "PREPARE of read (ZDM_PRIMARY_CLUSTER, ZDM_READ_MODE)",
+		},
+		{
+			name:        "intercepted",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewInterceptedRequestInfo(peersV1, newStarSelectClause()),
+			queryInfo:   inspectCqlQuery("SELECT * FROM system.peers", "", generator),
+			expected:    "system table virtualization (peersV1)",
+		},
+		{
+			name:        "write forwarded to a single cluster",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewGenericRequestInfo(forwardToOrigin, false, true),
+			queryInfo:   inspectCqlQuery("INSERT INTO ks.tb (a) VALUES (1)", "", generator),
+			expected:    "write (forwarded to origin only)",
+		},
+		{
+			name:        "schema statement",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewGenericRequestInfo(forwardToBoth, false, true),
+			queryInfo:   inspectCqlQuery("CREATE TABLE ks.tb (a int PRIMARY KEY)", "", generator),
+			expected:    "schema or other statement (forwarded to both clusters)",
+		},
+		{
+			name:        "schema statement forwarded to a single cluster",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewGenericRequestInfo(forwardToTarget, false, true),
+			queryInfo:   inspectCqlQuery("CREATE INDEX idx ON ks.tb (b)", "", generator),
+			expected:    "schema or other statement (forwarded to target only)",
+		},
+		{
+			name:        "schema statement policy origin only",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewSchemaStatementPolicyRequestInfo("ZDM_INDEX_STATEMENTS_POLICY"),
+			queryInfo:   inspectCqlQuery("CREATE INDEX idx ON ks.tb (b)", "", generator),
+			expected:    "schema statement policy (ZDM_INDEX_STATEMENTS_POLICY is ORIGIN_ONLY)",
+		},
+		{
+			name:        "schema statement policy reject",
+			opCode:      primitive.OpCodeQuery,
+			requestInfo: NewRejectedRequestInfo("Index statements are rejected"),
+			queryInfo:   inspectCqlQuery("CREATE INDEX idx ON ks.tb (b)", "", generator),
+			expected:    "rejected by the proxy (Index statements are rejected)",
+		},
+		{
+			name:   "table routing rule",
+			opCode: primitive.OpCodeQuery,
+			requestInfo: NewTableRoutingRequestInfo(
+				&common.TableRoutingRule{Keyspace: "ks", Table: "legacy", Cluster: common.ClusterTypeOrigin}, false),
+			queryInfo: inspectCqlQuery("INSERT INTO ks.legacy (a) VALUES (1)", "", generator),
+			expected:  "table routing rule for ks.legacy (ZDM_TABLE_ROUTING_RULES)",
+		},
+		{
+			name:        "execute",
+			opCode:      primitive.OpCodeExecute,
+			requestInfo: NewExecuteRequestInfo(prepared),
+			expected:    "prepared statement (routing decided when it was prepared)",
+		},
+		{
+			name:        "batch",
+			opCode:      primitive.OpCodeBatch,
+			requestInfo: NewBatchRequestInfo(map[int]PreparedData{0: prepared}, forwardToBoth),
+			expected:    "BATCH is a write (forwarded to both clusters)",
+		},
+		{
+			name:        "batch without writes",
+			opCode:      primitive.OpCodeBatch,
+			requestInfo: NewBatchRequestInfo(map[int]PreparedData{0: prepared}, forwardToOrigin),
+			expected:    "BATCH without writes (forwarded to origin only)",
+		},
+		{
+			name:        "register",
+			opCode:      primitive.OpCodeRegister,
+			requestInfo: NewGenericRequestInfo(forwardToBoth, false, false),
+			expected:    "OpCode REGISTER [0x0B] request forwarded to both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, explainRoutingRule(tt.opCode, tt.requestInfo, tt.queryInfo))
+		})
+	}
+}
+
+func TestGetRequestExplainLevel(t *testing.T) {
+	clientAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+
+	conf := config.New()
+	_, enabled := getRequestExplainLevel(conf, clientAddr)
+	require.False(t, enabled)
+
+	conf.ExplainRequests = true
+	level, enabled := getRequestExplainLevel(conf, clientAddr)
+	require.True(t, enabled)
+	require.Equal(t, log.DebugLevel, level)
+
+	conf.ExplainRequests = false
+	conf.ExplainRequestsClientAddresses = "10.0.0.2, 10.0.0.1"
+	level, enabled = getRequestExplainLevel(conf, clientAddr)
+	require.True(t, enabled)
+	require.Equal(t, log.InfoLevel, level)
+
+	_, enabled = getRequestExplainLevel(conf, &net.TCPAddr{IP: net.ParseIP("10.0.0.3"), Port: 50000})
+	require.False(t, enabled)
+}
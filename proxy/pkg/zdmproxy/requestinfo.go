@@ -1,6 +1,9 @@
 package zdmproxy
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
 
 type RequestInfo interface {
 	GetForwardDecision() forwardDecision
@@ -43,12 +46,90 @@ func (recv *GenericRequestInfo) String() string {
 		recv.forwardDecision, recv.shouldAlsoBeSentAsync, recv.trackMetrics)
 }
 
+// TableRoutingRequestInfo is a statement on a table that is pinned to a cluster by a table routing rule
+// (see ZDM_TABLE_ROUTING_RULES). It is never sent to the async connector and it is not routed by the read
+// routing rules.
+type TableRoutingRequestInfo struct {
+	*baseRequestInfo
+	rule *common.TableRoutingRule
+}
+
+func NewTableRoutingRequestInfo(rule *common.TableRoutingRule, trackMetrics bool) *TableRoutingRequestInfo {
+	decision := forwardToOrigin
+	if rule.Cluster == common.ClusterTypeTarget {
+		decision = forwardToTarget
+	}
+	return &TableRoutingRequestInfo{baseRequestInfo: newBaseRequestInfo(decision, false, trackMetrics), rule: rule}
+}
+
+func (recv *TableRoutingRequestInfo) String() string {
+	return fmt.Sprintf("TableRoutingRequestInfo{forwardDecision: %v, trackMetrics=%v, rule=%v}",
+		recv.forwardDecision, recv.trackMetrics, recv.rule)
+}
+
+func (recv *TableRoutingRequestInfo) GetRule() *common.TableRoutingRule {
+	return recv.rule
+}
+
+// StatementFilterRequestInfo is a statement that is forwarded to a single cluster by a statement filter rule
+// (see ZDM_STATEMENT_FILTER_RULES). Like TableRoutingRequestInfo, it is never sent to the async connector and it is
+// not routed by the read routing rules.
+type StatementFilterRequestInfo struct {
+	*baseRequestInfo
+	rule *common.StatementFilterRule
+}
+
+func NewStatementFilterRequestInfo(rule *common.StatementFilterRule, trackMetrics bool) *StatementFilterRequestInfo {
+	decision := forwardToOrigin
+	if rule.Action == common.StatementFilterActionTarget {
+		decision = forwardToTarget
+	}
+	return &StatementFilterRequestInfo{baseRequestInfo: newBaseRequestInfo(decision, false, trackMetrics), rule: rule}
+}
+
+func (recv *StatementFilterRequestInfo) String() string {
+	return fmt.Sprintf("StatementFilterRequestInfo{forwardDecision: %v, trackMetrics=%v, rule=%v}",
+		recv.forwardDecision, recv.trackMetrics, recv.rule)
+}
+
+func (recv *StatementFilterRequestInfo) GetRule() *common.StatementFilterRule {
+	return recv.rule
+}
+
+// SchemaStatementPolicyRequestInfo is a schema statement that is only forwarded to ORIGIN by a schema statement policy
+// (see ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY, ZDM_INDEX_STATEMENTS_POLICY and ZDM_CUSTOM_INDEX_STATEMENTS_POLICY).
+type SchemaStatementPolicyRequestInfo struct {
+	*baseRequestInfo
+	envVarName string
+}
+
+func NewSchemaStatementPolicyRequestInfo(envVarName string) *SchemaStatementPolicyRequestInfo {
+	return &SchemaStatementPolicyRequestInfo{
+		baseRequestInfo: newBaseRequestInfo(forwardToOrigin, false, true), envVarName: envVarName}
+}
+
+func (recv *SchemaStatementPolicyRequestInfo) String() string {
+	return fmt.Sprintf("SchemaStatementPolicyRequestInfo{forwardDecision: %v, trackMetrics=%v, envVarName=%v}",
+		recv.forwardDecision, recv.trackMetrics, recv.envVarName)
+}
+
+// GetEnvVarName returns the setting of the policy that forwarded the statement to ORIGIN.
+func (recv *SchemaStatementPolicyRequestInfo) GetEnvVarName() string {
+	return recv.envVarName
+}
+
 type PrepareRequestInfo struct {
 	baseRequestInfo           RequestInfo
 	replacedTerms             []*term
 	containsPositionalMarkers bool
 	query                     string
 	keyspace                  string
+
+	// only set if target write filter rules, target write sampling or application read routing rules are configured
+	queryInfo QueryInfo
+
+	// set for the statements that are forwarded, unlike queryInfo it is always set because it is cheap to keep
+	statementType statementType
 }
 
 func NewPrepareRequestInfo(
@@ -93,6 +174,16 @@ func (recv *PrepareRequestInfo) GetForwardDecision() forwardDecision {
 	return forwardToBoth // always send PREPARE to both, use origin's ID
 }
 
+// GetQueryInfo returns the inspected query of the PREPARE request, it is nil if there are no target write filter rules,
+// target write sampling or application read routing rules.
+func (recv *PrepareRequestInfo) GetQueryInfo() QueryInfo {
+	return recv.queryInfo
+}
+
+func (recv *PrepareRequestInfo) GetStatementType() statementType {
+	return recv.statementType
+}
+
 func (recv *PrepareRequestInfo) GetBaseRequestInfo() RequestInfo {
 	return recv.baseRequestInfo
 }
@@ -107,17 +198,31 @@ func (recv *PrepareRequestInfo) ContainsPositionalMarkers() bool {
 
 type ExecuteRequestInfo struct {
 	preparedData PreparedData
+
+	// overrides the forward decision of the prepared statement if the read is routed by an application
+	// read routing rule (see ZDM_APPLICATION_READ_ROUTING_RULES), empty otherwise
+	routedReadDecision forwardDecision
 }
 
 func NewExecuteRequestInfo(preparedData PreparedData) *ExecuteRequestInfo {
 	return &ExecuteRequestInfo{preparedData: preparedData}
 }
 
+// NewRoutedReadExecuteRequestInfo returns an EXECUTE of a prepared read that is forwarded to the cluster of
+// an application read routing rule instead of the cluster that was decided when the statement was prepared.
+func NewRoutedReadExecuteRequestInfo(preparedData PreparedData, decision forwardDecision) *ExecuteRequestInfo {
+	return &ExecuteRequestInfo{preparedData: preparedData, routedReadDecision: decision}
+}
+
 func (recv *ExecuteRequestInfo) String() string {
-	return fmt.Sprintf("ExecuteRequestInfo{PreparedData: %v}", recv.preparedData)
+	return fmt.Sprintf("ExecuteRequestInfo{PreparedData: %v, RoutedReadDecision: %v}",
+		recv.preparedData, recv.routedReadDecision)
 }
 
 func (recv *ExecuteRequestInfo) GetForwardDecision() forwardDecision {
+	if recv.routedReadDecision != "" {
+		return recv.routedReadDecision
+	}
 	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().GetForwardDecision()
 }
 
@@ -126,6 +231,9 @@ func (recv *ExecuteRequestInfo) GetPreparedData() PreparedData {
 }
 
 func (recv *ExecuteRequestInfo) ShouldAlsoBeSentAsync() bool {
+	if recv.routedReadDecision != "" {
+		return false
+	}
 	return recv.preparedData.GetPrepareRequestInfo().GetBaseRequestInfo().ShouldAlsoBeSentAsync()
 }
 
@@ -163,20 +271,68 @@ func (recv *InterceptedRequestInfo) GetParsedSelectClause() *selectClause {
 	return recv.parsedSelectClause
 }
 
+// RejectedRequestInfo means that the proxy returns an error to the client instead of forwarding the request
+// (see ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY, ZDM_INDEX_STATEMENTS_POLICY and ZDM_CUSTOM_INDEX_STATEMENTS_POLICY).
+type RejectedRequestInfo struct {
+	*baseRequestInfo
+	errorMessage string
+}
+
+func NewRejectedRequestInfo(errorMessage string) *RejectedRequestInfo {
+	return &RejectedRequestInfo{
+		baseRequestInfo: newBaseRequestInfo(forwardToNone, false, false),
+		errorMessage:    errorMessage,
+	}
+}
+
+func (recv *RejectedRequestInfo) String() string {
+	return fmt.Sprintf("RejectedRequestInfo{errorMessage: %v}", recv.errorMessage)
+}
+
+func (recv *RejectedRequestInfo) GetErrorMessage() string {
+	return recv.errorMessage
+}
+
+// AdminRequestInfo means that the request is a statement on the zdm_admin keyspace which is executed by the proxy
+// instead of being forwarded (see ZDM_ADMIN_KEYSPACE_ROLES).
+type AdminRequestInfo struct {
+	*baseRequestInfo
+	queryInfo QueryInfo
+}
+
+func NewAdminRequestInfo(queryInfo QueryInfo) *AdminRequestInfo {
+	return &AdminRequestInfo{
+		baseRequestInfo: newBaseRequestInfo(forwardToNone, false, false),
+		queryInfo:       queryInfo,
+	}
+}
+
+func (recv *AdminRequestInfo) String() string {
+	return fmt.Sprintf("AdminRequestInfo{query: %v}", recv.queryInfo.getQuery())
+}
+
+func (recv *AdminRequestInfo) GetQueryInfo() QueryInfo {
+	return recv.queryInfo
+}
+
+// BatchRequestInfo is a BATCH request, its forward decision is computed from the forward decisions of its child
+// statements (see getBatchForwardDecision).
 type BatchRequestInfo struct {
 	preparedDataByStmtIdx map[int]PreparedData
+	forwardDecision       forwardDecision
 }
 
-func NewBatchRequestInfo(preparedDataByStmtIdx map[int]PreparedData) *BatchRequestInfo {
-	return &BatchRequestInfo{preparedDataByStmtIdx: preparedDataByStmtIdx}
+func NewBatchRequestInfo(preparedDataByStmtIdx map[int]PreparedData, decision forwardDecision) *BatchRequestInfo {
+	return &BatchRequestInfo{preparedDataByStmtIdx: preparedDataByStmtIdx, forwardDecision: decision}
 }
 
 func (recv *BatchRequestInfo) String() string {
-	return fmt.Sprintf("BatchRequestInfo{PreparedDataByStmtIdx: %v}", recv.preparedDataByStmtIdx)
+	return fmt.Sprintf("BatchRequestInfo{PreparedDataByStmtIdx: %v, ForwardDecision: %v}",
+		recv.preparedDataByStmtIdx, recv.forwardDecision)
 }
 
 func (recv *BatchRequestInfo) GetForwardDecision() forwardDecision {
-	return forwardToBoth // always send BATCH to both, use origin's prepared IDs
+	return recv.forwardDecision
 }
 
 func (recv *BatchRequestInfo) ShouldAlsoBeSentAsync() bool {
@@ -190,3 +346,176 @@ func (recv *BatchRequestInfo) ShouldBeTrackedInMetrics() bool {
 func (recv *BatchRequestInfo) GetPreparedDataByStmtIdx() map[int]PreparedData {
 	return recv.preparedDataByStmtIdx
 }
+
+// FilteredWriteRequestInfo is a write that is only forwarded to ORIGIN because it matches a target write filter rule
+// (see ZDM_TARGET_WRITE_FILTER_RULES) or because it was not sampled (see ZDM_TARGET_WRITE_SAMPLING_PERCENTAGE).
+type FilteredWriteRequestInfo struct {
+	requestInfo RequestInfo
+}
+
+func NewFilteredWriteRequestInfo(requestInfo RequestInfo) *FilteredWriteRequestInfo {
+	return &FilteredWriteRequestInfo{requestInfo: requestInfo}
+}
+
+func (recv *FilteredWriteRequestInfo) String() string {
+	return fmt.Sprintf("FilteredWriteRequestInfo{RequestInfo: %v}", recv.requestInfo)
+}
+
+func (recv *FilteredWriteRequestInfo) GetForwardDecision() forwardDecision {
+	return forwardToOrigin
+}
+
+func (recv *FilteredWriteRequestInfo) ShouldAlsoBeSentAsync() bool {
+	return false
+}
+
+// ShouldBeTrackedInMetrics returns false because filtered writes would be tracked as reads, they are tracked by
+// the proxy_target_filtered_writes_total and proxy_target_unsampled_writes_total metrics instead.
+func (recv *FilteredWriteRequestInfo) ShouldBeTrackedInMetrics() bool {
+	return false
+}
+
+func (recv *FilteredWriteRequestInfo) GetRequestInfo() RequestInfo {
+	return recv.requestInfo
+}
+
+// AsyncWriteRequestInfo is a write that is only forwarded to the primary cluster before the client response is sent
+// because ZDM_DUAL_WRITE_MODE is ASYNC, the write is forwarded to the secondary cluster by the async connector.
+type AsyncWriteRequestInfo struct {
+	requestInfo    RequestInfo
+	primaryCluster common.ClusterType
+}
+
+func NewAsyncWriteRequestInfo(requestInfo RequestInfo, primaryCluster common.ClusterType) *AsyncWriteRequestInfo {
+	return &AsyncWriteRequestInfo{requestInfo: requestInfo, primaryCluster: primaryCluster}
+}
+
+func (recv *AsyncWriteRequestInfo) String() string {
+	return fmt.Sprintf("AsyncWriteRequestInfo{PrimaryCluster: %v, RequestInfo: %v}", recv.primaryCluster, recv.requestInfo)
+}
+
+func (recv *AsyncWriteRequestInfo) GetForwardDecision() forwardDecision {
+	if recv.primaryCluster == common.ClusterTypeTarget {
+		return forwardToTarget
+	}
+	return forwardToOrigin
+}
+
+// ShouldAlsoBeSentAsync returns false because the write is sent to the async connector by sendAsyncWrite instead of
+// being sent as a fire and forget request.
+func (recv *AsyncWriteRequestInfo) ShouldAlsoBeSentAsync() bool {
+	return false
+}
+
+func (recv *AsyncWriteRequestInfo) ShouldBeTrackedInMetrics() bool {
+	return recv.requestInfo.ShouldBeTrackedInMetrics()
+}
+
+func (recv *AsyncWriteRequestInfo) GetRequestInfo() RequestInfo {
+	return recv.requestInfo
+}
+
+// PrimaryOnlyWriteRequestInfo is a write that is only forwarded to the primary cluster, i.e. a lightweight transaction
+// with ZDM_LWT_POLICY PRIMARY_ONLY or a counter update with ZDM_COUNTER_WRITES_POLICY PRIMARY_ONLY.
+type PrimaryOnlyWriteRequestInfo struct {
+	requestInfo    RequestInfo
+	primaryCluster common.ClusterType
+}
+
+func NewPrimaryOnlyWriteRequestInfo(requestInfo RequestInfo, primaryCluster common.ClusterType) *PrimaryOnlyWriteRequestInfo {
+	return &PrimaryOnlyWriteRequestInfo{requestInfo: requestInfo, primaryCluster: primaryCluster}
+}
+
+func (recv *PrimaryOnlyWriteRequestInfo) String() string {
+	return fmt.Sprintf("PrimaryOnlyWriteRequestInfo{PrimaryCluster: %v, RequestInfo: %v}", recv.primaryCluster, recv.requestInfo)
+}
+
+func (recv *PrimaryOnlyWriteRequestInfo) GetForwardDecision() forwardDecision {
+	if recv.primaryCluster == common.ClusterTypeTarget {
+		return forwardToTarget
+	}
+	return forwardToOrigin
+}
+
+func (recv *PrimaryOnlyWriteRequestInfo) ShouldAlsoBeSentAsync() bool {
+	return false
+}
+
+func (recv *PrimaryOnlyWriteRequestInfo) ShouldBeTrackedInMetrics() bool {
+	return recv.requestInfo.ShouldBeTrackedInMetrics()
+}
+
+func (recv *PrimaryOnlyWriteRequestInfo) GetRequestInfo() RequestInfo {
+	return recv.requestInfo
+}
+
+// ContinuousPagingRequestInfo is a DSE continuous paging request, its pages can't be merged so it is only forwarded to
+// the cluster that the wrapped request info is routed to or to the primary cluster if it is routed to both.
+type ContinuousPagingRequestInfo struct {
+	requestInfo    RequestInfo
+	primaryCluster common.ClusterType
+}
+
+func NewContinuousPagingRequestInfo(requestInfo RequestInfo, primaryCluster common.ClusterType) *ContinuousPagingRequestInfo {
+	return &ContinuousPagingRequestInfo{requestInfo: requestInfo, primaryCluster: primaryCluster}
+}
+
+func (recv *ContinuousPagingRequestInfo) String() string {
+	return fmt.Sprintf("ContinuousPagingRequestInfo{PrimaryCluster: %v, RequestInfo: %v}", recv.primaryCluster, recv.requestInfo)
+}
+
+func (recv *ContinuousPagingRequestInfo) GetForwardDecision() forwardDecision {
+	switch decision := recv.requestInfo.GetForwardDecision(); decision {
+	case forwardToOrigin, forwardToTarget:
+		return decision
+	}
+	if recv.primaryCluster == common.ClusterTypeTarget {
+		return forwardToTarget
+	}
+	return forwardToOrigin
+}
+
+func (recv *ContinuousPagingRequestInfo) ShouldAlsoBeSentAsync() bool {
+	return false
+}
+
+func (recv *ContinuousPagingRequestInfo) ShouldBeTrackedInMetrics() bool {
+	return recv.requestInfo.ShouldBeTrackedInMetrics()
+}
+
+func (recv *ContinuousPagingRequestInfo) GetRequestInfo() RequestInfo {
+	return recv.requestInfo
+}
+
+// isPrimaryOnlyWrite returns true if the request is a write that is only forwarded to the primary cluster by the
+// client handler, i.e. an async write, a lightweight transaction with ZDM_LWT_POLICY PRIMARY_ONLY or a counter update
+// with ZDM_COUNTER_WRITES_POLICY PRIMARY_ONLY.
+func isPrimaryOnlyWrite(requestInfo RequestInfo) bool {
+	switch requestInfo.(type) {
+	case *AsyncWriteRequestInfo, *PrimaryOnlyWriteRequestInfo:
+		return true
+	}
+	return false
+}
+
+// unwrapRequestInfo returns the request info that is wrapped by the FilteredWriteRequestInfo, AsyncWriteRequestInfo,
+// PrimaryOnlyWriteRequestInfo and ContinuousPagingRequestInfo routing decisions of the client handler.
+func unwrapRequestInfo(requestInfo RequestInfo) RequestInfo {
+	for {
+		wrapper, ok := requestInfo.(interface{ GetRequestInfo() RequestInfo })
+		if !ok {
+			return requestInfo
+		}
+		requestInfo = wrapper.GetRequestInfo()
+	}
+}
+
+// getMetricsForwardDecision returns the forward decision that is used to track the proxy level metrics of a request,
+// async writes and primary only lightweight transactions or counter updates are only forwarded to the primary
+// cluster by the client handler but they are tracked as writes.
+func getMetricsForwardDecision(requestInfo RequestInfo) forwardDecision {
+	if isPrimaryOnlyWrite(requestInfo) {
+		return forwardToBoth
+	}
+	return requestInfo.GetForwardDecision()
+}
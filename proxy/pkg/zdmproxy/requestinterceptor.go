@@ -0,0 +1,174 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"net"
+)
+
+// RequestInterceptor is a hook of the programs that embed the proxy (see ZdmProxyOptions.RequestInterceptors) to
+// inspect, modify, route or reject the QUERY, PREPARE, EXECUTE and BATCH requests of the client connections, e.g.
+// to add custom routing rules, query rewrites or tenancy checks.
+//
+// The interceptors are called by the goroutines of the client connections so they must be safe for concurrent use,
+// and they should not block because the request waits for them.
+type RequestInterceptor interface {
+	// InterceptRequest is called before the proxy inspects the request to decide where it is forwarded and before it
+	// replaces the CQL function calls (ZDM_REPLACE_CQL_FUNCTIONS).
+	InterceptRequest(request *InterceptedRequest) RequestInterceptorResult
+}
+
+// RequestInterceptorFunc is a function that implements RequestInterceptor.
+type RequestInterceptorFunc func(request *InterceptedRequest) RequestInterceptorResult
+
+func (recv RequestInterceptorFunc) InterceptRequest(request *InterceptedRequest) RequestInterceptorResult {
+	return recv(request)
+}
+
+// InterceptedRequest is a request that is passed to the request interceptors.
+type InterceptedRequest struct {
+	ClientAddress net.Addr
+
+	// Username is the user that the client connection authenticated with, it is empty if the client didn't
+	// authenticate.
+	Username string
+
+	// Keyspace is the current keyspace of the client connection (USE), it is empty if none was set.
+	Keyspace string
+
+	// Frame is the decoded request, an interceptor that modifies it has to set RequestInterceptorResult.Modified.
+	// The next interceptors receive the modified request.
+	Frame *frame.Frame
+}
+
+// RequestRoute overrides the clusters that a request is forwarded to.
+type RequestRoute string
+
+const (
+	RequestRouteDefault = RequestRoute("")
+	RequestRouteOrigin  = RequestRoute("ORIGIN")
+	RequestRouteTarget  = RequestRoute("TARGET")
+	RequestRouteBoth    = RequestRoute("BOTH")
+)
+
+// RequestInterceptorResult is the outcome of a RequestInterceptor, the zero value lets the request through unchanged.
+type RequestInterceptorResult struct {
+	// Modified is true if the interceptor modified InterceptedRequest.Frame, the modified request is forwarded.
+	Modified bool
+
+	// Route overrides the clusters that the proxy would forward the request to, the route of the last interceptor
+	// that sets one is used. It only applies to the QUERY, EXECUTE and BATCH requests that are forwarded to the
+	// clusters, the PREPARE requests and the requests that are handled by the proxy itself (e.g. the system tables
+	// queries that are virtualized) keep the routing of the proxy.
+	Route RequestRoute
+
+	// Reject is returned to the client instead of forwarding the request, e.g. &message.Unauthorized{}. The next
+	// interceptors are not called.
+	Reject message.Error
+}
+
+// requestInterception is the combined outcome of the request interceptors of a request.
+type requestInterception struct {
+	frameContext *frameDecodeContext // context of the modified request, the original one if it was not modified
+	modified     bool
+	route        RequestRoute
+	response     *frame.RawFrame // response of the rejected requests
+}
+
+// interceptRequest calls the request interceptors of the proxy for the request, it returns nil if there are no
+// interceptors or if the request is not intercepted (i.e. it is not a QUERY, PREPARE, EXECUTE or BATCH request).
+func (ch *ClientHandler) interceptRequest(
+	frameContext *frameDecodeContext, currentKeyspace string) (*requestInterception, error) {
+	if len(ch.requestInterceptors) == 0 {
+		return nil, nil
+	}
+	switch frameContext.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return nil, nil
+	}
+
+	decodedFrame, err := frameContext.GetOrDecodeFrame()
+	if err != nil {
+		return nil, fmt.Errorf("could not decode request for the request interceptors: %w", err)
+	}
+
+	// the decoded frame of the context is cached, the interceptors get a copy so that it can't be modified in place
+	request := &InterceptedRequest{
+		ClientAddress: ch.clientConnector.connection.RemoteAddr(),
+		Username:      ch.clientRole,
+		Keyspace:      currentKeyspace,
+		Frame:         decodedFrame.Clone(),
+	}
+	interception := &requestInterception{frameContext: frameContext}
+	for _, interceptor := range ch.requestInterceptors {
+		result := interceptor.InterceptRequest(request)
+		if result.Reject != nil {
+			response, err := defaultCodec.ConvertToRawFrame(
+				frame.NewFrame(request.Frame.Header.Version, request.Frame.Header.StreamId, result.Reject))
+			if err != nil {
+				return nil, fmt.Errorf("could not encode the rejection of a request interceptor: %w", err)
+			}
+			interception.response = response
+			return interception, nil
+		}
+		interception.modified = interception.modified || result.Modified
+		if result.Route != RequestRouteDefault {
+			interception.route = result.Route
+		}
+	}
+
+	if interception.modified {
+		// the response must have the stream id of the client request
+		request.Frame.Header.StreamId = decodedFrame.Header.StreamId
+		rawFrame, err := defaultCodec.ConvertToRawFrame(request.Frame)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode the request modified by the request interceptors: %w", err)
+		}
+		interception.frameContext = NewFrameDecodeContext(rawFrame)
+	}
+	return interception, nil
+}
+
+// applyRoute replaces the forward decision of the request with the route of the request interceptors.
+func (recv *requestInterception) applyRoute(requestInfo RequestInfo) (RequestInfo, bool) {
+	if recv == nil {
+		return requestInfo, false
+	}
+
+	var decision forwardDecision
+	switch recv.route {
+	case RequestRouteOrigin:
+		decision = forwardToOrigin
+	case RequestRouteTarget:
+		decision = forwardToTarget
+	case RequestRouteBoth:
+		decision = forwardToBoth
+	default:
+		return requestInfo, false
+	}
+
+	switch requestInfo.GetForwardDecision() {
+	case forwardToOrigin, forwardToTarget, forwardToBoth:
+	default:
+		return requestInfo, false // handled by the proxy
+	}
+
+	switch typedRequestInfo := requestInfo.(type) {
+	case *GenericRequestInfo:
+		return NewGenericRequestInfo(decision, false, typedRequestInfo.ShouldBeTrackedInMetrics()), true
+	case *TableRoutingRequestInfo:
+		return NewGenericRequestInfo(decision, false, typedRequestInfo.ShouldBeTrackedInMetrics()), true
+	case *StatementFilterRequestInfo:
+		return NewGenericRequestInfo(decision, false, typedRequestInfo.ShouldBeTrackedInMetrics()), true
+	case *SchemaStatementPolicyRequestInfo:
+		return NewGenericRequestInfo(decision, false, typedRequestInfo.ShouldBeTrackedInMetrics()), true
+	case *ExecuteRequestInfo:
+		return NewRoutedReadExecuteRequestInfo(typedRequestInfo.GetPreparedData(), decision), true
+	case *BatchRequestInfo:
+		return NewBatchRequestInfo(typedRequestInfo.GetPreparedDataByStmtIdx(), decision), true
+	}
+	return requestInfo, false
+}
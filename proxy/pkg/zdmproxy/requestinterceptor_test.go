@@ -0,0 +1,45 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRequestInterception_ApplyRoute(t *testing.T) {
+	prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false,
+		"INSERT INTO tb (a) VALUES (?)", "ks")
+	preparedData := NewPreparedData(&message.PreparedResult{}, &message.PreparedResult{}, prepareRequestInfo)
+
+	tests := []struct {
+		name             string
+		route            RequestRoute
+		requestInfo      RequestInfo
+		expectedRouted   bool
+		expectedDecision forwardDecision
+	}{
+		{"default route", RequestRouteDefault, NewGenericRequestInfo(forwardToBoth, false, true), false, forwardToBoth},
+		{"query", RequestRouteTarget, NewGenericRequestInfo(forwardToBoth, true, true), true, forwardToTarget},
+		{"table routing rule", RequestRouteBoth,
+			NewTableRoutingRequestInfo(&common.TableRoutingRule{Cluster: common.ClusterTypeOrigin}, true), true, forwardToBoth},
+		{"execute", RequestRouteOrigin, NewExecuteRequestInfo(preparedData), true, forwardToOrigin},
+		{"batch", RequestRouteOrigin, NewBatchRequestInfo(nil, forwardToBoth), true, forwardToOrigin},
+		{"prepare", RequestRouteOrigin, prepareRequestInfo, false, forwardToBoth},
+		{"intercepted", RequestRouteOrigin, NewInterceptedRequestInfo(local, newStarSelectClause()), false, forwardToNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestInfo, routed := (&requestInterception{route: tt.route}).applyRoute(tt.requestInfo)
+			require.Equal(t, tt.expectedRouted, routed)
+			require.Equal(t, tt.expectedDecision, requestInfo.GetForwardDecision())
+			require.False(t, routed && requestInfo.ShouldAlsoBeSentAsync())
+		})
+	}
+
+	var interception *requestInterception
+	requestInfo := NewGenericRequestInfo(forwardToBoth, false, true)
+	routedRequestInfo, routed := interception.applyRoute(requestInfo)
+	require.False(t, routed)
+	require.Equal(t, requestInfo, routedRequestInfo)
+}
@@ -0,0 +1,172 @@
+package zdmproxy
+
+import (
+	"container/list"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// requestLimiter caps the number of requests that are in flight on a cluster across all client connections.
+//
+// Requests that exceed the limit wait in a bounded FIFO queue until a response from that cluster frees a slot.
+// Requests that can't be queued (queue is full) or that wait longer than the queue timeout are answered
+// with an OVERLOADED error so the client can retry.
+//
+// The queued requests are handed to the cluster connector that owns them (see ClusterConnector.handOffLimitedRequest)
+// so a response of one client connection never blocks on writing the requests of another one.
+type requestLimiter struct {
+	clusterType  common.ClusterType
+	maxInFlight  int
+	maxQueued    int
+	queueTimeout time.Duration
+
+	queuedRequests   metrics.Gauge
+	queueDuration    metrics.Histogram
+	rejectedRequests metrics.Counter
+
+	lock     *sync.Mutex
+	inFlight int
+	queue    *list.List
+}
+
+type queuedRequest struct {
+	connector  *ClusterConnector
+	request    *frame.RawFrame
+	enqueuedAt time.Time
+	timer      *time.Timer
+	element    *list.Element // nil if the request is no longer in the queue
+}
+
+func newRequestLimiter(
+	clusterType common.ClusterType, limitConfig *common.RequestLimitConfig, queuedRequests metrics.Gauge,
+	queueDuration metrics.Histogram, rejectedRequests metrics.Counter) *requestLimiter {
+	return &requestLimiter{
+		clusterType:      clusterType,
+		maxInFlight:      limitConfig.MaxConcurrentRequests,
+		maxQueued:        limitConfig.MaxQueuedRequests,
+		queueTimeout:     time.Duration(limitConfig.QueueTimeoutMs) * time.Millisecond,
+		queuedRequests:   queuedRequests,
+		queueDuration:    queueDuration,
+		rejectedRequests: rejectedRequests,
+		lock:             &sync.Mutex{},
+		inFlight:         0,
+		queue:            list.New(),
+	}
+}
+
+// send writes the request to the connector if there is a free slot, otherwise the request is queued.
+func (recv *requestLimiter) send(connector *ClusterConnector, request *frame.RawFrame) {
+	recv.lock.Lock()
+	if recv.inFlight < recv.maxInFlight && recv.queue.Len() == 0 {
+		recv.inFlight++
+		recv.lock.Unlock()
+		if !connector.dispatchLimitedRequest(request) {
+			recv.release(1)
+		}
+		return
+	}
+
+	if recv.queue.Len() >= recv.maxQueued {
+		recv.lock.Unlock()
+		recv.rejectedRequests.Add(1)
+		connector.rejectLimitedRequest(request, "request queue is full")
+		return
+	}
+
+	queued := &queuedRequest{
+		connector:  connector,
+		request:    request,
+		enqueuedAt: time.Now(),
+	}
+	queued.element = recv.queue.PushBack(queued)
+	queued.timer = time.AfterFunc(recv.queueTimeout, func() {
+		recv.expire(queued)
+	})
+	recv.lock.Unlock()
+	recv.queuedRequests.Add(1)
+}
+
+// trySend writes the request to the connector only if there is a free slot, it never queues the request.
+// Used for async requests which are discarded instead of delaying the client.
+func (recv *requestLimiter) trySend(connector *ClusterConnector, request *frame.RawFrame) bool {
+	recv.lock.Lock()
+	if recv.inFlight >= recv.maxInFlight || recv.queue.Len() > 0 {
+		recv.lock.Unlock()
+		return false
+	}
+	recv.inFlight++
+	recv.lock.Unlock()
+
+	if !connector.dispatchLimitedAsyncRequest(request) {
+		recv.release(1)
+		return false
+	}
+	return true
+}
+
+// release frees the provided number of slots and sends the requests at the head of the queue that fit in them.
+func (recv *requestLimiter) release(slots int) {
+	recv.lock.Lock()
+	recv.inFlight -= slots
+	var next []*queuedRequest
+	for recv.inFlight < recv.maxInFlight && recv.queue.Len() > 0 {
+		queued := recv.queue.Remove(recv.queue.Front()).(*queuedRequest)
+		queued.element = nil
+		queued.timer.Stop()
+		recv.inFlight++
+		next = append(next, queued)
+	}
+	recv.lock.Unlock()
+
+	notSent := 0
+	for _, queued := range next {
+		recv.queuedRequests.Subtract(1)
+		recv.queueDuration.Track(queued.enqueuedAt)
+		if !queued.connector.handOffLimitedRequest(queued.request, "") {
+			notSent++
+		}
+	}
+	if notSent > 0 {
+		recv.release(notSent)
+	}
+}
+
+func (recv *requestLimiter) expire(queued *queuedRequest) {
+	recv.lock.Lock()
+	if queued.element == nil {
+		recv.lock.Unlock()
+		return
+	}
+	recv.queue.Remove(queued.element)
+	queued.element = nil
+	recv.lock.Unlock()
+
+	recv.queuedRequests.Subtract(1)
+	recv.rejectedRequests.Add(1)
+	queued.connector.handOffLimitedRequest(queued.request, "request timed out in the request queue")
+}
+
+// removeConnector discards the queued requests of a connector that is shutting down.
+func (recv *requestLimiter) removeConnector(connector *ClusterConnector) {
+	recv.lock.Lock()
+	removed := 0
+	for e := recv.queue.Front(); e != nil; {
+		next := e.Next()
+		queued := e.Value.(*queuedRequest)
+		if queued.connector == connector {
+			recv.queue.Remove(e)
+			queued.element = nil
+			queued.timer.Stop()
+			removed++
+		}
+		e = next
+	}
+	recv.lock.Unlock()
+
+	if removed > 0 {
+		recv.queuedRequests.Subtract(removed)
+	}
+}
@@ -0,0 +1,154 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/jpillora/backoff"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// requestRetryPolicy retries the idempotent requests that fail on a cluster with a retryable error (see
+// ZDM_ORIGIN_REQUEST_MAX_RETRIES and ZDM_TARGET_REQUEST_MAX_RETRIES). The request is sent again to the same cluster
+// only, the response of the other cluster (if the request was also forwarded to it) is kept.
+type requestRetryPolicy struct {
+	maxRetries      int
+	backoffMin      time.Duration
+	backoffMax      time.Duration
+	retriedRequests metrics.Counter
+}
+
+// newRequestRetryPolicy returns nil if the retries are disabled.
+func newRequestRetryPolicy(conf *common.RequestRetryConfig, retriedRequests metrics.Counter) *requestRetryPolicy {
+	if !conf.Enabled {
+		return nil
+	}
+	return &requestRetryPolicy{
+		maxRetries:      conf.MaxRetries,
+		backoffMin:      time.Duration(conf.BackoffMinMs) * time.Millisecond,
+		backoffMax:      time.Duration(conf.BackoffMaxMs) * time.Millisecond,
+		retriedRequests: retriedRequests,
+	}
+}
+
+// newRequestRetry returns the retry state of a request that was forwarded to the cluster of the policy,
+// nil if the policy is nil.
+func (recv *requestRetryPolicy) newRequestRetry(request *frame.RawFrame) *requestRetry {
+	if recv == nil {
+		return nil
+	}
+	return &requestRetry{
+		policy:   recv,
+		request:  request,
+		streamId: request.Header.StreamId,
+		backoff: &backoff.Backoff{
+			Factor: 2,
+			Jitter: true,
+			Min:    recv.backoffMin,
+			Max:    recv.backoffMax,
+		},
+	}
+}
+
+// requestRetry is the retry state of a request on one cluster, it is guarded by the lock of the request context.
+type requestRetry struct {
+	policy   *requestRetryPolicy
+	request  *frame.RawFrame // the request that was forwarded to the cluster, i.e. with its rewrites
+	streamId int16           // the pooled cluster connections replace the stream id of the request that they send
+	retries  int
+	backoff  *backoff.Backoff
+}
+
+// newRequest returns a copy of the request that was forwarded to the cluster with its original stream id.
+func (recv *requestRetry) newRequest() *frame.RawFrame {
+	request := recv.request.Clone()
+	request.Header.StreamId = recv.streamId
+	return request
+}
+
+// isRetryableResponse returns true if the response is a READ_TIMEOUT, WRITE_TIMEOUT, UNAVAILABLE or OVERLOADED error.
+func isRetryableResponse(response *frame.RawFrame) bool {
+	if response.Header.OpCode != primitive.OpCodeError {
+		return false
+	}
+	errMsg, err := decodeError(response)
+	if err != nil {
+		log.Errorf("Could not decode error response to check if the request can be retried: %v", err)
+		return false
+	}
+	switch errMsg.GetErrorCode() {
+	case primitive.ErrorCodeReadTimeout, primitive.ErrorCodeWriteTimeout, primitive.ErrorCodeUnavailable,
+		primitive.ErrorCodeOverloaded:
+		return true
+	default:
+		return false
+	}
+}
+
+// isIdempotentRequest returns true if the request can be sent again to a cluster without changing the outcome:
+// SELECT statements and INSERT, UPDATE, DELETE and BATCH statements that are not lightweight transactions or counter
+// updates and that don't call now() or the other functions that the proxy replaces. Note that appending or
+// prepending to a list is not detected.
+func isIdempotentRequest(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (bool, error) {
+	switch frameContext.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeQuery:
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		return isIdempotentStatement(stmtQueryData.queryData), nil
+	case primitive.OpCodeExecute:
+		executeRequestInfo, ok := unwrapRequestInfo(requestInfo).(*ExecuteRequestInfo)
+		if !ok {
+			return false, nil
+		}
+		return isIdempotentPreparedStatement(executeRequestInfo.GetPreparedData()), nil
+	case primitive.OpCodeBatch:
+		batchRequestInfo, ok := unwrapRequestInfo(requestInfo).(*BatchRequestInfo)
+		if !ok {
+			return false, nil
+		}
+		decodedFrame, stmtsQueryData, err := frameContext.GetOrDecodeAndInspect(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		if batchMsg, ok := decodedFrame.Body.Message.(*message.Batch); !ok || batchMsg.Type == primitive.BatchTypeCounter {
+			return false, nil
+		}
+		for _, preparedData := range batchRequestInfo.GetPreparedDataByStmtIdx() {
+			if !isIdempotentPreparedStatement(preparedData) {
+				return false, nil
+			}
+		}
+		for _, stmtQueryData := range stmtsQueryData {
+			if !isIdempotentStatement(stmtQueryData.queryData) {
+				return false, nil
+			}
+		}
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func isIdempotentPreparedStatement(preparedData PreparedData) bool {
+	queryInfo := preparedData.GetPrepareRequestInfo().GetQueryInfo()
+	return queryInfo != nil && isIdempotentStatement(queryInfo) && !isCounterWritePreparedStatement(preparedData)
+}
+
+func isIdempotentStatement(queryInfo QueryInfo) bool {
+	switch queryInfo.getStatementType() {
+	case statementTypeSelect:
+		return true
+	case statementTypeInsert, statementTypeUpdate, statementTypeDelete, statementTypeBatch:
+		return !queryInfo.isLightweightTransaction() && !queryInfo.hasCounterUpdates() &&
+			!queryInfo.hasReplaceableFunctionCalls()
+	default:
+		return false
+	}
+}
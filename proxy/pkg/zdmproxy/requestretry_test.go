@@ -0,0 +1,114 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsIdempotentRequest(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newPreparedData := func(query string, variableTypes ...datatype.DataType) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, query, "")
+		prepareRequestInfo.queryInfo = inspectCqlQuery(query, "", generator)
+		variablesMetadata := &message.VariablesMetadata{}
+		for i, variableType := range variableTypes {
+			variablesMetadata.Columns = append(variablesMetadata.Columns, &message.ColumnMetadata{
+				Keyspace: "ks", Table: "tb", Name: "c", Index: int32(i), Type: variableType})
+		}
+		return &preparedDataImpl{
+			prepareRequestInfo:      prepareRequestInfo,
+			originVariablesMetadata: variablesMetadata,
+			targetVariablesMetadata: variablesMetadata,
+		}
+	}
+	newQuery := func(query string) message.Message {
+		return &message.Query{Query: query}
+	}
+	genericRequestInfo := NewGenericRequestInfo(forwardToBoth, false, true)
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    bool
+	}{
+		{"select", newQuery("SELECT * FROM ks.tb WHERE a = 1"), NewGenericRequestInfo(forwardToOrigin, true, true), true},
+		{"insert", newQuery("INSERT INTO ks.tb (a, b) VALUES (1, 2)"), genericRequestInfo, true},
+		{"update", newQuery("UPDATE ks.tb SET b = 1 WHERE a = 1"), genericRequestInfo, true},
+		{"delete", newQuery("DELETE FROM ks.tb WHERE a = 1"), genericRequestInfo, true},
+		{"insert if not exists", newQuery("INSERT INTO ks.tb (a) VALUES (1) IF NOT EXISTS"), genericRequestInfo, false},
+		{"insert now", newQuery("INSERT INTO ks.tb (a, b) VALUES (1, now())"), genericRequestInfo, false},
+		{"counter update", newQuery("UPDATE ks.tb SET c = c + 1 WHERE a = 1"), genericRequestInfo, false},
+		{"truncate", newQuery("TRUNCATE ks.tb"), genericRequestInfo, false},
+		{"create table", newQuery("CREATE TABLE ks.tb (a int PRIMARY KEY)"), genericRequestInfo, false},
+		{"primary only write", newQuery("INSERT INTO ks.tb (a) VALUES (1)"),
+			NewPrimaryOnlyWriteRequestInfo(genericRequestInfo, "ORIGIN"), true},
+		{"execute insert", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("INSERT INTO ks.tb (a) VALUES (?)", datatype.Int)), true},
+		{"execute insert with function call", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("INSERT INTO ks.tb (a, b) VALUES (?, uuid())", datatype.Int)), false},
+		{"execute counter update", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData(
+				"UPDATE ks.tb SET c = c + ? WHERE a = ?", datatype.Counter, datatype.Int)), false},
+		{"async write execute", &message.Execute{QueryId: []byte("ID")},
+			NewAsyncWriteRequestInfo(NewExecuteRequestInfo(newPreparedData(
+				"UPDATE ks.tb SET b = ? WHERE a = ?", datatype.Int, datatype.Int)), "ORIGIN"), true},
+		{"batch", &message.Batch{Type: primitive.BatchTypeLogged, Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+			{QueryOrId: []byte("ID")}}},
+			NewBatchRequestInfo(map[int]PreparedData{
+				1: newPreparedData("UPDATE ks.tb SET b = ? WHERE a = 1", datatype.Int)}, forwardToBoth), true},
+		{"batch with conditional child", &message.Batch{Type: primitive.BatchTypeLogged, Children: []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+			{QueryOrId: "UPDATE ks.tb SET b = 1 WHERE a = 1 IF b = 0"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), false},
+		{"batch with conditional prepared child", &message.Batch{Type: primitive.BatchTypeLogged, Children: []*message.BatchChild{
+			{QueryOrId: []byte("ID")}}},
+			NewBatchRequestInfo(map[int]PreparedData{
+				0: newPreparedData("INSERT INTO ks.tb (a) VALUES (?) IF NOT EXISTS", datatype.Int)}, forwardToBoth), false},
+		{"counter batch", &message.Batch{Type: primitive.BatchTypeCounter, Children: []*message.BatchChild{
+			{QueryOrId: "UPDATE ks.tb SET c = c + ? WHERE a = 1"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), false},
+		{"options", &message.Options{}, genericRequestInfo, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			idempotent, err := isIdempotentRequest(NewFrameDecodeContext(f), tt.requestInfo, "", generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, idempotent)
+		})
+	}
+}
+
+func TestIsRetryableResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      message.Message
+		expected bool
+	}{
+		{"read timeout", &message.ReadTimeout{Consistency: primitive.ConsistencyLevelQuorum}, true},
+		{"write timeout", &message.WriteTimeout{
+			Consistency: primitive.ConsistencyLevelQuorum, WriteType: primitive.WriteTypeSimple}, true},
+		{"unavailable", &message.Unavailable{Consistency: primitive.ConsistencyLevelQuorum}, true},
+		{"overloaded", &message.Overloaded{}, true},
+		{"invalid", &message.Invalid{}, false},
+		{"unprepared", &message.Unprepared{Id: []byte("ID")}, false},
+		{"void", &message.VoidResult{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, tt.msg))
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, isRetryableResponse(response))
+		})
+	}
+}
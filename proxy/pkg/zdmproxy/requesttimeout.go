@@ -0,0 +1,135 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"time"
+)
+
+// requestTimeoutPolicy decides how long the proxy waits for the response of each cluster (see
+// ZDM_ORIGIN_REQUEST_TIMEOUT_MS and ZDM_TARGET_REQUEST_TIMEOUT_MS), the reads, writes and schema changes can override
+// the timeout of both clusters (see ZDM_PROXY_READ_REQUEST_TIMEOUT_MS, ZDM_PROXY_WRITE_REQUEST_TIMEOUT_MS and
+// ZDM_PROXY_DDL_REQUEST_TIMEOUT_MS).
+type requestTimeoutPolicy struct {
+	origin time.Duration
+	target time.Duration
+	read   time.Duration // 0 if it is not overridden
+	write  time.Duration // 0 if it is not overridden
+	ddl    time.Duration // 0 if it is not overridden
+}
+
+func newRequestTimeoutPolicy(conf *common.RequestTimeoutConfig) *requestTimeoutPolicy {
+	return &requestTimeoutPolicy{
+		origin: time.Duration(conf.OriginMs) * time.Millisecond,
+		target: time.Duration(conf.TargetMs) * time.Millisecond,
+		read:   time.Duration(conf.ReadMs) * time.Millisecond,
+		write:  time.Duration(conf.WriteMs) * time.Millisecond,
+		ddl:    time.Duration(conf.DdlMs) * time.Millisecond,
+	}
+}
+
+// clusterTimeout returns the timeout of the requests that are not covered by the statement type timeouts.
+func (recv *requestTimeoutPolicy) clusterTimeout() requestTimeout {
+	return requestTimeout{origin: recv.origin, target: recv.target}
+}
+
+// getRequestTimeout returns the timeout of the request on each cluster, the statement is only inspected if one of the
+// statement type timeouts is set.
+func (recv *requestTimeoutPolicy) getRequestTimeout(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (requestTimeout, error) {
+	if recv.read == 0 && recv.write == 0 && recv.ddl == 0 {
+		return recv.clusterTimeout(), nil
+	}
+
+	queryInfo, err := getRequestQueryInfo(frameContext, requestInfo, currentKeyspace, timeUuidGenerator)
+	if err != nil {
+		return requestTimeout{}, err
+	}
+	override := time.Duration(0)
+	switch {
+	case frameContext.GetRawFrame().Header.OpCode == primitive.OpCodeBatch:
+		override = recv.write
+	case queryInfo == nil:
+	case queryInfo.getStatementType() == statementTypeSelect:
+		override = recv.read
+	case isWriteStatementType(queryInfo.getStatementType()):
+		override = recv.write
+	case queryInfo.getStatementType() == statementTypeOther && isSchemaChange(queryInfo.getQuery()):
+		override = recv.ddl
+	}
+	if override == 0 {
+		return recv.clusterTimeout(), nil
+	}
+	return newUniformRequestTimeout(override), nil
+}
+
+// getRequestQueryInfo returns the statement of a QUERY request or of the prepared statement of an EXECUTE request,
+// nil for the other requests.
+func getRequestQueryInfo(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (QueryInfo, error) {
+	switch frameContext.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeQuery:
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return nil, err
+		}
+		return stmtQueryData.queryData, nil
+	case primitive.OpCodeExecute:
+		executeRequestInfo, ok := unwrapRequestInfo(requestInfo).(*ExecuteRequestInfo)
+		if !ok {
+			return nil, nil
+		}
+		return executeRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetQueryInfo(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// isSchemaChange returns true if the statement is a CREATE, ALTER, DROP or TRUNCATE statement.
+func isSchemaChange(query string) bool {
+	tokens := tokenizeDdl(query)
+	if len(tokens) == 0 {
+		return false
+	}
+	switch tokens[0].text {
+	case "CREATE", "ALTER", "DROP", "TRUNCATE":
+		return true
+	default:
+		return false
+	}
+}
+
+// requestTimeout is how long the proxy waits for the response of each cluster that a request is forwarded to.
+type requestTimeout struct {
+	origin time.Duration
+	target time.Duration
+}
+
+func newUniformRequestTimeout(timeout time.Duration) requestTimeout {
+	return requestTimeout{origin: timeout, target: timeout}
+}
+
+func (recv requestTimeout) get(cluster common.ClusterType) time.Duration {
+	if cluster == common.ClusterTypeTarget {
+		return recv.target
+	}
+	return recv.origin
+}
+
+// forDecision returns the timeout of the request on the clusters that it is forwarded to, the timer of the request
+// fires after the shortest one.
+func (recv requestTimeout) forDecision(decision forwardDecision) time.Duration {
+	switch decision {
+	case forwardToOrigin:
+		return recv.origin
+	case forwardToTarget:
+		return recv.target
+	default:
+		if recv.target < recv.origin {
+			return recv.target
+		}
+		return recv.origin
+	}
+}
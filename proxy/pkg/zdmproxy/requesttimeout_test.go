@@ -0,0 +1,120 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutPolicy_GetRequestTimeout(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newPreparedData := func(query string) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, query, "")
+		prepareRequestInfo.queryInfo = inspectCqlQuery(query, "", generator)
+		variablesMetadata := &message.VariablesMetadata{Columns: []*message.ColumnMetadata{
+			{Keyspace: "ks", Table: "tb", Name: "a", Index: 0, Type: datatype.Int}}}
+		return &preparedDataImpl{
+			prepareRequestInfo:      prepareRequestInfo,
+			originVariablesMetadata: variablesMetadata,
+			targetVariablesMetadata: variablesMetadata,
+		}
+	}
+	newQuery := func(query string) message.Message {
+		return &message.Query{Query: query}
+	}
+	genericRequestInfo := NewGenericRequestInfo(forwardToBoth, false, true)
+
+	clusterTimeout := requestTimeout{origin: 10 * time.Second, target: 2 * time.Second}
+	policy := newRequestTimeoutPolicy(&common.RequestTimeoutConfig{
+		OriginMs: 10000, TargetMs: 2000, ReadMs: 1000, WriteMs: 0, DdlMs: 60000})
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    requestTimeout
+	}{
+		{"select", newQuery("SELECT * FROM ks.tb WHERE a = 1"), NewGenericRequestInfo(forwardToOrigin, true, true),
+			newUniformRequestTimeout(time.Second)},
+		{"insert without write timeout", newQuery("INSERT INTO ks.tb (a) VALUES (1)"), genericRequestInfo, clusterTimeout},
+		{"create table", newQuery("CREATE TABLE ks.tb (a int PRIMARY KEY)"), genericRequestInfo,
+			newUniformRequestTimeout(time.Minute)},
+		{"truncate", newQuery("/* comment */ truncate ks.tb"), genericRequestInfo, newUniformRequestTimeout(time.Minute)},
+		{"grant", newQuery("GRANT SELECT ON ks.tb TO role1"), genericRequestInfo, clusterTimeout},
+		{"execute select", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("SELECT * FROM ks.tb WHERE a = ?")), newUniformRequestTimeout(time.Second)},
+		{"continuous paging execute select", &message.Execute{QueryId: []byte("ID")},
+			NewContinuousPagingRequestInfo(NewExecuteRequestInfo(newPreparedData("SELECT * FROM ks.tb WHERE a = ?")),
+				common.ClusterTypeOrigin), newUniformRequestTimeout(time.Second)},
+		{"options", &message.Options{}, genericRequestInfo, clusterTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			timeout, err := policy.getRequestTimeout(NewFrameDecodeContext(f), tt.requestInfo, "", generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, timeout)
+		})
+	}
+
+	// the writes get the timeout of the statement type once it is set
+	policy.write = 5 * time.Second
+	f := mockFrame(t, &message.Batch{Type: primitive.BatchTypeLogged, Children: []*message.BatchChild{
+		{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"}}}, primitive.ProtocolVersion4)
+	timeout, err := policy.getRequestTimeout(
+		NewFrameDecodeContext(f), NewBatchRequestInfo(nil, forwardToBoth), "", generator)
+	require.Nil(t, err)
+	require.Equal(t, newUniformRequestTimeout(5*time.Second), timeout)
+}
+
+func TestRequestTimeout_ForDecision(t *testing.T) {
+	timeout := requestTimeout{origin: 10 * time.Second, target: 2 * time.Second}
+	require.Equal(t, 10*time.Second, timeout.forDecision(forwardToOrigin))
+	require.Equal(t, 2*time.Second, timeout.forDecision(forwardToTarget))
+	require.Equal(t, 2*time.Second, timeout.forDecision(forwardToBoth))
+	require.Equal(t, 2*time.Second, timeout.get(common.ClusterTypeTarget))
+}
+
+func TestRequestContext_ExtendTimer(t *testing.T) {
+	request := mockQueryFrame(t, "INSERT INTO ks.tb (a) VALUES (1)")
+	response := mockFrame(t, &message.VoidResult{}, primitive.ProtocolVersion4)
+	nodeMetrics := &metrics.NodeMetrics{
+		OriginMetrics: newTestNodeMetricsInstance(),
+		TargetMetrics: newTestNodeMetricsInstance(),
+	}
+
+	newWrite := func(timeout requestTimeout, elapsed time.Duration) *requestContextImpl {
+		reqCtx := NewRequestContext(request, NewGenericRequestInfo(forwardToBoth, false, false), time.Now(), nil)
+		reqCtx.timeout = timeout
+		reqCtx.timerStart = time.Now().Add(-elapsed)
+		reqCtx.SetTimer(time.NewTimer(time.Hour))
+		return reqCtx
+	}
+
+	// the timeout of TARGET expired but its response was received, ORIGIN still has time left
+	reqCtx := newWrite(requestTimeout{origin: 10 * time.Second, target: time.Second}, 2*time.Second)
+	reqCtx.SetResponse(nodeMetrics, response, common.ClusterTypeTarget, ClusterConnectorTypeTarget)
+	require.True(t, reqCtx.extendTimer())
+
+	// the timeout of TARGET expired before its response
+	reqCtx = newWrite(requestTimeout{origin: 10 * time.Second, target: time.Second}, 2*time.Second)
+	reqCtx.SetResponse(nodeMetrics, response, common.ClusterTypeOrigin, ClusterConnectorTypeOrigin)
+	require.False(t, reqCtx.extendTimer())
+
+	// both timeouts expired
+	reqCtx = newWrite(requestTimeout{origin: time.Second, target: time.Second}, 2*time.Second)
+	require.False(t, reqCtx.extendTimer())
+
+	// the request is not pending anymore
+	reqCtx = newWrite(requestTimeout{origin: 10 * time.Second, target: 10 * time.Second}, 0)
+	require.True(t, reqCtx.Cancel(nodeMetrics))
+	require.False(t, reqCtx.extendTimer())
+}
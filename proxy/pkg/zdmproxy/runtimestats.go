@@ -0,0 +1,142 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+// RuntimeStats is a snapshot of the goroutines, of the heap and of the garbage collector of the proxy process.
+type RuntimeStats struct {
+	GoVersion  string
+	GoMaxProcs int
+	Goroutines int
+
+	HeapAllocBytes uint64
+	HeapInUseBytes uint64
+	HeapObjects    uint64
+	// SysBytes is the memory obtained from the OS.
+	SysBytes uint64
+
+	NumGC          uint32
+	LastGCPause    time.Duration
+	TotalGCPause   time.Duration
+	GCCPUFraction  float64
+	NextGCHeapSize uint64
+}
+
+func GetRuntimeStats() *RuntimeStats {
+	memStats := &runtime.MemStats{}
+	runtime.ReadMemStats(memStats)
+	stats := &RuntimeStats{
+		GoVersion:      runtime.Version(),
+		GoMaxProcs:     runtime.GOMAXPROCS(0),
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: memStats.HeapAlloc,
+		HeapInUseBytes: memStats.HeapInuse,
+		HeapObjects:    memStats.HeapObjects,
+		SysBytes:       memStats.Sys,
+		NumGC:          memStats.NumGC,
+		TotalGCPause:   time.Duration(memStats.PauseTotalNs),
+		GCCPUFraction:  memStats.GCCPUFraction,
+		NextGCHeapSize: memStats.NextGC,
+	}
+	if memStats.NumGC > 0 {
+		stats.LastGCPause = time.Duration(memStats.PauseNs[(memStats.NumGC+255)%256])
+	}
+	return stats
+}
+
+// WriteRuntimeDiagnostics writes the runtime stats, the stacks of all goroutines and the heap profile in text format
+// so that the dump can be read without the pprof tool.
+func WriteRuntimeDiagnostics(w io.Writer) error {
+	stats := GetRuntimeStats()
+	_, err := fmt.Fprintf(w, "Runtime stats:\n"+
+		"  go version: %v\n  GOMAXPROCS: %v\n  goroutines: %v\n"+
+		"  heap allocated: %v bytes\n  heap in use: %v bytes\n  heap objects: %v\n"+
+		"  memory obtained from the OS: %v bytes\n"+
+		"  garbage collections: %v\n  last GC pause: %v\n  total GC pause: %v\n  GC CPU fraction: %.4f\n\n",
+		stats.GoVersion, stats.GoMaxProcs, stats.Goroutines,
+		stats.HeapAllocBytes, stats.HeapInUseBytes, stats.HeapObjects, stats.SysBytes,
+		stats.NumGC, stats.LastGCPause, stats.TotalGCPause, stats.GCCPUFraction)
+	if err != nil {
+		return err
+	}
+
+	for _, profile := range []string{"goroutine", "heap"} {
+		_, err = fmt.Fprintf(w, "Profile %v:\n", profile)
+		if err != nil {
+			return err
+		}
+		debug := 1
+		if profile == "goroutine" {
+			// same format as an unrecovered panic
+			debug = 2
+		}
+		err = pprof.Lookup(profile).WriteTo(w, debug)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runtimeStatsLogger logs the runtime stats periodically (see ZDM_RUNTIME_STATS_LOG_INTERVAL_MS) with the garbage
+// collections and their pauses since the previous log.
+type runtimeStatsLogger struct {
+	interval time.Duration
+
+	// only accessed by the goroutine that logs the stats
+	previous *RuntimeStats
+}
+
+// newRuntimeStatsLogger returns nil if the runtime stats are not logged.
+func newRuntimeStatsLogger(intervalMs int) *runtimeStatsLogger {
+	if intervalMs <= 0 {
+		return nil
+	}
+	return &runtimeStatsLogger{
+		interval: time.Duration(intervalMs) * time.Millisecond,
+	}
+}
+
+func (recv *runtimeStatsLogger) run(ctx context.Context) {
+	recv.previous = GetRuntimeStats()
+	ticker := time.NewTicker(recv.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recv.log()
+		}
+	}
+}
+
+func (recv *runtimeStatsLogger) log() {
+	stats := GetRuntimeStats()
+	log.WithFields(recv.fields(stats)).Info("Runtime stats")
+	recv.previous = stats
+}
+
+func (recv *runtimeStatsLogger) fields(stats *RuntimeStats) log.Fields {
+	return log.Fields{
+		"goroutines":       stats.Goroutines,
+		"heap_alloc_bytes": stats.HeapAllocBytes,
+		"heap_inuse_bytes": stats.HeapInUseBytes,
+		"heap_objects":     stats.HeapObjects,
+		"sys_bytes":        stats.SysBytes,
+		"gc_count":         stats.NumGC - recv.previous.NumGC,
+		"gc_pause":         stats.TotalGCPause - recv.previous.TotalGCPause,
+		"last_gc_pause":    stats.LastGCPause,
+	}
+}
@@ -0,0 +1,36 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"testing"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	runtime.GC()
+	stats := GetRuntimeStats()
+	require.Greater(t, stats.Goroutines, 0)
+	require.Greater(t, stats.HeapInUseBytes, uint64(0))
+	require.Greater(t, stats.NumGC, uint32(0))
+	require.Equal(t, runtime.Version(), stats.GoVersion)
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, WriteRuntimeDiagnostics(buf))
+	require.Contains(t, buf.String(), "goroutines: ")
+	require.Contains(t, buf.String(), "Profile goroutine:\ngoroutine ")
+	require.Contains(t, buf.String(), "TestRuntimeStats")
+	require.Contains(t, buf.String(), "Profile heap:\nheap profile: ")
+}
+
+func TestRuntimeStatsLogger_Fields(t *testing.T) {
+	require.Nil(t, newRuntimeStatsLogger(0))
+
+	logger := newRuntimeStatsLogger(1000)
+	logger.previous = GetRuntimeStats()
+	runtime.GC()
+	runtime.GC()
+	fields := logger.fields(GetRuntimeStats())
+	require.GreaterOrEqual(t, fields["gc_count"], uint32(2))
+	require.Greater(t, fields["goroutines"], 0)
+}
@@ -1,39 +1,75 @@
 package zdmproxy
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 type Scheduler struct {
+	// number of tasks that were taken from the queue by the workers, it is checked by the stall watchdog
+	dequeuedTasks int64
+
 	queue chan func()
+	stop  chan struct{} // a worker exits when it receives from this channel, see Resize
 	wg    *sync.WaitGroup
+
+	lock    *sync.Mutex
+	workers int
 }
 
 func NewScheduler(workers int) *Scheduler {
 	scheduler := &Scheduler{
 		queue: make(chan func(), workers),
+		stop:  make(chan struct{}),
 		wg:    &sync.WaitGroup{},
+		lock:  &sync.Mutex{},
 	}
+	scheduler.Resize(workers)
+	return scheduler
+}
 
-	for i := 0; i < workers; i++ {
-		scheduler.wg.Add(1)
-		go func() {
-			defer scheduler.wg.Done()
-			for {
-				task, ok := <-scheduler.queue
+func (recv *Scheduler) startWorker() {
+	recv.wg.Add(1)
+	go func() {
+		defer recv.wg.Done()
+		for {
+			select {
+			case task, ok := <-recv.queue:
 				if !ok {
 					return
 				}
+				atomic.AddInt64(&recv.dequeuedTasks, 1)
 				task()
+			case <-recv.stop:
+				return
 			}
-		}()
-	}
-
-	return scheduler
+		}
+	}()
 }
 
 func (recv *Scheduler) Schedule(task func()) {
 	recv.queue <- task
 }
 
+// Resize starts or stops workers until there are the provided number of workers, a busy worker stops after its
+// current task so Resize can wait until a task completes. The capacity of the queue doesn't change.
+func (recv *Scheduler) Resize(workers int) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for ; recv.workers < workers; recv.workers++ {
+		recv.startWorker()
+	}
+	for ; recv.workers > workers && recv.workers > 1; recv.workers-- {
+		recv.stop <- struct{}{}
+	}
+}
+
+func (recv *Scheduler) GetWorkers() int {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.workers
+}
+
 func (recv *Scheduler) Shutdown() {
 	close(recv.queue)
 	recv.wg.Wait()
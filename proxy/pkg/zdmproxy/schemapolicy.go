@@ -0,0 +1,81 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+// schemaStatementPolicies routes or rejects the schema statements whose support frequently differs between
+// ORIGIN and TARGET (see ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY, ZDM_INDEX_STATEMENTS_POLICY and
+// ZDM_CUSTOM_INDEX_STATEMENTS_POLICY).
+type schemaStatementPolicies struct {
+	materializedViews common.SchemaStatementPolicy
+	indexes           common.SchemaStatementPolicy
+	customIndexes     common.SchemaStatementPolicy
+}
+
+// newSchemaStatementPolicies returns nil if every statement is forwarded to both clusters.
+func newSchemaStatementPolicies(
+	materializedViews common.SchemaStatementPolicy, indexes common.SchemaStatementPolicy,
+	customIndexes common.SchemaStatementPolicy) *schemaStatementPolicies {
+	if materializedViews == common.SchemaStatementPolicyBoth &&
+		indexes == common.SchemaStatementPolicyBoth &&
+		customIndexes == common.SchemaStatementPolicyBoth {
+		return nil
+	}
+	return &schemaStatementPolicies{
+		materializedViews: materializedViews,
+		indexes:           indexes,
+		customIndexes:     customIndexes,
+	}
+}
+
+// getRequestInfo returns nil if the statement should be forwarded to both clusters like any other schema change.
+func (recv *schemaStatementPolicies) getRequestInfo(query string) RequestInfo {
+	policy, description, envVarName := recv.getPolicy(query)
+	switch policy {
+	case common.SchemaStatementPolicyOriginOnly:
+		return NewSchemaStatementPolicyRequestInfo(envVarName)
+	case common.SchemaStatementPolicyReject:
+		return NewRejectedRequestInfo(fmt.Sprintf(
+			"%v are rejected by ZDM proxy (%v is %v)", description, envVarName, policy))
+	default:
+		return nil
+	}
+}
+
+// getPolicy returns the policy that applies to the statement along with a description of the statement and the
+// setting that configures the policy. DROP INDEX statements only contain the index name so they are not
+// covered by the index policies.
+func (recv *schemaStatementPolicies) getPolicy(query string) (common.SchemaStatementPolicy, string, string) {
+	tokens := tokenizeDdl(query)
+	if len(tokens) < 2 {
+		return common.SchemaStatementPolicyBoth, "", ""
+	}
+
+	verb := tokens[0].text
+	object := tokens[1].text
+	switch {
+	case object == "MATERIALIZED" && (verb == "CREATE" || verb == "ALTER" || verb == "DROP"):
+		return recv.materializedViews, "Materialized view statements", "ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY"
+	case verb == "CREATE" && object == "CUSTOM":
+		return recv.customIndexes, "Custom index statements", "ZDM_CUSTOM_INDEX_STATEMENTS_POLICY"
+	case verb == "CREATE" && object == "INDEX":
+		// CREATE INDEX ... USING 'sai' creates a custom index as well
+		if hasUsingClause(tokens) {
+			return recv.customIndexes, "Custom index statements", "ZDM_CUSTOM_INDEX_STATEMENTS_POLICY"
+		}
+		return recv.indexes, "Index statements", "ZDM_INDEX_STATEMENTS_POLICY"
+	default:
+		return common.SchemaStatementPolicyBoth, "", ""
+	}
+}
+
+func hasUsingClause(tokens []ddlToken) bool {
+	for i := 0; i < len(tokens)-1; i++ {
+		if !tokens[i].literal && tokens[i].text == "USING" && tokens[i+1].literal {
+			return true
+		}
+	}
+	return false
+}
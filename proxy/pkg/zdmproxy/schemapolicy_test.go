@@ -0,0 +1,66 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSchemaStatementPolicies(t *testing.T) {
+	require.Nil(t, newSchemaStatementPolicies(
+		common.SchemaStatementPolicyBoth, common.SchemaStatementPolicyBoth, common.SchemaStatementPolicyBoth))
+
+	policies := newSchemaStatementPolicies(
+		common.SchemaStatementPolicyReject, common.SchemaStatementPolicyOriginOnly, common.SchemaStatementPolicyReject)
+	require.NotNil(t, policies)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected RequestInfo
+	}{
+		{"create table", "CREATE TABLE ks.tb (a int PRIMARY KEY)", nil},
+		{"drop index", "DROP INDEX IF EXISTS ks.idx", nil},
+		{"create index", "CREATE INDEX IF NOT EXISTS idx ON ks.tb (b)",
+			NewSchemaStatementPolicyRequestInfo("ZDM_INDEX_STATEMENTS_POLICY")},
+		{"create index lower case", "create index on ks.tb (b)",
+			NewSchemaStatementPolicyRequestInfo("ZDM_INDEX_STATEMENTS_POLICY")},
+		{"create index using", "CREATE INDEX idx ON ks.tb (b) USING 'sai'",
+			NewRejectedRequestInfo("Custom index statements are rejected by ZDM proxy " +
+				"(ZDM_CUSTOM_INDEX_STATEMENTS_POLICY is REJECT)")},
+		{"create custom index", "CREATE CUSTOM INDEX idx ON ks.tb (b) USING 'org.apache.cassandra.index.sasi.SASIIndex'",
+			NewRejectedRequestInfo("Custom index statements are rejected by ZDM proxy " +
+				"(ZDM_CUSTOM_INDEX_STATEMENTS_POLICY is REJECT)")},
+		{"create materialized view", "CREATE MATERIALIZED VIEW ks.mv AS SELECT * FROM ks.tb WHERE a IS NOT NULL PRIMARY KEY (a)",
+			NewRejectedRequestInfo("Materialized view statements are rejected by ZDM proxy " +
+				"(ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY is REJECT)")},
+		{"drop materialized view", "/* cleanup */ DROP MATERIALIZED VIEW ks.mv",
+			NewRejectedRequestInfo("Materialized view statements are rejected by ZDM proxy " +
+				"(ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY is REJECT)")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, policies.getRequestInfo(tt.query))
+		})
+	}
+}
+
+func TestGetRequestInfoFromQueryInfo_SchemaStatementPolicies(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	policies := newSchemaStatementPolicies(
+		common.SchemaStatementPolicyOriginOnly, common.SchemaStatementPolicyBoth, common.SchemaStatementPolicyBoth)
+
+	getRequestInfo := func(query string, policies *schemaStatementPolicies) RequestInfo {
+		return getRequestInfoFromQueryInfo(mockQueryFrame(t, query), common.ClusterTypeOrigin, false, true, false, false,
+			inspectCqlQuery(query, "ks", generator), policies, nil, nil)
+	}
+
+	createView := "CREATE MATERIALIZED VIEW mv AS SELECT * FROM tb WHERE a IS NOT NULL PRIMARY KEY (a)"
+	require.Equal(t, NewSchemaStatementPolicyRequestInfo("ZDM_MATERIALIZED_VIEW_STATEMENTS_POLICY"),
+		getRequestInfo(createView, policies))
+	require.Equal(t, NewGenericRequestInfo(forwardToBoth, false, true), getRequestInfo(createView, nil))
+	require.Equal(t, NewGenericRequestInfo(forwardToBoth, false, true), getRequestInfo("CREATE INDEX ON tb (b)", policies))
+	require.Equal(t, NewGenericRequestInfo(forwardToOrigin, true, true), getRequestInfo("SELECT * FROM mv", policies))
+}
@@ -0,0 +1,76 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"time"
+)
+
+// handleSecondaryWriteFailure handles a write that succeeded on the primary cluster but failed on the secondary
+// cluster when ZDM_SECONDARY_WRITE_FAILURE_MODE is LOG_AND_CONTINUE or QUEUE_FOR_REPLAY, the response of the primary
+// cluster is returned to the client. With QUEUE_FOR_REPLAY the write is added to the write journal if it is enabled
+// (ZDM_SECONDARY_WRITE_JOURNAL_TYPE), otherwise it is replayed by the async connector of the client connection.
+func (ch *ClientHandler) handleSecondaryWriteFailure(
+	reqCtx *requestContextImpl, secondaryCluster common.ClusterType, secondaryResponse *frame.RawFrame) {
+	ch.metricHandler.GetProxyMetrics().SecondaryWriteFailures.Add(1)
+
+	reason := "failed"
+	if errMsg, err := decodeError(secondaryResponse); err == nil && errMsg != nil {
+		reason = "failed with error code " + errMsg.GetErrorCode().String()
+	}
+	if ch.secondaryWriteFailureMode != common.SecondaryWriteFailureModeQueueForReplay {
+		ch.getLogger().Warnf("Write (%v) %v on %v, returning the %v response (ZDM_SECONDARY_WRITE_FAILURE_MODE is %v), "+
+			"the %v cluster might be missing this write.", reqCtx.request.Header.OpCode, reason, secondaryCluster,
+			ch.primaryCluster, ch.secondaryWriteFailureMode, secondaryCluster)
+		return
+	}
+
+	if ch.secondaryWriteJournal != nil {
+		entry := newWriteJournalEntry(
+			secondaryCluster, reqCtx.requestInfo, reqCtx.secondaryWrite, reqCtx.keyspace, reqCtx.startTime)
+		if ch.secondaryWriteJournal.add(entry) {
+			ch.getLogger().Warnf("Write (%v) %v on %v, returning the %v response and adding the write to the journal "+
+				"of the writes to replay on %v (ZDM_SECONDARY_WRITE_FAILURE_MODE is %v).", reqCtx.request.Header.OpCode,
+				reason, secondaryCluster, ch.primaryCluster, secondaryCluster, ch.secondaryWriteFailureMode)
+		}
+		return
+	}
+
+	ch.getLogger().Warnf("Write (%v) %v on %v, returning the %v response and replaying the write on %v in %vms "+
+		"(ZDM_SECONDARY_WRITE_FAILURE_MODE is %v).", reqCtx.request.Header.OpCode, reason, secondaryCluster,
+		ch.primaryCluster, secondaryCluster, ch.conf.SecondaryWriteReplayDelayMs, ch.secondaryWriteFailureMode)
+	ch.replaySecondaryWrite(reqCtx.requestInfo, reqCtx.secondaryWrite)
+}
+
+// replaySecondaryWrite sends the write to the secondary cluster again through the async connector after
+// ZDM_SECONDARY_WRITE_REPLAY_DELAY_MS, like the async writes it is retried if it fails and the request wait group
+// tracks it until it completes.
+func (ch *ClientHandler) replaySecondaryWrite(requestInfo RequestInfo, request *frame.RawFrame) {
+	if ch.asyncConnector == nil {
+		ch.getLogger().Warnf("Write (%v) can't be replayed because the async connector is not available.",
+			request.Header.OpCode)
+		ch.metricHandler.GetProxyMetrics().AsyncWritesFailed.Add(1)
+		return
+	}
+
+	write := &asyncWrite{
+		requestInfo:  requestInfo,
+		request:      request,
+		retriesLeft:  ch.conf.DualWriteAsyncMaxRetries,
+		proxyMetrics: ch.metricHandler.GetProxyMetrics(),
+	}
+	ch.clientHandlerRequestWaitGroup.Add(1)
+	go func() {
+		timer := time.NewTimer(time.Duration(ch.conf.SecondaryWriteReplayDelayMs) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-ch.clientHandlerContext.Done():
+			timer.Stop()
+			ch.asyncConnector.failAsyncWrite(write, "could not be replayed because the client connection was closed")
+			return
+		}
+		if !ch.asyncConnector.sendAsyncWrite(write) {
+			ch.asyncConnector.failAsyncWrite(write, "could not be replayed")
+		}
+	}()
+}
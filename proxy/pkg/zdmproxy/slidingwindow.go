@@ -0,0 +1,58 @@
+package zdmproxy
+
+import (
+	"sync/atomic"
+)
+
+// slidingWindow counts events in the slots of a sliding window, each slot has the same set of counters that are
+// identified by their index. The events are counted in the current slot without locks and the owner of the window
+// slides it periodically (e.g. every window duration / number of slots), which clears the oldest slot and makes it the
+// current slot. Events that are counted while the window slides may be counted in the slot that is being cleared.
+type slidingWindow struct {
+	counters int
+
+	// counters of the slots, the counters of slot i are values[i*counters:(i+1)*counters]
+	values      []int64
+	currentSlot int32
+}
+
+func newSlidingWindow(slots int, counters int) *slidingWindow {
+	return &slidingWindow{
+		counters: counters,
+		values:   make([]int64, slots*counters),
+	}
+}
+
+func (recv *slidingWindow) getSlots() int {
+	return len(recv.values) / recv.counters
+}
+
+// add adds delta to the provided counter of the current slot.
+func (recv *slidingWindow) add(counter int, delta int64) {
+	atomic.AddInt64(&recv.values[int(atomic.LoadInt32(&recv.currentSlot))*recv.counters+counter], delta)
+}
+
+// slide clears the oldest slot and makes it the current slot.
+func (recv *slidingWindow) slide() {
+	nextSlot := (atomic.LoadInt32(&recv.currentSlot) + 1) % int32(recv.getSlots())
+	for i := 0; i < recv.counters; i++ {
+		atomic.StoreInt64(&recv.values[int(nextSlot)*recv.counters+i], 0)
+	}
+	atomic.StoreInt32(&recv.currentSlot, nextSlot)
+}
+
+// sums returns the value of each counter over all the slots of the window.
+func (recv *slidingWindow) sums() []int64 {
+	sums := make([]int64, recv.counters)
+	for i := range recv.values {
+		sums[i%recv.counters] += atomic.LoadInt64(&recv.values[i])
+	}
+	return sums
+}
+
+// reset clears every slot of the window.
+func (recv *slidingWindow) reset() {
+	for i := range recv.values {
+		atomic.StoreInt64(&recv.values[i], 0)
+	}
+}
@@ -0,0 +1,32 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	window := newSlidingWindow(3, 2)
+	require.Equal(t, 3, window.getSlots())
+	require.Equal(t, []int64{0, 0}, window.sums())
+
+	window.add(0, 2)
+	window.add(1, 1)
+	window.slide()
+	window.add(0, 3)
+	require.Equal(t, []int64{5, 1}, window.sums())
+
+	// the first slot is cleared when the window slides over it
+	window.slide()
+	window.add(1, 4)
+	require.Equal(t, []int64{5, 5}, window.sums())
+	window.slide()
+	require.Equal(t, []int64{3, 4}, window.sums())
+	window.add(0, 1)
+	require.Equal(t, []int64{4, 4}, window.sums())
+
+	window.reset()
+	require.Equal(t, []int64{0, 0}, window.sums())
+	window.add(1, 1)
+	require.Equal(t, []int64{0, 1}, window.sums())
+}
@@ -0,0 +1,234 @@
+package zdmproxy
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// slowQueryLog logs the QUERY, PREPARE, EXECUTE and BATCH requests that take longer than
+// ZDM_SLOW_QUERY_LOG_THRESHOLD_MS as JSON lines with the obfuscated query (or only its digest), the keyspace and
+// table, the consistency level and the latency of each cluster. The slow queries are sampled
+// (ZDM_SLOW_QUERY_LOG_SAMPLE_RATIO) and at most ZDM_SLOW_QUERY_LOG_MAX_PER_SECOND are logged every second, the
+// proxy_slow_queries_total metric counts all of them.
+type slowQueryLog struct {
+	conf        *common.SlowQueryLogConfig // it can change at runtime, see setConfig
+	logger      *log.Logger
+	rand        *rand.Rand
+	slowQueries metrics.Counter
+
+	lock          *sync.Mutex
+	currentSecond int64
+	loggedQueries int   // slow queries logged during currentSecond
+	skipped       int64 // slow queries that were not logged since the last logged one
+}
+
+// newSlowQueryLog returns nil if the slow query log is disabled.
+func newSlowQueryLog(conf *common.SlowQueryLogConfig, slowQueries metrics.Counter) *slowQueryLog {
+	if !conf.Enabled {
+		return nil
+	}
+	logger := log.New()
+	logger.SetOutput(log.StandardLogger().Out)
+	logger.SetFormatter(&log.JSONFormatter{})
+	return &slowQueryLog{
+		conf:        conf,
+		logger:      logger,
+		rand:        NewThreadSafeRand(),
+		slowQueries: slowQueries,
+		lock:        &sync.Mutex{},
+	}
+}
+
+func (ch *ClientHandler) observeSlowQuery(reqCtx *requestContextImpl, response *frame.RawFrame) {
+	if ch.slowQueryLog == nil {
+		return
+	}
+	ch.slowQueryLog.observe(reqCtx, response, ch.clientConnector.connection.RemoteAddr().String())
+}
+
+// observe logs the request if it is slow, it is called when the response is sent to the client.
+func (recv *slowQueryLog) observe(
+	reqCtx *requestContextImpl, response *frame.RawFrame, clientAddress string) {
+	if recv == nil {
+		return
+	}
+	conf := recv.getConfig()
+	duration := time.Since(reqCtx.startTime)
+	if duration < time.Duration(conf.ThresholdMs)*time.Millisecond {
+		return
+	}
+	switch reqCtx.request.Header.OpCode {
+	case primitive.OpCodeQuery, primitive.OpCodePrepare, primitive.OpCodeExecute, primitive.OpCodeBatch:
+	default:
+		return
+	}
+
+	recv.slowQueries.Add(1)
+	skipped, ok := recv.sample(time.Now())
+	if !ok {
+		return
+	}
+
+	fields := log.Fields{
+		"duration_ms":      toMilliseconds(duration),
+		"threshold_ms":     conf.ThresholdMs,
+		"client":           clientAddress,
+		"stream":           reqCtx.request.Header.StreamId,
+		"opcode":           protocolConstantName(reqCtx.request.Header.OpCode),
+		"forward_decision": string(reqCtx.requestInfo.GetForwardDecision()),
+	}
+	originLatency, targetLatency := reqCtx.getClusterLatencies()
+	if originLatency > 0 {
+		fields["origin_latency_ms"] = toMilliseconds(originLatency)
+	}
+	if targetLatency > 0 {
+		fields["target_latency_ms"] = toMilliseconds(targetLatency)
+	}
+	if response != nil {
+		fields["response"] = describeSlowQueryResponse(response)
+	}
+	if skipped > 0 {
+		fields["skipped"] = skipped
+	}
+	addQueryFields(fields, reqCtx.request, reqCtx.requestInfo, reqCtx.keyspace, conf.QueryFormat)
+	recv.logger.WithFields(fields).Warn("Slow query")
+}
+
+func (recv *slowQueryLog) getConfig() *common.SlowQueryLogConfig {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.conf
+}
+
+// setConfig changes the threshold, the query format and the sampling of the slow query log, conf must be enabled.
+func (recv *slowQueryLog) setConfig(conf *common.SlowQueryLogConfig) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.conf = conf
+}
+
+// sample returns true if the slow query should be logged and the number of slow queries that were not logged since
+// the last logged one.
+func (recv *slowQueryLog) sample(now time.Time) (int64, bool) {
+	conf := recv.getConfig()
+	if conf.SampleRatio < 1 && recv.rand.Float64() >= conf.SampleRatio {
+		recv.lock.Lock()
+		recv.skipped++
+		recv.lock.Unlock()
+		return 0, false
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	second := now.Unix()
+	if second != recv.currentSecond {
+		recv.currentSecond = second
+		recv.loggedQueries = 0
+	}
+	if recv.loggedQueries >= conf.MaxPerSecond {
+		recv.skipped++
+		return 0, false
+	}
+	recv.loggedQueries++
+	skipped := recv.skipped
+	recv.skipped = 0
+	return skipped, true
+}
+
+// addQueryFields adds the query (in queryFormat), keyspace, table and consistency level of the request, they are
+// omitted if the request can't be decoded (i.e. compressed frames). The slow query log and the audit log use it.
+func addQueryFields(
+	fields log.Fields, request *frame.RawFrame, requestInfo RequestInfo, keyspace string,
+	queryFormat common.SlowQueryFormat) {
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(request)
+	if err != nil {
+		return
+	}
+
+	var queries []string
+	var consistency *primitive.ConsistencyLevel
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		queries = []string{msg.Query}
+		if msg.Options != nil {
+			consistency = &msg.Options.Consistency
+		}
+	case *message.Prepare:
+		queries = []string{msg.Query}
+	case *message.Execute:
+		if executeRequestInfo, ok := unwrapRequestInfo(requestInfo).(*ExecuteRequestInfo); ok {
+			// the keyspace of the connection may have changed since the statement was prepared
+			prepareRequestInfo := executeRequestInfo.GetPreparedData().GetPrepareRequestInfo()
+			queries = []string{prepareRequestInfo.GetQuery()}
+			keyspace = prepareRequestInfo.GetKeyspace()
+		}
+		if msg.Options != nil {
+			consistency = &msg.Options.Consistency
+		}
+	case *message.Batch:
+		var preparedDataByStmtIdx map[int]PreparedData
+		if batchRequestInfo, ok := unwrapRequestInfo(requestInfo).(*BatchRequestInfo); ok {
+			preparedDataByStmtIdx = batchRequestInfo.GetPreparedDataByStmtIdx()
+		}
+		for idx, child := range msg.Children {
+			switch queryOrId := child.QueryOrId.(type) {
+			case string:
+				queries = append(queries, queryOrId)
+			case []byte:
+				if preparedData, ok := preparedDataByStmtIdx[idx]; ok {
+					queries = append(queries, preparedData.GetPrepareRequestInfo().GetQuery())
+				}
+			}
+		}
+		fields["statements"] = len(msg.Children)
+		consistency = &msg.Consistency
+	}
+
+	if consistency != nil {
+		fields["consistency"] = protocolConstantName(*consistency)
+	}
+	if len(queries) == 0 {
+		return
+	}
+
+	queryInfo := inspectCqlQuery(queries[0], keyspace, nil)
+	if applicableKeyspace := queryInfo.getApplicableKeyspace(); applicableKeyspace != "" {
+		fields["keyspace"] = applicableKeyspace
+	}
+	if table := queryInfo.getTableName(); table != "" {
+		fields["table"] = table
+	}
+
+	obfuscatedQueries := make([]string, 0, len(queries))
+	for _, query := range queries {
+		obfuscatedQueries = append(obfuscatedQueries, maskCqlLiterals(query))
+	}
+	obfuscatedQuery := strings.Join(obfuscatedQueries, "; ")
+	digest := md5.Sum([]byte(obfuscatedQuery))
+	fields["query_digest"] = hex.EncodeToString(digest[:])
+	if queryFormat == common.SlowQueryFormatObfuscated {
+		fields["query"] = obfuscatedQuery
+	}
+}
+
+func describeSlowQueryResponse(response *frame.RawFrame) string {
+	errMsg, err := decodeError(response)
+	if err == nil && errMsg != nil {
+		return protocolConstantName(errMsg.GetErrorCode())
+	}
+	return protocolConstantName(response.Header.OpCode)
+}
+
+func toMilliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
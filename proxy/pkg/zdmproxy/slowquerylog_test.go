@@ -0,0 +1,152 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryLog_Sample(t *testing.T) {
+	slowQueryLog := newSlowQueryLog(&common.SlowQueryLogConfig{
+		Enabled: true, ThresholdMs: 1, SampleRatio: 1, MaxPerSecond: 2}, newFakeCounter())
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		skipped, ok := slowQueryLog.sample(now)
+		require.True(t, ok)
+		require.Equal(t, int64(0), skipped)
+	}
+	for i := 0; i < 3; i++ {
+		_, ok := slowQueryLog.sample(now)
+		require.False(t, ok)
+	}
+
+	// the skipped slow queries are reported by the next logged one
+	skipped, ok := slowQueryLog.sample(now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, int64(3), skipped)
+	skipped, ok = slowQueryLog.sample(now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, int64(0), skipped)
+
+	slowQueryLog.conf.SampleRatio = 0.000001
+	_, ok = slowQueryLog.sample(now.Add(2 * time.Second))
+	require.False(t, ok)
+}
+
+func TestSlowQueryLog_Observe(t *testing.T) {
+	require.Nil(t, newSlowQueryLog(&common.SlowQueryLogConfig{}, newFakeCounter()))
+
+	type test struct {
+		name           string
+		queryFormat    common.SlowQueryFormat
+		message        message.Message
+		requestInfo    RequestInfo
+		expectedFields map[string]interface{}
+	}
+	prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false,
+		"INSERT INTO tb (a, b) VALUES (?, 'x')", "ks1")
+	preparedData := NewPreparedData(&message.PreparedResult{}, &message.PreparedResult{}, prepareRequestInfo)
+	tests := []test{
+		{
+			name:        "query",
+			queryFormat: common.SlowQueryFormatObfuscated,
+			message: &message.Query{
+				Query:   "SELECT * FROM tb WHERE a = 'secret'",
+				Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalQuorum}},
+			requestInfo: NewGenericRequestInfo(forwardToOrigin, false, true),
+			expectedFields: map[string]interface{}{
+				"opcode":           "QUERY",
+				"forward_decision": "origin",
+				"keyspace":         "ks",
+				"table":            "tb",
+				"consistency":      "LOCAL_QUORUM",
+				"query":            "SELECT * FROM tb WHERE a = ?",
+				"response":         "RESULT",
+			},
+		},
+		{
+			name:        "execute",
+			queryFormat: common.SlowQueryFormatDigest,
+			message: &message.Execute{
+				QueryId: []byte{1}, Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne}},
+			requestInfo: NewExecuteRequestInfo(preparedData),
+			expectedFields: map[string]interface{}{
+				"opcode":      "EXECUTE",
+				"keyspace":    "ks1",
+				"table":       "tb",
+				"consistency": "ONE",
+				"query":       nil,
+			},
+		},
+		{
+			name:        "batch",
+			queryFormat: common.SlowQueryFormatObfuscated,
+			message: &message.Batch{
+				Children: []*message.BatchChild{
+					{QueryOrId: "INSERT INTO ks2.tb2 (a) VALUES (1)"},
+					{QueryOrId: []byte{1}},
+				},
+				Consistency: primitive.ConsistencyLevelQuorum},
+			requestInfo: NewBatchRequestInfo(map[int]PreparedData{1: preparedData}, forwardToBoth),
+			expectedFields: map[string]interface{}{
+				"opcode":      "BATCH",
+				"keyspace":    "ks2",
+				"table":       "tb2",
+				"statements":  float64(2),
+				"consistency": "QUORUM",
+				"query":       "INSERT INTO ks2.tb2 (a) VALUES (?); INSERT INTO tb (a, b) VALUES (?, ?)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slowQueryLog := newSlowQueryLog(&common.SlowQueryLogConfig{
+				Enabled: true, ThresholdMs: 10, QueryFormat: tt.queryFormat, SampleRatio: 1, MaxPerSecond: 10},
+				newFakeCounter())
+			output := &bytes.Buffer{}
+			slowQueryLog.logger.SetOutput(output)
+
+			request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 5, tt.message))
+			require.Nil(t, err)
+			response, err := defaultCodec.ConvertToRawFrame(
+				frame.NewFrame(primitive.ProtocolVersion4, 5, &message.VoidResult{}))
+			require.Nil(t, err)
+
+			// fast requests are not logged
+			reqCtx := NewRequestContext(request, tt.requestInfo, time.Now(), nil)
+			reqCtx.keyspace = "ks"
+			slowQueryLog.observe(reqCtx, response, "127.0.0.1:9042")
+			require.Equal(t, 0, output.Len())
+
+			reqCtx.startTime = time.Now().Add(-20 * time.Millisecond)
+			reqCtx.updateInternalState(response, common.ClusterTypeOrigin)
+			slowQueryLog.observe(reqCtx, response, "127.0.0.1:9042")
+			var fields map[string]interface{}
+			require.Nil(t, json.Unmarshal(output.Bytes(), &fields), output.String())
+			require.Equal(t, "Slow query", fields["msg"])
+			require.Equal(t, "warning", fields["level"])
+			require.Equal(t, "127.0.0.1:9042", fields["client"])
+			require.Equal(t, float64(5), fields["stream"])
+			require.Equal(t, float64(10), fields["threshold_ms"])
+			require.GreaterOrEqual(t, fields["duration_ms"], float64(20))
+			require.GreaterOrEqual(t, fields["origin_latency_ms"], float64(20))
+			require.NotContains(t, fields, "target_latency_ms")
+			require.Equal(t, 32, len(fields["query_digest"].(string)))
+			for key, value := range tt.expectedFields {
+				if value == nil {
+					require.NotContains(t, fields, key)
+				} else {
+					require.Equal(t, value, fields[key], key)
+				}
+			}
+			require.False(t, strings.Contains(output.String(), "secret"))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"net"
+)
+
+// applySocketOptions sets the socket options of a TCP connection, other connections (e.g. the in-memory connections
+// of the tests) are left untouched.
+func applySocketOptions(conn net.Conn, options *common.SocketOptions) error {
+	if options == nil {
+		return nil
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+
+	err := tcpConn.SetNoDelay(options.NoDelay)
+	if err != nil {
+		return fmt.Errorf("could not set TCP_NODELAY to %v: %w", options.NoDelay, err)
+	}
+	if options.ReceiveBufferSizeBytes > 0 {
+		err = tcpConn.SetReadBuffer(options.ReceiveBufferSizeBytes)
+		if err != nil {
+			return fmt.Errorf("could not set SO_RCVBUF to %v: %w", options.ReceiveBufferSizeBytes, err)
+		}
+	}
+	if options.SendBufferSizeBytes > 0 {
+		err = tcpConn.SetWriteBuffer(options.SendBufferSizeBytes)
+		if err != nil {
+			return fmt.Errorf("could not set SO_SNDBUF to %v: %w", options.SendBufferSizeBytes, err)
+		}
+	}
+	return nil
+}
+
+// socketOptionsListener sets the socket options of the accepted client connections, it wraps the TCP listener so
+// that the options are set before the TLS handshake.
+type socketOptionsListener struct {
+	net.Listener
+	options *common.SocketOptions
+}
+
+func newSocketOptionsListener(l net.Listener, options *common.SocketOptions) net.Listener {
+	return &socketOptionsListener{
+		Listener: l,
+		options:  options,
+	}
+}
+
+func (recv *socketOptionsListener) Accept() (net.Conn, error) {
+	conn, err := recv.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	err = applySocketOptions(conn, recv.options)
+	if err != nil {
+		log.Warnf("Could not set the socket options of the client connection from %v: %v", conn.RemoteAddr(), err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package zdmproxy
+
+import (
+	"context"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func getSocketOption(t *testing.T, conn net.Conn, level int, option int) int {
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	require.Nil(t, err)
+	var value int
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		value, sockErr = syscall.GetsockoptInt(int(fd), level, option)
+	})
+	require.Nil(t, err)
+	require.Nil(t, sockErr)
+	return value
+}
+
+func TestSocketOptions(t *testing.T) {
+	options := &common.SocketOptions{NoDelay: false, ReceiveBufferSizeBytes: 65536, SendBufferSizeBytes: 131072}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	l = newSocketOptionsListener(l, options)
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	clientConn, err := newClusterDialer(0, options).DialContext(context.Background(), l.Addr().String())
+	require.Nil(t, err)
+	defer clientConn.Close()
+
+	var serverConn net.Conn
+	select {
+	case serverConn = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the client connection")
+	}
+	defer serverConn.Close()
+
+	for _, conn := range []net.Conn{clientConn, serverConn} {
+		require.Equal(t, 0, getSocketOption(t, conn, syscall.IPPROTO_TCP, syscall.TCP_NODELAY))
+		// the kernel doubles the requested buffer sizes
+		require.GreaterOrEqual(t, getSocketOption(t, conn, syscall.SOL_SOCKET, syscall.SO_RCVBUF), 65536)
+		require.GreaterOrEqual(t, getSocketOption(t, conn, syscall.SOL_SOCKET, syscall.SO_SNDBUF), 131072)
+	}
+
+	require.Nil(t, applySocketOptions(clientConn, &common.SocketOptions{NoDelay: true}))
+	require.Equal(t, 1, getSocketOption(t, clientConn, syscall.IPPROTO_TCP, syscall.TCP_NODELAY))
+
+	// other connections are left untouched
+	pipeClient, pipeServer := net.Pipe()
+	defer pipeClient.Close()
+	defer pipeServer.Close()
+	require.Nil(t, applySocketOptions(pipeClient, options))
+}
@@ -0,0 +1,212 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stallWatchdogChecksPerThreshold is the number of times the components are checked during a stall detection
+// threshold.
+const stallWatchdogChecksPerThreshold = 4
+
+// stallWatchdogMaxStackDumpBytes is the maximum size of the goroutine stacks that are logged with the diagnostics.
+const stallWatchdogMaxStackDumpBytes = 64 * 1024 * 1024
+
+// stallWatchdog detects the internal queues (write queues of the connections and queues of the worker pools) that
+// stop making progress while they have pending items or that stay full for longer than the threshold. When a stall
+// is detected, the goroutine stacks and the main gauges are logged so that a hang is debuggable after the fact, at
+// most once every DiagnosticsIntervalMs to avoid flooding the log while the proxy stays stalled.
+type stallWatchdog struct {
+	conf          *common.StallDetectionConfig
+	activeClients *int32
+
+	lock   *sync.Mutex
+	probes map[*stallProbe]bool
+
+	// only accessed by the goroutine that checks the probes
+	lastDiagnostics time.Time
+
+	now func() time.Time
+}
+
+// stallProbe is a component that is checked by the watchdog.
+type stallProbe struct {
+	name string
+
+	// queue returns the number of pending items and the capacity of the queue of the component
+	queue func() (int, int)
+
+	// progress returns a counter that the component increments every time it takes an item from its queue
+	progress func() int64
+
+	// only accessed by the goroutine that checks the probes
+	observedProgress int64
+	pendingSince     time.Time
+	fullSince        time.Time
+}
+
+// newStallWatchdog returns nil if the stall detection is disabled.
+func newStallWatchdog(conf *common.StallDetectionConfig, activeClients *int32) *stallWatchdog {
+	if !conf.Enabled {
+		return nil
+	}
+	return &stallWatchdog{
+		conf:          conf,
+		activeClients: activeClients,
+		lock:          &sync.Mutex{},
+		probes:        make(map[*stallProbe]bool),
+		now:           time.Now,
+	}
+}
+
+// register starts checking a component, it returns nil if the stall detection is disabled.
+func (recv *stallWatchdog) register(name string, queue func() (int, int), progress func() int64) *stallProbe {
+	if recv == nil {
+		return nil
+	}
+	probe := &stallProbe{name: name, queue: queue, progress: progress}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.probes[probe] = true
+	return probe
+}
+
+func (recv *stallWatchdog) unregister(probe *stallProbe) {
+	if recv == nil || probe == nil {
+		return
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	delete(recv.probes, probe)
+}
+
+// registerScheduler starts checking the queue of the tasks of a worker pool.
+func (recv *stallWatchdog) registerScheduler(name string, scheduler *Scheduler) *stallProbe {
+	return recv.register(
+		name,
+		func() (int, int) { return len(scheduler.queue), cap(scheduler.queue) },
+		func() int64 { return atomic.LoadInt64(&scheduler.dequeuedTasks) })
+}
+
+// run checks the components several times per threshold until the context is canceled.
+func (recv *stallWatchdog) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(recv.conf.ThresholdMs) * time.Millisecond / stallWatchdogChecksPerThreshold)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recv.check()
+		}
+	}
+}
+
+// check logs the diagnostics if a component is stalled and if the diagnostics were not logged during the last
+// DiagnosticsIntervalMs.
+func (recv *stallWatchdog) check() {
+	now := recv.now()
+	threshold := time.Duration(recv.conf.ThresholdMs) * time.Millisecond
+
+	probes := recv.getProbes()
+	var stalls []string
+	for _, probe := range probes {
+		pending, capacity := probe.queue()
+		progress := probe.progress()
+
+		if pending == 0 {
+			probe.pendingSince = time.Time{}
+		} else if probe.pendingSince.IsZero() || progress != probe.observedProgress {
+			probe.pendingSince = now
+		} else if stalledFor := now.Sub(probe.pendingSince); stalledFor >= threshold {
+			stalls = append(stalls, fmt.Sprintf(
+				"%v has %v pending items and made no progress for %v", probe.name, pending, stalledFor))
+		}
+		probe.observedProgress = progress
+
+		if capacity == 0 || pending < capacity {
+			probe.fullSince = time.Time{}
+		} else if probe.fullSince.IsZero() {
+			probe.fullSince = now
+		} else if fullFor := now.Sub(probe.fullSince); fullFor >= threshold {
+			stalls = append(stalls, fmt.Sprintf("%v has been full (%v items) for %v", probe.name, capacity, fullFor))
+		}
+	}
+
+	if len(stalls) == 0 {
+		return
+	}
+	interval := time.Duration(recv.conf.DiagnosticsIntervalMs) * time.Millisecond
+	if !recv.lastDiagnostics.IsZero() && now.Sub(recv.lastDiagnostics) < interval {
+		log.Debugf("Stalled components detected, diagnostics were already logged at %v: %v.",
+			recv.lastDiagnostics, strings.Join(stalls, "; "))
+		return
+	}
+	recv.lastDiagnostics = now
+	log.Errorf("Stalled components detected, logging diagnostics (they will be logged again in %v at the earliest):\n%v",
+		interval, recv.diagnostics(stalls, probes))
+}
+
+func (recv *stallWatchdog) getProbes() []*stallProbe {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	probes := make([]*stallProbe, 0, len(recv.probes))
+	for probe := range recv.probes {
+		probes = append(probes, probe)
+	}
+	sort.Slice(probes, func(i, j int) bool {
+		return probes[i].name < probes[j].name
+	})
+	return probes
+}
+
+func (recv *stallWatchdog) diagnostics(stalls []string, probes []*stallProbe) string {
+	sb := &strings.Builder{}
+	sb.WriteString("Stalls:\n")
+	for _, stall := range stalls {
+		sb.WriteString(fmt.Sprintf("  %v\n", stall))
+	}
+
+	memStats := &runtime.MemStats{}
+	runtime.ReadMemStats(memStats)
+	sb.WriteString("Gauges:\n")
+	sb.WriteString(fmt.Sprintf("  goroutines: %v\n", runtime.NumGoroutine()))
+	sb.WriteString(fmt.Sprintf("  active client connections: %v\n", atomic.LoadInt32(recv.activeClients)))
+	sb.WriteString(fmt.Sprintf("  heap in use: %v bytes\n", memStats.HeapInuse))
+	sb.WriteString(fmt.Sprintf("  memory obtained from the OS: %v bytes\n", memStats.Sys))
+	sb.WriteString(fmt.Sprintf("  garbage collections: %v\n", memStats.NumGC))
+	sb.WriteString(fmt.Sprintf("  monitored queues: %v\n", len(probes)))
+
+	sb.WriteString("Queues with pending items:\n")
+	for _, probe := range probes {
+		pending, capacity := probe.queue()
+		if pending > 0 {
+			sb.WriteString(fmt.Sprintf("  %v: %v/%v\n", probe.name, pending, capacity))
+		}
+	}
+
+	sb.WriteString("Goroutines:\n")
+	sb.Write(dumpGoroutineStacks())
+	return sb.String()
+}
+
+// dumpGoroutineStacks returns the stacks of all goroutines, truncated to stallWatchdogMaxStackDumpBytes.
+func dumpGoroutineStacks() []byte {
+	buf := make([]byte, 1024*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= stallWatchdogMaxStackDumpBytes {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,103 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStallWatchdog(t *testing.T) {
+	var disabledWatchdog *stallWatchdog
+	disabledWatchdog.unregister(disabledWatchdog.register("disabled", nil, nil))
+	require.Nil(t, newStallWatchdog(&common.StallDetectionConfig{Enabled: false}, nil))
+
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	now := time.Now()
+	activeClients := int32(3)
+	watchdog := newStallWatchdog(
+		&common.StallDetectionConfig{Enabled: true, ThresholdMs: 1000, DiagnosticsIntervalMs: 60000}, &activeClients)
+	watchdog.now = func() time.Time { return now }
+	check := func(elapsed time.Duration) string {
+		now = now.Add(elapsed)
+		logs.Reset()
+		watchdog.check()
+		return logs.String()
+	}
+
+	pending, capacity, progress := 0, 10, int64(0)
+	probe := watchdog.register(
+		"test queue",
+		func() (int, int) { return pending, capacity },
+		func() int64 { return progress })
+
+	// idle queue
+	require.Empty(t, check(0))
+	require.Empty(t, check(time.Hour))
+
+	// the queue makes progress
+	pending = 2
+	require.Empty(t, check(0))
+	for i := 0; i < 4; i++ {
+		progress++
+		require.Empty(t, check(500*time.Millisecond))
+	}
+
+	// the queue stops making progress
+	require.Empty(t, check(500*time.Millisecond))
+	output := check(500 * time.Millisecond)
+	require.Contains(t, output, "Stalled components detected")
+	require.Contains(t, output, "test queue has 2 pending items and made no progress for 1s")
+	require.Contains(t, output, "active client connections: 3")
+	require.Contains(t, output, "test queue: 2/10")
+	require.Contains(t, output, "goroutine ")
+	require.Contains(t, output, "TestStallWatchdog")
+
+	// the diagnostics are rate limited
+	require.NotContains(t, check(time.Second), "Stalled components detected")
+	require.Contains(t, check(time.Minute), "made no progress for 1m2s")
+
+	// the queue stays full while making progress
+	pending = capacity
+	progress++
+	require.Empty(t, check(0))
+	progress++
+	require.Empty(t, check(999*time.Millisecond))
+	progress++
+	watchdog.lastDiagnostics = time.Time{}
+	output = check(time.Millisecond)
+	require.Contains(t, output, "test queue has been full (10 items) for 1s")
+	require.NotContains(t, output, "made no progress")
+
+	watchdog.unregister(probe)
+	watchdog.lastDiagnostics = time.Time{}
+	require.Empty(t, check(time.Hour))
+}
+
+func TestStallWatchdog_Scheduler(t *testing.T) {
+	activeClients := int32(0)
+	watchdog := newStallWatchdog(
+		&common.StallDetectionConfig{Enabled: true, ThresholdMs: 1000, DiagnosticsIntervalMs: 60000}, &activeClients)
+	scheduler := NewScheduler(1)
+	defer scheduler.Shutdown()
+	probe := watchdog.registerScheduler("worker pool", scheduler)
+
+	blocked := make(chan bool)
+	done := make(chan bool)
+	scheduler.Schedule(func() { <-blocked })
+	scheduler.Schedule(func() { close(done) })
+	require.Eventually(t, func() bool { return probe.progress() == 1 }, time.Second, 10*time.Millisecond)
+	pending, capacity := probe.queue()
+	require.Equal(t, 1, pending)
+	require.Equal(t, 1, capacity)
+
+	close(blocked)
+	<-done
+	require.Equal(t, int64(2), probe.progress())
+}
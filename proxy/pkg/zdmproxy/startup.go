@@ -24,47 +24,54 @@ func (recv *AuthError) Error() string {
 }
 
 func (ch *ClientHandler) handleSecondaryHandshakeStartup(
-	startupRequest *frame.RawFrame, startupResponse *frame.RawFrame, asyncConnector bool) error {
+	startupRequest *frame.RawFrame, startupResponse *frame.RawFrame, streamId int16, asyncConnector bool) error {
+	if asyncConnector {
+		return ch.handleClusterHandshakeStartup(
+			startupRequest, startupResponse, streamId, ch.asyncConnector.clusterType, true, ch.asyncHandshakeCreds)
+	}
+	secondaryCluster := common.ClusterTypeTarget
+	if ch.forwardAuthToTarget {
+		secondaryCluster = common.ClusterTypeOrigin
+	}
+	return ch.handleClusterHandshakeStartup(
+		startupRequest, startupResponse, streamId, secondaryCluster, false, ch.secondaryHandshakeCreds)
+}
+
+// handleClusterHandshakeStartup performs the handshake of a cluster connection with the provided credentials, the
+// STARTUP request was already sent to the cluster unless it is the async connector.
+func (ch *ClientHandler) handleClusterHandshakeStartup(
+	startupRequest *frame.RawFrame, startupResponse *frame.RawFrame, streamId int16,
+	clusterType common.ClusterType, asyncConnector bool, creds *AuthCredentials) error {
 
 	// extracting these into variables for convenience
 	clientIPAddress := ch.clientConnector.connection.RemoteAddr()
 	var clusterAddress net.Addr
 	var logIdentifier string
 	var forwardToSecondary forwardDecision
-	requestTimeout := time.Duration(ch.conf.ProxyRequestTimeoutMs) * time.Millisecond
+	timeout := ch.requestTimeoutPolicy.clusterTimeout()
 	if asyncConnector {
 		clusterAddress = ch.asyncConnector.connection.RemoteAddr()
 		logIdentifier = fmt.Sprintf("ASYNC-%v", ch.asyncConnector.clusterType)
 		forwardToSecondary = forwardToAsyncOnly
-		requestTimeout = time.Duration(ch.conf.AsyncHandshakeTimeoutMs) * time.Millisecond
-	} else if ch.forwardAuthToTarget {
-		// secondary is ORIGIN
-
+		timeout = newUniformRequestTimeout(time.Duration(ch.conf.AsyncHandshakeTimeoutMs) * time.Millisecond)
+	} else if clusterType == common.ClusterTypeOrigin {
 		clusterAddress = ch.originCassandraConnector.connection.RemoteAddr()
 		logIdentifier = "ORIGIN"
 		forwardToSecondary = forwardToOrigin
 	} else {
-		// secondary is TARGET
-
 		clusterAddress = ch.targetCassandraConnector.connection.RemoteAddr()
 		logIdentifier = "TARGET"
 		forwardToSecondary = forwardToTarget
 	}
 
-	log.Infof("Initiating startup between %v and %v (%v)", clientIPAddress, clusterAddress, logIdentifier)
+	ch.getLogger().Infof("Initiating startup between %v and %v (%v)", clientIPAddress, clusterAddress, logIdentifier)
 	phase := 1
 	attempts := 0
 
-	var authenticator *DsePlainTextAuthenticator
-	if asyncConnector {
-		if ch.asyncHandshakeCreds != nil {
-			authenticator = &DsePlainTextAuthenticator{
-				Credentials: ch.asyncHandshakeCreds,
-			}
-		}
-	} else if ch.secondaryHandshakeCreds != nil {
+	var authenticator handshakeAuthenticator
+	if creds != nil {
 		authenticator = &DsePlainTextAuthenticator{
-			Credentials: ch.secondaryHandshakeCreds,
+			Credentials: creds,
 		}
 	}
 
@@ -95,7 +102,7 @@ func (ch *ClientHandler) handleSecondaryHandshakeStartup(
 			var err error
 			var parsedRequest *frame.Frame
 			parsedRequest, err = performHandshakeStep(
-				authenticator, startupRequest.Header.Version, startupRequest.Header.StreamId, lastResponse)
+				authenticator, startupRequest.Header.Version, streamId, lastResponse)
 			if err != nil {
 				return fmt.Errorf("could not perform handshake step: %w", err)
 			}
@@ -116,7 +123,9 @@ func (ch *ClientHandler) handleSecondaryHandshakeStartup(
 				ch.LoadCurrentKeyspace(),
 				overallRequestStartTime,
 				channel,
-				requestTimeout)
+				timeout,
+				nil,
+				nil)
 
 			if err != nil {
 				return fmt.Errorf("unable to send secondary (%v) handshake frame to %v: %w", logIdentifier, clusterAddress, err)
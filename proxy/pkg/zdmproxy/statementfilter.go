@@ -0,0 +1,132 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"strings"
+)
+
+// statementFilter rejects the statements that match a rule of ZDM_STATEMENT_FILTER_RULES or forwards them to
+// a single cluster, e.g. to block TRUNCATE and DROP statements during the migration window. Like the table routing
+// rules, the rules are applied when a statement is inspected so a prepared statement keeps the decision of its
+// PREPARE request and the statements that are rejected can't be prepared.
+type statementFilter struct {
+	rules []*common.StatementFilterRule
+}
+
+// newStatementFilter returns nil if there are no rules.
+func newStatementFilter(rules []*common.StatementFilterRule) *statementFilter {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &statementFilter{rules: rules}
+}
+
+// getRequestInfo returns nil if no rule matches the statement.
+func (recv *statementFilter) getRequestInfo(queryInfo QueryInfo) RequestInfo {
+	rule := recv.match(queryInfo)
+	if rule == nil {
+		return nil
+	}
+	if rule.Action == common.StatementFilterActionReject {
+		return NewRejectedRequestInfo(fmt.Sprintf(
+			"Statement rejected by ZDM proxy (ZDM_STATEMENT_FILTER_RULES: %v)", rule.Definition))
+	}
+	return NewStatementFilterRequestInfo(rule, queryInfo.getStatementType() == statementTypeSelect)
+}
+
+// match returns the first rule that matches the statement and nil if there is none.
+func (recv *statementFilter) match(queryInfo QueryInfo) *common.StatementFilterRule {
+	if recv == nil {
+		return nil
+	}
+
+	var target *statementFilterTarget // only computed if there is a rule that needs it
+	for _, rule := range recv.rules {
+		if rule.Pattern != nil {
+			if rule.Pattern.MatchString(queryInfo.getQuery()) {
+				return rule
+			}
+			continue
+		}
+		if target == nil {
+			target = getStatementFilterTarget(queryInfo)
+		}
+		if (rule.Statement == "*" || rule.Statement == target.statement) &&
+			(rule.Keyspace == "" || rule.Keyspace == target.keyspace) &&
+			(rule.Table == "" || rule.Table == target.table) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// statementFilterTarget is the kind of a statement and the keyspace and table that it applies to.
+type statementFilterTarget struct {
+	statement string
+	keyspace  string
+	table     string
+}
+
+// getStatementFilterTarget only finds the keyspace and table of the schema statements for TRUNCATE statements and
+// for the statements on keyspaces and tables, e.g. they are empty for DROP INDEX statements.
+func getStatementFilterTarget(queryInfo QueryInfo) *statementFilterTarget {
+	switch statementType := queryInfo.getStatementType(); statementType {
+	case statementTypeSelect, statementTypeInsert, statementTypeUpdate, statementTypeDelete:
+		return &statementFilterTarget{
+			statement: strings.ToUpper(string(statementType)),
+			keyspace:  queryInfo.getApplicableKeyspace(),
+			table:     queryInfo.getTableName(),
+		}
+	case statementTypeBatch:
+		return &statementFilterTarget{statement: strings.ToUpper(string(statementType))}
+	}
+
+	tokens := tokenizeDdl(queryInfo.getQuery())
+	if len(tokens) == 0 {
+		return &statementFilterTarget{}
+	}
+	target := &statementFilterTarget{statement: tokens[0].text}
+	names := tokens[1:]
+	isTable := false
+	switch target.statement {
+	case "TRUNCATE":
+		if len(names) > 0 && (names[0].text == "TABLE" || names[0].text == "COLUMNFAMILY") {
+			names = names[1:]
+		}
+		isTable = true
+	case "CREATE", "ALTER", "DROP":
+		if len(names) == 0 {
+			return target
+		}
+		switch names[0].text {
+		case "TABLE", "COLUMNFAMILY":
+			isTable = true
+		case "KEYSPACE", "SCHEMA":
+		default:
+			return target
+		}
+		names = names[1:]
+		if len(names) > 0 && names[0].text == "IF" { // IF [NOT] EXISTS
+			for len(names) > 0 && (names[0].text == "IF" || names[0].text == "NOT" || names[0].text == "EXISTS") {
+				names = names[1:]
+			}
+		}
+	default:
+		return target
+	}
+
+	if len(names) == 0 || names[0].literal {
+		return target
+	}
+	if !isTable {
+		target.keyspace = adminIdentifier(names[0])
+	} else if len(names) >= 3 && names[1].text == "." {
+		target.keyspace = adminIdentifier(names[0])
+		target.table = adminIdentifier(names[2])
+	} else {
+		target.keyspace = queryInfo.getApplicableKeyspace()
+		target.table = adminIdentifier(names[0])
+	}
+	return target
+}
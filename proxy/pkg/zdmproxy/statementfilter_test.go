@@ -0,0 +1,107 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestStatementFilter_RequestInfo(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	truncateRule := &common.StatementFilterRule{
+		Definition: "TRUNCATE REJECT", Statement: "TRUNCATE", Action: common.StatementFilterActionReject}
+	dropKeyspaceRule := &common.StatementFilterRule{
+		Definition: "DROP ks.* REJECT", Statement: "DROP", Keyspace: "ks", Action: common.StatementFilterActionReject}
+	deleteRule := &common.StatementFilterRule{
+		Definition: "DELETE ks.events ORIGIN", Statement: "DELETE", Keyspace: "ks", Table: "events",
+		Action: common.StatementFilterActionOrigin}
+	legacyRule := &common.StatementFilterRule{
+		Definition: "* ks.legacy TARGET", Statement: "*", Keyspace: "ks", Table: "legacy",
+		Action: common.StatementFilterActionTarget}
+	filteringRule := &common.StatementFilterRule{
+		Definition: "/ALLOW\\s+FILTERING/ REJECT", Pattern: regexp.MustCompile("(?i)ALLOW\\s+FILTERING"),
+		Action: common.StatementFilterActionReject}
+	filter := newStatementFilter([]*common.StatementFilterRule{
+		truncateRule, dropKeyspaceRule, deleteRule, legacyRule, filteringRule})
+	require.Nil(t, newStatementFilter(nil))
+
+	getRequestInfo := func(query string, keyspace string, filter *statementFilter) RequestInfo {
+		return getRequestInfoFromQueryInfo(mockQueryFrame(t, query), common.ClusterTypeOrigin, false, true, false,
+			false, inspectCqlQuery(query, keyspace, generator), nil, nil, filter)
+	}
+	rejected := func(rule *common.StatementFilterRule) RequestInfo {
+		return NewRejectedRequestInfo("Statement rejected by ZDM proxy (ZDM_STATEMENT_FILTER_RULES: " + rule.Definition + ")")
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		keyspace string
+		expected RequestInfo
+	}{
+		{"truncate", "TRUNCATE ks2.tb", "", rejected(truncateRule)},
+		{"truncate table", "truncate table tb", "", rejected(truncateRule)},
+		{"drop table of keyspace", "DROP TABLE IF EXISTS ks.tb", "", rejected(dropKeyspaceRule)},
+		{"drop table with current keyspace", "DROP TABLE tb", "ks", rejected(dropKeyspaceRule)},
+		{"drop keyspace", "DROP KEYSPACE ks", "", rejected(dropKeyspaceRule)},
+		{"drop table of another keyspace", "DROP TABLE ks2.tb", "ks",
+			NewGenericRequestInfo(forwardToBoth, false, true)},
+		{"drop index", "DROP INDEX ks.idx", "", NewGenericRequestInfo(forwardToBoth, false, true)},
+		{"delete", "DELETE FROM events WHERE a = 1", "ks", NewStatementFilterRequestInfo(deleteRule, false)},
+		{"insert of filtered table", "INSERT INTO ks.events (a) VALUES (1)", "",
+			NewGenericRequestInfo(forwardToBoth, false, true)},
+		{"read of table", "SELECT * FROM ks.legacy", "", NewStatementFilterRequestInfo(legacyRule, true)},
+		{"write of table", "UPDATE ks.legacy SET b = 1 WHERE a = 1", "",
+			NewStatementFilterRequestInfo(legacyRule, false)},
+		{"pattern", "SELECT * FROM ks.tb WHERE b = 1 allow  filtering", "", rejected(filteringRule)},
+		{"no rule", "SELECT * FROM ks.tb", "", NewGenericRequestInfo(forwardToOrigin, true, true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, getRequestInfo(tt.query, tt.keyspace, filter))
+		})
+	}
+
+	require.Equal(t, NewGenericRequestInfo(forwardToBoth, false, true), getRequestInfo("TRUNCATE ks.tb", "", nil))
+}
+
+func TestStatementFilter_Batch(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	filter := newStatementFilter([]*common.StatementFilterRule{
+		{Definition: "DELETE REJECT", Statement: "DELETE", Action: common.StatementFilterActionReject},
+		{Definition: "* ks.legacy ORIGIN", Statement: "*", Keyspace: "ks", Table: "legacy",
+			Action: common.StatementFilterActionOrigin},
+	})
+
+	tests := []struct {
+		name     string
+		children []*message.BatchChild
+		expected RequestInfo
+	}{
+		{"rejected child statement", []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+			{QueryOrId: "DELETE FROM ks.tb WHERE a = 1"},
+		}, NewRejectedRequestInfo("Statement rejected by ZDM proxy (ZDM_STATEMENT_FILTER_RULES: DELETE REJECT)")},
+		{"routed child statements", []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.legacy (a) VALUES (1)"},
+			{QueryOrId: "UPDATE ks.legacy SET b = 1 WHERE a = 1"},
+		}, NewBatchRequestInfo(map[int]PreparedData{}, forwardToOrigin)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestInfo, err := buildRequestInfo(
+				NewFrameDecodeContext(mockBatchWithChildren(t, tt.children)), nil, NewPreparedStatementCache(), nil, "",
+				common.ClusterTypeOrigin, false, true, false, false, false, generator, nil, nil, filter)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, requestInfo)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+)
+
+// getStatementDuration returns the latency histogram of the statement type of the request (read, write, auth or
+// control) and of the cluster(s) that the request is forwarded to, nil if the request is not forwarded to a cluster.
+func getStatementDuration(
+	proxyMetrics *metrics.ProxyMetrics, frameContext *frameDecodeContext, requestInfo RequestInfo,
+	currentKeyspace string, timeUuidGenerator TimeUuidGenerator) (metrics.Histogram, error) {
+	var durations *metrics.StatementDurations
+	switch frameContext.GetRawFrame().Header.OpCode {
+	case primitive.OpCodeStartup, primitive.OpCodeAuthResponse:
+		durations = proxyMetrics.StatementDurationAuth
+	case primitive.OpCodeBatch:
+		durations = proxyMetrics.StatementDurationWrites
+	case primitive.OpCodeQuery, primitive.OpCodeExecute:
+		stmtType, err := getRequestStatementType(frameContext, requestInfo, currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case stmtType == statementTypeSelect:
+			durations = proxyMetrics.StatementDurationReads
+		case isWriteStatementType(stmtType):
+			durations = proxyMetrics.StatementDurationWrites
+		default:
+			durations = proxyMetrics.StatementDurationControl
+		}
+	default:
+		durations = proxyMetrics.StatementDurationControl
+	}
+
+	switch getMetricsForwardDecision(requestInfo) {
+	case forwardToOrigin:
+		return durations.Origin, nil
+	case forwardToTarget:
+		return durations.Target, nil
+	case forwardToBoth:
+		return durations.Both, nil
+	default:
+		return nil, nil
+	}
+}
+
+// getRequestStatementType returns the statement type of a QUERY request or of the prepared statement of an EXECUTE
+// request, the prepared statement always keeps its statement type even if its inspected query is not kept.
+func getRequestStatementType(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (statementType, error) {
+	if executeRequestInfo, ok := unwrapRequestInfo(requestInfo).(*ExecuteRequestInfo); ok {
+		return executeRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetStatementType(), nil
+	}
+	queryInfo, err := getRequestQueryInfo(frameContext, requestInfo, currentKeyspace, timeUuidGenerator)
+	if err != nil || queryInfo == nil {
+		return statementTypeOther, err
+	}
+	return queryInfo.getStatementType(), nil
+}
@@ -0,0 +1,80 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetStatementDuration(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newDurations := func() *metrics.StatementDurations {
+		return &metrics.StatementDurations{Origin: &countingHistogram{}, Target: &countingHistogram{}, Both: &countingHistogram{}}
+	}
+	proxyMetrics := &metrics.ProxyMetrics{
+		StatementDurationReads:   newDurations(),
+		StatementDurationWrites:  newDurations(),
+		StatementDurationAuth:    newDurations(),
+		StatementDurationControl: newDurations(),
+	}
+	newPreparedData := func(query string, decision forwardDecision) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(decision, false, true), nil, false, query, "")
+		prepareRequestInfo.statementType = inspectCqlQuery(query, "", generator).getStatementType()
+		return &preparedDataImpl{prepareRequestInfo: prepareRequestInfo}
+	}
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    metrics.Histogram
+	}{
+		{"read", &message.Query{Query: "SELECT * FROM ks.tb"},
+			NewGenericRequestInfo(forwardToOrigin, false, true), proxyMetrics.StatementDurationReads.Origin},
+		{"read of target", &message.Query{Query: "SELECT * FROM ks.tb"},
+			NewGenericRequestInfo(forwardToTarget, false, true), proxyMetrics.StatementDurationReads.Target},
+		{"write", &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"},
+			NewGenericRequestInfo(forwardToBoth, false, true), proxyMetrics.StatementDurationWrites.Both},
+		{"write of origin", &message.Query{Query: "DELETE FROM ks.tb WHERE a = 1"},
+			NewGenericRequestInfo(forwardToOrigin, false, true), proxyMetrics.StatementDurationWrites.Origin},
+		{"schema change", &message.Query{Query: "CREATE TABLE ks.tb (a int PRIMARY KEY)"},
+			NewGenericRequestInfo(forwardToBoth, false, true), proxyMetrics.StatementDurationControl.Both},
+		{"execute read", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("SELECT * FROM ks.tb WHERE a = ?", forwardToTarget)),
+			proxyMetrics.StatementDurationReads.Target},
+		{"execute write", &message.Execute{QueryId: []byte("ID")},
+			NewExecuteRequestInfo(newPreparedData("UPDATE ks.tb SET b = ? WHERE a = ?", forwardToBoth)),
+			proxyMetrics.StatementDurationWrites.Both},
+		{"primary only write", &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1) IF NOT EXISTS"},
+			NewPrimaryOnlyWriteRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), common.ClusterTypeOrigin),
+			proxyMetrics.StatementDurationWrites.Both},
+		{"batch", &message.Batch{Children: []*message.BatchChild{{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"}}},
+			NewBatchRequestInfo(nil, forwardToBoth), proxyMetrics.StatementDurationWrites.Both},
+		{"startup", &message.Startup{},
+			NewGenericRequestInfo(forwardToBoth, false, false), proxyMetrics.StatementDurationAuth.Both},
+		{"auth response", &message.AuthResponse{Token: []byte("token")},
+			NewGenericRequestInfo(forwardToTarget, false, false), proxyMetrics.StatementDurationAuth.Target},
+		{"options", &message.Options{},
+			NewGenericRequestInfo(forwardToBoth, true, false), proxyMetrics.StatementDurationControl.Both},
+		{"not forwarded", &message.Query{Query: "SELECT * FROM system.local"},
+			NewInterceptedRequestInfo(local, newStarSelectClause()), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			duration, err := getStatementDuration(proxyMetrics, NewFrameDecodeContext(f), tt.requestInfo, "", generator)
+			require.Nil(t, err)
+			if tt.expected == nil {
+				require.Nil(t, duration)
+			} else {
+				require.Same(t, tt.expected, duration)
+			}
+		})
+	}
+}
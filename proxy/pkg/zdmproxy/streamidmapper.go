@@ -0,0 +1,156 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"sync"
+)
+
+const (
+	maxStreamIdsV2 = 128
+	maxStreamIdsV3 = 32768
+)
+
+// streamIdMapper assigns the stream ids of a shared cluster connection to the requests of the client connections that
+// share it, the stream id of a response is mapped back to the stream id of the client request and to the pooledConn
+// that sent it. The stream ids in use, the stream ids of the open shared connections and the requests that found the
+// shared connection out of stream ids are tracked in the node metrics.
+type streamIdMapper struct {
+	ids       chan int16
+	exhausted metrics.Counter
+	inUse     metrics.Gauge
+	capacity  metrics.Gauge
+
+	lock    *sync.Mutex
+	pending []*pooledRequest
+	closed  bool
+}
+
+type pooledRequest struct {
+	client   *pooledConn
+	streamId int16
+}
+
+func newStreamIdMapper(
+	version primitive.ProtocolVersion, nodeMetricsInstance *metrics.NodeMetricsInstance) *streamIdMapper {
+	maxStreamIds := maxStreamIdsV3
+	if version <= primitive.ProtocolVersion2 {
+		maxStreamIds = maxStreamIdsV2
+	}
+	ids := make(chan int16, maxStreamIds)
+	for i := 0; i < maxStreamIds; i++ {
+		ids <- int16(i)
+	}
+	mapper := &streamIdMapper{
+		ids:     ids,
+		lock:    &sync.Mutex{},
+		pending: make([]*pooledRequest, maxStreamIds),
+	}
+	if nodeMetricsInstance != nil {
+		mapper.exhausted = nodeMetricsInstance.PooledStreamIdsExhausted
+		mapper.inUse = nodeMetricsInstance.PooledStreamIdsInUse
+		mapper.capacity = nodeMetricsInstance.PooledStreamIdsCapacity
+	}
+	if mapper.capacity != nil {
+		mapper.capacity.Add(maxStreamIds)
+	}
+	return mapper
+}
+
+// acquire returns the stream id of the shared connection that replaces the provided stream id of a client request.
+func (recv *streamIdMapper) acquire(client *pooledConn, clientStreamId int16) (int16, error) {
+	var streamId int16
+	select {
+	case streamId = <-recv.ids:
+	default:
+		if recv.exhausted != nil {
+			recv.exhausted.Add(1)
+		}
+		return 0, errStreamIdsExhausted
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.closed {
+		return 0, errSharedConnClosed
+	}
+	recv.pending[streamId] = &pooledRequest{client: client, streamId: clientStreamId}
+	if recv.inUse != nil {
+		recv.inUse.Add(1)
+	}
+	return streamId, nil
+}
+
+// available returns the number of free stream ids.
+func (recv *streamIdMapper) available() int {
+	return len(recv.ids)
+}
+
+// get returns the client request that the provided stream id of the shared connection is assigned to without
+// releasing it or nil if it is not in use.
+func (recv *streamIdMapper) get(streamId int16) *pooledRequest {
+	if streamId < 0 || int(streamId) >= len(recv.pending) {
+		return nil
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.pending[streamId]
+}
+
+// find returns the stream id of the shared connection that is assigned to the provided client request.
+func (recv *streamIdMapper) find(client *pooledConn, clientStreamId int16) (int16, bool) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	for streamId, request := range recv.pending {
+		if request != nil && request.client == client && request.streamId == clientStreamId {
+			return int16(streamId), true
+		}
+	}
+	return 0, false
+}
+
+// release frees the provided stream id of the shared connection, it returns the client request that it was assigned
+// to or nil if it is not in use.
+func (recv *streamIdMapper) release(streamId int16) *pooledRequest {
+	if streamId < 0 || int(streamId) >= len(recv.pending) {
+		return nil
+	}
+	recv.lock.Lock()
+	request := recv.pending[streamId]
+	recv.pending[streamId] = nil
+	if request == nil || recv.closed {
+		recv.lock.Unlock()
+		return request
+	}
+	if recv.inUse != nil {
+		recv.inUse.Subtract(1)
+	}
+	recv.lock.Unlock()
+	recv.ids <- streamId
+	return request
+}
+
+// close removes the stream ids of the shared connection from the metrics and returns the client requests that are
+// still pending, the responses that are received afterwards are not routed to them anymore.
+func (recv *streamIdMapper) close() []*pooledRequest {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.closed {
+		return nil
+	}
+	recv.closed = true
+	var pending []*pooledRequest
+	for streamId, request := range recv.pending {
+		if request != nil {
+			pending = append(pending, request)
+			recv.pending[streamId] = nil
+		}
+	}
+	if recv.inUse != nil {
+		recv.inUse.Subtract(len(pending))
+	}
+	if recv.capacity != nil {
+		recv.capacity.Subtract(len(recv.pending))
+	}
+	return pending
+}
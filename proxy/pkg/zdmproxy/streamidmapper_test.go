@@ -0,0 +1,90 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStreamIdMapper(t *testing.T) {
+	inUse, capacity, exhausted := &countingGauge{}, &countingGauge{}, &countingCounter{}
+	nodeMetrics := &metrics.NodeMetricsInstance{
+		PooledStreamIdsInUse: inUse, PooledStreamIdsCapacity: capacity, PooledStreamIdsExhausted: exhausted}
+	mapper := newStreamIdMapper(primitive.ProtocolVersion2, nodeMetrics)
+	require.Equal(t, int64(maxStreamIdsV2), capacity.value)
+	require.Equal(t, maxStreamIdsV2, mapper.available())
+
+	client1, client2 := &pooledConn{}, &pooledConn{}
+	streamIds := make(map[int16]bool)
+	for i := 0; i < maxStreamIdsV2; i++ {
+		client := client1
+		if i%2 == 1 {
+			client = client2
+		}
+		streamId, err := mapper.acquire(client, 5)
+		require.Nil(t, err)
+		streamIds[streamId] = true
+	}
+	// the client requests can have the same stream id
+	require.Equal(t, maxStreamIdsV2, len(streamIds))
+	require.Equal(t, int64(maxStreamIdsV2), inUse.value)
+	require.Equal(t, 0, mapper.available())
+
+	_, err := mapper.acquire(client1, 6)
+	require.Equal(t, errStreamIdsExhausted, err)
+	require.Equal(t, int64(1), exhausted.count)
+
+	request := mapper.release(1)
+	require.Equal(t, &pooledRequest{client: client2, streamId: 5}, request)
+	require.Nil(t, mapper.release(1))
+	require.Nil(t, mapper.release(-1))
+	require.Nil(t, mapper.release(maxStreamIdsV2))
+	require.Equal(t, int64(maxStreamIdsV2-1), inUse.value)
+
+	streamId, err := mapper.acquire(client1, 6)
+	require.Nil(t, err)
+	require.Equal(t, int16(1), streamId)
+
+	mapper.release(0)
+	pending := mapper.close()
+	require.Equal(t, maxStreamIdsV2-1, len(pending))
+	require.Contains(t, pending, &pooledRequest{client: client1, streamId: 6})
+	require.Equal(t, int64(0), inUse.value)
+	require.Equal(t, int64(0), capacity.value)
+	// the responses that are received after the connection is closed are not routed to the pending requests
+	require.Nil(t, mapper.release(1))
+	require.Equal(t, int64(0), inUse.value)
+	require.Nil(t, mapper.close())
+	require.Equal(t, int64(0), capacity.value)
+}
+
+func TestStreamIdMapper_ProtocolVersion(t *testing.T) {
+	require.Equal(t, maxStreamIdsV2, newStreamIdMapper(primitive.ProtocolVersion2, nil).available())
+	require.Equal(t, maxStreamIdsV3, newStreamIdMapper(primitive.ProtocolVersion3, nil).available())
+	require.Equal(t, maxStreamIdsV3, newStreamIdMapper(primitive.ProtocolVersion5, nil).available())
+}
+
+func TestStreamIdMapper_Find(t *testing.T) {
+	mapper := newStreamIdMapper(primitive.ProtocolVersion4, nil)
+	client1, client2 := &pooledConn{}, &pooledConn{}
+	streamId1, err := mapper.acquire(client1, 5)
+	require.Nil(t, err)
+	streamId2, err := mapper.acquire(client2, 5)
+	require.Nil(t, err)
+
+	streamId, ok := mapper.find(client2, 5)
+	require.True(t, ok)
+	require.Equal(t, streamId2, streamId)
+	_, ok = mapper.find(client1, 6)
+	require.False(t, ok)
+
+	// get doesn't release the stream id, e.g. for the intermediate pages of a continuous paging request
+	require.Equal(t, &pooledRequest{client: client1, streamId: 5}, mapper.get(streamId1))
+	require.Equal(t, &pooledRequest{client: client1, streamId: 5}, mapper.get(streamId1))
+	require.Nil(t, mapper.get(-1))
+	mapper.release(streamId1)
+	require.Nil(t, mapper.get(streamId1))
+	_, ok = mapper.find(client1, 5)
+	require.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const otherTablesLabel = "other"
+
+// tableMetrics tracks the requests and their latency by keyspace and table (see ZDM_METRICS_TABLE_LEVEL_ENABLED).
+// Only the first maxTables tables get their own labels, the requests of the other tables are tracked under the
+// "other" keyspace and table so that the number of time series is capped.
+type tableMetrics struct {
+	metricHandler *metrics.MetricHandler
+	maxTables     int
+
+	lock   *sync.RWMutex
+	tables map[string]bool
+}
+
+// newTableMetrics returns nil if the table level metrics are disabled.
+func newTableMetrics(maxTables int, metricHandler *metrics.MetricHandler) *tableMetrics {
+	if maxTables <= 0 {
+		return nil
+	}
+	return &tableMetrics{
+		metricHandler: metricHandler,
+		maxTables:     maxTables,
+		lock:          &sync.RWMutex{},
+		tables:        make(map[string]bool),
+	}
+}
+
+// getRequestMetrics returns the metrics of the table of the provided statement, nil if the statement doesn't target
+// a table of an application keyspace or if it isn't forwarded to a cluster.
+func (recv *tableMetrics) getRequestMetrics(queryInfo QueryInfo, decision forwardDecision) *tableRequestMetrics {
+	if recv == nil || queryInfo == nil || queryInfo.getTableName() == "" || isSystemQuery(queryInfo) {
+		return nil
+	}
+	switch decision {
+	case forwardToOrigin, forwardToTarget, forwardToBoth:
+	default:
+		return nil
+	}
+
+	keyspace, table := recv.getLabels(queryInfo.getApplicableKeyspace(), queryInfo.getTableName())
+	requests, err := recv.metricHandler.GetTableRequests(keyspace, table, string(decision))
+	if err != nil {
+		log.Errorf("Could not track requests of table %v.%v: %v", keyspace, table, err)
+		return nil
+	}
+	duration, err := recv.metricHandler.GetTableRequestDuration(keyspace, table, string(decision))
+	if err != nil {
+		log.Errorf("Could not track request duration of table %v.%v: %v", keyspace, table, err)
+		return nil
+	}
+	return &tableRequestMetrics{
+		requests: requests,
+		duration: duration,
+	}
+}
+
+// getLabels returns the provided keyspace and table or "other" if maxTables other tables are already tracked.
+func (recv *tableMetrics) getLabels(keyspace string, table string) (string, string) {
+	key := keyspace + "." + table
+	recv.lock.RLock()
+	tracked := recv.tables[key]
+	recv.lock.RUnlock()
+	if tracked {
+		return keyspace, table
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if !recv.tables[key] {
+		if len(recv.tables) >= recv.maxTables {
+			return otherTablesLabel, otherTablesLabel
+		}
+		recv.tables[key] = true
+		log.Debugf("Tracking table level metrics of %v.", key)
+	}
+	return keyspace, table
+}
+
+type tableRequestMetrics struct {
+	requests metrics.Counter
+	duration metrics.Histogram
+}
+
+func (recv *tableRequestMetrics) track(startTime time.Time) {
+	if recv == nil {
+		return
+	}
+	recv.requests.Add(1)
+	recv.duration.Track(startTime)
+}
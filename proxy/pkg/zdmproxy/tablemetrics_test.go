@@ -0,0 +1,63 @@
+package zdmproxy
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTableMetrics_GetLabels(t *testing.T) {
+	tableMetrics := newTableMetrics(2, newFakeMetricHandler())
+	require.Nil(t, newTableMetrics(0, newFakeMetricHandler()))
+
+	tests := []struct {
+		keyspace         string
+		table            string
+		expectedKeyspace string
+		expectedTable    string
+	}{
+		{"ks", "tb1", "ks", "tb1"},
+		{"ks", "tb2", "ks", "tb2"},
+		{"ks", "tb3", otherTablesLabel, otherTablesLabel},
+		{"ks2", "tb1", otherTablesLabel, otherTablesLabel},
+		{"ks", "tb1", "ks", "tb1"},
+		{"ks", "tb2", "ks", "tb2"},
+	}
+
+	for _, tt := range tests {
+		keyspace, table := tableMetrics.getLabels(tt.keyspace, tt.table)
+		require.Equal(t, tt.expectedKeyspace, keyspace, tt.keyspace+"."+tt.table)
+		require.Equal(t, tt.expectedTable, table, tt.keyspace+"."+tt.table)
+	}
+}
+
+func TestTableMetrics_GetRequestMetrics(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	metrics := newTableMetrics(10, newFakeMetricHandler())
+
+	tests := []struct {
+		name     string
+		query    string
+		keyspace string
+		decision forwardDecision
+		tracked  bool
+	}{
+		{"read", "SELECT * FROM ks.tb", "", forwardToOrigin, true},
+		{"write with current keyspace", "INSERT INTO tb (a) VALUES (1)", "ks", forwardToBoth, true},
+		{"read of target", "SELECT * FROM ks.tb", "", forwardToTarget, true},
+		{"system table", "SELECT * FROM system.local", "", forwardToOrigin, false},
+		{"no table", "USE ks", "", forwardToOrigin, false},
+		{"not forwarded", "SELECT * FROM ks.tb", "", forwardToNone, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestMetrics := metrics.getRequestMetrics(inspectCqlQuery(tt.query, tt.keyspace, generator), tt.decision)
+			require.Equal(t, tt.tracked, requestMetrics != nil)
+		})
+	}
+
+	var disabled *tableMetrics
+	require.Nil(t, disabled.getRequestMetrics(inspectCqlQuery("SELECT * FROM ks.tb", "", generator), forwardToOrigin))
+	require.Nil(t, metrics.getRequestMetrics(nil, forwardToOrigin))
+}
@@ -0,0 +1,33 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+)
+
+// tableRoutingRules pins the statements on specific tables to a cluster (see ZDM_TABLE_ROUTING_RULES), e.g. to keep
+// a table that is not migrated yet on ORIGIN or to read system_auth.roles from TARGET. The rules are applied when
+// a statement is inspected so the forward decision of a prepared statement is stored in the prepared statement
+// cache like the other decisions. PREPARE requests are still forwarded to both clusters.
+type tableRoutingRules struct {
+	rulesByTable map[string]*common.TableRoutingRule // keyed by <keyspace>.<table>
+}
+
+// newTableRoutingRules returns nil if there are no rules.
+func newTableRoutingRules(rules []*common.TableRoutingRule) *tableRoutingRules {
+	if len(rules) == 0 {
+		return nil
+	}
+	rulesByTable := make(map[string]*common.TableRoutingRule, len(rules))
+	for _, rule := range rules {
+		rulesByTable[rule.Keyspace+"."+rule.Table] = rule
+	}
+	return &tableRoutingRules{rulesByTable: rulesByTable}
+}
+
+// match returns the rule of the table of the statement and nil if there is none.
+func (recv *tableRoutingRules) match(queryInfo QueryInfo) *common.TableRoutingRule {
+	if recv == nil || queryInfo.getTableName() == "" {
+		return nil
+	}
+	return recv.rulesByTable[queryInfo.getApplicableKeyspace()+"."+queryInfo.getTableName()]
+}
@@ -0,0 +1,92 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTableRoutingRules_RequestInfo(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	rolesRule := &common.TableRoutingRule{Keyspace: "system_auth", Table: "roles", Cluster: common.ClusterTypeTarget}
+	localRule := &common.TableRoutingRule{Keyspace: "system", Table: "local", Cluster: common.ClusterTypeOrigin}
+	legacyRule := &common.TableRoutingRule{Keyspace: "ks", Table: "legacy", Cluster: common.ClusterTypeOrigin}
+	rules := newTableRoutingRules([]*common.TableRoutingRule{rolesRule, localRule, legacyRule})
+	require.Nil(t, newTableRoutingRules(nil))
+
+	getRequestInfo := func(query string, keyspace string, rules *tableRoutingRules) RequestInfo {
+		return getRequestInfoFromQueryInfo(mockQueryFrame(t, query), common.ClusterTypeTarget, false, true, false,
+			false, inspectCqlQuery(query, keyspace, generator), nil, rules, nil)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		keyspace string
+		expected RequestInfo
+	}{
+		{"system table read", "SELECT * FROM system_auth.roles", "",
+			NewTableRoutingRequestInfo(rolesRule, true)},
+		{"intercepted table read", "SELECT * FROM system.local", "",
+			NewTableRoutingRequestInfo(localRule, true)},
+		{"read with current keyspace", "SELECT * FROM legacy WHERE a = 1", "ks",
+			NewTableRoutingRequestInfo(legacyRule, true)},
+		{"write", "INSERT INTO ks.legacy (a) VALUES (1)", "",
+			NewTableRoutingRequestInfo(legacyRule, false)},
+		{"delete", "DELETE FROM ks.legacy WHERE a = 1", "",
+			NewTableRoutingRequestInfo(legacyRule, false)},
+		{"table of another keyspace", "INSERT INTO legacy (a) VALUES (1)", "ks2",
+			NewGenericRequestInfo(forwardToBoth, false, true)},
+		{"system table without rule", "SELECT * FROM system_auth.role_members", "",
+			NewGenericRequestInfo(forwardToOrigin, false, true)},
+		{"intercepted table without rule", "SELECT * FROM system.peers", "",
+			NewInterceptedRequestInfo(peersV1, newStarSelectClause())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, getRequestInfo(tt.query, tt.keyspace, rules))
+		})
+	}
+
+	require.Equal(t, NewGenericRequestInfo(forwardToBoth, false, true),
+		getRequestInfo("INSERT INTO ks.legacy (a) VALUES (1)", "", nil))
+}
+
+func TestTableRoutingRules_Batch(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	rules := newTableRoutingRules([]*common.TableRoutingRule{
+		{Keyspace: "ks", Table: "legacy", Cluster: common.ClusterTypeOrigin},
+		{Keyspace: "ks", Table: "legacy2", Cluster: common.ClusterTypeOrigin},
+	})
+
+	tests := []struct {
+		name     string
+		children []*message.BatchChild
+		expected forwardDecision
+	}{
+		{"writes of pinned tables", []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.legacy (a) VALUES (1)"},
+			{QueryOrId: "UPDATE ks.legacy2 SET b = 1 WHERE a = 1"},
+		}, forwardToOrigin},
+		{"writes of pinned and other tables", []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.legacy (a) VALUES (1)"},
+			{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"},
+		}, forwardToBoth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestInfo, err := buildRequestInfo(
+				NewFrameDecodeContext(mockBatchWithChildren(t, tt.children)), nil, NewPreparedStatementCache(), nil, "",
+				common.ClusterTypeOrigin, false, true, false, false, false, generator, nil, rules, nil)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, requestInfo.GetForwardDecision())
+		})
+	}
+}
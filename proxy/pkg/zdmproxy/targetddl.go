@@ -0,0 +1,174 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+	parser "github.com/datastax/zdm-proxy/antlr"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"strings"
+)
+
+// targetDdlChecker detects schema changes that use features that TARGET doesn't support
+// (see ZDM_TARGET_DDL_UNSUPPORTED_FEATURES and ZDM_TARGET_DDL_UNSUPPORTED_COMPACTION_STRATEGIES) so that they can be
+// reported (or rejected) by the proxy instead of failing on TARGET in the middle of the migration.
+//
+// Schema changes are not supported by the simplified CQL grammar so they are analyzed with the tokens of the lexer.
+type targetDdlChecker struct {
+	unsupportedFeatures             map[common.TargetDdlFeature]bool
+	unsupportedCompactionStrategies []string
+	mode                            common.TargetDdlCompatibilityMode
+}
+
+// newTargetDdlChecker returns nil if there are no unsupported features or compaction strategies.
+func newTargetDdlChecker(
+	unsupportedFeatures []common.TargetDdlFeature, unsupportedCompactionStrategies []string,
+	mode common.TargetDdlCompatibilityMode) *targetDdlChecker {
+	if len(unsupportedFeatures) == 0 && len(unsupportedCompactionStrategies) == 0 {
+		return nil
+	}
+	featuresMap := make(map[common.TargetDdlFeature]bool, len(unsupportedFeatures))
+	for _, feature := range unsupportedFeatures {
+		featuresMap[feature] = true
+	}
+	return &targetDdlChecker{
+		unsupportedFeatures:             featuresMap,
+		unsupportedCompactionStrategies: unsupportedCompactionStrategies,
+		mode:                            mode,
+	}
+}
+
+func (recv *targetDdlChecker) shouldReject() bool {
+	return recv.mode == common.TargetDdlCompatibilityModeReject
+}
+
+type ddlToken struct {
+	text    string // upper case unless it is a literal or a quoted identifier
+	literal bool
+}
+
+// check returns a description of each incompatibility between the schema change and TARGET,
+// it returns nil if the query is not a schema change or if it is compatible.
+func (recv *targetDdlChecker) check(query string) []string {
+	tokens := tokenizeDdl(query)
+	if len(tokens) < 2 {
+		return nil
+	}
+
+	verb := tokens[0].text
+	if verb != "CREATE" && verb != "ALTER" && verb != "DROP" {
+		return nil
+	}
+	object := tokens[1:]
+	if verb == "CREATE" && len(object) > 2 && object[0].text == "OR" && object[1].text == "REPLACE" {
+		object = object[2:]
+	}
+
+	var incompatibilities []string
+	addIncompatibility := func(feature common.TargetDdlFeature, description string) {
+		if recv.unsupportedFeatures[feature] {
+			incompatibilities = append(incompatibilities,
+				fmt.Sprintf("%v are not supported by TARGET (%v)", description, feature))
+		}
+	}
+
+	switch object[0].text {
+	case "MATERIALIZED":
+		if verb != "DROP" {
+			addIncompatibility(common.TargetDdlFeatureMaterializedViews, "materialized views")
+		}
+	case "CUSTOM":
+		if verb == "CREATE" && isSasiIndex(object) {
+			addIncompatibility(common.TargetDdlFeatureSasiIndexes, "SASI indexes")
+		}
+	case "TRIGGER":
+		if verb == "CREATE" {
+			addIncompatibility(common.TargetDdlFeatureTriggers, "triggers")
+		}
+	case "FUNCTION", "AGGREGATE":
+		if verb == "CREATE" {
+			addIncompatibility(common.TargetDdlFeatureUserDefinedFunctions, "user defined functions and aggregates")
+		}
+	case "KEYSPACE", "SCHEMA":
+		addIncompatibility(common.TargetDdlFeatureKeyspaceSchemaChanges, "keyspace schema changes")
+	}
+
+	if verb != "DROP" {
+		compactionStrategy := findCompactionStrategy(object)
+		if compactionStrategy != "" && recv.isUnsupportedCompactionStrategy(compactionStrategy) {
+			incompatibilities = append(incompatibilities, fmt.Sprintf(
+				"compaction strategy %v is not supported by TARGET", compactionStrategy))
+		}
+	}
+
+	return incompatibilities
+}
+
+func (recv *targetDdlChecker) isUnsupportedCompactionStrategy(compactionStrategy string) bool {
+	for _, unsupported := range recv.unsupportedCompactionStrategies {
+		if strings.EqualFold(compactionStrategy, unsupported) ||
+			strings.HasSuffix(strings.ToLower(compactionStrategy), "."+strings.ToLower(unsupported)) ||
+			strings.HasSuffix(strings.ToLower(unsupported), "."+strings.ToLower(compactionStrategy)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSasiIndex returns true for CUSTOM INDEX ... USING 'org.apache.cassandra.index.sasi.SASIIndex'.
+func isSasiIndex(tokens []ddlToken) bool {
+	for i := 0; i < len(tokens)-1; i++ {
+		if !tokens[i].literal && tokens[i].text == "USING" && tokens[i+1].literal {
+			return strings.HasSuffix(strings.ToLower(tokens[i+1].text), "sasiindex")
+		}
+	}
+	return false
+}
+
+// findCompactionStrategy returns the class of WITH compaction = {'class': '...', ...} or an empty string.
+func findCompactionStrategy(tokens []ddlToken) string {
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].literal || tokens[i].text != "COMPACTION" {
+			continue
+		}
+		for j := i + 1; j < len(tokens)-2 && tokens[j].text != "}"; j++ {
+			if tokens[j].literal && strings.EqualFold(tokens[j].text, "class") &&
+				tokens[j+1].text == ":" && tokens[j+2].literal {
+				return tokens[j+2].text
+			}
+		}
+	}
+	return ""
+}
+
+// tokenizeDdl returns the tokens of the query without whitespace and comments.
+func tokenizeDdl(query string) []ddlToken {
+	lexer := lexerPool.Get().(*parser.SimplifiedCqlLexer)
+	defer lexerPool.Put(lexer)
+	lexer.SetInputStream(newCqlInputStream(query))
+
+	var tokens []ddlToken
+	for _, token := range lexer.GetAllTokens() {
+		if token.GetChannel() != antlr.TokenDefaultChannel {
+			continue
+		}
+		switch token.GetTokenType() {
+		case parser.SimplifiedCqlLexerSTRING_LITERAL:
+			tokens = append(tokens, ddlToken{text: unquoteStringLiteral(token.GetText()), literal: true})
+		case parser.SimplifiedCqlLexerQUOTED_IDENTIFIER:
+			tokens = append(tokens, ddlToken{text: token.GetText()})
+		default:
+			tokens = append(tokens, ddlToken{text: strings.ToUpper(token.GetText())})
+		}
+	}
+	return tokens
+}
+
+func unquoteStringLiteral(literal string) string {
+	if strings.HasPrefix(literal, "$$") && strings.HasSuffix(literal, "$$") && len(literal) >= 4 {
+		return literal[2 : len(literal)-2]
+	}
+	if strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") && len(literal) >= 2 {
+		return strings.ReplaceAll(literal[1:len(literal)-1], "''", "'")
+	}
+	return literal
+}
@@ -0,0 +1,65 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTargetDdlChecker(t *testing.T) {
+	require.Nil(t, newTargetDdlChecker(nil, nil, common.TargetDdlCompatibilityModeWarn))
+
+	checker := newTargetDdlChecker(
+		[]common.TargetDdlFeature{
+			common.TargetDdlFeatureMaterializedViews,
+			common.TargetDdlFeatureSasiIndexes,
+			common.TargetDdlFeatureTriggers,
+			common.TargetDdlFeatureUserDefinedFunctions,
+		},
+		[]string{"DateTieredCompactionStrategy"},
+		common.TargetDdlCompatibilityModeReject)
+	require.True(t, checker.shouldReject())
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"insert", "INSERT INTO ks.tb (a) VALUES (1)", nil},
+		{"create table", "CREATE TABLE ks.tb (a int PRIMARY KEY) WITH compaction = {'class': 'LeveledCompactionStrategy'}", nil},
+		{"create materialized view",
+			"create materialized view ks.mv as select * from ks.tb where a is not null primary key (a)",
+			[]string{"materialized views are not supported by TARGET (MATERIALIZED_VIEWS)"}},
+		{"alter materialized view", "ALTER MATERIALIZED VIEW ks.mv WITH comment = 'x'",
+			[]string{"materialized views are not supported by TARGET (MATERIALIZED_VIEWS)"}},
+		{"drop materialized view", "DROP MATERIALIZED VIEW ks.mv", nil},
+		{"sasi index",
+			"CREATE CUSTOM INDEX idx ON ks.tb (b) USING 'org.apache.cassandra.index.sasi.SASIIndex' WITH OPTIONS = {'mode': 'CONTAINS'}",
+			[]string{"SASI indexes are not supported by TARGET (SASI_INDEXES)"}},
+		{"sai index", "CREATE CUSTOM INDEX idx ON ks.tb (b) USING 'StorageAttachedIndex'", nil},
+		{"secondary index", "CREATE INDEX idx ON ks.tb (b)", nil},
+		{"trigger", "CREATE TRIGGER tr ON ks.tb USING 'org.example.Trigger'",
+			[]string{"triggers are not supported by TARGET (TRIGGERS)"}},
+		{"trigger with trailing comment", "CREATE TRIGGER tr ON ks.tb USING 'org.example.Trigger' -- audit",
+			[]string{"triggers are not supported by TARGET (TRIGGERS)"}},
+		{"function with comment",
+			"/* udf */ CREATE OR REPLACE FUNCTION ks.f (a int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE java AS $$ return a; $$",
+			[]string{"user defined functions and aggregates are not supported by TARGET (USER_DEFINED_FUNCTIONS)"}},
+		{"keyspace not configured", "CREATE KEYSPACE ks WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}", nil},
+		{"compaction strategy",
+			"ALTER TABLE ks.tb WITH compaction = { 'class' : 'org.apache.cassandra.db.compaction.DateTieredCompactionStrategy' }",
+			[]string{"compaction strategy org.apache.cassandra.db.compaction.DateTieredCompactionStrategy is not supported by TARGET"}},
+		{"materialized view with compaction strategy",
+			"CREATE MATERIALIZED VIEW ks.mv AS SELECT * FROM ks.tb WHERE a IS NOT NULL PRIMARY KEY (a) " +
+				"WITH compaction = {'class': 'DateTieredCompactionStrategy'}",
+			[]string{
+				"materialized views are not supported by TARGET (MATERIALIZED_VIEWS)",
+				"compaction strategy DateTieredCompactionStrategy is not supported by TARGET"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, checker.check(tt.query))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package zdmproxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+const devModeTlsValidity = 7 * 24 * time.Hour
+
+// devModeTlsMaterial contains the PEM encoded certificates and keys that are generated at startup when
+// ZDM_PROXY_TLS_DEV_MODE_ENABLED is true. The client certificate is signed by the same CA as the server certificate
+// so that it can be used if ZDM_PROXY_TLS_REQUIRE_CLIENT_AUTH is true.
+type devModeTlsMaterial struct {
+	caCert     []byte
+	serverCert []byte
+	serverKey  []byte
+	clientCert []byte
+	clientKey  []byte
+}
+
+// generateDevModeTlsMaterial generates an ephemeral CA and a server certificate that is valid for the listen address
+// of the proxy and for localhost.
+func generateDevModeTlsMaterial(listenAddress string) (*devModeTlsMaterial, error) {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := notBefore.Add(devModeTlsValidity)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate dev mode CA key: %w", err)
+	}
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"ZDM Proxy"}, CommonName: "ZDM Proxy Dev Mode CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caCert, caDer, err := createDevModeCertificate(caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate dev mode CA certificate: %w", err)
+	}
+
+	serverTemplate := &x509.Certificate{
+		Subject:     pkix.Name{Organization: []string{"ZDM Proxy"}, CommonName: "ZDM Proxy Dev Mode Server"},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(listenAddress); ip != nil {
+		serverTemplate.IPAddresses = append(serverTemplate.IPAddresses, ip)
+	} else if listenAddress != "" && listenAddress != "localhost" {
+		serverTemplate.DNSNames = append(serverTemplate.DNSNames, listenAddress)
+	}
+	serverCert, serverKey, err := generateDevModeLeafCertificate(serverTemplate, caDer, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate dev mode server certificate: %w", err)
+	}
+
+	clientTemplate := &x509.Certificate{
+		Subject:     pkix.Name{Organization: []string{"ZDM Proxy"}, CommonName: "ZDM Proxy Dev Mode Client"},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientCert, clientKey, err := generateDevModeLeafCertificate(clientTemplate, caDer, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate dev mode client certificate: %w", err)
+	}
+
+	return &devModeTlsMaterial{
+		caCert:     caCert,
+		serverCert: serverCert,
+		serverKey:  serverKey,
+		clientCert: clientCert,
+		clientKey:  clientKey,
+	}, nil
+}
+
+// generateDevModeLeafCertificate returns the PEM encoded certificate and key of a new certificate signed by the CA.
+func generateDevModeLeafCertificate(
+	template *x509.Certificate, caDer []byte, caKey *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	caCert, err := x509.ParseCertificate(caDer)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, _, err := createDevModeCertificate(template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), nil
+}
+
+// createDevModeCertificate returns the PEM and DER encodings of the certificate.
+func createDevModeCertificate(
+	template *x509.Certificate, parent *x509.Certificate, publicKey *ecdsa.PublicKey,
+	signerKey *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serialNumber
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), der, nil
+}
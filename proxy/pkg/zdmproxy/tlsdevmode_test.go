@@ -0,0 +1,47 @@
+package zdmproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestGenerateDevModeTlsMaterial(t *testing.T) {
+	tlsMaterial, err := generateDevModeTlsMaterial("10.0.0.1")
+	require.Nil(t, err)
+
+	block, _ := pem.Decode(tlsMaterial.serverCert)
+	require.NotNil(t, block)
+	serverCert, err := x509.ParseCertificate(block.Bytes)
+	require.Nil(t, err)
+	require.Nil(t, serverCert.VerifyHostname("10.0.0.1"))
+	require.Nil(t, serverCert.VerifyHostname("localhost"))
+	require.Nil(t, serverCert.VerifyHostname("127.0.0.1"))
+
+	serverTlsConfig, err := getServerSideTlsConfig(
+		tlsMaterial.caCert, tlsMaterial.serverCert, tlsMaterial.serverKey, true)
+	require.Nil(t, err)
+
+	rootCAs := x509.NewCertPool()
+	require.True(t, rootCAs.AppendCertsFromPEM(tlsMaterial.caCert))
+	clientCert, err := tls.X509KeyPair(tlsMaterial.clientCert, tlsMaterial.clientKey)
+	require.Nil(t, err)
+	clientTlsConfig := &tls.Config{
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{clientCert},
+		ServerName:   "localhost",
+	}
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- tls.Server(serverConn, serverTlsConfig).Handshake()
+	}()
+	require.Nil(t, tls.Client(clientConn, clientTlsConfig).Handshake())
+	require.Nil(t, <-serverErr)
+}
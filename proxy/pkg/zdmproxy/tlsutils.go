@@ -36,8 +36,18 @@ func getClientSideTlsConfigFromProxyClusterTlsConfig(clusterTlsConfig *common.Cl
 	if err != nil {
 		return nil, err
 	}
-	// currently not supporting server hostname verification for non-Astra clusters
-	return getClientSideTlsConfig(serverCAFile, clientCertFile, clientKeyFile, "", "", clusterType)
+	// the host name is only verified if a server name was configured for non-Astra clusters
+	tlsConfig, err := getClientSideTlsConfig(
+		serverCAFile, clientCertFile, clientKeyFile, clusterTlsConfig.ServerName, clusterTlsConfig.ServerName, clusterType)
+	if err != nil {
+		return nil, err
+	}
+	if clusterTlsConfig.InsecureSkipVerify {
+		log.Warnf("The certificates of the %s nodes will not be verified.", clusterType)
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = nil
+	}
+	return tlsConfig, nil
 }
 
 func getClientSideTlsConfig(
@@ -53,15 +63,14 @@ func getClientSideTlsConfig(
 		}
 	}
 
-	// if TLS is used, server CA must always be specified
+	// the server CA is added to the system CAs, it can only be omitted if TLS was explicitly enabled for the cluster
 	if caCert != nil {
 		ok := rootCAs.AppendCertsFromPEM(caCert)
 		if !ok {
 			return nil, fmt.Errorf("the provided CA cert could not be added to the rootCAs")
 		}
 	} else {
-		// this should be caught when validating the configuration
-		log.Warnf("Using TLS for %s, but CA cert was not specified", clusterType)
+		log.Debugf("Using TLS for %s without a CA cert, the system CA certs are used.", clusterType)
 	}
 
 	var clientCerts []tls.Certificate
@@ -0,0 +1,82 @@
+package zdmproxy
+
+import (
+	"crypto/tls"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetClientSideTlsConfigFromProxyClusterTlsConfig(t *testing.T) {
+	tlsMaterial, err := generateDevModeTlsMaterial("127.0.0.1")
+	require.Nil(t, err)
+	otherTlsMaterial, err := generateDevModeTlsMaterial("127.0.0.1")
+	require.Nil(t, err)
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.crt")
+	otherCaPath := filepath.Join(dir, "other-ca.crt")
+	require.Nil(t, ioutil.WriteFile(caPath, tlsMaterial.caCert, 0600))
+	require.Nil(t, ioutil.WriteFile(otherCaPath, otherTlsMaterial.caCert, 0600))
+
+	serverTlsConfig, err := getServerSideTlsConfig(
+		tlsMaterial.caCert, tlsMaterial.serverCert, tlsMaterial.serverKey, false)
+	require.Nil(t, err)
+
+	tests := []struct {
+		name        string
+		config      *common.ClusterTlsConfig
+		errExpected bool
+	}{
+		{
+			name:   "CA without server name",
+			config: &common.ClusterTlsConfig{TlsEnabled: true, ServerCaPath: caPath},
+		},
+		{
+			name:   "CA with matching server name",
+			config: &common.ClusterTlsConfig{TlsEnabled: true, ServerCaPath: caPath, ServerName: "localhost"},
+		},
+		{
+			name:        "CA with wrong server name",
+			config:      &common.ClusterTlsConfig{TlsEnabled: true, ServerCaPath: caPath, ServerName: "node1.example.com"},
+			errExpected: true,
+		},
+		{
+			name:        "wrong CA",
+			config:      &common.ClusterTlsConfig{TlsEnabled: true, ServerCaPath: otherCaPath},
+			errExpected: true,
+		},
+		{
+			name: "wrong CA and server name with insecure skip verify",
+			config: &common.ClusterTlsConfig{
+				TlsEnabled: true, ServerCaPath: otherCaPath, ServerName: "node1.example.com", InsecureSkipVerify: true},
+		},
+		{
+			name:   "system CAs with insecure skip verify",
+			config: &common.ClusterTlsConfig{TlsEnabled: true, InsecureSkipVerify: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientTlsConfig, err := getClientSideTlsConfigFromProxyClusterTlsConfig(tt.config, common.ClusterTypeOrigin)
+			require.Nil(t, err)
+
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+			go func() {
+				_ = tls.Server(serverConn, serverTlsConfig).Handshake()
+			}()
+			err = tls.Client(clientConn, clientTlsConfig).Handshake()
+			if tt.errExpected {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	partitionKeyQueryTimeout    = 10 * time.Second
+	partitionKeyQueryRetryDelay = time.Minute
+)
+
+// tokenRouter finds the node of the local datacenter that owns the token of the partition key of a request so that
+// the pool can forward the request to that node (see ZDM_TOKEN_AWARE_ROUTING_ENABLED).
+//
+// The partition key of EXECUTE requests is read from the bound values using the variables metadata of the prepared
+// statement. The partition key of INSERT, UPDATE and DELETE statements sent with QUERY requests is read from the
+// equality relations of the statement using the partition key columns of the table, which are fetched in the
+// background by the control connection. Requests whose partition key can't be computed (yet) are not rerouted.
+//
+// The replica is the first node (in token order) of the local datacenter whose token is greater than or equal to
+// the token of the partition key, which is a replica with NetworkTopologyStrategy keyspaces.
+type tokenRouter struct {
+	controlConn *ControlConn
+	psCache     *PreparedStatementCache
+	isOrigin    bool
+	ctx         context.Context
+
+	lock          *sync.Mutex
+	ring          *tokenRing // nil if the cluster does not use the Murmur3 partitioner
+	ringVersion   int64
+	partitionKeys map[string]*partitionKeyEntry // keyed on writeFilterTableKey(keyspace, table)
+}
+
+type partitionKeyEntry struct {
+	columns   []*partitionKeyColumn // nil if the table was not found or has a partition key type that is not supported
+	pending   bool
+	fetchedAt time.Time
+}
+
+type partitionKeyColumn struct {
+	name    string
+	cqlType string
+}
+
+func newTokenRouter(
+	ctx context.Context, controlConn *ControlConn, psCache *PreparedStatementCache, isOrigin bool) *tokenRouter {
+	return &tokenRouter{
+		controlConn:   controlConn,
+		psCache:       psCache,
+		isOrigin:      isOrigin,
+		ctx:           ctx,
+		lock:          &sync.Mutex{},
+		ringVersion:   -1,
+		partitionKeys: make(map[string]*partitionKeyEntry),
+	}
+}
+
+// isTokenRoutableRequest returns true if the partition key of the provided request may be computed.
+func isTokenRoutableRequest(request *frame.RawFrame) bool {
+	opCode := request.Header.OpCode
+	return (opCode == primitive.OpCodeQuery || opCode == primitive.OpCodeExecute) &&
+		!request.Header.Flags.Contains(primitive.HeaderFlagCompressed)
+}
+
+// replica returns the node that owns the token of the partition key of the provided request,
+// or nil if it can't be computed or if that node is DOWN.
+func (recv *tokenRouter) replica(request *frame.RawFrame, keyspace string) *Host {
+	ring := recv.getRing()
+	if ring == nil {
+		return nil
+	}
+
+	var routingKey []byte
+	switch request.Header.OpCode {
+	case primitive.OpCodeExecute:
+		routingKey = recv.executeRoutingKey(request)
+	case primitive.OpCodeQuery:
+		routingKey = recv.queryRoutingKey(request, keyspace)
+	}
+	if routingKey == nil {
+		return nil
+	}
+
+	host := ring.replica(murmur3Token(routingKey))
+	if host == nil || !recv.controlConn.IsHostUp(host) {
+		return nil
+	}
+	return host
+}
+
+// getRing returns the token ring of the local datacenter, it is rebuilt when the topology changes.
+func (recv *tokenRouter) getRing() *tokenRing {
+	version := recv.controlConn.GetTopologyVersion()
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.ringVersion == version {
+		return recv.ring
+	}
+	recv.ringVersion = version
+	recv.ring = nil
+
+	partitioner := ""
+	if partitionerCol, ok := recv.controlConn.GetSystemLocalColumnData()[partitionerColumn.Name]; ok {
+		if value := partitionerCol.AsNillableString(); value != nil {
+			partitioner = *value
+		}
+	}
+	if !strings.Contains(partitioner, "Murmur3Partitioner") {
+		log.Warnf("Token aware routing is disabled for %v because its partitioner is %v instead of Murmur3.",
+			recv.controlConn.connConfig.GetClusterType(), partitioner)
+		return nil
+	}
+
+	hosts, err := recv.controlConn.GetOrderedHostsInLocalDatacenter()
+	if err != nil {
+		log.Debugf("Could not build token ring of %v: %v", recv.controlConn.connConfig.GetClusterType(), err)
+		return nil
+	}
+	recv.ring = newTokenRing(hosts)
+	return recv.ring
+}
+
+func (recv *tokenRouter) executeRoutingKey(request *frame.RawFrame) []byte {
+	body, err := defaultCodec.DecodeBody(request.Header, bytes.NewReader(request.Body))
+	if err != nil {
+		log.Debugf("Could not decode EXECUTE request to compute its partition key: %v", err)
+		return nil
+	}
+	execute, ok := body.Message.(*message.Execute)
+	if !ok {
+		return nil
+	}
+
+	if recv.isOrigin {
+		if prepared, ok := recv.psCache.Get(execute.QueryId); ok {
+			return preparedRoutingKey(prepared.GetOriginVariablesMetadata(), execute.Options)
+		}
+	} else if prepared, ok := recv.psCache.GetByTargetPreparedId(execute.QueryId); ok {
+		return preparedRoutingKey(prepared.GetTargetVariablesMetadata(), execute.Options)
+	}
+	return nil
+}
+
+// preparedRoutingKey returns the routing key made of the bound values of the partition key columns
+// (PkIndices are only sent with protocol v4 and later).
+func preparedRoutingKey(variablesMetadata *message.VariablesMetadata, options *message.QueryOptions) []byte {
+	if variablesMetadata == nil || len(variablesMetadata.PkIndices) == 0 || options == nil {
+		return nil
+	}
+	components := make([][]byte, 0, len(variablesMetadata.PkIndices))
+	for _, index := range variablesMetadata.PkIndices {
+		if int(index) >= len(variablesMetadata.Columns) {
+			return nil
+		}
+		value := routingKeyBoundValue(options, int(index), variablesMetadata.Columns[index].Name)
+		if value == nil {
+			return nil
+		}
+		components = append(components, value)
+	}
+	return encodeRoutingKey(components)
+}
+
+func (recv *tokenRouter) queryRoutingKey(request *frame.RawFrame, keyspace string) []byte {
+	body, err := defaultCodec.DecodeBody(request.Header, bytes.NewReader(request.Body))
+	if err != nil {
+		log.Debugf("Could not decode QUERY request to compute its partition key: %v", err)
+		return nil
+	}
+	query, ok := body.Message.(*message.Query)
+	if !ok || !isTokenRoutableStatement(query.Query) {
+		return nil
+	}
+
+	queryInfo := inspectCqlQuery(query.Query, keyspace, nil)
+	statements := queryInfo.getParsedStatements()
+	if !isWriteStatementType(queryInfo.getStatementType()) || len(statements) != 1 {
+		return nil
+	}
+	statement := statements[0]
+	statementKeyspace := statement.keyspaceName
+	if statementKeyspace == "" {
+		statementKeyspace = keyspace
+	}
+	if statementKeyspace == "" || statement.tableName == "" {
+		return nil
+	}
+
+	columns := recv.getPartitionKeyColumns(statementKeyspace, statement.tableName)
+	if columns == nil {
+		return nil
+	}
+	components := make([][]byte, 0, len(columns))
+	for _, column := range columns {
+		t, ok := statement.columnTerms[column.name]
+		if !ok {
+			return nil
+		}
+		var value []byte
+		switch {
+		case t.isLiteral():
+			value = encodeRoutingKeyLiteral(column.cqlType, t.literal)
+		case t.isPositionalBindMarker():
+			value = routingKeyBoundValue(query.Options, t.positionalIndex, "")
+		case t.isNamedBindMarker():
+			value = routingKeyBoundValue(query.Options, -1, t.bindMarkerName)
+		}
+		if value == nil {
+			return nil
+		}
+		components = append(components, value)
+	}
+	return encodeRoutingKey(components)
+}
+
+// isTokenRoutableStatement avoids parsing the statements that can't be routed (the simplified grammar
+// does not capture the relations of SELECT statements).
+func isTokenRoutableStatement(query string) bool {
+	trimmed := strings.TrimSpace(query)
+	if len(trimmed) < 6 {
+		return false
+	}
+	prefix := strings.ToLower(trimmed[:6])
+	return prefix == "insert" || prefix == "update" || prefix == "delete"
+}
+
+// routingKeyBoundValue returns the contents of the value bound at the provided index (positional values) or to the
+// provided name (named values), or nil if there is no such value or if it is null or unset.
+func routingKeyBoundValue(options *message.QueryOptions, index int, name string) []byte {
+	if options == nil {
+		return nil
+	}
+	var value *primitive.Value
+	if options.PositionalValues != nil {
+		if index >= 0 && index < len(options.PositionalValues) {
+			value = options.PositionalValues[index]
+		}
+	} else if options.NamedValues != nil && name != "" {
+		value = options.NamedValues[name]
+	}
+	if value == nil || value.Type != primitive.ValueTypeRegular || value.Contents == nil {
+		return nil
+	}
+	return value.Contents
+}
+
+// encodeRoutingKeyLiteral returns the serialized form of a CQL literal,
+// or nil if the literal or the type of the column is not supported.
+func encodeRoutingKeyLiteral(cqlType string, literal string) []byte {
+	value, ok := writeFilterLiteralValue(literal)
+	if !ok {
+		return nil
+	}
+	switch cqlType {
+	case "text", "varchar", "ascii":
+		return []byte(value)
+	case "bigint", "int", "smallint", "tinyint":
+		size := map[string]int{"bigint": 8, "int": 4, "smallint": 2, "tinyint": 1}[cqlType]
+		parsed, err := strconv.ParseInt(value, 10, size*8)
+		if err != nil {
+			return nil
+		}
+		encoded := make([]byte, 8)
+		binary.BigEndian.PutUint64(encoded, uint64(parsed))
+		return encoded[8-size:]
+	case "boolean":
+		parsed, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			return nil
+		}
+		if parsed {
+			return []byte{1}
+		}
+		return []byte{0}
+	case "uuid", "timeuuid":
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return nil
+		}
+		return parsed[:]
+	case "blob":
+		if !strings.HasPrefix(strings.ToLower(value), "0x") {
+			return nil
+		}
+		decoded, err := hex.DecodeString(value[2:])
+		if err != nil {
+			return nil
+		}
+		return decoded
+	}
+	return nil
+}
+
+// encodeRoutingKey returns the serialized partition key, composite partition keys are made of the length (2 bytes),
+// the contents and a 0 byte of each component.
+func encodeRoutingKey(components [][]byte) []byte {
+	if len(components) == 1 {
+		return components[0]
+	}
+	buf := &bytes.Buffer{}
+	for _, component := range components {
+		_ = binary.Write(buf, binary.BigEndian, uint16(len(component)))
+		buf.Write(component)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// getPartitionKeyColumns returns the partition key columns of the provided table or nil if they are not known yet,
+// in which case they are fetched in the background.
+func (recv *tokenRouter) getPartitionKeyColumns(keyspace string, table string) []*partitionKeyColumn {
+	key := writeFilterTableKey(keyspace, table)
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	entry, ok := recv.partitionKeys[key]
+	if ok && (entry.columns != nil || entry.pending || time.Since(entry.fetchedAt) < partitionKeyQueryRetryDelay) {
+		return entry.columns
+	}
+	recv.partitionKeys[key] = &partitionKeyEntry{pending: true}
+	go recv.fetchPartitionKeyColumns(key, keyspace, table)
+	return nil
+}
+
+func (recv *tokenRouter) fetchPartitionKeyColumns(key string, keyspace string, table string) {
+	columns, err := recv.queryPartitionKeyColumns(keyspace, table)
+	if err != nil {
+		log.Debugf("Could not fetch partition key of %v.%v from %v, its requests will not be token aware: %v",
+			keyspace, table, recv.controlConn.connConfig.GetClusterType(), err)
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.partitionKeys[key] = &partitionKeyEntry{columns: columns, fetchedAt: time.Now()}
+}
+
+func (recv *tokenRouter) queryPartitionKeyColumns(keyspace string, table string) ([]*partitionKeyColumn, error) {
+	conn, _ := recv.controlConn.getConnAndContactPoint()
+	if conn == nil {
+		return nil, fmt.Errorf("control connection is not open")
+	}
+	ctx, cancelFn := context.WithTimeout(recv.ctx, partitionKeyQueryTimeout)
+	defer cancelFn()
+	rowSet, err := conn.Query(fmt.Sprintf(
+		"SELECT column_name, kind, position, type FROM system_schema.columns WHERE keyspace_name = '%s' AND table_name = '%s'",
+		strings.ReplaceAll(keyspace, "'", "''"), strings.ReplaceAll(table, "'", "''")),
+		GetDefaultGenericTypeCodec(), ccProtocolVersion, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	columnsByPosition := make(map[int32]*partitionKeyColumn)
+	for _, row := range rowSet.Rows {
+		kind, err := parseString(row, "kind")
+		if err != nil {
+			return nil, err
+		}
+		if kind != "partition_key" {
+			continue
+		}
+		name, err := parseString(row, "column_name")
+		if err != nil {
+			return nil, err
+		}
+		cqlType, err := parseString(row, "type")
+		if err != nil {
+			return nil, err
+		}
+		position, ok := parseNillableInt(row, "position")
+		if !ok || position == nil {
+			return nil, fmt.Errorf("position of partition key column %v is missing", name)
+		}
+		columnsByPosition[*position] = &partitionKeyColumn{name: name, cqlType: cqlType}
+	}
+	if len(columnsByPosition) == 0 {
+		return nil, fmt.Errorf("table does not exist")
+	}
+
+	columns := make([]*partitionKeyColumn, len(columnsByPosition))
+	for position, column := range columnsByPosition {
+		if position < 0 || int(position) >= len(columns) {
+			return nil, fmt.Errorf("unexpected position %d of partition key column %v", position, column.name)
+		}
+		columns[position] = column
+	}
+	return columns, nil
+}
+
+// tokenRing maps the tokens of the nodes of a datacenter to the nodes that own them.
+type tokenRing struct {
+	tokens []int64
+	hosts  []*Host
+}
+
+func newTokenRing(hosts []*Host) *tokenRing {
+	ring := &tokenRing{}
+	for _, host := range hosts {
+		for _, token := range host.Tokens {
+			parsed, err := strconv.ParseInt(token, 10, 64)
+			if err != nil {
+				log.Debugf("Ignoring token %v of %v: %v", token, host, err)
+				continue
+			}
+			ring.tokens = append(ring.tokens, parsed)
+			ring.hosts = append(ring.hosts, host)
+		}
+	}
+	sort.Sort(ring)
+	return ring
+}
+
+func (recv *tokenRing) Len() int {
+	return len(recv.tokens)
+}
+
+func (recv *tokenRing) Less(i, j int) bool {
+	return recv.tokens[i] < recv.tokens[j]
+}
+
+func (recv *tokenRing) Swap(i, j int) {
+	recv.tokens[i], recv.tokens[j] = recv.tokens[j], recv.tokens[i]
+	recv.hosts[i], recv.hosts[j] = recv.hosts[j], recv.hosts[i]
+}
+
+// replica returns the node that owns the provided token, i.e., the node with the smallest token that is greater than
+// or equal to it (wrapping around the ring), or nil if the ring is empty.
+func (recv *tokenRing) replica(token int64) *Host {
+	if len(recv.tokens) == 0 {
+		return nil
+	}
+	i := sort.Search(len(recv.tokens), func(i int) bool {
+		return recv.tokens[i] >= token
+	})
+	if i == len(recv.tokens) {
+		i = 0
+	}
+	return recv.hosts[i]
+}
+
+// murmur3Token returns the token of a serialized partition key with the Murmur3Partitioner.
+func murmur3Token(routingKey []byte) int64 {
+	token := murmur3H1(routingKey)
+	if token == math.MinInt64 {
+		return math.MaxInt64
+	}
+	return token
+}
+
+const (
+	murmur3C1 uint64 = 0x87c37b91114253d5
+	murmur3C2 uint64 = 0x4cf5ad432745937f
+)
+
+// murmur3H1 returns the first half of the 128 bit x64 variant of MurmurHash3 the way Cassandra computes it,
+// i.e., the bytes of the tail are sign extended.
+func murmur3H1(data []byte) int64 {
+	length := len(data)
+	var h1, h2 uint64
+
+	nBlocks := length / 16
+	for i := 0; i < nBlocks; i++ {
+		k1 := binary.LittleEndian.Uint64(data[i*16:])
+		k2 := binary.LittleEndian.Uint64(data[i*16+8:])
+
+		k1 *= murmur3C1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmur3C2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= murmur3C2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmur3C1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	tail := data[nBlocks*16:]
+	if len(tail) > 8 {
+		var k2 uint64
+		for i := len(tail) - 1; i >= 8; i-- {
+			k2 ^= uint64(int64(int8(tail[i]))) << (uint(i-8) * 8)
+		}
+		k2 *= murmur3C2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmur3C1
+		h2 ^= k2
+	}
+	if len(tail) > 0 {
+		var k1 uint64
+		last := len(tail) - 1
+		if last > 7 {
+			last = 7
+		}
+		for i := last; i >= 0; i-- {
+			k1 ^= uint64(int64(int8(tail[i]))) << (uint(i) * 8)
+		}
+		k1 *= murmur3C1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmur3C2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(length)
+	h2 ^= uint64(length)
+	h1 += h2
+	h2 += h1
+	h1 = murmur3Fmix(h1)
+	h2 = murmur3Fmix(h2)
+	h1 += h2
+	return int64(h1)
+}
+
+func murmur3Fmix(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
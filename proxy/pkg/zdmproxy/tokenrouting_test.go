@@ -0,0 +1,243 @@
+package zdmproxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"math"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMurmur3H1(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected uint64
+	}{
+		{"hello", 0xcbd8a7b341bd9b02},
+		{"hello, world", 0x342fac623a5ebc8e},
+		{"The quick brown fox jumps over the lazy dog.", 0xcd99481f9ee902c9},
+	}
+	for _, test := range tests {
+		t.Run(test.data, func(t *testing.T) {
+			require.Equal(t, int64(test.expected), murmur3H1([]byte(test.data)))
+		})
+	}
+}
+
+func TestTokenRing_Replica(t *testing.T) {
+	require.Nil(t, newTokenRing(nil).replica(0))
+
+	host1 := NewHost(net.ParseIP("127.0.0.1"), 9042, uuid.New(), "dc1", "rack1", []string{"-100", "200"}, nil, nil)
+	host2 := NewHost(net.ParseIP("127.0.0.2"), 9042, uuid.New(), "dc1", "rack1", []string{"0", "invalid"}, nil, nil)
+	ring := newTokenRing([]*Host{host1, host2})
+	require.Equal(t, []int64{-100, 0, 200}, ring.tokens)
+
+	require.Equal(t, host1, ring.replica(math.MinInt64))
+	require.Equal(t, host1, ring.replica(-100))
+	require.Equal(t, host2, ring.replica(-99))
+	require.Equal(t, host2, ring.replica(0))
+	require.Equal(t, host1, ring.replica(1))
+	require.Equal(t, host1, ring.replica(200))
+	// wraps around
+	require.Equal(t, host1, ring.replica(201))
+}
+
+func TestEncodeRoutingKey(t *testing.T) {
+	require.Equal(t, []byte{1, 2}, encodeRoutingKey([][]byte{{1, 2}}))
+	require.Equal(t,
+		[]byte{0, 2, 1, 2, 0, 0, 1, 3, 0},
+		encodeRoutingKey([][]byte{{1, 2}, {3}}))
+}
+
+func TestEncodeRoutingKeyLiteral(t *testing.T) {
+	id := uuid.MustParse("7e3ec6c5-0d61-4a45-9b0a-a4c37b1e3c2e")
+	tests := []struct {
+		cqlType  string
+		literal  string
+		expected []byte
+	}{
+		{"text", "'it''s'", []byte("it's")},
+		{"varchar", "$$abc$$", []byte("abc")},
+		{"int", "-2", []byte{0xff, 0xff, 0xff, 0xfe}},
+		{"bigint", "1", []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+		{"smallint", "256", []byte{1, 0}},
+		{"tinyint", "7", []byte{7}},
+		{"boolean", "TRUE", []byte{1}},
+		{"uuid", id.String(), id[:]},
+		{"blob", "0xcafe", []byte{0xca, 0xfe}},
+		{"int", "2147483648", nil},
+		{"int", "null", nil},
+		{"blob", "cafe", nil},
+		{"frozen<list<int>>", "[1]", nil},
+		{"double", "1.5", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.cqlType+" "+test.literal, func(t *testing.T) {
+			require.Equal(t, test.expected, encodeRoutingKeyLiteral(test.cqlType, test.literal))
+		})
+	}
+}
+
+func TestTokenRouter_ExecuteRoutingKey(t *testing.T) {
+	psCache := NewPreparedStatementCache()
+	variablesMetadata := &message.VariablesMetadata{
+		PkIndices: []uint16{2, 0},
+		Columns: []*message.ColumnMetadata{
+			{Keyspace: "ks", Table: "tb", Name: "pk1", Type: datatype.Int},
+			{Keyspace: "ks", Table: "tb", Name: "v", Type: datatype.Int},
+			{Keyspace: "ks", Table: "tb", Name: "pk2", Type: datatype.Int},
+		},
+	}
+	originId, targetId := []byte{1}, []byte{2}
+	psCache.Store(
+		&message.PreparedResult{PreparedQueryId: originId, VariablesMetadata: variablesMetadata},
+		&message.PreparedResult{PreparedQueryId: targetId, VariablesMetadata: variablesMetadata},
+		NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, "", ""))
+
+	execute := func(queryId []byte, values ...*primitive.Value) *frame.RawFrame {
+		request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Execute{
+			QueryId: queryId, Options: &message.QueryOptions{PositionalValues: values}}))
+		require.Nil(t, err)
+		return request
+	}
+	values := []*primitive.Value{
+		primitive.NewValue([]byte{1}), primitive.NewValue([]byte{2}), primitive.NewValue([]byte{3})}
+	expected := encodeRoutingKey([][]byte{{3}, {1}})
+
+	originRouter := newTokenRouter(context.Background(), nil, psCache, true)
+	targetRouter := newTokenRouter(context.Background(), nil, psCache, false)
+	require.Equal(t, expected, originRouter.executeRoutingKey(execute(originId, values...)))
+	require.Equal(t, expected, targetRouter.executeRoutingKey(execute(targetId, values...)))
+	require.Nil(t, originRouter.executeRoutingKey(execute(targetId, values...)))
+	require.Nil(t, originRouter.executeRoutingKey(execute(originId, values[0], values[1], primitive.NewUnsetValue())))
+	require.Nil(t, originRouter.executeRoutingKey(execute(originId, values[0])))
+}
+
+func TestTokenRouter_QueryRoutingKey(t *testing.T) {
+	router := newTokenRouter(context.Background(), nil, nil, true)
+	router.partitionKeys[writeFilterTableKey("ks", "tb")] = &partitionKeyEntry{
+		columns: []*partitionKeyColumn{{name: "pk1", cqlType: "int"}, {name: "pk2", cqlType: "text"}},
+	}
+	router.partitionKeys[writeFilterTableKey("ks", "unknown")] = &partitionKeyEntry{fetchedAt: time.Now()}
+
+	query := func(cql string, options *message.QueryOptions) *frame.RawFrame {
+		request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{
+			Query: cql, Options: options}))
+		require.Nil(t, err)
+		return request
+	}
+	expected := encodeRoutingKey([][]byte{{0, 0, 0, 1}, []byte("a")})
+
+	require.Equal(t, expected, router.queryRoutingKey(
+		query("INSERT INTO ks.tb (pk1, pk2, v) VALUES (1, 'a', 2)", nil), ""))
+	require.Equal(t, expected, router.queryRoutingKey(
+		query("UPDATE tb SET v = 2 WHERE pk1 = ? AND pk2 = 'a'", &message.QueryOptions{
+			PositionalValues: []*primitive.Value{primitive.NewValue([]byte{0, 0, 0, 1})}}), "ks"))
+	require.Equal(t, expected, router.queryRoutingKey(
+		query("DELETE FROM tb WHERE pk2 = :p2 AND pk1 = :p1", &message.QueryOptions{
+			NamedValues: map[string]*primitive.Value{
+				"p1": primitive.NewValue([]byte{0, 0, 0, 1}), "p2": primitive.NewValue([]byte("a"))}}), "ks"))
+
+	// partition key not fully restricted
+	require.Nil(t, router.queryRoutingKey(query("DELETE FROM ks.tb WHERE pk1 = 1", nil), ""))
+	// no keyspace
+	require.Nil(t, router.queryRoutingKey(query("DELETE FROM tb WHERE pk1 = 1 AND pk2 = 'a'", nil), ""))
+	// partition key columns not found
+	require.Nil(t, router.queryRoutingKey(query("DELETE FROM ks.unknown WHERE pk1 = 1 AND pk2 = 'a'", nil), ""))
+	// statements that can't be routed
+	require.Nil(t, router.queryRoutingKey(query("SELECT * FROM ks.tb WHERE pk1 = 1 AND pk2 = 'a'", nil), ""))
+	require.Nil(t, router.queryRoutingKey(query(
+		"BEGIN BATCH INSERT INTO ks.tb (pk1, pk2) VALUES (1, 'a'); APPLY BATCH", nil), ""))
+}
+
+func TestClusterConnPool_TokenAwareRouting(t *testing.T) {
+	node1 := newFakeFailoverNode(t)
+	defer node1.close()
+	node2 := newFakeFailoverNode(t)
+	defer node2.close()
+
+	psCache := NewPreparedStatementCache()
+	preparedId := []byte{1}
+	psCache.Store(
+		&message.PreparedResult{PreparedQueryId: preparedId, VariablesMetadata: &message.VariablesMetadata{
+			PkIndices: []uint16{0},
+			Columns:   []*message.ColumnMetadata{{Keyspace: "ks", Table: "tb", Name: "pk", Type: datatype.Int}},
+		}},
+		&message.PreparedResult{PreparedQueryId: preparedId},
+		NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, "", ""))
+	routingKey := []byte{0, 0, 0, 1}
+
+	// node2 owns the token of the partition key
+	cc, hosts := newFailoverTestControlConn(t, node1.port(), node2.port())
+	token := murmur3Token(routingKey)
+	hosts[0].Tokens = []string{strconv.FormatInt(token-1, 10)}
+	hosts[1].Tokens = []string{strconv.FormatInt(token, 10)}
+	partitioner := "org.apache.cassandra.dht.Murmur3Partitioner"
+	cc.systemLocalColumnData = map[string]*optionalColumn{partitionerColumn.Name: NewOptionalColumn(&partitioner, true)}
+
+	conf := config.New()
+	conf.RequestReadBufferSizeBytes = 1024
+	conf.RequestWriteBufferSizeBytes = 1024
+	conf.RequestWriteQueueSizeFrames = 16
+	conf.ResponseReadBufferSizeBytes = 1024
+	conf.ResponseWriteQueueSizeFrames = 16
+	pool := newClusterConnPool(conf, 1, NewScheduler(1), nil, nil)
+	defer pool.Shutdown()
+
+	connInfo := NewClusterConnectionInfo(
+		cc.connConfig, cc.connConfig.CreateEndpoint(hosts[0]), true, pool, nil, nil, nil, nil,
+		newTokenRouter(context.Background(), cc, psCache, true), nil)
+	nodeMetrics := &metrics.NodeMetrics{OriginMetrics: &metrics.NodeMetricsInstance{OpenConnections: newFakeGauge()}}
+	conn, _, err := openConnectionToCluster(connInfo, conf, context.Background(), ClusterConnectorTypeOrigin, nodeMetrics)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	send := func(streamId int16, msg message.Message) error {
+		err := defaultCodec.EncodeFrame(frame.NewFrame(primitive.ProtocolVersion4, streamId, msg), conn)
+		if err != nil {
+			return err
+		}
+		response, err := defaultCodec.DecodeFrame(conn)
+		if err != nil {
+			return err
+		}
+		if response.Header.StreamId != streamId {
+			return fmt.Errorf("unexpected stream id %d", response.Header.StreamId)
+		}
+		return nil
+	}
+	require.Nil(t, send(0, &message.Startup{}))
+
+	execute := &message.Execute{QueryId: preparedId, Options: &message.QueryOptions{
+		PositionalValues: []*primitive.Value{primitive.NewValue(routingKey)}}}
+	countExecute := func(node *fakeFailoverNode) int {
+		count := 0
+		for _, request := range node.getRequests() {
+			if request == primitive.OpCodeExecute.String() {
+				count++
+			}
+		}
+		return count
+	}
+
+	// the first requests go to node1 while the connection to node2 is opened in the background
+	require.Eventually(t, func() bool {
+		return send(1, execute) == nil && countExecute(node2) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, primitive.OpCodeStartup.String(), node2.getRequests()[0])
+
+	// requests that can't be routed use the shared connection of the client connection
+	executed := countExecute(node1)
+	require.Nil(t, send(2, &message.Execute{QueryId: []byte{2}}))
+	require.Equal(t, executed+1, countExecute(node1))
+}
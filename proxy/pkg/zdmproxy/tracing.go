@@ -0,0 +1,596 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// traceParentKey is the custom payload key of the W3C trace context of a request (https://www.w3.org/TR/trace-context/)
+	traceParentKey = "traceparent"
+
+	tracingQueueSize = 4096
+	tracingBatchSize = 512
+)
+
+var (
+	tracingExportInterval = 5 * time.Second
+	tracingExportTimeout  = 10 * time.Second
+)
+
+// OTLP span kinds and status codes, see https://github.com/open-telemetry/opentelemetry-proto
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+
+	spanStatusCodeError = 2
+)
+
+// tracer records the spans of the request path (client read, inspect and modify, forward to the clusters, response
+// aggregation and client write) and exports them in batches to an OTLP/HTTP endpoint (ZDM_TRACING_OTLP_ENDPOINT).
+// The trace context that a client sends in the "traceparent" key of the custom payload of a request is the parent of
+// the spans of the request, the forwarded requests then carry the trace context of the proxy spans instead.
+type tracer struct {
+	conf       *common.TracingConfig
+	httpClient *http.Client
+	rand       *rand.Rand
+	spans      chan *span
+
+	// spans that were not exported because the queue was full
+	droppedSpans int64
+}
+
+// newTracer returns nil if the tracing is disabled.
+func newTracer(conf *common.TracingConfig) *tracer {
+	if !conf.Enabled {
+		return nil
+	}
+	return &tracer{
+		conf:       conf,
+		httpClient: &http.Client{Timeout: tracingExportTimeout},
+		rand:       NewThreadSafeRand(),
+		spans:      make(chan *span, tracingQueueSize),
+	}
+}
+
+// spanContext identifies a span, it is propagated with the W3C traceparent format.
+type spanContext struct {
+	traceId [16]byte
+	spanId  [8]byte
+	sampled bool
+}
+
+func (recv spanContext) traceParent() string {
+	flags := "00"
+	if recv.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(recv.traceId[:]), hex.EncodeToString(recv.spanId[:]), flags)
+}
+
+// parseTraceParent returns false if the value is not a valid W3C traceparent.
+func parseTraceParent(value string) (spanContext, bool) {
+	var ctx spanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || strings.EqualFold(parts[0], "ff") ||
+		(parts[0] == "00" && len(parts) != 4) || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx, false
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 {
+		return ctx, false
+	}
+	if _, err = hex.Decode(ctx.traceId[:], []byte(parts[1])); err != nil || ctx.traceId == [16]byte{} {
+		return ctx, false
+	}
+	if _, err = hex.Decode(ctx.spanId[:], []byte(parts[2])); err != nil || ctx.spanId == [8]byte{} {
+		return ctx, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return ctx, false
+	}
+	ctx.sampled = flags[0]&0x01 != 0
+	return ctx, true
+}
+
+// readTraceParent returns the trace context of the custom payload of the request, false if there is none. The
+// custom payload of compressed requests is not read.
+func readTraceParent(request *frame.RawFrame) (spanContext, bool) {
+	if !request.Header.Flags.Contains(primitive.HeaderFlagCustomPayload) ||
+		request.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return spanContext{}, false
+	}
+	payload, err := primitive.ReadBytesMap(bytes.NewReader(request.Body))
+	if err != nil {
+		return spanContext{}, false
+	}
+	value, ok := payload[traceParentKey]
+	if !ok {
+		return spanContext{}, false
+	}
+	return parseTraceParent(string(value))
+}
+
+// replaceTraceParent returns a copy of the request whose custom payload has the provided trace context, the request
+// is returned as is if it has no custom payload or if it is compressed. The provided frame is never modified because
+// it is shared with the other components of the proxy.
+func replaceTraceParent(request *frame.RawFrame, ctx spanContext) *frame.RawFrame {
+	if !request.Header.Flags.Contains(primitive.HeaderFlagCustomPayload) ||
+		request.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return request
+	}
+	reader := bytes.NewReader(request.Body)
+	payload, err := primitive.ReadBytesMap(reader)
+	if err != nil {
+		return request
+	}
+	payload[traceParentKey] = []byte(ctx.traceParent())
+
+	body := &bytes.Buffer{}
+	if err = primitive.WriteBytesMap(payload, body); err != nil {
+		return request
+	}
+	body.Write(request.Body[len(request.Body)-reader.Len():])
+
+	header := request.Header.Clone()
+	header.BodyLength = int32(body.Len())
+	return &frame.RawFrame{Header: header, Body: body.Bytes()}
+}
+
+// shouldSample applies the sampler of ZDM_TRACING_SAMPLER to a new trace, parent is nil if the client didn't send a
+// trace context.
+func (recv *tracer) shouldSample(traceId [16]byte, parent *spanContext) bool {
+	switch recv.conf.Sampler {
+	case common.TracingSamplerAlwaysOn:
+		return true
+	case common.TracingSamplerAlwaysOff:
+		return false
+	case common.TracingSamplerTraceIdRatio:
+		return isTraceIdSampled(traceId, recv.conf.SamplerRatio)
+	case common.TracingSamplerParentBasedAlwaysOn:
+		return parent == nil || parent.sampled
+	case common.TracingSamplerParentBasedAlwaysOff:
+		return parent != nil && parent.sampled
+	case common.TracingSamplerParentBasedTraceIdRatio:
+		if parent != nil {
+			return parent.sampled
+		}
+		return isTraceIdSampled(traceId, recv.conf.SamplerRatio)
+	default:
+		return false
+	}
+}
+
+// isTraceIdSampled is the TraceIdRatioBased sampler of the OpenTelemetry SDKs so that the proxy and the clients make
+// the same decision for the same trace id.
+func isTraceIdSampled(traceId [16]byte, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	upperBound := uint64(ratio * (1 << 63))
+	return binary.BigEndian.Uint64(traceId[8:16])>>1 < upperBound
+}
+
+func (recv *tracer) newTraceId() [16]byte {
+	var traceId [16]byte
+	for traceId == [16]byte{} {
+		binary.BigEndian.PutUint64(traceId[:8], recv.rand.Uint64())
+		binary.BigEndian.PutUint64(traceId[8:], recv.rand.Uint64())
+	}
+	return traceId
+}
+
+func (recv *tracer) newSpanId() [8]byte {
+	var spanId [8]byte
+	for spanId == [8]byte{} {
+		binary.BigEndian.PutUint64(spanId[:], recv.rand.Uint64())
+	}
+	return spanId
+}
+
+type spanAttribute struct {
+	key      string
+	value    string
+	intValue int64
+	isInt    bool
+}
+
+// span is a unit of work of a traced request, the methods are no-ops if the span is nil.
+type span struct {
+	tracer       *tracer
+	ctx          spanContext
+	parentSpanId [8]byte
+	name         string
+	kind         int
+	startTime    time.Time
+
+	lock       sync.Mutex
+	endTime    time.Time
+	attributes []spanAttribute
+	errMsg     string
+	ended      bool
+}
+
+func (recv *tracer) startSpan(name string, kind int, traceId [16]byte, parentSpanId [8]byte, startTime time.Time) *span {
+	return &span{
+		tracer:       recv,
+		ctx:          spanContext{traceId: traceId, spanId: recv.newSpanId(), sampled: true},
+		parentSpanId: parentSpanId,
+		name:         name,
+		kind:         kind,
+		startTime:    startTime,
+	}
+}
+
+func (recv *span) setAttribute(key string, value string) {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.attributes = append(recv.attributes, spanAttribute{key: key, value: value})
+}
+
+func (recv *span) setIntAttribute(key string, value int64) {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	recv.attributes = append(recv.attributes, spanAttribute{key: key, intValue: value, isInt: true})
+}
+
+// end records the span with an error status if err is not nil, it returns false if the span had already ended.
+func (recv *span) end(err error) bool {
+	if recv == nil {
+		return false
+	}
+	recv.lock.Lock()
+	if recv.ended {
+		recv.lock.Unlock()
+		return false
+	}
+	recv.ended = true
+	recv.endTime = time.Now()
+	if err != nil {
+		recv.errMsg = err.Error()
+	}
+	recv.lock.Unlock()
+
+	select {
+	case recv.tracer.spans <- recv:
+	default:
+		atomic.AddInt64(&recv.tracer.droppedSpans, 1)
+	}
+	return true
+}
+
+// requestTrace holds the spans of a request, the methods are no-ops if the request is not traced (nil).
+type requestTrace struct {
+	tracer  *tracer
+	root    *span
+	inspect *span
+
+	lock          sync.Mutex
+	originForward *span
+	targetForward *span
+}
+
+// newRequestTrace returns nil if the tracing is disabled or if the request is not sampled.
+func (ch *ClientHandler) newRequestTrace(request *frame.RawFrame, startTime time.Time) *requestTrace {
+	if ch.tracer == nil {
+		return nil
+	}
+	return ch.tracer.newRequestTrace(request, startTime, ch.clientConnector.connection.RemoteAddr().String())
+}
+
+func (recv *tracer) newRequestTrace(request *frame.RawFrame, startTime time.Time, clientAddress string) *requestTrace {
+	var parent *spanContext
+	var traceId [16]byte
+	if parentCtx, ok := readTraceParent(request); ok {
+		parent = &parentCtx
+		traceId = parentCtx.traceId
+	} else {
+		traceId = recv.newTraceId()
+	}
+	if !recv.shouldSample(traceId, parent) {
+		return nil
+	}
+
+	var parentSpanId [8]byte
+	if parent != nil {
+		parentSpanId = parent.spanId
+	}
+	opCode := protocolConstantName(request.Header.OpCode)
+	root := recv.startSpan(opCode, spanKindServer, traceId, parentSpanId, startTime)
+	root.setAttribute("db.system", "cassandra")
+	root.setAttribute("db.operation", opCode)
+	root.setAttribute("client.address", clientAddress)
+	root.setIntAttribute("cql.stream_id", int64(request.Header.StreamId))
+	root.setIntAttribute("cql.protocol_version", int64(request.Header.Version))
+	return &requestTrace{
+		tracer:  recv,
+		root:    root,
+		inspect: recv.startSpan("inspect request", spanKindInternal, traceId, root.ctx.spanId, startTime),
+	}
+}
+
+// protocolConstantName returns the name of a protocol constant without its code, e.g. QUERY or LOCAL_QUORUM.
+func protocolConstantName(constant fmt.Stringer) string {
+	fields := strings.Fields(constant.String())
+	if len(fields) < 2 {
+		return constant.String()
+	}
+	return fields[1]
+}
+
+// startSpan starts a child span of the root span.
+func (recv *requestTrace) startSpan(name string) *span {
+	if recv == nil {
+		return nil
+	}
+	return recv.tracer.startSpan(name, spanKindInternal, recv.root.ctx.traceId, recv.root.ctx.spanId, time.Now())
+}
+
+// endInspect ends the span of the inspection and modification of the request once it is forwarded to the clusters.
+func (recv *requestTrace) endInspect(fwdDecision forwardDecision) {
+	if recv == nil {
+		return
+	}
+	recv.root.setAttribute("zdm.forward_decision", string(fwdDecision))
+	recv.inspect.end(nil)
+}
+
+// startForward starts the span of the request that is forwarded to the cluster and returns the request that should be
+// forwarded, the trace context of its custom payload is replaced with the context of the span.
+func (recv *requestTrace) startForward(cluster common.ClusterType, request *frame.RawFrame) *frame.RawFrame {
+	if recv == nil || request == nil {
+		return request
+	}
+	forwardSpan := recv.tracer.startSpan(
+		fmt.Sprintf("forward to %v", cluster), spanKindClient, recv.root.ctx.traceId, recv.root.ctx.spanId, time.Now())
+	forwardSpan.setAttribute("zdm.cluster", string(cluster))
+
+	recv.lock.Lock()
+	if cluster == common.ClusterTypeOrigin {
+		recv.originForward = forwardSpan
+	} else {
+		recv.targetForward = forwardSpan
+	}
+	recv.lock.Unlock()
+	return replaceTraceParent(request, forwardSpan.ctx)
+}
+
+// endForward ends the span of the request that was forwarded to the cluster when its response is received.
+func (recv *requestTrace) endForward(cluster common.ClusterType, response *frame.RawFrame) {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	forwardSpan := recv.originForward
+	if cluster == common.ClusterTypeTarget {
+		forwardSpan = recv.targetForward
+	}
+	recv.lock.Unlock()
+	if forwardSpan == nil {
+		return
+	}
+
+	forwardSpan.setAttribute("cql.response", protocolConstantName(response.Header.OpCode))
+	forwardSpan.end(responseError(response))
+}
+
+// endForwardTimeouts ends the spans of the forwarded requests that didn't receive a response.
+func (recv *requestTrace) endForwardTimeouts() {
+	if recv == nil {
+		return
+	}
+	recv.lock.Lock()
+	forwardSpans := []*span{recv.originForward, recv.targetForward}
+	recv.lock.Unlock()
+	for _, forwardSpan := range forwardSpans {
+		forwardSpan.end(fmt.Errorf("request timed out"))
+	}
+}
+
+// end ends the spans of the request that are still open, response is nil if no response was sent to the client.
+func (recv *requestTrace) end(response *frame.RawFrame, err error) {
+	if recv == nil {
+		return
+	}
+	recv.inspect.end(err)
+	if err == nil && response != nil {
+		recv.root.setAttribute("cql.response", protocolConstantName(response.Header.OpCode))
+		err = responseError(response)
+	}
+	recv.root.end(err)
+}
+
+// responseError returns the error of an ERROR response, nil if it is not an ERROR response.
+func responseError(response *frame.RawFrame) error {
+	if response.Header.OpCode != primitive.OpCodeError {
+		return nil
+	}
+	errMsg, err := decodeError(response)
+	if err != nil || errMsg == nil {
+		return fmt.Errorf("error response")
+	}
+	return fmt.Errorf("%v (%v)", errMsg.GetErrorMessage(), errMsg.GetErrorCode())
+}
+
+// run exports the spans until the context is canceled, the remaining spans are then exported one last time.
+func (recv *tracer) run(ctx context.Context) {
+	ticker := time.NewTicker(tracingExportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*span, 0, tracingBatchSize)
+	for {
+		select {
+		case s := <-recv.spans:
+			batch = append(batch, s)
+			if len(batch) < tracingBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			for len(recv.spans) > 0 {
+				batch = append(batch, <-recv.spans)
+			}
+			exportCtx, cancelFn := context.WithTimeout(context.Background(), tracingExportTimeout)
+			recv.export(exportCtx, batch)
+			cancelFn()
+			recv.httpClient.CloseIdleConnections()
+			return
+		}
+		recv.export(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+func (recv *tracer) export(ctx context.Context, batch []*span) {
+	if dropped := atomic.SwapInt64(&recv.droppedSpans, 0); dropped > 0 {
+		log.Warnf("Dropped %v tracing spans because the export queue was full.", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(recv.newExportRequest(batch))
+	if err != nil {
+		log.Errorf("Could not encode tracing spans: %v.", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, recv.conf.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("Could not export %v tracing spans: %v.", len(batch), err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err := recv.httpClient.Do(req)
+	if err == nil {
+		_ = rsp.Body.Close()
+		if rsp.StatusCode/100 != 2 {
+			err = fmt.Errorf("unexpected status %v", rsp.Status)
+		}
+	}
+	if err != nil {
+		log.Warnf("Could not export %v tracing spans: %v.", len(batch), err)
+	}
+}
+
+// OtlpExportRequest is the JSON encoding of the OTLP ExportTraceServiceRequest message.
+type OtlpExportRequest struct {
+	ResourceSpans []*OtlpResourceSpans `json:"resourceSpans"`
+}
+
+type OtlpResourceSpans struct {
+	Resource   *OtlpResource     `json:"resource"`
+	ScopeSpans []*OtlpScopeSpans `json:"scopeSpans"`
+}
+
+type OtlpResource struct {
+	Attributes []*OtlpAttribute `json:"attributes"`
+}
+
+type OtlpScopeSpans struct {
+	Scope *OtlpScope  `json:"scope"`
+	Spans []*OtlpSpan `json:"spans"`
+}
+
+type OtlpScope struct {
+	Name string `json:"name"`
+}
+
+type OtlpSpan struct {
+	TraceId           string           `json:"traceId"`
+	SpanId            string           `json:"spanId"`
+	ParentSpanId      string           `json:"parentSpanId,omitempty"`
+	Name              string           `json:"name"`
+	Kind              int              `json:"kind"`
+	StartTimeUnixNano string           `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string           `json:"endTimeUnixNano"`
+	Attributes        []*OtlpAttribute `json:"attributes,omitempty"`
+	Status            *OtlpStatus      `json:"status,omitempty"`
+}
+
+type OtlpAttribute struct {
+	Key   string              `json:"key"`
+	Value *OtlpAttributeValue `json:"value"`
+}
+
+type OtlpAttributeValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type OtlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func newOtlpStringAttribute(key string, value string) *OtlpAttribute {
+	return &OtlpAttribute{Key: key, Value: &OtlpAttributeValue{StringValue: &value}}
+}
+
+func (recv *tracer) newExportRequest(batch []*span) *OtlpExportRequest {
+	spans := make([]*OtlpSpan, 0, len(batch))
+	for _, s := range batch {
+		spans = append(spans, s.toOtlp())
+	}
+	return &OtlpExportRequest{ResourceSpans: []*OtlpResourceSpans{{
+		Resource: &OtlpResource{Attributes: []*OtlpAttribute{
+			newOtlpStringAttribute("service.name", recv.conf.ServiceName),
+		}},
+		ScopeSpans: []*OtlpScopeSpans{{
+			Scope: &OtlpScope{Name: "zdm-proxy"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func (recv *span) toOtlp() *OtlpSpan {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+
+	otlpSpan := &OtlpSpan{
+		TraceId:           hex.EncodeToString(recv.ctx.traceId[:]),
+		SpanId:            hex.EncodeToString(recv.ctx.spanId[:]),
+		Name:              recv.name,
+		Kind:              recv.kind,
+		StartTimeUnixNano: strconv.FormatInt(recv.startTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(recv.endTime.UnixNano(), 10),
+	}
+	if recv.parentSpanId != [8]byte{} {
+		otlpSpan.ParentSpanId = hex.EncodeToString(recv.parentSpanId[:])
+	}
+	for _, attribute := range recv.attributes {
+		if attribute.isInt {
+			intValue := strconv.FormatInt(attribute.intValue, 10)
+			otlpSpan.Attributes = append(otlpSpan.Attributes,
+				&OtlpAttribute{Key: attribute.key, Value: &OtlpAttributeValue{IntValue: &intValue}})
+		} else {
+			otlpSpan.Attributes = append(otlpSpan.Attributes, newOtlpStringAttribute(attribute.key, attribute.value))
+		}
+	}
+	if recv.errMsg != "" {
+		otlpSpan.Status = &OtlpStatus{Code: spanStatusCodeError, Message: recv.errMsg}
+	}
+	return otlpSpan
+}
@@ -0,0 +1,205 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newTracedQuery(t *testing.T, customPayload map[string][]byte) *frame.RawFrame {
+	f := frame.NewFrame(primitive.ProtocolVersion4, 3, &message.Query{
+		Query: "SELECT * FROM ks.tb", Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelOne}})
+	f.SetCustomPayload(customPayload)
+	request, err := defaultCodec.ConvertToRawFrame(f)
+	require.Nil(t, err)
+	return request
+}
+
+func TestParseTraceParent(t *testing.T) {
+	ctx, ok := parseTraceParent(testTraceParent)
+	require.True(t, ok)
+	require.True(t, ctx.sampled)
+	require.Equal(t, testTraceParent, ctx.traceParent())
+
+	ctx, ok = parseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.True(t, ok)
+	require.False(t, ctx.sampled)
+
+	// future versions may have more fields
+	_, ok = parseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	require.True(t, ok)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+	} {
+		_, ok = parseTraceParent(invalid)
+		require.False(t, ok, invalid)
+	}
+}
+
+func TestReplaceTraceParent(t *testing.T) {
+	request := newTracedQuery(t, map[string][]byte{traceParentKey: []byte(testTraceParent), "other": {1, 2}})
+	parent, ok := readTraceParent(request)
+	require.True(t, ok)
+
+	tracer := newTracer(&common.TracingConfig{Enabled: true, Sampler: common.TracingSamplerAlwaysOn})
+	ctx := spanContext{traceId: parent.traceId, spanId: tracer.newSpanId(), sampled: true}
+	replaced := replaceTraceParent(request, ctx)
+	require.NotSame(t, request, replaced)
+	require.Equal(t, testTraceParent, mustReadCustomPayload(t, request)[traceParentKey])
+
+	replacedCtx, ok := readTraceParent(replaced)
+	require.True(t, ok)
+	require.Equal(t, ctx, replacedCtx)
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(replaced)
+	require.Nil(t, err)
+	require.Equal(t, []byte{1, 2}, decodedFrame.Body.CustomPayload["other"])
+	require.Equal(t, "SELECT * FROM ks.tb", decodedFrame.Body.Message.(*message.Query).Query)
+
+	// requests without custom payload are forwarded as is
+	request = newTracedQuery(t, nil)
+	require.Same(t, request, replaceTraceParent(request, ctx))
+	_, ok = readTraceParent(request)
+	require.False(t, ok)
+}
+
+func mustReadCustomPayload(t *testing.T, request *frame.RawFrame) map[string]string {
+	payload, err := primitive.ReadBytesMap(bytes.NewReader(request.Body))
+	require.Nil(t, err)
+	result := make(map[string]string)
+	for key, value := range payload {
+		result[key] = string(value)
+	}
+	return result
+}
+
+func TestTracer_ShouldSample(t *testing.T) {
+	lowTraceId := [16]byte{15: 1}
+	highTraceId := [16]byte{8: 0xFF, 15: 0xFF}
+	sampledParent := &spanContext{sampled: true}
+	unsampledParent := &spanContext{sampled: false}
+
+	type test struct {
+		sampler  common.TracingSampler
+		traceId  [16]byte
+		parent   *spanContext
+		expected bool
+	}
+	tests := []test{
+		{common.TracingSamplerAlwaysOn, highTraceId, unsampledParent, true},
+		{common.TracingSamplerAlwaysOff, lowTraceId, sampledParent, false},
+		{common.TracingSamplerTraceIdRatio, lowTraceId, nil, true},
+		{common.TracingSamplerTraceIdRatio, highTraceId, nil, false},
+		{common.TracingSamplerTraceIdRatio, highTraceId, sampledParent, false},
+		{common.TracingSamplerParentBasedAlwaysOn, highTraceId, nil, true},
+		{common.TracingSamplerParentBasedAlwaysOn, highTraceId, unsampledParent, false},
+		{common.TracingSamplerParentBasedAlwaysOff, highTraceId, nil, false},
+		{common.TracingSamplerParentBasedAlwaysOff, highTraceId, sampledParent, true},
+		{common.TracingSamplerParentBasedTraceIdRatio, lowTraceId, nil, true},
+		{common.TracingSamplerParentBasedTraceIdRatio, highTraceId, nil, false},
+		{common.TracingSamplerParentBasedTraceIdRatio, highTraceId, sampledParent, true},
+	}
+	for _, tt := range tests {
+		tracer := newTracer(&common.TracingConfig{Enabled: true, Sampler: tt.sampler, SamplerRatio: 0.5})
+		require.Equal(t, tt.expected, tracer.shouldSample(tt.traceId, tt.parent), "%v %v", tt.sampler, tt.parent)
+	}
+}
+
+func TestTracer_Export(t *testing.T) {
+	require.Nil(t, newTracer(&common.TracingConfig{}))
+	var disabledTrace *requestTrace
+	disabledTrace.endInspect(forwardToBoth)
+	require.Nil(t, disabledTrace.startSpan("aggregate responses"))
+	disabledTrace.end(nil, nil)
+
+	requestsLock := &sync.Mutex{}
+	var requests []*OtlpExportRequest
+	server := httptest.NewServer(http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		var exportRequest *OtlpExportRequest
+		require.Nil(t, json.NewDecoder(req.Body).Decode(&exportRequest))
+		requestsLock.Lock()
+		defer requestsLock.Unlock()
+		requests = append(requests, exportRequest)
+	}))
+	defer server.Close()
+
+	tracer := newTracer(&common.TracingConfig{
+		Enabled: true, Endpoint: server.URL, Sampler: common.TracingSamplerParentBasedAlwaysOn, ServiceName: "zdm"})
+	unsampledRequest := newTracedQuery(t, map[string][]byte{
+		traceParentKey: []byte("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")})
+	require.Nil(t, tracer.newRequestTrace(unsampledRequest, time.Now(), "127.0.0.1:9042"))
+
+	request := newTracedQuery(t, map[string][]byte{traceParentKey: []byte(testTraceParent)})
+	trace := tracer.newRequestTrace(request, time.Now(), "127.0.0.1:9042")
+	require.NotNil(t, trace)
+	trace.endInspect(forwardToBoth)
+	originRequest := trace.startForward(common.ClusterTypeOrigin, request)
+	targetRequest := trace.startForward(common.ClusterTypeTarget, request)
+	originCtx, _ := readTraceParent(originRequest)
+	targetCtx, _ := readTraceParent(targetRequest)
+	require.NotEqual(t, originCtx.spanId, targetCtx.spanId)
+
+	response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 3, &message.VoidResult{}))
+	require.Nil(t, err)
+	errorResponse, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 3,
+		&message.Overloaded{ErrorMessage: "overloaded"}))
+	require.Nil(t, err)
+	trace.endForward(common.ClusterTypeOrigin, response)
+	trace.endForward(common.ClusterTypeTarget, errorResponse)
+	trace.startSpan("aggregate responses").end(nil)
+	trace.end(errorResponse, nil)
+	trace.end(response, nil)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		tracer.run(ctx)
+		close(done)
+	}()
+	cancelFn()
+	<-done
+
+	require.Equal(t, 1, len(requests))
+	resourceSpans := requests[0].ResourceSpans[0]
+	require.Equal(t, "service.name", resourceSpans.Resource.Attributes[0].Key)
+	require.Equal(t, "zdm", *resourceSpans.Resource.Attributes[0].Value.StringValue)
+	spans := make(map[string]*OtlpSpan)
+	for _, s := range resourceSpans.ScopeSpans[0].Spans {
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.TraceId)
+		spans[s.Name] = s
+	}
+	require.Equal(t, 5, len(spans))
+
+	root := spans["QUERY"]
+	require.Equal(t, "00f067aa0ba902b7", root.ParentSpanId)
+	require.Equal(t, spanKindServer, root.Kind)
+	require.Equal(t, &OtlpStatus{Code: spanStatusCodeError, Message: "overloaded (ErrorCode Overloaded [0x00001001])"}, root.Status)
+	for _, name := range []string{"inspect request", "forward to ORIGIN", "forward to TARGET", "aggregate responses"} {
+		require.Equal(t, root.SpanId, spans[name].ParentSpanId, name)
+	}
+	require.Equal(t, originCtx.traceParent()[36:52], spans["forward to ORIGIN"].SpanId)
+	require.Equal(t, spanKindClient, spans["forward to ORIGIN"].Kind)
+	require.Nil(t, spans["forward to ORIGIN"].Status)
+	require.NotNil(t, spans["forward to TARGET"].Status)
+}
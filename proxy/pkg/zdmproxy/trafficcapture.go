@@ -0,0 +1,446 @@
+package zdmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// the frames are written as TCP segments of raw IP packets (LINKTYPE_RAW)
+	pcapLinkTypeRaw     = 101
+	pcapSnapLen         = 262144
+	pcapMaxSegmentBytes = 65000
+)
+
+// TrafficCapture records the frames that are exchanged with the client connections into pcap files for offline
+// analysis (see ZDM_TRAFFIC_CAPTURE_DIRECTORY), a capture is started and stopped with the admin API. Every frame is
+// written as a TCP segment between the client and the proxy so the files can be opened with Wireshark and decoded
+// with its CQL dissector ("Decode As..." CQL on the port of the proxy).
+//
+// The token of AUTH_RESPONSE requests is always removed. If the capture is redacted then the bound values, the
+// literals of the statements (see maskCqlLiterals) and the rows of the results are removed as well, the frames that
+// can't be redacted (i.e. compressed frames) are not written.
+type TrafficCapture struct {
+	lock             *sync.Mutex
+	active           int32 // 1 if session is not nil, it is read without holding the lock
+	session          *trafficCaptureSession
+	lastStatus       *TrafficCaptureStatus // status of the last capture that was stopped
+	directory        string
+	maxDuration      time.Duration
+	maxFileSizeBytes int64
+	now              func() time.Time
+}
+
+// TrafficCaptureStatus is the state of a capture that is reported by the admin API.
+type TrafficCaptureStatus struct {
+	Active        bool
+	File          string
+	Clients       []string // empty if every client connection is captured
+	Redacted      bool
+	StartedAt     time.Time
+	EndsAt        time.Time // the capture ended at this time if it is not active
+	Frames        int
+	SkippedFrames int // frames that could not be redacted
+	Bytes         int64
+	StopReason    string `json:",omitempty"`
+}
+
+type trafficCaptureSession struct {
+	file        *os.File
+	writer      *bufio.Writer
+	clients     map[string]bool // client IPs and addresses (IP:port) to capture, every client if empty
+	status      *TrafficCaptureStatus
+	connections map[string]*capturedConnection // keyed by client address
+	timer       *time.Timer
+}
+
+// capturedConnection has the TCP sequence numbers of both directions of a captured client connection.
+type capturedConnection struct {
+	clientSeq uint32
+	proxySeq  uint32
+}
+
+// newTrafficCapture returns nil if the traffic capture is disabled.
+func newTrafficCapture(conf *common.TrafficCaptureConfig) *TrafficCapture {
+	if !conf.Enabled {
+		return nil
+	}
+	return &TrafficCapture{
+		lock:             &sync.Mutex{},
+		directory:        conf.Directory,
+		maxDuration:      time.Duration(conf.MaxDurationMs) * time.Millisecond,
+		maxFileSizeBytes: int64(conf.MaxFileSizeBytes),
+		now:              time.Now,
+	}
+}
+
+// Start starts a capture of the connections of the provided clients (IPs or IP:port addresses, every client if
+// empty) that stops after the provided duration.
+func (recv *TrafficCapture) Start(duration time.Duration, clients []string, redact bool) (*TrafficCaptureStatus, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("capture duration must be positive but was %v", duration)
+	}
+	if duration > recv.maxDuration {
+		return nil, fmt.Errorf("capture duration %v exceeds the maximum of %v (ZDM_TRAFFIC_CAPTURE_MAX_DURATION_MS)",
+			duration, recv.maxDuration)
+	}
+	normalizedClients, err := normalizeClientAddresses(clients)
+	if err != nil {
+		return nil, err
+	}
+	clientSet := make(map[string]bool)
+	for _, client := range normalizedClients {
+		clientSet[client] = true
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.session != nil {
+		return nil, fmt.Errorf("a capture is already in progress (%v)", recv.session.status.File)
+	}
+
+	now := recv.now()
+	fileName := fmt.Sprintf("zdm-capture-%v-%v.pcap", now.UTC().Format("20060102T150405Z"), uuid.New().String()[:8])
+	file, err := os.OpenFile(filepath.Join(recv.directory, fileName), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not create capture file: %w", err)
+	}
+
+	session := &trafficCaptureSession{
+		file:        file,
+		writer:      bufio.NewWriter(file),
+		clients:     clientSet,
+		connections: make(map[string]*capturedConnection),
+		status: &TrafficCaptureStatus{
+			Active:    true,
+			File:      file.Name(),
+			Clients:   make([]string, 0, len(clientSet)),
+			Redacted:  redact,
+			StartedAt: now,
+			EndsAt:    now.Add(duration),
+		},
+	}
+	for client := range clientSet {
+		session.status.Clients = append(session.status.Clients, client)
+	}
+
+	// libpcap global header
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], pcapLinkTypeRaw)
+	_, err = session.writer.Write(header)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not write capture file: %w", err)
+	}
+	session.status.Bytes = int64(len(header))
+
+	session.timer = time.AfterFunc(duration, func() {
+		recv.stop(session, "the duration elapsed")
+	})
+	recv.session = session
+	atomic.StoreInt32(&recv.active, 1)
+	return recv.copyStatus(session.status), nil
+}
+
+// Stop stops the capture in progress and returns the status of the last capture (nil if there wasn't any).
+func (recv *TrafficCapture) Stop() *TrafficCaptureStatus {
+	recv.stopCurrent("stopped through the admin API")
+	return recv.GetStatus()
+}
+
+// GetStatus returns the status of the capture in progress or of the last capture (nil if there wasn't any).
+func (recv *TrafficCapture) GetStatus() *TrafficCaptureStatus {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.session != nil {
+		return recv.copyStatus(recv.session.status)
+	}
+	return recv.copyStatus(recv.lastStatus)
+}
+
+func (recv *TrafficCapture) copyStatus(status *TrafficCaptureStatus) *TrafficCaptureStatus {
+	if status == nil {
+		return nil
+	}
+	statusCopy := *status
+	statusCopy.Clients = append([]string{}, status.Clients...)
+	return &statusCopy
+}
+
+// shutdown stops the capture in progress when the proxy is shut down.
+func (recv *TrafficCapture) shutdown() {
+	if recv == nil {
+		return
+	}
+	recv.stopCurrent("the proxy was shut down")
+}
+
+func (recv *TrafficCapture) stopCurrent(reason string) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.session != nil {
+		recv.stopLocked(reason)
+	}
+}
+
+// stop stops the provided capture if it is still in progress.
+func (recv *TrafficCapture) stop(session *trafficCaptureSession, reason string) {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if recv.session != session {
+		return
+	}
+	recv.stopLocked(reason)
+}
+
+func (recv *TrafficCapture) stopLocked(reason string) {
+	session := recv.session
+	atomic.StoreInt32(&recv.active, 0)
+	recv.session = nil
+	session.timer.Stop()
+
+	err := session.writer.Flush()
+	if closeErr := session.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		reason = fmt.Sprintf("%v (could not write capture file: %v)", reason, err)
+	}
+
+	session.status.Active = false
+	session.status.EndsAt = recv.now()
+	session.status.StopReason = reason
+	recv.lastStatus = session.status
+	log.Infof("Traffic capture %v stopped, %v: %v frames (%v bytes) were captured.",
+		session.status.File, reason, session.status.Frames, session.status.Bytes)
+}
+
+// record writes the frame to the capture in progress if the client connection is captured, fromClient is true for
+// the requests and false for the responses and events.
+func (recv *TrafficCapture) record(clientAddr net.Addr, proxyAddr net.Addr, fromClient bool, f *frame.RawFrame) {
+	if recv == nil || atomic.LoadInt32(&recv.active) == 0 {
+		return
+	}
+
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	session := recv.session
+	if session == nil {
+		return
+	}
+	client := tcpAddrOf(clientAddr)
+	if len(session.clients) > 0 && !session.clients[client.IP.String()] && !session.clients[client.String()] {
+		return
+	}
+
+	capturedFrame, err := redactFrame(f, session.status.Redacted)
+	if err != nil {
+		log.Debugf("Frame %v of client %v was not captured because it could not be redacted: %v.",
+			f.Header, clientAddr, err)
+		session.status.SkippedFrames++
+		return
+	}
+	buf := &bytes.Buffer{}
+	err = defaultCodec.EncodeRawFrame(capturedFrame, buf)
+	if err != nil {
+		log.Debugf("Frame %v of client %v was not captured because it could not be encoded: %v.",
+			f.Header, clientAddr, err)
+		session.status.SkippedFrames++
+		return
+	}
+
+	connection, ok := session.connections[client.String()]
+	if !ok {
+		connection = &capturedConnection{clientSeq: 1, proxySeq: 1}
+		session.connections[client.String()] = connection
+	}
+	src, dst := client, tcpAddrOf(proxyAddr)
+	seq, ack := &connection.clientSeq, connection.proxySeq
+	if !fromClient {
+		src, dst = dst, src
+		seq, ack = &connection.proxySeq, connection.clientSeq
+	}
+
+	now := recv.now()
+	payload := buf.Bytes()
+	for len(payload) > 0 {
+		segment := payload
+		if len(segment) > pcapMaxSegmentBytes {
+			segment = segment[:pcapMaxSegmentBytes]
+		}
+		payload = payload[len(segment):]
+		packet := newCapturePacket(src, dst, *seq, ack, segment)
+		*seq += uint32(len(segment))
+
+		recordHeader := make([]byte, 16)
+		binary.LittleEndian.PutUint32(recordHeader[0:], uint32(now.Unix()))
+		binary.LittleEndian.PutUint32(recordHeader[4:], uint32(now.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(recordHeader[8:], uint32(len(packet)))
+		binary.LittleEndian.PutUint32(recordHeader[12:], uint32(len(packet)))
+		_, err = session.writer.Write(recordHeader)
+		if err == nil {
+			_, err = session.writer.Write(packet)
+		}
+		if err != nil {
+			recv.stopLocked(fmt.Sprintf("could not write capture file: %v", err))
+			return
+		}
+		session.status.Bytes += int64(len(recordHeader) + len(packet))
+	}
+	session.status.Frames++
+
+	if session.status.Bytes >= recv.maxFileSizeBytes {
+		recv.stopLocked("the maximum file size was reached (ZDM_TRAFFIC_CAPTURE_MAX_FILE_SIZE_BYTES)")
+	}
+}
+
+// redactFrame returns a copy of the frame without the token of AUTH_RESPONSE requests and, if redact is true, without
+// the bound values, the literals of the statements and the rows of the results. It returns an error if the frame
+// can't be redacted (i.e. compressed frames). The provided frame is never modified because it is shared with the
+// other components of the proxy.
+func redactFrame(f *frame.RawFrame, redact bool) (*frame.RawFrame, error) {
+	f = &frame.RawFrame{Header: f.Header.Clone(), Body: f.Body}
+	if f.Header.OpCode == primitive.OpCodeAuthResponse {
+		// the token has the credentials of the client, it is replaced with a null token
+		f.Header.Flags = f.Header.Flags.Remove(primitive.HeaderFlagCompressed).Remove(primitive.HeaderFlagCustomPayload)
+		f.Body = []byte{0xff, 0xff, 0xff, 0xff}
+		return f, nil
+	}
+	if !redact {
+		return f, nil
+	}
+	if f.Header.Flags.Contains(primitive.HeaderFlagCompressed) {
+		return nil, fmt.Errorf("the frame is compressed")
+	}
+
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(f)
+	if err != nil {
+		return nil, err
+	}
+	switch msg := decodedFrame.Body.Message.(type) {
+	case *message.Query:
+		msg.Query = maskCqlLiterals(msg.Query)
+		redactQueryOptions(msg.Options)
+	case *message.Prepare:
+		msg.Query = maskCqlLiterals(msg.Query)
+	case *message.Execute:
+		redactQueryOptions(msg.Options)
+	case *message.Batch:
+		for _, child := range msg.Children {
+			if query, ok := child.QueryOrId.(string); ok {
+				child.QueryOrId = maskCqlLiterals(query)
+			}
+			for i := range child.Values {
+				child.Values[i] = primitive.NewValue(nil)
+			}
+		}
+	case *message.RowsResult:
+		for _, row := range msg.Data {
+			for i := range row {
+				row[i] = nil
+			}
+		}
+	default:
+		return f, nil
+	}
+	return defaultCodec.ConvertToRawFrame(decodedFrame)
+}
+
+func redactQueryOptions(options *message.QueryOptions) {
+	if options == nil {
+		return
+	}
+	for i := range options.PositionalValues {
+		options.PositionalValues[i] = primitive.NewValue(nil)
+	}
+	for name := range options.NamedValues {
+		options.NamedValues[name] = primitive.NewValue(nil)
+	}
+}
+
+// newCapturePacket returns an IPv4 or IPv6 packet with a TCP segment that has the payload, the TCP checksum is not set.
+func newCapturePacket(src *net.TCPAddr, dst *net.TCPAddr, seq uint32, ack uint32, payload []byte) []byte {
+	tcpSegment := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint16(tcpSegment[0:], uint16(src.Port))
+	binary.BigEndian.PutUint16(tcpSegment[2:], uint16(dst.Port))
+	binary.BigEndian.PutUint32(tcpSegment[4:], seq)
+	binary.BigEndian.PutUint32(tcpSegment[8:], ack)
+	tcpSegment[12] = 5 << 4 // data offset
+	tcpSegment[13] = 0x18   // PSH and ACK
+	binary.BigEndian.PutUint16(tcpSegment[14:], 65535)
+	copy(tcpSegment[20:], payload)
+
+	srcIp4, dstIp4 := src.IP.To4(), dst.IP.To4()
+	if srcIp4 != nil && dstIp4 != nil {
+		packet := make([]byte, 20, 20+len(tcpSegment))
+		packet[0] = 0x45 // version and header length
+		binary.BigEndian.PutUint16(packet[2:], uint16(20+len(tcpSegment)))
+		binary.BigEndian.PutUint16(packet[6:], 0x4000) // don't fragment
+		packet[8] = 64                                 // TTL
+		packet[9] = 6                                  // TCP
+		copy(packet[12:], srcIp4)
+		copy(packet[16:], dstIp4)
+		var checksum uint32
+		for i := 0; i < 20; i += 2 {
+			checksum += uint32(binary.BigEndian.Uint16(packet[i:]))
+		}
+		for checksum > 0xffff {
+			checksum = (checksum >> 16) + (checksum & 0xffff)
+		}
+		binary.BigEndian.PutUint16(packet[10:], ^uint16(checksum))
+		return append(packet, tcpSegment...)
+	}
+
+	packet := make([]byte, 40, 40+len(tcpSegment))
+	packet[0] = 0x60 // version
+	binary.BigEndian.PutUint16(packet[4:], uint16(len(tcpSegment)))
+	packet[6] = 6  // TCP
+	packet[7] = 64 // hop limit
+	copy(packet[8:], src.IP.To16())
+	copy(packet[24:], dst.IP.To16())
+	return append(packet, tcpSegment...)
+}
+
+func tcpAddrOf(addr net.Addr) *net.TCPAddr {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok && tcpAddr.IP != nil {
+		return tcpAddr
+	}
+	if addr != nil {
+		if tcpAddr, err := net.ResolveTCPAddr("tcp", addr.String()); err == nil && tcpAddr.IP != nil {
+			return tcpAddr
+		}
+	}
+	return &net.TCPAddr{IP: net.IPv4zero}
+}
+
+// normalizeClientAddress returns the canonical form of a client IP or IP:port address.
+func normalizeClientAddress(client string) (string, error) {
+	if ip := net.ParseIP(client); ip != nil {
+		return ip.String(), nil
+	}
+	host, port, err := net.SplitHostPort(client)
+	if err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			return net.JoinHostPort(ip.String(), port), nil
+		}
+	}
+	return "", fmt.Errorf("invalid client %v, it must be an IP or an IP:port address", client)
+}
@@ -0,0 +1,218 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+type capturedFrame struct {
+	src   string
+	dst   string
+	frame *frame.Frame
+}
+
+// readCaptureFile returns the frames of a capture file in the order in which they were written.
+func readCaptureFile(t *testing.T, fileName string) []*capturedFrame {
+	data, err := os.ReadFile(fileName)
+	require.Nil(t, err)
+	require.GreaterOrEqual(t, len(data), 24)
+	require.Equal(t, uint32(0xa1b2c3d4), binary.LittleEndian.Uint32(data[0:]))
+	require.Equal(t, uint32(pcapLinkTypeRaw), binary.LittleEndian.Uint32(data[20:]))
+	data = data[24:]
+
+	var frames []*capturedFrame
+	streams := map[string]*bytes.Buffer{}
+	for len(data) > 0 {
+		packetLength := int(binary.LittleEndian.Uint32(data[8:]))
+		packet := data[16 : 16+packetLength]
+		data = data[16+packetLength:]
+
+		var srcIp, dstIp net.IP
+		var tcpSegment []byte
+		if packet[0]>>4 == 4 {
+			require.Equal(t, packetLength, int(binary.BigEndian.Uint16(packet[2:])))
+			srcIp, dstIp, tcpSegment = packet[12:16], packet[16:20], packet[20:]
+		} else {
+			require.Equal(t, byte(0x60), packet[0])
+			require.Equal(t, packetLength-40, int(binary.BigEndian.Uint16(packet[4:])))
+			srcIp, dstIp, tcpSegment = packet[8:24], packet[24:40], packet[40:]
+		}
+		src := (&net.TCPAddr{IP: srcIp, Port: int(binary.BigEndian.Uint16(tcpSegment[0:]))}).String()
+		dst := (&net.TCPAddr{IP: dstIp, Port: int(binary.BigEndian.Uint16(tcpSegment[2:]))}).String()
+
+		stream, ok := streams[src]
+		if !ok {
+			stream = &bytes.Buffer{}
+			streams[src] = stream
+		}
+		stream.Write(tcpSegment[20:])
+		for stream.Len() >= primitive.FrameHeaderLengthV3AndHigher {
+			bodyLength := int(binary.BigEndian.Uint32(stream.Bytes()[5:]))
+			if stream.Len() < primitive.FrameHeaderLengthV3AndHigher+bodyLength {
+				break
+			}
+			decodedFrame, err := defaultCodec.DecodeFrame(stream)
+			require.Nil(t, err)
+			frames = append(frames, &capturedFrame{src: src, dst: dst, frame: decodedFrame})
+		}
+	}
+	for _, stream := range streams {
+		require.Equal(t, 0, stream.Len())
+	}
+	return frames
+}
+
+func newCaptureTestFrame(t *testing.T, streamId int16, msg message.Message) *frame.RawFrame {
+	rawFrame, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, streamId, msg))
+	require.Nil(t, err)
+	return rawFrame
+}
+
+func TestTrafficCapture(t *testing.T) {
+	require.Nil(t, newTrafficCapture(&common.TrafficCaptureConfig{}))
+	var disabledCapture *TrafficCapture
+	disabledCapture.record(nil, nil, true, nil)
+	disabledCapture.shutdown()
+
+	capture := newTrafficCapture(&common.TrafficCaptureConfig{
+		Enabled: true, Directory: t.TempDir(), MaxDurationMs: 60000, MaxFileSizeBytes: 1024 * 1024})
+	require.Nil(t, capture.GetStatus())
+
+	_, err := capture.Start(0, nil, false)
+	require.Equal(t, "capture duration must be positive but was 0s", err.Error())
+	_, err = capture.Start(2*time.Minute, nil, false)
+	require.Equal(t, "capture duration 2m0s exceeds the maximum of 1m0s (ZDM_TRAFFIC_CAPTURE_MAX_DURATION_MS)", err.Error())
+	_, err = capture.Start(time.Minute, []string{"proxy1"}, false)
+	require.Equal(t, "invalid client proxy1, it must be an IP or an IP:port address", err.Error())
+
+	status, err := capture.Start(time.Minute, []string{"127.0.0.1", " 10.0.0.1:5000"}, false)
+	require.Nil(t, err)
+	require.True(t, status.Active)
+	require.ElementsMatch(t, []string{"127.0.0.1", "10.0.0.1:5000"}, status.Clients)
+	_, err = capture.Start(time.Minute, nil, false)
+	require.Contains(t, err.Error(), "a capture is already in progress")
+
+	proxy := &net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 14002}
+	client := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 6000}
+	otherClient := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5001}
+	query := &message.Query{Query: "SELECT * FROM ks.tb WHERE k = 'secret'", Options: &message.QueryOptions{
+		PositionalValues: []*primitive.Value{primitive.NewValue([]byte("value"))}}}
+	capture.record(client, proxy, true, newCaptureTestFrame(t, 1, &message.AuthResponse{Token: []byte("\x00user\x00password")}))
+	capture.record(client, proxy, false, newCaptureTestFrame(t, 1, &message.AuthSuccess{}))
+	capture.record(otherClient, proxy, true, newCaptureTestFrame(t, 2, query))
+	largeQuery := &message.Query{Query: "SELECT * FROM ks.tb WHERE k = '" + string(make([]byte, 100000)) + "'"}
+	capture.record(client, proxy, true, newCaptureTestFrame(t, 3, largeQuery))
+	capture.record(client, proxy, true, newCaptureTestFrame(t, 4, query))
+
+	status = capture.Stop()
+	require.False(t, status.Active)
+	require.Equal(t, "stopped through the admin API", status.StopReason)
+	require.Equal(t, 4, status.Frames)
+	fileInfo, err := os.Stat(status.File)
+	require.Nil(t, err)
+	require.Equal(t, fileInfo.Size(), status.Bytes)
+	require.Equal(t, status, capture.GetStatus())
+	capture.record(client, proxy, true, newCaptureTestFrame(t, 5, query))
+	require.Equal(t, 4, capture.GetStatus().Frames)
+
+	frames := readCaptureFile(t, status.File)
+	require.Len(t, frames, 4)
+	require.Equal(t, "127.0.0.1:6000", frames[0].src)
+	require.Equal(t, "127.0.0.2:14002", frames[0].dst)
+	require.Equal(t, &message.AuthResponse{}, frames[0].frame.Body.Message)
+	require.Equal(t, "127.0.0.2:14002", frames[1].src)
+	require.Equal(t, &message.AuthSuccess{}, frames[1].frame.Body.Message)
+	require.Equal(t, largeQuery.Query, frames[2].frame.Body.Message.(*message.Query).Query)
+	require.Equal(t, int16(4), frames[3].frame.Header.StreamId)
+	require.Equal(t, query, frames[3].frame.Body.Message)
+}
+
+func TestTrafficCapture_Redacted(t *testing.T) {
+	capture := newTrafficCapture(&common.TrafficCaptureConfig{
+		Enabled: true, Directory: t.TempDir(), MaxDurationMs: 60000, MaxFileSizeBytes: 1024 * 1024})
+	status, err := capture.Start(time.Minute, nil, true)
+	require.Nil(t, err)
+	require.Empty(t, status.Clients)
+
+	proxy := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 14002}
+	client := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 6000}
+	query := &message.Query{
+		Query: "INSERT INTO ks.tb (k, v, b, n, id) VALUES ('it''s secret', ?, 0xcafe, 1234, 5132b130-ae79-11e4-ab27-0800200c9a66)",
+		Options: &message.QueryOptions{
+			PositionalValues: []*primitive.Value{primitive.NewValue([]byte("value"))}},
+	}
+	batch := &message.Batch{Children: []*message.BatchChild{
+		{QueryOrId: "UPDATE ks.tb SET v = ?, f = 2.5, d = true WHERE k = $$secret$$ AND id = e1b2c3d4-0000-1000-8000-00a0c91e6bf6", Values: []*primitive.Value{primitive.NewValue([]byte("value"))}},
+		{QueryOrId: []byte{1, 2}, Values: []*primitive.Value{primitive.NewValue([]byte("value"))}},
+	}}
+	rows := &message.RowsResult{Metadata: &message.RowsMetadata{ColumnCount: 1}, Data: message.RowSet{{[]byte("secret")}}}
+	capture.record(client, proxy, true, newCaptureTestFrame(t, 1, query))
+	capture.record(client, proxy, true, newCaptureTestFrame(t, 2, batch))
+	capture.record(client, proxy, false, newCaptureTestFrame(t, 2, rows))
+	compressedFrame := newCaptureTestFrame(t, 3, &message.Options{})
+	compressedFrame.Header.Flags = compressedFrame.Header.Flags.Add(primitive.HeaderFlagCompressed)
+	capture.record(client, proxy, true, compressedFrame)
+	capture.record(client, proxy, true, newCaptureTestFrame(t, 4, &message.Options{}))
+
+	status = capture.Stop()
+	require.Equal(t, 4, status.Frames)
+	require.Equal(t, 1, status.SkippedFrames)
+
+	frames := readCaptureFile(t, status.File)
+	require.Len(t, frames, 4)
+	require.Equal(t, "[::1]:6000", frames[0].src)
+	require.Equal(t, &message.Query{
+		Query: "INSERT INTO ks.tb (k, v, b, n, id) VALUES (?, ?, ?, ?, ?)",
+		Options: &message.QueryOptions{
+			PositionalValues: []*primitive.Value{primitive.NewValue(nil)}},
+	}, frames[0].frame.Body.Message)
+	redactedBatch := frames[1].frame.Body.Message.(*message.Batch)
+	require.Equal(t, "UPDATE ks.tb SET v = ?, f = ?, d = ? WHERE k = ? AND id = ?", redactedBatch.Children[0].QueryOrId)
+	require.Equal(t, []*primitive.Value{primitive.NewValue(nil)}, redactedBatch.Children[0].Values)
+	require.Equal(t, []byte{1, 2}, redactedBatch.Children[1].QueryOrId)
+	require.Equal(t, []*primitive.Value{primitive.NewValue(nil)}, redactedBatch.Children[1].Values)
+	require.Equal(t, message.RowSet{{nil}}, frames[2].frame.Body.Message.(*message.RowsResult).Data)
+	require.Equal(t, &message.Options{}, frames[3].frame.Body.Message)
+
+	// the frames that are shared with the other components of the proxy are not modified
+	require.Equal(t, []*primitive.Value{primitive.NewValue([]byte("value"))}, query.Options.PositionalValues)
+	require.True(t, compressedFrame.Header.Flags.Contains(primitive.HeaderFlagCompressed))
+}
+
+func TestTrafficCapture_Limits(t *testing.T) {
+	capture := newTrafficCapture(&common.TrafficCaptureConfig{
+		Enabled: true, Directory: t.TempDir(), MaxDurationMs: 60000, MaxFileSizeBytes: 200})
+	proxy := &net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 14002}
+	client := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 6000}
+
+	_, err := capture.Start(time.Minute, nil, false)
+	require.Nil(t, err)
+	for i := int16(0); i < 10; i++ {
+		capture.record(client, proxy, true, newCaptureTestFrame(t, i, &message.Query{Query: "SELECT * FROM ks.tb"}))
+	}
+	status := capture.GetStatus()
+	require.False(t, status.Active)
+	require.Equal(t, "the maximum file size was reached (ZDM_TRAFFIC_CAPTURE_MAX_FILE_SIZE_BYTES)", status.StopReason)
+	require.Len(t, readCaptureFile(t, status.File), status.Frames)
+
+	_, err = capture.Start(50*time.Millisecond, nil, false)
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		return !capture.GetStatus().Active
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "the duration elapsed", capture.GetStatus().StopReason)
+
+	_, err = capture.Start(time.Minute, nil, false)
+	require.Nil(t, err)
+	capture.shutdown()
+	require.Equal(t, "the proxy was shut down", capture.GetStatus().StopReason)
+}
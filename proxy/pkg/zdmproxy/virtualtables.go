@@ -0,0 +1,355 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	systemViewsKeyspaceName          = "system_views"
+	proxyClientsTableName            = "zdm_clients"
+	proxyPreparedStatementsTableName = "zdm_prepared_statements"
+	proxyRoutingRulesTableName       = "zdm_routing_rules"
+)
+
+const (
+	targetWriteFilterRuleType      = "target_write_filter"
+	applicationReadRoutingRuleType = "application_read_routing"
+	tableRoutingRuleType           = "table_routing"
+)
+
+/*
+
+cqlsh> describe system_views.zdm_clients;
+CREATE TABLE system_views.zdm_clients (
+    address inet,
+    port int,
+    connected_at timestamp,
+    protocol_version int,
+    application_name text,
+    application_version text,
+    client_id text,
+    driver_name text,
+    driver_version text,
+    keyspace_name text,
+    in_flight_requests int
+)
+
+cqlsh> describe system_views.zdm_prepared_statements;
+CREATE TABLE system_views.zdm_prepared_statements (
+    prepared_id blob,
+    target_prepared_id blob,
+    query_string text,
+    keyspace_name text,
+    forward_decision text
+)
+
+cqlsh> describe system_views.zdm_routing_rules;
+CREATE TABLE system_views.zdm_routing_rules (
+    rule_type text,
+    application_name text,
+    keyspace_name text,
+    table_name text,
+    condition text,
+    cluster text
+)
+*/
+
+var proxyClientsColumns = []*message.ColumnMetadata{
+	newProxyVirtualTableColumn(proxyClientsTableName, "address", datatype.Inet),
+	newProxyVirtualTableColumn(proxyClientsTableName, "port", datatype.Int),
+	newProxyVirtualTableColumn(proxyClientsTableName, "connected_at", datatype.Timestamp),
+	newProxyVirtualTableColumn(proxyClientsTableName, "protocol_version", datatype.Int),
+	newProxyVirtualTableColumn(proxyClientsTableName, "application_name", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyClientsTableName, "application_version", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyClientsTableName, "client_id", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyClientsTableName, "driver_name", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyClientsTableName, "driver_version", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyClientsTableName, "keyspace_name", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyClientsTableName, "in_flight_requests", datatype.Int),
+}
+
+var proxyPreparedStatementsColumns = []*message.ColumnMetadata{
+	newProxyVirtualTableColumn(proxyPreparedStatementsTableName, "prepared_id", datatype.Blob),
+	newProxyVirtualTableColumn(proxyPreparedStatementsTableName, "target_prepared_id", datatype.Blob),
+	newProxyVirtualTableColumn(proxyPreparedStatementsTableName, "query_string", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyPreparedStatementsTableName, "keyspace_name", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyPreparedStatementsTableName, "forward_decision", datatype.Varchar),
+}
+
+var proxyRoutingRulesColumns = []*message.ColumnMetadata{
+	newProxyVirtualTableColumn(proxyRoutingRulesTableName, "rule_type", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyRoutingRulesTableName, "application_name", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyRoutingRulesTableName, "keyspace_name", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyRoutingRulesTableName, "table_name", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyRoutingRulesTableName, "condition", datatype.Varchar),
+	newProxyVirtualTableColumn(proxyRoutingRulesTableName, "cluster", datatype.Varchar),
+}
+
+func newProxyVirtualTableColumn(table string, name string, dataType datatype.DataType) *message.ColumnMetadata {
+	return &message.ColumnMetadata{Keyspace: systemViewsKeyspaceName, Table: table, Name: name, Type: dataType}
+}
+
+// proxyVirtualTables answers the queries on the system_views.zdm_* tables with the state of the proxy
+// (see ZDM_PROXY_VIRTUAL_TABLES_ENABLED) so that it can be inspected with cqlsh:
+//   - zdm_clients: the client connections that finished the handshake and their in flight requests
+//   - zdm_prepared_statements: the entries of the prepared statement cache
+//   - zdm_routing_rules: the target write filter rules, the application read routing rules and the table routing rules
+//
+// The WHERE clause of the queries is ignored, every row of the table is returned.
+type proxyVirtualTables struct {
+	clients      *connectedClients
+	psCache      *PreparedStatementCache
+	routingRules [][]interface{}
+}
+
+func newProxyVirtualTables(
+	clients *connectedClients, psCache *PreparedStatementCache, targetWriteFilterRules []*common.WriteFilterRule,
+	applicationReadRoutingRules []*common.ApplicationReadRoutingRule,
+	tableRoutingRules []*common.TableRoutingRule) *proxyVirtualTables {
+	routingRules := make(
+		[][]interface{}, 0, len(targetWriteFilterRules)+len(applicationReadRoutingRules)+len(tableRoutingRules))
+	for _, rule := range targetWriteFilterRules {
+		condition := fmt.Sprintf("%v %v %v", rule.Column, rule.Operator, strings.Join(rule.Values, ", "))
+		if rule.Operator == common.WriteFilterOperatorIn || rule.Operator == common.WriteFilterOperatorNotIn {
+			condition = fmt.Sprintf("%v %v (%v)", rule.Column, rule.Operator, strings.Join(rule.Values, ", "))
+		}
+		// writes that match the rule are only forwarded to ORIGIN
+		routingRules = append(routingRules, []interface{}{
+			targetWriteFilterRuleType, nil, rule.Keyspace, rule.Table, condition, string(common.ClusterTypeOrigin)})
+	}
+	for _, rule := range applicationReadRoutingRules {
+		routingRules = append(routingRules, []interface{}{
+			applicationReadRoutingRuleType, rule.Application, nullableString(rule.Keyspace),
+			nullableString(rule.Table), nil, string(rule.Cluster)})
+	}
+	for _, rule := range tableRoutingRules {
+		routingRules = append(routingRules, []interface{}{
+			tableRoutingRuleType, nil, rule.Keyspace, rule.Table, nil, string(rule.Cluster)})
+	}
+	return &proxyVirtualTables{
+		clients:      clients,
+		psCache:      psCache,
+		routingRules: routingRules,
+	}
+}
+
+func isProxyVirtualTableQueryType(queryType interceptedQueryType) bool {
+	return queryType == proxyClients || queryType == proxyPreparedStatements || queryType == proxyRoutingRules
+}
+
+func isProxyVirtualTable(keyspace string, tableName string) bool {
+	_, ok := getProxyVirtualTableQueryType(keyspace, tableName)
+	return ok
+}
+
+// getProxyVirtualTableQueryType returns false if the table is not one of the system_views.zdm_* tables.
+func getProxyVirtualTableQueryType(keyspace string, tableName string) (interceptedQueryType, bool) {
+	if keyspace != systemViewsKeyspaceName {
+		return "", false
+	}
+	switch tableName {
+	case proxyClientsTableName:
+		return proxyClients, true
+	case proxyPreparedStatementsTableName:
+		return proxyPreparedStatements, true
+	case proxyRoutingRulesTableName:
+		return proxyRoutingRules, true
+	default:
+		return "", false
+	}
+}
+
+// newResult returns a PreparedResult if the prepareRequestInfo parameter is not nil and it returns a
+// RowsResult if prepareRequestInfo is nil.
+func (recv *proxyVirtualTables) newResult(
+	prepareRequestInfo *PrepareRequestInfo, connectionKeyspace string, genericTypeCodec *GenericTypeCodec,
+	version primitive.ProtocolVersion, queryType interceptedQueryType, parsedSelectClause *selectClause) (message.Result, error) {
+
+	var tableName string
+	var tableColumns []*message.ColumnMetadata
+	var getRows func() [][]interface{}
+	switch queryType {
+	case proxyClients:
+		tableName, tableColumns, getRows = proxyClientsTableName, proxyClientsColumns, recv.getClientsRows
+	case proxyPreparedStatements:
+		tableName, tableColumns, getRows = proxyPreparedStatementsTableName, proxyPreparedStatementsColumns, recv.getPreparedStatementsRows
+	case proxyRoutingRules:
+		tableName, tableColumns, getRows = proxyRoutingRulesTableName, proxyRoutingRulesColumns, recv.getRoutingRulesRows
+	default:
+		return nil, fmt.Errorf("unexpected proxy virtual table query type: %v", queryType)
+	}
+	return newVirtualTableResult(prepareRequestInfo, connectionKeyspace, genericTypeCodec, version,
+		systemViewsKeyspaceName, tableName, tableColumns, getRows, parsedSelectClause)
+}
+
+// newVirtualTableResult returns a PreparedResult if the prepareRequestInfo parameter is not nil and it returns a
+// RowsResult with the selected columns of the rows returned by getRows if prepareRequestInfo is nil.
+func newVirtualTableResult(
+	prepareRequestInfo *PrepareRequestInfo, connectionKeyspace string, genericTypeCodec *GenericTypeCodec,
+	version primitive.ProtocolVersion, keyspace string, tableName string, tableColumns []*message.ColumnMetadata,
+	getRows func() [][]interface{}, parsedSelectClause *selectClause) (message.Result, error) {
+
+	resultColumns, columnIndexes, hasCountSelector, err := filterVirtualTableColumns(
+		parsedSelectClause, tableColumns, keyspace, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if prepareRequestInfo != nil {
+		return EncodePreparedResult(prepareRequestInfo, connectionKeyspace, resultColumns)
+	}
+
+	tableRows := getRows()
+	if hasCountSelector {
+		// the other selectors return the values of the first row like a regular aggregation query
+		var firstRow []interface{}
+		if len(tableRows) > 0 {
+			firstRow = tableRows[0]
+		}
+		row := make([]interface{}, len(columnIndexes))
+		for i, columnIndex := range columnIndexes {
+			if columnIndex < 0 {
+				row[i] = len(tableRows)
+			} else if firstRow != nil {
+				row[i] = firstRow[columnIndex]
+			}
+		}
+		return EncodeRowsResult(genericTypeCodec, version, resultColumns, [][]interface{}{row})
+	}
+
+	rows := make([][]interface{}, 0, len(tableRows))
+	for _, tableRow := range tableRows {
+		row := make([]interface{}, len(columnIndexes))
+		for i, columnIndex := range columnIndexes {
+			row[i] = tableRow[columnIndex]
+		}
+		rows = append(rows, row)
+	}
+	return EncodeRowsResult(genericTypeCodec, version, resultColumns, rows)
+}
+
+// filterVirtualTableColumns returns the result columns of the select clause and the index of their values
+// in the rows of the table, the index of count selectors is -1.
+func filterVirtualTableColumns(
+	parsedSelectClause *selectClause, tableColumns []*message.ColumnMetadata, keyspace string, tableName string) (
+	resultColumns []*message.ColumnMetadata, columnIndexes []int, hasCountSelector bool, err error) {
+	if parsedSelectClause.IsStarSelectClause() {
+		columnIndexes = make([]int, len(tableColumns))
+		for i := range tableColumns {
+			columnIndexes[i] = i
+		}
+		return tableColumns, columnIndexes, false, nil
+	}
+
+	selectors := parsedSelectClause.GetSelectors()
+	resultColumns = make([]*message.ColumnMetadata, 0, len(selectors))
+	columnIndexes = make([]int, 0, len(selectors))
+	for _, parsedSelector := range selectors {
+		column, isCountSelector, err := columnFromSelector(tableColumns, parsedSelector, keyspace, tableName)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		resultColumns = append(resultColumns, column)
+		if isCountSelector {
+			hasCountSelector = true
+			columnIndexes = append(columnIndexes, -1)
+			continue
+		}
+		unaliasedColumnName, err := unaliasedColumnNameFromSelector(parsedSelector)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		for i, tableColumn := range tableColumns {
+			if tableColumn.Name == unaliasedColumnName {
+				columnIndexes = append(columnIndexes, i)
+				break
+			}
+		}
+	}
+	return resultColumns, columnIndexes, hasCountSelector, nil
+}
+
+func (recv *proxyVirtualTables) getClientsRows() [][]interface{} {
+	connectedAt := recv.clients.get()
+	rows := make([][]interface{}, 0, len(connectedAt))
+	for ch, t := range connectedAt {
+		var address interface{}
+		var port interface{}
+		host, portStr, err := net.SplitHostPort(ch.clientConnector.connection.RemoteAddr().String())
+		if err == nil {
+			if ip := net.ParseIP(host); ip != nil {
+				address = ip
+			}
+			if parsedPort, err := strconv.Atoi(portStr); err == nil {
+				port = parsedPort
+			}
+		}
+		identity := ch.clientIdentity
+		if identity == nil {
+			identity = &clientIdentity{}
+		}
+		rows = append(rows, []interface{}{
+			address, port, t, int(ch.startupRequest.Header.Version),
+			nullableString(identity.applicationName), nullableString(identity.applicationVersion),
+			nullableString(identity.clientId), nullableString(identity.driverName),
+			nullableString(identity.driverVersion), nullableString(ch.LoadCurrentKeyspace()),
+			countInFlightRequests(ch.requestContextHolders),
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i][2].(time.Time).Before(rows[j][2].(time.Time))
+	})
+	return rows
+}
+
+func (recv *proxyVirtualTables) getPreparedStatementsRows() [][]interface{} {
+	entries := recv.psCache.GetEntries()
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].GetOriginPreparedId(), entries[j].GetOriginPreparedId()) < 0
+	})
+
+	rows := make([][]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		prepareRequestInfo := entry.GetPrepareRequestInfo()
+		rows = append(rows, []interface{}{
+			entry.GetOriginPreparedId(), entry.GetTargetPreparedId(), prepareRequestInfo.GetQuery(),
+			nullableString(prepareRequestInfo.GetKeyspace()),
+			string(prepareRequestInfo.GetBaseRequestInfo().GetForwardDecision()),
+		})
+	}
+	return rows
+}
+
+func (recv *proxyVirtualTables) getRoutingRulesRows() [][]interface{} {
+	return recv.routingRules
+}
+
+// countInFlightRequests returns the number of stream ids of the client connection that are waiting for a response.
+func countInFlightRequests(contextHoldersMap *sync.Map) int {
+	count := 0
+	contextHoldersMap.Range(func(key, value interface{}) bool {
+		if value.(*requestContextHolder).Get() != nil {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
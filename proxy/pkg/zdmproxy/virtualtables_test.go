@@ -0,0 +1,85 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestProxyVirtualTables_RequestInfo(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	getRequestInfo := func(query string, proxyVirtualTablesEnabled bool) RequestInfo {
+		return getRequestInfoFromQueryInfo(mockQueryFrame(t, query), common.ClusterTypeOrigin, false, true,
+			proxyVirtualTablesEnabled, false, inspectCqlQuery(query, "", generator), nil, nil, nil)
+	}
+
+	requestInfo := getRequestInfo("SELECT address, count(*) FROM system_views.zdm_clients", true)
+	require.IsType(t, &InterceptedRequestInfo{}, requestInfo)
+	require.Equal(t, proxyClients, requestInfo.(*InterceptedRequestInfo).GetQueryType())
+	require.Len(t, requestInfo.(*InterceptedRequestInfo).GetParsedSelectClause().GetSelectors(), 2)
+
+	require.Equal(t, NewGenericRequestInfo(forwardToOrigin, false, true),
+		getRequestInfo("SELECT * FROM system_views.zdm_clients", false))
+	require.Equal(t, NewGenericRequestInfo(forwardToOrigin, false, true),
+		getRequestInfo("SELECT * FROM system_views.clients", true))
+}
+
+func TestProxyVirtualTables_Results(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	psCache := NewPreparedStatementCache()
+	psCache.Store(
+		&message.PreparedResult{PreparedQueryId: []byte("ORIGIN")}, &message.PreparedResult{PreparedQueryId: []byte("TARGET")},
+		NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, "INSERT INTO ks.tb (a) VALUES (?)", "ks"))
+	virtualTables := newProxyVirtualTables(newConnectedClients(), psCache,
+		[]*common.WriteFilterRule{{Keyspace: "ks", Table: "users", Column: "tenant_id", Operator: common.WriteFilterOperatorIn, Values: []string{"t1", "t2"}}},
+		[]*common.ApplicationReadRoutingRule{{Application: "analytics", Keyspace: "ks", Cluster: common.ClusterTypeTarget}},
+		[]*common.TableRoutingRule{{Keyspace: "system_auth", Table: "roles", Cluster: common.ClusterTypeTarget}})
+
+	query := func(query string) *ParsedRowSet {
+		queryInfo := inspectCqlQuery(query, "", generator)
+		queryType, ok := getProxyVirtualTableQueryType(queryInfo.getApplicableKeyspace(), queryInfo.getTableName())
+		require.True(t, ok)
+		result, err := virtualTables.newResult(
+			nil, "", GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4, queryType, queryInfo.getParsedSelectClause())
+		require.Nil(t, err)
+		rowSet, err := ParseRowsResult(
+			GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4, result.(*message.RowsResult), nil, nil)
+		require.Nil(t, err)
+		return rowSet
+	}
+
+	rules := query("SELECT * FROM system_views.zdm_routing_rules")
+	require.Len(t, rules.Columns, len(proxyRoutingRulesColumns))
+	require.Len(t, rules.Rows, 3)
+	require.Equal(t, []interface{}{targetWriteFilterRuleType, nil, "ks", "users", "tenant_id IN (t1, t2)", "ORIGIN"}, rules.Rows[0].Values)
+	require.Equal(t, []interface{}{applicationReadRoutingRuleType, "analytics", "ks", nil, nil, "TARGET"}, rules.Rows[1].Values)
+	require.Equal(t, []interface{}{tableRoutingRuleType, nil, "system_auth", "roles", nil, "TARGET"}, rules.Rows[2].Values)
+
+	statements := query("SELECT query_string, forward_decision AS decision FROM system_views.zdm_prepared_statements")
+	require.Equal(t, "decision", statements.Columns[1].Name)
+	require.Len(t, statements.Rows, 1)
+	require.Equal(t, []interface{}{"INSERT INTO ks.tb (a) VALUES (?)", "both"}, statements.Rows[0].Values)
+
+	clients := query("SELECT count(*), address FROM system_views.zdm_clients")
+	require.Len(t, clients.Rows, 1)
+	require.Equal(t, []interface{}{int32(0), nil}, clients.Rows[0].Values)
+
+	_, err = virtualTables.newResult(
+		nil, "", GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4, proxyClients,
+		inspectCqlQuery("SELECT unknown FROM system_views.zdm_clients", "", generator).getParsedSelectClause())
+	require.Equal(t, &ColumnNotFoundErr{Name: "unknown"}, err)
+
+	prepareRequestInfo := NewPrepareRequestInfo(NewInterceptedRequestInfo(proxyRoutingRules, newStarSelectClause()),
+		nil, false, "SELECT * FROM system_views.zdm_routing_rules", "")
+	result, err := virtualTables.newResult(
+		prepareRequestInfo, "", GetDefaultGenericTypeCodec(), primitive.ProtocolVersion4, proxyRoutingRules, newStarSelectClause())
+	require.Nil(t, err)
+	require.IsType(t, &message.PreparedResult{}, result)
+	require.Equal(t, proxyRoutingRulesColumns, result.(*message.PreparedResult).ResultMetadata.Columns)
+}
@@ -0,0 +1,124 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+)
+
+const appliedColumnName = "[applied]"
+
+// writeComparisonSampler decides which dual writes have their ORIGIN and TARGET responses compared
+// (see ZDM_WRITE_COMPARISON_SAMPLE_PERCENTAGE).
+type writeComparisonSampler struct {
+	percentage float64
+	rand       *rand.Rand
+}
+
+// newWriteComparisonSampler returns nil if the responses of the writes are not compared.
+func newWriteComparisonSampler(percentage float64, rand *rand.Rand) *writeComparisonSampler {
+	if percentage <= 0 {
+		return nil
+	}
+	return &writeComparisonSampler{
+		percentage: percentage,
+		rand:       rand,
+	}
+}
+
+func (recv *writeComparisonSampler) isSampled() bool {
+	return recv.rand.Float64()*100 < recv.percentage
+}
+
+// writeComparison compares the ORIGIN and TARGET responses of a dual write once both are received, unlike the read
+// comparisons only the kind of the responses and the [applied] column of the lightweight transactions are compared.
+// The comparison is done after the client response is sent.
+type writeComparison struct {
+	request      *frame.RawFrame
+	requestInfo  RequestInfo
+	keyspace     string
+	proxyMetrics *metrics.ProxyMetrics
+}
+
+func newWriteComparison(
+	request *frame.RawFrame, requestInfo RequestInfo, keyspace string,
+	proxyMetrics *metrics.ProxyMetrics) *writeComparison {
+	return &writeComparison{
+		request:      request,
+		requestInfo:  requestInfo,
+		keyspace:     keyspace,
+		proxyMetrics: proxyMetrics,
+	}
+}
+
+func (recv *writeComparison) compare(originResponse *frame.RawFrame, targetResponse *frame.RawFrame) {
+	if recv == nil {
+		return
+	}
+
+	recv.proxyMetrics.WriteComparisons.Add(1)
+	originKind := describeComparedWriteResponse(originResponse)
+	targetKind := describeComparedWriteResponse(targetResponse)
+	if originKind != targetKind {
+		recv.proxyMetrics.WriteComparisonMismatchesResponse.Add(1)
+		recv.logMismatch("response", originKind, targetKind)
+		return
+	}
+
+	originApplied, originLwt := getAppliedState(originResponse)
+	targetApplied, targetLwt := getAppliedState(targetResponse)
+	if originLwt && targetLwt && originApplied != targetApplied {
+		recv.proxyMetrics.WriteComparisonMismatchesApplied.Add(1)
+		recv.logMismatch("applied", describeAppliedState(originApplied), describeAppliedState(targetApplied))
+	}
+}
+
+// logMismatch logs the digest of the query instead of the query because the literals can contain sensitive data.
+func (recv *writeComparison) logMismatch(mismatchType string, origin string, target string) {
+	fields := log.Fields{
+		"mismatch":        mismatchType,
+		"origin_response": origin,
+		"target_response": target,
+	}
+	addQueryFields(fields, recv.request, recv.requestInfo, recv.keyspace, common.SlowQueryFormatDigest)
+	log.WithFields(fields).Warn("Write comparison mismatch")
+}
+
+// describeComparedWriteResponse returns RESULT for the successful responses and the error code of the errors.
+func describeComparedWriteResponse(response *frame.RawFrame) string {
+	if response == nil {
+		return "no response"
+	}
+	return describeSlowQueryResponse(response)
+}
+
+// getAppliedState returns the [applied] column of the first row of the response of a lightweight transaction,
+// false if the response is not the result of a lightweight transaction.
+func getAppliedState(response *frame.RawFrame) (bool, bool) {
+	if response == nil || response.Header.OpCode != primitive.OpCodeResult {
+		return false, false
+	}
+	decodedFrame, err := defaultCodec.ConvertFromRawFrame(response)
+	if err != nil {
+		log.Debugf("Could not decode the response of a compared write: %v.", err)
+		return false, false
+	}
+	rows, ok := decodedFrame.Body.Message.(*message.RowsResult)
+	if !ok || rows.Metadata == nil || len(rows.Metadata.Columns) == 0 || len(rows.Data) == 0 ||
+		rows.Metadata.Columns[0].Name != appliedColumnName || len(rows.Data[0]) == 0 {
+		return false, false
+	}
+	applied := rows.Data[0][0]
+	return len(applied) == 1 && applied[0] != 0, true
+}
+
+func describeAppliedState(applied bool) string {
+	if applied {
+		return "applied"
+	}
+	return "not applied"
+}
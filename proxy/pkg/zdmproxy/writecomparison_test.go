@@ -0,0 +1,75 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWriteComparison_Compare(t *testing.T) {
+	newResponse := func(msg message.Message) *frame.RawFrame {
+		response, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, msg))
+		require.Nil(t, err)
+		return response
+	}
+	newLwtResponse := func(applied bool) *frame.RawFrame {
+		value := []byte{0}
+		if applied {
+			value = []byte{1}
+		}
+		return newResponse(&message.RowsResult{
+			Metadata: &message.RowsMetadata{
+				ColumnCount: 1,
+				Columns: []*message.ColumnMetadata{
+					{Keyspace: "ks", Table: "tb", Name: "[applied]", Type: datatype.Boolean},
+				},
+			},
+			Data: message.RowSet{{value}},
+		})
+	}
+	request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		primitive.ProtocolVersion4, 1, &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1) IF NOT EXISTS"}))
+	require.Nil(t, err)
+
+	tests := []struct {
+		name                       string
+		originResponse             *frame.RawFrame
+		targetResponse             *frame.RawFrame
+		expectedResponseMismatches int64
+		expectedAppliedMismatches  int64
+	}{
+		{"same responses", newResponse(&message.VoidResult{}), newResponse(&message.VoidResult{}), 0, 0},
+		{"same errors", newResponse(&message.Overloaded{}), newResponse(&message.Overloaded{}), 0, 0},
+		{"success and error", newResponse(&message.VoidResult{}),
+			newResponse(&message.WriteTimeout{WriteType: primitive.WriteTypeSimple}), 1, 0},
+		{"different errors", newResponse(&message.Overloaded{}), newResponse(&message.ServerError{}), 1, 0},
+		{"no response", newResponse(&message.VoidResult{}), nil, 1, 0},
+		{"same applied state", newLwtResponse(true), newLwtResponse(true), 0, 0},
+		{"different applied state", newLwtResponse(true), newLwtResponse(false), 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparisons, responseMismatches, appliedMismatches := &countingCounter{}, &countingCounter{}, &countingCounter{}
+			comparison := newWriteComparison(request, NewGenericRequestInfo(forwardToBoth, false, true), "",
+				&metrics.ProxyMetrics{
+					WriteComparisons:                  comparisons,
+					WriteComparisonMismatchesResponse: responseMismatches,
+					WriteComparisonMismatchesApplied:  appliedMismatches,
+				})
+			comparison.compare(tt.originResponse, tt.targetResponse)
+			require.Equal(t, int64(1), atomic.LoadInt64(&comparisons.count))
+			require.Equal(t, tt.expectedResponseMismatches, atomic.LoadInt64(&responseMismatches.count))
+			require.Equal(t, tt.expectedAppliedMismatches, atomic.LoadInt64(&appliedMismatches.count))
+		})
+	}
+
+	var disabled *writeComparison
+	disabled.compare(newResponse(&message.VoidResult{}), nil)
+	require.Nil(t, newWriteComparisonSampler(0, nil))
+}
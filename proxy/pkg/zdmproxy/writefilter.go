@@ -0,0 +1,373 @@
+package zdmproxy
+
+import (
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// targetWriteFilter decides which writes are not forwarded to TARGET based on the values that they write
+// (see ZDM_TARGET_WRITE_FILTER_RULES). This is meant for partial migrations where only a subset of the data
+// is moved to TARGET.
+//
+// A statement is filtered if any rule of its table matches the value of the rule's column in the statement, i.e.,
+// a value of an INSERT, a SET col = value of an UPDATE or a WHERE col = value of an UPDATE or DELETE.
+// Statements that don't have a value for the column or whose value can't be evaluated (e.g. bind markers of
+// non prepared statements because their type is unknown) are forwarded to both clusters.
+type targetWriteFilter struct {
+	rulesByTable map[string][]*common.WriteFilterRule
+}
+
+// newTargetWriteFilter returns nil if there are no rules.
+func newTargetWriteFilter(rules []*common.WriteFilterRule) *targetWriteFilter {
+	if len(rules) == 0 {
+		return nil
+	}
+	rulesByTable := make(map[string][]*common.WriteFilterRule)
+	for _, rule := range rules {
+		key := writeFilterTableKey(rule.Keyspace, rule.Table)
+		rulesByTable[key] = append(rulesByTable[key], rule)
+	}
+	return &targetWriteFilter{rulesByTable: rulesByTable}
+}
+
+func writeFilterTableKey(keyspace string, table string) string {
+	return keyspace + "." + table
+}
+
+// boundValueResolver returns the value (in text form) of a bind marker term and false if it is unknown or null.
+type boundValueResolver func(t *term) (string, bool)
+
+// filteredStatements returns the indexes of the statements of the request that should not be sent to TARGET
+// and the total number of statements in the request.
+func (recv *targetWriteFilter) filteredStatements(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	originRequest *frame.RawFrame, timeUuidGenerator TimeUuidGenerator) ([]int, int, error) {
+
+	switch typedRequestInfo := requestInfo.(type) {
+	case *GenericRequestInfo:
+		if frameContext.GetRawFrame().Header.OpCode != primitive.OpCodeQuery {
+			return nil, 0, nil
+		}
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return nil, 0, err
+		}
+		queryInfo := stmtQueryData.queryData
+		if !isWriteStatementType(queryInfo.getStatementType()) {
+			return nil, 0, nil
+		}
+		parsedStatements := queryInfo.getParsedStatements()
+		var filtered []int
+		for i, parsedStmt := range parsedStatements {
+			if recv.matchStatement(queryInfo, parsedStmt, unknownBoundValue) != nil {
+				filtered = append(filtered, i)
+			}
+		}
+		if len(filtered) > 0 && len(filtered) < len(parsedStatements) {
+			// the query string of a BATCH can't be modified, forward the whole BATCH to both clusters
+			log.Warnf("Only %d of the %d statements of the BATCH query are filtered by ZDM_TARGET_WRITE_FILTER_RULES, "+
+				"forwarding the whole BATCH to both clusters: %v", len(filtered), len(parsedStatements), queryInfo.getQuery())
+			return nil, len(parsedStatements), nil
+		}
+		return filtered, len(parsedStatements), nil
+	case *ExecuteRequestInfo:
+		decodedRequest, err := decodeRequestSentToOrigin(frameContext, originRequest)
+		if err != nil {
+			return nil, 0, err
+		}
+		executeMsg, ok := decodedRequest.Body.Message.(*message.Execute)
+		if !ok {
+			return nil, 0, fmt.Errorf("expected Execute but got %v instead", decodedRequest.Body.Message.GetOpCode())
+		}
+		if recv.matchPreparedStatement(
+			typedRequestInfo.GetPreparedData(), executeMsg.Options, decodedRequest.Header.Version) != nil {
+			return []int{0}, 1, nil
+		}
+		return nil, 1, nil
+	case *BatchRequestInfo:
+		decodedRequest, err := decodeRequestSentToOrigin(frameContext, originRequest)
+		if err != nil {
+			return nil, 0, err
+		}
+		batchMsg, ok := decodedRequest.Body.Message.(*message.Batch)
+		if !ok {
+			return nil, 0, fmt.Errorf("expected Batch but got %v instead", decodedRequest.Body.Message.GetOpCode())
+		}
+		stmtsQueryData, err := frameContext.GetOrInspectAllStatements(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return nil, 0, err
+		}
+		var filtered []int
+		for _, stmtQueryData := range stmtsQueryData {
+			for _, parsedStmt := range stmtQueryData.queryData.getParsedStatements() {
+				if recv.matchStatement(stmtQueryData.queryData, parsedStmt, unknownBoundValue) != nil {
+					filtered = append(filtered, stmtQueryData.statementIndex)
+				}
+			}
+		}
+		for stmtIdx, preparedData := range typedRequestInfo.GetPreparedDataByStmtIdx() {
+			options := &message.QueryOptions{PositionalValues: batchMsg.Children[stmtIdx].Values}
+			if recv.matchPreparedStatement(preparedData, options, decodedRequest.Header.Version) != nil {
+				filtered = append(filtered, stmtIdx)
+			}
+		}
+		sort.Ints(filtered)
+		return filtered, len(batchMsg.Children), nil
+	}
+
+	return nil, 0, nil
+}
+
+// decodeRequestSentToOrigin returns the request that was sent to ORIGIN because the bind markers of the prepared
+// statements match the values of that request if function calls were replaced.
+func decodeRequestSentToOrigin(frameContext *frameDecodeContext, originRequest *frame.RawFrame) (*frame.Frame, error) {
+	if originRequest == frameContext.GetRawFrame() {
+		return frameContext.GetOrDecodeFrame()
+	}
+	decodedRequest, err := defaultCodec.ConvertFromRawFrame(originRequest)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode origin request: %w", err)
+	}
+	return decodedRequest, nil
+}
+
+func (recv *targetWriteFilter) matchPreparedStatement(
+	preparedData PreparedData, options *message.QueryOptions, version primitive.ProtocolVersion) *common.WriteFilterRule {
+	queryInfo := preparedData.GetPrepareRequestInfo().GetQueryInfo()
+	if queryInfo == nil || !isWriteStatementType(queryInfo.getStatementType()) {
+		return nil
+	}
+	resolver := newPreparedBoundValueResolver(preparedData.GetOriginVariablesMetadata(), options, version)
+	for _, parsedStmt := range queryInfo.getParsedStatements() {
+		if rule := recv.matchStatement(queryInfo, parsedStmt, resolver); rule != nil {
+			return rule
+		}
+	}
+	return nil
+}
+
+// matchStatement returns the first rule that matches the statement or nil if the statement should be sent to TARGET.
+func (recv *targetWriteFilter) matchStatement(
+	queryInfo QueryInfo, parsedStmt *parsedStatement, resolver boundValueResolver) *common.WriteFilterRule {
+	keyspace := parsedStmt.keyspaceName
+	if keyspace == "" {
+		keyspace = queryInfo.getRequestKeyspace()
+	}
+	for _, rule := range recv.rulesByTable[writeFilterTableKey(keyspace, parsedStmt.tableName)] {
+		t, ok := parsedStmt.columnTerms[rule.Column]
+		if !ok {
+			continue
+		}
+
+		var value string
+		switch {
+		case t.isLiteral():
+			value, ok = writeFilterLiteralValue(t.literal)
+		case t.isPositionalBindMarker(), t.isNamedBindMarker():
+			value, ok = resolver(t)
+		default:
+			ok = false
+		}
+		if !ok {
+			log.Debugf("Could not evaluate ZDM_TARGET_WRITE_FILTER_RULES rule %v on statement %d of %v, "+
+				"forwarding it to both clusters.", rule, parsedStmt.statementIndex, queryInfo.getQuery())
+			continue
+		}
+
+		if evaluateWriteFilterRule(rule, value) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func isWriteStatementType(stmtType statementType) bool {
+	switch stmtType {
+	case statementTypeInsert, statementTypeUpdate, statementTypeDelete, statementTypeBatch:
+		return true
+	}
+	return false
+}
+
+func unknownBoundValue(*term) (string, bool) {
+	return "", false
+}
+
+// newPreparedBoundValueResolver resolves bind markers with the values of an EXECUTE (or BATCH child)
+// and the variables metadata of the prepared statement.
+func newPreparedBoundValueResolver(
+	variablesMetadata *message.VariablesMetadata, options *message.QueryOptions,
+	version primitive.ProtocolVersion) boundValueResolver {
+	return func(t *term) (string, bool) {
+		if variablesMetadata == nil || options == nil {
+			return "", false
+		}
+
+		index := -1
+		if t.isPositionalBindMarker() {
+			index = t.positionalIndex
+		} else {
+			for i, column := range variablesMetadata.Columns {
+				if column.Name == t.bindMarkerName {
+					index = i
+					break
+				}
+			}
+		}
+		if index < 0 || index >= len(variablesMetadata.Columns) {
+			return "", false
+		}
+		column := variablesMetadata.Columns[index]
+
+		var value *primitive.Value
+		if options.PositionalValues != nil {
+			if index < len(options.PositionalValues) {
+				value = options.PositionalValues[index]
+			}
+		} else if options.NamedValues != nil {
+			value = options.NamedValues[column.Name]
+		}
+		if value == nil || value.Type != primitive.ValueTypeRegular {
+			return "", false
+		}
+
+		decoded, err := GetDefaultGenericTypeCodec().Decode(column.Type, value.Contents, version)
+		if err != nil {
+			log.Debugf("Could not decode value of %v to evaluate ZDM_TARGET_WRITE_FILTER_RULES: %v", column.Name, err)
+			return "", false
+		}
+		return writeFilterDecodedValue(decoded)
+	}
+}
+
+// writeFilterLiteralValue returns the text form of a CQL literal, i.e., without the quotes of string literals.
+func writeFilterLiteralValue(literal string) (string, bool) {
+	switch {
+	case strings.EqualFold(literal, "null"):
+		return "", false
+	case len(literal) > 1 && strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'"):
+		return strings.ReplaceAll(literal[1:len(literal)-1], "''", "'"), true
+	case len(literal) > 3 && strings.HasPrefix(literal, "$$") && strings.HasSuffix(literal, "$$"):
+		return literal[2 : len(literal)-2], true
+	case strings.HasPrefix(literal, "(") || strings.HasPrefix(literal, "[") || strings.HasPrefix(literal, "{"):
+		// collections, tuples and UDTs are not supported
+		return "", false
+	}
+	return literal, true
+}
+
+// writeFilterDecodedValue returns the text form of a value decoded by GenericTypeCodec.
+func writeFilterDecodedValue(decoded interface{}) (string, bool) {
+	switch typedValue := decoded.(type) {
+	case nil:
+		return "", false
+	case string:
+		return typedValue, true
+	case *string:
+		if typedValue == nil {
+			return "", false
+		}
+		return *typedValue, true
+	case time.Time:
+		return typedValue.UTC().Format(time.RFC3339Nano), true
+	case *time.Time:
+		if typedValue == nil {
+			return "", false
+		}
+		return typedValue.UTC().Format(time.RFC3339Nano), true
+	case []byte, map[interface{}]interface{}, []interface{}:
+		return "", false
+	case fmt.Stringer:
+		return typedValue.String(), true
+	}
+	return fmt.Sprint(decoded), true
+}
+
+func evaluateWriteFilterRule(rule *common.WriteFilterRule, value string) bool {
+	switch rule.Operator {
+	case common.WriteFilterOperatorIn, common.WriteFilterOperatorNotIn:
+		in := false
+		for _, ruleValue := range rule.Values {
+			if compareWriteFilterValues(value, ruleValue) == 0 {
+				in = true
+				break
+			}
+		}
+		return in == (rule.Operator == common.WriteFilterOperatorIn)
+	}
+
+	cmp := compareWriteFilterValues(value, rule.Values[0])
+	switch rule.Operator {
+	case common.WriteFilterOperatorEqual:
+		return cmp == 0
+	case common.WriteFilterOperatorNotEqual:
+		return cmp != 0
+	case common.WriteFilterOperatorLessThan:
+		return cmp < 0
+	case common.WriteFilterOperatorLessOrEqual:
+		return cmp <= 0
+	case common.WriteFilterOperatorGreaterThan:
+		return cmp > 0
+	case common.WriteFilterOperatorGreaterOrEqual:
+		return cmp >= 0
+	}
+	return false
+}
+
+var writeFilterTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999Z0700",
+	"2006-01-02 15:04:05.999Z0700",
+	"2006-01-02 15:04:05.999",
+	"2006-01-02",
+}
+
+// compareWriteFilterValues compares the value of a column with a value of a rule: as numbers if both are numbers,
+// as timestamps if both are timestamps (a number is compared with a timestamp as milliseconds since the epoch),
+// case-insensitively if both are UUIDs and as text otherwise.
+func compareWriteFilterValues(value string, ruleValue string) int {
+	valueNumber, valueIsNumber := new(big.Float).SetString(value)
+	ruleNumber, ruleIsNumber := new(big.Float).SetString(ruleValue)
+	if valueIsNumber && ruleIsNumber {
+		return valueNumber.Cmp(ruleNumber)
+	}
+
+	valueTime, valueIsTime := parseWriteFilterTime(value)
+	ruleTime, ruleIsTime := parseWriteFilterTime(ruleValue)
+	if valueIsNumber && ruleIsTime {
+		millis, _ := valueNumber.Int64()
+		valueTime, valueIsTime = time.UnixMilli(millis), true
+	} else if ruleIsNumber && valueIsTime {
+		millis, _ := ruleNumber.Int64()
+		ruleTime, ruleIsTime = time.UnixMilli(millis), true
+	}
+	if valueIsTime && ruleIsTime {
+		return valueTime.Compare(ruleTime)
+	}
+
+	if _, err := uuid.Parse(value); err == nil {
+		if _, err = uuid.Parse(ruleValue); err == nil {
+			return strings.Compare(strings.ToLower(value), strings.ToLower(ruleValue))
+		}
+	}
+
+	return strings.Compare(value, ruleValue)
+}
+
+func parseWriteFilterTime(value string) (time.Time, bool) {
+	for _, layout := range writeFilterTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,158 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/datatype"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/config"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestTargetWriteFilter(t *testing.T, rules string) *targetWriteFilter {
+	conf := config.New()
+	conf.TargetWriteFilterRules = rules
+	parsedRules, err := conf.ParseTargetWriteFilterRules()
+	require.Nil(t, err)
+	return newTargetWriteFilter(parsedRules)
+}
+
+func TestTargetWriteFilter_Query(t *testing.T) {
+	filter := newTestTargetWriteFilter(t,
+		"ks.users.tenant_id IN ('t1', 't2'); ks.events.created_at < 2020-01-01T00:00:00Z")
+
+	tests := []struct {
+		name             string
+		query            string
+		keyspace         string
+		expectedFiltered []int
+		expectedTotal    int
+	}{
+		{"insert in skip-list", "INSERT INTO ks.users (tenant_id, name) VALUES ('t1', 'a')", "", []int{0}, 1},
+		{"insert not in skip-list", "INSERT INTO ks.users (tenant_id, name) VALUES ('t3', 'a')", "", nil, 1},
+		{"insert with current keyspace", "INSERT INTO users (tenant_id, name) VALUES ('t2', 'a')", "ks", []int{0}, 1},
+		{"insert with other keyspace", "INSERT INTO users (tenant_id, name) VALUES ('t2', 'a')", "ks2", nil, 1},
+		{"insert with bind marker", "INSERT INTO ks.users (tenant_id, name) VALUES (?, 'a')", "", nil, 1},
+		{"update where", "UPDATE ks.users SET name = 'a' WHERE tenant_id = 't1' AND id = 1", "", []int{0}, 1},
+		{"update set", "UPDATE ks.users SET tenant_id = 't1' WHERE id = 1", "", []int{0}, 1},
+		{"delete where", "DELETE FROM ks.users WHERE tenant_id = 't2'", "", []int{0}, 1},
+		{"delete where in", "DELETE FROM ks.users WHERE tenant_id IN ('t1', 't2')", "", nil, 1},
+		{"older timestamp", "INSERT INTO ks.events (id, created_at) VALUES (1, '2019-06-01 10:00:00+0000')", "", []int{0}, 1},
+		{"newer timestamp", "INSERT INTO ks.events (id, created_at) VALUES (1, '2021-06-01')", "", nil, 1},
+		{"timestamp in millis", "INSERT INTO ks.events (id, created_at) VALUES (1, 1500000000000)", "", []int{0}, 1},
+		{"other table", "INSERT INTO ks.other (tenant_id) VALUES ('t1')", "", nil, 1},
+		{"select", "SELECT * FROM ks.users WHERE tenant_id = 't1'", "", nil, 0},
+		{"batch all filtered",
+			"BEGIN BATCH INSERT INTO ks.users (tenant_id) VALUES ('t1'); DELETE FROM ks.users WHERE tenant_id = 't2'; APPLY BATCH",
+			"", []int{0, 1}, 2},
+		{"batch partially filtered",
+			"BEGIN BATCH INSERT INTO ks.users (tenant_id) VALUES ('t1'); INSERT INTO ks.users (tenant_id) VALUES ('t3'); APPLY BATCH",
+			"", nil, 2},
+	}
+
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockQueryFrame(t, tt.query)
+			filtered, total, err := filter.filteredStatements(
+				NewFrameDecodeContext(f), NewGenericRequestInfo(forwardToBoth, false, true), tt.keyspace, f, generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedFiltered, filtered)
+			require.Equal(t, tt.expectedTotal, total)
+		})
+	}
+}
+
+func TestTargetWriteFilter_PreparedStatements(t *testing.T) {
+	filter := newTestTargetWriteFilter(t, "ks.users.tenant_id NOT IN ('t1'); ks.users.id >= 100")
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newPreparedData := func(query string, columns ...*message.ColumnMetadata) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, query, "")
+		prepareRequestInfo.queryInfo = inspectCqlQuery(query, "", generator)
+		return &preparedDataImpl{
+			originPreparedId:        []byte("ORIGIN"),
+			targetPreparedId:        []byte("TARGET"),
+			prepareRequestInfo:      prepareRequestInfo,
+			originVariablesMetadata: &message.VariablesMetadata{Columns: columns},
+		}
+	}
+	tenantIdColumn := &message.ColumnMetadata{Keyspace: "ks", Table: "users", Name: "tenant_id", Type: datatype.Varchar}
+	idColumn := &message.ColumnMetadata{Keyspace: "ks", Table: "users", Name: "id", Type: datatype.Int}
+	positional := newPreparedData("INSERT INTO ks.users (tenant_id, id) VALUES (?, ?)", tenantIdColumn, idColumn)
+	named := newPreparedData("INSERT INTO ks.users (tenant_id, id) VALUES (:t, :i)",
+		&message.ColumnMetadata{Keyspace: "ks", Table: "users", Name: "t", Type: datatype.Varchar},
+		&message.ColumnMetadata{Keyspace: "ks", Table: "users", Name: "i", Type: datatype.Int})
+	literal := newPreparedData("INSERT INTO ks.users (tenant_id, id) VALUES ('t2', ?)", idColumn)
+
+	encode := func(dt datatype.DataType, value interface{}) *primitive.Value {
+		encoded, err := GetDefaultGenericTypeCodec().Encode(dt, value, primitive.ProtocolVersion4)
+		require.Nil(t, err)
+		return primitive.NewValue(encoded)
+	}
+
+	tests := []struct {
+		name         string
+		preparedData PreparedData
+		values       []*primitive.Value
+		expected     []int
+	}{
+		{"tenant not in list", positional, []*primitive.Value{encode(datatype.Varchar, "t2"), encode(datatype.Int, int32(1))}, []int{0}},
+		{"tenant in list", positional, []*primitive.Value{encode(datatype.Varchar, "t1"), encode(datatype.Int, int32(1))}, nil},
+		{"id greater than", positional, []*primitive.Value{encode(datatype.Varchar, "t1"), encode(datatype.Int, int32(100))}, []int{0}},
+		{"null tenant", positional, []*primitive.Value{primitive.NewNullValue(), encode(datatype.Int, int32(1))}, nil},
+		{"named bind markers", named, []*primitive.Value{encode(datatype.Varchar, "t2"), encode(datatype.Int, int32(1))}, []int{0}},
+		{"literal", literal, []*primitive.Value{encode(datatype.Int, int32(1))}, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, &message.Execute{
+				QueryId: []byte("ORIGIN"), Options: &message.QueryOptions{PositionalValues: tt.values}}, primitive.ProtocolVersion4)
+			filtered, total, err := filter.filteredStatements(
+				NewFrameDecodeContext(f), NewExecuteRequestInfo(tt.preparedData), "", f, generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, filtered)
+			require.Equal(t, 1, total)
+		})
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		f := mockBatchWithChildren(t, []*message.BatchChild{
+			{QueryOrId: "INSERT INTO ks.users (tenant_id, id) VALUES ('t1', 1)"},
+			{QueryOrId: []byte("ORIGIN"), Values: []*primitive.Value{encode(datatype.Varchar, "t2"), encode(datatype.Int, int32(1))}},
+			{QueryOrId: "INSERT INTO ks.users (tenant_id, id) VALUES ('t3', 1)"},
+		})
+		filtered, total, err := filter.filteredStatements(
+			NewFrameDecodeContext(f), NewBatchRequestInfo(map[int]PreparedData{1: positional}, forwardToBoth), "", f, generator)
+		require.Nil(t, err)
+		require.Equal(t, []int{1, 2}, filtered)
+		require.Equal(t, 3, total)
+	})
+}
+
+func TestCompareWriteFilterValues(t *testing.T) {
+	require.Equal(t, 0, compareWriteFilterValues("1.0", "1"))
+	require.Equal(t, -1, compareWriteFilterValues("9", "10"))
+	require.Equal(t, 1, compareWriteFilterValues("b", "a"))
+	require.Equal(t, -1, compareWriteFilterValues("A", "a"))
+	require.Equal(t, 0, compareWriteFilterValues(
+		"7B5A3F1E-8A7C-4E5B-9C8D-1234567890AB", "7b5a3f1e-8a7c-4e5b-9c8d-1234567890ab"))
+	require.Equal(t, -1, compareWriteFilterValues(
+		time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano), "2020-01-01"))
+	require.Equal(t, 1, compareWriteFilterValues("2020-01-01 00:00:01", "2020-01-01T00:00:00Z"))
+}
+
+func TestEvaluateWriteFilterRule(t *testing.T) {
+	rule := &common.WriteFilterRule{Operator: common.WriteFilterOperatorNotEqual, Values: []string{"a"}}
+	require.True(t, evaluateWriteFilterRule(rule, "b"))
+	require.False(t, evaluateWriteFilterRule(rule, "a"))
+
+	rule = &common.WriteFilterRule{Operator: common.WriteFilterOperatorLessOrEqual, Values: []string{"10"}}
+	require.True(t, evaluateWriteFilterRule(rule, "10"))
+	require.False(t, evaluateWriteFilterRule(rule, "11"))
+}
@@ -0,0 +1,721 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	writeJournalFileName = "secondary-writes.journal"
+
+	// the journal file starts with the offset of its first pending record
+	writeJournalFileHeaderLength = 8
+
+	// every record of the journal file starts with the length and the CRC-32 of the encoded entry
+	writeJournalRecordHeaderLength = 8
+
+	// the replayed records are removed from the journal file once they take more space than the pending records
+	// (plus an invalid record header that marks the end of the pending records) and at least
+	// writeJournalCompactionMinBytes
+	writeJournalCompactionMinBytes = 1024 * 1024
+
+	// delay before the next replay attempt when the secondary cluster is not available
+	writeJournalRetryInterval = 5 * time.Second
+)
+
+// writeJournal stores the writes that succeeded on the primary cluster but failed on the secondary cluster
+// (ZDM_SECONDARY_WRITE_JOURNAL_TYPE) and replays them in order on a dedicated connection to the secondary cluster
+// while its circuit breaker is closed, at most ZDM_SECONDARY_WRITE_JOURNAL_REPLAY_RATE writes per second.
+//
+// A write is removed from the journal once it is replayed successfully or if it is rejected by the secondary
+// cluster with an error that depends on the write itself (e.g. INVALID), if the secondary cluster is not available
+// the write is replayed again later. The writes are replayed with the time at which the proxy received them as
+// default timestamp (unless they have one already) so that they don't overwrite newer writes.
+//
+// The writes of a journal file that were not replayed when the proxy stopped are replayed after the proxy starts
+// again, a write may be replayed twice if the proxy stops while it is being replayed.
+type writeJournal struct {
+	cluster        common.ClusterType
+	conf           *common.WriteJournalConfig
+	controlConn    *ControlConn
+	circuitBreaker *circuitBreaker
+	proxyMetrics   *metrics.ProxyMetrics
+
+	lock     *sync.Mutex
+	store    writeJournalStore
+	notifyCh chan bool
+
+	// only used by the replay goroutine
+	conn         CqlConnection
+	connVersion  primitive.ProtocolVersion
+	connKeyspace string
+}
+
+// writeJournalEntry is a write of the journal, the prepared statements are the statements of the EXECUTE request or
+// of the BATCH statements so that they can be prepared again on the secondary cluster (e.g. after a restart).
+type writeJournalEntry struct {
+	timestamp          time.Time
+	keyspace           string
+	preparedStatements []*writeJournalPreparedStatement
+	request            *frame.RawFrame
+}
+
+type writeJournalPreparedStatement struct {
+	id       []byte // prepared id of the secondary cluster
+	query    string
+	keyspace string
+}
+
+// writeJournalStore stores the encoded entries of the journal in the order in which they were added.
+type writeJournalStore interface {
+	// append adds a record at the end of the journal.
+	append(record []byte) error
+	// first returns the first record of the journal or nil if the journal is empty.
+	first() ([]byte, error)
+	// removeFirst removes the first record of the journal.
+	removeFirst() error
+	// length returns the number of records of the journal.
+	length() int
+	// size returns the number of bytes of the records of the journal.
+	size() int64
+	close() error
+}
+
+type writeJournalReplayResult int
+
+const (
+	writeJournalReplayDone = writeJournalReplayResult(iota) // the first write was replayed or dropped
+	writeJournalReplayEmpty
+	writeJournalReplayUnavailable // the secondary cluster is not available, the write is replayed later
+)
+
+// newWriteJournal returns nil if the write journal is disabled.
+func newWriteJournal(
+	cluster common.ClusterType, conf *common.WriteJournalConfig, controlConn *ControlConn,
+	circuitBreaker *circuitBreaker, proxyMetrics *metrics.ProxyMetrics) (*writeJournal, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	var store writeJournalStore
+	if conf.Directory == "" {
+		store = newMemoryWriteJournalStore()
+	} else {
+		fileStore, err := openFileWriteJournalStore(filepath.Join(conf.Directory, writeJournalFileName))
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	}
+	proxyMetrics.WriteJournalPendingEntries.Add(store.length())
+
+	return &writeJournal{
+		cluster:        cluster,
+		conf:           conf,
+		controlConn:    controlConn,
+		circuitBreaker: circuitBreaker,
+		proxyMetrics:   proxyMetrics,
+		lock:           &sync.Mutex{},
+		store:          store,
+		notifyCh:       make(chan bool, 1),
+	}, nil
+}
+
+// newWriteJournalEntry returns the entry of the provided write of the secondary cluster.
+func newWriteJournalEntry(
+	cluster common.ClusterType, requestInfo RequestInfo, request *frame.RawFrame, keyspace string,
+	timestamp time.Time) *writeJournalEntry {
+	var preparedData []PreparedData
+	switch typedRequestInfo := requestInfo.(type) {
+	case *ExecuteRequestInfo:
+		preparedData = append(preparedData, typedRequestInfo.GetPreparedData())
+	case *BatchRequestInfo:
+		for _, data := range typedRequestInfo.GetPreparedDataByStmtIdx() {
+			preparedData = append(preparedData, data)
+		}
+	}
+
+	var preparedStatements []*writeJournalPreparedStatement
+	for _, data := range preparedData {
+		id := data.GetOriginPreparedId()
+		if cluster == common.ClusterTypeTarget {
+			id = data.GetTargetPreparedId()
+		}
+		preparedStatements = append(preparedStatements, &writeJournalPreparedStatement{
+			id:       id,
+			query:    data.GetPrepareRequestInfo().GetQuery(),
+			keyspace: data.GetPrepareRequestInfo().GetKeyspace(),
+		})
+	}
+
+	return &writeJournalEntry{
+		timestamp:          timestamp,
+		keyspace:           keyspace,
+		preparedStatements: preparedStatements,
+		request:            request.Clone(),
+	}
+}
+
+// add adds the write at the end of the journal, it returns false if the write was dropped.
+func (recv *writeJournal) add(entry *writeJournalEntry) bool {
+	record, err := encodeWriteJournalEntry(entry)
+	if err != nil {
+		recv.drop(entry, fmt.Sprintf("could not be encoded: %v", err))
+		return false
+	}
+
+	recv.lock.Lock()
+	if recv.store.size()+int64(len(record)+writeJournalRecordHeaderLength) > int64(recv.conf.MaxSizeBytes) {
+		pendingWrites := recv.store.length()
+		recv.lock.Unlock()
+		recv.drop(entry, fmt.Sprintf("could not be added to the journal because it is full (%v writes)", pendingWrites))
+		return false
+	}
+	err = recv.store.append(record)
+	if err == nil {
+		recv.proxyMetrics.WriteJournalPendingEntries.Add(1)
+	}
+	recv.lock.Unlock()
+	if err != nil {
+		recv.drop(entry, fmt.Sprintf("could not be added to the journal: %v", err))
+		return false
+	}
+
+	recv.proxyMetrics.WriteJournalQueued.Add(1)
+	select {
+	case recv.notifyCh <- true:
+	default:
+	}
+	return true
+}
+
+// pendingWrites returns the number of writes that are waiting to be replayed, 0 if the journal is nil (disabled).
+func (recv *writeJournal) pendingWrites() int {
+	if recv == nil {
+		return 0
+	}
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	return recv.store.length()
+}
+
+func (recv *writeJournal) drop(entry *writeJournalEntry, reason string) {
+	recv.proxyMetrics.WriteJournalDropped.Add(1)
+	log.Warnf("Write (%v) of %v received at %v %v, the %v cluster might be missing this write.",
+		entry.request.Header.OpCode, recv.cluster, entry.timestamp.Format(time.RFC3339Nano), reason, recv.cluster)
+}
+
+// run replays the writes of the journal until the context is canceled.
+func (recv *writeJournal) run(ctx context.Context) {
+	defer recv.close()
+	replayInterval := time.Second / time.Duration(recv.conf.ReplayRate)
+	for ctx.Err() == nil {
+		var waitCh <-chan bool
+		waitDuration := replayInterval
+		switch recv.replayFirst(ctx) {
+		case writeJournalReplayEmpty:
+			waitCh = recv.notifyCh
+			waitDuration = 0
+		case writeJournalReplayUnavailable:
+			waitDuration = writeJournalRetryInterval
+		}
+
+		var timer *time.Timer
+		var timerCh <-chan time.Time
+		if waitDuration > 0 {
+			timer = time.NewTimer(waitDuration)
+			timerCh = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-waitCh:
+		case <-timerCh:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// replayFirst replays the first write of the journal.
+func (recv *writeJournal) replayFirst(ctx context.Context) writeJournalReplayResult {
+	recv.lock.Lock()
+	record, err := recv.store.first()
+	recv.lock.Unlock()
+	if err != nil {
+		log.Errorf("Could not read the journal of the writes of %v: %v", recv.cluster, err)
+		return writeJournalReplayUnavailable
+	}
+	if record == nil {
+		return writeJournalReplayEmpty
+	}
+
+	if !recv.circuitBreaker.isClosed() {
+		return writeJournalReplayUnavailable
+	}
+
+	entry, err := decodeWriteJournalEntry(record)
+	if err != nil {
+		log.Errorf("Dropping a write of the journal of %v because it could not be decoded: %v", recv.cluster, err)
+		recv.proxyMetrics.WriteJournalDropped.Add(1)
+		recv.removeFirst()
+		return writeJournalReplayDone
+	}
+
+	response, err := recv.replay(ctx, entry)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warnf("Could not replay write (%v) on %v, it will be replayed again in %v: %v",
+				entry.request.Header.OpCode, recv.cluster, writeJournalRetryInterval, err)
+		}
+		recv.closeConnection()
+		return writeJournalReplayUnavailable
+	}
+
+	if errMsg, ok := response.(message.Error); ok {
+		if isRetryableAsyncWriteError(errMsg) {
+			log.Warnf("Replay of write (%v) on %v failed with error code %v (%v), it will be replayed again in %v.",
+				entry.request.Header.OpCode, recv.cluster, errMsg.GetErrorCode(), errMsg.GetErrorMessage(),
+				writeJournalRetryInterval)
+			return writeJournalReplayUnavailable
+		}
+		recv.drop(entry, fmt.Sprintf("could not be replayed: error code %v (%v)",
+			errMsg.GetErrorCode(), errMsg.GetErrorMessage()))
+		recv.removeFirst()
+		return writeJournalReplayDone
+	}
+
+	log.Debugf("Replayed write (%v) of %v received at %v.",
+		entry.request.Header.OpCode, recv.cluster, entry.timestamp.Format(time.RFC3339Nano))
+	recv.proxyMetrics.WriteJournalReplayed.Add(1)
+	recv.removeFirst()
+	return writeJournalReplayDone
+}
+
+func (recv *writeJournal) removeFirst() {
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if err := recv.store.removeFirst(); err != nil {
+		log.Errorf("Could not remove a write from the journal of the writes of %v: %v", recv.cluster, err)
+		return
+	}
+	recv.proxyMetrics.WriteJournalPendingEntries.Subtract(1)
+}
+
+// replay sends the write to the secondary cluster, it returns an error if the secondary cluster is not available.
+func (recv *writeJournal) replay(ctx context.Context, entry *writeJournalEntry) (message.Message, error) {
+	request, err := defaultCodec.ConvertFromRawFrame(entry.request)
+	if err != nil {
+		return &message.Invalid{ErrorMessage: fmt.Sprintf("could not decode write: %v", err)}, nil
+	}
+	setDefaultTimestamp(request, entry.timestamp)
+
+	version := request.Header.Version
+	if recv.conn != nil && recv.connVersion != version {
+		recv.closeConnection()
+	}
+	if recv.conn == nil {
+		conn, endpoint, err := recv.controlConn.OpenDedicatedConnection(version, ctx)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Opened connection to %v using endpoint %v to replay the writes of the journal.",
+			recv.cluster, endpoint.GetEndpointIdentifier())
+		recv.conn = conn
+		recv.connVersion = version
+		recv.connKeyspace = ""
+	}
+
+	if entry.keyspace != "" && entry.keyspace != recv.connKeyspace {
+		useQuery := fmt.Sprintf("USE \"%v\"", strings.ReplaceAll(entry.keyspace, "\"", "\"\""))
+		response, err := recv.conn.SendAndReceive(frame.NewFrame(version, 0, &message.Query{Query: useQuery}), ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := response.Body.Message.(message.Error); ok {
+			return response.Body.Message, nil
+		}
+		recv.connKeyspace = entry.keyspace
+	}
+
+	// every statement of a batch may have to be prepared again
+	for attempt := 0; ; attempt++ {
+		response, err := recv.conn.SendAndReceive(request, ctx)
+		if err != nil {
+			return nil, err
+		}
+		unprepared, ok := response.Body.Message.(*message.Unprepared)
+		if !ok || attempt >= len(entry.preparedStatements) {
+			return response.Body.Message, nil
+		}
+
+		var preparedStatement *writeJournalPreparedStatement
+		for _, statement := range entry.preparedStatements {
+			if bytes.Equal(statement.id, unprepared.Id) {
+				preparedStatement = statement
+			}
+		}
+		if preparedStatement == nil {
+			return response.Body.Message, nil
+		}
+		prepare := &message.Prepare{Query: preparedStatement.query, Keyspace: preparedStatement.keyspace}
+		response, err = recv.conn.SendAndReceive(frame.NewFrame(version, 0, prepare), ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := response.Body.Message.(message.Error); ok {
+			return response.Body.Message, nil
+		}
+	}
+}
+
+func (recv *writeJournal) closeConnection() {
+	if recv.conn == nil {
+		return
+	}
+	if err := recv.conn.Close(); err != nil {
+		log.Warnf("Failed to close the connection that replays the writes of the journal: %v", err)
+	}
+	recv.conn = nil
+}
+
+func (recv *writeJournal) close() {
+	recv.closeConnection()
+	recv.lock.Lock()
+	defer recv.lock.Unlock()
+	if err := recv.store.close(); err != nil {
+		log.Errorf("Could not close the journal of the writes of %v: %v", recv.cluster, err)
+	}
+}
+
+// setDefaultTimestamp sets the default timestamp (in microseconds) of a QUERY, EXECUTE or BATCH request that doesn't
+// have one.
+func setDefaultTimestamp(request *frame.Frame, timestamp time.Time) {
+	if request.Header.Version < primitive.ProtocolVersion3 {
+		return
+	}
+	defaultTimestamp := &primitive.NillableInt64{Value: timestamp.UnixNano() / int64(time.Microsecond)}
+	switch msg := request.Body.Message.(type) {
+	case *message.Query:
+		if msg.Options == nil {
+			msg.Options = &message.QueryOptions{}
+		}
+		if msg.Options.DefaultTimestamp == nil {
+			msg.Options.DefaultTimestamp = defaultTimestamp
+		}
+	case *message.Execute:
+		if msg.Options == nil {
+			msg.Options = &message.QueryOptions{}
+		}
+		if msg.Options.DefaultTimestamp == nil {
+			msg.Options.DefaultTimestamp = defaultTimestamp
+		}
+	case *message.Batch:
+		if msg.DefaultTimestamp == nil {
+			msg.DefaultTimestamp = defaultTimestamp
+		}
+	}
+}
+
+func encodeWriteJournalEntry(entry *writeJournalEntry) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := primitive.WriteLong(entry.timestamp.UnixNano(), buf); err != nil {
+		return nil, err
+	}
+	if err := primitive.WriteString(entry.keyspace, buf); err != nil {
+		return nil, err
+	}
+	if err := primitive.WriteShort(uint16(len(entry.preparedStatements)), buf); err != nil {
+		return nil, err
+	}
+	for _, statement := range entry.preparedStatements {
+		if err := primitive.WriteShortBytes(statement.id, buf); err != nil {
+			return nil, err
+		}
+		if err := primitive.WriteLongString(statement.query, buf); err != nil {
+			return nil, err
+		}
+		if err := primitive.WriteString(statement.keyspace, buf); err != nil {
+			return nil, err
+		}
+	}
+	if err := defaultCodec.EncodeRawFrame(entry.request, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeWriteJournalEntry(record []byte) (*writeJournalEntry, error) {
+	reader := bytes.NewReader(record)
+	timestamp, err := primitive.ReadLong(reader)
+	if err != nil {
+		return nil, err
+	}
+	keyspace, err := primitive.ReadString(reader)
+	if err != nil {
+		return nil, err
+	}
+	statementCount, err := primitive.ReadShort(reader)
+	if err != nil {
+		return nil, err
+	}
+	preparedStatements := make([]*writeJournalPreparedStatement, statementCount)
+	for i := range preparedStatements {
+		statement := &writeJournalPreparedStatement{}
+		if statement.id, err = primitive.ReadShortBytes(reader); err != nil {
+			return nil, err
+		}
+		if statement.query, err = primitive.ReadLongString(reader); err != nil {
+			return nil, err
+		}
+		if statement.keyspace, err = primitive.ReadString(reader); err != nil {
+			return nil, err
+		}
+		preparedStatements[i] = statement
+	}
+	request, err := defaultCodec.DecodeRawFrame(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &writeJournalEntry{
+		timestamp:          time.Unix(0, timestamp),
+		keyspace:           keyspace,
+		preparedStatements: preparedStatements,
+		request:            request,
+	}, nil
+}
+
+type memoryWriteJournalStore struct {
+	records [][]byte
+	bytes   int64
+}
+
+func newMemoryWriteJournalStore() *memoryWriteJournalStore {
+	return &memoryWriteJournalStore{}
+}
+
+func (recv *memoryWriteJournalStore) append(record []byte) error {
+	recv.records = append(recv.records, record)
+	recv.bytes += int64(len(record) + writeJournalRecordHeaderLength)
+	return nil
+}
+
+func (recv *memoryWriteJournalStore) first() ([]byte, error) {
+	if len(recv.records) == 0 {
+		return nil, nil
+	}
+	return recv.records[0], nil
+}
+
+func (recv *memoryWriteJournalStore) removeFirst() error {
+	if len(recv.records) == 0 {
+		return nil
+	}
+	recv.bytes -= int64(len(recv.records[0]) + writeJournalRecordHeaderLength)
+	recv.records[0] = nil
+	recv.records = recv.records[1:]
+	return nil
+}
+
+func (recv *memoryWriteJournalStore) length() int {
+	return len(recv.records)
+}
+
+func (recv *memoryWriteJournalStore) size() int64 {
+	return recv.bytes
+}
+
+func (recv *memoryWriteJournalStore) close() error {
+	return nil
+}
+
+// fileWriteJournalStore appends the records to a file that starts with the offset of its first pending record, the
+// file is truncated once every record was removed.
+type fileWriteJournalStore struct {
+	file    *os.File
+	start   int64 // offset of the first pending record
+	end     int64
+	records int
+}
+
+// openFileWriteJournalStore opens (or creates) the journal file, a record that was not fully written (e.g. because
+// the proxy was killed) is removed.
+func openFileWriteJournalStore(path string) (*fileWriteJournalStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open write journal file %v: %w", path, err)
+	}
+	store := &fileWriteJournalStore{file: file, start: writeJournalFileHeaderLength, end: writeJournalFileHeaderLength}
+	if err = store.load(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("could not load write journal file %v: %w", path, err)
+	}
+	if store.records > 0 {
+		log.Infof("Loaded %v writes to replay from write journal file %v.", store.records, path)
+	}
+	return store, nil
+}
+
+func (recv *fileWriteJournalStore) load() error {
+	fileInfo, err := recv.file.Stat()
+	if err != nil {
+		return err
+	}
+	if fileInfo.Size() < writeJournalFileHeaderLength {
+		return recv.reset()
+	}
+
+	header := make([]byte, writeJournalFileHeaderLength)
+	if _, err = recv.file.ReadAt(header, 0); err != nil {
+		return err
+	}
+	recv.start = int64(binary.BigEndian.Uint64(header))
+	if recv.start < writeJournalFileHeaderLength || recv.start > fileInfo.Size() {
+		return fmt.Errorf("invalid offset of the first record: %v", recv.start)
+	}
+
+	recv.end = recv.start
+	for recv.end < fileInfo.Size() {
+		record, err := recv.readRecord(recv.end)
+		if err != nil {
+			log.Warnf("Removing the end of write journal file %v at offset %v because it is not a valid record: %v",
+				recv.file.Name(), recv.end, err)
+			if err = recv.file.Truncate(recv.end); err != nil {
+				return err
+			}
+			break
+		}
+		recv.end += int64(len(record) + writeJournalRecordHeaderLength)
+		recv.records++
+	}
+	if recv.records == 0 {
+		return recv.reset()
+	}
+	return nil
+}
+
+func (recv *fileWriteJournalStore) readRecord(offset int64) ([]byte, error) {
+	header := make([]byte, writeJournalRecordHeaderLength)
+	if _, err := recv.file.ReadAt(header, offset); err != nil {
+		return nil, err
+	}
+	record := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := recv.file.ReadAt(record, offset+writeJournalRecordHeaderLength); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return record, nil
+}
+
+func (recv *fileWriteJournalStore) append(record []byte) error {
+	buf := make([]byte, writeJournalRecordHeaderLength+len(record))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(record)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(record))
+	copy(buf[writeJournalRecordHeaderLength:], record)
+	if _, err := recv.file.WriteAt(buf, recv.end); err != nil {
+		return err
+	}
+	recv.end += int64(len(buf))
+	recv.records++
+	return nil
+}
+
+func (recv *fileWriteJournalStore) first() ([]byte, error) {
+	if recv.records == 0 {
+		return nil, nil
+	}
+	return recv.readRecord(recv.start)
+}
+
+func (recv *fileWriteJournalStore) removeFirst() error {
+	if recv.records == 0 {
+		return nil
+	}
+	record, err := recv.readRecord(recv.start)
+	if err != nil {
+		return err
+	}
+	recv.records--
+	if recv.records == 0 {
+		return recv.reset()
+	}
+	recv.start += int64(len(record) + writeJournalRecordHeaderLength)
+	removedBytes := recv.start - writeJournalFileHeaderLength
+	if removedBytes >= writeJournalCompactionMinBytes && removedBytes >= recv.size()+writeJournalRecordHeaderLength {
+		return recv.compact()
+	}
+	return recv.writeHeader()
+}
+
+// compact moves the pending records to the beginning of the file followed by an invalid record header so that the
+// records after them are ignored if the proxy stops before the file is truncated. It is only called when the removed
+// records take more space than both so the pending records are not overwritten before the header is updated.
+func (recv *fileWriteJournalStore) compact() error {
+	pending := make([]byte, recv.size()+writeJournalRecordHeaderLength)
+	if _, err := recv.file.ReadAt(pending[:recv.size()], recv.start); err != nil {
+		return err
+	}
+	for i := recv.size(); i < int64(len(pending)); i++ {
+		pending[i] = 0xFF
+	}
+	if _, err := recv.file.WriteAt(pending, writeJournalFileHeaderLength); err != nil {
+		return err
+	}
+	pending = pending[:recv.size()]
+	recv.start = writeJournalFileHeaderLength
+	recv.end = writeJournalFileHeaderLength + int64(len(pending))
+	if err := recv.writeHeader(); err != nil {
+		return err
+	}
+	return recv.file.Truncate(recv.end)
+}
+
+func (recv *fileWriteJournalStore) reset() error {
+	recv.start = writeJournalFileHeaderLength
+	recv.end = writeJournalFileHeaderLength
+	recv.records = 0
+	if err := recv.file.Truncate(writeJournalFileHeaderLength); err != nil {
+		return err
+	}
+	return recv.writeHeader()
+}
+
+func (recv *fileWriteJournalStore) writeHeader() error {
+	header := make([]byte, writeJournalFileHeaderLength)
+	binary.BigEndian.PutUint64(header, uint64(recv.start))
+	_, err := recv.file.WriteAt(header, 0)
+	return err
+}
+
+func (recv *fileWriteJournalStore) length() int {
+	return recv.records
+}
+
+func (recv *fileWriteJournalStore) size() int64 {
+	return recv.end - recv.start
+}
+
+func (recv *fileWriteJournalStore) close() error {
+	return recv.file.Close()
+}
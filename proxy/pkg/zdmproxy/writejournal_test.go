@@ -0,0 +1,186 @@
+package zdmproxy
+
+import (
+	"bytes"
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/datastax/zdm-proxy/proxy/pkg/common"
+	"github.com/datastax/zdm-proxy/proxy/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestWriteJournalEntry(t *testing.T, query string) *writeJournalEntry {
+	request, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{
+		Query: query, Options: &message.QueryOptions{Consistency: primitive.ConsistencyLevelLocalQuorum}}))
+	require.Nil(t, err)
+	return newWriteJournalEntry(common.ClusterTypeTarget, NewGenericRequestInfo(forwardToBoth, true, false),
+		request, "ks", time.Unix(1650000000, 123456000))
+}
+
+func TestWriteJournalEntryEncoding(t *testing.T) {
+	executeRequest, err := defaultCodec.ConvertToRawFrame(frame.NewFrame(
+		primitive.ProtocolVersion4, 2, &message.Execute{QueryId: []byte{0x0B}}))
+	require.Nil(t, err)
+	preparedData := NewPreparedData(
+		&message.PreparedResult{PreparedQueryId: []byte{0x0A}}, &message.PreparedResult{PreparedQueryId: []byte{0x0B}},
+		NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, true, false), nil, false,
+			"INSERT INTO tb (a) VALUES (?)", "ks2"))
+	entry := newWriteJournalEntry(common.ClusterTypeTarget, NewExecuteRequestInfo(preparedData), executeRequest,
+		"ks", time.Unix(1650000000, 123456000))
+
+	record, err := encodeWriteJournalEntry(entry)
+	require.Nil(t, err)
+	decodedEntry, err := decodeWriteJournalEntry(record)
+	require.Nil(t, err)
+	require.True(t, entry.timestamp.Equal(decodedEntry.timestamp))
+	require.Equal(t, "ks", decodedEntry.keyspace)
+	require.Equal(t, []*writeJournalPreparedStatement{{
+		id: []byte{0x0B}, query: "INSERT INTO tb (a) VALUES (?)", keyspace: "ks2"}}, decodedEntry.preparedStatements)
+	require.Equal(t, entry.request, decodedEntry.request)
+
+	_, err = decodeWriteJournalEntry(record[:len(record)-1])
+	require.NotNil(t, err)
+}
+
+func TestFileWriteJournalStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), writeJournalFileName)
+	store, err := openFileWriteJournalStore(path)
+	require.Nil(t, err)
+	for _, record := range []string{"first", "second", "third"} {
+		require.Nil(t, store.append([]byte(record)))
+	}
+	require.Nil(t, store.removeFirst())
+	require.Equal(t, 2, store.length())
+	require.Equal(t, int64(len("second")+len("third")+2*writeJournalRecordHeaderLength), store.size())
+	require.Nil(t, store.close())
+
+	// the pending records are loaded and a record that was not fully written is removed
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.Nil(t, err)
+	_, err = file.Write([]byte{0, 0, 0, 10, 1, 2, 3, 4, 'f', 'o'})
+	require.Nil(t, err)
+	require.Nil(t, file.Close())
+
+	store, err = openFileWriteJournalStore(path)
+	require.Nil(t, err)
+	require.Equal(t, 2, store.length())
+	record, err := store.first()
+	require.Nil(t, err)
+	require.Equal(t, []byte("second"), record)
+	require.Nil(t, store.append([]byte("fourth")))
+	require.Nil(t, store.removeFirst())
+	record, err = store.first()
+	require.Nil(t, err)
+	require.Equal(t, []byte("third"), record)
+	require.Nil(t, store.removeFirst())
+	require.Nil(t, store.removeFirst())
+	record, err = store.first()
+	require.Nil(t, err)
+	require.Nil(t, record)
+	require.Nil(t, store.close())
+
+	// the file is truncated once every record was removed
+	fileInfo, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, int64(writeJournalFileHeaderLength), fileInfo.Size())
+}
+
+func TestFileWriteJournalStore_Compaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), writeJournalFileName)
+	store, err := openFileWriteJournalStore(path)
+	require.Nil(t, err)
+	records := make([][]byte, 5)
+	for i := range records {
+		records[i] = bytes.Repeat([]byte{byte(i)}, 300*1024)
+		require.Nil(t, store.append(records[i]))
+	}
+
+	// the removed records take 900KB, less than writeJournalCompactionMinBytes
+	for i := 0; i < 3; i++ {
+		require.Nil(t, store.removeFirst())
+	}
+	fileInfo, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, int64(writeJournalFileHeaderLength+5*(300*1024+writeJournalRecordHeaderLength)), fileInfo.Size())
+
+	require.Nil(t, store.removeFirst())
+	fileInfo, err = os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, int64(writeJournalFileHeaderLength+300*1024+writeJournalRecordHeaderLength), fileInfo.Size())
+	require.Nil(t, store.append([]byte("last")))
+	require.Nil(t, store.close())
+
+	store, err = openFileWriteJournalStore(path)
+	require.Nil(t, err)
+	require.Equal(t, 2, store.length())
+	record, err := store.first()
+	require.Nil(t, err)
+	require.Equal(t, records[4], record)
+	require.Nil(t, store.removeFirst())
+	record, err = store.first()
+	require.Nil(t, err)
+	require.Equal(t, []byte("last"), record)
+	require.Nil(t, store.close())
+}
+
+func TestWriteJournal_Add(t *testing.T) {
+	disabledJournal, err := newWriteJournal(common.ClusterTypeTarget, &common.WriteJournalConfig{}, nil, nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, disabledJournal)
+
+	queued, replayed, dropped := &countingCounter{}, &countingCounter{}, &countingCounter{}
+	pending := &countingGauge{}
+	proxyMetrics := &metrics.ProxyMetrics{
+		WriteJournalQueued:         queued,
+		WriteJournalReplayed:       replayed,
+		WriteJournalDropped:        dropped,
+		WriteJournalPendingEntries: pending,
+	}
+	entry := newTestWriteJournalEntry(t, "INSERT INTO tb (a) VALUES (1)")
+	record, err := encodeWriteJournalEntry(entry)
+	require.Nil(t, err)
+
+	journal, err := newWriteJournal(common.ClusterTypeTarget, &common.WriteJournalConfig{
+		Enabled: true, MaxSizeBytes: 2 * (len(record) + writeJournalRecordHeaderLength), ReplayRate: 10},
+		nil, nil, proxyMetrics)
+	require.Nil(t, err)
+	require.True(t, journal.add(entry))
+	require.True(t, journal.add(entry))
+	require.False(t, journal.add(entry))
+	require.Equal(t, int64(2), atomic.LoadInt64(&queued.count))
+	require.Equal(t, int64(1), atomic.LoadInt64(&dropped.count))
+	require.Equal(t, int64(2), atomic.LoadInt64(&pending.value))
+
+	journal.removeFirst()
+	require.Equal(t, int64(1), atomic.LoadInt64(&pending.value))
+	require.True(t, journal.add(entry))
+	require.Equal(t, int64(0), atomic.LoadInt64(&replayed.count))
+}
+
+func TestSetDefaultTimestamp(t *testing.T) {
+	timestamp := time.Unix(1650000000, 123456789)
+	existingTimestamp := &primitive.NillableInt64{Value: 42}
+
+	query := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Query{Query: "INSERT INTO tb (a) VALUES (1)"})
+	setDefaultTimestamp(query, timestamp)
+	require.Equal(t, int64(1650000000123456), query.Body.Message.(*message.Query).Options.DefaultTimestamp.Value)
+
+	execute := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Execute{
+		QueryId: []byte{1}, Options: &message.QueryOptions{DefaultTimestamp: existingTimestamp}})
+	setDefaultTimestamp(execute, timestamp)
+	require.Equal(t, existingTimestamp, execute.Body.Message.(*message.Execute).Options.DefaultTimestamp)
+
+	batch := frame.NewFrame(primitive.ProtocolVersion4, 1, &message.Batch{})
+	setDefaultTimestamp(batch, timestamp)
+	require.Equal(t, int64(1650000000123456), batch.Body.Message.(*message.Batch).DefaultTimestamp.Value)
+
+	oldQuery := frame.NewFrame(primitive.ProtocolVersion2, 1, &message.Query{Query: "INSERT INTO tb (a) VALUES (1)"})
+	setDefaultTimestamp(oldQuery, timestamp)
+	require.Nil(t, oldQuery.Body.Message.(*message.Query).Options)
+}
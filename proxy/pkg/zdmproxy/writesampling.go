@@ -0,0 +1,69 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/frame"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"math/rand"
+)
+
+// targetWriteSampler decides which writes are forwarded to TARGET when only a percentage of the writes should be
+// forwarded to TARGET (see ZDM_TARGET_WRITE_SAMPLING_PERCENTAGE). The writes that are not sampled are only
+// forwarded to ORIGIN. Each request is sampled as a whole, i.e., the statements of a BATCH are never split.
+type targetWriteSampler struct {
+	percentage           float64
+	ignoreTargetFailures bool
+	rand                 *rand.Rand
+}
+
+// newTargetWriteSampler returns nil if every write should be forwarded to TARGET.
+func newTargetWriteSampler(percentage float64, ignoreTargetFailures bool, rand *rand.Rand) *targetWriteSampler {
+	if percentage >= 100 {
+		return nil
+	}
+	return &targetWriteSampler{
+		percentage:           percentage,
+		ignoreTargetFailures: ignoreTargetFailures,
+		rand:                 rand,
+	}
+}
+
+func (recv *targetWriteSampler) isSampled() bool {
+	return recv.rand.Float64()*100 < recv.percentage
+}
+
+// isWriteRequest returns true if the request is a write that can be sampled. Requests that are forwarded to both
+// clusters but aren't writes (e.g. PREPARE, USE or schema changes) must always be forwarded to both clusters.
+func isWriteRequest(
+	frameContext *frameDecodeContext, requestInfo RequestInfo, currentKeyspace string,
+	timeUuidGenerator TimeUuidGenerator) (bool, error) {
+	switch typedRequestInfo := requestInfo.(type) {
+	case *BatchRequestInfo:
+		return true, nil
+	case *ExecuteRequestInfo:
+		queryInfo := typedRequestInfo.GetPreparedData().GetPrepareRequestInfo().GetQueryInfo()
+		return queryInfo != nil && isWriteStatementType(queryInfo.getStatementType()), nil
+	case *GenericRequestInfo:
+		if frameContext.GetRawFrame().Header.OpCode != primitive.OpCodeQuery {
+			return false, nil
+		}
+		stmtQueryData, err := frameContext.GetOrInspectStatement(currentKeyspace, timeUuidGenerator)
+		if err != nil {
+			return false, err
+		}
+		return isWriteStatementType(stmtQueryData.queryData.getStatementType()), nil
+	}
+	return false, nil
+}
+
+// isUnpreparedResponse returns true if the response is an UNPREPARED error. These are never ignored because
+// the client has to prepare the statement again.
+func isUnpreparedResponse(response *frame.RawFrame) bool {
+	if isResponseSuccessful(response) {
+		return false
+	}
+	errorMsg, err := decodeErrorResult(response)
+	if err != nil {
+		return false
+	}
+	return errorMsg.GetErrorCode() == primitive.ErrorCodeUnprepared
+}
@@ -0,0 +1,63 @@
+package zdmproxy
+
+import (
+	"github.com/datastax/go-cassandra-native-protocol/message"
+	"github.com/datastax/go-cassandra-native-protocol/primitive"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTargetWriteSampler(t *testing.T) {
+	require.Nil(t, newTargetWriteSampler(100, false, NewThreadSafeRand()))
+
+	sampler := newTargetWriteSampler(0, false, NewThreadSafeRand())
+	for i := 0; i < 1000; i++ {
+		require.False(t, sampler.isSampled())
+	}
+
+	sampler = newTargetWriteSampler(25, false, NewThreadSafeRand())
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		if sampler.isSampled() {
+			sampled++
+		}
+	}
+	require.InDelta(t, 2500, sampled, 500)
+}
+
+func TestIsWriteRequest(t *testing.T) {
+	generator, err := GetDefaultTimeUuidGenerator()
+	require.Nil(t, err)
+
+	newPreparedData := func(query string) PreparedData {
+		prepareRequestInfo := NewPrepareRequestInfo(NewGenericRequestInfo(forwardToBoth, false, true), nil, false, query, "")
+		prepareRequestInfo.queryInfo = inspectCqlQuery(query, "", generator)
+		return &preparedDataImpl{prepareRequestInfo: prepareRequestInfo}
+	}
+
+	tests := []struct {
+		name        string
+		msg         message.Message
+		requestInfo RequestInfo
+		expected    bool
+	}{
+		{"insert", &message.Query{Query: "INSERT INTO ks.tb (a) VALUES (1)"}, NewGenericRequestInfo(forwardToBoth, false, true), true},
+		{"update", &message.Query{Query: "UPDATE ks.tb SET b = 1 WHERE a = 1"}, NewGenericRequestInfo(forwardToBoth, false, true), true},
+		{"delete", &message.Query{Query: "DELETE FROM ks.tb WHERE a = 1"}, NewGenericRequestInfo(forwardToBoth, false, true), true},
+		{"use", &message.Query{Query: "USE ks"}, NewGenericRequestInfo(forwardToBoth, false, true), false},
+		{"schema change", &message.Query{Query: "CREATE TABLE ks.tb (a int PRIMARY KEY)"}, NewGenericRequestInfo(forwardToBoth, false, true), false},
+		{"register", &message.Register{EventTypes: []primitive.EventType{primitive.EventTypeSchemaChange}}, NewGenericRequestInfo(forwardToBoth, false, false), false},
+		{"execute insert", &message.Execute{QueryId: []byte("ID")}, NewExecuteRequestInfo(newPreparedData("INSERT INTO ks.tb (a) VALUES (?)")), true},
+		{"execute schema change", &message.Execute{QueryId: []byte("ID")}, NewExecuteRequestInfo(newPreparedData("DROP TABLE ks.tb")), false},
+		{"batch", &message.Batch{Children: []*message.BatchChild{{QueryOrId: "INSERT INTO ks.tb (a) VALUES (1)"}}}, NewBatchRequestInfo(nil, forwardToBoth), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mockFrame(t, tt.msg, primitive.ProtocolVersion4)
+			isWrite, err := isWriteRequest(NewFrameDecodeContext(f), tt.requestInfo, "", generator)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, isWrite)
+		})
+	}
+}